@@ -63,6 +63,7 @@ _datakit()
 			tool)
 				COMPREPLY=( $(compgen -W '--check-config
 				--check-sample
+				--config-drift
 				--completer-script
 				--default-main-conf
 				--dump-samples
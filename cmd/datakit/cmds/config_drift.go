@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	cp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/colorprint"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/confdrift"
+)
+
+const configDrift = "/v1/config/drift"
+
+func showConfigDrift() error {
+	d, err := requestConfigDrift(fmt.Sprintf("http://%s%s", config.Cfg.HTTPAPI.Listen, configDrift))
+	if err != nil {
+		cp.Warnf("[W] get drift from datakit failed: %s, compare with last applied manifest\n", err.Error())
+
+		if d, err = localConfigDrift(confdrift.ManifestPath()); err != nil {
+			return err
+		}
+	}
+
+	if *flagToolJSON {
+		j, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(j))
+		return nil
+	}
+
+	cp.Infof("applied at %s, %s ago\n", d.AppliedAt.Format(time.RFC3339), time.Since(d.AppliedAt).Round(time.Second))
+
+	if !d.Drifted() {
+		cp.Infof("no drift, all input confs applied\n")
+		return nil
+	}
+
+	if !d.Since.IsZero() {
+		cp.Warnf("drift detected since %s\n", d.Since.Format(time.RFC3339))
+	}
+
+	for _, c := range d.Changes {
+		msg := fmt.Sprintf("\t% 8s: %s", c.Change, c.Path)
+		if len(c.Inputs) > 0 {
+			msg += fmt.Sprintf(" (inputs: %s)", strings.Join(c.Inputs, ","))
+		}
+
+		cp.Warnf("%s\n", msg)
+	}
+
+	cp.Warnf("%d input confs changed but not applied, restart datakit to apply them\n", len(d.Changes))
+	return nil
+}
+
+func requestConfigDrift(requrl string) (*confdrift.Drift, error) {
+	resp, err := http.Get(requrl) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, string(body))
+	}
+
+	r := struct {
+		Content *confdrift.Drift `json:"content"`
+	}{}
+
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+
+	if r.Content == nil {
+		return nil, fmt.Errorf("empty drift response")
+	}
+
+	return r.Content, nil
+}
+
+// localConfigDrift compares on-disk input configures with the manifest
+// persisted by the last running datakit.
+func localConfigDrift(path string) (*confdrift.Drift, error) {
+	m, err := confdrift.LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := confdrift.Scan(m.Roots)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Diff(cur), nil
+}
@@ -83,6 +83,7 @@ var (
 	flagPLTxtFile     = fsPL.StringP("file", "F", "", "text file path for the pipeline or grok(json or raw text)")
 	flagPLTable       = fsPL.Bool("tab", false, "output result in table format")
 	flagPLDate        = fsPL.Bool("date", false, "append date display(according to local timezone) on timestamp")
+	flagPLTrace       = fsPL.Bool("trace", false, "show step-by-step execution trace of the script")
 	fsPLUsage         = func() {
 		fmt.Printf("usage: datakit pipeline [pipeline-script-name.p] [options]\n\n")
 		fmt.Printf("Pipeline used to debug exists pipeline script.\n\n")
@@ -169,6 +170,7 @@ var (
 	flagToolIPInfo            = fsTool.String("ipinfo", "", "show IP geo info")
 	flagToolWorkspaceInfo     = fsTool.Bool("workspace-info", false, "show workspace info")
 	flagToolCheckConfig       = fsTool.Bool("check-config", false, "check inputs configure and main configure")
+	flagToolConfigDrift       = fsTool.Bool("config-drift", false, "show input configures changed but not applied")
 	flagToolTestSNMP          = fsTool.String("test-snmp", "", "test snmp device")
 	flagToolDumpSamples       = fsTool.String("dump-samples", "", "dump all inputs samples")
 	flagToolLoadLog           = fsTool.Bool("upload-log", false, "upload log")
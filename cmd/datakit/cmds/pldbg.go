@@ -127,9 +127,22 @@ func pipelineDebugger(category, plname, ns, txt string, isPt bool) error {
 		pt = ptsW[0]
 	}
 
-	res, dropFlag, err := (&pipeline.Pipeline{
-		Script: plScript,
-	}).Run(pt, nil, opt, nil)
+	var (
+		res      *point.Point
+		dropFlag bool
+		trace    *script.Trace
+	)
+
+	runner := &pipeline.Pipeline{Script: plScript}
+	if *flagPLTrace {
+		res, dropFlag, trace, err = runner.RunTrace(pt, nil, opt, nil)
+		if trace != nil {
+			printTrace(trace)
+		}
+	} else {
+		res, dropFlag, err = runner.Run(pt, nil, opt, nil)
+	}
+
 	if err != nil {
 		return fmt.Errorf("run pipeline failed: %w", err)
 	}
@@ -202,6 +215,80 @@ func pipelineDebugger(category, plname, ns, txt string, isPt bool) error {
 	return nil
 }
 
+func traceValue(v interface{}) string {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(j)
+}
+
+func printTraceChanges(kind string, changes map[string]*script.Change) {
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		c := changes[k]
+		switch {
+		case c.Old == nil:
+			fmt.Printf("    + %s %s: %s\n", kind, k, traceValue(c.New))
+		case c.New == nil:
+			fmt.Printf("    - %s %s: %s\n", kind, k, traceValue(c.Old))
+		default:
+			fmt.Printf("    ~ %s %s: %s -> %s\n", kind, k, traceValue(c.Old), traceValue(c.New))
+		}
+	}
+}
+
+func printTraceDiff(d *script.PointDiff) {
+	if d == nil {
+		return
+	}
+
+	if d.Name != nil {
+		fmt.Printf("    ~ measurement: %s -> %s\n", traceValue(d.Name.Old), traceValue(d.Name.New))
+	}
+	printTraceChanges("tag", d.Tags)
+	printTraceChanges("field", d.Fields)
+	if d.Drop != nil {
+		fmt.Printf("    ~ drop: %v -> %v\n", d.Drop.Old, d.Drop.New)
+	}
+}
+
+func printTrace(trace *script.Trace) {
+	for i, step := range trace.Steps {
+		cp.Infof("[%d] line %d: %s\n", i+1, step.Line, step.Stmt)
+
+		for _, c := range step.Calls {
+			if c.Error != "" {
+				cp.Errorf("    call %s() at %d:%d failed: %s\n", c.Name, c.Line, c.Col, c.Error)
+			} else {
+				fmt.Printf("    call %s() at %d:%d -> %s\n", c.Name, c.Line, c.Col, traceValue(c.Return))
+			}
+		}
+
+		printTraceDiff(step.Diff)
+
+		if len(step.Vars) > 0 {
+			fmt.Printf("    vars: %s\n", traceValue(step.Vars))
+		}
+
+		if step.Error != "" {
+			cp.Errorf("    error: %s\n", step.Error)
+		}
+	}
+
+	if trace.Diff != nil {
+		cp.Infof("[final] changes of the point:\n")
+		printTraceDiff(trace.Diff)
+	}
+
+	cp.Infof("---------------\n")
+}
+
 func plScriptTmpStore(category string) (*script.ScriptStore, map[string]map[string]error) {
 	store := script.NewScriptStore(category)
 
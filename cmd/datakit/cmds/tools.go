@@ -117,6 +117,14 @@ func runToolFlags() error {
 		}
 		os.Exit(0)
 
+	case *flagToolConfigDrift:
+		tryLoadMainCfg()
+		if err := showConfigDrift(); err != nil {
+			cp.Errorf("[E] show config drift failed: %s\n", err.Error())
+			os.Exit(-1)
+		}
+		os.Exit(0)
+
 	case *flagToolTestSNMP != "":
 		if !datakit.FileExist(*flagToolTestSNMP) {
 			cp.Errorf("[E] File not exist: %s\n", *flagToolTestSNMP)
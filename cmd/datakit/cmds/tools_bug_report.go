@@ -320,11 +320,13 @@ func (info *datakitInfo) copyFile(src, dst string, transform transformFunc) erro
 	return nil
 }
 
-func (info *datakitInfo) compressDir() (string, error) {
+// compressDir compresses collected files into a zip under dir, the current
+// dir if empty.
+func (info *datakitInfo) compressDir(dir string) (string, error) {
 	srcDir := info.tmpDir
 	date := time.Now().UnixMilli()
 	fileName := fmt.Sprintf("info-%d", date)
-	zipPath := fmt.Sprintf("%s.zip", fileName)
+	zipPath := filepath.Join(dir, fmt.Sprintf("%s.zip", fileName))
 	// Open a file to write the compressed data to
 	zipFile, err := os.Create(filepath.Clean(zipPath))
 	if err != nil {
@@ -430,10 +432,22 @@ func (info *datakitInfo) copyDir(srcDir string, dstDir string, transform transfo
 }
 
 func bugReport() error {
+	zipPath, err := BugReportZip("")
+	if err != nil {
+		return err
+	}
+
+	cp.Infof("DataKit info saved into %s\n", zipPath)
+	return nil
+}
+
+// BugReportZip collects logs, configs and metrics of DataKit into a zip under
+// dir, and returns path of the zip.
+func BugReportZip(dir string) (string, error) {
 	infoInstance := &datakitInfo{}
 
 	if err := infoInstance.init(); err != nil {
-		return err
+		return "", err
 	}
 
 	defer func() {
@@ -444,14 +458,8 @@ func bugReport() error {
 	}()
 
 	if err := infoInstance.collect(); err != nil {
-		return err
-	}
-
-	if zipPath, err := infoInstance.compressDir(); err != nil {
-		cp.Errorf("compress zip file failed: %s\n", err.Error())
-	} else {
-		cp.Infof("DataKit info saved into %s\n", zipPath)
+		return "", err
 	}
 
-	return nil
+	return infoInstance.compressDir(dir)
 }
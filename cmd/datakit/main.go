@@ -23,11 +23,15 @@ import (
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/checkutil"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/confdrift"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/dnswatcher"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/election"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/metrics"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/service"
 	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	plRemote "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/remote"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/all"
@@ -187,6 +191,8 @@ func startIO() {
 		dkio.WithFilters(c.Filters),
 		dkio.WithCacheAll(c.CacheAll),
 		dkio.WithFlushWorkers(c.FlushWorkers),
+		dkio.WithDownsampling(c.Downsampling),
+		dkio.WithSendQueue(c.SendQueueSize, c.SendQueueWorkers, c.SendQueueOverflow),
 	}
 
 	du, err := time.ParseDuration(c.FlushInterval)
@@ -202,6 +208,16 @@ func startIO() {
 		opts = append(opts, dkio.WithDiskCacheCleanInterval(du))
 	}
 
+	if c.EnableLocalFederate {
+		var ttl time.Duration
+		if c.LocalFederateTTL != "" {
+			if ttl, err = time.ParseDuration(c.LocalFederateTTL); err != nil {
+				l.Warnf("parse LocalFederateTTL failed: %s, use default 5m", err)
+			}
+		}
+		opts = append(opts, dkio.WithLocalFederate(true, ttl))
+	}
+
 	dkio.Start(opts...)
 }
 
@@ -216,14 +232,25 @@ func doRun() error {
 		return true
 	})
 
+	loadshed.Start(config.Cfg.LoadShedding, func(pts []*point.Point) error {
+		return dkio.Feed("loadshed", datakit.KeyEvent, pts, nil)
+	})
+
+	if err := probelimit.Setup(config.Cfg.ProbeLimit); err != nil {
+		l.Errorf("probelimit.Setup: %s, outbound probes unlimited", err)
+	}
+
 	if config.Cfg.Dataway != nil {
 		election.Start(
 			election.WithElectionEnabled(config.Cfg.Election.Enable),
+			election.WithObserver(config.Cfg.Election.Observer),
 			election.WithID(config.Cfg.Hostname),
 			election.WithNamespace(config.Cfg.Election.Namespace),
 			election.WithPuller(config.Cfg.Dataway),
 		)
 
+		startRemoteJob()
+
 		if len(config.Cfg.Dataway.URLs) == 1 {
 			// https://gitlab.jiagouyun.com/cloudcare-tools/datakit/-/issues/524
 			plRemote.StartPipelineRemote(config.Cfg.Dataway.URLs)
@@ -248,6 +275,9 @@ func doRun() error {
 			return err
 		}
 	} else {
+		// inputs from confd are not on disk, no drift on them
+		confdrift.StartWatch()
+
 		if config.GitHasEnabled() {
 			if err := gitrepo.StartPull(); err != nil {
 				l.Errorf("gitrepo.StartPull failed: %v", err)
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/metrics"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/cmd/datakit/cmds"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/remotejob"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+func startRemoteJob() {
+	remotejob.Register(remotejob.JobBugReport, bugReportJob)
+	remotejob.Register(remotejob.JobInputTest, inputTestJob)
+	remotejob.Register(remotejob.JobFlushCache, flushCacheJob)
+
+	if err := remotejob.Start(config.Cfg.RemoteJob, config.Cfg.Dataway); err != nil {
+		l.Errorf("remotejob.Start: %s, remote jobs disabled", err)
+	}
+}
+
+func bugReportJob(_ context.Context, _ map[string]string) (*remotejob.Result, error) {
+	dir, err := os.MkdirTemp("", "datakit-remote-job")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	zipPath, err := cmds.BugReportZip(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := os.ReadFile(filepath.Clean(zipPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return &remotejob.Result{ContentType: "application/zip", Body: body}, nil
+}
+
+type inputTestResult struct {
+	Input     string                `json:"input"`
+	Instances int                   `json:"instances"`
+	Points    map[string]int        `json:"points,omitempty"` // points of each measurement collected once
+	Status    *dkio.CollectorStatus `json:"status,omitempty"`
+	Errors    []string              `json:"errors,omitempty"`
+}
+
+// inputTestJob collects once by instances of the input named by arg `input`,
+// and reports feed status of the input. Instances are built from conf files
+// of the input, running instances are not touched.
+func inputTestJob(ctx context.Context, args map[string]string) (*remotejob.Result, error) {
+	name := args["input"]
+	if name == "" {
+		return nil, fmt.Errorf("arg input required")
+	}
+
+	creator, ok := inputs.Inputs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown input %s", name)
+	}
+
+	confs := inputs.LoadedConfPaths(name)
+	if len(confs) == 0 {
+		return nil, fmt.Errorf("input %s not loaded", name)
+	}
+
+	res := &inputTestResult{Input: name}
+
+	for _, fp := range confs {
+		instances, err := config.LoadSingleConfFile(fp, map[string]inputs.Creator{name: creator}, true)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("load %s: %s", fp, err))
+			continue
+		}
+
+		for _, x := range instances[name] {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			res.Instances++
+
+			// inputs not collecting once are collected by the running
+			// instances, only status reported
+			i, ok := x.(inputs.InputOnceRunnableCollect)
+			if !ok {
+				continue
+			}
+
+			mpts, err := i.Collect()
+			if err != nil {
+				res.Errors = append(res.Errors, err.Error())
+				continue
+			}
+
+			if res.Points == nil {
+				res.Points = map[string]int{}
+			}
+			for k, pts := range mpts {
+				res.Points[k] += len(pts)
+			}
+		}
+	}
+
+	if mfs, err := metrics.Gather(); err == nil {
+		for _, cs := range dkio.FeedMetrics(mfs, time.Hour) {
+			if cs.Name == name {
+				res.Status = cs
+			}
+		}
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remotejob.Result{ContentType: "application/json", Body: body}, nil
+}
+
+func flushCacheJob(_ context.Context, _ map[string]string) (*remotejob.Result, error) {
+	dkio.ForceFlush()
+	return &remotejob.Result{Body: []byte("flush requested")}, nil
+}
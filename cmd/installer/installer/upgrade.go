@@ -12,6 +12,7 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	cp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/colorprint"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/migration"
 )
 
 var l = logger.DefaultSLogger("upgrade")
@@ -22,9 +23,11 @@ func SetLog() {
 
 func Upgrade() error {
 	mc := config.Cfg
+	fromVersion := ""
 
 	// load exists datakit.conf
 	if err := mc.LoadMainTOML(datakit.MainConfPath); err == nil {
+		fromVersion = mc.InstallVer
 		mc = upgradeMainConfig(mc)
 
 		if OTA {
@@ -49,6 +52,13 @@ func Upgrade() error {
 		}
 	}
 
+	// upgrade deprecated keys of input configures and functions of pipeline scripts
+	if rep, err := migration.Run(fromVersion, DataKitVersion, nil); err != nil {
+		cp.Warnf("migrate configures failed: %s, ignored\n", err.Error())
+	} else if len(rep.Changes) > 0 || len(rep.Errors) > 0 {
+		cp.Infof("%s\n", rep.String())
+	}
+
 	return nil
 }
 
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package config
+
+import (
+	"errors"
+	"path/filepath"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cloudmeta"
+)
+
+type CloudMetaCfg struct {
+	// Add tags of the cloud instance(provider, instance ID, region, zone and
+	// instance type) to all data.
+	Enable bool `toml:"enable"`
+
+	// Cloud provider(aws/gcp/azure/aliyun) to detect, all detected if empty.
+	Provider string `toml:"provider"`
+}
+
+// setCloudMeta detects the cloud instance, tags of it are added in setupGlobalTags.
+func (c *Config) setCloudMeta() {
+	if c.CloudMeta == nil || !c.CloudMeta.Enable {
+		return
+	}
+
+	m, err := cloudmeta.Detect(
+		cloudmeta.WithProvider(c.CloudMeta.Provider),
+		cloudmeta.WithCacheFile(filepath.Join(datakit.DataDir, ".cloud_meta"), 0),
+	)
+	if err != nil {
+		if errors.Is(err, cloudmeta.ErrNotCloud) {
+			l.Info("not running on cloud instance, cloud tags not added")
+		} else {
+			l.Warnf("detect cloud metadata: %s, ignored", err)
+		}
+		return
+	}
+
+	l.Infof("cloud instance: %+#v", m)
+	c.cloudMetaTags = m.Tags()
+}
+
+func (c *Config) loadCloudMetaEnvs() {
+	if c.CloudMeta == nil {
+		c.CloudMeta = &CloudMetaCfg{Enable: true}
+	}
+
+	if v := datakit.GetEnv("ENV_DISABLE_CLOUD_META_TAGS"); v != "" {
+		c.CloudMeta.Enable = false
+	}
+
+	if v := datakit.GetEnv("ENV_CLOUD_META_PROVIDER"); v != "" {
+		c.CloudMeta.Provider = v
+	}
+}
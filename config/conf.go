@@ -23,8 +23,10 @@ import (
 	"github.com/GuanceCloud/cliutils/logger"
 	gctoml "github.com/GuanceCloud/toml"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/hostid"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/man"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
 var (
@@ -51,6 +53,16 @@ type GitRepository struct {
 	SSHPrivateKeyPath     string `toml:"ssh_private_key_path"`
 	SSHPrivateKeyPassword string `toml:"ssh_private_key_password"`
 	Branch                string `toml:"branch"`
+
+	// Tag or commit pinned, take precedence over branch.
+	Tag    string `toml:"tag,omitempty"`
+	Commit string `toml:"commit,omitempty"`
+
+	// Verify signature of the pinned tag or the head commit with the armored key ring.
+	GPGKeyRingPath string `toml:"gpg_key_ring_path,omitempty"`
+
+	// sha256sum style checksum manifest within the repository, all files must match.
+	ChecksumManifest string `toml:"checksum_manifest,omitempty"`
 }
 
 type GitRepost struct {
@@ -377,6 +389,17 @@ func (c *Config) setupGlobalTags() {
 	// 此处不将 host 计入 c.GlobalHostTags，因为 c.GlobalHostTags 是读取的用户配置，而 host
 	// 是不允许修改的, 故单独添加这个 tag 到 io 模块
 	point.SetGlobalHostTags("host", c.Hostname)
+
+	if c.HostIdentity != nil && c.HostIdentity.EnableHostIDTag && datakit.DatakitHostID != "" {
+		point.SetGlobalHostTags("host_id", datakit.DatakitHostID)
+	}
+
+	// tags configured in global host tags not overwritten
+	for k, v := range c.cloudMetaTags {
+		if _, ok := c.GlobalHostTags[k]; !ok {
+			point.SetGlobalHostTags(k, v)
+		}
+	}
 }
 
 func (c *Config) ApplyMainConfig() error {
@@ -402,6 +425,9 @@ func (c *Config) ApplyMainConfig() error {
 		}
 	}
 
+	c.setHostID()
+	c.setCloudMeta()
+
 	if c.Dataway != nil && len(c.Dataway.URLs) > 0 {
 		if err := c.SetupDataway(); err != nil {
 			return err
@@ -425,6 +451,8 @@ func (c *Config) ApplyMainConfig() error {
 
 	c.setupGlobalTags()
 
+	inputs.SetStartup(c.Startup)
+
 	// remove deprecated UUID field in main configure
 	if c.UUIDDeprecated != "" {
 		c.UUIDDeprecated = "" // clear deprecated UUID field
@@ -453,8 +481,8 @@ func (c *Config) setHostname() error {
 		return nil
 	}
 
-	// get real hostname
-	hn, err := os.Hostname()
+	// get real hostname, the host's instead of the container's if running in container
+	hn, err := hostid.Hostname(hostid.WithRoot(hostRoot()))
 	if err != nil {
 		l.Errorf("get hostname failed: %s", err.Error())
 		return err
@@ -5,7 +5,10 @@
 
 package config
 
-import "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/filter"
+import (
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/filter"
+)
 
 type IOConf struct {
 	FeedChanSize int `toml:"feed_chan_size,omitzero"`
@@ -16,6 +19,12 @@ type IOConf struct {
 	FlushInterval string `toml:"flush_interval"`
 	FlushWorkers  int    `toml:"flush_workers"`
 
+	// bounded queue(in batches) between flushing and sending to dataway,
+	// overflow is one of block/spill/drop, points are sent on flushing if size is 0
+	SendQueueSize     int    `toml:"send_queue_size,omitzero"`
+	SendQueueWorkers  int    `toml:"send_queue_workers,omitzero"`
+	SendQueueOverflow string `toml:"send_queue_overflow,omitempty"`
+
 	OutputFile       string   `toml:"output_file"`
 	OutputFileInputs []string `toml:"output_file_inputs"`
 
@@ -24,5 +33,12 @@ type IOConf struct {
 	CacheSizeGB        int    `toml:"cache_max_size_gb"`
 	CacheCleanInterval string `toml:"cache_clean_interval"`
 
+	// expose recent metric points at /v1/federate in Prometheus text format
+	EnableLocalFederate bool   `toml:"enable_local_federate"`
+	LocalFederateTTL    string `toml:"local_federate_ttl"`
+
+	// aggregate high-frequency metric points into coarser intervals before uploading
+	Downsampling []*dkio.DownsampleRule `toml:"downsampling"`
+
 	Filters map[string]filter.FilterConditions `toml:"filters"`
 }
@@ -8,6 +8,7 @@ package config
 type ElectionCfg struct {
 	Enable             bool `toml:"enable"`
 	EnableNamespaceTag bool `toml:"enable_namespace_tag"`
+	Observer           bool `toml:"observer"`
 
 	Namespace string            `toml:"namespace"`
 	Tags      map[string]string `toml:"tags"`
@@ -12,9 +12,13 @@ import (
 	"time"
 
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/remotejob"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/filter"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/parser"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
 // LoadSink unmarshal sinker JSON string to dataway's sinker.
@@ -52,6 +56,10 @@ func (c *Config) loadElectionEnvs() {
 		c.Election.Tags["election_namespace"] = c.Election.Namespace
 	}
 
+	if v := datakit.GetEnv("ENV_ELECTION_OBSERVER"); v != "" {
+		c.Election.Observer = true
+	}
+
 	if v := datakit.GetEnv("ENV_CLUSTER_NAME_K8S"); v != "" {
 		c.Election.Tags["cluster_name_k8s"] = v
 	}
@@ -124,6 +132,46 @@ func (c *Config) loadIOEnvs() {
 		}
 	}
 
+	if v := datakit.GetEnv("ENV_IO_SEND_QUEUE_SIZE"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			l.Warnf("invalid env key ENV_IO_SEND_QUEUE_SIZE, value %s, err: %s ignored", v, err)
+		} else {
+			l.Infof("set ENV_IO_SEND_QUEUE_SIZE to %d", n)
+			c.IO.SendQueueSize = int(n)
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_IO_SEND_QUEUE_WORKERS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			l.Warnf("invalid env key ENV_IO_SEND_QUEUE_WORKERS, value %s, err: %s ignored", v, err)
+		} else {
+			l.Infof("set ENV_IO_SEND_QUEUE_WORKERS to %d", n)
+			c.IO.SendQueueWorkers = int(n)
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_IO_SEND_QUEUE_OVERFLOW"); v != "" {
+		l.Infof("set ENV_IO_SEND_QUEUE_OVERFLOW to %s", v)
+		c.IO.SendQueueOverflow = v
+	}
+
+	if v := datakit.GetEnv("ENV_IO_ENABLE_LOCAL_FEDERATE"); v != "" {
+		l.Info("ENV_IO_ENABLE_LOCAL_FEDERATE enabled")
+		c.IO.EnableLocalFederate = true
+	}
+
+	if v := datakit.GetEnv("ENV_IO_LOCAL_FEDERATE_TTL"); v != "" {
+		du, err := time.ParseDuration(v)
+		if err != nil {
+			l.Warnf("invalid env key ENV_IO_LOCAL_FEDERATE_TTL, value %s, err: %s ignored", v, err)
+		} else {
+			l.Infof("set ENV_IO_LOCAL_FEDERATE_TTL to %s", du)
+			c.IO.LocalFederateTTL = v
+		}
+	}
+
 	if v := datakit.GetEnv("ENV_IO_CACHE_CLEAN_INTERVAL"); v != "" {
 		du, err := time.ParseDuration(v)
 		if err != nil {
@@ -168,6 +216,42 @@ func (c *Config) LoadEnvs() error {
 		c.Pipeline.SQLiteMemMode = true
 	}
 
+	if v := datakit.GetEnv("ENV_PIPELINE_EMIT_METRIC_INTERVAL"); v != "" {
+		c.Pipeline.EmitMetricInterval = v
+	}
+
+	if v := datakit.GetEnv("ENV_PIPELINE_EMIT_METRIC_MAX_SERIES"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			l.Warnf("invalid ENV_PIPELINE_EMIT_METRIC_MAX_SERIES, expect int, got %s, ignored", v)
+		} else {
+			c.Pipeline.EmitMetricMaxSeries = n
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_PIPELINE_CACHE_MAX_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			l.Warnf("invalid ENV_PIPELINE_CACHE_MAX_ENTRIES, expect int, got %s, ignored", v)
+		} else {
+			c.Pipeline.CacheMaxEntries = n
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_PIPELINE_HTTP_REQUEST_TIMEOUT"); v != "" {
+		c.Pipeline.HTTPRequestTimeout = v
+	}
+
+	if v := datakit.GetEnv("ENV_PIPELINE_HTTP_REQUEST_CACHE_TTL"); v != "" {
+		c.Pipeline.HTTPRequestCacheTTL = v
+	}
+
+	if v := datakit.GetEnv("ENV_PIPELINE_HTTP_REQUEST_MAX_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			l.Warnf("invalid ENV_PIPELINE_HTTP_REQUEST_MAX_CONCURRENCY, expect int, got %s, ignored", v)
+		} else {
+			c.Pipeline.HTTPRequestMaxConcurrency = n
+		}
+	}
+
 	if v := datakit.GetEnv("ENV_REQUEST_RATE_LIMIT"); v != "" {
 		if x, err := strconv.ParseFloat(v, 64); err != nil {
 			l.Warnf("invalid ENV_REQUEST_RATE_LIMIT, expect int or float, got %s, ignored", v)
@@ -254,6 +338,50 @@ func (c *Config) LoadEnvs() error {
 		}
 	}
 
+	if v := datakit.GetEnv("ENV_DATAWAY_MAX_RESPONSE_SIZE"); v != "" {
+		value, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || value <= 0 {
+			l.Warnf("invalid ENV_DATAWAY_MAX_RESPONSE_SIZE: %s", v)
+		} else {
+			c.Dataway.MaxResponseSize = value
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_DATAWAY_WARM_UP"); v != "" {
+		c.Dataway.WarmUp = true
+	}
+
+	if v := datakit.GetEnv("ENV_DATAWAY_PING_INTERVAL"); v != "" {
+		if _, err := time.ParseDuration(v); err != nil {
+			l.Warnf("invalid ENV_DATAWAY_PING_INTERVAL: %s", v)
+		} else {
+			c.Dataway.PingInterval = v
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_DATAWAY_CHECKSUM"); v != "" {
+		c.Dataway.Checksum = v
+	}
+
+	// such as metric=s,logging=ms
+	if v := datakit.GetEnv("ENV_DATAWAY_PRECISION"); v != "" {
+		c.Dataway.Precision = ParseGlobalTags(v)
+	}
+
+	// such as {"openway.guance.com": {"X-Some-Header": "some-value"}}
+	if v := datakit.GetEnv("ENV_DATAWAY_ENDPOINT_HEADERS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &c.Dataway.EndpointHeaders); err != nil {
+			l.Warnf("invalid env key ENV_DATAWAY_ENDPOINT_HEADERS, value %q: %s", v, err)
+		}
+	}
+
+	// such as {"logging": {"X-Some-Header": "some-value"}}
+	if v := datakit.GetEnv("ENV_DATAWAY_CATEGORY_HEADERS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &c.Dataway.CategoryHeaders); err != nil {
+			l.Warnf("invalid env key ENV_DATAWAY_CATEGORY_HEADERS, value %q: %s", v, err)
+		}
+	}
+
 	if v := datakit.GetEnv("ENV_SINKER"); v != "" {
 		c.LoadSink(v)
 	}
@@ -279,6 +407,14 @@ func (c *Config) LoadEnvs() error {
 		c.HTTPAPI.CloseIdleConnection = true
 	}
 
+	if v := datakit.GetEnv("ENV_HTTP_MAX_DECOMPRESSED_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err != nil {
+			l.Warnf("invalid ENV_HTTP_MAX_DECOMPRESSED_SIZE, expect int, got %s, ignored", v)
+		} else {
+			c.HTTPAPI.MaxDecompressedSize = n
+		}
+	}
+
 	if v := datakit.GetEnv("ENV_HTTP_PUBLIC_APIS"); v != "" {
 		c.HTTPAPI.PublicAPIs = strings.Split(v, ",")
 	}
@@ -451,6 +587,10 @@ func (c *Config) LoadEnvs() error {
 					SSHPrivateKeyPath:     keyPath,
 					SSHPrivateKeyPassword: keyPasswd,
 					Branch:                branch,
+					Tag:                   datakit.GetEnv("ENV_GIT_TAG"),
+					Commit:                datakit.GetEnv("ENV_GIT_COMMIT"),
+					GPGKeyRingPath:        datakit.GetEnv("ENV_GIT_GPG_KEY_RING_PATH"),
+					ChecksumManifest:      datakit.GetEnv("ENV_GIT_CHECKSUM_MANIFEST"),
 				},
 			},
 		}
@@ -465,5 +605,128 @@ func (c *Config) LoadEnvs() error {
 		}
 	}
 
+	c.loadStartupEnvs()
+	c.loadHostIdentityEnvs()
+	c.loadCloudMetaEnvs()
+	c.loadLoadSheddingEnvs()
+	c.loadProbeLimitEnvs()
+	c.loadRemoteJobEnvs()
+
 	return nil
 }
+
+func (c *Config) loadLoadSheddingEnvs() {
+	if c.LoadShedding == nil {
+		c.LoadShedding = loadshed.DefaultConfig()
+	}
+
+	if v := datakit.GetEnv("ENV_ENABLE_LOAD_SHEDDING"); v != "" {
+		c.LoadShedding.Enable = true
+	}
+
+	if v := datakit.GetEnv("ENV_LOAD_SHEDDING_PRIORITIES"); v != "" {
+		c.LoadShedding.Priorities = strings.Split(v, ",")
+	}
+
+	for env, x := range map[string]*float64{
+		"ENV_LOAD_SHEDDING_HIGH_WATERMARK": &c.LoadShedding.HighWatermark,
+		"ENV_LOAD_SHEDDING_LOW_WATERMARK":  &c.LoadShedding.LowWatermark,
+	} {
+		if v := datakit.GetEnv(env); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				l.Warnf("invalid env key %s, value %s, err: %s ignored", env, v, err)
+			} else {
+				*x = f
+			}
+		}
+	}
+}
+
+func (c *Config) loadProbeLimitEnvs() {
+	if c.ProbeLimit == nil {
+		c.ProbeLimit = probelimit.DefaultConfig()
+	}
+
+	if v := datakit.GetEnv("ENV_ENABLE_PROBE_LIMIT"); v != "" {
+		c.ProbeLimit.Enable = true
+	}
+
+	if v := datakit.GetEnv("ENV_PROBE_LIMIT_RATE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err != nil {
+			l.Warnf("invalid env key ENV_PROBE_LIMIT_RATE, value %s, err: %s ignored", v, err)
+		} else {
+			c.ProbeLimit.Rate = f
+		}
+	}
+
+	for env, x := range map[string]*int{
+		"ENV_PROBE_LIMIT_BURST":     &c.ProbeLimit.Burst,
+		"ENV_PROBE_LIMIT_BANDWIDTH": &c.ProbeLimit.Bandwidth,
+	} {
+		if v := datakit.GetEnv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				l.Warnf("invalid env key %s, value %s, err: %s ignored", env, v, err)
+			} else {
+				*x = n
+			}
+		}
+	}
+}
+
+func (c *Config) loadRemoteJobEnvs() {
+	if c.RemoteJob == nil {
+		c.RemoteJob = remotejob.DefaultConfig()
+	}
+
+	if v := datakit.GetEnv("ENV_ENABLE_REMOTE_JOB"); v != "" {
+		c.RemoteJob.Enable = true
+	}
+
+	if v := datakit.GetEnv("ENV_REMOTE_JOB_PUBLIC_KEY"); v != "" {
+		c.RemoteJob.PublicKey = v
+	}
+
+	if v := datakit.GetEnv("ENV_REMOTE_JOB_ALLOWED_JOBS"); v != "" {
+		var jobs []string
+		for _, x := range strings.Split(v, ",") {
+			if x = strings.TrimSpace(x); x != "" {
+				jobs = append(jobs, x)
+			}
+		}
+		c.RemoteJob.AllowedJobs = jobs
+	}
+}
+
+func (c *Config) loadStartupEnvs() {
+	if c.Startup == nil {
+		c.Startup = inputs.DefaultStartupCfg()
+	}
+
+	if v := datakit.GetEnv("ENV_STARTUP_IMMEDIATE_INPUTS"); v != "" {
+		if v == "-" {
+			c.Startup.ImmediateInputs = nil
+		} else {
+			c.Startup.ImmediateInputs = strings.Split(v, ",")
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_STARTUP_STAGGERED_INPUTS"); v != "" {
+		if v == "-" {
+			c.Startup.StaggeredInputs = nil
+		} else {
+			c.Startup.StaggeredInputs = strings.Split(v, ",")
+		}
+	}
+
+	if v := datakit.GetEnv("ENV_STARTUP_STAGGER_WINDOW"); v != "" {
+		du, err := time.ParseDuration(v)
+		if err != nil {
+			l.Warnf("invalid env key ENV_STARTUP_STAGGER_WINDOW, value %s, err: %s ignored", v, err)
+		} else {
+			l.Infof("set ENV_STARTUP_STAGGER_WINDOW to %s", du)
+			c.Startup.StaggerWindow = v
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/hostid"
+)
+
+type HostIdentityCfg struct {
+	// Add tag host_id to all data, data of the same host can be associated
+	// by it even if the hostname changed.
+	EnableHostIDTag bool `toml:"enable_host_id_tag"`
+
+	// Cloud provider(aliyun/aws/tencent) to query instance-id as host ID if
+	// machine-id not available.
+	CloudProvider string `toml:"cloud_provider"`
+}
+
+// hostRoot returns mount point of host root filesystem if running in container.
+func hostRoot() string {
+	if !datakit.Docker {
+		return ""
+	}
+
+	if v := os.Getenv("HOST_ROOT"); v != "" {
+		return v
+	}
+
+	if fi, err := os.Stat("/rootfs"); err == nil && fi.IsDir() {
+		return "/rootfs"
+	}
+
+	return ""
+}
+
+func (c *Config) setHostID() {
+	opts := []hostid.Option{
+		hostid.WithRoot(hostRoot()),
+		hostid.WithStateFile(filepath.Join(datakit.DataDir, ".host_id")),
+	}
+
+	if c.HostIdentity != nil {
+		opts = append(opts, hostid.WithCloudProvider(c.HostIdentity.CloudProvider))
+	}
+
+	id, err := hostid.Detect(opts...)
+	if err != nil {
+		l.Warnf("detect host identity: %s, ignored", err)
+		return
+	}
+
+	l.Infof("host ID: %s(%s)", id.HostID, id.Source)
+	datakit.DatakitHostID = id.HostID
+}
+
+func (c *Config) loadHostIdentityEnvs() {
+	if c.HostIdentity == nil {
+		c.HostIdentity = &HostIdentityCfg{}
+	}
+
+	if v := datakit.GetEnv("ENV_ENABLE_HOST_ID_TAG"); v != "" {
+		c.HostIdentity.EnableHostIDTag = true
+	}
+
+	if v := datakit.GetEnv("ENV_CLOUD_PROVIDER"); v != "" {
+		c.HostIdentity.CloudProvider = v
+	}
+}
@@ -6,13 +6,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/confdrift"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
@@ -141,6 +146,40 @@ func loadInputsConfFromDirs(paths []string) {
 	}
 
 	inputs.Init()
+
+	confdrift.Applied(paths)
+	dataway.SetConfigHashes(inputConfHashes())
+}
+
+// inputConfHashes hashes configure files of each input, the hash attached
+// along with the input name on uploading points of the input.
+func inputConfHashes() map[string]string {
+	res := map[string]string{}
+	for name, c := range inputs.ConfigInfo {
+		var paths []string
+		for _, p := range c.ConfigPaths {
+			paths = append(paths, p.Path)
+		}
+
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+
+		h := sha256.New()
+		for _, p := range paths {
+			data, err := ioutil.ReadFile(filepath.Clean(p))
+			if err != nil {
+				l.Warnf("read %s: %s, ignored", p, err)
+				continue
+			}
+			h.Write(data) //nolint:errcheck,gosec
+		}
+
+		res[name] = hex.EncodeToString(h.Sum(nil))[:8]
+	}
+
+	return res
 }
 
 func enableDefaultInputs(list []string) {
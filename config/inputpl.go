@@ -12,6 +12,7 @@ import (
 
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	plrefertable "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/refertable"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
@@ -43,6 +44,10 @@ func initPluginPipeline() error {
 		}
 	}
 
+	if Cfg.Dataway != nil {
+		plrefertable.SetPuller(Cfg.Dataway)
+	}
+
 	if err := pipeline.Init(Cfg.Pipeline); err != nil {
 		return err
 	}
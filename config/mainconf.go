@@ -13,8 +13,12 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/remotejob"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
 type SinkerDeprecated struct {
@@ -97,6 +101,20 @@ type Config struct {
 	GitRepos *GitRepost `toml:"git_repos"`
 
 	Ulimit uint64 `toml:"ulimit"`
+
+	Startup *inputs.StartupCfg `toml:"startup"`
+
+	HostIdentity *HostIdentityCfg `toml:"host_identity"`
+
+	CloudMeta *CloudMetaCfg `toml:"cloud_meta"`
+
+	LoadShedding *loadshed.Config `toml:"load_shedding"`
+
+	ProbeLimit *probelimit.Config `toml:"probe_limit"`
+
+	RemoteJob *remotejob.Config `toml:"remote_job"`
+
+	cloudMetaTags map[string]string // tags of the cloud instance detected
 }
 
 func DefaultConfig() *Config {
@@ -155,6 +173,13 @@ func DefaultConfig() *Config {
 			RemotePullInterval:     "1m",
 			ReferTableURL:          "",
 			ReferTablePullInterval: "5m",
+			EmitMetricInterval:     "1m",
+			EmitMetricMaxSeries:    10000,
+			CacheMaxEntries:        10000,
+
+			HTTPRequestTimeout:        "3s",
+			HTTPRequestCacheTTL:       "5m",
+			HTTPRequestMaxConcurrency: 16,
 		},
 
 		Logging: &LoggerCfg{
@@ -184,6 +209,18 @@ func DefaultConfig() *Config {
 			},
 		},
 
+		Startup: inputs.DefaultStartupCfg(),
+
+		HostIdentity: &HostIdentityCfg{},
+
+		CloudMeta: &CloudMetaCfg{Enable: true},
+
+		LoadShedding: loadshed.DefaultConfig(),
+
+		ProbeLimit: probelimit.DefaultConfig(),
+
+		RemoteJob: remotejob.DefaultConfig(),
+
 		Ulimit: func() uint64 {
 			switch runtime.GOOS {
 			case "linux":
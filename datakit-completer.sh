@@ -35,7 +35,7 @@ _datakit()
 				;;
 
 			tool)
-				COMPREPLY=( $(compgen -W '--check-config --check-sample --default-main-conf --dump-samples
+				COMPREPLY=( $(compgen -W '--check-config --check-sample --config-drift --default-main-conf --dump-samples
 				--ipinfo --log --show-cloud-info --upload-log --workspace-info --setup-completer-script' -- "${cur_word}") )
 				;;
 
@@ -82,6 +82,7 @@ const (
 	PipelinePull      = "/v1/pipeline/pull"  // deprecated
 	LogFilter         = "/v2/logfilter/pull" // deprecated
 	DatakitPull       = "/v1/datakit/pull"
+	RemoteJobResult   = "/v1/datakit/remote_job/result"
 	ListDataWay       = "/v2/list/dataway"
 	DatakitInputName  = "self"
 
@@ -127,6 +128,7 @@ var (
 	InstallDir = optionalInstallDir[runtime.GOOS+"/"+runtime.GOARCH]
 
 	DatakitHostName = "" // 默认为空
+	DatakitHostID   = "" // stable ID of the host, see internal/hostid
 
 	optionalInstallDir = map[string]string{
 		OSArchWinAmd64: `C:\Program Files\datakit`,
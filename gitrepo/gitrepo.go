@@ -143,6 +143,13 @@ func doRun(c *config.GitRepository) error {
 		return err
 	}
 
+	var (
+		repo  = filepath.Base(clonePath)
+		rev   = pinnedRev(c)
+		start = time.Now()
+		prev  = headCommit(clonePath)
+	)
+
 	isUpdate := true
 
 	// check git repo exist
@@ -150,20 +157,53 @@ func doRun(c *config.GitRepository) error {
 		// clone in the exist dir
 		l.Debug("Pull start")
 
-		isUpdate, err = gitPull(clonePath, c.Branch, authMethod)
+		if rev != "" {
+			isUpdate, err = gitCheckoutPinned(clonePath, rev, authMethod)
+		} else {
+			isUpdate, err = gitPull(clonePath, c.Branch, authMethod)
+		}
 		if err != nil {
+			observeSync(repo, syncFailed, start)
 			return err
 		}
 	} else {
 		// clone a new one
 		l.Debug("PlainClone start")
 
-		if err := gitPlainClone(clonePath, c.URL, c.Branch, authMethod); err != nil {
+		branch := c.Branch
+		if rev != "" {
+			branch = "" // clone the default branch, then checkout the pinned
+		}
+
+		if err := gitPlainClone(clonePath, c.URL, branch, authMethod); err != nil {
 			l.Errorf("gitPlainClone failed: %v", err)
+			observeSync(repo, syncFailed, start)
+			return err
+		}
+
+		if rev != "" {
+			if _, err := gitCheckoutPinned(clonePath, rev, authMethod); err != nil {
+				observeSync(repo, syncFailed, start)
+				return err
+			}
+		}
+	}
+
+	if isUpdate {
+		if err := verifyRepo(clonePath, c); err != nil {
+			l.Errorf("verify repo %s failed: %s, rollback to %s", repo, err, prev)
+			if err := rollback(clonePath, prev); err != nil {
+				l.Warnf("rollback failed: %s", err)
+			}
+
+			observeSync(repo, syncVerifyFailed, start)
 			return err
 		}
 	}
 
+	observeSync(repo, syncOK, start)
+	observeSyncedCommit(repo, refOf(c), headCommit(clonePath).String())
+
 	if isUpdate || isFirst {
 		l.Info("reload")
 
@@ -257,7 +297,7 @@ func gitPull(clonePath, branch string, authMethod transport.AuthMethod) (isUpdat
 func gitPlainClone(clonePath, gitURL, branch string, authMethod transport.AuthMethod) error {
 	ctxNew, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if _, err := git.PlainCloneContext(ctxNew, clonePath, false, &git.CloneOptions{
+	opt := &git.CloneOptions{
 		// The intended use of a GitHub personal access token is in replace of your password
 		// because access tokens can easily be revoked.
 		// https://help.github.com/articles/creating-a-personal-access-token-for-the-command-line/
@@ -265,8 +305,14 @@ func gitPlainClone(clonePath, gitURL, branch string, authMethod transport.AuthMe
 		URL:             gitURL,
 		InsecureSkipTLS: true,
 		RemoteName:      "origin",
-		ReferenceName:   plumbing.NewBranchReferenceName(branch),
-	}); err != nil {
+	}
+
+	// empty branch means the remote HEAD
+	if branch != "" {
+		opt.ReferenceName = plumbing.NewBranchReferenceName(branch)
+	}
+
+	if _, err := git.PlainCloneContext(ctxNew, clonePath, false, opt); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package gitrepo
+
+import (
+	"time"
+
+	"github.com/GuanceCloud/cliutils/metrics"
+	p8s "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	syncOK           = "ok"
+	syncFailed       = "failed"
+	syncVerifyFailed = "verify_failed"
+)
+
+var (
+	syncVec *p8s.CounterVec
+
+	syncCostVec *p8s.SummaryVec
+
+	syncedCommitVec *p8s.GaugeVec
+)
+
+func metricsSetup() {
+	syncVec = p8s.NewCounterVec(
+		p8s.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "gitrepo",
+			Name:      "sync_total",
+			Help:      "Git repository sync count, partitioned by status(ok/failed/verify_failed)",
+		},
+		[]string{
+			"repo",
+			"status",
+		},
+	)
+
+	syncCostVec = p8s.NewSummaryVec(
+		p8s.SummaryOpts{
+			Namespace: "datakit",
+			Subsystem: "gitrepo",
+			Name:      "sync_cost_seconds",
+			Help:      "Git repository sync(clone/pull and verify) latency",
+		},
+		[]string{
+			"repo",
+		},
+	)
+
+	syncedCommitVec = p8s.NewGaugeVec(
+		p8s.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "gitrepo",
+			Name:      "synced_commit",
+			Help:      "Commit checked out on the last succeeded sync, the value is the unix timestamp(second) of the sync",
+		},
+		[]string{
+			"repo",
+			"ref",
+			"commit",
+		},
+	)
+
+	metrics.MustRegister(
+		syncVec,
+		syncCostVec,
+		syncedCommitVec,
+	)
+}
+
+func observeSync(repo, status string, start time.Time) {
+	syncVec.WithLabelValues(repo, status).Inc()
+	syncCostVec.WithLabelValues(repo).Observe(time.Since(start).Seconds())
+}
+
+func observeSyncedCommit(repo, ref, commit string) {
+	// only keep the latest commit of the repo
+	syncedCommitVec.DeletePartialMatch(p8s.Labels{"repo": repo})
+	syncedCommitVec.WithLabelValues(repo, ref, commit).Set(float64(time.Now().Unix()))
+}
+
+//nolint:gochecknoinits
+func init() {
+	metricsSetup()
+}
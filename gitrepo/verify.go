@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package gitrepo
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+)
+
+// pinnedRev returns the commit or tag the repository pinned at, empty if
+// the branch followed.
+func pinnedRev(c *config.GitRepository) string {
+	switch {
+	case c.Commit != "":
+		return c.Commit
+	case c.Tag != "":
+		return c.Tag
+	default:
+		return ""
+	}
+}
+
+// refOf returns the ref name used in metrics.
+func refOf(c *config.GitRepository) string {
+	if rev := pinnedRev(c); rev != "" {
+		return rev
+	}
+	return c.Branch
+}
+
+// gitCheckoutPinned fetches all branches and tags, then checkout the worktree
+// to the pinned commit or tag(detached HEAD).
+func gitCheckoutPinned(clonePath, rev string, authMethod transport.AuthMethod) (isUpdate bool, err error) {
+	r, err := git.PlainOpen(clonePath)
+	if err != nil {
+		l.Errorf("PlainOpen failed: %v", err)
+		return false, err
+	}
+
+	ctxNew, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = r.FetchContext(ctxNew, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs: []gitconfig.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*", // tag may be moved on remote
+		},
+		Auth:            authMethod,
+		Tags:            git.AllTags,
+		Force:           true,
+		InsecureSkipTLS: true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		l.Errorf("Fetch failed: %v", err)
+		return false, err
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return false, fmt.Errorf("resolve revision %q failed: %w", rev, err)
+	}
+
+	if head, err := r.Head(); err == nil && head.Hash() == *hash {
+		return false, nil
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		l.Errorf("Worktree failed: %v", err)
+		return false, err
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		l.Errorf("Checkout %s failed: %v", hash, err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// headCommit returns the HEAD commit hash of the cloned repository, zero
+// hash if not cloned.
+func headCommit(clonePath string) plumbing.Hash {
+	r, err := git.PlainOpen(clonePath)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return head.Hash()
+}
+
+// rollback resets the worktree to the commit before sync, or remove the
+// clone path if it's a new clone.
+func rollback(clonePath string, prev plumbing.Hash) error {
+	if prev.IsZero() {
+		return os.RemoveAll(clonePath)
+	}
+
+	r, err := git.PlainOpen(clonePath)
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	return w.Reset(&git.ResetOptions{Commit: prev, Mode: git.HardReset})
+}
+
+// verifyRepo checks the GPG signature and the checksum manifest of the
+// cloned repository if configured.
+func verifyRepo(clonePath string, c *config.GitRepository) error {
+	if c.GPGKeyRingPath != "" {
+		if err := verifySignature(clonePath, c.Tag, c.GPGKeyRingPath); err != nil {
+			return err
+		}
+	}
+
+	if c.ChecksumManifest != "" {
+		if err := verifyChecksum(clonePath, c.ChecksumManifest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySignature verifies the annotated tag if pinned at it, or the HEAD
+// commit with keys within the armored key ring.
+func verifySignature(clonePath, tag, keyRingPath string) error {
+	keyRing, err := os.ReadFile(filepath.Clean(keyRingPath))
+	if err != nil {
+		return fmt.Errorf("read key ring failed: %w", err)
+	}
+
+	r, err := git.PlainOpen(clonePath)
+	if err != nil {
+		return err
+	}
+
+	if tag != "" {
+		ref, err := r.Tag(tag)
+		if err != nil {
+			return fmt.Errorf("tag %q not found: %w", tag, err)
+		}
+
+		// lightweight tag has no signature, fallback to verify the commit
+		if t, err := r.TagObject(ref.Hash()); err == nil {
+			if _, err := t.Verify(string(keyRing)); err != nil {
+				return fmt.Errorf("verify signature of tag %q failed: %w", tag, err)
+			}
+			return nil
+		}
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	if _, err := commit.Verify(string(keyRing)); err != nil {
+		return fmt.Errorf("verify signature of commit %s failed: %w", head.Hash(), err)
+	}
+
+	return nil
+}
+
+// verifyChecksum checks all files within the worktree against the manifest,
+// which is the output of `sha256sum`, such as
+//
+//	e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  python.d/foo/foo.py
+//
+// Files not listed in the manifest are rejected.
+func verifyChecksum(root, manifest string) error {
+	f, err := os.Open(filepath.Join(root, filepath.Clean(manifest)))
+	if err != nil {
+		return fmt.Errorf("open checksum manifest failed: %w", err)
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid checksum manifest line %d: %q", lineNo, line)
+		}
+
+		// binary mode of sha256sum add '*' before the file name
+		sums[filepath.ToSlash(filepath.Clean(strings.TrimPrefix(parts[1], "*")))] = strings.ToLower(parts[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	manifest = filepath.ToSlash(filepath.Clean(manifest))
+	checked := 0
+
+	if err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == manifest {
+			return nil
+		}
+
+		expect, ok := sums[rel]
+		if !ok {
+			return fmt.Errorf("file %q not listed in checksum manifest", rel)
+		}
+
+		sum, err := sha256File(p)
+		if err != nil {
+			return err
+		}
+
+		if sum != expect {
+			return fmt.Errorf("checksum mismatch of file %q", rel)
+		}
+
+		checked++
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if checked != len(sums) {
+		return fmt.Errorf("%d files listed in checksum manifest not found", len(sums)-checked)
+	}
+
+	return nil
+}
+
+func sha256File(p string) (string, error) {
+	f, err := os.Open(filepath.Clean(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
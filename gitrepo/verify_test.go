@@ -0,0 +1,203 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package gitrepo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+)
+
+func sha256Of(s string) string {
+	x := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(x[:])
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for k, v := range files {
+		p := filepath.Join(root, k)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), os.ModePerm))
+		require.NoError(t, os.WriteFile(p, []byte(v), 0o600))
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	files := map[string]string{
+		"python.d/foo/foo.py": "print('foo')",
+		"conf.d/cpu.conf":     "[[inputs.cpu]]",
+	}
+
+	cases := []struct {
+		name     string
+		manifest string
+		extra    map[string]string
+		fail     bool
+	}{
+		{
+			name: "ok",
+			manifest: fmt.Sprintf("# comment\n%s  python.d/foo/foo.py\n%s *conf.d/cpu.conf\n",
+				sha256Of(files["python.d/foo/foo.py"]), sha256Of(files["conf.d/cpu.conf"])),
+		},
+
+		{
+			name: "mismatch",
+			manifest: fmt.Sprintf("%s  python.d/foo/foo.py\n%s  conf.d/cpu.conf\n",
+				sha256Of("print('bar')"), sha256Of(files["conf.d/cpu.conf"])),
+			fail: true,
+		},
+
+		{
+			name: "not-listed",
+			manifest: fmt.Sprintf("%s  python.d/foo/foo.py\n%s  conf.d/cpu.conf\n",
+				sha256Of(files["python.d/foo/foo.py"]), sha256Of(files["conf.d/cpu.conf"])),
+			extra: map[string]string{"python.d/foo/evil.py": "import os"},
+			fail:  true,
+		},
+
+		{
+			name: "listed-not-found",
+			manifest: fmt.Sprintf("%s  python.d/foo/foo.py\n%s  conf.d/cpu.conf\n%s  conf.d/mem.conf\n",
+				sha256Of(files["python.d/foo/foo.py"]), sha256Of(files["conf.d/cpu.conf"]), sha256Of("")),
+			fail: true,
+		},
+
+		{
+			name:     "bad-manifest",
+			manifest: "not-a-checksum-line",
+			fail:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, files)
+			writeFiles(t, root, tc.extra)
+			writeFiles(t, root, map[string]string{"SHA256SUMS": tc.manifest, ".git/HEAD": "ignored"})
+
+			err := verifyChecksum(root, "SHA256SUMS")
+			if tc.fail {
+				assert.Error(t, err)
+				t.Logf("expected error: %s", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGitCheckoutPinned(t *testing.T) {
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	key, err := openpgp.NewEntity("datakit", "", "datakit@guance.com", nil)
+	require.NoError(t, err)
+
+	var pubring bytes.Buffer
+	w, err := armor.Encode(&pubring, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, key.Serialize(w))
+	require.NoError(t, w.Close())
+
+	keyRingPath := filepath.Join(t.TempDir(), "pubring.asc")
+	require.NoError(t, os.WriteFile(keyRingPath, pubring.Bytes(), 0o600))
+
+	// setup the remote repository: c1(signed, tag v1) <- c2(not signed, tag v2)
+	src := t.TempDir()
+	r, err := git.PlainInit(src, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	commit := func(content string, signKey *openpgp.Entity) plumbing.Hash {
+		writeFiles(t, src, map[string]string{"python.d/foo/foo.py": content})
+		_, err := wt.Add("python.d")
+		require.NoError(t, err)
+
+		h, err := wt.Commit(content, &git.CommitOptions{
+			Author:  &object.Signature{Name: "datakit", Email: "datakit@guance.com", When: time.Now()},
+			SignKey: signKey,
+		})
+		require.NoError(t, err)
+		return h
+	}
+
+	c1 := commit("print('v1')", key)
+	_, err = r.CreateTag("v1", c1, nil)
+	require.NoError(t, err)
+
+	c2 := commit("print('v2')", nil)
+	_, err = r.CreateTag("v2", c2, nil)
+	require.NoError(t, err)
+
+	dst := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, gitPlainClone(dst, src, "", nil))
+
+	t.Run("pin-tag", func(t *testing.T) {
+		isUpdate, err := gitCheckoutPinned(dst, "v1", nil)
+		assert.NoError(t, err)
+		assert.True(t, isUpdate)
+		assert.Equal(t, c1, headCommit(dst))
+
+		isUpdate, err = gitCheckoutPinned(dst, "v1", nil)
+		assert.NoError(t, err)
+		assert.False(t, isUpdate)
+
+		assert.NoError(t, verifySignature(dst, "v1", keyRingPath))
+	})
+
+	t.Run("pin-short-commit", func(t *testing.T) {
+		isUpdate, err := gitCheckoutPinned(dst, c2.String()[:8], nil)
+		assert.NoError(t, err)
+		assert.True(t, isUpdate)
+		assert.Equal(t, c2, headCommit(dst))
+
+		x, err := os.ReadFile(filepath.Join(dst, "python.d/foo/foo.py"))
+		assert.NoError(t, err)
+		assert.Equal(t, "print('v2')", string(x))
+
+		// c2 not signed
+		assert.Error(t, verifySignature(dst, "", keyRingPath))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		assert.NoError(t, rollback(dst, c1))
+		assert.Equal(t, c1, headCommit(dst))
+
+		x, err := os.ReadFile(filepath.Join(dst, "python.d/foo/foo.py"))
+		assert.NoError(t, err)
+		assert.Equal(t, "print('v1')", string(x))
+	})
+
+	t.Run("unknown-rev", func(t *testing.T) {
+		_, err := gitCheckoutPinned(dst, "v3", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("pinned-rev", func(t *testing.T) {
+		assert.Equal(t, "abc", pinnedRev(&config.GitRepository{Branch: "master", Tag: "v1", Commit: "abc"}))
+		assert.Equal(t, "v1", pinnedRev(&config.GitRepository{Branch: "master", Tag: "v1"}))
+		assert.Equal(t, "master", refOf(&config.GitRepository{Branch: "master"}))
+	})
+}
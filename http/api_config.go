@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/confdrift"
+)
+
+// apiConfigDrift shows drift between on-disk input configures and the applied ones.
+func apiConfigDrift(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	d, err := confdrift.Check()
+	if err != nil {
+		if errors.Is(err, confdrift.ErrNotApplied) {
+			return nil, ErrConfigNotApplied
+		}
+
+		l.Errorf("confdrift.Check: %s", err.Error())
+		return nil, err
+	}
+
+	return d, nil
+}
@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/influxdata/toml"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/path"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
@@ -479,6 +481,39 @@ func dcaGetFilter(c *gin.Context) {
 	context.success(filterInfo{Content: string(pullFileBytes), FilePath: pullFilePath})
 }
 
+const defaultPreviewPoints = 10
+
+// dcaInputPreview return the last n(default 10) points fed by the input,
+// values of sensitive tags/fields masked.
+func dcaInputPreview(c *gin.Context) {
+	context := getContext(c)
+
+	name := c.Query("name")
+	if name == "" {
+		c.Status(400)
+		context.fail(dcaError{ErrorCode: "param.invalid", ErrorMsg: "parameter 'name' is required"})
+		return
+	}
+
+	n := defaultPreviewPoints
+	if s := c.Query("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			c.Status(400)
+			context.fail(dcaError{ErrorCode: "param.invalid", ErrorMsg: fmt.Sprintf("invalid parameter 'n': %q", s)})
+			return
+		}
+		n = v
+	}
+
+	pts := dcaAPI.InputPreview(name, n)
+	if pts == nil {
+		pts = []*dkio.PreviewPoint{}
+	}
+
+	context.success(pts)
+}
+
 func dcaDownloadLog(c *gin.Context) {
 	logType := "log"
 	context := getContext(c)
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/election"
+)
+
+var (
+	electionState  = election.GetState
+	electionResign = election.Resign
+	electionClaim  = election.Claim
+)
+
+// apiElectionState shows the election state and who the leader is.
+func apiElectionState(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	return electionResult(electionState())
+}
+
+// apiElectionResign releases the leadership of current DataKit, used on
+// maintenance of HA pairs.
+func apiElectionResign(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return electionResult(electionResign())
+}
+
+// apiElectionClaim campaigns for the leadership right now.
+func apiElectionClaim(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return electionResult(electionClaim())
+}
+
+func electionResult(st *election.State, err error) (interface{}, error) {
+	switch {
+	case err == nil:
+		return st, nil
+	case errors.Is(err, election.ErrElectionDisabled):
+		return nil, ErrElectionDisabled
+	case errors.Is(err, election.ErrObserverMode):
+		return nil, ErrElectionObserver
+	case errors.Is(err, election.ErrElectionBusy):
+		return nil, ErrElectionBusy
+	default:
+		l.Errorf("election: %s", err.Error())
+		return nil, err
+	}
+}
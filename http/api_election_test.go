@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/election"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+)
+
+func TestElectionAPI(t *T.T) {
+	dw = &dataway.Dataway{URLs: []string{"http://localhost:9529?token=tkn_abc123"}}
+	require.NoError(t, dw.Init())
+
+	var (
+		resigned bool
+		claimErr error
+	)
+
+	electionState = func() (*election.State, error) {
+		return &election.State{ID: "host-a", Namespace: "default", Status: "defeat", Leader: "host-b"}, nil
+	}
+	electionResign = func() (*election.State, error) {
+		resigned = true
+		return &election.State{ID: "host-a", Namespace: "default", Status: "resigned", Leader: "host-a"}, nil
+	}
+	electionClaim = func() (*election.State, error) {
+		return nil, claimErr
+	}
+
+	defer func() {
+		electionState = election.GetState
+		electionResign = election.Resign
+		electionClaim = election.Claim
+	}()
+
+	router := gin.New()
+	router.GET("/v1/election", rawHTTPWraper(nil, apiElectionState))
+	router.POST("/v1/election/resign", rawHTTPWraper(nil, apiElectionResign))
+	router.POST("/v1/election/claim", rawHTTPWraper(nil, apiElectionClaim))
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	t.Run("state", func(t *T.T) {
+		w := do(http.MethodGet, "/v1/election")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Content election.State `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "host-b", res.Content.Leader)
+		assert.Equal(t, "defeat", res.Content.Status)
+	})
+
+	t.Run("resign", func(t *T.T) {
+		w := do(http.MethodPost, "/v1/election/resign")
+		assert.Equal(t, ErrInvalidToken.HttpCode, w.Code)
+		assert.False(t, resigned)
+
+		w = do(http.MethodPost, "/v1/election/resign?token=tkn_abc123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, resigned)
+	})
+
+	t.Run("claim", func(t *T.T) {
+		claimErr = election.ErrObserverMode
+		w := do(http.MethodPost, "/v1/election/claim?token=tkn_abc123")
+		assert.Equal(t, ErrElectionObserver.HttpCode, w.Code)
+
+		claimErr = election.ErrElectionDisabled
+		w = do(http.MethodPost, "/v1/election/claim?token=tkn_abc123")
+		assert.Equal(t, ErrElectionDisabled.HttpCode, w.Code)
+
+		claimErr = errors.New("dataway unreachable")
+		w = do(http.MethodPost, "/v1/election/claim?token=tkn_abc123")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/event"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const defaultEventsInput = "events"
+
+// apiWriteEvents accepts structured events in JSON, and writes them as keyevent.
+func apiWriteEvents(w http.ResponseWriter, req *http.Request, x ...interface{}) (interface{}, error) {
+	if x == nil || len(x) != 1 {
+		l.Errorf("invalid handler")
+		return nil, ErrInvalidAPIHandler
+	}
+
+	h, ok := x[0].(IAPIWrite)
+	if !ok {
+		l.Errorf("not IApiWrite, got %s", reflect.TypeOf(x).String())
+		return nil, ErrInvalidAPIHandler
+	}
+
+	q := req.URL.Query()
+
+	input := defaultEventsInput
+	if x := q.Get(ArgInput); x != "" {
+		input = x
+	}
+
+	body, err := uhttp.ReadBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) == 0 {
+		return nil, ErrEmptyBody
+	}
+
+	events, err := event.Parse(body)
+	if err != nil {
+		return nil, uhttp.Errorf(ErrInvalidEvent, "%s", err)
+	}
+
+	if len(events) == 0 {
+		return nil, ErrNoPoints
+	}
+
+	var (
+		now   = time.Now()
+		pts   = make([]*point.Point, 0, len(events))
+		scale = precisionScale(q.Get(ArgPrecision))
+	)
+
+	for _, e := range events {
+		e.Time *= int64(scale)
+		pts = append(pts, e.Point(now))
+	}
+
+	ignoreGlobalTags := false
+	for _, arg := range []string{
+		ArgIgnoreGlobalHostTags,
+		ArgIgnoreGlobalTags, // deprecated
+	} {
+		if x := q.Get(arg); x != "" {
+			ignoreGlobalTags = true
+		}
+	}
+
+	if !ignoreGlobalTags {
+		appendTags(pts, dkpt.GlobalHostTags())
+	}
+
+	if x := q.Get(ArgGlobalElectionTags); x != "" {
+		appendTags(pts, dkpt.GlobalElectionTags())
+	}
+
+	l.Debugf("received %d events from %s", len(pts), input)
+
+	if err := h.feed(input, point.KeyEvent, pts, &io.Option{}); err != nil {
+		return nil, err
+	}
+
+	if q.Get(ArgEchoJSON) != "" {
+		return pts, nil
+	}
+
+	return nil, nil
+}
+
+// precisionScale returns the duration of time unit of the precision, default nanosecond.
+func precisionScale(prec string) time.Duration {
+	switch prec {
+	case "h":
+		return time.Hour
+	case "m":
+		return time.Minute
+	case "s":
+		return time.Second
+	case "ms":
+		return time.Millisecond
+	case "u", "us":
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+type apiWriteEventsMock struct {
+	apiWriteMock
+	input    string
+	category point.Category
+	pts      []*point.Point
+}
+
+func (x *apiWriteEventsMock) feed(input string, cat point.Category, pts []*point.Point, _ ...*io.Option) error {
+	x.input = input
+	x.category = cat
+	x.pts = pts
+	return nil
+}
+
+func TestAPIWriteEvents(t *testing.T) {
+	cases := []struct {
+		name, url, body string
+		input           string
+		npts            int
+		expectErr       error
+	}{
+		{
+			name:  "single",
+			url:   "/v1/write/events?ignore_global_host_tags=1",
+			body:  `{"title":"disk full","severity":"critical","source":"backup-app","dedup_key":"k1"}`,
+			input: defaultEventsInput,
+			npts:  1,
+		},
+		{
+			name:  "array-with-input",
+			url:   "/v1/write/events?input=my-app&precision=s",
+			body:  `[{"title":"a","severity":"ok","source":"s","time":1700000000},{"title":"b","severity":"info","source":"s"}]`,
+			input: "my-app",
+			npts:  2,
+		},
+		{
+			name:      "empty",
+			url:       "/v1/write/events",
+			expectErr: ErrEmptyBody,
+		},
+		{
+			name:      "invalid",
+			url:       "/v1/write/events",
+			body:      `{"title":"a","severity":"fatal","source":"s"}`,
+			expectErr: ErrInvalidEvent,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &apiWriteEventsMock{apiWriteMock: apiWriteMock{t: t}}
+
+			req := httptest.NewRequest(http.MethodPost, tc.url, bytes.NewReader([]byte(tc.body)))
+			_, err := apiWriteEvents(httptest.NewRecorder(), req, mock)
+			if tc.expectErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, getStatusCode(tc.expectErr), getStatusCode(err))
+				assert.Contains(t, err.Error(), tc.expectErr.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.input, mock.input)
+			assert.Equal(t, point.KeyEvent, mock.category)
+			require.Len(t, mock.pts, tc.npts)
+
+			for _, pt := range mock.pts {
+				t.Log(pt.Pretty())
+			}
+		})
+	}
+
+	t.Run("precision", func(t *testing.T) {
+		mock := &apiWriteEventsMock{apiWriteMock: apiWriteMock{t: t}}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/write/events?precision=ms",
+			bytes.NewReader([]byte(`{"title":"a","severity":"ok","source":"s","time":1700000000123}`)))
+		_, err := apiWriteEvents(httptest.NewRecorder(), req, mock)
+		require.NoError(t, err)
+		require.Len(t, mock.pts, 1)
+		assert.Equal(t, int64(1700000000123), mock.pts[0].Time().UnixMilli())
+	})
+}
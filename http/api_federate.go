@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"github.com/gin-gonic/gin"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+const promTextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// apiFederate exposes the most recent metric points in Prometheus text format,
+// measurements filtered by query like `?measurement=cpu,mem`.
+func apiFederate(c *gin.Context) {
+	var measurements []string
+	for _, arg := range c.QueryArray("measurement") {
+		for _, m := range strings.Split(arg, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				measurements = append(measurements, m)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := dkio.WriteLocalFederate(&buf, measurements); err != nil {
+		if errors.Is(err, dkio.ErrFederateDisabled) {
+			uhttp.HttpErr(c, ErrLocalFederateDisabled)
+		} else {
+			l.Errorf("WriteLocalFederate: %s", err.Error())
+			uhttp.HttpErr(c, err)
+		}
+		return
+	}
+
+	c.Data(http.StatusOK, promTextContentType, buf.Bytes())
+}
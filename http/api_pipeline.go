@@ -23,6 +23,7 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	plscript "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/script"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
@@ -51,6 +52,9 @@ type pipelineDebugRequest struct {
 	Encode    string   `json:"encode"`
 	Benchmark bool     `json:"benchmark"`
 	Timezone  string   `json:"timezone"`
+
+	// trace each statement of the script
+	Trace bool `json:"trace"`
 }
 
 // response body.
@@ -74,6 +78,8 @@ type pipelineResult struct {
 	Dropped bool        `json:"dropped"`
 
 	RunError *errchain.PlError `json:"run_error"`
+
+	Trace *plscript.Trace `json:"trace,omitempty"`
 }
 
 func apiPipelineDebugHandler(w http.ResponseWriter, req *http.Request, whatever ...interface{}) (interface{}, error) {
@@ -163,7 +169,17 @@ func apiPipelineDebugHandler(w http.ResponseWriter, req *http.Request, whatever
 	start := time.Now()
 	for _, pt := range pts {
 		// run pipeline
-		pt, drop, err := plRunner.Run(pt, nil, opt, newPlTestSingal())
+		var (
+			drop  bool
+			err   error
+			trace *plscript.Trace
+		)
+
+		if reqBody.Trace {
+			pt, drop, trace, err = plRunner.RunTrace(pt, nil, opt, newPlTestSingal())
+		} else {
+			pt, drop, err = plRunner.Run(pt, nil, opt, newPlTestSingal())
+		}
 
 		if err != nil {
 			plerr, ok := err.(*errchain.PlError) //nolint:errorlint
@@ -177,6 +193,7 @@ func apiPipelineDebugHandler(w http.ResponseWriter, req *http.Request, whatever
 
 			runResult = append(runResult, pipelineResult{
 				RunError: plerr,
+				Trace:    trace,
 			})
 		} else {
 			fields, err := pt.Fields()
@@ -194,6 +211,7 @@ func apiPipelineDebugHandler(w http.ResponseWriter, req *http.Request, whatever
 					TimeNS: int64(pt.Time().Nanosecond()),
 				},
 				Dropped: drop,
+				Trace:   trace,
 			})
 		}
 	}
@@ -20,6 +20,7 @@ import (
 
 	uhttp "github.com/GuanceCloud/cliutils/network/http"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 )
 
@@ -94,6 +95,7 @@ func TestApiDebugPipelineHandler(t *testing.T) {
 		expectStatusCode int
 		expectHeader     http.Header
 		hasResult        bool
+		expectTrace      bool
 		expect           *pipelineDebugResponse
 	}{
 		{
@@ -143,6 +145,23 @@ func TestApiDebugPipelineHandler(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "trace",
+			in: &pipelineDebugRequest{
+				Pipeline: map[string]map[string]string{
+					"logging": scriptsForTest(),
+				},
+				Category:   "logging",
+				ScriptName: "nginx",
+				Data: []string{base64.StdEncoding.EncodeToString([]byte(
+					`2021/11/10 16:59:53 [error] 16393#0: *17 open() "/usr/local/Cellar/nginx/1.21.3/html/server_status" failed` +
+						` (2: No such file or directory), client: 127.0.0.1, server: localhost, request:` +
+						` "GET /server_status HTTP/1.1", host: "localhost:8080"`))},
+				Trace: true,
+			},
+			expectStatusCode: http.StatusOK,
+			expectTrace:      true,
+		},
 		{
 			name: "invalid category",
 			in: &pipelineDebugRequest{
@@ -215,6 +234,17 @@ func TestApiDebugPipelineHandler(t *testing.T) {
 					assert.Equal(t, tc.expect.PLResults[0].Point.Fields[k], resp.PLResults[0].Point.Fields[k])
 				}
 			}
+
+			if tc.expectTrace {
+				require.Len(t, resp.PLResults, 1)
+				trace := resp.PLResults[0].Trace
+				require.NotNil(t, trace)
+				require.NotEmpty(t, trace.Steps)
+				assert.Equal(t, "grok", trace.Steps[1].Calls[0].Name, "steps: %+#v", trace.Steps)
+				assert.Equal(t, "127.0.0.1", trace.Diff.Fields["client_ip"].New)
+			} else if resp.PLResults != nil {
+				assert.Nil(t, resp.PLResults[0].Trace)
+			}
 		})
 	}
 }
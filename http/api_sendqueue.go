@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+var (
+	sendQueueState  = dkio.GetSendQueueState
+	sendQueuePause  = dkio.PauseSendQueue
+	sendQueueResume = dkio.ResumeSendQueue
+	sendQueueDrain  = dkio.DrainSendQueue
+	sendQueueFlush  = dkio.FlushSendQueue
+)
+
+// apiSendQueueState shows depth and age of the send queue of IO.
+func apiSendQueueState(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	return sendQueueResult(sendQueueState())
+}
+
+// apiSendQueuePause stops sending points to dataway, used on incidents of
+// dataway or network.
+func apiSendQueuePause(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return sendQueueResult(sendQueuePause())
+}
+
+// apiSendQueueResume resumes sending of the paused send queue.
+func apiSendQueueResume(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return sendQueueResult(sendQueueResume())
+}
+
+// apiSendQueueDrain empties the send queue, points queued are spilled to
+// disk cache if enabled, or dropped.
+func apiSendQueueDrain(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return sendQueueResult(sendQueueDrain())
+}
+
+// apiSendQueueFlush sends points queued right now.
+func apiSendQueueFlush(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return sendQueueResult(sendQueueFlush())
+}
+
+func sendQueueResult(res interface{}, err error) (interface{}, error) {
+	switch {
+	case err == nil:
+		return res, nil
+	case errors.Is(err, dkio.ErrSendQueueDisabled):
+		return nil, ErrSendQueueDisabled
+	default:
+		l.Errorf("send queue: %s", err.Error())
+		return nil, err
+	}
+}
@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+)
+
+func TestSendQueueAPI(t *T.T) {
+	dw = &dataway.Dataway{URLs: []string{"http://localhost:9529?token=tkn_abc123"}}
+	require.NoError(t, dw.Init())
+
+	var (
+		paused   bool
+		drained  bool
+		disabled bool
+	)
+
+	state := func() (*dkio.SendQueueState, error) {
+		if disabled {
+			return nil, dkio.ErrSendQueueDisabled
+		}
+		return &dkio.SendQueueState{Paused: paused, Capacity: 64, Overflow: "block", Length: 3, Points: 300}, nil
+	}
+
+	sendQueueState = state
+	sendQueuePause = func() (*dkio.SendQueueState, error) {
+		paused = true
+		return state()
+	}
+	sendQueueResume = func() (*dkio.SendQueueState, error) {
+		paused = false
+		return state()
+	}
+	sendQueueDrain = func() (*dkio.SendQueueResult, error) {
+		drained = true
+		return &dkio.SendQueueResult{Batches: 3, Points: 300, Spilled: 3}, nil
+	}
+
+	defer func() {
+		sendQueueState = dkio.GetSendQueueState
+		sendQueuePause = dkio.PauseSendQueue
+		sendQueueResume = dkio.ResumeSendQueue
+		sendQueueDrain = dkio.DrainSendQueue
+	}()
+
+	router := gin.New()
+	router.GET("/v1/io/queue", rawHTTPWraper(nil, apiSendQueueState))
+	router.POST("/v1/io/queue/pause", rawHTTPWraper(nil, apiSendQueuePause))
+	router.POST("/v1/io/queue/resume", rawHTTPWraper(nil, apiSendQueueResume))
+	router.POST("/v1/io/queue/drain", rawHTTPWraper(nil, apiSendQueueDrain))
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	t.Run("state", func(t *T.T) {
+		w := do(http.MethodGet, "/v1/io/queue")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Content dkio.SendQueueState `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 3, res.Content.Length)
+		assert.Equal(t, 64, res.Content.Capacity)
+	})
+
+	t.Run("pause-resume", func(t *T.T) {
+		w := do(http.MethodPost, "/v1/io/queue/pause")
+		assert.Equal(t, ErrInvalidToken.HttpCode, w.Code)
+		assert.False(t, paused)
+
+		w = do(http.MethodPost, "/v1/io/queue/pause?token=tkn_abc123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, paused)
+
+		w = do(http.MethodPost, "/v1/io/queue/resume?token=tkn_abc123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, paused)
+	})
+
+	t.Run("drain", func(t *T.T) {
+		w := do(http.MethodPost, "/v1/io/queue/drain?token=tkn_abc123")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, drained)
+
+		var res struct {
+			Content dkio.SendQueueResult `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 3, res.Content.Spilled)
+	})
+
+	t.Run("disabled", func(t *T.T) {
+		disabled = true
+		w := do(http.MethodGet, "/v1/io/queue")
+		assert.Equal(t, ErrSendQueueDisabled.HttpCode, w.Code)
+	})
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+var (
+	uploadPauseState = dkio.GetUploadPauseState
+	uploadPause      = dkio.PauseUpload
+	uploadResume     = dkio.ResumeUpload
+)
+
+// apiUploadPauseState lists categories of paused uploading.
+func apiUploadPauseState(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	return uploadPauseState(), nil
+}
+
+// apiUploadPause pauses uploading of categories, such as logging during
+// cost incidents, other categories keep uploading.
+func apiUploadPause(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return uploadPauseResult(uploadPause(queryCategories(r)...))
+}
+
+// apiUploadResume resumes uploading of paused categories.
+func apiUploadResume(w http.ResponseWriter, r *http.Request, x ...interface{}) (interface{}, error) {
+	if err := checkToken(r); err != nil {
+		return nil, err
+	}
+
+	return uploadPauseResult(uploadResume(queryCategories(r)...))
+}
+
+// queryCategories gets categories from query like ?category=logging,tracing
+// or ?category=logging&category=tracing.
+func queryCategories(r *http.Request) []string {
+	var cats []string
+	for _, v := range r.URL.Query()["category"] {
+		for _, c := range strings.Split(v, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				cats = append(cats, c)
+			}
+		}
+	}
+	return cats
+}
+
+func uploadPauseResult(res *dkio.UploadPauseState, err error) (interface{}, error) {
+	switch {
+	case err == nil:
+		return res, nil
+	case errors.Is(err, dkio.ErrUnknownCategory):
+		return nil, uhttp.Errorf(ErrInvalidCategory, "%s", err)
+	case errors.Is(err, dkio.ErrUploadPauseNoCache):
+		return nil, uhttp.Errorf(ErrUploadPauseNoCache, "%s", err)
+	default:
+		l.Errorf("upload pause: %s", err.Error())
+		return nil, err
+	}
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+)
+
+func TestUploadPauseAPI(t *T.T) {
+	dw = &dataway.Dataway{URLs: []string{"http://localhost:9529?token=tkn_abc123"}}
+	require.NoError(t, dw.Init())
+
+	paused := map[string]bool{}
+
+	state := func() *dkio.UploadPauseState {
+		st := &dkio.UploadPauseState{Paused: []*dkio.UploadPause{}}
+		for _, c := range []string{"logging", "tracing"} {
+			if paused[c] {
+				st.Paused = append(st.Paused, &dkio.UploadPause{Category: c})
+			}
+		}
+		return st
+	}
+
+	uploadPauseState = state
+	uploadPause = func(cats ...string) (*dkio.UploadPauseState, error) {
+		for _, c := range cats {
+			switch c {
+			case "logging", "tracing":
+			case "metric":
+				return nil, fmt.Errorf("%w on metric", dkio.ErrUploadPauseNoCache)
+			default:
+				return nil, fmt.Errorf("%w: %q", dkio.ErrUnknownCategory, c)
+			}
+		}
+
+		for _, c := range cats {
+			paused[c] = true
+		}
+		return state(), nil
+	}
+	uploadResume = func(cats ...string) (*dkio.UploadPauseState, error) {
+		for _, c := range cats {
+			delete(paused, c)
+		}
+		return state(), nil
+	}
+
+	defer func() {
+		uploadPauseState = dkio.GetUploadPauseState
+		uploadPause = dkio.PauseUpload
+		uploadResume = dkio.ResumeUpload
+	}()
+
+	router := gin.New()
+	router.GET("/v1/io/upload", rawHTTPWraper(nil, apiUploadPauseState))
+	router.POST("/v1/io/upload/pause", rawHTTPWraper(nil, apiUploadPause))
+	router.POST("/v1/io/upload/resume", rawHTTPWraper(nil, apiUploadResume))
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	t.Run("pause-resume", func(t *T.T) {
+		w := do(http.MethodPost, "/v1/io/upload/pause?category=logging")
+		assert.Equal(t, ErrInvalidToken.HttpCode, w.Code)
+		assert.Empty(t, paused)
+
+		w = do(http.MethodPost, "/v1/io/upload/pause?token=tkn_abc123&category=logging,tracing")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]bool{"logging": true, "tracing": true}, paused)
+
+		w = do(http.MethodPost, "/v1/io/upload/resume?token=tkn_abc123&category=tracing")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]bool{"logging": true}, paused)
+
+		w = do(http.MethodGet, "/v1/io/upload")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Content dkio.UploadPauseState `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Content.Paused, 1)
+		assert.Equal(t, "logging", res.Content.Paused[0].Category)
+	})
+
+	t.Run("invalid", func(t *T.T) {
+		w := do(http.MethodPost, "/v1/io/upload/pause?token=tkn_abc123&category=no-such-category")
+		assert.Equal(t, ErrInvalidCategory.HttpCode, w.Code)
+		assert.Contains(t, w.Body.String(), "no-such-category")
+
+		w = do(http.MethodPost, "/v1/io/upload/pause?token=tkn_abc123&category=metric")
+		assert.Equal(t, ErrUploadPauseNoCache.HttpCode, w.Code)
+		assert.Contains(t, w.Body.String(), "disk cache not enabled on metric")
+	})
+}
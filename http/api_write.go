@@ -49,6 +49,7 @@ const (
 
 	ArgVersion        = "version"
 	ArgPipelineSource = "source"
+	ArgPipeline       = "pipeline" // pipeline script of all points in the body
 
 	ArgLoose  = "loose" // Deprecated: default are loose mode
 	ArgStrict = "strict"
@@ -191,6 +192,17 @@ func apiWrite(w http.ResponseWriter, req *http.Request, x ...interface{}) (inter
 		feedOpt.PlScript = map[string]string{pipelineSource: pipelineSource + ".p"}
 	}
 
+	if x := q.Get(ArgPipeline); x != "" {
+		if feedOpt.PlScript == nil {
+			feedOpt.PlScript = map[string]string{}
+		}
+
+		script := strings.TrimSuffix(x, ".p") + ".p"
+		for _, pt := range pts {
+			feedOpt.PlScript[string(pt.Name())] = script
+		}
+	}
+
 	if err := h.feed(input, point.CatURL(categoryURL), pts, feedOpt); err != nil {
 		return err, nil
 	}
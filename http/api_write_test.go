@@ -261,6 +261,56 @@ func (x *apiWriteMock) geoInfo(ip string) map[string]string {
 	return nil // do nothing
 }
 
+type apiWritePlMock struct {
+	apiWriteMock
+	plScript map[string]string
+}
+
+func (x *apiWritePlMock) feed(_ string, _ point.Category, _ []*point.Point, opts ...*io.Option) error {
+	if len(opts) > 0 {
+		x.plScript = opts[0].PlScript
+	}
+	return nil
+}
+
+func TestAPIWritePipeline(t *testing.T) {
+	cases := []struct {
+		name, url string
+		expect    map[string]string
+	}{
+		{
+			name: "no-pipeline",
+			url:  "/v1/write/logging",
+		},
+		{
+			name:   "source",
+			url:    "/v1/write/logging?source=m1",
+			expect: map[string]string{"m1": "m1.p"},
+		},
+		{
+			name:   "pipeline",
+			url:    "/v1/write/logging?pipeline=nginx",
+			expect: map[string]string{"m1": "nginx.p", "m2": "nginx.p"},
+		},
+		{
+			name:   "pipeline-with-suffix",
+			url:    "/v1/write/metric?source=m1&pipeline=nginx.p",
+			expect: map[string]string{"m1": "nginx.p", "m2": "nginx.p"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &apiWritePlMock{apiWriteMock: apiWriteMock{t: t}}
+
+			req := httptest.NewRequest(http.MethodPost, tc.url, bytes.NewReader([]byte("m1 f1=1i\nm2 f1=2i")))
+			_, err := apiWrite(httptest.NewRecorder(), req, mock)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, mock.plScript)
+		})
+	}
+}
+
 func TestAPIWrite(t *testing.T) {
 	const timestamp = 1000000000 // 2001-09-09 01:46:40 +0000 UTC
 
@@ -15,6 +15,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -22,12 +23,14 @@ type apiList struct {
 	GetStats       func() (*DatakitStats, error)
 	RestartDataKit func() error
 	TestPipeline   func(string, string) (string, error)
+	InputPreview   func(string, int) []*dkio.PreviewPoint
 }
 
 var dcaAPI = &apiList{
 	GetStats:       GetStats,
 	RestartDataKit: restartDataKit,
 	TestPipeline:   pipelineTest,
+	InputPreview:   dkio.PreviewInput,
 }
 
 var ignoreAuthURI = []string{
@@ -186,6 +189,7 @@ func setupDcaRouter() *gin.Engine {
 
 	router.GET("/v1/dca/stats", dcaStats)
 	router.GET("/v1/dca/reload", dcaReload)
+	router.GET("/v1/dca/inputs/preview", dcaInputPreview)
 	// conf
 	router.POST("/v1/dca/saveConfig", dcaSaveConfig)
 	router.DELETE("/v1/dca/deleteConfig", dcaDeleteConfig)
@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -283,6 +284,50 @@ func TestDcaReload(t *testing.T) {
 	assert.Equal(t, "system.restart.error", res.ErrorCode)
 }
 
+func TestDcaInputPreview(t *testing.T) {
+	var name string
+	var n int
+	dcaAPI.InputPreview = func(s string, i int) []*dkio.PreviewPoint {
+		name, n = s, i
+		if s != "cpu" {
+			return nil
+		}
+		return []*dkio.PreviewPoint{{Category: "metric", Name: "cpu", Fields: map[string]interface{}{"usage_user": 1.5}}}
+	}
+	defer func() { dcaAPI.InputPreview = dkio.PreviewInput }()
+
+	get := func(query string) (*httptest.ResponseRecorder, *dcaResponse) {
+		req, _ := http.NewRequest("GET", "/v1/dca/inputs/preview"+query, nil)
+		req.Header.Add("X-Token", TOKEN)
+		w := getResponse(t, req, nil)
+		res, err := getResponseBody(w)
+		require.NoError(t, err)
+		return w, res
+	}
+
+	_, res := get("?name=cpu")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "cpu", name)
+	assert.Equal(t, defaultPreviewPoints, n)
+	pts, ok := res.Content.([]interface{})
+	require.True(t, ok)
+	require.Len(t, pts, 1)
+	assert.Equal(t, "cpu", pts[0].(map[string]interface{})["name"])
+
+	_, res = get("?name=mem&n=3")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []interface{}{}, res.Content)
+
+	w, res := get("")
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "param.invalid", res.ErrorCode)
+
+	w, res = get("?name=cpu&n=-1")
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "param.invalid", res.ErrorCode)
+}
+
 func TestDcaSaveConfig(t *testing.T) {
 	inputName := "demo-input"
 	inputs.ConfigInfo[inputName] = &inputs.Config{}
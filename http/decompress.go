@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+const defaultMaxDecompressedSize = 64 << 20 // 64MiB
+
+var errDecompressedTooLarge = errors.New("decompressed body too large")
+
+// decompressReader is the decompressed body, reading more than max bytes fails.
+type decompressReader struct {
+	r      io.Reader
+	body   io.Closer
+	closer func()
+
+	n, max int64
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.n += int64(n)
+	if d.n > d.max {
+		return n, fmt.Errorf("%w: exceeds %d bytes", errDecompressedTooLarge, d.max)
+	}
+	return n, err
+}
+
+func (d *decompressReader) Close() error {
+	if d.closer != nil {
+		d.closer()
+	}
+	return d.body.Close()
+}
+
+// newDecompressReader returns reader of the body decompressed by encoding,
+// nil returned if the encoding not supported.
+func newDecompressReader(encoding string, body io.ReadCloser, max int64) (io.ReadCloser, error) {
+	d := &decompressReader{body: body, max: max}
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		d.r, d.closer = zr, func() { _ = zr.Close() }
+
+	case "deflate":
+		// deflate should be zlib format, but some clients send raw deflate
+		br := bufio.NewReader(body)
+		if hdr, err := br.Peek(2); err == nil && isZlibHeader(hdr) {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			d.r, d.closer = zr, func() { _ = zr.Close() }
+		} else {
+			fr := flate.NewReader(br)
+			d.r, d.closer = fr, func() { _ = fr.Close() }
+		}
+
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		d.r, d.closer = zr, zr.Close
+
+	default:
+		return nil, nil
+	}
+
+	return d, nil
+}
+
+func isZlibHeader(hdr []byte) bool {
+	return hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0
+}
+
+// decompressBody decompresses request bodies of Content-Encoding gzip,
+// deflate and zstd for all APIs, so handlers always read plain bodies.
+// Bodies of other encodings(such as snappy of Prometheus remote write) are
+// left to their handlers.
+func decompressBody(max int64) gin.HandlerFunc {
+	if max <= 0 {
+		max = defaultMaxDecompressedSize
+	}
+
+	return func(c *gin.Context) {
+		req := c.Request
+
+		encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || req.Body == nil || req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body, err := newDecompressReader(encoding, req.Body, max)
+		if err != nil {
+			uhttp.HttpErr(c, uhttp.Errorf(ErrInvalidEncoding,
+				"invalid %s body of %s: %s", encoding, req.URL.Path, err))
+			c.Abort()
+			return
+		}
+
+		if body == nil {
+			c.Next()
+			return
+		}
+
+		req.Body = body
+		req.ContentLength = -1
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+
+		c.Next()
+	}
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf)
+	}
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestDecompressBody(t *testing.T) {
+	router := gin.New()
+	router.Use(decompressBody(1024))
+	router.POST("/v0.4/traces", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.String(http.StatusOK, c.GetHeader("Content-Encoding")+"|"+string(body))
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	data := []byte(`[{"trace_id": 1, "span_id": 2, "name": "GET /"}]`)
+
+	cases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		resp     string
+	}{
+		{name: "plain", body: data, status: 200, resp: "|" + string(data)},
+		{name: "gzip", encoding: "gzip", body: compress(t, "gzip", data), status: 200, resp: "|" + string(data)},
+		{name: "deflate", encoding: "deflate", body: compress(t, "deflate", data), status: 200, resp: "|" + string(data)},
+		{name: "raw-deflate", encoding: "Deflate", body: compress(t, "raw-deflate", data), status: 200, resp: "|" + string(data)},
+		{name: "zstd", encoding: "zstd", body: compress(t, "zstd", data), status: 200, resp: "|" + string(data)},
+		{name: "unknown-encoding", encoding: "snappy", body: []byte("xxx"), status: 200, resp: "snappy|xxx"},
+		{name: "invalid-gzip", encoding: "gzip", body: []byte("not gzip"), status: 400},
+		{
+			name:     "too-large",
+			encoding: "gzip",
+			body:     compress(t, "gzip", []byte(strings.Repeat("a", 2048))),
+			status:   400,
+			resp:     errDecompressedTooLarge.Error() + ": exceeds 1024 bytes",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/v0.4/traces", bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			if tc.encoding != "" {
+				req.Header.Set("Content-Encoding", tc.encoding)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close() //nolint:errcheck
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.status, resp.StatusCode, string(body))
+			if tc.resp != "" {
+				assert.Equal(t, tc.resp, string(body))
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/http"
 
 	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/election"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 )
 
 var OK = newErr(nil, http.StatusOK)
@@ -21,6 +23,7 @@ var (
 	ErrInvalidCategory = newErr(errors.New("invalid category"), http.StatusBadRequest)
 	ErrInvalidPipeline = newErr(errors.New("invalid pipeline"), http.StatusBadRequest)
 	ErrInvalidData     = newErr(errors.New("invalid data"), http.StatusBadRequest)
+	ErrInvalidEncoding = newErr(errors.New("invalid Content-Encoding body"), http.StatusBadRequest)
 	ErrCompiledFailed  = newErr(errors.New("pipeline compile failed"), http.StatusBadRequest)
 
 	ErrInvalidPrecision       = newErr(errors.New("invalid precision"), http.StatusBadRequest)
@@ -35,10 +38,19 @@ var (
 	ErrInvalidAPIHandler      = newErr(errors.New("invalid API handler"), http.StatusInternalServerError)
 	ErrPublicAccessDisabled   = newErr(errors.New("public access disabled"), http.StatusForbidden)
 	ErrReachLimit             = newErr(errors.New("reach max API limit"), http.StatusTooManyRequests)
+	ErrLocalFederateDisabled  = newErr(errors.New("local federate disabled"), http.StatusNotFound)
+	ErrConfigNotApplied       = newErr(errors.New("input configures not applied"), http.StatusNotFound)
+	ErrLoadShed               = newErr(errors.New("disabled on high load"), http.StatusServiceUnavailable)
+	ErrElectionDisabled       = newErr(election.ErrElectionDisabled, http.StatusNotFound)
+	ErrElectionObserver       = newErr(election.ErrObserverMode, http.StatusForbidden)
+	ErrElectionBusy           = newErr(election.ErrElectionBusy, http.StatusServiceUnavailable)
+	ErrSendQueueDisabled      = newErr(dkio.ErrSendQueueDisabled, http.StatusNotFound)
+	ErrUploadPauseNoCache     = newErr(dkio.ErrUploadPauseNoCache, http.StatusBadRequest)
 
 	// write body error.
 	ErrInvalidJSONPoint = newErr(errors.New("invalid json point"), http.StatusBadRequest)
 	ErrInvalidLinePoint = newErr(errors.New("invalid line point"), http.StatusBadRequest)
+	ErrInvalidEvent     = newErr(errors.New("invalid event"), http.StatusBadRequest)
 )
 
 func newErr(err error, code int) *uhttp.HttpError {
@@ -34,6 +34,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/git"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 	dkm "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/metrics"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
@@ -91,6 +92,8 @@ type APIConfig struct {
 	Timeout             string `toml:"timeout"`
 	CloseIdleConnection bool   `toml:"close_idle_connection"`
 	timeoutDuration     time.Duration
+
+	MaxDecompressedSize int64 `toml:"max_decompressed_size,omitzero"`
 }
 
 func Start(o *Option) {
@@ -271,6 +274,7 @@ func setupRouter() *gin.Engine {
 	router.Use(gin.Recovery())
 	router.Use(uhttp.CORSMiddleware)
 	router.Use(dkHTTPTimeout())
+	router.Use(decompressBody(apiConfig.MaxDecompressedSize))
 
 	if !apiConfig.Disable404Page {
 		router.NoRoute(page404)
@@ -294,16 +298,33 @@ func setupRouter() *gin.Engine {
 
 	router.GET("/v1/workspace", ginLimiter(reqLimiter), apiWorkspace)
 	router.GET("/v1/ping", rawHTTPWraper(reqLimiter, apiPing))
+	router.GET("/v1/federate", ginLimiter(reqLimiter), apiFederate)
 	router.POST("/v1/lasterror", ginLimiter(reqLimiter), apiGetDatakitLastError)
+	router.GET("/v1/config/drift", rawHTTPWraper(reqLimiter, apiConfigDrift))
+
+	router.GET("/v1/election", rawHTTPWraper(reqLimiter, apiElectionState))
+	router.POST("/v1/election/resign", rawHTTPWraper(reqLimiter, apiElectionResign))
+	router.POST("/v1/election/claim", rawHTTPWraper(reqLimiter, apiElectionClaim))
+
+	router.GET("/v1/io/queue", rawHTTPWraper(reqLimiter, apiSendQueueState))
+	router.POST("/v1/io/queue/pause", rawHTTPWraper(reqLimiter, apiSendQueuePause))
+	router.POST("/v1/io/queue/resume", rawHTTPWraper(reqLimiter, apiSendQueueResume))
+	router.POST("/v1/io/queue/drain", rawHTTPWraper(reqLimiter, apiSendQueueDrain))
+	router.POST("/v1/io/queue/flush", rawHTTPWraper(reqLimiter, apiSendQueueFlush))
+
+	router.GET("/v1/io/upload", rawHTTPWraper(reqLimiter, apiUploadPauseState))
+	router.POST("/v1/io/upload/pause", rawHTTPWraper(reqLimiter, apiUploadPause))
+	router.POST("/v1/io/upload/resume", rawHTTPWraper(reqLimiter, apiUploadResume))
 
 	router.POST("/v1/write/:category", rawHTTPWraper(reqLimiter, apiWrite, &apiWriteImpl{}))
+	router.POST("/v1/write/events", rawHTTPWraper(reqLimiter, apiWriteEvents, &apiWriteImpl{}))
 
 	router.POST("/v1/query/raw", ginLimiter(reqLimiter), apiQueryRaw)
 	router.POST("/v1/object/labels", ginLimiter(reqLimiter), apiCreateOrUpdateObjectLabel)
 	router.DELETE("/v1/object/labels", ginLimiter(reqLimiter), apiDeleteObjectLabel)
 
-	router.POST("/v1/pipeline/debug", rawHTTPWraper(reqLimiter, apiPipelineDebugHandler))
-	router.POST("/v1/dialtesting/debug", rawHTTPWraper(reqLimiter, apiDebugDialtestingHandler))
+	router.POST("/v1/pipeline/debug", ginLoadShed(loadshed.DebugAPI), rawHTTPWraper(reqLimiter, apiPipelineDebugHandler))
+	router.POST("/v1/dialtesting/debug", ginLoadShed(loadshed.DebugAPI), rawHTTPWraper(reqLimiter, apiDebugDialtestingHandler))
 	return router
 }
 
@@ -489,6 +510,12 @@ func portInUse(addr string) bool {
 	return true
 }
 
+// CheckToken checks the token of the DataWay in the query of the request, for
+// APIs registered by inputs which change the state of DataKit.
+func CheckToken(r *http.Request) error {
+	return checkToken(r)
+}
+
 func checkToken(r *http.Request) error {
 	if dw == nil {
 		return ErrInvalidToken
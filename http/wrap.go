@@ -13,6 +13,7 @@ import (
 	tollbooth "github.com/didip/tollbooth/v6"
 	"github.com/didip/tollbooth/v6/limiter"
 	"github.com/gin-gonic/gin"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 )
 
 var reqLimiter *limiter.Limiter
@@ -77,6 +78,20 @@ func ginLimiter(lmt *limiter.Limiter) gin.HandlerFunc {
 	}
 }
 
+// ginLoadShed rejects requests with 503 while item shed on high load.
+func ginLoadShed(item string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if loadshed.Shedding(item) {
+			c.Header("Retry-After", "60")
+			uhttp.HttpErr(c, ErrLoadShed)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 type APIHandler func(http.ResponseWriter, *http.Request, ...interface{}) (interface{}, error)
 
 func rawHTTPWraper(lmt *limiter.Limiter, next APIHandler, other ...interface{}) gin.HandlerFunc {
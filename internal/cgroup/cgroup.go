@@ -37,6 +37,13 @@ type CgroupOptions struct {
 	Enable     bool `toml:"enable"`
 }
 
+// A ProcessCgroup limits CPU and memory of processes forked by datakit,
+// such as python workers of pythond.
+type ProcessCgroup interface {
+	AddProc(pid int) error
+	Delete() error
+}
+
 //nolint:gochecknoinits
 func init() {
 	var err error
@@ -102,3 +109,21 @@ func MyMemPercent() (float32, error) {
 func MyCPUPercent(du time.Duration) (float64, error) {
 	return self.Percent(du)
 }
+
+// MyCPUTimes returns CPU time(user + system, in seconds) used by datakit.
+func MyCPUTimes() (float64, error) {
+	t, err := self.Times()
+	if err != nil {
+		return 0, err
+	}
+	return t.User + t.System, nil
+}
+
+// MyMemRSS returns resident memory(in bytes) of datakit.
+func MyMemRSS() (uint64, error) {
+	mi, err := self.MemoryInfo()
+	if err != nil {
+		return 0, err
+	}
+	return mi.RSS, nil
+}
@@ -5,6 +5,8 @@
 
 package cgroup
 
+import "errors"
+
 type Cgroup struct {
 	opt *CgroupOptions
 	err error
@@ -13,3 +15,7 @@ type Cgroup struct {
 func (c *Cgroup) start() {
 	l.Infof("not support darwin system, exit")
 }
+
+func NewProcessCgroup(_ string, _ float64, _ int64) (ProcessCgroup, error) {
+	return nil, errors.New("cgroup not supported on darwin")
+}
@@ -163,3 +163,47 @@ func (c *Cgroup) show() {
 		l.Debugf("cgroup state: %s", c.control.State())
 	}
 }
+
+type processCgroup struct {
+	control cgroup1.Cgroup
+	manager *cgroup2.Manager
+}
+
+// NewProcessCgroup creates the cgroup at path with CPU(percent of all CPUs)
+// and memory(MB) limits, 0 means no limit.
+func NewProcessCgroup(path string, cpuMax float64, memMaxMB int64) (ProcessCgroup, error) {
+	c := &Cgroup{opt: &CgroupOptions{Path: path, CPUMax: cpuMax, MemMax: memMaxMB}}
+	resource := c.makeLinuxResource()
+	if cpuMax <= 0 {
+		resource.CPU = nil
+	}
+
+	if cgroups.Mode() == cgroups.Unified {
+		manager, err := cgroup2.NewManager(defaultCgroup2Path, path, cgroup2.ToResources(resource))
+		if err != nil {
+			return nil, err
+		}
+		return &processCgroup{manager: manager}, nil
+	}
+
+	control, err := cgroup1.New(cgroup1.StaticPath(path), resource)
+	if err != nil {
+		return nil, err
+	}
+	return &processCgroup{control: control}, nil
+}
+
+func (c *processCgroup) AddProc(pid int) error {
+	if c.manager != nil {
+		return c.manager.AddProc(uint64(pid))
+	}
+	return c.control.Add(cgroup1.Process{Pid: pid})
+}
+
+// Delete removes the cgroup, all processes within it should have exited.
+func (c *processCgroup) Delete() error {
+	if c.manager != nil {
+		return c.manager.Delete()
+	}
+	return c.control.Delete()
+}
@@ -5,6 +5,8 @@
 
 package cgroup
 
+import "errors"
+
 type Cgroup struct {
 	opt *CgroupOptions
 
@@ -14,3 +16,7 @@ type Cgroup struct {
 func (c *Cgroup) start() {
 	l.Infof("not support windows system, exit")
 }
+
+func NewProcessCgroup(_ string, _ float64, _ int64) (ProcessCgroup, error) {
+	return nil, errors.New("cgroup not supported on windows")
+}
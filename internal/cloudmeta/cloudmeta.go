@@ -0,0 +1,408 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package cloudmeta detects the cloud instance datakit running on by the
+// metadata service of cloud providers.
+package cloudmeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+)
+
+const (
+	packageName = "cloudmeta"
+
+	AWS    = "aws"
+	GCP    = "gcp"
+	Azure  = "azure"
+	Aliyun = "aliyun"
+
+	defaultTimeout  = time.Second
+	defaultCacheTTL = 24 * time.Hour
+
+	maxResponseSize = 64 * 1024
+)
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	// ErrNotCloud returned if no metadata service of cloud providers available.
+	ErrNotCloud = errors.New("not running on any cloud instance")
+
+	// providers in order of detection, the first available wins.
+	providers = []string{AWS, GCP, Azure, Aliyun}
+
+	// base URLs of metadata services.
+	defaultEndpoints = map[string]string{
+		AWS:    "http://169.254.169.254",
+		GCP:    "http://metadata.google.internal",
+		Azure:  "http://169.254.169.254",
+		Aliyun: "http://100.100.100.200",
+	}
+)
+
+// Metadata of the cloud instance, empty provider for not on cloud.
+type Metadata struct {
+	Provider     string    `json:"provider"`
+	InstanceID   string    `json:"instance_id,omitempty"`
+	Region       string    `json:"region,omitempty"`
+	Zone         string    `json:"zone,omitempty"`
+	InstanceType string    `json:"instance_type,omitempty"`
+	Detected     time.Time `json:"detected"`
+}
+
+// Tags returns non-empty metadata as tags.
+func (m *Metadata) Tags() map[string]string {
+	tags := map[string]string{}
+	for k, v := range map[string]string{
+		"cloud_provider":      m.Provider,
+		"cloud_instance_id":   m.InstanceID,
+		"cloud_region":        m.Region,
+		"cloud_zone":          m.Zone,
+		"cloud_instance_type": m.InstanceType,
+	} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+type Option func(*detector)
+
+// WithProvider detects the provider(aws/gcp/azure/aliyun) only.
+func WithProvider(provider string) Option {
+	return func(d *detector) { d.provider = strings.ToLower(provider) }
+}
+
+// WithCacheFile persists the metadata detected, reused within ttl, so
+// metadata services not requested on each start.
+func WithCacheFile(path string, ttl time.Duration) Option {
+	return func(d *detector) {
+		d.cacheFile = path
+		if ttl > 0 {
+			d.cacheTTL = ttl
+		}
+	}
+}
+
+// WithTimeout set timeout of detection.
+func WithTimeout(du time.Duration) Option {
+	return func(d *detector) {
+		if du > 0 {
+			d.timeout = du
+		}
+	}
+}
+
+type detector struct {
+	provider  string
+	cacheFile string
+	cacheTTL  time.Duration
+	timeout   time.Duration
+
+	endpoints map[string]string
+	cli       *http.Client
+}
+
+func newDetector(opts ...Option) *detector {
+	d := &detector{
+		cacheTTL:  defaultCacheTTL,
+		timeout:   defaultTimeout,
+		endpoints: defaultEndpoints,
+		cli:       &http.Client{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(d)
+		}
+	}
+
+	return d
+}
+
+// Detect returns metadata of the cloud instance, or ErrNotCloud if not
+// running on any cloud instance.
+func Detect(opts ...Option) (*Metadata, error) {
+	l = logger.SLogger(packageName)
+
+	return newDetector(opts...).detect(time.Now())
+}
+
+func (d *detector) detect(now time.Time) (*Metadata, error) {
+	m := d.load(now)
+	if m == nil {
+		var err error
+		if m, err = d.query(); err != nil {
+			return nil, err
+		}
+
+		m.Detected = now
+		if err := d.save(m); err != nil {
+			l.Warnf("save cloud metadata to %s: %s, ignored", d.cacheFile, err)
+		}
+	}
+
+	if m.Provider == "" {
+		return nil, ErrNotCloud
+	}
+
+	return m, nil
+}
+
+// query requests metadata services of providers concurrently.
+func (d *detector) query() (*Metadata, error) {
+	arr := providers
+	if d.provider != "" {
+		if _, ok := d.endpoints[d.provider]; !ok {
+			return nil, fmt.Errorf("unknown cloud provider %q, only aws/gcp/azure/aliyun allowed", d.provider)
+		}
+		arr = []string{d.provider}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	results := make([]chan *Metadata, len(arr))
+	for i, p := range arr {
+		results[i] = make(chan *Metadata, 1)
+		go func(p string, ch chan<- *Metadata) {
+			m, err := d.queryProvider(ctx, p)
+			if err != nil {
+				l.Debugf("query metadata of %s: %s", p, err)
+			}
+			ch <- m
+		}(p, results[i])
+	}
+
+	// in order of providers, not the fastest
+	for _, ch := range results {
+		if m := <-ch; m != nil {
+			return m, nil
+		}
+	}
+
+	return &Metadata{}, nil
+}
+
+func (d *detector) queryProvider(ctx context.Context, provider string) (*Metadata, error) {
+	base := d.endpoints[provider]
+
+	switch provider {
+	case AWS:
+		return d.aws(ctx, base)
+	case GCP:
+		return d.gcp(ctx, base)
+	case Azure:
+		return d.azure(ctx, base)
+	case Aliyun:
+		return d.aliyun(ctx, base)
+	default:
+		return nil, fmt.Errorf("unknown cloud provider %q", provider)
+	}
+}
+
+func (d *detector) aws(ctx context.Context, base string) (*Metadata, error) {
+	headers := map[string]string{}
+
+	// IMDSv2 token, fallback to IMDSv1 if not available
+	if token, err := d.request(ctx, http.MethodPut, base+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"}); err == nil {
+		headers["X-aws-ec2-metadata-token"] = token
+	}
+
+	get := func(path string) (string, error) {
+		return d.request(ctx, http.MethodGet, base+"/latest/meta-data/"+path, headers)
+	}
+
+	id, err := get("instance-id")
+	if err != nil {
+		return nil, err
+	}
+
+	// metadata of OpenStack based clouds(such as hwcloud) served on the same address
+	if !strings.HasPrefix(id, "i-") {
+		return nil, fmt.Errorf("unexpected instance-id %q", id)
+	}
+
+	m := &Metadata{Provider: AWS, InstanceID: id}
+	m.Region, _ = get("placement/region")
+	m.Zone, _ = get("placement/availability-zone")
+	m.InstanceType, _ = get("instance-type")
+
+	return m, nil
+}
+
+func (d *detector) gcp(ctx context.Context, base string) (*Metadata, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	get := func(path string) (string, error) {
+		return d.request(ctx, http.MethodGet, base+"/computeMetadata/v1/instance/"+path, headers)
+	}
+
+	id, err := get("id")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{Provider: GCP, InstanceID: id}
+
+	// projects/<project-number>/zones/us-central1-a
+	if zone, err := get("zone"); err == nil {
+		m.Zone = lastSegment(zone)
+		if i := strings.LastIndex(m.Zone, "-"); i > 0 {
+			m.Region = m.Zone[:i]
+		}
+	}
+
+	// projects/<project-number>/machineTypes/n1-standard-1
+	if mt, err := get("machine-type"); err == nil {
+		m.InstanceType = lastSegment(mt)
+	}
+
+	return m, nil
+}
+
+func (d *detector) azure(ctx context.Context, base string) (*Metadata, error) {
+	body, err := d.request(ctx, http.MethodGet, base+"/metadata/instance/compute?api-version=2021-02-01",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var compute struct {
+		VMID     string `json:"vmId"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMSize   string `json:"vmSize"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return nil, fmt.Errorf("invalid compute metadata: %w", err)
+	}
+
+	if compute.VMID == "" {
+		return nil, fmt.Errorf("vmId not found")
+	}
+
+	return &Metadata{
+		Provider:     Azure,
+		InstanceID:   compute.VMID,
+		Region:       compute.Location,
+		Zone:         compute.Zone,
+		InstanceType: compute.VMSize,
+	}, nil
+}
+
+func (d *detector) aliyun(ctx context.Context, base string) (*Metadata, error) {
+	get := func(path string) (string, error) {
+		return d.request(ctx, http.MethodGet, base+"/latest/meta-data/"+path, nil)
+	}
+
+	id, err := get("instance-id")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{Provider: Aliyun, InstanceID: id}
+	m.Region, _ = get("region-id")
+	m.Zone, _ = get("zone-id")
+	m.InstanceType, _ = get("instance/instance-type")
+
+	return m, nil
+}
+
+// request returns the trimmed response body, error if not 200.
+func (d *detector) request(ctx context.Context, method, u string, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxResponseSize))
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", method, u, resp.Status)
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// load returns the cached metadata if not expired, nil if not cached.
+func (d *detector) load(now time.Time) *Metadata {
+	if d.cacheFile == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Clean(d.cacheFile))
+	if err != nil {
+		return nil
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		l.Warnf("invalid cloud metadata in %s, ignored", d.cacheFile)
+		return nil
+	}
+
+	if now.Sub(m.Detected) > d.cacheTTL || m.Detected.After(now) {
+		return nil
+	}
+
+	// cached one of another provider
+	if d.provider != "" && m.Provider != "" && m.Provider != d.provider {
+		return nil
+	}
+
+	return &m
+}
+
+func (d *detector) save(m *Metadata) error {
+	if d.cacheFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.cacheFile), os.ModePerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(d.cacheFile, data, 0o600)
+}
+
+func lastSegment(s string) string {
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cloudmeta
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *T.T, routes map[string]string, check func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.Method+" "+r.URL.RequestURI()]
+		if !ok || (check != nil && !check(r)) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, body) //nolint:errcheck,gosec
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func newTestDetector(endpoints map[string]string, opts ...Option) *detector {
+	d := newDetector(opts...)
+	d.endpoints = map[string]string{}
+	for _, p := range providers {
+		d.endpoints[p] = "http://127.0.0.1:1" // unavailable
+	}
+	for k, v := range endpoints {
+		d.endpoints[k] = v
+	}
+	return d
+}
+
+func TestDetect(t *T.T) {
+	now := time.Now()
+
+	t.Run("aws-imdsv2", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"PUT /latest/api/token":                             "tkn",
+			"GET /latest/meta-data/instance-id":                 "i-0123456789abcdef0",
+			"GET /latest/meta-data/placement/region":            "us-east-1",
+			"GET /latest/meta-data/placement/availability-zone": "us-east-1a",
+			"GET /latest/meta-data/instance-type":               "m5.large",
+		}, func(r *http.Request) bool {
+			return r.Method == http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token") == "tkn"
+		})
+
+		m, err := newTestDetector(map[string]string{AWS: ts.URL}).detect(now)
+		require.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Provider: AWS, InstanceID: "i-0123456789abcdef0", Region: "us-east-1",
+			Zone: "us-east-1a", InstanceType: "m5.large", Detected: now,
+		}, m)
+
+		assert.Equal(t, map[string]string{
+			"cloud_provider":      "aws",
+			"cloud_instance_id":   "i-0123456789abcdef0",
+			"cloud_region":        "us-east-1",
+			"cloud_zone":          "us-east-1a",
+			"cloud_instance_type": "m5.large",
+		}, m.Tags())
+	})
+
+	t.Run("openstack-not-aws", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"GET /latest/meta-data/instance-id": "8d2a7c4e-hwcloud",
+		}, nil)
+
+		_, err := newTestDetector(map[string]string{AWS: ts.URL}).detect(now)
+		assert.True(t, errors.Is(err, ErrNotCloud))
+	})
+
+	t.Run("gcp", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"GET /computeMetadata/v1/instance/id":           "4520031799277581759",
+			"GET /computeMetadata/v1/instance/zone":         "projects/123/zones/us-central1-a",
+			"GET /computeMetadata/v1/instance/machine-type": "projects/123/machineTypes/n1-standard-1",
+		}, func(r *http.Request) bool {
+			return r.Header.Get("Metadata-Flavor") == "Google"
+		})
+
+		m, err := newTestDetector(map[string]string{GCP: ts.URL}).detect(now)
+		require.NoError(t, err)
+		assert.Equal(t, GCP, m.Provider)
+		assert.Equal(t, "us-central1", m.Region)
+		assert.Equal(t, "us-central1-a", m.Zone)
+		assert.Equal(t, "n1-standard-1", m.InstanceType)
+	})
+
+	t.Run("azure", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"GET /metadata/instance/compute?api-version=2021-02-01": `{"vmId":"02aab8a4-74ef","location":"westus","zone":"1","vmSize":"Standard_A3"}`,
+		}, func(r *http.Request) bool {
+			return r.Header.Get("Metadata") == "true"
+		})
+
+		m, err := newTestDetector(map[string]string{Azure: ts.URL}).detect(now)
+		require.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Provider: Azure, InstanceID: "02aab8a4-74ef", Region: "westus",
+			Zone: "1", InstanceType: "Standard_A3", Detected: now,
+		}, m)
+	})
+
+	t.Run("aliyun-partial", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"GET /latest/meta-data/instance-id": "i-bp1abc",
+			"GET /latest/meta-data/region-id":   "cn-hangzhou",
+		}, nil)
+
+		m, err := newTestDetector(map[string]string{Aliyun: ts.URL}).detect(now)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"cloud_provider":    "aliyun",
+			"cloud_instance_id": "i-bp1abc",
+			"cloud_region":      "cn-hangzhou",
+		}, m.Tags())
+	})
+
+	t.Run("cache", func(t *T.T) {
+		ts := newServer(t, map[string]string{
+			"GET /latest/meta-data/instance-id": "i-bp1abc",
+		}, nil)
+
+		cache := filepath.Join(t.TempDir(), ".cloud_meta")
+
+		m, err := newTestDetector(map[string]string{Aliyun: ts.URL}, WithCacheFile(cache, time.Hour)).detect(now)
+		require.NoError(t, err)
+		assert.Equal(t, "i-bp1abc", m.InstanceID)
+
+		ts.Close()
+
+		// cached, metadata service not requested
+		m, err = newTestDetector(nil, WithCacheFile(cache, time.Hour)).detect(now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, "i-bp1abc", m.InstanceID)
+
+		// expired
+		_, err = newTestDetector(nil, WithCacheFile(cache, time.Hour)).detect(now.Add(2 * time.Hour))
+		assert.True(t, errors.Is(err, ErrNotCloud))
+
+		// not on cloud cached too
+		_, err = newTestDetector(nil, WithCacheFile(cache, time.Hour)).detect(now.Add(2*time.Hour + time.Minute))
+		assert.True(t, errors.Is(err, ErrNotCloud))
+	})
+
+	t.Run("provider", func(t *T.T) {
+		_, err := newTestDetector(nil, WithProvider("openstack")).detect(now)
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrNotCloud))
+	})
+}
@@ -0,0 +1,287 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package confdrift records the input configures applied by datakit, and
+// detects drift between the on-disk conf.d and the running state.
+package confdrift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bstoml "github.com/BurntSushi/toml"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+const (
+	packageName = "confdrift"
+
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+)
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	// ErrNotApplied returned on drift check while no configures applied yet.
+	ErrNotApplied = errors.New("no input configures applied")
+
+	mtx     sync.Mutex
+	applied *Manifest
+)
+
+// FileState is the state of a single input configure file.
+type FileState struct {
+	SHA256  string    `json:"sha256"`
+	ModTime time.Time `json:"mod_time"`
+	Inputs  []string  `json:"inputs,omitempty"`
+}
+
+// Manifest is the set of input configure files under conf roots.
+type Manifest struct {
+	AppliedAt time.Time             `json:"applied_at"`
+	Hash      string                `json:"hash"`
+	Roots     []string              `json:"roots"`
+	Files     map[string]*FileState `json:"files"`
+}
+
+// Change is a configure file changed since last applied.
+type Change struct {
+	Path    string    `json:"path"`
+	Change  string    `json:"change"`
+	ModTime time.Time `json:"mod_time,omitempty"`
+
+	// Inputs declared in the file, for removed file, they are inputs declared
+	// in the applied file.
+	Inputs []string `json:"inputs,omitempty"`
+}
+
+// Drift is the difference between the applied configures and the on-disk ones.
+type Drift struct {
+	AppliedAt   time.Time `json:"applied_at"`
+	AppliedHash string    `json:"applied_hash"`
+	CurrentHash string    `json:"current_hash"`
+	Changes     []*Change `json:"changes"`
+
+	// Since is the time drift first detected by the watcher, zero if drift not
+	// detected by the watcher yet.
+	Since time.Time `json:"since,omitempty"`
+}
+
+// Drifted returns true if any configure file changed since last applied.
+func (d *Drift) Drifted() bool {
+	return d.AppliedHash != d.CurrentHash
+}
+
+// ManifestPath returns the path of the persisted manifest.
+func ManifestPath() string {
+	return filepath.Join(datakit.DataDir, "applied-confs.json")
+}
+
+// Scan hashes all input configure files under roots.
+func Scan(roots []string) (*Manifest, error) {
+	m := &Manifest{
+		Roots: roots,
+		Files: map[string]*FileState{},
+	}
+
+	for _, root := range roots {
+		if err := filepath.Walk(root, func(fp string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+
+			if fi.IsDir() {
+				if fi.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !strings.HasSuffix(fi.Name(), ".conf") || fi.Name() == datakit.StrDefaultConfFile {
+				return nil
+			}
+
+			data, err := os.ReadFile(filepath.Clean(fp))
+			if err != nil {
+				return err
+			}
+
+			sum := sha256.Sum256(data)
+			m.Files[fp] = &FileState{
+				SHA256:  hex.EncodeToString(sum[:]),
+				ModTime: fi.ModTime(),
+				Inputs:  inputNames(data),
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", root, err)
+		}
+	}
+
+	m.Hash = m.hash()
+	return m, nil
+}
+
+// hash sums all the file path and hash of the manifest.
+func (m *Manifest) hash() string {
+	paths := make([]string, 0, len(m.Files))
+	for fp := range m.Files {
+		paths = append(paths, fp)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, fp := range paths {
+		fmt.Fprintf(h, "%s:%s\n", fp, m.Files[fp].SHA256)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// inputNames returns names of inputs declared in the configure, invalid
+// configure got no inputs.
+func inputNames(data []byte) []string {
+	var conf struct {
+		Inputs map[string]interface{} `toml:"inputs"`
+	}
+
+	if _, err := bstoml.Decode(string(data), &conf); err != nil {
+		return nil
+	}
+
+	var names []string
+	for k := range conf.Inputs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Diff returns the drift of cur against m.
+func (m *Manifest) Diff(cur *Manifest) *Drift {
+	d := &Drift{
+		AppliedAt:   m.AppliedAt,
+		AppliedHash: m.Hash,
+		CurrentHash: cur.Hash,
+	}
+
+	for fp, fs := range cur.Files {
+		old, ok := m.Files[fp]
+		switch {
+		case !ok:
+			d.Changes = append(d.Changes, &Change{Path: fp, Change: ChangeAdded, ModTime: fs.ModTime, Inputs: fs.Inputs})
+		case old.SHA256 != fs.SHA256:
+			d.Changes = append(d.Changes, &Change{Path: fp, Change: ChangeModified, ModTime: fs.ModTime, Inputs: fs.Inputs})
+		}
+	}
+
+	for fp, fs := range m.Files {
+		if _, ok := cur.Files[fp]; !ok {
+			d.Changes = append(d.Changes, &Change{Path: fp, Change: ChangeRemoved, Inputs: fs.Inputs})
+		}
+	}
+
+	sort.Slice(d.Changes, func(i, j int) bool {
+		return d.Changes[i].Path < d.Changes[j].Path
+	})
+
+	return d
+}
+
+// Applied records configures under roots as the applied ones, and persists the
+// manifest. It should be called after inputs (re)loaded from roots.
+func Applied(roots []string) {
+	m, err := Scan(roots)
+	if err != nil {
+		l.Warnf("Scan: %s, ignored", err.Error())
+		return
+	}
+
+	m.AppliedAt = time.Now()
+
+	mtx.Lock()
+	applied = m
+	mtx.Unlock()
+
+	resetWatch()
+
+	if err := m.save(ManifestPath()); err != nil {
+		l.Warnf("save applied manifest: %s, ignored", err.Error())
+	}
+
+	l.Infof("applied %d input confs, hash %s", len(m.Files), m.Hash)
+}
+
+// Check returns drift of on-disk configures against the applied ones.
+func Check() (*Drift, error) {
+	mtx.Lock()
+	m := applied
+	mtx.Unlock()
+
+	if m == nil {
+		return nil, ErrNotApplied
+	}
+
+	cur, err := Scan(m.Roots)
+	if err != nil {
+		return nil, err
+	}
+
+	d := m.Diff(cur)
+	if d.Drifted() {
+		d.Since = driftSince()
+	}
+
+	return d, nil
+}
+
+// LoadManifest loads the persisted manifest, used while datakit not running.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	if m.Files == nil {
+		m.Files = map[string]*FileState{}
+	}
+
+	return &m, nil
+}
+
+func (m *Manifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, datakit.ConfPerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
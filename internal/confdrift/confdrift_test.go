@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package confdrift
+
+import (
+	"os"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func writeConf(t *T.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	require.NoError(t, os.WriteFile(path, []byte(data), os.ModePerm))
+}
+
+func TestDrift(t *T.T) {
+	root := t.TempDir()
+
+	dataDir := datakit.DataDir
+	datakit.DataDir = t.TempDir()
+	defer func() { datakit.DataDir = dataDir }()
+
+	cpu := filepath.Join(root, "host", "cpu.conf")
+	mem := filepath.Join(root, "host", "mem.conf")
+	writeConf(t, cpu, "[[inputs.cpu]]\n")
+	writeConf(t, mem, "[[inputs.mem]]\n")
+	writeConf(t, filepath.Join(root, "host", "disk.conf.sample"), "[[inputs.disk]]\n")
+	writeConf(t, filepath.Join(root, datakit.StrDefaultConfFile), "name = 'dk'\n")
+	writeConf(t, filepath.Join(root, ".git", "some.conf"), "[[inputs.git]]\n")
+
+	t.Run("not-applied", func(t *T.T) {
+		_, err := Check()
+		assert.ErrorIs(t, err, ErrNotApplied)
+	})
+
+	t.Run("no-drift", func(t *T.T) {
+		Applied([]string{root})
+
+		d, err := Check()
+		require.NoError(t, err)
+		assert.False(t, d.Drifted())
+		assert.Empty(t, d.Changes)
+
+		m, err := LoadManifest(ManifestPath())
+		require.NoError(t, err)
+		assert.Len(t, m.Files, 2)
+		assert.Equal(t, []string{"cpu"}, m.Files[cpu].Inputs)
+		assert.Equal(t, d.AppliedHash, m.Hash)
+	})
+
+	t.Run("drifted", func(t *T.T) {
+		Applied([]string{root})
+
+		writeConf(t, cpu, "[[inputs.cpu]]\npercpu = true\n")
+		require.NoError(t, os.Remove(mem))
+		disk := filepath.Join(root, "host", "disk.conf")
+		writeConf(t, disk, "[[inputs.disk]]\n[[inputs.diskio]]\n")
+
+		d, err := Check()
+		require.NoError(t, err)
+		assert.True(t, d.Drifted())
+		require.Len(t, d.Changes, 3)
+
+		assert.Equal(t, cpu, d.Changes[0].Path)
+		assert.Equal(t, ChangeModified, d.Changes[0].Change)
+
+		assert.Equal(t, disk, d.Changes[1].Path)
+		assert.Equal(t, ChangeAdded, d.Changes[1].Change)
+		assert.Equal(t, []string{"disk", "diskio"}, d.Changes[1].Inputs)
+
+		assert.Equal(t, mem, d.Changes[2].Path)
+		assert.Equal(t, ChangeRemoved, d.Changes[2].Change)
+		assert.Equal(t, []string{"mem"}, d.Changes[2].Inputs)
+
+		// the persisted manifest works without the running state
+		m, err := LoadManifest(ManifestPath())
+		require.NoError(t, err)
+		cur, err := Scan(m.Roots)
+		require.NoError(t, err)
+		assert.Equal(t, d.Changes, m.Diff(cur).Changes)
+
+		// applied again, drift gone
+		Applied([]string{root})
+		d, err = Check()
+		require.NoError(t, err)
+		assert.False(t, d.Drifted())
+	})
+
+	t.Run("keyevent", func(t *T.T) {
+		var fed []*dkpt.Point
+		feed = func(name, category string, pts []*dkpt.Point, opt *dkio.Option) error {
+			assert.Equal(t, datakit.KeyEvent, category)
+			fed = append(fed, pts...)
+			return nil
+		}
+		defer func() { feed = dkio.Feed }()
+
+		Applied([]string{root})
+		writeConf(t, cpu, "[[inputs.cpu]]\npercpu = false\n")
+
+		now := time.Now()
+		checkOnce(now, time.Minute)
+		assert.Empty(t, fed)
+		assert.Equal(t, now, driftSince())
+
+		// drift persisted
+		checkOnce(now.Add(time.Minute), time.Minute)
+		require.Len(t, fed, 1)
+
+		fields, err := fed[0].Fields()
+		require.NoError(t, err)
+		assert.Equal(t, "warning", fields["df_status"])
+		assert.Contains(t, fields["df_message"], "modified: "+cpu)
+
+		// reported only once for the same drift
+		checkOnce(now.Add(2*time.Minute), time.Minute)
+		assert.Len(t, fed, 1)
+
+		// drift gone
+		Applied([]string{root})
+		checkOnce(now.Add(3*time.Minute), time.Minute)
+		assert.Len(t, fed, 1)
+		assert.True(t, driftSince().IsZero())
+	})
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package confdrift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	defaultCheckInterval = time.Minute
+
+	// keyevent emitted if the drift lasts more than driftThreshold.
+	driftThreshold = 10 * time.Minute
+
+	eventName = "datakit"
+)
+
+var (
+	runWatch sync.Once
+
+	feed = dkio.Feed
+
+	watchMtx sync.Mutex
+	since    time.Time // first time the drift detected
+	reported string    // current hash of the drift reported
+)
+
+// StartWatch checks drift periodically, and emits a keyevent if the drift
+// persisted.
+func StartWatch() {
+	runWatch.Do(func() {
+		l = logger.SLogger(packageName)
+		g := datakit.G(packageName)
+
+		g.Go(func(ctx context.Context) error {
+			tick := time.NewTicker(defaultCheckInterval)
+			defer tick.Stop()
+
+			for {
+				select {
+				case <-datakit.Exit.Wait():
+					l.Info("drift watcher exit")
+					return nil
+				case <-tick.C:
+					checkOnce(time.Now(), driftThreshold)
+				}
+			}
+		})
+	})
+}
+
+func resetWatch() {
+	watchMtx.Lock()
+	defer watchMtx.Unlock()
+
+	since = time.Time{}
+	reported = ""
+}
+
+func driftSince() time.Time {
+	watchMtx.Lock()
+	defer watchMtx.Unlock()
+
+	return since
+}
+
+func checkOnce(now time.Time, threshold time.Duration) {
+	d, err := Check()
+	if err != nil {
+		l.Debugf("Check: %s, ignored", err.Error())
+		return
+	}
+
+	watchMtx.Lock()
+	defer watchMtx.Unlock()
+
+	if !d.Drifted() {
+		since = time.Time{}
+		reported = ""
+		return
+	}
+
+	if since.IsZero() {
+		since = now
+		l.Infof("input confs drifted: %d changes not applied", len(d.Changes))
+	}
+
+	// only report once for the same drift
+	if now.Sub(since) < threshold || reported == d.CurrentHash {
+		return
+	}
+
+	pt, err := driftEvent(d, since, now)
+	if err != nil {
+		l.Errorf("driftEvent: %s", err.Error())
+		return
+	}
+
+	if err := feed(packageName, datakit.KeyEvent, []*dkpt.Point{pt}, nil); err != nil {
+		l.Errorf("Feed: %s", err.Error())
+		return
+	}
+
+	reported = d.CurrentHash
+}
+
+func driftEvent(d *Drift, since, now time.Time) (*dkpt.Point, error) {
+	var sb strings.Builder
+	for _, c := range d.Changes {
+		sb.WriteString(fmt.Sprintf("%s: %s", c.Change, c.Path))
+		if len(c.Inputs) > 0 {
+			sb.WriteString(fmt.Sprintf("(inputs: %s)", strings.Join(c.Inputs, ",")))
+		}
+		sb.WriteString("\n")
+	}
+
+	return dkpt.NewPoint(eventName,
+		map[string]string{"source": packageName},
+		map[string]interface{}{
+			"df_source":   "datakit",
+			"df_status":   "warning",
+			"df_event_id": fmt.Sprintf("%s-%s", packageName, d.CurrentHash),
+			"df_title": fmt.Sprintf("%d input confs changed since %s but not applied",
+				len(d.Changes), d.AppliedAt.Format(time.RFC3339)),
+			"df_message":    sb.String(),
+			"df_date_range": int64(now.Sub(since) / time.Second),
+		},
+		&dkpt.PointOption{Category: datakit.KeyEvent, Time: now})
+}
@@ -29,7 +29,7 @@ import (
  */
 
 var (
-	defaultCandidate        = &candidate{status: statusFail} // default set defeated
+	defaultCandidate        = &candidate{status: statusFail, ctrl: make(chan *ctrlReq)} // default set defeated
 	log                     = logger.DefaultSLogger("dk-election")
 	HTTPTimeout             = time.Second * 3
 	electionIntervalDefault = 4
@@ -43,9 +43,13 @@ type Puller interface {
 
 type candidate struct {
 	enabled       bool
+	observer      bool // never campaign, only report who the leader is
 	status        electionStatus
 	id, namespace string
 
+	ctrl         chan *ctrlReq // manual resign/claim requests
+	observeAfter time.Time
+
 	puller Puller
 
 	plugins []inputs.ElectionInput
@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package election
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrElectionDisabled = errors.New("election not enabled")
+	ErrObserverMode     = errors.New("election in observer mode")
+	ErrElectionBusy     = errors.New("election busy, try again later")
+
+	ctrlTimeout = time.Second * 10
+
+	// If the observer itself holds the leadership(for example, it was the
+	// leader before restarted as observer), stop heart-beating for a while
+	// to let the leadership expire.
+	observerBackoff = time.Minute
+)
+
+type ctrlOp int
+
+const (
+	opState ctrlOp = iota
+	opResign
+	opClaim
+)
+
+type ctrlReq struct {
+	op   ctrlOp
+	done chan *ctrlResp
+}
+
+type ctrlResp struct {
+	state *State
+	err   error
+}
+
+// State is the election state of current DataKit.
+type State struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+	Leader    string `json:"leader"` // ID of the DataKit elected in the namespace
+}
+
+// GetState returns the election state of current DataKit.
+func GetState() (*State, error) {
+	return defaultCandidate.request(opState)
+}
+
+// Resign releases the leadership(if elected) and stops campaigning until
+// Claim called or DataKit restarted. Other DataKits take over once the
+// leadership expires on the center.
+func Resign() (*State, error) {
+	return defaultCandidate.request(opResign)
+}
+
+// Claim campaigns for the leadership right now. It's not preemptive: if
+// another DataKit is the leader, the claim fails and the current DataKit
+// keeps campaigning as usual.
+func Claim() (*State, error) {
+	return defaultCandidate.request(opClaim)
+}
+
+func (x *candidate) request(op ctrlOp) (*State, error) {
+	if !x.enabled {
+		return x.state(), ErrElectionDisabled
+	}
+
+	req := &ctrlReq{op: op, done: make(chan *ctrlResp, 1)}
+
+	select {
+	case x.ctrl <- req:
+	case <-time.After(ctrlTimeout):
+		return nil, ErrElectionBusy
+	}
+
+	select {
+	case resp := <-req.done:
+		return resp.state, resp.err
+	case <-time.After(ctrlTimeout):
+		return nil, ErrElectionBusy
+	}
+}
+
+// handle runs within the election goroutine.
+func (x *candidate) handle(op ctrlOp) (*State, error) {
+	if op == opState {
+		return x.state(), nil
+	}
+
+	if x.status == statusObserver {
+		return x.state(), ErrObserverMode
+	}
+
+	switch op {
+	case opResign:
+		if x.status == statusSuccess {
+			x.pausePlugins()
+		}
+
+		x.status = statusResigned
+		x.setStatusMetric(0)
+		log.Infof("%s resigned from election", x.id)
+
+	case opClaim:
+		if x.status == statusSuccess {
+			break
+		}
+
+		x.status = statusFail
+		log.Infof("%s claiming leadership...", x.id)
+		if _, err := x.tryElection(); err != nil {
+			return x.state(), err
+		}
+
+	case opState: // unreachable
+	}
+
+	return x.state(), nil
+}
+
+func (x *candidate) state() *State {
+	status := x.status
+	if !x.enabled {
+		status = statusDisabled
+	}
+
+	return &State{
+		ID:        x.id,
+		Namespace: x.namespace,
+		Status:    status.String(),
+		Leader:    CurrentElected,
+	}
+}
+
+func (x *candidate) setStatusMetric(v float64) {
+	electionStatusVec.Reset()
+	electionStatusVec.WithLabelValues(
+		CurrentElected,
+		x.id,
+		x.namespace,
+		x.status.String(),
+	).Set(v)
+}
+
+// observe finds out who the leader is by the heartbeat API, which never
+// campaigns for the leadership.
+func (x *candidate) observe() (int, error) {
+	if time.Now().Before(x.observeAfter) {
+		return electionIntervalDefault, nil
+	}
+
+	body, err := x.puller.ElectionHeartbeat(x.namespace, x.id)
+	if err != nil {
+		log.Error(err)
+		return electionIntervalDefault, err
+	}
+
+	e := electionResult{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		log.Error(err)
+		return electionIntervalDefault, err
+	}
+
+	log.Debugf("result body: %s", body)
+
+	if e.Content.Status == statusSuccess.String() {
+		log.Warnf("observer %s holds the leadership, stop heartbeat for %s", x.id, observerBackoff)
+		x.observeAfter = time.Now().Add(observerBackoff)
+	}
+
+	if CurrentElected != e.Content.IncumbencyID {
+		CurrentElected = e.Content.IncumbencyID
+		x.setStatusMetric(0)
+	}
+
+	if e.Content.Interval <= 0 {
+		return electionIntervalDefault, nil
+	}
+
+	return e.Content.Interval, nil
+}
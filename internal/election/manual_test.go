@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package election
+
+import (
+	"fmt"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPuller struct {
+	leader    string
+	elections int
+	beats     int
+}
+
+func (p *mockPuller) result(id string) []byte {
+	status := statusFail.String()
+	if p.leader == id {
+		status = statusSuccess.String()
+	}
+
+	return []byte(fmt.Sprintf(`{"content":{"status":"%s","id":"%s","incumbency_id":"%s","interval":4}}`,
+		status, id, p.leader))
+}
+
+func (p *mockPuller) Election(namespace, id string) ([]byte, error) {
+	p.elections++
+	if p.leader == "" {
+		p.leader = id
+	}
+	return p.result(id), nil
+}
+
+func (p *mockPuller) ElectionHeartbeat(namespace, id string) ([]byte, error) {
+	p.beats++
+	return p.result(id), nil
+}
+
+func TestManualElection(t *T.T) {
+	t.Run("resign-and-claim", func(t *T.T) {
+		p := &mockPuller{}
+		x := &candidate{enabled: true, status: statusFail, id: "host-a", namespace: "default", puller: p}
+
+		x.runOnce()
+		require.Equal(t, statusSuccess, x.status)
+
+		st, err := x.handle(opResign)
+		require.NoError(t, err)
+		assert.Equal(t, "resigned", st.Status)
+
+		// resigned: no election nor heartbeat
+		p.leader = "host-b"
+		x.runOnce()
+		assert.Equal(t, 1, p.elections)
+		assert.Equal(t, 0, p.beats)
+
+		// claim failed, another DataKit elected
+		st, err = x.handle(opClaim)
+		require.NoError(t, err)
+		assert.Equal(t, "defeat", st.Status)
+		assert.Equal(t, "host-b", st.Leader)
+
+		p.leader = ""
+		st, err = x.handle(opClaim)
+		require.NoError(t, err)
+		assert.Equal(t, "success", st.Status)
+		assert.Equal(t, "host-a", st.Leader)
+	})
+
+	t.Run("observer", func(t *T.T) {
+		p := &mockPuller{leader: "host-b"}
+		x := &candidate{enabled: true, observer: true, id: "host-a", namespace: "default", puller: p}
+		x.status = statusObserver
+
+		x.runOnce()
+		assert.Equal(t, 0, p.elections)
+		assert.Equal(t, 1, p.beats)
+		assert.Equal(t, "host-b", x.state().Leader)
+
+		_, err := x.handle(opClaim)
+		assert.ErrorIs(t, err, ErrObserverMode)
+		_, err = x.handle(opResign)
+		assert.ErrorIs(t, err, ErrObserverMode)
+
+		// the observer holds the leadership, stop heartbeat to let it expire
+		p.leader = "host-a"
+		x.runOnce()
+		x.runOnce()
+		assert.Equal(t, 2, p.beats)
+		assert.Equal(t, statusObserver, x.status)
+	})
+
+	t.Run("disabled", func(t *T.T) {
+		x := &candidate{status: statusFail}
+		st, err := x.request(opResign)
+		assert.ErrorIs(t, err, ErrElectionDisabled)
+		assert.Equal(t, "disabled", st.Status)
+	})
+}
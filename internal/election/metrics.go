@@ -130,7 +130,7 @@ func MetricElectionInfo(mf *dto.MetricFamily) *ElectionInfo {
 	}
 
 	x := int64(m.GetGauge().GetValue())
-	if x > int64(statusFail) { // elected ok: if elect ok, there is a unix timestamp
+	if x > int64(statusObserver) { // elected ok: if elect ok, there is a unix timestamp
 		res.ElectedTime = time.Since(time.Unix(x, 0))
 	}
 
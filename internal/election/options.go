@@ -13,6 +13,12 @@ func WithElectionEnabled(on bool) ElectionOption {
 	}
 }
 
+func WithObserver(on bool) ElectionOption {
+	return func(c *candidate) {
+		c.observer = on
+	}
+}
+
 func WithID(id string) ElectionOption {
 	return func(c *candidate) {
 		c.id = id
@@ -31,6 +31,11 @@ func (x *candidate) run() {
 		return
 	}
 
+	if x.observer {
+		x.status = statusObserver
+		log.Info("election in observer mode, never campaign")
+	}
+
 	x.plugins = inputs.GetElectionInputs()
 
 	electionInputs.WithLabelValues(x.namespace).Set(float64(len(x.plugins)))
@@ -52,6 +57,9 @@ func (x *candidate) startElection() {
 			select {
 			case <-datakit.Exit.Wait():
 				return nil
+			case req := <-x.ctrl:
+				st, err := x.handle(req.op)
+				req.done <- &ctrlResp{state: st, err: err}
 			case <-tick.C:
 				electionInterval := x.runOnce()
 				if electionInterval != electionIntervalDefault {
@@ -74,7 +82,9 @@ func (x *candidate) runOnce() int {
 		elecIntv, err = x.keepalive()
 	case statusFail:
 		elecIntv, err = x.tryElection()
-	case statusDisabled: // pass
+	case statusObserver:
+		elecIntv, err = x.observe()
+	case statusDisabled, statusResigned: // pass
 		return electionIntervalDefault
 	}
 
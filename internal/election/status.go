@@ -15,6 +15,10 @@ func (e electionStatus) String() string {
 		return "success"
 	case statusFail:
 		return "defeat"
+	case statusResigned:
+		return "resigned"
+	case statusObserver:
+		return "observer"
 	default:
 		return "unknown" // should not been here
 	}
@@ -24,4 +28,6 @@ const (
 	statusDisabled = electionStatus(0)
 	statusSuccess  = electionStatus(1)
 	statusFail     = electionStatus(2)
+	statusResigned = electionStatus(3)
+	statusObserver = electionStatus(4)
 )
@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package event defines structured events, such as alerts of applications,
+// written by API /v1/write/events and mapped onto keyevent points.
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/hashcode"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	SeverityOK       = "ok"
+
+	// dfSource of all events written by API, the same as user events of pythond.
+	dfSource = "user"
+)
+
+var severities = []string{SeverityCritical, SeverityError, SeverityWarning, SeverityInfo, SeverityOK}
+
+// reservedTags can not be set by entities and tags, they are set by the event.
+var reservedTags = map[string]bool{
+	"source": true,
+}
+
+// Event is a structured event.
+type Event struct {
+	// Title of the event, required.
+	Title string `json:"title"`
+
+	// Severity of the event, one of critical/error/warning/info/ok, required.
+	Severity string `json:"severity"`
+
+	// Source is who emitted the event, such as the application name,
+	// used as the measurement name, required.
+	Source string `json:"source"`
+
+	// DedupKey identifies the same event reported repeatedly, events of the
+	// same dedup key are grouped into one issue. Hash of source, title and
+	// entities if not set.
+	DedupKey string `json:"dedup_key,omitempty"`
+
+	Message string `json:"message,omitempty"`
+
+	// Entities related to the event, such as {"host": "web-1", "service": "checkout"}.
+	// They are added as tags, so the event can be found from these entities.
+	Entities map[string]string `json:"entities,omitempty"`
+
+	// Tags are extra tags of the event.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Time of the event in unix nanoseconds, now if not set.
+	Time int64 `json:"time,omitempty"`
+}
+
+// Parse parses a single event or an array of events in JSON.
+func Parse(body []byte) ([]*Event, error) {
+	body = bytes.TrimSpace(body)
+
+	var events []*Event
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, err
+		}
+	} else {
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+
+	for idx, e := range events {
+		if e == nil {
+			return nil, fmt.Errorf("event[%d]: null event", idx)
+		}
+
+		if err := e.Check(); err != nil {
+			return nil, fmt.Errorf("event[%d]: %w", idx, err)
+		}
+	}
+
+	return events, nil
+}
+
+// Check checks required fields of the event, and normalizes severity to lower case.
+func (e *Event) Check() error {
+	if strings.TrimSpace(e.Title) == "" {
+		return fmt.Errorf("title required")
+	}
+
+	if strings.TrimSpace(e.Source) == "" {
+		return fmt.Errorf("source required")
+	}
+
+	sev := strings.ToLower(e.Severity)
+	valid := false
+	for _, x := range severities {
+		if sev == x {
+			valid = true
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid severity %q, expect one of %v", e.Severity, severities)
+	}
+	e.Severity = sev
+
+	for _, kvs := range []map[string]string{e.Entities, e.Tags} {
+		for k := range kvs {
+			if reservedTags[k] || strings.HasPrefix(k, "df_") {
+				return fmt.Errorf("tag %q reserved", k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// EventID returns the dedup key, or hash of source, title and entities if not set.
+func (e *Event) EventID() string {
+	if e.DedupKey != "" {
+		return e.DedupKey
+	}
+
+	keys := make([]string, 0, len(e.Entities))
+	for k := range e.Entities {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := []string{e.Source, "\n", e.Title}
+	for _, k := range keys {
+		ss = append(ss, "\n", k, "=", e.Entities[k])
+	}
+
+	return hashcode.GenStringsHash(ss...)
+}
+
+// Point maps the event onto keyevent point:
+//
+//   - title/severity/message to df_title/df_status/df_message
+//   - dedup key to df_event_id
+//   - entities to tags and df_dimension_tags
+//
+// The event should be checked before.
+func (e *Event) Point(now time.Time, opts ...point.Option) *point.Point {
+	var kvs point.KVs
+
+	for k, v := range e.Tags {
+		kvs = kvs.AddTag([]byte(k), []byte(v))
+	}
+
+	// entities overwrite tags of the same key
+	for k, v := range e.Entities {
+		kvs = kvs.MustAddTag([]byte(k), []byte(v))
+	}
+
+	kvs = kvs.MustAddTag([]byte("source"), []byte(e.Source))
+
+	kvs = kvs.Add([]byte("df_source"), dfSource, false, true)
+	kvs = kvs.Add([]byte("df_status"), e.Severity, false, true)
+	kvs = kvs.Add([]byte("df_event_id"), e.EventID(), false, true)
+	kvs = kvs.Add([]byte("df_title"), e.Title, false, true)
+	kvs = kvs.Add([]byte("df_message"), e.Message, false, true)
+
+	if len(e.Entities) > 0 {
+		if j, err := json.Marshal(e.Entities); err == nil {
+			kvs = kvs.Add([]byte("df_dimension_tags"), string(j), false, true)
+		}
+	}
+
+	ts := now
+	if e.Time > 0 {
+		ts = time.Unix(0, e.Time)
+	}
+
+	return point.NewPointV2([]byte(e.Source), kvs, append([]point.Option{point.WithTime(ts)}, opts...)...)
+}
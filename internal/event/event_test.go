@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package event
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *T.T) {
+	cases := []struct {
+		name string
+		body string
+		n    int
+		fail bool
+	}{
+		{
+			name: "single",
+			body: `{"title":"disk full","severity":"Critical","source":"backup-app"}`,
+			n:    1,
+		},
+		{
+			name: "array",
+			body: ` [{"title":"a","severity":"ok","source":"s"},{"title":"b","severity":"info","source":"s"}]`,
+			n:    2,
+		},
+		{
+			name: "missing-title",
+			body: `{"severity":"ok","source":"s"}`,
+			fail: true,
+		},
+		{
+			name: "missing-source",
+			body: `{"title":"a","severity":"ok"}`,
+			fail: true,
+		},
+		{
+			name: "invalid-severity",
+			body: `{"title":"a","severity":"fatal","source":"s"}`,
+			fail: true,
+		},
+		{
+			name: "reserved-tag",
+			body: `{"title":"a","severity":"ok","source":"s","tags":{"df_status":"ok"}}`,
+			fail: true,
+		},
+		{
+			name: "null-event",
+			body: `[null]`,
+			fail: true,
+		},
+		{
+			name: "invalid-json",
+			body: `{"title":`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			events, err := Parse([]byte(tc.body))
+			if tc.fail {
+				assert.Error(t, err)
+				t.Logf("expected error: %s", err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, events, tc.n)
+		})
+	}
+}
+
+func TestPoint(t *T.T) {
+	e := &Event{
+		Title:    "disk full",
+		Severity: "critical",
+		Source:   "backup-app",
+		Message:  "/data 100% used",
+		Entities: map[string]string{"host": "web-1", "service": "backup"},
+		Tags:     map[string]string{"host": "overwritten", "env": "prod"},
+		Time:     time.Unix(1700000000, 0).UnixNano(),
+	}
+	require.NoError(t, e.Check())
+
+	pt := e.Point(time.Now())
+	assert.Equal(t, "backup-app", string(pt.Name()))
+	assert.Equal(t, int64(1700000000), pt.Time().Unix())
+
+	tags := pt.Tags()
+	assert.Equal(t, []byte("web-1"), tags.Get([]byte("host")).GetD())
+	assert.Equal(t, []byte("prod"), tags.Get([]byte("env")).GetD())
+	assert.Equal(t, []byte("backup-app"), tags.Get([]byte("source")).GetD())
+
+	assert.Equal(t, []byte("critical"), pt.Get([]byte("df_status")))
+	assert.Equal(t, []byte("disk full"), pt.Get([]byte("df_title")))
+	assert.Equal(t, []byte("user"), pt.Get([]byte("df_source")))
+	assert.Equal(t, []byte(`{"host":"web-1","service":"backup"}`), pt.Get([]byte("df_dimension_tags")))
+
+	// event ID hashed from source, title and entities if no dedup key
+	id := string(pt.Get([]byte("df_event_id")).([]byte))
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, e.EventID())
+
+	e.Entities["host"] = "web-2"
+	assert.NotEqual(t, id, e.EventID())
+
+	e.DedupKey = "backup-disk-full"
+	assert.Equal(t, "backup-disk-full", e.EventID())
+}
@@ -0,0 +1,329 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package hostid detects a stable identity of the host datakit running on,
+// even if datakit running in container, or the hostname changed.
+package hostid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+)
+
+const (
+	packageName = "hostid"
+
+	// Sources of host ID.
+	SourceMachineID       = "machine-id"
+	SourceCloudInstanceID = "cloud-instance-id"
+	SourceDMIUUID         = "dmi-uuid"
+	SourceRandom          = "random"
+)
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	// instance-id metadata URLs of cloud providers.
+	cloudMetaURLs = map[string]string{
+		"aliyun":  "http://100.100.100.200/latest/meta-data/instance-id",
+		"aws":     "http://169.254.169.254/latest/meta-data/instance-id",
+		"tencent": "http://metadata.tencentyun.com/latest/meta-data/instance-id",
+	}
+
+	// DMI UUIDs of some VMs are not unique.
+	invalidDMIUUIDs = map[string]bool{
+		"00000000-0000-0000-0000-000000000000": true,
+		"03000200-0400-0500-0006-000700080009": true,
+		"ffffffff-ffff-ffff-ffff-ffffffffffff": true,
+	}
+)
+
+// Identity is the identity of the host.
+type Identity struct {
+	Hostname string `json:"hostname"`
+	HostID   string `json:"host_id"`
+	Source   string `json:"source"` // where the HostID comes from
+}
+
+type Option func(*detector)
+
+// WithRoot set the mount point of host root filesystem, such as /rootfs,
+// used when datakit running in container.
+func WithRoot(root string) Option {
+	return func(d *detector) { d.root = root }
+}
+
+// WithStateFile set the file to persist the identity, the host ID kept
+// across restarts even if no stable source available.
+func WithStateFile(path string) Option {
+	return func(d *detector) { d.stateFile = path }
+}
+
+// WithCloudProvider query instance-id of the cloud provider(aliyun/aws/tencent)
+// as host ID if machine-id not available.
+func WithCloudProvider(provider string) Option {
+	return func(d *detector) { d.cloud = provider }
+}
+
+type detector struct {
+	root      string
+	stateFile string
+	cloud     string
+
+	// mounted root of host PID 1, available if running with host PID namespace.
+	procRoot string
+
+	cloudMetaURLs map[string]string
+	cli           *http.Client
+}
+
+func newDetector(opts ...Option) *detector {
+	d := &detector{
+		procRoot:      "/proc/1/root",
+		cloudMetaURLs: cloudMetaURLs,
+		cli:           &http.Client{Timeout: time.Second},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(d)
+		}
+	}
+
+	if !hostPIDNamespace() {
+		d.procRoot = ""
+	}
+
+	return d
+}
+
+// Hostname returns hostname of the host. Within container, hostname of the
+// container is the pod/container name, so the hostname of the host is read
+// from mounted host root or root of host PID 1.
+func Hostname(opts ...Option) (string, error) {
+	return newDetector(opts...).hostname()
+}
+
+// Detect returns identity of the host. The host ID comes from machine-id,
+// cloud instance-id and DMI product UUID in order, or a random one if none
+// of them available. If state file set, the host ID is persisted and reused.
+func Detect(opts ...Option) (*Identity, error) {
+	l = logger.SLogger(packageName)
+
+	return newDetector(opts...).detect()
+}
+
+func (d *detector) detect() (*Identity, error) {
+	hn, err := d.hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	id := d.load()
+	if id == nil {
+		id = &Identity{}
+		id.HostID, id.Source = d.hostID()
+	}
+
+	id.Hostname = hn
+
+	if err := d.save(id); err != nil {
+		l.Warnf("save host identity to %s: %s, ignored", d.stateFile, err)
+	}
+
+	return id, nil
+}
+
+func (d *detector) hostname() (string, error) {
+	for _, root := range d.hostRoots() {
+		if x := readTrimmed(filepath.Join(root, "etc/hostname")); x != "" {
+			return x, nil
+		}
+	}
+
+	return os.Hostname()
+}
+
+// hostRoots returns mounted root filesystems of the host if running in
+// container, they are empty if not in container.
+func (d *detector) hostRoots() (arr []string) {
+	for _, root := range []string{d.root, d.procRoot} {
+		if root != "" {
+			arr = append(arr, root)
+		}
+	}
+	return
+}
+
+// roots returns root filesystems to search host files in.
+func (d *detector) roots() []string {
+	if arr := d.hostRoots(); len(arr) > 0 {
+		return arr
+	}
+	return []string{"/"}
+}
+
+func (d *detector) hostID() (string, string) {
+	if x := d.machineID(); x != "" {
+		return x, SourceMachineID
+	}
+
+	if x := d.cloudInstanceID(); x != "" {
+		return x, SourceCloudInstanceID
+	}
+
+	if x := d.dmiUUID(); x != "" {
+		return x, SourceDMIUUID
+	}
+
+	return randomID(), SourceRandom
+}
+
+func (d *detector) machineID() string {
+	for _, root := range d.roots() {
+		for _, f := range []string{"etc/machine-id", "var/lib/dbus/machine-id"} {
+			if x := readTrimmed(filepath.Join(root, f)); x != "" {
+				return x
+			}
+		}
+	}
+
+	return ""
+}
+
+func (d *detector) cloudInstanceID() string {
+	if d.cloud == "" {
+		return ""
+	}
+
+	u, ok := d.cloudMetaURLs[d.cloud]
+	if !ok {
+		l.Warnf("unknown cloud provider %q, ignored", d.cloud)
+		return ""
+	}
+
+	resp, err := d.cli.Get(u)
+	if err != nil {
+		l.Warnf("get instance-id of %s: %s", d.cloud, err)
+		return ""
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		l.Warnf("get instance-id of %s: %s(%v)", d.cloud, resp.Status, err)
+		return ""
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+func (d *detector) dmiUUID() string {
+	// sysfs is not namespaced, /sys within container is the host's.
+	for _, root := range append(d.hostRoots(), "/") {
+		x := strings.ToLower(readTrimmed(filepath.Join(root, "sys/class/dmi/id/product_uuid")))
+		if x != "" && !invalidDMIUUIDs[x] {
+			return x
+		}
+	}
+
+	return ""
+}
+
+// load returns the persisted identity, nil if not persisted, or the
+// persisted one conflicts with the host, such as disk cloned to another host.
+func (d *detector) load() *Identity {
+	if d.stateFile == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(d.stateFile)
+	if err != nil {
+		return nil
+	}
+
+	var id Identity
+	if err := json.Unmarshal(data, &id); err != nil || id.HostID == "" {
+		l.Warnf("invalid host identity in %s, ignored", d.stateFile)
+		return nil
+	}
+
+	var cur string
+	switch id.Source {
+	case SourceMachineID:
+		cur = d.machineID()
+	case SourceDMIUUID:
+		cur = d.dmiUUID()
+	default: // cloud instance-id not queried again
+	}
+
+	if cur != "" && cur != id.HostID {
+		l.Infof("host ID changed from %s to %s(%s)", id.HostID, cur, id.Source)
+		return nil
+	}
+
+	return &id
+}
+
+func (d *detector) save(id *Identity) error {
+	if d.stateFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.stateFile), os.ModePerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(d.stateFile, data, 0o600)
+}
+
+func readTrimmed(path string) string {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// hostPIDNamespace checks if running in container with host PID namespace:
+// PID 1 is the init of host, its mount namespace differs from ours.
+func hostPIDNamespace() bool {
+	self, err := os.Readlink("/proc/self/ns/mnt")
+	if err != nil {
+		return false
+	}
+
+	pid1, err := os.Readlink("/proc/1/ns/mnt")
+	if err != nil {
+		return false
+	}
+
+	return self != pid1
+}
+
+func randomID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package hostid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *T.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestDetect(t *T.T) {
+	t.Run("machine-id", func(t *T.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a\n")
+		writeFile(t, filepath.Join(root, "etc/machine-id"), "0123456789abcdef\n")
+
+		d := &detector{root: root, stateFile: filepath.Join(t.TempDir(), ".host_id")}
+
+		id, err := d.detect()
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{Hostname: "host-a", HostID: "0123456789abcdef", Source: SourceMachineID}, id)
+
+		// hostname changed, host ID kept
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-b\n")
+
+		id, err = d.detect()
+		require.NoError(t, err)
+		assert.Equal(t, "host-b", id.Hostname)
+		assert.Equal(t, "0123456789abcdef", id.HostID)
+	})
+
+	t.Run("machine-id-changed", func(t *T.T) {
+		// disk with state file cloned to another host
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a")
+		writeFile(t, filepath.Join(root, "etc/machine-id"), "aaaa")
+
+		d := &detector{root: root, stateFile: filepath.Join(t.TempDir(), ".host_id")}
+
+		_, err := d.detect()
+		require.NoError(t, err)
+
+		writeFile(t, filepath.Join(root, "etc/machine-id"), "bbbb")
+
+		id, err := d.detect()
+		require.NoError(t, err)
+		assert.Equal(t, "bbbb", id.HostID)
+	})
+
+	t.Run("cloud-instance-id", func(t *T.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("i-12345")) //nolint:errcheck,gosec
+		}))
+		defer ts.Close()
+
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a")
+
+		d := &detector{
+			root:          root,
+			cloud:         "aws",
+			cloudMetaURLs: map[string]string{"aws": ts.URL},
+			cli:           ts.Client(),
+		}
+
+		id, err := d.detect()
+		require.NoError(t, err)
+		assert.Equal(t, "i-12345", id.HostID)
+		assert.Equal(t, SourceCloudInstanceID, id.Source)
+	})
+
+	t.Run("dmi-uuid", func(t *T.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a")
+		writeFile(t, filepath.Join(root, "sys/class/dmi/id/product_uuid"), "4C4C4544-0042-3510-8052-B4C04F303232\n")
+
+		d := &detector{root: root}
+		assert.Equal(t, "4c4c4544-0042-3510-8052-b4c04f303232", d.dmiUUID())
+	})
+
+	t.Run("random-persisted", func(t *T.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a")
+
+		d := &detector{root: root, stateFile: filepath.Join(t.TempDir(), "data", ".host_id")}
+
+		// no machine-id/DMI within root, random ID used if DMI of testing host not available.
+		first, err := d.detect()
+		require.NoError(t, err)
+		require.NotEmpty(t, first.HostID)
+
+		second, err := d.detect()
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("invalid-state-file", func(t *T.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "etc/hostname"), "host-a")
+		writeFile(t, filepath.Join(root, "etc/machine-id"), "aaaa")
+
+		state := filepath.Join(t.TempDir(), ".host_id")
+		writeFile(t, state, "not-json")
+
+		id, err := (&detector{root: root, stateFile: state}).detect()
+		require.NoError(t, err)
+		assert.Equal(t, "aaaa", id.HostID)
+	})
+}
+
+func TestHostname(t *T.T) {
+	t.Run("not-in-container", func(t *T.T) {
+		expect, err := os.Hostname()
+		require.NoError(t, err)
+
+		hn, err := (&detector{}).hostname()
+		require.NoError(t, err)
+		assert.Equal(t, expect, hn)
+	})
+
+	t.Run("host-root-without-hostname", func(t *T.T) {
+		expect, err := os.Hostname()
+		require.NoError(t, err)
+
+		hn, err := (&detector{root: t.TempDir()}).hostname()
+		require.NoError(t, err)
+		assert.Equal(t, expect, hn)
+	})
+}
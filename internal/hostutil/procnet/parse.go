@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package procnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseKeyedTable parses the header/value line pairs used by net/snmp and
+// net/netstat, returning the values of the table with the given prefix, such
+// as `Tcp:` or `TcpExt:`.
+func ParseKeyedTable(r io.Reader, prefix string) (map[string]int64, error) {
+	var header []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != prefix {
+			continue
+		}
+
+		if header == nil {
+			header = fields[1:]
+			continue
+		}
+
+		values := fields[1:]
+		if len(values) != len(header) {
+			return nil, fmt.Errorf("%s header/value mismatch: %d != %d", prefix, len(header), len(values))
+		}
+
+		res := make(map[string]int64, len(header))
+		for i, k := range header {
+			v, err := strconv.ParseInt(values[i], 10, 64)
+			if err != nil {
+				continue
+			}
+			res[k] = v
+		}
+		return res, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("table %s not found", prefix)
+}
+
+// ConntrackStat is the conntrack statistics of a network namespace.
+type ConntrackStat struct {
+	Entries      int64
+	Drop         int64
+	EarlyDrop    int64
+	InsertFailed int64
+}
+
+// ParseConntrackStat parses net/stat/nf_conntrack, which contains one line of
+// hex encoded counters per CPU.
+func ParseConntrackStat(r io.Reader) (*ConntrackStat, error) {
+	var (
+		header []string
+		lines  int
+		stat   = &ConntrackStat{}
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if header == nil {
+			header = fields
+			continue
+		}
+
+		if len(fields) != len(header) {
+			return nil, fmt.Errorf("nf_conntrack header/value mismatch: %d != %d", len(header), len(fields))
+		}
+
+		for i, k := range header {
+			v, err := strconv.ParseInt(fields[i], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid nf_conntrack value %q: %w", fields[i], err)
+			}
+
+			switch k {
+			case "entries":
+				// entries is the same table wide count on every CPU line
+				stat.Entries = v
+			case "drop":
+				stat.Drop += v
+			case "early_drop":
+				stat.EarlyDrop += v
+			case "insert_failed":
+				stat.InsertFailed += v
+			}
+		}
+		lines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lines == 0 {
+		return nil, fmt.Errorf("empty nf_conntrack stat")
+	}
+
+	return stat, nil
+}
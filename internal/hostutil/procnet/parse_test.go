@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package procnet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyedTable(t *testing.T) {
+	data := `Ip: Forwarding DefaultTTL InReceives
+Ip: 1 64 100
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 10 20 0 1 5 1000 900 42 0 3 0
+`
+	tcp, err := ParseKeyedTable(strings.NewReader(data), "Tcp:")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), tcp["RetransSegs"])
+	assert.Equal(t, int64(900), tcp["OutSegs"])
+	assert.Equal(t, int64(-1), tcp["MaxConn"])
+
+	_, err = ParseKeyedTable(strings.NewReader(data), "TcpExt:")
+	assert.Error(t, err)
+}
+
+func TestParseConntrackStat(t *testing.T) {
+	data := `entries  searched found new invalid ignore delete delete_list insert insert_failed drop early_drop icmp_error  expect_new expect_create expect_delete search_restart
+000000c8  00000000 00000000 00000000 00000010 00000020 00000000 00000000 00000000 00000001 0000000a 00000002 00000000  00000000 00000000 00000000 00000000
+000000c8  00000000 00000000 00000000 00000010 00000020 00000000 00000000 00000000 00000002 00000005 00000001 00000000  00000000 00000000 00000000 00000000
+`
+	stat, err := ParseConntrackStat(strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, &ConntrackStat{Entries: 200, Drop: 15, EarlyDrop: 3, InsertFailed: 3}, stat)
+
+	_, err = ParseConntrackStat(strings.NewReader(""))
+	assert.Error(t, err)
+
+	_, err = ParseConntrackStat(strings.NewReader("entries  searched found\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package procnet reads network stats of network namespaces under /proc/<pid>.
+package procnet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Root is the proc filesystem, which is mounted to HOST_PROC in containers.
+var Root = func() string {
+	if v, ok := os.LookupEnv("HOST_PROC"); ok && v != "" {
+		return v
+	}
+	return "/proc"
+}()
+
+// Path returns the path of elem under /proc/<pid>.
+func Path(pid int, elem ...string) string {
+	return filepath.Join(append([]string{Root, strconv.Itoa(pid)}, elem...)...)
+}
+
+// ReadFile opens the file elem under /proc/<pid> and parses it.
+func ReadFile(pid int, parse func(io.Reader) error, elem ...string) error {
+	f, err := os.Open(Path(pid, elem...)) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	return parse(f)
+}
+
+// NetnsInode returns the inode of the network namespace the process lives in.
+func NetnsInode(pid int) (uint64, error) {
+	// the link looks like `net:[4026531992]`
+	link, err := os.Readlink(Path(pid, "ns", "net"))
+	if err != nil {
+		return 0, err
+	}
+
+	start, end := strings.IndexByte(link, '['), strings.IndexByte(link, ']')
+	if start < 0 || end <= start {
+		return 0, fmt.Errorf("unexpected netns link: %s", link)
+	}
+
+	return strconv.ParseUint(link[start+1:end], 10, 64)
+}
+
+// NetnsPids walks all processes and returns one process for each network
+// namespace by inode, the process with the smallest pid is chosen, which is
+// usually the pause/sandbox process of the pod.
+func NetnsPids() (map[uint64]int, error) {
+	entries, err := os.ReadDir(Root)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := map[uint64]int{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+
+		inode, err := NetnsInode(pid)
+		if err != nil {
+			continue
+		}
+
+		if v, ok := pids[inode]; !ok || pid < v {
+			pids[inode] = pid
+		}
+	}
+
+	return pids, nil
+}
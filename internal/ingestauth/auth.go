@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package ingestauth validates API keys of requests on ingest endpoints, such
+// as tracing and RUM receivers, so a DataKit exposed on a shared network can
+// reject unauthorized writers.
+package ingestauth
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+)
+
+const keysPullWhat = "ingest_keys=true"
+
+var (
+	keysPullInterval = 30 * time.Second
+	keysPullOnce     sync.Once
+	remoteKeys       = &pulledKeys{}
+)
+
+// KeysPuller pulls API keys configured in center, implemented by dataway.
+type KeysPuller interface {
+	Pull(what string) ([]byte, error)
+}
+
+// Auth is the request-level authentication of an input. Requests carry the
+// key by one of:
+//
+//   - header `Authorization: Bearer <key>`
+//   - header `X-API-Key: <key>`, or `DD-API-KEY: <key>` sent by DDTrace agents
+//   - URL query `api_key=<key>`, for browsers unable to set headers
+//
+// gRPC requests carry the key by metadata authorization or x-api-key.
+type Auth struct {
+	// Keys are static API keys.
+	Keys []string `toml:"keys"`
+
+	// PullKeys also accepts API keys configured in center, pulled from dataway.
+	PullKeys bool `toml:"pull_keys"`
+}
+
+// Enabled returns true if any key configured. Requests are rejected if
+// PullKeys set but no key pulled yet.
+func (a *Auth) Enabled() bool {
+	return a != nil && (len(a.Keys) > 0 || a.PullKeys)
+}
+
+// Setup starts pulling keys from puller if PullKeys set.
+func (a *Auth) Setup(puller KeysPuller) {
+	if a == nil || !a.PullKeys {
+		return
+	}
+
+	StartKeysPull(puller)
+}
+
+// Valid returns true if key is one of the static or pulled keys.
+func (a *Auth) Valid(key string) bool {
+	if !a.Enabled() {
+		return true
+	}
+
+	if key == "" {
+		return false
+	}
+
+	if matchKey(a.Keys, key) {
+		return true
+	}
+
+	return a.PullKeys && remoteKeys.match(key)
+}
+
+// matchKey compares in constant time, so keys can not be guessed by timing.
+func matchKey(keys []string, key string) bool {
+	ok := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// bearer returns key of Authorization value `Bearer <key>`.
+func bearer(v string) string {
+	const prefix = "bearer "
+	if len(v) > len(prefix) && strings.EqualFold(v[:len(prefix)], prefix) {
+		return strings.TrimSpace(v[len(prefix):])
+	}
+	return ""
+}
+
+type pulledKeysBody struct {
+	IngestKeys []string `json:"ingest_keys"`
+	// other fields ignored
+	PullInterval time.Duration `json:"pull_interval"`
+}
+
+type pulledKeys struct {
+	sync.RWMutex
+	keys []string
+	md5  string
+}
+
+func (pk *pulledKeys) match(key string) bool {
+	pk.RLock()
+	defer pk.RUnlock()
+
+	return matchKey(pk.keys, key)
+}
+
+// refresh updates the keys and returns the new pull interval if set.
+func (pk *pulledKeys) refresh(body []byte) (time.Duration, error) {
+	bodymd5 := fmt.Sprintf("%x", md5.Sum(body)) //nolint:gosec
+
+	pk.Lock()
+	defer pk.Unlock()
+
+	if bodymd5 == pk.md5 {
+		return 0, nil
+	}
+
+	var rbody pulledKeysBody
+	if err := json.Unmarshal(body, &rbody); err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, 0, len(rbody.IngestKeys))
+	for _, k := range rbody.IngestKeys {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	pk.keys = keys
+	pk.md5 = bodymd5
+
+	return rbody.PullInterval, nil
+}
+
+// StartKeysPull starts pulling API keys configured in center. It's shared by
+// all inputs and only the first call takes effect. Keys pulled are kept on
+// pull failure.
+func StartKeysPull(puller KeysPuller) {
+	keysPullOnce.Do(func() {
+		log := logger.SLogger("ingestauth")
+
+		g := goroutine.NewGroup(goroutine.Option{Name: "internal_ingestauth"})
+		g.Go(func(ctx context.Context) error {
+			pull := func() time.Duration {
+				body, err := puller.Pull(keysPullWhat)
+				if err != nil {
+					log.Warnf("pull API keys: %s, ignored", err)
+
+					return 0
+				}
+
+				interval, err := remoteKeys.refresh(body)
+				if err != nil {
+					log.Warnf("refresh API keys: %s, ignored", err)
+				}
+
+				return interval
+			}
+
+			tick := time.NewTicker(keysPullInterval)
+			defer tick.Stop()
+
+			interval := keysPullInterval
+			for {
+				if i := pull(); i > 0 && i != interval {
+					log.Infof("set API keys pull interval from %s to %s", interval, i)
+					interval = i
+					tick.Reset(interval)
+				}
+
+				select {
+				case <-tick.C:
+				case <-datakit.Exit.Wait():
+					log.Info("API keys pull exits")
+
+					return nil
+				}
+			}
+		})
+	})
+}
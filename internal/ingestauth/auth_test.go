@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package ingestauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestValid(t *T.T) {
+	defer func(pk *pulledKeys) { remoteKeys = pk }(remoteKeys)
+	remoteKeys = &pulledKeys{}
+
+	var nilAuth *Auth
+	assert.False(t, nilAuth.Enabled())
+	assert.True(t, nilAuth.Valid(""))
+	assert.True(t, (&Auth{}).Valid(""))
+
+	a := &Auth{Keys: []string{"key-1", "key-2"}}
+	assert.True(t, a.Valid("key-2"))
+	assert.False(t, a.Valid("key-3"))
+	assert.False(t, a.Valid(""))
+
+	// pulled keys only accepted if pull_keys set
+	interval, err := remoteKeys.refresh([]byte(`{"ingest_keys":["key-3",""],"pull_interval":60000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, interval)
+	assert.False(t, a.Valid("key-3"))
+
+	a.PullKeys = true
+	assert.True(t, a.Valid("key-3"))
+	assert.True(t, a.Valid("key-1"))
+
+	// keys removed in center
+	_, err = remoteKeys.refresh([]byte(`{"filters":{}}`))
+	require.NoError(t, err)
+	assert.False(t, a.Valid("key-3"))
+
+	// nothing pulled, all rejected
+	assert.False(t, (&Auth{PullKeys: true}).Valid("key-1"))
+
+	_, err = remoteKeys.refresh([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestHTTPWrapper(t *T.T) {
+	log := logger.DefaultSLogger("ingestauth-test")
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) }
+
+	a := &Auth{Keys: []string{"abc"}}
+	h := a.HTTPWrapper(ok, log)
+
+	cases := []struct {
+		name   string
+		url    string
+		header map[string]string
+		code   int
+	}{
+		{name: "bearer", url: "/v1/write/rum", header: map[string]string{"Authorization": "Bearer abc"}, code: http.StatusAccepted},
+		{name: "x-api-key", url: "/v1/write/rum", header: map[string]string{"X-API-Key": "abc"}, code: http.StatusAccepted},
+		{name: "dd-api-key", url: "/v0.4/traces", header: map[string]string{"DD-API-KEY": "abc"}, code: http.StatusAccepted},
+		{name: "query", url: "/v1/write/rum?api_key=abc", code: http.StatusAccepted},
+		{name: "basic", url: "/v1/write/rum", header: map[string]string{"Authorization": "Basic abc"}, code: http.StatusUnauthorized},
+		{name: "invalid", url: "/v1/write/rum", header: map[string]string{"X-API-Key": "abd"}, code: http.StatusUnauthorized},
+		{name: "no-key", url: "/v1/write/rum", code: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			h(w, req)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	t.Run("disabled", func(t *T.T) {
+		w := httptest.NewRecorder()
+		(&Auth{}).HTTPWrapper(ok, log)(w, httptest.NewRequest(http.MethodPost, "/v1/write/rum", nil))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+}
+
+func TestGRPC(t *T.T) {
+	a := &Auth{Keys: []string{"abc"}}
+	assert.Len(t, a.ServerOptions(), 2)
+	assert.Nil(t, (*Auth)(nil).ServerOptions())
+
+	ctx := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+	}
+
+	assert.NoError(t, a.checkGRPC(ctx("authorization", "Bearer abc")))
+	assert.NoError(t, a.checkGRPC(ctx("x-api-key", "abc")))
+
+	err := a.checkGRPC(ctx("x-api-key", "abd"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	err = a.checkGRPC(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package ingestauth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcKey returns the key carried by metadata of the incoming context.
+func grpcKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, v := range md.Get("authorization") {
+		if k := bearer(v); k != "" {
+			return k
+		}
+	}
+
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+func (a *Auth) checkGRPC(ctx context.Context) error {
+	if !a.Valid(grpcKey(ctx)) {
+		return status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	return nil
+}
+
+// ServerOptions returns interceptors rejecting requests without a valid key
+// with code Unauthenticated, nil if auth not enabled.
+func (a *Auth) ServerOptions() []grpc.ServerOption {
+	if !a.Enabled() {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{},
+			info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			if err := a.checkGRPC(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream,
+			info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+		) error {
+			if err := a.checkGRPC(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
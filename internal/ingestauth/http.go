@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package ingestauth
+
+import (
+	"net/http"
+
+	"github.com/GuanceCloud/cliutils/logger"
+)
+
+// httpKey returns the key carried by request.
+func httpKey(req *http.Request) string {
+	if k := bearer(req.Header.Get("Authorization")); k != "" {
+		return k
+	}
+
+	for _, h := range []string{"X-API-Key", "DD-API-KEY"} {
+		if k := req.Header.Get(h); k != "" {
+			return k
+		}
+	}
+
+	return req.URL.Query().Get("api_key")
+}
+
+// HTTPWrapper rejects requests without a valid key with 401, next is
+// returned as is if auth not enabled.
+func (a *Auth) HTTPWrapper(next http.HandlerFunc, log *logger.Logger) http.HandlerFunc {
+	if !a.Enabled() {
+		return next
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if !a.Valid(httpKey(req)) {
+			log.Debugf("### unauthorized request on %s from %s", req.URL.Path, req.RemoteAddr)
+
+			resp.Header().Set("WWW-Authenticate", `Bearer realm="datakit"`)
+			resp.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		next(resp, req)
+	}
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loadshed
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	procSelfCgroup = "/proc/self/cgroup"
+	cgroupRoot     = "/sys/fs/cgroup"
+)
+
+// cgroupPaths returns cgroup paths of datakit by controller, the unified
+// hierarchy of cgroup v2 keyed by "".
+func cgroupPaths() (map[string]string, error) {
+	f, err := os.Open(filepath.Clean(procSelfCgroup))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	paths := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// such as 0::/datakit or 4:cpu,cpuacct:/docker/<id>
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+
+		for _, ctrl := range strings.Split(parts[1], ",") {
+			paths[ctrl] = parts[2]
+		}
+	}
+
+	return paths, scanner.Err()
+}
+
+// readCgroupFile reads file of the cgroup. Within a container without cgroup
+// namespace, the path is of the host and the cgroup of the container is
+// mounted on the root, so the root is tried if the path not found.
+func readCgroupFile(dir, path, file string) (string, bool) {
+	for _, p := range []string{filepath.Join(cgroupRoot, dir, path, file), filepath.Join(cgroupRoot, dir, file)} {
+		if data, err := os.ReadFile(filepath.Clean(p)); err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	return "", false
+}
+
+// readLimits reads CPU(in cores) and memory(in bytes) limits of datakit's
+// cgroup, 0 means no limit.
+func readLimits() (*limits, error) {
+	paths, err := cgroupPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	lim := &limits{}
+
+	if path, ok := paths[""]; ok {
+		// cgroup v2: cpu.max is "<quota> <period>" or "max <period>"
+		if v, ok := readCgroupFile("", path, "cpu.max"); ok {
+			if fields := strings.Fields(v); len(fields) == 2 && fields[0] != "max" {
+				quota, err1 := strconv.ParseFloat(fields[0], 64)
+				period, err2 := strconv.ParseFloat(fields[1], 64)
+				if err1 == nil && err2 == nil && period > 0 {
+					lim.cpu = quota / period
+				}
+			}
+		}
+
+		if v, ok := readCgroupFile("", path, "memory.max"); ok && v != "max" {
+			lim.mem, _ = strconv.ParseUint(v, 10, 64)
+		}
+
+		if lim.cpu > 0 || lim.mem > 0 {
+			return lim, nil
+		}
+	}
+
+	// cgroup v1
+	if path, ok := paths["cpu"]; ok {
+		q, ok1 := readCgroupFile("cpu", path, "cpu.cfs_quota_us")
+		p, ok2 := readCgroupFile("cpu", path, "cpu.cfs_period_us")
+		if ok1 && ok2 {
+			quota, err1 := strconv.ParseFloat(q, 64)
+			period, err2 := strconv.ParseFloat(p, 64)
+			if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+				lim.cpu = quota / period
+			}
+		}
+	}
+
+	if path, ok := paths["memory"]; ok {
+		if v, ok := readCgroupFile("memory", path, "memory.limit_in_bytes"); ok {
+			// no limit is a huge number, such as 9223372036854771712
+			if n, err := strconv.ParseUint(v, 10, 64); err == nil && n < 1<<62 {
+				lim.mem = n
+			}
+		}
+	}
+
+	return lim, nil
+}
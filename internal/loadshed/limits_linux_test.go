@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loadshed
+
+import (
+	"os"
+	"path/filepath"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLimits(t *T.T) {
+	cases := []struct {
+		name   string
+		cgroup string
+		files  map[string]string
+		expect limits
+	}{
+		{
+			name:   "v2",
+			cgroup: "0::/datakit\n",
+			files: map[string]string{
+				"datakit/cpu.max":    "150000 100000",
+				"datakit/memory.max": "1073741824",
+			},
+			expect: limits{cpu: 1.5, mem: 1 << 30},
+		},
+
+		{
+			name:   "v2-no-cpu-limit",
+			cgroup: "0::/datakit\n",
+			files: map[string]string{
+				"datakit/cpu.max":    "max 100000",
+				"datakit/memory.max": "1073741824",
+			},
+			expect: limits{mem: 1 << 30},
+		},
+
+		{
+			name:   "v2-container-root",
+			cgroup: "0::/kubepods/pod-xyz/abc\n",
+			files: map[string]string{
+				"cpu.max":    "200000 100000",
+				"memory.max": "max",
+			},
+			expect: limits{cpu: 2},
+		},
+
+		{
+			name:   "v1",
+			cgroup: "4:cpu,cpuacct:/datakit\n9:memory:/datakit\n1:name=systemd:/\n",
+			files: map[string]string{
+				"cpu/datakit/cpu.cfs_quota_us":         "50000",
+				"cpu/datakit/cpu.cfs_period_us":        "100000",
+				"memory/datakit/memory.limit_in_bytes": "536870912",
+			},
+			expect: limits{cpu: 0.5, mem: 1 << 29},
+		},
+
+		{
+			name:   "v1-no-limit",
+			cgroup: "4:cpu,cpuacct:/\n9:memory:/\n",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1",
+				"cpu/cpu.cfs_period_us":        "100000",
+				"memory/memory.limit_in_bytes": "9223372036854771712",
+			},
+			expect: limits{},
+		},
+	}
+
+	oldProc, oldRoot := procSelfCgroup, cgroupRoot
+	t.Cleanup(func() {
+		procSelfCgroup, cgroupRoot = oldProc, oldRoot
+	})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			dir := t.TempDir()
+			procSelfCgroup = filepath.Join(dir, "cgroup")
+			cgroupRoot = filepath.Join(dir, "fs")
+
+			require.NoError(t, os.WriteFile(procSelfCgroup, []byte(tc.cgroup), 0o600))
+			for name, content := range tc.files {
+				p := filepath.Join(cgroupRoot, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o700))
+				require.NoError(t, os.WriteFile(p, []byte(content+"\n"), 0o600))
+			}
+
+			lim, err := readLimits()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, *lim)
+		})
+	}
+}
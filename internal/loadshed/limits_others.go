@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !linux
+// +build !linux
+
+package loadshed
+
+import "errors"
+
+func readLimits() (*limits, error) {
+	return nil, errors.New("cgroup not supported")
+}
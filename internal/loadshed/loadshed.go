@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package loadshed disables low-priority work of datakit when it approaches
+// its cgroup CPU/memory limits, and restores them after the pressure subsides.
+package loadshed
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	packageName = "loadshed"
+	eventName   = "datakit"
+
+	// Work can be shed.
+	DebugAPI  = "debug_api" // debug APIs, such as pipeline and dial-testing debugging
+	Profiling = "profiling" // forwarding of profiling data
+	Object    = "object"    // object collecting of process and container
+)
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	knownItems = map[string]bool{DebugAPI: true, Profiling: true, Object: true}
+
+	shedding sync.Map // items being shed
+)
+
+// Config configures load shedding in datakit.conf.
+type Config struct {
+	Enable bool `toml:"enable"`
+
+	// Interval of checking usage, only one item shed or restored on each check.
+	Interval string `toml:"interval"`
+
+	// HighWatermark and LowWatermark are percents of CPU or memory usage
+	// against the cgroup limits, items shed above high and restored below low.
+	HighWatermark float64 `toml:"high_watermark"`
+	LowWatermark  float64 `toml:"low_watermark"`
+
+	// Priorities are items shed from first to last, restored in reverse order.
+	Priorities []string `toml:"priorities"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Enable:        false,
+		Interval:      "10s",
+		HighWatermark: 90.0,
+		LowWatermark:  70.0,
+		Priorities:    []string{DebugAPI, Profiling, Object},
+	}
+}
+
+func (c *Config) check() error {
+	if !(0 < c.LowWatermark && c.LowWatermark < c.HighWatermark && c.HighWatermark <= 100) {
+		return fmt.Errorf("invalid watermarks %.1f/%.1f, expect 0 < low < high <= 100",
+			c.LowWatermark, c.HighWatermark)
+	}
+
+	if len(c.Priorities) == 0 {
+		return fmt.Errorf("no priorities set")
+	}
+
+	for _, item := range c.Priorities {
+		if !knownItems[item] {
+			return fmt.Errorf("unknown item %q in priorities", item)
+		}
+	}
+
+	if _, err := time.ParseDuration(c.Interval); err != nil {
+		return fmt.Errorf("invalid interval %q: %w", c.Interval, err)
+	}
+
+	return nil
+}
+
+// Shedding returns true if item is shed, work of the item should be skipped.
+func Shedding(item string) bool {
+	_, ok := shedding.Load(item)
+	return ok
+}
+
+// pressure is the usage against limits, in percent.
+type pressure struct {
+	cpu, mem float64
+}
+
+func (p *pressure) max() float64 {
+	if p.cpu > p.mem {
+		return p.cpu
+	}
+	return p.mem
+}
+
+func (p *pressure) String() string {
+	return fmt.Sprintf("CPU %.1f%%, memory %.1f%% of limits", p.cpu, p.mem)
+}
+
+// shedder sheds one more item on each step above the high watermark, and
+// restores the last shed one on each step below the low watermark.
+type shedder struct {
+	conf  *Config
+	level int // the first level items of priorities are shed
+}
+
+func (s *shedder) step(p *pressure, now time.Time) *dkpt.Point {
+	var (
+		item   string
+		status string
+	)
+
+	switch x := p.max(); {
+	case x >= s.conf.HighWatermark && s.level < len(s.conf.Priorities):
+		item = s.conf.Priorities[s.level]
+		s.level++
+		shedding.Store(item, now)
+		sheddingVec.WithLabelValues(item).Set(1)
+		status = "warning"
+		l.Warnf("%s, shed %s", p, item)
+
+	case x <= s.conf.LowWatermark && s.level > 0:
+		s.level--
+		item = s.conf.Priorities[s.level]
+		shedding.Delete(item)
+		sheddingVec.WithLabelValues(item).Set(0)
+		status = "ok"
+		l.Infof("%s, restore %s", p, item)
+
+	default:
+		return nil
+	}
+
+	levelGauge.Set(float64(s.level))
+
+	pt, err := event(item, status, p, now)
+	if err != nil {
+		l.Errorf("event: %s", err.Error())
+		return nil
+	}
+	return pt
+}
+
+func event(item, status string, p *pressure, now time.Time) (*dkpt.Point, error) {
+	title := fmt.Sprintf("load shedding: %s disabled on %s", item, p)
+	if status == "ok" {
+		title = fmt.Sprintf("load shedding: %s restored on %s", item, p)
+	}
+
+	return dkpt.NewPoint(eventName,
+		map[string]string{"source": packageName, "item": item},
+		map[string]interface{}{
+			"df_source":   "datakit",
+			"df_status":   status,
+			"df_event_id": fmt.Sprintf("%s-%s", packageName, item),
+			"df_title":    title,
+			"df_message":  fmt.Sprintf("%s\nshed items: %v", title, shedItems()),
+		},
+		&dkpt.PointOption{Category: datakit.KeyEvent, Time: now})
+}
+
+func shedItems() []string {
+	var items []string
+	shedding.Range(func(k, _ interface{}) bool {
+		items = append(items, k.(string))
+		return true
+	})
+	sort.Strings(items)
+	return items
+}
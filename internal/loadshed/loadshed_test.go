@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loadshed
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigCheck(t *T.T) {
+	cases := []struct {
+		name string
+		conf func() *Config
+		fail bool
+	}{
+		{
+			name: "default",
+			conf: DefaultConfig,
+		},
+
+		{
+			name: "low-above-high",
+			conf: func() *Config {
+				c := DefaultConfig()
+				c.LowWatermark = 95
+				return c
+			},
+			fail: true,
+		},
+
+		{
+			name: "high-above-100",
+			conf: func() *Config {
+				c := DefaultConfig()
+				c.HighWatermark = 120
+				return c
+			},
+			fail: true,
+		},
+
+		{
+			name: "unknown-item",
+			conf: func() *Config {
+				c := DefaultConfig()
+				c.Priorities = []string{DebugAPI, "logging"}
+				return c
+			},
+			fail: true,
+		},
+
+		{
+			name: "no-priorities",
+			conf: func() *Config {
+				c := DefaultConfig()
+				c.Priorities = nil
+				return c
+			},
+			fail: true,
+		},
+
+		{
+			name: "invalid-interval",
+			conf: func() *Config {
+				c := DefaultConfig()
+				c.Interval = "10"
+				return c
+			},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			err := tc.conf().check()
+			if tc.fail {
+				assert.Error(t, err)
+				t.Logf("expected error: %s", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestShedderStep(t *T.T) {
+	s := &shedder{conf: DefaultConfig()}
+	now := time.Now()
+
+	t.Cleanup(func() {
+		for _, item := range s.conf.Priorities {
+			shedding.Delete(item)
+		}
+	})
+
+	high := &pressure{cpu: 95, mem: 10}
+	mid := &pressure{cpu: 80, mem: 10}
+	low := &pressure{cpu: 20, mem: 60}
+
+	// shed one item on each step from first to last
+	for i, item := range s.conf.Priorities {
+		pt := s.step(high, now)
+		require.NotNil(t, pt)
+		assert.Equal(t, i+1, s.level)
+		assert.True(t, Shedding(item))
+
+		fields, err := pt.Fields()
+		require.NoError(t, err)
+		assert.Equal(t, "warning", fields["df_status"])
+		assert.Equal(t, "loadshed-"+item, fields["df_event_id"])
+		assert.Equal(t, item, pt.Tags()["item"])
+	}
+
+	// all shed
+	assert.Nil(t, s.step(high, now))
+	assert.Len(t, shedItems(), 3)
+
+	// nothing changed between watermarks
+	assert.Nil(t, s.step(mid, now))
+	assert.Equal(t, 3, s.level)
+
+	// restore from last to first
+	for i := len(s.conf.Priorities) - 1; i >= 0; i-- {
+		item := s.conf.Priorities[i]
+		pt := s.step(low, now)
+		require.NotNil(t, pt)
+		assert.False(t, Shedding(item))
+
+		fields, err := pt.Fields()
+		require.NoError(t, err)
+		assert.Equal(t, "ok", fields["df_status"])
+	}
+
+	assert.Nil(t, s.step(low, now))
+	assert.Equal(t, 0, s.level)
+	assert.Empty(t, shedItems())
+}
+
+func TestPressure(t *T.T) {
+	assert.Equal(t, 90.0, (&pressure{cpu: 50, mem: 90}).max())
+	assert.Equal(t, 50.0, (&pressure{cpu: 50, mem: 30}).max())
+	assert.Equal(t, "CPU 50.0%, memory 30.0% of limits", (&pressure{cpu: 50, mem: 30}).String())
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loadshed
+
+import (
+	"github.com/GuanceCloud/cliutils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	levelGauge  prometheus.Gauge
+	sheddingVec *prometheus.GaugeVec
+)
+
+//nolint:gochecknoinits
+func init() {
+	levelGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "loadshed",
+			Name:      "level",
+			Help:      "Count of items shed",
+		},
+	)
+
+	sheddingVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "loadshed",
+			Name:      "shedding",
+			Help:      "Whether the item shed(1) or not(0)",
+		},
+		[]string{
+			"item",
+		},
+	)
+
+	metrics.MustRegister(Metrics()...)
+}
+
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		levelGauge,
+		sheddingVec,
+	}
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loadshed
+
+import (
+	"context"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+// FeedFunc feeds keyevents of shedding and restoring. The io module imports
+// this package, so the feeding is passed in by the caller.
+type FeedFunc func(pts []*dkpt.Point) error
+
+// limits of CPU(in cores) and memory(in bytes), 0 means no limit.
+type limits struct {
+	cpu float64
+	mem uint64
+}
+
+// usage samples CPU and memory used by datakit.
+type usage struct {
+	lastCPU  float64 // CPU seconds
+	lastTime time.Time
+}
+
+func (u *usage) sample(lim *limits, now time.Time) (*pressure, error) {
+	cpuSecs, err := cgroup.MyCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	rss, err := cgroup.MyMemRSS()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pressure{}
+	if lim.cpu > 0 && !u.lastTime.IsZero() {
+		if du := now.Sub(u.lastTime).Seconds(); du > 0 {
+			p.cpu = (cpuSecs - u.lastCPU) / du / lim.cpu * 100
+		}
+	}
+
+	if lim.mem > 0 {
+		p.mem = float64(rss) / float64(lim.mem) * 100
+	}
+
+	u.lastCPU, u.lastTime = cpuSecs, now
+	return p, nil
+}
+
+// Start checks usage of datakit against its cgroup limits periodically, and
+// sheds or restores items by the priorities. Limits are read on each check,
+// for the cgroup set up by datakit itself may not be ready yet.
+func Start(c *Config, feed FeedFunc) {
+	l = logger.SLogger(packageName)
+
+	if c == nil || !c.Enable {
+		return
+	}
+
+	if err := c.check(); err != nil {
+		l.Errorf("load shedding disabled: %s", err.Error())
+		return
+	}
+
+	interval, _ := time.ParseDuration(c.Interval)
+
+	g := datakit.G(packageName)
+	g.Go(func(ctx context.Context) error {
+		s := &shedder{conf: c}
+		u := &usage{}
+
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		warned := false
+		for {
+			select {
+			case <-datakit.Exit.Wait():
+				l.Info("load shedding exit")
+				return nil
+
+			case now := <-tick.C:
+				lim, err := readLimits()
+				if err != nil || (lim.cpu == 0 && lim.mem == 0) {
+					if !warned {
+						l.Warnf("no cgroup limits found(err: %v), load shedding paused", err)
+						warned = true
+					}
+					continue
+				}
+				warned = false
+
+				p, err := u.sample(lim, now)
+				if err != nil {
+					l.Warnf("sample usage: %s, ignored", err.Error())
+					continue
+				}
+
+				l.Debugf("%s", p)
+
+				if pt := s.step(p, now); pt != nil && feed != nil {
+					if err := feed([]*dkpt.Point{pt}); err != nil {
+						l.Errorf("feed: %s", err.Error())
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package migration
+
+// builtinMigrations lists all renames across DataKit versions, add new
+// migration here on renaming any key of input configures or function of
+// pipeline, so old configures keep working after upgraded.
+var builtinMigrations = []*Migration{
+	{
+		Version: "1.5.10",
+		Desc:    "option match of logging inputs renamed to multiline_match",
+		Keys: []*KeyRename{
+			{Input: "logging", From: "match", To: "multiline_match"},
+			{Input: "mysql", Table: "log", From: "match", To: "multiline_match"},
+			{Input: "redis", Table: "log", From: "match", To: "multiline_match"},
+		},
+	},
+}
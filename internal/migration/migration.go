@@ -0,0 +1,266 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package migration upgrades deprecated input configures and pipeline
+// scripts across DataKit versions.
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/version"
+)
+
+const backupSuffix = ".migrate.bak"
+
+var l = logger.DefaultSLogger("migration")
+
+// Migration is a set of renames introduced in Version. It's applied if
+// DataKit upgraded from a version older than Version.
+type Migration struct {
+	Version string
+	Desc    string
+
+	Keys  []*KeyRename
+	Funcs []*FuncRename
+}
+
+// KeyRename renames the TOML key of an input configure.
+type KeyRename struct {
+	Input string // input name, such as mysql
+	Table string // sub-table under [[inputs.<input>]], such as log, empty for the input table itself
+	From  string
+	To    string
+}
+
+func (r *KeyRename) table() string {
+	if r.Table == "" {
+		return "inputs." + r.Input
+	}
+	return "inputs." + r.Input + "." + r.Table
+}
+
+// FuncRename renames the function called in pipeline scripts.
+type FuncRename struct {
+	From string
+	To   string
+}
+
+// Change is a rename applied(or skipped) on the file.
+type Change struct {
+	Version string `json:"version"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Skipped string `json:"skipped,omitempty"` // why the rename not applied
+}
+
+// Report is the result of migrations, saved as JSON.
+type Report struct {
+	From       string    `json:"from_version"`
+	To         string    `json:"to_version"`
+	Time       time.Time `json:"time"`
+	Migrations []string  `json:"migrations"`
+	Changes    []*Change `json:"changes"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+// Applied returns the number of renames applied.
+func (r *Report) Applied() int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Skipped == "" {
+			n++
+		}
+	}
+	return n
+}
+
+type Option struct {
+	ConfdDir    string
+	PipelineDir string
+	ReportPath  string
+
+	Migrations []*Migration // builtin migrations if nil
+}
+
+func (o *Option) setDefaults() {
+	if o.ConfdDir == "" {
+		o.ConfdDir = datakit.ConfdDir
+	}
+
+	if o.PipelineDir == "" {
+		o.PipelineDir = datakit.PipelineDir
+	}
+
+	if o.ReportPath == "" {
+		o.ReportPath = filepath.Join(datakit.DataDir, "migration-report.json")
+	}
+
+	if o.Migrations == nil {
+		o.Migrations = builtinMigrations
+	}
+}
+
+// Run applies migrations introduced after fromVersion to input configures
+// and pipeline scripts. Files changed are backed up with suffix
+// .migrate.bak. The report is written if any migration applied.
+func Run(fromVersion, toVersion string, opt *Option) (*Report, error) {
+	l = logger.SLogger("migration")
+
+	if opt == nil {
+		opt = &Option{}
+	}
+	opt.setDefaults()
+
+	rep := &Report{From: fromVersion, To: toVersion, Time: time.Now()}
+
+	var keys []*versionedKey
+	var funcs []*versionedFunc
+	for _, m := range opt.Migrations {
+		if !needMigrate(fromVersion, m.Version) {
+			continue
+		}
+
+		rep.Migrations = append(rep.Migrations, fmt.Sprintf("%s: %s", m.Version, m.Desc))
+		for _, k := range m.Keys {
+			keys = append(keys, &versionedKey{KeyRename: k, version: m.Version})
+		}
+		for _, f := range m.Funcs {
+			funcs = append(funcs, &versionedFunc{FuncRename: f, version: m.Version})
+		}
+	}
+
+	if len(rep.Migrations) == 0 {
+		return rep, nil
+	}
+
+	if len(keys) > 0 {
+		rep.walk(opt.ConfdDir, ".conf", func(path, text string) (string, []*Change) {
+			return migrateKeys(path, text, keys)
+		})
+	}
+
+	if len(funcs) > 0 {
+		rep.walk(opt.PipelineDir, ".p", func(path, text string) (string, []*Change) {
+			return migrateFuncs(path, text, funcs)
+		})
+	}
+
+	if err := rep.save(opt.ReportPath); err != nil {
+		return rep, err
+	}
+
+	return rep, nil
+}
+
+// needMigrate checks if the migration introduced in ver should be applied
+// on upgrading from version from. All migrations applied if from unknown.
+func needMigrate(from, ver string) bool {
+	fv := &version.VerInfo{VersionString: from}
+	if err := fv.Parse(); err != nil {
+		return true
+	}
+
+	mv := &version.VerInfo{VersionString: ver}
+	if err := mv.Parse(); err != nil {
+		l.Warnf("invalid migration version %q: %s", ver, err)
+		return false
+	}
+
+	return fv.Compare(mv) < 0
+}
+
+func (r *Report) walk(root, ext string, migrate func(path, text string) (string, []*Change)) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ext {
+			return nil
+		}
+
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			r.Errors = append(r.Errors, err.Error())
+			return nil
+		}
+
+		text, changes := migrate(path, string(data))
+		r.Changes = append(r.Changes, changes...)
+
+		if text == string(data) {
+			return nil
+		}
+
+		if err := rewrite(path, data, text); err != nil {
+			r.Errors = append(r.Errors, err.Error())
+		} else {
+			l.Infof("%s migrated", path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+func rewrite(path string, old []byte, text string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+backupSuffix, old, fi.Mode()); err != nil {
+		return fmt.Errorf("backup %s: %w", path, err)
+	}
+
+	return os.WriteFile(path, []byte(text), fi.Mode())
+}
+
+func (r *Report) save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), datakit.ConfPerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, datakit.ConfPerm)
+}
+
+// String summarizes the report.
+func (r *Report) String() string {
+	var arr []string
+	for _, c := range r.Changes {
+		if c.Skipped != "" {
+			arr = append(arr, fmt.Sprintf("  %s:%d: %s -> %s skipped: %s", c.File, c.Line, c.From, c.To, c.Skipped))
+		} else {
+			arr = append(arr, fmt.Sprintf("  %s:%d: %s -> %s", c.File, c.Line, c.From, c.To))
+		}
+	}
+
+	for _, e := range r.Errors {
+		arr = append(arr, "  error: "+e)
+	}
+
+	return fmt.Sprintf("%d renames applied on upgrading from %s:\n%s", r.Applied(), r.From, strings.Join(arr, "\n"))
+}
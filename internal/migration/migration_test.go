@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package migration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeedMigrate(t *T.T) {
+	assert.True(t, needMigrate("1.5.9", "1.5.10"))
+	assert.True(t, needMigrate("1.4.20", "1.5.10"))
+	assert.True(t, needMigrate("", "1.5.10"))
+	assert.False(t, needMigrate("1.5.10", "1.5.10"))
+	assert.False(t, needMigrate("1.6.0", "1.5.10"))
+}
+
+func TestMigrateKeys(t *T.T) {
+	keys := []*versionedKey{
+		{KeyRename: &KeyRename{Input: "logging", From: "match", To: "multiline_match"}, version: "1.5.10"},
+		{KeyRename: &KeyRename{Input: "mysql", Table: "log", From: "match", To: "multiline_match"}, version: "1.5.10"},
+	}
+
+	conf := `[[inputs.logging]]
+  # match = "commented"
+  logfiles = ["/var/log/a.log"]
+  match = '''^\S'''
+
+[[inputs.logging]]
+  match = "^\\d"
+  multiline_match = "^\\S"
+
+[[ inputs . mysql ]]
+  host = "localhost"
+  match = "not under log"
+  sql = '''
+  match = "in string"
+  '''
+
+  [inputs.mysql.log]
+    files = ["/var/log/mysql.log"]
+    match = "^\\d{4}"
+`
+
+	text, changes := migrateKeys("a.conf", conf, keys)
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, 4, changes[0].Line)
+	assert.Empty(t, changes[0].Skipped)
+	assert.Equal(t, 7, changes[1].Line)
+	assert.NotEmpty(t, changes[1].Skipped)
+	assert.Equal(t, 19, changes[2].Line)
+	assert.Equal(t, "inputs.mysql.log.multiline_match", changes[2].To)
+
+	assert.Equal(t, `[[inputs.logging]]
+  # match = "commented"
+  logfiles = ["/var/log/a.log"]
+  multiline_match = '''^\S'''
+
+[[inputs.logging]]
+  match = "^\\d"
+  multiline_match = "^\\S"
+
+[[ inputs . mysql ]]
+  host = "localhost"
+  match = "not under log"
+  sql = '''
+  match = "in string"
+  '''
+
+  [inputs.mysql.log]
+    files = ["/var/log/mysql.log"]
+    multiline_match = "^\\d{4}"
+`, text)
+}
+
+func TestMigrateFuncs(t *T.T) {
+	funcs := []*versionedFunc{
+		{FuncRename: &FuncRename{From: "old_fn", To: "new_fn"}, version: "1.5.10"},
+	}
+
+	script := `# old_fn(_) in comment
+old_fn(_, "old_fn(x)")
+x = old_fn ("a")
+y = a.old_fn(b)
+z = """
+old_fn(multiline)
+"""
+my_old_fn(_)
+old_fn_x(_)
+if true { old_fn(_) }
+`
+
+	text, changes := migrateFuncs("a.p", script, funcs)
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, 2, changes[0].Line)
+	assert.Equal(t, 3, changes[1].Line)
+	assert.Equal(t, 10, changes[2].Line)
+
+	assert.Equal(t, `# old_fn(_) in comment
+new_fn(_, "old_fn(x)")
+x = new_fn ("a")
+y = a.old_fn(b)
+z = """
+old_fn(multiline)
+"""
+my_old_fn(_)
+old_fn_x(_)
+if true { new_fn(_) }
+`, text)
+}
+
+func TestRun(t *T.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+	pldir := filepath.Join(dir, "pipeline")
+	report := filepath.Join(dir, "data", "migration-report.json")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(confd, "log"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(pldir, "logging"), 0o755))
+
+	confPath := filepath.Join(confd, "log", "logging.conf")
+	plPath := filepath.Join(pldir, "logging", "nginx.p")
+	conf := "[[inputs.logging]]\n  match = \"^\\\\S\"\n"
+	require.NoError(t, os.WriteFile(confPath, []byte(conf), 0o600))
+	require.NoError(t, os.WriteFile(plPath, []byte("old_fn(_)\n"), 0o600))
+
+	opt := &Option{
+		ConfdDir:    confd,
+		PipelineDir: pldir,
+		ReportPath:  report,
+		Migrations: []*Migration{
+			{
+				Version: "1.5.10",
+				Desc:    "rename match",
+				Keys:    []*KeyRename{{Input: "logging", From: "match", To: "multiline_match"}},
+			},
+			{
+				Version: "1.6.0",
+				Desc:    "rename old_fn",
+				Funcs:   []*FuncRename{{From: "old_fn", To: "new_fn"}},
+			},
+		},
+	}
+
+	t.Run("up-to-date", func(t *T.T) {
+		rep, err := Run("1.6.0", "1.6.1", opt)
+		require.NoError(t, err)
+		assert.Empty(t, rep.Migrations)
+
+		_, err = os.Stat(report)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("partial", func(t *T.T) {
+		rep, err := Run("1.5.10", "1.6.1", opt)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.6.0: rename old_fn"}, rep.Migrations)
+		assert.Equal(t, 1, rep.Applied())
+
+		data, err := os.ReadFile(plPath)
+		require.NoError(t, err)
+		assert.Equal(t, "new_fn(_)\n", string(data))
+
+		data, err = os.ReadFile(plPath + backupSuffix)
+		require.NoError(t, err)
+		assert.Equal(t, "old_fn(_)\n", string(data))
+
+		data, err = os.ReadFile(confPath)
+		require.NoError(t, err)
+		assert.Equal(t, conf, string(data))
+	})
+
+	t.Run("all", func(t *T.T) {
+		rep, err := Run("1.5.9", "1.6.1", opt)
+		require.NoError(t, err)
+		assert.Len(t, rep.Migrations, 2)
+		assert.Equal(t, 1, rep.Applied()) // pipeline already migrated
+
+		data, err := os.ReadFile(confPath)
+		require.NoError(t, err)
+		assert.Equal(t, "[[inputs.logging]]\n  multiline_match = \"^\\\\S\"\n", string(data))
+
+		data, err = os.ReadFile(report)
+		require.NoError(t, err)
+
+		var saved Report
+		require.NoError(t, json.Unmarshal(data, &saved))
+		assert.Equal(t, "1.5.9", saved.From)
+		require.Len(t, saved.Changes, 1)
+		assert.Equal(t, confPath, saved.Changes[0].File)
+	})
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package migration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	tableRegexp = regexp.MustCompile(`^\s*\[{1,2}\s*([^\[\]]+?)\s*\]{1,2}\s*(#.*)?$`)
+	keyRegexp   = regexp.MustCompile(`^(\s*)([A-Za-z0-9_-]+)(\s*=.*)$`)
+)
+
+type versionedKey struct {
+	*KeyRename
+	version string
+}
+
+type versionedFunc struct {
+	*FuncRename
+	version string
+}
+
+// tomlLine is a line of the TOML text.
+type tomlLine struct {
+	section int    // index of the table the line belongs to
+	key     string // key defined on the line, empty if not a key line
+}
+
+// migrateKeys renames keys line by line, so comments and layout of the
+// configure are kept.
+func migrateKeys(path, text string, keys []*versionedKey) (string, []*Change) {
+	lines := strings.Split(text, "\n")
+	parsed := make([]tomlLine, len(lines))
+
+	var (
+		tables      = []string{""} // root table
+		sectionKeys = []map[string]bool{{}}
+		inMultiline bool
+	)
+
+	for i, line := range lines {
+		// skip content of multiline strings
+		quotes := strings.Count(line, `'''`) + strings.Count(line, `"""`)
+		if inMultiline {
+			parsed[i] = tomlLine{section: len(tables) - 1}
+			if quotes%2 == 1 {
+				inMultiline = false
+			}
+			continue
+		}
+
+		if m := tableRegexp.FindStringSubmatch(line); m != nil {
+			tables = append(tables, normalizeTable(m[1]))
+			sectionKeys = append(sectionKeys, map[string]bool{})
+			parsed[i] = tomlLine{section: len(tables) - 1}
+			continue
+		}
+
+		parsed[i] = tomlLine{section: len(tables) - 1}
+		if m := keyRegexp.FindStringSubmatch(line); m != nil {
+			parsed[i].key = m[2]
+			sectionKeys[len(tables)-1][m[2]] = true
+		}
+
+		if quotes%2 == 1 {
+			inMultiline = true
+		}
+	}
+
+	var changes []*Change
+	for i, pl := range parsed {
+		if pl.key == "" {
+			continue
+		}
+
+		for _, k := range keys {
+			if pl.key != k.From || tables[pl.section] != k.table() {
+				continue
+			}
+
+			c := &Change{
+				Version: k.version,
+				File:    path,
+				Line:    i + 1,
+				From:    k.table() + "." + k.From,
+				To:      k.table() + "." + k.To,
+			}
+
+			if sectionKeys[pl.section][k.To] {
+				c.Skipped = fmt.Sprintf("key %s already set", k.To)
+			} else {
+				m := keyRegexp.FindStringSubmatch(lines[i])
+				lines[i] = m[1] + k.To + m[3]
+			}
+
+			changes = append(changes, c)
+			break
+		}
+	}
+
+	return strings.Join(lines, "\n"), changes
+}
+
+// normalizeTable strips spaces around dots, such as `inputs . mysql`.
+func normalizeTable(s string) string {
+	parts := strings.Split(s, ".")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
+
+// migrateFuncs renames the functions called in the pipeline script, strings
+// and comments untouched.
+func migrateFuncs(path, text string, funcs []*versionedFunc) (string, []*Change) {
+	var (
+		out     strings.Builder
+		changes []*Change
+		line    = 1
+		i       = 0
+	)
+
+	for i < len(text) {
+		c := text[i]
+
+		switch {
+		case c == '\n':
+			line++
+			out.WriteByte(c)
+			i++
+
+		case c == '#': // comment
+			j := strings.IndexByte(text[i:], '\n')
+			if j < 0 {
+				j = len(text) - i
+			}
+			out.WriteString(text[i : i+j])
+			i += j
+
+		case c == '"' || c == '\'' || c == '`':
+			j := skipString(text, i)
+			line += strings.Count(text[i:j], "\n")
+			out.WriteString(text[i:j])
+			i = j
+
+		case isIdentChar(c):
+			j := i
+			for j < len(text) && isIdentChar(text[j]) {
+				j++
+			}
+			ident := text[i:j]
+
+			// called as function: not a member, followed by `(`
+			k := j
+			for k < len(text) && (text[k] == ' ' || text[k] == '\t') {
+				k++
+			}
+
+			if (i == 0 || text[i-1] != '.') && k < len(text) && text[k] == '(' {
+				for _, f := range funcs {
+					if f.From == ident {
+						changes = append(changes, &Change{
+							Version: f.version,
+							File:    path,
+							Line:    line,
+							From:    f.From,
+							To:      f.To,
+						})
+						ident = f.To
+						break
+					}
+				}
+			}
+
+			out.WriteString(ident)
+			i = j
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String(), changes
+}
+
+// skipString returns the end of the string literal started at i.
+func skipString(text string, i int) int {
+	q := text[i : i+1]
+	if strings.HasPrefix(text[i:], q+q+q) {
+		q += q + q
+	}
+
+	j := i + len(q)
+	for j < len(text) {
+		if len(q) == 1 && q != "`" && text[j] == '\\' {
+			j += 2
+			continue
+		}
+
+		if strings.HasPrefix(text[j:], q) {
+			return j + len(q)
+		}
+
+		j++
+	}
+
+	return len(text)
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
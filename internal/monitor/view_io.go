@@ -39,6 +39,7 @@ func (app *monitorAPP) renderIOTable(mfs map[string]*dto.MetricFamily, colArr []
 	chanCap := mfs["datakit_io_chan_capacity"]
 	chanUsage := mfs["datakit_io_chan_usage"]
 	dwPtsTotal := mfs["datakit_io_dataway_point_total"]
+	uploadPaused := mfs["datakit_io_upload_paused"]
 
 	if chanUsage == nil {
 		return
@@ -92,6 +93,21 @@ func (app *monitorAPP) renderIOTable(mfs map[string]*dto.MetricFamily, colArr []
 			// show here, we can see them via /metrics API.
 		}
 
+		paused := false
+		if uploadPaused != nil {
+			if x := metricWithLabel(uploadPaused, cat); x != nil {
+				paused = x.GetGauge().GetValue() > 0
+			}
+		}
+
+		if paused {
+			table.SetCell(row, 3, tview.NewTableCell("paused").
+				SetMaxWidth(app.maxTableWidth).SetTextColor(tcell.ColorRed).SetAlign(tview.AlignRight))
+		} else {
+			table.SetCell(row, 3, tview.NewTableCell("-").
+				SetMaxWidth(app.maxTableWidth).SetAlign(tview.AlignRight))
+		}
+
 		row++
 	}
 }
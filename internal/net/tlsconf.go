@@ -64,6 +64,46 @@ func (c *TLSClientConfig) TLSConfig() (*tls.Config, error) {
 	return tlsConfig, nil
 }
 
+// TLSServerConfig represents the standard server TLS config.
+type TLSServerConfig struct {
+	Cert    string `json:"cert" toml:"cert"`
+	CertKey string `json:"cert_key" toml:"cert_key"`
+
+	// CaCerts used to verify client certificates, mutual TLS required if set.
+	CaCerts []string `json:"ca_certs" toml:"ca_certs"`
+}
+
+// TLSConfig returns a tls.Config, nil without error if TLS is not configured.
+func (c *TLSServerConfig) TLSConfig() (*tls.Config, error) {
+	if c == nil || (c.Cert == "" && c.CertKey == "") {
+		return nil, nil
+	}
+
+	if c.Cert == "" || c.CertKey == "" {
+		return nil, fmt.Errorf("both cert and cert_key required")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if err := loadCertificate(tlsConfig, c.Cert, c.CertKey); err != nil {
+		return nil, err
+	}
+
+	if len(c.CaCerts) != 0 {
+		pool, err := makeCertPool(c.CaCerts)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
 func makeCertPool(certFiles []string) (*x509.CertPool, error) {
 	pool := x509.NewCertPool()
 	for _, certFile := range certFiles {
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package probelimit
+
+import (
+	"github.com/GuanceCloud/cliutils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	waitVec     *prometheus.CounterVec
+	waitSeconds prometheus.Summary
+)
+
+//nolint:gochecknoinits
+func init() {
+	waitVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "probelimit",
+			Name:      "probes_total",
+			Help:      "Probes checked against the outbound probe budget, status ok or skipped",
+		},
+		[]string{
+			"status",
+		},
+	)
+
+	waitSeconds = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Namespace: "datakit",
+			Subsystem: "probelimit",
+			Name:      "wait_seconds",
+			Help:      "Time delayed by the outbound probe budget",
+		},
+	)
+
+	metrics.MustRegister(Metrics()...)
+}
+
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		waitVec,
+		waitSeconds,
+	}
+}
@@ -0,0 +1,391 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package probelimit shares one budget of outbound probe traffic among active
+// check inputs(dialtesting, socket, netscan and so on), so that all probes to
+// the same destination network stay under the configured request rate and
+// bandwidth, and do not trip WAFs or rate limits of the destination.
+package probelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"golang.org/x/time/rate"
+)
+
+const packageName = "probelimit"
+
+// maxNetworks limits destination networks tracked, idle ones are evicted on
+// reaching it, networks beyond it share one budget.
+const maxNetworks = 4096
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	// ErrBudgetExceeded returned if the probe can not get its budget within max_wait.
+	ErrBudgetExceeded = errors.New("outbound probe budget exceeded")
+
+	defaultLimiter *limiter
+	mu             sync.RWMutex
+)
+
+// Config configures the outbound probe budget in datakit.conf.
+type Config struct {
+	Enable bool `toml:"enable"`
+
+	// Rate is probes per second to each destination network, and Burst is
+	// the bucket size, probes beyond it are queued and leak out at Rate.
+	Rate  float64 `toml:"rate"`
+	Burst int     `toml:"burst"`
+
+	// Bandwidth is bytes per second to each destination network, 0 for unlimited.
+	Bandwidth int `toml:"bandwidth"`
+
+	// IP destinations are grouped into networks by the prefix lengths,
+	// domain names are grouped by themselves.
+	IPv4Prefix int `toml:"ipv4_prefix"`
+	IPv6Prefix int `toml:"ipv6_prefix"`
+
+	// MaxWait is the max time a probe waits for its budget, skipped beyond it.
+	MaxWait string `toml:"max_wait"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Enable:     false,
+		Rate:       20,
+		Burst:      20,
+		Bandwidth:  0,
+		IPv4Prefix: 24,
+		IPv6Prefix: 64,
+		MaxWait:    "10s",
+	}
+}
+
+func (c *Config) check() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("invalid rate %f, expect > 0", c.Rate)
+	}
+
+	if c.Bandwidth < 0 {
+		return fmt.Errorf("invalid bandwidth %d, expect >= 0", c.Bandwidth)
+	}
+
+	if c.IPv4Prefix < 0 || c.IPv4Prefix > 32 || c.IPv6Prefix < 0 || c.IPv6Prefix > 128 {
+		return fmt.Errorf("invalid prefix /%d or /%d", c.IPv4Prefix, c.IPv6Prefix)
+	}
+
+	if _, err := time.ParseDuration(c.MaxWait); err != nil {
+		return fmt.Errorf("invalid max_wait %q: %w", c.MaxWait, err)
+	}
+
+	return nil
+}
+
+// Setup enables the budget, all probes are unlimited if c is nil or disabled.
+func Setup(c *Config) error {
+	l = logger.SLogger(packageName)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil || !c.Enable {
+		defaultLimiter = nil
+		return nil
+	}
+
+	if err := c.check(); err != nil {
+		defaultLimiter = nil
+		return err
+	}
+
+	defaultLimiter = newLimiter(c)
+	l.Infof("outbound probe budget enabled: %.1f probes/s, %d bytes/s per network", c.Rate, c.Bandwidth)
+
+	return nil
+}
+
+func current() *limiter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLimiter
+}
+
+// Wait blocks until a probe to dest is allowed, dest may be an IP, domain
+// name, host:port or URL. It returns ErrBudgetExceeded if the budget is not
+// available within max_wait.
+func Wait(ctx context.Context, dest string) error {
+	if lmt := current(); lmt != nil {
+		return lmt.wait(ctx, dest)
+	}
+	return nil
+}
+
+// Consume charges n bytes transferred to dest afterwards, following probes
+// to the network are delayed until the bytes paid off.
+func Consume(dest string, n int) {
+	if lmt := current(); lmt != nil {
+		lmt.consume(dest, n)
+	}
+}
+
+// DialFunc is the signature of net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// WrapDial returns dial waiting for the budget before dialing, and bytes of
+// the connection are limited by the bandwidth.
+func WrapDial(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		lmt := current()
+		if lmt == nil {
+			return dial(ctx, network, address)
+		}
+
+		if err := lmt.wait(ctx, address); err != nil {
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, address)
+		if err != nil || lmt.bandwidth == 0 {
+			return conn, err
+		}
+
+		return &limitedConn{Conn: conn, lmt: lmt, network: lmt.network(address)}, nil
+	}
+}
+
+// Network returns the destination network of dest, probes to the same
+// network share one budget.
+func Network(dest string) string {
+	if lmt := current(); lmt != nil {
+		return lmt.network(dest)
+	}
+	return host(dest)
+}
+
+// host returns host of dest, scheme, user info, port and path stripped.
+func host(dest string) string {
+	h := dest
+	if i := strings.Index(h, "://"); i >= 0 {
+		h = h[i+3:]
+	}
+	if i := strings.IndexAny(h, "/?#"); i >= 0 {
+		h = h[:i]
+	}
+	if i := strings.LastIndex(h, "@"); i >= 0 {
+		h = h[i+1:]
+	}
+
+	if x, _, err := net.SplitHostPort(h); err == nil {
+		h = x
+	}
+
+	return strings.ToLower(strings.Trim(h, "[]"))
+}
+
+type bucket struct {
+	probes *rate.Limiter
+	bytes  *rate.Limiter
+}
+
+type limiter struct {
+	conf      *Config
+	maxWait   time.Duration
+	bandwidth int
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	overflow *bucket
+}
+
+func newLimiter(c *Config) *limiter {
+	maxWait, _ := time.ParseDuration(c.MaxWait)
+
+	lmt := &limiter{
+		conf:      c,
+		maxWait:   maxWait,
+		bandwidth: c.Bandwidth,
+		buckets:   map[string]*bucket{},
+	}
+	lmt.overflow = lmt.newBucket()
+
+	return lmt
+}
+
+func (lmt *limiter) newBucket() *bucket {
+	burst := lmt.conf.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	b := &bucket{probes: rate.NewLimiter(rate.Limit(lmt.conf.Rate), burst)}
+	if lmt.bandwidth > 0 {
+		b.bytes = rate.NewLimiter(rate.Limit(lmt.bandwidth), lmt.bandwidth)
+	}
+	return b
+}
+
+func (lmt *limiter) network(dest string) string {
+	h := host(dest)
+
+	ip := net.ParseIP(h)
+	if ip == nil {
+		return h
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		n := &net.IPNet{IP: v4.Mask(net.CIDRMask(lmt.conf.IPv4Prefix, 32)), Mask: net.CIDRMask(lmt.conf.IPv4Prefix, 32)}
+		return n.String()
+	}
+
+	n := &net.IPNet{IP: ip.Mask(net.CIDRMask(lmt.conf.IPv6Prefix, 128)), Mask: net.CIDRMask(lmt.conf.IPv6Prefix, 128)}
+	return n.String()
+}
+
+func (lmt *limiter) bucket(network string) *bucket {
+	lmt.mu.Lock()
+	defer lmt.mu.Unlock()
+
+	if b, ok := lmt.buckets[network]; ok {
+		return b
+	}
+
+	if len(lmt.buckets) >= maxNetworks {
+		lmt.evictIdle()
+	}
+
+	if len(lmt.buckets) >= maxNetworks {
+		return lmt.overflow
+	}
+
+	b := lmt.newBucket()
+	lmt.buckets[network] = b
+	return b
+}
+
+// evictIdle removes buckets full of tokens, which are the same as new ones.
+func (lmt *limiter) evictIdle() {
+	now := time.Now()
+	for k, b := range lmt.buckets {
+		if b.probes.TokensAt(now) < float64(b.probes.Burst()) {
+			continue
+		}
+
+		if b.bytes != nil && b.bytes.TokensAt(now) < float64(b.bytes.Burst()) {
+			continue
+		}
+
+		delete(lmt.buckets, k)
+	}
+}
+
+func (lmt *limiter) wait(ctx context.Context, dest string) error {
+	network := lmt.network(dest)
+	b := lmt.bucket(network)
+
+	if err := lmt.waitN(ctx, b.probes, 1); err != nil {
+		waitVec.WithLabelValues("skipped").Inc()
+		l.Debugf("probe to %s(%s): %s", dest, network, err)
+		return fmt.Errorf("%w: %s", ErrBudgetExceeded, network)
+	}
+
+	waitVec.WithLabelValues("ok").Inc()
+	return nil
+}
+
+// waitN waits n tokens of limiter, at most max_wait.
+func (lmt *limiter) waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	r := limiter.ReserveN(time.Now(), n)
+	if !r.OK() {
+		return fmt.Errorf("%d exceeds burst %d", n, limiter.Burst())
+	}
+
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+
+	if delay > lmt.maxWait {
+		r.Cancel()
+		return fmt.Errorf("wait %s exceeds max_wait %s", delay, lmt.maxWait)
+	}
+
+	waitSeconds.Observe(delay.Seconds())
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+func (lmt *limiter) consume(dest string, n int) {
+	if lmt.bandwidth == 0 || n <= 0 {
+		return
+	}
+
+	b := lmt.bucket(lmt.network(dest))
+	for n > 0 {
+		x := n
+		if x > lmt.bandwidth {
+			x = lmt.bandwidth
+		}
+		b.bytes.ReserveN(time.Now(), x) // paid by following probes
+		n -= x
+	}
+}
+
+// waitBytes waits budget of n bytes to the network, in chunks of bandwidth.
+func (lmt *limiter) waitBytes(network string, n int) error {
+	b := lmt.bucket(network)
+	for n > 0 {
+		x := n
+		if x > lmt.bandwidth {
+			x = lmt.bandwidth
+		}
+
+		if err := lmt.waitN(context.Background(), b.bytes, x); err != nil {
+			return fmt.Errorf("%w: %s", ErrBudgetExceeded, network)
+		}
+		n -= x
+	}
+	return nil
+}
+
+// limitedConn limits bytes written and read by the bandwidth.
+type limitedConn struct {
+	net.Conn
+	lmt     *limiter
+	network string
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	if err := c.lmt.waitBytes(c.network, len(p)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if e := c.lmt.waitBytes(c.network, n); e != nil && err == nil {
+			err = e
+		}
+	}
+	return n, err
+}
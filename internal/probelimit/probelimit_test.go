@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package probelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *T.T) {
+	t.Cleanup(func() { _ = Setup(nil) })
+
+	require.NoError(t, Setup(DefaultConfig()))
+	assert.Nil(t, current())
+
+	c := DefaultConfig()
+	c.Enable = true
+	require.NoError(t, Setup(c))
+	assert.NotNil(t, current())
+
+	for _, f := range []func(c *Config){
+		func(c *Config) { c.Rate = 0 },
+		func(c *Config) { c.Bandwidth = -1 },
+		func(c *Config) { c.IPv4Prefix = 33 },
+		func(c *Config) { c.MaxWait = "10" },
+	} {
+		c := DefaultConfig()
+		c.Enable = true
+		f(c)
+		assert.Error(t, Setup(c))
+		assert.Nil(t, current())
+	}
+}
+
+func TestNetwork(t *T.T) {
+	lmt := newLimiter(DefaultConfig())
+
+	cases := map[string]string{
+		"10.1.2.3":                       "10.1.2.0/24",
+		"10.1.2.200:443":                 "10.1.2.0/24",
+		"http://user@10.1.2.3:8080/path": "10.1.2.0/24",
+		"[2001:db8::1]:80":               "2001:db8::/64",
+		"https://Example.COM/a?b=c":      "example.com",
+		"wss://example.com:9443":         "example.com",
+		"example.com":                    "example.com",
+	}
+
+	for dest, expect := range cases {
+		assert.Equal(t, expect, lmt.network(dest), dest)
+	}
+
+	assert.Equal(t, "example.com", Network("http://example.com:80"))
+}
+
+func TestWait(t *T.T) {
+	c := DefaultConfig()
+	c.Rate = 1
+	c.Burst = 2
+	c.MaxWait = "100ms"
+	lmt := newLimiter(c)
+
+	ctx := context.Background()
+	require.NoError(t, lmt.wait(ctx, "10.0.0.1"))
+	require.NoError(t, lmt.wait(ctx, "10.0.0.2"))
+
+	// the /24 exhausted, next probe needs 1s, beyond max_wait
+	err := lmt.wait(ctx, "10.0.0.3:22")
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	// other networks not affected
+	assert.NoError(t, lmt.wait(ctx, "10.0.1.1"))
+
+	// queued within max_wait
+	c.Rate = 20
+	c.Burst = 1
+	c.MaxWait = "1s"
+	lmt = newLimiter(c)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, lmt.wait(ctx, "example.com"))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	// canceled while queued
+	c.Rate = 0.1
+	c.MaxWait = "1m"
+	lmt = newLimiter(c)
+	require.NoError(t, lmt.wait(ctx, "example.com"))
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, lmt.wait(ctx, "example.com"))
+}
+
+func TestConsume(t *T.T) {
+	c := DefaultConfig()
+	c.Bandwidth = 1000
+	c.MaxWait = "100ms"
+	lmt := newLimiter(c)
+
+	lmt.consume("10.0.0.1", 5000)
+
+	// 5KB at 1KB/s paid by following bytes to the network
+	assert.Error(t, lmt.waitBytes(lmt.network("10.0.0.2"), 10))
+	assert.NoError(t, lmt.waitBytes(lmt.network("10.0.1.2"), 10))
+}
+
+func TestEvict(t *T.T) {
+	lmt := newLimiter(DefaultConfig())
+	for i := 0; i < maxNetworks; i++ {
+		lmt.buckets[fmt.Sprintf("n-%d", i)] = lmt.newBucket()
+	}
+
+	busy := lmt.buckets["n-0"]
+	require.True(t, busy.probes.Allow())
+
+	// idle buckets evicted, the busy one kept
+	b := lmt.bucket("new")
+	assert.NotSame(t, lmt.overflow, b)
+	assert.Len(t, lmt.buckets, 2)
+	assert.Same(t, busy, lmt.buckets["n-0"])
+}
+
+func TestWrapDial(t *T.T) {
+	t.Cleanup(func() { _ = Setup(nil) })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close() //nolint:errcheck
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("hello"))
+			_ = conn.Close()
+		}
+	}()
+
+	var d net.Dialer
+	dial := WrapDial(d.DialContext)
+
+	// unlimited
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_, ok := conn.(*limitedConn)
+	assert.False(t, ok)
+	_ = conn.Close()
+
+	c := DefaultConfig()
+	c.Enable = true
+	c.Rate = 0.1
+	c.Burst = 1
+	c.Bandwidth = 1024
+	c.MaxWait = "10ms"
+	require.NoError(t, Setup(c))
+
+	conn, err = dial(context.Background(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_, ok = conn.(*limitedConn)
+	assert.True(t, ok)
+
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+	_ = conn.Close()
+
+	_, err = dial(context.Background(), "tcp", ln.Addr().String())
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+}
@@ -90,6 +90,10 @@ type Option struct {
 	TLSOpen bool   `toml:"tls_open"`
 	UDSPath string `toml:"uds_path"`
 	Disable bool   `toml:"disble"`
+
+	// ScrapeProtobuf prefers the protobuf exposition format, so native
+	// histograms and exemplars are collected if exposed.
+	ScrapeProtobuf bool `toml:"scrape_protobuf"`
 }
 
 const defaultInterval = 30 * time.Second
@@ -232,6 +236,9 @@ func (p *Prom) GetReq(url string) (*http.Request, error) {
 	} else {
 		req, err = http.NewRequest("GET", url, nil)
 	}
+	if err == nil && p.opt.ScrapeProtobuf {
+		req.Header.Set("Accept", acceptProtobuf)
+	}
 	for k, v := range p.opt.HTTPHeaders {
 		req.Header.Set(k, v)
 	}
@@ -262,6 +269,11 @@ func (p *Prom) CollectFromHTTP(u string) ([]*point.Point, error) {
 		}
 	}
 	defer resp.Body.Close() //nolint:errcheck
+
+	if expfmt.ResponseFormat(resp.Header) == expfmt.FmtProtoDelim {
+		return p.proto2Metrics(resp.Body, u)
+	}
+
 	pts, err := p.text2Metrics(resp.Body, u)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package prom
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"google.golang.org/protobuf/proto"
+)
+
+// acceptProtobuf prefers the delimited protobuf exposition format, which
+// carries native histograms and exemplars, and falls back to text format.
+const acceptProtobuf = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,` +
+	`text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
+// exemplarTagKeys maps exemplar labels of trace ID and span ID used by
+// different SDKs to the tag keys used by tracing.
+var exemplarTagKeys = map[string]string{
+	"trace_id": "trace_id",
+	"traceID":  "trace_id",
+	"traceId":  "trace_id",
+	"span_id":  "span_id",
+	"spanID":   "span_id",
+	"spanId":   "span_id",
+}
+
+// proto2Metrics converts delimited protobuf metric families to line protocol points.
+func (p *Prom) proto2Metrics(in io.Reader, u string) ([]*point.Point, error) {
+	metricFamilies := map[string]*dto.MetricFamily{}
+
+	dec := expfmt.NewDecoder(in, expfmt.FmtProtoDelim)
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if x, ok := metricFamilies[mf.GetName()]; ok {
+			x.Metric = append(x.Metric, mf.GetMetric()...)
+		} else {
+			metricFamilies[mf.GetName()] = mf
+		}
+	}
+
+	return p.metricFamilies2Points(metricFamilies, u)
+}
+
+// exemplarPoint makes a point of the exemplar with field <fieldName>_exemplar,
+// labels of the exemplar(such as trace_id) are added to tags, so the metric
+// can be correlated to the trace.
+func (p *Prom) exemplarPoint(e *dto.Exemplar,
+	measurementName, fieldName string,
+	tags map[string]string,
+	u string,
+) (*point.Point, error) {
+	exemplarTags := make(map[string]string, len(tags)+len(e.GetLabel()))
+	for k, v := range tags {
+		exemplarTags[k] = v
+	}
+
+	for _, lab := range e.GetLabel() {
+		if k, ok := exemplarTagKeys[lab.GetName()]; ok {
+			exemplarTags[k] = lab.GetValue()
+		} else {
+			exemplarTags[lab.GetName()] = lab.GetValue()
+		}
+	}
+
+	fields := map[string]interface{}{
+		fieldName + "_exemplar": e.GetValue(),
+	}
+	if p.opt.AsLogging != nil && p.opt.AsLogging.Enable {
+		fields["status"] = statusInfo
+	}
+
+	pointOpt := *p.opt.pointOpt
+	if shouldDisableGlobalHostTag(u) {
+		pointOpt.DisableGlobalTags = true
+	}
+	if ts := e.GetTimestamp(); ts != nil {
+		pointOpt.Time = time.Unix(ts.GetSeconds(), int64(ts.GetNanos()))
+	}
+
+	return point.NewPoint(measurementName, exemplarTags, fields, &pointOpt)
+}
+
+// isNativeHistogram checks if the histogram is a native(sparse) histogram.
+func isNativeHistogram(h *dto.Histogram) bool {
+	return h.GetZeroThreshold() > 0 ||
+		h.GetZeroCount() > 0 ||
+		len(h.GetPositiveSpan()) > 0 ||
+		len(h.GetNegativeSpan()) > 0
+}
+
+type nativeBucket struct {
+	index int32
+	count uint64
+}
+
+// spanBuckets expands spans and deltas of native histogram to buckets
+// with absolute counts, ordered by bucket index.
+func spanBuckets(spans []*dto.BucketSpan, deltas []int64) []nativeBucket {
+	var (
+		buckets []nativeBucket
+		idx     int32
+		count   int64
+		n       int
+	)
+
+	for _, s := range spans {
+		idx += s.GetOffset()
+		for j := uint32(0); j < s.GetLength() && n < len(deltas); j++ {
+			count += deltas[n]
+			n++
+
+			if count > 0 {
+				buckets = append(buckets, nativeBucket{index: idx, count: uint64(count)})
+			}
+			idx++
+		}
+	}
+
+	return buckets
+}
+
+// nativeBucketBound returns the upper bound of the positive bucket with index
+// idx, i.e. base^idx where base is 2^(2^-schema).
+func nativeBucketBound(schema, idx int32) float64 {
+	return math.Exp2(float64(idx) * math.Exp2(-float64(schema)))
+}
+
+// nativeBuckets converts the native histogram to classic cumulative buckets,
+// so they are queried the same way as classic histograms with tag le. Only
+// integer histograms are converted, float histograms are not exposed by
+// client libraries.
+func nativeBuckets(h *dto.Histogram) []*dto.Bucket {
+	var (
+		buckets []*dto.Bucket
+		cum     uint64
+		schema  = h.GetSchema()
+	)
+
+	// Negative bucket with index i covers [-base^i, -base^(i-1)), the most
+	// negative one comes first.
+	neg := spanBuckets(h.GetNegativeSpan(), h.GetNegativeDelta())
+	for i := len(neg) - 1; i >= 0; i-- {
+		cum += neg[i].count
+		buckets = append(buckets, &dto.Bucket{
+			UpperBound:      proto.Float64(-nativeBucketBound(schema, neg[i].index-1)),
+			CumulativeCount: proto.Uint64(cum),
+		})
+	}
+
+	if h.GetZeroThreshold() > 0 || h.GetZeroCount() > 0 {
+		cum += h.GetZeroCount()
+		buckets = append(buckets, &dto.Bucket{
+			UpperBound:      proto.Float64(h.GetZeroThreshold()),
+			CumulativeCount: proto.Uint64(cum),
+		})
+	}
+
+	for _, b := range spanBuckets(h.GetPositiveSpan(), h.GetPositiveDelta()) {
+		cum += b.count
+		buckets = append(buckets, &dto.Bucket{
+			UpperBound:      proto.Float64(nativeBucketBound(schema, b.index)),
+			CumulativeCount: proto.Uint64(cum),
+		})
+	}
+
+	return append(buckets, &dto.Bucket{
+		UpperBound:      proto.Float64(math.Inf(1)),
+		CumulativeCount: proto.Uint64(h.GetSampleCount()),
+	})
+}
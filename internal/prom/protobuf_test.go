@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package prom
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNativeBuckets(t *testing.T) {
+	h := &dto.Histogram{
+		SampleCount:   proto.Uint64(6),
+		Schema:        proto.Int32(0), // base 2
+		ZeroThreshold: proto.Float64(0.001),
+		ZeroCount:     proto.Uint64(1),
+		NegativeSpan:  []*dto.BucketSpan{{Offset: proto.Int32(1), Length: proto.Uint32(1)}},
+		NegativeDelta: []int64{1},
+		PositiveSpan: []*dto.BucketSpan{
+			{Offset: proto.Int32(0), Length: proto.Uint32(2)},
+			{Offset: proto.Int32(1), Length: proto.Uint32(1)},
+		},
+		PositiveDelta: []int64{2, -1, -1}, // counts 2, 1, 0
+	}
+
+	require.True(t, isNativeHistogram(h))
+
+	var (
+		bounds []float64
+		counts []uint64
+	)
+	for _, b := range nativeBuckets(h) {
+		bounds = append(bounds, b.GetUpperBound())
+		counts = append(counts, b.GetCumulativeCount())
+	}
+
+	assert.Equal(t, []float64{-1, 0.001, 1, 2, math.Inf(1)}, bounds)
+	assert.Equal(t, []uint64{1, 2, 4, 5, 6}, counts)
+
+	assert.False(t, isNativeHistogram(&dto.Histogram{
+		Bucket: []*dto.Bucket{{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(1)}},
+	}))
+}
+
+func TestCollectProtobuf(t *testing.T) {
+	exemplarTime := time.Unix(1700000000, 0)
+
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("http_requests_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{{Name: proto.String("code"), Value: proto.String("200")}},
+				Counter: &dto.Counter{
+					Value: proto.Float64(10),
+					Exemplar: &dto.Exemplar{
+						Label: []*dto.LabelPair{
+							{Name: proto.String("traceID"), Value: proto.String("abc")},
+							{Name: proto.String("span_id"), Value: proto.String("123")},
+						},
+						Value:     proto.Float64(1),
+						Timestamp: timestamppb.New(exemplarTime),
+					},
+				},
+			}},
+		},
+		{
+			Name: proto.String("http_latency_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Histogram: &dto.Histogram{
+					SampleCount:   proto.Uint64(3),
+					SampleSum:     proto.Float64(2.5),
+					Schema:        proto.Int32(0),
+					ZeroThreshold: proto.Float64(0.001),
+					PositiveSpan:  []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(2)}},
+					PositiveDelta: []int64{1, 1}, // counts 1, 2
+				},
+			}},
+		},
+	}
+
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+
+		format := expfmt.Negotiate(r.Header)
+		w.Header().Set("Content-Type", string(format))
+		enc := expfmt.NewEncoder(w, format)
+		for _, mf := range families {
+			if err := enc.Encode(mf); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewProm(&Option{
+		URL:                srv.URL,
+		ScrapeProtobuf:     true,
+		DisableHostTag:     true,
+		DisableInstanceTag: true,
+	})
+	require.NoError(t, err)
+
+	pts, err := p.CollectFromHTTP(srv.URL)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(accept, "application/vnd.google.protobuf"))
+
+	var (
+		exemplars int
+		buckets   = map[string]interface{}{}
+	)
+
+	for _, pt := range pts {
+		fields, err := pt.Fields()
+		require.NoError(t, err)
+		tags := pt.Tags()
+
+		if v, ok := fields["requests_total_exemplar"]; ok {
+			exemplars++
+			assert.Equal(t, 1.0, v)
+			assert.Equal(t, "abc", tags["trace_id"])
+			assert.Equal(t, "123", tags["span_id"])
+			assert.Equal(t, "200", tags["code"])
+			assert.Equal(t, exemplarTime.UnixNano(), pt.Time().UnixNano())
+		}
+
+		if v, ok := fields["latency_seconds_bucket"]; ok {
+			buckets[tags["le"]] = v
+		}
+	}
+
+	assert.Equal(t, 1, exemplars)
+	assert.Equal(t, map[string]interface{}{
+		"0.001": int64(0),
+		"1":     int64(1),
+		"2":     int64(3),
+		"+Inf":  int64(3),
+	}, buckets)
+
+	// text format if not preferred
+	p, err = NewProm(&Option{URL: srv.URL})
+	require.NoError(t, err)
+
+	pts, err = p.CollectFromHTTP(srv.URL)
+	require.NoError(t, err)
+	assert.NotEmpty(t, pts)
+	assert.False(t, strings.HasPrefix(accept, "application/vnd.google.protobuf"))
+}
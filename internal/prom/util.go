@@ -386,12 +386,18 @@ func newTokenIterator(text []byte, start int) tokenIterator {
 }
 
 // doText2Metrics converts raw prometheus metric text to line protocol point.
-func (p *Prom) doText2Metrics(in io.Reader, u string) (pts []*point.Point, lastErr error) {
+func (p *Prom) doText2Metrics(in io.Reader, u string) ([]*point.Point, error) {
 	metricFamilies, err := p.parser.TextToMetricFamilies(in)
 	if err != nil {
 		return nil, err
 	}
 
+	return p.metricFamilies2Points(metricFamilies, u)
+}
+
+// metricFamilies2Points converts metric families, parsed from text or
+// protobuf, to line protocol points.
+func (p *Prom) metricFamilies2Points(metricFamilies map[string]*dto.MetricFamily, u string) (pts []*point.Point, lastErr error) {
 	filteredMetricFamilies := p.filterMetricFamilies(metricFamilies)
 
 	for name, value := range filteredMetricFamilies {
@@ -424,6 +430,14 @@ func (p *Prom) doText2Metrics(in io.Reader, u string) (pts []*point.Point, lastE
 					} else {
 						pts = append(pts, pt)
 					}
+
+					if e := m.GetCounter().GetExemplar(); e != nil {
+						if pt, err := p.exemplarPoint(e, measurementName, fieldName, tags, u); err != nil {
+							lastErr = err
+						} else {
+							pts = append(pts, pt)
+						}
+					}
 				}
 			}
 
@@ -502,7 +516,13 @@ func (p *Prom) doText2Metrics(in io.Reader, u string) (pts []*point.Point, lastE
 					}
 				}
 
-				for _, b := range m.GetHistogram().GetBucket() {
+				// Classic buckets preferred if both classic and native buckets exposed.
+				buckets := m.GetHistogram().GetBucket()
+				if len(buckets) == 0 && isNativeHistogram(m.GetHistogram()) {
+					buckets = nativeBuckets(m.GetHistogram())
+				}
+
+				for _, b := range buckets {
 					fields := map[string]interface{}{
 						fieldName + "_bucket": b.GetCumulativeCount(),
 					}
@@ -523,6 +543,14 @@ func (p *Prom) doText2Metrics(in io.Reader, u string) (pts []*point.Point, lastE
 						} else {
 							pts = append(pts, pt)
 						}
+
+						if e := b.GetExemplar(); e != nil {
+							if pt, err := p.exemplarPoint(e, measurementName, fieldName, tags, u); err != nil {
+								lastErr = err
+							} else {
+								pts = append(pts, pt)
+							}
+						}
 					}
 				}
 			}
@@ -532,7 +560,7 @@ func (p *Prom) doText2Metrics(in io.Reader, u string) (pts []*point.Point, lastE
 		}
 	}
 	if lastErr != nil {
-		return pts, fmt.Errorf("doText2Metrics encountered make point error: %w", lastErr)
+		return pts, fmt.Errorf("metricFamilies2Points encountered make point error: %w", lastErr)
 	}
 	return pts, nil
 }
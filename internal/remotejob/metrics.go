@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package remotejob
+
+import (
+	"github.com/GuanceCloud/cliutils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var jobsVec *prometheus.CounterVec
+
+//nolint:gochecknoinits
+func init() {
+	jobsVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "remote_job",
+			Name:      "total",
+			Help:      "Remote jobs pulled, status succeeded, failed or rejected",
+		},
+		[]string{
+			"name",
+			"status",
+		},
+	)
+
+	metrics.MustRegister(Metrics()...)
+}
+
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		jobsVec,
+	}
+}
@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package remotejob runs diagnostic jobs requested by the center through
+// dataway. Only jobs built in DataKit(such as collecting a bug report bundle)
+// and allowed in datakit.conf are run, each job must be signed by the key
+// configured, and all jobs are audited locally. There is no way to run
+// arbitrary commands.
+package remotejob
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+)
+
+const (
+	packageName = "remotejob"
+
+	jobsPullWhat = "remote_jobs=true"
+
+	// Jobs built in, only these can be allowed.
+	JobBugReport  = "bug_report"  // collect logs, configs and metrics of DataKit into a zip
+	JobInputTest  = "input_test"  // collect once by the input named by arg `input`
+	JobFlushCache = "flush_cache" // flush data in memory and fail cache to dataway
+)
+
+var (
+	l = logger.DefaultSLogger(packageName)
+
+	builtinJobs = []string{JobBugReport, JobInputTest, JobFlushCache}
+
+	handlers   = map[string]Handler{}
+	handlersMu sync.RWMutex
+
+	startOnce sync.Once
+)
+
+// Config configures remote jobs in datakit.conf.
+type Config struct {
+	Enable bool `toml:"enable"`
+
+	// AllowedJobs are jobs allowed to run, others are rejected.
+	AllowedJobs []string `toml:"allowed_jobs"`
+
+	// PublicKey is the base64 encoded Ed25519 public key to verify jobs.
+	PublicKey string `toml:"public_key"`
+
+	PullInterval string `toml:"pull_interval"`
+
+	// MaxAge rejects jobs created before it, so signed jobs can not be replayed later.
+	MaxAge string `toml:"max_age"`
+
+	// Timeout of each job.
+	Timeout string `toml:"timeout"`
+
+	// AuditLog is the file jobs audited into, remote_job.log under the
+	// install dir if empty.
+	AuditLog string `toml:"audit_log"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Enable:       false,
+		AllowedJobs:  []string{JobBugReport, JobInputTest, JobFlushCache},
+		PullInterval: "30s",
+		MaxAge:       "10m",
+		Timeout:      "5m",
+	}
+}
+
+// Result is the output of a job, uploaded to dataway.
+type Result struct {
+	ContentType string
+	Body        []byte
+}
+
+// Handler runs a job with args of the job, it should return once ctx done.
+// Handlers not returning in time are left running in background, and the job
+// fails with timeout.
+type Handler func(ctx context.Context, args map[string]string) (*Result, error)
+
+// Dataway pulls jobs and uploads results of jobs, implemented by dataway.
+type Dataway interface {
+	Pull(what string) ([]byte, error)
+	UploadRemoteJobResult(headers map[string]string, body io.Reader) error
+}
+
+// Register sets the handler of the built-in job, called before Start.
+func Register(name string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers[name] = h
+}
+
+func handler(name string) Handler {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+
+	return handlers[name]
+}
+
+type options struct {
+	allowed      map[string]bool
+	publicKey    ed25519.PublicKey
+	pullInterval time.Duration
+	maxAge       time.Duration
+	timeout      time.Duration
+	auditLog     string
+}
+
+func (c *Config) check() (*options, error) {
+	opt := &options{allowed: map[string]bool{}}
+
+	for _, name := range c.AllowedJobs {
+		known := false
+		for _, x := range builtinJobs {
+			if name == x {
+				known = true
+			}
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown job %q, available: %v", name, builtinJobs)
+		}
+
+		opt.allowed[name] = true
+	}
+
+	key, err := base64.StdEncoding.DecodeString(c.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public_key, expect base64 encoded Ed25519 public key")
+	}
+	opt.publicKey = key
+
+	for _, x := range []struct {
+		name string
+		s    string
+		d    *time.Duration
+	}{
+		{"pull_interval", c.PullInterval, &opt.pullInterval},
+		{"max_age", c.MaxAge, &opt.maxAge},
+		{"timeout", c.Timeout, &opt.timeout},
+	} {
+		d, err := time.ParseDuration(x.s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", x.name, x.s)
+		}
+		*x.d = d
+	}
+
+	opt.auditLog = c.AuditLog
+	if opt.auditLog == "" {
+		opt.auditLog = filepath.Join(datakit.InstallDir, "remote_job.log")
+	}
+
+	return opt, nil
+}
+
+// Start pulls and runs jobs from dw, nothing done if c is nil or disabled.
+func Start(c *Config, dw Dataway) error {
+	l = logger.SLogger(packageName)
+
+	if c == nil || !c.Enable {
+		return nil
+	}
+
+	opt, err := c.check()
+	if err != nil {
+		return err
+	}
+
+	startOnce.Do(func() {
+		r := newRunner(opt, dw)
+
+		g := goroutine.NewGroup(goroutine.Option{Name: "internal_remotejob"})
+		g.Go(func(ctx context.Context) error {
+			r.run()
+			return nil
+		})
+
+		l.Infof("remote jobs enabled, allowed: %v, audited into %s", c.AllowedJobs, opt.auditLog)
+	})
+
+	return nil
+}
@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package remotejob
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDataway struct {
+	jobs    []byte
+	results []map[string]string // headers and body of each result
+}
+
+func (m *mockDataway) Pull(what string) ([]byte, error) {
+	if what != jobsPullWhat {
+		return nil, fmt.Errorf("unexpected pull %q", what)
+	}
+	return m.jobs, nil
+}
+
+func (m *mockDataway) UploadRemoteJobResult(headers map[string]string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	res := map[string]string{"body": string(body)}
+	for k, v := range headers {
+		res[k] = v
+	}
+	m.results = append(m.results, res)
+
+	return nil
+}
+
+func signedJob(key ed25519.PrivateKey, id, name string, args map[string]string, created time.Time) *job {
+	j := &job{ID: id, Name: name, Args: args, CreatedAt: created.Unix()}
+	j.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, j.signedMessage()))
+	return j
+}
+
+func TestCheck(t *T.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	c := DefaultConfig()
+	_, err = c.check()
+	assert.Error(t, err, "public key required")
+
+	c.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	opt, err := c.check()
+	require.NoError(t, err)
+	assert.True(t, opt.allowed[JobBugReport])
+	assert.Equal(t, 10*time.Minute, opt.maxAge)
+	assert.True(t, strings.HasSuffix(opt.auditLog, "remote_job.log"))
+
+	c.AllowedJobs = []string{"shell"}
+	_, err = c.check()
+	assert.Error(t, err)
+
+	c.AllowedJobs = nil
+	c.Timeout = "0s"
+	_, err = c.check()
+	assert.Error(t, err)
+}
+
+func TestRunner(t *T.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var gotArgs []map[string]string
+	Register(JobInputTest, func(_ context.Context, args map[string]string) (*Result, error) {
+		gotArgs = append(gotArgs, args)
+		if args["input"] == "" {
+			return nil, fmt.Errorf("arg input required")
+		}
+		return &Result{ContentType: "application/json", Body: []byte(`{"ok":true}`)}, nil
+	})
+	defer Register(JobInputTest, nil)
+
+	auditLog := filepath.Join(t.TempDir(), "audit", "remote_job.log")
+
+	c := DefaultConfig()
+	c.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	c.AllowedJobs = []string{JobInputTest, JobFlushCache}
+	c.AuditLog = auditLog
+	opt, err := c.check()
+	require.NoError(t, err)
+
+	now := time.Now()
+	forged := signedJob(priv, "job-6", JobInputTest, map[string]string{"input": "cpu"}, now)
+	forged.Args["input"] = "mem" // args changed after signed
+
+	dw := &mockDataway{}
+	dw.jobs, err = json.Marshal(&pulledJobs{
+		RemoteJobs: []*job{
+			signedJob(priv, "job-1", JobInputTest, map[string]string{"input": "cpu"}, now),
+			signedJob(priv, "job-2", JobInputTest, nil, now),
+			signedJob(priv, "job-3", JobBugReport, nil, now),                 // not allowed
+			signedJob(priv, "job-4", JobFlushCache, nil, now),                // no handler
+			signedJob(priv, "job-5", JobInputTest, nil, now.Add(-time.Hour)), // expired
+			forged, // not uploaded
+			signedJob(priv, "job-1", JobInputTest, map[string]string{"input": "cpu"}, now), // duplicated
+		},
+		PullInterval: pullInterval(time.Minute),
+	})
+	require.NoError(t, err)
+
+	r := newRunner(opt, dw)
+	assert.Equal(t, time.Minute, r.pull())
+
+	require.Len(t, dw.results, 5)
+	assert.Equal(t, []map[string]string{{"input": "cpu"}, nil}, gotArgs)
+
+	status := map[string]string{}
+	for _, res := range dw.results {
+		status[res["X-Job-ID"]] = res["X-Job-Status"]
+	}
+	assert.Equal(t, map[string]string{
+		"job-1": statusSucceeded,
+		"job-2": statusFailed,
+		"job-3": statusRejected,
+		"job-4": statusRejected,
+		"job-5": statusRejected,
+	}, status)
+
+	assert.Equal(t, `{"ok":true}`, dw.results[0]["body"])
+	assert.Equal(t, "application/json", dw.results[0]["Content-Type"])
+	assert.Equal(t, "arg input required", dw.results[1]["body"])
+
+	// pulled again, nothing run
+	r.pull()
+	assert.Len(t, dw.results, 5)
+
+	// all audited, including the forged one, which is not kept as done and
+	// audited on each pull
+	data, err := os.ReadFile(auditLog)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 7)
+
+	var rec auditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[5]), &rec))
+	assert.Equal(t, "job-6", rec.ID)
+	assert.Equal(t, statusRejected, rec.Status)
+	assert.Equal(t, reasonInvalidSignature, rec.Reason)
+}
+
+func TestPullInterval(t *T.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	c := DefaultConfig()
+	c.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	c.PullInterval = "30s"
+	opt, err := c.check()
+	require.NoError(t, err)
+
+	cases := []struct {
+		body     string
+		interval time.Duration
+	}{
+		{`{"pull_interval":"1m"}`, time.Minute},
+		{`{"pull_interval":120}`, 2 * time.Minute},
+		{`{"pull_interval":1}`, 30 * time.Second},     // no less than the configured one
+		{`{"pull_interval":"1ns"}`, 30 * time.Second}, // no less than the configured one
+		{`{"pull_interval":"abc"}`, 0},
+		{`{"pull_interval":true}`, 0},
+		{`{}`, 0},
+	}
+
+	for _, tc := range cases {
+		r := newRunner(opt, &mockDataway{jobs: []byte(tc.body)})
+		assert.Equal(t, tc.interval, r.pull(), tc.body)
+	}
+}
+
+func TestDoneBounded(t *T.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	c := DefaultConfig()
+	c.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	c.AllowedJobs = nil
+	c.AuditLog = filepath.Join(t.TempDir(), "remote_job.log")
+	opt, err := c.check()
+	require.NoError(t, err)
+
+	var (
+		r   = newRunner(opt, &mockDataway{})
+		now = time.Now()
+	)
+
+	// forged jobs never kept
+	for i := 0; i < maxDone+10; i++ {
+		r.handle(&job{ID: fmt.Sprintf("forged-%d", i), Name: JobInputTest, CreatedAt: now.Unix(), Signature: "Zm9yZ2Vk"})
+	}
+	assert.Empty(t, r.done)
+
+	// the oldest evicted once full
+	for i := 0; i < maxDone+10; i++ {
+		r.handle(signedJob(priv, fmt.Sprintf("job-%d", i), JobInputTest, nil, now))
+	}
+	assert.Len(t, r.done, maxDone)
+	assert.False(t, r.isDone(signedJob(priv, "job-0", JobInputTest, nil, now).key()))
+	assert.True(t, r.isDone(signedJob(priv, fmt.Sprintf("job-%d", maxDone+9), JobInputTest, nil, now).key()))
+}
+
+func TestRunnerTimeout(t *T.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hang := make(chan struct{})
+	defer close(hang)
+
+	Register(JobInputTest, func(context.Context, map[string]string) (*Result, error) {
+		<-hang // ctx ignored
+		return &Result{}, nil
+	})
+	defer Register(JobInputTest, nil)
+
+	c := DefaultConfig()
+	c.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	c.Timeout = "100ms"
+	c.AuditLog = filepath.Join(t.TempDir(), "remote_job.log")
+	opt, err := c.check()
+	require.NoError(t, err)
+
+	dw := &mockDataway{}
+	dw.jobs, err = json.Marshal(&pulledJobs{
+		RemoteJobs: []*job{signedJob(priv, "job-1", JobInputTest, nil, time.Now())},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	newRunner(opt, dw).pull()
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	require.Len(t, dw.results, 1)
+	assert.Equal(t, statusFailed, dw.results[0]["X-Job-Status"])
+	assert.Contains(t, dw.results[0]["body"], context.DeadlineExceeded.Error())
+}
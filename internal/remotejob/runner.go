@@ -0,0 +1,363 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package remotejob
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+const (
+	statusRejected  = "rejected"
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+
+	reasonInvalidSignature = "invalid signature"
+
+	// maxDone limits IDs of jobs kept to reject duplicated ones, older ones are
+	// rejected by max_age anyway, the oldest ones are evicted if still full.
+	maxDone = 1024
+)
+
+// job is a job requested by the center.
+type job struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Args      map[string]string `json:"args"`
+	CreatedAt int64             `json:"created_at"` // unix seconds
+	Signature string            `json:"signature"`  // base64 encoded
+}
+
+type pulledJobs struct {
+	RemoteJobs []*job `json:"remote_jobs"`
+	// other fields ignored
+	PullInterval pullInterval `json:"pull_interval"`
+}
+
+// pullInterval is the pull interval set by the center, in duration string
+// such as "1m" or in seconds. Invalid ones are ignored.
+type pullInterval time.Duration
+
+func (pi pullInterval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(pi).String())
+}
+
+func (pi *pullInterval) UnmarshalJSON(data []byte) error {
+	var x interface{}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+
+	switch v := x.(type) {
+	case string:
+		du, err := time.ParseDuration(v)
+		if err != nil {
+			l.Warnf("invalid pull_interval %q: %s, ignored", v, err)
+			return nil
+		}
+		*pi = pullInterval(du)
+	case float64:
+		*pi = pullInterval(v * float64(time.Second))
+	default:
+		l.Warnf("invalid pull_interval %s, ignored", data)
+	}
+
+	return nil
+}
+
+// signedMessage returns the message signed, args sorted by key and encoded
+// as URL query:
+//
+//	<id>\n<name>\n<created_at>\n<args>
+func (j *job) signedMessage() []byte {
+	args := url.Values{}
+	for k, v := range j.Args {
+		args.Set(k, v)
+	}
+
+	return []byte(j.ID + "\n" + j.Name + "\n" + strconv.FormatInt(j.CreatedAt, 10) + "\n" + args.Encode())
+}
+
+func (j *job) key() string {
+	return j.ID + "\n" + j.Signature
+}
+
+type runner struct {
+	opt *options
+	dw  Dataway
+
+	mu   sync.Mutex
+	done map[string]time.Time // jobs with valid signature run or rejected, by ID and signature
+}
+
+func newRunner(opt *options, dw Dataway) *runner {
+	return &runner{
+		opt:  opt,
+		dw:   dw,
+		done: map[string]time.Time{},
+	}
+}
+
+func (r *runner) run() {
+	tick := time.NewTicker(r.opt.pullInterval)
+	defer tick.Stop()
+
+	interval := r.opt.pullInterval
+	for {
+		if i := r.pull(); i > 0 && i != interval {
+			l.Infof("set remote jobs pull interval from %s to %s", interval, i)
+			interval = i
+			tick.Reset(interval)
+		}
+
+		select {
+		case <-tick.C:
+		case <-datakit.Exit.Wait():
+			l.Info("remote jobs exits")
+			return
+		}
+	}
+}
+
+// pull pulls and runs jobs one by one, returns the new pull interval if set,
+// which is no less than the configured one.
+func (r *runner) pull() time.Duration {
+	body, err := r.dw.Pull(jobsPullWhat)
+	if err != nil {
+		l.Warnf("pull remote jobs: %s, ignored", err)
+		return 0
+	}
+
+	var jobs pulledJobs
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		l.Warnf("unmarshal remote jobs: %s, ignored", err)
+		return 0
+	}
+
+	for _, j := range jobs.RemoteJobs {
+		if j == nil || r.isDone(j.key()) {
+			continue
+		}
+
+		r.handle(j)
+	}
+
+	interval := time.Duration(jobs.PullInterval)
+	if interval > 0 && interval < r.opt.pullInterval {
+		l.Warnf("pull interval %s less than %s, reset to %s", interval, r.opt.pullInterval, r.opt.pullInterval)
+		interval = r.opt.pullInterval
+	}
+
+	return interval
+}
+
+func (r *runner) isDone(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.done[key]
+	return ok
+}
+
+func (r *runner) setDone(key string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.done) >= maxDone {
+		var (
+			oldestKey string
+			oldest    time.Time
+		)
+
+		for k, t := range r.done {
+			if now.Sub(t) > r.opt.maxAge {
+				delete(r.done, k)
+				continue
+			}
+
+			if oldestKey == "" || t.Before(oldest) {
+				oldestKey, oldest = k, t
+			}
+		}
+
+		if len(r.done) >= maxDone {
+			delete(r.done, oldestKey)
+		}
+	}
+
+	r.done[key] = now
+}
+
+// verify returns why the job rejected, empty if it's allowed to run.
+func (r *runner) verify(j *job, now time.Time) string {
+	if j.ID == "" {
+		return "missing job ID"
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(j.Signature)
+	if err != nil || !ed25519.Verify(r.opt.publicKey, j.signedMessage(), sig) {
+		return reasonInvalidSignature
+	}
+
+	created := time.Unix(j.CreatedAt, 0)
+	if now.Sub(created) > r.opt.maxAge || created.Sub(now) > r.opt.maxAge {
+		return fmt.Sprintf("created at %s, out of max_age %s", created.Format(time.RFC3339), r.opt.maxAge)
+	}
+
+	if !r.opt.allowed[j.Name] {
+		return fmt.Sprintf("job %q not allowed", j.Name)
+	}
+
+	if handler(j.Name) == nil {
+		return fmt.Sprintf("job %q not supported", j.Name)
+	}
+
+	return ""
+}
+
+// handle runs the job if verified, and uploads the result. Jobs are done
+// once whatever the result, the center should request a new job to retry.
+// Forged jobs are not kept as done, or a flood of them fills up done.
+func (r *runner) handle(j *job) {
+	var (
+		start  = time.Now()
+		res    *Result
+		status = statusSucceeded
+		reason = r.verify(j, start)
+	)
+
+	if j.ID != "" && reason != reasonInvalidSignature {
+		r.setDone(j.key(), start)
+	}
+
+	if reason != "" {
+		status = statusRejected
+		l.Warnf("remote job %s(%s) rejected: %s", j.ID, j.Name, reason)
+	} else {
+		l.Infof("run remote job %s(%s), args: %v", j.ID, j.Name, j.Args)
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.opt.timeout)
+		var err error
+		res, err = runHandler(ctx, handler(j.Name), j.Args)
+		cancel()
+
+		if err != nil {
+			status = statusFailed
+			reason = err.Error()
+		}
+	}
+
+	cost := time.Since(start)
+	jobsVec.WithLabelValues(j.Name, status).Inc()
+
+	r.audit(&auditRecord{
+		Time:   start.Format(time.RFC3339),
+		ID:     j.ID,
+		Name:   j.Name,
+		Args:   j.Args,
+		Status: status,
+		Reason: reason,
+		Cost:   cost.String(),
+	})
+
+	// results of jobs with invalid signature not uploaded, they are not
+	// requested by the center
+	if status == statusRejected && reason == reasonInvalidSignature {
+		return
+	}
+
+	r.upload(j, status, reason, res)
+}
+
+// runHandler runs the handler and returns on ctx done, even if the handler
+// hangs, so that the runner is not blocked.
+func runHandler(ctx context.Context, h Handler, args map[string]string) (*Result, error) {
+	type result struct {
+		res *Result
+		err error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		res, err := h(ctx, args)
+		ch <- result{res: res, err: err}
+	}()
+
+	select {
+	case x := <-ch:
+		return x.res, x.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("job not finished: %w", ctx.Err())
+	}
+}
+
+func (r *runner) upload(j *job, status, reason string, res *Result) {
+	headers := map[string]string{
+		"X-Job-ID":     j.ID,
+		"X-Job-Name":   j.Name,
+		"X-Job-Status": status,
+		"Content-Type": "text/plain; charset=utf-8",
+	}
+
+	body := []byte(reason)
+	if status == statusSucceeded && res != nil {
+		body = res.Body
+		if res.ContentType != "" {
+			headers["Content-Type"] = res.ContentType
+		}
+	}
+
+	if err := r.dw.UploadRemoteJobResult(headers, bytes.NewReader(body)); err != nil {
+		l.Warnf("upload result of remote job %s(%s): %s", j.ID, j.Name, err)
+	}
+}
+
+type auditRecord struct {
+	Time   string            `json:"time"`
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Args   map[string]string `json:"args,omitempty"`
+	Status string            `json:"status"`
+	Reason string            `json:"reason,omitempty"`
+	Cost   string            `json:"cost"`
+}
+
+// audit appends the record as a JSON line to the audit log.
+func (r *runner) audit(rec *auditRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		l.Errorf("marshal audit record: %s", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.opt.auditLog), 0o750); err != nil {
+		l.Errorf("audit remote job: %s", err)
+		return
+	}
+
+	f, err := os.OpenFile(filepath.Clean(r.opt.auditLog), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		l.Errorf("audit remote job: %s", err)
+		return
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		l.Errorf("audit remote job: %s", err)
+	}
+}
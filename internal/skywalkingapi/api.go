@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
 	itrace "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
@@ -25,17 +26,20 @@ import (
 type SkyAPI struct {
 	inputName      string
 	plugins        []string
+	afterGather    *itrace.AfterGather
 	afterGatherRun itrace.AfterGatherHandler
 	customerKeys   []string
 	tags           map[string]string
 	localCache     *storage.Storage
 	log            *logger.Logger
+	// convert trace and span IDs to OTel-compatible IDs
+	otelCompatibleID bool
 }
 
 // InitApiPluginAges 可以初始化多次, 用 name 区分.
 //nolint:gofumpt,stylecheck
 func InitApiPluginAges(pls []string, localCacheConfig *storage.StorageConfig, closeResource map[string][]string,
-	keepRareResource bool, sampler *itrace.Sampler, customerTags []string, itags map[string]string, name string) *SkyAPI {
+	keepRareResource bool, sampler *itrace.Sampler, tailSampling *itrace.TailSampling, spanMetrics *itrace.SpanMetrics, customerTags []string, itags map[string]string, name string) *SkyAPI {
 	api := &SkyAPI{inputName: name, plugins: pls, tags: itags}
 	api.log = logger.SLogger(name)
 	if localCacheConfig != nil {
@@ -61,12 +65,20 @@ func InitApiPluginAges(pls []string, localCacheConfig *storage.StorageConfig, cl
 		}
 	}
 
-	var afterGather *itrace.AfterGather
+	opts := []itrace.Option{itrace.WithLogger(api.log)}
 	if api.localCache != nil && api.localCache.Enabled() {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(api.log), itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
-	} else {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(api.log))
+		opts = append(opts, itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
 	}
+	// pull per-service sampling rates configured in center
+	opts = append(opts, itrace.WithSamplingPull(config.Cfg.Dataway))
+	if tailSampling != nil {
+		opts = append(opts, itrace.WithTailSampling(tailSampling))
+	}
+	if spanMetrics != nil {
+		opts = append(opts, itrace.WithSpanMetrics(spanMetrics))
+	}
+	afterGather := itrace.NewAfterGather(opts...)
+	api.afterGather = afterGather
 	api.afterGatherRun = afterGather
 
 	if len(closeResource) != 0 {
@@ -75,8 +87,10 @@ func InitApiPluginAges(pls []string, localCacheConfig *storage.StorageConfig, cl
 		afterGather.AppendFilter(iCloseResource.Close)
 	}
 
-	// add error status penetration
-	afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	// add error status penetration, tail sampling keeps error traces by itself
+	if tailSampling == nil {
+		afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	}
 	// add rare resource keeper
 	if keepRareResource {
 		krs := &itrace.KeepRareResource{}
@@ -85,13 +99,15 @@ func InitApiPluginAges(pls []string, localCacheConfig *storage.StorageConfig, cl
 	}
 
 	// add sampler
-	var isampler *itrace.Sampler
-	if sampler != nil && (sampler.SamplingRateGlobal >= 0 && sampler.SamplingRateGlobal <= 1) {
-		isampler = sampler
-	} else {
-		isampler = &itrace.Sampler{SamplingRateGlobal: 1}
+	if tailSampling == nil {
+		var isampler *itrace.Sampler
+		if sampler != nil && (sampler.SamplingRateGlobal >= 0 && sampler.SamplingRateGlobal <= 1) {
+			isampler = sampler
+		} else {
+			isampler = &itrace.Sampler{SamplingRateGlobal: 1}
+		}
+		afterGather.AppendFilter(isampler.Sample)
 	}
-	afterGather.AppendFilter(isampler.Sample)
 
 	api.customerKeys = customerTags
 
@@ -105,3 +121,10 @@ func (api *SkyAPI) CloseLocalCache() {
 		}
 	}
 }
+
+// StopAfterGather releases the tail sampler and span metrics of the API.
+func (api *SkyAPI) StopAfterGather() {
+	if api.afterGather != nil {
+		api.afterGather.Stop()
+	}
+}
@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalkingapi
+
+import (
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
+)
+
+const (
+	browserViewName  = "view"
+	browserErrorName = "error"
+	browserSDKName   = "skywalking-client-js"
+)
+
+// ProcessBrowserPerf converts the page performance data reported by SkyWalking
+// browser agent to RUM view.
+func (api *SkyAPI) ProcessBrowserPerf(perf *agentv3.BrowserPerfData) {
+	tags := api.browserTags(perf.Service, perf.ServiceVersion, perf.PagePath)
+	fields := map[string]interface{}{
+		"page_redirect":      perf.RedirectTime,
+		"page_dns":           perf.DnsTime,
+		"page_ttfb":          perf.TtfbTime,
+		"page_tcp":           perf.TcpTime,
+		"page_trans":         perf.TransTime,
+		"page_dom":           perf.DomAnalysisTime,
+		"page_fpt":           perf.FptTime,
+		"page_dom_ready":     perf.DomReadyTime,
+		"page_load":          perf.LoadPageTime,
+		"page_resource_load": perf.ResTime,
+		"page_ssl":           perf.SslTime,
+		"page_ttl":           perf.TtlTime,
+		"page_first_byte":    perf.FirstPackTime,
+		"page_fmp":           perf.FmpTime,
+	}
+
+	api.feedRUM(browserViewName, tags, fields, perf.Time)
+}
+
+// ProcessBrowserErrorLog converts the error log reported by SkyWalking browser
+// agent to RUM error.
+func (api *SkyAPI) ProcessBrowserErrorLog(errLog *agentv3.BrowserErrorLog) {
+	tags := api.browserTags(errLog.Service, errLog.ServiceVersion, errLog.PagePath)
+	tags["error_source"] = agentv3.ErrorCategory_name[int32(errLog.Category)]
+	if errLog.Grade != "" {
+		tags["error_grade"] = errLog.Grade
+	}
+	fields := map[string]interface{}{
+		"error_id":             errLog.UniqueId,
+		"error_message":        errLog.Message,
+		"error_stack":          errLog.Stack,
+		"error_url":            errLog.ErrorUrl,
+		"error_line":           errLog.Line,
+		"error_col":            errLog.Col,
+		"first_reported_error": errLog.FirstReportedError,
+	}
+
+	api.feedRUM(browserErrorName, tags, fields, errLog.Time)
+}
+
+func (api *SkyAPI) browserTags(service, version, pagePath string) map[string]string {
+	tags := map[string]string{
+		"app_id":    service,
+		"service":   service,
+		"version":   version,
+		"view_path": pagePath,
+		"sdk_name":  browserSDKName,
+		"source":    api.inputName,
+	}
+	for k, v := range api.tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	return tags
+}
+
+func (api *SkyAPI) feedRUM(name string, tags map[string]string, fields map[string]interface{}, msec int64) {
+	// time of browser data is optional and set by the backend.
+	ts := time.Now()
+	if msec > 0 {
+		ts = time.UnixMilli(msec)
+	}
+
+	pt, err := point.NewPoint(name, tags, fields, &point.PointOption{Category: datakit.RUM, Time: ts})
+	if err != nil {
+		api.log.Errorf("new rum point %s failed: %s", name, err.Error())
+		return
+	}
+
+	if err = dkio.Feed(api.inputName, datakit.RUM, []*point.Point{pt}, nil); err != nil {
+		api.log.Errorf("feed rum %s failed: %s", name, err.Error())
+	}
+}
+
+var _ inputs.Measurement = &BrowserViewMeasurement{}
+
+type BrowserViewMeasurement struct{}
+
+func (*BrowserViewMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+func (*BrowserViewMeasurement) Info() *inputs.MeasurementInfo {
+	durationField := func(desc string) *inputs.FieldInfo {
+		return &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.DurationMS, Desc: desc}
+	}
+
+	return &inputs.MeasurementInfo{
+		Name: browserViewName,
+		Desc: "page performance data reported by skywalking browser agent.",
+		Type: "rum",
+		Tags: browserTagsInfo(),
+		Fields: map[string]interface{}{
+			"page_redirect":      durationField("time of redirection."),
+			"page_dns":           durationField("time of DNS query."),
+			"page_ttfb":          durationField("time to first byte."),
+			"page_tcp":           durationField("time of TCP connection."),
+			"page_trans":         durationField("time of content transfer."),
+			"page_dom":           durationField("time of DOM analysis."),
+			"page_fpt":           durationField("first paint time, i.e. white screen time."),
+			"page_dom_ready":     durationField("time of DOM ready."),
+			"page_load":          durationField("time of the whole page loading."),
+			"page_resource_load": durationField("time of synchronous resources loading."),
+			"page_ssl":           durationField("time of SSL handshake, only for HTTPS."),
+			"page_ttl":           durationField("time to interact."),
+			"page_first_byte":    durationField("time of the first package."),
+			"page_fmp":           durationField("first meaningful paint time."),
+		},
+	}
+}
+
+var _ inputs.Measurement = &BrowserErrorMeasurement{}
+
+type BrowserErrorMeasurement struct{}
+
+func (*BrowserErrorMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+func (*BrowserErrorMeasurement) Info() *inputs.MeasurementInfo {
+	tags := browserTagsInfo()
+	tags["error_source"] = &inputs.TagInfo{Desc: "error category: ajax/resource/vue/promise/js/unknown"}
+	tags["error_grade"] = &inputs.TagInfo{Desc: "error grade"}
+
+	return &inputs.MeasurementInfo{
+		Name: browserErrorName,
+		Desc: "error logs reported by skywalking browser agent.",
+		Type: "rum",
+		Tags: tags,
+		Fields: map[string]interface{}{
+			"error_id":             &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "unique ID of the error."},
+			"error_message":        &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "error message."},
+			"error_stack":          &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "error stack."},
+			"error_url":            &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "URL of the resource that causes the error."},
+			"error_line":           &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.UnknownUnit, Desc: "line of the error in source."},
+			"error_col":            &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.UnknownUnit, Desc: "column of the error in source."},
+			"first_reported_error": &inputs.FieldInfo{DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "whether it is the first error reported by the page."},
+		},
+	}
+}
+
+func browserTagsInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"app_id":    &inputs.TagInfo{Desc: "same as service"},
+		"service":   &inputs.TagInfo{Desc: "service name of the browser application"},
+		"version":   &inputs.TagInfo{Desc: "service version"},
+		"view_path": &inputs.TagInfo{Desc: "page path without parameters"},
+		"sdk_name":  &inputs.TagInfo{Desc: "fixed to `" + browserSDKName + "`"},
+		"source":    &inputs.TagInfo{Desc: "fixed to `skywalking`"},
+	}
+}
@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalkingapi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+/*
+SkyWalking 的 trace ID 为任意字符串（默认为 `<uuid>.<thread>.<timestamp>`），span ID 由
+segment ID 与 segment 内的序号组成，无法与 OpenTelemetry/W3C 的 128-bit trace ID 及
+64-bit span ID 对应。开启 ID 映射后按以下规则确定性地转换：
+
+  - trace ID 若已是 32 位十六进制（可带 UUID 的 `-`），则转为小写后直接使用；
+    否则取其 FNV-1a 128-bit 哈希，以 32 位小写十六进制表示
+  - span ID 取 `<segment-id>-<span-id>` 的 FNV-1a 64-bit 哈希，以 16 位小写十六进制表示
+  - 全零的 ID 在 W3C 中非法，此时将最后一个字节置为 1
+*/
+
+const tagSkyWalkingTraceID = "sw_trace_id"
+
+// SetOTelCompatibleID enable converting SkyWalking trace and span IDs to
+// OTel-compatible IDs.
+func (api *SkyAPI) SetOTelCompatibleID(enabled bool) {
+	api.otelCompatibleID = enabled
+}
+
+func (api *SkyAPI) traceID(swTraceID string) string {
+	if !api.otelCompatibleID {
+		return swTraceID
+	}
+
+	return ToOTelTraceID(swTraceID)
+}
+
+func (api *SkyAPI) spanID(segmentID string, spanID int32) string {
+	if !api.otelCompatibleID {
+		return segmentID + strconv.FormatInt(int64(spanID), 10)
+	}
+
+	return ToOTelSpanID(segmentID, spanID)
+}
+
+// ToOTelTraceID converts SkyWalking trace ID to 128-bit trace ID in lower
+// case hex, which is the same format as the trace ID of OTel spans.
+func ToOTelTraceID(swTraceID string) string {
+	if id, ok := normalizeHexID(swTraceID); ok {
+		return id
+	}
+
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(swTraceID))
+
+	return encodeID(h.Sum(nil))
+}
+
+// ToOTelSpanID converts SkyWalking span, identified by segment ID and span ID
+// inside the segment, to 64-bit span ID in lower case hex.
+func ToOTelSpanID(segmentID string, spanID int32) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(segmentID + "-" + strconv.FormatInt(int64(spanID), 10)))
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, h.Sum64())
+
+	return encodeID(buf)
+}
+
+func normalizeHexID(id string) (string, bool) {
+	id = strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if len(id) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	if strings.Trim(id, "0") == "" {
+		return "", false
+	}
+
+	return id, true
+}
+
+func encodeID(buf []byte) string {
+	zero := true
+	for _, b := range buf {
+		if b != 0 {
+			zero = false
+			break
+		}
+	}
+	if zero {
+		buf[len(buf)-1] = 1
+	}
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalkingapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToOTelTraceID(t *testing.T) {
+	cases := []struct {
+		name, in, expect string
+	}{
+		{name: "hex-id", in: "4BF92F3577B34DA6A3CE929D0E0E4736", expect: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "uuid", in: "4bf92f35-77b3-4da6-a3ce-929d0e0e4736", expect: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "sw-default-id", in: "a1f3ae8a7e5a4b2e8c1d2e3f4a5b6c7d.95.16784307670360001"},
+		{name: "zero-id", in: "00000000000000000000000000000000"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ToOTelTraceID(tc.in)
+			assert.Len(t, got, 32)
+			assert.NotEqual(t, "00000000000000000000000000000000", got)
+			if tc.expect != "" {
+				assert.Equal(t, tc.expect, got)
+			}
+			// deterministic
+			assert.Equal(t, got, ToOTelTraceID(tc.in))
+		})
+	}
+
+	assert.NotEqual(t, ToOTelTraceID("trace-a"), ToOTelTraceID("trace-b"))
+}
+
+func TestToOTelSpanID(t *testing.T) {
+	id := ToOTelSpanID("a1f3ae8a7e5a4b2e8c1d2e3f4a5b6c7d.95.16784307670360000", 1)
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, ToOTelSpanID("a1f3ae8a7e5a4b2e8c1d2e3f4a5b6c7d.95.16784307670360000", 1))
+	assert.NotEqual(t, id, ToOTelSpanID("a1f3ae8a7e5a4b2e8c1d2e3f4a5b6c7d.95.16784307670360000", 2))
+
+	// segment "ab" + span 12 must not collide with segment "ab1" + span 2
+	assert.NotEqual(t, ToOTelSpanID("ab", 12), ToOTelSpanID("ab1", 2))
+}
+
+func TestSpanIDCompatible(t *testing.T) {
+	api := &SkyAPI{}
+	assert.Equal(t, "seg12", api.spanID("seg1", 2))
+	assert.Equal(t, "sw-trace", api.traceID("sw-trace"))
+
+	api.SetOTelCompatibleID(true)
+	assert.Equal(t, ToOTelSpanID("seg1", 2), api.spanID("seg1", 2))
+	assert.Equal(t, ToOTelTraceID("sw-trace"), api.traceID("sw-trace"))
+}
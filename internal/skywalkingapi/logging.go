@@ -43,8 +43,13 @@ func (api *SkyAPI) ProcessLog(plog *loggingv3.LogData) {
 
 	// 不用 pipeline 就是因为这里已经处理好了。
 	if ctx := plog.GetTraceContext(); ctx != nil {
-		extraTags["trace_id"] = ctx.TraceId
-		extraTags["span_id"] = strconv.FormatInt(int64(ctx.SpanId), 10)
+		extraTags["trace_id"] = api.traceID(ctx.TraceId)
+		if api.otelCompatibleID {
+			extraTags["span_id"] = api.spanID(ctx.TraceSegmentId, ctx.SpanId)
+			extraTags[tagSkyWalkingTraceID] = ctx.TraceId
+		} else {
+			extraTags["span_id"] = strconv.FormatInt(int64(ctx.SpanId), 10)
+		}
 		extraTags["trace_segment_id"] = ctx.TraceSegmentId
 	}
 	line := ""
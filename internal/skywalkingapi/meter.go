@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalkingapi
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	clipt "github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
+)
+
+const meterMetricName = "skywalking_meter"
+
+// ProcessMeters converts meters reported by Meter API of SkyWalking agent into
+// metric points. Service, service instance and timestamp are only set in the
+// first meter of a report, the following meters inherit them.
+func (api *SkyAPI) ProcessMeters(meters []*agentv3.MeterData) {
+	var (
+		pts     []*point.Point
+		start   = time.Now()
+		service string
+		inst    string
+		ts      int64
+	)
+	for _, m := range meters {
+		if m == nil {
+			continue
+		}
+		if m.Service != "" {
+			service = m.Service
+		}
+		if m.ServiceInstance != "" {
+			inst = m.ServiceInstance
+		}
+		if m.Timestamp != 0 {
+			ts = m.Timestamp
+		}
+
+		t := start
+		if ts != 0 {
+			t = time.Unix(0, ts*int64(time.Millisecond))
+		}
+
+		pts = append(pts, api.extractMeter(m, service, inst, t)...)
+	}
+
+	if len(pts) != 0 {
+		if err := dkio.Feed(meterMetricName, datakit.Metric, pts, &dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			dkio.FeedLastError(meterMetricName, err.Error(), clipt.Tracing)
+		}
+	}
+}
+
+func (api *SkyAPI) meterTags(service, instance string, labels []*agentv3.Label) map[string]string {
+	tags := map[string]string{}
+	for k, v := range api.tags {
+		tags[k] = v
+	}
+	for _, l := range labels {
+		if l != nil && l.Name != "" {
+			tags[l.Name] = l.Value
+		}
+	}
+	tags["service"] = service
+	tags["service_instance"] = instance
+
+	return tags
+}
+
+// extractMeter returns one point for single value, for histogram it returns
+// a point for each bucket tagged with bucket(lower bound of the bucket) and
+// a point of the total count.
+func (api *SkyAPI) extractMeter(m *agentv3.MeterData, service, instance string, t time.Time) []*point.Point {
+	opt := &point.PointOption{Time: t, Category: datakit.Metric}
+
+	if sv := m.GetSingleValue(); sv != nil {
+		if sv.Name == "" {
+			return nil
+		}
+
+		pt, err := point.NewPoint(meterMetricName, api.meterTags(service, instance, sv.Labels),
+			map[string]interface{}{sv.Name: sv.Value}, opt)
+		if err != nil {
+			api.log.Debugf("make meter point failed: %s", err)
+			return nil
+		}
+
+		return []*point.Point{pt}
+	}
+
+	hg := m.GetHistogram()
+	if hg == nil || hg.Name == "" {
+		return nil
+	}
+
+	var (
+		pts   []*point.Point
+		total int64
+	)
+	for _, b := range hg.Values {
+		if b == nil {
+			continue
+		}
+		total += b.Count
+
+		tags := api.meterTags(service, instance, hg.Labels)
+		tags["bucket"] = meterBucket(b)
+
+		pt, err := point.NewPoint(meterMetricName, tags, map[string]interface{}{hg.Name + "_bucket": b.Count}, opt)
+		if err != nil {
+			api.log.Debugf("make meter point failed: %s", err)
+			continue
+		}
+		pts = append(pts, pt)
+	}
+
+	pt, err := point.NewPoint(meterMetricName, api.meterTags(service, instance, hg.Labels),
+		map[string]interface{}{hg.Name + "_count": total}, opt)
+	if err != nil {
+		api.log.Debugf("make meter point failed: %s", err)
+		return pts
+	}
+
+	return append(pts, pt)
+}
+
+func meterBucket(b *agentv3.MeterBucketValue) string {
+	if b.IsNegativeInfinity || math.IsInf(b.Bucket, -1) {
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(b.Bucket, 'f', -1, 64)
+}
+
+var _ inputs.Measurement = &MeterMeasurement{}
+
+type MeterMeasurement struct{}
+
+func (*MeterMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+func (*MeterMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: meterMetricName,
+		Desc: "metrics reported by Meter API of skywalking language agent, labels of meter are added as tags.",
+		Type: "metric",
+		Tags: map[string]interface{}{
+			"service":          &inputs.TagInfo{Desc: "service name"},
+			"service_instance": &inputs.TagInfo{Desc: "service instance name"},
+			"bucket":           &inputs.TagInfo{Desc: "lower bound of histogram bucket, only for `<meter>_bucket`"},
+		},
+		Fields: map[string]interface{}{
+			"<meter>": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Float,
+				Unit:     inputs.UnknownUnit,
+				Desc:     "value of counter or gauge meter.",
+			},
+			"<meter>_bucket": &inputs.FieldInfo{
+				Type:     inputs.Count,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "count of values in the bucket of histogram meter.",
+			},
+			"<meter>_count": &inputs.FieldInfo{
+				Type:     inputs.Count,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "count of values in all buckets of histogram meter.",
+			},
+		},
+	}
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalkingapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/stretchr/testify/assert"
+	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
+)
+
+func TestExtractMeter(t *testing.T) {
+	api := &SkyAPI{tags: map[string]string{"env": "test"}, log: logger.DefaultSLogger("test")}
+	now := time.Now()
+
+	t.Run("single-value", func(t *testing.T) {
+		pts := api.extractMeter(&agentv3.MeterData{
+			Metric: &agentv3.MeterData_SingleValue{SingleValue: &agentv3.MeterSingleValue{
+				Name:   "order_created",
+				Labels: []*agentv3.Label{{Name: "channel", Value: "app"}},
+				Value:  42,
+			}},
+		}, "shop", "shop-1", now)
+
+		assert.Len(t, pts, 1)
+		assert.Equal(t, map[string]string{"env": "test", "channel": "app", "service": "shop", "service_instance": "shop-1"}, pts[0].Tags())
+
+		fields, err := pts[0].Fields()
+		assert.NoError(t, err)
+		assert.Equal(t, float64(42), fields["order_created"])
+		assert.Equal(t, now.UnixNano(), pts[0].Time().UnixNano())
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		pts := api.extractMeter(&agentv3.MeterData{
+			Metric: &agentv3.MeterData_Histogram{Histogram: &agentv3.MeterHistogram{
+				Name: "pay_latency",
+				Values: []*agentv3.MeterBucketValue{
+					{IsNegativeInfinity: true, Count: 1},
+					{Bucket: 10, Count: 3},
+					{Bucket: 50.5, Count: 2},
+				},
+			}},
+		}, "shop", "shop-1", now)
+
+		assert.Len(t, pts, 4)
+
+		buckets := map[string]interface{}{}
+		for _, pt := range pts[:3] {
+			fields, err := pt.Fields()
+			assert.NoError(t, err)
+			buckets[pt.Tags()["bucket"]] = fields["pay_latency_bucket"]
+		}
+		assert.Equal(t, map[string]interface{}{"-Inf": int64(1), "10": int64(3), "50.5": int64(2)}, buckets)
+
+		fields, err := pts[3].Fields()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), fields["pay_latency_count"])
+		assert.NotContains(t, pts[3].Tags(), "bucket")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Empty(t, api.extractMeter(&agentv3.MeterData{}, "shop", "shop-1", now))
+	})
+}
@@ -19,7 +19,10 @@ import (
 	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
 )
 
-const jvmMetricName = "skywalking_jvm"
+const (
+	jvmMetricName = "skywalking_jvm"
+	clrMetricName = "skywalking_clr"
+)
 
 func (api *SkyAPI) ProcessMetrics(jvm *agentv3.JVMMetricCollection) {
 	var (
@@ -54,6 +57,27 @@ func (api *SkyAPI) ProcessMetrics(jvm *agentv3.JVMMetricCollection) {
 	}
 }
 
+func (api *SkyAPI) ProcessCLRMetrics(clr *agentv3.CLRMetricCollection) {
+	var (
+		m     []inputs.Measurement
+		start = time.Now()
+	)
+	for _, cm := range clr.Metrics {
+		if cm == nil {
+			continue
+		}
+		if mm := extractCLRMetric(clr.Service, clr.ServiceInstance, start, cm); mm != nil {
+			m = append(m, mm)
+		}
+	}
+
+	if len(m) != 0 {
+		if err := inputs.FeedMeasurement(clrMetricName, datakit.Metric, m, &dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			dkio.FeedLastError(clrMetricName, err.Error(), clipt.Tracing)
+		}
+	}
+}
+
 type jvmMeasurement struct {
 	name   string
 	tags   map[string]string
@@ -179,6 +203,35 @@ func extractJVMClass(service string, start time.Time, class *agentv3.Class) inpu
 	}
 }
 
+func extractCLRMetric(service, instance string, start time.Time, clr *agentv3.CLRMetric) inputs.Measurement {
+	fields := map[string]interface{}{}
+	if clr.Cpu != nil {
+		fields["cpu_usage_percent"] = clr.Cpu.UsagePercent
+	}
+	if clr.Gc != nil {
+		fields["gc_gen0_collect_count"] = clr.Gc.Gen0CollectCount
+		fields["gc_gen1_collect_count"] = clr.Gc.Gen1CollectCount
+		fields["gc_gen2_collect_count"] = clr.Gc.Gen2CollectCount
+		fields["gc_heap_memory"] = clr.Gc.HeapMemory
+	}
+	if clr.Thread != nil {
+		fields["thread_available_completion_port_threads"] = clr.Thread.AvailableCompletionPortThreads
+		fields["thread_available_worker_threads"] = clr.Thread.AvailableWorkerThreads
+		fields["thread_max_completion_port_threads"] = clr.Thread.MaxCompletionPortThreads
+		fields["thread_max_worker_threads"] = clr.Thread.MaxWorkerThreads
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &jvmMeasurement{
+		name:   clrMetricName,
+		tags:   map[string]string{"service": service, "service_instance": instance},
+		fields: fields,
+		ts:     start,
+	}
+}
+
 var _ inputs.Measurement = &MetricMeasurement{}
 
 type MetricMeasurement struct {
@@ -321,3 +374,77 @@ func (*MetricMeasurement) Info() *inputs.MeasurementInfo {
 		},
 	}
 }
+
+var _ inputs.Measurement = &CLRMetricMeasurement{}
+
+type CLRMetricMeasurement struct{}
+
+func (*CLRMetricMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+func (*CLRMetricMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: clrMetricName,
+		Desc: "CLR metrics collected by skywalking .NET agent.",
+		Type: "metric",
+		Tags: map[string]interface{}{
+			"service":          &inputs.TagInfo{Desc: "service name"},
+			"service_instance": &inputs.TagInfo{Desc: "service instance name"},
+		},
+		Fields: map[string]interface{}{
+			"cpu_usage_percent": &inputs.FieldInfo{
+				Type:     inputs.Rate,
+				DataType: inputs.Float,
+				Unit:     inputs.Percent,
+				Desc:     "cpu usage percentile",
+			},
+			"gc_gen0_collect_count": &inputs.FieldInfo{
+				Type:     inputs.Count,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "gen0 gc collect count.",
+			},
+			"gc_gen1_collect_count": &inputs.FieldInfo{
+				Type:     inputs.Count,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "gen1 gc collect count.",
+			},
+			"gc_gen2_collect_count": &inputs.FieldInfo{
+				Type:     inputs.Count,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "gen2 gc collect count.",
+			},
+			"gc_heap_memory": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Int,
+				Unit:     inputs.SizeByte,
+				Desc:     "heap memory size.",
+			},
+			"thread_available_completion_port_threads": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "available completion port threads count.",
+			},
+			"thread_available_worker_threads": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "available worker threads count.",
+			},
+			"thread_max_completion_port_threads": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "max completion port threads count.",
+			},
+			"thread_max_worker_threads": &inputs.FieldInfo{
+				Type:     inputs.Gauge,
+				DataType: inputs.Int,
+				Unit:     inputs.NCount,
+				Desc:     "max worker threads count.",
+			},
+		},
+	}
+}
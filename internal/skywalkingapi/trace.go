@@ -8,7 +8,6 @@ package skywalkingapi
 
 import (
 	"encoding/json"
-	"fmt"
 	"strings"
 	"time"
 
@@ -41,8 +40,8 @@ func (api *SkyAPI) parseSegmentObject(segment *agentv3.SegmentObject) {
 		}
 
 		dkspan := &itrace.DatakitSpan{
-			TraceID:    segment.TraceId,
-			SpanID:     fmt.Sprintf("%s%d", segment.TraceSegmentId, span.SpanId),
+			TraceID:    api.traceID(segment.TraceId),
+			SpanID:     api.spanID(segment.TraceSegmentId, span.SpanId),
 			Service:    segment.Service,
 			Resource:   span.OperationName,
 			Operation:  span.OperationName,
@@ -54,10 +53,10 @@ func (api *SkyAPI) parseSegmentObject(segment *agentv3.SegmentObject) {
 
 		if span.ParentSpanId < 0 {
 			if len(span.Refs) > 0 {
-				dkspan.ParentID = fmt.Sprintf("%s%d", span.Refs[0].ParentTraceSegmentId, span.Refs[0].ParentSpanId)
+				dkspan.ParentID = api.spanID(span.Refs[0].ParentTraceSegmentId, span.Refs[0].ParentSpanId)
 				if span.Refs[0].RefType == agentv3.RefType_CrossProcess && strings.Contains(span.Refs[0].ParentService, "_rum_") {
 					dktrace = append(dktrace, &itrace.DatakitSpan{
-						TraceID:    segment.TraceId,
+						TraceID:    dkspan.TraceID,
 						ParentID:   "0",
 						SpanID:     dkspan.ParentID,
 						Service:    span.Refs[0].ParentService,
@@ -79,9 +78,9 @@ func (api *SkyAPI) parseSegmentObject(segment *agentv3.SegmentObject) {
 			}
 		} else {
 			if len(span.Refs) > 0 {
-				dkspan.ParentID = fmt.Sprintf("%s%d", span.Refs[0].ParentTraceSegmentId, span.Refs[0].ParentSpanId)
+				dkspan.ParentID = api.spanID(span.Refs[0].ParentTraceSegmentId, span.Refs[0].ParentSpanId)
 			} else {
-				dkspan.ParentID = fmt.Sprintf("%s%d", segment.TraceSegmentId, span.ParentSpanId)
+				dkspan.ParentID = api.spanID(segment.TraceSegmentId, span.ParentSpanId)
 			}
 		}
 
@@ -128,6 +127,9 @@ func (api *SkyAPI) parseSegmentObject(segment *agentv3.SegmentObject) {
 		if span.Peer != "" {
 			dkspan.Tags[itrace.TAG_ENDPOINT] = span.Peer
 		}
+		if api.otelCompatibleID {
+			dkspan.Tags[tagSkyWalkingTraceID] = segment.TraceId
+		}
 
 		if buf, err := json.Marshal(span); err != nil {
 			api.log.Warn(err.Error())
@@ -268,6 +268,7 @@ func (sl *socketLogger) feed(pending []string) {
 			PlOption: &script.Option{
 				DisableAddStatusField: sl.opt.DisableAddStatusField,
 				IgnoreStatus:          sl.ignorePatterns,
+				Meta:                  sl.opt.Meta,
 			},
 		}
 	}
@@ -68,6 +68,8 @@ type Option struct {
 
 	// 添加tag
 	GlobalTags map[string]string
+	// 结构化元数据（例如 pod labels），不作为 tag/field 上传，pipeline 中通过 __meta["key"] 访问
+	Meta map[string]string
 	// 是否从文件起始处开始读取
 	// 注意，如果打开此项，可能会导致大量数据重复
 	FromBeginning bool
@@ -81,6 +83,9 @@ type Option struct {
 	ForwardFunc   ForwardFunc
 	IgnoreDeadLog time.Duration
 
+	// 日志文本的过滤（例如采集限额），返回 false 的文本将被丢弃
+	LineFilter func(text string) bool
+
 	// 是否开启阻塞发送模式
 	BlockingMode bool
 
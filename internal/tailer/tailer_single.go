@@ -462,6 +462,13 @@ func (t *Single) defaultHandler(lines []string) {
 }
 
 func (t *Single) feed(pending []string) {
+	if t.opt.LineFilter != nil {
+		pending = t.filter(pending)
+		if len(pending) == 0 {
+			return
+		}
+	}
+
 	// feed to remote
 	if t.opt.ForwardFunc != nil {
 		t.feedToRemote(pending)
@@ -479,6 +486,16 @@ func (t *Single) feed(pending []string) {
 	t.feedToIO(pending)
 }
 
+func (t *Single) filter(pending []string) []string {
+	res := pending[:0]
+	for _, text := range pending {
+		if t.opt.LineFilter(text) {
+			res = append(res, text)
+		}
+	}
+	return res
+}
+
 func (t *Single) feedToRemote(pending []string) {
 	for _, text := range pending {
 		err := t.opt.ForwardFunc(t.filename, text)
@@ -582,6 +599,7 @@ func (t *Single) feedToIO(pending []string) {
 		PlOption: &script.Option{
 			DisableAddStatusField: t.opt.DisableAddStatusField,
 			IgnoreStatus:          t.opt.IgnoreStatus,
+			Meta:                  t.opt.Meta,
 		},
 		Blocking: t.opt.BlockingMode,
 	}); err != nil {
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ContainerCase is implemented by cases running containers. On the case
+// failed, RunCases captures logs and inspect of these containers, so image-side
+// failures can be diagnosed without re-running.
+type ContainerCase interface {
+	Containers() []Container
+}
+
+const (
+	// capturedLogLines is tail lines of container logs kept in the case result,
+	// full logs are in the artifacts directory.
+	capturedLogLines = 200
+
+	// maxCapturedField is max length of captured fields in the case result.
+	maxCapturedField = 32 * 1024
+)
+
+// artifactsDir is directory of captured container logs and inspects, set by
+// env TESTING_ARTIFACTS_DIR, default to artifacts under the report directory.
+// Nothing written if both empty.
+func artifactsDir() string {
+	if dir := os.Getenv("TESTING_ARTIFACTS_DIR"); dir != "" {
+		return dir
+	}
+
+	if reportDir != "" {
+		return filepath.Join(reportDir, "artifacts")
+	}
+	return ""
+}
+
+var unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// CaptureContainers adds tail logs and inspect of containers into fields
+// container_logs and container_inspect of cr, and writes the full logs and
+// inspect into the artifacts directory. Containers failed to capture are
+// noted in the fields, and the first error returned.
+func CaptureContainers(cr *CaseResult, containers ...Container) error {
+	var (
+		logs, inspects strings.Builder
+		firstErr       error
+	)
+
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	dir := artifactsDir()
+	if dir != "" {
+		dir = filepath.Join(dir, unsafePathChars.ReplaceAllString(cr.Name+"_"+cr.Case, "_"))
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			setErr(err)
+			dir = ""
+		}
+	}
+
+	for _, c := range containers {
+		if c == nil {
+			continue
+		}
+
+		name := c.Name()
+		fmt.Fprintf(&logs, "==> %s <==\n", name)
+		fmt.Fprintf(&inspects, "==> %s <==\n", name)
+
+		full, err := c.Logs(0)
+		if err != nil {
+			setErr(fmt.Errorf("logs of %s: %w", name, err))
+			fmt.Fprintf(&logs, "logs failed: %s\n", err)
+		} else {
+			logs.WriteString(tailLines(full, capturedLogLines))
+		}
+
+		inspect, err := c.Inspect()
+		if err != nil {
+			setErr(fmt.Errorf("inspect %s: %w", name, err))
+			fmt.Fprintf(&inspects, "inspect failed: %s\n", err)
+		} else {
+			inspects.WriteString(inspect)
+		}
+
+		if dir == "" {
+			continue
+		}
+
+		file := filepath.Join(dir, unsafePathChars.ReplaceAllString(name, "_"))
+		if err := os.WriteFile(file+".log", []byte(full), 0o644); err != nil { //nolint:gosec
+			setErr(err)
+		}
+		if err := os.WriteFile(file+".inspect.json", []byte(inspect), 0o644); err != nil { //nolint:gosec
+			setErr(err)
+		}
+	}
+
+	cr.AddField("container_logs", truncateHead(logs.String(), maxCapturedField))
+	cr.AddField("container_inspect", truncateHead(inspects.String(), maxCapturedField))
+	if dir != "" {
+		cr.AddField("artifacts", dir)
+	}
+
+	return firstErr
+}
+
+// tailLines returns the last n lines of s.
+func tailLines(s string, n int) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// truncateHead keeps the last max bytes of s, the head of logs are less
+// helpful than the tail.
+func truncateHead(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return "...(truncated)\n" + s[len(s)-max:]
+}
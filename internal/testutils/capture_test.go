@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeContainer struct {
+	name       string
+	logs       string
+	inspectErr error
+}
+
+func (c *fakeContainer) Name() string                        { return c.name }
+func (c *fakeContainer) GetPort(id string) string            { return "" }
+func (c *fakeContainer) Exec(cmd []string) (int, error)      { return 0, nil }
+func (c *fakeContainer) Restart(timeout time.Duration) error { return nil }
+func (c *fakeContainer) Logs(tail int) (string, error)       { return c.logs, nil }
+func (c *fakeContainer) Purge() error                        { return nil }
+
+func (c *fakeContainer) Inspect() (string, error) {
+	if c.inspectErr != nil {
+		return "", c.inspectErr
+	}
+	return fmt.Sprintf(`[{"Name":%q}]`, c.name), nil
+}
+
+func TestCaptureContainers(t *T.T) {
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("line-%d", i))
+	}
+
+	redis := &fakeContainer{name: "redis-case", logs: strings.Join(lines, "\n") + "\n"}
+	proxy := &fakeContainer{name: "toxiproxy", logs: "started\n", inspectErr: errors.New("no such container")}
+
+	dir := t.TempDir()
+	t.Setenv("TESTING_ARTIFACTS_DIR", dir)
+
+	cr := &CaseResult{Name: "TestRedisInput/redis:6.0", Case: "redis:6.0"}
+	err := CaptureContainers(cr, redis, nil, proxy)
+	assert.ErrorContains(t, err, "inspect toxiproxy")
+
+	logs := cr.ExtraFields["container_logs"].(string)
+	assert.True(t, strings.HasPrefix(logs, "==> redis-case <==\nline-100\n"), logs[:50])
+	assert.NotContains(t, logs, "line-99\n")
+	assert.Contains(t, logs, "==> toxiproxy <==\nstarted\n")
+
+	inspect := cr.ExtraFields["container_inspect"].(string)
+	assert.Contains(t, inspect, `[{"Name":"redis-case"}]`)
+	assert.Contains(t, inspect, "inspect failed: no such container")
+
+	caseDir := filepath.Join(dir, "TestRedisInput_redis_6.0_redis_6.0")
+	assert.Equal(t, caseDir, cr.ExtraFields["artifacts"])
+
+	data, err := os.ReadFile(filepath.Join(caseDir, "redis-case.log"))
+	require.NoError(t, err)
+	assert.Equal(t, redis.logs, string(data))
+
+	data, err = os.ReadFile(filepath.Join(caseDir, "redis-case.inspect.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `[{"Name":"redis-case"}]`, string(data))
+}
+
+func TestTruncateHead(t *T.T) {
+	assert.Equal(t, "abc", truncateHead("abc", 3))
+	assert.Equal(t, "...(truncated)\nbc", truncateHead("abc", 2))
+	assert.Equal(t, "", tailLines("\n", 2))
+	assert.Equal(t, "b\nc\n", tailLines("a\nb\nc", 2))
+}
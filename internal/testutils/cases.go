@@ -0,0 +1,351 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+// TestingCase is an integration testing case run by RunCases. Cases are run
+// in parallel, so all states of the case should be held by itself, not by
+// package variables.
+type TestingCase interface {
+	// CaseName returns name of the sub-test.
+	CaseName() string
+
+	// Run runs the case within sub-test t.
+	Run(t *testing.T) error
+
+	// Result returns result of the case, its status and cost are set by RunCases.
+	Result() *CaseResult
+
+	// Cleanup releases resources of the case, such as the remote container.
+	// It's called even if Run failed.
+	Cleanup() error
+}
+
+// ResourceRequirer is implemented by cases with requirements other than
+// DefaultCaseResources on remote docker host.
+type ResourceRequirer interface {
+	Requirement() Resources
+}
+
+// ExclusiveResourcer is implemented by cases holding resources can not be
+// shared with other cases, such as fixed container name or host port on
+// remote docker host. Cases with the same exclusive resource are run one by one.
+type ExclusiveResourcer interface {
+	ExclusiveResources() []string
+}
+
+// Resources is the capacity of remote docker host, or the requirement of a case.
+type Resources struct {
+	CPU    float64
+	Memory int64 // in bytes
+}
+
+func (r Resources) add(x Resources) Resources {
+	return Resources{CPU: r.CPU + x.CPU, Memory: r.Memory + x.Memory}
+}
+
+func (r Resources) sub(x Resources) Resources {
+	return Resources{CPU: r.CPU - x.CPU, Memory: r.Memory - x.Memory}
+}
+
+func (r Resources) fits(total Resources) bool {
+	return r.CPU <= total.CPU && r.Memory <= total.Memory
+}
+
+// DefaultCaseResources is the requirement of cases not implementing ResourceRequirer.
+var DefaultCaseResources = Resources{CPU: 1, Memory: 512 << 20}
+
+// RemoteCapacity returns CPUs and memory of remote host.
+func RemoteCapacity() (*Resources, error) {
+	rt, err := GetRemote().ContainerRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.Capacity()
+}
+
+type runOption struct {
+	capacity    *Resources
+	maxParallel int
+}
+
+type RunOption func(*runOption)
+
+// WithCapacity set capacity of remote docker host, it's queried from the
+// docker daemon if not set.
+func WithCapacity(r Resources) RunOption {
+	return func(o *runOption) { o.capacity = &r }
+}
+
+// WithMaxParallel limits cases running at the same time, env
+// TESTING_MAX_PARALLEL also works, set it to 1 to run cases serially.
+func WithMaxParallel(n int) RunOption {
+	return func(o *runOption) { o.maxParallel = n }
+}
+
+// budget admits cases while their requirements fit into the capacity. A case
+// requires more than the whole capacity runs alone.
+type budget struct {
+	mtx  sync.Mutex
+	cond *sync.Cond
+
+	capacity    Resources
+	used        Resources
+	running     int
+	maxParallel int
+	held        map[string]bool // exclusive resources held by running cases
+}
+
+func newBudget(capacity Resources, maxParallel int) *budget {
+	b := &budget{capacity: capacity, maxParallel: maxParallel, held: map[string]bool{}}
+	b.cond = sync.NewCond(&b.mtx)
+	return b
+}
+
+func (b *budget) admissible(need Resources, exclusive []string) bool {
+	for _, x := range exclusive {
+		if b.held[x] {
+			return false
+		}
+	}
+
+	if b.running == 0 {
+		return true
+	}
+
+	if b.maxParallel > 0 && b.running >= b.maxParallel {
+		return false
+	}
+
+	return b.used.add(need).fits(b.capacity)
+}
+
+func (b *budget) acquire(need Resources, exclusive []string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for !b.admissible(need, exclusive) {
+		b.cond.Wait()
+	}
+
+	for _, x := range exclusive {
+		b.held[x] = true
+	}
+	b.used = b.used.add(need)
+	b.running++
+}
+
+func (b *budget) release(need Resources, exclusive []string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, x := range exclusive {
+		delete(b.held, x)
+	}
+	b.used = b.used.sub(need)
+	b.running--
+	b.cond.Broadcast()
+}
+
+// SuiteResult is the aggregated result of cases run by RunCases.
+type SuiteResult struct {
+	Name string
+
+	Cases,
+	Passed,
+	Failed,
+	Skipped int
+
+	MaxParallel int
+	Capacity    Resources
+	Cost        time.Duration
+
+	mtx sync.Mutex
+}
+
+func (sr *SuiteResult) add(cr *CaseResult) {
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+
+	sr.Cases++
+	switch cr.Status {
+	case TestPassed:
+		sr.Passed++
+	case TestFailed:
+		sr.Failed++
+	case TestSkipped:
+		sr.Skipped++
+	case TestStatusUnknown:
+	}
+}
+
+// Status is failed if any case failed.
+func (sr *SuiteResult) Status() TestStatus {
+	switch {
+	case sr.Failed > 0:
+		return TestFailed
+	case sr.Cases > 0 && sr.Skipped == sr.Cases:
+		return TestSkipped
+	default:
+		return TestPassed
+	}
+}
+
+func (sr *SuiteResult) LineProtocol() string {
+	tags := map[string]string{
+		"name":   sr.Name,
+		"status": sr.Status().String(),
+		"host":   hostname,
+	}
+
+	fields := map[string]any{
+		"cost":         int64(sr.Cost),
+		"cases":        sr.Cases,
+		"passed":       sr.Passed,
+		"failed":       sr.Failed,
+		"skipped":      sr.Skipped,
+		"max_parallel": sr.MaxParallel,
+		"cpu":          sr.Capacity.CPU,
+		"memory":       sr.Capacity.Memory,
+	}
+
+	p := point.NewPointV2([]byte("testing_suite"), append(point.NewTags(tags), point.NewKVs(fields)...))
+
+	return p.LineProto() + "\n"
+}
+
+// RunCases runs cases as parallel sub-tests of t. Cases running at the same
+// time are limited by their requirements and capacity of remote docker host,
+// and cases with the same exclusive resource never run at the same time.
+// Result of each case is flushed on it done, and a SuiteResult aggregated
+// flushed after all cases done.
+func RunCases(t *testing.T, cases []TestingCase, opts ...RunOption) {
+	t.Helper()
+
+	ro := &runOption{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(ro)
+		}
+	}
+
+	if v := os.Getenv("TESTING_MAX_PARALLEL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			ro.maxParallel = n
+		}
+	}
+
+	if ro.capacity == nil {
+		c, err := RemoteCapacity()
+		if err != nil {
+			t.Logf("get capacity of remote docker host: %s, run cases serially", err)
+			def := DefaultCaseResources
+			c = &def
+			ro.maxParallel = 1
+		}
+		ro.capacity = c
+	}
+
+	b := newBudget(*ro.capacity, ro.maxParallel)
+	sr := &SuiteResult{
+		Name:        t.Name(),
+		MaxParallel: ro.maxParallel,
+		Capacity:    *ro.capacity,
+	}
+
+	t.Logf("testing %d cases, capacity: %+#v, max parallel: %d", len(cases), *ro.capacity, ro.maxParallel)
+
+	start := time.Now()
+
+	// cleanups of t run after all parallel sub-tests done.
+	t.Cleanup(func() {
+		sr.Cost = time.Since(start)
+		if err := Flush(sr); err != nil {
+			t.Logf("flush suite result: %s", err)
+		}
+	})
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.CaseName(), func(t *testing.T) {
+			t.Parallel()
+			runCase(t, tc, b, sr)
+		})
+	}
+}
+
+func runCase(t *testing.T, tc TestingCase, b *budget, sr *SuiteResult) {
+	t.Helper()
+
+	need := DefaultCaseResources
+	if rr, ok := tc.(ResourceRequirer); ok {
+		need = rr.Requirement()
+	}
+
+	var exclusive []string
+	if er, ok := tc.(ExclusiveResourcer); ok {
+		exclusive = er.ExclusiveResources()
+	}
+
+	b.acquire(need, exclusive)
+
+	// cleanups run in LIFO order: resources of the case released before the budget.
+	t.Cleanup(func() { b.release(need, exclusive) })
+	t.Cleanup(func() {
+		if err := tc.Cleanup(); err != nil {
+			t.Errorf("cleanup: %s", err)
+		}
+	})
+
+	cr := tc.Result()
+	start := time.Now()
+
+	// deferred for Run may exit by t.FailNow or t.Skip.
+	defer func() {
+		cr.Cost = time.Since(start)
+
+		// captured before Cleanup, containers of the case are still there.
+		if t.Failed() {
+			if cc, ok := tc.(ContainerCase); ok {
+				if err := CaptureContainers(cr, cc.Containers()...); err != nil {
+					t.Logf("capture containers: %s", err)
+				}
+			}
+		}
+
+		switch {
+		case t.Skipped():
+			cr.Status = TestSkipped
+		case t.Failed():
+			cr.Status = TestFailed
+		default:
+			cr.Status = TestPassed
+		}
+
+		sr.add(cr)
+
+		if err := Flush(cr); err != nil {
+			t.Errorf("flush case result: %s", err)
+		}
+	}()
+
+	t.Logf("testing %s...", tc.CaseName())
+
+	if err := tc.Run(t); err != nil {
+		cr.FailedMessage = err.Error()
+		t.Error(err)
+	}
+}
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCase struct {
+	name    string
+	need    Resources
+	cr      *CaseResult
+	err     error
+	cleaned bool
+
+	tracker *runningTracker
+}
+
+func (fc *fakeCase) CaseName() string       { return fc.name }
+func (fc *fakeCase) Result() *CaseResult    { return fc.cr }
+func (fc *fakeCase) Requirement() Resources { return fc.need }
+func (fc *fakeCase) Cleanup() error         { fc.cleaned = true; return nil }
+func (fc *fakeCase) Run(t *T.T) error {
+	t.Helper()
+
+	fc.tracker.enter(fc.need)
+	defer fc.tracker.leave(fc.need)
+
+	time.Sleep(50 * time.Millisecond)
+	return fc.err
+}
+
+// runningTracker records max cases and CPUs used at the same time.
+type runningTracker struct {
+	mtx             sync.Mutex
+	running, maxRun int
+	cpu, maxCPU     float64
+}
+
+func (rt *runningTracker) enter(need Resources) {
+	rt.mtx.Lock()
+	defer rt.mtx.Unlock()
+
+	rt.running++
+	rt.cpu += need.CPU
+	if rt.running > rt.maxRun {
+		rt.maxRun = rt.running
+	}
+	if rt.cpu > rt.maxCPU {
+		rt.maxCPU = rt.cpu
+	}
+}
+
+func (rt *runningTracker) leave(need Resources) {
+	rt.mtx.Lock()
+	defer rt.mtx.Unlock()
+
+	rt.running--
+	rt.cpu -= need.CPU
+}
+
+func TestRunCases(t *T.T) {
+	old := metricFile
+	metricFile = filepath.Join(t.TempDir(), "testing_metrics")
+	t.Cleanup(func() { metricFile = old })
+
+	rt := &runningTracker{}
+
+	var cases []*fakeCase
+	for i := 0; i < 8; i++ {
+		cases = append(cases, &fakeCase{
+			name:    fmt.Sprintf("case-%d", i),
+			need:    Resources{CPU: 1, Memory: 1 << 20},
+			cr:      &CaseResult{Name: "TestRunCases", Case: fmt.Sprintf("case-%d", i)},
+			tracker: rt,
+		})
+	}
+
+	// requires more than the capacity, should run alone
+	cases = append(cases, &fakeCase{
+		name:    "huge",
+		need:    Resources{CPU: 8, Memory: 1 << 20},
+		cr:      &CaseResult{Name: "TestRunCases", Case: "huge"},
+		tracker: rt,
+	})
+
+	t.Run("run", func(t *T.T) {
+		var arr []TestingCase
+		for _, c := range cases {
+			arr = append(arr, c)
+		}
+
+		RunCases(t, arr, WithCapacity(Resources{CPU: 3, Memory: 1 << 30}))
+	})
+
+	// sub-tests running in parallel are also limited by `go test -parallel`
+	assert.LessOrEqual(t, rt.maxRun, 3)
+	assert.LessOrEqual(t, rt.maxCPU, 8.0)
+
+	for _, c := range cases {
+		assert.True(t, c.cleaned, c.name)
+		assert.Equal(t, TestPassed, c.cr.Status, c.name)
+		assert.True(t, c.cr.Cost > 0, c.name)
+	}
+
+	data, err := os.ReadFile(metricFile)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, len(cases)+1)
+
+	suite := lines[len(lines)-1]
+	assert.True(t, strings.HasPrefix(suite, "testing_suite,"), suite)
+	assert.Contains(t, suite, "status=pass")
+	assert.Contains(t, suite, "cases=9i")
+	assert.Contains(t, suite, "passed=9i")
+}
+
+func TestBudget(t *T.T) {
+	t.Run("max-parallel", func(t *T.T) {
+		b := newBudget(Resources{CPU: 100, Memory: 100}, 2)
+		need := Resources{CPU: 1, Memory: 1}
+
+		b.acquire(need, nil)
+		b.acquire(need, nil)
+
+		acquired := make(chan struct{})
+		go func() {
+			b.acquire(need, nil)
+			close(acquired)
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("should not acquired over max parallel")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		b.release(need, nil)
+		<-acquired
+		assert.Equal(t, 2, b.running)
+	})
+
+	t.Run("memory", func(t *T.T) {
+		b := newBudget(Resources{CPU: 100, Memory: 10}, 0)
+
+		b.acquire(Resources{CPU: 1, Memory: 6}, nil)
+
+		acquired := make(chan struct{})
+		go func() {
+			b.acquire(Resources{CPU: 1, Memory: 6}, nil)
+			close(acquired)
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("should not acquired over memory")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		b.release(Resources{CPU: 1, Memory: 6}, nil)
+		<-acquired
+		assert.Equal(t, Resources{CPU: 1, Memory: 6}, b.used)
+	})
+
+	t.Run("exclusive", func(t *T.T) {
+		b := newBudget(Resources{CPU: 100, Memory: 100}, 0)
+		need := Resources{CPU: 1, Memory: 1}
+
+		b.acquire(need, []string{"nginx", "80/tcp"})
+		b.acquire(need, []string{"redis"})
+
+		acquired := make(chan struct{})
+		go func() {
+			b.acquire(need, []string{"nginx-vts", "80/tcp"})
+			close(acquired)
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("should not acquired on exclusive resource held")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		b.release(need, []string{"nginx", "80/tcp"})
+		<-acquired
+		assert.Equal(t, map[string]bool{"redis": true, "nginx-vts": true, "80/tcp": true}, b.held)
+	})
+}
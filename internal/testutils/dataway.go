@@ -0,0 +1,318 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const writePrefix = "/v1/write/"
+
+// MockDataway is a HTTP server accepting points on /v1/write/<category>, like
+// dataway or the datakit write API. Bodies in line-protocol, JSON or
+// protobuf(gzipped or not) are parsed into points and recorded per category,
+// so integration tests can check points without comparing raw bodies.
+type MockDataway struct {
+	srv *http.Server
+	ln  net.Listener
+
+	mtx      sync.Mutex
+	points   map[string][]*point.Point
+	requests map[string]int
+	errs     []error
+	failures []int         // HTTP status to respond to following requests
+	updated  chan struct{} // closed and renewed on every request
+}
+
+// NewMockDataway starts a mock dataway on an ephemeral port of all
+// interfaces, so that it's reachable from containers. It's closed on
+// the cleanup of t.
+func NewMockDataway(t testing.TB) *MockDataway {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen mock dataway: %s", err)
+	}
+
+	md := &MockDataway{
+		ln:       ln,
+		points:   map[string][]*point.Point{},
+		requests: map[string]int{},
+		updated:  make(chan struct{}),
+	}
+
+	md.srv = &http.Server{Handler: md} //nolint:gosec
+	go func() {
+		if err := md.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.Logf("mock dataway exited: %s", err)
+		}
+	}()
+
+	t.Cleanup(md.Close)
+	return md
+}
+
+// Port returns the listening port.
+func (md *MockDataway) Port() int {
+	return md.ln.Addr().(*net.TCPAddr).Port
+}
+
+// URL returns the base URL of the mock dataway on localhost.
+func (md *MockDataway) URL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", md.Port())
+}
+
+// Close shuts down the mock dataway.
+func (md *MockDataway) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_ = md.srv.Shutdown(ctx)
+}
+
+func (md *MockDataway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, writePrefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	category := strings.TrimPrefix(r.URL.Path, writePrefix)
+
+	if status := md.accept(category); status != 0 {
+		_, _ = io.Copy(io.Discard, r.Body)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	pts, err := decodeBody(r)
+	if err != nil {
+		md.record(category, nil, fmt.Errorf("%s: %w", r.URL.Path, err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	md.record(category, pts, nil)
+	w.WriteHeader(http.StatusOK)
+}
+
+func decodeBody(r *http.Request) ([]*point.Point, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close() //nolint:errcheck
+		body = zr
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := point.LineProtocol
+	switch ct := r.Header.Get("Content-Type"); {
+	case ct == point.PBContentType:
+		enc = point.Protobuf
+	case strings.Contains(ct, "json"), bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		enc = point.JSON
+	}
+
+	var opts []point.Option
+	if p := r.URL.Query().Get("precision"); p != "" {
+		opts = append(opts, point.WithPrecision(point.PrecStr(p)))
+	}
+
+	dec := point.GetDecoder(point.WithDecEncoding(enc))
+	defer point.PutDecoder(dec)
+
+	return dec.Decode(data, opts...)
+}
+
+// accept counts the request of category, and returns the status of
+// injected failure, 0 if the request should be accepted.
+func (md *MockDataway) accept(category string) int {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	md.requests[category]++
+
+	if len(md.failures) == 0 {
+		return 0
+	}
+
+	status := md.failures[0]
+	md.failures = md.failures[1:]
+
+	close(md.updated)
+	md.updated = make(chan struct{})
+
+	return status
+}
+
+// FailNext responds status, such as 500 or 503, to the next n write
+// requests and drops their points, to exercise retry and fail-cache of
+// the dataway writer.
+func (md *MockDataway) FailNext(n, status int) {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	for i := 0; i < n; i++ {
+		md.failures = append(md.failures, status)
+	}
+}
+
+// Requests returns count of write requests received on the category,
+// failed ones included.
+func (md *MockDataway) Requests(category string) int {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	return md.requests[category]
+}
+
+func (md *MockDataway) record(category string, pts []*point.Point, err error) {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	if err != nil {
+		md.errs = append(md.errs, err)
+	}
+
+	md.points[category] = append(md.points[category], pts...)
+
+	close(md.updated)
+	md.updated = make(chan struct{})
+}
+
+// Points returns points received of the category, such as metric/logging.
+func (md *MockDataway) Points(category string) []*point.Point {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	return append([]*point.Point(nil), md.points[category]...)
+}
+
+// Errors returns errors of bodies failed to parse.
+func (md *MockDataway) Errors() []error {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	return append([]error(nil), md.errs...)
+}
+
+// Reset drops all received points, errors and injected failures.
+func (md *MockDataway) Reset() {
+	md.mtx.Lock()
+	defer md.mtx.Unlock()
+
+	md.points = map[string][]*point.Point{}
+	md.requests = map[string]int{}
+	md.errs = nil
+	md.failures = nil
+}
+
+// Await waits until cond returns true, cond is checked on start and after
+// every request received. It returns false on timeout.
+func (md *MockDataway) Await(timeout time.Duration, cond func(md *MockDataway) bool) bool {
+	tmr := time.NewTimer(timeout)
+	defer tmr.Stop()
+
+	for {
+		md.mtx.Lock()
+		updated := md.updated
+		md.mtx.Unlock()
+
+		if cond(md) {
+			return true
+		}
+
+		select {
+		case <-updated:
+		case <-tmr.C:
+			return false
+		}
+	}
+}
+
+// AwaitPoints waits until at least n points of the category received.
+func (md *MockDataway) AwaitPoints(category string, n int, timeout time.Duration) ([]*point.Point, error) {
+	var pts []*point.Point
+	if md.Await(timeout, func(md *MockDataway) bool {
+		pts = md.Points(category)
+		return len(pts) >= n
+	}) {
+		return pts, nil
+	}
+
+	return pts, fmt.Errorf("expect at least %d %s points in %s, got %d", n, category, timeout, len(pts))
+}
+
+// FindPoint returns the first point of the category with the name and all
+// the tags and fields, nil if not found. Field values are compared in their
+// printed form, so that an int field matches the float of the same value
+// from JSON body.
+func (md *MockDataway) FindPoint(category, name string, tags map[string]string, fields map[string]any) *point.Point {
+	for _, pt := range md.Points(category) {
+		if matchPoint(pt, name, tags, fields) {
+			return pt
+		}
+	}
+	return nil
+}
+
+// AssertPoint fails t if no point matched, see FindPoint.
+func (md *MockDataway) AssertPoint(t testing.TB, category, name string, tags map[string]string, fields map[string]any) bool {
+	t.Helper()
+
+	if md.FindPoint(category, name, tags, fields) != nil {
+		return true
+	}
+
+	var got []string
+	for _, pt := range md.Points(category) {
+		got = append(got, pt.LineProto())
+	}
+
+	t.Errorf("no %s point %s matched tags %v, fields %v, got:\n%s", category, name, tags, fields, strings.Join(got, "\n"))
+	return false
+}
+
+func matchPoint(pt *point.Point, name string, tags map[string]string, fields map[string]any) bool {
+	if string(pt.Name()) != name {
+		return false
+	}
+
+	for k, v := range tags {
+		if string(pt.GetTag([]byte(k))) != v {
+			return false
+		}
+	}
+
+	got := pt.InfluxFields()
+	for k, v := range fields {
+		if x, ok := got[k]; !ok || fmt.Sprint(x) != fmt.Sprint(v) {
+			return false
+		}
+	}
+
+	return true
+}
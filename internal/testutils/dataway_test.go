@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *T.T, url, contentType string, body []byte, gz bool) int {
+	t.Helper()
+
+	if gz {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Type", contentType)
+	if gz {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	return resp.StatusCode
+}
+
+func TestMockDataway(t *T.T) {
+	md := NewMockDataway(t)
+
+	t.Run("line-protocol", func(t *T.T) {
+		defer md.Reset()
+
+		code := post(t, md.URL()+"/v1/write/metric?precision=s", "text/plain",
+			[]byte("cpu,host=h1 usage=12.5,cores=4i 1680000000\ncpu,host=h2 usage=1.5 1680000000"), true)
+		assert.Equal(t, http.StatusOK, code)
+
+		pts, err := md.AwaitPoints("metric", 2, time.Second)
+		require.NoError(t, err)
+		assert.True(t, time.Unix(1680000000, 0).Equal(pts[0].Time()))
+
+		md.AssertPoint(t, "metric", "cpu", map[string]string{"host": "h1"}, map[string]any{"cores": 4, "usage": 12.5})
+		assert.Nil(t, md.FindPoint("metric", "cpu", map[string]string{"host": "h3"}, nil))
+		assert.Empty(t, md.Points("logging"))
+	})
+
+	t.Run("json", func(t *T.T) {
+		defer md.Reset()
+
+		code := post(t, md.URL()+"/v1/write/logging", "application/json",
+			[]byte(`[{"measurement": "nginx", "tags": {"t1": "v1"}, "fields": {"message": "hello", "code": 200}, "time": null}]`), false)
+		assert.Equal(t, http.StatusOK, code)
+
+		_, err := md.AwaitPoints("logging", 1, time.Second)
+		require.NoError(t, err)
+
+		// JSON numbers are floats, matched with int
+		md.AssertPoint(t, "logging", "nginx", map[string]string{"t1": "v1"}, map[string]any{"message": "hello", "code": 200})
+	})
+
+	t.Run("invalid-body", func(t *T.T) {
+		defer md.Reset()
+
+		code := post(t, md.URL()+"/v1/write/metric", "text/plain", []byte("cpu,host=h1"), false)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		require.Len(t, md.Errors(), 1)
+		assert.True(t, strings.HasPrefix(md.Errors()[0].Error(), "/v1/write/metric"))
+	})
+
+	t.Run("fail-next", func(t *T.T) {
+		defer md.Reset()
+
+		md.FailNext(2, http.StatusServiceUnavailable)
+
+		for i := 0; i < 3; i++ {
+			code := post(t, md.URL()+"/v1/write/logging", "text/plain", []byte("nginx message=\"hello\" 1680000000000000000"), false)
+			if i < 2 {
+				assert.Equal(t, http.StatusServiceUnavailable, code)
+			} else {
+				assert.Equal(t, http.StatusOK, code)
+			}
+		}
+
+		assert.Equal(t, 3, md.Requests("logging"))
+		assert.Len(t, md.Points("logging"), 1)
+	})
+
+	t.Run("await-timeout", func(t *T.T) {
+		_, err := md.AwaitPoints("object", 1, 100*time.Millisecond)
+		assert.Error(t, err)
+	})
+}
@@ -19,6 +19,16 @@ type RemoteInfo struct {
 	// docker info
 	Port string
 	Host string
+
+	// Runtime is the container runtime, see ContainerRuntime.
+	Runtime string
+
+	// Socket is the docker(or podman) API endpoint used instead of Host:Port,
+	// such as unix:///run/podman/podman.sock.
+	Socket string
+
+	// Nerdctl is the command to run nerdctl for runtime containerd.
+	Nerdctl string
 }
 
 // RemoteAPIOK test if remote HTTP API ok.
@@ -36,7 +46,7 @@ func (i *RemoteInfo) PortOK(port string, args ...time.Duration) bool {
 		err error
 	)
 
-	addr := fmt.Sprintf("%s:%s", i.Host, port)
+	addr := net.JoinHostPort(i.Host, port)
 
 	if len(args) > 0 {
 		iter := time.NewTicker(time.Second)
@@ -83,11 +93,33 @@ func (i *RemoteInfo) TCPURL() string {
 	return "tcp://" + net.JoinHostPort(i.Host, i.Port)
 }
 
+// Endpoint get docker(or podman) API endpoint.
+func (i *RemoteInfo) Endpoint() string {
+	if i.Socket != "" {
+		return i.Socket
+	}
+	return i.TCPURL()
+}
+
 // GetRemote only return the IP of remote node.
 func GetRemote() *RemoteInfo {
 	ri := &RemoteInfo{
-		Host: "0.0.0.0",
-		Port: "2375",
+		Host:    "0.0.0.0",
+		Port:    "2375",
+		Runtime: RuntimeDocker,
+		Nerdctl: "nerdctl",
+	}
+
+	if v := os.Getenv("REMOTE_RUNTIME"); v != "" {
+		ri.Runtime = v
+	}
+
+	if v := os.Getenv("REMOTE_SOCKET"); v != "" {
+		ri.Socket = v
+	}
+
+	if v := os.Getenv("REMOTE_NERDCTL"); v != "" {
+		ri.Nerdctl = v
 	}
 
 	if v := os.Getenv("REMOTE_HOST"); v != "" {
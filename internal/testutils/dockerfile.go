@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DockerfileVars are per-case variables of Dockerfile template, so one
+// template drives a version matrix, such as:
+//
+//	FROM python:{{.Version}}-slim
+//	RUN apt-get update && apt-get install -y {{join .Packages " "}}
+type DockerfileVars struct {
+	// Version of the service, such as 7.17.9.
+	Version string
+
+	// Arch of the image, such as amd64 or arm64.
+	Arch string
+
+	// Packages are extra packages installed in the image.
+	Packages []string
+
+	// Extra are any other variables, such as {{.Extra.PythonVersion}}.
+	Extra map[string]string
+}
+
+var dockerfileFuncs = template.FuncMap{
+	"join": strings.Join,
+
+	// default returns def if v is empty, such as {{default "amd64" .Arch}}.
+	"default": func(def, v string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// RenderDockerfile renders Dockerfile text as Go template with vars. Text
+// without template actions is kept as is, shell variables like ${A} and $a
+// need no escaping.
+func RenderDockerfile(text string, vars *DockerfileVars) (string, error) {
+	if vars == nil {
+		vars = &DockerfileVars{}
+	}
+
+	tmpl, err := template.New("Dockerfile").Funcs(dockerfileFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse Dockerfile template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render Dockerfile template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// WriteDockerfile renders the Dockerfile template into a new temporary
+// directory, which should be removed after the image built.
+func WriteDockerfile(text string, vars *DockerfileVars) (dir, path string, err error) {
+	content, err := RenderDockerfile(text, vars)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir, err = os.MkdirTemp("", "dockerfiles_")
+	if err != nil {
+		return "", "", err
+	}
+
+	path = filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec
+		os.RemoveAll(dir) //nolint:errcheck,gosec
+		return "", "", err
+	}
+
+	return dir, path, nil
+}
+
+// sortedBuildArgs returns build args in KEY=VALUE, sorted by key.
+func sortedBuildArgs(args map[string]string) []string {
+	arr := make([]string, 0, len(args))
+	for k, v := range args {
+		arr = append(arr, k+"="+v)
+	}
+	sort.Strings(arr)
+	return arr
+}
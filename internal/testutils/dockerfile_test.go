@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDockerfile(t *T.T) {
+	t.Run("vars", func(t *T.T) {
+		text := `ARG BASE=debian
+FROM {{default "amd64" .Arch}}/python:{{.Version}}-slim
+RUN apt-get install -y {{join .Packages " "}}
+RUN sed -i '$a hosts: ["${DATAKIT_HOST}:5044"]' /etc/some.yml
+ENV PY={{.Extra.PythonVersion}}`
+
+		out, err := RenderDockerfile(text, &DockerfileVars{
+			Version:  "3.11",
+			Packages: []string{"curl", "procps"},
+			Extra:    map[string]string{"PythonVersion": "3"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, `ARG BASE=debian
+FROM amd64/python:3.11-slim
+RUN apt-get install -y curl procps
+RUN sed -i '$a hosts: ["${DATAKIT_HOST}:5044"]' /etc/some.yml
+ENV PY=3`, out)
+	})
+
+	t.Run("raw", func(t *T.T) {
+		text := "FROM nginx:latest\nRUN echo $a"
+		out, err := RenderDockerfile(text, nil)
+		require.NoError(t, err)
+		assert.Equal(t, text, out)
+	})
+
+	t.Run("missing-extra", func(t *T.T) {
+		_, err := RenderDockerfile("FROM {{.Extra.Image}}", &DockerfileVars{Extra: map[string]string{}})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid", func(t *T.T) {
+		_, err := RenderDockerfile("FROM {{.Version", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteDockerfile(t *T.T) {
+	dir, path, err := WriteDockerfile("FROM redis:{{.Version}}", &DockerfileVars{Version: "7.0"})
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	assert.Equal(t, dir, filepath.Dir(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "FROM redis:7.0", string(data))
+}
+
+func TestNerdctlBuild(t *T.T) {
+	cmd, argsLog := fakeNerdctl(t)
+
+	rt, err := (&RemoteInfo{Runtime: RuntimeContainerd, Nerdctl: cmd}).ContainerRuntime()
+	require.NoError(t, err)
+
+	dir, path, err := WriteDockerfile("ARG VERSION\nFROM redis:${VERSION}", nil)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	_, err = rt.Run(&RunOptions{
+		Name:       "redis-case",
+		Repository: "redis-testing",
+		Tag:        "7.0",
+		Dockerfile: path,
+		BuildArgs:  map[string]string{"VERSION": "7.0", "ARCH": "arm64"},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(argsLog)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"build -t redis-testing:7.0 --build-arg ARCH=arm64 --build-arg VERSION=7.0 -f - .",
+		"run -d --restart no --name redis-case redis-testing:7.0",
+	}, strings.Split(strings.TrimSpace(string(data)), "\n"))
+}
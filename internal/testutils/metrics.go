@@ -172,7 +172,9 @@ func (cr *CaseResult) LineProtocol() string {
 	return p.LineProto() + "\n"
 }
 
-// Flush write line-protocol data to file or remote HTTP server.
+// Flush write line-protocol data to file or remote HTTP server. If env
+// TESTING_REPORT_DIR set, CaseResults are also exported to JUnit XML and
+// HTML reports within it.
 func Flush(m TestingMetric) error {
 	mtx.Lock()
 	defer mtx.Unlock()
@@ -183,6 +185,12 @@ func Flush(m TestingMetric) error {
 		return err
 	}
 
+	if cr, ok := m.(*CaseResult); ok {
+		if err := addReport(cr); err != nil {
+			return err
+		}
+	}
+
 	if err := flushToDataway([]byte(lp)); err != nil {
 		return err
 	}
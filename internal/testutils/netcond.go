@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// NetworkCondition is a named set of toxics simulating a bad network.
+type NetworkCondition struct {
+	Name   string
+	Toxics []*Toxic
+}
+
+// BadNetworks returns network conditions commonly seen between datakit
+// and dataway: high latency, low bandwidth and connections reset.
+func BadNetworks() []*NetworkCondition {
+	return []*NetworkCondition{
+		{
+			Name:   "high-latency",
+			Toxics: []*Toxic{Latency(2*time.Second, 500*time.Millisecond)},
+		},
+		{
+			Name: "low-bandwidth",
+			Toxics: []*Toxic{
+				Bandwidth(16),
+				toxicOn(Bandwidth(16), Upstream, 1),
+			},
+		},
+		{
+			Name:   "connection-reset",
+			Toxics: []*Toxic{toxicOn(ResetPeer(0), Upstream, 0.5)},
+		},
+	}
+}
+
+func toxicOn(x *Toxic, stream string, toxicity float32) *Toxic {
+	x.Stream = stream
+	x.Toxicity = toxicity
+	return x
+}
+
+// Apply adds toxics of the condition to the proxy.
+func (p *Proxy) Apply(cond *NetworkCondition) error {
+	for _, x := range cond.Toxics {
+		if err := p.AddToxic(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunNetworkConditions runs fn as subtest of t under each condition, toxics
+// of the condition are applied to p before fn and cleared after it, so fn
+// can clear them earlier to check recovery of the network.
+func RunNetworkConditions(t *testing.T, p *Proxy, conds []*NetworkCondition, fn func(t *testing.T, cond *NetworkCondition)) {
+	t.Helper()
+
+	for _, cond := range conds {
+		cond := cond
+		t.Run(cond.Name, func(t *testing.T) {
+			if err := p.Apply(cond); err != nil {
+				t.Fatalf("apply %s: %s", cond.Name, err)
+			}
+
+			defer func() {
+				if err := p.ClearToxics(); err != nil {
+					t.Errorf("clear toxics of %s: %s", cond.Name, err)
+				}
+			}()
+
+			fn(t, cond)
+		})
+	}
+}
+
+// ProxyDataway starts a toxiproxy whose proxy forwards to md, the dataway
+// writer sends to Proxy.URL() to be affected by network conditions.
+func ProxyDataway(t testing.TB, md *MockDataway) (*Proxy, error) {
+	t.Helper()
+
+	ip, err := ExternalIP()
+	if err != nil {
+		return nil, err
+	}
+
+	port := RandPort("tcp")
+	tp, err := StartToxiproxy(t, port)
+	if err != nil {
+		return nil, err
+	}
+
+	return tp.CreateProxy("dataway-"+RandStrID(8), port, net.JoinHostPort(ip, strconv.Itoa(md.Port())))
+}
+
+// ExternalIP returns the first non-loopback IPv4 address of the host,
+// servers on the host are reachable by it from containers.
+func ExternalIP() (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return "", fmt.Errorf("addrs of %s: %w", iface.Name, err)
+		}
+
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+
+			if ip = ip.To4(); ip != nil {
+				return ip.String(), nil
+			}
+		}
+	}
+
+	return "", errors.New("no external IPv4 address found")
+}
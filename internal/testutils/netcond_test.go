@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunNetworkConditions(t *T.T) {
+	srv, requests := fakeToxiproxy(t)
+
+	t.Setenv("TOXIPROXY_URL", srv.URL)
+
+	tp, err := StartToxiproxy(t, 18080)
+	require.NoError(t, err)
+
+	p, err := tp.CreateProxy("dataway", 18080, "10.0.0.1:9528")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:18080", p.URL())
+
+	var names []string
+	RunNetworkConditions(t, p, BadNetworks(), func(t *T.T, cond *NetworkCondition) {
+		names = append(names, cond.Name)
+		assert.Len(t, p.toxics, len(cond.Toxics))
+	})
+
+	assert.Equal(t, []string{"high-latency", "low-bandwidth", "connection-reset"}, names)
+	assert.Empty(t, p.toxics)
+
+	var added, removed []string
+	for _, r := range requests()[1:] {
+		switch r.method {
+		case "POST":
+			added = append(added, r.body["name"].(string))
+		case "DELETE":
+			removed = append(removed, r.path)
+		}
+	}
+
+	assert.Equal(t, []string{
+		"latency_downstream",
+		"bandwidth_downstream", "bandwidth_upstream",
+		"reset_peer_upstream",
+	}, added)
+
+	assert.Equal(t, []string{
+		"/proxies/dataway/toxics/latency_downstream",
+		"/proxies/dataway/toxics/bandwidth_downstream",
+		"/proxies/dataway/toxics/bandwidth_upstream",
+		"/proxies/dataway/toxics/reset_peer_upstream",
+	}, removed)
+}
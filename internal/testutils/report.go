@@ -0,0 +1,311 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// reportDir is directory of JUnit XML and HTML reports, set by env
+	// TESTING_REPORT_DIR, no report written if empty.
+	reportDir  = os.Getenv("TESTING_REPORT_DIR")
+	reportName = strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+	runStart   = time.Now()
+
+	caseResults []*CaseResult
+)
+
+// addReport collects cr and rewrites reports of the test run, so the reports
+// are complete whenever the test process exits. Should be called within mtx.
+func addReport(cr *CaseResult) error {
+	if reportDir == "" {
+		return nil
+	}
+
+	x := *cr
+	caseResults = append(caseResults, &x)
+
+	if err := os.MkdirAll(reportDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for file, export := range map[string]func(io.Writer, []*CaseResult) error{
+		"junit-" + reportName + ".xml":   ExportJUnit,
+		"report-" + reportName + ".html": ExportHTML,
+	} {
+		if err := exportToFile(filepath.Join(reportDir, file), export); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportToFile(path string, export func(io.Writer, []*CaseResult) error) error {
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	return export(f, caseResults)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Hostname  string          `xml:"hostname,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+	SystemErr  string          `xml:"system-err,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(du time.Duration) string {
+	return fmt.Sprintf("%.3f", du.Seconds())
+}
+
+// suiteName returns top-level test name of the case, such as TestRedisInput
+// of TestRedisInput/remote-redis.
+func (cr *CaseResult) suiteName() string {
+	return strings.SplitN(cr.Name, "/", 2)[0]
+}
+
+func (cr *CaseResult) caseName() string {
+	if cr.Case != "" {
+		return cr.Case
+	}
+	return cr.Name
+}
+
+// image returns image:tag of the case, empty if not set.
+func (cr *CaseResult) image() string {
+	img := cr.ExtraTags["image"]
+	if img == "" {
+		return ""
+	}
+
+	if tag := cr.ExtraTags["image_tag"]; tag != "" {
+		return img + ":" + tag
+	}
+	return img
+}
+
+// ExportJUnit writes results in JUnit XML, one testsuite per top-level test,
+// so CI systems(such as GitLab, Jenkins) can display them.
+func ExportJUnit(w io.Writer, results []*CaseResult) error {
+	suites := junitTestSuites{}
+	idx := map[string]int{}
+	costs := map[int]time.Duration{}
+	var total time.Duration
+
+	for _, cr := range results {
+		name := cr.suiteName()
+		i, ok := idx[name]
+		if !ok {
+			i = len(suites.Suites)
+			idx[name] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{
+				Name:      name,
+				Timestamp: runStart.Format("2006-01-02T15:04:05"),
+				Hostname:  hostname,
+			})
+		}
+
+		tc := junitTestCase{
+			Name:      cr.caseName(),
+			ClassName: cr.Name,
+			Time:      seconds(cr.Cost),
+			SystemOut: cr.Message,
+		}
+
+		if logs, ok := cr.ExtraFields["container_logs"].(string); ok {
+			tc.SystemErr = logs
+		}
+
+		for _, k := range sortedKeys(cr.ExtraTags) {
+			tc.Properties = append(tc.Properties, junitProperty{Name: k, Value: cr.ExtraTags[k]})
+		}
+
+		s := &suites.Suites[i]
+		switch cr.Status {
+		case TestFailed:
+			tc.Failure = &junitMessage{Message: firstLine(cr.FailedMessage), Text: cr.FailedMessage}
+			s.Failures++
+			suites.Failures++
+		case TestSkipped:
+			tc.Skipped = &junitMessage{Message: firstLine(cr.Message)}
+			s.Skipped++
+			suites.Skipped++
+		case TestPassed, TestStatusUnknown:
+		}
+
+		s.Cases = append(s.Cases, tc)
+		s.Tests++
+		suites.Tests++
+
+		costs[i] += cr.Cost
+		total += cr.Cost
+	}
+
+	for i := range suites.Suites {
+		suites.Suites[i].Time = seconds(costs[i])
+	}
+	suites.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Testing report: {{.Name}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.pass { color: green; }
+.fail { color: red; }
+.skip { color: gray; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Testing report: {{.Name}}</h1>
+<p>Host: {{.Host}}, started at {{.Start}}</p>
+<p>Total: {{.Total}}, passed: {{.Passed}}, failed: {{.Failed}}, skipped: {{.Skipped}}, cost: {{.Cost}}</p>
+<table>
+<tr><th>Test</th><th>Case</th><th>Image</th><th>Status</th><th>Cost</th><th>Message</th></tr>
+{{- range .Cases}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.Case}}</td>
+<td>{{.Image}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.Cost}}</td>
+<td><pre>{{.Message}}</pre></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+type htmlCase struct {
+	Name, Case, Image, Status, Message string
+	Cost                               time.Duration
+}
+
+// ExportHTML writes summary of results in HTML.
+func ExportHTML(w io.Writer, results []*CaseResult) error {
+	data := struct {
+		Name, Host, Start              string
+		Total, Passed, Failed, Skipped int
+		Cost                           time.Duration
+		Cases                          []htmlCase
+	}{
+		Name:  reportName,
+		Host:  hostname,
+		Start: runStart.Format(time.RFC3339),
+		Total: len(results),
+	}
+
+	for _, cr := range results {
+		switch cr.Status {
+		case TestPassed:
+			data.Passed++
+		case TestFailed:
+			data.Failed++
+		case TestSkipped:
+			data.Skipped++
+		case TestStatusUnknown:
+		}
+
+		msg := cr.Message
+		if cr.FailedMessage != "" {
+			msg = cr.FailedMessage
+		}
+
+		data.Cost += cr.Cost
+		data.Cases = append(data.Cases, htmlCase{
+			Name:    cr.Name,
+			Case:    cr.Case,
+			Image:   cr.image(),
+			Status:  cr.Status.String(),
+			Message: msg,
+			Cost:    cr.Cost.Round(time.Millisecond),
+		})
+	}
+
+	data.Cost = data.Cost.Round(time.Millisecond)
+
+	return htmlReport.Execute(w, data)
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
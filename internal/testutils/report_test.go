@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testingResults() []*CaseResult {
+	return []*CaseResult{
+		{
+			Name:   "TestRedisInput/redis:6.0",
+			Case:   "remote-redis",
+			Status: TestPassed,
+			Cost:   1500 * time.Millisecond,
+			ExtraTags: map[string]string{
+				"image":     "redis",
+				"image_tag": "6.0",
+			},
+		},
+		{
+			Name:          "TestRedisInput/redis:7.0",
+			Case:          "remote-redis",
+			Status:        TestFailed,
+			Cost:          2 * time.Second,
+			FailedMessage: "no point received\n<detail>",
+			ExtraTags: map[string]string{
+				"image":     "redis",
+				"image_tag": "7.0",
+			},
+			ExtraFields: map[string]any{
+				"container_logs": "==> redis-case <==\nOOM command not allowed\n",
+			},
+		},
+		{
+			Name:    "TestMySQLInput",
+			Status:  TestSkipped,
+			Message: "remote docker not available",
+		},
+	}
+}
+
+func TestExportJUnit(t *T.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, ExportJUnit(buf, testingResults()))
+
+	t.Logf("junit:\n%s", buf.String())
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, "3.500", suites.Time)
+
+	require.Len(t, suites.Suites, 2)
+
+	redis := suites.Suites[0]
+	assert.Equal(t, "TestRedisInput", redis.Name)
+	assert.Equal(t, 2, redis.Tests)
+	assert.Equal(t, 1, redis.Failures)
+	assert.Equal(t, "3.500", redis.Time)
+
+	require.Len(t, redis.Cases, 2)
+	assert.Equal(t, "remote-redis", redis.Cases[0].Name)
+	assert.Equal(t, "TestRedisInput/redis:6.0", redis.Cases[0].ClassName)
+	assert.Nil(t, redis.Cases[0].Failure)
+	assert.Equal(t, []junitProperty{{Name: "image", Value: "redis"}, {Name: "image_tag", Value: "6.0"}}, redis.Cases[0].Properties)
+
+	require.NotNil(t, redis.Cases[1].Failure)
+	assert.Equal(t, "no point received", redis.Cases[1].Failure.Message)
+	assert.Equal(t, "no point received\n<detail>", redis.Cases[1].Failure.Text)
+	assert.Equal(t, "==> redis-case <==\nOOM command not allowed\n", redis.Cases[1].SystemErr)
+	assert.Empty(t, redis.Cases[0].SystemErr)
+
+	mysql := suites.Suites[1]
+	require.Len(t, mysql.Cases, 1)
+	assert.Equal(t, "TestMySQLInput", mysql.Cases[0].Name)
+	require.NotNil(t, mysql.Cases[0].Skipped)
+	assert.Equal(t, "remote docker not available", mysql.Cases[0].Skipped.Message)
+}
+
+func TestExportHTML(t *T.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, ExportHTML(buf, testingResults()))
+
+	html := buf.String()
+	assert.Contains(t, html, "Total: 3, passed: 1, failed: 1, skipped: 1, cost: 3.5s")
+	assert.Contains(t, html, "<td>redis:7.0</td>")
+	assert.Contains(t, html, `<td class="fail">fail</td>`)
+	assert.Contains(t, html, "no point received\n&lt;detail&gt;") // escaped
+}
+
+func TestFlushReport(t *T.T) {
+	dir := t.TempDir()
+
+	reportDir, metricFile = dir, filepath.Join(dir, "testing_metrics")
+	t.Cleanup(func() {
+		reportDir, metricFile, caseResults = "", "", nil
+	})
+
+	for _, cr := range testingResults() {
+		require.NoError(t, Flush(cr))
+	}
+
+	require.NoError(t, Flush(&ModuleResult{Name: "redis"})) // not reported
+
+	data, err := os.ReadFile(filepath.Join(dir, "junit-"+reportName+".xml"))
+	require.NoError(t, err)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &suites))
+	assert.Equal(t, 3, suites.Tests)
+
+	_, err = os.Stat(filepath.Join(dir, "report-"+reportName+".html"))
+	assert.NoError(t, err)
+}
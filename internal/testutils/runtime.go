@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Container runtimes of remote host, set by env REMOTE_RUNTIME.
+const (
+	RuntimeDocker     = "docker"
+	RuntimePodman     = "podman"
+	RuntimeContainerd = "containerd"
+)
+
+// ContainerRuntime runs testing containers on remote host, whatever the
+// runtime is docker, podman or containerd.
+type ContainerRuntime interface {
+	// Name returns name of the runtime, such as RuntimeDocker.
+	Name() string
+
+	Ping() error
+
+	// Capacity returns CPUs and memory of the remote host.
+	Capacity() (*Resources, error)
+
+	// RemoveByName removes the container if exist.
+	RemoveByName(name string) error
+
+	Run(opts *RunOptions) (Container, error)
+}
+
+// RunOptions are options to run a container.
+type RunOptions struct {
+	Name       string
+	Repository string
+	Tag        string
+	Env        []string
+	Cmd        []string
+
+	// ExposedPorts are container ports(such as 6379/tcp) published to random host ports.
+	ExposedPorts []string
+
+	// PortBindings publish container ports(key) to host ports(value), such as
+	// {"6379/tcp": "16379"}. Random host port used if value empty.
+	PortBindings map[string]string
+
+	// Dockerfile is path of the Dockerfile, if set, image Repository:Tag is built from it before run.
+	Dockerfile string
+
+	// BuildArgs are ARGs of the Dockerfile, such as versions of multi-stage builds.
+	BuildArgs map[string]string
+
+	// AutoRemove removes the container on it exit.
+	AutoRemove bool
+}
+
+func (o *RunOptions) image() string {
+	if o.Tag == "" {
+		return o.Repository + ":latest"
+	}
+	return o.Repository + ":" + o.Tag
+}
+
+// portBindings merges ExposedPorts into PortBindings.
+func (o *RunOptions) portBindings() map[string]string {
+	res := map[string]string{}
+	for _, p := range o.ExposedPorts {
+		res[p] = ""
+	}
+	for k, v := range o.PortBindings {
+		res[k] = v
+	}
+	return res
+}
+
+// Container is a container running on remote host.
+type Container interface {
+	Name() string
+
+	// GetPort returns host port published by container port id, such as 6379/tcp.
+	GetPort(id string) string
+
+	// Exec runs cmd within the container, output redirected to stdout and stderr.
+	Exec(cmd []string) (exitCode int, err error)
+
+	Restart(timeout time.Duration) error
+
+	// Logs returns the last tail lines of stdout and stderr of the container,
+	// all lines if tail <= 0.
+	Logs(tail int) (string, error)
+
+	// Inspect returns low-level information of the container in JSON, the
+	// same as `docker inspect`.
+	Inspect() (string, error)
+
+	// Purge removes the container and its volumes.
+	Purge() error
+}
+
+// ContainerRuntime returns container runtime of the remote host:
+//
+//   - docker: the docker API on REMOTE_SOCKET or REMOTE_HOST:DOCKER_PORT
+//   - podman: the docker-compatible API of podman, on REMOTE_SOCKET or REMOTE_HOST:DOCKER_PORT,
+//     such as started by `podman system service -t 0 tcp://0.0.0.0:2375`
+//   - containerd: nerdctl command in REMOTE_NERDCTL, such as `ssh ci@10.0.0.1 sudo nerdctl`
+func (i *RemoteInfo) ContainerRuntime() (ContainerRuntime, error) {
+	switch i.Runtime {
+	case "", RuntimeDocker:
+		return newDockerRuntime(i.Endpoint(), false)
+	case RuntimePodman:
+		return newDockerRuntime(i.Endpoint(), true)
+	case RuntimeContainerd:
+		return newNerdctlRuntime(i.Nerdctl)
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q", i.Runtime)
+	}
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dt "github.com/ory/dockertest/v3"
+	docker "github.com/ory/dockertest/v3/docker"
+)
+
+// dockerRuntime drives docker, or podman through its docker-compatible API.
+type dockerRuntime struct {
+	pool   *dt.Pool
+	podman bool
+}
+
+func newDockerRuntime(endpoint string, podman bool) (*dockerRuntime, error) {
+	p, err := dt.NewPool(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dockerRuntime{pool: p, podman: podman}, nil
+}
+
+func (r *dockerRuntime) Name() string {
+	if r.podman {
+		return RuntimePodman
+	}
+	return RuntimeDocker
+}
+
+func (r *dockerRuntime) Ping() error {
+	return r.pool.Client.Ping()
+}
+
+func (r *dockerRuntime) Capacity() (*Resources, error) {
+	info, err := r.pool.Client.Info()
+	if err != nil {
+		return nil, fmt.Errorf("%s info: %w", r.Name(), err)
+	}
+
+	return &Resources{CPU: float64(info.NCPU), Memory: info.MemTotal}, nil
+}
+
+func (r *dockerRuntime) RemoveByName(name string) error {
+	return r.pool.RemoveContainerByName(name)
+}
+
+func (r *dockerRuntime) Run(opts *RunOptions) (Container, error) {
+	repo := opts.Repository
+
+	if opts.Dockerfile != "" {
+		var args []docker.BuildArg
+		for k, v := range opts.BuildArgs {
+			args = append(args, docker.BuildArg{Name: k, Value: v})
+		}
+
+		dir, file := filepath.Split(opts.Dockerfile)
+		if err := r.pool.Client.BuildImage(docker.BuildImageOptions{
+			Name:         opts.image(),
+			Dockerfile:   file,
+			ContextDir:   dir,
+			BuildArgs:    args,
+			OutputStream: io.Discard,
+		}); err != nil {
+			return nil, fmt.Errorf("build image %s: %w", opts.image(), err)
+		}
+	} else if r.podman {
+		// podman do not search docker.io for short names by default
+		repo = qualifiedRepository(repo)
+	}
+
+	bindings := map[docker.Port][]docker.PortBinding{}
+	var exposed []string
+	for port, hostPort := range opts.portBindings() {
+		exposed = append(exposed, port)
+		bindings[docker.Port(port)] = []docker.PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}
+	}
+
+	res, err := r.pool.RunWithOptions(&dt.RunOptions{
+		Name:         opts.Name,
+		Repository:   repo,
+		Tag:          opts.Tag,
+		Env:          opts.Env,
+		Cmd:          opts.Cmd,
+		ExposedPorts: exposed,
+		PortBindings: bindings,
+	}, func(c *docker.HostConfig) {
+		c.RestartPolicy = docker.RestartPolicy{Name: "no"}
+		c.AutoRemove = opts.AutoRemove
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dockerContainer{name: opts.Name, pool: r.pool, resource: res}, nil
+}
+
+// qualifiedRepository prefixes docker.io to image names without registry,
+// such as redis to docker.io/library/redis.
+func qualifiedRepository(repo string) string {
+	parts := strings.SplitN(repo, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return repo
+	}
+
+	if len(parts) == 1 {
+		return "docker.io/library/" + repo
+	}
+	return "docker.io/" + repo
+}
+
+type dockerContainer struct {
+	name     string
+	pool     *dt.Pool
+	resource *dt.Resource
+}
+
+func (c *dockerContainer) Name() string { return c.name }
+
+func (c *dockerContainer) GetPort(id string) string {
+	return c.resource.GetPort(id)
+}
+
+func (c *dockerContainer) Exec(cmd []string) (int, error) {
+	return c.resource.Exec(cmd, dt.ExecOptions{StdOut: os.Stdout, StdErr: os.Stderr})
+}
+
+func (c *dockerContainer) Restart(timeout time.Duration) error {
+	return c.pool.Client.RestartContainer(c.resource.Container.ID, uint(timeout/time.Second))
+}
+
+func (c *dockerContainer) Logs(tail int) (string, error) {
+	opts := docker.LogsOptions{
+		Container: c.resource.Container.ID,
+		Stdout:    true,
+		Stderr:    true,
+		Tail:      "all",
+	}
+
+	if tail > 0 {
+		opts.Tail = strconv.Itoa(tail)
+	}
+
+	var buf bytes.Buffer
+	opts.OutputStream, opts.ErrorStream = &buf, &buf
+
+	if err := c.pool.Client.Logs(opts); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (c *dockerContainer) Inspect() (string, error) {
+	x, err := c.pool.Client.InspectContainer(c.resource.Container.ID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (c *dockerContainer) Purge() error {
+	return c.pool.Purge(c.resource)
+}
@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nerdctlRuntime drives containerd by nerdctl. containerd has no remote API
+// like docker, so nerdctl is run on the remote host, such as `ssh ci@10.0.0.1 nerdctl`.
+type nerdctlRuntime struct {
+	cmd []string
+	ssh bool // arguments are passed to remote shell by ssh, they should be quoted
+}
+
+func newNerdctlRuntime(cmd string) (*nerdctlRuntime, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("nerdctl command not set")
+	}
+
+	return &nerdctlRuntime{cmd: fields, ssh: filepath.Base(fields[0]) == "ssh"}, nil
+}
+
+func (r *nerdctlRuntime) command(stdin io.Reader, args ...string) *exec.Cmd {
+	all := append([]string{}, r.cmd[1:]...)
+	for _, arg := range args {
+		if r.ssh {
+			arg = shellQuote(arg)
+		}
+		all = append(all, arg)
+	}
+
+	cmd := exec.Command(r.cmd[0], all...) //nolint:gosec
+	cmd.Stdin = stdin
+	return cmd
+}
+
+// output runs nerdctl and returns its stdout.
+func (r *nerdctlRuntime) output(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := r.command(nil, args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("nerdctl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *nerdctlRuntime) Name() string { return RuntimeContainerd }
+
+func (r *nerdctlRuntime) Ping() error {
+	_, err := r.output("version")
+	return err
+}
+
+func (r *nerdctlRuntime) Capacity() (*Resources, error) {
+	out, err := r.output("info", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		NCPU     int
+		MemTotal int64
+	}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		return nil, fmt.Errorf("unmarshal nerdctl info: %w", err)
+	}
+
+	return &Resources{CPU: float64(info.NCPU), Memory: info.MemTotal}, nil
+}
+
+func (r *nerdctlRuntime) RemoveByName(name string) error {
+	if _, err := r.output("rm", "-f", "-v", name); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "no such container") {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *nerdctlRuntime) Run(opts *RunOptions) (Container, error) {
+	if opts.Dockerfile != "" {
+		// the Dockerfile is local, send it by stdin without build context.
+		data, err := os.ReadFile(opts.Dockerfile)
+		if err != nil {
+			return nil, err
+		}
+
+		args := []string{"build", "-t", opts.image()}
+		for _, arg := range sortedBuildArgs(opts.BuildArgs) {
+			args = append(args, "--build-arg", arg)
+		}
+		args = append(args, "-f", "-", ".")
+
+		var stderr bytes.Buffer
+		cmd := r.command(bytes.NewReader(data), args...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("nerdctl build %s: %w: %s", opts.image(), err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	args := []string{"run", "-d", "--restart", "no", "--name", opts.Name}
+	if opts.AutoRemove {
+		args = append(args, "--rm")
+	}
+	for port, hostPort := range opts.portBindings() {
+		if hostPort == "" {
+			args = append(args, "-p", port)
+		} else {
+			args = append(args, "-p", hostPort+":"+port)
+		}
+	}
+	for _, env := range opts.Env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, opts.image())
+	args = append(args, opts.Cmd...)
+
+	if _, err := r.output(args...); err != nil {
+		return nil, err
+	}
+
+	return &nerdctlContainer{name: opts.Name, rt: r}, nil
+}
+
+type nerdctlContainer struct {
+	name string
+	rt   *nerdctlRuntime
+}
+
+func (c *nerdctlContainer) Name() string { return c.name }
+
+func (c *nerdctlContainer) GetPort(id string) string {
+	// output like `0.0.0.0:32768`, one line per binding
+	out, err := c.rt.output("port", c.name, id)
+	if err != nil {
+		return ""
+	}
+
+	_, port, err := net.SplitHostPort(strings.TrimSpace(strings.Split(out, "\n")[0]))
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+func (c *nerdctlContainer) Exec(cmd []string) (int, error) {
+	x := c.rt.command(nil, append([]string{"exec", c.name}, cmd...)...)
+	x.Stdout, x.Stderr = os.Stdout, os.Stderr
+
+	if err := x.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+func (c *nerdctlContainer) Restart(timeout time.Duration) error {
+	_, err := c.rt.output("restart", "-t", fmt.Sprintf("%d", int(timeout/time.Second)), c.name)
+	return err
+}
+
+func (c *nerdctlContainer) Logs(tail int) (string, error) {
+	args := []string{"logs"}
+	if tail > 0 {
+		args = append(args, "--tail", fmt.Sprintf("%d", tail))
+	}
+
+	// stderr of the container is on stderr of nerdctl.
+	var out bytes.Buffer
+	x := c.rt.command(nil, append(args, c.name)...)
+	x.Stdout, x.Stderr = &out, &out
+
+	if err := x.Run(); err != nil {
+		return "", fmt.Errorf("nerdctl logs: %w: %s", err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+func (c *nerdctlContainer) Inspect() (string, error) {
+	return c.rt.output("inspect", c.name)
+}
+
+func (c *nerdctlContainer) Purge() error {
+	_, err := c.rt.output("rm", "-f", "-v", c.name)
+	return err
+}
+
+// shellQuote quotes s in single quotes for POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerRuntime(t *T.T) {
+	t.Run("docker", func(t *T.T) {
+		rt, err := (&RemoteInfo{Host: "1.2.3.4", Port: "2375"}).ContainerRuntime()
+		require.NoError(t, err)
+		assert.Equal(t, RuntimeDocker, rt.Name())
+	})
+
+	t.Run("podman-socket", func(t *T.T) {
+		ri := &RemoteInfo{Host: "1.2.3.4", Port: "2375", Runtime: RuntimePodman, Socket: "unix:///run/podman/podman.sock"}
+		assert.Equal(t, "unix:///run/podman/podman.sock", ri.Endpoint())
+
+		rt, err := ri.ContainerRuntime()
+		require.NoError(t, err)
+		assert.Equal(t, RuntimePodman, rt.Name())
+	})
+
+	t.Run("unknown", func(t *T.T) {
+		_, err := (&RemoteInfo{Runtime: "lxc"}).ContainerRuntime()
+		assert.Error(t, err)
+	})
+
+	t.Run("env", func(t *T.T) {
+		t.Setenv("REMOTE_RUNTIME", RuntimeContainerd)
+		t.Setenv("REMOTE_NERDCTL", "ssh ci@1.2.3.4 nerdctl")
+
+		ri := GetRemote()
+		assert.Equal(t, RuntimeContainerd, ri.Runtime)
+		assert.Equal(t, "ssh ci@1.2.3.4 nerdctl", ri.Nerdctl)
+		assert.Equal(t, "tcp://0.0.0.0:2375", ri.Endpoint())
+	})
+}
+
+func TestQualifiedRepository(t *T.T) {
+	assert.Equal(t, "docker.io/library/redis", qualifiedRepository("redis"))
+	assert.Equal(t, "docker.io/bitnami/redis", qualifiedRepository("bitnami/redis"))
+	assert.Equal(t, "pubrepo.guance.com/image-repo-for-testing/nginx", qualifiedRepository("pubrepo.guance.com/image-repo-for-testing/nginx"))
+	assert.Equal(t, "localhost:5000/nginx", qualifiedRepository("localhost:5000/nginx"))
+	assert.Equal(t, "localhost/nginx", qualifiedRepository("localhost/nginx"))
+}
+
+func TestShellQuote(t *T.T) {
+	assert.Equal(t, `'mysql -e "select 1"'`, shellQuote(`mysql -e "select 1"`))
+	assert.Equal(t, `'it'\''s'`, shellQuote(`it's`))
+}
+
+// fakeNerdctl writes a script logs its arguments and prints answers of some sub-commands.
+func fakeNerdctl(t *T.T) (cmd, argsLog string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script not supported")
+	}
+
+	dir := t.TempDir()
+	argsLog = filepath.Join(dir, "args")
+	cmd = filepath.Join(dir, "nerdctl")
+
+	script := `#!/bin/sh
+echo "$@" >> ` + argsLog + `
+case "$1" in
+info) echo '{"NCPU":4,"MemTotal":8589934592}' ;;
+port) printf '0.0.0.0:32768\n[::]:32768\n' ;;
+rm) [ "$4" = "not-exist" ] && { echo "no such container: not-exist" >&2; exit 1; } ;;
+exec) exit 3 ;;
+logs) echo "ready to accept connections"; echo "out of memory" >&2 ;;
+inspect) echo '[{"Id":"abc"}]' ;;
+esac
+exit 0
+`
+	require.NoError(t, os.WriteFile(cmd, []byte(script), 0o700)) //nolint:gosec
+	return cmd, argsLog
+}
+
+func TestNerdctlRuntime(t *T.T) {
+	cmd, argsLog := fakeNerdctl(t)
+
+	rt, err := (&RemoteInfo{Runtime: RuntimeContainerd, Nerdctl: cmd}).ContainerRuntime()
+	require.NoError(t, err)
+
+	assert.NoError(t, rt.Ping())
+
+	capacity, err := rt.Capacity()
+	require.NoError(t, err)
+	assert.Equal(t, Resources{CPU: 4, Memory: 8 << 30}, *capacity)
+
+	assert.NoError(t, rt.RemoveByName("not-exist"))
+
+	c, err := rt.Run(&RunOptions{
+		Name:         "redis-case",
+		Repository:   "redis",
+		Tag:          "6.0",
+		Env:          []string{"A=1"},
+		PortBindings: map[string]string{"6379/tcp": "16379"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "32768", c.GetPort("6379/tcp"))
+
+	code, err := c.Exec([]string{"/bin/sh", "-c", "exit 3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	assert.NoError(t, c.Restart(30*time.Second))
+	assert.NoError(t, c.Purge())
+
+	data, err := os.ReadFile(argsLog)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"version",
+		"info --format {{json .}}",
+		"rm -f -v not-exist",
+		"run -d --restart no --name redis-case -p 16379:6379/tcp -e A=1 redis:6.0",
+		"port redis-case 6379/tcp",
+		"exec redis-case /bin/sh -c exit 3",
+		"restart -t 30 redis-case",
+		"rm -f -v redis-case",
+	}, strings.Split(strings.TrimSpace(string(data)), "\n"))
+}
+
+func TestNerdctlLogsAndInspect(t *T.T) {
+	cmd, argsLog := fakeNerdctl(t)
+
+	rt, err := newNerdctlRuntime(cmd)
+	require.NoError(t, err)
+
+	c := &nerdctlContainer{rt: rt, name: "redis-case"}
+
+	logs, err := c.Logs(10)
+	require.NoError(t, err)
+	assert.Equal(t, "ready to accept connections\nout of memory\n", logs)
+
+	inspect, err := c.Inspect()
+	require.NoError(t, err)
+	assert.Equal(t, `[{"Id":"abc"}]`, strings.TrimSpace(inspect))
+
+	data, err := os.ReadFile(argsLog)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"logs --tail 10 redis-case",
+		"inspect redis-case",
+	}, strings.Split(strings.TrimSpace(string(data)), "\n"))
+}
@@ -0,0 +1,357 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	toxiproxyRepo    = "ghcr.io/shopify/toxiproxy"
+	toxiproxyTag     = "2.5.0"
+	toxiproxyAPIPort = "8474"
+)
+
+// Toxiproxy is a toxiproxy(https://github.com/Shopify/toxiproxy) server used
+// to inject network faults, such as latency, bandwidth limit and connection
+// reset, between the service and datakit during integration tests.
+//
+// Proxies listen on the toxiproxy host and forward connections to their
+// upstream, so an input connects to Proxy.Addr() instead of the service,
+// or the dataway writer sends to a proxy whose upstream is MockDataway.
+type Toxiproxy struct {
+	api  string // API base URL, such as http://1.2.3.4:8474
+	host string // host proxies listening on
+
+	container Container
+	cli       *http.Client
+	proxies   []*Proxy
+}
+
+// StartToxiproxy runs a toxiproxy container on remote host, container ports
+// listenPorts are published to the same host ports for proxies to listen on.
+// If env TOXIPROXY_URL set, the toxiproxy server on it used instead, and
+// listenPorts should be reachable on its host. The container, or proxies
+// created on the server of TOXIPROXY_URL, are removed on the cleanup of t.
+func StartToxiproxy(t testing.TB, listenPorts ...int) (*Toxiproxy, error) {
+	t.Helper()
+
+	if v := os.Getenv("TOXIPROXY_URL"); v != "" {
+		tp, err := NewToxiproxyClient(v)
+		if err != nil {
+			return nil, err
+		}
+
+		// the server is shared, only proxies created are removed.
+		t.Cleanup(func() {
+			for _, p := range tp.proxies {
+				if err := p.Delete(); err != nil {
+					t.Logf("delete proxy %s: %s", p.Name, err)
+				}
+			}
+		})
+		return tp, nil
+	}
+
+	r := GetRemote()
+	rt, err := r.ContainerRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown-hostname"
+	}
+	name := fmt.Sprintf("%s.toxiproxy.%s", hostname, RandStrID(8))
+
+	ports := map[string]string{toxiproxyAPIPort + "/tcp": ""}
+	for _, p := range listenPorts {
+		ports[fmt.Sprintf("%d/tcp", p)] = strconv.Itoa(p)
+	}
+
+	c, err := rt.Run(&RunOptions{
+		Name:         name,
+		Repository:   toxiproxyRepo,
+		Tag:          toxiproxyTag,
+		PortBindings: ports,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("run toxiproxy: %w", err)
+	}
+
+	tp := &Toxiproxy{
+		api:       "http://" + net.JoinHostPort(r.Host, c.GetPort(toxiproxyAPIPort+"/tcp")),
+		host:      r.Host,
+		container: c,
+		cli:       &http.Client{Timeout: 10 * time.Second},
+	}
+
+	t.Cleanup(func() {
+		if err := tp.Purge(); err != nil {
+			t.Logf("purge toxiproxy: %s", err)
+		}
+	})
+
+	if err := tp.waitReady(time.Minute); err != nil {
+		return nil, err
+	}
+
+	return tp, nil
+}
+
+// NewToxiproxyClient returns client of a running toxiproxy server on apiURL, such as http://1.2.3.4:8474.
+func NewToxiproxyClient(apiURL string) (*Toxiproxy, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid toxiproxy URL %q: %w", apiURL, err)
+	}
+
+	return &Toxiproxy{
+		api:  strings.TrimSuffix(apiURL, "/"),
+		host: u.Hostname(),
+		cli:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (tp *Toxiproxy) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tp.do(http.MethodGet, "/version", nil, nil)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("toxiproxy not ready: %w", err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Purge removes the toxiproxy container.
+func (tp *Toxiproxy) Purge() error {
+	if tp.container == nil {
+		return nil
+	}
+	return tp.container.Purge()
+}
+
+// Reset enables all proxies and removes all toxics.
+func (tp *Toxiproxy) Reset() error {
+	return tp.do(http.MethodPost, "/reset", nil, nil)
+}
+
+// CreateProxy creates proxy name listening on port of toxiproxy host and
+// forwarding to upstream, such as 1.2.3.4:6379.
+func (tp *Toxiproxy) CreateProxy(name string, port int, upstream string) (*Proxy, error) {
+	p := &Proxy{
+		Name:     name,
+		Listen:   net.JoinHostPort("0.0.0.0", strconv.Itoa(port)),
+		Upstream: upstream,
+		Enabled:  true,
+		tp:       tp,
+	}
+
+	if err := tp.do(http.MethodPost, "/proxies", p, p); err != nil {
+		return nil, fmt.Errorf("create proxy %s: %w", name, err)
+	}
+
+	tp.proxies = append(tp.proxies, p)
+	return p, nil
+}
+
+func (tp *Toxiproxy) do(method, path string, body, result any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, tp.api+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := tp.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+
+	if result != nil && len(data) > 0 {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+// Proxy is a TCP proxy of toxiproxy.
+type Proxy struct {
+	Name     string `json:"name"`
+	Listen   string `json:"listen"`
+	Upstream string `json:"upstream"`
+	Enabled  bool   `json:"enabled"`
+
+	tp     *Toxiproxy
+	toxics []string // names of toxics added
+}
+
+// Addr returns address to connect to the proxy.
+func (p *Proxy) Addr() string {
+	_, port, _ := net.SplitHostPort(p.Listen)
+	return net.JoinHostPort(p.tp.host, port)
+}
+
+// URL returns HTTP URL of Addr, such as http://1.2.3.4:18080.
+func (p *Proxy) URL() string {
+	return "http://" + p.Addr()
+}
+
+// Host returns host of Addr.
+func (p *Proxy) Host() string { return p.tp.host }
+
+// Port returns port of Addr.
+func (p *Proxy) Port() int {
+	_, port, _ := net.SplitHostPort(p.Listen)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// AddToxic adds the toxic to the proxy, the toxic is not modified and can
+// be shared among proxies.
+func (p *Proxy) AddToxic(x *Toxic) error {
+	toxic := *x
+	if toxic.Stream == "" {
+		toxic.Stream = Downstream
+	}
+	if toxic.Toxicity == 0 {
+		toxic.Toxicity = 1
+	}
+	if toxic.Name == "" {
+		toxic.Name = toxic.Type + "_" + toxic.Stream
+	}
+
+	if err := p.tp.do(http.MethodPost, "/proxies/"+p.Name+"/toxics", &toxic, nil); err != nil {
+		return fmt.Errorf("add toxic %s to %s: %w", toxic.Name, p.Name, err)
+	}
+
+	p.toxics = append(p.toxics, toxic.Name)
+	return nil
+}
+
+// RemoveToxic removes the toxic named name from the proxy.
+func (p *Proxy) RemoveToxic(name string) error {
+	if err := p.tp.do(http.MethodDelete, "/proxies/"+p.Name+"/toxics/"+name, nil, nil); err != nil {
+		return err
+	}
+
+	for i, x := range p.toxics {
+		if x == name {
+			p.toxics = append(p.toxics[:i], p.toxics[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ClearToxics removes all toxics added by AddToxic, the network of the proxy
+// turns to normal.
+func (p *Proxy) ClearToxics() error {
+	for len(p.toxics) > 0 {
+		if err := p.RemoveToxic(p.toxics[0]); err != nil {
+			return fmt.Errorf("remove toxic %s from %s: %w", p.toxics[0], p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Disable closes all connections of the proxy and refuses new ones, like the service is down.
+func (p *Proxy) Disable() error {
+	return p.setEnabled(false)
+}
+
+// Enable accepts new connections again.
+func (p *Proxy) Enable() error {
+	return p.setEnabled(true)
+}
+
+func (p *Proxy) setEnabled(enabled bool) error {
+	if err := p.tp.do(http.MethodPost, "/proxies/"+p.Name, map[string]bool{"enabled": enabled}, nil); err != nil {
+		return err
+	}
+	p.Enabled = enabled
+	return nil
+}
+
+// Delete deletes the proxy.
+func (p *Proxy) Delete() error {
+	return p.tp.do(http.MethodDelete, "/proxies/"+p.Name, nil, nil)
+}
+
+// Directions of data toxics applied to.
+const (
+	// Downstream is data from upstream to the client, such as responses of the service.
+	Downstream = "downstream"
+	// Upstream is data from the client to upstream, such as requests to the service.
+	Upstream = "upstream"
+)
+
+// Toxic is a network fault applied to a proxy, see Latency, Bandwidth,
+// ResetPeer and Timeout for common toxics.
+type Toxic struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Stream     string         `json:"stream"`
+	Toxicity   float32        `json:"toxicity"` // probability of connections the toxic applied to
+	Attributes map[string]any `json:"attributes"`
+}
+
+// Latency delays data by latency ± jitter.
+func Latency(latency, jitter time.Duration) *Toxic {
+	return &Toxic{Type: "latency", Attributes: map[string]any{
+		"latency": latency.Milliseconds(),
+		"jitter":  jitter.Milliseconds(),
+	}}
+}
+
+// Bandwidth limits data to rate KB/s.
+func Bandwidth(rate int64) *Toxic {
+	return &Toxic{Type: "bandwidth", Attributes: map[string]any{"rate": rate}}
+}
+
+// ResetPeer resets connections(TCP RST) after timeout, or immediately if timeout is 0.
+func ResetPeer(timeout time.Duration) *Toxic {
+	return &Toxic{Type: "reset_peer", Attributes: map[string]any{"timeout": timeout.Milliseconds()}}
+}
+
+// Timeout stops all data and closes connections after timeout, or never closes if timeout is 0.
+func Timeout(timeout time.Duration) *Toxic {
+	return &Toxic{Type: "timeout", Attributes: map[string]any{"timeout": timeout.Milliseconds()}}
+}
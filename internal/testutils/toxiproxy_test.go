@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package testutils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type toxiproxyReq struct {
+	method, path string
+	body         map[string]any
+}
+
+// fakeToxiproxy records requests to toxiproxy API.
+func fakeToxiproxy(t *T.T) (*httptest.Server, func() []toxiproxyReq) {
+	t.Helper()
+
+	var (
+		mtx  sync.Mutex
+		reqs []toxiproxyReq
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+
+		req := toxiproxyReq{method: r.Method, path: r.URL.Path}
+		if len(data) > 0 {
+			require.NoError(t, json.Unmarshal(data, &req.body))
+		}
+
+		mtx.Lock()
+		reqs = append(reqs, req)
+		mtx.Unlock()
+
+		switch {
+		case r.URL.Path == "/proxies" && r.Method == http.MethodPost:
+			if req.body["name"] == "exist" {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"error":"proxy already exists","status":409}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(data)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []toxiproxyReq {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return append([]toxiproxyReq{}, reqs...)
+	}
+}
+
+func TestToxiproxy(t *T.T) {
+	srv, requests := fakeToxiproxy(t)
+
+	t.Setenv("TOXIPROXY_URL", srv.URL+"/")
+
+	tp, err := StartToxiproxy(t, 16379)
+	require.NoError(t, err)
+
+	p, err := tp.CreateProxy("redis", 16379, "10.0.0.1:6379")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:16379", p.Addr())
+	assert.Equal(t, 16379, p.Port())
+
+	_, err = tp.CreateProxy("exist", 16380, "10.0.0.1:6379")
+	assert.ErrorContains(t, err, "409")
+
+	require.NoError(t, p.AddToxic(Latency(time.Second, 100*time.Millisecond)))
+	require.NoError(t, p.AddToxic(&Toxic{Name: "slow", Type: "bandwidth", Stream: Upstream, Toxicity: 0.5, Attributes: map[string]any{"rate": 1}}))
+	require.NoError(t, p.RemoveToxic("latency_downstream"))
+	require.NoError(t, p.Disable())
+	assert.False(t, p.Enabled)
+	require.NoError(t, p.Enable())
+	require.NoError(t, p.Delete())
+
+	reqs := requests()
+	require.Len(t, reqs, 8)
+
+	assert.Equal(t, toxiproxyReq{"POST", "/proxies", map[string]any{
+		"name": "redis", "listen": "0.0.0.0:16379", "upstream": "10.0.0.1:6379", "enabled": true,
+	}}, reqs[0])
+
+	assert.Equal(t, toxiproxyReq{"POST", "/proxies/redis/toxics", map[string]any{
+		"name": "latency_downstream", "type": "latency", "stream": "downstream", "toxicity": 1.0,
+		"attributes": map[string]any{"latency": 1000.0, "jitter": 100.0},
+	}}, reqs[2])
+
+	assert.Equal(t, "upstream", reqs[3].body["stream"])
+	assert.Equal(t, 0.5, reqs[3].body["toxicity"])
+
+	assert.Equal(t, toxiproxyReq{"DELETE", "/proxies/redis/toxics/latency_downstream", nil}, reqs[4])
+	assert.Equal(t, toxiproxyReq{"POST", "/proxies/redis", map[string]any{"enabled": false}}, reqs[5])
+	assert.Equal(t, toxiproxyReq{"DELETE", "/proxies/redis", nil}, reqs[7])
+
+	t.Run("toxics", func(t *T.T) {
+		assert.Equal(t, map[string]any{"rate": int64(100)}, Bandwidth(100).Attributes)
+		assert.Equal(t, map[string]any{"timeout": int64(500)}, ResetPeer(500*time.Millisecond).Attributes)
+		assert.Equal(t, "timeout", Timeout(0).Type)
+	})
+}
@@ -7,6 +7,7 @@ package trace
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +36,12 @@ type AfterGather struct {
 	filters        []FilterFunc
 	ReFeedInterval time.Duration
 	BlockIOModel   bool
+	tailSampling   *TailSampling
+	tailSampler    *TailSampler
+	spanMetrics    *SpanMetrics
+	spanAggregator *SpanMetricsAggregator
+	samplingPuller SamplingPuller
+	stopOnce       sync.Once
 }
 
 type Option func(aga *AfterGather)
@@ -57,15 +64,74 @@ func WithBlockIOModel(block bool) Option {
 	}
 }
 
+// WithTailSampling buffers traces after filters and decides on whole traces,
+// traces forced to keep by filters are always kept. The tail sampler is shared
+// by all AfterGathers, AfterGather.Stop should be called once it's unused.
+func WithTailSampling(conf *TailSampling) Option {
+	return func(aga *AfterGather) {
+		aga.tailSampling = conf
+	}
+}
+
+// WithSpanMetrics generates RED metrics from all spans received, before
+// filters and sampling applied.
+func WithSpanMetrics(conf *SpanMetrics) Option {
+	return func(aga *AfterGather) {
+		aga.spanMetrics = conf
+	}
+}
+
+// WithSamplingPull pulls sampling rates configured in center by puller, the
+// rates pulled override the ones of Sampler and TailSampling. Nil puller is
+// ignored.
+func WithSamplingPull(puller SamplingPuller) Option {
+	return func(aga *AfterGather) {
+		if v := reflect.ValueOf(puller); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			return
+		}
+		aga.samplingPuller = puller
+	}
+}
+
 func NewAfterGather(options ...Option) *AfterGather {
 	aga := &AfterGather{log: logger.DefaultSLogger("after-gather")}
 	for i := range options {
 		options[i](aga)
 	}
 
+	if aga.samplingPuller != nil {
+		StartSamplingPull(aga.samplingPuller)
+	}
+
+	if aga.tailSampling != nil {
+		if ts, err := acquireTailSampler(aga.tailSampling, aga.log); err != nil {
+			aga.log.Errorf("tail sampling disabled: %s", err.Error())
+		} else {
+			aga.tailSampler = ts
+		}
+	}
+
+	if aga.spanMetrics != nil {
+		aga.spanAggregator = NewSpanMetricsAggregator(aga.spanMetrics, aga.log)
+		aga.spanAggregator.Start()
+	}
+
 	return aga
 }
 
+// Stop releases the tail sampler and stops the span metrics aggregator, traces
+// pending in tail sampler are decided and fed before exit.
+func (aga *AfterGather) Stop() {
+	aga.stopOnce.Do(func() {
+		if aga.tailSampler != nil {
+			releaseTailSampler(aga.tailSampler)
+		}
+		if aga.spanAggregator != nil {
+			aga.spanAggregator.Stop()
+		}
+	})
+}
+
 // AppendFilter will append new filters into AfterGather structure
 // and run them as the order they added. If one filter func return false then
 // the filters loop will break.
@@ -83,20 +149,30 @@ func (aga *AfterGather) Run(inputName string, dktraces DatakitTraces, stricktMod
 		return
 	}
 
-	var afterFilters DatakitTraces
+	if aga.spanAggregator != nil {
+		aga.spanAggregator.Observe(inputName, dktraces)
+	}
+
+	var (
+		afterFilters DatakitTraces
+		forceKeep    []bool
+	)
 	if len(aga.filters) == 0 {
 		afterFilters = dktraces
 	} else {
 		for k := range dktraces {
-			var temp DatakitTrace
+			var (
+				temp DatakitTrace
+				skip bool
+			)
 			for i := range aga.filters {
-				var skip bool
 				if temp, skip = aga.filters[i](aga.log, dktraces[k]); skip {
 					break
 				}
 			}
 			if temp != nil {
 				afterFilters = append(afterFilters, temp)
+				forceKeep = append(forceKeep, skip)
 			}
 		}
 	}
@@ -104,7 +180,17 @@ func (aga *AfterGather) Run(inputName string, dktraces DatakitTraces, stricktMod
 		return
 	}
 
-	if pts := aga.BuildPointsBatch(afterFilters, stricktMod); len(pts) != 0 {
+	if aga.tailSampler != nil {
+		aga.tailSampler.Add(inputName, AfterGatherFunc(aga.feed), afterFilters, forceKeep, stricktMod)
+
+		return
+	}
+
+	aga.feed(inputName, afterFilters, stricktMod)
+}
+
+func (aga *AfterGather) feed(inputName string, dktraces DatakitTraces, stricktMod bool) {
+	if pts := aga.BuildPointsBatch(dktraces, stricktMod); len(pts) != 0 {
 		var (
 			start = time.Now()
 			opt   = &dkio.Option{Blocking: aga.BlockIOModel}
@@ -231,14 +231,15 @@ func (smp *Sampler) Sample(log *logger.Logger, dktrace DatakitTrace) (DatakitTra
 			}
 			switch priority {
 			case PRIORITY_AUTO_KEEP:
-				if multiplicativeHashFunc(UnifyToUint64ID(dktrace[i].TraceID), smp.SamplingRateGlobal) {
+				rate := remoteSampling.rate(rootService(dktrace), smp.SamplingRateGlobal)
+				if multiplicativeHashFunc(UnifyToUint64ID(dktrace[i].TraceID), rate) {
 					log.Debugf("keep tid: %s service: %s resource: %s according to PRIORITY_AUTO_KEEP and sampling ratio: %d%%",
-						dktrace[i].TraceID, dktrace[i].Service, dktrace[i].Resource, int(smp.SamplingRateGlobal*100))
+						dktrace[i].TraceID, dktrace[i].Service, dktrace[i].Resource, int(rate*100))
 
 					return dktrace, false
 				} else {
 					log.Debugf("drop tid: %s service: %s resource: %s according to PRIORITY_AUTO_KEEP and sampling ratio: %d%%",
-						dktrace[i].TraceID, dktrace[i].Service, dktrace[i].Resource, int(smp.SamplingRateGlobal*100))
+						dktrace[i].TraceID, dktrace[i].Service, dktrace[i].Resource, int(rate*100))
 
 					return nil, true
 				}
@@ -261,3 +262,15 @@ func (smp *Sampler) Sample(log *logger.Logger, dktrace DatakitTrace) (DatakitTra
 
 	return dktrace, false
 }
+
+// rootService returns the service of the root span, spans of one trace share
+// the same sampling rate.
+func rootService(dktrace DatakitTrace) string {
+	for i := range dktrace {
+		if dktrace[i].ParentID == "0" || dktrace[i].ParentID == "" {
+			return dktrace[i].Service
+		}
+	}
+
+	return dktrace[0].Service
+}
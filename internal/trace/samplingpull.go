@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+)
+
+const samplingPullWhat = "trace_sampling=true"
+
+var (
+	samplingPullInterval = 30 * time.Second
+	samplingPullOnce     sync.Once
+	remoteSampling       = &samplingOverrides{}
+)
+
+// SamplingPuller pulls sampling config from center, implemented by dataway.
+type SamplingPuller interface {
+	Pull(what string) ([]byte, error)
+}
+
+// RemoteSampling is the sampling config configured in center, SamplingRate
+// overrides the sampling rate of all tracing inputs, Services overrides the
+// sampling rate by the service name of the root span.
+type RemoteSampling struct {
+	SamplingRate *float64           `json:"sampling_rate"`
+	Services     map[string]float64 `json:"services"`
+}
+
+type remoteSamplingBody struct {
+	TraceSampling *RemoteSampling `json:"trace_sampling"`
+	// other fields ignored
+	PullInterval time.Duration `json:"pull_interval"`
+}
+
+type samplingOverrides struct {
+	sync.RWMutex
+	conf *RemoteSampling
+	md5  string
+}
+
+// rate returns the sampling rate of service, local is returned if no
+// override found.
+func (so *samplingOverrides) rate(service string, local float64) float64 {
+	so.RLock()
+	defer so.RUnlock()
+
+	if so.conf == nil {
+		return local
+	}
+	if r, ok := so.conf.Services[service]; ok {
+		return r
+	}
+	if so.conf.SamplingRate != nil {
+		return *so.conf.SamplingRate
+	}
+
+	return local
+}
+
+// refresh updates the overrides and returns the new pull interval if set.
+func (so *samplingOverrides) refresh(body []byte) (time.Duration, error) {
+	bodymd5 := fmt.Sprintf("%x", md5.Sum(body)) //nolint:gosec
+
+	so.Lock()
+	defer so.Unlock()
+
+	if bodymd5 == so.md5 {
+		return 0, nil
+	}
+
+	var rbody remoteSamplingBody
+	if err := json.Unmarshal(body, &rbody); err != nil {
+		return 0, err
+	}
+
+	if rs := rbody.TraceSampling; rs != nil {
+		if rs.SamplingRate != nil && (*rs.SamplingRate < 0 || *rs.SamplingRate > 1) {
+			return 0, fmt.Errorf("invalid sampling rate %f", *rs.SamplingRate)
+		}
+		for service, r := range rs.Services {
+			if r < 0 || r > 1 {
+				return 0, fmt.Errorf("invalid sampling rate %f of service %s", r, service)
+			}
+		}
+	}
+
+	so.conf = rbody.TraceSampling
+	so.md5 = bodymd5
+
+	return rbody.PullInterval, nil
+}
+
+// StartSamplingPull starts pulling remote sampling config, sampling rates
+// pulled override the ones of Sampler. It's shared by all tracing inputs and
+// only the first call takes effect.
+func StartSamplingPull(puller SamplingPuller) {
+	samplingPullOnce.Do(func() {
+		log := logger.SLogger("trace-sampling-pull")
+
+		g := goroutine.NewGroup(goroutine.Option{Name: "internal_trace"})
+		g.Go(func(ctx context.Context) error {
+			pull := func() time.Duration {
+				body, err := puller.Pull(samplingPullWhat)
+				if err != nil {
+					log.Warnf("pull remote sampling: %s, ignored", err)
+
+					return 0
+				}
+
+				interval, err := remoteSampling.refresh(body)
+				if err != nil {
+					log.Warnf("refresh remote sampling: %s, ignored", err)
+				}
+
+				return interval
+			}
+
+			tick := time.NewTicker(samplingPullInterval)
+			defer tick.Stop()
+
+			interval := samplingPullInterval
+			for {
+				if i := pull(); i > 0 && i != interval {
+					log.Infof("set sampling pull interval from %s to %s", interval, i)
+					interval = i
+					tick.Reset(interval)
+				}
+
+				select {
+				case <-tick.C:
+				case <-datakit.Exit.Wait():
+					log.Info("sampling pull exits")
+
+					return nil
+				}
+			}
+		})
+	})
+}
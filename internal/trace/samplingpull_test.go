@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+)
+
+func TestSamplingOverridesRefresh(t *testing.T) {
+	so := &samplingOverrides{}
+
+	if r := so.rate("login", 0.3); r != 0.3 {
+		t.Errorf("expected local rate 0.3 without overrides, got %f", r)
+	}
+
+	interval, err := so.refresh([]byte(`{"trace_sampling":{"sampling_rate":0.5,"services":{"checkout":1,"login":0}},"pull_interval":60000000000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != time.Minute {
+		t.Errorf("expected pull interval 1m, got %s", interval)
+	}
+
+	for service, expected := range map[string]float64{"checkout": 1, "login": 0, "other": 0.5} {
+		if r := so.rate(service, 0.3); r != expected {
+			t.Errorf("service %s: expected %f, got %f", service, expected, r)
+		}
+	}
+
+	// invalid config keeps the old one
+	if _, err := so.refresh([]byte(`{"trace_sampling":{"services":{"checkout":2}}}`)); err == nil {
+		t.Error("expect error on invalid sampling rate")
+	}
+	if r := so.rate("checkout", 0.3); r != 1 {
+		t.Errorf("expected old overrides kept, got %f", r)
+	}
+
+	// overrides removed in center
+	if _, err := so.refresh([]byte(`{"filters":{}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if r := so.rate("checkout", 0.3); r != 0.3 {
+		t.Errorf("expected local rate 0.3 after overrides removed, got %f", r)
+	}
+}
+
+func TestSamplerWithOverrides(t *testing.T) {
+	defer func(so *samplingOverrides) { remoteSampling = so }(remoteSampling)
+
+	remoteSampling = &samplingOverrides{}
+	if _, err := remoteSampling.refresh([]byte(`{"trace_sampling":{"services":{"checkout":0,"login":1}}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		sampler = &Sampler{SamplingRateGlobal: 0.5}
+		log     = logger.DefaultSLogger("filters-test")
+		kept    = map[string]int{}
+	)
+	for i := 0; i < 100; i++ {
+		for _, service := range []string{"checkout", "login"} {
+			dktrace := randDatakitTrace(t, 3, randService(service), randPriority(PRIORITY_AUTO_KEEP))
+			parentialize(dktrace)
+			// only the service of root span counts
+			dktrace[1].Service = "other"
+
+			if dktrace, _ = sampler.Sample(log, dktrace); dktrace != nil {
+				kept[service]++
+			}
+		}
+	}
+
+	if kept["checkout"] != 0 || kept["login"] != 100 {
+		t.Errorf("unexpected kept traces: %v", kept)
+	}
+}
+
+type nilPuller struct{}
+
+func (*nilPuller) Pull(string) ([]byte, error) { return nil, nil }
+
+func TestWithSamplingPull(t *testing.T) {
+	var puller *nilPuller // such as nil dataway
+
+	for _, p := range []SamplingPuller{nil, puller} {
+		if aga := NewAfterGather(WithSamplingPull(p)); aga.samplingPuller != nil {
+			t.Errorf("expect nil puller %#v ignored", p)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	SpanMetricsName = "tracing_metrics"
+
+	defaultSpanMetricsInterval = time.Minute
+	minSpanMetricsInterval     = 10 * time.Second
+)
+
+// SpanMetrics is the config of RED(request, error and duration) metrics
+// generated from spans before sampling.
+type SpanMetrics struct {
+	Interval datakit.Duration `toml:"interval" json:"interval"`
+	// span tags used as extra dimensions, such as http_method
+	ExtraTags []string `toml:"extra_tags" json:"extra_tags"`
+}
+
+type redSeries struct {
+	inputName   string
+	tags        map[string]string
+	hits        int64
+	errors      int64
+	durationSum int64
+	durationMax int64
+}
+
+// SpanMetricsAggregator aggregates spans per service and resource and
+// feeds them as metrics periodically.
+type SpanMetricsAggregator struct {
+	sync.Mutex
+	conf     *SpanMetrics
+	log      *logger.Logger
+	series   map[string]*redSeries
+	exit     *cliutils.Sem
+	stopOnce sync.Once
+}
+
+func NewSpanMetricsAggregator(conf *SpanMetrics, log *logger.Logger) *SpanMetricsAggregator {
+	if log == nil {
+		log = logger.DefaultSLogger("span-metrics")
+	}
+
+	if conf.Interval.Duration <= 0 {
+		conf.Interval.Duration = defaultSpanMetricsInterval
+	} else if conf.Interval.Duration < minSpanMetricsInterval {
+		conf.Interval.Duration = minSpanMetricsInterval
+	}
+
+	return &SpanMetricsAggregator{
+		conf:   conf,
+		log:    log,
+		series: make(map[string]*redSeries),
+		exit:   cliutils.NewSem(),
+	}
+}
+
+// Start runs the flush loop, the metrics aggregated are flushed on exit.
+func (sma *SpanMetricsAggregator) Start() {
+	g := goroutine.NewGroup(goroutine.Option{Name: "internal_trace"})
+	g.Go(func(ctx context.Context) error {
+		tick := time.NewTicker(sma.conf.Interval.Duration)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				sma.flush(time.Now())
+			case <-datakit.Exit.Wait():
+				sma.flush(time.Now())
+				return nil
+			case <-sma.exit.Wait():
+				sma.flush(time.Now())
+				return nil
+			}
+		}
+	})
+}
+
+// Stop stops the flush loop.
+func (sma *SpanMetricsAggregator) Stop() {
+	sma.stopOnce.Do(sma.exit.Close)
+}
+
+// Observe counts spans of the traces.
+func (sma *SpanMetricsAggregator) Observe(inputName string, dktraces DatakitTraces) {
+	var sb strings.Builder
+
+	sma.Lock()
+	defer sma.Unlock()
+
+	for i := range dktraces {
+		for _, dkspan := range dktraces[i] {
+			if dkspan == nil {
+				continue
+			}
+
+			tags := sma.tags(dkspan)
+
+			sb.Reset()
+			sb.WriteString(inputName)
+			keys := make([]string, 0, len(tags))
+			for k := range tags {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				sb.WriteByte(0)
+				sb.WriteString(k)
+				sb.WriteByte('=')
+				sb.WriteString(tags[k])
+			}
+
+			key := sb.String()
+			s, ok := sma.series[key]
+			if !ok {
+				s = &redSeries{inputName: inputName, tags: tags}
+				sma.series[key] = s
+			}
+
+			s.hits++
+			if dkspan.Status == STATUS_ERR || dkspan.Status == STATUS_CRITICAL {
+				s.errors++
+			}
+			s.durationSum += dkspan.Duration
+			if dkspan.Duration > s.durationMax {
+				s.durationMax = dkspan.Duration
+			}
+		}
+	}
+}
+
+func (sma *SpanMetricsAggregator) tags(dkspan *DatakitSpan) map[string]string {
+	service := dkspan.Service
+	if service == "" {
+		service = UnknowServiceName(dkspan)
+	}
+	spanType := dkspan.SpanType
+	if spanType == "" {
+		spanType = SPAN_TYPE_UNKNOW
+	}
+
+	tags := map[string]string{
+		"source":       dkspan.Source,
+		TAG_SERVICE:    service,
+		FIELD_RESOURCE: dkspan.Resource,
+		TAG_OPERATION:  dkspan.Operation,
+		TAG_SPAN_TYPE:  spanType,
+	}
+	for _, k := range sma.conf.ExtraTags {
+		if v, ok := dkspan.Tags[k]; ok {
+			tags[strings.ReplaceAll(k, ".", "_")] = v
+		}
+	}
+
+	return tags
+}
+
+func (sma *SpanMetricsAggregator) flush(now time.Time) {
+	sma.Lock()
+	series := sma.series
+	sma.series = make(map[string]*redSeries)
+	sma.Unlock()
+
+	if len(series) == 0 {
+		return
+	}
+
+	ptsByInput := make(map[string][]*point.Point)
+	for _, s := range series {
+		pt, err := point.NewPoint(SpanMetricsName, s.tags, map[string]interface{}{
+			"hits":         s.hits,
+			"errors":       s.errors,
+			"duration_sum": s.durationSum / int64(time.Microsecond),
+			"duration_max": s.durationMax / int64(time.Microsecond),
+			"duration_avg": float64(s.durationSum) / float64(s.hits) / float64(time.Microsecond),
+		}, &point.PointOption{Time: now, Category: datakit.Metric})
+		if err != nil {
+			sma.log.Warnf("build span metrics point error: %s", err.Error())
+			continue
+		}
+		ptsByInput[s.inputName] = append(ptsByInput[s.inputName], pt)
+	}
+
+	for inputName, pts := range ptsByInput {
+		if err := dkioFeed(inputName, datakit.Metric, pts, nil); err != nil {
+			sma.log.Warnf("io feed span metrics failed: %s, ignored", err.Error())
+		}
+	}
+}
+
+// SpanMetricsMeasurement is used to document the span metrics.
+type SpanMetricsMeasurement struct{}
+
+func (*SpanMetricsMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (*SpanMetricsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: SpanMetricsName,
+		Type: "metric",
+		Desc: "RED metrics generated from spans before sampling, enabled by `[inputs.<tracer>.span_metrics]`",
+		Tags: map[string]interface{}{
+			"source":       &inputs.TagInfo{Desc: "tracing source of the span"},
+			TAG_SERVICE:    &inputs.TagInfo{Desc: "service name"},
+			FIELD_RESOURCE: &inputs.TagInfo{Desc: "resource name"},
+			TAG_OPERATION:  &inputs.TagInfo{Desc: "span name"},
+			TAG_SPAN_TYPE:  &inputs.TagInfo{Desc: "span type"},
+		},
+		Fields: map[string]interface{}{
+			"hits":         &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "count of spans in the interval"},
+			"errors":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "count of error spans in the interval"},
+			"duration_sum": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationUS, Desc: "total duration of spans in the interval"},
+			"duration_max": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationUS, Desc: "max duration of spans in the interval"},
+			"duration_avg": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationUS, Desc: "average duration of spans in the interval"},
+		},
+	}
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestSpanMetricsAggregator(t *testing.T) {
+	defer func(f func(string, string, []*point.Point, *dkio.Option) error) { dkioFeed = f }(dkioFeed)
+
+	fed := map[string][]*point.Point{}
+	dkioFeed = func(name, category string, pts []*point.Point, opt *dkio.Option) error {
+		if category != datakit.Metric {
+			t.Errorf("expected category %s, got %s", datakit.Metric, category)
+		}
+		fed[name] = append(fed[name], pts...)
+
+		return nil
+	}
+
+	sma := NewSpanMetricsAggregator(&SpanMetrics{ExtraTags: []string{TAG_HTTP_METHOD}}, nil)
+	if sma.conf.Interval.Duration != defaultSpanMetricsInterval {
+		t.Errorf("expected default interval %s, got %s", defaultSpanMetricsInterval, sma.conf.Interval.Duration)
+	}
+
+	opts := []randSpanOption{
+		randService("checkout"), randResource("POST /pay"), randSource("ddtrace"),
+		randSpanTypes(SPAN_TYPE_ENTRY), randHTTPMethod("POST"), randSpanStatus(STATUS_OK),
+	}
+	dktrace := randDatakitTrace(t, 4, opts...)
+	for i := range dktrace {
+		dktrace[i].Operation = "pay"
+		dktrace[i].Duration = int64(time.Duration(i+1) * time.Millisecond)
+	}
+	dktrace[3].Status = STATUS_ERR
+
+	sma.Observe("ddtrace", DatakitTraces{dktrace[:2], dktrace[2:]})
+	sma.Observe("zipkin", DatakitTraces{randDatakitTrace(t, 1, opts...)})
+	sma.flush(time.Now())
+
+	if len(fed["ddtrace"]) != 1 || len(fed["zipkin"]) != 1 {
+		t.Fatalf("unexpected points fed: %v", fed)
+	}
+
+	pt := fed["ddtrace"][0]
+	tags := pt.Tags()
+	if tags[TAG_SERVICE] != "checkout" || tags[FIELD_RESOURCE] != "POST /pay" || tags[TAG_HTTP_METHOD] != "POST" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, expected := range map[string]interface{}{
+		"hits":         int64(4),
+		"errors":       int64(1),
+		"duration_sum": int64(10000),
+		"duration_max": int64(4000),
+		"duration_avg": float64(2500),
+	} {
+		if fields[k] != expected {
+			t.Errorf("field %s: expected %v, got %v", k, expected, fields[k])
+		}
+	}
+
+	// series reset after flush
+	if len(sma.series) != 0 {
+		t.Errorf("expected empty series after flush, got %d", len(sma.series))
+	}
+}
+
+func TestSpanMetricsBeforeSampling(t *testing.T) {
+	defer func(f func(string, string, []*point.Point, *dkio.Option) error) { dkioFeed = f }(dkioFeed)
+
+	hits := int64(0)
+	dkioFeed = func(name, category string, pts []*point.Point, opt *dkio.Option) error {
+		if category == datakit.Metric {
+			for _, pt := range pts {
+				fields, _ := pt.Fields()
+				hits += fields["hits"].(int64)
+			}
+		}
+
+		return nil
+	}
+
+	aga := NewAfterGather(WithSpanMetrics(&SpanMetrics{}))
+	defer aga.spanAggregator.Stop()
+
+	sampler := &Sampler{SamplingRateGlobal: 0}
+	aga.AppendFilter(sampler.Sample)
+
+	for i := 0; i < 10; i++ {
+		aga.Run("ddtrace", DatakitTraces{randDatakitTrace(t, 3, randPriority(PRIORITY_AUTO_KEEP))}, false)
+	}
+	aga.spanAggregator.flush(time.Now())
+
+	if hits != 30 {
+		t.Errorf("expected 30 hits counted before sampling, got %d", hits)
+	}
+}
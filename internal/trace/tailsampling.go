@@ -0,0 +1,387 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+)
+
+const (
+	defaultDecisionWait = 10 * time.Second
+	minDecisionWait     = time.Second
+	maxDecisionWait     = 5 * time.Minute
+	defaultMaxTraces    = 50000
+)
+
+// TailSamplingPolicy keeps the trace if any span in it matches all the
+// non-empty conditions.
+type TailSamplingPolicy struct {
+	Name     string            `toml:"name" json:"name"`
+	Service  string            `toml:"service" json:"service"`
+	Resource string            `toml:"resource" json:"resource"` // regular expression
+	Tags     map[string]string `toml:"tags" json:"tags"`
+
+	resource *regexp.Regexp
+}
+
+func (p *TailSamplingPolicy) match(dkspan *DatakitSpan) bool {
+	if p.Service != "" && p.Service != dkspan.Service {
+		return false
+	}
+	if p.resource != nil && !p.resource.MatchString(dkspan.Resource) {
+		return false
+	}
+	for k, v := range p.Tags {
+		if dkspan.Tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TailSampling is the config of tail-based sampling shared by tracing inputs.
+// Spans are buffered by trace ID for DecisionWait after the first span of the
+// trace arrived, then the whole trace is kept if it contains error spans,
+// lasts longer than LatencyThreshold or matches any of the policies, other
+// traces are kept by SamplingRate.
+type TailSampling struct {
+	DecisionWait     datakit.Duration      `toml:"decision_wait" json:"decision_wait"`
+	MaxTraces        int                   `toml:"max_traces" json:"max_traces"`
+	KeepError        bool                  `toml:"keep_error" json:"keep_error"`
+	LatencyThreshold datakit.Duration      `toml:"latency_threshold" json:"latency_threshold"`
+	SamplingRate     float64               `toml:"sampling_rate" json:"sampling_rate"`
+	Policies         []*TailSamplingPolicy `toml:"policies" json:"policies"`
+}
+
+type tailSpan struct {
+	inputName string
+	strict    bool
+	dkspan    *DatakitSpan
+}
+
+type traceBuffer struct {
+	spans     []*tailSpan
+	firstSeen time.Time
+	// set by filters like KeepRareResource which skip the rest filters
+	forceKeep bool
+}
+
+type tailDecision struct {
+	keep   bool
+	expire time.Time
+}
+
+// TailSampler buffers spans and makes sampling decisions on whole traces,
+// traces kept are fed back to the inputs the spans came from.
+type TailSampler struct {
+	sync.Mutex
+	conf     *TailSampling
+	feeds    map[string]AfterGatherHandler
+	log      *logger.Logger
+	traces   map[string]*traceBuffer
+	decided  map[string]*tailDecision
+	exit     *cliutils.Sem
+	stopOnce sync.Once
+}
+
+// NewTailSampler creates a tail sampler.
+func NewTailSampler(conf *TailSampling, log *logger.Logger) (*TailSampler, error) {
+	if log == nil {
+		log = logger.DefaultSLogger("tail-sampling")
+	}
+
+	if conf.DecisionWait.Duration <= 0 {
+		conf.DecisionWait.Duration = defaultDecisionWait
+	} else if conf.DecisionWait.Duration < minDecisionWait {
+		conf.DecisionWait.Duration = minDecisionWait
+	} else if conf.DecisionWait.Duration > maxDecisionWait {
+		conf.DecisionWait.Duration = maxDecisionWait
+	}
+	if conf.MaxTraces <= 0 {
+		conf.MaxTraces = defaultMaxTraces
+	}
+	if conf.SamplingRate < 0 || conf.SamplingRate > 1 {
+		log.Warnf("invalid tail sampling rate %f, reset to 1", conf.SamplingRate)
+		conf.SamplingRate = 1
+	}
+	for _, p := range conf.Policies {
+		if p.Resource == "" {
+			continue
+		}
+		reg, err := regexp.Compile(p.Resource)
+		if err != nil {
+			return nil, err
+		}
+		p.resource = reg
+	}
+
+	return &TailSampler{
+		conf:    conf,
+		feeds:   make(map[string]AfterGatherHandler),
+		log:     log,
+		traces:  make(map[string]*traceBuffer),
+		decided: make(map[string]*tailDecision),
+		exit:    cliutils.NewSem(),
+	}, nil
+}
+
+var (
+	sharedTailSamplerMtx sync.Mutex
+	sharedTailSampler    *TailSampler
+	sharedTailSamplerRef int
+)
+
+// acquireTailSampler returns the tail sampler shared by all tracing inputs, so
+// that spans of one trace received by different inputs are decided together.
+// The sampler is created on the first acquire, and its config is used until
+// all acquirers released.
+func acquireTailSampler(conf *TailSampling, log *logger.Logger) (*TailSampler, error) {
+	sharedTailSamplerMtx.Lock()
+	defer sharedTailSamplerMtx.Unlock()
+
+	if sharedTailSampler == nil {
+		ts, err := NewTailSampler(conf, log)
+		if err != nil {
+			return nil, err
+		}
+		ts.Start()
+		sharedTailSampler = ts
+	} else if sharedTailSampler.conf != conf {
+		log.Info("tail sampler already started by other tracing input, its config is used")
+	}
+
+	sharedTailSamplerRef++
+	return sharedTailSampler, nil
+}
+
+// releaseTailSampler stops the shared tail sampler once all acquirers
+// released, pending traces are decided on stop.
+func releaseTailSampler(ts *TailSampler) {
+	sharedTailSamplerMtx.Lock()
+	defer sharedTailSamplerMtx.Unlock()
+
+	if ts != sharedTailSampler {
+		return
+	}
+
+	if sharedTailSamplerRef--; sharedTailSamplerRef <= 0 {
+		ts.Stop()
+		sharedTailSampler = nil
+		sharedTailSamplerRef = 0
+	}
+}
+
+// Start runs the decision loop, pending traces are decided on exit.
+func (ts *TailSampler) Start() {
+	g := goroutine.NewGroup(goroutine.Option{Name: "internal_trace"})
+	g.Go(func(ctx context.Context) error {
+		interval := ts.conf.DecisionWait.Duration / 10
+		if interval < 100*time.Millisecond {
+			interval = 100 * time.Millisecond
+		}
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				ts.flush(time.Now(), false)
+			case <-datakit.Exit.Wait():
+				ts.flush(time.Now(), true)
+				return nil
+			case <-ts.exit.Wait():
+				ts.flush(time.Now(), true)
+				return nil
+			}
+		}
+	})
+}
+
+// Stop stops the decision loop.
+func (ts *TailSampler) Stop() {
+	ts.stopOnce.Do(ts.exit.Close)
+}
+
+func (ts *TailSampler) feed(inputName string, dktraces DatakitTraces, strict bool) {
+	ts.Lock()
+	feed, ok := ts.feeds[inputName]
+	ts.Unlock()
+
+	if !ok {
+		ts.log.Warnf("no feed of input %s, drop %d traces", inputName, len(dktraces))
+		return
+	}
+
+	feed.Run(inputName, dktraces, strict)
+}
+
+// Add buffers spans of the traces, spans in one DatakitTrace may belong to
+// different traces. forceKeep is aligned with dktraces. Traces kept of the
+// input are passed to feed, the one added later replaces the former.
+func (ts *TailSampler) Add(inputName string, feed AfterGatherHandler, dktraces DatakitTraces, forceKeep []bool, strict bool) {
+	var (
+		now  = time.Now()
+		late = make(map[string]DatakitTrace)
+		full bool
+	)
+
+	ts.Lock()
+	ts.feeds[inputName] = feed
+	for i := range dktraces {
+		force := i < len(forceKeep) && forceKeep[i]
+		for _, dkspan := range dktraces[i] {
+			if dkspan == nil {
+				continue
+			}
+
+			if d, ok := ts.decided[dkspan.TraceID]; ok {
+				// spans arrived after the decision follow it
+				if d.keep {
+					late[dkspan.TraceID] = append(late[dkspan.TraceID], dkspan)
+				}
+				continue
+			}
+
+			tb, ok := ts.traces[dkspan.TraceID]
+			if !ok {
+				if len(ts.traces) >= ts.conf.MaxTraces {
+					full = true
+				}
+				tb = &traceBuffer{firstSeen: now}
+				ts.traces[dkspan.TraceID] = tb
+			}
+			tb.spans = append(tb.spans, &tailSpan{inputName: inputName, strict: strict, dkspan: dkspan})
+			tb.forceKeep = tb.forceKeep || force
+		}
+	}
+	ts.Unlock()
+
+	if len(late) != 0 {
+		var lateTraces DatakitTraces
+		for _, dktrace := range late {
+			lateTraces = append(lateTraces, dktrace)
+		}
+		feed.Run(inputName, lateTraces, strict)
+	}
+
+	if full {
+		ts.log.Warnf("tail sampling buffer exceeds %d traces, decide the oldest traces now", ts.conf.MaxTraces)
+		ts.flushOldest(now)
+	}
+}
+
+// flushOldest decides the traces that first seen before half of the decision
+// wait, so that the buffer does not grow without bound.
+func (ts *TailSampler) flushOldest(now time.Time) {
+	ts.flush(now.Add(ts.conf.DecisionWait.Duration/2), false)
+}
+
+func (ts *TailSampler) flush(now time.Time, all bool) {
+	var keep []*traceBuffer
+
+	ts.Lock()
+	for tid, tb := range ts.traces {
+		if !all && now.Sub(tb.firstSeen) < ts.conf.DecisionWait.Duration {
+			continue
+		}
+		delete(ts.traces, tid)
+
+		d := &tailDecision{keep: ts.decide(tid, tb), expire: now.Add(ts.conf.DecisionWait.Duration)}
+		ts.decided[tid] = d
+		if d.keep {
+			keep = append(keep, tb)
+		}
+	}
+	for tid, d := range ts.decided {
+		if now.After(d.expire) {
+			delete(ts.decided, tid)
+		}
+	}
+	ts.Unlock()
+
+	type feedKey struct {
+		inputName string
+		strict    bool
+	}
+	batches := make(map[feedKey]DatakitTraces)
+	for _, tb := range keep {
+		byInput := make(map[feedKey]DatakitTrace)
+		for _, s := range tb.spans {
+			k := feedKey{inputName: s.inputName, strict: s.strict}
+			byInput[k] = append(byInput[k], s.dkspan)
+		}
+		for k, dktrace := range byInput {
+			batches[k] = append(batches[k], dktrace)
+		}
+	}
+
+	for k, dktraces := range batches {
+		ts.feed(k.inputName, dktraces, k.strict)
+	}
+}
+
+func (ts *TailSampler) decide(tid string, tb *traceBuffer) bool {
+	if tb.forceKeep {
+		ts.log.Debugf("keep tid: %s according to filters", tid)
+
+		return true
+	}
+
+	var start, end int64
+	for i, s := range tb.spans {
+		if ts.conf.KeepError && (s.dkspan.Status == STATUS_ERR || s.dkspan.Status == STATUS_CRITICAL) {
+			ts.log.Debugf("keep tid: %s service: %s resource: %s with error status", tid, s.dkspan.Service, s.dkspan.Resource)
+
+			return true
+		}
+
+		for _, p := range ts.conf.Policies {
+			if p.match(s.dkspan) {
+				ts.log.Debugf("keep tid: %s service: %s resource: %s according to policy %s", tid, s.dkspan.Service, s.dkspan.Resource, p.Name)
+
+				return true
+			}
+		}
+
+		if i == 0 || s.dkspan.Start < start {
+			start = s.dkspan.Start
+		}
+		if e := s.dkspan.Start + s.dkspan.Duration; e > end {
+			end = e
+		}
+	}
+
+	if threshold := ts.conf.LatencyThreshold.Duration; threshold > 0 && time.Duration(end-start) >= threshold {
+		ts.log.Debugf("keep tid: %s according to latency %s", tid, time.Duration(end-start))
+
+		return true
+	}
+
+	dktrace := make(DatakitTrace, 0, len(tb.spans))
+	for _, s := range tb.spans {
+		dktrace = append(dktrace, s.dkspan)
+	}
+
+	rate := remoteSampling.rate(rootService(dktrace), ts.conf.SamplingRate)
+	if multiplicativeHashFunc(UnifyToUint64ID(tid), rate) {
+		ts.log.Debugf("keep tid: %s according to sampling ratio: %d%%", tid, int(rate*100))
+
+		return true
+	}
+
+	ts.log.Debugf("drop tid: %s according to sampling ratio: %d%%", tid, int(rate*100))
+
+	return false
+}
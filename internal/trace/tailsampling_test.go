@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package trace
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+type tailCollector struct {
+	sync.Mutex
+	inputs map[string]int
+	spans  map[string]int
+}
+
+func (c *tailCollector) Run(inputName string, dktraces DatakitTraces, strikMod bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range dktraces {
+		c.inputs[inputName] += len(dktraces[i])
+		for j := range dktraces[i] {
+			c.spans[dktraces[i][j].TraceID]++
+		}
+	}
+}
+
+func newTailCollector() *tailCollector {
+	return &tailCollector{inputs: make(map[string]int), spans: make(map[string]int)}
+}
+
+func newTailTrace(t *testing.T, n int, opts ...randSpanOption) DatakitTrace {
+	t.Helper()
+
+	dktrace := randDatakitTrace(t, n, append([]randSpanOption{randSpanStatus(STATUS_OK)}, opts...)...)
+	parentialize(dktrace)
+	for i := range dktrace {
+		dktrace[i].Start = int64(time.Second)
+		dktrace[i].Duration = int64(time.Millisecond)
+	}
+
+	return dktrace
+}
+
+func TestTailSamplerDecide(t *testing.T) {
+	var (
+		normal  = newTailTrace(t, 3)
+		errored = newTailTrace(t, 3)
+		slow    = newTailTrace(t, 3)
+		matched = newTailTrace(t, 3, randService("checkout"), randResource("POST /pay"))
+		forced  = newTailTrace(t, 3)
+	)
+	errored[1].Status = STATUS_ERR
+	slow[2].Duration = int64(3 * time.Second)
+
+	c := newTailCollector()
+	ts, err := NewTailSampler(&TailSampling{
+		DecisionWait:     datakit.Duration{Duration: time.Second},
+		KeepError:        true,
+		LatencyThreshold: datakit.Duration{Duration: 2 * time.Second},
+		Policies:         []*TailSamplingPolicy{{Name: "pay", Service: "checkout", Resource: "^POST /"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Add("ddtrace", c, DatakitTraces{normal, errored, slow, matched, forced}, []bool{false, false, false, false, true}, false)
+
+	ts.flush(time.Now(), false)
+	if len(c.spans) != 0 {
+		t.Fatalf("traces fed before decision wait: %v", c.spans)
+	}
+
+	ts.flush(time.Now().Add(time.Second), false)
+	for _, dktrace := range []DatakitTrace{errored, slow, matched, forced} {
+		if c.spans[dktrace[0].TraceID] != 3 {
+			t.Errorf("trace %s expected to be kept", dktrace[0].TraceID)
+		}
+	}
+	if _, ok := c.spans[normal[0].TraceID]; ok {
+		t.Errorf("trace %s expected to be dropped", normal[0].TraceID)
+	}
+	if len(ts.traces) != 0 {
+		t.Errorf("expected empty buffer, got %d traces", len(ts.traces))
+	}
+}
+
+func TestTailSamplerLateSpans(t *testing.T) {
+	var (
+		kept    = newTailTrace(t, 2)
+		dropped = newTailTrace(t, 2)
+	)
+	kept[0].Status = STATUS_ERR
+
+	c := newTailCollector()
+	ts, err := NewTailSampler(&TailSampling{KeepError: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Add("zipkin", c, DatakitTraces{kept[:1], dropped[:1]}, nil, false)
+	ts.flush(time.Now(), true)
+
+	// the rest spans of decided traces follow the decision
+	ts.Add("zipkin", c, DatakitTraces{kept[1:], dropped[1:]}, nil, false)
+	if c.spans[kept[0].TraceID] != 2 {
+		t.Errorf("expected 2 spans kept, got %d", c.spans[kept[0].TraceID])
+	}
+	if _, ok := c.spans[dropped[0].TraceID]; ok {
+		t.Errorf("trace %s expected to be dropped", dropped[0].TraceID)
+	}
+	if len(ts.traces) != 0 {
+		t.Errorf("late spans should not be buffered, got %d traces", len(ts.traces))
+	}
+}
+
+func TestTailSamplerGroupByTraceID(t *testing.T) {
+	var (
+		a = newTailTrace(t, 2)
+		b = newTailTrace(t, 2)
+	)
+	b[1].Status = STATUS_ERR
+
+	c := newTailCollector()
+	ts, err := NewTailSampler(&TailSampling{KeepError: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one chunk mixed with spans of different traces, from different inputs
+	ts.Add("opentelemetry", c, DatakitTraces{{a[0], b[0]}}, nil, false)
+	ts.Add("skywalking", c, DatakitTraces{{a[1], b[1]}}, nil, false)
+	ts.flush(time.Now(), true)
+
+	if _, ok := c.spans[a[0].TraceID]; ok {
+		t.Errorf("trace %s expected to be dropped", a[0].TraceID)
+	}
+	if c.spans[b[0].TraceID] != 2 {
+		t.Errorf("expected 2 spans kept, got %d", c.spans[b[0].TraceID])
+	}
+	if c.inputs["opentelemetry"] != 1 || c.inputs["skywalking"] != 1 {
+		t.Errorf("unexpected spans fed by input: %v", c.inputs)
+	}
+}
+
+func TestTailSamplerSamplingRate(t *testing.T) {
+	dktraces := make(DatakitTraces, 1000)
+	for i := range dktraces {
+		dktraces[i] = newTailTrace(t, 1)
+	}
+
+	for _, rate := range []float64{0, 1} {
+		c := newTailCollector()
+		ts, err := NewTailSampler(&TailSampling{SamplingRate: rate}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ts.Add("ddtrace", c, dktraces, nil, false)
+		ts.flush(time.Now(), true)
+
+		if expected := int(rate * float64(len(dktraces))); len(c.spans) != expected {
+			t.Errorf("sampling rate %f: expected %d traces, got %d", rate, expected, len(c.spans))
+		}
+	}
+}
+
+func TestTailSamplerInvalidPolicy(t *testing.T) {
+	if _, err := NewTailSampler(&TailSampling{Policies: []*TailSamplingPolicy{{Resource: "(*"}}}, nil); err == nil {
+		t.Error("expect error on invalid resource regexp")
+	}
+}
+
+func TestSharedTailSampler(t *testing.T) {
+	var (
+		ddtrace = NewAfterGather(WithTailSampling(&TailSampling{KeepError: true}))
+		otel    = NewAfterGather(WithTailSampling(&TailSampling{SamplingRate: 1}))
+	)
+
+	if ddtrace.tailSampler == nil || ddtrace.tailSampler != otel.tailSampler {
+		t.Fatal("expect tail sampler shared by AfterGathers")
+	}
+	if ts := ddtrace.tailSampler; !ts.conf.KeepError || ts.conf.SamplingRate != 0 {
+		t.Errorf("expect config of the first AfterGather, got %+v", ts.conf)
+	}
+
+	ddtrace.Stop()
+	ddtrace.Stop() // stop twice should not release twice
+	if sharedTailSampler != otel.tailSampler {
+		t.Fatal("tail sampler released before all AfterGathers stopped")
+	}
+
+	otel.Stop()
+	if sharedTailSampler != nil {
+		t.Error("expect tail sampler released")
+	}
+	select {
+	case <-otel.tailSampler.exit.Wait():
+	default:
+		t.Error("expect tail sampler stopped")
+	}
+}
+
+func TestTailSamplerWithOverrides(t *testing.T) {
+	defer func(so *samplingOverrides) { remoteSampling = so }(remoteSampling)
+
+	remoteSampling = &samplingOverrides{}
+	if _, err := remoteSampling.refresh([]byte(`{"trace_sampling":{"services":{"checkout":0,"login":1}}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTailCollector()
+	ts, err := NewTailSampler(&TailSampling{SamplingRate: 0.5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := map[string]string{}
+	for i := 0; i < 100; i++ {
+		for _, service := range []string{"checkout", "login"} {
+			dktrace := newTailTrace(t, 3, randService(service))
+			// only the service of root span counts
+			dktrace[1].Service = "other"
+			roots[dktrace[0].TraceID] = service
+
+			ts.Add("ddtrace", c, DatakitTraces{dktrace}, nil, false)
+		}
+	}
+	ts.flush(time.Now(), true)
+
+	kept := map[string]int{}
+	for tid := range c.spans {
+		kept[roots[tid]]++
+	}
+	if kept["checkout"] != 0 || kept["login"] != 100 {
+		t.Errorf("unexpected kept traces: %v", kept)
+	}
+}
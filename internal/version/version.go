@@ -38,6 +38,8 @@ func (vi *VerInfo) Compare(i *VerInfo) int {
 
 	if a > b {
 		return 1
+	} else if a < b {
+		return -1
 	}
 
 	// same version number: 1.1.7
@@ -22,6 +22,7 @@ type consumer struct {
 	flushTiker *time.Ticker
 
 	pts               []*point.Point
+	origins           []string // input name of each point in pts
 	lastFlush         time.Time
 	dynamicDatawayPts map[string][]*point.Point // 拨测数据
 }
@@ -74,6 +75,11 @@ func (x *dkIO) runConsumer(category string) {
 		case <-fcTick.C:
 			x.flushFailCache(c)
 
+		case <-x.forceFlushChans[category]:
+			log.Infof("force flush %s(%d pts)", c.category, len(c.pts))
+			x.flush(c)
+			x.flushFailCache(c)
+
 		case <-datakit.Exit.Wait():
 			log.Infof("io consumer on %s exit on exit", c.category)
 			return
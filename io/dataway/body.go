@@ -17,7 +17,7 @@ type bodyPayload int
 
 const (
 	payloadLineProtocol bodyPayload = iota
-	// TODO: used to cache protobuf point.
+	payloadProtobuf
 )
 
 type body struct {
@@ -26,6 +26,10 @@ type body struct {
 	gzon    bool
 	npts    int
 	payload bodyPayload
+
+	precision string // timestamp precision of lines, empty for ns
+	checksum  string // checksum header of the uncompressed buf, computed on sending
+	origin    string // origin header of inputs producing the points, not cached
 }
 
 func (b *body) String() string {
@@ -56,8 +60,8 @@ func getBody(lines [][]byte, idxBegin, idxEnd, curPartSize int) (*body, error) {
 	return out, nil
 }
 
-// buildBody convert pts to lineprotocol body.
-func buildBody(pts []*point.Point, max int) ([]*body, error) {
+// buildBody convert pts to lineprotocol body with timestamps in precision.
+func buildBody(pts []*point.Point, max int, precision string) ([]*body, error) {
 	lines := [][]byte{}
 	curPartSize := 0
 
@@ -65,7 +69,7 @@ func buildBody(pts []*point.Point, max int) ([]*body, error) {
 
 	idxBegin := 0
 	for idx, pt := range pts {
-		ptbytes := []byte(pt.String())
+		ptbytes := []byte(lineOf(pt, precision))
 
 		// 此处必须提前预判包是否会大于上限值，当新进来的 ptbytes 可能
 		// 会超过上限时，就应该及时将已有数据（肯定没超限）打包一下。
@@ -75,6 +79,7 @@ func buildBody(pts []*point.Point, max int) ([]*body, error) {
 			if body, err := getBody(lines, idxBegin, idx, curPartSize); err != nil {
 				return nil, err
 			} else {
+				body.precision = precision
 				idxBegin = idx
 				bodies = append(bodies, body)
 				lines = lines[:0]
@@ -92,6 +97,7 @@ func buildBody(pts []*point.Point, max int) ([]*body, error) {
 		if body, err := getBody(lines, idxBegin, len(pts), curPartSize); err != nil {
 			return nil, err
 		} else {
+			body.precision = precision
 			return append(bodies, body), nil
 		}
 	} else {
@@ -30,7 +30,7 @@ func TestBuildBody(t *T.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *T.T) {
-			bodies, err := buildBody(tc.pts, maxBody)
+			bodies, err := buildBody(tc.pts, maxBody, "")
 			if err != nil {
 				t.Error(err)
 			}
@@ -45,7 +45,7 @@ func TestBuildBody(t *T.T) {
 	// test body === pts
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *T.T) {
-			bodies, err := buildBody(tc.pts, maxBody)
+			bodies, err := buildBody(tc.pts, maxBody, "")
 			if err != nil {
 				t.Error(err)
 			}
@@ -85,7 +85,7 @@ func BenchmarkBuildBody(b *T.B) {
 	for _, tc := range cases {
 		b.Run(tc.name, func(t *T.B) {
 			for i := 0; i < b.N; i++ {
-				_, err := buildBody(tc.pts, MaxKodoBody)
+				_, err := buildBody(tc.pts, MaxKodoBody, "")
 				if err != nil {
 					t.Error(err)
 				}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// headerChecksum carries the checksum of the uncompressed body, in form
+	// of "<algorithm>=<hex>", and is echoed by Dataway in the response.
+	headerChecksum = "X-Body-Checksum"
+
+	checksumCRC32  = "crc32"
+	checksumXXHash = "xxhash"
+
+	checksumOK       = "ok"
+	checksumMismatch = "mismatch"
+	checksumMissing  = "missing" // not echoed, Dataway may not support it
+)
+
+var checksumAlgorithms = map[string]func([]byte) string{
+	checksumCRC32: func(data []byte) string {
+		return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+	},
+	checksumXXHash: func(data []byte) string {
+		return fmt.Sprintf("%016x", xxhash.Sum64(data))
+	},
+}
+
+// setupChecksum checks the checksum algorithm, empty for disabled.
+func setupChecksum(algorithm string) (string, error) {
+	algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+	if algorithm == "" {
+		return "", nil
+	}
+
+	if _, ok := checksumAlgorithms[algorithm]; !ok {
+		return "", fmt.Errorf("invalid checksum %q, only crc32/xxhash allowed", algorithm)
+	}
+
+	return algorithm, nil
+}
+
+func withChecksum(algorithm string) endPointOption {
+	return func(ep *endPoint) {
+		ep.checksum = algorithm
+	}
+}
+
+// bodyChecksum returns the checksum header value of the uncompressed body.
+func bodyChecksum(algorithm string, b *body, gz bool) (string, error) {
+	if b.checksum != "" && strings.HasPrefix(b.checksum, algorithm+"=") {
+		return b.checksum, nil
+	}
+
+	data := b.buf
+	if gz {
+		r, err := gzip.NewReader(bytes.NewReader(b.buf))
+		if err != nil {
+			return "", err
+		}
+
+		if data, err = io.ReadAll(r); err != nil {
+			return "", err
+		}
+	}
+
+	b.checksum = algorithm + "=" + checksumAlgorithms[algorithm](data)
+	return b.checksum, nil
+}
+
+// verifyChecksum checks the checksum echoed in the response against the sent one.
+func verifyChecksum(sent string, resp *http.Response) string {
+	echoed := resp.Header.Get(headerChecksum)
+	switch {
+	case echoed == "":
+		return checksumMissing
+	case strings.EqualFold(echoed, sent):
+		return checksumOK
+	default:
+		return checksumMismatch
+	}
+}
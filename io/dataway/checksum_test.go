@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+
+	"github.com/GuanceCloud/cliutils/metrics"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+func TestChecksum(t *T.T) {
+	raw := []byte("cpu,host=abc usage=0.1 123\nmem,host=abc used=1024i 123\n")
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	expect := fmt.Sprintf("crc32=%08x", crc32.ChecksumIEEE(raw))
+
+	t.Run("setup", func(t *T.T) {
+		algo, err := setupChecksum(" CRC32 ")
+		assert.NoError(t, err)
+		assert.Equal(t, checksumCRC32, algo)
+
+		algo, err = setupChecksum("")
+		assert.NoError(t, err)
+		assert.Empty(t, algo)
+
+		_, err = setupChecksum("md5")
+		assert.Error(t, err)
+	})
+
+	t.Run("body", func(t *T.T) {
+		b := &body{buf: buf.Bytes()}
+		sum, err := bodyChecksum(checksumCRC32, b, true)
+		require.NoError(t, err)
+		assert.Equal(t, expect, sum)
+
+		sum, err = bodyChecksum(checksumXXHash, &body{buf: raw}, false)
+		require.NoError(t, err)
+		assert.Regexp(t, `^xxhash=[0-9a-f]{16}$`, sum)
+
+		_, err = bodyChecksum(checksumCRC32, &body{buf: raw}, true)
+		assert.Error(t, err, "not gzipped")
+	})
+
+	for _, tc := range []struct {
+		name, echo, result string
+	}{
+		{"ok", "", checksumOK},
+		{"mismatch", "crc32=00000000", checksumMismatch},
+		{"missing", "-", checksumMissing},
+	} {
+		t.Run(tc.name, func(t *T.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent := r.Header.Get(headerChecksum)
+				assert.Equal(t, expect, sent)
+
+				switch tc.echo {
+				case "":
+					w.Header().Set(headerChecksum, sent)
+				case "-":
+				default:
+					w.Header().Set(headerChecksum, tc.echo)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			ep, err := newEndpoint(fmt.Sprintf("%s?token=abc", ts.URL),
+				withAPIs([]string{datakit.Metric}), withChecksum(checksumCRC32))
+			require.NoError(t, err)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(Metrics()...)
+
+			w := &writer{category: datakit.Metric, gzip: true}
+			assert.NoError(t, ep.writePointData(&body{buf: buf.Bytes(), npts: 2}, w))
+
+			mfs, err := reg.Gather()
+			require.NoError(t, err)
+
+			m := metrics.GetMetricOnLabels(mfs, `datakit_io_dataway_checksum_total`, point.Metric.String(), tc.result)
+			require.NotNil(t, m)
+			assert.Equal(t, float64(1), m.GetCounter().GetValue())
+
+			t.Cleanup(func() {
+				ts.Close()
+				metricsReset()
+			})
+		})
+	}
+}
@@ -37,6 +37,7 @@ var (
 		point.Profiling.URL(),
 
 		datakit.DatakitPull,
+		datakit.RemoteJobResult,
 		datakit.LogFilter,
 		datakit.SessionReplayUpload,
 		datakit.HeartBeat,
@@ -67,6 +68,9 @@ type Dataway struct {
 
 	Sinkers []*Sinker `toml:"sinkers,omitempty"`
 
+	// Shadows duplicate part of points to other dataways for migration testing.
+	Shadows []*Shadow `toml:"shadows,omitempty"`
+
 	// Deprecated
 	DeprecatedHost   string `toml:"host,omitempty"`
 	DeprecatedScheme string `toml:"scheme,omitempty"`
@@ -75,12 +79,43 @@ type Dataway struct {
 
 	MaxIdleConnsPerHost int `toml:"max_idle_conns_per_host,omitempty"`
 
+	// Max size in bytes of (decompressed) response from dataway, such as log
+	// filter rules and pulled pipelines, default 32MB.
+	MaxResponseSize int64 `toml:"max_response_size,omitempty"`
+
 	httpTimeout time.Duration
 
+	// WarmUp connects to each dataway on startup, and PingInterval pings
+	// dataways idle for the interval to keep the connections open, so the
+	// first upload after idle periods not pay for the DNS and TLS setup.
+	WarmUp       bool   `toml:"warm_up,omitempty"`
+	PingInterval string `toml:"ping_interval,omitempty"`
+
+	pingInterval time.Duration
+
 	Proxy bool `toml:"proxy,omitempty"`
 
 	EnableHTTPTrace bool `toml:"enable_httptrace,omitempty"`
 
+	// timestamp precision(s/ms/us/ns) of line protocol keyed by category
+	// name, such as {metric = "s", logging = "ms"}, ns if not set.
+	Precision map[string]string `toml:"precision,omitempty"`
+
+	precisions map[string]string // category URL -> line protocol precision
+
+	// extra HTTP headers of dataway requests keyed by host of dataway URL, or by
+	// category name, they are merged with the global headers at request time.
+	EndpointHeaders map[string]map[string]string `toml:"endpoint_headers,omitempty"`
+	CategoryHeaders map[string]map[string]string `toml:"category_headers,omitempty"`
+
+	categoryHeaders map[string]map[string]string // category URL -> headers
+
+	// Checksum(crc32/xxhash) of the uncompressed body sent in header, Dataway
+	// echoes it in the response to detect bodies corrupted on the way.
+	Checksum string `toml:"checksum,omitempty"`
+
+	checksum string
+
 	eps        []*endPoint
 	locker     sync.RWMutex
 	dnsCachers []*dnsCacher
@@ -155,12 +190,43 @@ func (dw *Dataway) doInit() error {
 	}
 	dw.httpTimeout = du
 
+	if dw.PingInterval != "" {
+		if dw.pingInterval, err = time.ParseDuration(dw.PingInterval); err != nil {
+			return fmt.Errorf("invalid ping_interval %q: %w", dw.PingInterval, err)
+		}
+	}
+
+	if dw.precisions, err = setupPrecisions(dw.Precision); err != nil {
+		return err
+	}
+
+	if dw.categoryHeaders, err = setupCategoryHeaders(dw.CategoryHeaders); err != nil {
+		return err
+	}
+
+	if dw.checksum, err = setupChecksum(dw.Checksum); err != nil {
+		return err
+	}
+
 	for _, s := range dw.Sinkers {
+		s.precisions = dw.precisions
+		s.checksum = dw.checksum
+		s.endpointHeaders = dw.EndpointHeaders
+		s.categoryHeaders = dw.categoryHeaders
 		if err := s.Setup(); err != nil {
 			log.Warnf("sinker %s setup failed: %s", s.String(), err.Error())
 		}
 	}
 
+	for _, s := range dw.Shadows {
+		s.checksum = dw.checksum
+		s.endpointHeaders = dw.EndpointHeaders
+		s.categoryHeaders = dw.categoryHeaders
+		if err := s.Setup(); err != nil {
+			log.Warnf("shadow %s setup failed: %s", s.String(), err.Error())
+		}
+	}
+
 	for _, u := range dw.URLs {
 		ep, err := newEndpoint(u,
 			withProxy(dw.HTTPProxy),
@@ -168,6 +234,9 @@ func (dw *Dataway) doInit() error {
 			withHTTPTimeout(dw.httpTimeout),
 			withHTTPTrace(dw.EnableHTTPTrace),
 			withMaxHTTPIdleConnectionPerHost(dw.MaxIdleConnsPerHost),
+			withMaxResponseSize(dw.MaxResponseSize),
+			withHeaders(dw.EndpointHeaders, dw.categoryHeaders),
+			withChecksum(dw.checksum),
 		)
 		if err != nil {
 			log.Errorf("init dataway url %s failed: %s", u, err.Error())
@@ -179,6 +248,8 @@ func (dw *Dataway) doInit() error {
 		dw.addDNSCache(ep.host)
 	}
 
+	dw.startPing()
+
 	return nil
 }
 
@@ -205,5 +276,10 @@ func (dw *Dataway) initEndpoints() error {
 		}
 	}
 
+	// connections of the new HTTP clients not warmed up yet.
+	if dw.WarmUp {
+		go dw.pingEndpoints(0)
+	}
+
 	return nil
 }
@@ -40,7 +40,13 @@ type endPoint struct {
 	apis                         []string
 	httpTimeout                  time.Duration
 	maxHTTPIdleConnectionPerHost int
+	maxResponseSize              int64
 	httpTrace                    bool
+	headers                      map[string]string
+	categoryHeaders              map[string]map[string]string // category URL -> headers
+	checksum                     string                       // checksum algorithm of bodies, empty if disabled
+
+	lastActive int64 // unix nano of the last request
 }
 
 func (ep *endPoint) String() string {
@@ -92,10 +98,11 @@ func newEndpoint(urlstr string, opts ...endPointOption) (*endPoint, error) {
 	}
 
 	ep := &endPoint{
-		categoryURL: map[string]string{},
-		token:       u.Query().Get("token"),
-		host:        u.Host,
-		scheme:      u.Scheme,
+		categoryURL:     map[string]string{},
+		token:           u.Query().Get("token"),
+		host:            u.Host,
+		scheme:          u.Scheme,
+		maxResponseSize: defaultMaxResponseSize,
 	}
 
 	// apply options
@@ -211,11 +218,19 @@ func (ep *endPoint) writePoints(w *writer) error {
 		err    error
 	)
 
-	bodies, err = buildBody(w.pts, MaxKodoBody)
+	bodies, err = buildBody(w.pts, MaxKodoBody, w.precision)
 	if err != nil {
 		return err
 	}
 
+	if len(w.origins) == len(w.pts) {
+		off := 0
+		for _, body := range bodies {
+			body.origin = originOf(w.origins[off : off+body.npts])
+			off += body.npts
+		}
+	}
+
 	for _, body := range bodies {
 		ep.writeBody(w, body)
 	}
@@ -228,6 +243,7 @@ func doCache(w *writer, b *body) error {
 		Category:    int32(point.CatURL(w.category)),
 		PayloadType: int32(b.payload),
 		Payload:     b.buf,
+		Precision:   b.precision,
 	}); err != nil {
 		return err
 	} else {
@@ -271,6 +287,15 @@ func (ep *endPoint) writePointData(b *body, w *writer) error {
 			cat = point.DynamicDWCategory.String()
 		}
 
+		if w.shadow != nil { // shadow points not counted on primary metrics
+			shadowPtsVec.WithLabelValues(cat, "shadow", w.shadow.Encoding, httpCodeStr).Add(float64(b.npts))
+			return
+		}
+
+		if w.shadowed {
+			shadowPtsVec.WithLabelValues(cat, "primary", EncodingLineProtocol, httpCodeStr).Add(float64(b.npts))
+		}
+
 		bytesCounterVec.WithLabelValues(
 			cat,
 			httpCodeStr).Add(float64(len(b.buf)))
@@ -281,7 +306,7 @@ func (ep *endPoint) writePointData(b *body, w *writer) error {
 		}
 	}()
 
-	req, err := http.NewRequest("POST", requrl, bytes.NewBuffer(b.buf))
+	req, err := http.NewRequest("POST", withPrecision(requrl, b.precision), bytes.NewBuffer(b.buf))
 	if err != nil {
 		log.Error(err)
 		return err
@@ -291,8 +316,19 @@ func (ep *endPoint) writePointData(b *body, w *writer) error {
 		req.Header.Set("Content-Encoding", "gzip")
 	}
 
-	for k, v := range ExtraHeaders {
-		req.Header.Set(k, v)
+	if b.payload == payloadProtobuf {
+		req.Header.Set("Content-Type", contentTypeProtobuf)
+	}
+
+	ep.setHeaders(req, w.category, b.origin)
+
+	var checksum string
+	if ep.checksum != "" {
+		if checksum, err = bodyChecksum(ep.checksum, b, w.gzip); err != nil {
+			log.Warnf("checksum of %d bytes on %s: %s, ignored", len(b.buf), w.category, err)
+		} else {
+			req.Header.Set(headerChecksum, checksum)
+		}
 	}
 
 	resp, err := ep.sendReq(req)
@@ -331,9 +367,19 @@ func (ep *endPoint) writePointData(b *body, w *writer) error {
 	case 2:
 		log.Debugf("post %d bytes to %s ok(gz: %v)", len(b.buf), requrl, w.gzip)
 
+		if checksum != "" {
+			result := verifyChecksum(checksum, resp)
+			if result == checksumMismatch {
+				log.Errorf("post %d bytes to %s: checksum %s mismatched with %q echoed, the body may be corrupted on the way",
+					len(b.buf), requrl, checksum, resp.Header.Get(headerChecksum))
+			}
+
+			checksumCounterVec.WithLabelValues(point.CatURL(w.category).String(), result).Inc()
+		}
+
 		// Send data ok, it means the error `beyond-usage` error is cleared by kodo server,
 		// we have to clear the hint in monitor too.
-		if strings.Contains(requrl, "/v1/write/") && atomic.LoadInt64(&metrics.BeyondUsage) > 0 {
+		if w.shadow == nil && strings.Contains(requrl, "/v1/write/") && atomic.LoadInt64(&metrics.BeyondUsage) > 0 {
 			log.Info("clear BeyondUsage")
 			atomic.StoreInt64(&metrics.BeyondUsage, 0)
 		}
@@ -350,7 +396,7 @@ func (ep *endPoint) writePointData(b *body, w *writer) error {
 
 		switch resp.StatusCode {
 		case http.StatusForbidden:
-			if strings.Contains(strBody, "beyondDataUsage") {
+			if w.shadow == nil && strings.Contains(strBody, "beyondDataUsage") {
 				atomic.AddInt64(&metrics.BeyondUsage, time.Now().Unix()) // will set `beyond-usage' hint in monitor.
 				log.Info("set BeyondUsage")
 			}
@@ -425,13 +471,13 @@ func (ep *endPoint) datakitPull(args string) ([]byte, error) {
 		return nil, err
 	}
 
+	defer resp.Body.Close() //nolint:errcheck
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Error(err.Error())
 		return nil, err
 	}
 
-	defer resp.Body.Close() //nolint:errcheck
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("datakitPull failed with status code %d, body: %s", resp.StatusCode, string(body))
 	}
@@ -474,6 +520,9 @@ func (ep *endPoint) sendReq(req *http.Request) (*http.Response, error) {
 		req = req.WithContext(httptrace.WithClientTrace(req.Context(), t))
 	}
 
+	acceptGzip(req)
+	ep.touch()
+
 	x, err := rhttp.FromRequest(req)
 	if err != nil {
 		log.Errorf("rhttp.FromRequest: %s", err)
@@ -495,5 +544,9 @@ func (ep *endPoint) sendReq(req *http.Request) (*http.Response, error) {
 		httpCodeStr = http.StatusText(resp.StatusCode)
 	}
 
+	if err := ep.guardResponse(resp); err != nil {
+		return nil, err
+	}
+
 	return resp, nil
 }
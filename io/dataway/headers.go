@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const (
+	// headerOrigin lists inputs(with hash of their configures) producing
+	// points of the request body, such as `cpu:1a2b3c4d,mem`, so the server
+	// side can attribute bad payload to the input and DataKit(see header
+	// X-Datakit-Info).
+	headerOrigin = "X-Datakit-Origin"
+
+	maxOriginInputs = 32
+)
+
+var (
+	confHashes   = map[string]string{}
+	confHashesMu sync.RWMutex
+)
+
+// SetConfigHashes set hash of configures keyed by input name, the hash is
+// attached along with the input name in origin header.
+func SetConfigHashes(hashes map[string]string) {
+	confHashesMu.Lock()
+	defer confHashesMu.Unlock()
+	confHashes = hashes
+}
+
+// originOf build origin header value of inputs producing the points.
+func originOf(inputs []string) string {
+	uniq := map[string]bool{}
+	for _, x := range inputs {
+		if x != "" {
+			uniq[x] = true
+		}
+	}
+
+	if len(uniq) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(uniq))
+	for x := range uniq {
+		names = append(names, x)
+	}
+	sort.Strings(names)
+
+	confHashesMu.RLock()
+	defer confHashesMu.RUnlock()
+
+	arr := make([]string, 0, len(names))
+	for idx, x := range names {
+		if idx == maxOriginInputs {
+			arr = append(arr, "...")
+			break
+		}
+
+		if h, ok := confHashes[x]; ok {
+			arr = append(arr, x+":"+h)
+		} else {
+			arr = append(arr, x)
+		}
+	}
+
+	return strings.Join(arr, ",")
+}
+
+// setupCategoryHeaders checks category names of the headers settings, and
+// returns headers keyed by category URL.
+func setupCategoryHeaders(settings map[string]map[string]string) (map[string]map[string]string, error) {
+	res := map[string]map[string]string{}
+	for k, v := range settings {
+		cat := point.CatString(k)
+		if cat == point.UnknownCategory {
+			return nil, fmt.Errorf("invalid headers category %q", k)
+		}
+
+		res[cat.URL()] = v
+	}
+
+	return res, nil
+}
+
+// withHeaders set headers of the endpoint, headers picked by host of the
+// endpoint URL, and per-category headers keyed by category URL.
+func withHeaders(endpointHeaders, categoryHeaders map[string]map[string]string) endPointOption {
+	return func(ep *endPoint) {
+		ep.headers = endpointHeaders[ep.host] // host already parsed before options applied
+		ep.categoryHeaders = categoryHeaders
+	}
+}
+
+// setHeaders set global extra headers, category headers and endpoint headers
+// on req, the later override the former.
+func (ep *endPoint) setHeaders(req *http.Request, category, origin string) {
+	if origin != "" {
+		req.Header.Set(headerOrigin, origin)
+	}
+
+	for k, v := range ExtraHeaders {
+		req.Header.Set(k, v)
+	}
+
+	for k, v := range ep.categoryHeaders[category] {
+		req.Header.Set(k, v)
+	}
+
+	for k, v := range ep.headers {
+		req.Header.Set(k, v)
+	}
+}
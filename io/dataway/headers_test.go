@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestExtraHeaders(t *T.T) {
+	t.Run("bad-category", func(t *T.T) {
+		_, err := setupCategoryHeaders(map[string]map[string]string{"unknown": {"X-A": "a"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("merged", func(t *T.T) {
+		headers := map[string]http.Header{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers[r.URL.Path] = r.Header.Clone()
+			w.WriteHeader(200)
+		}))
+		defer srv.Close()
+
+		u, err := url.Parse(srv.URL)
+		require.NoError(t, err)
+
+		origin := ExtraHeaders
+		ExtraHeaders = map[string]string{"X-Global": "global", "X-Route": "global"}
+		defer func() { ExtraHeaders = origin }()
+
+		dw := &Dataway{
+			URLs: []string{fmt.Sprintf("%s?token=tkn_for_test", srv.URL)},
+			EndpointHeaders: map[string]map[string]string{
+				u.Host:          {"X-Tenant": "t1"},
+				"some.host.com": {"X-Tenant": "t2"},
+			},
+			CategoryHeaders: map[string]map[string]string{
+				"logging": {"X-Route": "logs", "X-Tenant": "overridden"},
+			},
+		}
+		require.NoError(t, dw.Init())
+
+		pts := []*dkpt.Point{
+			dkpt.MustNewPoint("m1", nil, map[string]any{"f1": 1}, &dkpt.PointOption{Category: datakit.Metric}),
+		}
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts)))
+		assert.NoError(t, dw.Write(WithCategory(datakit.Logging), WithPoints(pts)))
+
+		h := headers[datakit.Metric]
+		require.NotNil(t, h)
+		assert.Equal(t, "global", h.Get("X-Global"))
+		assert.Equal(t, "global", h.Get("X-Route"))
+		assert.Equal(t, "t1", h.Get("X-Tenant"))
+
+		h = headers[datakit.Logging]
+		require.NotNil(t, h)
+		assert.Equal(t, "global", h.Get("X-Global"))
+		assert.Equal(t, "logs", h.Get("X-Route"))
+		assert.Equal(t, "t1", h.Get("X-Tenant"))
+
+		metricsReset()
+	})
+}
+
+func TestOriginHeader(t *T.T) {
+	SetConfigHashes(map[string]string{"cpu": "1a2b3c4d"})
+	defer SetConfigHashes(map[string]string{})
+
+	t.Run("origin-of", func(t *T.T) {
+		assert.Equal(t, "", originOf(nil))
+		assert.Equal(t, "cpu:1a2b3c4d,mem", originOf([]string{"mem", "cpu", "", "mem"}))
+	})
+
+	t.Run("per-body", func(t *T.T) {
+		var origins []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origins = append(origins, r.Header.Get(headerOrigin))
+			w.WriteHeader(200)
+		}))
+		defer srv.Close()
+
+		dw := &Dataway{URLs: []string{fmt.Sprintf("%s?token=tkn_for_test", srv.URL)}}
+		require.NoError(t, dw.Init())
+
+		pts := []*dkpt.Point{
+			dkpt.MustNewPoint("m1", nil, map[string]any{"f1": 1}, &dkpt.PointOption{Category: datakit.Metric}),
+			dkpt.MustNewPoint("m2", nil, map[string]any{"f1": 2}, &dkpt.PointOption{Category: datakit.Metric}),
+		}
+
+		// each point as a body
+		origin := MaxKodoBody
+		MaxKodoBody = len(pts[0].String()) + 1
+		defer func() { MaxKodoBody = origin }()
+
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts), WithOrigins([]string{"cpu", "mem"})))
+		assert.Equal(t, []string{"cpu:1a2b3c4d", "mem"}, origins)
+
+		origins = origins[:0]
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts)))
+		assert.Equal(t, []string{"", ""}, origins)
+
+		metricsReset()
+	})
+}
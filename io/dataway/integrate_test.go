@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"os"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/diskcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	tu "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+// TestDatawayResilience writes points to dataway behind toxiproxy under bad
+// networks, points failed are cached and should be all delivered by cleaning
+// the cache after the network recovered.
+func TestDatawayResilience(t *T.T) {
+	if os.Getenv("REMOTE_HOST") == "" && os.Getenv("TOXIPROXY_URL") == "" {
+		t.Skip("REMOTE_HOST or TOXIPROXY_URL not set, skipped")
+	}
+
+	md := tu.NewMockDataway(t)
+
+	p, err := tu.ProxyDataway(t, md)
+	require.NoError(t, err)
+
+	const npts = 100
+
+	tu.RunNetworkConditions(t, p, tu.BadNetworks(), func(t *T.T, cond *tu.NetworkCondition) {
+		md.Reset()
+
+		fc, err := diskcache.Open(diskcache.WithPath(t.TempDir()))
+		require.NoError(t, err)
+		defer fc.Close() //nolint:errcheck
+
+		dw := &Dataway{
+			URLs:        []string{p.URL() + "?token=tkn_11111111111111111111"},
+			HTTPTimeout: "1s",
+		}
+		require.NoError(t, dw.Init())
+
+		for i := 0; i < npts/10; i++ {
+			assert.NoError(t, dw.Write(WithCategory(datakit.Logging),
+				WithFailCache(fc),
+				WithPoints(dkpt.RandPoints(10))))
+		}
+
+		sent := len(md.Points("logging"))
+		t.Logf("%d/%d points sent under %s", sent, npts, cond.Name)
+
+		// network recovered, cached points sent on cleaning
+		require.NoError(t, p.ClearToxics())
+
+		require.True(t, md.Await(time.Minute, func(md *tu.MockDataway) bool {
+			if len(md.Points("logging")) >= npts {
+				return true
+			}
+
+			_ = fc.Rotate()
+			_ = dw.Write(WithCategory(datakit.Logging), WithFailCache(fc), WithCacheClean(true))
+			return len(md.Points("logging")) >= npts
+		}), "expect %d points after network recovered, got %d", npts, len(md.Points("logging")))
+	})
+}
@@ -269,6 +269,42 @@ func (dw *Dataway) UploadLog(r io.Reader, hostName string) (*http.Response, erro
 	return ep.sendReq(req)
 }
 
+// UploadRemoteJobResult uploads the result of a remote job, the job is
+// described by headers.
+func (dw *Dataway) UploadRemoteJobResult(headers map[string]string, r io.Reader) error {
+	if len(dw.eps) == 0 {
+		return fmt.Errorf("no dataway available")
+	}
+
+	ep := dw.eps[0]
+	reqURL, ok := ep.categoryURL[datakit.RemoteJobResult]
+	if !ok {
+		return fmt.Errorf("no remote job result URL available")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqURL, r)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := ep.sendReq(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("upload failed with status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 func (dw *Dataway) Pull(args string) ([]byte, error) {
 	if dw.ClientsCount() == 0 {
 		return nil, fmt.Errorf("dataway URL not set")
@@ -15,7 +15,10 @@ var (
 	ptsCounterVec,
 	bytesCounterVec,
 	sinkCounterVec,
-	sinkPtsVec *prometheus.CounterVec
+	sinkPtsVec,
+	shadowPtsVec,
+	pingCounterVec,
+	checksumCounterVec *prometheus.CounterVec
 
 	flushFailCacheVec,
 	apiSumVec *prometheus.SummaryVec
@@ -30,6 +33,9 @@ func Metrics() []prometheus.Collector {
 		apiSumVec,
 		sinkCounterVec,
 		sinkPtsVec,
+		shadowPtsVec,
+		pingCounterVec,
+		checksumCounterVec,
 		flushFailCacheVec,
 	}
 }
@@ -43,6 +49,9 @@ func metricsReset() {
 	sinkCounterVec.Reset()
 	flushFailCacheVec.Reset()
 	sinkPtsVec.Reset()
+	shadowPtsVec.Reset()
+	pingCounterVec.Reset()
+	checksumCounterVec.Reset()
 }
 
 func doRegister() {
@@ -55,6 +64,9 @@ func doRegister() {
 		flushFailCacheVec,
 		sinkCounterVec,
 		sinkPtsVec,
+		shadowPtsVec,
+		pingCounterVec,
+		checksumCounterVec,
 	)
 }
 
@@ -130,5 +142,35 @@ func init() {
 		[]string{"category", "status"},
 	)
 
+	shadowPtsVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "dataway_shadow_point_total",
+			Help:      "dataway points of shadowed categories, partitioned by category, target(primary/shadow), encoding and send status(HTTP status/dropped)",
+		},
+		[]string{"category", "target", "encoding", "status"},
+	)
+
+	pingCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "dataway_ping_total",
+			Help:      "dataway warm-up and keepalive pings, partitioned by dataway host and HTTP status(failed if no response)",
+		},
+		[]string{"host", "status"},
+	)
+
+	checksumCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "dataway_checksum_total",
+			Help:      "dataway body checksums verified on uploads accepted, partitioned by category and result(ok/mismatch/missing)",
+		},
+		[]string{"category", "result"},
+	)
+
 	doRegister()
 }
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+// pingPath is requested by HEAD, any HTTP response means the connection is
+// ready in the pool, so no specific API required on dataway.
+const pingPath = "/"
+
+// touch marks the endpoint active, it's not pinged within the ping interval.
+func (ep *endPoint) touch() {
+	atomic.StoreInt64(&ep.lastActive, time.Now().UnixNano())
+}
+
+// idleFor returns true if no request sent to the endpoint within d.
+func (ep *endPoint) idleFor(d time.Duration) bool {
+	last := atomic.LoadInt64(&ep.lastActive)
+	return time.Since(time.Unix(0, last)) >= d
+}
+
+// ping sets up or keeps a connection to the dataway, so the next upload after
+// idle periods skips DNS, TCP and TLS setup.
+func (ep *endPoint) ping() error {
+	req, err := http.NewRequest(http.MethodHead, fmt.Sprintf("%s://%s%s", ep.scheme, ep.host, pingPath), nil)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	// not retried: the next ping is on the way.
+	resp, err := ep.httpCli.HTTPClient.Do(req)
+	if err != nil {
+		pingCounterVec.WithLabelValues(ep.host, "failed").Inc()
+		return err
+	}
+
+	// drain the body, or the connection not reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	ep.touch()
+	pingCounterVec.WithLabelValues(ep.host, http.StatusText(resp.StatusCode)).Inc()
+	log.Debugf("ping %s: %s, cost %s", ep.host, resp.Status, time.Since(start))
+
+	return nil
+}
+
+// pingEndpoints pings endpoints without requests within idle.
+func (dw *Dataway) pingEndpoints(idle time.Duration) {
+	dw.locker.RLock()
+	defer dw.locker.RUnlock()
+
+	for _, ep := range dw.eps {
+		if !ep.idleFor(idle) {
+			continue
+		}
+
+		if err := ep.ping(); err != nil {
+			log.Warnf("ping dataway %s: %s, ignored", ep.host, err.Error())
+		}
+	}
+}
+
+// startPing warms up connections of endpoints, then pings idle ones on each
+// ping interval before the idle connections closed.
+func (dw *Dataway) startPing() {
+	if !dw.WarmUp && dw.pingInterval <= 0 {
+		return
+	}
+
+	g := datakit.G("dataway")
+	g.Go(func(ctx context.Context) error {
+		if dw.WarmUp {
+			dw.pingEndpoints(0)
+		}
+
+		if dw.pingInterval <= 0 {
+			return nil
+		}
+
+		tick := time.NewTicker(dw.pingInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-datakit.Exit.Wait():
+				log.Info("dataway ping exit")
+				return nil
+
+			case <-tick.C:
+				dw.pingEndpoints(dw.pingInterval)
+			}
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *T.T) {
+	var pings, conns int64
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == pingPath {
+			atomic.AddInt64(&pings, 1)
+		}
+		w.WriteHeader(http.StatusNotFound) // ping not care about the status
+	}))
+	ts.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	dw := &Dataway{
+		URLs:         []string{ts.URL + "?token=tkn_for_testing"},
+		PingInterval: "1m",
+	}
+	require.NoError(t, dw.doInit())
+	require.Equal(t, time.Minute, dw.pingInterval)
+
+	ep := dw.eps[0]
+
+	t.Run("warm-up", func(t *T.T) {
+		assert.True(t, ep.idleFor(time.Minute))
+
+		dw.pingEndpoints(0)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&pings))
+		assert.False(t, ep.idleFor(time.Minute))
+	})
+
+	t.Run("skip-active", func(t *T.T) {
+		dw.pingEndpoints(time.Minute)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&pings))
+	})
+
+	t.Run("keepalive", func(t *T.T) {
+		dw.pingEndpoints(0)
+		dw.pingEndpoints(0)
+		assert.Equal(t, int64(3), atomic.LoadInt64(&pings))
+
+		// all pings on the same connection
+		assert.Equal(t, int64(1), atomic.LoadInt64(&conns))
+	})
+
+	t.Run("invalid-interval", func(t *T.T) {
+		dw := &Dataway{
+			URLs:         []string{ts.URL + "?token=tkn_for_testing"},
+			PingInterval: "30",
+		}
+		assert.Error(t, dw.doInit())
+	})
+}
@@ -22,9 +22,13 @@ func putWriter(w *writer) {
 	w.category = "not-set"
 	w.dynamicURL = ""
 	w.pts = w.pts[:0]
+	w.origins = nil
+	w.precision = ""
 	w.gzip = false
+	w.shadowed = false
 	w.cacheClean = false
 	w.cacheAll = false
+	w.cacheOnly = false
 	w.fc = nil
 	wpool.Put(w)
 }
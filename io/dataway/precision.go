@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GuanceCloud/cliutils/point"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+var (
+	// configured precision -> precision of line protocol, influxdb use u for us
+	precisions = map[string]string{"s": "s", "ms": "ms", "us": "u", "ns": ""}
+
+	// timestamps of these categories can be truncated, for tracing/RUM/profiling
+	// etc., the order of events within 1s or 1ms matters.
+	truncatableCategories = map[point.Category]bool{
+		point.Metric:       true,
+		point.Logging:      true,
+		point.Object:       true,
+		point.CustomObject: true,
+		point.KeyEvent:     true,
+	}
+)
+
+// setupPrecisions checks precision settings of categories, and returns the
+// line protocol precisions keyed by category URL. ns is dropped as it's the default.
+func setupPrecisions(settings map[string]string) (map[string]string, error) {
+	res := map[string]string{}
+	for k, v := range settings {
+		cat := point.CatString(k)
+		if cat == point.UnknownCategory {
+			return nil, fmt.Errorf("invalid precision category %q", k)
+		}
+
+		if !truncatableCategories[cat] {
+			return nil, fmt.Errorf("timestamp of %q should not be truncated", k)
+		}
+
+		prec, ok := precisions[strings.ToLower(strings.TrimSpace(v))]
+		if !ok {
+			return nil, fmt.Errorf("invalid precision %q of %q, only s/ms/us/ns allowed", v, k)
+		}
+
+		if prec != "" {
+			res[cat.URL()] = prec
+		}
+	}
+
+	return res, nil
+}
+
+// lineOf returns the line protocol of pt with timestamp in precision.
+func lineOf(pt *dkpt.Point, precision string) string {
+	if precision == "" {
+		return pt.String()
+	}
+	return pt.PrecisionString(precision)
+}
+
+// withPrecision adds precision query to url if not ns.
+func withPrecision(u, precision string) string {
+	if precision == "" {
+		return u
+	}
+
+	if strings.Contains(u, "?") {
+		return u + "&precision=" + precision
+	}
+	return u + "?precision=" + precision
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/diskcache"
+	lp "github.com/GuanceCloud/cliutils/lineproto"
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestSetupPrecisions(t *T.T) {
+	cases := []struct {
+		name     string
+		settings map[string]string
+		expect   map[string]string
+		fail     bool
+	}{
+		{
+			name:     "ok",
+			settings: map[string]string{"metric": "s", "logging": "MS", "object": "us", "keyevent": "ns"},
+			expect:   map[string]string{datakit.Metric: "s", datakit.Logging: "ms", datakit.Object: "u"},
+		},
+		{
+			name:     "not-truncatable",
+			settings: map[string]string{"tracing": "ms"},
+			fail:     true,
+		},
+		{
+			name:     "unknown-category",
+			settings: map[string]string{"metrics": "s"},
+			fail:     true,
+		},
+		{
+			name:     "invalid-precision",
+			settings: map[string]string{"metric": "m"},
+			fail:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			res, err := setupPrecisions(tc.settings)
+			if tc.fail {
+				assert.Error(t, err)
+				t.Logf("expected error: %s", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, res)
+		})
+	}
+
+	t.Run("url", func(t *T.T) {
+		assert.Equal(t, "http://a.com/v1/write/metric?token=x&precision=s", withPrecision("http://a.com/v1/write/metric?token=x", "s"))
+		assert.Equal(t, "http://a.com/v1/write/metric?precision=ms", withPrecision("http://a.com/v1/write/metric", "ms"))
+		assert.Equal(t, "http://a.com/v1/write/metric", withPrecision("http://a.com/v1/write/metric", ""))
+	})
+}
+
+func TestWriteWithPrecision(t *T.T) {
+	ts := time.Unix(1680000000, 123456789)
+	pts := []*dkpt.Point{
+		dkpt.MustNewPoint("m1", nil, map[string]any{"f1": 1}, &dkpt.PointOption{Time: ts, Category: datakit.Metric}),
+	}
+
+	t.Run("truncated", func(t *T.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "s", r.URL.Query().Get("precision"))
+			assert.Equal(t, "tkn_for_test", r.URL.Query().Get("token"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close() //nolint:errcheck
+			assert.NoError(t, err)
+
+			x, err := uhttp.Unzip(body)
+			assert.NoError(t, err)
+			assert.Equal(t, "m1 f1=1i 1680000000", string(x))
+
+			got, err := lp.ParsePoints(x, &lp.Option{Precision: "s"})
+			assert.NoError(t, err)
+			assert.Equal(t, ts.Truncate(time.Second).UnixNano(), got[0].Time().UnixNano())
+
+			w.WriteHeader(200)
+		}))
+		defer srv.Close()
+
+		dw := &Dataway{
+			URLs:      []string{fmt.Sprintf("%s?token=tkn_for_test", srv.URL)},
+			Precision: map[string]string{"metric": "s"},
+		}
+		assert.NoError(t, dw.Init())
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts)))
+	})
+
+	t.Run("precision-kept-in-cache", func(t *T.T) {
+		var precisions []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			precisions = append(precisions, r.URL.Query().Get("precision"))
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		fc, err := diskcache.Open(diskcache.WithPath(t.TempDir()))
+		require.NoError(t, err)
+		defer fc.Close() //nolint:errcheck
+
+		dw := &Dataway{
+			URLs:      []string{fmt.Sprintf("%s?token=tkn_for_test", srv.URL)},
+			Precision: map[string]string{"logging": "ms"},
+		}
+		assert.NoError(t, dw.Init())
+
+		pts := []*dkpt.Point{
+			dkpt.MustNewPoint("l1", nil, map[string]any{"message": "hello"}, &dkpt.PointOption{Time: ts, Category: datakit.Logging}),
+		}
+		assert.NoError(t, dw.Write(WithCategory(datakit.Logging), WithFailCache(fc), WithPoints(pts)))
+		assert.NoError(t, fc.Rotate())
+
+		// precision of the cached body used even if config changed
+		dw.precisions = nil
+		assert.NoError(t, dw.Write(WithCategory(datakit.Logging), WithFailCache(fc), WithCacheClean(true)))
+
+		// requests retried on both write and clean
+		assert.GreaterOrEqual(t, len(precisions), 2)
+		for _, p := range precisions {
+			assert.Equal(t, "ms", p)
+		}
+
+		metricsReset()
+		diskcache.ResetMetrics()
+	})
+}
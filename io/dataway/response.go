@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxResponseSize is max (decompressed) size of dataway responses,
+// such as log filter rules and pulled pipelines.
+const defaultMaxResponseSize = 32 * 1024 * 1024
+
+var errResponseTooLarge = errors.New("response too large")
+
+func withMaxResponseSize(n int64) endPointOption {
+	return func(ep *endPoint) {
+		if n > 0 {
+			ep.maxResponseSize = n
+		}
+	}
+}
+
+// acceptGzip asks for gzip compressed response. We set the header ourselves
+// instead of the transparent decompression of http.Transport, so the size of
+// the response guarded after decompressed.
+func acceptGzip(req *http.Request) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+}
+
+// guardResponse decompresses gzip response body, and fails reading of the body
+// if its size exceeds maxResponseSize.
+func (ep *endPoint) guardResponse(resp *http.Response) error {
+	max := ep.maxResponseSize
+	if max <= 0 {
+		max = defaultMaxResponseSize
+	}
+
+	gz := strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip")
+
+	if !gz && resp.ContentLength > max {
+		resp.Body.Close() //nolint:errcheck,gosec
+		return fmt.Errorf("%w: %d bytes, exceeds %d bytes", errResponseTooLarge, resp.ContentLength, max)
+	}
+
+	body := resp.Body
+	if gz {
+		body = &gzipBody{rc: resp.Body}
+
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+
+	resp.Body = &limitedBody{rc: body, n: max, max: max}
+	return nil
+}
+
+// gzipBody decompresses the body lazily, empty body(such as 204) is fine if
+// not read.
+type gzipBody struct {
+	rc io.ReadCloser
+	zr *gzip.Reader
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil {
+		zr, err := gzip.NewReader(b.rc)
+		if err != nil {
+			return 0, fmt.Errorf("decompress response: %w", err)
+		}
+		b.zr = zr
+	}
+
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.rc.Close()
+}
+
+type limitedBody struct {
+	rc     io.ReadCloser
+	n, max int64 // n is bytes left
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		// probe 1 byte to check if there is more
+		var x [1]byte
+		n, err := b.rc.Read(x[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: exceeds %d bytes", errResponseTooLarge, b.max)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+
+	n, err := b.rc.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+func TestGuardResponse(t *T.T) {
+	rules := bytes.Repeat([]byte(`{"source":"nginx","filter":"{ status = 'debug' }"}`), 1000)
+
+	gzipped := func(t *T.T, data []byte) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	newServer := func(t *T.T, body []byte) *httptest.Server {
+		t.Helper()
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept-Encoding") == "gzip" {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(gzipped(t, body)) //nolint:errcheck,gosec
+				return
+			}
+			w.Write(body) //nolint:errcheck,gosec
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+
+	newEp := func(t *T.T, ts *httptest.Server, opts ...endPointOption) *endPoint {
+		t.Helper()
+
+		opts = append(opts, withAPIs([]string{datakit.LogFilter, datakit.DatakitPull}))
+		ep, err := newEndpoint(fmt.Sprintf("%s?token=abc", ts.URL), opts...)
+		require.NoError(t, err)
+		return ep
+	}
+
+	t.Run("gzip", func(t *T.T) {
+		ts := newServer(t, rules)
+		ep := newEp(t, ts)
+
+		body, err := ep.getLogFilter()
+		require.NoError(t, err)
+		assert.Equal(t, rules, body)
+
+		body, err = ep.datakitPull("filters=true")
+		require.NoError(t, err)
+		assert.Equal(t, rules, body)
+	})
+
+	t.Run("gzip-too-large", func(t *T.T) {
+		ts := newServer(t, rules)
+		ep := newEp(t, ts, withMaxResponseSize(int64(len(rules)-1)))
+
+		_, err := ep.getLogFilter()
+		assert.True(t, errors.Is(err, errResponseTooLarge), "got %v", err)
+	})
+
+	t.Run("exactly-max", func(t *T.T) {
+		ts := newServer(t, rules)
+		ep := newEp(t, ts, withMaxResponseSize(int64(len(rules))))
+
+		body, err := ep.getLogFilter()
+		require.NoError(t, err)
+		assert.Equal(t, rules, body)
+	})
+
+	t.Run("plain-too-large", func(t *T.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(rules) //nolint:errcheck,gosec
+		}))
+		t.Cleanup(ts.Close)
+
+		ep := newEp(t, ts, withMaxResponseSize(100))
+
+		_, err := ep.datakitPull("filters=true")
+		assert.True(t, errors.Is(err, errResponseTooLarge), "got %v", err)
+	})
+
+	t.Run("accept-encoding-set", func(t *T.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "identity", r.Header.Get("Accept-Encoding"))
+			w.Write(rules) //nolint:errcheck,gosec
+		}))
+		t.Cleanup(ts.Close)
+
+		ep := newEp(t, ts, withHeaders(map[string]map[string]string{
+			ts.Listener.Addr().String(): {"Accept-Encoding": "identity"},
+		}, nil))
+
+		req, err := http.NewRequest(http.MethodGet, ep.categoryURL[datakit.LogFilter], nil)
+		require.NoError(t, err)
+		ep.setHeaders(req, "", "")
+
+		resp, err := ep.sendReq(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	EncodingLineProtocol = "line-protocol"
+	EncodingProtobuf     = "protobuf"
+
+	contentTypeProtobuf = "application/x-protobuf"
+
+	// max points of a protobuf body.
+	shadowPBBatchSize = 1000
+
+	// max concurrent in-flight shadow writes, new writes dropped if exceeded.
+	shadowMaxInflight = 4
+)
+
+// Shadow duplicates part of the points of some categories to another
+// dataway, maybe with another encoding. It's used to de-risk migrations of
+// dataway or protocol: send the same data to the new one and compare the
+// acceptance rates on metric datakit_io_dataway_shadow_point_total, while
+// the primary dataway is not affected by the shadow.
+type Shadow struct {
+	Categories []string `toml:"categories" json:"categories"`
+	URL        string   `toml:"url" json:"url"`
+	Proxy      string   `toml:"proxy" json:"proxy"`
+
+	// Percentage(0~100) of points duplicated to the shadow.
+	Percentage float64 `toml:"percentage" json:"percentage"`
+
+	// Encoding of the shadow body, line-protocol(default) or protobuf.
+	Encoding string `toml:"encoding" json:"encoding"`
+
+	ep       *endPoint
+	cats     []point.Category
+	inflight chan struct{}
+	checksum string // same as dataway
+
+	endpointHeaders,
+	categoryHeaders map[string]map[string]string // same as dataway
+}
+
+func (s *Shadow) String() string {
+	return fmt.Sprintf("[categories: %s][URL: %s][proxy: %s][percentage: %v][encoding: %s]",
+		strings.Join(s.Categories, ","),
+		s.URL, s.Proxy, s.Percentage, s.Encoding,
+	)
+}
+
+func (s *Shadow) Setup() error {
+	switch s.Encoding {
+	case "":
+		s.Encoding = EncodingLineProtocol
+	case EncodingLineProtocol, EncodingProtobuf:
+	default:
+		return fmt.Errorf("invalid shadow encoding %q, only %s/%s allowed",
+			s.Encoding, EncodingLineProtocol, EncodingProtobuf)
+	}
+
+	if s.Percentage < 0 || s.Percentage > 100 {
+		return fmt.Errorf("invalid shadow percentage %v, should be 0~100", s.Percentage)
+	}
+
+	var apis []string
+	for _, x := range sinkerAPIs {
+		apis = append(apis, x.URL())
+	}
+
+	ep, err := newEndpoint(s.URL,
+		withAPIs(apis),
+		withProxy(s.Proxy),
+		withHeaders(s.endpointHeaders, s.categoryHeaders),
+		withChecksum(s.checksum))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("set endpoint %s on shadow %s", ep, s)
+	s.ep = ep
+
+	for _, c := range s.Categories {
+		s.cats = append(s.cats, point.CatAlias(c))
+	}
+
+	s.inflight = make(chan struct{}, shadowMaxInflight)
+
+	return nil
+}
+
+func (s *Shadow) expectedCategory(cat point.Category) bool {
+	if s.ep == nil { // setup failed
+		return false
+	}
+
+	for _, c := range s.cats {
+		if c == cat {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sample selects about Percentage% of pts, pts not modified.
+func (s *Shadow) sample(pts []*dkpt.Point) []*dkpt.Point {
+	var res []*dkpt.Point
+	for _, pt := range pts {
+		if s.Percentage >= 100 || rand.Float64()*100 < s.Percentage { //nolint:gosec
+			res = append(res, pt)
+		}
+	}
+	return res
+}
+
+// shadow sends sampled pts to the shadow in background. The send is
+// dropped if too many sends in-flight, the primary write never blocked.
+func (s *Shadow) shadow(cat point.Category, pts []*dkpt.Point, precision string) {
+	sampled := s.sample(pts)
+	if len(sampled) == 0 {
+		return
+	}
+
+	select {
+	case s.inflight <- struct{}{}:
+	default:
+		shadowPtsVec.WithLabelValues(cat.String(), "shadow", s.Encoding, "dropped").Add(float64(len(sampled)))
+		return
+	}
+
+	go func() {
+		defer func() { <-s.inflight }()
+
+		if err := s.write(cat.URL(), sampled, precision); err != nil {
+			log.Warnf("shadow %d points on %s to %s: %s, ignored", len(sampled), cat, s.URL, err)
+		}
+	}()
+}
+
+func (s *Shadow) write(category string, pts []*dkpt.Point, precision string) error {
+	w := &writer{
+		category:  category,
+		pts:       pts,
+		precision: precision,
+		shadow:    s,
+	}
+
+	if s.Encoding == EncodingLineProtocol {
+		return s.ep.writePoints(w)
+	}
+
+	bodies, err := buildPBBody(pts, shadowPBBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bodies {
+		s.ep.writeBody(w, b)
+	}
+
+	return nil
+}
+
+// buildPBBody convert pts to gzipped protobuf bodies, each with at most batch points.
+func buildPBBody(pts []*dkpt.Point, batch int) ([]*body, error) {
+	var arr []*point.Point
+	for _, pt := range pts {
+		fields, err := pt.Fields()
+		if err != nil {
+			log.Warnf("invalid point %q: %s, ignored", pt.Name(), err)
+			continue
+		}
+
+		arr = append(arr, point.NewPointV2([]byte(pt.Name()),
+			append(point.NewTags(pt.Tags()), point.NewKVs(fields)...),
+			point.WithTime(pt.Time()),
+			point.WithPrecheck(false), // already checked on the primary
+			point.WithEncoding(point.Protobuf)))
+	}
+
+	var bodies []*body
+
+	enc := point.GetEncoder(point.WithEncEncoding(point.Protobuf),
+		point.WithEncBatchSize(batch),
+		point.WithEncFn(func(n int, payload []byte) error {
+			gzbuf, err := datakit.GZip(payload)
+			if err != nil {
+				return err
+			}
+
+			bodies = append(bodies, &body{
+				buf:     gzbuf,
+				rawLen:  len(payload),
+				gzon:    true,
+				npts:    n,
+				payload: payloadProtobuf,
+			})
+			return nil
+		}))
+	defer point.PutEncoder(enc)
+
+	if _, err := enc.Encode(arr); err != nil {
+		return nil, err
+	}
+
+	return bodies, nil
+}
@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package dataway
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/metrics"
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestShadowSetup(t *T.T) {
+	t.Run("default-encoding", func(t *T.T) {
+		s := &Shadow{Categories: []string{"L"}, URL: "https://some.host.com?token=tkn_xxx", Percentage: 10}
+		require.NoError(t, s.Setup())
+		assert.Equal(t, EncodingLineProtocol, s.Encoding)
+		assert.True(t, s.expectedCategory(point.Logging))
+		assert.False(t, s.expectedCategory(point.Metric))
+	})
+
+	t.Run("invalid-encoding", func(t *T.T) {
+		s := &Shadow{Categories: []string{"L"}, URL: "https://some.host.com", Encoding: "json"}
+		assert.Error(t, s.Setup())
+		assert.False(t, s.expectedCategory(point.Logging))
+	})
+
+	t.Run("invalid-percentage", func(t *T.T) {
+		s := &Shadow{Categories: []string{"L"}, URL: "https://some.host.com", Percentage: 120}
+		assert.Error(t, s.Setup())
+	})
+}
+
+func TestShadowSample(t *T.T) {
+	pts := dkpt.RandPoints(1000)
+
+	assert.Len(t, (&Shadow{Percentage: 100}).sample(pts), 1000)
+	assert.Len(t, (&Shadow{Percentage: 0}).sample(pts), 0)
+
+	n := len((&Shadow{Percentage: 50}).sample(pts))
+	assert.True(t, n > 300 && n < 700, "sampled %d", n)
+}
+
+func TestShadowWrite(t *T.T) {
+	pts := []*dkpt.Point{
+		dkpt.MustNewPoint("test", map[string]string{"t1": "v1"}, map[string]any{"f1": 1},
+			&dkpt.PointOption{Category: datakit.Logging, Time: time.Unix(0, 123)}),
+		dkpt.MustNewPoint("test", map[string]string{"t1": "v2"}, map[string]any{"f1": 2},
+			&dkpt.PointOption{Category: datakit.Logging, Time: time.Unix(0, 123)}),
+	}
+
+	t.Run("protobuf", func(t *T.T) {
+		var primary, shadow int64
+
+		primaryTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&primary, 1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer primaryTS.Close()
+
+		shadowed := make(chan []*point.Point, 1)
+		shadowTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close() //nolint:errcheck
+			atomic.AddInt64(&shadow, 1)
+
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			x, err := uhttp.Unzip(body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, contentTypeProtobuf, r.Header.Get("Content-Type"))
+
+			dec := point.GetDecoder(point.WithDecEncoding(point.Protobuf))
+			defer point.PutDecoder(dec)
+
+			decoded, err := dec.Decode(x)
+			assert.NoError(t, err)
+
+			shadowed <- decoded
+
+			w.WriteHeader(http.StatusBadRequest) // shadow rejected
+		}))
+		defer shadowTS.Close()
+
+		dw := &Dataway{
+			URLs: []string{fmt.Sprintf("%s?token=tkn_11111111111111111111", primaryTS.URL)},
+			Shadows: []*Shadow{
+				{
+					Categories: []string{"L"},
+					URL:        fmt.Sprintf("%s?token=tkn_22222222222222222222", shadowTS.URL),
+					Percentage: 100,
+					Encoding:   EncodingProtobuf,
+				},
+			},
+		}
+		require.NoError(t, dw.Init())
+
+		require.NoError(t, dw.Write(WithCategory(datakit.Logging), WithPoints(pts)))
+		require.NoError(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts))) // not shadowed
+
+		select {
+		case decoded := <-shadowed:
+			require.Len(t, decoded, 2)
+			assert.Equal(t, "test", string(decoded[0].Name()))
+			assert.Equal(t, int64(123), decoded[0].Time().UnixNano())
+		case <-time.After(10 * time.Second):
+			t.Fatal("shadow not received")
+		}
+
+		// wait the shadow metric updated
+		for i := 0; i < 100 && len(dw.Shadows[0].inflight) > 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.Equal(t, int64(2), atomic.LoadInt64(&primary))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&shadow))
+
+		mfs := metrics.MustGather()
+
+		// labels sorted by name: category, encoding, status, target
+		m := metrics.GetMetricOnLabels(mfs, "datakit_io_dataway_shadow_point_total",
+			point.Logging.String(), EncodingLineProtocol, http.StatusText(http.StatusOK), "primary")
+		require.NotNil(t, m)
+		assert.Equal(t, float64(2), m.GetCounter().GetValue())
+
+		m = metrics.GetMetricOnLabels(mfs, "datakit_io_dataway_shadow_point_total",
+			point.Logging.String(), EncodingProtobuf, http.StatusText(http.StatusBadRequest), "shadow")
+		require.NotNil(t, m)
+		assert.Equal(t, float64(2), m.GetCounter().GetValue())
+
+		// shadow points not counted on primary metrics
+		m = metrics.GetMetricOnLabels(mfs, "datakit_io_dataway_point_total",
+			point.Logging.String(), http.StatusText(http.StatusBadRequest))
+		assert.Nil(t, m)
+
+		t.Cleanup(func() {
+			metricsReset()
+		})
+	})
+
+	t.Run("line-protocol", func(t *T.T) {
+		received := make(chan []byte, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close() //nolint:errcheck
+
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			x, err := uhttp.Unzip(body)
+			assert.NoError(t, err)
+
+			received <- x
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		s := &Shadow{Categories: []string{"L"}, URL: ts.URL, Percentage: 100}
+		require.NoError(t, s.Setup())
+
+		s.shadow(point.Logging, pts, "")
+
+		select {
+		case x := <-received:
+			assert.Equal(t, "test,t1=v1 f1=1i 123\ntest,t1=v2 f1=2i 123", string(x))
+		case <-time.After(10 * time.Second):
+			t.Fatal("shadow not received")
+		}
+
+		t.Cleanup(func() {
+			metricsReset()
+		})
+	})
+}
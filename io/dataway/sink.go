@@ -25,6 +25,11 @@ type Sinker struct {
 	conditions parser.WhereConditions
 	ep         *endPoint
 	cats       []point.Category
+	precisions map[string]string // same as dataway
+	checksum   string            // same as dataway
+
+	endpointHeaders,
+	categoryHeaders map[string]map[string]string // same as dataway
 }
 
 var sinkerAPIs = []point.Category{
@@ -118,9 +123,10 @@ __write:
 func (s *Sinker) write(category string, pts []*dkpt.Point) error {
 	return s.ep.writePoints(
 		&writer{
-			isSinker: true,
-			category: category,
-			pts:      pts,
+			isSinker:  true,
+			category:  category,
+			pts:       pts,
+			precision: s.precisions[category],
 		})
 }
 
@@ -134,7 +140,11 @@ func (s *Sinker) Setup() error {
 		apis = append(apis, x.URL())
 	}
 
-	ep, err := newEndpoint(s.URL, withAPIs(apis), withProxy(s.Proxy)) // no proxy allowed
+	ep, err := newEndpoint(s.URL,
+		withAPIs(apis),
+		withProxy(s.Proxy), // no proxy allowed
+		withHeaders(s.endpointHeaders, s.categoryHeaders),
+		withChecksum(s.checksum))
 	if err != nil {
 		return err
 	}
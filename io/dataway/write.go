@@ -7,6 +7,7 @@ package dataway
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/GuanceCloud/cliutils/diskcache"
 	"github.com/GuanceCloud/cliutils/point"
@@ -34,6 +35,14 @@ func WithPoints(pts []*dkpt.Point) WriteOption {
 	}
 }
 
+// WithOrigins set input names producing each of the points, the origins
+// are index-aligned with points.
+func WithOrigins(origins []string) WriteOption {
+	return func(w *writer) {
+		w.origins = origins
+	}
+}
+
 func WithDynamicURL(urlStr string) WriteOption {
 	return func(w *writer) {
 		w.dynamicURL = urlStr
@@ -58,6 +67,14 @@ func WithCacheClean(on bool) WriteOption {
 	}
 }
 
+// WithCacheOnly write points to the fail cache directly without sending, they
+// are sent on cleaning the cache. Used to spill points queued in memory.
+func WithCacheOnly(on bool) WriteOption {
+	return func(w *writer) {
+		w.cacheOnly = on
+	}
+}
+
 func WithGzip(on bool) WriteOption {
 	return func(w *writer) {
 		w.gzip = on
@@ -69,9 +86,14 @@ type writer struct {
 	dynamicURL string
 
 	pts                  []*dkpt.Point
+	origins              []string // input name of each point
+	precision            string
 	gzip                 bool
 	isSinker             bool
+	shadow               *Shadow // not nil if write to the shadow
+	shadowed             bool    // points also shadowed to somewhere
 	cacheClean, cacheAll bool
+	cacheOnly            bool
 
 	fc failcache.Cache
 }
@@ -101,7 +123,7 @@ func (dw *Dataway) cleanCache(w *writer, data []byte) error {
 		// If some of endpoint send ok, any failed write will cause re-write on these ok ones.
 		// So, do NOT configure multiple endpoint in dataway URL list.
 		if err := ep.writePointData(
-			&body{buf: pd.Payload}, w); err != nil {
+			&body{buf: pd.Payload, precision: pd.Precision}, w); err != nil {
 			log.Warnf("cleanCache: %s", err)
 			return err
 		}
@@ -145,11 +167,18 @@ func (dw *Dataway) Write(opts ...WriteOption) error {
 		return nil
 	}
 
+	if w.cacheOnly {
+		return dw.cacheOnly(w)
+	}
+
 	// Points in cache do not send to sinkers.
 	// sink points to multiple sinkers, after sinker, not-sinked points
 	// are passed to default dataway.
 	if len(dw.Sinkers) > 0 {
-		var remainPts []*dkpt.Point
+		var (
+			remainPts     []*dkpt.Point
+			remainOrigins []string
+		)
 		for _, sinker := range dw.Sinkers {
 			log.Debugf("try sink %q to %s...", w.category, sinker)
 
@@ -160,6 +189,9 @@ func (dw *Dataway) Write(opts ...WriteOption) error {
 
 			for _, i := range arr {
 				remainPts = append(remainPts, w.pts[i])
+				if len(w.origins) == len(w.pts) {
+					remainOrigins = append(remainOrigins, w.origins[i])
+				}
 			}
 		}
 
@@ -169,6 +201,18 @@ func (dw *Dataway) Write(opts ...WriteOption) error {
 
 		// sending remaining points
 		w.pts = remainPts
+		w.origins = remainOrigins
+	}
+
+	w.precision = dw.precisions[w.category]
+
+	// duplicate points to shadows after sinkers, the shadows see the
+	// same points as the primary dataway.
+	for _, s := range dw.Shadows {
+		if s.expectedCategory(point.CatURL(w.category)) {
+			s.shadow(point.CatURL(w.category), w.pts, w.precision)
+			w.shadowed = true
+		}
 	}
 
 	// write points to multiple endpoints
@@ -180,3 +224,24 @@ func (dw *Dataway) Write(opts ...WriteOption) error {
 
 	return nil
 }
+
+// cacheOnly puts points to the fail cache. Points in cache are sent to the
+// default dataway on cleaning, not to sinkers and shadows.
+func (dw *Dataway) cacheOnly(w *writer) error {
+	if w.fc == nil {
+		return fmt.Errorf("no fail cache on %q", w.category)
+	}
+
+	bodies, err := buildBody(w.pts, MaxKodoBody, dw.precisions[w.category])
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bodies {
+		if err := doCache(w, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
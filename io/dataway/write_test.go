@@ -161,6 +161,44 @@ func TestWriteWithCache(t *T.T) {
 			diskcache.ResetMetrics()
 		})
 	})
+
+	t.Run(`write-cache-only`, func(t *T.T) {
+		var requests int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}))
+		defer ts.Close()
+
+		fc, err := diskcache.Open(diskcache.WithPath(t.TempDir()))
+		assert.NoError(t, err)
+
+		dw := &Dataway{URLs: []string{fmt.Sprintf("%s?token=tkn_11111111111111111111", ts.URL)}}
+		assert.NoError(t, dw.Init())
+
+		pts := dkpt.RandPoints(100)
+
+		// no fail cache
+		assert.Error(t, dw.Write(WithCategory(datakit.Metric), WithPoints(pts), WithCacheOnly(true)))
+
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric),
+			WithFailCache(fc),
+			WithPoints(pts),
+			WithCacheOnly(true)))
+		assert.Equal(t, 0, requests)
+
+		// sent on cleaning
+		assert.NoError(t, fc.Rotate())
+		assert.NoError(t, dw.Write(WithCategory(datakit.Metric),
+			WithFailCache(fc),
+			WithCacheClean(true)))
+		assert.Equal(t, 1, requests)
+
+		t.Cleanup(func() {
+			assert.NoError(t, fc.Close())
+			metricsReset()
+			diskcache.ResetMetrics()
+		})
+	})
 }
 
 func TestWritePoints(t *T.T) {
@@ -0,0 +1,338 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sync"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	aggrMean  = "mean"
+	aggrMax   = "max"
+	aggrMin   = "min"
+	aggrSum   = "sum"
+	aggrLast  = "last"
+	aggrCount = "count"
+
+	downsampleFrom = "downsampling"
+
+	defaultDownsampleMaxSeries = 100000
+)
+
+var downsampleAggrs = map[string]bool{
+	aggrMean: true, aggrMax: true, aggrMin: true, aggrSum: true, aggrLast: true, aggrCount: true,
+}
+
+// DownsampleRule aggregates metric points of the measurement(glob allowed)
+// with all the tags matched into one point per series every interval.
+type DownsampleRule struct {
+	Measurement string            `toml:"measurement"`
+	Tags        map[string]string `toml:"tags"`
+	Interval    datakit.Duration  `toml:"interval"`
+
+	// Aggregations of each numeric field, default mean. With more than one
+	// aggregation, fields are renamed to <field>_<aggregation>.
+	Aggregations []string `toml:"aggregations"`
+}
+
+func (r *DownsampleRule) check() error {
+	if r.Measurement == "" {
+		return fmt.Errorf("measurement required")
+	}
+
+	if _, err := path.Match(r.Measurement, ""); err != nil {
+		return fmt.Errorf("invalid measurement %q: %w", r.Measurement, err)
+	}
+
+	if r.Interval.Duration < time.Second {
+		return fmt.Errorf("interval %s of %q should be at least 1s", r.Interval.Duration, r.Measurement)
+	}
+
+	if len(r.Aggregations) == 0 {
+		r.Aggregations = []string{aggrMean}
+	}
+
+	for _, a := range r.Aggregations {
+		if !downsampleAggrs[a] {
+			return fmt.Errorf("invalid aggregation %q of %q, only mean/max/min/sum/last/count allowed", a, r.Measurement)
+		}
+	}
+
+	return nil
+}
+
+func (r *DownsampleRule) match(name string, tags map[string]string) bool {
+	if ok, _ := path.Match(r.Measurement, name); !ok {
+		return false
+	}
+
+	for k, v := range r.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+type fieldAggr struct {
+	sum, min, max, last float64
+	count               int64
+}
+
+func (fa *fieldAggr) add(v float64) {
+	if fa.count == 0 || v < fa.min {
+		fa.min = v
+	}
+	if fa.count == 0 || v > fa.max {
+		fa.max = v
+	}
+
+	fa.sum += v
+	fa.last = v
+	fa.count++
+}
+
+func (fa *fieldAggr) value(aggr string) interface{} {
+	switch aggr {
+	case aggrMax:
+		return fa.max
+	case aggrMin:
+		return fa.min
+	case aggrSum:
+		return fa.sum
+	case aggrLast:
+		return fa.last
+	case aggrCount:
+		return fa.count
+	default:
+		return fa.sum / float64(fa.count)
+	}
+}
+
+// downsampleWindow holds aggregations of a series within an interval.
+type downsampleWindow struct {
+	rule        *DownsampleRule
+	measurement string
+	tags        map[string]string
+	start       time.Time
+	fields      map[string]*fieldAggr
+}
+
+func (w *downsampleWindow) point() (*dkpt.Point, error) {
+	fields := make(map[string]interface{}, len(w.fields)*len(w.rule.Aggregations))
+	for k, fa := range w.fields {
+		for _, a := range w.rule.Aggregations {
+			if len(w.rule.Aggregations) == 1 {
+				fields[k] = fa.value(a)
+			} else {
+				fields[k+"_"+a] = fa.value(a)
+			}
+		}
+	}
+
+	return dkpt.NewPoint(w.measurement, w.tags, fields, &dkpt.PointOption{
+		Time:              w.start,
+		Category:          datakit.Metric,
+		DisableGlobalTags: true, // tags of the points aggregated already added
+	})
+}
+
+// downsampler aggregates metric points matched by rules, the aggregated
+// points are fed into IO once their window closed.
+type downsampler struct {
+	sync.Mutex
+	rules     []*DownsampleRule
+	maxSeries int
+	windows   map[string]*downsampleWindow
+}
+
+func newDownsampler(rules []*DownsampleRule) *downsampler {
+	ds := &downsampler{
+		maxSeries: defaultDownsampleMaxSeries,
+		windows:   map[string]*downsampleWindow{},
+	}
+
+	for _, r := range rules {
+		if r == nil {
+			continue
+		}
+
+		if err := r.check(); err != nil {
+			log.Warnf("downsampling rule: %s, ignored", err)
+			continue
+		}
+
+		ds.rules = append(ds.rules, r)
+	}
+
+	if len(ds.rules) == 0 {
+		return nil
+	}
+
+	return ds
+}
+
+func (ds *downsampler) matchRule(name string, tags map[string]string) *DownsampleRule {
+	for _, r := range ds.rules {
+		if r.match(name, tags) {
+			return r
+		}
+	}
+	return nil
+}
+
+// add aggregates points matched by any rule, and returns points not
+// matched along with points of windows closed by the added ones.
+func (ds *downsampler) add(pts []*dkpt.Point) (rest []*dkpt.Point) {
+	ds.Lock()
+	defer ds.Unlock()
+
+	for _, pt := range pts {
+		tags := pt.Tags()
+		r := ds.matchRule(pt.Name(), tags)
+		if r == nil {
+			rest = append(rest, pt)
+			continue
+		}
+
+		fields, err := pt.Fields()
+		if err != nil {
+			rest = append(rest, pt)
+			continue
+		}
+
+		start := pt.Time().Truncate(r.Interval.Duration)
+		key := seriesKey(pt.Name(), tags)
+
+		w, ok := ds.windows[key]
+		if ok && !w.start.Equal(start) {
+			// window of another interval, close it
+			if x := ds.close(key, w); x != nil {
+				rest = append(rest, x)
+			}
+			ok = false
+		}
+
+		if !ok {
+			if len(ds.windows) >= ds.maxSeries {
+				log.Warnf("downsampling reach max series %d, point %s not downsampled", ds.maxSeries, pt.Name())
+				rest = append(rest, pt)
+				continue
+			}
+
+			w = &downsampleWindow{
+				rule:        r,
+				measurement: pt.Name(),
+				tags:        tags,
+				start:       start,
+				fields:      map[string]*fieldAggr{},
+			}
+			ds.windows[key] = w
+		}
+
+		for k, v := range fields {
+			f, ok := toFloat(v)
+			if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+				continue
+			}
+
+			fa, ok := w.fields[k]
+			if !ok {
+				fa = &fieldAggr{}
+				w.fields[k] = fa
+			}
+			fa.add(f)
+		}
+
+		downsampledPtsVec.WithLabelValues(pt.Name()).Inc()
+	}
+
+	return rest
+}
+
+// expire returns points of windows ended before now.
+func (ds *downsampler) expire(now time.Time) (pts []*dkpt.Point) {
+	ds.Lock()
+	defer ds.Unlock()
+
+	for k, w := range ds.windows {
+		if now.Before(w.start.Add(w.rule.Interval.Duration)) {
+			continue
+		}
+
+		if x := ds.close(k, w); x != nil {
+			pts = append(pts, x)
+		}
+	}
+
+	return pts
+}
+
+// close removes the window and returns its point, should be called with lock held.
+func (ds *downsampler) close(key string, w *downsampleWindow) *dkpt.Point {
+	delete(ds.windows, key)
+
+	if len(w.fields) == 0 {
+		return nil
+	}
+
+	pt, err := w.point()
+	if err != nil {
+		log.Warnf("downsampling point %s: %s, ignored", w.measurement, err)
+		return nil
+	}
+
+	return pt
+}
+
+func (x *dkIO) runDownsampler() {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+		case now := <-tick.C:
+			if pts := x.downsampler.expire(now); len(pts) > 0 {
+				x.feedDownsampled(pts)
+			}
+
+		case <-datakit.Exit.Wait():
+			log.Info("downsampler exit")
+			return
+		}
+	}
+}
+
+func (x *dkIO) feedDownsampled(pts []*dkpt.Point) {
+	if err := unblockingFeed(&iodata{
+		category: datakit.Metric,
+		from:     downsampleFrom,
+		pts:      pts,
+	}, x.chans[datakit.Metric]); err != nil {
+		log.Warnf("feed %d downsampled points: %s", len(pts), err)
+	}
+}
+
+func (x *dkIO) startDownsampler() {
+	if x.downsampler == nil {
+		return
+	}
+
+	log.Infof("start downsampling on %d rules", len(x.downsampler.rules))
+	g.Go(func(_ context.Context) error {
+		x.runDownsampler()
+		return nil
+	})
+}
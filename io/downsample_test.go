@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestDownsampler(t *T.T) {
+	ts := time.Unix(1680000000, 0) // aligned to 30s
+
+	newPoint := func(t *T.T, name string, tags map[string]string, fields map[string]any, offset time.Duration) *dkpt.Point {
+		t.Helper()
+
+		pt, err := dkpt.NewPoint(name, tags, fields,
+			&dkpt.PointOption{Time: ts.Add(offset), Category: datakit.Metric, DisableGlobalTags: true})
+		require.NoError(t, err)
+		return pt
+	}
+
+	fieldsOf := func(t *T.T, pt *dkpt.Point) map[string]any {
+		t.Helper()
+
+		fields, err := pt.Fields()
+		require.NoError(t, err)
+		return fields
+	}
+
+	t.Run("check", func(t *T.T) {
+		assert.Nil(t, newDownsampler(nil))
+		assert.Nil(t, newDownsampler([]*DownsampleRule{
+			{Measurement: "statsd", Interval: datakit.Duration{Duration: time.Millisecond}},
+			{Measurement: "statsd", Interval: datakit.Duration{Duration: time.Minute}, Aggregations: []string{"p99"}},
+			{Measurement: "[", Interval: datakit.Duration{Duration: time.Minute}},
+		}))
+
+		ds := newDownsampler([]*DownsampleRule{{Measurement: "statsd", Interval: datakit.Duration{Duration: time.Minute}}})
+		require.NotNil(t, ds)
+		assert.Equal(t, []string{aggrMean}, ds.rules[0].Aggregations)
+	})
+
+	t.Run("mean", func(t *T.T) {
+		ds := newDownsampler([]*DownsampleRule{
+			{Measurement: "statsd_*", Interval: datakit.Duration{Duration: 30 * time.Second}},
+		})
+
+		rest := ds.add([]*dkpt.Point{
+			newPoint(t, "statsd_req", map[string]string{"host": "h1"}, map[string]any{"latency": 1.0, "count": int64(2)}, 0),
+			newPoint(t, "statsd_req", map[string]string{"host": "h1"}, map[string]any{"latency": 3.0, "count": int64(4)}, time.Second),
+			newPoint(t, "cpu", map[string]string{"host": "h1"}, map[string]any{"usage": 1.0}, 0),
+		})
+		require.Len(t, rest, 1)
+		assert.Equal(t, "cpu", rest[0].Name())
+
+		assert.Empty(t, ds.expire(ts.Add(29*time.Second)))
+
+		pts := ds.expire(ts.Add(30 * time.Second))
+		require.Len(t, pts, 1)
+		assert.Equal(t, "statsd_req", pts[0].Name())
+		assert.Equal(t, ts, pts[0].Time())
+		assert.Equal(t, map[string]string{"host": "h1"}, pts[0].Tags())
+		assert.Equal(t, map[string]any{"latency": 2.0, "count": 3.0}, fieldsOf(t, pts[0]))
+
+		assert.Empty(t, ds.windows)
+	})
+
+	t.Run("multiple-aggregations-and-tags", func(t *T.T) {
+		ds := newDownsampler([]*DownsampleRule{
+			{
+				Measurement:  "statsd",
+				Tags:         map[string]string{"env": "test"},
+				Interval:     datakit.Duration{Duration: 30 * time.Second},
+				Aggregations: []string{aggrMax, aggrCount},
+			},
+		})
+
+		rest := ds.add([]*dkpt.Point{
+			newPoint(t, "statsd", map[string]string{"env": "test"}, map[string]any{"v": 1.0}, 0),
+			newPoint(t, "statsd", map[string]string{"env": "test"}, map[string]any{"v": 5.0}, time.Second),
+			newPoint(t, "statsd", map[string]string{"env": "prod"}, map[string]any{"v": 1.0}, 0),
+
+			// next window closes the previous one
+			newPoint(t, "statsd", map[string]string{"env": "test"}, map[string]any{"v": 2.0}, 31*time.Second),
+		})
+		require.Len(t, rest, 2)
+		assert.Equal(t, map[string]string{"env": "prod"}, rest[0].Tags())
+		assert.Equal(t, ts, rest[1].Time())
+		assert.Equal(t, map[string]any{"v_max": 5.0, "v_count": int64(2)}, fieldsOf(t, rest[1]))
+
+		pts := ds.expire(ts.Add(time.Minute))
+		require.Len(t, pts, 1)
+		assert.Equal(t, ts.Add(30*time.Second), pts[0].Time())
+		assert.Equal(t, map[string]any{"v_max": 2.0, "v_count": int64(1)}, fieldsOf(t, pts[0]))
+	})
+}
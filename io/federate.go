@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	defaultFederateTTL       = 5 * time.Minute
+	defaultFederateMaxSeries = 100000
+)
+
+// ErrFederateDisabled returned if local federate not enabled.
+var ErrFederateDisabled = errors.New("local federate disabled")
+
+type federateSeries struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]float64
+	ts          time.Time
+	updated     time.Time
+}
+
+// localFederate keeps the most recent values of metric points, exposed in
+// Prometheus text format.
+type localFederate struct {
+	sync.Mutex
+	ttl       time.Duration
+	maxSeries int
+	series    map[string]*federateSeries
+}
+
+func newLocalFederate(ttl time.Duration) *localFederate {
+	if ttl <= 0 {
+		ttl = defaultFederateTTL
+	}
+
+	return &localFederate{
+		ttl:       ttl,
+		maxSeries: defaultFederateMaxSeries,
+		series:    map[string]*federateSeries{},
+	}
+}
+
+func seriesKey(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(measurement)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(tags[k])
+	}
+
+	return sb.String()
+}
+
+// update replaces field values of series with the points, non-numeric fields ignored.
+func (lf *localFederate) update(pts []*dkpt.Point) {
+	now := time.Now()
+
+	lf.Lock()
+	defer lf.Unlock()
+
+	for _, pt := range pts {
+		fields, err := pt.Fields()
+		if err != nil {
+			continue
+		}
+
+		tags := pt.Tags()
+		key := seriesKey(pt.Name(), tags)
+
+		s, ok := lf.series[key]
+		if !ok {
+			if len(lf.series) >= lf.maxSeries {
+				lf.expire(now)
+				if len(lf.series) >= lf.maxSeries {
+					log.Warnf("local federate reach max series %d, point %s dropped", lf.maxSeries, pt.Name())
+					continue
+				}
+			}
+
+			s = &federateSeries{measurement: pt.Name(), tags: tags, fields: map[string]float64{}}
+			lf.series[key] = s
+		}
+
+		for k, v := range fields {
+			if f, ok := toFloat(v); ok {
+				s.fields[k] = f
+			}
+		}
+
+		s.ts = pt.Time()
+		s.updated = now
+	}
+}
+
+// expire removes series not updated within ttl, should be called with lock held.
+func (lf *localFederate) expire(now time.Time) {
+	for k, s := range lf.series {
+		if now.Sub(s.updated) > lf.ttl {
+			delete(lf.series, k)
+		}
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+type promSample struct {
+	labels string
+	value  float64
+	ts     int64
+}
+
+// write exposes the series of measurements(all if empty) in Prometheus text format.
+func (lf *localFederate) write(w io.Writer, measurements []string) error {
+	filter := map[string]bool{}
+	for _, m := range measurements {
+		filter[m] = true
+	}
+
+	metrics := map[string][]*promSample{}
+
+	lf.Lock()
+	lf.expire(time.Now())
+	for _, s := range lf.series {
+		if len(filter) > 0 && !filter[s.measurement] {
+			continue
+		}
+
+		labels := promLabels(s.tags)
+		for f, v := range s.fields {
+			name := promName(s.measurement + "_" + f)
+			metrics[name] = append(metrics[name], &promSample{labels: labels, value: v, ts: s.ts.UnixNano() / int64(time.Millisecond)})
+		}
+	}
+	lf.Unlock()
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		samples := metrics[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+
+		fmt.Fprintf(bw, "# TYPE %s untyped\n", name)
+		for _, s := range samples {
+			fmt.Fprintf(bw, "%s%s %s %d\n", name, s.labels, promValue(s.value), s.ts)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func promLabels(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(promLabelName(k))
+		sb.WriteString(`="`)
+		sb.WriteString(promEscaper.Replace(tags[k]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func promValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// promName replaces characters not allowed in Prometheus metric name with '_'.
+func promName(s string) string {
+	return promSanitize(s, true)
+}
+
+func promLabelName(s string) string {
+	return promSanitize(s, false)
+}
+
+func promSanitize(s string, colon bool) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		case c == ':' && colon:
+		default:
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+// WriteLocalFederate writes the most recent metric points of measurements(all
+// if empty) in Prometheus text format.
+func WriteLocalFederate(w io.Writer, measurements []string) error {
+	if defIO.federate == nil {
+		return ErrFederateDisabled
+	}
+
+	return defIO.federate.write(w, measurements)
+}
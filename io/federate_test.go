@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"bytes"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestLocalFederate(t *T.T) {
+	ts := time.Unix(1680000000, 0)
+
+	newPoint := func(t *T.T, name string, tags map[string]string, fields map[string]any) *dkpt.Point {
+		t.Helper()
+
+		pt, err := dkpt.NewPoint(name, tags, fields, &dkpt.PointOption{Time: ts, Category: datakit.Metric, DisableGlobalTags: true})
+		assert.NoError(t, err)
+		return pt
+	}
+
+	t.Run("basic", func(t *T.T) {
+		lf := newLocalFederate(0)
+		lf.update([]*dkpt.Point{
+			newPoint(t, "cpu", map[string]string{"host": "h1", "cpu": "cpu-total"}, map[string]any{"usage_user": 1.5, "core": int64(4), "model": "intel"}),
+			newPoint(t, "mem", map[string]string{"host": "h1"}, map[string]any{"used_percent": 30.0}),
+		})
+
+		// the latest value wins
+		lf.update([]*dkpt.Point{
+			newPoint(t, "cpu", map[string]string{"host": "h1", "cpu": "cpu-total"}, map[string]any{"usage_user": 2.5}),
+		})
+
+		var buf bytes.Buffer
+		assert.NoError(t, lf.write(&buf, nil))
+		assert.Equal(t, `# TYPE cpu_core untyped
+cpu_core{cpu="cpu-total",host="h1"} 4 1680000000000
+# TYPE cpu_usage_user untyped
+cpu_usage_user{cpu="cpu-total",host="h1"} 2.5 1680000000000
+# TYPE mem_used_percent untyped
+mem_used_percent{host="h1"} 30 1680000000000
+`, buf.String())
+
+		buf.Reset()
+		assert.NoError(t, lf.write(&buf, []string{"mem"}))
+		assert.Equal(t, `# TYPE mem_used_percent untyped
+mem_used_percent{host="h1"} 30 1680000000000
+`, buf.String())
+	})
+
+	t.Run("sanitize", func(t *T.T) {
+		lf := newLocalFederate(0)
+		lf.update([]*dkpt.Point{
+			newPoint(t, "nginx-vts", map[string]string{"server.zone": `a"b\c`}, map[string]any{"1xx": int64(1), "up": true}),
+		})
+
+		var buf bytes.Buffer
+		assert.NoError(t, lf.write(&buf, nil))
+		assert.Equal(t, `# TYPE nginx_vts_1xx untyped
+nginx_vts_1xx{server_zone="a\"b\\c"} 1 1680000000000
+# TYPE nginx_vts_up untyped
+nginx_vts_up{server_zone="a\"b\\c"} 1 1680000000000
+`, buf.String())
+	})
+
+	t.Run("expire-and-max-series", func(t *T.T) {
+		lf := newLocalFederate(time.Minute)
+		lf.maxSeries = 1
+
+		lf.update([]*dkpt.Point{newPoint(t, "cpu", map[string]string{"host": "h1"}, map[string]any{"usage": 1.0})})
+		lf.update([]*dkpt.Point{newPoint(t, "cpu", map[string]string{"host": "h2"}, map[string]any{"usage": 1.0})})
+		assert.Len(t, lf.series, 1, "series over max dropped")
+
+		for _, s := range lf.series {
+			s.updated = time.Now().Add(-2 * time.Minute)
+		}
+
+		lf.update([]*dkpt.Point{newPoint(t, "cpu", map[string]string{"host": "h2"}, map[string]any{"usage": 1.0})})
+		assert.Len(t, lf.series, 1)
+		_, ok := lf.series[seriesKey("cpu", map[string]string{"host": "h2"})]
+		assert.True(t, ok, "expired series replaced")
+	})
+}
@@ -46,6 +46,11 @@ type Option struct {
 
 	Blocking bool
 
+	// NonBlocking returns ErrIOBusy if the feed queue is full, even for
+	// categories fed blocking by default(such as logging), so HTTP inputs
+	// can tell the client to back off.
+	NonBlocking bool
+
 	PlScript map[string]string // <measurement>: <script name>
 	PlOption *plscript.Option
 }
@@ -145,7 +150,7 @@ func beforeFeed(category string, pts []*dkpt.Point, opt *Option) ([]*dkpt.Point,
 		datakit.Profiling:
 		if opt == nil {
 			opt = &Option{Blocking: true}
-		} else {
+		} else if !opt.NonBlocking {
 			opt.Blocking = true
 		}
 	case datakit.Metric, datakit.MetricDeprecated:
@@ -186,6 +191,18 @@ func (x *dkIO) doFeed(pts []*dkpt.Point, category, from string, opt *Option) err
 
 	filtered := len(pts) - len(after)
 
+	if x.preview != nil {
+		x.preview.add(from, category, after)
+	}
+
+	if x.federate != nil && category == datakit.Metric {
+		x.federate.update(after)
+	}
+
+	if x.downsampler != nil && category == datakit.Metric && (opt == nil || opt.HTTPHost == "") {
+		after = x.downsampler.add(after)
+	}
+
 	ch := x.chans[category]
 	if opt != nil && opt.HTTPHost != "" {
 		ch = x.chans[datakit.DynamicDatawayCategory]
@@ -10,6 +10,7 @@ import (
 
 	"github.com/GuanceCloud/cliutils/point"
 	"github.com/stretchr/testify/assert"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 )
 
 func TestPointConvert(t *T.T) {
@@ -20,3 +21,17 @@ func TestPointConvert(t *T.T) {
 		assert.Equal(t, 1, len(pts))
 	})
 }
+
+func TestNonBlockingFeed(t *T.T) {
+	x := &dkIO{chans: map[string]chan *iodata{
+		datakit.Logging: make(chan *iodata, 1),
+	}}
+
+	pts := point2dkpt(point.NewPointV2([]byte(`abc`), point.NewKVs(map[string]any{"message": "hello"})))
+
+	assert.NoError(t, x.doFeed(pts, datakit.Logging, "test", &Option{NonBlocking: true}))
+
+	// queue full
+	assert.ErrorIs(t, x.doFeed(pts, datakit.Logging, "test", &Option{NonBlocking: true}), ErrIOBusy)
+	assert.Len(t, x.chans[datakit.Logging], 1)
+}
@@ -47,15 +47,30 @@ type dkIO struct {
 	flushInterval time.Duration
 	flushWorkers  int
 
+	// bounded queue between consumers and dataway senders, disabled if size is 0.
+	sendQueueSize     int
+	sendQueueWorkers  int
+	sendQueueOverflow string
+	sendQueue         *sendQueue
+
 	feedChanSize  int
 	maxCacheCount int
 
+	federate    *localFederate
+	downsampler *downsampler
+	preview     *inputPreview
+
 	//////////////////////////
 	// inner fields
 	//////////////////////////
 	chans map[string]chan *iodata
 	fcs   map[string]failcache.Cache
 
+	// signals consumers to flush immediately
+	forceFlushChans map[string]chan struct{}
+
+	pauses *uploadPauses
+
 	lock sync.RWMutex
 
 	fd *os.File
@@ -90,6 +105,12 @@ func getIO() *dkIO {
 		chans: map[string]chan *iodata{},
 		fcs:   map[string]failcache.Cache{},
 
+		forceFlushChans: map[string]chan struct{}{},
+
+		pauses: &uploadPauses{pauses: map[string]*UploadPause{}},
+
+		preview: newInputPreview(defaultPreviewSize),
+
 		lock: sync.RWMutex{},
 	}
 
@@ -112,6 +133,18 @@ func (x *dkIO) chanSetup() {
 		datakit.DynamicDatawayCategory,
 	} {
 		x.chans[c] = make(chan *iodata, x.feedChanSize)
+		x.forceFlushChans[c] = make(chan struct{}, 1)
+	}
+}
+
+// ForceFlush flushes points in memory and fail caches of all categories to
+// dataway right now, instead of waiting for the flush interval.
+func ForceFlush() {
+	for _, ch := range defIO.forceFlushChans {
+		select {
+		case ch <- struct{}{}:
+		default: // flush pending
+		}
 	}
 }
 
@@ -142,11 +175,22 @@ func (x *dkIO) start() {
 		}
 	}
 
+	x.startDownsampler()
+	x.startPlMetricFlusher()
+
 	nworker := runtime.NumCPU()*2 + 1
 	if x.flushWorkers > 0 {
 		nworker = x.flushWorkers
 	}
 
+	if x.sendQueueSize > 0 {
+		n := nworker
+		if x.sendQueueWorkers > 0 {
+			n = x.sendQueueWorkers
+		}
+		x.startSendQueue(n)
+	}
+
 	for _, c := range []string{
 		datakit.Metric,
 		datakit.Network,
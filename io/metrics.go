@@ -18,8 +18,12 @@ var (
 	inputsFeedPtsVec,
 	errCountVec,
 	flushVec,
+	downsampledPtsVec,
+	sendQueueOverflowVec,
+	uploadPausedPtsVec,
 	inputsFilteredPtsVec *prometheus.CounterVec
 
+	sendQueueWaitVec,
 	inputsCollectLatencyVec *prometheus.SummaryVec
 
 	queuePtsVec,
@@ -27,7 +31,13 @@ var (
 	inputsLastFeedVec,
 	lastErrVec,
 	ioChanCap,
+	sendQueueLenVec,
+	uploadPausedVec,
 	ioChanLen *prometheus.GaugeVec
+
+	sendQueueCap,
+	sendQueuePaused,
+	sendQueueOldestAge prometheus.Gauge
 )
 
 func metricsSetup() {
@@ -176,6 +186,107 @@ func metricsSetup() {
 		},
 	)
 
+	downsampledPtsVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "downsampled_points_total",
+			Help:      "Metric points aggregated by downsampling rules",
+		},
+		[]string{
+			"measurement",
+		},
+	)
+
+	sendQueueLenVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_length",
+			Help:      "Batches of points waiting in the send queue",
+		},
+		[]string{
+			"category",
+		},
+	)
+
+	sendQueueCap = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_capacity",
+			Help:      "Capacity(batches) of the send queue",
+		},
+	)
+
+	sendQueuePaused = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_paused",
+			Help:      "Whether the send queue paused(1) or not(0)",
+		},
+	)
+
+	sendQueueOldestAge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_oldest_age_seconds",
+			Help:      "Age(in seconds) of the oldest batch in the send queue",
+		},
+	)
+
+	sendQueueWaitVec = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_wait_seconds",
+			Help:      "Time(in seconds) batches of points waited in the send queue",
+		},
+		[]string{
+			"category",
+		},
+	)
+
+	uploadPausedVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "upload_paused",
+			Help:      "Whether uploading of the category paused(1) or not(0)",
+		},
+		[]string{
+			"category",
+		},
+	)
+
+	uploadPausedPtsVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "upload_paused_points_total",
+			Help:      "Points of paused categories, partitioned by category and action(spilled/dropped)",
+		},
+		[]string{
+			"category",
+			"action",
+		},
+	)
+
+	sendQueueOverflowVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "datakit",
+			Subsystem: "io",
+			Name:      "send_queue_overflow_points_total",
+			Help:      "Points on full send queue, partitioned by category and action(blocked/spilled/dropped)",
+		},
+		[]string{
+			"category",
+			"action",
+		},
+	)
+
 	// add more...
 }
 
@@ -199,6 +310,15 @@ func Metrics() []prometheus.Collector {
 		errCountVec,
 		flushVec,
 		flushWorkersVec,
+		downsampledPtsVec,
+		sendQueueLenVec,
+		sendQueueCap,
+		sendQueuePaused,
+		sendQueueOldestAge,
+		sendQueueWaitVec,
+		sendQueueOverflowVec,
+		uploadPausedVec,
+		uploadPausedPtsVec,
 	}
 }
 
@@ -217,6 +337,12 @@ func MetricsReset() {
 	ioChanLen.Reset()
 	flushVec.Reset()
 	flushWorkersVec.Reset()
+	downsampledPtsVec.Reset()
+	sendQueueLenVec.Reset()
+	sendQueueWaitVec.Reset()
+	sendQueueOverflowVec.Reset()
+	uploadPausedVec.Reset()
+	uploadPausedPtsVec.Reset()
 }
 
 // A CollectorStatus used to describe a input's status.
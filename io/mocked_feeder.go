@@ -6,7 +6,10 @@
 package io
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/GuanceCloud/cliutils/point"
@@ -23,19 +26,44 @@ type MockedFeeder struct {
 	ch chan []*point.Point
 
 	lastErrors [][2]string
+
+	mtx       sync.Mutex
+	fed       map[point.Category][]*point.Point // all points fed
+	fedNotify chan struct{}                     // closed on any feed
+	subs      map[*Subscription]struct{}
+	recording bool // await/subscribe API used
 }
 
 func NewMockedFeeder() *MockedFeeder {
 	return &MockedFeeder{
-		ch: make(chan []*point.Point, chanCap),
+		ch:        make(chan []*point.Point, chanCap),
+		fed:       map[point.Category][]*point.Point{},
+		fedNotify: make(chan struct{}),
+		subs:      map[*Subscription]struct{}{},
 	}
 }
 
+// Feed records pts for Points/AnyPointsWithin/Subscribe, and queues them
+// for AnyPoints/NPoints. ErrBusy returned if the queue is full, unless the
+// await/subscribe API used, the queue is not consumed on that case.
 func (f *MockedFeeder) Feed(name string, category point.Category, pts []*point.Point, opts ...*Option) error {
+	f.mtx.Lock()
+	f.fed[category] = append(f.fed[category], pts...)
+	close(f.fedNotify)
+	f.fedNotify = make(chan struct{})
+
+	for sub := range f.subs {
+		sub.push(category, pts)
+	}
+	recording := f.recording
+	f.mtx.Unlock()
+
 	select {
 	case f.ch <- pts:
 	default:
-		return ErrBusy
+		if !recording {
+			return ErrBusy
+		}
 	}
 
 	return nil
@@ -101,3 +129,180 @@ func (f *MockedFeeder) NPoints(n int, args ...time.Duration) (pts []*point.Point
 func (f *MockedFeeder) LastErrors() [][2]string {
 	return f.lastErrors
 }
+
+// PointFilter selects points for Points, AnyPointsWithin and Subscribe.
+type PointFilter func(pt *point.Point) bool
+
+// WithMeasurement selects points of any of the measurements.
+func WithMeasurement(names ...string) PointFilter {
+	return func(pt *point.Point) bool {
+		for _, name := range names {
+			if string(pt.Name()) == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithTag selects points with tag k=v.
+func WithTag(k, v string) PointFilter {
+	return func(pt *point.Point) bool {
+		x := pt.GetTag([]byte(k))
+		return x != nil && bytes.Equal(x, []byte(v))
+	}
+}
+
+func matchFilters(pt *point.Point, filters []PointFilter) bool {
+	for _, fn := range filters {
+		if fn != nil && !fn(pt) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *MockedFeeder) matched(category point.Category, filters []PointFilter) (pts []*point.Point) {
+	for _, pt := range f.fed[category] {
+		if matchFilters(pt, filters) {
+			pts = append(pts, pt)
+		}
+	}
+	return
+}
+
+// Points returns all points fed on category that selected by filters.
+func (f *MockedFeeder) Points(category point.Category, filters ...PointFilter) []*point.Point {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.matched(category, filters)
+}
+
+// AnyPointsWithin wait until at least n points fed(since the feeder created)
+// on category that selected by filters, and returns all of them. If ctx done
+// before that, points got so far returned with ErrTimeout, or ctx.Err() if
+// ctx canceled.
+func (f *MockedFeeder) AnyPointsWithin(ctx context.Context,
+	category point.Category,
+	n int,
+	filters ...PointFilter,
+) ([]*point.Point, error) {
+	for {
+		f.mtx.Lock()
+		f.recording = true
+		pts := f.matched(category, filters)
+		notify := f.fedNotify
+		f.mtx.Unlock()
+
+		if len(pts) >= n {
+			return pts, nil
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return pts, ErrTimeout
+			}
+			return pts, ctx.Err()
+		}
+	}
+}
+
+// Subscription receives points fed after subscribed on C, in fed order.
+type Subscription struct {
+	// C is closed after the subscription closed.
+	C <-chan *point.Point
+
+	c        chan *point.Point
+	category point.Category
+	filters  []PointFilter
+
+	mtx    sync.Mutex
+	queue  []*point.Point
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	f *MockedFeeder
+}
+
+// Subscribe returns a subscription of points fed on category that selected by
+// filters. Points are buffered, Feed never blocked by slow receivers.
+func (f *MockedFeeder) Subscribe(category point.Category, filters ...PointFilter) *Subscription {
+	c := make(chan *point.Point)
+	sub := &Subscription{
+		C:        c,
+		c:        c,
+		category: category,
+		filters:  filters,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		f:        f,
+	}
+
+	f.mtx.Lock()
+	f.recording = true
+	f.subs[sub] = struct{}{}
+	f.mtx.Unlock()
+
+	go sub.pump()
+
+	return sub
+}
+
+func (s *Subscription) push(category point.Category, pts []*point.Point) {
+	if category != s.category {
+		return
+	}
+
+	s.mtx.Lock()
+	for _, pt := range pts {
+		if matchFilters(pt, s.filters) {
+			s.queue = append(s.queue, pt)
+		}
+	}
+	s.mtx.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default: // already notified
+	}
+}
+
+func (s *Subscription) pump() {
+	defer close(s.c)
+
+	for {
+		s.mtx.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mtx.Unlock()
+
+		for _, pt := range queue {
+			select {
+			case s.c <- pt:
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops the subscription, points not received are dropped.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.f.mtx.Lock()
+		delete(s.f.subs, s)
+		s.f.mtx.Unlock()
+
+		close(s.done)
+	})
+}
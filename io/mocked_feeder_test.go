@@ -6,11 +6,13 @@
 package io
 
 import (
+	"context"
 	T "testing"
 	"time"
 
 	"github.com/GuanceCloud/cliutils/point"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNPoints(t *T.T) {
@@ -75,19 +77,12 @@ func TestNPoints(t *T.T) {
 	})
 
 	t.Run("feed-busy", func(t *T.T) {
+		// feeders of above cases may still feeding, use a new one.
+		f := NewMockedFeeder()
+
 		pt, _ := point.NewPoint(t.Name(), nil, map[string]any{"abc": 123})
 		pts := []*point.Point{pt}
 
-		// cleanup chans
-		for {
-			select {
-			case <-f.ch:
-			default:
-				goto out
-			}
-		}
-	out:
-
 		for i := 0; i < chanCap; i++ {
 			assert.NoError(t, f.Feed(t.Name(), point.Metric, pts), "feed err on %dth", i)
 		}
@@ -97,3 +92,123 @@ func TestNPoints(t *T.T) {
 		t.Logf("got expect error: %s", err)
 	})
 }
+
+func newTestPoint(t *T.T, name, host string) *point.Point {
+	t.Helper()
+
+	pt, err := point.NewPoint(name, map[string]string{"host": host}, map[string]any{"abc": 123})
+	require.NoError(t, err)
+	return pt
+}
+
+func TestAnyPointsWithin(t *T.T) {
+	t.Run("filtered", func(t *T.T) {
+		f := NewMockedFeeder()
+
+		go func() {
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{
+					newTestPoint(t, "cpu", "h1"),
+					newTestPoint(t, "mem", "h1"),
+					newTestPoint(t, "cpu", "h2"),
+				}))
+				time.Sleep(time.Millisecond * 10)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		pts, err := f.AnyPointsWithin(ctx, point.Metric, 3, WithMeasurement("cpu"), WithTag("host", "h2"))
+		require.NoError(t, err)
+		assert.Len(t, pts, 3)
+
+		for _, pt := range pts {
+			assert.Equal(t, "cpu", string(pt.Name()))
+			assert.Equal(t, "h2", string(pt.GetTag([]byte("host"))))
+		}
+
+		assert.Len(t, f.Points(point.Metric, WithMeasurement("mem")), 3)
+		assert.Empty(t, f.Points(point.Logging))
+	})
+
+	t.Run("timeout", func(t *T.T) {
+		f := NewMockedFeeder()
+		assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{newTestPoint(t, "cpu", "h1")}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+
+		pts, err := f.AnyPointsWithin(ctx, point.Metric, 2)
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Len(t, pts, 1)
+	})
+
+	t.Run("canceled", func(t *T.T) {
+		f := NewMockedFeeder()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := f.AnyPointsWithin(ctx, point.Metric, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("no-busy-on-recording", func(t *T.T) {
+		f := NewMockedFeeder()
+		_ = f.Points(point.Metric) // not start recording
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		for i := 0; i < chanCap*2; i++ {
+			if i == chanCap {
+				_, err := f.AnyPointsWithin(ctx, point.Metric, 1)
+				require.NoError(t, err)
+			}
+
+			assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{newTestPoint(t, "cpu", "h1")}), "feed err on %dth", i)
+		}
+
+		assert.Len(t, f.Points(point.Metric), chanCap*2)
+	})
+}
+
+func TestSubscribe(t *T.T) {
+	f := NewMockedFeeder()
+
+	// points fed before subscribed not received
+	assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{newTestPoint(t, "cpu", "h0")}))
+
+	sub := f.Subscribe(point.Metric, WithMeasurement("cpu"))
+
+	for i := 0; i < chanCap*2; i++ {
+		assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{
+			newTestPoint(t, "cpu", "h1"),
+			newTestPoint(t, "mem", "h1"),
+		}))
+	}
+	assert.NoError(t, f.Feed(t.Name(), point.Logging, []*point.Point{newTestPoint(t, "cpu", "h1")}))
+
+	tick := time.NewTimer(time.Second)
+	defer tick.Stop()
+
+	for i := 0; i < chanCap*2; i++ {
+		select {
+		case pt := <-sub.C:
+			assert.Equal(t, "cpu", string(pt.Name()))
+			assert.Equal(t, "h1", string(pt.GetTag([]byte("host"))))
+		case <-tick.C:
+			t.Fatalf("%dth point not received", i)
+		}
+	}
+
+	sub.Close()
+	sub.Close() // closed twice ok
+
+	for range sub.C { // C closed after Close()
+	}
+
+	// feed after subscription closed
+	assert.NoError(t, f.Feed(t.Name(), point.Metric, []*point.Point{newTestPoint(t, "cpu", "h1")}))
+}
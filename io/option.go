@@ -121,6 +121,17 @@ func WithFlushWorkers(n int) IOOption {
 	}
 }
 
+// WithSendQueue used to queue points flushed in a bounded queue, sent to
+// dataway by workers. The overflow(block/spill/drop) policy applied when the
+// queue is full. Points are sent on flushing if size is 0.
+func WithSendQueue(size, workers int, overflow string) IOOption {
+	return func(x *dkIO) {
+		x.sendQueueSize = size
+		x.sendQueueWorkers = workers
+		x.sendQueueOverflow = overflow
+	}
+}
+
 // WithFlushInterval used to contol when to flush cached data.
 func WithFlushInterval(d time.Duration) IOOption {
 	return func(x *dkIO) {
@@ -148,3 +159,22 @@ func WithMaxCacheCount(count int) IOOption {
 		}
 	}
 }
+
+// WithDownsampling used to aggregate high-frequency metric points matched
+// by rules into coarser intervals before uploading.
+func WithDownsampling(rules []*DownsampleRule) IOOption {
+	return func(x *dkIO) {
+		x.downsampler = newDownsampler(rules)
+	}
+}
+
+// WithLocalFederate used to keep the most recent metric points, which
+// can be scraped in Prometheus text format. Series not updated within
+// ttl are removed.
+func WithLocalFederate(on bool, ttl time.Duration) IOOption {
+	return func(x *dkIO) {
+		if on {
+			x.federate = newLocalFederate(ttl)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+)
+
+var (
+	ErrUnknownCategory = errors.New("unknown category")
+
+	// ErrUploadPauseNoCache returned on pausing categories without disk
+	// cache, points of them have nowhere to go during the pause.
+	ErrUploadPauseNoCache = errors.New("disk cache not enabled")
+)
+
+// UploadPause is the pause of uploading of a category.
+type UploadPause struct {
+	Category string    `json:"category"`
+	Since    time.Time `json:"since"`
+
+	// Spilled is the number of points written to disk cache during the pause.
+	Spilled int64 `json:"spilled"`
+}
+
+// UploadPauseState lists categories of paused uploading.
+type UploadPauseState struct {
+	Paused []*UploadPause `json:"paused"`
+}
+
+// uploadPauses are categories(in URL form) of paused uploading.
+type uploadPauses struct {
+	mtx    sync.RWMutex
+	pauses map[string]*UploadPause
+}
+
+func (p *uploadPauses) paused(cat string) bool {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	_, ok := p.pauses[cat]
+	return ok
+}
+
+func (p *uploadPauses) addSpilled(cat string, n int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if x, ok := p.pauses[cat]; ok {
+		x.Spilled += int64(n)
+	}
+}
+
+func (p *uploadPauses) state() *UploadPauseState {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	st := &UploadPauseState{Paused: []*UploadPause{}}
+	for _, x := range p.pauses {
+		cp := *x
+		st.Paused = append(st.Paused, &cp)
+	}
+
+	sort.Slice(st.Paused, func(i, j int) bool {
+		return st.Paused[i].Category < st.Paused[j].Category
+	})
+
+	return st
+}
+
+// parseCategories converts category names(such as logging) to their URLs.
+func parseCategories(cats []string) ([]point.Category, error) {
+	if len(cats) == 0 {
+		return nil, fmt.Errorf("%w: no category specified", ErrUnknownCategory)
+	}
+
+	var res []point.Category
+	for _, c := range cats {
+		cat := point.CatString(c)
+		if cat == point.UnknownCategory || cat == point.DynamicDWCategory {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCategory, c)
+		}
+		res = append(res, cat)
+	}
+
+	return res, nil
+}
+
+// spillPaused writes points of the consumer to disk cache if its category
+// paused.
+func (x *dkIO) spillPaused(c *consumer) {
+	if c.fc == nil || len(c.pts) == 0 || !x.pauses.paused(c.category) {
+		return
+	}
+
+	cat := point.CatURL(c.category).String()
+
+	if err := x.dw.Write(dataway.WithCacheOnly(true),
+		dataway.WithCategory(c.category),
+		dataway.WithPoints(c.pts),
+		dataway.WithFailCache(c.fc)); err != nil {
+		log.Warnf("upload of %s paused, spill %d points: %s, dropped", c.category, len(c.pts), err)
+		uploadPausedPtsVec.WithLabelValues(cat, "dropped").Add(float64(len(c.pts)))
+	} else {
+		x.pauses.addSpilled(c.category, len(c.pts))
+		uploadPausedPtsVec.WithLabelValues(cat, "spilled").Add(float64(len(c.pts)))
+	}
+
+	c.pts = c.pts[:0]
+	c.origins = c.origins[:0]
+}
+
+// GetUploadPauseState returns categories of paused uploading.
+func GetUploadPauseState() *UploadPauseState {
+	return defIO.pauses.state()
+}
+
+// PauseUpload stops uploading points of the categories until ResumeUpload
+// called, points of them are written to disk cache during the pause. Other
+// categories are not affected. Categories without disk cache can not be
+// paused.
+func PauseUpload(cats ...string) (*UploadPauseState, error) {
+	arr, err := parseCategories(cats)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range arr {
+		if _, ok := defIO.fcs[c.URL()]; !ok {
+			return nil, fmt.Errorf("%w on %s", ErrUploadPauseNoCache, c)
+		}
+	}
+
+	defIO.pauses.mtx.Lock()
+	for _, c := range arr {
+		if _, ok := defIO.pauses.pauses[c.URL()]; ok {
+			continue
+		}
+
+		log.Infof("upload of %s paused", c)
+		defIO.pauses.pauses[c.URL()] = &UploadPause{Category: c.String(), Since: time.Now()}
+		uploadPausedVec.WithLabelValues(c.String()).Set(1)
+	}
+	defIO.pauses.mtx.Unlock()
+
+	return GetUploadPauseState(), nil
+}
+
+// ResumeUpload resumes uploading of paused categories, points cached during
+// the pause are sent right now.
+func ResumeUpload(cats ...string) (*UploadPauseState, error) {
+	arr, err := parseCategories(cats)
+	if err != nil {
+		return nil, err
+	}
+
+	defIO.pauses.mtx.Lock()
+	for _, c := range arr {
+		if _, ok := defIO.pauses.pauses[c.URL()]; !ok {
+			continue
+		}
+
+		log.Infof("upload of %s resumed", c)
+		delete(defIO.pauses.pauses, c.URL())
+		uploadPausedVec.WithLabelValues(c.String()).Set(0)
+	}
+	defIO.pauses.mtx.Unlock()
+
+	ForceFlush()
+
+	return GetUploadPauseState(), nil
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	T "testing"
+
+	"github.com/GuanceCloud/cliutils/diskcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/failcache"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+// countFailCache counts puts and gets of the cache.
+type countFailCache struct {
+	sync.Mutex
+	puts, gets int
+}
+
+func (c *countFailCache) Get(diskcache.Fn) error {
+	c.Lock()
+	defer c.Unlock()
+	c.gets++
+	return nil
+}
+
+func (c *countFailCache) Put([]byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.puts++
+	return nil
+}
+
+func TestUploadPause(t *T.T) {
+	var (
+		mtx  sync.Mutex
+		apis []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		apis = append(apis, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	dw := &dataway.Dataway{URLs: []string{ts.URL + "?token=tkn_abc123"}}
+	require.NoError(t, dw.Init())
+
+	fc := &countFailCache{}
+
+	oldDW, oldFCs := defIO.dw, defIO.fcs
+	defIO.dw = dw
+	defIO.fcs = map[string]failcache.Cache{datakit.Logging: fc}
+	defer func() {
+		defIO.dw, defIO.fcs = oldDW, oldFCs
+		defIO.pauses = &uploadPauses{pauses: map[string]*UploadPause{}}
+	}()
+
+	consumerOf := func(cat string) *consumer {
+		return &consumer{
+			category:          cat,
+			fc:                defIO.fcs[cat],
+			pts:               dkpt.RandPoints(10),
+			origins:           make([]string, 10),
+			dynamicDatawayPts: map[string][]*dkpt.Point{},
+		}
+	}
+
+	sent := func() []string {
+		mtx.Lock()
+		defer mtx.Unlock()
+		x := apis
+		apis = nil
+		return x
+	}
+
+	t.Run("invalid", func(t *T.T) {
+		_, err := PauseUpload()
+		assert.ErrorIs(t, err, ErrUnknownCategory)
+
+		_, err = PauseUpload("logging", "no-such-category")
+		assert.ErrorIs(t, err, ErrUnknownCategory)
+
+		_, err = PauseUpload("metric") // no disk cache on metric
+		assert.ErrorIs(t, err, ErrUploadPauseNoCache)
+
+		assert.Empty(t, GetUploadPauseState().Paused)
+	})
+
+	t.Run("pause", func(t *T.T) {
+		st, err := PauseUpload("logging")
+		require.NoError(t, err)
+		require.Len(t, st.Paused, 1)
+		assert.Equal(t, "logging", st.Paused[0].Category)
+
+		c := consumerOf(datakit.Logging)
+		defIO.flush(c)
+		defIO.flushFailCache(c)
+
+		assert.Empty(t, c.pts)
+		assert.Equal(t, 1, fc.puts)
+		assert.Equal(t, 0, fc.gets, "cache should not be cleaned during the pause")
+		assert.Empty(t, sent())
+
+		st = GetUploadPauseState()
+		require.Len(t, st.Paused, 1)
+		assert.Equal(t, int64(10), st.Paused[0].Spilled)
+
+		// other categories not affected
+		defIO.flush(consumerOf(datakit.Metric))
+		assert.Equal(t, []string{datakit.Metric}, sent())
+	})
+
+	t.Run("resume", func(t *T.T) {
+		st, err := ResumeUpload("logging")
+		require.NoError(t, err)
+		assert.Empty(t, st.Paused)
+
+		c := consumerOf(datakit.Logging)
+		defIO.flush(c)
+		defIO.flushFailCache(c)
+
+		assert.Equal(t, 1, fc.gets)
+		assert.Equal(t, []string{datakit.Logging}, sent())
+	})
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"context"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plmetric"
+)
+
+const plMetricFrom = "pipeline_metrics"
+
+// runPlMetricFlusher feeds metrics emitted by pipeline scripts(emit_metric())
+// once their flush interval passed.
+func (x *dkIO) runPlMetricFlusher() {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+		case now := <-tick.C:
+			pts := plmetric.Expire(now)
+			if len(pts) == 0 {
+				continue
+			}
+
+			if err := unblockingFeed(&iodata{
+				category: datakit.Metric,
+				from:     plMetricFrom,
+				pts:      pts,
+			}, x.chans[datakit.Metric]); err != nil {
+				log.Warnf("feed %d pipeline metric points: %s", len(pts), err)
+			}
+
+		case <-datakit.Exit.Wait():
+			log.Info("pipeline metric flusher exit")
+			return
+		}
+	}
+}
+
+func (x *dkIO) startPlMetricFlusher() {
+	g.Go(func(_ context.Context) error {
+		x.runPlMetricFlusher()
+		return nil
+	})
+}
@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/GuanceCloud/cliutils/point"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	defaultPreviewSize = 100
+
+	previewMask        = "******"
+	previewMaxValueLen = 1024
+)
+
+// keys of tags/fields containing these words are masked on preview.
+var previewSensitiveKeys = []string{
+	"password", "passwd", "pwd", "secret", "token",
+	"apikey", "api_key", "access_key", "credential", "authorization", "cookie",
+}
+
+// PreviewPoint is the scrubbed point fed by an input.
+type PreviewPoint struct {
+	Category string                 `json:"category"`
+	Name     string                 `json:"name"`
+	Tags     map[string]string      `json:"tags"`
+	Fields   map[string]interface{} `json:"fields"`
+	Time     int64                  `json:"time"` // unix nanoseconds
+}
+
+type previewEntry struct {
+	category string
+	pt       *dkpt.Point
+}
+
+// previewRing keeps the last points of an input.
+type previewRing struct {
+	entries []previewEntry
+	next    int
+	full    bool
+}
+
+// inputPreview keeps the last points fed by each input in memory, so users
+// can preview data of the input they are configuring.
+type inputPreview struct {
+	sync.Mutex
+	size  int
+	rings map[string]*previewRing
+}
+
+func newInputPreview(size int) *inputPreview {
+	if size <= 0 {
+		size = defaultPreviewSize
+	}
+
+	return &inputPreview{
+		size:  size,
+		rings: map[string]*previewRing{},
+	}
+}
+
+func (ip *inputPreview) add(from, category string, pts []*dkpt.Point) {
+	if len(pts) == 0 {
+		return
+	}
+
+	ip.Lock()
+	defer ip.Unlock()
+
+	r, ok := ip.rings[from]
+	if !ok {
+		r = &previewRing{entries: make([]previewEntry, ip.size)}
+		ip.rings[from] = r
+	}
+
+	// only the last size points kept
+	if len(pts) > ip.size {
+		pts = pts[len(pts)-ip.size:]
+	}
+
+	for _, pt := range pts {
+		r.entries[r.next] = previewEntry{category: category, pt: pt}
+		r.next++
+		if r.next == len(r.entries) {
+			r.next = 0
+			r.full = true
+		}
+	}
+}
+
+// last returns the last n points of the input, oldest first.
+func (ip *inputPreview) last(from string, n int) []*PreviewPoint {
+	ip.Lock()
+
+	r, ok := ip.rings[from]
+	if !ok {
+		ip.Unlock()
+		return nil
+	}
+
+	total := r.next
+	if r.full {
+		total = len(r.entries)
+	}
+
+	if n <= 0 || n > total {
+		n = total
+	}
+
+	entries := make([]previewEntry, 0, n)
+	for i := n; i > 0; i-- {
+		idx := (r.next - i + len(r.entries)) % len(r.entries)
+		entries = append(entries, r.entries[idx])
+	}
+
+	ip.Unlock()
+
+	res := make([]*PreviewPoint, 0, len(entries))
+	for _, e := range entries {
+		if pp := scrubPoint(e.category, e.pt); pp != nil {
+			res = append(res, pp)
+		}
+	}
+
+	return res
+}
+
+func scrubPoint(category string, pt *dkpt.Point) *PreviewPoint {
+	fields, err := pt.Fields()
+	if err != nil {
+		return nil
+	}
+
+	pp := &PreviewPoint{
+		Category: point.CatURL(category).String(),
+		Name:     pt.Name(),
+		Tags:     map[string]string{},
+		Fields:   map[string]interface{}{},
+		Time:     pt.Time().UnixNano(),
+	}
+
+	for k, v := range pt.Tags() {
+		if isSensitiveKey(k) {
+			v = previewMask
+		}
+		pp.Tags[k] = v
+	}
+
+	for k, v := range fields {
+		switch {
+		case isSensitiveKey(k):
+			v = previewMask
+		default:
+			if s, ok := v.(string); ok && len(s) > previewMaxValueLen {
+				v = s[:previewMaxValueLen] + "..."
+			}
+		}
+		pp.Fields[k] = v
+	}
+
+	return pp
+}
+
+func isSensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range previewSensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviewInput returns the last n(all kept if n <= 0) points fed by the
+// input, oldest first, values of sensitive tags/fields masked.
+func PreviewInput(name string, n int) []*PreviewPoint {
+	if defIO.preview == nil {
+		return nil
+	}
+
+	return defIO.preview.last(name, n)
+}
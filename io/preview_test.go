@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+func TestInputPreview(t *T.T) {
+	ts := time.Unix(1680000000, 0)
+
+	newPoint := func(t *T.T, tags map[string]string, fields map[string]any) *dkpt.Point {
+		t.Helper()
+
+		pt, err := dkpt.NewPoint("mysql", tags, fields, &dkpt.PointOption{Time: ts, Category: datakit.Logging, DisableGlobalTags: true})
+		require.NoError(t, err)
+		return pt
+	}
+
+	t.Run("ring", func(t *T.T) {
+		ip := newInputPreview(3)
+		assert.Nil(t, ip.last("mysql", 10))
+
+		for i := int64(0); i < 5; i++ {
+			ip.add("mysql", datakit.Metric, []*dkpt.Point{newPoint(t, nil, map[string]any{"seq": i})})
+		}
+		ip.add("mysql", datakit.Metric, nil)
+
+		pts := ip.last("mysql", 10)
+		require.Len(t, pts, 3)
+		assert.Equal(t, int64(2), pts[0].Fields["seq"])
+		assert.Equal(t, int64(4), pts[2].Fields["seq"])
+		assert.Equal(t, "metric", pts[0].Category)
+		assert.Equal(t, "mysql", pts[0].Name)
+		assert.Equal(t, ts.UnixNano(), pts[0].Time)
+
+		pts = ip.last("mysql", 1)
+		require.Len(t, pts, 1)
+		assert.Equal(t, int64(4), pts[0].Fields["seq"])
+
+		// batch larger than the ring
+		var batch []*dkpt.Point
+		for i := int64(10); i < 15; i++ {
+			batch = append(batch, newPoint(t, nil, map[string]any{"seq": i}))
+		}
+		ip.add("mysql", datakit.Metric, batch)
+
+		pts = ip.last("mysql", 0)
+		require.Len(t, pts, 3)
+		assert.Equal(t, int64(12), pts[0].Fields["seq"])
+		assert.Equal(t, int64(14), pts[2].Fields["seq"])
+	})
+
+	t.Run("scrub", func(t *T.T) {
+		ip := newInputPreview(0)
+		ip.add("mysql", datakit.Logging, []*dkpt.Point{newPoint(t,
+			map[string]string{"server": "localhost:3306", "Auth_Token": "abc"},
+			map[string]any{
+				"password":  "123456",
+				"api_key":   "xyz",
+				"message":   strings.Repeat("x", 2000),
+				"qps":       1.5,
+				"keyspaces": int64(3),
+			})})
+
+		pts := ip.last("mysql", 1)
+		require.Len(t, pts, 1)
+		pt := pts[0]
+
+		assert.Equal(t, "localhost:3306", pt.Tags["server"])
+		assert.Equal(t, previewMask, pt.Tags["Auth_Token"])
+		assert.Equal(t, previewMask, pt.Fields["password"])
+		assert.Equal(t, previewMask, pt.Fields["api_key"])
+		assert.Len(t, pt.Fields["message"], previewMaxValueLen+3)
+		assert.Equal(t, 1.5, pt.Fields["qps"])
+		assert.Equal(t, int64(3), pt.Fields["keyspaces"])
+	})
+}
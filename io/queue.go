@@ -43,6 +43,9 @@ func (x *dkIO) cacheData(c *consumer, d *iodata, tryClean bool) {
 		c.dynamicDatawayPts[d.opt.HTTPHost] = append(c.dynamicDatawayPts[d.opt.HTTPHost], d.pts...)
 	} else {
 		c.pts = append(c.pts, d.pts...)
+		for range d.pts {
+			c.origins = append(c.origins, d.from)
+		}
 	}
 
 	if (tryClean &&
@@ -63,14 +66,24 @@ func (x *dkIO) flush(c *consumer) {
 		flushVec.WithLabelValues(point.CatURL(c.category).String()).Inc()
 	}()
 
-	if err := x.doFlush(c.pts, c.category, c.fc); err != nil {
+	// points of paused categories are not queued, or they may be sent by
+	// the send queue
+	x.spillPaused(c)
+
+	if x.sendQueue != nil {
+		x.enqueueFlush(c)
+		return
+	}
+
+	if err := x.doFlush(c.pts, c.origins, c.category, c.fc); err != nil {
 		log.Warnf("post %d points to %s failed: %s, ignored", len(c.pts), c.category, err)
 	}
 
 	c.pts = c.pts[:0] // clear
+	c.origins = c.origins[:0]
 
 	for k, pts := range c.dynamicDatawayPts {
-		if err := x.doFlush(pts, datakit.DynamicDatawayCategory, c.fc, k); err != nil {
+		if err := x.doFlush(pts, nil, datakit.DynamicDatawayCategory, c.fc, k); err != nil {
 			log.Warnf("post %d points to %s failed: %s, ignored", len(pts), k, err)
 		}
 		c.dynamicDatawayPts[k] = c.dynamicDatawayPts[k][:0] // clear
@@ -78,7 +91,7 @@ func (x *dkIO) flush(c *consumer) {
 }
 
 func (x *dkIO) flushFailCache(c *consumer) {
-	if c.fc == nil {
+	if c.fc == nil || x.pauses.paused(c.category) {
 		return
 	}
 
@@ -90,7 +103,7 @@ func (x *dkIO) flushFailCache(c *consumer) {
 	}
 }
 
-func (x *dkIO) doFlush(pts []*dkpt.Point, category string, fc failcache.Cache, dynamicURL ...string) error {
+func (x *dkIO) doFlush(pts []*dkpt.Point, origins []string, category string, fc failcache.Cache, dynamicURL ...string) error {
 	if x.dw == nil {
 		return fmt.Errorf("dataway not set")
 	}
@@ -101,6 +114,7 @@ func (x *dkIO) doFlush(pts []*dkpt.Point, category string, fc failcache.Cache, d
 
 	opts := []dataway.WriteOption{
 		dataway.WithPoints(pts),
+		dataway.WithOrigins(origins),
 		dataway.WithCategory(category),
 		dataway.WithFailCache(fc),
 		dataway.WithCacheAll(x.cacheAll),
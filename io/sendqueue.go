@@ -0,0 +1,451 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/failcache"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+// Overflow policies of the send queue, applied when the queue is full.
+const (
+	// SendQueueOverflowBlock blocks the flushing consumer until the queue
+	// has room, i.e., back pressure to inputs.
+	SendQueueOverflowBlock = "block"
+
+	// SendQueueOverflowSpill writes the batch to the disk cache of its
+	// category, and blocks if the category has no disk cache.
+	SendQueueOverflowSpill = "spill"
+
+	// SendQueueOverflowDrop drops the batch.
+	SendQueueOverflowDrop = "drop"
+)
+
+var ErrSendQueueDisabled = errors.New("send queue disabled")
+
+// SendQueueState is the state of the send queue.
+type SendQueueState struct {
+	Paused   bool   `json:"paused"`
+	Capacity int    `json:"capacity"`
+	Overflow string `json:"overflow"`
+
+	// Length is the number of batches queued, and Points the points of them.
+	Length int `json:"length"`
+	Points int `json:"points"`
+
+	// OldestAge is how long(in seconds) the oldest batch has been queued.
+	OldestAge float64 `json:"oldest_age"`
+
+	// Categories is the number of batches queued of each category.
+	Categories map[string]int `json:"categories"`
+}
+
+// SendQueueResult is the result of draining or flushing the send queue.
+type SendQueueResult struct {
+	Batches int `json:"batches"`
+	Points  int `json:"points"`
+	Spilled int `json:"spilled,omitempty"` // batches spilled to disk cache
+	Dropped int `json:"dropped,omitempty"` // batches dropped
+	Failed  int `json:"failed,omitempty"`  // batches failed to send
+}
+
+// sendJob is a batch of points flushed by a consumer, waiting for sending.
+type sendJob struct {
+	pts        []*dkpt.Point
+	origins    []string
+	category   string
+	dynamicURL string
+	fc         failcache.Cache
+	enqueued   time.Time
+}
+
+// spillable checks if the job can be spilled to disk cache. Points of
+// dynamic URLs are not spilled, the URL is not kept in the cache.
+func (j *sendJob) spillable() bool {
+	return j.fc != nil && j.dynamicURL == ""
+}
+
+func (j *sendJob) cat() string {
+	if j.category == datakit.DynamicDatawayCategory {
+		return point.DynamicDWCategory.String()
+	}
+	return point.CatURL(j.category).String()
+}
+
+// sendQueue is a bounded FIFO between consumers flushing points and workers
+// sending them to dataway.
+type sendQueue struct {
+	capacity int
+	overflow string
+
+	send  func(*sendJob) error
+	spill func(*sendJob) error
+
+	mtx      sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	jobs     []*sendJob
+	paused   bool
+	closed   bool
+}
+
+func newSendQueue(capacity int, overflow string, send, spill func(*sendJob) error) *sendQueue {
+	switch overflow {
+	case SendQueueOverflowBlock, SendQueueOverflowSpill, SendQueueOverflowDrop:
+	default:
+		if overflow != "" {
+			log.Warnf("invalid send queue overflow policy %q, use %q", overflow, SendQueueOverflowBlock)
+		}
+		overflow = SendQueueOverflowBlock
+	}
+
+	q := &sendQueue{
+		capacity: capacity,
+		overflow: overflow,
+		send:     send,
+		spill:    spill,
+	}
+
+	q.notEmpty = sync.NewCond(&q.mtx)
+	q.notFull = sync.NewCond(&q.mtx)
+
+	return q
+}
+
+// push queues the job, if the queue is full, the overflow policy applied.
+func (q *sendQueue) push(j *sendJob) {
+	j.enqueued = time.Now()
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	blocked := false
+	for len(q.jobs) >= q.capacity && !q.closed {
+		switch q.overflow {
+		case SendQueueOverflowDrop:
+			log.Warnf("send queue full, drop %d points on %s", len(j.pts), j.category)
+			sendQueueOverflowVec.WithLabelValues(j.cat(), "dropped").Add(float64(len(j.pts)))
+			return
+
+		case SendQueueOverflowSpill:
+			if j.spillable() && !blocked {
+				q.mtx.Unlock()
+				err := q.spill(j)
+				q.mtx.Lock()
+
+				if err == nil {
+					sendQueueOverflowVec.WithLabelValues(j.cat(), "spilled").Add(float64(len(j.pts)))
+					return
+				}
+
+				log.Warnf("send queue full, spill %d points on %s: %s, block", len(j.pts), j.category, err)
+			}
+		}
+
+		if !blocked {
+			blocked = true
+			sendQueueOverflowVec.WithLabelValues(j.cat(), "blocked").Add(float64(len(j.pts)))
+		}
+
+		q.notFull.Wait()
+	}
+
+	if q.closed {
+		log.Warnf("send queue closed, drop %d points on %s", len(j.pts), j.category)
+		return
+	}
+
+	q.jobs = append(q.jobs, j)
+	sendQueueLenVec.WithLabelValues(j.cat()).Inc()
+	q.notEmpty.Signal()
+}
+
+// pop gets the oldest job, blocks if the queue is empty or paused. It
+// returns false once the queue closed.
+func (q *sendQueue) pop() (*sendJob, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for (len(q.jobs) == 0 || q.paused) && !q.closed {
+		q.notEmpty.Wait()
+	}
+
+	if q.closed {
+		return nil, false
+	}
+
+	return q.shift(), true
+}
+
+// shift removes the oldest job, the lock should be held.
+func (q *sendQueue) shift() *sendJob {
+	j := q.jobs[0]
+	q.jobs[0] = nil
+	q.jobs = q.jobs[1:]
+
+	sendQueueLenVec.WithLabelValues(j.cat()).Dec()
+	sendQueueWaitVec.WithLabelValues(j.cat()).Observe(float64(time.Since(j.enqueued)) / float64(time.Second))
+	q.notFull.Signal()
+
+	return j
+}
+
+// takeAll removes all queued jobs.
+func (q *sendQueue) takeAll() []*sendJob {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	jobs := make([]*sendJob, 0, len(q.jobs))
+	for len(q.jobs) > 0 {
+		jobs = append(jobs, q.shift())
+	}
+
+	q.notFull.Broadcast()
+	return jobs
+}
+
+func (q *sendQueue) setPaused(paused bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.paused = paused
+	if paused {
+		sendQueuePaused.Set(1)
+	} else {
+		sendQueuePaused.Set(0)
+		q.notEmpty.Broadcast()
+	}
+}
+
+func (q *sendQueue) close() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+func (q *sendQueue) state() *SendQueueState {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	st := &SendQueueState{
+		Paused:     q.paused,
+		Capacity:   q.capacity,
+		Overflow:   q.overflow,
+		Length:     len(q.jobs),
+		Categories: map[string]int{},
+	}
+
+	for _, j := range q.jobs {
+		st.Points += len(j.pts)
+		st.Categories[j.cat()]++
+	}
+
+	if len(q.jobs) > 0 {
+		st.OldestAge = time.Since(q.jobs[0].enqueued).Seconds()
+	}
+
+	return st
+}
+
+// drain removes all queued jobs, jobs are spilled to disk cache if their
+// category has one, or dropped.
+func (q *sendQueue) drain() *SendQueueResult {
+	res := &SendQueueResult{}
+
+	for _, j := range q.takeAll() {
+		res.Batches++
+		res.Points += len(j.pts)
+
+		if j.spillable() {
+			err := q.spill(j)
+			if err == nil {
+				res.Spilled++
+				sendQueueOverflowVec.WithLabelValues(j.cat(), "spilled").Add(float64(len(j.pts)))
+				continue
+			}
+
+			log.Warnf("drain: spill %d points on %s: %s, dropped", len(j.pts), j.category, err)
+		}
+
+		res.Dropped++
+		sendQueueOverflowVec.WithLabelValues(j.cat(), "dropped").Add(float64(len(j.pts)))
+	}
+
+	return res
+}
+
+// flush sends all queued jobs right now, even if the queue paused.
+func (q *sendQueue) flush() *SendQueueResult {
+	res := &SendQueueResult{}
+
+	for _, j := range q.takeAll() {
+		res.Batches++
+		res.Points += len(j.pts)
+
+		if err := q.send(j); err != nil {
+			log.Warnf("flush: post %d points to %s failed: %s, ignored", len(j.pts), j.category, err)
+			res.Failed++
+		}
+	}
+
+	return res
+}
+
+func (q *sendQueue) runWorker() {
+	for {
+		j, ok := q.pop()
+		if !ok {
+			return
+		}
+
+		if err := q.send(j); err != nil {
+			log.Warnf("post %d points to %s failed: %s, ignored", len(j.pts), j.category, err)
+		}
+	}
+}
+
+func (x *dkIO) startSendQueue(nworker int) {
+	x.sendQueue = newSendQueue(x.sendQueueSize, x.sendQueueOverflow,
+		func(j *sendJob) error {
+			if j.dynamicURL != "" {
+				return x.doFlush(j.pts, nil, j.category, j.fc, j.dynamicURL)
+			}
+			return x.doFlush(j.pts, j.origins, j.category, j.fc)
+		},
+
+		func(j *sendJob) error {
+			return x.dw.Write(dataway.WithCacheOnly(true),
+				dataway.WithCategory(j.category),
+				dataway.WithPoints(j.pts),
+				dataway.WithFailCache(j.fc))
+		})
+
+	sendQueueCap.Set(float64(x.sendQueueSize))
+
+	log.Infof("start %d send workers, queue size %d, overflow %q", nworker, x.sendQueueSize, x.sendQueue.overflow)
+
+	for i := 0; i < nworker; i++ {
+		g.Go(func(_ context.Context) error {
+			x.sendQueue.runWorker()
+			return nil
+		})
+	}
+
+	g.Go(func(_ context.Context) error {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				sendQueueOldestAge.Set(x.sendQueue.state().OldestAge)
+
+			case <-datakit.Exit.Wait():
+				x.sendQueue.close()
+				return nil
+			}
+		}
+	})
+}
+
+// enqueueFlush hands over points of the consumer to the send queue.
+func (x *dkIO) enqueueFlush(c *consumer) {
+	if len(c.pts) > 0 {
+		x.sendQueue.push(&sendJob{
+			pts:      c.pts,
+			origins:  c.origins,
+			category: c.category,
+			fc:       c.fc,
+		})
+
+		// the queued job owns the slices now
+		c.pts, c.origins = nil, nil
+	}
+
+	for k, pts := range c.dynamicDatawayPts {
+		if len(pts) == 0 {
+			continue
+		}
+
+		x.sendQueue.push(&sendJob{
+			pts:        pts,
+			category:   datakit.DynamicDatawayCategory,
+			dynamicURL: k,
+			fc:         c.fc,
+		})
+		c.dynamicDatawayPts[k] = nil
+	}
+}
+
+// GetSendQueueState returns state of the send queue.
+func GetSendQueueState() (*SendQueueState, error) {
+	if defIO.sendQueue == nil {
+		return nil, ErrSendQueueDisabled
+	}
+
+	return defIO.sendQueue.state(), nil
+}
+
+// PauseSendQueue stops sending queued points until ResumeSendQueue called.
+// Points keep queued during the pause, and the overflow policy applied once
+// the queue is full.
+func PauseSendQueue() (*SendQueueState, error) {
+	if defIO.sendQueue == nil {
+		return nil, ErrSendQueueDisabled
+	}
+
+	log.Infof("send queue paused")
+	defIO.sendQueue.setPaused(true)
+	return defIO.sendQueue.state(), nil
+}
+
+// ResumeSendQueue resumes sending of the paused send queue.
+func ResumeSendQueue() (*SendQueueState, error) {
+	if defIO.sendQueue == nil {
+		return nil, ErrSendQueueDisabled
+	}
+
+	log.Infof("send queue resumed")
+	defIO.sendQueue.setPaused(false)
+	return defIO.sendQueue.state(), nil
+}
+
+// DrainSendQueue empties the send queue, points queued are spilled to disk
+// cache if enabled, or dropped.
+func DrainSendQueue() (*SendQueueResult, error) {
+	if defIO.sendQueue == nil {
+		return nil, ErrSendQueueDisabled
+	}
+
+	res := defIO.sendQueue.drain()
+	log.Infof("send queue drained: %+#v", res)
+	return res, nil
+}
+
+// FlushSendQueue sends points queued right now, and flushes points buffered
+// by consumers to the queue.
+func FlushSendQueue() (*SendQueueResult, error) {
+	if defIO.sendQueue == nil {
+		return nil, ErrSendQueueDisabled
+	}
+
+	res := defIO.sendQueue.flush()
+	ForceFlush()
+
+	log.Infof("send queue flushed: %+#v", res)
+	return res, nil
+}
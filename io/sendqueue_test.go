@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package io
+
+import (
+	"errors"
+	"sync"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/diskcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+type nopFailCache struct{}
+
+func (nopFailCache) Get(diskcache.Fn) error { return nil }
+func (nopFailCache) Put([]byte) error       { return nil }
+
+func TestSendQueue(t *T.T) {
+	type recorder struct {
+		sync.Mutex
+		sent, spilled []*sendJob
+		spillErr      error
+	}
+
+	newQueue := func(capacity int, overflow string) (*sendQueue, *recorder) {
+		r := &recorder{}
+		q := newSendQueue(capacity, overflow,
+			func(j *sendJob) error {
+				r.Lock()
+				defer r.Unlock()
+				r.sent = append(r.sent, j)
+				return nil
+			},
+			func(j *sendJob) error {
+				r.Lock()
+				defer r.Unlock()
+				if r.spillErr != nil {
+					return r.spillErr
+				}
+				r.spilled = append(r.spilled, j)
+				return nil
+			})
+		return q, r
+	}
+
+	job := func(cat string, n int) *sendJob {
+		return &sendJob{pts: dkpt.RandPoints(n), category: cat, fc: nopFailCache{}}
+	}
+
+	t.Run("fifo", func(t *T.T) {
+		q, _ := newQueue(4, "")
+		assert.Equal(t, SendQueueOverflowBlock, q.overflow)
+
+		q.push(job(datakit.Logging, 1))
+		q.push(job(datakit.Metric, 2))
+		q.push(job(datakit.Metric, 3))
+
+		st := q.state()
+		assert.Equal(t, 3, st.Length)
+		assert.Equal(t, 6, st.Points)
+		assert.Equal(t, map[string]int{"logging": 1, "metric": 2}, st.Categories)
+		assert.True(t, st.OldestAge >= 0)
+
+		j, ok := q.pop()
+		require.True(t, ok)
+		assert.Equal(t, datakit.Logging, j.category)
+		assert.Equal(t, 2, q.state().Length)
+	})
+
+	t.Run("overflow-drop", func(t *T.T) {
+		q, _ := newQueue(1, SendQueueOverflowDrop)
+		q.push(job(datakit.Logging, 1))
+		q.push(job(datakit.Logging, 2)) // dropped
+
+		assert.Equal(t, 1, q.state().Length)
+		assert.Equal(t, 1, q.state().Points)
+	})
+
+	t.Run("overflow-spill", func(t *T.T) {
+		q, r := newQueue(1, SendQueueOverflowSpill)
+		q.push(job(datakit.Logging, 1))
+		q.push(job(datakit.Logging, 2)) // spilled
+
+		assert.Equal(t, 1, q.state().Length)
+		require.Len(t, r.spilled, 1)
+		assert.Len(t, r.spilled[0].pts, 2)
+
+		// no fail cache, blocked until popped
+		done := make(chan struct{})
+		go func() {
+			q.push(&sendJob{pts: dkpt.RandPoints(3), category: datakit.Metric})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("push should block on full queue")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		_, ok := q.pop()
+		require.True(t, ok)
+		<-done
+		assert.Equal(t, 3, q.state().Points)
+	})
+
+	t.Run("pause-resume", func(t *T.T) {
+		q, r := newQueue(4, "")
+		q.setPaused(true)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.runWorker()
+		}()
+
+		q.push(job(datakit.Logging, 1))
+		time.Sleep(100 * time.Millisecond)
+
+		r.Lock()
+		assert.Len(t, r.sent, 0)
+		r.Unlock()
+		assert.True(t, q.state().Paused)
+
+		q.setPaused(false)
+		assert.Eventually(t, func() bool {
+			r.Lock()
+			defer r.Unlock()
+			return len(r.sent) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		q.close()
+		wg.Wait()
+	})
+
+	t.Run("drain-flush", func(t *T.T) {
+		q, r := newQueue(4, "")
+		r.spillErr = errors.New("disk full")
+
+		q.push(job(datakit.Logging, 1))
+		q.push(&sendJob{pts: dkpt.RandPoints(2), category: datakit.Metric}) // no fail cache
+
+		res := q.drain()
+		assert.Equal(t, &SendQueueResult{Batches: 2, Points: 3, Dropped: 2}, res)
+		assert.Equal(t, 0, q.state().Length)
+
+		r.spillErr = nil
+		q.push(job(datakit.Logging, 1))
+		q.push(&sendJob{pts: dkpt.RandPoints(2), category: datakit.DynamicDatawayCategory, dynamicURL: "http://dw"})
+		assert.Equal(t, &SendQueueResult{Batches: 2, Points: 3, Spilled: 1, Dropped: 1}, q.drain())
+
+		q.setPaused(true)
+		q.push(job(datakit.Logging, 4))
+		assert.Equal(t, &SendQueueResult{Batches: 1, Points: 4}, q.flush())
+		assert.Len(t, r.sent, 1)
+	})
+}
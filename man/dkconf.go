@@ -38,6 +38,96 @@ protect_mode = true
 ################################################
 ulimit = 64000
 
+################################################
+# startup: phased start of inputs after datakit boots
+################################################
+[startup]
+  # inputs start immediately
+  immediate_inputs = ["cpu", "mem", "disk", "diskio", "net", "system", "swap", "logging"]
+
+  # heavy inputs start one by one evenly within stagger_window,
+  # other inputs start randomly within 10s
+  staggered_inputs = ["container", "snmp", "netscan"]
+  stagger_window = "1m"
+
+################################################
+# host_identity: stable identity of the host
+################################################
+[host_identity]
+  # add tag host_id(from machine-id, cloud instance-id or DMI UUID) to all data,
+  # data of the same host can be associated by it even if the hostname changed
+  enable_host_id_tag = false
+
+  # cloud provider(aliyun/aws/tencent) to query instance-id as host ID if machine-id not available
+  cloud_provider = ""
+
+################################################
+# cloud_meta: tags of the cloud instance
+################################################
+[cloud_meta]
+  # add tags cloud_provider/cloud_instance_id/cloud_region/cloud_zone/cloud_instance_type
+  # of the cloud instance(detected by the metadata service) to all data
+  enable = true
+
+  # cloud provider(aws/gcp/azure/aliyun) to detect, all detected if empty
+  provider = ""
+
+################################################
+# load_shedding: shed low-priority work on high CPU/memory usage
+################################################
+[load_shedding]
+  enable = false
+  interval = "10s"
+
+  # percents of CPU or memory usage against cgroup limits, items
+  # shed above high watermark and restored below low watermark
+  high_watermark = 90.0
+  low_watermark = 70.0
+
+  # items shed from first to last, available: debug_api/profiling/object
+  priorities = ["debug_api", "profiling", "object"]
+
+################################################
+# probe_limit: outbound probe budget of active-check inputs
+################################################
+[probe_limit]
+  enable = false
+
+  # probes per second and burst probes to each destination network
+  rate = 20.0
+  burst = 20
+
+  # bytes per second to each destination network, 0 for unlimited
+  bandwidth = 0
+
+  # IP destinations grouped into networks by the prefix lengths
+  ipv4_prefix = 24
+  ipv6_prefix = 64
+
+  # probes waiting budget longer than max_wait are skipped
+  max_wait = "10s"
+
+################################################
+# remote_job: diagnostic jobs requested by the center
+################################################
+[remote_job]
+  enable = false
+
+  # built-in jobs allowed: bug_report/input_test/flush_cache
+  allowed_jobs = ["bug_report", "input_test", "flush_cache"]
+
+  # base64 encoded Ed25519 public key to verify signatures of jobs
+  public_key = ""
+
+  pull_interval = "30s"
+
+  # jobs created before max_age are rejected
+  max_age = "10m"
+  timeout = "5m"
+
+  # jobs audited into the file, remote_job.log under the install dir if empty
+  audit_log = ""
+
 ################################################
 # DCA configure
 ################################################
@@ -65,7 +155,7 @@ ulimit = 64000
   #
   # reftab configures
   #
-  # Reftab remote HTTP URL(https/http)
+  # Reftab remote HTTP URL(https/http) of JSON or CSV, or dataway:// to pull from dataway
   refer_table_url = ""
 
   # How often reftab sync the remote
@@ -76,6 +166,20 @@ ulimit = 64000
   # or use pure memory to cache the reftab data
   sqlite_mem_mode = false
 
+  # How often metrics emitted by emit_metric() flushed
+  emit_metric_interval = "1m"
+
+  # max series of metrics emitted by emit_metric() within an interval
+  emit_metric_max_series = 10000
+
+  # max entries of the cache used by cache_set()/cache_get()
+  cache_max_entries = 10000
+
+  # timeout, TTL of cached GET responses and max concurrent requests of http_request()
+  http_request_timeout = "3s"
+  http_request_cache_ttl = "5m"
+  http_request_max_concurrency = 16
+
 ################################################
 # HTTP server(9529)
 ################################################
@@ -94,6 +198,9 @@ ulimit = 64000
   timeout = "30s"
   close_idle_connection = false
 
+  # Max size(in bytes) of request bodies decompressed(gzip/deflate/zstd), 64MiB if not set
+  # max_decompressed_size = 67108864
+
   #
   # RUM related: we should port these configures to RUM inputs(TODO)
   #
@@ -132,6 +239,21 @@ ulimit = 64000
   # failed-data-point at specified interval.
   cache_clean_interval = "5s"
 
+  # Keep the most recent metric points, which can be scraped at /v1/federate
+  # in Prometheus text format. Series not updated within ttl are removed.
+  enable_local_federate = false
+  local_federate_ttl = "5m"
+
+  # Aggregate high-frequency metric points of the measurement(glob allowed) with
+  # all the tags matched into one point per series every interval, aggregations
+  # can be mean/max/min/sum/last/count, default mean.
+  #[[io.downsampling]]
+  #  measurement = "statsd_*"
+  #  interval = "30s"
+  #  aggregations = ["mean", "max"]
+  #  [io.downsampling.tags]
+  #    env = "test"
+
   # Data point filter configures.
   # NOTE: Most of the time, you should use web-side filter, it's a debug helper for developers.
   #[io.filters]
@@ -164,6 +286,34 @@ ulimit = 64000
   # HTTP Proxy(IP:Port)
   http_proxy = ""
 
+  # Connect to Dataway on startup, and ping Dataway idle for ping_interval to keep
+  # the connection open, so the first upload after idle periods not pay for the
+  # DNS and TLS setup. ping_interval should be less than 90s(idle connection timeout).
+  #warm_up = true
+  #ping_interval = "30s"
+
+  # Max size in bytes of (decompressed) responses from Dataway, such as log filter
+  # rules and pulled pipelines. Responses are requested in gzip. Default 32MB.
+  #max_response_size = 33554432
+
+  # Timestamp precision(s/ms/us/ns) of points uploaded, default ns. Truncating
+  # timestamps reduces the payload size, only allowed on metric/logging/object/
+  # custom_object/keyevent.
+  #[dataway.precision]
+  #  metric = "s"
+  #  logging = "ms"
+
+  # Checksum(crc32/xxhash) of the uncompressed body sent in the header X-Body-Checksum,
+  # Dataway echoes it in the response to detect bodies corrupted by transparent proxies.
+  #checksum = "crc32"
+
+  # Extra HTTP headers of dataway requests, such as route or tenancy headers required
+  # by some gateways. Headers keyed by host of dataway URL override the category ones.
+  #[dataway.endpoint_headers."openway.guance.com"]
+  #  X-Some-Header = "some-value"
+  #[dataway.category_headers.logging]
+  #  X-Some-Header = "some-value"
+
   # Sinkers: DataKit are able to upload data point to multiple workspace
   #[[dataway.sinkers]]
   #  categories = [ "L/M/O/..." ]
@@ -176,6 +326,15 @@ ulimit = 64000
   #[[dataway.sinkers]]
   #  another sinker...
 
+  # Shadows: duplicate percentage of points to another dataway, maybe in protobuf
+  # encoding, to compare acceptance rates before migrating dataway or protocol.
+  # See metric datakit_io_dataway_shadow_point_total.
+  #[[dataway.shadows]]
+  #  categories = [ "L/M/O/..." ]
+  #  percentage = 10.0
+  #  encoding = "protobuf" # or line-protocol
+  #  url = "https//new-openway.guance.com?token=<YOUR-TOKEN>"
+
 ################################################
 # Datakit logging configure
 ################################################
@@ -220,6 +379,9 @@ ulimit = 64000
   # If enabled, every data point will add a tag with election_namespace = <your-election-namespace>
   enable_namespace_tag = false
 
+  # Observer mode: never campaign, only report who the leader is.
+  observer = false
+
   # Like global_host_tags, but only for data points that are remotely collected(such as MySQL/Nginx).
   [election.tags]
     #  project = "my-project"
@@ -274,4 +436,14 @@ ulimit = 64000
     # For formats Git and SSH, we need extra configures:
     ssh_private_key_path = ""
     ssh_private_key_password = ""
+
+    # Pin the repository at a tag or commit(commit take precedence), the branch is ignored if pinned.
+    #tag = "v1.0.0"
+    #commit = ""
+
+    # Verify the GPG signature of the pinned (annotated) tag or the HEAD commit with the armored
+    # public key ring, and verify all files against the sha256sum style manifest within the repo.
+    # Updates failed the verification are rolled back and not applied.
+    #gpg_key_ring_path = "/usr/local/datakit/data/pubring.asc"
+    #checksum_manifest = "SHA256SUMS"
 `
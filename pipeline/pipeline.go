@@ -26,6 +26,9 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ipdb"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ipdb/geoip"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ipdb/iploc"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plcache"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plhttp"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plmetric"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput/funcs"
 	plrefertable "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/refertable"
@@ -64,6 +67,13 @@ type PipelineCfg struct {
 	ReferTablePullInterval string            `toml:"refer_table_pull_interval"`
 	UseSQLite              bool              `toml:"use_sqlite"`
 	SQLiteMemMode          bool              `toml:"sqlite_mem_mode"`
+	EmitMetricInterval     string            `toml:"emit_metric_interval"`
+	EmitMetricMaxSeries    int               `toml:"emit_metric_max_series"`
+	CacheMaxEntries        int               `toml:"cache_max_entries"`
+
+	HTTPRequestTimeout        string `toml:"http_request_timeout"`
+	HTTPRequestCacheTTL       string `toml:"http_request_cache_ttl"`
+	HTTPRequestMaxConcurrency int    `toml:"http_request_max_concurrency"`
 }
 
 func NewPipelineFromFile(category string, path string) (*Pipeline, error) {
@@ -141,6 +151,42 @@ func (p *Pipeline) Run(pt *point.Point, plOpt *plscript.Option, ioPtOpt *point.P
 	}
 }
 
+// RunTrace runs the pipeline like Run, and returns the step-by-step trace
+// of the script for debugging.
+func (p *Pipeline) RunTrace(pt *point.Point, plOpt *plscript.Option, ioPtOpt *point.PointOption, signal plruntime.Signal,
+) (*point.Point, bool, *plscript.Trace, error) {
+	if p.Script == nil || p.Script.Engine() == nil {
+		return nil, false, nil, fmt.Errorf("pipeline engine not initialized")
+	}
+
+	if pt == nil {
+		return nil, false, nil, fmt.Errorf("no data")
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	plpt := ptinput.InitPt(&ptinput.Point{}, pt.Name(), pt.Tags(), fields, ioPtOpt.Time)
+
+	trace, err := p.Script.RunTrace(plpt, signal, plOpt)
+	if err != nil {
+		return nil, false, trace, err
+	}
+
+	if !plpt.Time.IsZero() {
+		ioPtOpt.Time = plpt.Time
+	}
+
+	res, err := point.NewPoint(plpt.Name, plpt.Tags, plpt.Fields, ioPtOpt)
+	if err != nil {
+		return nil, false, trace, err
+	}
+
+	return res, plpt.Drop, trace, nil
+}
+
 func Init(pipelineCfg *PipelineCfg) error {
 	l = logger.SLogger("pipeline")
 	plscript.InitStore()
@@ -164,6 +210,14 @@ func Init(pipelineCfg *PipelineCfg) error {
 		}
 	}
 
+	plmetric.Setup(parseDurationOr(pipelineCfg.EmitMetricInterval, plmetric.DefaultInterval),
+		pipelineCfg.EmitMetricMaxSeries)
+	plcache.Setup(pipelineCfg.CacheMaxEntries)
+
+	plhttp.Setup(parseDurationOr(pipelineCfg.HTTPRequestTimeout, plhttp.DefaultTimeout),
+		parseDurationOr(pipelineCfg.HTTPRequestCacheTTL, plhttp.DefaultCacheTTL),
+		pipelineCfg.HTTPRequestMaxConcurrency)
+
 	if err := loadPatterns(); err != nil {
 		return err
 	}
@@ -171,6 +225,21 @@ func Init(pipelineCfg *PipelineCfg) error {
 	return nil
 }
 
+// parseDurationOr parses the duration, def used if empty or invalid.
+func parseDurationOr(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	dur, err := time.ParseDuration(s)
+	if err != nil || dur <= 0 {
+		l.Warnf("invalid duration %q, use default %s", s, def)
+		return def
+	}
+
+	return dur
+}
+
 // InitIPdb init ipdb instance.
 func InitIPdb(pipelineCfg *PipelineCfg) (ipdb.IPdb, error) {
 	if pipelineCfg == nil {
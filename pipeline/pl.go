@@ -41,20 +41,19 @@ func RunPl(category string, pts []*point.Point, plOpt *plscript.Option, scriptMa
 	defer ptinput.PutPoint(plPt)
 
 	var ok bool
-	var script *plscript.PlScript
+	var scripts []*plscript.PlScript
 	for _, pt := range pts {
 		// 这里将清理 plPt 并填充 point 到 plPt,
 		// plPt 在函数运行结束后尽量放回对象池
-		script, plPt, ok = getScriptAndFillPlPt(category, pt, scriptMap, plPt)
+		scripts, plPt, ok = getScriptAndFillPlPt(category, pt, scriptMap, plPt)
 
-		if !ok || script == nil {
+		if !ok || len(scripts) == 0 {
 			ret = append(ret, pt)
 			continue
 		}
 
-		err := script.Run(plPt, nil, plOpt)
-		if err != nil {
-			l.Warn(err)
+		script, ran := runScriptChain(scripts, plPt, plOpt)
+		if !ran {
 			ret = append(ret, pt)
 			continue
 		}
@@ -77,8 +76,30 @@ func RunPl(category string, pts []*point.Point, plOpt *plscript.Option, scriptMa
 	return ret, nil
 }
 
+// runScriptChain runs scripts on plpt one by one in order, each stage works on
+// the result of the previous one. A failed stage is skipped and the chain goes
+// on, the chain stops once the point dropped. It returns the last script run,
+// and false if all stages failed.
+func runScriptChain(scripts []*plscript.PlScript, plpt *ptinput.Point, plOpt *plscript.Option) (*plscript.PlScript, bool) {
+	var last *plscript.PlScript
+
+	for _, script := range scripts {
+		if err := script.Run(plpt, nil, plOpt); err != nil {
+			l.Warnf("run script %s: %s", script.Name(), err)
+			continue
+		}
+
+		last = script
+		if plpt.Drop {
+			break
+		}
+	}
+
+	return last, last != nil
+}
+
 func getScriptAndFillPlPt(category string, pt *point.Point, scriptMap map[string]string, plpt *ptinput.Point) (
-	*plscript.PlScript, *ptinput.Point, bool,
+	[]*plscript.PlScript, *ptinput.Point, bool,
 ) {
 	if pt == nil {
 		return nil, plpt, false
@@ -102,7 +123,7 @@ func getScriptAndFillPlPt(category string, pt *point.Point, scriptMap map[string
 		if !ok {
 			break
 		}
-		if s, ok := plscript.QueryScript(category, scriptName); ok {
+		if s, ok := queryScriptChain(category, scriptName); ok {
 			fields, err := pt.Fields()
 			if err != nil {
 				l.Errorf("Fields: %s", err)
@@ -120,7 +141,7 @@ func getScriptAndFillPlPt(category string, pt *point.Point, scriptMap map[string
 			break
 		}
 		// 未查询到脚本时条过解析 Point
-		s, ok := plscript.QueryScript(category, scriptName)
+		s, ok := queryScriptChain(category, scriptName)
 		if !ok {
 			break
 		}
@@ -140,6 +161,32 @@ func getScriptAndFillPlPt(category string, pt *point.Point, scriptMap map[string
 	return nil, plpt, false
 }
 
+// scriptChainSep separates scripts chained for a source, such as
+// `nginx.p,geo.p,scrub.p`.
+const scriptChainSep = ","
+
+// queryScriptChain returns scripts of the chain in the order declared. Each
+// stage is queried from the store separately, so that it's reloaded on its
+// own, and a stage not found is skipped.
+func queryScriptChain(category, name string) ([]*plscript.PlScript, bool) {
+	var scripts []*plscript.PlScript
+
+	for _, sName := range strings.Split(name, scriptChainSep) {
+		sName = strings.TrimSpace(sName)
+		if sName == "" {
+			continue
+		}
+
+		if s, ok := plscript.QueryScript(category, sName); ok {
+			scripts = append(scripts, s)
+		} else {
+			l.Debugf("script %s of chain %q not found, skipped", sName, name)
+		}
+	}
+
+	return scripts, len(scripts) > 0
+}
+
 func scriptName(category string, name string, tags map[string]string, fields map[string]interface{},
 	scriptMap map[string]string,
 ) (string, bool) {
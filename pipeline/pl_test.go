@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	plscript "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/script"
 )
 
 var scheckTestPointData = []byte(`0144-crontab,category=system,host=localhost.localdomain,level=warn,` +
@@ -34,6 +35,93 @@ action,t1=tag1,t2=tag2 f1=1.0,f2=2i,f3="abc"`)
 func TestRunPl(t *testing.T) {
 }
 
+func TestRunPlChain(t *testing.T) {
+	ns := plscript.GitRepoScriptNS
+	plscript.CleanAllScript(ns)
+	t.Cleanup(func() { plscript.CleanAllScript(ns) })
+
+	plscript.LoadScript(datakit.Logging, ns, map[string]string{
+		"parse.p":  `add_key(stage, "parse")`,
+		"enrich.p": `add_key(stage, stage + ",enrich")`,
+		"scrub.p":  `drop_key(secret)`,
+		"drop.p":   `drop()`,
+		"bad.p":    `add_key(n, 1 + "a")`,
+	}, nil)
+
+	newPts := func() []*point.Point {
+		pt, err := point.NewPoint("src", nil,
+			map[string]interface{}{"message": "hello", "secret": "abc"},
+			&point.PointOption{Category: datakit.Logging})
+		assert.NoError(t, err)
+		return []*point.Point{pt}
+	}
+
+	cases := []struct {
+		name   string
+		chain  string
+		expect map[string]interface{} // nil for dropped
+	}{
+		{
+			name:  "ordered",
+			chain: "parse.p, enrich.p,scrub.p",
+			expect: map[string]interface{}{
+				"stage": "parse,enrich",
+			},
+		},
+		{
+			name:  "stage-not-found",
+			chain: "parse.p,not-exist.p,enrich.p",
+			expect: map[string]interface{}{
+				"stage":  "parse,enrich",
+				"secret": "abc",
+			},
+		},
+		{
+			name:  "stage-failed",
+			chain: "parse.p,bad.p,enrich.p",
+			expect: map[string]interface{}{
+				"stage":  "parse,enrich",
+				"secret": "abc",
+			},
+		},
+		{
+			name:  "dropped",
+			chain: "parse.p,drop.p,enrich.p",
+		},
+		{
+			name:  "single",
+			chain: "scrub.p",
+			expect: map[string]interface{}{
+				"message": "hello",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pts, err := RunPl(datakit.Logging, newPts(), nil, map[string]string{"src": tc.chain})
+			assert.NoError(t, err)
+
+			if tc.expect == nil {
+				assert.Len(t, pts, 0)
+				return
+			}
+
+			assert.Len(t, pts, 1)
+			fields, err := pts[0].Fields()
+			assert.NoError(t, err)
+
+			for k, v := range tc.expect {
+				assert.Equal(t, v, fields[k], k)
+			}
+
+			if _, ok := tc.expect["secret"]; !ok {
+				assert.NotContains(t, fields, "secret")
+			}
+		})
+	}
+}
+
 func TestSCriptName(t *testing.T) {
 	pt, err := point.NewPoint("m_name",
 		map[string]string{"service": "svc_name"},
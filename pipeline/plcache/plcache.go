@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package plcache is a key-value cache shared by pipeline scripts, values are
+// kept across points with TTL, such as the start time of a request seen in
+// one log to calculate its duration when the end log arrives.
+package plcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+)
+
+const (
+	DefaultTTL        = time.Minute
+	DefaultMaxEntries = 10000
+)
+
+type entry struct {
+	key      string
+	value    any
+	dtype    ast.DType
+	expireAt time.Time
+}
+
+// Cache is a LRU cache with TTL of each entry, least recently used entries
+// are evicted if the cache is full.
+type Cache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	lru        *list.List // front is the most recently used
+	entries    map[string]*list.Element
+
+	now func() time.Time
+}
+
+func NewCache(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &Cache{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+// Set sets value of the key expired after ttl, DefaultTTL used if ttl <= 0.
+func (c *Cache) Set(key string, value any, dtype ast.DType, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	expireAt := c.now().Add(ttl)
+
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.value, e.dtype, e.expireAt = value, dtype, expireAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	for c.lru.Len() >= c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	c.entries[key] = c.lru.PushFront(&entry{
+		key:      key,
+		value:    value,
+		dtype:    dtype,
+		expireAt: expireAt,
+	})
+}
+
+// Get returns value of the key, false if not found or expired.
+func (c *Cache) Get(key string) (any, ast.DType, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, ast.Nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expireAt) {
+		c.remove(elem)
+		return nil, ast.Nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return e.value, e.dtype, true
+}
+
+// Len returns count of entries, including the expired but not evicted.
+func (c *Cache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
+
+var defaultCache = NewCache(DefaultMaxEntries)
+
+// Setup resets the default cache with max entries.
+func Setup(maxEntries int) {
+	defaultCache = NewCache(maxEntries)
+}
+
+// Set sets value of the key in the default cache.
+func Set(key string, value any, dtype ast.DType, ttl time.Duration) {
+	defaultCache.Set(key, value, dtype, ttl)
+}
+
+// Get returns value of the key in the default cache.
+func Get(key string) (any, ast.DType, bool) {
+	return defaultCache.Get(key)
+}
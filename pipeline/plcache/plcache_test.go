@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package plcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("ttl", func(t *testing.T) {
+		now := time.Now()
+
+		c := NewCache(0)
+		c.now = func() time.Time { return now }
+
+		c.Set("a", int64(1), ast.Int, time.Second)
+		c.Set("b", "x", ast.String, 0)
+
+		v, dtype, ok := c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, int64(1), v)
+		assert.Equal(t, ast.Int, dtype)
+
+		now = now.Add(time.Second)
+
+		_, _, ok = c.Get("a")
+		assert.False(t, ok, "a should be expired")
+		assert.Equal(t, 1, c.Len(), "expired a should be removed")
+
+		v, _, ok = c.Get("b")
+		assert.True(t, ok, "b expired after DefaultTTL")
+		assert.Equal(t, "x", v)
+
+		// overwrite restarts the expiration
+		now = now.Add(DefaultTTL - time.Second)
+		c.Set("b", "y", ast.String, 0)
+		now = now.Add(time.Second)
+
+		v, _, ok = c.Get("b")
+		assert.True(t, ok)
+		assert.Equal(t, "y", v)
+	})
+
+	t.Run("max-entries", func(t *testing.T) {
+		c := NewCache(3)
+
+		for i := 0; i < 3; i++ {
+			c.Set(fmt.Sprintf("k%d", i), int64(i), ast.Int, time.Minute)
+		}
+
+		// k0 used recently, k1 is evicted
+		_, _, ok := c.Get("k0")
+		assert.True(t, ok)
+
+		c.Set("k3", int64(3), ast.Int, time.Minute)
+		assert.Equal(t, 3, c.Len())
+
+		for k, expected := range map[string]bool{"k0": true, "k1": false, "k2": true, "k3": true} {
+			_, _, ok := c.Get(k)
+			assert.Equal(t, expected, ok, "key %s", k)
+		}
+	})
+}
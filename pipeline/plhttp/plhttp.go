@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package plhttp sends HTTP requests of pipeline scripts, such as querying
+// CMDB to enrich logs, responses of GET are cached within TTL.
+package plhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plcache"
+)
+
+const (
+	DefaultTimeout        = 3 * time.Second
+	DefaultCacheTTL       = 5 * time.Minute
+	DefaultMaxConcurrency = 16
+
+	maxCacheEntries = 10000
+	maxBodySize     = 1 << 20
+)
+
+var ErrTooManyRequests = errors.New("too many concurrent requests")
+
+// Response is the status code and body of the request.
+type Response struct {
+	StatusCode int
+	Body       string
+}
+
+// Client sends requests with timeout and concurrency limited.
+type Client struct {
+	cli      *http.Client
+	timeout  time.Duration
+	cacheTTL time.Duration
+	sem      chan struct{}
+	cache    *plcache.Cache
+}
+
+func NewClient(timeout, cacheTTL time.Duration, maxConcurrency int) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+
+	return &Client{
+		cli:      &http.Client{Timeout: timeout},
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		sem:      make(chan struct{}, maxConcurrency),
+		cache:    plcache.NewCache(maxCacheEntries),
+	}
+}
+
+func cacheKey(method, url string, headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(method + " " + url)
+	for _, k := range keys {
+		sb.WriteString("\x00" + k + ":" + headers[k])
+	}
+
+	return sb.String()
+}
+
+// Do sends the request, responses of GET with status code < 500 are cached.
+func (c *Client) Do(method, url, body string, headers map[string]string) (*Response, error) {
+	method = strings.ToUpper(method)
+	cacheable := method == http.MethodGet
+
+	key := cacheKey(method, url, headers)
+	if cacheable {
+		// dtype unused, the cache is only accessed here
+		if v, _, ok := c.cache.Get(key); ok {
+			return v.(*Response), nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	select {
+	case c.sem <- struct{}{}:
+		defer func() { <-c.sem }()
+	case <-ctx.Done():
+		return nil, ErrTooManyRequests
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxBodySize {
+		return nil, fmt.Errorf("response body exceeds %d bytes", maxBodySize)
+	}
+
+	res := &Response{StatusCode: resp.StatusCode, Body: string(data)}
+	if cacheable && resp.StatusCode < http.StatusInternalServerError {
+		c.cache.Set(key, res, ast.Invalid, c.cacheTTL)
+	}
+
+	return res, nil
+}
+
+var defaultClient = NewClient(DefaultTimeout, DefaultCacheTTL, DefaultMaxConcurrency)
+
+// Setup resets the default client with timeout, TTL of cached responses
+// and max concurrent requests.
+func Setup(timeout, cacheTTL time.Duration, maxConcurrency int) {
+	defaultClient = NewClient(timeout, cacheTTL, maxConcurrency)
+}
+
+// Do sends the request by the default client.
+func Do(method, url, body string, headers map[string]string) (*Response, error) {
+	return defaultClient.Do(method, url, body, headers)
+}
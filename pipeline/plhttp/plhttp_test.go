@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package plhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var hits int64
+	block := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+
+		switch r.URL.Path {
+		case "/block":
+			<-block
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", maxBodySize+1)))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write([]byte(r.Method + " " + r.Header.Get("X-Token") + " " + string(body)))
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	t.Run("cache-get", func(t *testing.T) {
+		atomic.StoreInt64(&hits, 0)
+		c := NewClient(time.Second, time.Minute, 0)
+
+		for i := 0; i < 3; i++ {
+			resp, err := c.Do("get", srv.URL+"/a", "", map[string]string{"X-Token": "t1"})
+			require.NoError(t, err)
+			assert.Equal(t, &Response{StatusCode: 200, Body: "GET t1 "}, resp)
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+
+		// different headers not cached
+		resp, err := c.Do("GET", srv.URL+"/a", "", map[string]string{"X-Token": "t2"})
+		require.NoError(t, err)
+		assert.Equal(t, "GET t2 ", resp.Body)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&hits))
+	})
+
+	t.Run("no-cache", func(t *testing.T) {
+		atomic.StoreInt64(&hits, 0)
+		c := NewClient(time.Second, time.Minute, 0)
+
+		for i := 0; i < 2; i++ {
+			resp, err := c.Do("POST", srv.URL+"/a", "data", nil)
+			require.NoError(t, err)
+			assert.Equal(t, "POST  data", resp.Body)
+
+			resp, err = c.Do("GET", srv.URL+"/error", "", nil)
+			require.NoError(t, err)
+			assert.Equal(t, 500, resp.StatusCode)
+		}
+		assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+	})
+
+	t.Run("large-body", func(t *testing.T) {
+		c := NewClient(time.Second, time.Minute, 0)
+
+		_, err := c.Do("GET", srv.URL+"/large", "", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		c := NewClient(100*time.Millisecond, time.Minute, 1)
+
+		c.sem <- struct{}{} // occupied by others
+		_, err := c.Do("GET", srv.URL+"/a", "", nil)
+		assert.ErrorIs(t, err, ErrTooManyRequests)
+		<-c.sem
+
+		_, err = c.Do("GET", srv.URL+"/block", "", nil)
+		assert.Error(t, err, "should be timeout")
+	})
+}
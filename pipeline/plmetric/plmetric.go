@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package plmetric aggregates metrics emitted by pipeline scripts, such as
+// counts of status codes and latency percentiles of logs, they are flushed
+// as metric points every interval.
+package plmetric
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+// Types of metrics.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultMaxSeries = 10000
+
+	// samples kept of each histogram series to calculate percentiles.
+	maxSamples = 1024
+)
+
+var (
+	ErrTooManySeries = errors.New("too many series")
+
+	l = logger.DefaultSLogger("plmetric")
+)
+
+var percentiles = []struct {
+	field string
+	q     float64
+}{
+	{"p50", 0.50},
+	{"p90", 0.90},
+	{"p95", 0.95},
+	{"p99", 0.99},
+}
+
+// series is a metric of the name and tags within an interval.
+type series struct {
+	typ  string
+	name string
+	tags map[string]string
+
+	count         int64
+	sum, min, max float64
+	last          float64
+	samples       []float64
+}
+
+func (s *series) add(v float64) {
+	s.count++
+	s.sum += v
+	s.last = v
+
+	if s.count == 1 || v < s.min {
+		s.min = v
+	}
+	if s.count == 1 || v > s.max {
+		s.max = v
+	}
+
+	if s.typ != TypeHistogram {
+		return
+	}
+
+	// reservoir sampling
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, v)
+	} else if i := rand.Int63n(s.count); i < maxSamples { //nolint:gosec
+		s.samples[i] = v
+	}
+}
+
+// fields of the series:
+//
+//   - counter: value, the sum of values emitted
+//   - gauge: value, the last value emitted
+//   - histogram: count, sum, min, max, avg and percentiles p50/p90/p95/p99
+func (s *series) fields() map[string]interface{} {
+	switch s.typ {
+	case TypeCounter:
+		return map[string]interface{}{"value": s.sum}
+	case TypeGauge:
+		return map[string]interface{}{"value": s.last}
+	}
+
+	fields := map[string]interface{}{
+		"count": s.count,
+		"sum":   s.sum,
+		"min":   s.min,
+		"max":   s.max,
+		"avg":   s.sum / float64(s.count),
+	}
+
+	sort.Float64s(s.samples)
+	for _, p := range percentiles {
+		i := int(math.Ceil(p.q*float64(len(s.samples)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		fields[p.field] = s.samples[i]
+	}
+
+	return fields
+}
+
+// Aggregator aggregates emitted metrics per series, and flushes them every interval.
+type Aggregator struct {
+	mtx       sync.Mutex
+	interval  time.Duration
+	maxSeries int
+	series    map[string]*series
+	lastFlush time.Time
+}
+
+func NewAggregator(interval time.Duration, maxSeries int) *Aggregator {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	if maxSeries <= 0 {
+		maxSeries = DefaultMaxSeries
+	}
+
+	return &Aggregator{
+		interval:  interval,
+		maxSeries: maxSeries,
+		series:    map[string]*series{},
+		lastFlush: time.Now(),
+	}
+}
+
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString("\x00" + k + "=" + tags[k])
+	}
+
+	return sb.String()
+}
+
+// Emit adds value to the series of name and tags.
+func (a *Aggregator) Emit(typ, name string, value float64, tags map[string]string) error {
+	switch typ {
+	case TypeCounter, TypeGauge, TypeHistogram:
+	default:
+		return fmt.Errorf("unknown metric type %q", typ)
+	}
+
+	key := seriesKey(name, tags)
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	s, ok := a.series[key]
+	if !ok {
+		if len(a.series) >= a.maxSeries {
+			return ErrTooManySeries
+		}
+
+		s = &series{typ: typ, name: name, tags: tags}
+		a.series[key] = s
+	} else if s.typ != typ {
+		return fmt.Errorf("metric %s is %s, not %s", name, s.typ, typ)
+	}
+
+	s.add(value)
+	return nil
+}
+
+// Expire flushes the series if the interval passed since last flush.
+func (a *Aggregator) Expire(now time.Time) []*dkpt.Point {
+	a.mtx.Lock()
+	due := now.Sub(a.lastFlush) >= a.interval
+	a.mtx.Unlock()
+
+	if !due {
+		return nil
+	}
+
+	return a.Flush(now)
+}
+
+// Flush returns points of all series and resets them.
+func (a *Aggregator) Flush(now time.Time) []*dkpt.Point {
+	a.mtx.Lock()
+	arr := a.series
+	a.series = map[string]*series{}
+	a.lastFlush = now
+	a.mtx.Unlock()
+
+	pts := make([]*dkpt.Point, 0, len(arr))
+	for _, s := range arr {
+		pt, err := dkpt.NewPoint(s.name, s.tags, s.fields(), &dkpt.PointOption{
+			Time:     now,
+			Category: datakit.Metric,
+		})
+		if err != nil {
+			l.Warnf("make point of %s: %s, ignored", s.name, err)
+			continue
+		}
+
+		pts = append(pts, pt)
+	}
+
+	return pts
+}
+
+var defaultAggregator = NewAggregator(DefaultInterval, DefaultMaxSeries)
+
+// Setup resets the default aggregator with the flush interval and max series.
+func Setup(interval time.Duration, maxSeries int) {
+	l = logger.SLogger("plmetric")
+	defaultAggregator = NewAggregator(interval, maxSeries)
+}
+
+// Emit adds value to the series of the default aggregator.
+func Emit(typ, name string, value float64, tags map[string]string) error {
+	return defaultAggregator.Emit(typ, name, value, tags)
+}
+
+// Expire flushes the default aggregator if its interval passed.
+func Expire(now time.Time) []*dkpt.Point {
+	return defaultAggregator.Expire(now)
+}
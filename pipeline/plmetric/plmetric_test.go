@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package plmetric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator(t *testing.T) {
+	t.Run("counter-and-gauge", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 0)
+
+		for _, code := range []string{"200", "200", "500"} {
+			require.NoError(t, a.Emit(TypeCounter, "requests", 1, map[string]string{"status": code}))
+		}
+
+		require.NoError(t, a.Emit(TypeGauge, "queue", 3, nil))
+		require.NoError(t, a.Emit(TypeGauge, "queue", 7, nil))
+
+		now := time.Now()
+		pts := a.Flush(now)
+		require.Len(t, pts, 3)
+
+		res := map[string]interface{}{}
+		for _, pt := range pts {
+			fields, err := pt.Fields()
+			require.NoError(t, err)
+			assert.Equal(t, now.UnixNano(), pt.Time().UnixNano())
+
+			res[pt.Name()+pt.Tags()["status"]] = fields["value"]
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"requests200": 2.0,
+			"requests500": 1.0,
+			"queue":       7.0,
+		}, res)
+
+		assert.Empty(t, a.Flush(now), "series should be reset after flush")
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 0)
+
+		for i := 1; i <= 100; i++ {
+			require.NoError(t, a.Emit(TypeHistogram, "latency", float64(i), map[string]string{"path": "/"}))
+		}
+
+		pts := a.Flush(time.Now())
+		require.Len(t, pts, 1)
+
+		fields, err := pts[0].Fields()
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"count": int64(100),
+			"sum":   5050.0,
+			"min":   1.0,
+			"max":   100.0,
+			"avg":   50.5,
+			"p50":   50.0,
+			"p90":   90.0,
+			"p95":   95.0,
+			"p99":   99.0,
+		}, fields)
+	})
+
+	t.Run("histogram-sampled", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 0)
+
+		n := maxSamples * 4
+		for i := 0; i < n; i++ {
+			require.NoError(t, a.Emit(TypeHistogram, "latency", float64(i), nil))
+		}
+
+		pts := a.Flush(time.Now())
+		require.Len(t, pts, 1)
+
+		fields, err := pts[0].Fields()
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(n), fields["count"])
+		assert.Equal(t, float64(n-1), fields["max"])
+		assert.InDelta(t, float64(n)/2, fields["p50"], float64(n)/10)
+	})
+
+	t.Run("max-series", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 2)
+
+		assert.NoError(t, a.Emit(TypeCounter, "m", 1, map[string]string{"k": "1"}))
+		assert.NoError(t, a.Emit(TypeCounter, "m", 1, map[string]string{"k": "2"}))
+		assert.ErrorIs(t, a.Emit(TypeCounter, "m", 1, map[string]string{"k": "3"}), ErrTooManySeries)
+
+		// existing series still accepted
+		assert.NoError(t, a.Emit(TypeCounter, "m", 1, map[string]string{"k": "1"}))
+	})
+
+	t.Run("type-mismatch", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 0)
+
+		assert.NoError(t, a.Emit(TypeCounter, "m", 1, nil))
+		assert.Error(t, a.Emit(TypeGauge, "m", 1, nil))
+		assert.Error(t, a.Emit("summary", "x", 1, nil))
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		a := NewAggregator(time.Minute, 0)
+		start := a.lastFlush
+
+		require.NoError(t, a.Emit(TypeCounter, "m", 1, nil))
+
+		assert.Empty(t, a.Expire(start.Add(30*time.Second)))
+		assert.Len(t, a.Expire(start.Add(time.Minute)), 1)
+		assert.Empty(t, a.Expire(start.Add(2*time.Minute)))
+	})
+}
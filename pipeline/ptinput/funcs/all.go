@@ -68,6 +68,14 @@ var FuncsMap = map[string]runtime.FuncCall{
 	"decode":                Decode,
 	"sample":                Sample,
 	"url_parse":             URLParse,
+	"parse_journald":        ParseJournald,
+	"parse_evtx":            ParseEvtx,
+	"emit_metric":           EmitMetric,
+	"cache_get":             CacheGet,
+	"cache_set":             CacheSet,
+	"http_request":          HTTPRequest,
+	"decode_protobuf":       DecodeProtobuf,
+	"decode_avro":           DecodeAvro,
 	// disable
 	"json_all": JSONAll,
 }
@@ -122,7 +130,15 @@ var FuncsCheckMap = map[string]runtime.FuncCheck{
 	"match":                 MatchChecking,
 	"decode":                DecodeChecking,
 	"url_parse":             URLParseChecking,
+	"parse_journald":        ParseJournaldChecking,
+	"parse_evtx":            ParseEvtxChecking,
 	"sample":                SampleChecking,
+	"emit_metric":           EmitMetricChecking,
+	"cache_get":             CacheGetChecking,
+	"cache_set":             CacheSetChecking,
+	"http_request":          HTTPRequestChecking,
+	"decode_protobuf":       DecodeProtobufChecking,
+	"decode_avro":           DecodeAvroChecking,
 	// disable
 	"json_all": JSONAllChecking,
 }
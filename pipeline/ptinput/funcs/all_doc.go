@@ -67,6 +67,14 @@ var PipelineFunctionDocs = map[string]*PLDoc{
 	"xml()":                &xmlMarkdown,
 	"sample()":             &sampleMarkdown,
 	"url_parse()":          &urlParseMarkdown,
+	"parse_journald()":     &parseJournaldMarkdown,
+	"parse_evtx()":         &parseEvtxMarkdown,
+	"emit_metric()":        &emitMetricMarkdown,
+	"cache_get()":          &cacheGetMarkdown,
+	"cache_set()":          &cacheSetMarkdown,
+	"http_request()":       &httpRequestMarkdown,
+	"decode_protobuf()":    &decodeProtobufMarkdown,
+	"decode_avro()":        &decodeAvroMarkdown,
 	"timestamp()":          &timestampMarkdown,
 }
 
@@ -119,6 +127,14 @@ var PipelineFunctionDocsEN = map[string]*PLDoc{
 	"xml()":                &xmlMarkdownEN,
 	"sample()":             &sampleMarkdownEN,
 	"url_parse()":          &urlParseMarkdownEN,
+	"parse_journald()":     &parseJournaldMarkdownEN,
+	"parse_evtx()":         &parseEvtxMarkdownEN,
+	"emit_metric()":        &emitMetricMarkdownEN,
+	"cache_get()":          &cacheGetMarkdownEN,
+	"cache_set()":          &cacheSetMarkdownEN,
+	"http_request()":       &httpRequestMarkdownEN,
+	"decode_protobuf()":    &decodeProtobufMarkdownEN,
+	"decode_avro()":        &decodeAvroMarkdownEN,
 	"timestamp()":          &timestampMarkdownEN,
 }
 
@@ -270,6 +286,30 @@ var (
 
 	//go:embed md/kv_split.md
 	docKVSplit string
+
+	//go:embed md/parse_journald.md
+	docParseJournald string
+
+	//go:embed md/parse_evtx.md
+	docParseEvtx string
+
+	//go:embed md/emit_metric.md
+	docEmitMetric string
+
+	//go:embed md/cache_get.md
+	docCacheGet string
+
+	//go:embed md/cache_set.md
+	docCacheSet string
+
+	//go:embed md/http_request.md
+	docHTTPRequest string
+
+	//go:embed md/decode_protobuf.md
+	docDecodeProtobuf string
+
+	//go:embed md/decode_avro.md
+	docDecodeAvro string
 )
 
 const (
@@ -589,4 +629,60 @@ var (
 			langTagZhCN: {cRegExp},
 		},
 	}
+
+	parseJournaldMarkdown = PLDoc{
+		Doc: docParseJournald, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cEncodeDecode},
+		},
+	}
+
+	parseEvtxMarkdown = PLDoc{
+		Doc: docParseEvtx, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cXML, cEncodeDecode},
+		},
+	}
+
+	emitMetricMarkdown = PLDoc{
+		Doc: docEmitMetric, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cMeasurementOp},
+		},
+	}
+
+	cacheGetMarkdown = PLDoc{
+		Doc: docCacheGet, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cOther},
+		},
+	}
+
+	cacheSetMarkdown = PLDoc{
+		Doc: docCacheSet, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cOther},
+		},
+	}
+
+	httpRequestMarkdown = PLDoc{
+		Doc: docHTTPRequest, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cNetwork},
+		},
+	}
+
+	decodeProtobufMarkdown = PLDoc{
+		Doc: docDecodeProtobuf, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cEncodeDecode},
+		},
+	}
+
+	decodeAvroMarkdown = PLDoc{
+		Doc: docDecodeAvro, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagZhCN: {cEncodeDecode},
+		},
+	}
 )
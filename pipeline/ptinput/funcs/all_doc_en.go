@@ -157,6 +157,30 @@ var (
 
 	//go:embed md/kv_split.en.md
 	docKVSplitEN string
+
+	//go:embed md/parse_journald.en.md
+	docParseJournaldEN string
+
+	//go:embed md/parse_evtx.en.md
+	docParseEvtxEN string
+
+	//go:embed md/emit_metric.en.md
+	docEmitMetricEN string
+
+	//go:embed md/cache_get.en.md
+	docCacheGetEN string
+
+	//go:embed md/cache_set.en.md
+	docCacheSetEN string
+
+	//go:embed md/http_request.en.md
+	docHTTPRequestEN string
+
+	//go:embed md/decode_protobuf.en.md
+	docDecodeProtobufEN string
+
+	//go:embed md/decode_avro.en.md
+	docDecodeAvroEN string
 )
 
 const (
@@ -477,4 +501,60 @@ var (
 			langTagEnUS: {eRegExp},
 		},
 	}
+
+	parseJournaldMarkdownEN = PLDoc{
+		Doc: docParseJournaldEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eEncodeDecode},
+		},
+	}
+
+	parseEvtxMarkdownEN = PLDoc{
+		Doc: docParseEvtxEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eXML, eEncodeDecode},
+		},
+	}
+
+	emitMetricMarkdownEN = PLDoc{
+		Doc: docEmitMetricEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eMeasurementOp},
+		},
+	}
+
+	cacheGetMarkdownEN = PLDoc{
+		Doc: docCacheGetEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eOther},
+		},
+	}
+
+	cacheSetMarkdownEN = PLDoc{
+		Doc: docCacheSetEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eOther},
+		},
+	}
+
+	httpRequestMarkdownEN = PLDoc{
+		Doc: docHTTPRequestEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eNetwork},
+		},
+	}
+
+	decodeProtobufMarkdownEN = PLDoc{
+		Doc: docDecodeProtobufEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eEncodeDecode},
+		},
+	}
+
+	decodeAvroMarkdownEN = PLDoc{
+		Doc: docDecodeAvroEN, Deprecated: false,
+		FnCategory: map[string][]string{
+			langTagEnUS: {eEncodeDecode},
+		},
+	}
 )
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plcache"
+)
+
+func CacheGetChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+	return nil
+}
+
+// CacheGet returns value of the key in the pipeline cache, nil if not found or expired.
+func CacheGet(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	key, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[0])
+	if errR != nil {
+		return errR
+	}
+	if dtype != ast.String {
+		l.Debugf("cache key expects str, got %s", dtype)
+		ctx.Regs.ReturnAppend(nil, ast.Nil)
+		return nil
+	}
+
+	val, dtype, ok := plcache.Get(key.(string))
+	if !ok {
+		ctx.Regs.ReturnAppend(nil, ast.Nil)
+		return nil
+	}
+
+	ctx.Regs.ReturnAppend(val, dtype)
+	return nil
+}
+
+func CacheSetChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 3 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 or 3 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	if len(funcExpr.Param) == 3 {
+		switch funcExpr.Param[2].NodeType { //nolint:exhaustive
+		case ast.TypeIntegerLiteral:
+			if funcExpr.Param[2].IntegerLiteral.Val <= 0 {
+				return runtime.NewRunError(ctx, "param ttl expects positive int",
+					funcExpr.Param[2].StartPos())
+			}
+		case ast.TypeIdentifier, ast.TypeAttrExpr, ast.TypeCallExpr:
+		default:
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"param ttl expects IntegerLiteral, got %s", funcExpr.Param[2].NodeType),
+				funcExpr.Param[2].StartPos())
+		}
+	}
+
+	return nil
+}
+
+// CacheSet sets value of the key in the pipeline cache, expired after ttl seconds.
+func CacheSet(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 3 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 or 3 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	key, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[0])
+	if errR != nil {
+		return errR
+	}
+	if dtype != ast.String {
+		l.Debugf("cache key expects str, got %s", dtype)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	val, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[1])
+	if errR != nil {
+		return errR
+	}
+
+	// list and map are mutable, only values of basic types cached
+	switch dtype { //nolint:exhaustive
+	case ast.Bool, ast.Int, ast.Float, ast.String:
+	default:
+		l.Debugf("cache value expects bool, int, float or str, got %s", dtype)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	ttl := plcache.DefaultTTL
+	if len(funcExpr.Param) == 3 {
+		v, dt, errR := runtime.RunStmt(ctx, funcExpr.Param[2])
+		if errR != nil {
+			return errR
+		}
+
+		n, ok := v.(int64)
+		if !ok || dt != ast.Int || n <= 0 {
+			l.Debugf("cache ttl expects positive int, got %v", v)
+			ctx.Regs.ReturnAppend(false, ast.Bool)
+			return nil
+		}
+		ttl = time.Duration(n) * time.Second
+	}
+
+	plcache.Set(key.(string), val, dtype, ttl)
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
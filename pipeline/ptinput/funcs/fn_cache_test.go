@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plcache"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func TestCache(t *testing.T) {
+	cases := []struct {
+		name     string
+		pl       string
+		in       []string
+		fail     bool
+		expected []map[string]any // fields of each point
+	}{
+		{
+			name: "correlate",
+			pl: `json(_, req_id)
+json(_, event)
+json(_, time, ts)
+cast(ts, "int")
+
+if event == "start" {
+    ok = cache_set("req:" + req_id, ts, 300)
+    add_key(ok)
+} elif event == "end" {
+    start = cache_get("req:" + req_id)
+    if start != nil {
+        add_key(duration_ms, ts - start)
+    }
+}`,
+			in: []string{
+				`{"req_id": "abc", "event": "start", "time": 1680000000000}`,
+				`{"req_id": "xyz", "event": "end", "time": 1680000000100}`,
+				`{"req_id": "abc", "event": "end", "time": 1680000000250}`,
+			},
+			expected: []map[string]any{
+				{"ok": true},
+				{"duration_ms": nil},
+				{"duration_ms": int64(250)},
+			},
+		},
+
+		{
+			name: "types",
+			pl: `cache_set("b", true)
+cache_set("f", 1.5)
+cache_set("s", "x")
+add_key(ok, cache_set("l", [1, 2]))
+add_key(b, cache_get("b"))
+add_key(f, cache_get("f"))
+add_key(s, cache_get("s"))
+add_key(l, cache_get("l"))`,
+			in: []string{`{}`},
+			expected: []map[string]any{
+				{"ok": false, "b": true, "f": 1.5, "s": "x", "l": nil},
+			},
+		},
+
+		{
+			name: "invalid-ttl",
+			pl:   `cache_set("a", 1, 0)`,
+			fail: true,
+		},
+
+		{
+			name: "invalid-args",
+			pl:   `cache_get("a", "b")`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			plcache.Setup(0)
+
+			for i, in := range tc.in {
+				pt := ptinput.GetPoint()
+				ptinput.InitPt(pt, "test", nil, map[string]any{"message": in}, time.Now())
+
+				require.Nil(t, runScript(runner, pt))
+				for k, v := range tc.expected[i] {
+					assert.Equal(t, v, pt.Fields[k], "key %s of point %d", k, i)
+				}
+				ptinput.PutPoint(pt)
+			}
+		})
+	}
+}
@@ -0,0 +1,540 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+)
+
+func DecodeAvroChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if err := reindexFuncArgs(funcExpr, []string{
+		"schema", "key",
+	}, 1); err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.NamePos)
+	}
+
+	if funcExpr.Param[1] != nil {
+		if _, err := getKeyName(funcExpr.Param[1]); err != nil {
+			return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[1].StartPos())
+		}
+	}
+
+	// check inline schemas on compiling
+	if funcExpr.Param[0].NodeType == ast.TypeStringLiteral {
+		if s := funcExpr.Param[0].StringLiteral.Val; isInlineAvroSchema(s) {
+			if _, err := avroSchemaOf(s); err != nil {
+				return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[0].StartPos())
+			}
+		}
+	}
+
+	return nil
+}
+
+// DecodeAvro decodes the Avro binary encoded datum in the key(default _) by
+// the schema, fields of the record are added to the point.
+func DecodeAvro(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	v, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[0])
+	if errR != nil {
+		return errR
+	}
+
+	if dtype != ast.String {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"param schema expects str, got %s", dtype), funcExpr.Param[0].StartPos())
+	}
+
+	key := "_"
+	if funcExpr.Param[1] != nil {
+		var err error
+		if key, err = getKeyName(funcExpr.Param[1]); err != nil {
+			return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[1].StartPos())
+		}
+	}
+
+	cont, err := ctx.GetKeyConv2Str(key)
+	if err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	schema, err := avroSchemaOf(v.(string))
+	if err != nil {
+		l.Debugf("decode_avro: %s", err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	if schema.typ != avroRecord {
+		l.Debugf("decode_avro: expect record schema, got %s", schema.typ)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	d := &avroDecoder{buf: []byte(cont)}
+	val, err := d.decode(schema)
+	if err != nil {
+		l.Debugf("decode_avro: %s", err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	addDecodedFields(ctx, val.(map[string]any))
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
+
+const (
+	avroNull    = "null"
+	avroBoolean = "boolean"
+	avroInt     = "int"
+	avroLong    = "long"
+	avroFloat   = "float"
+	avroDouble  = "double"
+	avroBytes   = "bytes"
+	avroString  = "string"
+	avroRecord  = "record"
+	avroEnum    = "enum"
+	avroArray   = "array"
+	avroMap     = "map"
+	avroFixed   = "fixed"
+	avroUnion   = "union"
+)
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// avroSchema is the parsed Avro schema, logical types are decoded as their
+// underlying types.
+type avroSchema struct {
+	typ string
+
+	fields  []*avroField  // record
+	symbols []string      // enum
+	items   *avroSchema   // array and map(values)
+	size    int           // fixed
+	types   []*avroSchema // union
+}
+
+// isInlineAvroSchema checks if the schema is JSON, otherwise it's a file.
+func isInlineAvroSchema(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") || strings.HasPrefix(s, `"`)
+}
+
+var inlineAvroSchemas sync.Map // JSON -> *avroSchema
+
+// avroSchemaOf parses the schema in JSON or loads it from the file.
+func avroSchemaOf(s string) (*avroSchema, error) {
+	if !isInlineAvroSchema(s) {
+		x, err := loadSchemaFile(s, func(data []byte) (any, error) {
+			return parseAvroSchema(data)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return x.(*avroSchema), nil
+	}
+
+	if x, ok := inlineAvroSchemas.Load(s); ok {
+		return x.(*avroSchema), nil
+	}
+
+	schema, err := parseAvroSchema([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+
+	inlineAvroSchemas.Store(s, schema)
+	return schema, nil
+}
+
+func parseAvroSchema(data []byte) (*avroSchema, error) {
+	var x any
+	if err := json.Unmarshal(data, &x); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+
+	p := &avroSchemaParser{named: map[string]*avroSchema{}}
+	s, err := p.parse(x, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// values of recursive records without a null branch, an array or a map
+	// to stop are infinite, decoding them never ends
+	for _, name := range p.records {
+		if !avroTerminable(p.named[name], map[*avroSchema]bool{}) {
+			return nil, fmt.Errorf("record %s can not terminate", name)
+		}
+	}
+
+	return s, nil
+}
+
+type avroSchemaParser struct {
+	named   map[string]*avroSchema // named types(record, enum and fixed) by full name
+	records []string               // full names of records in order
+}
+
+// avroTerminable checks if the schema has finite values, records being
+// checked(visiting) are not.
+func avroTerminable(s *avroSchema, visiting map[*avroSchema]bool) bool {
+	switch s.typ {
+	case avroUnion:
+		for _, t := range s.types {
+			if avroTerminable(t, visiting) {
+				return true
+			}
+		}
+		return false
+
+	case avroRecord:
+		if visiting[s] {
+			return false
+		}
+
+		visiting[s] = true
+		defer delete(visiting, s)
+
+		for _, f := range s.fields {
+			if !avroTerminable(f.schema, visiting) {
+				return false
+			}
+		}
+		return true
+
+	default: // arrays and maps may be empty
+		return true
+	}
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *avroSchemaParser) parse(x any, namespace string) (*avroSchema, error) {
+	switch v := x.(type) {
+	case string:
+		switch v {
+		case avroNull, avroBoolean, avroInt, avroLong, avroFloat, avroDouble, avroBytes, avroString:
+			return &avroSchema{typ: v}, nil
+		}
+
+		if s, ok := p.named[avroFullName(v, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[v]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type %q", v)
+
+	case []any:
+		s := &avroSchema{typ: avroUnion}
+		for _, t := range v {
+			ts, err := p.parse(t, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.types = append(s.types, ts)
+		}
+		return s, nil
+
+	case map[string]any:
+		return p.parseComplex(v, namespace)
+
+	default:
+		return nil, fmt.Errorf("invalid Avro schema %v", x)
+	}
+}
+
+func (p *avroSchemaParser) parseComplex(v map[string]any, namespace string) (*avroSchema, error) {
+	typ, _ := v["type"].(string)
+
+	// named types
+	name, _ := v["name"].(string)
+	if ns, ok := v["namespace"].(string); ok {
+		namespace = ns
+	}
+	if i := strings.LastIndex(name, "."); i > 0 {
+		namespace = name[:i]
+	}
+
+	s := &avroSchema{typ: typ}
+
+	switch typ {
+	case avroRecord, "error":
+		s.typ = avroRecord
+		if name == "" {
+			return nil, fmt.Errorf("record without name")
+		}
+		p.named[avroFullName(name, namespace)] = s // may be referenced by its fields
+		p.records = append(p.records, avroFullName(name, namespace))
+
+		fields, _ := v["fields"].([]any)
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid field of record %s", name)
+			}
+
+			fname, _ := fm["name"].(string)
+			fs, err := p.parse(fm["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", name, fname, err)
+			}
+			s.fields = append(s.fields, &avroField{name: fname, schema: fs})
+		}
+
+	case avroEnum:
+		syms, _ := v["symbols"].([]any)
+		for _, sym := range syms {
+			str, _ := sym.(string)
+			s.symbols = append(s.symbols, str)
+		}
+		p.named[avroFullName(name, namespace)] = s
+
+	case avroFixed:
+		size, _ := v["size"].(float64)
+		s.size = int(size)
+		p.named[avroFullName(name, namespace)] = s
+
+	case avroArray, avroMap:
+		key := "items"
+		if typ == avroMap {
+			key = "values"
+		}
+
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.items = items
+
+	default: // primitive types with attributes, such as logical types
+		return p.parse(typ, namespace)
+	}
+
+	return s, nil
+}
+
+var errAvroShortBuffer = errors.New("unexpected end of Avro data")
+
+// avroDecoder decodes the binary encoding of Avro, see
+// https://avro.apache.org/docs/1.11.1/specification/#binary-encoding.
+type avroDecoder struct {
+	buf   []byte
+	depth int // nesting of records, arrays and maps
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Varint(d.buf) // zig-zag encoded
+	if n <= 0 {
+		return 0, errAvroShortBuffer
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+func (d *avroDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf) {
+		return nil, errAvroShortBuffer
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt32 {
+		return nil, errAvroShortBuffer
+	}
+	return d.next(int(n))
+}
+
+// blockCount reads count of the next block of arrays and maps.
+func (d *avroDecoder) blockCount() (int64, error) {
+	n, err := d.long()
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 { // followed by size of the block in bytes
+		n = -n
+		if _, err := d.long(); err != nil {
+			return 0, err
+		}
+	}
+
+	// items take one byte at least(except nulls and empty records), more
+	// items than bytes left are corrupted data
+	if n > int64(len(d.buf)) {
+		return 0, errAvroShortBuffer
+	}
+
+	return n, nil
+}
+
+func (d *avroDecoder) decode(s *avroSchema) (any, error) {
+	switch s.typ {
+	case avroRecord, avroArray, avroMap:
+		if d.depth++; d.depth > maxDecodeDepth {
+			return nil, errDecodeTooDeep
+		}
+		defer func() { d.depth-- }()
+	}
+
+	switch s.typ {
+	case avroNull:
+		return nil, nil
+
+	case avroBoolean:
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+
+	case avroInt, avroLong:
+		return d.long()
+
+	case avroFloat:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+
+	case avroDouble:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+
+	case avroString:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+
+	case avroBytes:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+
+	case avroFixed:
+		b, err := d.next(s.size)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+
+	case avroEnum:
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("enum index %d out of range", i)
+		}
+		return s.symbols[i], nil
+
+	case avroUnion:
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.types)) {
+			return nil, fmt.Errorf("union index %d out of range", i)
+		}
+		return d.decode(s.types[i])
+
+	case avroRecord:
+		m := make(map[string]any, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.decode(f.schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+			m[f.name] = v
+		}
+		return m, nil
+
+	case avroArray:
+		arr := []any{}
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return arr, nil
+			}
+
+			for ; n > 0; n-- {
+				v, err := d.decode(s.items)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+		}
+
+	case avroMap:
+		m := map[string]any{}
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return m, nil
+			}
+
+			for ; n > 0; n-- {
+				k, err := d.bytes()
+				if err != nil {
+					return nil, err
+				}
+
+				v, err := d.decode(s.items)
+				if err != nil {
+					return nil, err
+				}
+				m[string(k)] = v
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported Avro type %q", s.typ)
+	}
+}
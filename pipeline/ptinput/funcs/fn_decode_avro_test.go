@@ -0,0 +1,238 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+const accessAvroSchema = `{
+  "type": "record",
+  "name": "Access",
+  "namespace": "web",
+  "fields": [
+    {"name": "host", "type": "string"},
+    {"name": "status", "type": "int"},
+    {"name": "user", "type": ["null", "string"]},
+    {"name": "cost", "type": "double"},
+    {"name": "ok", "type": "boolean"},
+    {"name": "method", "type": {"type": "enum", "name": "Method", "symbols": ["GET", "POST"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "headers", "type": {"type": "map", "values": "long"}},
+    {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "upstream", "type": ["null", {"type": "record", "name": "Upstream", "fields": [
+      {"name": "addr", "type": "string"},
+      {"name": "method", "type": "web.Method"}
+    ]}]}
+  ]
+}`
+
+type avroEncoder []byte
+
+func (e *avroEncoder) long(v int64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	*e = append(*e, buf[:binary.PutVarint(buf, v)]...)
+}
+
+func (e *avroEncoder) str(s string) {
+	e.long(int64(len(s)))
+	*e = append(*e, s...)
+}
+
+func encodeAccess() string {
+	e := &avroEncoder{}
+	e.str("web-01")
+	e.long(200)
+	e.long(1) // union index: string
+	e.str("alice")
+
+	cost := make([]byte, 8)
+	binary.LittleEndian.PutUint64(cost, math.Float64bits(0.25))
+	*e = append(*e, cost...)
+
+	*e = append(*e, 1) // true
+	e.long(1)          // POST
+
+	e.long(2) // array block of 2 items
+	e.str("a")
+	e.str("b")
+	e.long(0)
+
+	e.long(-1) // map block of 1 item, followed by block size
+	e.long(6)
+	e.str("x-len")
+	e.long(42)
+	e.long(0)
+
+	e.long(1680000000000)
+
+	e.long(1) // union index: Upstream
+	e.str("10.0.0.1:80")
+	e.long(0) // GET
+
+	return string(*e)
+}
+
+func TestDecodeAvro(t *testing.T) {
+	schemaFile := filepath.Join(t.TempDir(), "access.avsc")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(accessAvroSchema), 0o600))
+
+	expected := map[string]any{
+		"host":     "web-01",
+		"status":   int64(200),
+		"user":     "alice",
+		"cost":     0.25,
+		"ok":       true,
+		"method":   "POST",
+		"tags":     `["a","b"]`,
+		"headers":  `{"x-len":42}`,
+		"ts":       int64(1680000000000),
+		"upstream": `{"addr":"10.0.0.1:80","method":"GET"}`,
+	}
+
+	cases := []struct {
+		name     string
+		pl, in   string
+		expected map[string]any
+		ok       bool
+		fail     bool
+	}{
+		{
+			name:     "inline-schema",
+			pl:       `add_key(succ, decode_avro("""` + accessAvroSchema + `"""))`,
+			in:       encodeAccess(),
+			expected: expected,
+			ok:       true,
+		},
+
+		{
+			name:     "schema-file",
+			pl:       `add_key(succ, decode_avro("` + schemaFile + `"))`,
+			in:       encodeAccess(),
+			expected: expected,
+			ok:       true,
+		},
+
+		{
+			name: "named-key",
+			pl: `add_key(data, "\x02\x06abc")
+add_key(succ, decode_avro("""{"type": "record", "name": "R", "fields": [{"name": "v", "type": ["null", "string"]}]}""", key = data))`,
+			expected: map[string]any{"v": "abc"},
+			ok:       true,
+		},
+
+		{
+			name: "truncated",
+			pl:   `add_key(succ, decode_avro("` + schemaFile + `"))`,
+			in:   encodeAccess()[:10],
+		},
+
+		{
+			name: "not-record",
+			pl:   `add_key(succ, decode_avro("\"string\""))`,
+			in:   "\x06abc",
+		},
+
+		{
+			name: "invalid-inline-schema",
+			pl:   `decode_avro("""{"type": "record", "name": "R", "fields": [{"name": "v", "type": "no-such-type"}]}""")`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			pt := ptinput.GetPoint()
+			defer ptinput.PutPoint(pt)
+			ptinput.InitPt(pt, "test", nil, map[string]any{"message": tc.in}, time.Now())
+
+			require.Nil(t, runScript(runner, pt))
+
+			assert.Equal(t, tc.ok, pt.Fields["succ"])
+			for k, v := range tc.expected {
+				assert.Equal(t, v, pt.Fields[k], "key %s", k)
+			}
+		})
+	}
+}
+
+func TestParseAvroSchemaRecursive(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		fail   bool
+	}{
+		{
+			name:   "linked-list",
+			schema: `{"type": "record", "name": "Node", "fields": [{"name": "next", "type": ["null", "Node"]}]}`,
+		},
+
+		{
+			name:   "tree",
+			schema: `{"type": "record", "name": "Tree", "fields": [{"name": "children", "type": {"type": "array", "items": "Tree"}}]}`,
+		},
+
+		{
+			name:   "self",
+			schema: `{"type": "record", "name": "Node", "fields": [{"name": "next", "type": "Node"}]}`,
+			fail:   true,
+		},
+
+		{
+			name: "mutual",
+			schema: `{"type": "record", "name": "A", "fields": [{"name": "b", "type":
+  {"type": "record", "name": "B", "fields": [{"name": "a", "type": ["A"]}]}}]}`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseAvroSchema([]byte(tc.schema))
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDecodeAvroTooDeep(t *testing.T) {
+	s, err := parseAvroSchema([]byte(`{"type": "record", "name": "Node", "fields": [{"name": "next", "type": ["null", "Node"]}]}`))
+	require.NoError(t, err)
+
+	nodes := func(n int) []byte {
+		e := &avroEncoder{}
+		for i := 1; i < n; i++ {
+			e.long(1) // union index: Node
+		}
+		e.long(0) // null
+		return *e
+	}
+
+	_, err = (&avroDecoder{buf: nodes(maxDecodeDepth)}).decode(s)
+	assert.NoError(t, err)
+
+	_, err = (&avroDecoder{buf: nodes(maxDecodeDepth + 1)}).decode(s)
+	assert.ErrorIs(t, err, errDecodeTooDeep)
+}
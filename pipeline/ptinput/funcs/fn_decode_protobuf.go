@@ -0,0 +1,406 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func DecodeProtobufChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if err := reindexFuncArgs(funcExpr, []string{
+		"descriptor", "message", "key",
+	}, 2); err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.NamePos)
+	}
+
+	if funcExpr.Param[2] != nil {
+		if _, err := getKeyName(funcExpr.Param[2]); err != nil {
+			return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[2].StartPos())
+		}
+	}
+
+	return nil
+}
+
+// DecodeProtobuf decodes the protobuf message in the key(default _) by the
+// descriptor set file, fields of the message are added to the point.
+func DecodeProtobuf(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	var args [2]string
+	for i := range args {
+		v, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[i])
+		if errR != nil {
+			return errR
+		}
+
+		if dtype != ast.String {
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"param %s expects str, got %s", []string{"descriptor", "message"}[i], dtype),
+				funcExpr.Param[i].StartPos())
+		}
+		args[i] = v.(string)
+	}
+
+	key := "_"
+	if funcExpr.Param[2] != nil {
+		var err error
+		if key, err = getKeyName(funcExpr.Param[2]); err != nil {
+			return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[2].StartPos())
+		}
+	}
+
+	cont, err := ctx.GetKeyConv2Str(key)
+	if err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	md, err := protoMessageDescriptor(args[0], args[1])
+	if err != nil {
+		l.Debugf("decode_protobuf: %s", err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	fields, err := decodeProtoMessage(md, []byte(cont), 0)
+	if err != nil {
+		l.Debugf("decode_protobuf: decode %s: %s", md.FullName(), err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	addDecodedFields(ctx, fields)
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
+
+// addDecodedFields adds fields decoded from binary payloads to the point,
+// maps and lists are added as JSON strings.
+func addDecodedFields(ctx *runtime.Context, fields map[string]any) {
+	for k, v := range fields {
+		val, dtype := ast.DectDataType(v)
+		if err := addKey2PtWithVal(ctx.InData(), k, val, dtype, ptinput.KindPtDefault); err != nil {
+			l.Debug(err)
+		}
+	}
+}
+
+// schemaFile is a schema(descriptor set or Avro schema) loaded from file,
+// reloaded once the file modified.
+type schemaFile struct {
+	modTime time.Time
+	schema  any
+}
+
+var schemaFiles sync.Map // path -> *schemaFile
+
+// loadSchemaFile loads the file by parse, relative paths are under the
+// pipeline directory of datakit.
+func loadSchemaFile(path string, parse func([]byte) (any, error)) (any, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(datakit.PipelineDir, path)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if x, ok := schemaFiles.Load(path); ok {
+		if sf := x.(*schemaFile); sf.modTime.Equal(fi.ModTime()) {
+			return sf.schema, nil
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	schemaFiles.Store(path, &schemaFile{modTime: fi.ModTime(), schema: schema})
+	return schema, nil
+}
+
+// protoMessageDescriptor finds the message in the descriptor set file, which
+// is generated by protoc --include_imports --descriptor_set_out.
+func protoMessageDescriptor(path, message string) (protoreflect.MessageDescriptor, error) {
+	x, err := loadSchemaFile(path, func(data []byte) (any, error) {
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, err
+		}
+		return protodesc.NewFiles(&set)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := x.(*protoregistry.Files).FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, fmt.Errorf("message %s: %w", message, err)
+	}
+
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", message)
+	}
+
+	return md, nil
+}
+
+// maxDecodeDepth limits the nesting of decoded messages and records, same as
+// protobuf-go.
+const maxDecodeDepth = protowire.DefaultRecursionLimit
+
+var errDecodeTooDeep = fmt.Errorf("exceeds max nesting depth %d", maxDecodeDepth)
+
+// decodeProtoMessage decodes the wire format of the message, fields are
+// keyed by their names in the .proto file. Unknown fields are ignored.
+func decodeProtoMessage(md protoreflect.MessageDescriptor, b []byte, depth int) (map[string]any, error) {
+	if depth > maxDecodeDepth {
+		return nil, errDecodeTooDeep
+	}
+
+	res := map[string]any{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		fd := md.Fields().ByNumber(num)
+		if fd == nil {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		name := string(fd.Name())
+
+		switch {
+		case fd.IsMap():
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+
+			entry, err := decodeProtoMessage(fd.Message(), v, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			m, _ := res[name].(map[string]any)
+			if m == nil {
+				m = map[string]any{}
+				res[name] = m
+			}
+
+			mk := "" // the default value of absent key
+			if k, ok := entry[string(fd.MapKey().Name())]; ok {
+				mk = fmt.Sprint(k)
+			} else if fd.MapKey().Kind() != protoreflect.StringKind {
+				mk = "0"
+			}
+			m[mk] = entry[string(fd.MapValue().Name())]
+
+		case fd.IsList() && typ == protowire.BytesType && isProtoScalar(fd.Kind()): // packed
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+
+			arr, _ := res[name].([]any)
+			for len(v) > 0 {
+				x, n, err := decodeProtoValue(fd, protoScalarWireType(fd.Kind()), v, depth)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				v = v[n:]
+				arr = append(arr, x)
+			}
+			res[name] = arr
+
+		default:
+			x, n, err := decodeProtoValue(fd, typ, b, depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			b = b[n:]
+
+			if fd.IsList() {
+				arr, _ := res[name].([]any)
+				res[name] = append(arr, x)
+			} else {
+				res[name] = x
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func isProtoScalar(k protoreflect.Kind) bool {
+	switch k { //nolint:exhaustive
+	case protoreflect.StringKind, protoreflect.BytesKind,
+		protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	default:
+		return true
+	}
+}
+
+func protoScalarWireType(k protoreflect.Kind) protowire.Type {
+	switch k { //nolint:exhaustive
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return protowire.Fixed64Type
+	default:
+		return protowire.VarintType
+	}
+}
+
+// decodeProtoValue decodes one value of the field in the message at depth,
+// and returns the number of bytes consumed.
+func decodeProtoValue(fd protoreflect.FieldDescriptor, typ protowire.Type, b []byte, depth int) (any, int, error) {
+	kind := fd.Kind()
+
+	if kind == protoreflect.GroupKind {
+		if typ != protowire.StartGroupType {
+			return nil, 0, fmt.Errorf("unexpected wire type %d", typ)
+		}
+
+		v, n := protowire.ConsumeGroup(fd.Number(), b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+
+		m, err := decodeProtoMessage(fd.Message(), v, depth+1)
+		return m, n, err
+	}
+
+	if expect := protoScalarWireType(kind); isProtoScalar(kind) && typ != expect {
+		return nil, 0, fmt.Errorf("unexpected wire type %d, expect %d", typ, expect)
+	} else if !isProtoScalar(kind) && typ != protowire.BytesType {
+		return nil, 0, fmt.Errorf("unexpected wire type %d, expect %d", typ, protowire.BytesType)
+	}
+
+	switch typ { //nolint:exhaustive
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+
+		switch kind { //nolint:exhaustive
+		case protoreflect.BoolKind:
+			return v != 0, n, nil
+		case protoreflect.Int32Kind:
+			return int64(int32(v)), n, nil
+		case protoreflect.Sint32Kind:
+			return int64(int32(protowire.DecodeZigZag(v & math.MaxUint32))), n, nil
+		case protoreflect.Sint64Kind:
+			return protowire.DecodeZigZag(v), n, nil
+		case protoreflect.Uint32Kind:
+			return int64(uint32(v)), n, nil
+		case protoreflect.Uint64Kind:
+			return protoUint64(v), n, nil
+		case protoreflect.EnumKind:
+			if ev := fd.Enum().Values().ByNumber(protoreflect.EnumNumber(int32(v))); ev != nil {
+				return string(ev.Name()), n, nil
+			}
+			return int64(int32(v)), n, nil
+		default: // int64
+			return int64(v), n, nil
+		}
+
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+
+		switch kind { //nolint:exhaustive
+		case protoreflect.FloatKind:
+			return float64(math.Float32frombits(v)), n, nil
+		case protoreflect.Sfixed32Kind:
+			return int64(int32(v)), n, nil
+		default: // fixed32
+			return int64(v), n, nil
+		}
+
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+
+		switch kind { //nolint:exhaustive
+		case protoreflect.DoubleKind:
+			return math.Float64frombits(v), n, nil
+		case protoreflect.Sfixed64Kind:
+			return int64(v), n, nil
+		default: // fixed64
+			return protoUint64(v), n, nil
+		}
+
+	default: // bytes
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+
+		switch kind { //nolint:exhaustive
+		case protoreflect.StringKind:
+			return string(v), n, nil
+		case protoreflect.MessageKind:
+			m, err := decodeProtoMessage(fd.Message(), v, depth+1)
+			return m, n, err
+		default: // bytes
+			return base64.StdEncoding.EncodeToString(v), n, nil
+		}
+	}
+}
+
+// protoUint64 converts the uint64 to int64, values overflowing int64 are
+// kept in string, like the JSON mapping of protobuf.
+func protoUint64(v uint64) any {
+	if v > math.MaxInt64 {
+		return fmt.Sprint(v)
+	}
+	return int64(v)
+}
@@ -0,0 +1,288 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+// orderDescriptorSet is the descriptor set of:
+//
+//	syntax = "proto3";
+//	package shop;
+//	enum Status { UNKNOWN = 0; PAID = 1; }
+//	message Item { string name = 1; double price = 2; }
+//	message Order {
+//	  string id = 1;
+//	  int64 amount = 2;
+//	  repeated string tags = 3;
+//	  Status status = 4;
+//	  Item item = 5;
+//	  repeated int32 codes = 6;
+//	  map<string, int64> counts = 7;
+//	  sint32 delta = 8;
+//	  bytes raw = 9;
+//	  uint64 big = 10;
+//	}
+func orderDescriptorSet() *descriptorpb.FileDescriptorSet {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type,
+		label descriptorpb.FieldDescriptorProto_Label, typeName string,
+	) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	const (
+		opt = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		rep = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	)
+
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("order.proto"),
+			Package: proto.String("shop"),
+			Syntax:  proto.String("proto3"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+					{Name: proto.String("PAID"), Number: proto.Int32(1)},
+				},
+			}},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Item"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+						field("price", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, opt, ""),
+					},
+				},
+				{
+					Name: proto.String("Order"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+						field("amount", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, opt, ""),
+						field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, rep, ""),
+						field("status", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, opt, ".shop.Status"),
+						field("item", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, opt, ".shop.Item"),
+						field("codes", 6, descriptorpb.FieldDescriptorProto_TYPE_INT32, rep, ""),
+						field("counts", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, rep, ".shop.Order.CountsEntry"),
+						field("delta", 8, descriptorpb.FieldDescriptorProto_TYPE_SINT32, opt, ""),
+						field("raw", 9, descriptorpb.FieldDescriptorProto_TYPE_BYTES, opt, ""),
+						field("big", 10, descriptorpb.FieldDescriptorProto_TYPE_UINT64, opt, ""),
+					},
+					NestedType: []*descriptorpb.DescriptorProto{{
+						Name: proto.String("CountsEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+							field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, opt, ""),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					}},
+				},
+			},
+		}},
+	}
+}
+
+func encodeOrder() []byte {
+	var item []byte
+	item = protowire.AppendTag(item, 1, protowire.BytesType)
+	item = protowire.AppendString(item, "apple")
+	item = protowire.AppendTag(item, 2, protowire.Fixed64Type)
+	item = protowire.AppendFixed64(item, math.Float64bits(1.5))
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, "sku-1")
+	entry = protowire.AppendTag(entry, 2, protowire.VarintType)
+	entry = protowire.AppendVarint(entry, 3)
+
+	var codes []byte
+	for _, c := range []int32{200, -1} {
+		codes = protowire.AppendVarint(codes, uint64(int64(c)))
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "o-1")
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, 100)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "a")
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "b")
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	b = protowire.AppendTag(b, 5, protowire.BytesType)
+	b = protowire.AppendBytes(b, item)
+	b = protowire.AppendTag(b, 6, protowire.BytesType)
+	b = protowire.AppendBytes(b, codes)
+	b = protowire.AppendTag(b, 7, protowire.BytesType)
+	b = protowire.AppendBytes(b, entry)
+	b = protowire.AppendTag(b, 8, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeZigZag(-5))
+	b = protowire.AppendTag(b, 9, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{0xff, 0x00})
+	b = protowire.AppendTag(b, 10, protowire.VarintType)
+	b = protowire.AppendVarint(b, math.MaxUint64)
+	b = protowire.AppendTag(b, 99, protowire.VarintType) // unknown field
+	b = protowire.AppendVarint(b, 1)
+
+	return b
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	data, err := proto.Marshal(orderDescriptorSet())
+	require.NoError(t, err)
+
+	desc := filepath.Join(t.TempDir(), "order.desc")
+	require.NoError(t, os.WriteFile(desc, data, 0o600))
+
+	cases := []struct {
+		name     string
+		pl, in   string
+		expected map[string]any
+		ok       bool
+		fail     bool
+	}{
+		{
+			name: "order",
+			pl:   `add_key(ok, decode_protobuf("` + desc + `", "shop.Order"))`,
+			in:   string(encodeOrder()),
+			expected: map[string]any{
+				"id":     "o-1",
+				"amount": int64(100),
+				"tags":   `["a","b"]`,
+				"status": "PAID",
+				"item":   `{"name":"apple","price":1.5}`,
+				"codes":  `[200,-1]`,
+				"counts": `{"sku-1":3}`,
+				"delta":  int64(-5),
+				"raw":    "/wA=",
+				"big":    "18446744073709551615",
+			},
+			ok: true,
+		},
+
+		{
+			name: "named-key",
+			pl: `json(_, payload)
+b64dec(payload)
+add_key(ok, decode_protobuf("` + desc + `", "shop.Item", key = payload))`,
+			in: `{"payload": "CgVhcHBsZREAAAAAAAD4Pw=="}`,
+			expected: map[string]any{
+				"name":  "apple",
+				"price": 1.5,
+			},
+			ok: true,
+		},
+
+		{
+			name: "unknown-message",
+			pl:   `add_key(ok, decode_protobuf("` + desc + `", "shop.Nothing"))`,
+			in:   string(encodeOrder()),
+		},
+
+		{
+			name: "descriptor-not-found",
+			pl:   `add_key(ok, decode_protobuf("no-such.desc", "shop.Order"))`,
+			in:   string(encodeOrder()),
+		},
+
+		{
+			name: "corrupted",
+			pl:   `add_key(ok, decode_protobuf("` + desc + `", "shop.Order"))`,
+			in:   string(encodeOrder()[:20]),
+		},
+
+		{
+			name: "too-few-args",
+			pl:   `decode_protobuf("` + desc + `")`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			pt := ptinput.GetPoint()
+			defer ptinput.PutPoint(pt)
+			ptinput.InitPt(pt, "test", nil, map[string]any{"message": tc.in}, time.Now())
+
+			require.Nil(t, runScript(runner, pt))
+
+			assert.Equal(t, tc.ok, pt.Fields["ok"])
+			for k, v := range tc.expected {
+				assert.Equal(t, v, pt.Fields[k], "key %s", k)
+			}
+		})
+	}
+}
+
+func TestDecodeProtobufTooDeep(t *testing.T) {
+	// message Node { Node next = 1; }
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("node.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Node"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("next"),
+				JsonName: proto.String("next"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				TypeName: proto.String(".test.Node"),
+			}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	md := fd.Messages().ByName("Node")
+
+	nodes := func(n int) []byte {
+		var b []byte
+		for i := 1; i < n; i++ {
+			b = protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), b)
+		}
+		return b
+	}
+
+	_, err = decodeProtoMessage(md, nodes(maxDecodeDepth+1), 0)
+	assert.NoError(t, err)
+
+	_, err = decodeProtoMessage(md, nodes(maxDecodeDepth+2), 0)
+	assert.ErrorIs(t, err, errDecodeTooDeep)
+}
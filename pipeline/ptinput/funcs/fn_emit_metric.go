@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"fmt"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plmetric"
+)
+
+func EmitMetricChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 4 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 to 4 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	if len(funcExpr.Param) > 2 {
+		if funcExpr.Param[2].NodeType != ast.TypeListInitExpr {
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"param tags expects ListInitExpr, got %s", funcExpr.Param[2].NodeType),
+				funcExpr.Param[2].StartPos())
+		}
+
+		for _, tag := range funcExpr.Param[2].ListInitExpr.List {
+			if _, err := getKeyName(tag); err != nil {
+				return runtime.NewRunError(ctx, err.Error(), tag.StartPos())
+			}
+		}
+	}
+
+	if len(funcExpr.Param) > 3 {
+		if funcExpr.Param[3].NodeType != ast.TypeStringLiteral {
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"param type expects StringLiteral, got %s", funcExpr.Param[3].NodeType),
+				funcExpr.Param[3].StartPos())
+		}
+
+		switch typ := funcExpr.Param[3].StringLiteral.Val; typ {
+		case plmetric.TypeCounter, plmetric.TypeGauge, plmetric.TypeHistogram:
+		default:
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"unknown metric type %q, expects counter, gauge or histogram", typ),
+				funcExpr.Param[3].StartPos())
+		}
+	}
+
+	return nil
+}
+
+// EmitMetric adds value to the metric of name, tagged with values of the keys
+// in tags, metrics are aggregated and flushed as metric points periodically.
+func EmitMetric(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 4 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 to 4 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	name, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[0])
+	if errR != nil {
+		return errR
+	}
+	if dtype != ast.String || name == "" {
+		l.Debugf("metric name expects non-empty str, got %s", dtype)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	v, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[1])
+	if errR != nil {
+		return errR
+	}
+
+	var value float64
+	switch dtype { //nolint:exhaustive
+	case ast.Int:
+		value = float64(v.(int64))
+	case ast.Float:
+		value = v.(float64)
+	default:
+		l.Debugf("metric value expects int or float, got %s", dtype)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	tags := map[string]string{}
+	if len(funcExpr.Param) > 2 && funcExpr.Param[2].NodeType == ast.TypeListInitExpr {
+		for _, tag := range funcExpr.Param[2].ListInitExpr.List {
+			key, err := getKeyName(tag)
+			if err != nil {
+				return runtime.NewRunError(ctx, err.Error(), tag.StartPos())
+			}
+
+			if val, err := ctx.GetKeyConv2Str(key); err == nil {
+				tags[key] = val
+			}
+		}
+	}
+
+	typ := plmetric.TypeCounter
+	if len(funcExpr.Param) > 3 && funcExpr.Param[3].NodeType == ast.TypeStringLiteral {
+		typ = funcExpr.Param[3].StringLiteral.Val
+	}
+
+	if err := plmetric.Emit(typ, name.(string), value, tags); err != nil {
+		l.Debugf("emit metric %s: %s", name, err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plmetric"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func TestEmitMetric(t *testing.T) {
+	cases := []struct {
+		name     string
+		pl       string
+		in       []string
+		fail     bool
+		ok       bool
+		expected map[string]map[string]interface{} // measurement+tags -> fields
+	}{
+		{
+			name: "counter",
+			pl: `json(_, status)
+json(_, path)
+ok = emit_metric("nginx_requests", 1, ["status", path])
+add_key(ok)`,
+			in: []string{
+				`{"status": "200", "path": "/a"}`,
+				`{"status": "200", "path": "/a"}`,
+				`{"status": "500", "path": "/a"}`,
+			},
+			ok: true,
+			expected: map[string]map[string]interface{}{
+				"nginx_requests path=/a status=200": {"value": 2.0},
+				"nginx_requests path=/a status=500": {"value": 1.0},
+			},
+		},
+
+		{
+			name: "histogram",
+			pl: `json(_, latency)
+ok = emit_metric("nginx_latency", latency, [], "histogram")
+add_key(ok)`,
+			in: []string{`{"latency": 10}`, `{"latency": 30}`},
+			ok: true,
+			expected: map[string]map[string]interface{}{
+				"nginx_latency": {
+					"count": int64(2), "sum": 40.0, "min": 10.0, "max": 30.0, "avg": 20.0,
+					"p50": 10.0, "p90": 30.0, "p95": 30.0, "p99": 30.0,
+				},
+			},
+		},
+
+		{
+			name: "gauge-missing-tag",
+			pl: `json(_, size)
+ok = emit_metric("queue", size, ["not_exist"], "gauge")
+add_key(ok)`,
+			in: []string{`{"size": 3}`, `{"size": 5}`},
+			ok: true,
+			expected: map[string]map[string]interface{}{
+				"queue": {"value": 5.0},
+			},
+		},
+
+		{
+			name: "invalid-value",
+			pl: `ok = emit_metric("m", "abc")
+add_key(ok)`,
+			in:       []string{`{}`},
+			ok:       false,
+			expected: map[string]map[string]interface{}{},
+		},
+
+		{
+			name: "invalid-type",
+			pl:   `emit_metric("m", 1, [], "summary")`,
+			fail: true,
+		},
+
+		{
+			name: "invalid-tags",
+			pl:   `emit_metric("m", 1, "status")`,
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			plmetric.Setup(time.Minute, 0)
+
+			for _, in := range tc.in {
+				pt := ptinput.GetPoint()
+				ptinput.InitPt(pt, "test", nil, map[string]any{"message": in}, time.Now())
+
+				require.Nil(t, runScript(runner, pt))
+				assert.Equal(t, tc.ok, pt.Fields["ok"])
+				ptinput.PutPoint(pt)
+			}
+
+			res := map[string]map[string]interface{}{}
+			for _, pt := range plmetric.Expire(time.Now().Add(time.Minute)) {
+				fields, err := pt.Fields()
+				require.NoError(t, err)
+
+				key := pt.Name()
+				for _, k := range []string{"path", "status"} {
+					if v, ok := pt.Tags()[k]; ok {
+						key += " " + k + "=" + v
+					}
+				}
+				res[key] = fields
+			}
+
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"fmt"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plhttp"
+)
+
+func HTTPRequestChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 4 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 to 4 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	if funcExpr.Param[0].NodeType == ast.TypeStringLiteral {
+		switch funcExpr.Param[0].StringLiteral.Val {
+		case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			return runtime.NewRunError(ctx, fmt.Sprintf(
+				"unsupported method %q", funcExpr.Param[0].StringLiteral.Val),
+				funcExpr.Param[0].StartPos())
+		}
+	}
+
+	return nil
+}
+
+// HTTPRequest sends the HTTP request and returns its status code and body,
+// nil on failure.
+func HTTPRequest(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) < 2 || len(funcExpr.Param) > 4 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 2 to 4 args", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	var args [3]string
+	for i := 0; i < 3 && i < len(funcExpr.Param); i++ {
+		v, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[i])
+		if errR != nil {
+			return errR
+		}
+
+		if dtype != ast.String {
+			l.Debugf("param %d of %s expects str, got %s", i, funcExpr.Name, dtype)
+			ctx.Regs.ReturnAppend(nil, ast.Nil)
+			return nil
+		}
+		args[i] = v.(string)
+	}
+
+	headers := map[string]string{}
+	if len(funcExpr.Param) == 4 {
+		v, dtype, errR := runtime.RunStmt(ctx, funcExpr.Param[3])
+		if errR != nil {
+			return errR
+		}
+
+		m, ok := v.(map[string]any)
+		if !ok || dtype != ast.Map {
+			l.Debugf("headers of %s expects map, got %s", funcExpr.Name, dtype)
+			ctx.Regs.ReturnAppend(nil, ast.Nil)
+			return nil
+		}
+
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				headers[k] = s
+			}
+		}
+	}
+
+	resp, err := plhttp.Do(args[0], args[1], args[2], headers)
+	if err != nil {
+		l.Debugf("%s %s: %s", args[0], args[1], err)
+		ctx.Regs.ReturnAppend(nil, ast.Nil)
+		return nil
+	}
+
+	ctx.Regs.ReturnAppend(map[string]any{
+		"status_code": int64(resp.StatusCode),
+		"body":        resp.Body,
+	}, ast.Map)
+	return nil
+}
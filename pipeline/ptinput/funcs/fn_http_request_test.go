@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/plhttp"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func TestHTTPRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xxx" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/api/hosts/web-01" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"owner": "ops", "idc": "sh-01"}`))
+	}))
+	defer srv.Close()
+
+	enrich := `json(_, host)
+json(_, token)
+
+resp = http_request("GET", "` + srv.URL + `/api/hosts/" + host, "", {"Authorization": token})
+if resp != nil {
+    add_key(status_code, resp["status_code"])
+}
+if resp != nil && resp["status_code"] == 200 {
+    info = load_json(resp["body"])
+    add_key(owner, info["owner"])
+    add_key(idc, info["idc"])
+}`
+
+	cases := []struct {
+		name     string
+		pl, in   string
+		fail     bool
+		expected map[string]any
+	}{
+		{
+			name: "enrich",
+			pl:   enrich,
+			in:   `{"host": "web-01", "token": "Bearer xxx"}`,
+			expected: map[string]any{
+				"status_code": int64(200),
+				"owner":       "ops",
+				"idc":         "sh-01",
+			},
+		},
+
+		{
+			name: "not-found",
+			pl:   enrich,
+			in:   `{"host": "web-02", "token": "Bearer xxx"}`,
+			expected: map[string]any{
+				"status_code": int64(404),
+				"owner":       nil,
+			},
+		},
+
+		{
+			name: "unauthorized",
+			pl:   enrich,
+			in:   `{"host": "web-01", "token": "Bearer yyy"}`,
+			expected: map[string]any{
+				"status_code": int64(401),
+			},
+		},
+
+		{
+			name: "unreachable",
+			pl: `resp = http_request("GET", "http://127.0.0.1:0/")
+add_key(failed, resp == nil)`,
+			in: `{}`,
+			expected: map[string]any{
+				"failed": true,
+			},
+		},
+
+		{
+			name: "invalid-method",
+			pl:   `http_request("CONNECT", "http://localhost")`,
+			fail: true,
+		},
+
+		{
+			name: "invalid-args",
+			pl:   `http_request("GET")`,
+			fail: true,
+		},
+	}
+
+	plhttp.Setup(time.Second, time.Minute, 0)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			pt := ptinput.GetPoint()
+			defer ptinput.PutPoint(pt)
+			ptinput.InitPt(pt, "test", nil, map[string]any{"message": tc.in}, time.Now())
+
+			require.Nil(t, runScript(runner, pt))
+			for k, v := range tc.expected {
+				assert.Equal(t, v, pt.Fields[k], "key %s", k)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+// levels of Windows event, 0(LogAlways) ~ 5(Verbose).
+var evtxStatus = []string{"info", "critical", "error", "warning", "info", "debug"}
+
+// evtxEvent is the XML rendered Windows event, such as exported by wevtutil
+// or Get-WinEvent.
+type evtxEvent struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     string `xml:"EventID"`
+		Version     string `xml:"Version"`
+		Level       string `xml:"Level"`
+		Task        string `xml:"Task"`
+		Opcode      string `xml:"Opcode"`
+		Keywords    string `xml:"Keywords"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID string `xml:"EventRecordID"`
+		Correlation   struct {
+			ActivityID string `xml:"ActivityID,attr"`
+		} `xml:"Correlation"`
+		Execution struct {
+			ProcessID string `xml:"ProcessID,attr"`
+			ThreadID  string `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+		Channel  string `xml:"Channel"`
+		Computer string `xml:"Computer"`
+		Security struct {
+			UserID string `xml:"UserID,attr"`
+		} `xml:"Security"`
+	} `xml:"System"`
+
+	EventData struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Data"`
+	} `xml:"EventData"`
+
+	RenderingInfo struct {
+		Message string `xml:"Message"`
+	} `xml:"RenderingInfo"`
+}
+
+func ParseEvtxChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	if _, err := getKeyName(funcExpr.Param[0]); err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[0].StartPos())
+	}
+
+	return nil
+}
+
+// ParseEvtx parses an XML rendered Windows event, fields of System are added
+// to the point, EventData as event_data_<name>, rendered message as message,
+// Level as status and TimeCreated as time of the point.
+func ParseEvtx(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	key, err := getKeyName(funcExpr.Param[0])
+	if err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[0].StartPos())
+	}
+
+	cont, err := ctx.GetKeyConv2Str(key)
+	if err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	var ev evtxEvent
+	if err := xml.Unmarshal([]byte(cont), &ev); err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	sys := &ev.System
+	if sys.EventID == "" && sys.Provider.Name == "" {
+		l.Debug("not a Windows event: System/EventID and System/Provider not found")
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	in := ctx.InData()
+	setStr := func(k, v string) {
+		if v == "" {
+			return
+		}
+		if err := addKey2PtWithVal(in, k, v, ast.String, ptinput.KindPtDefault); err != nil {
+			l.Debug(err)
+		}
+	}
+	setInt := func(k, v string) {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			setStr(k, v)
+			return
+		}
+		if err := addKey2PtWithVal(in, k, n, ast.Int, ptinput.KindPtDefault); err != nil {
+			l.Debug(err)
+		}
+	}
+
+	setStr("provider", sys.Provider.Name)
+	setInt("event_id", sys.EventID)
+	setStr("version", sys.Version)
+	setStr("task", sys.Task)
+	setStr("opcode", sys.Opcode)
+	setStr("keywords", sys.Keywords)
+	setInt("record_id", sys.EventRecordID)
+	setStr("activity_id", sys.Correlation.ActivityID)
+	setInt("process_id", sys.Execution.ProcessID)
+	setInt("thread_id", sys.Execution.ThreadID)
+	setStr("channel", sys.Channel)
+	setStr("computer", sys.Computer)
+	setStr("user_id", sys.Security.UserID)
+
+	if n, err := strconv.Atoi(strings.TrimSpace(sys.Level)); err == nil && n >= 0 && n < len(evtxStatus) {
+		setStr("status", evtxStatus[n])
+	}
+
+	if sys.TimeCreated.SystemTime != "" {
+		if t, err := time.Parse(time.RFC3339Nano, sys.TimeCreated.SystemTime); err != nil {
+			l.Debugf("invalid TimeCreated %q: %s", sys.TimeCreated.SystemTime, err)
+		} else if err := addKey2PtWithVal(in, "time", t.UnixNano(), ast.Int, ptinput.KindPtDefault); err != nil {
+			l.Debug(err)
+		}
+	}
+
+	for i, d := range ev.EventData.Data {
+		name := d.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		setStr("event_data_"+name, d.Value)
+	}
+
+	setStr("message", strings.TrimSpace(ev.RenderingInfo.Message))
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func TestParseEvtx(t *testing.T) {
+	cases := []struct {
+		name     string
+		pl, in   string
+		expected map[string]any
+		ok       bool
+	}{
+		{
+			name: "security",
+			pl:   `add_key(ok, parse_evtx(_))`,
+			in: `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+  <System>
+    <Provider Name="Microsoft-Windows-Security-Auditing" Guid="{54849625-5478-4994-a5ba-3e3b0328c30d}"/>
+    <EventID>4625</EventID>
+    <Version>0</Version>
+    <Level>0</Level>
+    <Task>12544</Task>
+    <Opcode>0</Opcode>
+    <Keywords>0x8010000000000000</Keywords>
+    <TimeCreated SystemTime="2023-03-28T10:40:00.123456700Z"/>
+    <EventRecordID>1024</EventRecordID>
+    <Execution ProcessID="636" ThreadID="4420"/>
+    <Channel>Security</Channel>
+    <Computer>DC01</Computer>
+    <Security/>
+  </System>
+  <EventData>
+    <Data Name="TargetUserName">admin</Data>
+    <Data Name="IpAddress">10.0.0.8</Data>
+  </EventData>
+  <RenderingInfo Culture="en-US">
+    <Message>An account failed to log on.</Message>
+  </RenderingInfo>
+</Event>`,
+			expected: map[string]any{
+				"provider":                  "Microsoft-Windows-Security-Auditing",
+				"event_id":                  int64(4625),
+				"task":                      "12544",
+				"keywords":                  "0x8010000000000000",
+				"record_id":                 int64(1024),
+				"process_id":                int64(636),
+				"thread_id":                 int64(4420),
+				"channel":                   "Security",
+				"computer":                  "DC01",
+				"status":                    "info",
+				"time":                      int64(1680000000123456700),
+				"event_data_TargetUserName": "admin",
+				"event_data_IpAddress":      "10.0.0.8",
+				"message":                   "An account failed to log on.",
+			},
+			ok: true,
+		},
+
+		{
+			name: "no-rendering-unnamed-data",
+			pl:   `add_key(ok, parse_evtx(_))`,
+			in: `<Event><System><Provider Name="Application Error"/><EventID Qualifiers="0">1000</EventID>` +
+				`<Level>2</Level><Security UserID="S-1-5-18"/></System>` +
+				`<EventData><Data>app.exe</Data><Data>1.0.0.0</Data></EventData></Event>`,
+			expected: map[string]any{
+				"provider":     "Application Error",
+				"event_id":     int64(1000),
+				"status":       "error",
+				"user_id":      "S-1-5-18",
+				"event_data_0": "app.exe",
+				"event_data_1": "1.0.0.0",
+			},
+			ok: true,
+		},
+
+		{
+			name: "not-event",
+			pl:   `add_key(ok, parse_evtx(_))`,
+			in:   `<OrderEvent><OrderNumber>ORD12345</OrderNumber></OrderEvent>`,
+		},
+
+		{
+			name: "invalid-xml",
+			pl:   `add_key(ok, parse_evtx(_))`,
+			in:   `<Event><System>`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			require.NoError(t, err)
+
+			pt := ptinput.GetPoint()
+			defer ptinput.PutPoint(pt)
+			ptinput.InitPt(pt, "test", nil, map[string]any{"message": tc.in}, time.Now())
+
+			require.Nil(t, runScript(runner, pt))
+
+			assert.Equal(t, tc.ok, pt.Fields["ok"])
+			for k, v := range tc.expected {
+				assert.Equal(t, v, pt.Fields[k], "key %s", k)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	"github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+// syslog priorities of journald, 0(emerg) ~ 7(debug).
+var journaldStatus = []string{"emerg", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+func ParseJournaldChecking(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	if _, err := getKeyName(funcExpr.Param[0]); err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[0].StartPos())
+	}
+
+	return nil
+}
+
+// ParseJournald parses an entry of journald export format, fields of the
+// entry are added to the point in lower case, MESSAGE as message, PRIORITY
+// as status and __REALTIME_TIMESTAMP as time of the point.
+func ParseJournald(ctx *runtime.Context, funcExpr *ast.CallExpr) *errchain.PlError {
+	if len(funcExpr.Param) != 1 {
+		return runtime.NewRunError(ctx, fmt.Sprintf(
+			"func %s expects 1 arg", funcExpr.Name), funcExpr.NamePos)
+	}
+
+	key, err := getKeyName(funcExpr.Param[0])
+	if err != nil {
+		return runtime.NewRunError(ctx, err.Error(), funcExpr.Param[0].StartPos())
+	}
+
+	cont, err := ctx.GetKeyConv2Str(key)
+	if err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	fields, err := parseJournaldExport([]byte(cont))
+	if err != nil {
+		l.Debug(err)
+		ctx.Regs.ReturnAppend(false, ast.Bool)
+		return nil
+	}
+
+	for k, v := range fields {
+		var (
+			val   any = v
+			dtype     = ast.String
+		)
+
+		switch k {
+		case "MESSAGE":
+			k = "message"
+
+		case "PRIORITY":
+			k = "status"
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < len(journaldStatus) {
+				val = journaldStatus[n]
+			}
+
+		case "__REALTIME_TIMESTAMP": // in microseconds
+			us, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				l.Debugf("invalid __REALTIME_TIMESTAMP %q: %s", v, err)
+				continue
+			}
+			k, val, dtype = "time", us*1000, ast.Int
+
+		default:
+			k = strings.ToLower(k)
+		}
+
+		if err := addKey2PtWithVal(ctx.InData(), k, val, dtype, ptinput.KindPtDefault); err != nil {
+			l.Debug(err)
+		}
+	}
+
+	ctx.Regs.ReturnAppend(true, ast.Bool)
+	return nil
+}
+
+// parseJournaldExport parses one entry of journald export format, see
+// https://systemd.io/JOURNAL_EXPORT_FORMATS/. Text fields are in the form of
+// NAME=value, and binary ones(such as a multi-line MESSAGE) are the name,
+// a newline, 64bit little endian size and the data.
+func parseJournaldExport(data []byte) (map[string]string, error) {
+	fields := map[string]string{}
+
+	for len(data) > 0 {
+		idx := bytes.IndexByte(data, '\n')
+		line := data
+		if idx >= 0 {
+			line = data[:idx]
+		}
+
+		if len(line) == 0 { // end of the entry
+			break
+		}
+
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			if idx < 0 {
+				break
+			}
+			data = data[idx+1:]
+			continue
+		}
+
+		// binary field
+		if idx < 0 || len(data) < idx+1+8 {
+			return nil, fmt.Errorf("field %q: size missing", line)
+		}
+
+		size := binary.LittleEndian.Uint64(data[idx+1 : idx+1+8])
+		rest := data[idx+1+8:]
+		if uint64(len(rest)) < size {
+			return nil, fmt.Errorf("field %q: %d bytes expected, got %d", line, size, len(rest))
+		}
+
+		fields[string(line)] = string(rest[:size])
+		data = bytes.TrimPrefix(rest[size:], []byte("\n"))
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no journald field found")
+	}
+
+	return fields, nil
+}
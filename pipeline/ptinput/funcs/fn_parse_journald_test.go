@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package funcs
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func binaryField(name, val string) string {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(val)))
+	return name + "\n" + string(size) + val + "\n"
+}
+
+func TestParseJournald(t *testing.T) {
+	cases := []struct {
+		name     string
+		pl, in   string
+		expected map[string]any
+		ok       bool
+	}{
+		{
+			name: "text",
+			pl:   `add_key(ok, parse_journald(_))`,
+			in: "__REALTIME_TIMESTAMP=1680000000000000\n" +
+				"PRIORITY=3\n" +
+				"_SYSTEMD_UNIT=nginx.service\n" +
+				"MESSAGE=connect() failed\n",
+			expected: map[string]any{
+				"_systemd_unit": "nginx.service",
+				"message":       "connect() failed",
+				"status":        "error",
+				"time":          int64(1680000000000000000),
+			},
+			ok: true,
+		},
+
+		{
+			name: "binary-message",
+			pl:   `add_key(ok, parse_journald(_))`,
+			in: "PRIORITY=6\n" +
+				binaryField("MESSAGE", "line1\n\nline2") +
+				"SYSLOG_IDENTIFIER=app\n",
+			expected: map[string]any{
+				"syslog_identifier": "app",
+				"message":           "line1\n\nline2",
+				"status":            "info",
+			},
+			ok: true,
+		},
+
+		{
+			name: "truncated-binary",
+			pl:   `add_key(ok, parse_journald(_))`,
+			in:   "PRIORITY=6\nMESSAGE\n\x05\x00",
+			expected: map[string]any{
+				"message": "PRIORITY=6\nMESSAGE\n\x05\x00",
+			},
+		},
+
+		{
+			name: "not-journald",
+			pl:   `add_key(ok, parse_journald(_))`,
+			in:   "\nhello",
+			expected: map[string]any{
+				"message": "\nhello",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := NewTestingRunner(tc.pl)
+			require.NoError(t, err)
+
+			pt := ptinput.GetPoint()
+			defer ptinput.PutPoint(pt)
+			ptinput.InitPt(pt, "test", nil, map[string]any{"message": tc.in}, time.Now())
+
+			require.Nil(t, runScript(runner, pt))
+
+			assert.Equal(t, tc.ok, pt.Fields["ok"])
+			for k, v := range tc.expected {
+				assert.Equal(t, v, pt.Fields[k], "key %s", k)
+			}
+		})
+	}
+}
@@ -37,6 +37,10 @@ const (
 
 const Originkey = "message"
 
+// KeyMeta is the name of the structured metadata(such as pod labels) carried
+// beside the point, scripts can read it as a map, but it's never uploaded.
+const KeyMeta = "__meta"
+
 type TFMeta struct {
 	DType  ast.DType
 	PtFlag PtFlag
@@ -249,6 +253,27 @@ func (pt *Point) Mv2Tag(key string) error {
 	return nil
 }
 
+// AttachMeta expose meta to the script as the map variable __meta.
+func (pt *Point) AttachMeta(meta map[string]string) {
+	if len(meta) == 0 {
+		return
+	}
+
+	m := make(map[string]any, len(meta))
+	for k, v := range meta {
+		m[k] = v
+	}
+
+	pt.Delete(KeyMeta)
+	pt.Fields[KeyMeta] = m
+	pt.Meta[KeyMeta] = GetMeta(ast.Map, PtField)
+}
+
+// DetachMeta remove __meta from the point, whatever the script did on it.
+func (pt *Point) DetachMeta() {
+	pt.Delete(KeyMeta)
+}
+
 func (pt *Point) SetMeasurement(m string) {
 	pt.Name = m
 }
@@ -7,12 +7,16 @@ package refertable
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GuanceCloud/cliutils/logger"
@@ -32,10 +36,25 @@ var (
 )
 
 const (
-	SchemeHTTP  = "http"
-	SchemeHTTPS = "https"
+	SchemeHTTP    = "http"
+	SchemeHTTPS   = "https"
+	SchemeDataway = "dataway" // pulled from dataway, such as dataway://
+
+	referTablePullWhat = "refer_tables=true"
 )
 
+// Puller pulls data from dataway.
+type Puller interface {
+	Pull(args string) ([]byte, error)
+}
+
+var _puller Puller
+
+// SetPuller sets the dataway puller used by refer table URL dataway://.
+func SetPuller(p Puller) {
+	_puller = p
+}
+
 func QueryReferTable(tableName string, colName []string, colValue []any,
 	selected []string,
 ) (map[string]any, bool) {
@@ -56,7 +75,10 @@ func Stats() *ReferTableStats {
 	if _plReferTables == nil {
 		return nil
 	}
-	return _plReferTables.stats()
+
+	stats := _plReferTables.stats()
+	stats.Version, stats.UpdatedAt = _runner.Version()
+	return stats
 }
 
 func InitFinished(interval time.Duration) bool {
@@ -71,10 +93,24 @@ type InConfig struct {
 type Runner struct {
 	inConfig InConfig
 
-	cli *retryablehttp.Client
-	g   *goroutine.Group
+	cli    *retryablehttp.Client
+	puller Puller
+	g      *goroutine.Group
 
 	initFinished chan struct{}
+
+	mtx       sync.RWMutex
+	etag      string
+	version   string // sha256 of the data
+	updatedAt time.Time
+}
+
+// Version returns version of the tables in use and when they updated.
+func (r *Runner) Version() (string, time.Time) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.version, r.updatedAt
 }
 
 func (r *Runner) InitFinished(interval time.Duration) bool {
@@ -150,11 +186,18 @@ func initReferTableRunner(runner *Runner, plRefTables PlReferTables, tableURL st
 			MaxIdleConnsPerHost: 64,
 		}
 		runner.cli = newRetryCli(opt, time.Minute)
-		runner.g.Go(func(ctx context.Context) error {
-			return httpGetWkr(plRefTables, runner, datakit.Exit.Wait())
-		})
+
+	case SchemeDataway:
+		if _puller == nil {
+			return fmt.Errorf("url: %s, dataway not set", tableURL)
+		}
+		runner.puller = _puller
 	}
 
+	runner.g.Go(func(ctx context.Context) error {
+		return httpGetWkr(plRefTables, runner, datakit.Exit.Wait())
+	})
+
 	return nil
 }
 
@@ -166,7 +209,7 @@ func checkURL(tableURL string) (string, error) {
 	}
 	scheme := strings.ToLower(u.Scheme)
 	switch scheme {
-	case SchemeHTTP, SchemeHTTPS:
+	case SchemeHTTP, SchemeHTTPS, SchemeDataway:
 	default:
 		return "", fmt.Errorf("url: %s, unsupported scheme %s",
 			tableURL, scheme)
@@ -187,12 +230,8 @@ func httpGetWkr(plRefTables PlReferTables, runner *Runner, ch <-chan any) error
 }
 
 func getAndUpdate(plRefTables PlReferTables, runner *Runner) {
-	if tables, err := httpGet(runner.cli, runner.inConfig.URL); err != nil {
-		l.Errorf("get table data from URL: %v", err)
-	} else {
-		if err := plRefTables.updateAll(tables); err != nil {
-			l.Errorf("failed to update tables: %v", err)
-		}
+	if err := runner.update(plRefTables); err != nil {
+		l.Errorf("update refer tables from %s: %v", runner.inConfig.URL, err)
 	}
 
 	select {
@@ -204,26 +243,127 @@ func getAndUpdate(plRefTables PlReferTables, runner *Runner) {
 	}
 }
 
-func httpGet(cli *retryablehttp.Client, url string) ([]referTable, error) {
-	resp, err := cli.Get(url)
-	defer func() { _ = resp.Body.Close() }()
+// update fetches the tables and replaces the ones in use if their version
+// changed, tables in use are kept if any of the new tables is invalid.
+func (r *Runner) update(plRefTables PlReferTables) error {
+	r.mtx.RLock()
+	etag := r.etag
+	r.mtx.RUnlock()
+
+	var (
+		data        []byte
+		contentType string
+		err         error
+	)
+
+	if r.puller != nil {
+		data, err = r.puller.Pull(referTablePullWhat)
+	} else {
+		data, contentType, etag, err = httpGet(r.cli, r.inConfig.URL, etag)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if data == nil { // not modified
+		l.Debugf("refer tables not modified")
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+
+	if v, _ := r.Version(); v == version {
+		l.Debugf("refer tables of version %s not changed", version)
+		return nil
+	}
+
+	var tables []referTable
+	if isCSV(r.inConfig.URL, contentType) {
+		tables, err = decodeCSVData(csvTableName(r.inConfig.URL), data)
+	} else {
+		tables, err = decodeJSONData(data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := plRefTables.updateAll(tables); err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	r.etag = etag
+	r.version = version
+	r.updatedAt = time.Now()
+	r.mtx.Unlock()
+
+	l.Infof("refer tables updated to version %s, %d tables", version, len(tables))
+	return nil
+}
+
+// isCSV checks if the data of URL is CSV by its Content-Type or extension.
+func isCSV(tableURL, contentType string) bool {
+	if strings.Contains(strings.ToLower(contentType), "csv") {
+		return true
+	}
+
+	u, err := url.Parse(tableURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(path.Ext(u.Path), ".csv")
+}
+
+// csvTableName returns table name of the CSV, which is query parameter
+// table_name or file name of the URL, such as ip_service of
+// http://host/ip_service.csv.
+func csvTableName(tableURL string) string {
+	u, err := url.Parse(tableURL)
 	if err != nil {
-		return nil, err
+		return ""
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("url: %s, status: %s", url, resp.Status)
+	if name := u.Query().Get("table_name"); name != "" {
+		return name
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	name := path.Base(u.Path)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// httpGet gets data of the URL, nil data returned if not modified since etag.
+func httpGet(cli *retryablehttp.Client, url, etag string) (data []byte, contentType, newETag string, err error) {
+	req, err := retryablehttp.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, "", etag, nil
+	default:
+		return nil, "", "", fmt.Errorf("url: %s, status: %s", url, resp.Status)
 	}
 
-	tables, err := decodeJSONData(data)
+	data, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
 	}
 
-	return tables, nil
+	return data, resp.Header.Get("Content-Type"), resp.Header.Get("ETag"), nil
 }
@@ -6,12 +6,15 @@
 package refertable
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
 )
 
 func TestCheckUrl(t *testing.T) {
@@ -112,3 +115,98 @@ func newJsonDataServer(files map[string]string) *httptest.Server {
 	))
 	return server
 }
+
+type mockPuller struct {
+	data  []byte
+	pulls int
+}
+
+func (p *mockPuller) Pull(args string) ([]byte, error) {
+	p.pulls++
+	if args != referTablePullWhat {
+		return nil, fmt.Errorf("unexpected pull args %s", args)
+	}
+	return p.data, nil
+}
+
+func TestRunnerUpdate(t *testing.T) {
+	t.Run("csv-etag", func(t *testing.T) {
+		var (
+			data     = "ip,service,port:int\n10.0.0.1,web,80\n10.0.0.2,db,3306\n"
+			requests int
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			etag := fmt.Sprintf(`"%d"`, len(data))
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write([]byte(data))
+		}))
+		defer server.Close()
+
+		runner := &Runner{
+			inConfig: InConfig{URL: server.URL + "/ip_service.csv"},
+			cli:      newRetryCli(&ihttp.Options{}, time.Second),
+		}
+		tables := &PlReferTablesInMemory{}
+
+		require.NoError(t, runner.update(tables))
+		v, ok := tables.query("ip_service", []string{"ip"}, []any{"10.0.0.2"}, nil)
+		require.True(t, ok)
+		assert.Equal(t, map[string]any{"ip": "10.0.0.2", "service": "db", "port": int64(3306)}, v)
+
+		version, updatedAt := runner.Version()
+		assert.NotEmpty(t, version)
+
+		// not modified
+		require.NoError(t, runner.update(tables))
+		v2, updatedAt2 := runner.Version()
+		assert.Equal(t, version, v2)
+		assert.Equal(t, updatedAt, updatedAt2)
+		assert.Equal(t, 2, requests)
+
+		// invalid data, tables in use kept
+		data = "ip,port:int\n10.0.0.3,abc\n"
+		assert.Error(t, runner.update(tables))
+		v2, _ = runner.Version()
+		assert.Equal(t, version, v2)
+		_, ok = tables.query("ip_service", []string{"ip"}, []any{"10.0.0.1"}, nil)
+		assert.True(t, ok)
+
+		data = "ip,service\n10.0.0.3,cache\n"
+		require.NoError(t, runner.update(tables))
+		v2, _ = runner.Version()
+		assert.NotEqual(t, version, v2)
+		_, ok = tables.query("ip_service", []string{"ip"}, []any{"10.0.0.1"}, nil)
+		assert.False(t, ok)
+		_, ok = tables.query("ip_service", []string{"ip"}, []any{"10.0.0.3"}, nil)
+		assert.True(t, ok)
+	})
+
+	t.Run("dataway", func(t *testing.T) {
+		puller := &mockPuller{data: []byte(testTableData)}
+
+		_, err := checkURL("dataway://")
+		require.NoError(t, err)
+
+		runner := &Runner{inConfig: InConfig{URL: "dataway://"}, puller: puller}
+		tables := &PlReferTablesInMemory{}
+
+		require.NoError(t, runner.update(tables))
+		version, updatedAt := runner.Version()
+
+		// same data pulled, tables not rebuilt
+		require.NoError(t, runner.update(tables))
+		v2, updatedAt2 := runner.Version()
+		assert.Equal(t, version, v2)
+		assert.Equal(t, updatedAt, updatedAt2)
+		assert.Equal(t, 2, puller.pulls)
+
+		stats := tables.stats()
+		assert.Equal(t, []string{"table1", "table2"}, stats.Name)
+	})
+}
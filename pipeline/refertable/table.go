@@ -7,10 +7,13 @@
 package refertable
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/cast"
 )
@@ -38,6 +41,9 @@ type PlReferTablesInMemory struct {
 type ReferTableStats struct {
 	Name []string
 	Row  []int
+
+	Version   string
+	UpdatedAt time.Time
 }
 
 func (plrefer *PlReferTablesInMemory) query(tableName string, colName []string, colValue []any,
@@ -234,3 +240,43 @@ func decodeJSONData(data []byte) ([]referTable, error) {
 		return tables, nil
 	}
 }
+
+// decodeCSVData decodes CSV as a table, columns of the header are name and
+// type separated by colon, type is string if omitted, such as:
+//
+//	ip,service,port:int
+//	10.0.0.1,web,80
+func decodeCSVData(tableName string, data []byte) ([]referTable, error) {
+	if tableName == "" {
+		return nil, fmt.Errorf("empty table name of CSV")
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("table: %s, decode CSV: %w", tableName, err)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("table: %s, CSV header not found", tableName)
+	}
+
+	table := referTable{TableName: tableName}
+	for _, col := range records[0] {
+		name, typ, ok := strings.Cut(col, ":")
+		if !ok {
+			typ = columnTypeStr
+		}
+		table.ColumnName = append(table.ColumnName, strings.TrimSpace(name))
+		table.ColumnType = append(table.ColumnType, strings.ToLower(strings.TrimSpace(typ)))
+	}
+
+	for _, record := range records[1:] {
+		row := make([]any, 0, len(record))
+		for _, v := range record {
+			row = append(row, v)
+		}
+		table.RowData = append(table.RowData, row)
+	}
+
+	return []referTable{table}, nil
+}
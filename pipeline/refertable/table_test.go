@@ -7,6 +7,9 @@ package refertable
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTable(t *testing.T) {
@@ -29,6 +32,35 @@ func TestTable(t *testing.T) {
 	}
 }
 
+func TestDecodeCSVData(t *testing.T) {
+	tables, err := decodeCSVData("t1", []byte("name, id:INT ,ok:bool\na,1,true\n\"b,c\",2,false\n"))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+
+	assert.Equal(t, "t1", tables[0].TableName)
+	assert.Equal(t, []string{"name", "id", "ok"}, tables[0].ColumnName)
+	assert.Equal(t, []string{"string", "int", "bool"}, tables[0].ColumnType)
+	assert.Equal(t, [][]any{{"a", "1", "true"}, {"b,c", "2", "false"}}, tables[0].RowData)
+
+	_, err = decodeCSVData("", []byte("a\n1\n"))
+	assert.Error(t, err)
+
+	_, err = decodeCSVData("t1", []byte(""))
+	assert.Error(t, err)
+
+	_, err = decodeCSVData("t1", []byte("a,b\n1\n"))
+	assert.Error(t, err, "wrong number of fields")
+}
+
+func TestCSVTableName(t *testing.T) {
+	assert.Equal(t, "ip_service", csvTableName("http://host/tables/ip_service.csv"))
+	assert.Equal(t, "users", csvTableName("http://host/export?table_name=users&fmt=csv"))
+
+	assert.True(t, isCSV("http://host/a.CSV", ""))
+	assert.True(t, isCSV("http://host/export", "text/csv; charset=utf-8"))
+	assert.False(t, isCSV("http://host/a.json", "application/json"))
+}
+
 func BenchmarkTableQueyr(b *testing.B) {
 	tables, err := decodeJSONData([]byte(testTableData))
 	if err != nil {
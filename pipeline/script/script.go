@@ -23,6 +23,10 @@ type Option struct {
 	MaxFieldValLen        int // deprecated
 	DisableAddStatusField bool
 	IgnoreStatus          []string
+
+	// Meta is the structured metadata(pod labels, source manifest and so on)
+	// carried beside the data, scripts access it via __meta["key"].
+	Meta map[string]string
 }
 
 type PlScript struct {
@@ -94,7 +98,12 @@ func (script *PlScript) Run(plpt *ptinput.Point, signal plruntime.Signal, opt *O
 		return fmt.Errorf("no script")
 	}
 
+	if opt != nil {
+		plpt.AttachMeta(opt.Meta)
+	}
+
 	err := plengine.RunScriptWithRMapIn(script.proc, plpt, signal)
+	plpt.DetachMeta()
 	if err != nil {
 		stats.WriteScriptStats(script.category, script.ns, script.name, 1, 0, 1, int64(time.Since(startTime)), err)
 		return err
@@ -127,3 +127,36 @@ func TestDrop(t *testing.T) {
 		t.Error("drop != true")
 	}
 }
+
+func TestMeta(t *testing.T) {
+	ret, retErr := NewScripts(map[string]string{"meta.p": `
+add_key(app, __meta["label_app"])
+add_key(manifest, __meta["source"])
+add_key(absent, __meta["not_exist"])
+`}, nil, DefaultScriptNS, datakit.Logging)
+	if len(retErr) > 0 {
+		t.Fatal(retErr)
+	}
+
+	plpt := ptinput.InitPt(&ptinput.Point{}, "ng", nil, nil, time.Now())
+	err := ret["meta.p"].Run(plpt, nil, &Option{
+		DisableAddStatusField: true,
+		Meta:                  map[string]string{"label_app": "nginx", "source": "nginx-access"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "nginx", plpt.Fields["app"])
+	assert.Equal(t, "nginx-access", plpt.Fields["manifest"])
+	assert.Nil(t, plpt.Fields["absent"])
+
+	_, ok := plpt.Fields[ptinput.KeyMeta]
+	assert.False(t, ok, "__meta should not be uploaded")
+
+	// without meta, __meta is absent
+	plpt = ptinput.InitPt(&ptinput.Point{}, "ng", nil, nil, time.Now())
+	_ = ret["meta.p"].Run(plpt, nil, &Option{DisableAddStatusField: true})
+	_, ok = plpt.Fields[ptinput.KeyMeta]
+	assert.False(t, ok)
+}
@@ -0,0 +1,291 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/platypus/pkg/ast"
+	plruntime "github.com/GuanceCloud/platypus/pkg/engine/runtime"
+	"github.com/GuanceCloud/platypus/pkg/errchain"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+// Trace is the step-by-step execution of the script on one point, each
+// top-level statement is a step.
+type Trace struct {
+	Steps []*TraceStep `json:"steps"`
+
+	// changes of the point after the whole script, including the status
+	// processing of logging
+	Diff *PointDiff `json:"diff"`
+	Cost string     `json:"cost"`
+}
+
+type TraceStep struct {
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+	Stmt string `json:"stmt"`
+
+	// functions called in the statement, in the order they returned
+	Calls []*TraceCall `json:"calls,omitempty"`
+
+	// script variables after the statement
+	Vars map[string]any `json:"vars,omitempty"`
+
+	Diff  *PointDiff `json:"diff,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+type TraceCall struct {
+	Name   string `json:"name"`
+	Line   int    `json:"line"`
+	Col    int    `json:"col"`
+	Return any    `json:"return,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Change is the value before and after, nil Old means added and nil New
+// means deleted.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type PointDiff struct {
+	Name   *Change            `json:"name,omitempty"`
+	Tags   map[string]*Change `json:"tags,omitempty"`
+	Fields map[string]*Change `json:"fields,omitempty"`
+	Drop   *Change            `json:"drop,omitempty"`
+}
+
+func (d *PointDiff) empty() bool {
+	return d.Name == nil && d.Drop == nil && len(d.Tags) == 0 && len(d.Fields) == 0
+}
+
+type ptSnapshot struct {
+	name   string
+	tags   map[string]any
+	fields map[string]any
+	drop   bool
+}
+
+func snapshot(pt *ptinput.Point) *ptSnapshot {
+	s := &ptSnapshot{
+		name:   pt.Name,
+		tags:   make(map[string]any, len(pt.Tags)),
+		fields: make(map[string]any, len(pt.Fields)),
+		drop:   pt.Drop,
+	}
+
+	for k, v := range pt.Tags {
+		s.tags[k] = v
+	}
+
+	for k, v := range pt.Fields {
+		s.fields[k] = v
+	}
+
+	return s
+}
+
+func diffKV(before, after map[string]any) map[string]*Change {
+	changes := map[string]*Change{}
+
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			changes[k] = &Change{Old: old, New: v}
+		}
+	}
+
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			changes[k] = &Change{Old: v}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func diffSnapshot(before, after *ptSnapshot) *PointDiff {
+	d := &PointDiff{
+		Tags:   diffKV(before.tags, after.tags),
+		Fields: diffKV(before.fields, after.fields),
+	}
+
+	if before.name != after.name {
+		d.Name = &Change{Old: before.name, New: after.name}
+	}
+
+	if before.drop != after.drop {
+		d.Drop = &Change{Old: before.drop, New: after.drop}
+	}
+
+	return d
+}
+
+// tracer records function calls of the current step.
+type tracer struct {
+	step *TraceStep
+}
+
+func (t *tracer) wrap(funcs map[string]plruntime.FuncCall) map[string]plruntime.FuncCall {
+	wrapped := make(map[string]plruntime.FuncCall, len(funcs))
+
+	for name, fn := range funcs {
+		fn := fn
+		wrapped[name] = func(ctx *plruntime.Context, expr *ast.CallExpr) *errchain.PlError {
+			err := fn(ctx, expr)
+
+			call := &TraceCall{
+				Name: expr.Name,
+				Line: expr.NamePos.Ln,
+				Col:  expr.NamePos.Col,
+			}
+
+			if err != nil {
+				call.Error = err.Error()
+			} else if ctx.Regs.Count() > 0 {
+				call.Return, _, _ = ctx.Regs.Get(plruntime.RegR0)
+			}
+
+			if t.step != nil {
+				t.step.Calls = append(t.step.Calls, call)
+			}
+
+			return err
+		}
+	}
+
+	return wrapped
+}
+
+func sourceLine(content string, ln int) string {
+	lines := strings.Split(content, "\n")
+	if ln <= 0 || ln > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[ln-1])
+}
+
+// RunTrace runs the script like Run, and traces each top-level statement.
+// Stats of the script are not updated.
+func (script *PlScript) RunTrace(plpt *ptinput.Point, signal plruntime.Signal, opt *Option,
+) (*Trace, error) {
+	startTime := time.Now()
+	if script.proc == nil {
+		return nil, fmt.Errorf("no script")
+	}
+
+	if opt != nil {
+		plpt.AttachMeta(opt.Meta)
+	}
+
+	proc := script.proc
+	trace := &Trace{}
+	tr := &tracer{}
+
+	ctx := plruntime.GetContext()
+	defer plruntime.PutContext(ctx)
+
+	ctx = plruntime.InitCtxWithRMap(ctx, plpt, tr.wrap(proc.FuncCall), proc.CallRef,
+		signal, proc.Name, proc.Content)
+
+	origin := snapshot(plpt)
+	before := origin
+	var varNames []string
+
+	var runErr *errchain.PlError
+	for _, node := range proc.Ast {
+		pos := node.StartPos()
+		step := &TraceStep{
+			Line: pos.Ln,
+			Col:  pos.Col,
+			Stmt: sourceLine(proc.Content, pos.Ln),
+		}
+		trace.Steps = append(trace.Steps, step)
+		tr.step = step
+
+		_, _, runErr = plruntime.RunStmt(ctx, node)
+
+		// variables assigned in blocks are gone after the statement,
+		// only ones of top-level assignments kept
+		if runErr == nil && node.NodeType == ast.TypeAssignmentExpr &&
+			node.AssignmentExpr.LHS != nil &&
+			node.AssignmentExpr.LHS.NodeType == ast.TypeIdentifier {
+			if name := node.AssignmentExpr.LHS.Identifier.Name; name != "_" {
+				varNames = appendUniq(varNames, name)
+			}
+		}
+
+		if len(varNames) > 0 {
+			step.Vars = map[string]any{}
+			for _, name := range varNames {
+				if v, err := ctx.GetKey(name); err == nil {
+					step.Vars[name] = v.Value
+				}
+			}
+		}
+
+		after := snapshot(plpt)
+		if d := diffSnapshot(before, after); !d.empty() {
+			step.Diff = d
+		}
+		before = after
+
+		if runErr != nil {
+			step.Error = runErr.Error()
+			break
+		}
+
+		if ctx.StmtRetrun() {
+			break
+		}
+	}
+
+	plpt.DetachMeta()
+
+	if runErr != nil {
+		trace.Cost = time.Since(startTime).String()
+		return trace, runErr
+	}
+
+	if script.category == datakit.Logging {
+		var disable bool
+		var ignore []string
+
+		if opt != nil {
+			disable = opt.DisableAddStatusField
+			ignore = opt.IgnoreStatus
+		}
+
+		plpt.Tags, plpt.Fields, plpt.Drop = ProcLoggingStatus(plpt.Tags, plpt.Fields, plpt.Drop, disable, ignore)
+	}
+
+	trace.Diff = diffSnapshot(origin, snapshot(plpt))
+
+	plpt.KeyTime2Time()
+
+	trace.Cost = time.Since(startTime).String()
+	return trace, nil
+}
+
+func appendUniq(arr []string, s string) []string {
+	for _, x := range arr {
+		if x == s {
+			return arr
+		}
+	}
+	return append(arr, s)
+}
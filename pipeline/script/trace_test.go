@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/ptinput"
+)
+
+func TestRunTrace(t *testing.T) {
+	content := `x = 1
+add_key(k1, "v1")
+if x == 1 {
+  set_tag(t1, "tv")
+  y = 2
+}
+rename("k2", k1)
+`
+	ret, retErr := NewScripts(map[string]string{"abc.p": content}, nil, DefaultScriptNS, datakit.Logging)
+	require.Empty(t, retErr)
+
+	plpt := ptinput.InitPt(&ptinput.Point{}, "ng", nil, map[string]any{"message": "hello"}, time.Now())
+	trace, err := ret["abc.p"].RunTrace(plpt, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, trace.Steps, 4)
+
+	s := trace.Steps[0]
+	assert.Equal(t, 1, s.Line)
+	assert.Equal(t, "x = 1", s.Stmt)
+	assert.Equal(t, map[string]any{"x": int64(1)}, s.Vars)
+	assert.Nil(t, s.Diff)
+	assert.Empty(t, s.Calls)
+
+	s = trace.Steps[1]
+	require.Len(t, s.Calls, 1)
+	assert.Equal(t, "add_key", s.Calls[0].Name)
+	assert.Equal(t, 2, s.Calls[0].Line)
+	assert.Equal(t, map[string]*Change{"k1": {New: "v1"}}, s.Diff.Fields)
+
+	// y is gone after the if block
+	s = trace.Steps[2]
+	assert.Equal(t, 3, s.Line)
+	assert.Equal(t, "if x == 1 {", s.Stmt)
+	require.Len(t, s.Calls, 1)
+	assert.Equal(t, "set_tag", s.Calls[0].Name)
+	assert.Equal(t, map[string]*Change{"t1": {New: "tv"}}, s.Diff.Tags)
+	assert.Equal(t, map[string]any{"x": int64(1)}, s.Vars)
+
+	s = trace.Steps[3]
+	assert.Equal(t, map[string]*Change{
+		"k1": {Old: "v1"},
+		"k2": {New: "v1"},
+	}, s.Diff.Fields)
+
+	// status added by logging status processing
+	assert.Equal(t, map[string]*Change{
+		"k2":     {New: "v1"},
+		"status": {New: DefaultStatus},
+	}, trace.Diff.Fields)
+	assert.Equal(t, map[string]*Change{"t1": {New: "tv"}}, trace.Diff.Tags)
+
+	// result same as Run
+	plpt2 := ptinput.InitPt(&ptinput.Point{}, "ng", nil, map[string]any{"message": "hello"}, time.Now())
+	require.NoError(t, ret["abc.p"].Run(plpt2, nil, nil))
+	assert.Equal(t, plpt2.Tags, plpt.Tags)
+	assert.Equal(t, plpt2.Fields, plpt.Fields)
+}
+
+func TestRunTraceError(t *testing.T) {
+	content := `add_key(k1, "v1")
+a = [1]
+b = a - 1
+add_key(k2, "v2")
+`
+	ret, retErr := NewScripts(map[string]string{"abc.p": content}, nil, DefaultScriptNS, datakit.Logging)
+	require.Empty(t, retErr)
+
+	plpt := ptinput.InitPt(&ptinput.Point{}, "ng", nil, nil, time.Now())
+	trace, err := ret["abc.p"].RunTrace(plpt, nil, nil)
+	require.Error(t, err)
+
+	// steps after the failed one not run
+	require.Len(t, trace.Steps, 3)
+	assert.Equal(t, map[string]any{"a": []any{int64(1)}}, trace.Steps[2].Vars)
+	assert.Equal(t, err.Error(), trace.Steps[2].Error)
+	assert.Nil(t, trace.Diff)
+	assert.NotContains(t, plpt.Fields, "k2")
+}
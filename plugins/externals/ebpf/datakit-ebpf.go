@@ -28,6 +28,7 @@ import (
 	dkdns "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/dnsflow"
 	dkhttpflow "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/httpflow"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/k8sinfo"
+	dknetdrop "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/netdrop"
 	dknetflow "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/netflow"
 	dkout "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/output"
 	dksysmonitor "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/sysmonitor"
@@ -48,6 +49,10 @@ var (
 	enableHTTPFlowTLS = false
 
 	ipv6Disabled = false
+
+	enableNetDrop = false
+
+	enableDNSFlowFailureLog = false
 )
 
 var pidFile = filepath.Join(datakit.InstallDir, "externals", "datakit-ebpf.pid")
@@ -74,6 +79,10 @@ type Option struct {
 
 	IPv6Disabled string `long:"ipv6-disabled" description:"ipv6 is not enabled on the system"`
 
+	NetDropEnabled string `long:"netdrop-enabled" description:"collect per container packet drop, retransmit and conntrack metrics"`
+
+	DNSFlowFailureLog string `long:"dnsflow-failure-log" description:"send failed DNS queries as logging"`
+
 	PProfPort string `long:"pprof-port" description:"pprof port" default:""`
 
 	Service string `long:"service" description:"service" default:"ebpf"`
@@ -93,6 +102,7 @@ const (
 	inputNameNetNet  = "ebpf-net/netflow"
 	inputNameNetDNS  = "ebpf-net/dnsflow"
 	inputNameNetHTTP = "ebpf-net/httpflow"
+	inputNameNetDrop = "ebpf-net/netdrop"
 )
 
 var (
@@ -151,6 +161,7 @@ func main() {
 	dkbash.SetLogger(l)
 	dkhttpflow.SetLogger(l)
 	dksysmonitor.SetLogger(l)
+	dknetdrop.SetLogger(l)
 
 	// duration is between 10s and 30min, if not, take the boundary value.
 	if tmp, err := time.ParseDuration(opt.Interval); err == nil {
@@ -199,6 +210,7 @@ func main() {
 			dknetflow.SetK8sNetInfo(k8sinfo)
 			dkhttpflow.SetK8sNetInfo(k8sinfo)
 			dkdns.SetK8sNetInfo(k8sinfo)
+			dknetdrop.SetK8sNetInfo(k8sinfo)
 		}
 
 		constEditor := dkoffset.NewConstEditor(offset)
@@ -236,9 +248,15 @@ func main() {
 		if tp, err := dkdns.NewTPacketDNS(); err != nil {
 			l.Error(err)
 		} else {
+			var dnsFailureLogURL string
+			if enableDNSFlowFailureLog {
+				dnsFailureLogURL = fmt.Sprintf("http://%s%s?input=",
+					dkout.DataKitAPIServer, datakit.Logging) + url.QueryEscape(inputNameNetDNS)
+			}
+
 			dnsTracer := dkdns.NewDNSFlowTracer()
 			go dnsTracer.Run(ctx, tp, gTags, dnsRecord, fmt.Sprintf("http://%s%s?input=",
-				dkout.DataKitAPIServer, datakit.Network)+url.QueryEscape(inputNameNetDNS))
+				dkout.DataKitAPIServer, datakit.Network)+url.QueryEscape(inputNameNetDNS), dnsFailureLogURL)
 		}
 
 		// run netflow
@@ -251,6 +269,13 @@ func main() {
 			return
 		}
 
+		if enableNetDrop {
+			l.Info(" >>> datakit ebpf-net netdrop starting ...")
+			netdropTracer := dknetdrop.NewNetDropTracer(gTags, fmt.Sprintf("http://%s%s?input=",
+				dkout.DataKitAPIServer, datakit.Network)+url.QueryEscape(inputNameNetDrop))
+			go netdropTracer.Run(ctx, interval)
+		}
+
 		if enableHTTPFlow {
 			bpfMapSockFD, ok, err := ebpfNetManger.GetMap("bpfmap_sockfd")
 			if err != nil {
@@ -393,6 +418,18 @@ func parseFlags() (*Option, map[string]string, error) {
 	default:
 	}
 
+	switch strings.ToLower(opt.NetDropEnabled) {
+	case "true", "t", "yes", "y", "1":
+		enableNetDrop = true
+	default:
+	}
+
+	switch strings.ToLower(opt.DNSFlowFailureLog) {
+	case "true", "t", "yes", "y", "1":
+		enableDNSFlowFailureLog = true
+	default:
+	}
+
 	optTags := strings.Split(opt.Tags, ";")
 	for _, item := range optTags {
 		tagArr := strings.Split(item, "=")
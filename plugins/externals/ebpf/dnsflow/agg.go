@@ -4,10 +4,12 @@
 package dnsflow
 
 import (
+	"fmt"
 	"math"
 	"strconv"
 	"time"
 
+	"github.com/google/gopacket/layers"
 	client "github.com/influxdata/influxdb1-client/v2"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/k8sinfo"
@@ -29,6 +31,9 @@ type aggKey struct {
 	family    string
 	transport string
 	direction string
+
+	processName string
+	containerID string
 }
 
 type aggValue struct {
@@ -77,6 +82,13 @@ func kv2point(key *aggKey, value *aggValue, pTime time.Time,
 		tags["dst_port"] = strconv.FormatInt(int64(key.dPort), 10)
 	}
 
+	if key.processName != "" {
+		tags["process_name"] = key.processName
+	}
+	if key.containerID != "" {
+		tags["container_id"] = key.containerID
+	}
+
 	for k, v := range addTags {
 		if _, ok := tags[k]; !ok {
 			tags[k] = v
@@ -150,6 +162,11 @@ func (agg *FlowAgg) Append(dnsKey DNSQAKey, stats DNSStats) error {
 		return nil
 	}
 
+	if stats.Proc != nil {
+		key.processName = stats.Proc.Name
+		key.containerID = stats.Proc.ContainerID
+	}
+
 	_, err := dknetflow.SrcIPPortRecorder.Query(dnsKey.ServerIP)
 	if err == nil {
 		// swap ip type
@@ -198,3 +215,152 @@ func (agg *FlowAgg) ToPoint(tags map[string]string, k8sInfo *k8sinfo.K8sNetInfo)
 func (agg *FlowAgg) Clean() {
 	agg.data = make(map[aggKey]*aggValue)
 }
+
+type failureKey struct {
+	clientIP string
+	serverIP string
+	qname    string
+	rcode    int
+
+	processName string
+	containerID string
+}
+
+type failureValue struct {
+	count   int
+	lastPID int
+	lastTS  time.Time
+}
+
+// FailureAgg aggregates the failed(timeout or rcode not 0) DNS queries,
+// which are sent as logging.
+type FailureAgg struct {
+	data map[failureKey]*failureValue
+}
+
+func (agg *FailureAgg) Len() int {
+	return len(agg.data)
+}
+
+func (agg *FailureAgg) Append(dnsKey DNSQAKey, stats DNSStats) {
+	if agg.data == nil {
+		agg.data = map[failureKey]*failureValue{}
+	}
+
+	key := failureKey{
+		clientIP: dknetflow.U32BEToIP(dnsKey.ClientIP, !dnsKey.IsV4).String(),
+		serverIP: dknetflow.U32BEToIP(dnsKey.ServerIP, !dnsKey.IsV4).String(),
+		qname:    stats.QName,
+		rcode:    stats.RCODE,
+	}
+
+	pid := 0
+	if stats.Proc != nil {
+		key.processName = stats.Proc.Name
+		key.containerID = stats.Proc.ContainerID
+		pid = stats.Proc.PID
+	}
+
+	v, ok := agg.data[key]
+	if !ok {
+		v = &failureValue{}
+		agg.data[key] = v
+	}
+
+	v.count++
+	v.lastPID = pid
+	v.lastTS = stats.TS
+}
+
+func (agg *FailureAgg) ToPoint(tags map[string]string, k8sInfo *k8sinfo.K8sNetInfo) []*point.Point {
+	var result []*client.Point
+
+	for k, v := range agg.data {
+		if pt, err := failure2point(&k, v, tags, k8sInfo); err != nil {
+			l.Debug(err)
+		} else {
+			result = append(result, pt)
+		}
+	}
+
+	return point.WrapPoint(result)
+}
+
+func (agg *FailureAgg) Clean() {
+	agg.data = make(map[failureKey]*failureValue)
+}
+
+func failure2point(key *failureKey, value *failureValue,
+	addTags map[string]string, k8sNetInfo *k8sinfo.K8sNetInfo,
+) (*client.Point, error) {
+	tags := map[string]string{
+		"src_ip": key.clientIP,
+		"dst_ip": key.serverIP,
+		"rcode":  rcodeName(key.rcode),
+	}
+
+	if key.qname != "" {
+		tags["dns_query"] = key.qname
+	}
+	if key.processName != "" {
+		tags["process_name"] = key.processName
+	}
+	if key.containerID != "" {
+		tags["container_id"] = key.containerID
+	}
+
+	for k, v := range addTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	status := "error"
+	if key.rcode == 3 { // NXDomain, usually search domains tried
+		status = "warning"
+	}
+
+	proc := "unknown process"
+	if key.processName != "" {
+		proc = fmt.Sprintf("%s(pid %d)", key.processName, value.lastPID)
+	}
+
+	fields := map[string]any{
+		"message": fmt.Sprintf("DNS query %q from %s %s to resolver %s failed: %s, %d times",
+			key.qname, key.clientIP, proc, key.serverIP, rcodeName(key.rcode), value.count),
+		"status": status,
+		"count":  value.count,
+	}
+
+	if value.lastPID > 0 {
+		fields["pid"] = value.lastPID
+	}
+
+	tags = dknetflow.AddK8sTags2Map(k8sNetInfo, key.clientIP, key.serverIP,
+		math.MaxUint32, 53, "udp", tags)
+	return client.NewPoint(srcNameLog, tags, fields, value.lastTS)
+}
+
+// rcodeName returns the name of the DNS response code, `Timeout` for -1.
+func rcodeName(rcode int) string {
+	if rcode < 0 {
+		return "Timeout"
+	}
+
+	switch layers.DNSResponseCode(rcode) { //nolint:exhaustive
+	case layers.DNSResponseCodeNoErr:
+		return "NoError"
+	case layers.DNSResponseCodeFormErr:
+		return "FormErr"
+	case layers.DNSResponseCodeServFail:
+		return "ServFail"
+	case layers.DNSResponseCodeNXDomain:
+		return "NXDomain"
+	case layers.DNSResponseCodeNotImp:
+		return "NotImp"
+	case layers.DNSResponseCodeRefused:
+		return "Refused"
+	default:
+		return strconv.Itoa(rcode)
+	}
+}
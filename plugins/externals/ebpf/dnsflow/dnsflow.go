@@ -11,11 +11,13 @@ import (
 	"github.com/google/gopacket/afpacket"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/k8sinfo"
+	dknetflow "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/netflow"
 	dkout "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/output"
 )
 
 const (
 	srcNameM   = "dnsflow"
+	srcNameLog = "dnsflow_failure"
 	DNSTIMEOUT = time.Second * 6
 )
 
@@ -33,14 +35,23 @@ func SetK8sNetInfo(n *k8sinfo.K8sNetInfo) {
 
 func NewDNSFlowTracer() *DNSFlowTracer {
 	return &DNSFlowTracer{
-		statsMap: map[DNSQAKey]DNSStats{},
-		pInfoCh:  make(chan *DNSPacketInfo, 1024),
+		statsMap:  map[DNSQAKey]DNSStats{},
+		pInfoCh:   make(chan *DNSPacketInfo, 1024),
+		sockOwner: newSockOwnerResolver(procRoot),
 	}
 }
 
 type DNSFlowTracer struct {
-	statsMap map[DNSQAKey]DNSStats
-	pInfoCh  chan *DNSPacketInfo
+	statsMap  map[DNSQAKey]DNSStats
+	pInfoCh   chan *DNSPacketInfo
+	sockOwner *sockOwnerResolver
+}
+
+// queryProc returns the process sent the DNS query.
+func (tracer *DNSFlowTracer) queryProc(packetInfo *DNSPacketInfo) *ProcInfo {
+	key := packetInfo.Key
+	return tracer.sockOwner.Lookup(dknetflow.U32BEToIP(key.ClientIP, !key.IsV4),
+		key.ClientPort, key.IsUDP, packetInfo.TS)
 }
 
 func (tracer *DNSFlowTracer) updateDNSStats(packetInfo *DNSPacketInfo, dnsRecord *DNSAnswerRecord) *DNSStats {
@@ -53,8 +64,10 @@ func (tracer *DNSFlowTracer) updateDNSStats(packetInfo *DNSPacketInfo, dnsRecord
 				Timeout:   false,
 				Responded: false,
 				RCODE:     -1,
+				QName:     packetInfo.QName,
+				Proc:      tracer.queryProc(packetInfo),
 			}
-			return &stats
+			return nil
 		}
 	} else {
 		if packetInfo.QR { // answer
@@ -115,11 +128,25 @@ func (tracer *DNSFlowTracer) readPacket(ctx context.Context, tp *afpacket.TPacke
 	}
 }
 
+// Run collects DNS metrics, and logging of failed(timeout or rcode not 0) queries
+// if failureLogAddr not empty.
 func (tracer *DNSFlowTracer) Run(ctx context.Context, tp *afpacket.TPacket, gTag map[string]string,
-	dnsRecord *DNSAnswerRecord, feedAddr string,
+	dnsRecord *DNSAnswerRecord, feedAddr, failureLogAddr string,
 ) {
 	mCh := make(chan []*point.Point, 8)
+	logCh := make(chan []*point.Point, 8)
 	agg := FlowAgg{}
+	failureAgg := FailureAgg{}
+
+	appendStats := func(k DNSQAKey, v DNSStats) {
+		if err := agg.Append(k, v); err != nil {
+			l.Debug(err)
+		}
+		if failureLogAddr != "" && v.RCODE != 0 {
+			failureAgg.Append(k, v)
+		}
+	}
+
 	go tracer.readPacket(ctx, tp)
 	go func() {
 		t := time.NewTicker(time.Second * 30)
@@ -128,10 +155,7 @@ func (tracer *DNSFlowTracer) Run(ctx context.Context, tp *afpacket.TPacket, gTag
 			case <-t.C:
 				stats := tracer.checkTimeoutDNSQuery()
 				for k, v := range stats {
-					err := agg.Append(k, v)
-					if err != nil {
-						l.Debug(err)
-					}
+					appendStats(k, v)
 				}
 
 				pts := agg.ToPoint(gTag, k8sNetInfo)
@@ -141,12 +165,19 @@ func (tracer *DNSFlowTracer) Run(ctx context.Context, tp *afpacket.TPacket, gTag
 				default:
 					l.Warn("mCh full, drop data")
 				}
+
+				if failureAgg.Len() > 0 {
+					logs := failureAgg.ToPoint(gTag, k8sNetInfo)
+					failureAgg.Clean()
+					select {
+					case logCh <- logs:
+					default:
+						l.Warn("logCh full, drop data")
+					}
+				}
 			case pinfo := <-tracer.pInfoCh:
 				if stats := tracer.updateDNSStats(pinfo, dnsRecord); stats != nil {
-					err := agg.Append(pinfo.Key, *stats)
-					if err != nil {
-						l.Debug(err)
-					}
+					appendStats(pinfo.Key, *stats)
 				}
 			case <-ctx.Done():
 				return
@@ -163,6 +194,10 @@ func (tracer *DNSFlowTracer) Run(ctx context.Context, tp *afpacket.TPacket, gTag
 			} else if err := dkout.FeedMeasurement(feedAddr, m); err != nil {
 				l.Error(err)
 			}
+		case logs := <-logCh:
+			if err := dkout.FeedMeasurement(failureLogAddr, logs); err != nil {
+				l.Error(err)
+			}
 		}
 	}
 }
@@ -2,3 +2,187 @@
 // +build linux,amd64,ebpf linux,arm64,ebpf
 
 package dnsflow
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const udpTable = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 3500007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 18001 2 0000000000000000 0
+  200: 0A01A8C0:C350 0A00000A:0035 01 00000000:00000000 00:00000000 00000000     0        0 18002 2 0000000000000000 0
+  300: 00000000:9C40 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 18003 2 0000000000000000 0
+`
+
+func TestParseHexAddr(t *testing.T) {
+	ip, port, err := parseHexAddr("0100007F:0035")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.ParseIP("127.0.0.1")) || port != 53 {
+		t.Errorf("unexpected %s:%d", ip, port)
+	}
+
+	ip, port, err = parseHexAddr("00000000000000000000000001000000:1F90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.ParseIP("::1")) || port != 8080 {
+		t.Errorf("unexpected %s:%d", ip, port)
+	}
+
+	for _, s := range []string{"0100007F", "01007F:0035", "0100007F:XYZ"} {
+		if _, _, err := parseHexAddr(s); err == nil {
+			t.Errorf("expect error on %q", s)
+		}
+	}
+}
+
+func TestFindSockInode(t *testing.T) {
+	cases := []struct {
+		ip    string
+		port  uint16
+		inode uint64
+	}{
+		{"192.168.1.10", 50000, 18002},
+		{"10.0.0.1", 40000, 18003}, // bound on the wildcard address
+		{"127.0.0.53", 53, 18001},
+	}
+
+	for _, tc := range cases {
+		inode, err := findSockInode(strings.NewReader(udpTable), net.ParseIP(tc.ip), tc.port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inode != tc.inode {
+			t.Errorf("%s:%d: expect inode %d, got %d", tc.ip, tc.port, tc.inode, inode)
+		}
+	}
+
+	if _, err := findSockInode(strings.NewReader(udpTable), net.ParseIP("192.168.1.10"), 50001); err == nil {
+		t.Error("expect error on socket not found")
+	}
+}
+
+func TestSockOwnerResolver(t *testing.T) {
+	root := t.TempDir()
+
+	mustWrite := func(data string, elem ...string) {
+		p := filepath.Join(append([]string{root}, elem...)...)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustLink := func(target string, elem ...string) {
+		p := filepath.Join(append([]string{root}, elem...)...)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// host network namespace
+	mustWrite("", "1", "net", "udp")
+	mustLink("net:[1]", "1", "ns", "net")
+
+	// process in the container network namespace
+	mustLink("net:[2]", "123", "ns", "net")
+	mustWrite(udpTable, "123", "net", "udp")
+	mustWrite(`Local:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 192.168.1.10
+        /32 host LOCAL
+`, "123", "net", "fib_trie")
+	mustLink("socket:[18002]", "123", "fd", "3")
+	mustLink("/dev/null", "123", "fd", "0")
+	mustWrite("curl\n", "123", "comm")
+	mustWrite("0::/kubepods/pod1/"+strings.Repeat("ab", 32)+"\n", "123", "cgroup")
+
+	r := newSockOwnerResolver(root)
+	now := time.Now()
+
+	p := r.Lookup(net.ParseIP("192.168.1.10"), 50000, true, now)
+	if p == nil {
+		t.Fatal("process not found")
+	}
+	if p.PID != 123 || p.Name != "curl" || p.ContainerID != strings.Repeat("ab", 32) {
+		t.Errorf("unexpected process %+v", p)
+	}
+
+	if p := r.Lookup(net.ParseIP("192.168.1.10"), 50001, true, now); p != nil {
+		t.Errorf("unexpected process %+v", p)
+	}
+
+	if p := r.Lookup(net.ParseIP("10.0.0.1"), 50000, true, now); p != nil {
+		t.Errorf("unexpected process %+v of host network namespace", p)
+	}
+}
+
+func TestFailureAgg(t *testing.T) {
+	key := DNSQAKey{IsUDP: true, IsV4: true, ClientPort: 50000, ServerPort: 53}
+	key.ClientIP[3] = 0x0A01A8C0 // 192.168.1.10
+	key.ServerIP[3] = 0x0A00000A // 10.0.0.10
+
+	ts := time.Unix(1700000000, 0)
+	proc := &ProcInfo{PID: 123, Name: "curl"}
+
+	agg := FailureAgg{}
+	agg.Append(key, DNSStats{TS: ts, RCODE: 3, QName: "foo.svc", Proc: proc})
+	agg.Append(key, DNSStats{TS: ts, RCODE: 3, QName: "foo.svc", Proc: proc})
+	agg.Append(key, DNSStats{TS: ts, RCODE: -1, QName: "bar.svc"})
+
+	if agg.Len() != 2 {
+		t.Fatalf("expect 2 failures, got %d", agg.Len())
+	}
+
+	pts := agg.ToPoint(map[string]string{"host": "node-1"}, nil)
+	if len(pts) != 2 {
+		t.Fatalf("expect 2 points, got %d", len(pts))
+	}
+
+	for _, pt := range pts {
+		if pt.Name() != srcNameLog {
+			t.Errorf("unexpected name %s", pt.Name())
+		}
+
+		tags := pt.Tags()
+		fields, err := pt.Fields()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tags["host"] != "node-1" || tags["src_ip"] != "192.168.1.10" || tags["dst_ip"] != "10.0.0.10" {
+			t.Errorf("unexpected tags %v", tags)
+		}
+
+		switch tags["dns_query"] {
+		case "foo.svc":
+			if tags["rcode"] != "NXDomain" || tags["process_name"] != "curl" ||
+				fields["count"] != int64(2) || fields["status"] != "warning" || fields["pid"] != int64(123) {
+				t.Errorf("unexpected point %s", pt.String())
+			}
+		case "bar.svc":
+			if tags["rcode"] != "Timeout" || fields["status"] != "error" ||
+				fields["message"] != `DNS query "bar.svc" from 192.168.1.10 unknown process to resolver 10.0.0.10 failed: Timeout, 1 times` {
+				t.Errorf("unexpected point %s", pt.String())
+			}
+		default:
+			t.Errorf("unexpected point %s", pt.String())
+		}
+	}
+
+	agg.Clean()
+	if agg.Len() != 0 {
+		t.Error("expect empty after clean")
+	}
+}
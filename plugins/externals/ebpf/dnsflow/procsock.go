@@ -0,0 +1,302 @@
+//go:build (linux && amd64 && ebpf) || (linux && arm64 && ebpf)
+// +build linux,amd64,ebpf linux,arm64,ebpf
+
+package dnsflow
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	procRescanInterval = time.Second * 30
+	procRescanMinGap   = time.Second * 2
+)
+
+var procRoot = func() string {
+	if v, ok := os.LookupEnv("HOST_PROC"); ok && v != "" {
+		return v
+	}
+	return "/proc"
+}()
+
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// ProcInfo is the process owning the socket of the DNS query.
+type ProcInfo struct {
+	PID         int
+	Name        string
+	ContainerID string
+}
+
+// sockOwnerResolver finds the process of the DNS client socket by the
+// socket tables and fd links in procfs. The DNS client sockets are usually
+// short-lived, so it's best effort: the socket may have been closed when
+// looking up.
+type sockOwnerResolver struct {
+	root string
+
+	netnsPID map[string]int    // local ip -> pid in the network namespace
+	inodePID map[uint64]int    // socket inode -> pid
+	procs    map[int]*ProcInfo // pid -> process
+
+	lastScan time.Time
+}
+
+func newSockOwnerResolver(root string) *sockOwnerResolver {
+	return &sockOwnerResolver{
+		root:     root,
+		netnsPID: map[string]int{},
+		inodePID: map[uint64]int{},
+		procs:    map[int]*ProcInfo{},
+	}
+}
+
+// Lookup returns the process owning the socket bound to ip:port, nil if not found.
+func (r *sockOwnerResolver) Lookup(ip net.IP, port uint16, udp bool, now time.Time) *ProcInfo {
+	if now.Sub(r.lastScan) > procRescanInterval {
+		r.scan(now)
+	}
+
+	inode, ok := r.sockInode(ip, port, udp)
+	if !ok {
+		return nil
+	}
+
+	pid, ok := r.inodePID[inode]
+	if !ok && now.Sub(r.lastScan) > procRescanMinGap {
+		r.scan(now)
+		pid, ok = r.inodePID[inode]
+	}
+	if !ok {
+		return nil
+	}
+
+	return r.proc(pid)
+}
+
+// sockInode looks up the socket table of the network namespace the ip lives in.
+func (r *sockOwnerResolver) sockInode(ip net.IP, port uint16, udp bool) (uint64, bool) {
+	pid, ok := r.netnsPID[ip.String()]
+	if !ok {
+		pid = 1 // host network namespace
+	}
+
+	name := "tcp"
+	if udp {
+		name = "udp"
+	}
+	if ip.To4() == nil {
+		name += "6"
+	}
+
+	f, err := os.Open(filepath.Join(r.root, strconv.Itoa(pid), "net", name)) //nolint:gosec
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	inode, err := findSockInode(f, ip, port)
+	if err != nil {
+		return 0, false
+	}
+
+	return inode, true
+}
+
+// scan refreshes the network namespaces and socket inodes of all processes.
+func (r *sockOwnerResolver) scan(now time.Time) {
+	r.lastScan = now
+
+	entries, err := os.ReadDir(r.root)
+	if err != nil {
+		l.Debugf("read %s: %s", r.root, err)
+		return
+	}
+
+	netnsPID := map[string]int{}
+	inodePID := map[uint64]int{}
+	seenNetns := map[string]bool{}
+
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+
+		pidDir := filepath.Join(r.root, e.Name())
+
+		if ns, err := os.Readlink(filepath.Join(pidDir, "ns", "net")); err == nil && !seenNetns[ns] {
+			seenNetns[ns] = true
+			if f, err := os.Open(filepath.Join(pidDir, "net", "fib_trie")); err == nil { //nolint:gosec
+				ips, _ := parseFibTrieLocalIPs(f)
+				_ = f.Close() //nolint:errcheck,gosec
+				for _, ip := range ips {
+					netnsPID[ip] = pid
+				}
+			}
+		}
+
+		fds, err := os.ReadDir(filepath.Join(pidDir, "fd"))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(pidDir, "fd", fd.Name()))
+			if err != nil {
+				continue
+			}
+			if inode, ok := parseSocketLink(link); ok {
+				inodePID[inode] = pid
+			}
+		}
+	}
+
+	r.netnsPID = netnsPID
+	r.inodePID = inodePID
+	r.procs = map[int]*ProcInfo{}
+}
+
+func (r *sockOwnerResolver) proc(pid int) *ProcInfo {
+	if p, ok := r.procs[pid]; ok {
+		return p
+	}
+
+	p := &ProcInfo{PID: pid}
+	pidDir := filepath.Join(r.root, strconv.Itoa(pid))
+
+	if comm, err := os.ReadFile(filepath.Join(pidDir, "comm")); err == nil { //nolint:gosec
+		p.Name = strings.TrimSpace(string(comm))
+	}
+
+	if f, err := os.Open(filepath.Join(pidDir, "cgroup")); err == nil { //nolint:gosec
+		p.ContainerID = parseContainerID(f)
+		_ = f.Close() //nolint:errcheck,gosec
+	}
+
+	r.procs[pid] = p
+	return p
+}
+
+// parseSocketLink parses the fd link such as `socket:[12345]`.
+func parseSocketLink(link string) (uint64, bool) {
+	if !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+
+	inode, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return inode, true
+}
+
+// findSockInode finds the inode of the socket bound to ip:port(or the
+// wildcard address) in /proc/<pid>/net/{tcp,udp}[6].
+func findSockInode(r io.Reader, ip net.IP, port uint16) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		localIP, localPort, err := parseHexAddr(fields[1])
+		if err != nil || localPort != port {
+			continue
+		}
+
+		if !localIP.Equal(ip) && !localIP.IsUnspecified() {
+			continue
+		}
+
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+
+		return inode, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("socket of %s:%d not found", ip, port)
+}
+
+// parseHexAddr parses the address such as `0100007F:0035`, the ip is
+// in host byte order(little-endian) of 32-bit words.
+func parseHexAddr(s string) (net.IP, uint16, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+
+	b, err := hex.DecodeString(s[:i])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+
+	for j := 0; j < len(b); j += 4 {
+		b[j], b[j+1], b[j+2], b[j+3] = b[j+3], b[j+2], b[j+1], b[j]
+	}
+
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+
+	return net.IP(b), uint16(port), nil
+}
+
+// parseFibTrieLocalIPs returns the non-loopback local IPv4 addresses found
+// in /proc/<pid>/net/fib_trie.
+func parseFibTrieLocalIPs(r io.Reader) ([]string, error) {
+	var (
+		last string
+		ips  []string
+		seen = map[string]bool{}
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "|--"):
+			last = strings.TrimSpace(strings.TrimPrefix(line, "|--"))
+		case strings.HasPrefix(line, "/32 host LOCAL"):
+			if last != "" && !strings.HasPrefix(last, "127.") && !seen[last] {
+				seen[last] = true
+				ips = append(ips, last)
+			}
+		}
+	}
+
+	return ips, scanner.Err()
+}
+
+// parseContainerID extracts the container ID from /proc/<pid>/cgroup.
+func parseContainerID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if id := containerIDRegexp.FindString(scanner.Text()); id != "" {
+			return id
+		}
+	}
+	return ""
+}
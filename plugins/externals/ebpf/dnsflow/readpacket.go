@@ -93,6 +93,9 @@ type DNSStats struct {
 	RespTime  time.Duration
 	Timeout   bool
 	Responded bool
+
+	QName string    // name of the first question
+	Proc  *ProcInfo // process of the client socket, nil if not found
 }
 
 type DNSQAKey struct {
@@ -110,6 +113,7 @@ type DNSPacketInfo struct {
 	QR      bool // query(false) response(true)
 	RCODE   uint8
 	TS      time.Time
+	QName   string
 	Answers []layers.DNSResourceRecord
 }
 
@@ -145,6 +149,9 @@ func ReadPacketInfoFromDNSParser(ts time.Time, dnsParser *DNSParser) (*DNSPacket
 			pinfo.QR = dnsParser.dns.QR
 			pinfo.RCODE = uint8(dnsParser.dns.ResponseCode)
 			pinfo.Answers = dnsParser.dns.Answers
+			if len(dnsParser.dns.Questions) > 0 {
+				pinfo.QName = string(dnsParser.dns.Questions[0].Name)
+			}
 			haveDNSLayer = true
 		case gopacket.LayerTypeDecodeFailure, gopacket.LayerTypeFragment,
 			gopacket.LayerTypePayload, gopacket.LayerTypeZero:
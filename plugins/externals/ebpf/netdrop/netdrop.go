@@ -0,0 +1,374 @@
+//go:build (linux && amd64 && ebpf) || (linux && arm64 && ebpf)
+// +build linux,amd64,ebpf linux,arm64,ebpf
+
+package netdrop
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	tc "github.com/florianl/go-tc"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/hostutil/procnet"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/k8sinfo"
+	dkout "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/externals/ebpf/output"
+)
+
+const (
+	srcNameM = "netdrop"
+	noValue  = "N/A"
+)
+
+var l = logger.DefaultSLogger("ebpf")
+
+func SetLogger(nl *logger.Logger) {
+	l = nl
+}
+
+var k8sNetInfo *k8sinfo.K8sNetInfo
+
+func SetK8sNetInfo(n *k8sinfo.K8sNetInfo) {
+	k8sNetInfo = n
+}
+
+// netnsStats holds the counters of one network namespace, all fields except
+// the gauges (qdiscBacklog, qdiscQlen, conntrackEntries) are cumulative.
+type netnsStats struct {
+	dev devStats
+
+	qdiscDrops      int64
+	qdiscOverlimits int64
+	qdiscRequeues   int64
+	qdiscBacklog    int64
+	qdiscQlen       int64
+
+	tcpRetransSegs     int64
+	tcpOutSegs         int64
+	tcpListenDrops     int64
+	tcpListenOverflows int64
+	tcpBacklogDrop     int64
+
+	conntrack procnet.ConntrackStat
+}
+
+type netnsInfo struct {
+	inode uint64
+	pid   int
+}
+
+type NetDropTracer struct {
+	gTags   map[string]string
+	postURL string
+
+	hostNetns uint64
+	last      map[uint64]*netnsStats
+}
+
+func NewNetDropTracer(gTags map[string]string, postURL string) *NetDropTracer {
+	return &NetDropTracer{
+		gTags:   gTags,
+		postURL: postURL,
+		last:    map[uint64]*netnsStats{},
+	}
+}
+
+func (tracer *NetDropTracer) Run(ctx context.Context, interval time.Duration) {
+	if inode, err := procnet.NetnsInode(1); err != nil {
+		l.Warnf("get host netns: %s", err.Error())
+	} else {
+		tracer.hostNetns = inode
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// first round only records the baseline of the counters
+	tracer.collect(time.Now())
+
+	for {
+		select {
+		case <-ticker.C:
+			pts := tracer.collect(time.Now())
+			if len(pts) == 0 {
+				continue
+			}
+			if err := dkout.FeedMeasurement(tracer.postURL, pts); err != nil {
+				l.Debug(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (tracer *NetDropTracer) collect(ts time.Time) []*point.Point {
+	nsList, err := listNetns()
+	if err != nil {
+		l.Error(err)
+		return nil
+	}
+
+	var pts []*point.Point
+	current := make(map[uint64]*netnsStats, len(nsList))
+
+	for _, ns := range nsList {
+		stats, err := readNetnsStats(ns.pid)
+		if err != nil {
+			l.Debugf("read netns %d stats of pid %d: %s", ns.inode, ns.pid, err.Error())
+			continue
+		}
+		current[ns.inode] = stats
+
+		prev, ok := tracer.last[ns.inode]
+		if !ok {
+			continue
+		}
+
+		pt, err := point.NewPoint(srcNameM, tracer.tags(ns), statsFields(prev, stats),
+			&point.PointOption{Category: datakit.Network, Time: ts})
+		if err != nil {
+			l.Debug(err)
+			continue
+		}
+		pts = append(pts, pt)
+	}
+
+	// namespaces that disappeared are dropped together with the old map
+	tracer.last = current
+
+	return pts
+}
+
+func (tracer *NetDropTracer) tags(ns *netnsInfo) map[string]string {
+	tags := map[string]string{
+		"netns":        strconv.FormatUint(ns.inode, 10),
+		"pid":          strconv.Itoa(ns.pid),
+		"host_network": strconv.FormatBool(ns.inode == tracer.hostNetns),
+		"source":       srcNameM,
+	}
+
+	if comm, err := os.ReadFile(procnet.Path(ns.pid, "comm")); err == nil {
+		tags["process_name"] = strings.TrimSpace(string(comm))
+	} else {
+		tags["process_name"] = noValue
+	}
+
+	_ = procnet.ReadFile(ns.pid, func(r io.Reader) error {
+		if id := parseContainerID(r); id != "" {
+			tags["container_id"] = id
+		}
+		return nil
+	}, "cgroup")
+
+	// pods sharing the host network can not be told apart by IP
+	if k8sNetInfo != nil && ns.inode != tracer.hostNetns {
+		var ips []string
+		_ = procnet.ReadFile(ns.pid, func(r io.Reader) (err error) {
+			ips, err = parseFibTrieLocalIPs(r)
+			return
+		}, "net", "fib_trie")
+
+		for _, ip := range ips {
+			name, svc, namespace, deployment, err := k8sNetInfo.QueryPodInfo(ip, 0, "tcp")
+			if err != nil {
+				continue
+			}
+
+			tags["ip"] = ip
+			tags["k8s_pod_name"] = name
+			tags["k8s_namespace"] = namespace
+			if svc != "" {
+				tags["k8s_service_name"] = svc
+			}
+			if deployment != "" {
+				tags["k8s_deployment_name"] = deployment
+			}
+			tags["sub_source"] = "K8s"
+			break
+		}
+	}
+
+	for k, v := range tracer.gTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	return tags
+}
+
+func delta(prev, cur int64) int64 {
+	if cur < prev { // counter reset
+		return cur
+	}
+	return cur - prev
+}
+
+func statsFields(prev, cur *netnsStats) map[string]any {
+	fields := map[string]any{
+		"rx_dropped": delta(prev.dev.rxDropped, cur.dev.rxDropped),
+		"tx_dropped": delta(prev.dev.txDropped, cur.dev.txDropped),
+		"rx_errors":  delta(prev.dev.rxErrors, cur.dev.rxErrors),
+		"tx_errors":  delta(prev.dev.txErrors, cur.dev.txErrors),
+
+		"qdisc_drops":      delta(prev.qdiscDrops, cur.qdiscDrops),
+		"qdisc_overlimits": delta(prev.qdiscOverlimits, cur.qdiscOverlimits),
+		"qdisc_requeues":   delta(prev.qdiscRequeues, cur.qdiscRequeues),
+		"qdisc_backlog":    cur.qdiscBacklog,
+		"qdisc_qlen":       cur.qdiscQlen,
+
+		"tcp_retrans_segs":     delta(prev.tcpRetransSegs, cur.tcpRetransSegs),
+		"tcp_out_segs":         delta(prev.tcpOutSegs, cur.tcpOutSegs),
+		"tcp_listen_drops":     delta(prev.tcpListenDrops, cur.tcpListenDrops),
+		"tcp_listen_overflows": delta(prev.tcpListenOverflows, cur.tcpListenOverflows),
+		"tcp_backlog_drop":     delta(prev.tcpBacklogDrop, cur.tcpBacklogDrop),
+
+		"conntrack_entries":       cur.conntrack.Entries,
+		"conntrack_drop":          delta(prev.conntrack.Drop, cur.conntrack.Drop),
+		"conntrack_early_drop":    delta(prev.conntrack.EarlyDrop, cur.conntrack.EarlyDrop),
+		"conntrack_insert_failed": delta(prev.conntrack.InsertFailed, cur.conntrack.InsertFailed),
+	}
+
+	if max := conntrackMax(); max > 0 {
+		fields["conntrack_max"] = max
+		fields["conntrack_usage"] = float64(cur.conntrack.Entries) / float64(max) * 100
+	}
+
+	return fields
+}
+
+// listNetns returns one process for each network namespace on the host,
+// ordered by pid.
+func listNetns() ([]*netnsInfo, error) {
+	pids, err := procnet.NetnsPids()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*netnsInfo, 0, len(pids))
+	for inode, pid := range pids {
+		res = append(res, &netnsInfo{inode: inode, pid: pid})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].pid < res[j].pid })
+
+	return res, nil
+}
+
+func readNetnsStats(pid int) (*netnsStats, error) {
+	stats := &netnsStats{}
+
+	if err := procnet.ReadFile(pid, func(r io.Reader) error {
+		dev, err := parseNetDev(r)
+		if err != nil {
+			return err
+		}
+		stats.dev = *dev
+		return nil
+	}, "net", "dev"); err != nil {
+		return nil, err
+	}
+
+	if err := procnet.ReadFile(pid, func(r io.Reader) error {
+		tcp, err := procnet.ParseKeyedTable(r, "Tcp:")
+		if err != nil {
+			return err
+		}
+		stats.tcpRetransSegs = tcp["RetransSegs"]
+		stats.tcpOutSegs = tcp["OutSegs"]
+		return nil
+	}, "net", "snmp"); err != nil {
+		return nil, err
+	}
+
+	if err := procnet.ReadFile(pid, func(r io.Reader) error {
+		tcpExt, err := procnet.ParseKeyedTable(r, "TcpExt:")
+		if err != nil {
+			return err
+		}
+		stats.tcpListenDrops = tcpExt["ListenDrops"]
+		stats.tcpListenOverflows = tcpExt["ListenOverflows"]
+		stats.tcpBacklogDrop = tcpExt["TCPBacklogDrop"]
+		return nil
+	}, "net", "netstat"); err != nil {
+		l.Debugf("read net/netstat of pid %d: %s", pid, err.Error())
+	}
+
+	// nf_conntrack module may not be loaded
+	if err := procnet.ReadFile(pid, func(r io.Reader) error {
+		ct, err := procnet.ParseConntrackStat(r)
+		if err != nil {
+			return err
+		}
+		stats.conntrack = *ct
+		return nil
+	}, "net", "stat", "nf_conntrack"); err != nil {
+		l.Debugf("read net/stat/nf_conntrack of pid %d: %s", pid, err.Error())
+	}
+
+	if err := readQdiscStats(pid, stats); err != nil {
+		l.Debugf("read qdisc stats of pid %d: %s", pid, err.Error())
+	}
+
+	return stats, nil
+}
+
+// readQdiscStats sums the statistics of all qdiscs inside the network namespace
+// of the process through rtnetlink.
+func readQdiscStats(pid int, stats *netnsStats) error {
+	f, err := os.Open(procnet.Path(pid, "ns", "net")) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	rtnl, err := tc.Open(&tc.Config{NetNS: int(f.Fd())})
+	if err != nil {
+		return err
+	}
+	defer rtnl.Close() //nolint:errcheck
+
+	qdiscs, err := rtnl.Qdisc().Get()
+	if err != nil {
+		return err
+	}
+
+	for _, q := range qdiscs {
+		switch {
+		case q.Stats2 != nil:
+			stats.qdiscDrops += int64(q.Stats2.Drops)
+			stats.qdiscOverlimits += int64(q.Stats2.Overlimits)
+			stats.qdiscRequeues += int64(q.Stats2.Requeues)
+			stats.qdiscBacklog += int64(q.Stats2.Backlog)
+			stats.qdiscQlen += int64(q.Stats2.Qlen)
+		case q.Stats != nil:
+			stats.qdiscDrops += int64(q.Stats.Drops)
+			stats.qdiscOverlimits += int64(q.Stats.Overlimits)
+			stats.qdiscBacklog += int64(q.Stats.Backlog)
+			stats.qdiscQlen += int64(q.Stats.Qlen)
+		}
+	}
+
+	return nil
+}
+
+// conntrackMax returns the size of the conntrack table, which is shared by all
+// network namespaces.
+func conntrackMax() int64 {
+	b, err := os.ReadFile(procnet.Root + "/sys/net/netfilter/nf_conntrack_max")
+	if err != nil {
+		return 0
+	}
+
+	v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
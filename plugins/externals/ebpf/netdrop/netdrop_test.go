@@ -0,0 +1,88 @@
+//go:build (linux && amd64 && ebpf) || (linux && arm64 && ebpf)
+// +build linux,amd64,ebpf linux,arm64,ebpf
+
+package netdrop
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetDev(t *testing.T) {
+	data := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    9    0     0          0         0   123456     100    0    9    0     0       0          0
+  eth0: 9876543    2000    1    3    0     0          0         0  1234567    1500    2    4    0     0       0          0
+  eth1:    1000      10    0    2    0     0          0         0     1000      10    0    5    0     0       0          0
+`
+	stats, err := parseNetDev(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &devStats{rxDropped: 5, txDropped: 9, rxErrors: 1, txErrors: 2}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestParseFibTrieLocalIPs(t *testing.T) {
+	data := `Main:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 0.0.0.0
+        /0 universe UNICAST
+     +-- 10.244.1.0/24 2 0 2
+        |-- 10.244.1.0
+           /24 link UNICAST
+        |-- 10.244.1.17
+           /32 host LOCAL
+     +-- 127.0.0.0/8 2 0 2
+        |-- 127.0.0.1
+           /32 host LOCAL
+Local:
+  +-- 0.0.0.0/0 3 0 5
+        |-- 10.244.1.17
+           /32 host LOCAL
+`
+	ips, err := parseFibTrieLocalIPs(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ips, []string{"10.244.1.17"}) {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+}
+
+func TestParseContainerID(t *testing.T) {
+	id := "3f4c5e1d2b7a8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d"
+	data := "12:pids:/kubepods/besteffort/pod1234/" + id + "\n0::/\n"
+
+	if got := parseContainerID(strings.NewReader(data)); got != id {
+		t.Errorf("expected %s, got %s", id, got)
+	}
+
+	if got := parseContainerID(strings.NewReader("0::/init.scope\n")); got != "" {
+		t.Errorf("expected empty, got %s", got)
+	}
+}
+
+func TestStatsFields(t *testing.T) {
+	prev := &netnsStats{tcpRetransSegs: 10, qdiscDrops: 100}
+	cur := &netnsStats{tcpRetransSegs: 15, qdiscDrops: 20, qdiscBacklog: 3}
+
+	fields := statsFields(prev, cur)
+	if fields["tcp_retrans_segs"] != int64(5) {
+		t.Errorf("unexpected tcp_retrans_segs: %v", fields["tcp_retrans_segs"])
+	}
+
+	// counter reset
+	if fields["qdisc_drops"] != int64(20) {
+		t.Errorf("unexpected qdisc_drops: %v", fields["qdisc_drops"])
+	}
+
+	if fields["qdisc_backlog"] != int64(3) {
+		t.Errorf("unexpected qdisc_backlog: %v", fields["qdisc_backlog"])
+	}
+}
@@ -0,0 +1,3 @@
+// Package netdrop collects per network namespace packet drop, TCP retransmit
+// and conntrack table pressure metrics, attributed to container/pod
+package netdrop
@@ -0,0 +1,100 @@
+//go:build (linux && amd64 && ebpf) || (linux && arm64 && ebpf)
+// +build linux,amd64,ebpf linux,arm64,ebpf
+
+package netdrop
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+type devStats struct {
+	rxDropped int64
+	txDropped int64
+	rxErrors  int64
+	txErrors  int64
+}
+
+// parseNetDev sums the drop/error counters of all non-loopback interfaces
+// listed in /proc/<pid>/net/dev.
+func parseNetDev(r io.Reader) (*devStats, error) {
+	stats := &devStats{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue // header lines
+		}
+
+		if strings.TrimSpace(line[:colon]) == "lo" {
+			continue
+		}
+
+		// receive: bytes packets errs drop fifo frame compressed multicast
+		// transmit: bytes packets errs drop fifo colls carrier compressed
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("invalid net/dev line: %q", line)
+		}
+
+		vals := make([]int64, 16)
+		for i := range vals {
+			v, err := strconv.ParseInt(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid net/dev line %q: %w", line, err)
+			}
+			vals[i] = v
+		}
+
+		stats.rxErrors += vals[2]
+		stats.rxDropped += vals[3]
+		stats.txErrors += vals[10]
+		stats.txDropped += vals[11]
+	}
+
+	return stats, scanner.Err()
+}
+
+// parseFibTrieLocalIPs returns the non-loopback local IPv4 addresses found
+// in /proc/<pid>/net/fib_trie, which are used to match the pod of the namespace.
+func parseFibTrieLocalIPs(r io.Reader) ([]string, error) {
+	var (
+		last string
+		ips  []string
+		seen = map[string]bool{}
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "|--"):
+			last = strings.TrimSpace(strings.TrimPrefix(line, "|--"))
+		case strings.HasPrefix(line, "/32 host LOCAL"):
+			if last != "" && !strings.HasPrefix(last, "127.") && !seen[last] {
+				seen[last] = true
+				ips = append(ips, last)
+			}
+		}
+	}
+
+	return ips, scanner.Err()
+}
+
+// parseContainerID extracts the container ID from /proc/<pid>/cgroup.
+func parseContainerID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if id := containerIDRegexp.FindString(scanner.Text()); id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -36,6 +36,7 @@ var (
 	globalService            = os.Getenv("LOGFWD_GLOBAL_SERVICE")
 	podName                  = os.Getenv("LOGFWD_POD_NAME")
 	podNamespace             = os.Getenv("LOGFWD_POD_NAMESPACE")
+	podLabels                = os.Getenv("LOGFWD_POD_LABELS")
 	wsHost                   = os.Getenv("LOGFWD_DATAKIT_HOST")
 	wsPort                   = os.Getenv("LOGFWD_DATAKIT_PORT")
 	envMainJSONConfig        = os.Getenv("LOGFWD_JSON_CONFIG")
@@ -151,6 +152,7 @@ func forwardFunc(lg *logging, fn writeMessage) tailer.ForwardFunc {
 			Pipeline: lg.Pipeline,
 			Log:      text,
 			Tags:     make(map[string]string),
+			Meta:     lg.Meta,
 		}
 
 		msg.Tags["filename"] = filename
@@ -250,6 +252,7 @@ type logging struct {
 	MultilineMatch        string            `json:"multiline_match"`
 	RemoveAnsiEscapeCodes bool              `json:"remove_ansi_escape_codes"`
 	Tags                  map[string]string `json:"tags"`
+	Meta                  map[string]string `json:"meta"`
 }
 
 func (lg *logging) merge(cfgs loggings) {
@@ -299,6 +302,27 @@ func (lg *logging) setup() {
 		lg.Tags["image_short_name"] = imageShortName
 		lg.Tags["image_tag"] = imageTag
 	}
+
+	if lg.Meta == nil {
+		lg.Meta = make(map[string]string)
+	}
+
+	// source manifest
+	lg.Meta["source"] = lg.Source
+	lg.Meta["service"] = lg.Service
+	if lg.Pipeline != "" {
+		lg.Meta["pipeline"] = lg.Pipeline
+	}
+
+	if podLabels != "" {
+		labels := make(map[string]string)
+		if err := json.Unmarshal([]byte(podLabels), &labels); err != nil {
+			l.Warnf("invalid LOGFWD_POD_LABELS %q: %s, ignored", podLabels, err)
+		}
+		for k, v := range labels {
+			lg.Meta["label_"+k] = v
+		}
+	}
 }
 
 type message struct {
@@ -306,6 +330,7 @@ type message struct {
 	Source   string            `json:"source"`
 	Pipeline string            `json:"pipeline,omitempty"`
 	Tags     map[string]string `json:"tags,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
 	Log      string            `json:"log"`
 }
 
@@ -277,6 +277,10 @@ func TestLoggingSetup(t *testing.T) {
 					"pod_name":      "test_pod_name",
 					"service":       "t_source",
 				},
+				Meta: map[string]string{
+					"source":  "t_source",
+					"service": "t_source",
+				},
 			},
 		},
 		{
@@ -291,6 +295,10 @@ func TestLoggingSetup(t *testing.T) {
 					"pod_name":      "test_pod_name",
 					"service":       "t_service",
 				},
+				Meta: map[string]string{
+					"source":  "default",
+					"service": "t_service",
+				},
 			},
 		},
 		{
@@ -303,6 +311,10 @@ func TestLoggingSetup(t *testing.T) {
 					"pod_name":      "test_pod_name",
 					"service":       "default",
 				},
+				Meta: map[string]string{
+					"source":  "default",
+					"service": "default",
+				},
 			},
 		},
 	}
@@ -334,3 +346,32 @@ func TestMessageToJSON(t *testing.T) {
 		assert.Equal(t, tc.out, out)
 	}
 }
+
+func TestSetupMeta(t *testing.T) {
+	podLabels = `{"app":"nginx","app.kubernetes.io/version":"1.22"}`
+	defer func() { podLabels = "" }()
+
+	lg := &logging{
+		Source:   "t_source",
+		Pipeline: "t_pipeline",
+		Meta:     map[string]string{"team": "ops"},
+	}
+	lg.setup()
+
+	assert.Equal(t, map[string]string{
+		"source":                          "t_source",
+		"service":                         "t_source",
+		"pipeline":                        "t_pipeline",
+		"team":                            "ops",
+		"label_app":                       "nginx",
+		"label_app.kubernetes.io/version": "1.22",
+	}, lg.Meta)
+
+	var got string
+	err := forwardFunc(lg, func(b []byte) error {
+		got = string(b)
+		return nil
+	})("/tmp/111", "hello")
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"meta":{"label_app":"nginx",`)
+}
@@ -9,7 +9,10 @@ package inputs
 import (
 	// nolint:typecheck
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/apache"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/backupcheck"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/beats_output"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/cassandra"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/clickhouse"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/clickhousev1"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/cloudprober"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/consul"
@@ -26,7 +29,9 @@ import (
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/etcd"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/external"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/flinkv1"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/fluentforward"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/gitlab"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/graphite"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/hostdir"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/hostobject"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/iis"
@@ -34,23 +39,31 @@ import (
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/ipmi"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/jaeger"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/jenkins"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/jmx"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/jvm"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/k8sevents"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/kafka"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/kafkamq"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/logfwdserver"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/logging"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/loginaudit"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/logstreaming"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mem"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/memcached"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mongodb"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mongodbatlas"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mysql"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/net"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/netnsstat"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/netscan"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/netstat"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/nginx"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/nsq"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/nvidiasmi"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentsdb"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/oracle"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/osupdate"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/postgresql"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/process"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/profile"
@@ -73,9 +86,11 @@ import (
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/ssh"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/statsd"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/swap"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/syslog"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/system"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/tdengine"
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/tomcat"
+	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/vsphere"
 
 	// only windows.
 	_ "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/winevent"
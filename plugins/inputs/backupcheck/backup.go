@@ -0,0 +1,375 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package backupcheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+
+	// drivers of catalog databases.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const ossScheme = "oss://"
+
+// backupInfo is the latest backup found.
+type backupInfo struct {
+	time time.Time
+	size int64  // bytes, -1 if unknown
+	path string // file or object of the backup, empty for catalog
+}
+
+// catalog checks backups recorded in catalog tables of the database, such as
+// backup history of Percona XtraBackup or pgBackRest.
+type catalog struct {
+	Driver string `toml:"driver"`
+	DSN    string `toml:"dsn"`
+	Query  string `toml:"query"`
+
+	db *sql.DB
+}
+
+func (c *catalog) open() error {
+	var driver string
+	switch c.Driver {
+	case "mysql":
+		driver = "mysql"
+	case "postgres", "postgresql":
+		driver = "postgres"
+	default:
+		return fmt.Errorf("unknown catalog driver %q, only mysql/postgres supported", c.Driver)
+	}
+
+	if c.Query == "" {
+		return fmt.Errorf("catalog query not set")
+	}
+
+	db, err := sql.Open(driver, c.DSN)
+	if err != nil {
+		return err
+	}
+
+	db.SetMaxOpenConns(1)
+	c.db = db
+	return nil
+}
+
+func (c *catalog) close() {
+	if c.db != nil {
+		if err := c.db.Close(); err != nil {
+			l.Warnf("close catalog db: %s, ignored", err)
+		}
+	}
+}
+
+// latest returns the backup by the first row of the query, whose first
+// column is end time of the backup, and the optional second column is size
+// of the backup in bytes. Nil returned if no row.
+func (c *catalog) latest(ctx context.Context) (*backupInfo, error) {
+	rows, err := c.db.QueryContext(ctx, c.Query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+
+	return parseCatalogRow(vals)
+}
+
+func parseCatalogRow(vals []interface{}) (*backupInfo, error) {
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("no column returned")
+	}
+
+	if vals[0] == nil { // such as MAX() on empty table
+		return nil, nil
+	}
+
+	ts, err := toTime(vals[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup time: %w", err)
+	}
+
+	info := &backupInfo{time: ts, size: -1}
+	if len(vals) > 1 && vals[1] != nil {
+		if info.size, err = toInt(vals[1]); err != nil {
+			return nil, fmt.Errorf("invalid backup size: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// toTime converts time column of the catalog, unix timestamp in seconds
+// accepted. Time without zone is in local time.
+func toTime(v interface{}) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case int64:
+		return time.Unix(x, 0), nil
+	case float64:
+		return time.Unix(int64(x), 0), nil
+	case []byte:
+		return parseTime(string(x))
+	case string:
+		return parseTime(x)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if ts, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return ts, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch x := v.(type) {
+	case int64:
+		return x, nil
+	case float64:
+		return int64(x), nil
+	case []byte:
+		return parseInt(string(x))
+	case string:
+		return parseInt(x)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func parseInt(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64) // such as SUM() of decimal
+	if err != nil {
+		return 0, err
+	}
+	return int64(f), nil
+}
+
+// artifact checks backup files on local disk, or objects on OSS.
+type artifact struct {
+	// Glob of local files, or oss://bucket/prefix* of objects.
+	Path string `toml:"path"`
+
+	OSSEndpoint        string `toml:"oss_endpoint"`
+	OSSAccessKeyID     string `toml:"oss_access_key_id"`
+	OSSAccessKeySecret string `toml:"oss_access_key_secret"`
+
+	bucket *oss.Bucket
+	glob   string // glob of object keys
+}
+
+func (a *artifact) isOSS() bool {
+	return strings.HasPrefix(a.Path, ossScheme)
+}
+
+func (a *artifact) storage() string {
+	if a.isOSS() {
+		return "oss"
+	}
+	return "file"
+}
+
+func (a *artifact) open() error {
+	if a.Path == "" {
+		return fmt.Errorf("artifact path not set")
+	}
+
+	if !a.isOSS() {
+		_, err := filepath.Match(a.Path, "")
+		return err
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(a.Path, ossScheme), "/", 2)
+	if parts[0] == "" {
+		return fmt.Errorf("invalid artifact path %q, bucket not set", a.Path)
+	}
+
+	if len(parts) == 2 {
+		a.glob = parts[1]
+	}
+
+	if _, err := path.Match(a.glob, ""); err != nil {
+		return err
+	}
+
+	cli, err := oss.New(a.OSSEndpoint, a.OSSAccessKeyID, a.OSSAccessKeySecret)
+	if err != nil {
+		return err
+	}
+
+	if a.bucket, err = cli.Bucket(parts[0]); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// latest returns the last modified file/object, nil if nothing matched.
+func (a *artifact) latest(ctx context.Context) (*backupInfo, error) {
+	if a.isOSS() {
+		return a.latestObject(ctx)
+	}
+	return latestFile(a.Path)
+}
+
+// latestFile returns the last modified one of files matched. A directory
+// matched is taken as a backup as a whole, such as output of XtraBackup.
+func latestFile(glob string) (*backupInfo, error) {
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *backupInfo
+	for _, f := range matches {
+		x, err := statBackup(f)
+		if err != nil {
+			return nil, err
+		}
+
+		if info == nil || x.time.After(info.time) {
+			info = x
+		}
+	}
+
+	return info, nil
+}
+
+func statBackup(f string) (*backupInfo, error) {
+	fi, err := os.Stat(f)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &backupInfo{time: fi.ModTime(), size: fi.Size(), path: f}
+	if !fi.IsDir() {
+		return info, nil
+	}
+
+	info.size = 0
+	err = filepath.Walk(f, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() {
+			info.size += fi.Size()
+			if fi.ModTime().After(info.time) {
+				info.time = fi.ModTime()
+			}
+		}
+		return nil
+	})
+
+	return info, err
+}
+
+// globPrefix returns the prefix of glob before any meta characters.
+func globPrefix(glob string) string {
+	if i := strings.IndexAny(glob, `*?[\`); i >= 0 {
+		return glob[:i]
+	}
+	return glob
+}
+
+func (a *artifact) latestObject(ctx context.Context) (*backupInfo, error) {
+	var (
+		info  *backupInfo
+		token string
+	)
+
+	prefix := globPrefix(a.glob)
+	pattern := a.glob
+	if pattern == prefix { // no meta characters, all objects under prefix
+		pattern = ""
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		res, err := a.bucket.ListObjectsV2(oss.Prefix(prefix), oss.ContinuationToken(token), oss.MaxKeys(1000))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range res.Objects {
+			if strings.HasSuffix(obj.Key, "/") { // folder
+				continue
+			}
+
+			if pattern != "" {
+				if ok, _ := path.Match(pattern, obj.Key); !ok {
+					continue
+				}
+			}
+
+			if info == nil || obj.LastModified.After(info.time) {
+				info = &backupInfo{
+					time: obj.LastModified,
+					size: obj.Size,
+					path: ossScheme + a.bucket.BucketName + "/" + obj.Key,
+				}
+			}
+		}
+
+		if !res.IsTruncated {
+			return info, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
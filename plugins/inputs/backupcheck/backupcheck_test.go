@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package backupcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+func TestParseCatalogRow(t *T.T) {
+	ts := time.Date(2023, 6, 1, 2, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		vals   []interface{}
+		expect *backupInfo
+		fail   bool
+	}{
+		{
+			name:   "time-and-size",
+			vals:   []interface{}{ts, int64(1024)},
+			expect: &backupInfo{time: ts, size: 1024},
+		},
+		{
+			name:   "mysql-bytes",
+			vals:   []interface{}{[]byte("2023-06-01T02:00:00Z"), []byte("1024.00")},
+			expect: &backupInfo{time: ts, size: 1024},
+		},
+		{
+			name:   "postgres-text",
+			vals:   []interface{}{"2023-06-01 02:00:00+00"},
+			expect: &backupInfo{time: ts, size: -1},
+		},
+		{
+			name:   "unix-seconds",
+			vals:   []interface{}{ts.Unix(), nil},
+			expect: &backupInfo{time: time.Unix(ts.Unix(), 0), size: -1},
+		},
+		{
+			name: "max-of-empty-table",
+			vals: []interface{}{nil},
+		},
+		{
+			name: "invalid-time",
+			vals: []interface{}{"yesterday"},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			info, err := parseCatalogRow(tc.vals)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tc.expect == nil {
+				assert.Nil(t, info)
+				return
+			}
+
+			assert.True(t, tc.expect.time.Equal(info.time), "expect %s, got %s", tc.expect.time, info.time)
+			assert.Equal(t, tc.expect.size, info.size)
+		})
+	}
+}
+
+func TestLatestFile(t *T.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	write := func(name string, size int, mtime time.Time) {
+		f := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), os.ModePerm))
+		require.NoError(t, os.WriteFile(f, make([]byte, size), 0o600))
+		require.NoError(t, os.Chtimes(f, mtime, mtime))
+	}
+
+	write("base-1.tar.gz", 10, now.Add(-48*time.Hour))
+	write("base-2.tar.gz", 20, now.Add(-time.Hour))
+	write("base-3.tmp", 30, now)
+
+	info, err := latestFile(filepath.Join(dir, "base-*.tar.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "base-2.tar.gz"), info.path)
+	assert.Equal(t, int64(20), info.size)
+
+	// directory as a whole backup
+	write("xtrabackup/2023-06-01/ibdata1", 100, now.Add(-3*time.Hour))
+	write("xtrabackup/2023-06-01/xtrabackup_info", 5, now.Add(-2*time.Hour))
+
+	info, err = latestFile(filepath.Join(dir, "xtrabackup", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(105), info.size)
+	assert.True(t, info.time.After(now.Add(-2*time.Hour-time.Second)))
+
+	info, err = latestFile(filepath.Join(dir, "nothing-*"))
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func TestGlobPrefix(t *T.T) {
+	assert.Equal(t, "mysql/full-", globPrefix("mysql/full-*.xbstream"))
+	assert.Equal(t, "mysql/", globPrefix("mysql/"))
+	assert.Equal(t, "", globPrefix("*"))
+}
+
+func TestCheck(t *T.T) {
+	b := &backup{
+		Name:     "pg",
+		MaxAge:   datakit.Duration{Duration: 26 * time.Hour},
+		MinSize:  100,
+		Artifact: &artifact{Path: "/backup/*.tar.gz"},
+	}
+
+	now := time.Now()
+
+	// fresh backup
+	m, events := b.check(&backupInfo{time: now.Add(-time.Hour), size: 1000}, now)
+	assert.Empty(t, events)
+	assert.Equal(t, false, m.fields["stale"])
+	assert.Equal(t, int64(3600), m.fields["age"])
+	assert.Equal(t, map[string]string{"name": "pg", "source": "artifact", "type": "file"}, m.tags)
+
+	// too old, event sent once
+	m, events = b.check(&backupInfo{time: now.Add(-27 * time.Hour), size: 1000}, now)
+	assert.Equal(t, true, m.fields["stale"])
+	require.Len(t, events, 1)
+	assert.Equal(t, statusWarning, events[0].fields["df_status"])
+	assert.Equal(t, "backupcheck-stale-pg", events[0].fields["df_event_id"])
+	assert.Contains(t, events[0].fields["df_message"], "exceeds max age")
+
+	_, events = b.check(&backupInfo{time: now.Add(-28 * time.Hour), size: 1000}, now)
+	assert.Empty(t, events)
+
+	// recovered
+	_, events = b.check(&backupInfo{time: now, size: 1000}, now)
+	require.Len(t, events, 1)
+	assert.Equal(t, statusOK, events[0].fields["df_status"])
+
+	// too small
+	_, events = b.check(&backupInfo{time: now, size: 10}, now)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].fields["df_message"], "less than min size")
+
+	// size unknown not checked
+	b.stale = false
+	_, events = b.check(&backupInfo{time: now, size: -1}, now)
+	assert.Empty(t, events)
+
+	// no backup
+	m, events = b.check(nil, now)
+	require.Len(t, events, 1)
+	assert.Equal(t, "no backup found", events[0].fields["df_message"])
+	assert.NotContains(t, m.fields, "age")
+}
+
+func TestRestore(t *T.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh not available")
+	}
+
+	info := &backupInfo{time: time.Now(), path: "/backup/base-2.tar.gz"}
+
+	b := &backup{
+		Name: "pg",
+		Restore: &restoreCheck{
+			Command: []string{"sh", "-c", `echo "restoring $BACKUP_NAME from $BACKUP_PATH"; test -n "$FAIL" && exit 1; exit 0`},
+		},
+		Artifact: &artifact{Path: "/backup/*.tar.gz"},
+	}
+	require.NoError(t, b.Restore.setup())
+	assert.True(t, b.Restore.due(time.Now()))
+
+	res := b.Restore.run(context.Background(), b.Name, info)
+	assert.NoError(t, res.err)
+	assert.Equal(t, "restoring pg from /backup/base-2.tar.gz", res.output)
+	assert.Empty(t, b.onRestored(res))
+	assert.False(t, b.Restore.due(time.Now()))
+
+	t.Setenv("FAIL", "1")
+	res = b.Restore.run(context.Background(), b.Name, info)
+	assert.Error(t, res.err)
+
+	events := b.onRestored(res)
+	require.Len(t, events, 1)
+	assert.Equal(t, statusError, events[0].fields["df_status"])
+	assert.Contains(t, events[0].fields["df_message"], "restoring pg from")
+
+	m, _ := b.check(info, time.Now())
+	assert.Equal(t, false, m.fields["restore_check_ok"])
+
+	t.Setenv("FAIL", "")
+	events = b.onRestored(b.Restore.run(context.Background(), b.Name, info))
+	require.Len(t, events, 1)
+	assert.Equal(t, statusOK, events[0].fields["df_status"])
+
+	// timeout
+	b.Restore.Command = []string{"sleep", "10"}
+	b.Restore.Timeout.Duration = 100 * time.Millisecond
+	res = b.Restore.run(context.Background(), b.Name, info)
+	assert.ErrorContains(t, res.err, "timeout")
+}
+
+func TestSetup(t *T.T) {
+	assert.Error(t, (&backup{Name: "x"}).setup())
+	assert.Error(t, (&backup{Name: "x", Catalog: &catalog{}, Artifact: &artifact{}}).setup())
+	assert.Error(t, (&backup{Name: "x", Catalog: &catalog{Driver: "oracle", Query: "select 1"}}).setup())
+	assert.Error(t, (&backup{Name: "x", Artifact: &artifact{Path: "oss:///abc"}}).setup())
+
+	b := &backup{Name: "x", Catalog: &catalog{Driver: "postgres", DSN: "postgres://localhost/db", Query: "select 1"}}
+	require.NoError(t, b.setup())
+	defer b.Catalog.close()
+	assert.Equal(t, defaultMaxAge, b.MaxAge.Duration)
+}
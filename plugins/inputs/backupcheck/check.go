@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package backupcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	statusOK      = "ok"
+	statusWarning = "warning"
+	statusError   = "error"
+)
+
+func (b *backup) setup() error {
+	if b.Name == "" {
+		return fmt.Errorf("name not set")
+	}
+
+	if (b.Catalog == nil) == (b.Artifact == nil) {
+		return fmt.Errorf("one and only one of catalog and artifact required")
+	}
+
+	if b.MaxAge.Duration <= 0 {
+		b.MaxAge.Duration = defaultMaxAge
+	}
+
+	if b.Restore != nil {
+		if err := b.Restore.setup(); err != nil {
+			return err
+		}
+	}
+
+	if b.Catalog != nil {
+		return b.Catalog.open()
+	}
+	return b.Artifact.open()
+}
+
+func (b *backup) latest(ctx context.Context) (*backupInfo, error) {
+	if b.Catalog != nil {
+		return b.Catalog.latest(ctx)
+	}
+	return b.Artifact.latest(ctx)
+}
+
+func (b *backup) tags() map[string]string {
+	tags := map[string]string{"name": b.Name}
+
+	if b.Catalog != nil {
+		tags["source"] = "catalog"
+		tags["type"] = b.Catalog.Driver
+	} else {
+		tags["source"] = "artifact"
+		tags["type"] = b.Artifact.storage()
+	}
+
+	return tags
+}
+
+// staleReason returns why the backup is stale, empty if it's not.
+func (b *backup) staleReason(info *backupInfo, now time.Time) string {
+	if info == nil {
+		return "no backup found"
+	}
+
+	if age := now.Sub(info.time); age > b.MaxAge.Duration {
+		return fmt.Sprintf("latest backup finished at %s, %s ago, exceeds max age %s",
+			info.time.Format(time.RFC3339), age.Round(time.Second), b.MaxAge.Duration)
+	}
+
+	if b.MinSize > 0 && info.size >= 0 && info.size < b.MinSize {
+		return fmt.Sprintf("latest backup finished at %s is %d bytes, less than min size %d bytes",
+			info.time.Format(time.RFC3339), info.size, b.MinSize)
+	}
+
+	return ""
+}
+
+// check returns the metric of the latest backup, and keyevents if the backup
+// turned stale or recovered.
+func (b *backup) check(info *backupInfo, now time.Time) (*backupMeasurement, []*eventMeasurement) {
+	reason := b.staleReason(info, now)
+
+	fields := map[string]interface{}{
+		"stale": reason != "",
+	}
+
+	if info != nil {
+		fields["last_backup_time"] = info.time.Unix()
+		fields["age"] = int64(now.Sub(info.time) / time.Second)
+		if info.size >= 0 {
+			fields["size"] = info.size
+		}
+	}
+
+	if r := b.restore; r != nil {
+		fields["restore_check_ok"] = r.err == nil
+		fields["restore_check_time"] = r.time.Unix()
+		fields["restore_check_cost"] = int64(r.cost / time.Second)
+	}
+
+	m := &backupMeasurement{
+		name:   metricName,
+		tags:   b.tags(),
+		fields: fields,
+		ts:     now,
+	}
+
+	var events []*eventMeasurement
+	switch {
+	case reason != "" && !b.stale:
+		b.stale = true
+		events = append(events, b.event("stale", statusWarning,
+			fmt.Sprintf("Backup %s is stale", b.Name), reason, now))
+
+	case reason == "" && b.stale:
+		b.stale = false
+		events = append(events, b.event("stale", statusOK,
+			fmt.Sprintf("Backup %s recovered", b.Name),
+			fmt.Sprintf("latest backup finished at %s", info.time.Format(time.RFC3339)), now))
+	}
+
+	return m, events
+}
+
+// onRestored records result of the restore check, and returns keyevent if
+// the check failed, or passed after failure.
+func (b *backup) onRestored(res *restoreResult) []*eventMeasurement {
+	last := b.restore
+	b.restore = res
+
+	if res.err == nil {
+		if last != nil && last.err != nil {
+			return []*eventMeasurement{b.event("restore", statusOK,
+				fmt.Sprintf("Restore check of backup %s passed", b.Name),
+				fmt.Sprintf("restore check passed in %s", res.cost.Round(time.Second)), res.time)}
+		}
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "restore check command %q failed: %s", strings.Join(b.Restore.Command, " "), res.err)
+	if res.output != "" {
+		fmt.Fprintf(&sb, "\noutput:\n%s", res.output)
+	}
+
+	return []*eventMeasurement{b.event("restore", statusError,
+		fmt.Sprintf("Restore check of backup %s failed", b.Name), sb.String(), res.time)}
+}
+
+func (b *backup) event(kind, status, title, msg string, ts time.Time) *eventMeasurement {
+	return &eventMeasurement{
+		name: eventName,
+		tags: b.tags(),
+		fields: map[string]interface{}{
+			"df_source":   "system",
+			"df_status":   status,
+			"df_event_id": fmt.Sprintf("%s-%s-%s", inputName, kind, b.Name),
+			"df_title":    title,
+			"df_message":  msg,
+		},
+		ts: ts,
+	}
+}
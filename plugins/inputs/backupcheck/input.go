@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package backupcheck verifies database and file backups are fresh and restorable.
+package backupcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	minInterval = time.Minute
+	maxInterval = 24 * time.Hour
+
+	defaultMaxAge = 26 * time.Hour
+)
+
+const (
+	inputName  = "backupcheck"
+	metricName = "backup"
+	eventName  = "backup_check"
+	sampleCfg  = `
+[[inputs.backupcheck]]
+  ## Interval between two checks.
+  interval = "10m"
+
+  ## Backup checked by catalog table of the database. The query returns the
+  ## end time(and optional size in bytes) of the latest successful backup in
+  ## its first row. Time in unix seconds accepted.
+  [[inputs.backupcheck.backup]]
+    name = "mysql-orders"
+
+    ## Keyevent sent if the latest backup is older than max_age, or smaller
+    ## than min_size bytes(0 to disable).
+    max_age  = "26h"
+    min_size = 0
+
+    [inputs.backupcheck.backup.catalog]
+      ## mysql/postgres
+      driver = "mysql"
+      dsn    = "datakit:<PASS>@tcp(127.0.0.1:3306)/backup_meta"
+      query  = "SELECT end_time, backup_size FROM backup_history WHERE status = 'SUCCEEDED' ORDER BY end_time DESC LIMIT 1"
+
+    ## Optional: run a command to restore the latest backup and verify it,
+    ## it's passed if exit with 0. The backup is in envs BACKUP_NAME,
+    ## BACKUP_TIME(RFC3339) and BACKUP_PATH.
+    # [inputs.backupcheck.backup.restore]
+    #   command  = ["/usr/local/bin/restore-smoke-check.sh"]
+    #   interval = "24h"
+    #   timeout  = "1h"
+
+  ## Backup checked by files or OSS objects, the last modified one matched
+  ## is the latest backup. A directory matched is taken as a whole backup.
+  # [[inputs.backupcheck.backup]]
+  #   name     = "pg-basebackup"
+  #   max_age  = "26h"
+  #   min_size = 1048576
+  #
+  #   [inputs.backupcheck.backup.artifact]
+  #     ## Glob of local files, or oss://<bucket>/<prefix-glob> of OSS objects.
+  #     path = "/data/backup/pg/base-*.tar.gz"
+  #
+  #     ## Required on OSS.
+  #     # oss_endpoint          = "oss-cn-hangzhou.aliyuncs.com"
+  #     # oss_access_key_id     = ""
+  #     # oss_access_key_secret = ""
+
+[inputs.backupcheck.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type backup struct {
+	Name     string           `toml:"name"`
+	MaxAge   datakit.Duration `toml:"max_age"`
+	MinSize  int64            `toml:"min_size"`
+	Catalog  *catalog         `toml:"catalog"`
+	Artifact *artifact        `toml:"artifact"`
+	Restore  *restoreCheck    `toml:"restore"`
+
+	stale     bool // stale keyevent sent
+	restoring bool
+	restore   *restoreResult // last restore check
+}
+
+type Input struct {
+	Interval datakit.Duration  `toml:"interval"`
+	Backups  []*backup         `toml:"backup"`
+	Tags     map[string]string `toml:"tags"`
+
+	restoreCh chan *restored
+	semStop   *cliutils.Sem
+}
+
+type restored struct {
+	b   *backup
+	res *restoreResult
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	l.Info("backupcheck input started")
+
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+
+	backups := ipt.setup()
+	defer func() {
+		for _, b := range backups {
+			if b.Catalog != nil {
+				b.Catalog.close()
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-datakit.Exit.Wait():
+		case <-ipt.semStop.Wait():
+		}
+		cancel()
+	}()
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	ipt.collect(ctx, backups)
+
+	for {
+		select {
+		case <-tick.C:
+			ipt.collect(ctx, backups)
+		case r := <-ipt.restoreCh:
+			r.b.restoring = false
+			ipt.feedEvents(r.b.onRestored(r.res))
+		case <-ctx.Done():
+			l.Info("backupcheck input exit")
+			return
+		}
+	}
+}
+
+// setup returns backups configured properly.
+func (ipt *Input) setup() (backups []*backup) {
+	for _, b := range ipt.Backups {
+		if err := b.setup(); err != nil {
+			l.Errorf("backup %q: %s, ignored", b.Name, err)
+			io.FeedLastError(inputName, fmt.Sprintf("backup %q: %s", b.Name, err))
+			continue
+		}
+		backups = append(backups, b)
+	}
+	return
+}
+
+func (ipt *Input) collect(ctx context.Context, backups []*backup) {
+	for _, b := range backups {
+		if ctx.Err() != nil {
+			return
+		}
+
+		start := time.Now()
+
+		info, err := b.latest(ctx)
+		if err != nil {
+			if ctx.Err() != nil { // exiting
+				return
+			}
+
+			l.Errorf("check backup %q: %s", b.Name, err)
+			io.FeedLastError(inputName, fmt.Sprintf("check backup %q: %s", b.Name, err))
+			continue
+		}
+
+		m, events := b.check(info, start)
+		m.tags = ipt.mergeTags(m.tags)
+
+		if err := inputs.FeedMeasurement(metricName, datakit.Metric, []inputs.Measurement{m},
+			&io.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("FeedMeasurement: %s", err)
+		}
+
+		ipt.feedEvents(events)
+
+		if info != nil && b.Restore != nil && !b.restoring && b.Restore.due(start) {
+			b.restoring = true
+			go func(b *backup, info *backupInfo) {
+				res := b.Restore.run(ctx, b.Name, info)
+				select {
+				case ipt.restoreCh <- &restored{b: b, res: res}:
+				case <-ctx.Done():
+				}
+			}(b, info)
+		}
+	}
+}
+
+func (ipt *Input) feedEvents(events []*eventMeasurement) {
+	if len(events) == 0 {
+		return
+	}
+
+	ms := make([]inputs.Measurement, 0, len(events))
+	for _, e := range events {
+		e.tags = ipt.mergeTags(e.tags)
+		ms = append(ms, e)
+	}
+
+	if err := inputs.FeedMeasurement(eventName, datakit.KeyEvent, ms, nil); err != nil {
+		l.Errorf("FeedMeasurement: %s", err)
+	}
+}
+
+func (ipt *Input) mergeTags(tags map[string]string) map[string]string {
+	for k, v := range ipt.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (*Input) Catalog() string { return "db" }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&backupMeasurement{},
+		&eventMeasurement{},
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Interval:  datakit.Duration{Duration: 10 * time.Minute},
+		Tags:      map[string]string{},
+		restoreCh: make(chan *restored),
+		semStop:   cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
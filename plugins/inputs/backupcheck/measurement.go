@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package backupcheck
+
+import (
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var backupTags = map[string]interface{}{
+	"host":   &inputs.TagInfo{Desc: "Hostname"},
+	"name":   &inputs.TagInfo{Desc: "Name of the backup"},
+	"source": &inputs.TagInfo{Desc: "How the backup checked, `catalog` or `artifact`"},
+	"type":   &inputs.TagInfo{Desc: "Driver of the catalog(`mysql/postgres`), or storage of the artifact(`file/oss`)"},
+}
+
+type backupMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *backupMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.Metric, Time: m.ts})
+}
+
+//nolint:lll
+func (m *backupMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricName,
+		Type: "metric",
+		Desc: "Status of the latest backup.",
+		Tags: backupTags,
+		Fields: map[string]interface{}{
+			"last_backup_time":   &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.TimestampSec, Desc: "Time of the latest backup, absent if no backup found"},
+			"age":                &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Age of the latest backup, absent if no backup found"},
+			"size":               &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.SizeByte, Desc: "Size of the latest backup, absent if unknown"},
+			"stale":              &inputs.FieldInfo{DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "Whether no backup found, or the latest backup older than `max_age` or smaller than `min_size`"},
+			"restore_check_ok":   &inputs.FieldInfo{DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "Whether the last restore check passed, absent if not checked"},
+			"restore_check_time": &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.TimestampSec, Desc: "Time of the last restore check, absent if not checked"},
+			"restore_check_cost": &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Cost of the last restore check, absent if not checked"},
+		},
+	}
+}
+
+type eventMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *eventMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.KeyEvent, Time: m.ts})
+}
+
+//nolint:lll
+func (m *eventMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: eventName,
+		Type: "keyevent",
+		Desc: "Keyevent sent when the backup turned stale or recovered, or the restore check failed or passed after failure.",
+		Tags: backupTags,
+		Fields: map[string]interface{}{
+			"df_source":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Source of the event, always `system`"},
+			"df_status":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Status of the event, `warning` for stale backup, `error` for restore check failure, `ok` for recovery"},
+			"df_event_id": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event ID, `backupcheck-<stale|restore>-<name>`"},
+			"df_title":    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Title of the event"},
+			"df_message":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Detail of the event"},
+		},
+	}
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package backupcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+const (
+	defaultRestoreInterval = 24 * time.Hour
+	defaultRestoreTimeout  = time.Hour
+
+	maxRestoreOutput = 1024
+)
+
+// restoreCheck runs a command to restore the latest backup to somewhere and
+// check it, such as restore to a temporary database and count rows of tables.
+type restoreCheck struct {
+	Command  []string         `toml:"command"`
+	Interval datakit.Duration `toml:"interval"`
+	Timeout  datakit.Duration `toml:"timeout"`
+
+	last time.Time // last run
+}
+
+type restoreResult struct {
+	time   time.Time
+	cost   time.Duration
+	err    error
+	output string // tail of stdout/stderr
+}
+
+func (r *restoreCheck) setup() error {
+	if len(r.Command) == 0 {
+		return fmt.Errorf("restore command not set")
+	}
+
+	if r.Interval.Duration <= 0 {
+		r.Interval.Duration = defaultRestoreInterval
+	}
+
+	if r.Timeout.Duration <= 0 {
+		r.Timeout.Duration = defaultRestoreTimeout
+	}
+
+	return nil
+}
+
+func (r *restoreCheck) due(now time.Time) bool {
+	return r.last.IsZero() || now.Sub(r.last) >= r.Interval.Duration
+}
+
+// run runs the command with the latest backup in envs BACKUP_NAME,
+// BACKUP_TIME(RFC3339) and BACKUP_PATH. The check passed if exit with 0.
+func (r *restoreCheck) run(ctx context.Context, name string, info *backupInfo) *restoreResult {
+	start := time.Now()
+	r.last = start
+
+	tctx, cancel := context.WithTimeout(ctx, r.Timeout.Duration)
+	defer cancel()
+
+	//nolint:gosec
+	cmd := exec.CommandContext(tctx, r.Command[0], r.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BACKUP_NAME="+name,
+		"BACKUP_TIME="+info.time.Format(time.RFC3339),
+		"BACKUP_PATH="+info.path,
+	)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if err != nil && tctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timeout after %s", r.Timeout.Duration)
+	}
+
+	output := out.Bytes()
+	if len(output) > maxRestoreOutput {
+		output = output[len(output)-maxRestoreOutput:]
+	}
+
+	return &restoreResult{
+		time:   start,
+		cost:   time.Since(start),
+		err:    err,
+		output: string(bytes.TrimSpace(output)),
+	}
+}
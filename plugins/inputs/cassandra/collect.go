@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cassandra
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const (
+	measurementThreadPool = "cassandra_thread_pool"
+	measurementCache      = "cassandra_cache"
+	measurementTable      = "cassandra_table"
+
+	// CQL error code of invalid query, such as keyspace not found
+	errCodeInvalid = 0x2200
+)
+
+// latency tables of system_views, with prefix of fields.
+var latencyTables = []struct {
+	table  string
+	prefix string
+}{
+	{"local_read_latency", "read_"},
+	{"local_write_latency", "write_"},
+	{"local_scan_latency", "scan_"},
+}
+
+func (ipt *Input) collectServer(server string) ([]*point.Point, error) {
+	addr := server
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultPort)
+	}
+
+	conn, err := ipt.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := dialCQL(conn, ipt.Username, ipt.Password, ipt.Timeout.Duration)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	defer c.close() //nolint:errcheck
+
+	var (
+		now  = time.Now()
+		tags = map[string]string{"server": server}
+		pts  []*point.Point
+	)
+
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+
+	rows, err := c.query("SELECT * FROM system_views.thread_pools")
+	if err != nil {
+		return nil, queryError("thread_pools", err)
+	}
+	pts = append(pts, ipt.makePoints(measurementThreadPool, rows, tags,
+		map[string]string{"name": "thread_pool"}, now)...)
+
+	rows, err = c.query("SELECT * FROM system_views.caches")
+	if err != nil {
+		return pts, queryError("caches", err)
+	}
+	pts = append(pts, ipt.makePoints(measurementCache, rows, tags,
+		map[string]string{"name": "cache"}, now)...)
+
+	tablePts, err := ipt.collectTables(c, tags, now)
+	return append(pts, tablePts...), err
+}
+
+// collectTables merges latencies of read, write and scan of each table into
+// one point.
+func (ipt *Input) collectTables(c *cqlConn, tags map[string]string, now time.Time) ([]*point.Point, error) {
+	type tableKey struct{ keyspace, table string }
+
+	var (
+		keys   []tableKey
+		fields = map[tableKey]map[string]interface{}{}
+	)
+
+	for _, lt := range latencyTables {
+		rows, err := c.query("SELECT * FROM system_views." + lt.table)
+		if err != nil {
+			return nil, queryError(lt.table, err)
+		}
+
+		for _, r := range rows {
+			key := tableKey{}
+			key.keyspace, _ = r["keyspace_name"].(string)
+			key.table, _ = r["table_name"].(string)
+			if key.keyspace == "" || key.table == "" {
+				continue
+			}
+
+			kvs, ok := fields[key]
+			if !ok {
+				kvs = map[string]interface{}{}
+				fields[key] = kvs
+				keys = append(keys, key)
+			}
+
+			for k, v := range r {
+				if k == "keyspace_name" || k == "table_name" {
+					continue
+				}
+				if x, ok := numericValue(v); ok {
+					kvs[lt.prefix+k] = x
+				}
+			}
+		}
+	}
+
+	var pts []*point.Point
+	for _, key := range keys {
+		if len(fields[key]) == 0 {
+			continue
+		}
+
+		ptTags := map[string]string{"keyspace": key.keyspace, "table": key.table}
+		for k, v := range tags {
+			ptTags[k] = v
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(measurementTable),
+			append(point.NewTags(ptTags), point.NewKVs(fields[key])...),
+			ipt.pointOptions(now)...))
+	}
+
+	return pts, nil
+}
+
+// makePoints makes a point of each row, columns in tagColumns as tags named
+// by the map value, and other numeric ones as fields.
+func (ipt *Input) makePoints(name string,
+	rows []row,
+	tags map[string]string,
+	tagColumns map[string]string,
+	now time.Time,
+) []*point.Point {
+	var pts []*point.Point
+
+	for _, r := range rows {
+		ptTags := map[string]string{}
+		for k, v := range tags {
+			ptTags[k] = v
+		}
+
+		fields := map[string]interface{}{}
+
+		for k, v := range r {
+			if tag, ok := tagColumns[k]; ok {
+				if s, ok := v.(string); ok {
+					ptTags[tag] = s
+				}
+				continue
+			}
+
+			if x, ok := numericValue(v); ok {
+				fields[k] = x
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(name),
+			append(point.NewTags(ptTags), point.NewKVs(fields)...),
+			ipt.pointOptions(now)...))
+	}
+
+	return pts
+}
+
+// numericValue returns int64 or float64 value, NaN and Inf(such as hit ratio
+// of empty caches) dropped.
+func numericValue(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, false
+		}
+		return x, true
+	default:
+		return nil, false
+	}
+}
+
+func queryError(table string, err error) error {
+	var ce *cqlError
+	if errors.As(err, &ce) && ce.code == errCodeInvalid {
+		return fmt.Errorf("query system_views.%s: %w, virtual tables require Cassandra 4.0+", table, err)
+	}
+	return fmt.Errorf("query system_views.%s: %w", table, err)
+}
@@ -0,0 +1,482 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cassandra
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// A minimal client of CQL native protocol v4, only to query rows of scalar
+// columns, see
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec
+
+const (
+	protoVersion  = 0x04
+	respDirection = 0x80
+
+	headerSize   = 9
+	maxFrameSize = 256 << 20
+
+	// opcodes
+	opError         = 0x00
+	opStartup       = 0x01
+	opReady         = 0x02
+	opAuthenticate  = 0x03
+	opQuery         = 0x07
+	opResult        = 0x08
+	opAuthChallenge = 0x0E
+	opAuthResponse  = 0x0F
+	opAuthSuccess   = 0x10
+
+	// frame flags
+	flagTracing       = 0x02
+	flagCustomPayload = 0x04
+	flagWarning       = 0x08
+
+	consistencyOne = 0x0001
+
+	resultRows = 0x0002
+
+	// rows metadata flags
+	rowsGlobalTableSpec = 0x0001
+	rowsHasMorePages    = 0x0002
+	rowsNoMetadata      = 0x0004
+)
+
+// types of column
+const (
+	typeCustom   = 0x0000
+	typeASCII    = 0x0001
+	typeBigint   = 0x0002
+	typeBoolean  = 0x0004
+	typeCounter  = 0x0005
+	typeDouble   = 0x0007
+	typeFloat    = 0x0008
+	typeInt      = 0x0009
+	typeVarchar  = 0x000D
+	typeSmallint = 0x0013
+	typeTinyint  = 0x0014
+	typeList     = 0x0020
+	typeMap      = 0x0021
+	typeSet      = 0x0022
+	typeUDT      = 0x0030
+	typeTuple    = 0x0031
+)
+
+var errShortFrame = errors.New("frame too short")
+
+type cqlError struct {
+	code int32
+	msg  string
+}
+
+func (e *cqlError) Error() string {
+	return fmt.Sprintf("CQL error 0x%04x: %s", e.code, e.msg)
+}
+
+type column struct {
+	name string
+	typ  uint16
+}
+
+// row maps column name to value, in string, int64, float64 or bool. Values
+// of other types and null ones are absent.
+type row map[string]interface{}
+
+type cqlConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// dialCQL connects to the node and authenticates by PasswordAuthenticator if
+// the node requires.
+func dialCQL(conn net.Conn, username, password string, timeout time.Duration) (*cqlConn, error) {
+	c := &cqlConn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		timeout: timeout,
+	}
+
+	var body []byte
+	body = appendStringMap(body, map[string]string{"CQL_VERSION": "3.0.0"})
+
+	op, resp, err := c.roundTrip(opStartup, body)
+	if err != nil {
+		return nil, fmt.Errorf("startup: %w", err)
+	}
+
+	switch op {
+	case opReady:
+		return c, nil
+
+	case opAuthenticate:
+		if username == "" {
+			return nil, fmt.Errorf("authentication required")
+		}
+
+		token := append([]byte{0}, username...)
+		token = append(token, 0)
+		token = append(token, password...)
+
+		op, resp, err = c.roundTrip(opAuthResponse, appendBytes(nil, token))
+		if err != nil {
+			return nil, fmt.Errorf("authenticate: %w", err)
+		}
+
+		switch op {
+		case opAuthSuccess:
+			return c, nil
+		case opAuthChallenge:
+			return nil, fmt.Errorf("authenticate: SASL challenge not supported")
+		default:
+			return nil, fmt.Errorf("authenticate: unexpected opcode 0x%02x", op)
+		}
+
+	default:
+		return nil, fmt.Errorf("startup: unexpected opcode 0x%02x(%d bytes)", op, len(resp))
+	}
+}
+
+func (c *cqlConn) close() error {
+	return c.conn.Close()
+}
+
+// query runs the CQL and returns the rows, not paged.
+func (c *cqlConn) query(cql string) ([]row, error) {
+	body := appendLongString(nil, cql)
+	body = appendUint16(body, consistencyOne)
+	body = append(body, 0) // no flags
+
+	op, resp, err := c.roundTrip(opQuery, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if op != opResult {
+		return nil, fmt.Errorf("unexpected opcode 0x%02x", op)
+	}
+
+	return parseRows(resp)
+}
+
+func (c *cqlConn) roundTrip(op byte, body []byte) (byte, []byte, error) {
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	frame := make([]byte, headerSize, headerSize+len(body))
+	frame[0] = protoVersion
+	// flags and stream 0, only one request in flight
+	frame[4] = op
+	binary.BigEndian.PutUint32(frame[5:], uint32(len(body)))
+	frame = append(frame, body...)
+
+	if _, err := c.conn.Write(frame); err != nil {
+		return 0, nil, err
+	}
+
+	var head [headerSize]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return 0, nil, err
+	}
+
+	if head[0] != respDirection|protoVersion {
+		return 0, nil, fmt.Errorf("unsupported protocol version 0x%02x", head[0])
+	}
+
+	n := binary.BigEndian.Uint32(head[5:])
+	if n > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame of %d bytes too large", n)
+	}
+
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return 0, nil, err
+	}
+
+	resp, err := skipFrameExtras(head[1], resp)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if head[4] == opError {
+		return 0, nil, parseError(resp)
+	}
+
+	return head[4], resp, nil
+}
+
+// skipFrameExtras skips tracing ID, warnings and custom payload before the
+// body.
+func skipFrameExtras(flags byte, b []byte) ([]byte, error) {
+	d := &decoder{b: b}
+
+	if flags&flagTracing != 0 {
+		d.skip(16)
+	}
+
+	if flags&flagWarning != 0 {
+		n := d.short()
+		for i := 0; i < int(n); i++ {
+			d.string()
+		}
+	}
+
+	if flags&flagCustomPayload != 0 {
+		n := d.short()
+		for i := 0; i < int(n); i++ {
+			d.string()
+			d.bytes()
+		}
+	}
+
+	return d.b, d.err
+}
+
+func parseError(b []byte) error {
+	d := &decoder{b: b}
+	e := &cqlError{code: d.int()}
+	e.msg = d.string()
+	if d.err != nil {
+		return d.err
+	}
+	return e
+}
+
+func parseRows(b []byte) ([]row, error) {
+	d := &decoder{b: b}
+
+	if kind := d.int(); kind != resultRows {
+		if d.err != nil {
+			return nil, d.err
+		}
+		return nil, fmt.Errorf("unexpected result kind 0x%04x", kind)
+	}
+
+	flags := d.int()
+	ncols := d.int()
+
+	if flags&rowsHasMorePages != 0 {
+		d.bytes()
+	}
+
+	if flags&rowsNoMetadata != 0 {
+		return nil, fmt.Errorf("rows without metadata")
+	}
+
+	if flags&rowsGlobalTableSpec != 0 {
+		d.string()
+		d.string()
+	}
+
+	if ncols < 0 || int(ncols) > len(d.b) {
+		return nil, errShortFrame
+	}
+
+	cols := make([]column, ncols)
+	for i := range cols {
+		if flags&rowsGlobalTableSpec == 0 {
+			d.string()
+			d.string()
+		}
+		cols[i].name = d.string()
+		cols[i].typ = d.option()
+	}
+
+	nrows := d.int()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if nrows < 0 || int(nrows) > len(d.b) {
+		return nil, errShortFrame
+	}
+
+	rows := make([]row, 0, nrows)
+	for i := 0; i < int(nrows); i++ {
+		r := row{}
+		for _, col := range cols {
+			v := d.bytes()
+			if v == nil {
+				continue // null
+			}
+
+			if x, ok := decodeValue(col.typ, v); ok {
+				r[col.name] = x
+			}
+		}
+		rows = append(rows, r)
+	}
+
+	return rows, d.err
+}
+
+func decodeValue(typ uint16, v []byte) (interface{}, bool) {
+	switch typ {
+	case typeASCII, typeVarchar:
+		return string(v), true
+	case typeBigint, typeCounter:
+		if len(v) == 8 {
+			return int64(binary.BigEndian.Uint64(v)), true
+		}
+	case typeInt:
+		if len(v) == 4 {
+			return int64(int32(binary.BigEndian.Uint32(v))), true
+		}
+	case typeSmallint:
+		if len(v) == 2 {
+			return int64(int16(binary.BigEndian.Uint16(v))), true
+		}
+	case typeTinyint:
+		if len(v) == 1 {
+			return int64(int8(v[0])), true
+		}
+	case typeDouble:
+		if len(v) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(v)), true
+		}
+	case typeFloat:
+		if len(v) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(v))), true
+		}
+	case typeBoolean:
+		if len(v) == 1 {
+			return v[0] != 0, true
+		}
+	}
+
+	return nil, false
+}
+
+// decoder reads notations of the protocol, the first error kept.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n < 0 || n > len(d.b) {
+		d.err = errShortFrame
+		d.b = nil
+		return nil
+	}
+
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) skip(n int) {
+	d.next(n)
+}
+
+func (d *decoder) short() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) int() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	n := d.short()
+	return string(d.next(int(n)))
+}
+
+// bytes returns nil for null(negative length), and non-nil for empty.
+func (d *decoder) bytes() []byte {
+	n := d.int()
+	if n < 0 {
+		return nil
+	}
+
+	b := d.next(int(n))
+	if b == nil && d.err == nil {
+		return []byte{}
+	}
+	return b
+}
+
+// option returns the type ID, with parameters of the type skipped.
+func (d *decoder) option() uint16 {
+	id := d.short()
+
+	switch id {
+	case typeCustom:
+		d.string()
+	case typeList, typeSet:
+		d.option()
+	case typeMap:
+		d.option()
+		d.option()
+	case typeUDT:
+		d.string()
+		d.string()
+		n := d.short()
+		for i := 0; i < int(n) && d.err == nil; i++ {
+			d.string()
+			d.option()
+		}
+	case typeTuple:
+		n := d.short()
+		for i := 0; i < int(n) && d.err == nil; i++ {
+			d.option()
+		}
+	}
+
+	return id
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendLongString(b []byte, s string) []byte {
+	b = appendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendBytes(b, v []byte) []byte {
+	b = appendUint32(b, uint32(len(v)))
+	return append(b, v...)
+}
+
+func appendStringMap(b []byte, m map[string]string) []byte {
+	b = appendUint16(b, uint16(len(m)))
+	for k, v := range m {
+		b = appendString(b, k)
+		b = appendString(b, v)
+	}
+	return b
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
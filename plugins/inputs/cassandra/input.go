@@ -0,0 +1,242 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package cassandra collects Cassandra metrics from virtual tables over CQL.
+package cassandra
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	dknet "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/net"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "cassandra"
+	catalog   = "db"
+
+	minInterval = time.Second
+	maxInterval = 10 * time.Minute
+
+	defaultPort = "9042"
+
+	sampleCfg = `
+[[inputs.cassandra]]
+  ## CQL native transport addresses of nodes. Virtual tables are local to
+  ## the node, so all nodes of the cluster should be listed, or run DataKit
+  ## on each node to collect 127.0.0.1 only.
+  servers = ["127.0.0.1:9042"]
+
+  ## Set if PasswordAuthenticator enabled, the user should be able to
+  ## SELECT on keyspace system_views.
+  # username = "datakit"
+  # password = "<PASS>"
+
+  interval = "10s"
+  timeout  = "5s"
+
+  ## Set true to collect on the elected DataKit only, if servers of all
+  ## nodes are listed.
+  election = false
+
+  ## TLS of client-to-node encryption
+  # [inputs.cassandra.tlsconf]
+  #   ca_certs = ["/path/to/ca.pem"]
+  #   cert = "/path/to/cert.pem"
+  #   cert_key = "/path/to/key.pem"
+  #   insecure_skip_verify = false
+
+  [inputs.cassandra.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	Servers  []string               `toml:"servers"`
+	Username string                 `toml:"username"`
+	Password string                 `toml:"password"`
+	Interval datakit.Duration       `toml:"interval"`
+	Timeout  datakit.Duration       `toml:"timeout"`
+	Election bool                   `toml:"election"`
+	TLSConf  *dknet.TLSClientConfig `toml:"tlsconf"`
+	Tags     map[string]string      `toml:"tags"`
+
+	tlsConf *tls.Config
+	dial    func(addr string) (net.Conn, error)
+
+	pause   bool
+	pauseCh chan bool
+
+	feeder  dkio.Feeder
+	semStop *cliutils.Sem // start stop signal
+}
+
+var (
+	_ inputs.ElectionInput = (*Input)(nil)
+	_ inputs.InputV2       = (*Input)(nil)
+)
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOSWithElection }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&threadPoolMeasurement{},
+		&cacheMeasurement{},
+		&tableMeasurement{},
+	}
+}
+
+func (ipt *Input) ElectionEnabled() bool {
+	return ipt.Election
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		if ipt.pause {
+			l.Debugf("not leader, skipped")
+		} else {
+			ipt.collect()
+		}
+
+		select {
+		case <-datakit.Exit.Wait():
+			l.Info(inputName + " exit")
+			return
+		case <-ipt.semStop.Wait():
+			l.Info(inputName + " return")
+			return
+		case ipt.pause = <-ipt.pauseCh:
+		case <-tick.C:
+		}
+	}
+}
+
+func (ipt *Input) setup() error {
+	if ipt.TLSConf != nil {
+		conf, err := ipt.TLSConf.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("TLS config: %w", err)
+		}
+		ipt.tlsConf = conf
+	}
+
+	if ipt.dial == nil {
+		ipt.dial = ipt.dialTCP
+	}
+
+	return nil
+}
+
+func (ipt *Input) dialTCP(addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: ipt.Timeout.Duration}
+
+	if ipt.tlsConf != nil {
+		return tls.DialWithDialer(dialer, "tcp", addr, ipt.tlsConf)
+	}
+	return dialer.Dial("tcp", addr)
+}
+
+func (ipt *Input) collect() {
+	for _, server := range ipt.Servers {
+		start := time.Now()
+
+		pts, err := ipt.collectServer(server)
+		if err != nil {
+			l.Errorf("collect %s: %s", server, err)
+			ipt.feeder.FeedLastError(inputName, fmt.Sprintf("collect %s: %s", server, err))
+		}
+
+		if len(pts) > 0 {
+			if err := ipt.feeder.Feed(inputName, point.Metric, pts,
+				&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+				l.Errorf("feed: %s", err)
+			}
+		}
+	}
+}
+
+// pointOptions returns options of points, with the election tags if enabled.
+func (ipt *Input) pointOptions(ts time.Time) []point.Option {
+	opts := append(point.DefaultMetricOptions(), point.WithTime(ts))
+	if ipt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+	return opts
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (ipt *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (ipt *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Servers:  []string{"127.0.0.1:" + defaultPort},
+		Interval: datakit.Duration{Duration: 10 * time.Second},
+		Timeout:  datakit.Duration{Duration: 5 * time.Second},
+		Tags:     map[string]string{},
+		pauseCh:  make(chan bool, inputs.ElectionPauseChannelLength),
+		feeder:   dkio.DefaultFeeder(),
+		semStop:  cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -0,0 +1,332 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cassandra
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+type fakeColumn struct {
+	name string
+	typ  uint16
+}
+
+type fakeTable struct {
+	cols []fakeColumn
+	rows [][]interface{} // nil for null
+}
+
+// fakeServer serves queries of system_views tables on the connection.
+type fakeServer struct {
+	username, password string
+	tables             map[string]*fakeTable
+}
+
+func (s *fakeServer) serve(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close() //nolint:errcheck
+
+	for {
+		var head [headerSize]byte
+		if _, err := io.ReadFull(conn, head[:]); err != nil {
+			return
+		}
+
+		body := make([]byte, binary.BigEndian.Uint32(head[5:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		var (
+			op   byte
+			resp []byte
+		)
+
+		switch head[4] {
+		case opStartup:
+			if s.username != "" {
+				op, resp = opAuthenticate, appendString(nil, "org.apache.cassandra.auth.PasswordAuthenticator")
+			} else {
+				op = opReady
+			}
+
+		case opAuthResponse:
+			d := &decoder{b: body}
+			token := string(d.bytes())
+			if token == "\x00"+s.username+"\x00"+s.password {
+				op, resp = opAuthSuccess, appendBytes(nil, nil)
+			} else {
+				op, resp = opError, fakeError(0x0100, "bad credentials")
+			}
+
+		case opQuery:
+			d := &decoder{b: body}
+			n := d.int()
+			cql := string(d.next(int(n)))
+			name := cql[strings.LastIndex(cql, ".")+1:]
+
+			if tbl, ok := s.tables[name]; ok {
+				op, resp = opResult, tbl.encode()
+			} else {
+				op, resp = opError, fakeError(errCodeInvalid, "table "+name+" does not exist")
+			}
+		}
+
+		frame := []byte{respDirection | protoVersion, 0, 0, 0, op}
+		frame = appendUint32(frame, uint32(len(resp)))
+		if _, err := conn.Write(append(frame, resp...)); err != nil {
+			return
+		}
+	}
+}
+
+func fakeError(code uint32, msg string) []byte {
+	return appendString(appendUint32(nil, code), msg)
+}
+
+func (tbl *fakeTable) encode() []byte {
+	b := appendUint32(nil, resultRows)
+	b = appendUint32(b, rowsGlobalTableSpec)
+	b = appendUint32(b, uint32(len(tbl.cols)))
+	b = appendString(b, "system_views")
+	b = appendString(b, "some_table")
+
+	for _, col := range tbl.cols {
+		b = appendString(b, col.name)
+		b = appendUint16(b, col.typ)
+	}
+
+	b = appendUint32(b, uint32(len(tbl.rows)))
+	for _, r := range tbl.rows {
+		for i, v := range r {
+			if v == nil {
+				b = appendUint32(b, math.MaxUint32) // -1 for null
+				continue
+			}
+			b = appendBytes(b, encodeValue(tbl.cols[i].typ, v))
+		}
+	}
+
+	return b
+}
+
+func encodeValue(typ uint16, v interface{}) []byte {
+	switch typ {
+	case typeVarchar:
+		return []byte(v.(string))
+	case typeInt:
+		return appendUint32(nil, uint32(int32(v.(int))))
+	case typeBigint:
+		return appendUint32(appendUint32(nil, 0), uint32(v.(int)))
+	case typeDouble:
+		bits := math.Float64bits(v.(float64))
+		return appendUint32(appendUint32(nil, uint32(bits>>32)), uint32(bits))
+	default:
+		panic("unsupported type")
+	}
+}
+
+func latencyTable(rows ...[]interface{}) *fakeTable {
+	return &fakeTable{
+		cols: []fakeColumn{
+			{"keyspace_name", typeVarchar},
+			{"table_name", typeVarchar},
+			{"count", typeBigint},
+			{"max_ms", typeDouble},
+			{"median_ms", typeDouble},
+			{"p99th_ms", typeDouble},
+			{"per_second", typeDouble},
+		},
+		rows: rows,
+	}
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		tables: map[string]*fakeTable{
+			"thread_pools": {
+				cols: []fakeColumn{
+					{"name", typeVarchar},
+					{"active_tasks", typeInt},
+					{"completed_tasks", typeBigint},
+					{"pending_tasks", typeInt},
+				},
+				rows: [][]interface{}{
+					{"ReadStage", 1, 100, 2},
+					{"MutationStage", 0, 200, 0},
+				},
+			},
+			"caches": {
+				cols: []fakeColumn{
+					{"name", typeVarchar},
+					{"capacity_bytes", typeBigint},
+					{"hit_count", typeBigint},
+					{"hit_ratio", typeDouble},
+				},
+				rows: [][]interface{}{
+					{"keys", 1024, 30, 0.75},
+					{"rows", 0, 0, math.NaN()},
+				},
+			},
+			"local_read_latency": latencyTable(
+				[]interface{}{"ks1", "t1", 10, 2.5, 0.5, 2.0, 1.5},
+				[]interface{}{"ks1", "t2", 0, nil, nil, nil, 0.0},
+			),
+			"local_write_latency": latencyTable(
+				[]interface{}{"ks1", "t1", 20, 1.5, 0.2, 1.0, 3.0},
+			),
+			"local_scan_latency": latencyTable(),
+		},
+	}
+}
+
+func newTestInput(t *testing.T, srv *fakeServer) (*Input, *dkio.MockedFeeder) {
+	t.Helper()
+
+	feeder := dkio.NewMockedFeeder()
+
+	ipt := defaultInput()
+	ipt.feeder = feeder
+	ipt.Servers = []string{"node1:9042"}
+	ipt.Tags = map[string]string{"cluster": "c1"}
+	ipt.dial = func(addr string) (net.Conn, error) {
+		assert.Equal(t, "node1:9042", addr)
+
+		client, server := net.Pipe()
+		go srv.serve(t, server)
+		return client, nil
+	}
+
+	require.NoError(t, ipt.setup())
+	return ipt, feeder
+}
+
+func TestCollect(t *testing.T) {
+	t.Run("basic", func(t *testing.T) {
+		ipt, feeder := newTestInput(t, newFakeServer())
+
+		pts, err := ipt.collectServer("node1:9042")
+		require.NoError(t, err)
+
+		byName := map[string]int{}
+		for _, pt := range pts {
+			byName[string(pt.Name())]++
+			assert.Equal(t, "node1:9042", string(pt.GetTag([]byte("server"))))
+			assert.Equal(t, "c1", string(pt.GetTag([]byte("cluster"))))
+
+			switch string(pt.GetTag([]byte("thread_pool"))) + string(pt.GetTag([]byte("cache"))) + string(pt.GetTag([]byte("table"))) {
+			case "ReadStage":
+				assert.Equal(t, measurementThreadPool, string(pt.Name()))
+				assert.Equal(t, int64(1), pt.Get([]byte("active_tasks")))
+				assert.Equal(t, int64(100), pt.Get([]byte("completed_tasks")))
+				assert.Equal(t, int64(2), pt.Get([]byte("pending_tasks")))
+				assert.Nil(t, pt.Get([]byte("name")))
+
+			case "keys":
+				assert.Equal(t, measurementCache, string(pt.Name()))
+				assert.Equal(t, 0.75, pt.Get([]byte("hit_ratio")))
+
+			case "rows":
+				assert.Nil(t, pt.Get([]byte("hit_ratio")), "NaN dropped")
+				assert.Equal(t, int64(0), pt.Get([]byte("hit_count")))
+
+			case "t1":
+				assert.Equal(t, measurementTable, string(pt.Name()))
+				assert.Equal(t, "ks1", string(pt.GetTag([]byte("keyspace"))))
+				assert.Equal(t, int64(10), pt.Get([]byte("read_count")))
+				assert.Equal(t, 2.0, pt.Get([]byte("read_p99th_ms")))
+				assert.Equal(t, int64(20), pt.Get([]byte("write_count")))
+				assert.Equal(t, 3.0, pt.Get([]byte("write_per_second")))
+
+			case "t2":
+				assert.Equal(t, int64(0), pt.Get([]byte("read_count")))
+				assert.Nil(t, pt.Get([]byte("read_max_ms")), "null dropped")
+				assert.Nil(t, pt.Get([]byte("write_count")))
+			}
+		}
+
+		assert.Equal(t, map[string]int{
+			measurementThreadPool: 2,
+			measurementCache:      2,
+			measurementTable:      2,
+		}, byName)
+
+		ipt.collect()
+		fed, err := feeder.NPoints(6, time.Second)
+		require.NoError(t, err)
+		assert.Len(t, fed, 6)
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.username, srv.password = "dk", "secret"
+
+		ipt, _ := newTestInput(t, srv)
+		ipt.Username, ipt.Password = "dk", "secret"
+
+		pts, err := ipt.collectServer("node1:9042")
+		require.NoError(t, err)
+		assert.Len(t, pts, 6)
+
+		ipt.Password = "wrong"
+		_, err = ipt.collectServer("node1:9042")
+		assert.ErrorContains(t, err, "bad credentials")
+
+		ipt.Username = ""
+		_, err = ipt.collectServer("node1:9042")
+		assert.ErrorContains(t, err, "authentication required")
+	})
+
+	t.Run("before-4.0", func(t *testing.T) {
+		srv := newFakeServer()
+		srv.tables = nil
+
+		ipt, _ := newTestInput(t, srv)
+
+		_, err := ipt.collectServer("node1:9042")
+		assert.ErrorContains(t, err, "Cassandra 4.0+")
+	})
+
+	t.Run("default-port", func(t *testing.T) {
+		ipt, _ := newTestInput(t, newFakeServer())
+
+		_, err := ipt.collectServer("node1")
+		require.NoError(t, err)
+	})
+}
+
+func TestParseRows(t *testing.T) {
+	t.Run("short", func(t *testing.T) {
+		b := newFakeServer().tables["thread_pools"].encode()
+
+		for i := 0; i < len(b); i++ {
+			_, err := parseRows(b[:i])
+			assert.Error(t, err, "truncated at %d", i)
+		}
+
+		rows, err := parseRows(b)
+		require.NoError(t, err)
+		assert.Equal(t, []row{
+			{"name": "ReadStage", "active_tasks": int64(1), "completed_tasks": int64(100), "pending_tasks": int64(2)},
+			{"name": "MutationStage", "active_tasks": int64(0), "completed_tasks": int64(200), "pending_tasks": int64(0)},
+		}, rows)
+	})
+
+	t.Run("non-rows", func(t *testing.T) {
+		_, err := parseRows(appendUint32(nil, 0x0001))
+		assert.ErrorContains(t, err, "unexpected result kind")
+	})
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package cassandra
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+type threadPoolMeasurement struct{}
+
+func (m *threadPoolMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *threadPoolMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementThreadPool,
+		Type: "metric",
+		Desc: "Thread pools of the node, from `system_views.thread_pools`.",
+		Tags: map[string]interface{}{
+			"server":      &inputs.TagInfo{Desc: "Address of the node."},
+			"thread_pool": &inputs.TagInfo{Desc: "Name of the thread pool, such as `ReadStage`."},
+		},
+		Fields: map[string]interface{}{
+			"active_tasks":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tasks being executed."},
+			"active_tasks_limit":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Max number of tasks executed concurrently."},
+			"pending_tasks":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tasks queued."},
+			"completed_tasks":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of tasks completed."},
+			"blocked_tasks":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tasks blocked as the queue full."},
+			"blocked_tasks_all_time": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of tasks ever blocked."},
+		},
+	}
+}
+
+type cacheMeasurement struct{}
+
+func (m *cacheMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *cacheMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementCache,
+		Type: "metric",
+		Desc: "Caches of the node, from `system_views.caches`.",
+		Tags: map[string]interface{}{
+			"server": &inputs.TagInfo{Desc: "Address of the node."},
+			"cache":  &inputs.TagInfo{Desc: "Name of the cache, such as `keys`, `rows`, `counters` and `chunks`."},
+		},
+		Fields: map[string]interface{}{
+			"capacity_bytes":                 &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Capacity of the cache."},
+			"size_bytes":                     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Size of entries in the cache."},
+			"entry_count":                    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of entries in the cache."},
+			"request_count":                  &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of requests to the cache."},
+			"hit_count":                      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of hits of the cache."},
+			"hit_ratio":                      &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Hit ratio of the cache, in 0~1, absent before any request."},
+			"recent_request_rate_per_second": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.RequestsPerSec, Desc: "Recent rate of requests."},
+			"recent_hit_rate_per_second":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.RequestsPerSec, Desc: "Recent rate of hits."},
+		},
+	}
+}
+
+type tableMeasurement struct{}
+
+func (m *tableMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *tableMeasurement) Info() *inputs.MeasurementInfo {
+	fields := map[string]interface{}{}
+
+	for _, lt := range latencyTables {
+		op := lt.prefix[:len(lt.prefix)-1]
+
+		fields[lt.prefix+"count"] = &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of " + op + " requests."}
+		fields[lt.prefix+"per_second"] = &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.RequestsPerSec, Desc: "Recent rate of " + op + " requests."}
+		fields[lt.prefix+"median_ms"] = &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Median latency of " + op + " requests."}
+		fields[lt.prefix+"p99th_ms"] = &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "P99 latency of " + op + " requests."}
+		fields[lt.prefix+"max_ms"] = &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Max latency of " + op + " requests."}
+	}
+
+	return &inputs.MeasurementInfo{
+		Name: measurementTable,
+		Type: "metric",
+		Desc: "Local latencies of tables, from `system_views.local_read_latency`, `local_write_latency` and `local_scan_latency`.",
+		Tags: map[string]interface{}{
+			"server":   &inputs.TagInfo{Desc: "Address of the node."},
+			"keyspace": &inputs.TagInfo{Desc: "Keyspace of the table."},
+			"table":    &inputs.TagInfo{Desc: "Name of the table."},
+		},
+		Fields: fields,
+	}
+}
@@ -0,0 +1,252 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const (
+	measurementMetrics          = "clickhouse_metrics"
+	measurementEvents           = "clickhouse_events"
+	measurementAsyncMetrics     = "clickhouse_asynchronous_metrics"
+	measurementReplicationQueue = "clickhouse_replication_queue"
+	measurementParts            = "clickhouse_parts"
+	measurementSlowQuery        = "clickhouse_slow_query"
+
+	maxSlowQueries = 1000 // per collection
+)
+
+const (
+	sqlReplicationQueue = `
+SELECT
+  database,
+  table,
+  count() AS tasks,
+  countIf(last_exception != '') AS tasks_with_exception,
+  sum(num_tries) AS tries,
+  max(now() - create_time) AS oldest_task_age
+FROM system.replication_queue
+GROUP BY database, table`
+
+	sqlParts = `
+SELECT
+  database,
+  table,
+  count() AS parts,
+  sum(rows) AS rows,
+  sum(bytes_on_disk) AS bytes_on_disk,
+  sum(data_compressed_bytes) AS data_compressed_bytes,
+  sum(data_uncompressed_bytes) AS data_uncompressed_bytes
+FROM system.parts
+WHERE active
+GROUP BY database, table`
+
+	sqlSlowQueries = `
+SELECT
+  query_id,
+  user,
+  query,
+  query_duration_ms,
+  read_rows,
+  read_bytes,
+  written_rows,
+  result_rows,
+  memory_usage,
+  arrayStringConcat(databases, ',') AS databases,
+  toUnixTimestamp64Micro(event_time_microseconds) AS ts
+FROM system.query_log
+WHERE type = 'QueryFinish'
+  AND event_date >= toDate(fromUnixTimestamp64Micro(toInt64(%[1]d)))
+  AND event_time_microseconds > fromUnixTimestamp64Micro(toInt64(%[1]d))
+  AND query_duration_ms >= %[2]d
+ORDER BY event_time_microseconds
+LIMIT %[3]d`
+)
+
+// collectMetrics returns points of system tables, tables failed to query are
+// skipped with errors returned.
+func (ipt *Input) collectMetrics(ctx context.Context, now time.Time) ([]*point.Point, []error) {
+	var (
+		pts  []*point.Point
+		errs []error
+	)
+
+	// tables of name-value rows, each table into one point
+	for _, t := range []struct {
+		measurement, table, nameColumn string
+		float                          bool
+	}{
+		{measurementMetrics, "system.metrics", "metric", false},
+		{measurementEvents, "system.events", "event", false},
+		{measurementAsyncMetrics, "system.asynchronous_metrics", "metric", true},
+	} {
+		rows, err := ipt.client.query(ctx, fmt.Sprintf("SELECT %s AS name, value FROM %s", t.nameColumn, t.table))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("query %s: %w", t.table, err))
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		for _, r := range rows {
+			name, _ := r["name"].(string)
+			if name == "" {
+				continue
+			}
+
+			if v, ok := numericValue(r["value"], t.float); ok {
+				fields[name] = v
+			}
+		}
+
+		if len(fields) > 0 {
+			pts = append(pts, point.NewPointV2([]byte(t.measurement),
+				append(point.NewTags(ipt.tags(nil)), point.NewKVs(fields)...),
+				ipt.pointOptions(point.DefaultMetricOptions(), now)...))
+		}
+	}
+
+	// tables grouped by database and table
+	for _, t := range []struct {
+		measurement, table, sql string
+	}{
+		{measurementReplicationQueue, "system.replication_queue", sqlReplicationQueue},
+		{measurementParts, "system.parts", sqlParts},
+	} {
+		rows, err := ipt.client.query(ctx, t.sql)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("query %s: %w", t.table, err))
+			continue
+		}
+
+		pts = append(pts, ipt.makePoints(t.measurement, rows, now)...)
+	}
+
+	return pts, errs
+}
+
+// makePoints makes a point of each row, with database and table as tags.
+func (ipt *Input) makePoints(name string, rows []row, now time.Time) []*point.Point {
+	var pts []*point.Point
+
+	for _, r := range rows {
+		tags := map[string]string{}
+		fields := map[string]interface{}{}
+
+		for k, v := range r {
+			switch k {
+			case "database", "table":
+				if s, ok := v.(string); ok {
+					tags[k] = s
+				}
+			default:
+				if x, ok := numericValue(v, false); ok {
+					fields[k] = x
+				}
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(name),
+			append(point.NewTags(ipt.tags(tags)), point.NewKVs(fields)...),
+			ipt.pointOptions(point.DefaultMetricOptions(), now)...))
+	}
+
+	return pts
+}
+
+// collectSlowQueries returns queries finished since last collected and
+// slower than slow_query_time.
+func (ipt *Input) collectSlowQueries(ctx context.Context) ([]*point.Point, error) {
+	rows, err := ipt.client.query(ctx, fmt.Sprintf(sqlSlowQueries,
+		ipt.slowSince.UnixMicro(), ipt.SlowQueryTime.Duration.Milliseconds(), maxSlowQueries))
+	if err != nil {
+		return nil, fmt.Errorf("query system.query_log: %w", err)
+	}
+
+	var pts []*point.Point
+	for _, r := range rows {
+		ts, _ := r["ts"].(int64)
+		if ts <= 0 {
+			continue
+		}
+
+		t := time.UnixMicro(ts)
+		if t.After(ipt.slowSince) {
+			ipt.slowSince = t
+		}
+
+		tags := map[string]string{}
+		if user, ok := r["user"].(string); ok && user != "" {
+			tags["user"] = user
+		}
+
+		fields := map[string]interface{}{
+			"status": "warning",
+		}
+
+		for k, v := range r {
+			switch k {
+			case "ts", "user":
+			case "query":
+				fields["message"] = v
+			case "query_id", "databases":
+				if s, ok := v.(string); ok {
+					fields[k] = s
+				}
+			default:
+				if x, ok := numericValue(v, false); ok {
+					fields[k] = x
+				}
+			}
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(measurementSlowQuery),
+			append(point.NewTags(ipt.tags(tags)), point.NewKVs(fields)...),
+			ipt.pointOptions(point.DefaultLoggingOptions(), t)...))
+	}
+
+	return pts, nil
+}
+
+// tags returns tags of the server and the input, with extra ones.
+func (ipt *Input) tags(extra map[string]string) map[string]string {
+	tags := map[string]string{"server": ipt.server}
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+	for k, v := range extra {
+		tags[k] = v
+	}
+	return tags
+}
+
+// numericValue returns int64 or float64 value, or float64 only if asFloat,
+// NaN and Inf dropped.
+func numericValue(v interface{}, asFloat bool) (interface{}, bool) {
+	switch x := v.(type) {
+	case int64:
+		if asFloat {
+			return float64(x), true
+		}
+		return x, true
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, false
+		}
+		return x, true
+	default:
+		return nil, false
+	}
+}
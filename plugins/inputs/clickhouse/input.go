@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package clickhouse collects ClickHouse metrics from system tables over the HTTP interface.
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	dknet "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/net"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "clickhouse"
+	catalog   = "db"
+
+	minInterval = time.Second
+	maxInterval = 10 * time.Minute
+
+	sampleCfg = `
+[[inputs.clickhouse]]
+  ## DSN of the HTTP interface, with the user and password if required.
+  ## The user should be able to SELECT on database system.
+  dsn = "http://default:@127.0.0.1:8123"
+
+  interval = "10s"
+  timeout  = "5s"
+
+  ## Set true to collect on the elected DataKit only
+  election = true
+
+  ## Collect queries in system.query_log slower than slow_query_time as
+  ## logging, disabled if 0. The query_log should be enabled on the server.
+  slow_query_time = "0s"
+
+  ## TLS of https DSN
+  # [inputs.clickhouse.tlsconf]
+  #   ca_certs = ["/path/to/ca.pem"]
+  #   cert = "/path/to/cert.pem"
+  #   cert_key = "/path/to/key.pem"
+  #   insecure_skip_verify = false
+
+  [inputs.clickhouse.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	DSN           string                 `toml:"dsn"`
+	Interval      datakit.Duration       `toml:"interval"`
+	Timeout       datakit.Duration       `toml:"timeout"`
+	Election      bool                   `toml:"election"`
+	SlowQueryTime datakit.Duration       `toml:"slow_query_time"`
+	TLSConf       *dknet.TLSClientConfig `toml:"tlsconf"`
+	Tags          map[string]string      `toml:"tags"`
+
+	client    *client
+	server    string    // host:port of the DSN, as tag
+	slowSince time.Time // end time of slow queries collected
+
+	pause   bool
+	pauseCh chan bool
+
+	feeder  dkio.Feeder
+	semStop *cliutils.Sem // start stop signal
+}
+
+var (
+	_ inputs.ElectionInput = (*Input)(nil)
+	_ inputs.InputV2       = (*Input)(nil)
+)
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOSWithElection }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&metricsMeasurement{},
+		&eventsMeasurement{},
+		&asyncMetricsMeasurement{},
+		&replicationQueueMeasurement{},
+		&partsMeasurement{},
+		&slowQueryMeasurement{},
+	}
+}
+
+func (ipt *Input) ElectionEnabled() bool {
+	return ipt.Election
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		if ipt.pause {
+			l.Debugf("not leader, skipped")
+		} else {
+			ipt.collect()
+		}
+
+		select {
+		case <-datakit.Exit.Wait():
+			l.Info(inputName + " exit")
+			return
+		case <-ipt.semStop.Wait():
+			l.Info(inputName + " return")
+			return
+		case ipt.pause = <-ipt.pauseCh:
+		case <-tick.C:
+		}
+	}
+}
+
+func (ipt *Input) setup() error {
+	tr := &http.Transport{}
+	if ipt.TLSConf != nil {
+		conf, err := ipt.TLSConf.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("TLS config: %w", err)
+		}
+		tr.TLSClientConfig = conf
+	}
+
+	c, err := newClient(ipt.DSN, &http.Client{Transport: tr, Timeout: ipt.Timeout.Duration})
+	if err != nil {
+		return fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	u, _ := url.Parse(c.url)
+	ipt.client = c
+	ipt.server = u.Host
+
+	// slow queries before started are not collected
+	ipt.slowSince = time.Now()
+
+	return nil
+}
+
+func (ipt *Input) collect() {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ipt.Interval.Duration)
+	defer cancel()
+
+	pts, errs := ipt.collectMetrics(ctx, start)
+	for _, err := range errs {
+		l.Errorf("collect %s: %s", ipt.server, err)
+		ipt.feeder.FeedLastError(inputName, fmt.Sprintf("collect %s: %s", ipt.server, err))
+	}
+
+	if len(pts) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.Metric, pts,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed: %s", err)
+		}
+	}
+
+	if ipt.SlowQueryTime.Duration <= 0 {
+		return
+	}
+
+	logs, err := ipt.collectSlowQueries(ctx)
+	if err != nil {
+		l.Errorf("collect slow queries of %s: %s", ipt.server, err)
+		ipt.feeder.FeedLastError(inputName, fmt.Sprintf("collect slow queries of %s: %s", ipt.server, err))
+	}
+
+	if len(logs) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.Logging, logs,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed: %s", err)
+		}
+	}
+}
+
+// pointOptions returns options of points, with the election tags if enabled.
+func (ipt *Input) pointOptions(opts []point.Option, ts time.Time) []point.Option {
+	opts = append(opts, point.WithTime(ts))
+	if ipt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+	return opts
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (ipt *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (ipt *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		DSN:      "http://127.0.0.1:8123",
+		Interval: datakit.Duration{Duration: 10 * time.Second},
+		Timeout:  datakit.Duration{Duration: 5 * time.Second},
+		Election: true,
+		Tags:     map[string]string{},
+		pauseCh:  make(chan bool, inputs.ElectionPauseChannelLength),
+		feeder:   dkio.DefaultFeeder(),
+		semStop:  cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
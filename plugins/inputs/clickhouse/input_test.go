@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func fakeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "datakit" || r.Header.Get("X-ClickHouse-Key") != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, "Code: 516. DB::Exception: datakit: Authentication failed") //nolint:errcheck
+			return
+		}
+
+		assert.Equal(t, "JSON", r.URL.Query().Get("default_format"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		sql := string(body)
+
+		var data string
+		switch {
+		case strings.Contains(sql, "FROM system.metrics"):
+			data = `[{"name":"Query","value":3},{"name":"MemoryTracking","value":1048576}]`
+		case strings.Contains(sql, "FROM system.events"):
+			data = `[{"name":"SelectQuery","value":18446744073709551615},{"name":"FailedQuery","value":2}]`
+		case strings.Contains(sql, "FROM system.asynchronous_metrics"):
+			data = `[{"name":"Uptime","value":3600},{"name":"Jitter","value":0.000125},{"name":"Bad","value":null}]`
+		case strings.Contains(sql, "FROM system.replication_queue"):
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "Code: 60. DB::Exception: Table system.replication_queue doesn't exist") //nolint:errcheck
+			return
+		case strings.Contains(sql, "FROM system.parts"):
+			data = `[{"database":"default","table":"hits","parts":5,"rows":1000,"bytes_on_disk":4096,` +
+				`"data_compressed_bytes":4000,"data_uncompressed_bytes":8000}]`
+		case strings.Contains(sql, "FROM system.query_log"):
+			assert.Contains(t, sql, "query_duration_ms >= 500")
+			data = `[{"query_id":"q1","user":"default","query":"SELECT sleep(1)","query_duration_ms":1001,` +
+				`"read_rows":1,"read_bytes":1,"written_rows":0,"result_rows":1,"memory_usage":1024,` +
+				`"databases":"default","ts":1700000000123456}]`
+		default:
+			t.Errorf("unexpected query %s", sql)
+		}
+
+		io.WriteString(w, `{"meta":[],"data":`+data+`,"rows":1}`) //nolint:errcheck
+	}))
+}
+
+func TestCollect(t *testing.T) {
+	ts := fakeServer(t)
+	defer ts.Close()
+
+	ipt := defaultInput()
+	ipt.DSN = strings.Replace(ts.URL, "http://", "http://datakit:pass@", 1)
+	ipt.Election = false
+	ipt.SlowQueryTime.Duration = 500 * time.Millisecond
+	ipt.Tags["cluster"] = "c1"
+
+	feeder := dkio.NewMockedFeeder()
+	ipt.feeder = feeder
+
+	require.NoError(t, ipt.setup())
+	ipt.slowSince = time.UnixMicro(1700000000000000)
+	ipt.collect()
+
+	pts, err := feeder.NPoints(5, time.Second)
+	require.NoError(t, err)
+
+	byName := map[string]*point.Point{}
+	for _, pt := range pts {
+		byName[string(pt.Name())] = pt
+		assert.Equal(t, []byte(ipt.server), pt.Get([]byte("server")))
+		assert.Equal(t, []byte("c1"), pt.Get([]byte("cluster")))
+	}
+
+	require.Len(t, byName, 5)
+	assert.NotContains(t, byName, measurementReplicationQueue)
+
+	pt := byName[measurementMetrics]
+	assert.Equal(t, int64(3), pt.Get([]byte("Query")))
+	assert.Equal(t, int64(1048576), pt.Get([]byte("MemoryTracking")))
+
+	pt = byName[measurementEvents]
+	assert.Equal(t, float64(18446744073709551615), pt.Get([]byte("SelectQuery")))
+	assert.Equal(t, int64(2), pt.Get([]byte("FailedQuery")))
+
+	pt = byName[measurementAsyncMetrics]
+	assert.Equal(t, 3600.0, pt.Get([]byte("Uptime")))
+	assert.Equal(t, 0.000125, pt.Get([]byte("Jitter")))
+	assert.Nil(t, pt.Get([]byte("Bad")))
+
+	pt = byName[measurementParts]
+	assert.Equal(t, []byte("hits"), pt.Get([]byte("table")))
+	assert.Equal(t, int64(5), pt.Get([]byte("parts")))
+
+	pt = byName[measurementSlowQuery]
+	assert.Equal(t, []byte("default"), pt.Get([]byte("user")))
+	assert.Equal(t, []byte("SELECT sleep(1)"), pt.Get([]byte("message")))
+	assert.Equal(t, int64(1001), pt.Get([]byte("query_duration_ms")))
+	assert.Equal(t, time.UnixMicro(1700000000123456), ipt.slowSince)
+	assert.Equal(t, time.UnixMicro(1700000000123456).UnixNano(), pt.Time().UnixNano())
+
+	t.Run("auth-failed", func(t *testing.T) {
+		ipt := defaultInput()
+		ipt.DSN = ts.URL
+
+		require.NoError(t, ipt.setup())
+
+		_, errs := ipt.collectMetrics(context.Background(), time.Now())
+		require.Len(t, errs, 5)
+		assert.Contains(t, errs[0].Error(), "Authentication failed")
+	})
+
+	t.Run("invalid-dsn", func(t *testing.T) {
+		ipt := defaultInput()
+		ipt.DSN = "tcp://127.0.0.1:9000"
+		assert.Error(t, ipt.setup())
+	})
+}
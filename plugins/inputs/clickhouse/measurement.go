@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package clickhouse
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var serverTag = &inputs.TagInfo{Desc: "Address of the server, host:port of the DSN."}
+
+type metricsMeasurement struct{}
+
+func (m *metricsMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *metricsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementMetrics,
+		Type: "metric",
+		Desc: "Current values of `system.metrics`, each metric as a field of the same name, only some common ones listed.",
+		Tags: map[string]interface{}{
+			"server": serverTag,
+		},
+		Fields: map[string]interface{}{
+			"Query":                                &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of executing queries."},
+			"Merge":                                &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of executing background merges."},
+			"TCPConnection":                        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of connections to the TCP server."},
+			"HTTPConnection":                       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of connections to the HTTP server."},
+			"MemoryTracking":                       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Total memory allocated by the server."},
+			"ReadonlyReplica":                      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of replicated tables in readonly state."},
+			"DelayedInserts":                       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of INSERT queries throttled due to many active parts."},
+			"ReplicatedFetch":                      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of data parts being fetched from replicas."},
+			"BackgroundMergesAndMutationsPoolTask": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of active merges and mutations in the background pool."},
+		},
+	}
+}
+
+type eventsMeasurement struct{}
+
+func (m *eventsMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *eventsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementEvents,
+		Type: "metric",
+		Desc: "Cumulative counters of `system.events` since the server started, each event as a field of the same name, only some common ones listed.",
+		Tags: map[string]interface{}{
+			"server": serverTag,
+		},
+		Fields: map[string]interface{}{
+			"Query":                 &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of queries to be interpreted and potentially executed."},
+			"SelectQuery":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of SELECT queries."},
+			"InsertQuery":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of INSERT queries."},
+			"FailedQuery":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of failed queries."},
+			"InsertedRows":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of rows inserted to all tables."},
+			"InsertedBytes":         &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.SizeByte, Desc: "Number of bytes inserted to all tables."},
+			"SelectedRows":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of rows selected from all tables."},
+			"SelectedBytes":         &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.SizeByte, Desc: "Number of bytes selected from all tables."},
+			"Merge":                 &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of launched background merges."},
+			"MergedRows":            &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Rows read for background merges."},
+			"DelayedInserts":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of times INSERT was throttled due to many active parts."},
+			"RejectedInserts":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of times INSERT was rejected with `Too many parts`."},
+			"ReplicatedPartFetches": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of data parts downloaded from replicas."},
+		},
+	}
+}
+
+type asyncMetricsMeasurement struct{}
+
+func (m *asyncMetricsMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *asyncMetricsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementAsyncMetrics,
+		Type: "metric",
+		Desc: "Values of `system.asynchronous_metrics` calculated periodically in background, each metric as a float field of the same name, only some common ones listed.",
+		Tags: map[string]interface{}{
+			"server": serverTag,
+		},
+		Fields: map[string]interface{}{
+			"Uptime":                      &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Uptime of the server."},
+			"MaxPartCountForPartition":    &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Max number of parts per partition across all MergeTree tables."},
+			"ReplicasMaxAbsoluteDelay":    &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Max delay of replicated tables behind the freshest replica."},
+			"ReplicasMaxQueueSize":        &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Max queue size of replicated tables."},
+			"NumberOfDatabases":           &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of databases."},
+			"NumberOfTables":              &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tables in all databases."},
+			"TotalBytesOfMergeTreeTables": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Total bytes of all MergeTree tables."},
+			"TotalRowsOfMergeTreeTables":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Total rows of all MergeTree tables."},
+		},
+	}
+}
+
+type replicationQueueMeasurement struct{}
+
+func (m *replicationQueueMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *replicationQueueMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementReplicationQueue,
+		Type: "metric",
+		Desc: "Tasks of replicated tables in `system.replication_queue`, absent if the queue is empty.",
+		Tags: map[string]interface{}{
+			"server":   serverTag,
+			"database": &inputs.TagInfo{Desc: "Database of the table."},
+			"table":    &inputs.TagInfo{Desc: "Name of the table."},
+		},
+		Fields: map[string]interface{}{
+			"tasks":                &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tasks in the queue."},
+			"tasks_with_exception": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tasks failed with exceptions."},
+			"tries":                &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of tries of all tasks."},
+			"oldest_task_age":      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Age of the oldest task."},
+		},
+	}
+}
+
+type partsMeasurement struct{}
+
+func (m *partsMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *partsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementParts,
+		Type: "metric",
+		Desc: "Active parts of MergeTree tables in `system.parts`.",
+		Tags: map[string]interface{}{
+			"server":   serverTag,
+			"database": &inputs.TagInfo{Desc: "Database of the table."},
+			"table":    &inputs.TagInfo{Desc: "Name of the table."},
+		},
+		Fields: map[string]interface{}{
+			"parts":                   &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of active parts."},
+			"rows":                    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of rows."},
+			"bytes_on_disk":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Size of all part files on disk."},
+			"data_compressed_bytes":   &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Size of compressed data."},
+			"data_uncompressed_bytes": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Size of uncompressed data."},
+		},
+	}
+}
+
+type slowQueryMeasurement struct{}
+
+func (m *slowQueryMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+//nolint:lll
+func (m *slowQueryMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementSlowQuery,
+		Type: "logging",
+		Desc: "Finished queries slower than `slow_query_time` in `system.query_log`, only if `slow_query_time` set.",
+		Tags: map[string]interface{}{
+			"server": serverTag,
+			"user":   &inputs.TagInfo{Desc: "User of the query."},
+		},
+		Fields: map[string]interface{}{
+			"message":           &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "Text of the query."},
+			"status":            &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "Always `warning`."},
+			"query_id":          &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "ID of the query."},
+			"databases":         &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "Databases accessed by the query, separated by comma."},
+			"query_duration_ms": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Duration of the query."},
+			"read_rows":         &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of rows read."},
+			"read_bytes":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Number of bytes read."},
+			"written_rows":      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of rows written."},
+			"result_rows":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of rows in the result."},
+			"memory_usage":      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Memory consumed by the query."},
+		},
+	}
+}
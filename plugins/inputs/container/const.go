@@ -80,6 +80,17 @@ const sampleCfg = `
   [inputs.container.logging_source_multiline_map]
     # source = '''^\d{4}'''
 
+  ## Logging quotas(MB per minute) shared by containers in the namespace and of pods
+  ## matched by the label selector, the first matched applied. Logs over quota are
+  ## dropped with a keyevent sent(over_quota = "drop"), or sampled by sample_ratio
+  ## (over_quota = "sample").
+  # [[inputs.container.logging_quotas]]
+  #   namespace = "default"
+  #   pod_label_selector = "app=nginx,tier in (frontend)"
+  #   mb_per_minute = 10.0
+  #   over_quota = "drop"
+  #   sample_ratio = 0.1
+
   [inputs.container.tags]
     # some_tag = "some_value"
     # more_tag = "some_other_value"
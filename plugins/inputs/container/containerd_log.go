@@ -121,6 +121,7 @@ func (c *containerdInput) tailingLog(status *cri.ContainerStatus) error {
 	opt, _ := composeTailerOption(c.k8sClient, info)
 	opt.Mode = tailer.ContainerdMode
 	opt.BlockingMode = c.ipt.LoggingBlockingMode
+	opt.LineFilter = c.ipt.loggingQuotas.lineFilter(info.tags, info.podLabels)
 	opt.MinFlushInterval = c.ipt.LoggingMinFlushInterval
 	opt.MaxMultilineLifeDuration = c.ipt.LoggingMaxMultilineLifeDuration
 	opt.Done = c.ipt.semStop.Wait()
@@ -97,6 +97,7 @@ func (d *dockerInput) tailingLog(ctx context.Context, container *types.Container
 		opt, paths := composeTailerOption(d.k8sClient, info)
 		opt.Mode = tailer.FileMode
 		opt.BlockingMode = d.ipt.LoggingBlockingMode
+		opt.LineFilter = d.ipt.loggingQuotas.lineFilter(info.tags, info.podLabels)
 		opt.MinFlushInterval = d.ipt.LoggingMinFlushInterval
 		opt.MaxMultilineLifeDuration = d.ipt.LoggingMaxMultilineLifeDuration
 		opt.Done = d.ipt.semStop.Wait()
@@ -124,6 +125,7 @@ func (d *dockerInput) tailingLog(ctx context.Context, container *types.Container
 		opt, _ := composeTailerOption(d.k8sClient, info)
 		opt.Mode = tailer.DockerMode
 		opt.BlockingMode = d.ipt.LoggingBlockingMode
+		opt.LineFilter = d.ipt.loggingQuotas.lineFilter(info.tags, info.podLabels)
 		opt.MinFlushInterval = d.ipt.LoggingMinFlushInterval
 		opt.MaxMultilineLifeDuration = d.ipt.LoggingMaxMultilineLifeDuration
 		opt.Done = d.ipt.semStop.Wait()
@@ -214,6 +216,7 @@ func deepCopyTailerOption(in *tailer.Option) *tailer.Option {
 	out.DisableAddStatusField = in.DisableAddStatusField
 	out.DisableHighFreqIODdata = in.DisableHighFreqIODdata
 	out.ForwardFunc = in.ForwardFunc
+	out.LineFilter = in.LineFilter
 	out.IgnoreDeadLog = in.IgnoreDeadLog
 	out.BlockingMode = in.BlockingMode
 	out.MinFlushInterval = in.MinFlushInterval
@@ -224,6 +227,11 @@ func deepCopyTailerOption(in *tailer.Option) *tailer.Option {
 		out.GlobalTags[k] = v
 	}
 
+	out.Meta = make(map[string]string)
+	for k, v := range in.Meta {
+		out.Meta[k] = v
+	}
+
 	return out
 }
 
@@ -40,6 +40,7 @@ import (
 //   ENV_INPUT_CONTAINER_LOGGING_AUTO_MULTILINE_EXTRA_PATTERNS_JSON : string (JSON string array)
 //   ENV_INPUT_CONTAINER_LOGGING_MIN_FLUSH_INTERVAL: string ("10s")
 //   ENV_INPUT_CONTAINER_LOGGING_MAX_MULTILINE_LIFE_DURATION : string ("5s")
+//   ENV_INPUT_CONTAINER_LOGGING_QUOTAS_JSON : string (JSON array of logging quotas)
 //   ENV_INPUT_CONTAINER_PROMETHEUS_MONITORING_MATCHES_CONFIG : string (JSON to prometheusMonitoringExtraConfig)
 func (i *Input) ReadEnv(envs map[string]string) {
 	if endpoint, ok := envs["ENV_INPUT_CONTAINER_DOCKER_ENDPOINT"]; ok {
@@ -221,6 +222,15 @@ func (i *Input) ReadEnv(envs map[string]string) {
 		}
 	}
 
+	if str, ok := envs["ENV_INPUT_CONTAINER_LOGGING_QUOTAS_JSON"]; ok {
+		var quotas []*LoggingQuota
+		if err := json.Unmarshal([]byte(str), &quotas); err != nil {
+			l.Warnf("parse ENV_INPUT_CONTAINER_LOGGING_QUOTAS_JSON to logging quotas: %s, ignore", err)
+		} else {
+			i.LoggingQuotas = quotas
+		}
+	}
+
 	if confStr, ok := envs["ENV_INPUT_CONTAINER_PROMETHEUS_MONITORING_MATCHES_CONFIG"]; ok {
 		var conf prometheusMonitoringExtraConfig
 		if err := json.Unmarshal([]byte(confStr), &conf); err != nil {
@@ -14,6 +14,7 @@ import (
 	"github.com/GuanceCloud/cliutils"
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/logtail/multiline"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/tailer"
 	timex "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/time"
@@ -64,6 +65,7 @@ type Input struct {
 	LoggingAutoMultilineExtraPatterns []string          `toml:"logging_auto_multiline_extra_patterns"`
 	LoggingMinFlushInterval           time.Duration     `toml:"-"`
 	LoggingMaxMultilineLifeDuration   time.Duration     `toml:"-"`
+	LoggingQuotas                     []*LoggingQuota   `toml:"logging_quotas"`
 
 	Tags map[string]string `toml:"tags"`
 
@@ -82,6 +84,8 @@ type Input struct {
 	chPause chan bool
 	pause   bool
 
+	loggingQuotas loggingQuotas
+
 	discovery                       *discovery
 	prometheusMonitoringExtraConfig *prometheusMonitoringExtraConfig
 }
@@ -165,6 +169,13 @@ func (i *Input) Run() {
 		})
 	}
 
+	if datakit.Docker && i.k8sInput != nil {
+		g.Go(func(ctx context.Context) error {
+			i.k8sInput.selfCheckRBAC(i.semStop.Wait())
+			return nil
+		})
+	}
+
 	if datakit.Docker {
 		g := datakit.G("kubernetes-autodiscovery")
 		g.Go(func(ctx context.Context) error {
@@ -226,6 +237,11 @@ func (i *Input) collectObject() {
 		l.Debugf("collect object, cost %s", time.Since(timeNow))
 	}()
 
+	if loadshed.Shedding(loadshed.Object) {
+		l.Debug("container object shed on high load, skipped")
+		return
+	}
+
 	if err := i.gatherDockerContainerObject(); err != nil {
 		l.Errorf("failed to collect docker container object: %s", err)
 	}
@@ -449,6 +465,8 @@ func (i *Input) setup() {
 		i.DockerEndpoint = i.DepercatedEndpoint
 	}
 
+	i.loggingQuotas = newLoggingQuotas(i.LoggingQuotas, io.DefaultFeeder())
+
 	if d, err := newDockerInput(i); err != nil {
 		l.Warnf("create docker input err: %s", err)
 	} else {
@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubev1authorization "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+const (
+	rbacCheckInterval = 10 * time.Minute
+	rbacCheckTimeout  = 30 * time.Second
+)
+
+type rbacRule struct {
+	group           string
+	resources       []string
+	nonResourceURLs []string
+	verbs           []string
+}
+
+// rbacRules are permissions required by the kubernetes collecting, keep
+// the same as the ClusterRole of datakit.yaml and the helm chart.
+var rbacRules = []rbacRule{
+	{group: "rbac.authorization.k8s.io", resources: []string{"clusterroles"}, verbs: []string{"get", "list", "watch"}},
+	{
+		group:     "",
+		resources: []string{"nodes", "nodes/proxy", "namespaces", "pods", "pods/log", "events", "services", "endpoints"},
+		verbs:     []string{"get", "list", "watch"},
+	},
+	{group: "apps", resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"}, verbs: []string{"get", "list", "watch"}},
+	{group: "batch", resources: []string{"jobs", "cronjobs"}, verbs: []string{"get", "list", "watch"}},
+	{group: "guance.com", resources: []string{"datakits"}, verbs: []string{"get", "list"}},
+	{group: "monitoring.coreos.com", resources: []string{"podmonitors", "servicemonitors"}, verbs: []string{"get", "list"}},
+	{group: "metrics.k8s.io", resources: []string{"pods", "nodes"}, verbs: []string{"get", "list"}},
+	{nonResourceURLs: []string{"/metrics"}, verbs: []string{"get"}},
+}
+
+type rbacDenied struct {
+	verb     string
+	group    string
+	resource string // resource or non-resource URL
+	reason   string
+}
+
+func (d *rbacDenied) String() string {
+	group := d.group
+	if group == "" && !strings.HasPrefix(d.resource, "/") {
+		group = "core"
+	}
+
+	s := d.verb + " " + d.resource
+	if group != "" {
+		s += "(" + group + ")"
+	}
+	if d.reason != "" {
+		s += ": " + d.reason
+	}
+	return s
+}
+
+// checkRBAC asks the API server whether the ServiceAccount of datakit is
+// allowed to access each resource of rules, the denied ones are returned.
+func checkRBAC(client kubev1authorization.SelfSubjectAccessReviewInterface, rules []rbacRule) ([]*rbacDenied, error) {
+	var denied []*rbacDenied
+
+	review := func(attr *authorizationv1.ResourceAttributes, nonAttr *authorizationv1.NonResourceAttributes) (*authorizationv1.SubjectAccessReviewStatus, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), rbacCheckTimeout)
+		defer cancel()
+
+		res, err := client.Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes:    attr,
+				NonResourceAttributes: nonAttr,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &res.Status, nil
+	}
+
+	for _, rule := range rules {
+		for _, verb := range rule.verbs {
+			for _, res := range rule.resources {
+				resource, sub := res, ""
+				if idx := strings.Index(res, "/"); idx > 0 {
+					resource, sub = res[:idx], res[idx+1:]
+				}
+
+				status, err := review(&authorizationv1.ResourceAttributes{
+					Verb: verb, Group: rule.group, Resource: resource, Subresource: sub,
+				}, nil)
+				if err != nil {
+					return nil, fmt.Errorf("self subject access review failed: %w", err)
+				}
+				if !status.Allowed {
+					denied = append(denied, &rbacDenied{verb: verb, group: rule.group, resource: res, reason: status.Reason})
+				}
+			}
+
+			for _, u := range rule.nonResourceURLs {
+				status, err := review(nil, &authorizationv1.NonResourceAttributes{Verb: verb, Path: u})
+				if err != nil {
+					return nil, fmt.Errorf("self subject access review failed: %w", err)
+				}
+				if !status.Allowed {
+					denied = append(denied, &rbacDenied{verb: verb, resource: u, reason: status.Reason})
+				}
+			}
+		}
+	}
+
+	return denied, nil
+}
+
+func rbacDeniedMessage(denied []*rbacDenied) string {
+	arr := make([]string, 0, len(denied))
+	for _, d := range denied {
+		arr = append(arr, d.String())
+	}
+
+	return fmt.Sprintf("ServiceAccount of datakit missing %d RBAC permissions, "+
+		"related kubernetes data will not be collected, please update the ClusterRole: %s",
+		len(denied), strings.Join(arr, "; "))
+}
+
+// selfCheckRBAC checks permissions of datakit on startup and periodically,
+// missing permissions are reported as last error of the input.
+func (k *kubernetesInput) selfCheckRBAC(done <-chan interface{}) {
+	tick := time.NewTicker(rbacCheckInterval)
+	defer tick.Stop()
+
+	var lastDenied int
+	for {
+		denied, err := checkRBAC(k.client.AuthorizationV1().SelfSubjectAccessReviews(), rbacRules)
+		switch {
+		case err != nil:
+			l.Warnf("RBAC self-check skipped: %s", err)
+
+		case len(denied) > 0:
+			msg := rbacDeniedMessage(denied)
+			l.Error(msg)
+			io.FeedLastError(inputName, msg)
+
+		case lastDenied > 0:
+			l.Info("RBAC self-check passed, all missing permissions granted")
+
+		default:
+			l.Debug("RBAC self-check passed")
+		}
+		lastDenied = len(denied)
+
+		select {
+		case <-datakit.Exit.Wait():
+			return
+		case <-done:
+			return
+		case <-tick.C:
+		}
+	}
+}
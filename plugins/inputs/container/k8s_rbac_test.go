@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package container
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type mockAccessReview struct {
+	allowed func(*authorizationv1.SelfSubjectAccessReviewSpec) bool
+	err     error
+	count   int
+}
+
+func (m *mockAccessReview) Create(_ context.Context,
+	r *authorizationv1.SelfSubjectAccessReview, _ metav1.CreateOptions,
+) (*authorizationv1.SelfSubjectAccessReview, error) {
+	m.count++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	r.Status.Allowed = m.allowed(&r.Spec)
+	if !r.Status.Allowed {
+		r.Status.Reason = "forbidden"
+	}
+	return r, nil
+}
+
+func TestCheckRBAC(t *testing.T) {
+	rules := []rbacRule{
+		{group: "", resources: []string{"pods", "pods/log"}, verbs: []string{"get", "list"}},
+		{group: "apps", resources: []string{"deployments"}, verbs: []string{"list"}},
+		{nonResourceURLs: []string{"/metrics"}, verbs: []string{"get"}},
+	}
+
+	t.Run("all-allowed", func(t *testing.T) {
+		m := &mockAccessReview{allowed: func(*authorizationv1.SelfSubjectAccessReviewSpec) bool { return true }}
+
+		denied, err := checkRBAC(m, rules)
+		assert.NoError(t, err)
+		assert.Empty(t, denied)
+		assert.Equal(t, 6, m.count)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		m := &mockAccessReview{allowed: func(s *authorizationv1.SelfSubjectAccessReviewSpec) bool {
+			if s.NonResourceAttributes != nil {
+				return false
+			}
+			a := s.ResourceAttributes
+			return !(a.Resource == "pods" && a.Subresource == "log") && a.Group != "apps"
+		}}
+
+		denied, err := checkRBAC(m, rules)
+		assert.NoError(t, err)
+
+		var arr []string
+		for _, d := range denied {
+			arr = append(arr, d.String())
+		}
+		assert.Equal(t, []string{
+			"get pods/log(core): forbidden",
+			"list pods/log(core): forbidden",
+			"list deployments(apps): forbidden",
+			"get /metrics: forbidden",
+		}, arr)
+
+		assert.Contains(t, rbacDeniedMessage(denied), "missing 4 RBAC permissions")
+	})
+
+	t.Run("review-failed", func(t *testing.T) {
+		m := &mockAccessReview{err: errors.New("not found")}
+
+		_, err := checkRBAC(m, rules)
+		assert.Error(t, err)
+		assert.Equal(t, 1, m.count)
+	})
+}
@@ -26,6 +26,7 @@ type containerLogBasisInfo struct {
 	image                 string
 	labels                map[string]string
 	tags                  map[string]string
+	podLabels             map[string]string
 	created               string
 	extraSourceMap        map[string]string
 	sourceMultilineMap    map[string]string
@@ -65,6 +66,7 @@ func composeTailerOption(k8sClient k8sClientX, info *containerLogBasisInfo) (*ta
 
 	opt := &tailer.Option{
 		GlobalTags:    info.tags,
+		Meta:          make(map[string]string),
 		IgnoreDeadLog: ignoreDeadLogDuration,
 	}
 
@@ -113,6 +115,13 @@ func composeTailerOption(k8sClient k8sClientX, info *containerLogBasisInfo) (*ta
 			info.tags["deployment"] = deployment
 		}
 
+		info.podLabels = meta.Labels
+
+		// pod labels always go to __meta for pipeline
+		for k, v := range meta.Labels {
+			opt.Meta["label_"+k] = v
+		}
+
 		// extract pod labels to tags
 		if info.extractK8sLabelAsTags {
 			for k, v := range meta.Labels {
@@ -179,6 +188,9 @@ func composeTailerOption(k8sClient k8sClientX, info *containerLogBasisInfo) (*ta
 		for k, v := range logconf.Tags {
 			opt.GlobalTags[k] = v
 		}
+		for k, v := range logconf.Meta {
+			opt.Meta[k] = v
+		}
 
 		l.Debugf("use container logconfig:%#v, containerId: %s, source: %s, logpath: %s", logconf, info.id, opt.Source, info.logPath)
 	}
@@ -214,6 +226,7 @@ type containerLogConfig struct {
 	Multiline  string            `json:"multiline_match"`
 	OnlyImages []string          `json:"only_images"`
 	Tags       map[string]string `json:"tags"`
+	Meta       map[string]string `json:"meta"`
 }
 
 const (
@@ -0,0 +1,199 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package container
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	quotaActionDrop   = "drop"
+	quotaActionSample = "sample"
+
+	defaultQuotaSampleRatio = 0.1
+	quotaWindow             = time.Minute
+	quotaEventName          = "logging_quota"
+)
+
+// LoggingQuota limits the logging bytes collected per minute, shared by all
+// containers in the namespace and of pods matched by the label selector.
+type LoggingQuota struct {
+	Namespace        string  `toml:"namespace" json:"namespace"`
+	PodLabelSelector string  `toml:"pod_label_selector" json:"pod_label_selector"`
+	MBPerMinute      float64 `toml:"mb_per_minute" json:"mb_per_minute"`
+	OverQuota        string  `toml:"over_quota" json:"over_quota"`
+	SampleRatio      float64 `toml:"sample_ratio" json:"sample_ratio"`
+}
+
+type quotaLimiter struct {
+	conf      *LoggingQuota
+	selector  labels.Selector
+	limit     int64 // bytes per window
+	keepEvery int64 // keep 1 of every keepEvery lines if over quota and sampling
+
+	feeder dkio.Feeder
+
+	mu        sync.Mutex
+	window    time.Time
+	bytes     int64
+	overLines int64
+	notified  bool
+}
+
+func newQuotaLimiter(conf *LoggingQuota, feeder dkio.Feeder) (*quotaLimiter, error) {
+	if conf.MBPerMinute <= 0 {
+		return nil, fmt.Errorf("invalid mb_per_minute %v, should be greater than 0", conf.MBPerMinute)
+	}
+
+	q := &quotaLimiter{
+		conf:     conf,
+		selector: labels.Everything(),
+		limit:    int64(conf.MBPerMinute * 1024 * 1024),
+		feeder:   feeder,
+	}
+
+	if conf.PodLabelSelector != "" {
+		s, err := labels.Parse(conf.PodLabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod_label_selector %q: %w", conf.PodLabelSelector, err)
+		}
+		q.selector = s
+	}
+
+	switch conf.OverQuota {
+	case "", quotaActionDrop:
+		conf.OverQuota = quotaActionDrop
+	case quotaActionSample:
+		ratio := conf.SampleRatio
+		if ratio <= 0 || ratio > 1 {
+			ratio = defaultQuotaSampleRatio
+		}
+		q.keepEvery = int64(1/ratio + 0.5)
+	default:
+		return nil, fmt.Errorf("invalid over_quota %q, only drop/sample allowed", conf.OverQuota)
+	}
+
+	return q, nil
+}
+
+func (q *quotaLimiter) match(namespace string, podLabels map[string]string) bool {
+	if q.conf.Namespace != "" && q.conf.Namespace != namespace {
+		return false
+	}
+	return q.selector.Matches(labels.Set(podLabels))
+}
+
+// allow checks the text of the container tagged, the keyevent sent once in
+// the window in which the text dropped over quota.
+func (q *quotaLimiter) allow(text string, tags map[string]string, now time.Time) bool {
+	window := now.Truncate(quotaWindow)
+
+	q.mu.Lock()
+	if !window.Equal(q.window) {
+		q.window = window
+		q.bytes = 0
+		q.overLines = 0
+		q.notified = false
+	}
+
+	if q.bytes+int64(len(text)) <= q.limit {
+		q.bytes += int64(len(text))
+		q.mu.Unlock()
+		return true
+	}
+
+	if q.keepEvery > 0 {
+		keep := q.overLines%q.keepEvery == 0
+		q.overLines++
+		q.mu.Unlock()
+		return keep
+	}
+
+	notify := !q.notified
+	q.notified = true
+	q.mu.Unlock()
+
+	if notify {
+		q.notify(tags, now)
+	}
+
+	return false
+}
+
+func (q *quotaLimiter) notify(tags map[string]string, now time.Time) {
+	scope := q.scope()
+
+	eventTags := map[string]string{}
+	for _, k := range []string{"namespace", "pod_name", "container_name"} {
+		if v := tags[k]; v != "" {
+			eventTags[k] = v
+		}
+	}
+
+	fields := map[string]interface{}{
+		"df_source": inputName,
+		"df_status": "warning",
+		"df_title":  fmt.Sprintf("Logging quota of %s exceeded", scope),
+		"df_message": fmt.Sprintf("Logging of %s exceeded the quota %v MB/min by container %s, logs dropped until %s",
+			scope, q.conf.MBPerMinute, tags["container_name"], now.Truncate(quotaWindow).Add(quotaWindow).Format(time.RFC3339)),
+		"quota_bytes": q.limit,
+	}
+
+	pt := point.NewPointV2([]byte(quotaEventName),
+		append(point.NewTags(eventTags), point.NewKVs(fields)...), point.WithTime(now))
+	if err := q.feeder.Feed(inputName, point.KeyEvent, []*point.Point{pt}, nil); err != nil {
+		l.Warnf("feed logging quota keyevent: %s", err)
+	}
+}
+
+func (q *quotaLimiter) scope() string {
+	switch {
+	case q.conf.Namespace != "" && q.conf.PodLabelSelector != "":
+		return fmt.Sprintf("namespace %s with pods %s", q.conf.Namespace, q.conf.PodLabelSelector)
+	case q.conf.Namespace != "":
+		return "namespace " + q.conf.Namespace
+	case q.conf.PodLabelSelector != "":
+		return "pods " + q.conf.PodLabelSelector
+	default:
+		return "all containers"
+	}
+}
+
+type loggingQuotas []*quotaLimiter
+
+func newLoggingQuotas(confs []*LoggingQuota, feeder dkio.Feeder) loggingQuotas {
+	var res loggingQuotas
+	for _, conf := range confs {
+		q, err := newQuotaLimiter(conf, feeder)
+		if err != nil {
+			l.Warnf("invalid logging quota: %s, ignored", err)
+			continue
+		}
+		res = append(res, q)
+	}
+	return res
+}
+
+// lineFilter returns filter of the first quota matched, nil if none.
+func (qs loggingQuotas) lineFilter(tags, podLabels map[string]string) func(string) bool {
+	for _, q := range qs {
+		if q.match(tags["namespace"], podLabels) {
+			l.Debugf("logging quota of %s applied to container %s", q.scope(), tags["container_name"])
+
+			q := q
+			return func(text string) bool {
+				return q.allow(text, tags, time.Now())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package container
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func TestLoggingQuota(t *testing.T) {
+	line := strings.Repeat("x", 1024)
+	tags := map[string]string{"namespace": "default", "pod_name": "web-1", "container_name": "nginx"}
+	now := time.Date(2023, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	t.Run("match", func(t *testing.T) {
+		qs := newLoggingQuotas([]*LoggingQuota{
+			{MBPerMinute: 0},                       // invalid
+			{MBPerMinute: 1, OverQuota: "unknown"}, // invalid
+			{MBPerMinute: 1, PodLabelSelector: "app in ("},
+			{Namespace: "kube-system", MBPerMinute: 1},
+			{Namespace: "default", PodLabelSelector: "app=web,tier in (frontend)", MBPerMinute: 1},
+			{PodLabelSelector: "app=web", MBPerMinute: 2},
+		}, dkio.NewMockedFeeder())
+		require.Len(t, qs, 3)
+
+		assert.Nil(t, qs.lineFilter(map[string]string{"namespace": "default"}, nil))
+		assert.NotNil(t, qs.lineFilter(map[string]string{"namespace": "kube-system"}, nil))
+
+		assert.True(t, qs[1].match("default", map[string]string{"app": "web", "tier": "frontend"}))
+		assert.False(t, qs[1].match("default", map[string]string{"app": "web"}))
+		assert.True(t, qs[2].match("default", map[string]string{"app": "web"}))
+		assert.False(t, qs[2].match("other", map[string]string{"app": "db"}))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		feeder := dkio.NewMockedFeeder()
+		q, err := newQuotaLimiter(&LoggingQuota{Namespace: "default", MBPerMinute: 0.01}, feeder) // 10485 bytes
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			assert.True(t, q.allow(line, tags, now))
+		}
+		for i := 0; i < 5; i++ {
+			assert.False(t, q.allow(line, tags, now))
+		}
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, quotaEventName, string(pts[0].Name()))
+		assert.Equal(t, []byte("warning"), pts[0].Get([]byte("df_status")))
+		assert.Equal(t, []byte("web-1"), pts[0].Get([]byte("pod_name")))
+		assert.Equal(t, []byte("Logging quota of namespace default exceeded"), pts[0].Get([]byte("df_title")))
+
+		// keyevent sent once in the window
+		_, err = feeder.NPoints(1, 100*time.Millisecond)
+		assert.Error(t, err)
+
+		// next window
+		assert.True(t, q.allow(line, tags, now.Add(time.Minute)))
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		feeder := dkio.NewMockedFeeder()
+		q, err := newQuotaLimiter(&LoggingQuota{MBPerMinute: 0.001, OverQuota: quotaActionSample, SampleRatio: 0.25}, feeder)
+		require.NoError(t, err)
+
+		assert.True(t, q.allow(line, tags, now))
+
+		kept := 0
+		for i := 0; i < 100; i++ {
+			if q.allow(line, tags, now) {
+				kept++
+			}
+		}
+		assert.Equal(t, 25, kept)
+
+		_, err = feeder.NPoints(1, 100*time.Millisecond)
+		assert.Error(t, err, "no keyevent on sampling")
+	})
+}
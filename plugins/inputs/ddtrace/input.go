@@ -15,8 +15,10 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/ingestauth"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
 	itrace "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/workerpool"
@@ -38,6 +40,10 @@ const (
   ## Default value set as below. DO NOT MODIFY THESE ENDPOINTS if not necessary.
   endpoints = ["/v0.3/traces", "/v0.4/traces", "/v0.5/traces"]
 
+  ## Instrumentation telemetry of tracers, dependencies(libraries and versions) loaded by
+  ## services are collected as custom object "tracing_service_dependency". Enable it by
+  ## adding "/telemetry/proxy/api/v2/apmtelemetry" to endpoints.
+
   ## customer_tags is a list of keys contains keys set by client code like span.SetTag(key, value)
   ## that want to send to data center. Those keys set by client code will take precedence over
   ## keys in [inputs.ddtrace.tags]. DOT(.) IN KEY WILL BE REPLACED BY DASH(_) WHEN SENDING.
@@ -68,11 +74,42 @@ const (
   # [inputs.ddtrace.sampler]
     # sampling_rate = 1.0
 
+  ## Tail sampling buffers spans of a trace for decision_wait, then keeps the whole trace
+  ## if it contains error spans, lasts longer than latency_threshold or matches any policy,
+  ## the other traces are kept by sampling_rate. [inputs.ddtrace.sampler] and error penetration
+  ## are ignored once tail sampling is enabled.
+  # [inputs.ddtrace.tail_sampling]
+    # decision_wait = "10s"
+    # max_traces = 50000
+    # keep_error = true
+    # latency_threshold = "2s"
+    # sampling_rate = 0.1
+    # [[inputs.ddtrace.tail_sampling.policies]]
+      # name = "checkout"
+      # service = "checkout"
+      # resource = "^POST /pay"
+      # [inputs.ddtrace.tail_sampling.policies.tags]
+        # "http.status_code" = "429"
+
+  ## Span metrics generates RED(requests, errors and duration) metrics per service and resource
+  ## from all spans received, before filters and sampling applied, fed as metric "tracing_metrics".
+  ## extra_tags are span tags used as extra dimensions.
+  # [inputs.ddtrace.span_metrics]
+    # interval = "60s"
+    # extra_tags = ["http_method"]
+
   # [inputs.ddtrace.tags]
     # key1 = "value1"
     # key2 = "value2"
     # ...
 
+  ## Request-level authentication, requests without a valid API key are rejected with 401.
+  ## The key is carried by header "Authorization: Bearer <key>", "X-API-Key" or "DD-API-KEY",
+  ## or URL query api_key. pull_keys also accepts API keys configured in center.
+  # [inputs.ddtrace.auth]
+    # keys = ["<api-key>"]
+    # pull_keys = false
+
   ## Threads config controls how many goroutines an agent cloud start to handle HTTP request.
   ## buffer is the size of jobs' buffering of worker channel.
   ## threads is the total number fo goroutines at running time.
@@ -93,6 +130,8 @@ var (
 	log                = logger.DefaultSLogger(inputName)
 	v1, v2, v3, v4, v5 = "/v0.1/spans", "/v0.2/traces", "/v0.3/traces", "/v0.4/traces", "/v0.5/traces"
 	info, stats        = "/info", "/v0.6/stats"
+	telemetry          = "/telemetry/proxy/api/v2/apmtelemetry"
+	afterGather        *itrace.AfterGather
 	afterGatherRun     itrace.AfterGatherHandler
 	customerKeys       []string
 	tags               map[string]string
@@ -112,7 +151,10 @@ type Input struct {
 	OmitErrStatus    []string                     `toml:"omit_err_status"`
 	CloseResource    map[string][]string          `toml:"close_resource"`
 	Sampler          *itrace.Sampler              `toml:"sampler"`
+	TailSampling     *itrace.TailSampling         `toml:"tail_sampling"`
+	SpanMetrics      *itrace.SpanMetrics          `toml:"span_metrics"`
 	Tags             map[string]string            `toml:"tags"`
+	Auth             *ingestauth.Auth             `toml:"auth"`
 	WPConfig         *workerpool.WorkerPoolConfig `toml:"threads"`
 	LocalCacheConfig *storage.StorageConfig       `toml:"storage"`
 }
@@ -124,7 +166,7 @@ func (*Input) AvailableArchs() []string { return datakit.AllOS }
 func (*Input) SampleConfig() string { return sampleConfig }
 
 func (*Input) SampleMeasurement() []inputs.Measurement {
-	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}}
+	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}, &itrace.SpanMetricsMeasurement{}, &dependencyMeasurement{}}
 }
 
 func (ipt *Input) RegHTTPHandler() {
@@ -180,12 +222,19 @@ func (ipt *Input) RegHTTPHandler() {
 		}
 	}
 
-	var afterGather *itrace.AfterGather
+	opts := []itrace.Option{itrace.WithLogger(log)}
 	if localCache != nil && localCache.Enabled() {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log), itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
-	} else {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log))
+		opts = append(opts, itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
 	}
+	// pull per-service sampling rates configured in center
+	opts = append(opts, itrace.WithSamplingPull(config.Cfg.Dataway))
+	if ipt.TailSampling != nil {
+		opts = append(opts, itrace.WithTailSampling(ipt.TailSampling))
+	}
+	if ipt.SpanMetrics != nil {
+		opts = append(opts, itrace.WithSpanMetrics(ipt.SpanMetrics))
+	}
+	afterGather = itrace.NewAfterGather(opts...)
 	afterGatherRun = afterGather
 
 	// add filters: the order of appending filters into AfterGather is important!!!
@@ -198,8 +247,10 @@ func (ipt *Input) RegHTTPHandler() {
 	}
 	// add RespectUserRule filter to obey client priority rules.
 	afterGather.AppendFilter(itrace.RespectUserRule)
-	// add error status penetration
-	afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	// add error status penetration, tail sampling keeps error traces by itself
+	if ipt.TailSampling == nil {
+		afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	}
 	// add omit certain error status list
 	if len(ipt.OmitErrStatus) != 0 {
 		afterGather.AppendFilter(itrace.OmitHTTPStatusCodeFilterWrapper(ipt.OmitErrStatus))
@@ -223,13 +274,20 @@ func (ipt *Input) RegHTTPHandler() {
 		return dktrace, false
 	})
 	// add sampler
-	var sampler *itrace.Sampler
-	if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
-		sampler = ipt.Sampler
-	} else {
-		sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+	if ipt.TailSampling == nil {
+		var sampler *itrace.Sampler
+		if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
+			sampler = ipt.Sampler
+		} else {
+			sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+		}
+		afterGather.AppendFilter(sampler.Sample)
+	}
+
+	// pull API keys configured in center
+	if config.Cfg.Dataway != nil {
+		ipt.Auth.Setup(config.Cfg.Dataway)
 	}
-	afterGather.AppendFilter(sampler.Sample)
 
 	log.Debugf("### register handlers for %s agent", inputName)
 	var isReg bool
@@ -237,13 +295,17 @@ func (ipt *Input) RegHTTPHandler() {
 		switch endpoint {
 		case v1, v2, v3, v4, v5:
 			dkhttp.RegHTTPHandler(http.MethodPost, endpoint,
-				workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
-					storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleDDTraces)))
+				ipt.Auth.HTTPWrapper(workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
+					storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleDDTraces)), log))
 			dkhttp.RegHTTPHandler(http.MethodPut, endpoint,
-				workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
-					storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleDDTraces)))
+				ipt.Auth.HTTPWrapper(workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
+					storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleDDTraces)), log))
 			isReg = true
 			log.Debugf("### pattern %s registered for %s agent", endpoint, inputName)
+		case telemetry:
+			dkhttp.RegHTTPHandler(http.MethodPost, endpoint, ipt.Auth.HTTPWrapper(handleDDTelemetry, log))
+			go inventories.run()
+			log.Debugf("### pattern %s registered for %s agent", endpoint, inputName)
 		default:
 			log.Debugf("### unrecognized pattern %s for %s agent", endpoint, inputName)
 		}
@@ -253,8 +315,8 @@ func (ipt *Input) RegHTTPHandler() {
 		// unsupported api yet
 		dkhttp.RegHTTPHandler(http.MethodGet, info, handleDDInfo)
 		dkhttp.RegHTTPHandler(http.MethodPost, info, handleDDInfo)
-		dkhttp.RegHTTPHandler(http.MethodGet, stats, handleDDStats)
-		dkhttp.RegHTTPHandler(http.MethodPost, stats, handleDDStats)
+		dkhttp.RegHTTPHandler(http.MethodGet, stats, ipt.Auth.HTTPWrapper(handleDDStats, log))
+		dkhttp.RegHTTPHandler(http.MethodPost, stats, ipt.Auth.HTTPWrapper(handleDDStats, log))
 	}
 }
 
@@ -281,6 +343,10 @@ func (*Input) Terminate() {
 		}
 		log.Debug("### local storage closed")
 	}
+	if afterGather != nil {
+		afterGather.Stop()
+		log.Debug("### after gather stopped")
+	}
 }
 
 func init() { //nolint:gochecknoinits
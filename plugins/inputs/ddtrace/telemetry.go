@@ -0,0 +1,333 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package ddtrace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	dependencyObjectName = "tracing_service_dependency"
+
+	// limits of inventories kept and dependencies of each inventory.
+	maxInventories   = 10000
+	maxDependencies  = 10000
+	maxTelemetrySize = 8 << 20
+
+	inventoryFlushInterval   = 10 * time.Second
+	inventoryRefreshInterval = 5 * time.Minute
+	inventoryIdleTimeout     = time.Hour
+)
+
+// telemetryRequest is the instrumentation telemetry(API v1 and v2) sent by
+// DDTrace tracers, only fields for dependency inventories decoded.
+type telemetryRequest struct {
+	RequestType string          `json:"request_type"`
+	Application telemetryApp    `json:"application"`
+	Host        telemetryHost   `json:"host"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+type telemetryApp struct {
+	ServiceName     string `json:"service_name"`
+	Env             string `json:"env"`
+	ServiceVersion  string `json:"service_version"`
+	LanguageName    string `json:"language_name"`
+	LanguageVersion string `json:"language_version"`
+	TracerVersion   string `json:"tracer_version"`
+	RuntimeName     string `json:"runtime_name"`
+	RuntimeVersion  string `json:"runtime_version"`
+}
+
+type telemetryHost struct {
+	Hostname string `json:"hostname"`
+}
+
+type dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// inventory is dependencies loaded by all runtimes of a service version.
+type inventory struct {
+	app      telemetryApp
+	hostname string
+	deps     map[string]string
+
+	changed  bool
+	lastSeen time.Time
+	lastFed  time.Time
+}
+
+type inventoryStore struct {
+	mu          sync.Mutex
+	inventories map[string]*inventory
+	feeder      dkio.Feeder
+}
+
+var inventories = newInventoryStore(dkio.DefaultFeeder())
+
+func newInventoryStore(feeder dkio.Feeder) *inventoryStore {
+	return &inventoryStore{
+		inventories: map[string]*inventory{},
+		feeder:      feeder,
+	}
+}
+
+func handleDDTelemetry(resp http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxTelemetrySize))
+	if err != nil {
+		log.Errorf("### read telemetry failed: %s", err.Error())
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err = inventories.add(body); err != nil {
+		log.Debugf("### parse telemetry failed: %s", err.Error())
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// add merges dependencies carried by the telemetry, other telemetry ignored.
+func (s *inventoryStore) add(body []byte) error {
+	var req telemetryRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return err
+	}
+
+	if req.Application.ServiceName == "" {
+		return fmt.Errorf("service_name missing")
+	}
+
+	deps, err := dependencies(req.RequestType, req.Payload)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.Join([]string{
+		req.Application.ServiceName,
+		req.Application.Env,
+		req.Application.ServiceVersion,
+		req.Application.LanguageName,
+	}, "\n")
+
+	inv, ok := s.inventories[key]
+	if !ok {
+		if len(deps) == 0 {
+			return nil
+		}
+
+		if len(s.inventories) >= maxInventories {
+			log.Warnf("### too many dependency inventories, service %s dropped", req.Application.ServiceName)
+			return nil
+		}
+
+		inv = &inventory{deps: map[string]string{}}
+		s.inventories[key] = inv
+	}
+
+	inv.app = req.Application
+	inv.hostname = req.Host.Hostname
+	inv.lastSeen = time.Now()
+
+	for _, d := range deps {
+		if d.Name == "" {
+			continue
+		}
+
+		if v, ok := inv.deps[d.Name]; ok && v == d.Version {
+			continue
+		}
+
+		if _, ok := inv.deps[d.Name]; !ok && len(inv.deps) >= maxDependencies {
+			continue
+		}
+
+		inv.deps[d.Name] = d.Version
+		inv.changed = true
+	}
+
+	return nil
+}
+
+// dependencies returns dependencies in the payload, message-batch unpacked.
+func dependencies(requestType string, payload json.RawMessage) ([]*dependency, error) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	if requestType == "message-batch" {
+		var batch []struct {
+			RequestType string          `json:"request_type"`
+			Payload     json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			return nil, err
+		}
+
+		var res []*dependency
+		for _, m := range batch {
+			deps, err := dependencies(m.RequestType, m.Payload)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, deps...)
+		}
+		return res, nil
+	}
+
+	// app-started of API v1 and app-dependencies-loaded carry dependencies
+	if requestType != "app-started" && requestType != "app-dependencies-loaded" {
+		return nil, nil
+	}
+
+	var x struct {
+		Dependencies []*dependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(payload, &x); err != nil {
+		return nil, err
+	}
+
+	return x.Dependencies, nil
+}
+
+// flush feeds inventories changed or not fed for a while, and removes those
+// not seen for long.
+func (s *inventoryStore) flush(now time.Time) {
+	var pts []*point.Point
+
+	s.mu.Lock()
+	for k, inv := range s.inventories {
+		if now.Sub(inv.lastSeen) > inventoryIdleTimeout {
+			delete(s.inventories, k)
+			continue
+		}
+
+		if inv.changed || now.Sub(inv.lastFed) >= inventoryRefreshInterval {
+			pts = append(pts, inv.point(now))
+			inv.changed = false
+			inv.lastFed = now
+		}
+	}
+	s.mu.Unlock()
+
+	if len(pts) == 0 {
+		return
+	}
+
+	if err := s.feeder.Feed(inputName, point.CustomObject, pts, nil); err != nil {
+		log.Errorf("### feed dependency inventories failed: %s", err.Error())
+	}
+}
+
+func (s *inventoryStore) run() {
+	tick := time.NewTicker(inventoryFlushInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-datakit.Exit.Wait():
+			return
+		case now := <-tick.C:
+			s.flush(now)
+		}
+	}
+}
+
+func (inv *inventory) point(now time.Time) *point.Point {
+	deps := make([]*dependency, 0, len(inv.deps))
+	for k, v := range inv.deps {
+		deps = append(deps, &dependency{Name: k, Version: v})
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+
+	name := inv.app.ServiceName
+	if inv.app.ServiceVersion != "" {
+		name += "@" + inv.app.ServiceVersion
+	}
+	if inv.app.Env != "" {
+		name = inv.app.Env + "/" + name
+	}
+
+	var kvs point.KVs
+	for k, v := range tags {
+		kvs = kvs.AddTag([]byte(k), []byte(v))
+	}
+
+	for k, v := range map[string]string{
+		"name":             name,
+		"service":          inv.app.ServiceName,
+		"env":              inv.app.Env,
+		"version":          inv.app.ServiceVersion,
+		"language_name":    inv.app.LanguageName,
+		"language_version": inv.app.LanguageVersion,
+		"runtime_name":     inv.app.RuntimeName,
+		"runtime_version":  inv.app.RuntimeVersion,
+		"tracer_version":   inv.app.TracerVersion,
+		"hostname":         inv.hostname,
+	} {
+		if v != "" {
+			kvs = kvs.MustAddTag([]byte(k), []byte(v))
+		}
+	}
+
+	kvs = kvs.Add([]byte("dependency_count"), int64(len(deps)), false, true)
+	if j, err := json.Marshal(deps); err == nil {
+		kvs = kvs.Add([]byte("dependencies"), string(j), false, true)
+	}
+
+	return point.NewPointV2([]byte(dependencyObjectName), kvs, append(point.DefaultObjectOptions(), point.WithTime(now))...)
+}
+
+type dependencyMeasurement struct{}
+
+func (*dependencyMeasurement) LineProto() (*dkpt.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*dependencyMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: dependencyObjectName,
+		Type: "custom_object",
+		Desc: "Libraries loaded by each service version, reported by the instrumentation telemetry of DDTrace tracers. Dependencies of all runtimes of the same service, env and version are merged.",
+		Tags: map[string]interface{}{
+			"name":             &inputs.TagInfo{Desc: "Object name, in `<env>/<service>@<version>`, env and version omitted if not set."},
+			"service":          &inputs.TagInfo{Desc: "Service name."},
+			"env":              &inputs.TagInfo{Desc: "Env of the service."},
+			"version":          &inputs.TagInfo{Desc: "Version of the service."},
+			"language_name":    &inputs.TagInfo{Desc: "Language of the service, such as `jvm`, `nodejs` and `python`."},
+			"language_version": &inputs.TagInfo{Desc: "Version of the language."},
+			"runtime_name":     &inputs.TagInfo{Desc: "Name of the runtime, such as `OpenJDK`."},
+			"runtime_version":  &inputs.TagInfo{Desc: "Version of the runtime."},
+			"tracer_version":   &inputs.TagInfo{Desc: "Version of the tracer."},
+			"hostname":         &inputs.TagInfo{Desc: "Host of the service of the latest telemetry."},
+		},
+		Fields: map[string]interface{}{
+			"dependency_count": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of dependencies."},
+			"dependencies":     &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "Dependencies in JSON array of `{\"name\": ..., \"version\": ...}`, sorted by name."},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package ddtrace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+const (
+	telemetryApp1 = `"application":{"service_name":"checkout","env":"prod","service_version":"1.2.0",
+		"language_name":"jvm","language_version":"17","tracer_version":"1.20.0"},"host":{"hostname":"node-1"}`
+
+	telemetryStarted = `{"api_version":"v2","request_type":"app-started","runtime_id":"r1",` + telemetryApp1 + `,
+		"payload":{"configuration":[]}}`
+
+	telemetryDeps = `{"api_version":"v2","request_type":"app-dependencies-loaded","runtime_id":"r1",` + telemetryApp1 + `,
+		"payload":{"dependencies":[{"name":"org.yaml:snakeyaml","version":"1.33"},{"name":"com.fasterxml.jackson.core:jackson-databind","version":"2.13.0"}]}}`
+
+	telemetryBatch = `{"api_version":"v2","request_type":"message-batch","runtime_id":"r2",` + telemetryApp1 + `,
+		"payload":[
+			{"request_type":"app-heartbeat","payload":{}},
+			{"request_type":"app-dependencies-loaded","payload":{"dependencies":[{"name":"org.yaml:snakeyaml","version":"1.33"},{"name":"io.netty:netty-codec","version":"4.1.86"}]}}
+		]}`
+)
+
+func TestInventoryStore(t *T.T) {
+	feeder := dkio.NewMockedFeeder()
+	s := newInventoryStore(feeder)
+
+	require.NoError(t, s.add([]byte(telemetryStarted)))
+	assert.Empty(t, s.inventories, "no inventory without dependencies")
+
+	require.NoError(t, s.add([]byte(telemetryDeps)))
+	require.NoError(t, s.add([]byte(telemetryBatch)))
+	require.Len(t, s.inventories, 1)
+
+	now := time.Now()
+	s.flush(now)
+
+	pts := feeder.Points(point.CustomObject)
+	require.Len(t, pts, 1)
+
+	pt := pts[0]
+	assert.Equal(t, dependencyObjectName, string(pt.Name()))
+	tags := pt.InfluxTags()
+	assert.Equal(t, "prod/checkout@1.2.0", tags["name"])
+	assert.Equal(t, "checkout", tags["service"])
+	assert.Equal(t, "1.2.0", tags["version"])
+	assert.Equal(t, "jvm", tags["language_name"])
+	assert.Equal(t, "node-1", tags["hostname"])
+	assert.Equal(t, int64(3), pt.Get([]byte("dependency_count")))
+	assert.Equal(t, []byte(`[{"name":"com.fasterxml.jackson.core:jackson-databind","version":"2.13.0"},`+
+		`{"name":"io.netty:netty-codec","version":"4.1.86"},{"name":"org.yaml:snakeyaml","version":"1.33"}]`),
+		pt.Get([]byte("dependencies")))
+
+	// unchanged, not fed until refresh
+	require.NoError(t, s.add([]byte(telemetryDeps)))
+	s.flush(now.Add(time.Minute))
+	assert.Len(t, feeder.Points(point.CustomObject), 1)
+
+	s.flush(now.Add(inventoryRefreshInterval))
+	assert.Len(t, feeder.Points(point.CustomObject), 2)
+
+	// idle ones removed
+	s.flush(now.Add(2 * inventoryIdleTimeout))
+	assert.Empty(t, s.inventories)
+
+	// invalid telemetry
+	assert.Error(t, s.add([]byte(`{"request_type":"app-dependencies-loaded","payload":{}}`)))
+	assert.Error(t, s.add([]byte(`not json`)))
+}
+
+func TestHandleDDTelemetry(t *T.T) {
+	inventories = newInventoryStore(dkio.NewMockedFeeder())
+
+	for body, code := range map[string]int{
+		telemetryDeps: http.StatusAccepted,
+		"{}":          http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, telemetry, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleDDTelemetry(w, req)
+		assert.Equal(t, code, w.Code)
+	}
+
+	assert.Len(t, inventories.inventories, 1)
+}
@@ -0,0 +1,675 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !windows
+// +build !windows
+
+package dialtesting
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	dt "github.com/GuanceCloud/cliutils/dialtesting"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+const (
+	defaultBrowserTimeout  = time.Minute
+	defaultStepTimeout     = 10 * time.Second
+	browserMetricName      = "browser_dial_testing"
+	browserPollingInterval = 100 * time.Millisecond
+)
+
+// Actions of browser steps.
+const (
+	actionNavigate   = "navigate"
+	actionType       = "type"
+	actionClick      = "click"
+	actionWaitFor    = "wait_for"
+	actionAssertText = "assert_text"
+)
+
+var screenshotDir = filepath.Join(datakit.DataDir, "dialtesting", "screenshots")
+
+// browserRuntime is the headless browser on the probe.
+type browserRuntime struct {
+	path string
+	args []string
+}
+
+type browserStep struct {
+	Action   string `json:"action"`
+	Selector string `json:"selector,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// businessHours limits the task to run only within [Start, End) of Weekdays.
+type businessHours struct {
+	Timezone string `json:"timezone,omitempty"`
+	Weekdays []int  `json:"weekdays,omitempty"` // 0(Sunday) ~ 6(Saturday), all days if empty
+	Start    string `json:"start"`              // such as 09:00
+	End      string `json:"end"`                // such as 18:00
+
+	loc        *time.Location
+	start, end int // minutes of the day
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock %q, expect HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (b *businessHours) init() error {
+	b.loc = time.Local
+	if b.Timezone != "" {
+		loc, err := time.LoadLocation(b.Timezone)
+		if err != nil {
+			return err
+		}
+		b.loc = loc
+	}
+
+	for _, d := range b.Weekdays {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("invalid weekday %d, expect 0 ~ 6", d)
+		}
+	}
+
+	var err error
+	if b.start, err = parseClock(b.Start); err != nil {
+		return err
+	}
+	if b.end, err = parseClock(b.End); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// contains checks if now is within the business hours, ranges across
+// midnight(such as 22:00 ~ 06:00) supported.
+func (b *businessHours) contains(now time.Time) bool {
+	now = now.In(b.loc)
+	minute := now.Hour()*60 + now.Minute()
+
+	day := now.Weekday()
+	inRange := false
+	switch {
+	case b.start < b.end:
+		inRange = minute >= b.start && minute < b.end
+	case b.start > b.end:
+		inRange = minute >= b.start || minute < b.end
+		if minute < b.end { // the range started at yesterday
+			day = (day + 6) % 7
+		}
+	default: // whole day
+		inRange = true
+	}
+
+	if !inRange {
+		return false
+	}
+
+	if len(b.Weekdays) == 0 {
+		return true
+	}
+
+	for _, d := range b.Weekdays {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+type browserSuccess struct {
+	ResponseTime           string `json:"response_time,omitempty"`
+	FirstContentfulPaint   string `json:"first_contentful_paint,omitempty"`
+	LargestContentfulPaint string `json:"largest_contentful_paint,omitempty"`
+
+	responseTime, fcp, lcp time.Duration
+}
+
+// webVitals are timings of the page load, in milliseconds.
+type webVitals struct {
+	TTFB             float64 `json:"ttfb"`
+	DOMContentLoaded float64 `json:"dom_content_loaded"`
+	Load             float64 `json:"load"`
+	FCP              float64 `json:"fcp"`
+	LCP              float64 `json:"lcp"`
+	CLS              float64 `json:"cls"`
+}
+
+const webVitalsJS = `new Promise(resolve => {
+  const nav = performance.getEntriesByType('navigation')[0] || {};
+  const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+  let lcp = 0, cls = 0;
+  try {
+    new PerformanceObserver(l => { for (const e of l.getEntries()) lcp = e.startTime; })
+      .observe({type: 'largest-contentful-paint', buffered: true});
+    new PerformanceObserver(l => { for (const e of l.getEntries()) if (!e.hadRecentInput) cls += e.value; })
+      .observe({type: 'layout-shift', buffered: true});
+  } catch (e) {}
+  setTimeout(() => resolve({
+    ttfb: nav.responseStart || 0,
+    dom_content_loaded: nav.domContentLoadedEventEnd || 0,
+    load: nav.loadEventEnd || 0,
+    fcp: fcp ? fcp.startTime : 0,
+    lcp: lcp,
+    cls: cls,
+  }), 100);
+})`
+
+// browserTask loads the page by the headless browser, then runs the steps,
+// such as filling and submitting the login form.
+type browserTask struct {
+	URL              string            `json:"url"`
+	Steps            []*browserStep    `json:"steps,omitempty"`
+	BusinessHours    *businessHours    `json:"business_hours,omitempty"`
+	Timeout          string            `json:"timeout,omitempty"`
+	SuccessWhen      []*browserSuccess `json:"success_when"`
+	SuccessWhenLogic string            `json:"success_when_logic"`
+	ExternalID       string            `json:"external_id"`
+	Name             string            `json:"name"`
+	AK               string            `json:"access_key"`
+	PostURL          string            `json:"post_url"`
+	CurStatus        string            `json:"status"`
+	Frequency        string            `json:"frequency"`
+	Region           string            `json:"region"`
+	OwnerExternalID  string            `json:"owner_external_id"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Labels           []string          `json:"labels,omitempty"`
+	UpdateTime       int64             `json:"update_time,omitempty"`
+
+	runtime *browserRuntime
+
+	timeout     time.Duration
+	ticker      *time.Ticker
+	reqCost     time.Duration
+	vitals      webVitals
+	stepsPassed int
+	reqError    string
+	screenshot  string
+}
+
+var _ dt.Task = (*browserTask)(nil)
+
+func newBrowserTask(rt *browserRuntime) *browserTask {
+	return &browserTask{runtime: rt}
+}
+
+func (t *browserTask) init(debug bool) error {
+	t.timeout = defaultBrowserTimeout
+	if t.Timeout != "" {
+		du, err := time.ParseDuration(t.Timeout)
+		if err != nil {
+			return err
+		}
+		t.timeout = du
+	}
+
+	if !debug {
+		du, err := time.ParseDuration(t.Frequency)
+		if err != nil {
+			return err
+		}
+		if t.ticker != nil {
+			t.ticker.Stop()
+		}
+		t.ticker = time.NewTicker(du)
+	}
+
+	if strings.EqualFold(t.CurStatus, dt.StatusStop) {
+		return nil
+	}
+
+	if t.URL == "" {
+		return fmt.Errorf("URL should not be empty")
+	}
+
+	for i, s := range t.Steps {
+		switch s.Action {
+		case actionNavigate:
+			if s.Value == "" {
+				return fmt.Errorf("step %d: navigate URL should not be empty", i)
+			}
+		case actionType, actionClick, actionWaitFor:
+			if s.Selector == "" {
+				return fmt.Errorf("step %d: %s selector should not be empty", i, s.Action)
+			}
+		case actionAssertText:
+			if s.Value == "" {
+				return fmt.Errorf("step %d: assert text should not be empty", i)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown action %q", i, s.Action)
+		}
+
+		s.timeout = defaultStepTimeout
+		if s.Timeout != "" {
+			du, err := time.ParseDuration(s.Timeout)
+			if err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+			s.timeout = du
+		}
+	}
+
+	if t.BusinessHours != nil {
+		if err := t.BusinessHours.init(); err != nil {
+			return err
+		}
+	}
+
+	for _, chk := range t.SuccessWhen {
+		for _, x := range []struct {
+			s  string
+			du *time.Duration
+		}{
+			{chk.ResponseTime, &chk.responseTime},
+			{chk.FirstContentfulPaint, &chk.fcp},
+			{chk.LargestContentfulPaint, &chk.lcp},
+		} {
+			if x.s == "" {
+				continue
+			}
+
+			du, err := time.ParseDuration(x.s)
+			if err != nil {
+				return err
+			}
+			*x.du = du
+		}
+	}
+
+	return nil
+}
+
+func (t *browserTask) InitDebug() error {
+	return t.init(true)
+}
+
+func (t *browserTask) Init() error {
+	return t.init(false)
+}
+
+func (t *browserTask) Check() error {
+	if t.ExternalID == "" {
+		return fmt.Errorf("external ID missing")
+	}
+
+	return t.Init()
+}
+
+// inSchedule checks if the task should run at now.
+func (t *browserTask) inSchedule(now time.Time) bool {
+	return t.BusinessHours == nil || t.BusinessHours.contains(now)
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (t *browserTask) CheckResult() (reasons []string, succFlag bool) {
+	for _, chk := range t.SuccessWhen {
+		for _, x := range []struct {
+			name          string
+			cost, target  time.Duration
+			skipIfMissing bool
+		}{
+			{"response time", t.reqCost, chk.responseTime, false},
+			{"first contentful paint", msDuration(t.vitals.FCP), chk.fcp, true},
+			{"largest contentful paint", msDuration(t.vitals.LCP), chk.lcp, true},
+		} {
+			if x.target <= 0 || (x.skipIfMissing && x.cost == 0) {
+				continue
+			}
+
+			if x.cost > x.target {
+				reasons = append(reasons, fmt.Sprintf("%s(%v) larger than %v", x.name, x.cost, x.target))
+			} else {
+				succFlag = true
+			}
+		}
+	}
+
+	return reasons, succFlag
+}
+
+func (t *browserTask) GetResults() (tags map[string]string, fields map[string]interface{}) {
+	tags = map[string]string{
+		"name":   t.Name,
+		"url":    t.URL,
+		"status": "FAIL",
+		"proto":  "browser",
+	}
+
+	responseTime := int64(t.reqCost) / 1000 // us
+
+	fields = map[string]interface{}{
+		"response_time":            responseTime,
+		"ttfb":                     int64(msDuration(t.vitals.TTFB)) / 1000,
+		"dom_content_loaded":       int64(msDuration(t.vitals.DOMContentLoaded)) / 1000,
+		"load_event":               int64(msDuration(t.vitals.Load)) / 1000,
+		"first_contentful_paint":   int64(msDuration(t.vitals.FCP)) / 1000,
+		"largest_contentful_paint": int64(msDuration(t.vitals.LCP)) / 1000,
+		"cumulative_layout_shift":  t.vitals.CLS,
+		"steps_passed":             int64(t.stepsPassed),
+		"success":                  int64(-1),
+	}
+
+	if t.screenshot != "" {
+		fields["screenshot"] = t.screenshot
+	}
+
+	for k, v := range t.Tags {
+		tags[k] = v
+	}
+
+	message := map[string]interface{}{}
+
+	reasons, succFlag := t.CheckResult()
+	if t.reqError != "" {
+		reasons = append(reasons, t.reqError)
+	}
+
+	switch t.SuccessWhenLogic {
+	case "or":
+		if (succFlag || len(t.SuccessWhen) == 0) && t.reqError == "" {
+			tags["status"] = "OK"
+			fields["success"] = int64(1)
+			message["response_time"] = responseTime
+		} else {
+			message[`fail_reason`] = strings.Join(reasons, `;`)
+			fields[`fail_reason`] = strings.Join(reasons, `;`)
+		}
+	default:
+		if len(reasons) != 0 {
+			message[`fail_reason`] = strings.Join(reasons, `;`)
+			fields[`fail_reason`] = strings.Join(reasons, `;`)
+		} else {
+			message["response_time"] = responseTime
+		}
+
+		if t.reqError == "" && len(reasons) == 0 {
+			tags["status"] = "OK"
+			fields["success"] = int64(1)
+		}
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		fields[`message`] = err.Error()
+	} else {
+		fields[`message`] = string(data)
+	}
+
+	return tags, fields
+}
+
+func (t *browserTask) MetricName() string {
+	return browserMetricName
+}
+
+func (t *browserTask) Clear() {
+	t.reqCost = 0
+	t.vitals = webVitals{}
+	t.stepsPassed = 0
+	t.reqError = ""
+	t.screenshot = ""
+}
+
+func (t *browserTask) Run() error {
+	t.Clear()
+
+	if t.runtime == nil || t.runtime.path == "" {
+		err := fmt.Errorf("no browser runtime configured")
+		t.reqError = err.Error()
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	proc, err := launchBrowser(ctx, t.runtime.path, t.runtime.args)
+	if err != nil {
+		t.reqError = err.Error()
+		return err
+	}
+	defer proc.close()
+
+	conn, err := dialCDP(ctx, proc.wsURL)
+	if err != nil {
+		t.reqError = err.Error()
+		return err
+	}
+	defer conn.close() //nolint:errcheck
+
+	if err := conn.attachPage(ctx); err != nil {
+		t.reqError = err.Error()
+		return err
+	}
+
+	return t.runFlow(ctx, conn)
+}
+
+// runFlow loads the page and runs the steps on the attached page, the page
+// is captured on failure.
+func (t *browserTask) runFlow(ctx context.Context, conn *cdpConn) error {
+	start := time.Now()
+	err := t.doRunFlow(ctx, conn)
+	t.reqCost = time.Since(start)
+
+	if err != nil {
+		t.reqError = err.Error()
+		t.captureScreenshot(conn)
+	}
+
+	return err
+}
+
+func (t *browserTask) doRunFlow(ctx context.Context, conn *cdpConn) error {
+	if err := conn.navigate(ctx, t.URL); err != nil {
+		return err
+	}
+
+	if err := conn.evaluate(ctx, webVitalsJS, &t.vitals); err != nil {
+		l.Warnf("get web vitals of %s: %s, ignored", t.URL, err)
+	}
+
+	for i, s := range t.Steps {
+		if err := t.runStep(ctx, conn, s); err != nil {
+			return fmt.Errorf("step %d(%s): %w", i, s.Action, err)
+		}
+		t.stepsPassed++
+	}
+
+	return nil
+}
+
+func jsString(s string) string {
+	j, _ := json.Marshal(s) //nolint:errchkjson
+	return string(j)
+}
+
+func (t *browserTask) runStep(ctx context.Context, conn *cdpConn, s *browserStep) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	switch s.Action {
+	case actionNavigate:
+		return conn.navigate(ctx, s.Value)
+
+	case actionType:
+		return waitTrue(ctx, conn, fmt.Sprintf(`(() => {
+  const el = document.querySelector(%s);
+  if (!el) return false;
+  el.focus();
+  el.value = %s;
+  el.dispatchEvent(new Event('input', {bubbles: true}));
+  el.dispatchEvent(new Event('change', {bubbles: true}));
+  return true;
+})()`, jsString(s.Selector), jsString(s.Value)), "element "+s.Selector+" not found")
+
+	case actionClick:
+		// the click may trigger navigation, later steps should wait_for the new page
+		return waitTrue(ctx, conn, fmt.Sprintf(`(() => {
+  const el = document.querySelector(%s);
+  if (!el) return false;
+  el.click();
+  return true;
+})()`, jsString(s.Selector)), "element "+s.Selector+" not found")
+
+	case actionWaitFor:
+		return waitTrue(ctx, conn, fmt.Sprintf(`document.querySelector(%s) !== null`,
+			jsString(s.Selector)), "element "+s.Selector+" not found")
+
+	case actionAssertText:
+		return waitTrue(ctx, conn, fmt.Sprintf(`!!document.body && document.body.innerText.includes(%s)`,
+			jsString(s.Value)), "text "+jsString(s.Value)+" not found")
+
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+}
+
+// waitTrue evaluates the expression until it's true or timeout.
+func waitTrue(ctx context.Context, conn *cdpConn, expr, reason string) error {
+	ticker := time.NewTicker(browserPollingInterval)
+	defer ticker.Stop()
+
+	for {
+		var ok bool
+		// the page may be navigating, evaluate errors are retried
+		if err := conn.evaluate(ctx, expr, &ok); err == nil && ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s within timeout", reason)
+		case <-ticker.C:
+		}
+	}
+}
+
+var invalidFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// captureScreenshot saves the page to file, only the latest one of the task kept.
+func (t *browserTask) captureScreenshot(conn *cdpConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStepTimeout)
+	defer cancel()
+
+	data, err := conn.screenshot(ctx)
+	if err != nil {
+		l.Warnf("capture screenshot of task %s: %s", t.ID(), err)
+		return
+	}
+
+	png, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		l.Warnf("decode screenshot of task %s: %s", t.ID(), err)
+		return
+	}
+
+	if err := os.MkdirAll(screenshotDir, os.ModePerm); err != nil {
+		l.Warnf("mkdir %s: %s", screenshotDir, err)
+		return
+	}
+
+	f := filepath.Join(screenshotDir, invalidFileChars.ReplaceAllString(t.ID(), "_")+".png")
+	if err := os.WriteFile(f, png, 0o600); err != nil {
+		l.Warnf("write screenshot %s: %s", f, err)
+		return
+	}
+
+	t.screenshot = f
+}
+
+func (t *browserTask) Stop() error {
+	return nil
+}
+
+func (t *browserTask) UpdateTimeUs() int64 {
+	return t.UpdateTime
+}
+
+func (t *browserTask) ID() string {
+	if t.ExternalID == `` {
+		return cliutils.XID("dtst_")
+	}
+	return fmt.Sprintf("%s_%s", t.AK, t.ExternalID)
+}
+
+func (t *browserTask) GetOwnerExternalID() string {
+	return t.OwnerExternalID
+}
+
+func (t *browserTask) SetOwnerExternalID(exid string) {
+	t.OwnerExternalID = exid
+}
+
+func (t *browserTask) SetRegionID(regionID string) {
+	t.Region = regionID
+}
+
+func (t *browserTask) SetAk(ak string) {
+	t.AK = ak
+}
+
+func (t *browserTask) SetStatus(status string) {
+	t.CurStatus = status
+}
+
+func (t *browserTask) SetUpdateTime(ts int64) {
+	t.UpdateTime = ts
+}
+
+func (t *browserTask) Status() string {
+	return t.CurStatus
+}
+
+func (t *browserTask) Ticker() *time.Ticker {
+	return t.ticker
+}
+
+func (t *browserTask) Class() string {
+	return dt.ClassHeadless
+}
+
+func (t *browserTask) GetFrequency() string {
+	return t.Frequency
+}
+
+func (t *browserTask) GetLineData() string {
+	return ""
+}
+
+func (t *browserTask) RegionName() string {
+	return t.Region
+}
+
+func (t *browserTask) PostURLStr() string {
+	return t.PostURL
+}
+
+func (t *browserTask) AccessKey() string {
+	return t.AK
+}
@@ -0,0 +1,302 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !windows
+// +build !windows
+
+package dialtesting
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCDPServer answers CDP commands like a browser, elements matching
+// selector #missing never exist.
+func fakeCDPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close() //nolint:errcheck
+
+		for {
+			var req struct {
+				ID     int64           `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			if err := ws.ReadJSON(&req); err != nil {
+				return
+			}
+
+			var res interface{} = map[string]interface{}{}
+
+			switch req.Method {
+			case "Target.createTarget":
+				res = map[string]interface{}{"targetId": "t1"}
+			case "Target.attachToTarget":
+				res = map[string]interface{}{"sessionId": "s1"}
+			case "Page.navigate":
+				var p struct {
+					URL string `json:"url"`
+				}
+				_ = json.Unmarshal(req.Params, &p)
+				if strings.Contains(p.URL, "unreachable") {
+					res = map[string]interface{}{"errorText": "net::ERR_NAME_NOT_RESOLVED"}
+				}
+			case "Page.captureScreenshot":
+				res = map[string]interface{}{"data": base64.StdEncoding.EncodeToString([]byte("fake-png"))}
+			case "Runtime.evaluate":
+				var p struct {
+					Expression string `json:"expression"`
+				}
+				_ = json.Unmarshal(req.Params, &p)
+
+				var v interface{} = true
+				switch {
+				case strings.Contains(p.Expression, "PerformanceObserver"):
+					v = map[string]interface{}{"ttfb": 12.5, "dom_content_loaded": 80, "load": 120, "fcp": 90, "lcp": 150, "cls": 0.05}
+				case strings.Contains(p.Expression, "#missing"):
+					v = false
+				}
+				res = map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": v}}
+			}
+
+			if err := ws.WriteJSON(map[string]interface{}{"id": req.ID, "result": res}); err != nil {
+				return
+			}
+
+			if req.Method == "Page.navigate" {
+				_ = ws.WriteJSON(map[string]interface{}{"method": "Page.loadEventFired", "sessionId": "s1", "params": map[string]interface{}{}})
+			}
+		}
+	}))
+}
+
+func TestBrowserTaskRunFlow(t *testing.T) {
+	ts := fakeCDPServer(t)
+	defer ts.Close()
+
+	screenshotDir = t.TempDir()
+
+	cases := []struct {
+		name        string
+		task        *browserTask
+		status      string
+		stepsPassed int64
+		ttfb        int64
+		failReason  string
+	}{
+		{
+			name: "login-ok",
+			task: &browserTask{
+				URL: "https://oa.example.com/login",
+				Steps: []*browserStep{
+					{Action: actionType, Selector: "#username", Value: "probe"},
+					{Action: actionType, Selector: "#password", Value: "secret"},
+					{Action: actionClick, Selector: "button[type=submit]"},
+					{Action: actionWaitFor, Selector: ".dashboard"},
+					{Action: actionAssertText, Value: "Welcome"},
+				},
+				SuccessWhen: []*browserSuccess{{LargestContentfulPaint: "1s"}},
+			},
+			status:      "OK",
+			stepsPassed: 5,
+			ttfb:        12500,
+		},
+		{
+			name: "element-not-found",
+			task: &browserTask{
+				URL: "https://oa.example.com/login",
+				Steps: []*browserStep{
+					{Action: actionType, Selector: "#username", Value: "probe"},
+					{Action: actionClick, Selector: "#missing", Timeout: "300ms"},
+				},
+			},
+			status:      "FAIL",
+			stepsPassed: 1,
+			ttfb:        12500,
+			failReason:  "step 1(click): element #missing not found",
+		},
+		{
+			name: "lcp-too-slow",
+			task: &browserTask{
+				URL:         "https://oa.example.com/login",
+				SuccessWhen: []*browserSuccess{{LargestContentfulPaint: "100ms"}},
+			},
+			status:     "FAIL",
+			ttfb:       12500,
+			failReason: "largest contentful paint(150ms) larger than 100ms",
+		},
+		{
+			name: "page-unreachable",
+			task: &browserTask{
+				URL: "https://unreachable.example.com",
+			},
+			status:     "FAIL",
+			failReason: "ERR_NAME_NOT_RESOLVED",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.task.ExternalID = "dial_" + tc.name
+			tc.task.AK = "ak"
+			require.NoError(t, tc.task.InitDebug())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			conn, err := dialCDP(ctx, "ws"+strings.TrimPrefix(ts.URL, "http"))
+			require.NoError(t, err)
+			defer conn.close() //nolint:errcheck
+
+			require.NoError(t, conn.attachPage(ctx))
+
+			_ = tc.task.runFlow(ctx, conn)
+
+			tags, fields := tc.task.GetResults()
+			assert.Equal(t, tc.status, tags["status"])
+			assert.Equal(t, "browser", tags["proto"])
+			assert.Equal(t, tc.stepsPassed, fields["steps_passed"])
+			assert.Equal(t, tc.ttfb, fields["ttfb"], "fields: %+#v", fields)
+
+			if tc.failReason == "" {
+				assert.Equal(t, int64(1), fields["success"])
+				assert.Equal(t, int64(150000), fields["largest_contentful_paint"])
+				assert.NotContains(t, fields, "screenshot")
+				return
+			}
+
+			assert.Equal(t, int64(-1), fields["success"])
+			assert.Contains(t, fields["fail_reason"], tc.failReason)
+
+			if tc.task.reqError != "" {
+				f, ok := fields["screenshot"].(string)
+				require.True(t, ok)
+				png, err := os.ReadFile(f)
+				require.NoError(t, err)
+				assert.Equal(t, "fake-png", string(png))
+			}
+		})
+	}
+}
+
+func TestBrowserTaskInit(t *testing.T) {
+	cases := []struct {
+		name string
+		task *browserTask
+		fail bool
+	}{
+		{
+			name: "ok",
+			task: &browserTask{
+				URL:   "https://oa.example.com",
+				Steps: []*browserStep{{Action: actionWaitFor, Selector: "#app", Timeout: "5s"}},
+				BusinessHours: &businessHours{
+					Timezone: "Asia/Shanghai", Weekdays: []int{1, 2, 3, 4, 5}, Start: "09:00", End: "18:00",
+				},
+			},
+		},
+		{
+			name: "no-url",
+			task: &browserTask{},
+			fail: true,
+		},
+		{
+			name: "unknown-action",
+			task: &browserTask{URL: "https://oa.example.com", Steps: []*browserStep{{Action: "scroll"}}},
+			fail: true,
+		},
+		{
+			name: "click-without-selector",
+			task: &browserTask{URL: "https://oa.example.com", Steps: []*browserStep{{Action: actionClick}}},
+			fail: true,
+		},
+		{
+			name: "invalid-business-hours",
+			task: &browserTask{URL: "https://oa.example.com", BusinessHours: &businessHours{Start: "9am", End: "18:00"}},
+			fail: true,
+		},
+		{
+			name: "invalid-weekday",
+			task: &browserTask{URL: "https://oa.example.com", BusinessHours: &businessHours{Weekdays: []int{7}, Start: "09:00", End: "18:00"}},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.task.InitDebug()
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBusinessHours(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	workdays := &businessHours{Timezone: "Asia/Shanghai", Weekdays: []int{1, 2, 3, 4, 5}, Start: "09:00", End: "18:00"}
+	require.NoError(t, workdays.init())
+
+	night := &businessHours{Timezone: "Asia/Shanghai", Weekdays: []int{5}, Start: "22:00", End: "06:00"}
+	require.NoError(t, night.init())
+
+	cases := []struct {
+		bh  *businessHours
+		now time.Time
+		in  bool
+	}{
+		{workdays, time.Date(2023, 3, 6, 9, 0, 0, 0, loc), true},       // Monday
+		{workdays, time.Date(2023, 3, 6, 17, 59, 0, 0, loc), true},     // Monday
+		{workdays, time.Date(2023, 3, 6, 18, 0, 0, 0, loc), false},     // Monday
+		{workdays, time.Date(2023, 3, 6, 8, 59, 0, 0, loc), false},     // Monday
+		{workdays, time.Date(2023, 3, 5, 10, 0, 0, 0, loc), false},     // Sunday
+		{workdays, time.Date(2023, 3, 6, 1, 30, 0, 0, time.UTC), true}, // Monday 09:30 at Shanghai
+		{night, time.Date(2023, 3, 10, 23, 0, 0, 0, loc), true},        // Friday
+		{night, time.Date(2023, 3, 11, 5, 0, 0, 0, loc), true},         // Saturday, started at Friday
+		{night, time.Date(2023, 3, 11, 23, 0, 0, 0, loc), false},       // Saturday
+		{night, time.Date(2023, 3, 10, 5, 0, 0, 0, loc), false},        // Friday, started at Thursday
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.in, tc.bh.contains(tc.now), "%s ~ %s at %s", tc.bh.Start, tc.bh.End, tc.now)
+	}
+
+	task := &browserTask{BusinessHours: workdays}
+	assert.False(t, inSchedule(task, time.Date(2023, 3, 5, 10, 0, 0, 0, loc)))
+	assert.True(t, inSchedule(&browserTask{}, time.Date(2023, 3, 5, 10, 0, 0, 0, loc)))
+}
+
+func TestBrowserTaskRunWithoutBrowser(t *testing.T) {
+	task := newBrowserTask(&browserRuntime{})
+	task.URL = "https://oa.example.com"
+	task.Frequency = "1m"
+
+	_, err := (&Input{}).newTaskRun(task)
+	assert.ErrorContains(t, err, "browser_path")
+}
@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !windows
+// +build !windows
+
+package dialtesting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Minimal Chrome DevTools Protocol(CDP) client, only commands used by
+// browser tasks are supported.
+
+const devToolsListening = "DevTools listening on "
+
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *cdpError) Error() string {
+	return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+type cdpMessage struct {
+	ID        int64           `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdpError       `json:"error,omitempty"`
+}
+
+type cdpConn struct {
+	ws *websocket.Conn
+
+	wmtx sync.Mutex // websocket allows only one concurrent writer
+
+	mtx     sync.Mutex
+	id      int64
+	pending map[int64]chan *cdpMessage
+
+	sessionID string
+	events    chan *cdpMessage
+	closed    chan struct{}
+}
+
+func dialCDP(ctx context.Context, wsURL string) (*cdpConn, error) {
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cdpConn{
+		ws:      ws,
+		pending: map[int64]chan *cdpMessage{},
+		events:  make(chan *cdpMessage, 64),
+		closed:  make(chan struct{}),
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *cdpConn) readLoop() {
+	defer close(c.closed)
+
+	for {
+		var msg cdpMessage
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		if msg.ID == 0 { // event
+			select {
+			case c.events <- &msg:
+			default: // nobody cares, dropped
+			}
+			continue
+		}
+
+		c.mtx.Lock()
+		ch, ok := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mtx.Unlock()
+
+		if ok {
+			ch <- &msg
+		}
+	}
+}
+
+// call sends the command and decodes its result into res if not nil.
+func (c *cdpConn) call(ctx context.Context, method string, params, res interface{}) error {
+	c.mtx.Lock()
+	c.id++
+	id := c.id
+	ch := make(chan *cdpMessage, 1)
+	c.pending[id] = ch
+	c.mtx.Unlock()
+
+	req := map[string]interface{}{"id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if c.sessionID != "" {
+		req["sessionId"] = c.sessionID
+	}
+
+	c.wmtx.Lock()
+	err := c.ws.WriteJSON(req)
+	c.wmtx.Unlock()
+
+	if err != nil {
+		c.mtx.Lock()
+		delete(c.pending, id)
+		c.mtx.Unlock()
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.mtx.Lock()
+		delete(c.pending, id)
+		c.mtx.Unlock()
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+
+	case <-c.closed:
+		return fmt.Errorf("%s: connection closed", method)
+
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %w", method, msg.Error)
+		}
+
+		if res != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, res)
+		}
+		return nil
+	}
+}
+
+// drainEvents drops events received so far.
+func (c *cdpConn) drainEvents() {
+	for {
+		select {
+		case <-c.events:
+		default:
+			return
+		}
+	}
+}
+
+// waitEvent waits until the event received.
+func (c *cdpConn) waitEvent(ctx context.Context, method string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait %s: %w", method, ctx.Err())
+		case <-c.closed:
+			return fmt.Errorf("wait %s: connection closed", method)
+		case ev := <-c.events:
+			if ev.Method == method {
+				return nil
+			}
+		}
+	}
+}
+
+// attachPage opens a blank page and attaches to it, later commands are
+// sent to the page.
+func (c *cdpConn) attachPage(ctx context.Context) error {
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := c.call(ctx, "Target.createTarget",
+		map[string]interface{}{"url": "about:blank"}, &target); err != nil {
+		return err
+	}
+
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := c.call(ctx, "Target.attachToTarget",
+		map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &session); err != nil {
+		return err
+	}
+
+	c.sessionID = session.SessionID
+	return c.call(ctx, "Page.enable", nil, nil)
+}
+
+// navigate opens the URL and waits the page loaded.
+func (c *cdpConn) navigate(ctx context.Context, u string) error {
+	c.drainEvents()
+
+	var res struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := c.call(ctx, "Page.navigate", map[string]interface{}{"url": u}, &res); err != nil {
+		return err
+	}
+
+	if res.ErrorText != "" {
+		return fmt.Errorf("navigate %s: %s", u, res.ErrorText)
+	}
+
+	return c.waitEvent(ctx, "Page.loadEventFired")
+}
+
+// evaluate runs the JavaScript expression and decodes its value into res,
+// promises are awaited.
+func (c *cdpConn) evaluate(ctx context.Context, expr string, res interface{}) error {
+	var ret struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+
+	if err := c.call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    expr,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}, &ret); err != nil {
+		return err
+	}
+
+	if ret.ExceptionDetails != nil {
+		return fmt.Errorf("evaluate: %s", ret.ExceptionDetails.Text)
+	}
+
+	if res != nil && len(ret.Result.Value) > 0 {
+		return json.Unmarshal(ret.Result.Value, res)
+	}
+	return nil
+}
+
+// screenshot captures the page in PNG, base64 encoded.
+func (c *cdpConn) screenshot(ctx context.Context) (string, error) {
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := c.call(ctx, "Page.captureScreenshot",
+		map[string]interface{}{"format": "png"}, &res); err != nil {
+		return "", err
+	}
+	return res.Data, nil
+}
+
+func (c *cdpConn) close() error {
+	return c.ws.Close()
+}
+
+// browserProc is the headless browser process.
+type browserProc struct {
+	cmd     *exec.Cmd
+	dataDir string
+	wsURL   string
+}
+
+// launchBrowser starts the headless browser with remote debugging enabled,
+// and gets its DevTools websocket URL from stderr.
+func launchBrowser(ctx context.Context, path string, args []string) (*browserProc, error) {
+	dataDir, err := os.MkdirTemp("", "dialtesting-browser-")
+	if err != nil {
+		return nil, err
+	}
+
+	argv := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--remote-debugging-port=0",
+		"--user-data-dir=" + dataDir,
+	}
+	if os.Geteuid() == 0 {
+		argv = append(argv, "--no-sandbox") // chromium refuses to run as root within sandbox
+	}
+	argv = append(argv, args...)
+	argv = append(argv, "about:blank")
+
+	cmd := exec.Command(path, argv...) //nolint:gosec
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		_ = os.RemoveAll(dataDir)
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		_ = os.RemoveAll(dataDir)
+		return nil, err
+	}
+
+	p := &browserProc{cmd: cmd, dataDir: dataDir}
+
+	found := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, devToolsListening) {
+				found <- strings.TrimSpace(strings.TrimPrefix(line, devToolsListening))
+				break
+			}
+		}
+		_, _ = io.Copy(io.Discard, stderr) // keep the browser from blocking on stderr
+		close(found)
+	}()
+
+	select {
+	case <-ctx.Done():
+		p.close()
+		return nil, fmt.Errorf("launch browser %s: %w", path, ctx.Err())
+	case u, ok := <-found:
+		if !ok {
+			p.close()
+			return nil, fmt.Errorf("launch browser %s: DevTools URL not found", path)
+		}
+		p.wsURL = u
+	}
+
+	return p, nil
+}
+
+func (p *browserProc) close() {
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+		_ = p.cmd.Wait()
+	}
+	_ = os.RemoveAll(p.dataDir)
+}
@@ -9,6 +9,7 @@
 package dialtesting
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	dt "github.com/GuanceCloud/cliutils/dialtesting"
 	_ "github.com/go-ping/ping"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/dataway"
 )
 
@@ -90,18 +92,25 @@ func (d *dialer) run() error {
 		l.Debugf(`dialer run %+#v, fail count: %d`, d, failCount)
 		d.testCnt++
 
-		switch d.task.Class() {
-		case dt.ClassHeadless:
-			return fmt.Errorf("headless task deprecated")
+		switch {
+		case !inSchedule(d.task, time.Now()):
+			l.Debugf("dial testing %s out of schedule, skipped", d.task.ID())
 		default:
+			dest := taskDest(d.task)
+			if err := probelimit.Wait(context.Background(), dest); err != nil {
+				// skipped, no result recorded
+				l.Warnf("dial testing %s skipped: %s", d.task.ID(), err)
+				break
+			}
+
 			_ = d.task.Run() //nolint:errcheck
-		}
+			d.consumeBandwidth(dest)
 
-		// dialtesting start
-		// 无论成功或失败，都要记录测试结果
-		err := d.feedIO()
-		if err != nil {
-			l.Warnf("io feed failed, %s", err.Error())
+			// dialtesting start
+			// 无论成功或失败，都要记录测试结果
+			if err := d.feedIO(); err != nil {
+				l.Warnf("io feed failed, %s", err.Error())
+			}
 		}
 
 		select {
@@ -127,6 +136,49 @@ func (d *dialer) run() error {
 	}
 }
 
+// taskDest returns the destination probed by the task.
+func taskDest(t dt.Task) string {
+	switch x := t.(type) {
+	case *dt.HTTPTask:
+		return x.URL
+	case *dt.TCPTask:
+		return x.Host
+	case *dt.ICMPTask:
+		return x.Host
+	case *dt.WebsocketTask:
+		return x.URL
+	case *browserTask:
+		return x.URL
+	default:
+		return ""
+	}
+}
+
+// scheduledTask is task only run within specified time, such as business hours.
+type scheduledTask interface {
+	inSchedule(now time.Time) bool
+}
+
+func inSchedule(t dt.Task, now time.Time) bool {
+	if x, ok := t.(scheduledTask); ok {
+		return x.inSchedule(now)
+	}
+	return true
+}
+
+// consumeBandwidth charges the response body downloaded by HTTP tasks to
+// the outbound probe budget.
+func (d *dialer) consumeBandwidth(dest string) {
+	if d.task.Class() != dt.ClassHTTP {
+		return
+	}
+
+	_, fields := d.task.GetResults()
+	if n, ok := fields["response_body_size"].(int64); ok {
+		probelimit.Consume(dest, int(n))
+	}
+}
+
 func (d *dialer) feedIO() error {
 	// 考虑到推送至不同的dataway地址
 	u, err := url.Parse(d.task.PostURLStr())
@@ -139,11 +191,9 @@ func (d *dialer) feedIO() error {
 
 	urlStr := u.String()
 	switch d.task.Class() {
-	case dt.ClassHTTP, dt.ClassTCP, dt.ClassICMP, dt.ClassWebsocket:
+	case dt.ClassHTTP, dt.ClassTCP, dt.ClassICMP, dt.ClassWebsocket, dt.ClassHeadless:
 		d.category = urlStr
 		return d.pointsFeed(urlStr)
-	case dt.ClassHeadless:
-		return fmt.Errorf("headless task deprecated")
 	default:
 		// TODO other class
 	}
@@ -34,6 +34,103 @@ var httpCases = []struct {
 	reasonCnt int
 	succFlag  bool
 }{
+	{
+		name:      "test dial with JSONPath",
+		reasonCnt: 0,
+		succFlag:  true,
+		t: &dt.HTTPTask{
+			ExternalID: cliutils.XID("dtst_"),
+			Method:     "GET",
+			URL:        "http://localhost:54321/_test_json_body",
+			Name:       "_test_json_body",
+			Frequency:  "1s",
+			SuccessWhen: []*dt.HTTPSuccess{
+				{
+					JSONPath: []*dt.JSONPathSuccess{
+						{Path: "$.code", SuccessOption: dt.SuccessOption{Is: "0"}},
+						{Path: "$.data.items[0]['name']", SuccessOption: dt.SuccessOption{MatchRegex: "^foo"}},
+						{Path: "$.data.total", Value: &dt.ValueSuccess{Op: "geq", Target: 2}},
+					},
+				},
+			},
+		},
+	},
+	{
+		name:      "test dial with JSONPath not matched",
+		reasonCnt: 3,
+		succFlag:  false,
+		t: &dt.HTTPTask{
+			ExternalID: cliutils.XID("dtst_"),
+			Method:     "GET",
+			URL:        "http://localhost:54321/_test_json_body",
+			Name:       "_test_json_body",
+			Frequency:  "1s",
+			SuccessWhen: []*dt.HTTPSuccess{
+				{
+					JSONPath: []*dt.JSONPathSuccess{
+						{Path: "$.data.items[1].name", SuccessOption: dt.SuccessOption{Is: "foo"}},
+						{Path: "$.data.total", Value: &dt.ValueSuccess{Op: "lt", Target: 2}},
+						{Path: "$.data.missing", SuccessOption: dt.SuccessOption{Contains: "foo"}},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "test dial with invalid JSONPath",
+		fail: true,
+		t: &dt.HTTPTask{
+			ExternalID: cliutils.XID("dtst_"),
+			Method:     "GET",
+			URL:        "http://localhost:54321/_test_json_body",
+			Name:       "_test_json_body",
+			Frequency:  "1s",
+			SuccessWhen: []*dt.HTTPSuccess{
+				{
+					JSONPath: []*dt.JSONPathSuccess{{Path: "data.items[?(@.id > 1)]"}},
+				},
+			},
+		},
+	},
+	{
+		name:      "test dial with response time of region",
+		reasonCnt: 0,
+		succFlag:  true,
+		t: &dt.HTTPTask{
+			ExternalID: cliutils.XID("dtst_"),
+			Method:     "GET",
+			URL:        "http://localhost:54321/_test_resp_time_less_10ms",
+			Name:       "_test_resp_time_less_10ms",
+			Region:     "far_away",
+			Frequency:  "1s",
+			SuccessWhen: []*dt.HTTPSuccess{
+				{
+					ResponseTime:         "10ms",
+					ResponseTimeByRegion: map[string]string{"far_away": "1s"},
+				},
+			},
+		},
+	},
+	{
+		name:      "test dial with response time of other region",
+		reasonCnt: 1,
+		succFlag:  false,
+		t: &dt.HTTPTask{
+			ExternalID: cliutils.XID("dtst_"),
+			Method:     "GET",
+			URL:        "http://localhost:54321/_test_resp_time_less_10ms",
+			Name:       "_test_resp_time_less_10ms",
+			Region:     "nearby",
+			Frequency:  "1s",
+			SuccessWhen: []*dt.HTTPSuccess{
+				{
+					ResponseTime:         "10ms",
+					ResponseTimeByRegion: map[string]string{"far_away": "1s"},
+				},
+			},
+		},
+	},
+
 	{
 		name:      "test dial with certificate",
 		fail:      false,
@@ -618,6 +715,11 @@ func addTestingRoutes(t *testing.T, r *gin.Engine, https bool) {
 		c.Data(http.StatusOK, ``, nil)
 	})
 
+	r.GET("/_test_json_body", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json",
+			[]byte(`{"code":0,"data":{"total":2,"items":[{"id":1,"name":"foo"},{"id":2,"name":"bar"}]}}`))
+	})
+
 	r.GET("/_test_header_checking", func(c *gin.Context) {
 		c.DataFromReader(http.StatusOK, 0, "", bytes.NewBuffer([]byte("")),
 			map[string]string{
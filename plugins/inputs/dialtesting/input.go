@@ -71,6 +71,8 @@ type Input struct {
 	TimeOut          *datakit.Duration `toml:"time_out,omitempty"` // 单位为秒
 	Workers          int               `toml:"workers,omitempty"`
 	MaxSendFailCount int32             `toml:"max_send_fail_count,omitempty"` // max send fail count
+	BrowserPath      string            `toml:"browser_path,omitempty"`        // headless browser for BROWSER tasks
+	BrowserArgs      []string          `toml:"browser_args,omitempty"`
 	Tags             map[string]string
 
 	semStop *cliutils.Sem // start stop signal
@@ -101,6 +103,11 @@ const sample = `
   # 发送数据失败最大次数，根据任务的post_url进行累计，超过最大次数后，发送至该地址的拨测任务将退出
   max_send_fail_count = 16
 
+  # 无头浏览器（Chromium/Chrome）路径，配置后才会执行 BROWSER 拨测任务
+  # browser_path = "/usr/bin/chromium"
+  # 启动浏览器时追加的参数
+  # browser_args = []
+
   [inputs.dialtesting.tags]
   # some_tag = "some_value"
   # more_tag = "some_other_value"
@@ -120,6 +127,7 @@ func (*Input) SampleMeasurement() []inputs.Measurement {
 		&tcpMeasurement{},
 		&icmpMeasurement{},
 		&websocketMeasurement{},
+		&browserMeasurement{},
 	}
 }
 
@@ -264,7 +272,9 @@ func (d *Input) newTaskRun(t dt.Task) (*dialer, error) {
 	case dt.ClassHTTP:
 		apiTasksNum++
 	case dt.ClassHeadless:
-		return nil, fmt.Errorf("headless task deprecated")
+		if d.BrowserPath == "" {
+			return nil, fmt.Errorf("headless task requires browser_path")
+		}
 	case dt.ClassDNS:
 		// TODO
 	case dt.ClassTCP:
@@ -381,8 +391,8 @@ func (d *Input) dispatchTasks(j []byte) error {
 			continue
 		}
 
-		if k == dt.ClassHeadless {
-			l.Debugf("ignore %d headless tasks", len(arr))
+		if k == dt.ClassHeadless && d.BrowserPath == "" {
+			l.Debugf("no browser runtime configured, ignore %d headless tasks", len(arr))
 			continue
 		}
 
@@ -402,6 +412,8 @@ func (d *Input) dispatchTasks(j []byte) error {
 				t = &dt.WebsocketTask{}
 			case dt.ClassICMP:
 				t = &dt.ICMPTask{}
+			case dt.ClassHeadless:
+				t = newBrowserTask(&browserRuntime{path: d.BrowserPath, args: d.BrowserArgs})
 			case dt.ClassOther:
 				// TODO
 				l.Warnf("OTHER task deprecated, ignored")
@@ -428,6 +440,11 @@ func (d *Input) dispatchTasks(j []byte) error {
 
 			l.Debugf("unmarshal task: %+#v", t)
 
+			// success checking like response time may be specified per region
+			if d.RegionID != "" {
+				t.SetRegionID(d.RegionID)
+			}
+
 			// update dialer pos
 			ts := t.UpdateTimeUs()
 			if d.pos < ts {
@@ -591,7 +608,8 @@ func (d *Input) pullHTTPTask(reqURL *url.URL, sinceUs int64) ([]byte, int, error
 // ENV_INPUT_DIALTESTING_AK: string
 // ENV_INPUT_DIALTESTING_SK: string
 // ENV_INPUT_DIALTESTING_REGION_ID: string
-// ENV_INPUT_DIALTESTING_SERVER: string.
+// ENV_INPUT_DIALTESTING_SERVER: string
+// ENV_INPUT_DIALTESTING_BROWSER_PATH: string.
 func (d *Input) ReadEnv(envs map[string]string) {
 	if ak, ok := envs["ENV_INPUT_DIALTESTING_AK"]; ok {
 		d.AK = ak
@@ -608,6 +626,10 @@ func (d *Input) ReadEnv(envs map[string]string) {
 	if server, ok := envs["ENV_INPUT_DIALTESTING_SERVER"]; ok {
 		d.Server = server
 	}
+
+	if browserPath, ok := envs["ENV_INPUT_DIALTESTING_BROWSER_PATH"]; ok {
+		d.BrowserPath = browserPath
+	}
 }
 
 func (d *Input) stopAlltask() {
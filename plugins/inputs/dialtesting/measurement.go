@@ -347,3 +347,105 @@ func (m *websocketMeasurement) Info() *inputs.MeasurementInfo {
 		},
 	}
 }
+
+type browserMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+func (m *browserMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.LOpt())
+}
+
+//nolint:lll
+func (m *browserMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "browser_dial_testing",
+		Tags: map[string]interface{}{
+			"name":     &inputs.TagInfo{Desc: "示例 拨测名称,OA 登录"},
+			"url":      &inputs.TagInfo{Desc: "示例 `https://oa.example.com/login`"},
+			"country":  &inputs.TagInfo{Desc: "示例 中国"},
+			"province": &inputs.TagInfo{Desc: "示例 浙江"},
+			"city":     &inputs.TagInfo{Desc: "示例 杭州"},
+			"internal": &inputs.TagInfo{Desc: "示例 true（国内 true /海外 false）"},
+			"isp":      &inputs.TagInfo{Desc: "示例 电信/移动/联通"},
+			"status":   &inputs.TagInfo{Desc: "示例 OK/FAIL 两种状态 "},
+			"proto":    &inputs.TagInfo{Desc: "示例 browser"},
+		},
+		Fields: map[string]interface{}{
+			"message": &inputs.FieldInfo{
+				DataType: inputs.String,
+				Type:     inputs.Gauge,
+				Unit:     inputs.UnknownUnit,
+				Desc:     "包括响应时间(response_time)/错误原因(fail_reason)",
+			},
+			"fail_reason": &inputs.FieldInfo{
+				DataType: inputs.String,
+				Type:     inputs.Gauge,
+				Unit:     inputs.UnknownUnit,
+				Desc:     "拨测失败原因",
+			},
+			"response_time": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "页面加载及执行全部步骤的时间",
+			},
+			"ttfb": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "页面首字节时间",
+			},
+			"dom_content_loaded": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "DOMContentLoaded 事件完成时间",
+			},
+			"load_event": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "load 事件完成时间",
+			},
+			"first_contentful_paint": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "首次内容绘制时间（FCP）",
+			},
+			"largest_contentful_paint": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.DurationUS,
+				Desc:     "最大内容绘制时间（LCP）",
+			},
+			"cumulative_layout_shift": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.NCount,
+				Desc:     "累积布局偏移（CLS）",
+			},
+			"steps_passed": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.NCount,
+				Desc:     "执行成功的步骤数",
+			},
+			"screenshot": &inputs.FieldInfo{
+				DataType: inputs.String,
+				Type:     inputs.Gauge,
+				Unit:     inputs.UnknownUnit,
+				Desc:     "失败时页面截图在拨测节点上的路径",
+			},
+			"success": &inputs.FieldInfo{
+				DataType: inputs.Int,
+				Type:     inputs.Gauge,
+				Unit:     inputs.UnknownUnit,
+				Desc:     "只有 1/-1 两种状态, 1 表示成功, -1 表示失败",
+			},
+		},
+	}
+}
@@ -53,6 +53,8 @@ type Input struct {
 	L7NetDisabled  []string      `toml:"l7net_disabled"`
 	L7NetEnabled   []string      `toml:"l7net_enabled"`
 	IPv6Disabled   bool          `toml:"ipv6_disabled"`
+	NetDropEnabled bool          `toml:"netdrop_enabled"`
+	DNSFailureLog  bool          `toml:"dnsflow_failure_log"`
 	EphemeralPort  int32         `toml:"ephemeral_port"`
 	Interval       string        `toml:"interval"`
 	semStop        *cliutils.Sem // start stop signal
@@ -151,6 +153,16 @@ loop:
 			"--ipv6-disabled", "true")
 	}
 
+	if ipt.NetDropEnabled {
+		ipt.ExternalInput.Args = append(ipt.ExternalInput.Args,
+			"--netdrop-enabled", "true")
+	}
+
+	if ipt.DNSFailureLog {
+		ipt.ExternalInput.Args = append(ipt.ExternalInput.Args,
+			"--dnsflow-failure-log", "true")
+	}
+
 	if ipt.EphemeralPort >= 0 {
 		ipt.ExternalInput.Args = append(ipt.ExternalInput.Args,
 			"--ephemeral_port", strconv.FormatInt(int64(ipt.EphemeralPort), 10))
@@ -200,6 +212,8 @@ func (*Input) SampleMeasurement() []inputs.Measurement {
 		&DNSStatsM{},
 		&BashM{},
 		&HTTPFlowM{},
+		&NetDropM{},
+		&DNSFailureLogM{},
 	}
 }
 
@@ -211,6 +225,8 @@ func (*Input) AvailableArchs() []string {
 //   ENV_INPUT_EBPF_ENABLED_PLUGINS : []string
 //   ENV_INPUT_EBPF_L7NET_ENABLED   : []string
 //   ENV_INPUT_EBPF_IPV6_DISABLED   : bool
+//   ENV_INPUT_EBPF_NETDROP_ENABLED : bool
+//   ENV_INPUT_EBPF_DNSFLOW_FAILURE_LOG : bool
 // 	 ENV_INPUT_EBPF_EPHEMERAL_PORT  : int32
 //   ENV_INPUT_EBPF_INTERVAL        : string
 func (ipt *Input) ReadEnv(envs map[string]string) {
@@ -233,6 +249,24 @@ func (ipt *Input) ReadEnv(envs map[string]string) {
 		}
 	}
 
+	if v, ok := envs["ENV_INPUT_EBPF_NETDROP_ENABLED"]; ok {
+		switch v {
+		case "", "f", "false", "FALSE", "False", "0":
+			ipt.NetDropEnabled = false
+		default:
+			ipt.NetDropEnabled = true
+		}
+	}
+
+	if v, ok := envs["ENV_INPUT_EBPF_DNSFLOW_FAILURE_LOG"]; ok {
+		switch v {
+		case "", "f", "false", "FALSE", "False", "0":
+			ipt.DNSFailureLog = false
+		default:
+			ipt.DNSFailureLog = true
+		}
+	}
+
 	if v, ok := envs["ENV_INPUT_EBPF_EPHEMERAL_PORT"]; ok {
 		if p, err := strconv.ParseInt(v, 10, 32); err != nil {
 			l.Warn("parse ENV_INPUT_EBPF_EPHEMERAL_PORT: %w", err)
@@ -145,12 +145,12 @@ func (m *DNSStatsM) Info() *inputs.MeasurementInfo {
 			"dst_k8s_deployment_name": inputs.TagInfo{Desc: "Destination K8s deployment name."},
 			"dst_k8s_service_name":    inputs.TagInfo{Desc: "Destination K8s service name."},
 			"dst_k8s_namespace":       inputs.TagInfo{Desc: "Destination K8s namespace."},
-			// "pid":                     inputs.TagInfo{Desc: "Process identification number."},
-			// "process_name":            inputs.TagInfo{Desc: "Process name."},
-			"transport": inputs.TagInfo{Desc: "Transport layer protocol. (udp/tcp)"},
-			"family":    inputs.TagInfo{Desc: "Network layer protocol. (IPv4/IPv6)"},
-			"direction": inputs.TagInfo{Desc: "Use the source as a frame of reference to identify the connection initiator. (incoming/outgoing)"},
-			"source":    inputs.TagInfo{Desc: "Fixed value: dnsflow."},
+			"process_name":            inputs.TagInfo{Desc: "Name of the process sent the DNS query, if found."},
+			"container_id":            inputs.TagInfo{Desc: "Container ID of the process sent the DNS query, if found."},
+			"transport":               inputs.TagInfo{Desc: "Transport layer protocol. (udp/tcp)"},
+			"family":                  inputs.TagInfo{Desc: "Network layer protocol. (IPv4/IPv6)"},
+			"direction":               inputs.TagInfo{Desc: "Use the source as a frame of reference to identify the connection initiator. (incoming/outgoing)"},
+			"source":                  inputs.TagInfo{Desc: "Fixed value: dnsflow."},
 			"sub_source": inputs.TagInfo{Desc: "Some specific connection classifications, " +
 				"such as the sub_source value for Kubernetes network traffic is K8s."},
 		},
@@ -164,6 +164,88 @@ func (m *DNSStatsM) Info() *inputs.MeasurementInfo {
 	}
 }
 
+type DNSFailureLogM measurement
+
+func (m *DNSFailureLogM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.LOpt())
+}
+
+func (m *DNSFailureLogM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "dnsflow_failure",
+		Type: "logging",
+		Tags: map[string]interface{}{
+			"host":                    inputs.TagInfo{Desc: "System hostname."},
+			"src_ip":                  inputs.TagInfo{Desc: "IP address of the DNS client."},
+			"dst_ip":                  inputs.TagInfo{Desc: "IP address of the DNS resolver."},
+			"rcode":                   inputs.TagInfo{Desc: "DNS response code name, such as NXDomain/ServFail/Refused, Timeout if the request timed out."},
+			"dns_query":               inputs.TagInfo{Desc: "The domain name queried."},
+			"process_name":            inputs.TagInfo{Desc: "Name of the process sent the DNS query, if found."},
+			"container_id":            inputs.TagInfo{Desc: "Container ID of the process sent the DNS query, if found."},
+			"src_k8s_pod_name":        inputs.TagInfo{Desc: "Source K8s pod name."},
+			"src_k8s_deployment_name": inputs.TagInfo{Desc: "Source K8s deployment name."},
+			"src_k8s_service_name":    inputs.TagInfo{Desc: "Source K8s service name."},
+			"src_k8s_namespace":       inputs.TagInfo{Desc: "Source K8s namespace."},
+		},
+		Fields: map[string]interface{}{
+			"message": newFString("Description of the failed DNS queries."),
+			"status":  newFString("Log status, warning for NXDomain, error for the others."),
+			"count":   newFInfInt("The number of failed DNS queries in a collection cycle.", inputs.NCount),
+			"pid":     newFInfInt("Process identification number of the process sent the DNS query, if found.", inputs.UnknownUnit),
+		},
+	}
+}
+
+type NetDropM measurement
+
+func (m *NetDropM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOpt())
+}
+
+//nolint:lll
+func (m *NetDropM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "netdrop",
+		Tags: map[string]interface{}{
+			"host":                inputs.TagInfo{Desc: "System hostname."},
+			"netns":               inputs.TagInfo{Desc: "Inode of the network namespace."},
+			"host_network":        inputs.TagInfo{Desc: "Whether the network namespace is the host network namespace. (true/false)"},
+			"pid":                 inputs.TagInfo{Desc: "Process identification number of the process picked to represent the network namespace."},
+			"process_name":        inputs.TagInfo{Desc: "Process name."},
+			"container_id":        inputs.TagInfo{Desc: "Container ID of the process."},
+			"ip":                  inputs.TagInfo{Desc: "Pod IP address."},
+			"k8s_pod_name":        inputs.TagInfo{Desc: "K8s pod name."},
+			"k8s_namespace":       inputs.TagInfo{Desc: "K8s namespace."},
+			"k8s_deployment_name": inputs.TagInfo{Desc: "K8s deployment name."},
+			"k8s_service_name":    inputs.TagInfo{Desc: "K8s service name."},
+			"source":              inputs.TagInfo{Desc: "Fixed value: netdrop."},
+			"sub_source":          inputs.TagInfo{Desc: "The sub_source value for network namespaces matched to a Kubernetes pod is K8s."},
+		},
+		Fields: map[string]interface{}{
+			"rx_dropped":              newFInfInt("The number of received packets dropped by the network interfaces in a collection cycle.", inputs.NCount),
+			"tx_dropped":              newFInfInt("The number of transmitted packets dropped by the network interfaces in a collection cycle.", inputs.NCount),
+			"rx_errors":               newFInfInt("The number of receive errors of the network interfaces in a collection cycle.", inputs.NCount),
+			"tx_errors":               newFInfInt("The number of transmit errors of the network interfaces in a collection cycle.", inputs.NCount),
+			"qdisc_drops":             newFInfInt("The number of packets dropped by the qdiscs in a collection cycle.", inputs.NCount),
+			"qdisc_overlimits":        newFInfInt("The number of qdisc throttle events in a collection cycle.", inputs.NCount),
+			"qdisc_requeues":          newFInfInt("The number of qdisc requeues in a collection cycle.", inputs.NCount),
+			"qdisc_backlog":           newFInfInt("Bytes currently queued in the qdiscs.", inputs.SizeByte),
+			"qdisc_qlen":              newFInfInt("Packets currently queued in the qdiscs.", inputs.NCount),
+			"tcp_retrans_segs":        newFInfInt("The number of TCP segments retransmitted in a collection cycle.", inputs.NCount),
+			"tcp_out_segs":            newFInfInt("The number of TCP segments sent in a collection cycle.", inputs.NCount),
+			"tcp_listen_drops":        newFInfInt("The number of SYNs to LISTEN sockets dropped in a collection cycle.", inputs.NCount),
+			"tcp_listen_overflows":    newFInfInt("The number of times the listen queue of a socket overflowed in a collection cycle.", inputs.NCount),
+			"tcp_backlog_drop":        newFInfInt("The number of packets dropped because the socket backlog was full in a collection cycle.", inputs.NCount),
+			"conntrack_entries":       newFInfInt("The number of conntrack entries of the network namespace.", inputs.NCount),
+			"conntrack_max":           newFInfInt("The size of the conntrack table.", inputs.NCount),
+			"conntrack_usage":         &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "Percentage of the conntrack table used by the network namespace."},
+			"conntrack_drop":          newFInfInt("The number of packets dropped because the conntrack table is full in a collection cycle.", inputs.NCount),
+			"conntrack_early_drop":    newFInfInt("The number of conntrack entries evicted to make room for new ones in a collection cycle.", inputs.NCount),
+			"conntrack_insert_failed": newFInfInt("The number of conntrack entries failed to insert in a collection cycle.", inputs.NCount),
+		},
+	}
+}
+
 type BashM measurement
 
 func (m *BashM) LineProto() (*point.Point, error) {
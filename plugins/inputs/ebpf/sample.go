@@ -48,6 +48,17 @@ const configSample = `
   ##
   ipv6_disabled = false
 
+  ## If you enable the ebpf-net plugin, per container/pod packet drop (qdisc and
+  ## interface), TCP retransmit and conntrack table pressure metrics (netdrop)
+  ## can be collected
+  ##
+  # netdrop_enabled = true
+
+  ## If you enable the ebpf-net plugin, failed DNS queries (error response code
+  ## or timeout) are aggregated and uploaded as logging (dnsflow_failure)
+  ##
+  # dnsflow_failure_log = true
+
   ## ephemeral port strart from <ephemeral_port>
   ##
   # ephemeral_port = 10001
@@ -0,0 +1,263 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Forward protocol, see
+// https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+
+// eventTimeExt is the msgpack ext type of EventTime: seconds and nanoseconds
+// in 32bit big endian.
+const eventTimeExt = 0
+
+var mh = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+	h.WriteExt = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+type entry struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// message is one of Message, Forward, PackedForward and CompressedPackedForward
+// mode of the forward protocol.
+type message struct {
+	tag     string
+	entries []*entry
+	option  map[string]interface{}
+}
+
+// chunk returns the chunk option, the client requires an ack with it if not
+// empty.
+func (m *message) chunk() string {
+	if m.option == nil {
+		return ""
+	}
+	s, _ := m.option["chunk"].(string)
+	return s
+}
+
+func decodeMessage(arr []interface{}) (*message, error) {
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("invalid message: %d elements", len(arr))
+	}
+
+	tag, ok := arr[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid tag type %T", arr[0])
+	}
+
+	m := &message{tag: tag}
+
+	switch x := arr[1].(type) {
+	case []interface{}: // Forward mode: [tag, [[time, record], ...], option]
+		if len(arr) > 2 {
+			m.option = toMap(arr[2])
+		}
+
+		for _, e := range x {
+			ent, err := decodeEntry(e)
+			if err != nil {
+				return nil, err
+			}
+			m.entries = append(m.entries, ent)
+		}
+
+	case string: // PackedForward mode: [tag, msgpack stream of entries, option]
+		if len(arr) > 2 {
+			m.option = toMap(arr[2])
+		}
+
+		entries, err := decodePacked([]byte(x), m.option)
+		if err != nil {
+			return nil, err
+		}
+		m.entries = entries
+
+	default: // Message mode: [tag, time, record, option]
+		if len(arr) < 3 {
+			return nil, fmt.Errorf("invalid message mode: %d elements", len(arr))
+		}
+
+		if len(arr) > 3 {
+			m.option = toMap(arr[3])
+		}
+
+		ent, err := decodeEntry([]interface{}{arr[1], arr[2]})
+		if err != nil {
+			return nil, err
+		}
+		m.entries = append(m.entries, ent)
+	}
+
+	return m, nil
+}
+
+func decodePacked(data []byte, option map[string]interface{}) ([]*entry, error) {
+	if c, _ := option["compressed"].(string); c == "gzip" {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip.NewReader: %w", err)
+		}
+
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("gunzip entries: %w", err)
+		}
+	}
+
+	var (
+		entries []*entry
+		dec     = codec.NewDecoderBytes(data, mh)
+	)
+
+	for {
+		var e interface{}
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("decode packed entries: %w", err)
+		}
+
+		ent, err := decodeEntry(e)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, ent)
+	}
+}
+
+func decodeEntry(x interface{}) (*entry, error) {
+	arr, ok := x.([]interface{})
+	if !ok || len(arr) != 2 {
+		return nil, fmt.Errorf("invalid entry: %v", x)
+	}
+
+	t, err := eventTime(arr[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry{time: t, record: toMap(arr[1])}, nil
+}
+
+// eventTime converts time of the entry, which is seconds in integer or
+// EventTime. Within the event format of fluent-bit 2.x, it's [time, metadata].
+func eventTime(x interface{}) (time.Time, error) {
+	switch t := x.(type) {
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		return time.Unix(0, int64(t*float64(time.Second))), nil
+	case codec.RawExt:
+		return extTime(&t)
+	case *codec.RawExt:
+		return extTime(t)
+	case []interface{}:
+		if len(t) > 0 {
+			return eventTime(t[0])
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time type %T", x)
+}
+
+func extTime(ext *codec.RawExt) (time.Time, error) {
+	if ext.Tag != eventTimeExt || len(ext.Data) != 8 {
+		return time.Time{}, fmt.Errorf("invalid EventTime: ext type %d, %d bytes", ext.Tag, len(ext.Data))
+	}
+
+	return time.Unix(int64(binary.BigEndian.Uint32(ext.Data[:4])),
+		int64(binary.BigEndian.Uint32(ext.Data[4:]))), nil
+}
+
+func toMap(x interface{}) map[string]interface{} {
+	switch m := x.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			res[fmt.Sprintf("%v", k)] = v
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// The handshake on shared key:
+//
+//	server: ["HELO", {"nonce": nonce, "auth": "", "keepalive": true}]
+//	client: ["PING", hostname, salt, sha512_hex(salt+hostname+nonce+key), "", ""]
+//	server: ["PONG", true, "", hostname, sha512_hex(salt+hostname+nonce+key)]
+//
+// user authentication not supported.
+
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func helo(nonce string) []interface{} {
+	return []interface{}{"HELO", map[string]interface{}{
+		"nonce":     nonce,
+		"auth":      "",
+		"keepalive": true,
+	}}
+}
+
+func sharedKeyDigest(salt, hostname, nonce, key string) string {
+	h := sha512.New()
+	h.Write([]byte(salt + hostname + nonce + key))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pong checks the PING of the client, returns the PONG to reply and error if
+// authentication failed.
+func pong(ping []interface{}, nonce, key, hostname string) ([]interface{}, error) {
+	if len(ping) < 4 {
+		return []interface{}{"PONG", false, "invalid PING", "", ""}, fmt.Errorf("invalid PING: %d elements", len(ping))
+	}
+
+	if s, _ := ping[0].(string); s != "PING" {
+		return []interface{}{"PONG", false, "PING expected", "", ""}, fmt.Errorf("PING expected, got %v", ping[0])
+	}
+
+	clientHost, _ := ping[1].(string)
+	salt, _ := ping[2].(string)
+	digest, _ := ping[3].(string)
+
+	if digest != sharedKeyDigest(salt, clientHost, nonce, key) {
+		return []interface{}{"PONG", false, "shared_key mismatch", "", ""}, fmt.Errorf("shared_key mismatch from %s", clientHost)
+	}
+
+	return []interface{}{"PONG", true, "", hostname, sharedKeyDigest(salt, hostname, nonce, key)}, nil
+}
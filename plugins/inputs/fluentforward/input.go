@@ -0,0 +1,427 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package fluentforward serves the forward protocol of fluentd, so fluentd and
+// fluent-bit can forward logs to datakit.
+package fluentforward
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/ugorji/go/codec"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "fluent_forward"
+	catalog   = "log"
+
+	sampleCfg = `
+[[inputs.fluent_forward]]
+  # listen address, with protocol scheme and port
+  listen = "tcp://0.0.0.0:24224"
+
+  ## source of logs, if it's empty, use tag of the fluent event
+  source = ""
+
+  ## add service tag, if it's empty, use $source.
+  service = ""
+
+  ## pipeline script name, if it's empty, use $source.p
+  pipeline = ""
+
+  ## shared key of the handshake, same as shared_key of fluentd/fluent-bit.
+  ## No authentication if it's empty.
+  # shared_key = ""
+
+  ## hostname replied in the handshake, default to hostname of datakit
+  # self_hostname = ""
+
+  ## enable TLS if both set
+  # tls_cert = "/path/to/cert.pem"
+  # tls_key = "/path/to/key.pem"
+
+  ## route logs to source and pipeline by tag of the fluent event, the first
+  ## matched one used. match is the same as <match> of fluentd: '*' matches a
+  ## part of the tag and '**' matches zero or more parts.
+  # [[inputs.fluent_forward.route]]
+  #   match = "kube.var.log.containers.nginx-**"
+  #   source = "nginx"
+  #   pipeline = "nginx.p"
+
+  [inputs.fluent_forward.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+
+	// keys of the record used as message, in order.
+	keyLog     = "log"
+	keyMessage = "message"
+
+	// idle connections closed, fluent-bit reconnects on the next flush.
+	readTimeout = 10 * time.Minute
+)
+
+var (
+	l = logger.DefaultSLogger(inputName)
+	g = datakit.G("inputs_fluent_forward")
+)
+
+type Input struct {
+	Listen       string            `toml:"listen"`
+	Source       string            `toml:"source"`
+	Service      string            `toml:"service"`
+	Pipeline     string            `toml:"pipeline"`
+	SharedKey    string            `toml:"shared_key"`
+	SelfHostname string            `toml:"self_hostname"`
+	TLSCert      string            `toml:"tls_cert"`
+	TLSKey       string            `toml:"tls_key"`
+	Routes       []*route          `toml:"route"`
+	Tags         map[string]string `toml:"tags"`
+
+	feeder dkio.Feeder
+
+	mtx      sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+
+	semStop *cliutils.Sem // start stop signal
+}
+
+// Make sure Input implements the inputs.InputV2 interface.
+var _ inputs.InputV2 = &Input{}
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{&loggingMeasurement{}}
+}
+
+type loggingMeasurement struct{}
+
+func (*loggingMeasurement) LineProto() (*dkpt.Point, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+//nolint:lll
+func (*loggingMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "default",
+		Type: "logging",
+		Desc: "Using `source` of the matched route or the config file, or tag of the fluent event if not set.",
+		Tags: map[string]interface{}{
+			"fluent_tag": inputs.NewTagInfo("Tag of the fluent event."),
+			"service":    inputs.NewTagInfo("Service name, equal to `service` field in the config file."),
+		},
+		Fields: map[string]interface{}{
+			"message": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Field `log` or `message` of the record, or the whole record in JSON if both not found."},
+			"status":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Log status."},
+		},
+	}
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+
+	if ipt.SelfHostname == "" {
+		ipt.SelfHostname = datakit.DatakitHostName
+	}
+
+	for _, r := range ipt.Routes {
+		if err := r.init(); err != nil {
+			l.Errorf("route: %s", err)
+			return
+		}
+	}
+
+	listener, err := ipt.listen()
+	if err != nil {
+		l.Errorf("listen %s: %s", ipt.Listen, err)
+		return
+	}
+
+	ipt.mtx.Lock()
+	ipt.listener = listener
+	ipt.mtx.Unlock()
+
+	l.Infof("listening on %s", ipt.Listen)
+
+	g.Go(func(ctx context.Context) error {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return nil
+				}
+				l.Warnf("accept: %s", err)
+				continue
+			}
+
+			if !ipt.track(conn) {
+				_ = conn.Close()
+				return nil
+			}
+
+			go ipt.serve(conn)
+		}
+	})
+
+	select {
+	case <-datakit.Exit.Wait():
+		l.Info(inputName + " exit")
+	case <-ipt.semStop.Wait():
+		l.Info(inputName + " return")
+	}
+
+	ipt.exit()
+}
+
+func (ipt *Input) listen() (net.Listener, error) {
+	u, err := url.Parse(ipt.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(u.Scheme, u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	if ipt.TLSCert == "" || ipt.TLSKey == "" {
+		return listener, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(ipt.TLSCert, ipt.TLSKey)
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("load TLS cert: %w", err)
+	}
+
+	return tls.NewListener(listener, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// track records the connection to close on exit, false returned if exited.
+func (ipt *Input) track(conn net.Conn) bool {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	if ipt.conns == nil {
+		return false
+	}
+	ipt.conns[conn] = struct{}{}
+	return true
+}
+
+func (ipt *Input) untrack(conn net.Conn) {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	delete(ipt.conns, conn)
+	_ = conn.Close()
+}
+
+func (ipt *Input) exit() {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	if ipt.listener != nil {
+		_ = ipt.listener.Close()
+	}
+
+	for conn := range ipt.conns {
+		_ = conn.Close()
+	}
+	ipt.conns = nil
+}
+
+func (ipt *Input) serve(conn net.Conn) {
+	defer ipt.untrack(conn)
+
+	var (
+		remote = conn.RemoteAddr().String()
+		dec    = codec.NewDecoder(bufio.NewReader(conn), mh)
+		enc    = codec.NewEncoder(conn, mh)
+	)
+
+	if ipt.SharedKey != "" {
+		if err := ipt.handshake(conn, dec, enc); err != nil {
+			l.Warnf("handshake with %s: %s", remote, err)
+			return
+		}
+	}
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var arr []interface{}
+		if err := dec.Decode(&arr); err != nil {
+			if !errors.Is(err, io.EOF) {
+				l.Warnf("decode message from %s: %s", remote, err)
+			}
+			return
+		}
+
+		msg, err := decodeMessage(arr)
+		if err != nil {
+			l.Warnf("invalid message from %s: %s", remote, err)
+			return
+		}
+
+		ipt.feed(msg)
+
+		if chunk := msg.chunk(); chunk != "" {
+			if err := enc.Encode(map[string]interface{}{"ack": chunk}); err != nil {
+				l.Warnf("ack %s: %s", remote, err)
+				return
+			}
+		}
+	}
+}
+
+func (ipt *Input) handshake(conn net.Conn, dec *codec.Decoder, enc *codec.Encoder) error {
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	if err := enc.Encode(helo(nonce)); err != nil {
+		return fmt.Errorf("send HELO: %w", err)
+	}
+
+	var ping []interface{}
+	if err := dec.Decode(&ping); err != nil {
+		return fmt.Errorf("read PING: %w", err)
+	}
+
+	reply, authErr := pong(ping, nonce, ipt.SharedKey, ipt.SelfHostname)
+	if err := enc.Encode(reply); err != nil {
+		return fmt.Errorf("send PONG: %w", err)
+	}
+
+	return authErr
+}
+
+func (ipt *Input) feed(msg *message) {
+	source, pl := ipt.resolve(msg.tag)
+
+	service := ipt.Service
+	if service == "" {
+		service = source
+	}
+
+	pts := make([]*point.Point, 0, len(msg.entries))
+	for _, e := range msg.entries {
+		tags := map[string]string{
+			"fluent_tag": msg.tag,
+			"service":    service,
+		}
+		for k, v := range ipt.Tags {
+			tags[k] = v
+		}
+
+		opts := append(point.DefaultLoggingOptions(), point.WithTime(e.time))
+		pts = append(pts, point.NewPointV2([]byte(source),
+			append(point.NewTags(tags), point.NewKVs(recordFields(e.record))...), opts...))
+	}
+
+	if len(pts) == 0 {
+		return
+	}
+
+	if err := ipt.feeder.Feed(inputName+"/"+source, point.Logging, pts, &dkio.Option{
+		PlScript: map[string]string{source: pl},
+	}); err != nil {
+		l.Errorf("feed %d logs of %s: %s", len(pts), source, err)
+	}
+}
+
+// recordFields converts the record to fields, field log or message is the
+// message, nested values in JSON.
+func recordFields(record map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(record)+2)
+
+	for k, v := range record {
+		switch x := v.(type) {
+		case string, int64, uint64, float64, bool:
+			fields[k] = x
+		case nil:
+		default:
+			j, err := json.Marshal(x)
+			if err != nil {
+				fields[k] = fmt.Sprintf("%v", x)
+			} else {
+				fields[k] = string(j)
+			}
+		}
+	}
+
+	switch {
+	case fields[keyLog] != nil:
+		fields[pipeline.FieldMessage] = fields[keyLog]
+		delete(fields, keyLog)
+	case fields[keyMessage] != nil:
+	default:
+		j, err := json.Marshal(record)
+		if err != nil {
+			l.Warnf("json.Marshal: %s", err)
+		}
+		fields[pipeline.FieldMessage] = string(j)
+	}
+
+	if _, ok := fields[pipeline.FieldStatus]; !ok {
+		fields[pipeline.FieldStatus] = pipeline.DefaultStatus
+	}
+
+	return fields
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Listen:  "tcp://0.0.0.0:24224",
+		Tags:    map[string]string{},
+		feeder:  dkio.DefaultFeeder(),
+		conns:   map[net.Conn]struct{}{},
+		semStop: cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"net"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func evTime(t time.Time) *codec.RawExt {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(t.Nanosecond()))
+	return &codec.RawExt{Tag: eventTimeExt, Data: data}
+}
+
+func encode(t *T.T, v ...interface{}) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf, mh)
+	for _, x := range v {
+		require.NoError(t, enc.Encode(x))
+	}
+	return buf.Bytes()
+}
+
+func decode(t *T.T, data []byte) *message {
+	t.Helper()
+
+	var arr []interface{}
+	require.NoError(t, codec.NewDecoderBytes(data, mh).Decode(&arr))
+
+	msg, err := decodeMessage(arr)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestDecodeMessage(t *T.T) {
+	ts := time.Unix(1680000000, 123456789)
+	rec := map[string]interface{}{"log": "hello"}
+
+	t.Run("message", func(t *T.T) {
+		msg := decode(t, encode(t, []interface{}{"app", ts.Unix(), rec}))
+		assert.Equal(t, "app", msg.tag)
+		require.Len(t, msg.entries, 1)
+		assert.Equal(t, time.Unix(ts.Unix(), 0), msg.entries[0].time)
+		assert.Equal(t, "hello", msg.entries[0].record["log"])
+		assert.Empty(t, msg.chunk())
+	})
+
+	t.Run("forward-event-time", func(t *T.T) {
+		msg := decode(t, encode(t, []interface{}{"app", []interface{}{
+			[]interface{}{evTime(ts), rec},
+			[]interface{}{[]interface{}{evTime(ts), map[string]interface{}{}}, rec}, // fluent-bit 2.x
+		}, map[string]interface{}{"chunk": "abc"}}))
+
+		require.Len(t, msg.entries, 2)
+		assert.True(t, ts.Equal(msg.entries[0].time))
+		assert.True(t, ts.Equal(msg.entries[1].time))
+		assert.Equal(t, "abc", msg.chunk())
+	})
+
+	t.Run("packed-forward", func(t *T.T) {
+		packed := encode(t, []interface{}{evTime(ts), rec}, []interface{}{evTime(ts), rec})
+		msg := decode(t, encode(t, []interface{}{"app", packed, map[string]interface{}{"size": 2}}))
+
+		require.Len(t, msg.entries, 2)
+		assert.Equal(t, "hello", msg.entries[1].record["log"])
+	})
+
+	t.Run("compressed-packed-forward", func(t *T.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(encode(t, []interface{}{evTime(ts), rec}))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		msg := decode(t, encode(t, []interface{}{"app", buf.Bytes(), map[string]interface{}{"compressed": "gzip"}}))
+
+		require.Len(t, msg.entries, 1)
+		assert.True(t, ts.Equal(msg.entries[0].time))
+	})
+
+	t.Run("invalid", func(t *T.T) {
+		_, err := decodeMessage([]interface{}{"app"})
+		assert.Error(t, err)
+
+		_, err = decodeMessage([]interface{}{"app", "not-msgpack-entries"})
+		assert.Error(t, err)
+	})
+}
+
+func TestRecordFields(t *T.T) {
+	t.Run("log", func(t *T.T) {
+		fields := recordFields(map[string]interface{}{
+			"log":        "hello",
+			"stream":     "stdout",
+			"kubernetes": map[string]interface{}{"pod_name": "nginx"},
+		})
+
+		assert.Equal(t, map[string]interface{}{
+			"message":    "hello",
+			"stream":     "stdout",
+			"kubernetes": `{"pod_name":"nginx"}`,
+			"status":     "unknown",
+		}, fields)
+	})
+
+	t.Run("message", func(t *T.T) {
+		fields := recordFields(map[string]interface{}{"message": "hello", "status": "error"})
+		assert.Equal(t, map[string]interface{}{"message": "hello", "status": "error"}, fields)
+	})
+
+	t.Run("json", func(t *T.T) {
+		fields := recordFields(map[string]interface{}{"code": int64(200)})
+		assert.Equal(t, `{"code":200}`, fields["message"])
+		assert.Equal(t, int64(200), fields["code"])
+	})
+}
+
+func TestServe(t *T.T) {
+	ts := time.Unix(1680000000, 123456789)
+
+	newInput := func() (*Input, *dkio.MockedFeeder) {
+		feeder := dkio.NewMockedFeeder()
+		ipt := defaultInput()
+		ipt.feeder = feeder
+		ipt.SharedKey = "secret"
+		ipt.SelfHostname = "datakit"
+		return ipt, feeder
+	}
+
+	handshake := func(t *T.T, conn net.Conn, key string) []interface{} {
+		t.Helper()
+
+		dec := codec.NewDecoder(conn, mh)
+
+		var helo []interface{}
+		require.NoError(t, dec.Decode(&helo))
+		require.Equal(t, "HELO", helo[0])
+		nonce, _ := toMap(helo[1])["nonce"].(string)
+
+		_, err := conn.Write(encode(t, []interface{}{
+			"PING", "fluent-bit", "salt", sharedKeyDigest("salt", "fluent-bit", nonce, key), "", "",
+		}))
+		require.NoError(t, err)
+
+		var reply []interface{}
+		require.NoError(t, dec.Decode(&reply))
+		require.Equal(t, "PONG", reply[0])
+
+		if reply[1] == true {
+			assert.Equal(t, sharedKeyDigest("salt", "datakit", nonce, key), reply[4])
+		}
+		return reply
+	}
+
+	t.Run("ack", func(t *T.T) {
+		ipt, feeder := newInput()
+		ipt.Source = "nginx"
+
+		client, server := net.Pipe()
+		defer client.Close() //nolint:errcheck
+		go ipt.serve(server)
+
+		reply := handshake(t, client, "secret")
+		require.Equal(t, true, reply[1])
+
+		_, err := client.Write(encode(t, []interface{}{"kube.nginx", []interface{}{
+			[]interface{}{evTime(ts), map[string]interface{}{"log": "GET / 200"}},
+		}, map[string]interface{}{"chunk": "c1"}}))
+		require.NoError(t, err)
+
+		var ack map[string]interface{}
+		require.NoError(t, codec.NewDecoder(client, mh).Decode(&ack))
+		assert.Equal(t, "c1", ack["ack"])
+
+		pts := feeder.Points(point.Logging)
+		require.Len(t, pts, 1)
+
+		pt := pts[0]
+		assert.Equal(t, "nginx", string(pt.Name()))
+		assert.Equal(t, ts.UnixNano(), pt.Time().UnixNano())
+		assert.Equal(t, []byte("GET / 200"), pt.Get([]byte("message")))
+		assert.Equal(t, "kube.nginx", string(pt.GetTag([]byte("fluent_tag"))))
+		assert.Equal(t, "nginx", string(pt.GetTag([]byte("service"))))
+	})
+
+	t.Run("shared-key-mismatch", func(t *T.T) {
+		ipt, feeder := newInput()
+
+		client, server := net.Pipe()
+		defer client.Close() //nolint:errcheck
+		go ipt.serve(server)
+
+		reply := handshake(t, client, "wrong")
+		assert.Equal(t, false, reply[1])
+
+		// connection closed by server
+		_, err := client.Write(encode(t, []interface{}{"app", ts.Unix(), map[string]interface{}{"log": "x"}}))
+		assert.Error(t, err)
+		assert.Empty(t, feeder.Points(point.Logging))
+	})
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package fluentforward
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// route sets source and pipeline of logs by tag of the fluent event.
+type route struct {
+	// Match is the tag pattern same as <match> of fluentd: parts of tag are
+	// separated by '.', '*' matches a part and '**' matches zero or more
+	// parts. Patterns separated by whitespaces match if any matches.
+	Match    string `toml:"match"`
+	Source   string `toml:"source"`
+	Pipeline string `toml:"pipeline"`
+
+	patterns [][]string
+}
+
+func (r *route) init() error {
+	r.patterns = r.patterns[:0]
+
+	for _, p := range strings.Fields(r.Match) {
+		parts := strings.Split(p, ".")
+		for _, part := range parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return fmt.Errorf("invalid match %q: %w", p, err)
+			}
+		}
+		r.patterns = append(r.patterns, parts)
+	}
+
+	if len(r.patterns) == 0 {
+		return fmt.Errorf("match not set")
+	}
+
+	return nil
+}
+
+func (r *route) match(tag string) bool {
+	parts := strings.Split(tag, ".")
+	for _, p := range r.patterns {
+		if matchParts(p, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+
+	return matchParts(pattern[1:], parts[1:])
+}
+
+// resolve returns source and pipeline of logs with the tag, the first matched
+// route used, or source and pipeline of the input.
+func (ipt *Input) resolve(tag string) (source, pipeline string) {
+	source, pipeline = ipt.Source, ipt.Pipeline
+
+	for _, r := range ipt.Routes {
+		if !r.match(tag) {
+			continue
+		}
+
+		if r.Source != "" {
+			source = r.Source
+		}
+		if r.Pipeline != "" {
+			pipeline = r.Pipeline
+		}
+		break
+	}
+
+	if source == "" {
+		source = tag
+	}
+
+	return source, pipeline
+}
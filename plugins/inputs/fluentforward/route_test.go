@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package fluentforward
+
+import (
+	T "testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteMatch(t *T.T) {
+	cases := []struct {
+		match string
+		tag   string
+		ok    bool
+	}{
+		{"a.b", "a.b", true},
+		{"a.b", "a.b.c", false},
+		{"a.*", "a.b", true},
+		{"a.*", "a", false},
+		{"a.*", "a.b.c", false},
+		{"a.**", "a", true},
+		{"a.**", "a.b.c", true},
+		{"**.c", "a.b.c", true},
+		{"a.**.d", "a.d", true},
+		{"a.**.d", "a.b.c.d", true},
+		{"kube.*.nginx-*", "kube.prod.nginx-7d9f", true},
+		{"kube.*.nginx-*", "kube.prod.redis-0", false},
+		{"a.* b.**", "b.x.y", true},
+		{"**", "anything.at.all", true},
+	}
+
+	for _, tc := range cases {
+		r := &route{Match: tc.match}
+		require.NoError(t, r.init())
+		assert.Equal(t, tc.ok, r.match(tc.tag), "%q ~ %q", tc.match, tc.tag)
+	}
+
+	t.Run("invalid", func(t *T.T) {
+		assert.Error(t, (&route{Match: "a.[b"}).init())
+		assert.Error(t, (&route{Match: " "}).init())
+	})
+}
+
+func TestResolve(t *T.T) {
+	var ipt Input
+	_, err := toml.Decode(`
+pipeline = "default.p"
+
+[[route]]
+  match = "kube.**.nginx-**"
+  source = "nginx"
+  pipeline = "nginx.p"
+
+[[route]]
+  match = "app.**"
+  pipeline = "app.p"
+
+[[route]]
+  match = "app.**"
+  source = "never"
+`, &ipt)
+	require.NoError(t, err)
+	require.Len(t, ipt.Routes, 3)
+
+	for _, r := range ipt.Routes {
+		require.NoError(t, r.init())
+	}
+
+	source, pl := ipt.resolve("kube.var.log.nginx-0")
+	assert.Equal(t, "nginx", source)
+	assert.Equal(t, "nginx.p", pl)
+
+	// the first matched only, source is the tag if not set
+	source, pl = ipt.resolve("app.web")
+	assert.Equal(t, "app.web", source)
+	assert.Equal(t, "app.p", pl)
+
+	source, pl = ipt.resolve("other")
+	assert.Equal(t, "other", source)
+	assert.Equal(t, "default.p", pl)
+
+	ipt.Source = "fluent"
+	source, _ = ipt.resolve("other")
+	assert.Equal(t, "fluent", source)
+}
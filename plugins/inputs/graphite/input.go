@@ -0,0 +1,442 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package graphite receives metrics of graphite plaintext and pickle
+// protocol.
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "graphite"
+	catalog   = "graphite"
+
+	sampleCfg = `
+[[inputs.graphite]]
+  ## listen addresses of plaintext protocol, with protocol scheme(tcp or udp) and port
+  listen = ["tcp://0.0.0.0:2003", "udp://0.0.0.0:2003"]
+
+  ## listen address of pickle protocol, tcp only, disabled if empty
+  pickle_listen = "tcp://0.0.0.0:2004"
+
+  ## separator to join parts of measurement and field
+  separator = "_"
+
+  ## templates to map the metric path to measurement, field and tags, such as
+  ##   "servers.* .host.measurement.field*"
+  ## which maps servers.web01.cpu.load.shortterm to measurement cpu, field
+  ## load_shortterm and tag host=web01. Default is "measurement.field*".
+  ## See https://github.com/influxdata/telegraf/tree/master/docs/TEMPLATE_PATTERN.md
+  templates = [
+    # "servers.* .host.measurement.field*",
+  ]
+
+  ## max size of a plaintext line or a pickle message
+  max_message_size = 1048576
+
+  [inputs.graphite.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+
+	defaultField          = "value"
+	defaultMaxMessageSize = 1 << 20
+
+	feedBatch    = 1024
+	feedInterval = time.Second
+)
+
+var (
+	l = logger.DefaultSLogger(inputName)
+	g = datakit.G("inputs_graphite")
+)
+
+type Input struct {
+	Listen         []string          `toml:"listen"`
+	PickleListen   string            `toml:"pickle_listen"`
+	Separator      string            `toml:"separator"`
+	Templates      []string          `toml:"templates"`
+	MaxMessageSize int               `toml:"max_message_size"`
+	Tags           map[string]string `toml:"tags"`
+
+	parser    *graphite.GraphiteParser
+	parserMtx sync.Mutex // templates not safe for concurrent use
+
+	feeder dkio.Feeder
+	ch     chan *point.Point
+
+	mtx     sync.RWMutex
+	closers map[io.Closer]struct{} // listeners and connections
+	exited  bool
+
+	semStop *cliutils.Sem // start stop signal
+}
+
+// Make sure Input implements the inputs.InputV2 interface.
+var _ inputs.InputV2 = &Input{}
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement { return nil }
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		return
+	}
+
+	for _, addr := range ipt.Listen {
+		if err := ipt.listen(addr, false); err != nil {
+			l.Errorf("listen %s: %s", addr, err)
+			ipt.exit()
+			return
+		}
+	}
+
+	if ipt.PickleListen != "" {
+		if err := ipt.listen(ipt.PickleListen, true); err != nil {
+			l.Errorf("listen %s: %s", ipt.PickleListen, err)
+			ipt.exit()
+			return
+		}
+	}
+
+	g.Go(func(ctx context.Context) error {
+		ipt.feedLoop()
+		return nil
+	})
+
+	select {
+	case <-datakit.Exit.Wait():
+		l.Info(inputName + " exit")
+	case <-ipt.semStop.Wait():
+		l.Info(inputName + " return")
+	}
+
+	ipt.exit()
+}
+
+func (ipt *Input) setup() error {
+	if ipt.MaxMessageSize <= 0 {
+		ipt.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	p, err := newTemplateParser(ipt.Separator, ipt.Templates)
+	if err != nil {
+		return err
+	}
+	ipt.parser = p
+
+	return nil
+}
+
+func (ipt *Input) listen(addr string, pickle bool) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		listener, err := net.Listen(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		ipt.track(listener)
+
+		g.Go(func(ctx context.Context) error {
+			ipt.acceptLoop(listener, pickle)
+			return nil
+		})
+
+	case "udp", "udp4", "udp6":
+		if pickle {
+			return fmt.Errorf("pickle protocol over UDP not supported")
+		}
+
+		conn, err := net.ListenPacket(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		ipt.track(conn)
+
+		g.Go(func(ctx context.Context) error {
+			ipt.readPackets(conn)
+			return nil
+		})
+
+	default:
+		return fmt.Errorf("unsupported protocol %q, only tcp and udp supported", u.Scheme)
+	}
+
+	l.Infof("listening on %s", addr)
+	return nil
+}
+
+// track records the listener or connection to close on exit, false returned
+// if exited.
+func (ipt *Input) track(c io.Closer) bool {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	if ipt.exited {
+		return false
+	}
+	ipt.closers[c] = struct{}{}
+	return true
+}
+
+func (ipt *Input) untrack(c io.Closer) {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	delete(ipt.closers, c)
+	_ = c.Close()
+}
+
+func (ipt *Input) exit() {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	for c := range ipt.closers {
+		_ = c.Close()
+	}
+	ipt.closers = map[io.Closer]struct{}{}
+
+	if !ipt.exited {
+		ipt.exited = true
+		close(ipt.ch)
+	}
+}
+
+func (ipt *Input) acceptLoop(listener net.Listener, pickle bool) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.Warnf("accept: %s", err)
+			continue
+		}
+
+		if !ipt.track(conn) {
+			_ = conn.Close()
+			return
+		}
+
+		if pickle {
+			go ipt.servePickle(conn)
+		} else {
+			go ipt.servePlaintext(conn)
+		}
+	}
+}
+
+func (ipt *Input) servePlaintext(conn net.Conn) {
+	defer ipt.untrack(conn)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), ipt.MaxMessageSize)
+
+	for scanner.Scan() {
+		if !ipt.handleLine(scanner.Bytes()) {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		l.Warnf("read from %s: %s", conn.RemoteAddr(), err)
+	}
+}
+
+// servePickle reads messages of 4 bytes big-endian length and the pickled
+// payload.
+func (ipt *Input) servePickle(conn net.Conn) {
+	defer ipt.untrack(conn)
+
+	r := bufio.NewReader(conn)
+	remote := conn.RemoteAddr().String()
+
+	var head [4]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				l.Warnf("read from %s: %s", remote, err)
+			}
+			return
+		}
+
+		n := binary.BigEndian.Uint32(head[:])
+		if n > uint32(ipt.MaxMessageSize) {
+			l.Warnf("pickle message of %d bytes from %s exceeds max_message_size", n, remote)
+			return
+		}
+
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			l.Warnf("read from %s: %s", remote, err)
+			return
+		}
+
+		samples, err := parsePickle(data)
+		if err != nil {
+			l.Warnf("pickle from %s: %s", remote, err)
+		}
+
+		now := time.Now()
+		for _, s := range samples {
+			if !ipt.handle(s, now) {
+				return
+			}
+		}
+	}
+}
+
+func (ipt *Input) readPackets(conn net.PacketConn) {
+	buf := make([]byte, 64*1024)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.Warnf("read: %s", err)
+			}
+			return
+		}
+
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			if !ipt.handleLine(line) {
+				return
+			}
+		}
+	}
+}
+
+// handleLine parses the plaintext line and queues it to feed, false returned
+// if exited.
+func (ipt *Input) handleLine(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return true
+	}
+
+	s, err := parseLine(string(line))
+	if err != nil {
+		l.Debugf("parse: %s", err)
+		return true
+	}
+
+	return ipt.handle(s, time.Now())
+}
+
+// handle queues the sample to feed, false returned if exited.
+func (ipt *Input) handle(s *sample, now time.Time) bool {
+	pt, err := ipt.makePoint(s, now)
+	if err != nil {
+		l.Debugf("apply template on %s: %s", s.path, err)
+		return true
+	}
+
+	// ch closed on exit under the write lock
+	ipt.mtx.RLock()
+	defer ipt.mtx.RUnlock()
+
+	if ipt.exited {
+		return false
+	}
+
+	ipt.ch <- pt
+	return true
+}
+
+// feedLoop feeds points in batch, on each feed interval or the batch full.
+func (ipt *Input) feedLoop() {
+	tick := time.NewTicker(feedInterval)
+	defer tick.Stop()
+
+	var pts []*point.Point
+
+	flush := func() {
+		if len(pts) == 0 {
+			return
+		}
+
+		if err := ipt.feeder.Feed(inputName, point.Metric, pts, &dkio.Option{}); err != nil {
+			l.Errorf("feed %d points: %s", len(pts), err)
+		}
+		pts = nil
+	}
+
+	for {
+		select {
+		case pt, ok := <-ipt.ch:
+			if !ok {
+				flush()
+				return
+			}
+
+			pts = append(pts, pt)
+			if len(pts) >= feedBatch {
+				flush()
+			}
+
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Listen:         []string{"tcp://0.0.0.0:2003", "udp://0.0.0.0:2003"},
+		PickleListen:   "tcp://0.0.0.0:2004",
+		Separator:      "_",
+		MaxMessageSize: defaultMaxMessageSize,
+		Tags:           map[string]string{},
+		feeder:         dkio.DefaultFeeder(),
+		ch:             make(chan *point.Point, feedBatch),
+		closers:        map[io.Closer]struct{}{},
+		semStop:        cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
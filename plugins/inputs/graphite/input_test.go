@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package graphite
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func newTestInput(t *T.T) (*Input, *dkio.MockedFeeder) {
+	t.Helper()
+
+	feeder := dkio.NewMockedFeeder()
+	ipt := defaultInput()
+	ipt.feeder = feeder
+	require.NoError(t, ipt.setup())
+
+	go ipt.feedLoop()
+	return ipt, feeder
+}
+
+func TestPlaintext(t *T.T) {
+	ipt, feeder := newTestInput(t)
+	defer ipt.exit()
+
+	require.NoError(t, ipt.listen("tcp://127.0.0.1:0", false))
+	require.NoError(t, ipt.listen("udp://127.0.0.1:0", false))
+
+	var tcpAddr, udpAddr string
+	ipt.mtx.RLock()
+	for c := range ipt.closers {
+		switch x := c.(type) {
+		case net.Listener:
+			tcpAddr = x.Addr().String()
+		case net.PacketConn:
+			udpAddr = x.LocalAddr().String()
+		}
+	}
+	ipt.mtx.RUnlock()
+
+	conn, err := net.Dial("tcp", tcpAddr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("cpu.load 1 1600000000\ninvalid\r\nmem.used 2 1600000000\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	pts, err := feeder.NPoints(2, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, pts[0].Get([]byte("load")))
+	assert.Equal(t, 2.0, pts[1].Get([]byte("used")))
+	assert.Equal(t, int64(1600000000), pts[1].Time().Unix())
+
+	conn, err = net.Dial("udp", udpAddr)
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+	_, err = conn.Write([]byte("disk.free 3\ndisk.total 4\n"))
+	require.NoError(t, err)
+
+	pts, err = feeder.NPoints(2, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, pts[0].Get([]byte("free")))
+	assert.Equal(t, 4.0, pts[1].Get([]byte("total")))
+}
+
+func TestPickle(t *T.T) {
+	ipt, feeder := newTestInput(t)
+	defer ipt.exit()
+
+	ipt.MaxMessageSize = 256
+	require.NoError(t, ipt.listen("tcp://127.0.0.1:0", true))
+	assert.Error(t, ipt.listen("udp://127.0.0.1:0", true))
+
+	var addr string
+	ipt.mtx.RLock()
+	for c := range ipt.closers {
+		addr = c.(net.Listener).Addr().String()
+	}
+	ipt.mtx.RUnlock()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	payload := []byte("\x80\x02]q\x00(X\x16\x00\x00\x00servers.web01.cpu.loadq\x01J\x00\x10^_G?\xf8\x00\x00\x00\x00\x00\x00\x86q\x02\x86q\x03X\x0c\x00\x00\x00a.b;env=prodq\x04GA\xd7\xd7\x84\x00`\x00\x00K\x02\x86q\x05\x86q\x06e.")
+	head := make([]byte, 4)
+	binary.BigEndian.PutUint32(head, uint32(len(payload)))
+	_, err = conn.Write(append(head, payload...))
+	require.NoError(t, err)
+
+	pts, err := feeder.NPoints(2, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "servers", string(pts[0].Name()))
+	assert.Equal(t, 1.5, pts[0].Get([]byte("web01_cpu_load")))
+	assert.Equal(t, "prod", string(pts[1].GetTag([]byte("env"))))
+
+	// too large, the connection closed
+	binary.BigEndian.PutUint32(head, 1024)
+	_, err = conn.Write(head)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, os.ErrDeadlineExceeded))
+}
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package graphite
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+)
+
+// defaultTemplate uses the first part of the path as measurement and others
+// as field, same as statsd.
+const defaultTemplate = "measurement.field*"
+
+// sample of graphite metric.
+type sample struct {
+	path  string
+	value float64
+	ts    time.Time // zero for now
+}
+
+// parseLine parses the plaintext line:
+//
+//	<path> <value> [<timestamp>]
+func parseLine(line string) (*sample, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("invalid line %q", line)
+	}
+
+	v, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of %s: %w", fields[0], err)
+	}
+
+	s := &sample{path: fields[0], value: v}
+
+	if len(fields) == 3 {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp of %s: %w", fields[0], err)
+		}
+		s.ts = unixTime(ts)
+	}
+
+	return s, nil
+}
+
+// unixTime returns time of seconds, zero for -1 which means now in carbon.
+func unixTime(sec float64) time.Time {
+	if sec == -1 {
+		return time.Time{}
+	}
+
+	i, frac := math.Modf(sec)
+	return time.Unix(int64(i), int64(frac*float64(time.Second)))
+}
+
+// parsePickle parses metrics of carbon pickle protocol:
+//
+//	[(path, (timestamp, value)), ...]
+func parsePickle(data []byte) ([]*sample, error) {
+	v, err := unpickle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, ok := items(v)
+	if !ok {
+		return nil, fmt.Errorf("list expected, got %T", v)
+	}
+
+	var (
+		res  []*sample
+		errs []string
+	)
+
+	for _, m := range metrics {
+		s, err := pickledSample(m)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		res = append(res, s)
+	}
+
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%d invalid metrics: %s", len(errs), strings.Join(errs, "; "))
+	}
+
+	return res, nil
+}
+
+func pickledSample(m interface{}) (*sample, error) {
+	pair, ok := items(m)
+	if !ok || len(pair) != 2 {
+		return nil, fmt.Errorf("(path, (timestamp, value)) expected")
+	}
+
+	path, ok := pair[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("path of %T", pair[0])
+	}
+
+	tv, ok := items(pair[1])
+	if !ok || len(tv) != 2 {
+		return nil, fmt.Errorf("(timestamp, value) of %s expected", path)
+	}
+
+	ts, err := toFloat(tv[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp of %s: %w", path, err)
+	}
+
+	v, err := toFloat(tv[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of %s: %w", path, err)
+	}
+
+	return &sample{path: path, value: v, ts: unixTime(ts)}, nil
+}
+
+// items returns items of list or tuple.
+func items(v interface{}) ([]interface{}, bool) {
+	switch x := v.(type) {
+	case *list:
+		return x.items, true
+	case []interface{}:
+		return x, true
+	default:
+		return nil, false
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case string:
+		return strconv.ParseFloat(x, 64)
+	default:
+		return 0, fmt.Errorf("number expected, got %T", v)
+	}
+}
+
+// newTemplateParser returns the graphite parser to apply templates.
+func newTemplateParser(separator string, templates []string) (*graphite.GraphiteParser, error) {
+	if len(templates) == 0 {
+		templates = []string{defaultTemplate}
+	}
+	return graphite.NewGraphiteParser(separator, templates, nil)
+}
+
+// makePoint maps the path to measurement, field and tags by templates. Tags
+// of graphite tagged series(path;tag1=value1;tag2=value2) are also added.
+func (ipt *Input) makePoint(s *sample, now time.Time) (*point.Point, error) {
+	parts := strings.Split(s.path, ";")
+
+	ipt.parserMtx.Lock()
+	name, tags, field, err := ipt.parser.ApplyTemplate(parts[0])
+	ipt.parserMtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = parts[0]
+	}
+	if field == "" {
+		field = defaultField
+	}
+
+	for _, kv := range parts[1:] {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			tags[k] = v
+		}
+	}
+
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+
+	ts := s.ts
+	if ts.IsZero() {
+		ts = now
+	}
+
+	return point.NewPointV2([]byte(name),
+		append(point.NewTags(tags), point.NewKVs(map[string]interface{}{field: s.value})...),
+		append(point.DefaultMetricOptions(), point.WithTime(ts))...), nil
+}
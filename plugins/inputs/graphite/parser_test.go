@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package graphite
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *T.T) {
+	s, err := parseLine("servers.web01.cpu.load 1.5 1600000000.5")
+	require.NoError(t, err)
+	assert.Equal(t, "servers.web01.cpu.load", s.path)
+	assert.Equal(t, 1.5, s.value)
+	assert.Equal(t, int64(1600000000500000000), s.ts.UnixNano())
+
+	s, err = parseLine("a.b 2")
+	require.NoError(t, err)
+	assert.True(t, s.ts.IsZero())
+
+	s, err = parseLine("a.b 2 -1")
+	require.NoError(t, err)
+	assert.True(t, s.ts.IsZero())
+
+	for _, line := range []string{"a.b", "a.b x", "a.b 1 x", "a.b 1 2 3"} {
+		_, err := parseLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParsePickle(t *T.T) {
+	cases := []struct {
+		name   string
+		data   string
+		expect []*sample
+		fail   bool
+	}{
+		{
+			name: "protocol-0",
+			data: "(lp0\n(Vservers.web01.cpu.load\np1\n(I1600000000\nF1.5\ntp2\ntp3\na(Va.b;env=prod\np4\n(F1600000001.5\nI2\ntp5\ntp6\na.",
+			expect: []*sample{
+				{path: "servers.web01.cpu.load", value: 1.5, ts: time.Unix(1600000000, 0)},
+				{path: "a.b;env=prod", value: 2, ts: time.Unix(1600000001, 5e8)},
+			},
+		},
+		{
+			name: "protocol-0-python2",
+			data: "(lp0\n(S'a.b'\np1\n(L1600000000L\nI-3\ntp2\ntp3\na.",
+			expect: []*sample{
+				{path: "a.b", value: -3, ts: time.Unix(1600000000, 0)},
+			},
+		},
+		{
+			name: "protocol-2",
+			data: "\x80\x02]q\x00(X\x16\x00\x00\x00servers.web01.cpu.loadq\x01J\x00\x10^_G?\xf8\x00\x00\x00\x00\x00\x00\x86q\x02\x86q\x03X\x0c\x00\x00\x00a.b;env=prodq\x04GA\xd7\xd7\x84\x00`\x00\x00K\x02\x86q\x05\x86q\x06e.",
+			expect: []*sample{
+				{path: "servers.web01.cpu.load", value: 1.5, ts: time.Unix(1600000000, 0)},
+				{path: "a.b;env=prod", value: 2, ts: time.Unix(1600000001, 5e8)},
+			},
+		},
+		{
+			name: "protocol-4",
+			data: "\x80\x04\x95N\x00\x00\x00\x00\x00\x00\x00]\x94(\x8c\x16servers.web01.cpu.load\x94J\x00\x10^_G?\xf8\x00\x00\x00\x00\x00\x00\x86\x94\x86\x94\x8c\x0ca.b;env=prod\x94GA\xd7\xd7\x84\x00`\x00\x00K\x02\x86\x94\x86\x94e.",
+			expect: []*sample{
+				{path: "servers.web01.cpu.load", value: 1.5, ts: time.Unix(1600000000, 0)},
+				{path: "a.b;env=prod", value: 2, ts: time.Unix(1600000001, 5e8)},
+			},
+		},
+		{
+			name: "memo",
+			data: "\x80\x02]q\x00(X\x03\x00\x00\x00x.yq\x01J\x00\x10^_J\xd4\xfe\xff\xff\x86q\x02\x86q\x03h\x03h\x03e.",
+			expect: []*sample{
+				{path: "x.y", value: -300, ts: time.Unix(1600000000, 0)},
+				{path: "x.y", value: -300, ts: time.Unix(1600000000, 0)},
+				{path: "x.y", value: -300, ts: time.Unix(1600000000, 0)},
+			},
+		},
+		{
+			name: "long1",
+			data: "\x80\x02]q\x00X\x01\x00\x00\x00xq\x01J\x00\x10^_\x8a\x06\x00\x00\x00\x00\x00\xff\x86q\x02\x86q\x03a.",
+			expect: []*sample{
+				{path: "x", value: -(1 << 40), ts: time.Unix(1600000000, 0)},
+			},
+		},
+		{
+			name: "truncated",
+			data: "\x80\x02]q\x00X\x01\x00\x00",
+			fail: true,
+		},
+		{
+			name: "global-not-supported",
+			data: "cos\nsystem\n(S'id'\ntR.",
+			fail: true,
+		},
+		{
+			name: "not-list",
+			data: "K\x01.",
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			res, err := parsePickle([]byte(tc.data))
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, res, len(tc.expect))
+			for i := range res {
+				assert.Equal(t, tc.expect[i].path, res[i].path)
+				assert.Equal(t, tc.expect[i].value, res[i].value)
+				assert.True(t, tc.expect[i].ts.Equal(res[i].ts), "%s != %s", tc.expect[i].ts, res[i].ts)
+			}
+		})
+	}
+}
+
+func TestMakePoint(t *T.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		templates []string
+		path      string
+
+		measurement, field string
+		tags               map[string]string
+	}{
+		{
+			name:        "default",
+			path:        "servers.web01.cpu.load",
+			measurement: "servers",
+			field:       "web01_cpu_load",
+			tags:        map[string]string{"env": "test"},
+		},
+		{
+			name:        "single-part",
+			path:        "uptime",
+			measurement: "uptime",
+			field:       "value",
+			tags:        map[string]string{"env": "test"},
+		},
+		{
+			name:        "template",
+			templates:   []string{"servers.* .host.measurement.field*", "measurement*"},
+			path:        "servers.web01.cpu.load.shortterm",
+			measurement: "cpu",
+			field:       "load_shortterm",
+			tags:        map[string]string{"env": "test", "host": "web01"},
+		},
+		{
+			name:        "template-not-matched",
+			templates:   []string{"servers.* .host.measurement.field*", "measurement*"},
+			path:        "app.requests",
+			measurement: "app_requests",
+			field:       "value",
+			tags:        map[string]string{"env": "test"},
+		},
+		{
+			name:        "tagged-series",
+			path:        "disk.used;dc=sh;env=prod",
+			measurement: "disk",
+			field:       "used",
+			tags:        map[string]string{"env": "test", "dc": "sh"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *T.T) {
+			ipt := defaultInput()
+			ipt.Templates = tc.templates
+			ipt.Tags = map[string]string{"env": "test"}
+			require.NoError(t, ipt.setup())
+
+			pt, err := ipt.makePoint(&sample{path: tc.path, value: 42}, now)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.measurement, string(pt.Name()))
+			assert.Equal(t, 42.0, pt.Get([]byte(tc.field)))
+			assert.Equal(t, now.UnixNano(), pt.Time().UnixNano())
+
+			tags := map[string]string{}
+			for _, tag := range pt.Tags() {
+				tags[string(tag.Key)] = string(tag.GetD())
+			}
+			assert.Equal(t, tc.tags, tags)
+		})
+	}
+}
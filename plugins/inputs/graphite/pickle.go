@@ -0,0 +1,484 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Opcodes of pickle used by carbon senders, objects other than list, tuple,
+// string and number are not supported, so no code can be executed.
+const (
+	opMark           = '('
+	opStop           = '.'
+	opNone           = 'N'
+	opInt            = 'I'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opBinInt2        = 'M'
+	opLong           = 'L'
+	opFloat          = 'F'
+	opBinFloat       = 'G'
+	opString         = 'S'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	opUnicode        = 'V'
+	opBinUnicode     = 'X'
+	opEmptyList      = ']'
+	opList           = 'l'
+	opAppend         = 'a'
+	opAppends        = 'e'
+	opEmptyTuple     = ')'
+	opTuple          = 't'
+	opPut            = 'p'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opGet            = 'g'
+	opBinGet         = 'h'
+	opLongBinGet     = 'j'
+
+	// protocol 2+
+	opProto           = 0x80
+	opTuple1          = 0x85
+	opTuple2          = 0x86
+	opTuple3          = 0x87
+	opNewTrue         = 0x88
+	opNewFalse        = 0x89
+	opLong1           = 0x8a
+	opShortBinUnicode = 0x8c
+	opBinUnicode8     = 0x8d
+	opMemoize         = 0x94
+	opFrame           = 0x95
+)
+
+var errPickleMark = errors.New("mark not found")
+
+// list is mutable and may be referenced in memo.
+type list struct {
+	items []interface{}
+}
+
+type mark struct{}
+
+type unpickler struct {
+	data  []byte
+	stack []interface{}
+	memo  map[int]interface{}
+}
+
+// unpickle decodes the pickle data with list, tuple([]interface{}), string,
+// int64, float64, bool and nil.
+func unpickle(data []byte) (interface{}, error) {
+	u := &unpickler{
+		data: data,
+		memo: map[int]interface{}{},
+	}
+
+	for {
+		op, err := u.readByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		if op == opStop {
+			v, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+
+		if err := u.exec(op); err != nil {
+			return nil, fmt.Errorf("opcode 0x%02x: %w", op, err)
+		}
+	}
+}
+
+//nolint:gocyclo
+func (u *unpickler) exec(op byte) error {
+	switch op {
+	case opProto:
+		_, err := u.readByte()
+		return err
+
+	case opFrame:
+		_, err := u.read(8)
+		return err
+
+	case opMark:
+		u.push(mark{})
+	case opNone:
+		u.push(nil)
+	case opNewTrue:
+		u.push(true)
+	case opNewFalse:
+		u.push(false)
+
+	case opInt:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "00":
+			u.push(false)
+		case "01":
+			u.push(true)
+		default:
+			n, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return err
+			}
+			u.push(n)
+		}
+
+	case opLong:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseInt(strings.TrimSuffix(line, "L"), 10, 64)
+		if err != nil {
+			return err
+		}
+		u.push(n)
+
+	case opBinInt:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		u.push(int64(int32(binary.LittleEndian.Uint32(b))))
+
+	case opBinInt1:
+		b, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		u.push(int64(b))
+
+	case opBinInt2:
+		b, err := u.read(2)
+		if err != nil {
+			return err
+		}
+		u.push(int64(binary.LittleEndian.Uint16(b)))
+
+	case opLong1:
+		n, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		if n > 8 {
+			return fmt.Errorf("long of %d bytes overflows int64", n)
+		}
+		b, err := u.read(int(n))
+		if err != nil {
+			return err
+		}
+		var v int64
+		for i := len(b) - 1; i >= 0; i-- {
+			v = v<<8 | int64(b[i])
+		}
+		if n > 0 && n < 8 && b[n-1]&0x80 != 0 { // negative in two's complement
+			v -= 1 << (8 * n)
+		}
+		u.push(v)
+
+	case opFloat:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return err
+		}
+		u.push(f)
+
+	case opBinFloat:
+		b, err := u.read(8)
+		if err != nil {
+			return err
+		}
+		u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+
+	case opString:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		s, err := unquote(line)
+		if err != nil {
+			return err
+		}
+		u.push(s)
+
+	case opUnicode:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		u.push(line)
+
+	case opShortBinString, opShortBinBytes, opShortBinUnicode:
+		n, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		return u.pushString(int(n))
+
+	case opBinString, opBinBytes, opBinUnicode:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		return u.pushString(int(binary.LittleEndian.Uint32(b)))
+
+	case opBinUnicode8:
+		b, err := u.read(8)
+		if err != nil {
+			return err
+		}
+		n := binary.LittleEndian.Uint64(b)
+		if n > math.MaxInt32 {
+			return fmt.Errorf("string too long")
+		}
+		return u.pushString(int(n))
+
+	case opEmptyList:
+		u.push(&list{})
+
+	case opList:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(&list{items: items})
+
+	case opAppend:
+		v, err := u.pop()
+		if err != nil {
+			return err
+		}
+		l, err := u.topList()
+		if err != nil {
+			return err
+		}
+		l.items = append(l.items, v)
+
+	case opAppends:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		l, err := u.topList()
+		if err != nil {
+			return err
+		}
+		l.items = append(l.items, items...)
+
+	case opEmptyTuple:
+		u.push([]interface{}{})
+
+	case opTuple:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(items)
+
+	case opTuple1, opTuple2, opTuple3:
+		n := int(op-opTuple1) + 1
+		if len(u.stack) < n {
+			return fmt.Errorf("stack underflow")
+		}
+		items := make([]interface{}, n)
+		copy(items, u.stack[len(u.stack)-n:])
+		u.stack = u.stack[:len(u.stack)-n]
+		u.push(items)
+
+	case opPut:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		idx, err := strconv.Atoi(line)
+		if err != nil {
+			return err
+		}
+		return u.put(idx)
+
+	case opBinPut:
+		b, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		return u.put(int(b))
+
+	case opLongBinPut:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		return u.put(int(binary.LittleEndian.Uint32(b)))
+
+	case opMemoize:
+		return u.put(len(u.memo))
+
+	case opGet:
+		line, err := u.line()
+		if err != nil {
+			return err
+		}
+		idx, err := strconv.Atoi(line)
+		if err != nil {
+			return err
+		}
+		return u.get(idx)
+
+	case opBinGet:
+		b, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		return u.get(int(b))
+
+	case opLongBinGet:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		return u.get(int(binary.LittleEndian.Uint32(b)))
+
+	default:
+		return fmt.Errorf("unsupported")
+	}
+
+	return nil
+}
+
+func (u *unpickler) readByte() (byte, error) {
+	if len(u.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	b := u.data[0]
+	u.data = u.data[1:]
+	return b, nil
+}
+
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n > len(u.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := u.data[:n]
+	u.data = u.data[n:]
+	return b, nil
+}
+
+func (u *unpickler) line() (string, error) {
+	i := bytes.IndexByte(u.data, '\n')
+	if i < 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+
+	line := u.data[:i]
+	u.data = u.data[i+1:]
+	return string(bytes.TrimSuffix(line, []byte("\r"))), nil
+}
+
+func (u *unpickler) pushString(n int) error {
+	b, err := u.read(n)
+	if err != nil {
+		return err
+	}
+	u.push(string(b))
+	return nil
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+	if _, ok := v.(mark); ok {
+		return nil, fmt.Errorf("unexpected mark")
+	}
+	return v, nil
+}
+
+// popMark pops items after the last mark, and the mark.
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(mark); ok {
+			items := make([]interface{}, len(u.stack)-i-1)
+			copy(items, u.stack[i+1:])
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errPickleMark
+}
+
+func (u *unpickler) topList() (*list, error) {
+	if len(u.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+
+	l, ok := u.stack[len(u.stack)-1].(*list)
+	if !ok {
+		return nil, fmt.Errorf("append to %T", u.stack[len(u.stack)-1])
+	}
+	return l, nil
+}
+
+func (u *unpickler) put(idx int) error {
+	if len(u.stack) == 0 {
+		return fmt.Errorf("stack underflow")
+	}
+	u.memo[idx] = u.stack[len(u.stack)-1]
+	return nil
+}
+
+func (u *unpickler) get(idx int) error {
+	v, ok := u.memo[idx]
+	if !ok {
+		return fmt.Errorf("memo %d not found", idx)
+	}
+	u.push(v)
+	return nil
+}
+
+// unquote unquotes repr of python string such as 'abc' or "abc".
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] || (s[0] != '\'' && s[0] != '"') {
+		return "", fmt.Errorf("invalid string %q", s)
+	}
+
+	if s[0] == '\'' {
+		s = strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`)
+		s = `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+
+	return strconv.Unquote(s)
+}
@@ -35,6 +35,10 @@ only_physical_device = false
 # Ignore the disk which space is zero
 ignore_zero_bytes_disk = true
 
+# Inventory GPU, NIC, FPGA and other accelerators on PCI bus, with their models,
+# drivers and firmware versions(Linux only)
+enable_pci_inventory = false
+
 # Disable cloud provider information synchronization
 disable_cloud_provider_sync = false
 
@@ -11,6 +11,7 @@ import (
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -38,6 +39,39 @@ var (
 	NetSendBytesPerSec     int64
 )
 
+var (
+	extraFieldsMtx sync.Mutex
+	extraFields    = map[string]map[string]interface{}{}
+)
+
+// SetExtraFields sets fields appended to the host object by other inputs,
+// the fields of the same source are replaced, and nil fields removes them.
+func SetExtraFields(source string, fields map[string]interface{}) {
+	extraFieldsMtx.Lock()
+	defer extraFieldsMtx.Unlock()
+
+	if fields == nil {
+		delete(extraFields, source)
+		return
+	}
+
+	extraFields[source] = fields
+}
+
+func getExtraFields() map[string]interface{} {
+	extraFieldsMtx.Lock()
+	defer extraFieldsMtx.Unlock()
+
+	res := map[string]interface{}{}
+	for _, fields := range extraFields {
+		for k, v := range fields {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
 type (
 	HostMetaInfo struct {
 		HostName        string `json:"host_name"`
@@ -94,6 +128,7 @@ type (
 		Conntrack              *conntrackutil.Info    `json:"conntrack"`
 		FileFd                 *filefdutil.Info       `json:"filefd"`
 		Election               *election.ElectionInfo `json:"election"`
+		PCI                    []*PCIDeviceInfo       `json:"pci,omitempty"`
 		cpuPercent             float64
 		load5                  float64
 		cloudInfo              map[string]interface{}
@@ -397,6 +432,14 @@ func (ipt *Input) getHostObjectMessage() (*HostObjectMessage, error) {
 	l.Debugf("get election info...")
 	election := ipt.getElectionInfo()
 
+	var pci []*PCIDeviceInfo
+	if ipt.EnablePCIInventory {
+		l.Debugf("get PCI devices...")
+		if pci, err = getPCIDevices(); err != nil {
+			l.Warnf("getPCIDevices(): %s, ignored", err)
+		}
+	}
+
 	var diskUsedPercent float64 = 0
 	diskUsed := atomic.LoadUint64(&DiskUsed)
 	diskFree := atomic.LoadUint64(&DiskFree)
@@ -415,6 +458,7 @@ func (ipt *Input) getHostObjectMessage() (*HostObjectMessage, error) {
 		Conntrack:              conntrack,
 		FileFd:                 fileFd,
 		Election:               election,
+		PCI:                    pci,
 		diskUsedPercent:        diskUsedPercent,
 		diskIOReadBytesPerSec:  atomic.LoadInt64(&DiskIOReadBytesPerSec),
 		diskIOWriteBytesPerSec: atomic.LoadInt64(&DiskIOWriteBytesPerSec),
@@ -50,6 +50,8 @@ type Input struct {
 	ExtraDevice                []string `toml:"extra_device"`
 	ExcludeDevice              []string `toml:"exclude_device"`
 
+	EnablePCIInventory bool `toml:"enable_pci_inventory"`
+
 	DisableCloudProviderSync bool              `toml:"disable_cloud_provider_sync"`
 	CloudInfo                map[string]string `toml:"cloud_info,omitempty"`
 	lastSync                 time.Time
@@ -130,6 +132,15 @@ func (ipt *Input) ReadEnv(envs map[string]string) {
 		}
 	}
 
+	if enable, ok := envs["ENV_INPUT_HOSTOBJECT_ENABLE_PCI_INVENTORY"]; ok {
+		b, err := strconv.ParseBool(enable)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_HOSTOBJECT_ENABLE_PCI_INVENTORY to bool: %s, ignore", err)
+		} else {
+			ipt.EnablePCIInventory = b
+		}
+	}
+
 	if _, ok := envs["ENV_INPUT_HOSTOBJECT_ONLY_PHYSICAL_DEVICE"]; ok {
 		l.Info("setup OnlyPhysicalDevice...")
 		ipt.OnlyPhysicalDevice = true
@@ -187,19 +198,39 @@ func (hm *hostMeasurement) Info() *inputs.MeasurementInfo {
 			"os": &inputs.TagInfo{Desc: "Host OS type"},
 		},
 		Fields: map[string]interface{}{
-			"message":                    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Summary of all host information"},
-			"start_time":                 &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Host startup time (Unix timestamp)"},
-			"datakit_ver":                &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "collector version"},
-			"cpu_usage":                  &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "CPU usage"},
-			"mem_used_percent":           &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "memory usage"},
-			"load":                       &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.UnknownUnit, Desc: "system load"},
-			"state":                      &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Host Status"},
-			"disk_used_percent":          &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "disk usage"},
-			"diskio_read_bytes_per_sec":  &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "disk read rate"},
-			"diskio_write_bytes_per_sec": &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "disk write rate"},
-			"net_recv_bytes_per_sec":     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "network receive rate"},
-			"net_send_bytes_per_sec":     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "network send rate"},
-			"logging_level":              &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "log level"},
+			"message":                       &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Summary of all host information"},
+			"start_time":                    &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Host startup time (Unix timestamp)"},
+			"datakit_ver":                   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "collector version"},
+			"cpu_usage":                     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "CPU usage"},
+			"mem_used_percent":              &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "memory usage"},
+			"load":                          &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.UnknownUnit, Desc: "system load"},
+			"state":                         &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Host Status"},
+			"disk_used_percent":             &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Float, Unit: inputs.Percent, Desc: "disk usage"},
+			"diskio_read_bytes_per_sec":     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "disk read rate"},
+			"diskio_write_bytes_per_sec":    &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "disk write rate"},
+			"net_recv_bytes_per_sec":        &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "network receive rate"},
+			"net_send_bytes_per_sec":        &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.BytesPerSec, Desc: "network send rate"},
+			"logging_level":                 &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "log level"},
+			"os_pending_updates":            &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Pending OS updates, only if input `osupdate` enabled"},
+			"os_pending_security_updates":   &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Pending OS security updates, only if input `osupdate` enabled"},
+			"os_reboot_required":            &inputs.FieldInfo{DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "Whether reboot required to finish the installed updates, only if input `osupdate` enabled"},
+			"os_last_patch_time":            &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.TimestampSec, Desc: "Time of the last package installed or updated, only if input `osupdate` enabled"},
+			"gpu_count":                     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Count of GPUs, only if `enable_pci_inventory` enabled"},
+			"gpu_models":                    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Models of GPUs, separated by comma"},
+			"gpu_drivers":                   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Drivers and their versions of GPUs, separated by comma"},
+			"gpu_firmware_versions":         &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Firmware versions of GPUs, separated by comma"},
+			"nic_count":                     &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Count of NICs, only if `enable_pci_inventory` enabled"},
+			"nic_models":                    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Models of NICs, separated by comma"},
+			"nic_drivers":                   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Drivers and their versions of NICs, separated by comma"},
+			"nic_firmware_versions":         &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Firmware versions of NICs, separated by comma"},
+			"fpga_count":                    &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Count of FPGAs, only if `enable_pci_inventory` enabled"},
+			"fpga_models":                   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Models of FPGAs, separated by comma"},
+			"fpga_drivers":                  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Drivers and their versions of FPGAs, separated by comma"},
+			"fpga_firmware_versions":        &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Firmware versions of FPGAs, separated by comma"},
+			"accelerator_count":             &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Count of other accelerators, only if `enable_pci_inventory` enabled"},
+			"accelerator_models":            &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Models of other accelerators, separated by comma"},
+			"accelerator_drivers":           &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Drivers and their versions of other accelerators, separated by comma"},
+			"accelerator_firmware_versions": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Firmware versions of other accelerators, separated by comma"},
 		},
 	}
 }
@@ -260,6 +291,18 @@ func (ipt *Input) doCollect() error {
 		},
 	}
 
+	// append PCI device summaries
+	for k, v := range pciFields(message.Host.PCI) {
+		ipt.collectData.fields[k] = v
+	}
+
+	// append fields from other inputs, existing fields not overridden
+	for k, v := range getExtraFields() {
+		if _, ok := ipt.collectData.fields[k]; !ok {
+			ipt.collectData.fields[k] = v
+		}
+	}
+
 	if !ipt.isTestMode {
 		ipt.collectData.fields["Scheck"] = message.Collectors[0].Version
 	}
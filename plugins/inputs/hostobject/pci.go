@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package hostobject
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of PCI devices inventoried.
+const (
+	PCIKindGPU         = "gpu"
+	PCIKindNIC         = "nic"
+	PCIKindFPGA        = "fpga"
+	PCIKindAccelerator = "accelerator"
+)
+
+var pciKinds = []string{PCIKindGPU, PCIKindNIC, PCIKindFPGA, PCIKindAccelerator}
+
+// PCIDeviceInfo is a GPU, NIC, FPGA or other accelerator on PCI bus.
+type PCIDeviceInfo struct {
+	Slot            string   `json:"slot"` // such as 0000:3b:00.0
+	Kind            string   `json:"kind"`
+	Class           string   `json:"class"`
+	VendorID        string   `json:"vendor_id"`
+	DeviceID        string   `json:"device_id"`
+	Vendor          string   `json:"vendor,omitempty"`
+	Model           string   `json:"model"`
+	Driver          string   `json:"driver,omitempty"`
+	DriverVersion   string   `json:"driver_version,omitempty"`
+	FirmwareVersion string   `json:"firmware_version,omitempty"`
+	Interfaces      []string `json:"interfaces,omitempty"` // network interfaces of NIC
+}
+
+// vendors of FPGA cards: Xilinx and Altera(Intel).
+var fpgaVendors = map[string]bool{
+	"10ee": true,
+	"1172": true,
+}
+
+// pciKind returns kind of the device by its class code(such as 0x030000)
+// and vendor ID, empty if not inventoried.
+func pciKind(class, vendor string) string {
+	class = strings.TrimPrefix(strings.ToLower(class), "0x")
+	vendor = strings.TrimPrefix(strings.ToLower(vendor), "0x")
+
+	if len(class) < 2 {
+		return ""
+	}
+
+	switch class[:2] {
+	case "03": // display controller
+		return PCIKindGPU
+	case "02": // network controller
+		return PCIKindNIC
+	case "12": // processing accelerator
+		if fpgaVendors[vendor] {
+			return PCIKindFPGA
+		}
+		return PCIKindAccelerator
+	}
+
+	// FPGA cards may report class of 0xff(unassigned) or others
+	if fpgaVendors[vendor] {
+		return PCIKindFPGA
+	}
+
+	return ""
+}
+
+// pciFields summarizes devices as object fields of each kind, values of the
+// same kind are deduplicated and joined by comma, such as:
+//
+//	nic_count: 2
+//	nic_models: MT2892 Family [ConnectX-6 Dx]
+//	nic_drivers: mlx5_core 5.8-1.0.1
+//	nic_firmware_versions: 22.31.1014
+func pciFields(devs []*PCIDeviceInfo) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	for _, kind := range pciKinds {
+		var (
+			count                   int
+			models, drivers, fwvers []string
+		)
+
+		for _, d := range devs {
+			if d.Kind != kind {
+				continue
+			}
+
+			count++
+			models = append(models, d.Model)
+			if d.Driver != "" {
+				drivers = append(drivers, strings.TrimSpace(d.Driver+" "+d.DriverVersion))
+			}
+			if d.FirmwareVersion != "" {
+				fwvers = append(fwvers, d.FirmwareVersion)
+			}
+		}
+
+		if count == 0 {
+			continue
+		}
+
+		fields[kind+"_count"] = count
+		fields[kind+"_models"] = joinUniq(models)
+		fields[kind+"_drivers"] = joinUniq(drivers)
+		fields[kind+"_firmware_versions"] = joinUniq(fwvers)
+	}
+
+	return fields
+}
+
+func joinUniq(arr []string) string {
+	uniq := map[string]bool{}
+	var res []string
+	for _, s := range arr {
+		if !uniq[s] {
+			uniq[s] = true
+			res = append(res, s)
+		}
+	}
+
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+// pciIDs is names of vendors and devices of the pci.ids database.
+type pciIDs struct {
+	vendors map[string]string // vendor ID -> name
+	devices map[string]string // vendor ID + device ID -> name
+}
+
+var (
+	pciIDsPaths = []string{
+		"/usr/share/hwdata/pci.ids",
+		"/usr/share/misc/pci.ids",
+		"/usr/share/pci.ids",
+	}
+
+	pciIDsOnce sync.Once
+	pciIDsDB   *pciIDs
+)
+
+func getPCIIDs() *pciIDs {
+	pciIDsOnce.Do(func() {
+		for _, p := range pciIDsPaths {
+			f, err := os.Open(p) //nolint:gosec
+			if err != nil {
+				continue
+			}
+
+			pciIDsDB = parsePCIIDs(f)
+			_ = f.Close()
+
+			l.Debugf("load %d PCI vendors from %s", len(pciIDsDB.vendors), p)
+			return
+		}
+
+		l.Debugf("pci.ids not found, PCI device models shown as IDs")
+		pciIDsDB = &pciIDs{vendors: map[string]string{}, devices: map[string]string{}}
+	})
+
+	return pciIDsDB
+}
+
+// parsePCIIDs parses pci.ids, see https://pci-ids.ucw.cz/.
+func parsePCIIDs(r io.Reader) *pciIDs {
+	ids := &pciIDs{vendors: map[string]string{}, devices: map[string]string{}}
+
+	var vendor string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "C "): // device classes follow vendors
+			return ids
+
+		case strings.HasPrefix(line, "\t\t"): // subsystem
+			continue
+
+		case line[0] == '\t':
+			if id, name, ok := splitPCIID(line[1:]); ok && vendor != "" {
+				ids.devices[vendor+id] = name
+			}
+
+		default:
+			if id, name, ok := splitPCIID(line); ok {
+				vendor = id
+				ids.vendors[id] = name
+			}
+		}
+	}
+
+	return ids
+}
+
+func splitPCIID(s string) (string, string, bool) {
+	if len(s) < 6 || s[4] != ' ' {
+		return "", "", false
+	}
+	return strings.ToLower(s[:4]), strings.TrimSpace(s[5:]), true
+}
+
+// name sets vendor and model name of the device, IDs are used if not found.
+func (ids *pciIDs) name(d *PCIDeviceInfo) {
+	d.Vendor = ids.vendors[d.VendorID]
+	d.Model = ids.devices[d.VendorID+d.DeviceID]
+	if d.Model == "" {
+		d.Model = d.VendorID + ":" + d.DeviceID
+	}
+}
@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build linux
+// +build linux
+
+package hostobject
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	pciDevicesDir   = "/sys/bus/pci/devices"
+	sysModuleDir    = "/sys/module"
+	nvidiaGPUsDir   = "/proc/driver/nvidia/gpus"
+	ethtoolDrvInfoF = ethtoolDrvInfo
+)
+
+// firmware version attributes exported by drivers, such as vbios_version of amdgpu.
+var pciFirmwareAttrs = []string{"vbios_version", "fw_version", "firmware_version"}
+
+// getPCIDevices lists GPU, NIC, FPGA and other accelerators under sysfs.
+func getPCIDevices() ([]*PCIDeviceInfo, error) {
+	entries, err := os.ReadDir(pciDevicesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := getPCIIDs()
+
+	var devs []*PCIDeviceInfo
+	for _, e := range entries {
+		dir := filepath.Join(pciDevicesDir, e.Name())
+
+		d := &PCIDeviceInfo{
+			Slot:     e.Name(),
+			Class:    readSysfs(dir, "class"),
+			VendorID: strings.TrimPrefix(readSysfs(dir, "vendor"), "0x"),
+			DeviceID: strings.TrimPrefix(readSysfs(dir, "device"), "0x"),
+		}
+
+		if d.Kind = pciKind(d.Class, d.VendorID); d.Kind == "" {
+			continue
+		}
+
+		ids.name(d)
+
+		if link, err := os.Readlink(filepath.Join(dir, "driver")); err == nil {
+			d.Driver = filepath.Base(link)
+			d.DriverVersion = readSysfs(filepath.Join(sysModuleDir, d.Driver), "version")
+		}
+
+		for _, attr := range pciFirmwareAttrs {
+			if v := readSysfs(dir, attr); v != "" {
+				d.FirmwareVersion = v
+				break
+			}
+		}
+
+		switch d.Kind {
+		case PCIKindNIC:
+			d.Interfaces = pciNetInterfaces(dir)
+			for _, iface := range d.Interfaces {
+				drvVer, fwVer, err := ethtoolDrvInfoF(iface)
+				if err != nil {
+					l.Debugf("ethtool drvinfo of %s: %s, ignored", iface, err)
+					continue
+				}
+
+				if d.DriverVersion == "" {
+					d.DriverVersion = drvVer
+				}
+				if d.FirmwareVersion == "" {
+					d.FirmwareVersion = fwVer
+				}
+				break
+			}
+
+		case PCIKindGPU:
+			if d.FirmwareVersion == "" {
+				d.FirmwareVersion = nvidiaVBIOSVersion(d.Slot)
+			}
+		}
+
+		devs = append(devs, d)
+	}
+
+	return devs, nil
+}
+
+func readSysfs(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// pciNetInterfaces returns network interfaces of the NIC, they are under
+// net/ of the device, or under its virtio sub-device.
+func pciNetInterfaces(dir string) []string {
+	var res []string
+	for _, pattern := range []string{"net/*", "*/net/*"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, m := range matches {
+			res = append(res, filepath.Base(m))
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// ethtoolDrvInfo gets driver and firmware version of the interface, like `ethtool -i`.
+func ethtoolDrvInfo(iface string) (string, string, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return "", "", err
+	}
+	defer unix.Close(fd) //nolint:errcheck
+
+	info, err := unix.IoctlGetEthtoolDrvinfo(fd, iface)
+	if err != nil {
+		return "", "", err
+	}
+
+	fwVer := unix.ByteSliceToString(info.Fw_version[:])
+	if fwVer == "N/A" {
+		fwVer = ""
+	}
+
+	return unix.ByteSliceToString(info.Version[:]), fwVer, nil
+}
+
+// nvidiaVBIOSVersion reads VBIOS version of the NVIDIA GPU on slot, such as
+//
+//	Video BIOS: 	 90.04.96.00.01
+func nvidiaVBIOSVersion(slot string) string {
+	f, err := os.Open(filepath.Join(nvidiaGPUsDir, slot, "information")) //nolint:gosec
+	if err != nil {
+		return ""
+	}
+	defer f.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if k, v, ok := strings.Cut(scanner.Text(), ":"); ok && strings.TrimSpace(k) == "Video BIOS" {
+			if v = strings.TrimSpace(v); v != "??.??.??.??.??" {
+				return v
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build linux
+// +build linux
+
+package hostobject
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPCIDevices(t *testing.T) {
+	root := t.TempDir()
+
+	write := func(path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content+"\n"), 0o600))
+	}
+
+	device := func(slot, class, vendor, dev, driver string) {
+		t.Helper()
+		dir := "devices/" + slot
+		write(dir+"/class", class)
+		write(dir+"/vendor", vendor)
+		write(dir+"/device", dev)
+		if driver != "" {
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "drivers", driver), 0o755))
+			require.NoError(t, os.Symlink(filepath.Join(root, "drivers", driver), filepath.Join(root, dir, "driver")))
+		}
+	}
+
+	device("0000:00:00.0", "0x060000", "0x8086", "0x0d57", "")          // host bridge, ignored
+	device("0000:3b:00.0", "0x020000", "0x15b3", "0x101d", "mlx5_core") // NIC
+	device("0000:5e:00.0", "0x030200", "0x10de", "0x20b0", "nvidia")    // GPU
+	device("0000:af:00.0", "0x030000", "0x1002", "0x738c", "amdgpu")    // GPU
+	device("0000:d8:00.0", "0x120000", "0x10ee", "0x5004", "xclmgmt")   // FPGA
+	write("devices/0000:3b:00.0/net/ens1f0/ifindex", "2")
+	write("devices/0000:af:00.0/vbios_version", "113-D3431401-X00")
+	write("devices/0000:d8:00.0/fw_version", "2.9.317")
+	write("module/mlx5_core/version", "5.8-1.0.1")
+	write("nvidia/0000:5e:00.0/information", "Model: \t\t A100-SXM4-40GB\nVideo BIOS: \t 92.00.45.00.03\n")
+
+	pciDevicesDir = filepath.Join(root, "devices")
+	sysModuleDir = filepath.Join(root, "module")
+	nvidiaGPUsDir = filepath.Join(root, "nvidia")
+	ethtoolDrvInfoF = func(iface string) (string, string, error) {
+		if iface != "ens1f0" {
+			return "", "", fmt.Errorf("no such device")
+		}
+		return "5.8-1.0.2", "22.31.1014", nil
+	}
+
+	defer func() {
+		pciDevicesDir = "/sys/bus/pci/devices"
+		sysModuleDir = "/sys/module"
+		nvidiaGPUsDir = "/proc/driver/nvidia/gpus"
+		ethtoolDrvInfoF = ethtoolDrvInfo
+	}()
+
+	devs, err := getPCIDevices()
+	require.NoError(t, err)
+	require.Len(t, devs, 4)
+
+	nic := devs[0]
+	assert.Equal(t, PCIKindNIC, nic.Kind)
+	assert.Equal(t, "mlx5_core", nic.Driver)
+	assert.Equal(t, "5.8-1.0.1", nic.DriverVersion) // module version preferred
+	assert.Equal(t, "22.31.1014", nic.FirmwareVersion)
+	assert.Equal(t, []string{"ens1f0"}, nic.Interfaces)
+
+	assert.Equal(t, PCIKindGPU, devs[1].Kind)
+	assert.Equal(t, "92.00.45.00.03", devs[1].FirmwareVersion)
+
+	assert.Equal(t, "113-D3431401-X00", devs[2].FirmwareVersion)
+
+	assert.Equal(t, PCIKindFPGA, devs[3].Kind)
+	assert.Equal(t, "xclmgmt", devs[3].Driver)
+	assert.Equal(t, "2.9.317", devs[3].FirmwareVersion)
+}
@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !linux
+// +build !linux
+
+package hostobject
+
+// getPCIDevices lists PCI devices, only supported on Linux.
+func getPCIDevices() ([]*PCIDeviceInfo, error) {
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package hostobject
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPCIKind(t *testing.T) {
+	cases := []struct {
+		class, vendor, kind string
+	}{
+		{"0x030200", "0x10de", PCIKindGPU},
+		{"0x030000", "0x1002", PCIKindGPU},
+		{"0x020000", "0x15b3", PCIKindNIC},
+		{"0x020700", "0x15b3", PCIKindNIC},
+		{"0x120000", "0x10ee", PCIKindFPGA},
+		{"0xff0000", "0x1172", PCIKindFPGA},
+		{"0x120000", "0x1d0f", PCIKindAccelerator},
+		{"0x060000", "0x8086", ""},
+		{"", "0x8086", ""},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.kind, pciKind(tc.class, tc.vendor), "%s/%s", tc.class, tc.vendor)
+	}
+}
+
+func TestPCIFields(t *testing.T) {
+	devs := []*PCIDeviceInfo{
+		{Kind: PCIKindNIC, Model: "MT2892 Family [ConnectX-6 Dx]", Driver: "mlx5_core", DriverVersion: "5.8-1.0.1", FirmwareVersion: "22.31.1014"},
+		{Kind: PCIKindNIC, Model: "MT2892 Family [ConnectX-6 Dx]", Driver: "mlx5_core", DriverVersion: "5.8-1.0.1", FirmwareVersion: "22.31.1014"},
+		{Kind: PCIKindNIC, Model: "Ethernet Controller X710", Driver: "i40e", FirmwareVersion: "8.30"},
+		{Kind: PCIKindGPU, Model: "10de:20b0"},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"nic_count":             3,
+		"nic_models":            "Ethernet Controller X710,MT2892 Family [ConnectX-6 Dx]",
+		"nic_drivers":           "i40e,mlx5_core 5.8-1.0.1",
+		"nic_firmware_versions": "22.31.1014,8.30",
+
+		"gpu_count":             1,
+		"gpu_models":            "10de:20b0",
+		"gpu_drivers":           "",
+		"gpu_firmware_versions": "",
+	}, pciFields(devs))
+
+	assert.Empty(t, pciFields(nil))
+}
+
+func TestParsePCIIDs(t *testing.T) {
+	ids := parsePCIIDs(strings.NewReader(`# pci.ids
+10de  NVIDIA Corporation
+	20b0  GA100 [A100 SXM4 40GB]
+		10de 134f  A100-SXM4-40GB
+15b3  Mellanox Technologies
+	101d  MT2892 Family [ConnectX-6 Dx]
+
+C 00  Unclassified device
+	00  Non-VGA unclassified device
+`))
+
+	assert.Equal(t, map[string]string{"10de": "NVIDIA Corporation", "15b3": "Mellanox Technologies"}, ids.vendors)
+	assert.Len(t, ids.devices, 2)
+
+	d := &PCIDeviceInfo{VendorID: "15b3", DeviceID: "101d"}
+	ids.name(d)
+	assert.Equal(t, "Mellanox Technologies", d.Vendor)
+	assert.Equal(t, "MT2892 Family [ConnectX-6 Dx]", d.Model)
+
+	d = &PCIDeviceInfo{VendorID: "15b3", DeviceID: "1017"}
+	ids.name(d)
+	assert.Equal(t, "15b3:1017", d.Model)
+}
@@ -7,7 +7,9 @@ package influxdb
 
 const sampleConfig = `
 [[inputs.influxdb]]
+  ## InfluxDB 1.x exposes stats on /debug/vars, and InfluxDB 2.x on /metrics
   url = "http://localhost:8086/debug/vars"
+  # url = "http://localhost:8086/metrics"
 
   ## (optional) collect interval, default is 10 seconds
   interval = '10s'
@@ -7,6 +7,7 @@
 package influxdb
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/GuanceCloud/cliutils"
@@ -127,6 +129,13 @@ func (*Input) SampleMeasurement() []inputs.Measurement {
 		&InfluxdbTsm1FilestoreM{},
 		&InfluxdbTsm1WalM{},
 		&InfluxdbWriteM{},
+		&InfluxdbV2M{},
+		&InfluxdbV2BoltdbM{},
+		&InfluxdbV2GoM{},
+		&InfluxdbV2HTTPM{},
+		&InfluxdbV2QueryControllerM{},
+		&InfluxdbV2StorageM{},
+		&InfluxdbV2TaskM{},
 	}
 }
 
@@ -267,36 +276,49 @@ func (i *Input) Collect() error {
 	if err != nil {
 		return err
 	}
-	fc, err := DebugVarsDataParse2Point(data, MetricMap)
-	if err != nil {
-		return err
-	}
-	for {
-		point, err := fc()
+
+	var points []*Point
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		// InfluxDB 2.x exposes metrics in Prometheus format on /metrics
+		if points, err = PromDataParse2Point(bytes.NewReader(data)); err != nil {
+			return err
+		}
+	} else {
+		fc, err := DebugVarsDataParse2Point(data, MetricMap)
 		if err != nil {
-			if reflect.TypeOf(err) == reflect.TypeOf(NoMoreDataError{}) || err.Error() == "no more data" {
-				break
-			} else {
-				return err
-			}
+			return err
 		}
-		if point != nil {
-			if point.Tags == nil {
-				point.Tags = make(map[string]string)
+		for {
+			point, err := fc()
+			if err != nil {
+				if reflect.TypeOf(err) == reflect.TypeOf(NoMoreDataError{}) || err.Error() == "no more data" {
+					break
+				} else {
+					return err
+				}
 			}
-			setHostTagIfNotLoopback(point.Tags, i.URL)
-			for k, v := range i.Tags {
-				point.Tags[k] = v
+			if point != nil {
+				points = append(points, point)
 			}
-			i.collectCache = append(i.collectCache, &measurement{
-				name:     metricNamePrefix + point.Name,
-				tags:     point.Tags,
-				fields:   point.Values,
-				ts:       ts,
-				election: i.Election,
-			})
 		}
 	}
+
+	for _, point := range points {
+		if point.Tags == nil {
+			point.Tags = make(map[string]string)
+		}
+		setHostTagIfNotLoopback(point.Tags, i.URL)
+		for k, v := range i.Tags {
+			point.Tags[k] = v
+		}
+		i.collectCache = append(i.collectCache, &measurement{
+			name:     metricNamePrefix + point.Name,
+			tags:     point.Tags,
+			fields:   point.Values,
+			ts:       ts,
+			election: i.Election,
+		})
+	}
 	return nil
 }
 
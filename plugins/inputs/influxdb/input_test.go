@@ -7,10 +7,14 @@ package influxdb
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -1293,3 +1297,147 @@ const (
 	}
 	`
 )
+
+func TestPromDataParse2Point(t *testing.T) {
+	pts, err := PromDataParse2Point(strings.NewReader(dataInfluxMetrics2v7))
+	require.NoError(t, err)
+
+	byName := map[string][]*Point{}
+	for _, pt := range pts {
+		byName[pt.Name] = append(byName[pt.Name], pt)
+	}
+
+	require.Len(t, byName["v2"], 2)
+	require.Len(t, byName["v2_boltdb"], 1)
+	assert.Equal(t, map[string]interface{}{"reads_total": 120.0, "writes_total": 7.0}, byName["v2_boltdb"][0].Values)
+
+	require.Len(t, byName["v2_query_controller"], 2)
+	for _, pt := range byName["v2_query_controller"] {
+		if len(pt.Tags) == 0 {
+			assert.Equal(t, map[string]interface{}{
+				"all_active":                 1.0,
+				"all_duration_seconds_count": 3.0,
+				"all_duration_seconds_sum":   0.5,
+			}, pt.Values)
+		} else {
+			assert.Equal(t, map[string]string{"org": "o1", "result": "success"}, pt.Tags)
+			assert.Equal(t, map[string]interface{}{"requests_total": 3.0}, pt.Values)
+		}
+	}
+
+	require.Len(t, byName["v2_storage"], 1)
+	assert.Equal(t, "b1", byName["v2_storage"][0].Tags["bucket"])
+	assert.Equal(t, map[string]interface{}{"shard_disk_size": 4096.0, "wal_size": 512.0}, byName["v2_storage"][0].Values)
+
+	require.Len(t, byName["v2_go"], 1)
+	assert.Equal(t, map[string]interface{}{
+		"goroutines":                120.0,
+		"gc_duration_seconds_count": 50.0,
+		"gc_duration_seconds_sum":   0.25,
+	}, byName["v2_go"][0].Values)
+
+	require.Len(t, byName["v2_http"], 1)
+	assert.Equal(t, "204", byName["v2_http"][0].Tags["response_code"])
+	assert.Equal(t, map[string]interface{}{"api_requests_total": 10.0}, byName["v2_http"][0].Values)
+
+	require.Len(t, byName["v2_task"], 1)
+	assert.Equal(t, map[string]interface{}{"scheduler_current_execution": 2.0}, byName["v2_task"][0].Values)
+
+	assert.Empty(t, byName["v2_service"])
+	assert.Empty(t, byName["v2_unknown"])
+	assert.Len(t, pts, 9)
+}
+
+func TestCollectV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(dataInfluxMetrics2v7))
+	}))
+	defer ts.Close()
+
+	i := newInput()
+	i.client = ts.Client()
+	i.URL = ts.URL + "/metrics"
+	i.Tags = map[string]string{"some_tag": "some_value"}
+
+	require.NoError(t, i.Collect())
+	require.Len(t, i.collectCache, 9)
+
+	docs := map[string]*inputs.MeasurementInfo{}
+	for _, m := range i.SampleMeasurement() {
+		docs[m.Info().Name] = m.Info()
+	}
+
+	for _, m := range i.collectCache {
+		pt, err := m.LineProto()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pt.Name(), "influxdb_v2"), pt.Name())
+		assert.Equal(t, "some_value", pt.Tags()["some_tag"])
+
+		// all collected are documented
+		doc, ok := docs[pt.Name()]
+		require.True(t, ok, pt.Name())
+		fields, err := pt.Fields()
+		require.NoError(t, err)
+		for k := range fields {
+			assert.Contains(t, doc.Fields, k, pt.Name())
+		}
+		for k := range pt.Tags() {
+			if k != "some_tag" {
+				assert.Contains(t, doc.Tags, k, pt.Name())
+			}
+		}
+	}
+}
+
+const dataInfluxMetrics2v7 = `# HELP boltdb_reads_total Total number of boltdb reads
+# TYPE boltdb_reads_total counter
+boltdb_reads_total 120
+# HELP boltdb_writes_total Total number of boltdb writes
+# TYPE boltdb_writes_total counter
+boltdb_writes_total 7
+# HELP influxdb_buckets_total Number of total buckets on the server
+# TYPE influxdb_buckets_total counter
+influxdb_buckets_total 3
+# HELP influxdb_info Information about the influxdb environment.
+# TYPE influxdb_info gauge
+influxdb_info{arch="amd64",os="linux",version="v2.7.1"} 1
+# HELP qc_all_active Number of queries in all states
+# TYPE qc_all_active gauge
+qc_all_active 1
+# HELP qc_all_duration_seconds Histogram of total times spent in all query states
+# TYPE qc_all_duration_seconds histogram
+qc_all_duration_seconds_bucket{le="0.005"} 1
+qc_all_duration_seconds_bucket{le="+Inf"} 3
+qc_all_duration_seconds_sum 0.5
+qc_all_duration_seconds_count 3
+# HELP qc_requests_total Count of the query requests
+# TYPE qc_requests_total counter
+qc_requests_total{org="o1",result="success"} 3
+# HELP storage_shard_disk_size Gauge of the disk size for the shard
+# TYPE storage_shard_disk_size gauge
+storage_shard_disk_size{bucket="b1",engine="tsm1"} 4096
+# HELP storage_wal_size Gauge of size of WAL in bytes
+# TYPE storage_wal_size gauge
+storage_wal_size{bucket="b1",engine="tsm1"} 512
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 120
+# HELP go_gc_duration_seconds A summary of the pause duration of garbage collection cycles.
+# TYPE go_gc_duration_seconds summary
+go_gc_duration_seconds{quantile="0.5"} 0.0001
+go_gc_duration_seconds_sum 0.25
+go_gc_duration_seconds_count 50
+# HELP http_api_requests_total Number of http requests received
+# TYPE http_api_requests_total counter
+http_api_requests_total{handler="platform",method="POST",path="/api/v2/write",response_code="204",status="2XX",user_agent="Telegraf"} 10
+# HELP task_scheduler_current_execution Number of tasks currently being executed
+# TYPE task_scheduler_current_execution gauge
+task_scheduler_current_execution 2
+# HELP service_bucket_new_call_total Number of calls
+# TYPE service_bucket_new_call_total counter
+service_bucket_new_call_total{method="find_buckets"} 42
+# HELP unknown_metric Not collected
+# TYPE unknown_metric gauge
+unknown_metric 1
+`
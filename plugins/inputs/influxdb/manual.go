@@ -489,3 +489,271 @@ func nFIFloatTimestampNs(desc string) *inputs.FieldInfo {
 		Desc:     desc,
 	}
 }
+
+type InfluxdbV2M measurement
+
+func (m *InfluxdbV2M) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2M) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":    &inputs.TagInfo{Desc: "System hostname."},
+			"arch":    &inputs.TagInfo{Desc: "CPU architecture of InfluxDB."},
+			"os":      &inputs.TagInfo{Desc: "OS of InfluxDB."},
+			"version": &inputs.TagInfo{Desc: "Version of InfluxDB."},
+		},
+		Fields: map[string]interface{}{
+			"buckets_total":       nFIFloatUnknown("The total number of buckets."),
+			"dashboards_total":    nFIFloatUnknown("The total number of dashboards."),
+			"info":                nFIFloatUnknown("Always 1, the environment of InfluxDB is in tags `arch`, `os` and `version`."),
+			"organizations_total": nFIFloatUnknown("The total number of organizations."),
+			"scrapers_total":      nFIFloatUnknown("The total number of scrapers."),
+			"telegrafs_total":     nFIFloatUnknown("The total number of Telegraf configurations."),
+			"tokens_total":        nFIFloatUnknown("The total number of API tokens."),
+			"users_total":         nFIFloatUnknown("The total number of users."),
+			"uptime_seconds":      nFIFloatDurationSecond("The number of seconds InfluxDB has been running."),
+		},
+	}
+}
+
+type InfluxdbV2StorageM measurement
+
+func (m *InfluxdbV2StorageM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2StorageM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_storage",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":    &inputs.TagInfo{Desc: "System hostname."},
+			"bucket":  &inputs.TagInfo{Desc: "Bucket ID."},
+			"engine":  &inputs.TagInfo{Desc: "Storage engine."},
+			"id":      &inputs.TagInfo{Desc: "Shard ID."},
+			"level":   &inputs.TagInfo{Desc: "Level of TSM files."},
+			"path":    &inputs.TagInfo{Desc: "Data path of the shard."},
+			"walPath": &inputs.TagInfo{Desc: "WAL path of the shard."},
+		},
+		Fields: map[string]interface{}{
+			"bucket_measurement_num": nFIFloatUnknown("The number of measurements in the bucket."),
+			"bucket_series_num":      nFIFloatUnknown("The number of series in the bucket."),
+			"cache_disk_bytes":       nFIFloatBytes("The size of the most recent cache snapshot."),
+			"cache_inuse_bytes":      nFIFloatBytes("The current memory consumption of the cache."),
+			"cache_writes_total":     nFIFloatUnknown("The number of cache writes."),
+			"compactions_active":     nFIFloatUnknown("The number of active compactions."),
+			"shard_disk_size":        nFIFloatBytes("The disk size of the shard."),
+			"shard_series":           nFIFloatUnknown("The number of series in the shard index."),
+			"shard_write_count":      nFIFloatUnknown("The number of writes to the shard."),
+			"tsm_files_disk_bytes":   nFIFloatBytes("The disk usage of TSM files."),
+			"tsm_files_total":        nFIFloatUnknown("The number of TSM files."),
+			"wal_size":               nFIFloatBytes("The size of the WAL."),
+			"wal_writes":             nFIFloatUnknown("The number of writes to the WAL."),
+			"writer_dropped_points":  nFIFloatUnknown("The number of points dropped on write."),
+			"writer_err_points":      nFIFloatUnknown("The number of points failed to write."),
+			"writer_ok_points":       nFIFloatUnknown("The number of points written successfully."),
+		},
+	}
+}
+
+type InfluxdbV2QueryControllerM measurement
+
+func (m *InfluxdbV2QueryControllerM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2QueryControllerM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_query_controller",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":   &inputs.TagInfo{Desc: "System hostname."},
+			"org":    &inputs.TagInfo{Desc: "Organization ID."},
+			"result": &inputs.TagInfo{Desc: "Result of queries."},
+		},
+		Fields: map[string]interface{}{
+			"all_active":                 nFIFloatUnknown("The number of queries in all states."),
+			"compiling_active":           nFIFloatUnknown("The number of queries compiling."),
+			"executing_active":           nFIFloatUnknown("The number of queries executing."),
+			"queueing_active":            nFIFloatUnknown("The number of queries queueing."),
+			"memory_unused_bytes":        nFIFloatBytes("The free memory of the query controller."),
+			"requests_total":             nFIFloatUnknown("The number of query requests."),
+			"all_duration_seconds_count": nFIFloatUnknown("The number of queries finished."),
+			"all_duration_seconds_sum":   nFIFloatDurationSecond("The total duration of queries in all states."),
+		},
+	}
+}
+
+type InfluxdbV2BoltdbM measurement
+
+func (m *InfluxdbV2BoltdbM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2BoltdbM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_boltdb",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host": &inputs.TagInfo{Desc: "System hostname."},
+		},
+		Fields: map[string]interface{}{
+			"reads_total":  nFIFloatUnknown("The number of reads of the metadata store."),
+			"writes_total": nFIFloatUnknown("The number of writes of the metadata store."),
+		},
+	}
+}
+
+type InfluxdbV2GoM measurement
+
+func (m *InfluxdbV2GoM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2GoM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_go",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":    &inputs.TagInfo{Desc: "System hostname."},
+			"version": &inputs.TagInfo{Desc: "Go version."},
+		},
+		Fields: map[string]interface{}{
+			"gc_duration_seconds_count":     nFIFloatUnknown("The number of GC."),
+			"gc_duration_seconds_sum":       nFIFloatDurationSecond("The total pause duration of GC."),
+			"goroutines":                    nFIFloatUnknown("The number of goroutines."),
+			"info":                          nFIFloatUnknown("Always 1, the Go version is in tag `version`."),
+			"memstats_alloc_bytes":          nFIFloatBytes("The bytes of allocated heap objects."),
+			"memstats_alloc_bytes_total":    nFIFloatBytes("The cumulative bytes allocated for heap objects."),
+			"memstats_buck_hash_sys_bytes":  nFIFloatBytes("The bytes of memory in profiling bucket hash tables."),
+			"memstats_frees_total":          nFIFloatUnknown("The cumulative count of heap objects freed."),
+			"memstats_gc_sys_bytes":         nFIFloatBytes("The bytes of memory in garbage collection metadata."),
+			"memstats_heap_alloc_bytes":     nFIFloatBytes("The bytes of allocated heap objects."),
+			"memstats_heap_idle_bytes":      nFIFloatBytes("The bytes in idle (unused) spans."),
+			"memstats_heap_inuse_bytes":     nFIFloatBytes("The bytes in in-use spans."),
+			"memstats_heap_objects":         nFIFloatUnknown("The number of allocated heap objects."),
+			"memstats_heap_released_bytes":  nFIFloatBytes("The bytes of physical memory returned to the OS."),
+			"memstats_heap_sys_bytes":       nFIFloatBytes("The bytes of heap memory obtained from the OS."),
+			"memstats_last_gc_time_seconds": nFIFloatTimestampSec("The time the last GC finished."),
+			"memstats_lookups_total":        nFIFloatUnknown("The number of pointer lookups performed by the runtime."),
+			"memstats_mallocs_total":        nFIFloatUnknown("The cumulative count of heap objects allocated."),
+			"memstats_mcache_inuse_bytes":   nFIFloatBytes("The bytes of allocated mcache structures."),
+			"memstats_mcache_sys_bytes":     nFIFloatBytes("The bytes of memory obtained from the OS for mcache structures."),
+			"memstats_mspan_inuse_bytes":    nFIFloatBytes("The bytes of allocated mspan structures."),
+			"memstats_mspan_sys_bytes":      nFIFloatBytes("The bytes of memory obtained from the OS for mspan structures."),
+			"memstats_next_gc_bytes":        nFIFloatBytes("The target heap size of the next GC cycle."),
+			"memstats_other_sys_bytes":      nFIFloatBytes("The bytes of memory in miscellaneous off-heap runtime allocations."),
+			"memstats_stack_inuse_bytes":    nFIFloatBytes("The bytes in stack spans."),
+			"memstats_stack_sys_bytes":      nFIFloatBytes("The bytes of stack memory obtained from the OS."),
+			"memstats_sys_bytes":            nFIFloatBytes("The total bytes of memory obtained from the OS."),
+			"threads":                       nFIFloatUnknown("The number of OS threads created."),
+		},
+	}
+}
+
+type InfluxdbV2HTTPM measurement
+
+func (m *InfluxdbV2HTTPM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2HTTPM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_http",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":          &inputs.TagInfo{Desc: "System hostname."},
+			"endpoint":      &inputs.TagInfo{Desc: "Endpoint of query or write requests."},
+			"handler":       &inputs.TagInfo{Desc: "Handler of API requests."},
+			"method":        &inputs.TagInfo{Desc: "HTTP method."},
+			"org_id":        &inputs.TagInfo{Desc: "Organization ID."},
+			"path":          &inputs.TagInfo{Desc: "Path of API requests."},
+			"response_code": &inputs.TagInfo{Desc: "HTTP response code."},
+			"status":        &inputs.TagInfo{Desc: "Class of the response code, such as 2XX."},
+			"user_agent":    &inputs.TagInfo{Desc: "User agent of API requests."},
+		},
+		Fields: map[string]interface{}{
+			"api_request_duration_seconds_count": nFIFloatUnknown("The number of API requests finished."),
+			"api_request_duration_seconds_sum":   nFIFloatDurationSecond("The total duration of API requests."),
+			"api_requests_total":                 nFIFloatUnknown("The number of API requests."),
+			"query_request_bytes":                nFIFloatBytes("The bytes of query requests."),
+			"query_request_count":                nFIFloatUnknown("The number of query requests."),
+			"query_response_bytes":               nFIFloatBytes("The bytes of query responses."),
+			"write_request_bytes":                nFIFloatBytes("The bytes of write requests."),
+			"write_request_count":                nFIFloatUnknown("The number of write requests."),
+		},
+	}
+}
+
+type InfluxdbV2TaskM measurement
+
+func (m *InfluxdbV2TaskM) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m *InfluxdbV2TaskM) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricNamePrefix + "v2_task",
+		Desc: "InfluxDB 2.x only.",
+		Tags: map[string]interface{}{
+			"host":      &inputs.TagInfo{Desc: "System hostname."},
+			"errorType": &inputs.TagInfo{Desc: "Type of task errors."},
+			"status":    &inputs.TagInfo{Desc: "Status of task runs."},
+			"taskID":    &inputs.TagInfo{Desc: "Task ID."},
+			"task_type": &inputs.TagInfo{Desc: "Type of the task, such as system."},
+		},
+		Fields: map[string]interface{}{
+			"executor_errors_counter":            nFIFloatUnknown("The number of errors thrown by the executor."),
+			"executor_manual_runs_counter":       nFIFloatUnknown("The number of manual runs."),
+			"executor_promise_queue_usage":       nFIFloatUnknown("The percent of the promise queue used."),
+			"executor_run_duration_count":        nFIFloatUnknown("The number of task runs finished."),
+			"executor_run_duration_sum":          nFIFloatDurationSecond("The total duration of task runs."),
+			"executor_run_latency_seconds_count": nFIFloatUnknown("The number of task runs started."),
+			"executor_run_latency_seconds_sum":   nFIFloatDurationSecond("The total latency between the scheduled and the actual time of task runs."),
+			"executor_run_queue_delta_count":     nFIFloatUnknown("The number of task runs queued."),
+			"executor_run_queue_delta_sum":       nFIFloatDurationSecond("The total duration between the scheduled and the queued time of task runs."),
+			"executor_total_runs_active":         nFIFloatUnknown("The number of workers running tasks."),
+			"executor_total_runs_complete":       nFIFloatUnknown("The number of task runs completed."),
+			"executor_workers_busy":              nFIFloatUnknown("The percent of workers busy."),
+			"scheduler_current_execution":        nFIFloatUnknown("The number of tasks running."),
+			"scheduler_execute_delta_count":      nFIFloatUnknown("The number of tasks executed by the scheduler."),
+			"scheduler_execute_delta_sum":        nFIFloatDurationSecond("The total duration between the scheduled and the executed time of tasks."),
+			"scheduler_schedule_delay_count":     nFIFloatUnknown("The number of tasks scheduled."),
+			"scheduler_schedule_delay_sum":       nFIFloatDurationSecond("The total delay of scheduling tasks."),
+			"scheduler_total_execute_failure":    nFIFloatUnknown("The number of failed executions."),
+			"scheduler_total_execution_calls":    nFIFloatUnknown("The number of executions."),
+			"scheduler_total_release_calls":      nFIFloatUnknown("The number of releasing tasks."),
+			"scheduler_total_schedule_calls":     nFIFloatUnknown("The number of scheduling tasks."),
+			"scheduler_total_schedule_fails":     nFIFloatUnknown("The number of failures of scheduling tasks."),
+		},
+	}
+}
+
+func nFIFloatTimestampSec(desc string) *inputs.FieldInfo {
+	return &inputs.FieldInfo{
+		DataType: inputs.Float,
+		Type:     inputs.Gauge,
+		Unit:     inputs.TimestampSec,
+		Desc:     desc,
+	}
+}
+
+func nFIFloatDurationSecond(desc string) *inputs.FieldInfo {
+	return &inputs.FieldInfo{
+		DataType: inputs.Float,
+		Type:     inputs.Gauge,
+		Unit:     inputs.DurationSecond,
+		Desc:     desc,
+	}
+}
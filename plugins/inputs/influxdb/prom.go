@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package influxdb
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// v2Subsystems maps prefixes of metrics exposed on /metrics of InfluxDB 2.x
+// to measurement names, other metrics are ignored. Metrics of service_ are
+// not collected, they are named by each API call of InfluxDB.
+var v2Subsystems = []struct {
+	prefix, name string
+}{
+	{"boltdb_", "v2_boltdb"},
+	{"go_", "v2_go"},
+	{"http_", "v2_http"},
+	{"influxdb_", "v2"},
+	{"qc_", "v2_query_controller"},
+	{"storage_", "v2_storage"},
+	{"task_", "v2_task"},
+}
+
+// PromDataParse2Point parses metrics of InfluxDB 2.x in Prometheus text format,
+// samples of the same subsystem and labels are merged into one point.
+func PromDataParse2Point(data io.Reader) ([]*Point, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pts   []*Point
+		index = map[string]*Point{}
+	)
+
+	add := func(name string, labels []*dto.LabelPair, field string, value float64) {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return
+		}
+
+		tags := make(map[string]string, len(labels))
+		keys := make([]string, 0, len(labels))
+		for _, lp := range labels {
+			tags[lp.GetName()] = lp.GetValue()
+			keys = append(keys, lp.GetName()+"="+lp.GetValue())
+		}
+		sort.Strings(keys)
+
+		key := name + "," + strings.Join(keys, ",")
+		pt, ok := index[key]
+		if !ok {
+			pt = &Point{Name: name, Tags: tags, Values: map[string]interface{}{}}
+			index[key] = pt
+			pts = append(pts, pt)
+		}
+		pt.Values[field] = value
+	}
+
+	for metricName, family := range families {
+		var name, field string
+		for _, s := range v2Subsystems {
+			if strings.HasPrefix(metricName, s.prefix) {
+				name, field = s.name, strings.TrimPrefix(metricName, s.prefix)
+				break
+			}
+		}
+
+		if name == "" {
+			l.Debugf("ignore metric %s", metricName)
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetLabel(), field, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetLabel(), field, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetLabel(), field, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				add(name, m.GetLabel(), field+"_count", float64(m.GetHistogram().GetSampleCount()))
+				add(name, m.GetLabel(), field+"_sum", m.GetHistogram().GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				add(name, m.GetLabel(), field+"_count", float64(m.GetSummary().GetSampleCount()))
+				add(name, m.GetLabel(), field+"_sum", m.GetSummary().GetSampleSum())
+			}
+		}
+	}
+
+	return pts, nil
+}
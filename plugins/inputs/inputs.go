@@ -10,7 +10,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"os"
 	"reflect"
 	"strings"
@@ -190,6 +189,22 @@ func GetInput() ([]byte, error) {
 	return b, nil
 }
 
+// LoadedConfPaths returns conf files of the input loaded.
+func LoadedConfPaths(name string) []string {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	var res []string
+	if c, ok := ConfigInfo[name]; ok {
+		for _, p := range c.ConfigPaths {
+			if p.Loaded == 1 {
+				res = append(res, p.Path)
+			}
+		}
+	}
+	return res
+}
+
 // AddConfigInfoPath add or update input info.
 //  if fp is empty, add new config when inputName not exist, or set ConfigPaths empty when exist.
 func AddConfigInfoPath(inputName string, fp string, loaded int8) {
@@ -309,6 +324,8 @@ func RunInputs() error {
 
 	envs := getEnvs()
 
+	sched := newStartScheduler(getStartup())
+	runnings := map[string][]*inputInfo{}
 	for name, arr := range InputsInfo {
 		if len(arr) > 1 {
 			if _, ok := arr[0].input.(Singleton); ok {
@@ -316,6 +333,13 @@ func RunInputs() error {
 			}
 		}
 
+		runnings[name] = arr
+		if sched.staggered[name] {
+			sched.total += len(arr)
+		}
+	}
+
+	for name, arr := range runnings {
 		inputInstanceVec.WithLabelValues(name).Set(float64(len(arr)))
 		for _, ii := range arr {
 			if ii.input == nil {
@@ -335,14 +359,14 @@ func RunInputs() error {
 				inp.ReadEnv(envs)
 			}
 
-			func(name string, ii *inputInfo) {
+			func(name string, ii *inputInfo, delay time.Duration) {
 				g.Go(func(ctx context.Context) error {
 					// NOTE: 让每个采集器间歇运行，防止每个采集器扎堆启动，导致主机资源消耗出现规律性的峰值
-					tick := time.NewTicker(time.Duration(rand.Int63n(int64(10 * time.Second)))) //nolint:gosec
-					defer tick.Stop()
+					timer := time.NewTimer(delay)
+					defer timer.Stop()
 					select {
-					case <-tick.C:
-						l.Infof("starting input %s ...", name)
+					case <-timer.C:
+						l.Infof("starting input %s after %s ...", name, delay)
 
 						protectRunningInput(name, ii)
 
@@ -353,7 +377,7 @@ func RunInputs() error {
 					}
 					return nil
 				})
-			}(name, ii)
+			}(name, ii, sched.delay(name))
 		}
 	}
 	return nil
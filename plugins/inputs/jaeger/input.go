@@ -15,6 +15,7 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
@@ -90,6 +91,7 @@ const (
 
 var (
 	log            = logger.DefaultSLogger(inputName)
+	afterGather    *itrace.AfterGather
 	afterGatherRun itrace.AfterGatherHandler
 	customerKeys   []string
 	tags           map[string]string
@@ -175,12 +177,13 @@ func (ipt *Input) RegHTTPHandler() {
 		}
 	}
 
-	var afterGather *itrace.AfterGather
+	opts := []itrace.Option{itrace.WithLogger(log)}
 	if localCache != nil && localCache.Enabled() {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log), itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
-	} else {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log))
+		opts = append(opts, itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
 	}
+	// pull per-service sampling rates configured in center
+	opts = append(opts, itrace.WithSamplingPull(config.Cfg.Dataway))
+	afterGather = itrace.NewAfterGather(opts...)
 	afterGatherRun = afterGather
 
 	// add filters: the order of appending filters into AfterGather is important!!!
@@ -245,6 +248,10 @@ func (*Input) Terminate() {
 		}
 		log.Debug("### storage closed")
 	}
+	if afterGather != nil {
+		afterGather.Stop()
+		log.Debug("### after gather stopped")
+	}
 }
 
 func init() { //nolint:gochecknoinits
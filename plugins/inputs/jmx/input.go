@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package jmx collects MBeans of any Java application by Jolokia.
+package jmx
+
+import (
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName   = "jmx"
+	minInterval = time.Second
+	maxInterval = time.Minute * 20
+
+	sampleCfg = `
+[[inputs.jmx]]
+  ## URLs of Jolokia agents, such as the bundled one attached to the JVM by
+  ##   java -javaagent:/usr/local/datakit/data/jolokia-jvm-agent.jar=port=8080,host=localhost -jar app.jar
+  urls = ["http://localhost:8080/jolokia"]
+
+  # username = ""
+  # password = ""
+  # response_timeout = "5s"
+
+  ## Optional TLS config
+  # tls_ca   = "/var/private/ca.pem"
+  # tls_cert = "/var/private/client.pem"
+  # tls_key  = "/var/private/client-key.pem"
+  # insecure_skip_verify = false
+
+  # interval = "15s"
+
+  ## MBeans to read. Each metric reads MBeans of the object name, in which
+  ## properties can be wildcard "*", into the measurement of name, with
+  ## properties in tag_keys as tags.
+  ##
+  ## paths select attributes, and inner paths of composite attributes, such
+  ## as "HeapMemoryUsage/used". Glob patterns are allowed, such as "*Count"
+  ## and "HeapMemoryUsage/*". All attributes are read if no paths.
+  [[inputs.jmx.metric]]
+    name  = "jmx_memory"
+    mbean = "java.lang:type=Memory"
+    paths = ["HeapMemoryUsage", "NonHeapMemoryUsage"]
+
+  [[inputs.jmx.metric]]
+    name     = "jmx_garbage_collector"
+    mbean    = "java.lang:name=*,type=GarbageCollector"
+    paths    = ["Collection*"]
+    tag_keys = ["name"]
+
+  # [[inputs.jmx.metric]]
+  #   name     = "jmx_kafka_broker_topic"
+  #   mbean    = "kafka.server:type=BrokerTopicMetrics,name=*,topic=*"
+  #   paths    = ["Count", "*Rate"]
+  #   tag_keys = ["name", "topic"]
+
+  [inputs.jmx.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+type Input struct {
+	inputs.JolokiaAgent
+	Tags map[string]string `toml:"tags"`
+}
+
+func (*Input) Catalog() string { return inputName }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+// SampleMeasurement returns nil, measurements are named by configured metrics.
+func (*Input) SampleMeasurement() []inputs.Measurement { return nil }
+
+func (ipt *Input) Run() {
+	if d, err := time.ParseDuration(ipt.JolokiaAgent.Interval); err != nil {
+		ipt.JolokiaAgent.Interval = (time.Second * 15).String()
+	} else {
+		ipt.JolokiaAgent.Interval = config.ProtectedInterval(minInterval, maxInterval, d).String()
+	}
+
+	ipt.JolokiaAgent.PluginName = inputName
+	ipt.JolokiaAgent.Tags = ipt.Tags
+	ipt.JolokiaAgent.Collect()
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.SemStop != nil {
+		ipt.SemStop.Close()
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		JolokiaAgent: inputs.JolokiaAgent{
+			DefaultFieldSeparator: "_",
+			ResponseTimeout:       5 * time.Second,
+			SemStop:               cliutils.NewSem(),
+		},
+		Tags: map[string]string{},
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -364,8 +364,8 @@ func metricMatchesResponse(metric Metric, response ReadResponse) bool {
 		return false
 	}
 
-	if len(metric.Paths) == 0 {
-		return len(response.RequestAttributes) == 0
+	if len(response.RequestAttributes) == 0 {
+		return len(metric.Paths) == 0 || len(metric.pathPatterns) > 0
 	}
 
 	for _, attribute := range response.RequestAttributes {
@@ -466,6 +466,14 @@ func makeReadRequests(metrics []Metric) []ReadRequest {
 					})
 				}
 			}
+
+			// all attributes read to match path patterns
+			if len(metric.pathPatterns) > 0 {
+				requests = append(requests, ReadRequest{
+					Mbean:      metric.Mbean,
+					Attributes: []string{},
+				})
+			}
 		}
 	}
 	return requests
@@ -548,16 +556,27 @@ type Metric struct {
 
 	mbeanDomain     string
 	mbeanProperties []string
+
+	// paths of glob patterns, such as "*Count" and "HeapMemoryUsage/*",
+	// matched on all attributes of the mbean
+	pathPatterns []string
 }
 
 func NewMetric(config MetricConfig, defaultFieldPrefix, defaultFieldSeparator, defaultTagPrefix string) Metric {
 	metric := Metric{
 		Name:    config.Name,
 		Mbean:   config.Mbean,
-		Paths:   config.Paths,
 		TagKeys: config.TagKeys,
 	}
 
+	for _, p := range config.Paths {
+		if strings.ContainsAny(p, "*?[") {
+			metric.pathPatterns = append(metric.pathPatterns, p)
+		} else {
+			metric.Paths = append(metric.Paths, p)
+		}
+	}
+
 	if config.FieldName != nil {
 		metric.FieldName = *config.FieldName
 	}
@@ -630,6 +649,24 @@ func (m Metric) MatchAttributeAndPath(attribute, innerPath string) bool {
 	return false
 }
 
+// matchPathPattern returns true if the path or any parent of it matches
+// any of the path patterns.
+func (m Metric) matchPathPattern(p string) bool {
+	for {
+		for _, pattern := range m.pathPatterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+
+		i := strings.LastIndex(p, "/")
+		if i == -1 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
 func parseMbeanObjectName(name string) (string, []string) {
 	index := strings.Index(name, ":")
 	if index == -1 {
@@ -740,6 +777,12 @@ func (pb *pointBuilder) extractFields(mbean string, value interface{}) map[strin
 	if ok {
 		// complex value
 		switch {
+		case len(pb.objectAttributes) == 0 && len(pb.metric.pathPatterns) > 0:
+			// all attributes requested for path patterns,
+			// only matched ones kept
+			for attribute, value := range valueMap {
+				pb.fillMatchedFields(pb.formatFieldName(attribute, ""), attribute, value, fieldMap)
+			}
 		case len(pb.objectAttributes) == 0:
 			// if there were no attributes requested,
 			// then the keys are attributes
@@ -836,6 +879,21 @@ func (pb *pointBuilder) fillFields(name string, value interface{}, fieldMap map[
 	fieldMap[name] = value
 }
 
+// fillMatchedFields fills fields of the value at the path if it matches path
+// patterns, or recurses into the value to find matched ones.
+func (pb *pointBuilder) fillMatchedFields(name, p string, value interface{}, fieldMap map[string]interface{}) {
+	if pb.metric.matchPathPattern(p) {
+		pb.fillFields(name, value, fieldMap)
+		return
+	}
+
+	if valueMap, ok := value.(map[string]interface{}); ok {
+		for key, innerValue := range valueMap {
+			pb.fillMatchedFields(name+pb.metric.FieldSeparator+key, p+"/"+key, innerValue, fieldMap)
+		}
+	}
+}
+
 // applySubstitutions updates all the keys in the supplied map
 // of fields to account for $1-style substitution instructions.
 func (pb *pointBuilder) applySubstitutions(mbean string, fieldMap map[string]interface{}) {
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package inputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJolokiaPathPatterns(t *testing.T) {
+	newMetric := func(mbean string, paths ...string) Metric {
+		return NewMetric(MetricConfig{
+			Name:    "jmx_test",
+			Mbean:   mbean,
+			Paths:   paths,
+			TagKeys: []string{"name"},
+		}, "", "_", "")
+	}
+
+	t.Run("requests", func(t *testing.T) {
+		m := newMetric("java.lang:type=Memory", "HeapMemoryUsage/*", "Verbose")
+		assert.Equal(t, []string{"Verbose"}, m.Paths)
+		assert.Equal(t, []string{"HeapMemoryUsage/*"}, m.pathPatterns)
+
+		assert.Equal(t, []ReadRequest{
+			{Mbean: "java.lang:type=Memory", Attributes: []string{"Verbose"}},
+			{Mbean: "java.lang:type=Memory", Attributes: []string{}},
+		}, makeReadRequests([]Metric{m}))
+
+		m = newMetric("java.lang:type=Memory", "*Usage")
+		assert.Empty(t, m.Paths)
+		assert.Equal(t, []ReadRequest{
+			{Mbean: "java.lang:type=Memory", Attributes: []string{}},
+		}, makeReadRequests([]Metric{m}))
+	})
+
+	t.Run("match", func(t *testing.T) {
+		m := newMetric("java.lang:type=Memory", "HeapMemoryUsage/*", "*Count")
+
+		for p, expect := range map[string]bool{
+			"HeapMemoryUsage/used":     true,
+			"HeapMemoryUsage":          false,
+			"NonHeapMemoryUsage/used":  false,
+			"ObjectPendingFinalCount":  true,
+			"ObjectPendingFinalCount2": false,
+			"HeapMemoryUsage/used/x":   true,
+		} {
+			assert.Equal(t, expect, m.matchPathPattern(p), p)
+		}
+
+		assert.True(t, metricMatchesResponse(m, ReadResponse{RequestMbean: "java.lang:type=Memory"}))
+		assert.False(t, metricMatchesResponse(newMetric("java.lang:type=Memory", "Verbose"),
+			ReadResponse{RequestMbean: "java.lang:type=Memory"}))
+	})
+
+	t.Run("points", func(t *testing.T) {
+		m := newMetric("kafka.server:type=BrokerTopicMetrics,name=*", "Count", "*Rate")
+		g := NewGatherer([]Metric{m})
+
+		value := map[string]interface{}{
+			"kafka.server:name=BytesInPerSec,type=BrokerTopicMetrics": map[string]interface{}{
+				"Count":         100.0,
+				"OneMinuteRate": 1.5,
+				"MeanRate":      2.5,
+				"RateUnit":      "SECONDS",
+			},
+		}
+
+		pts, errs := g.generatePoints(m, []ReadResponse{
+			{
+				Status:            200,
+				RequestMbean:      m.Mbean,
+				RequestAttributes: []string{"Count"},
+				Value: map[string]interface{}{
+					"kafka.server:name=BytesInPerSec,type=BrokerTopicMetrics": 100.0,
+				},
+			},
+			{
+				Status:       200,
+				RequestMbean: m.Mbean,
+				Value:        value,
+			},
+		})
+		require.Empty(t, errs)
+
+		pts = compactPoints(pts)
+		require.Len(t, pts, 1)
+		assert.Equal(t, map[string]string{"name": "BytesInPerSec"}, pts[0].Tags)
+		assert.Equal(t, map[string]interface{}{
+			"Count":         100.0,
+			"OneMinuteRate": 1.5,
+			"MeanRate":      2.5,
+		}, pts[0].Fields)
+	})
+
+	t.Run("inner-points", func(t *testing.T) {
+		m := newMetric("java.lang:type=Memory", "HeapMemoryUsage/*")
+		pb := newPointBuilder(m, nil, "")
+
+		pts := pb.Build(m.Mbean, map[string]interface{}{
+			"HeapMemoryUsage": map[string]interface{}{
+				"used": 10.0,
+				"max":  20.0,
+			},
+			"NonHeapMemoryUsage": map[string]interface{}{
+				"used": 5.0,
+			},
+			"Verbose": false,
+		})
+
+		require.Len(t, pts, 1)
+		assert.Equal(t, map[string]interface{}{
+			"HeapMemoryUsage_used": 10.0,
+			"HeapMemoryUsage_max":  20.0,
+		}, pts[0].Fields)
+	})
+}
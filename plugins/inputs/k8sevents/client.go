@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package k8sevents
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubewatch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// eventsClient lists and watches Events of the namespace, all namespaces if empty.
+type eventsClient interface {
+	// resourceVersion returns the current resource version of Events, so
+	// events before watching are skipped.
+	resourceVersion(ctx context.Context, namespace string) (string, error)
+	watch(ctx context.Context, namespace, resourceVersion string) (kubewatch.Interface, error)
+}
+
+type k8sClient struct {
+	*kubernetes.Clientset
+}
+
+func newK8sClient(baseURL, tokenPath, token string) (*k8sClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("invalid kubernetes_url, cannot be empty")
+	}
+
+	if token == "" && tokenPath != "" {
+		b, err := os.ReadFile(filepath.Clean(tokenPath))
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	cli, err := kubernetes.NewForConfig(&rest.Config{
+		Host:        baseURL,
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: true,
+		},
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(100, 100),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &k8sClient{Clientset: cli}, nil
+}
+
+func (c *k8sClient) resourceVersion(ctx context.Context, namespace string) (string, error) {
+	// only the resource version of the list required
+	list, err := c.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return "", err
+	}
+
+	return list.ResourceVersion, nil
+}
+
+func (c *k8sClient) watch(ctx context.Context, namespace, resourceVersion string) (kubewatch.Interface, error) {
+	return c.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{Watch: true, ResourceVersion: resourceVersion})
+}
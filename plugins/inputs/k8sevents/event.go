@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package k8sevents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	kubeapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubewatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// handle feeds the event watched, an error returned if the watch should be restarted.
+func (ipt *Input) handle(ev kubewatch.Event) error {
+	switch ev.Type {
+	case kubewatch.Added, kubewatch.Modified:
+	case kubewatch.Error:
+		if status, ok := ev.Object.(*metav1.Status); ok {
+			return fmt.Errorf("watch error: %s", status.Message)
+		}
+		return fmt.Errorf("unexpected watch error: %v", ev.Object)
+	default: // Deleted and Bookmark ignored
+		return nil
+	}
+
+	event, ok := ev.Object.(*kubeapi.Event)
+	if !ok {
+		l.Warnf("unexpected object %T watched, ignored", ev.Object)
+		return nil
+	}
+
+	if ipt.paused() {
+		return nil
+	}
+
+	now := time.Now()
+	if !ipt.accept(event, now) {
+		return nil
+	}
+
+	if err := ipt.feeder.Feed(inputName, point.KeyEvent,
+		[]*point.Point{ipt.makePoint(event, now)}, nil); err != nil {
+		l.Errorf("feed: %s", err)
+	}
+
+	return nil
+}
+
+// accept checks the event by reasons filter, and drops events repeated
+// within the dedup interval.
+func (ipt *Input) accept(event *kubeapi.Event, now time.Time) bool {
+	if len(ipt.includeReasons) > 0 && !ipt.includeReasons[event.Reason] {
+		return false
+	}
+
+	if ipt.excludeReasons[event.Reason] {
+		return false
+	}
+
+	if ipt.DedupInterval.Duration <= 0 {
+		return true
+	}
+
+	key := dedupKey(event)
+
+	ipt.dedupMu.Lock()
+	defer ipt.dedupMu.Unlock()
+
+	if sent, ok := ipt.dedup[key]; ok && now.Sub(sent) < ipt.DedupInterval.Duration {
+		l.Debugf("event %s repeated, ignored", key)
+		return false
+	}
+
+	ipt.dedup[key] = now
+	return true
+}
+
+// expireDedup removes events sent before the dedup interval.
+func (ipt *Input) expireDedup(now time.Time) {
+	ipt.dedupMu.Lock()
+	defer ipt.dedupMu.Unlock()
+
+	for k, sent := range ipt.dedup {
+		if now.Sub(sent) >= ipt.DedupInterval.Duration {
+			delete(ipt.dedup, k)
+		}
+	}
+}
+
+func dedupKey(event *kubeapi.Event) string {
+	obj := event.InvolvedObject
+	return strings.Join([]string{obj.Kind, event.Namespace, obj.Name, string(obj.UID), event.Reason, event.Message}, "/")
+}
+
+func (ipt *Input) makePoint(event *kubeapi.Event, now time.Time) *point.Point {
+	obj := event.InvolvedObject
+
+	tags := map[string]string{
+		"namespace": event.Namespace,
+		"kind":      obj.Kind,
+		"name":      obj.Name,
+		"reason":    event.Reason,
+		"type":      event.Type,
+	}
+
+	if event.Source.Host != "" {
+		tags["node_name"] = event.Source.Host
+	}
+
+	component := event.Source.Component
+	if component == "" {
+		component = event.ReportingController
+	}
+	if component != "" {
+		tags["component"] = component
+	}
+
+	for k, v := range ipt.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	status := "info"
+	if event.Type == kubeapi.EventTypeWarning {
+		status = "warning"
+	}
+
+	count := int64(event.Count)
+	if event.Series != nil && int64(event.Series.Count) > count {
+		count = int64(event.Series.Count)
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	fields := map[string]interface{}{
+		"df_source":   inputName,
+		"df_status":   status,
+		"df_event_id": "k8sevents-" + string(event.UID),
+		"df_title":    fmt.Sprintf("%s %s/%s %s", obj.Kind, event.Namespace, obj.Name, event.Reason),
+		"df_message":  event.Message,
+		"count":       count,
+	}
+
+	if obj.UID != "" {
+		fields["object_uid"] = string(obj.UID)
+	}
+
+	opts := []point.Option{point.WithTime(eventTime(event, now))}
+	if ipt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+
+	return point.NewPointV2([]byte(eventName), append(point.NewTags(tags), point.NewKVs(fields)...), opts...)
+}
+
+// eventTime returns the latest time the event observed.
+func eventTime(event *kubeapi.Event, now time.Time) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return now
+	}
+}
@@ -0,0 +1,277 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package k8sevents watches Kubernetes Events as keyevents.
+package k8sevents
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "k8sevents"
+	eventName = "kubernetes_event"
+	catalog   = "container"
+
+	defaultDedupInterval = 5 * time.Minute
+	retryInterval        = time.Second
+)
+
+const sampleCfg = `
+[[inputs.k8sevents]]
+  ## Kubernetes API server and the bearer token of the service account,
+  ## bearer_token_string takes priority if set.
+  kubernetes_url = "https://kubernetes.default:443"
+  bearer_token = "/run/secrets/kubernetes.io/serviceaccount/token"
+  # bearer_token_string = "<your-token-string>"
+
+  ## Set true to watch on the elected DataKit only
+  election = true
+
+  ## Namespaces to watch, all namespaces if empty.
+  namespaces = []
+
+  ## Events of these reasons only, all if empty. Events of reasons excluded are
+  ## dropped even included.
+  include_reasons = []
+  exclude_reasons = []
+
+  ## Repeated events(same object, reason and message) within the interval are
+  ## sent only once, disabled if 0.
+  dedup_interval = "5m"
+
+  [inputs.k8sevents.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	K8sURL               string            `toml:"kubernetes_url"`
+	K8sBearerToken       string            `toml:"bearer_token"`
+	K8sBearerTokenString string            `toml:"bearer_token_string"`
+	Election             bool              `toml:"election"`
+	Namespaces           []string          `toml:"namespaces"`
+	IncludeReasons       []string          `toml:"include_reasons"`
+	ExcludeReasons       []string          `toml:"exclude_reasons"`
+	DedupInterval        datakit.Duration  `toml:"dedup_interval"`
+	Tags                 map[string]string `toml:"tags"`
+
+	client eventsClient
+
+	includeReasons,
+	excludeReasons map[string]bool
+
+	dedupMu sync.Mutex
+	dedup   map[string]time.Time // event key -> time sent
+
+	pauseMu sync.RWMutex
+	pause   bool
+	pauseCh chan bool
+
+	feeder  dkio.Feeder
+	semStop *cliutils.Sem // start stop signal
+}
+
+var (
+	_ inputs.ElectionInput = (*Input)(nil)
+	_ inputs.InputV2       = (*Input)(nil)
+)
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string {
+	return []string{datakit.OSLabelLinux, datakit.LabelK8s, datakit.LabelElection}
+}
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{&eventMeasurement{}}
+}
+
+func (ipt *Input) ElectionEnabled() bool {
+	return ipt.Election
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+
+	if ipt.client == nil {
+		cli, err := newK8sClient(ipt.K8sURL, ipt.K8sBearerToken, ipt.K8sBearerTokenString)
+		if err != nil {
+			l.Errorf("new kubernetes client: %s", err)
+			ipt.feeder.FeedLastError(inputName, fmt.Sprintf("new kubernetes client: %s", err))
+			return
+		}
+		ipt.client = cli
+	}
+
+	ipt.setup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	namespaces := ipt.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""} // all namespaces
+	}
+
+	var wg sync.WaitGroup
+	for _, ns := range namespaces {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			ipt.watchLoop(ctx, ns)
+		}(ns)
+	}
+
+	tick := time.NewTicker(time.Minute)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-datakit.Exit.Wait():
+			l.Info(inputName + " exit")
+			cancel()
+			wg.Wait()
+			return
+		case <-ipt.semStop.Wait():
+			l.Info(inputName + " return")
+			cancel()
+			wg.Wait()
+			return
+		case pause := <-ipt.pauseCh:
+			ipt.pauseMu.Lock()
+			ipt.pause = pause
+			ipt.pauseMu.Unlock()
+		case now := <-tick.C:
+			ipt.expireDedup(now)
+		}
+	}
+}
+
+func (ipt *Input) setup() {
+	ipt.includeReasons = map[string]bool{}
+	for _, r := range ipt.IncludeReasons {
+		ipt.includeReasons[r] = true
+	}
+
+	ipt.excludeReasons = map[string]bool{}
+	for _, r := range ipt.ExcludeReasons {
+		ipt.excludeReasons[r] = true
+	}
+
+	ipt.dedup = map[string]time.Time{}
+}
+
+func (ipt *Input) paused() bool {
+	ipt.pauseMu.RLock()
+	defer ipt.pauseMu.RUnlock()
+	return ipt.pause
+}
+
+// watchLoop watches events of the namespace, and watches again from the
+// current resource version if the watch closed.
+func (ipt *Input) watchLoop(ctx context.Context, namespace string) {
+	for {
+		if err := ipt.watch(ctx, namespace); err != nil {
+			l.Warnf("watch events of namespace %q: %s, retry", namespace, err)
+			ipt.feeder.FeedLastError(inputName, fmt.Sprintf("watch events of namespace %q: %s", namespace, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (ipt *Input) watch(ctx context.Context, namespace string) error {
+	rv, err := ipt.client.resourceVersion(ctx, namespace)
+	if err != nil {
+		return fmt.Errorf("list events: %w", err)
+	}
+
+	w, err := ipt.client.watch(ctx, namespace, rv)
+	if err != nil {
+		return fmt.Errorf("watch events: %w", err)
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				l.Infof("event watch of namespace %q closed", namespace)
+				return nil
+			}
+
+			if err := ipt.handle(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (ipt *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (ipt *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		K8sURL:         "https://kubernetes.default:443",
+		K8sBearerToken: "/run/secrets/kubernetes.io/serviceaccount/token",
+		Election:       true,
+		DedupInterval:  datakit.Duration{Duration: defaultDedupInterval},
+		Tags:           map[string]string{},
+		pauseCh:        make(chan bool, inputs.ElectionPauseChannelLength),
+		feeder:         dkio.DefaultFeeder(),
+		semStop:        cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
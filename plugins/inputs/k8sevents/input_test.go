@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package k8sevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	kubeapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubewatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type fakeClient struct {
+	w         *kubewatch.FakeWatcher
+	namespace string
+}
+
+func (c *fakeClient) resourceVersion(_ context.Context, namespace string) (string, error) {
+	c.namespace = namespace
+	return "100", nil
+}
+
+func (c *fakeClient) watch(_ context.Context, _, resourceVersion string) (kubewatch.Interface, error) {
+	if resourceVersion != "100" {
+		panic("unexpected resource version " + resourceVersion)
+	}
+	return c.w, nil
+}
+
+func newEvent(uid, reason, typ, msg string) *kubeapi.Event {
+	return &kubeapi.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1." + uid, Namespace: "default", UID: types.UID(uid)},
+		InvolvedObject: kubeapi.ObjectReference{
+			Kind: "Pod",
+			Name: "web-1",
+			UID:  "pod-uid-1",
+		},
+		Reason:        reason,
+		Type:          typ,
+		Message:       msg,
+		Count:         3,
+		Source:        kubeapi.EventSource{Component: "kubelet", Host: "node-1"},
+		LastTimestamp: metav1.NewTime(time.Unix(1700000000, 0)),
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ipt := defaultInput()
+	ipt.Election = false
+	ipt.ExcludeReasons = []string{"Pulled"}
+	ipt.Tags["cluster"] = "c1"
+
+	cli := &fakeClient{w: kubewatch.NewFakeWithChanSize(10, false)}
+	ipt.client = cli
+
+	feeder := dkio.NewMockedFeeder()
+	ipt.feeder = feeder
+	ipt.setup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- ipt.watch(ctx, "default") }()
+
+	cli.w.Add(newEvent("e1", "BackOff", kubeapi.EventTypeWarning, "Back-off restarting failed container"))
+	cli.w.Modify(newEvent("e1", "BackOff", kubeapi.EventTypeWarning, "Back-off restarting failed container")) // repeated
+	cli.w.Add(newEvent("e2", "Pulled", kubeapi.EventTypeNormal, "Image pulled"))                              // excluded
+	cli.w.Delete(newEvent("e3", "Started", kubeapi.EventTypeNormal, "Started container"))                     // deleted
+	cli.w.Add(newEvent("e4", "Started", kubeapi.EventTypeNormal, "Started container"))
+
+	pts, err := feeder.NPoints(2, time.Second)
+	require.NoError(t, err)
+	require.Len(t, pts, 2)
+
+	pt := pts[0]
+	assert.Equal(t, eventName, string(pt.Name()))
+	assert.Equal(t, []byte("default"), pt.Get([]byte("namespace")))
+	assert.Equal(t, []byte("Pod"), pt.Get([]byte("kind")))
+	assert.Equal(t, []byte("web-1"), pt.Get([]byte("name")))
+	assert.Equal(t, []byte("BackOff"), pt.Get([]byte("reason")))
+	assert.Equal(t, []byte("node-1"), pt.Get([]byte("node_name")))
+	assert.Equal(t, []byte("kubelet"), pt.Get([]byte("component")))
+	assert.Equal(t, []byte("c1"), pt.Get([]byte("cluster")))
+	assert.Equal(t, []byte("warning"), pt.Get([]byte("df_status")))
+	assert.Equal(t, []byte("k8sevents-e1"), pt.Get([]byte("df_event_id")))
+	assert.Equal(t, []byte("Pod default/web-1 BackOff"), pt.Get([]byte("df_title")))
+	assert.Equal(t, int64(3), pt.Get([]byte("count")))
+	assert.Equal(t, time.Unix(1700000000, 0).UnixNano(), pt.Time().UnixNano())
+
+	assert.Equal(t, []byte("Started"), pts[1].Get([]byte("reason")))
+	assert.Equal(t, []byte("info"), pts[1].Get([]byte("df_status")))
+
+	// repeated events sent again after the dedup interval
+	ipt.expireDedup(time.Now().Add(ipt.DedupInterval.Duration))
+	assert.Empty(t, ipt.dedup)
+
+	// watch restarted on error
+	cli.w.Error(&metav1.Status{Message: "too old resource version"})
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "too old resource version")
+	case <-time.After(time.Second):
+		t.Fatal("watch not returned on error")
+	}
+	assert.Equal(t, "default", cli.namespace)
+
+	t.Run("include-reasons-and-paused", func(t *testing.T) {
+		ipt := defaultInput()
+		ipt.IncludeReasons = []string{"BackOff"}
+		ipt.DedupInterval.Duration = 0
+		ipt.setup()
+
+		now := time.Now()
+		assert.True(t, ipt.accept(newEvent("e1", "BackOff", kubeapi.EventTypeWarning, "x"), now))
+		assert.True(t, ipt.accept(newEvent("e1", "BackOff", kubeapi.EventTypeWarning, "x"), now), "dedup disabled")
+		assert.False(t, ipt.accept(newEvent("e2", "Started", kubeapi.EventTypeNormal, "x"), now))
+
+		feeder := dkio.NewMockedFeeder()
+		ipt.feeder = feeder
+		ipt.pause = true
+		require.NoError(t, ipt.handle(kubewatch.Event{Type: kubewatch.Added, Object: newEvent("e1", "BackOff", kubeapi.EventTypeWarning, "x")}))
+
+		_, err := feeder.NPoints(1, 100*time.Millisecond)
+		assert.Error(t, err, "no points fed while paused")
+	})
+}
@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package k8sevents
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+type eventMeasurement struct{}
+
+func (*eventMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*eventMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: eventName,
+		Type: "keyevent",
+		Desc: "Keyevent of the Kubernetes Event, repeated ones within `dedup_interval` sent only once.",
+		Tags: map[string]interface{}{
+			"namespace": inputs.NewTagInfo("Namespace of the event"),
+			"kind":      inputs.NewTagInfo("Kind of the involved object, such as `Pod`"),
+			"name":      inputs.NewTagInfo("Name of the involved object"),
+			"reason":    inputs.NewTagInfo("Reason of the event, such as `BackOff`"),
+			"type":      inputs.NewTagInfo("Type of the event, `Normal` or `Warning`"),
+			"node_name": inputs.NewTagInfo("Node the event reported from, if any"),
+			"component": inputs.NewTagInfo("Component reported the event, such as `kubelet`"),
+		},
+		Fields: map[string]interface{}{
+			"df_source":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Source of the event, always `k8sevents`"},
+			"df_status":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Status of the event, `warning` for Warning events, `info` for others"},
+			"df_event_id": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event ID, `k8sevents-<UID of the event>`"},
+			"df_title":    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Title of the event, `<kind> <namespace>/<name> <reason>`"},
+			"df_message":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Message of the event"},
+			"count":       &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.NCount, Desc: "Times the event occurred"},
+			"object_uid":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "UID of the involved object"},
+		},
+	}
+}
@@ -152,7 +152,7 @@ func (ipt *Input) Run() {
 	log.Infof("init input = %v", ipt)
 
 	api := skywalkingapi.InitApiPluginAges(ipt.Plugins, ipt.localCacheConfig, ipt.CloseResource, ipt.KeepRareResource,
-		ipt.Sampler, ipt.CustomerTags, ipt.Tags, inputName)
+		ipt.Sampler, nil, nil, ipt.CustomerTags, ipt.Tags, inputName)
 	addrs := getAddrs(ipt.Addr, ipt.Addrs)
 	version := getKafkaVersion(ipt.KafkaVersion)
 	balance := getAssignors(ipt.Assignor)
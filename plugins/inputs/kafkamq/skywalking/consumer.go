@@ -105,13 +105,14 @@ func (c *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 					}
 					log.Debugf("unmarshal instance is= %+v", instance)
 				case meters:
-					meters := &agentv3.MeterData{}
+					meters := &agentv3.MeterDataCollection{}
 					err := proto.Unmarshal(msg.Value, meters)
 					if err != nil {
 						log.Warnf("Marshal err =%v , and val is [%s]", err, string(msg.Value))
 						break
 					}
 					log.Debugf("unmarshal Metrer is= %+v", meters)
+					api.ProcessMeters(meters.MeterData)
 				case logging:
 					pLog := &loggingv3.LogData{}
 					err := proto.Unmarshal(msg.Value, pLog)
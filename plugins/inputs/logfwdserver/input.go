@@ -26,6 +26,7 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	plscript "gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline/script"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
 
@@ -113,6 +114,7 @@ type message struct {
 	Source   string            `json:"source"`
 	Pipeline string            `json:"pipeline"`
 	Tags     map[string]string `json:"tags"`
+	Meta     map[string]string `json:"meta"`
 	Log      string            `json:"log"`
 }
 
@@ -161,6 +163,7 @@ func (ipt *Input) setup() bool {
 		if pts := makePts(msg.Source, []string{msg.Log}, tags); len(pts) > 0 {
 			if err := io.Feed(name, datakit.Logging, pts, &io.Option{
 				PlScript: map[string]string{msg.Source: msg.Pipeline},
+				PlOption: &plscript.Option{Meta: msg.Meta},
 			}); err != nil {
 				l.Errorf("logfwd failed to feed log, pod_name:%s filename:%s, err: %w", tags["pod_name"], tags["filename"], err)
 				return err
@@ -83,6 +83,11 @@ const (
   [inputs.logging.tags]
   # some_tag = "some_value"
   # more_tag = "some_other_value"
+
+  ## Structured metadata not uploaded as tags/fields, pipeline
+  ## can access them via __meta["some_key"].
+  [inputs.logging.meta]
+  # some_key = "some_value"
 `
 )
 
@@ -100,6 +105,7 @@ type Input struct {
 	AutoMultilineExtraPatterns []string          `toml:"auto_multiline_extra_patterns"`
 	RemoveAnsiEscapeCodes      bool              `toml:"remove_ansi_escape_codes"`
 	Tags                       map[string]string `toml:"tags"`
+	Meta                       map[string]string `toml:"meta"`
 	BlockingMode               bool              `toml:"blocking_mode"`
 	FromBeginning              bool              `toml:"from_beginning,omitempty"`
 	DockerMode                 bool              `toml:"docker_mode,omitempty"`
@@ -155,6 +161,7 @@ func (ipt *Input) Run() {
 		RemoveAnsiEscapeCodes: ipt.RemoveAnsiEscapeCodes,
 		IgnoreDeadLog:         ignoreDuration,
 		GlobalTags:            ipt.Tags,
+		Meta:                  ipt.Meta,
 		BlockingMode:          ipt.BlockingMode,
 		Done:                  ipt.semStop.Wait(),
 	}
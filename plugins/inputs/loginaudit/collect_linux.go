@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build linux
+// +build linux
+
+package loginaudit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type utmpCollector struct {
+	readers       []*utmpReader
+	fromBeginning bool
+}
+
+func newCollector(ipt *Input) collector {
+	c := &utmpCollector{fromBeginning: ipt.FromBeginning}
+	if ipt.WtmpFile != "" {
+		c.readers = append(c.readers, newUtmpReader(ipt.WtmpFile, false))
+	}
+	if ipt.BtmpFile != "" {
+		c.readers = append(c.readers, newUtmpReader(ipt.BtmpFile, true))
+	}
+	return c
+}
+
+// collect reads all files, events are sorted by time for brute-force
+// detection. Error of one file not stops reading others.
+func (c *utmpCollector) collect() ([]*loginEvent, error) {
+	var (
+		res  []*loginEvent
+		errs []string
+	)
+
+	for _, r := range c.readers {
+		events, err := r.read(c.fromBeginning)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		res = append(res, events...)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].ts.Before(res[j].ts) })
+
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return res, nil
+}
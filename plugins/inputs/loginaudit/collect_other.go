@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package loginaudit
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type unsupportedCollector struct{}
+
+func newCollector(_ *Input) collector {
+	return unsupportedCollector{}
+}
+
+func (unsupportedCollector) collect() ([]*loginEvent, error) {
+	return nil, fmt.Errorf("login audit not supported on %s", runtime.GOOS)
+}
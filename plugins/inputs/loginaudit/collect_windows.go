@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build windows
+// +build windows
+
+package loginaudit
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	wevtutilTimeout = 30 * time.Second
+
+	// max events read each round, the rest are read in the next round.
+	wevtutilBatch = 1000
+)
+
+type evtCollector struct {
+	fromBeginning bool
+
+	lastID uint64
+	inited bool
+}
+
+func newCollector(ipt *Input) collector {
+	return &evtCollector{fromBeginning: ipt.FromBeginning}
+}
+
+func (c *evtCollector) collect() ([]*loginEvent, error) {
+	if !c.inited {
+		if !c.fromBeginning {
+			// start from the newest record
+			out, err := wevtutil("qe", "Security", "/c:1", "/rd:true", "/f:xml")
+			if err != nil {
+				return nil, err
+			}
+
+			_, id, err := parseWinEvents(out)
+			if err != nil {
+				return nil, fmt.Errorf("parse events: %w", err)
+			}
+			c.lastID = id
+		}
+		c.inited = true
+	}
+
+	query := fmt.Sprintf("/q:*[System[(EventID=%d or EventID=%d or EventID=%d or EventID=%d) and EventRecordID>%d]]",
+		evtLogon, evtLogonFailed, evtLogoff, evtLogoffUser, c.lastID)
+
+	out, err := wevtutil("qe", "Security", query, fmt.Sprintf("/c:%d", wevtutilBatch), "/f:xml")
+	if err != nil {
+		return nil, err
+	}
+
+	events, id, err := parseWinEvents(out)
+	if id > c.lastID {
+		c.lastID = id
+	}
+	if err != nil {
+		return events, fmt.Errorf("parse events: %w", err)
+	}
+
+	return events, nil
+}
+
+func wevtutil(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wevtutilTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "wevtutil", args...).Output() //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("wevtutil %v: %w", args, err)
+	}
+	return out, nil
+}
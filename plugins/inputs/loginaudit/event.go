@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loginaudit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	eventLogin       = "login"
+	eventLogout      = "logout"
+	eventFailedLogin = "failed_login"
+
+	// source of failed logins without remote address, such as console logins.
+	localSource = "local"
+)
+
+type loginEvent struct {
+	kind string
+	ts   time.Time
+
+	user       string
+	sourceIP   string
+	sourceHost string
+	tty        string
+	pid        int64
+	logonType  string // Windows only
+}
+
+func (e *loginEvent) source() string {
+	if e.sourceIP != "" {
+		return e.sourceIP
+	}
+	return localSource
+}
+
+func (e *loginEvent) level() string {
+	if e.kind == eventFailedLogin {
+		return "warn"
+	}
+	return "info"
+}
+
+func (e *loginEvent) title() string {
+	switch e.kind {
+	case eventLogin:
+		return fmt.Sprintf("User %s logged in from %s", e.user, e.source())
+	case eventLogout:
+		return fmt.Sprintf("User %s logged out", e.user)
+	default:
+		return fmt.Sprintf("Failed login of user %s from %s", e.user, e.source())
+	}
+}
+
+func (e *loginEvent) message() string {
+	var sb strings.Builder
+	sb.WriteString(e.title())
+	if e.sourceHost != "" && e.sourceHost != e.sourceIP {
+		fmt.Fprintf(&sb, "\nsource host: %s", e.sourceHost)
+	}
+	if e.tty != "" {
+		fmt.Fprintf(&sb, "\ntty: %s", e.tty)
+	}
+	if e.logonType != "" {
+		fmt.Fprintf(&sb, "\nlogon type: %s", e.logonType)
+	}
+	return sb.String()
+}
+
+func (e *loginEvent) tags() map[string]string {
+	return map[string]string{
+		"category": "system",
+		"event":    e.kind,
+		"level":    e.level(),
+	}
+}
+
+func (e *loginEvent) fields() map[string]interface{} {
+	f := map[string]interface{}{
+		"title":     e.title(),
+		"message":   e.message(),
+		"user":      e.user,
+		"source_ip": e.sourceIP,
+	}
+
+	if e.sourceHost != "" {
+		f["source_host"] = e.sourceHost
+	}
+	if e.tty != "" {
+		f["tty"] = e.tty
+	}
+	if e.pid > 0 {
+		f["pid"] = e.pid
+	}
+	if e.logonType != "" {
+		f["logon_type"] = e.logonType
+	}
+	return f
+}
+
+// bruteForce is the brute-force pattern detected: failures failed logins
+// from the same source within the window.
+type bruteForce struct {
+	source   string
+	failures int
+	users    []string
+	first    time.Time
+	last     time.Time
+	window   time.Duration
+}
+
+func (bf *bruteForce) title() string {
+	return fmt.Sprintf("Possible brute-force attack from %s: %d failed logins within %s",
+		bf.source, bf.failures, bf.window)
+}
+
+func (bf *bruteForce) message() string {
+	var sb strings.Builder
+	sb.WriteString(bf.title())
+	fmt.Fprintf(&sb, "\nusers: %s", strings.Join(bf.users, ","))
+	fmt.Fprintf(&sb, "\nfirst failure: %s", bf.first.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "\nlast failure: %s", bf.last.Format(time.RFC3339))
+	return sb.String()
+}
+
+func (bf *bruteForce) tags() map[string]string {
+	return map[string]string{
+		"source_ip": bf.source,
+	}
+}
+
+func (bf *bruteForce) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"df_source":     "system",
+		"df_status":     "warning",
+		"df_event_id":   fmt.Sprintf("%s-brute_force-%s", inputName, bf.source),
+		"df_title":      bf.title(),
+		"df_message":    bf.message(),
+		"df_date_range": int64(bf.window / time.Second),
+		"failures":      int64(bf.failures),
+		"users":         strings.Join(bf.users, ","),
+	}
+}
+
+// bruteForceDetector counts failed logins of each source in a sliding window.
+type bruteForceDetector struct {
+	threshold int
+	window    time.Duration
+
+	failures map[string][]*loginEvent // source -> failed logins within window, oldest first
+}
+
+func newBruteForceDetector(threshold int, window time.Duration) *bruteForceDetector {
+	return &bruteForceDetector{
+		threshold: threshold,
+		window:    window,
+		failures:  map[string][]*loginEvent{},
+	}
+}
+
+// add counts e if it's a failed login, and returns the brute-force detected
+// if failures of the source reach the threshold. Failures of the source are
+// cleared on detected, so the keyevent is sent once for each threshold reached.
+func (d *bruteForceDetector) add(e *loginEvent) *bruteForce {
+	if d.threshold <= 0 || e.kind != eventFailedLogin {
+		return nil
+	}
+
+	src := e.source()
+	arr := append(d.expire(d.failures[src], e.ts), e)
+
+	if len(arr) < d.threshold {
+		d.failures[src] = arr
+		return nil
+	}
+
+	delete(d.failures, src)
+
+	bf := &bruteForce{
+		source:   src,
+		failures: len(arr),
+		first:    arr[0].ts,
+		last:     e.ts,
+		window:   d.window,
+	}
+
+	users := map[string]bool{}
+	for _, x := range arr {
+		if !users[x.user] {
+			users[x.user] = true
+			bf.users = append(bf.users, x.user)
+		}
+	}
+	sort.Strings(bf.users)
+
+	return bf
+}
+
+// expire drops failures out of the window ending at now.
+func (d *bruteForceDetector) expire(arr []*loginEvent, now time.Time) []*loginEvent {
+	i := 0
+	for ; i < len(arr); i++ {
+		if now.Sub(arr[i].ts) < d.window {
+			break
+		}
+	}
+	return arr[i:]
+}
+
+// gc removes sources without failures within the window.
+func (d *bruteForceDetector) gc(now time.Time) {
+	for src, arr := range d.failures {
+		if arr = d.expire(arr, now); len(arr) == 0 {
+			delete(d.failures, src)
+		} else {
+			d.failures[src] = arr
+		}
+	}
+}
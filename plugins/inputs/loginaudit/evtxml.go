@@ -0,0 +1,140 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loginaudit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IDs of Windows security events audited.
+const (
+	evtLogon       = 4624
+	evtLogonFailed = 4625
+	evtLogoff      = 4634
+	evtLogoffUser  = 4647
+
+	// logon type of services, they are too noisy to audit.
+	logonTypeService = "5"
+)
+
+type winEvent struct {
+	System struct {
+		EventID       int `xml:"EventID"`
+		EventRecordID uint64
+		TimeCreated   struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		}
+	}
+	EventData struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		}
+	}
+}
+
+func (we *winEvent) data(name string) string {
+	for _, d := range we.EventData.Data {
+		if d.Name == name {
+			v := strings.TrimSpace(d.Value)
+			if v == "-" { // placeholder of empty value
+				return ""
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// parseWinEvents parses output of `wevtutil qe Security /f:xml`, which are
+// <Event> elements without a root. It returns login events and the max
+// record ID seen.
+func parseWinEvents(data []byte) ([]*loginEvent, uint64, error) {
+	var (
+		res    []*loginEvent
+		lastID uint64
+	)
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return res, lastID, err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "Event" {
+			continue
+		}
+
+		var we winEvent
+		if err := dec.DecodeElement(&we, &se); err != nil {
+			return res, lastID, err
+		}
+
+		if we.System.EventRecordID > lastID {
+			lastID = we.System.EventRecordID
+		}
+
+		if e := winLoginEvent(&we); e != nil {
+			res = append(res, e)
+		}
+	}
+
+	return res, lastID, nil
+}
+
+func winLoginEvent(we *winEvent) *loginEvent {
+	e := &loginEvent{
+		user:       we.data("TargetUserName"),
+		sourceIP:   we.data("IpAddress"),
+		sourceHost: we.data("WorkstationName"),
+		logonType:  we.data("LogonType"),
+	}
+
+	if ts, err := time.Parse(time.RFC3339Nano, we.System.TimeCreated.SystemTime); err == nil {
+		e.ts = ts
+	} else {
+		e.ts = time.Now()
+	}
+
+	if pid, err := strconv.ParseInt(strings.TrimPrefix(we.data("ProcessId"), "0x"), 16, 64); err == nil {
+		e.pid = pid
+	}
+
+	// local logons recorded with loopback address
+	if e.sourceIP == "127.0.0.1" || e.sourceIP == "::1" {
+		e.sourceIP = ""
+	}
+
+	switch we.System.EventID {
+	case evtLogon:
+		e.kind = eventLogin
+	case evtLogoff, evtLogoffUser:
+		e.kind = eventLogout
+	case evtLogonFailed:
+		e.kind = eventFailedLogin
+		return e
+	default:
+		return nil
+	}
+
+	// skip successful logons/logoffs of services and computer accounts
+	if e.logonType == logonTypeService || strings.HasSuffix(e.user, "$") {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,277 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package loginaudit collects user login/logout and failed authentication events.
+package loginaudit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var (
+	_ inputs.ReadEnv   = (*Input)(nil)
+	_ inputs.Singleton = (*Input)(nil)
+)
+
+const (
+	minInterval = time.Second
+	maxInterval = 10 * time.Minute
+
+	defaultBruteForceFailures = 5
+	defaultBruteForceWindow   = 5 * time.Minute
+)
+
+const (
+	inputName     = "loginaudit"
+	securityName  = "login_audit"
+	bruteForceEvt = "login_brute_force"
+	sampleCfg     = `
+[[inputs.loginaudit]]
+  ## Interval between two reads of login records.
+  interval = "10s"
+
+  ## Login records on Linux, wtmp for login/logout and btmp for failed logins.
+  ## They are ignored on Windows, where the Security event log is read.
+  wtmp_file = "/var/log/wtmp"
+  btmp_file = "/var/log/btmp"
+
+  ## Report records existing before DataKit started, only new records are
+  ## reported by default.
+  from_beginning = false
+
+  ## Send a keyevent when failed logins from the same source reach
+  ## bruteforce_failures within bruteforce_window, set bruteforce_failures
+  ## to 0 to disable it.
+  bruteforce_failures = 5
+  bruteforce_window = "5m"
+
+[inputs.loginaudit.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	Interval           datakit.Duration  `toml:"interval"`
+	WtmpFile           string            `toml:"wtmp_file"`
+	BtmpFile           string            `toml:"btmp_file"`
+	FromBeginning      bool              `toml:"from_beginning"`
+	BruteForceFailures int               `toml:"bruteforce_failures"`
+	BruteForceWindow   datakit.Duration  `toml:"bruteforce_window"`
+	Tags               map[string]string `toml:"tags"`
+
+	collector collector
+	detector  *bruteForceDetector
+
+	semStop *cliutils.Sem
+}
+
+// collector reads login events happened since last read.
+type collector interface {
+	collect() ([]*loginEvent, error)
+}
+
+func (ipt *Input) Singleton() {}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	l.Info("loginaudit input started")
+
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+	if ipt.BruteForceWindow.Duration <= 0 {
+		ipt.BruteForceWindow.Duration = defaultBruteForceWindow
+	}
+
+	ipt.collector = newCollector(ipt)
+	ipt.detector = newBruteForceDetector(ipt.BruteForceFailures, ipt.BruteForceWindow.Duration)
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		ipt.collect()
+
+		select {
+		case <-tick.C:
+		case <-datakit.Exit.Wait():
+			l.Info("loginaudit input exit")
+			return
+		case <-ipt.semStop.Wait():
+			l.Info("loginaudit input return")
+			return
+		}
+	}
+}
+
+func (ipt *Input) collect() {
+	start := time.Now()
+
+	events, err := ipt.collector.collect()
+	if err != nil {
+		l.Errorf("collect: %s", err)
+		io.FeedLastError(inputName, err.Error())
+	}
+
+	ipt.feed(events, start)
+}
+
+func (ipt *Input) feed(events []*loginEvent, start time.Time) {
+	var (
+		pts    []inputs.Measurement
+		alerts []inputs.Measurement
+	)
+
+	for _, e := range events {
+		pts = append(pts, &loginMeasurement{
+			name:   securityName,
+			tags:   ipt.mergeTags(e.tags()),
+			fields: e.fields(),
+			ts:     e.ts,
+		})
+
+		if bf := ipt.detector.add(e); bf != nil {
+			alerts = append(alerts, &bruteForceMeasurement{
+				name:   bruteForceEvt,
+				tags:   ipt.mergeTags(bf.tags()),
+				fields: bf.fields(),
+				ts:     bf.last,
+			})
+		}
+	}
+
+	ipt.detector.gc(start)
+
+	if len(pts) > 0 {
+		if err := inputs.FeedMeasurement(securityName, datakit.Security, pts,
+			&io.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("FeedMeasurement: %s", err)
+		}
+	}
+
+	if len(alerts) > 0 {
+		if err := inputs.FeedMeasurement(bruteForceEvt, datakit.KeyEvent, alerts, nil); err != nil {
+			l.Errorf("FeedMeasurement: %s", err)
+		}
+	}
+}
+
+func (ipt *Input) mergeTags(tags map[string]string) map[string]string {
+	for k, v := range ipt.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (*Input) Catalog() string { return "host" }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string {
+	return []string{datakit.OSLabelLinux, datakit.OSLabelWindows}
+}
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&loginMeasurement{},
+		&bruteForceMeasurement{},
+	}
+}
+
+// ReadEnv support envs：only for K8S.
+func (ipt *Input) ReadEnv(envs map[string]string) {
+	// ENV_INPUT_LOGINAUDIT_INTERVAL : datakit.Duration
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_INTERVAL"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_LOGINAUDIT_INTERVAL to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, da)
+		}
+	}
+
+	// ENV_INPUT_LOGINAUDIT_WTMP_FILE : string
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_WTMP_FILE"]; ok {
+		ipt.WtmpFile = str
+	}
+
+	// ENV_INPUT_LOGINAUDIT_BTMP_FILE : string
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_BTMP_FILE"]; ok {
+		ipt.BtmpFile = str
+	}
+
+	// ENV_INPUT_LOGINAUDIT_FROM_BEGINNING : bool
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_FROM_BEGINNING"]; ok {
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_LOGINAUDIT_FROM_BEGINNING: %s, ignore", err)
+		} else {
+			ipt.FromBeginning = b
+		}
+	}
+
+	// ENV_INPUT_LOGINAUDIT_BRUTEFORCE_FAILURES : int
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_BRUTEFORCE_FAILURES"]; ok {
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_LOGINAUDIT_BRUTEFORCE_FAILURES: %s, ignore", err)
+		} else {
+			ipt.BruteForceFailures = n
+		}
+	}
+
+	// ENV_INPUT_LOGINAUDIT_BRUTEFORCE_WINDOW : datakit.Duration
+	if str, ok := envs["ENV_INPUT_LOGINAUDIT_BRUTEFORCE_WINDOW"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_LOGINAUDIT_BRUTEFORCE_WINDOW to time.Duration: %s, ignore", err)
+		} else {
+			ipt.BruteForceWindow.Duration = da
+		}
+	}
+
+	// ENV_INPUT_LOGINAUDIT_TAGS : "a=b,c=d"
+	if tagsStr, ok := envs["ENV_INPUT_LOGINAUDIT_TAGS"]; ok {
+		tags := config.ParseGlobalTags(tagsStr)
+		for k, v := range tags {
+			ipt.Tags[k] = v
+		}
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Interval:           datakit.Duration{Duration: 10 * time.Second},
+		WtmpFile:           "/var/log/wtmp",
+		BtmpFile:           "/var/log/btmp",
+		BruteForceFailures: defaultBruteForceFailures,
+		BruteForceWindow:   datakit.Duration{Duration: defaultBruteForceWindow},
+		Tags:               map[string]string{},
+		semStop:            cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
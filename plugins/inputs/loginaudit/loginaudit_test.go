@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loginaudit
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utmpBytes(typ int16, pid int32, line, user, host string, ip net.IP, ts time.Time) []byte {
+	b := make([]byte, utmpRecordSize)
+	le := binary.LittleEndian
+
+	le.PutUint16(b[0:], uint16(typ))
+	le.PutUint32(b[4:], uint32(pid))
+	copy(b[8:8+utmpLineSize], line)
+	copy(b[44:44+utmpUserSize], user)
+	copy(b[76:76+utmpHostSize], host)
+	le.PutUint32(b[340:], uint32(ts.Unix()))
+	le.PutUint32(b[344:], uint32(ts.Nanosecond()/1000))
+	if v4 := ip.To4(); v4 != nil {
+		copy(b[348:], v4)
+	} else {
+		copy(b[348:], ip)
+	}
+	return b
+}
+
+func TestParseUtmp(t *T.T) {
+	ts := time.Unix(1700000000, 123000)
+
+	var data []byte
+	data = append(data, utmpBytes(utmpUserProcess, 1234, "pts/0", "alice", "10.0.0.1", net.ParseIP("10.0.0.1"), ts)...)
+	data = append(data, utmpBytes(utmpUserProcess, 1235, "pts/1", "bob", "fe80::1", net.ParseIP("fe80::1"), ts)...)
+	data = append(data, utmpBytes(utmpUserProcess, 1236, "tty1", "root", "", nil, ts)...)
+	data = append(data, []byte("partial")...)
+
+	records := parseUtmp(data)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, int16(utmpUserProcess), records[0].typ)
+	assert.Equal(t, int32(1234), records[0].pid)
+	assert.Equal(t, "pts/0", records[0].line)
+	assert.Equal(t, "alice", records[0].user)
+	assert.Equal(t, "10.0.0.1", records[0].addr)
+	assert.True(t, ts.Equal(records[0].ts))
+
+	assert.Equal(t, "fe80::1", records[1].addr)
+	assert.Equal(t, "", records[2].addr)
+}
+
+func TestUtmpReader(t *T.T) {
+	dir := t.TempDir()
+	wtmp := filepath.Join(dir, "wtmp")
+	ts := time.Unix(1700000000, 0)
+
+	write := func(data []byte) {
+		f, err := os.OpenFile(wtmp, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	// login before started
+	write(utmpBytes(utmpUserProcess, 100, "pts/0", "alice", "10.0.0.1", net.ParseIP("10.0.0.1"), ts))
+
+	r := newUtmpReader(wtmp, false)
+	events, err := r.read(false)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	write(utmpBytes(utmpDeadProcess, 100, "pts/0", "", "", nil, ts.Add(time.Minute)))
+	write(utmpBytes(utmpDeadProcess, 200, "tty1", "", "", nil, ts.Add(time.Minute))) // getty
+	write(utmpBytes(utmpUserProcess, 300, "pts/1", "bob", "", nil, ts.Add(2*time.Minute)))
+
+	events, err = r.read(false)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, eventLogout, events[0].kind)
+	assert.Equal(t, "alice", events[0].user)
+	assert.Equal(t, eventLogin, events[1].kind)
+	assert.Equal(t, "bob", events[1].user)
+	assert.Equal(t, localSource, events[1].source())
+
+	// rotated
+	require.NoError(t, os.Remove(wtmp))
+	write(utmpBytes(utmpDeadProcess, 300, "pts/1", "", "", nil, ts.Add(3*time.Minute)))
+
+	events, err = r.read(false)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, eventLogout, events[0].kind)
+	assert.Equal(t, "bob", events[0].user)
+
+	t.Run("btmp-from-beginning", func(t *T.T) {
+		btmp := filepath.Join(dir, "btmp")
+		require.NoError(t, os.WriteFile(btmp,
+			utmpBytes(6, 400, "ssh:notty", "admin", "1.2.3.4", net.ParseIP("1.2.3.4"), ts), 0o600))
+
+		events, err := newUtmpReader(btmp, true).read(true)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, eventFailedLogin, events[0].kind)
+		assert.Equal(t, "admin", events[0].user)
+		assert.Equal(t, "1.2.3.4", events[0].sourceIP)
+	})
+}
+
+func TestParseWinEvents(t *T.T) {
+	out := `<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Microsoft-Windows-Security-Auditing'/><EventID>4624</EventID><TimeCreated SystemTime='2023-11-14T22:13:20.1234567Z'/><EventRecordID>101</EventRecordID></System><EventData><Data Name='TargetUserName'>alice</Data><Data Name='LogonType'>10</Data><Data Name='IpAddress'>10.0.0.1</Data><Data Name='WorkstationName'>WS01</Data><Data Name='ProcessId'>0x2a4</Data></EventData></Event>
+<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><EventID>4624</EventID><TimeCreated SystemTime='2023-11-14T22:13:21Z'/><EventRecordID>102</EventRecordID></System><EventData><Data Name='TargetUserName'>SYSTEM</Data><Data Name='LogonType'>5</Data><Data Name='IpAddress'>-</Data></EventData></Event>
+<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><EventID>4625</EventID><TimeCreated SystemTime='2023-11-14T22:13:22Z'/><EventRecordID>103</EventRecordID></System><EventData><Data Name='TargetUserName'>admin</Data><Data Name='LogonType'>3</Data><Data Name='IpAddress'>1.2.3.4</Data></EventData></Event>
+<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><EventID>4634</EventID><TimeCreated SystemTime='2023-11-14T22:13:23Z'/><EventRecordID>104</EventRecordID></System><EventData><Data Name='TargetUserName'>alice</Data><Data Name='LogonType'>10</Data></EventData></Event>
+`
+
+	events, lastID, err := parseWinEvents([]byte(out))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(104), lastID)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, eventLogin, events[0].kind)
+	assert.Equal(t, "alice", events[0].user)
+	assert.Equal(t, "10.0.0.1", events[0].sourceIP)
+	assert.Equal(t, "WS01", events[0].sourceHost)
+	assert.Equal(t, "10", events[0].logonType)
+	assert.Equal(t, int64(0x2a4), events[0].pid)
+
+	assert.Equal(t, eventFailedLogin, events[1].kind)
+	assert.Equal(t, "1.2.3.4", events[1].sourceIP)
+
+	assert.Equal(t, eventLogout, events[2].kind)
+	assert.Equal(t, "", events[2].sourceIP)
+}
+
+func TestBruteForceDetector(t *T.T) {
+	d := newBruteForceDetector(3, time.Minute)
+	ts := time.Unix(1700000000, 0)
+
+	failed := func(ip, user string, off time.Duration) *loginEvent {
+		return &loginEvent{kind: eventFailedLogin, sourceIP: ip, user: user, ts: ts.Add(off)}
+	}
+
+	assert.Nil(t, d.add(failed("1.2.3.4", "root", 0)))
+	assert.Nil(t, d.add(&loginEvent{kind: eventLogin, sourceIP: "1.2.3.4", user: "root", ts: ts}))
+	assert.Nil(t, d.add(failed("5.6.7.8", "root", time.Second)))
+	assert.Nil(t, d.add(failed("1.2.3.4", "admin", 10*time.Second)))
+
+	// the first failure expired
+	assert.Nil(t, d.add(failed("1.2.3.4", "admin", 61*time.Second)))
+
+	bf := d.add(failed("1.2.3.4", "test", 62*time.Second))
+	require.NotNil(t, bf)
+	assert.Equal(t, "1.2.3.4", bf.source)
+	assert.Equal(t, 3, bf.failures)
+	assert.Equal(t, []string{"admin", "test"}, bf.users)
+	assert.Equal(t, ts.Add(10*time.Second), bf.first)
+
+	f := bf.fields()
+	assert.Equal(t, "loginaudit-brute_force-1.2.3.4", f["df_event_id"])
+	assert.Equal(t, int64(60), f["df_date_range"])
+	assert.Equal(t, "admin,test", f["users"])
+
+	// reset after detected
+	assert.Nil(t, d.add(failed("1.2.3.4", "root", 63*time.Second)))
+
+	d.gc(ts.Add(time.Hour))
+	assert.Empty(t, d.failures)
+
+	t.Run("disabled", func(t *T.T) {
+		d := newBruteForceDetector(0, time.Minute)
+		for i := 0; i < 10; i++ {
+			assert.Nil(t, d.add(failed("1.2.3.4", "root", 0)))
+		}
+	})
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loginaudit
+
+import (
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+type loginMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *loginMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.Security, Time: m.ts})
+}
+
+//nolint:lll
+func (m *loginMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: securityName,
+		Type: "security",
+		Desc: "Login, logout and failed login events of users.",
+		Tags: map[string]interface{}{
+			"host":     &inputs.TagInfo{Desc: "Hostname"},
+			"category": &inputs.TagInfo{Desc: "Category of the event, always `system`"},
+			"event":    &inputs.TagInfo{Desc: "Type of the event, one of `login/logout/failed_login`"},
+			"level":    &inputs.TagInfo{Desc: "Level of the event, `warn` for failed login, otherwise `info`"},
+		},
+		Fields: map[string]interface{}{
+			"title":       &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Title of the event"},
+			"message":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Detail of the event"},
+			"user":        &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "User name, the attempted one for failed login"},
+			"source_ip":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "IP address the user logged in from, empty for local login"},
+			"source_host": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Remote host name recorded(Linux) or workstation name(Windows), absent if unknown"},
+			"tty":         &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Terminal of the session, Linux only"},
+			"pid":         &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.UnknownUnit, Desc: "Process ID of the login process, absent if unknown"},
+			"logon_type":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Logon type, such as `2`(interactive) and `10`(remote interactive), Windows only"},
+		},
+	}
+}
+
+type bruteForceMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *bruteForceMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.KeyEvent, Time: m.ts})
+}
+
+//nolint:lll
+func (m *bruteForceMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: bruteForceEvt,
+		Type: "keyevent",
+		Desc: "Keyevent sent when failed logins from the same source reach `bruteforce_failures` within `bruteforce_window`.",
+		Tags: map[string]interface{}{
+			"host":      &inputs.TagInfo{Desc: "Hostname"},
+			"source_ip": &inputs.TagInfo{Desc: "IP address of the failed logins, `local` if no remote address"},
+		},
+		Fields: map[string]interface{}{
+			"df_source":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Source of the event, always `system`"},
+			"df_status":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Status of the event, always `warning`"},
+			"df_event_id":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event ID"},
+			"df_title":      &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Title of the event"},
+			"df_message":    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Detail of the event"},
+			"df_date_range": &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Detection window"},
+			"failures":      &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Number of failed logins within the window"},
+			"users":         &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Users attempted, separated by comma"},
+		},
+	}
+}
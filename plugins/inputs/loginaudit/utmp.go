@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package loginaudit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Layout of struct utmp of glibc on 64-bit Linux, see utmp(5).
+const (
+	utmpRecordSize = 384
+
+	utmpUserProcess = 7
+	utmpDeadProcess = 8
+
+	utmpLineSize = 32
+	utmpUserSize = 32
+	utmpHostSize = 256
+)
+
+type utmpRecord struct {
+	typ  int16
+	pid  int32
+	line string
+	user string
+	host string
+	addr string
+	ts   time.Time
+}
+
+// parseUtmp parses records in data, the trailing partial record is ignored.
+func parseUtmp(data []byte) []*utmpRecord {
+	var res []*utmpRecord
+	for off := 0; off+utmpRecordSize <= len(data); off += utmpRecordSize {
+		res = append(res, parseUtmpRecord(data[off:off+utmpRecordSize]))
+	}
+	return res
+}
+
+func parseUtmpRecord(b []byte) *utmpRecord {
+	le := binary.LittleEndian
+
+	r := &utmpRecord{
+		typ:  int16(le.Uint16(b[0:])),
+		pid:  int32(le.Uint32(b[4:])),
+		line: cstring(b[8 : 8+utmpLineSize]),
+		user: cstring(b[44 : 44+utmpUserSize]),
+		host: cstring(b[76 : 76+utmpHostSize]),
+		ts: time.Unix(int64(int32(le.Uint32(b[340:]))),
+			int64(int32(le.Uint32(b[344:])))*int64(time.Microsecond)),
+	}
+
+	// ut_addr_v6: IPv4 address is in the first 4 bytes only.
+	addr := b[348:364]
+	switch {
+	case bytes.Count(addr, []byte{0}) == len(addr):
+	case bytes.Count(addr[4:], []byte{0}) == len(addr)-4:
+		r.addr = net.IP(addr[:4]).String()
+	default:
+		r.addr = net.IP(addr).String()
+	}
+
+	if r.addr == "" && net.ParseIP(r.host) != nil {
+		r.addr = r.host
+	}
+
+	return r
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// utmpReader reads records appended to wtmp/btmp since last read.
+type utmpReader struct {
+	path   string
+	failed bool // btmp, all records are failed logins
+
+	offset int64
+	inited bool
+
+	// tty -> user of sessions logged in, for logout records of wtmp
+	// have no user.
+	sessions map[string]string
+}
+
+func newUtmpReader(path string, failed bool) *utmpReader {
+	return &utmpReader{
+		path:     path,
+		failed:   failed,
+		sessions: map[string]string{},
+	}
+}
+
+// read returns events of new records. On first read, records existing are
+// only used to track sessions unless fromBeginning.
+func (r *utmpReader) read(fromBeginning bool) ([]*loginEvent, error) {
+	f, err := os.Open(filepath.Clean(r.path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.Size() < r.offset { // rotated or truncated
+		r.offset = 0
+	}
+
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, fi.Size()-r.offset))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", r.path, err)
+	}
+
+	data = data[:len(data)/utmpRecordSize*utmpRecordSize]
+	r.offset += int64(len(data))
+
+	events := r.events(parseUtmp(data))
+
+	if !r.inited {
+		r.inited = true
+		if !fromBeginning {
+			return nil, nil
+		}
+	}
+
+	return events, nil
+}
+
+func (r *utmpReader) events(records []*utmpRecord) []*loginEvent {
+	var res []*loginEvent
+
+	for _, rec := range records {
+		e := &loginEvent{
+			ts:         rec.ts,
+			user:       rec.user,
+			sourceIP:   rec.addr,
+			sourceHost: rec.host,
+			tty:        rec.line,
+			pid:        int64(rec.pid),
+		}
+
+		switch {
+		case r.failed:
+			e.kind = eventFailedLogin
+
+		case rec.typ == utmpUserProcess:
+			e.kind = eventLogin
+			r.sessions[rec.line] = rec.user
+
+		case rec.typ == utmpDeadProcess:
+			user, ok := r.sessions[rec.line]
+			if !ok { // not a user session, such as getty exited
+				continue
+			}
+			delete(r.sessions, rec.line)
+
+			e.kind = eventLogout
+			e.user = user
+			e.sourceIP, e.sourceHost = "", ""
+
+		default: // boot, run level and so on
+			continue
+		}
+
+		res = append(res, e)
+	}
+
+	return res
+}
@@ -7,11 +7,19 @@ package logstreaming
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 )
 
 const body = `body`
@@ -37,3 +45,81 @@ func TestLogstreamingHandler(t *testing.T) {
 
 	t.Log(string(b))
 }
+
+func TestHandleLogstreamingResult(t *testing.T) {
+	var (
+		feedErr error
+		fedOpt  *dkio.Option
+		fed     int
+	)
+
+	feed = func(name, category string, pts []*point.Point, opt *dkio.Option) error {
+		fedOpt = opt
+		if feedErr != nil {
+			return feedErr
+		}
+		fed += len(pts)
+		return nil
+	}
+	defer func() { feed = dkio.Feed }()
+
+	ipt := &Input{}
+
+	post := func(w http.ResponseWriter, query, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/write/logstreaming?"+query, strings.NewReader(body))
+		ipt.handleLogstreaming(w, req)
+	}
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) *result {
+		t.Helper()
+
+		var res result
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return &res
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		feedErr, fed = nil, 0
+
+		w := httptest.NewRecorder()
+		post(w, "source=nginx", "line1\nline2\nline3\n")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, &result{Status: "success", Accepted: 3}, decode(t, w))
+		assert.Equal(t, 3, fed)
+		assert.True(t, fedOpt.NonBlocking)
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		feedErr, fed = dkio.ErrIOBusy, 0
+
+		w := httptest.NewRecorder()
+		post(w, "source=nginx", "line1\nline2\n")
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, busyRetryAfter, w.Header().Get("Retry-After"))
+		assert.Equal(t, &result{Status: "busy", Dropped: 2}, decode(t, w))
+	})
+
+	t.Run("invalid-line-protocol", func(t *testing.T) {
+		feedErr, fed = nil, 0
+
+		w := httptest.NewRecorder()
+		post(w, "type=influxdb", "invalid line protocol")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		res := decode(t, w)
+		assert.Equal(t, "error", res.Status)
+		assert.NotEmpty(t, res.Error)
+		assert.Equal(t, 0, fed)
+	})
+
+	t.Run("async-blocking", func(t *testing.T) {
+		feedErr, fed = nil, 0
+
+		post(&ihttp.NopResponseWriter{}, "source=nginx", "line1\n")
+
+		assert.Equal(t, 1, fed)
+		assert.False(t, fedOpt.NonBlocking)
+	})
+}
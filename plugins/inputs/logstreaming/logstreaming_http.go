@@ -8,6 +8,8 @@ package logstreaming
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -15,21 +17,37 @@ import (
 	"time"
 
 	lp "github.com/GuanceCloud/cliutils/lineproto"
-	client "github.com/influxdata/influxdb1-client/v2"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/bufpool"
+	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
 	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
 )
 
+// busyRetryAfter is the Retry-After(seconds) responded if io is busy.
+const busyRetryAfter = "5"
+
+// feed is replaced in testing.
+var feed = dkio.Feed
+
 func httpStatusRespFunc(resp http.ResponseWriter, req *http.Request, err error) {
 	resp.Write([]byte(`{"status":"success"}`)) // nolint: errcheck,gosec
 }
 
+// result is the response of a request: number of logs accepted by io, and
+// dropped ones, which are invalid or rejected on io busy.
+type result struct {
+	Status   string `json:"status"`
+	Accepted int    `json:"accepted"`
+	Dropped  int    `json:"dropped"`
+	Error    string `json:"error,omitempty"`
+}
+
 type parameters struct {
 	ignoreURLTags bool
+	nonBlocking   bool
 	url           *url.URL
 	queryValues   url.Values
 	body          *bytes.Buffer
@@ -49,20 +67,47 @@ func (ipt *Input) handleLogstreaming(resp http.ResponseWriter, req *http.Request
 		return
 	}
 
+	// Requests from worker-pool or local cache are responded already, they
+	// are fed blocking, or logs dropped silently on io busy.
+	_, async := resp.(*ihttp.NopResponseWriter)
+
 	param := &parameters{
 		ignoreURLTags: ipt.IgnoreURLTags,
+		nonBlocking:   !async,
 		url:           req.URL,
 		queryValues:   req.URL.Query(),
 		body:          pbuf,
 	}
-	if err = processLogBody(param); err != nil {
+
+	res, err := processLogBody(param)
+	switch {
+	case errors.Is(err, dkio.ErrIOBusy):
+		log.Warnf("io busy, %d logs of %s dropped", res.Dropped, req.URL.String())
+		res.Status = "busy"
+		resp.Header().Set("Retry-After", busyRetryAfter)
+		writeResult(resp, http.StatusTooManyRequests, res)
+
+	case err != nil:
 		log.Error(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
+		res.Status = "error"
+		res.Error = err.Error()
+		writeResult(resp, http.StatusBadRequest, res)
 
-		return
+	default:
+		res.Status = "success"
+		writeResult(resp, http.StatusOK, res)
+	}
+}
+
+func writeResult(resp http.ResponseWriter, status int, res *result) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		log.Error(err.Error())
 	}
 
-	resp.Write([]byte(`{"status":"success"}`)) //nolint:errcheck,gosec
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	resp.Write(body) //nolint:errcheck,gosec
 }
 
 func completeSource(source string) string {
@@ -89,7 +134,7 @@ func completePrecision(precision string) string {
 	return precision
 }
 
-func processLogBody(param *parameters) error {
+func processLogBody(param *parameters) (*result, error) {
 	var (
 		source = completeSource(param.queryValues.Get("source"))
 		// TODO
@@ -104,39 +149,39 @@ func processLogBody(param *parameters) error {
 
 	var (
 		urlstr = param.url.String()
-		err    error
+		res    = &result{}
+		pts    []*point.Point
+		name   string
+		opt    *dkio.Option
 	)
 	switch param.queryValues.Get("type") {
 	case "influxdb":
-		body, _err := ioutil.ReadAll(param.body)
-		if _err != nil {
-			log.Errorf("url %s failed to read body: %s", urlstr, _err)
-			err = _err
-			break
+		body, err := ioutil.ReadAll(param.body)
+		if err != nil {
+			log.Errorf("url %s failed to read body: %s", urlstr, err)
+			return res, err
 		}
 
-		var pts []*client.Point
-		if pts, err = lp.ParsePoints(body, &lp.Option{
+		lppts, err := lp.ParsePoints(body, &lp.Option{
 			Time:      time.Now(),
 			ExtraTags: extraTags,
 			Strict:    true,
 			Precision: completePrecision(param.queryValues.Get("precision")),
-		}); err != nil {
+		})
+		if err != nil {
 			log.Errorf("url %s handler err: %s", urlstr, err)
 
-			return err
+			return res, err
 		}
-		if len(pts) == 0 {
-			log.Debugf("len(points) is zero, skip")
 
-			return nil
-		}
-
-		pts1 := point.WrapPoint(pts)
-		err = dkio.Feed(inputName, datakit.Logging, pts1, nil)
+		pts, name, opt = point.WrapPoint(lppts), inputName, &dkio.Option{}
 	default:
+		// lines by default, or entries of journald/evtx
+		split, maxSize := splitFunc(param.queryValues.Get("type"))
 		scanner := bufio.NewScanner(param.body)
-		pts := []*point.Point{}
+		scanner.Buffer(make([]byte, 0, 4096), maxSize)
+		scanner.Split(split)
+
 		for scanner.Scan() {
 			pt, err := point.NewPoint(source, extraTags,
 				map[string]interface{}{
@@ -145,15 +190,13 @@ func processLogBody(param *parameters) error {
 				}, point.LOpt())
 			if err != nil {
 				log.Error(err)
+				res.Dropped++
 			} else {
 				pts = append(pts, pt)
 			}
 		}
-		// pts := plRunCnt(source, pipeLlinePath, pending, extraTags)
-		if len(pts) == 0 {
-			log.Debugf("len(points) is zero, skip")
-
-			return nil
+		if err := scanner.Err(); err != nil {
+			log.Warnf("url %s split body: %s", urlstr, err)
 		}
 
 		var scriptMap map[string]string
@@ -164,8 +207,21 @@ func processLogBody(param *parameters) error {
 			}
 		}
 
-		err = dkio.Feed(source, datakit.Logging, pts, &dkio.Option{PlScript: scriptMap})
+		name, opt = source, &dkio.Option{PlScript: scriptMap}
+	}
+
+	if len(pts) == 0 {
+		log.Debugf("len(points) is zero, skip")
+
+		return res, nil
+	}
+
+	opt.NonBlocking = param.nonBlocking
+	if err := feed(name, datakit.Logging, pts, opt); err != nil {
+		res.Dropped += len(pts)
+		return res, err
 	}
 
-	return err
+	res.Accepted = len(pts)
+	return res, nil
 }
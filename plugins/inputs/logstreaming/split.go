@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package logstreaming
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// maxEntrySize is the max size of a journald entry or Windows event, which
+// may be much longer than a line.
+const maxEntrySize = 4 * 1024 * 1024
+
+// splitFunc returns split function of body by type, lines by default.
+func splitFunc(typ string) (bufio.SplitFunc, int) {
+	switch typ {
+	case "journald":
+		return scanJournaldEntries, maxEntrySize
+	case "evtx":
+		return scanEvtxEvents, maxEntrySize
+	default:
+		return bufio.ScanLines, bufio.MaxScanTokenSize
+	}
+}
+
+// scanJournaldEntries splits entries of journald export format, which are
+// separated by an empty line. Binary fields(name, newline, 64bit little
+// endian size and the data) may contain empty lines, so they are skipped by
+// the size.
+func scanJournaldEntries(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	pos := start
+	for pos < len(data) {
+		idx := bytes.IndexByte(data[pos:], '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := data[pos : pos+idx]
+		if len(line) == 0 { // end of the entry
+			return pos + 1, data[start:pos], nil
+		}
+
+		if bytes.IndexByte(line, '=') >= 0 {
+			pos += idx + 1
+			continue
+		}
+
+		sizeAt := pos + idx + 1
+		if len(data) < sizeAt+8 {
+			break
+		}
+
+		size := binary.LittleEndian.Uint64(data[sizeAt : sizeAt+8])
+		if size > maxEntrySize {
+			return 0, nil, fmt.Errorf("journald field %q too large: %d bytes", line, size)
+		}
+
+		end := sizeAt + 8 + int(size) + 1 // and the trailing newline
+		if len(data) < end {
+			break
+		}
+		pos = end
+	}
+
+	if atEOF {
+		if start < len(data) { // the last entry without the empty line
+			return len(data), data[start:], nil
+		}
+		return len(data), nil, nil
+	}
+
+	return start, nil, nil
+}
+
+var (
+	evtxStart = []byte("<Event")
+	evtxEnd   = []byte("</Event>")
+)
+
+// scanEvtxEvents splits XML rendered Windows events, such as output of
+// wevtutil, the root <Events> ignored.
+func scanEvtxEvents(data []byte, atEOF bool) (int, []byte, error) {
+	start := -1
+	for off := 0; off < len(data); {
+		idx := bytes.Index(data[off:], evtxStart)
+		if idx < 0 {
+			break
+		}
+
+		next := off + idx + len(evtxStart)
+		if next >= len(data) {
+			break // need more data to tell <Event from <Events
+		}
+
+		if c := data[next]; c == '>' || c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			start = off + idx
+			break
+		}
+		off = next
+	}
+
+	if start < 0 {
+		if atEOF {
+			return len(data), nil, nil
+		}
+
+		// keep the tail, it may be part of <Event
+		if n := len(data) - len(evtxStart); n > 0 {
+			return n, nil, nil
+		}
+		return 0, nil, nil
+	}
+
+	if end := bytes.Index(data[start:], evtxEnd); end >= 0 {
+		end += start + len(evtxEnd)
+		return end, data[start:end], nil
+	}
+
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+
+	return start, nil, nil
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package logstreaming
+
+import (
+	"bufio"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func split(t *testing.T, typ, body string) []string {
+	t.Helper()
+
+	fn, maxSize := splitFunc(typ)
+
+	// read byte by byte to cover entries across reads
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(body)))
+	scanner.Buffer(make([]byte, 0, 16), maxSize)
+	scanner.Split(fn)
+
+	var res []string
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return res
+}
+
+func TestSplitJournald(t *testing.T) {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len("a\n\nb")))
+	binaryMsg := "MESSAGE\n" + string(size) + "a\n\nb\n"
+
+	body := "__CURSOR=s=1\nMESSAGE=first\n\n" +
+		"__CURSOR=s=2\n" + binaryMsg + "PRIORITY=6\n\n" +
+		"__CURSOR=s=3\nMESSAGE=last\n"
+
+	assert.Equal(t, []string{
+		"__CURSOR=s=1\nMESSAGE=first\n",
+		"__CURSOR=s=2\n" + binaryMsg + "PRIORITY=6\n",
+		"__CURSOR=s=3\nMESSAGE=last\n",
+	}, split(t, "journald", body))
+}
+
+func TestSplitEvtx(t *testing.T) {
+	ev1 := `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event"><System><EventID>1</EventID></System></Event>`
+	ev2 := "<Event>\n<System><EventID>2</EventID></System>\n</Event>"
+
+	t.Run("concatenated", func(t *testing.T) {
+		assert.Equal(t, []string{ev1, ev2}, split(t, "evtx", ev1+"\r\n"+ev2+"\r\n"))
+	})
+
+	t.Run("with-root", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="UTF-8"?><Events>` + ev1 + ev2 + `</Events>`
+		assert.Equal(t, []string{ev1, ev2}, split(t, "evtx", body))
+	})
+}
+
+func TestSplitLines(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, split(t, "", "a\nb\n"))
+}
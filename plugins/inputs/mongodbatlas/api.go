@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package mongodbatlas
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// acceptHeader selects the version of the Atlas Admin API v2.
+	acceptHeader = "application/vnd.atlas.2023-01-01+json"
+
+	itemsPerPage = 500
+	maxPages     = 100
+)
+
+type project struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	OrgID        string `json:"orgId"`
+	ClusterCount int64  `json:"clusterCount"`
+	Created      string `json:"created"`
+}
+
+type cluster struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	ClusterType    string  `json:"clusterType"`
+	MongoDBVersion string  `json:"mongoDBVersion"`
+	StateName      string  `json:"stateName"`
+	Paused         bool    `json:"paused"`
+	DiskSizeGB     float64 `json:"diskSizeGB"`
+	BackupEnabled  bool    `json:"backupEnabled"`
+	CreateDate     string  `json:"createDate"`
+}
+
+type process struct {
+	ID             string `json:"id"`
+	Hostname       string `json:"hostname"`
+	Port           int    `json:"port"`
+	TypeName       string `json:"typeName"`
+	UserAlias      string `json:"userAlias"`
+	ReplicaSetName string `json:"replicaSetName"`
+	Version        string `json:"version"`
+}
+
+type disk struct {
+	PartitionName string `json:"partitionName"`
+}
+
+type dataPoint struct {
+	Timestamp string   `json:"timestamp"`
+	Value     *float64 `json:"value"`
+}
+
+type measurements struct {
+	Measurements []struct {
+		Name       string       `json:"name"`
+		Units      string       `json:"units"`
+		DataPoints []*dataPoint `json:"dataPoints"`
+	} `json:"measurements"`
+}
+
+type alert struct {
+	ID              string `json:"id"`
+	EventTypeName   string `json:"eventTypeName"`
+	Status          string `json:"status"`
+	Created         string `json:"created"`
+	Updated         string `json:"updated"`
+	ClusterName     string `json:"clusterName"`
+	HostnameAndPort string `json:"hostnameAndPort"`
+	ReplicaSetName  string `json:"replicaSetName"`
+	MetricName      string `json:"metricName"`
+	CurrentValue    *struct {
+		Number float64 `json:"number"`
+		Units  string  `json:"units"`
+	} `json:"currentValue"`
+}
+
+// apiError is the error body of the Atlas Admin API.
+type apiError struct {
+	StatusCode int    `json:"error"`
+	ErrorCode  string `json:"errorCode"`
+	Detail     string `json:"detail"`
+}
+
+func (e *apiError) Error() string {
+	if e.ErrorCode == "" {
+		return fmt.Sprintf("atlas API error %d: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("atlas API error %d %s: %s", e.StatusCode, e.ErrorCode, e.Detail)
+}
+
+type client struct {
+	baseURL string
+	cli     *http.Client
+}
+
+// get requests path under the base URL and decodes the JSON response to v.
+func (c *client) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		e := &apiError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, e) != nil || e.Detail == "" {
+			e.Detail = strings.TrimSpace(string(body))
+		}
+		if e.Detail == "" {
+			e.Detail = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// list requests all pages of path and returns results of them.
+func list[T any](ctx context.Context, c *client, path string, query url.Values) ([]*T, error) {
+	var res []*T
+
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("itemsPerPage", strconv.Itoa(itemsPerPage))
+
+	for page := 1; page <= maxPages; page++ {
+		q.Set("pageNum", strconv.Itoa(page))
+
+		var resp struct {
+			Results    []*T `json:"results"`
+			TotalCount int  `json:"totalCount"`
+		}
+		if err := c.get(ctx, path, q, &resp); err != nil {
+			return nil, err
+		}
+
+		res = append(res, resp.Results...)
+		if len(resp.Results) < itemsPerPage || len(res) >= resp.TotalCount {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// digestTransport authenticates requests by HTTP digest with the API key,
+// the challenge is cached and refreshed once the server rejects it.
+type digestTransport struct {
+	username string
+	password string
+	base     http.RoundTripper
+
+	mu   sync.Mutex
+	chal *challenge
+	nc   int
+}
+
+type challenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if auth := t.authorization(req); auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !t.refresh(resp) {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+
+	// retry with the new challenge, requests are GETs without body
+	r = req.Clone(req.Context())
+	r.Header.Set("Authorization", t.authorization(req))
+	return t.base.RoundTrip(r)
+}
+
+// refresh takes the digest challenge of resp, false if resp has none.
+func (t *digestTransport) refresh(resp *http.Response) bool {
+	chal := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.chal = chal
+	t.nc = 0
+	return chal != nil
+}
+
+func (t *digestTransport) authorization(req *http.Request) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.chal == nil {
+		return ""
+	}
+
+	t.nc++
+	nc := fmt.Sprintf("%08x", t.nc)
+	cnonce := newCnonce()
+	uri := req.URL.RequestURI()
+
+	ha1 := md5Hex(t.username + ":" + t.chal.realm + ":" + t.password)
+	if strings.EqualFold(t.chal.algorithm, "MD5-sess") {
+		ha1 = md5Hex(ha1 + ":" + t.chal.nonce + ":" + cnonce)
+	}
+	ha2 := md5Hex(req.Method + ":" + uri)
+
+	var response string
+	if t.chal.qop == "" {
+		response = md5Hex(ha1 + ":" + t.chal.nonce + ":" + ha2)
+	} else {
+		response = md5Hex(ha1 + ":" + t.chal.nonce + ":" + nc + ":" + cnonce + ":" + t.chal.qop + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, t.username),
+		fmt.Sprintf(`realm="%s"`, t.chal.realm),
+		fmt.Sprintf(`nonce="%s"`, t.chal.nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if t.chal.algorithm != "" {
+		parts = append(parts, "algorithm="+t.chal.algorithm)
+	}
+	if t.chal.opaque != "" {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, t.chal.opaque))
+	}
+	if t.chal.qop != "" {
+		parts = append(parts, "qop="+t.chal.qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+
+	return "Digest " + strings.Join(parts, ", ")
+}
+
+// parseChallenge parses the digest challenge, nil if not a digest one.
+func parseChallenge(s string) *challenge {
+	const prefix = "digest "
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return nil
+	}
+
+	chal := &challenge{}
+	for _, kv := range splitParams(s[len(prefix):]) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "realm":
+			chal.realm = v
+		case "nonce":
+			chal.nonce = v
+		case "opaque":
+			chal.opaque = v
+		case "algorithm":
+			chal.algorithm = v
+		case "qop":
+			// only qop auth supported
+			for _, q := range strings.Split(v, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					chal.qop = "auth"
+				}
+			}
+		}
+	}
+
+	if chal.nonce == "" {
+		return nil
+	}
+	return chal
+}
+
+// splitParams splits params by commas out of quotes.
+func splitParams(s string) []string {
+	var (
+		res    []string
+		quoted bool
+		start  int
+	)
+
+	for i, c := range s {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				res = append(res, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, s[start:])
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+func newCnonce() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
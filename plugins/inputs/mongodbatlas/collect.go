@@ -0,0 +1,454 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package mongodbatlas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const (
+	defaultProcessMetrics = `
+CONNECTIONS
+OPCOUNTER_CMD
+OPCOUNTER_QUERY
+OPCOUNTER_INSERT
+OPCOUNTER_UPDATE
+OPCOUNTER_DELETE
+OPCOUNTER_GETMORE
+QUERY_TARGETING_SCANNED_OBJECTS_PER_RETURNED
+SYSTEM_NORMALIZED_CPU_USER
+SYSTEM_NORMALIZED_CPU_KERNEL
+SYSTEM_MEMORY_USED
+SYSTEM_MEMORY_AVAILABLE
+CACHE_USED_BYTES
+OPLOG_SLAVE_LAG_MASTER_TIME
+OPLOG_MASTER_TIME
+NETWORK_BYTES_IN
+NETWORK_BYTES_OUT`
+
+	defaultDiskMetrics = `
+DISK_PARTITION_IOPS_READ
+DISK_PARTITION_IOPS_WRITE
+DISK_PARTITION_LATENCY_READ
+DISK_PARTITION_LATENCY_WRITE
+DISK_PARTITION_SPACE_USED
+DISK_PARTITION_SPACE_FREE
+DISK_PARTITION_SPACE_PERCENT_USED`
+
+	measurementProcess = "mongodb_atlas_process"
+	measurementDisk    = "mongodb_atlas_disk"
+	measurementProject = "mongodb_atlas_project"
+	measurementCluster = "mongodb_atlas_cluster"
+	measurementAlert   = "mongodb_atlas_alert"
+
+	diskPrefix = "DISK_PARTITION_"
+)
+
+var granularityRe = regexp.MustCompile(`^PT(\d+)([MH])$`)
+
+// parseGranularity parses granularity of measurements, such as PT1M and PT1H.
+func parseGranularity(s string) (time.Duration, error) {
+	m := granularityRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid granularity %q, expect PT1M, PT5M, PT1H and so on", s)
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	if m[2] == "H" {
+		return time.Duration(n) * time.Hour, nil
+	}
+	return time.Duration(n) * time.Minute, nil
+}
+
+type collectResult struct {
+	metrics, objects, events []*point.Point
+}
+
+func (ipt *Input) collectAll(ctx context.Context, withObjects bool, ts time.Time) (*collectResult, error) {
+	res := &collectResult{}
+
+	projects, err := ipt.projects(ctx)
+	if err != nil {
+		return res, fmt.Errorf("list projects: %w", err)
+	}
+
+	var lastErr error
+	for _, p := range projects {
+		clusters, err := list[cluster](ctx, ipt.client, "/groups/"+p.ID+"/clusters", nil)
+		if err != nil {
+			lastErr = fmt.Errorf("list clusters of project %s: %w", p.Name, err)
+			l.Warn(lastErr)
+		}
+
+		if withObjects {
+			res.objects = append(res.objects, ipt.projectObject(p, ts))
+			for _, c := range clusters {
+				res.objects = append(res.objects, ipt.clusterObject(p, c, ts))
+			}
+		}
+
+		pts, err := ipt.collectProcesses(ctx, p, clusters)
+		if err != nil {
+			lastErr = err
+			l.Warn(err)
+		}
+		res.metrics = append(res.metrics, pts...)
+
+		if ipt.Alerts {
+			pts, err := ipt.collectAlerts(ctx, p, ts)
+			if err != nil {
+				lastErr = fmt.Errorf("list alerts of project %s: %w", p.Name, err)
+				l.Warn(lastErr)
+			}
+			res.events = append(res.events, pts...)
+		}
+
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+
+	return res, lastErr
+}
+
+// projects returns projects configured, or all projects of the API key.
+func (ipt *Input) projects(ctx context.Context) ([]*project, error) {
+	all, err := list[project](ctx, ipt.client, "/groups", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ipt.Projects) == 0 {
+		return all, nil
+	}
+
+	var res []*project
+	for _, p := range all {
+		for _, x := range ipt.Projects {
+			if x == p.ID || x == p.Name {
+				res = append(res, p)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+func (ipt *Input) collectProcesses(ctx context.Context, p *project, clusters []*cluster) ([]*point.Point, error) {
+	processes, err := list[process](ctx, ipt.client, "/groups/"+p.ID+"/processes", nil)
+	if err != nil {
+		return nil, fmt.Errorf("list processes of project %s: %w", p.Name, err)
+	}
+
+	var (
+		pts     []*point.Point
+		lastErr error
+	)
+
+	for _, proc := range processes {
+		tags := map[string]string{
+			"project":     p.Name,
+			"project_id":  p.ID,
+			"cluster":     clusterOf(proc, clusters),
+			"process":     proc.ID,
+			"type_name":   proc.TypeName,
+			"replica_set": proc.ReplicaSetName,
+		}
+
+		base := "/groups/" + p.ID + "/processes/" + url.PathEscape(proc.ID)
+
+		if len(ipt.ProcessMetrics) > 0 {
+			pt, err := ipt.measure(ctx, base+"/measurements", ipt.ProcessMetrics, "", measurementProcess, tags)
+			if err != nil {
+				lastErr = fmt.Errorf("measurements of process %s: %w", proc.ID, err)
+			} else if pt != nil {
+				pts = append(pts, pt)
+			}
+		}
+
+		if len(ipt.DiskMetrics) == 0 {
+			continue
+		}
+
+		disks, err := list[disk](ctx, ipt.client, base+"/disks", nil)
+		if err != nil {
+			lastErr = fmt.Errorf("list disks of process %s: %w", proc.ID, err)
+			continue
+		}
+
+		for _, d := range disks {
+			dtags := map[string]string{"partition": d.PartitionName}
+			for k, v := range tags {
+				dtags[k] = v
+			}
+
+			pt, err := ipt.measure(ctx, base+"/disks/"+url.PathEscape(d.PartitionName)+"/measurements",
+				ipt.DiskMetrics, diskPrefix, measurementDisk, dtags)
+			if err != nil {
+				lastErr = fmt.Errorf("measurements of disk %s of process %s: %w", d.PartitionName, proc.ID, err)
+			} else if pt != nil {
+				pts = append(pts, pt)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return pts, ctx.Err()
+		}
+	}
+
+	return pts, lastErr
+}
+
+// measure queries measurements and returns a point of the latest values,
+// fields named by measurement names in lower case with prefix trimmed.
+func (ipt *Input) measure(ctx context.Context, path string, names []string,
+	prefix, name string, tags map[string]string,
+) (*point.Point, error) {
+	granularity, _ := parseGranularity(ipt.Granularity)
+
+	// cover 2 intervals at least, the latest data points may be not ready
+	period := 2 * ipt.Interval.Duration
+	if period < 5*granularity {
+		period = 5 * granularity
+	}
+
+	q := url.Values{}
+	q.Set("granularity", ipt.Granularity)
+	q.Set("period", fmt.Sprintf("PT%dM", int(period.Minutes())))
+	for _, n := range names {
+		q.Add("m", n)
+	}
+
+	var resp measurements
+	if err := ipt.client.get(ctx, path, q, &resp); err != nil {
+		return nil, err
+	}
+
+	var (
+		fields = map[string]interface{}{}
+		ts     time.Time
+	)
+
+	for _, m := range resp.Measurements {
+		dp := latest(m.DataPoints)
+		if dp == nil {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, dp.Timestamp)
+		if err != nil {
+			continue
+		}
+		if t.After(ts) {
+			ts = t
+		}
+
+		fields[strings.ToLower(strings.TrimPrefix(m.Name, prefix))] = *dp.Value
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	kvs := ipt.kvs(tags)
+	for k, v := range fields {
+		kvs = kvs.Add([]byte(k), v, false, true)
+	}
+
+	return point.NewPointV2([]byte(name), kvs, ipt.pointOptions(point.DefaultMetricOptions(), ts)...), nil
+}
+
+// latest returns the latest data point with value.
+func latest(dps []*dataPoint) *dataPoint {
+	for i := len(dps) - 1; i >= 0; i-- {
+		if dps[i] != nil && dps[i].Value != nil {
+			return dps[i]
+		}
+	}
+	return nil
+}
+
+// clusterOf returns name of the cluster running the process, by host names
+// of Atlas such as cluster0-shard-00-01.xxxxx.mongodb.net.
+func clusterOf(proc *process, clusters []*cluster) string {
+	for _, h := range []string{proc.UserAlias, proc.Hostname} {
+		h = strings.ToLower(h)
+		for _, sep := range []string{"-shard-", "-config-"} {
+			i := strings.Index(h, sep)
+			if i <= 0 {
+				continue
+			}
+
+			for _, c := range clusters {
+				if strings.EqualFold(c.Name, h[:i]) {
+					return c.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (ipt *Input) projectObject(p *project, ts time.Time) *point.Point {
+	kvs := ipt.kvs(map[string]string{
+		"name":       p.Name,
+		"project_id": p.ID,
+		"org_id":     p.OrgID,
+	})
+	kvs = kvs.Add([]byte("cluster_count"), p.ClusterCount, false, true)
+
+	if j, err := json.Marshal(p); err == nil {
+		kvs = kvs.Add([]byte("message"), string(j), false, true)
+	}
+
+	return point.NewPointV2([]byte(measurementProject), kvs, ipt.pointOptions(point.DefaultObjectOptions(), ts)...)
+}
+
+func (ipt *Input) clusterObject(p *project, c *cluster, ts time.Time) *point.Point {
+	kvs := ipt.kvs(map[string]string{
+		"name":            c.Name,
+		"cluster_id":      c.ID,
+		"project":         p.Name,
+		"project_id":      p.ID,
+		"cluster_type":    c.ClusterType,
+		"mongodb_version": c.MongoDBVersion,
+		"state_name":      c.StateName,
+	})
+	kvs = kvs.Add([]byte("paused"), c.Paused, false, true)
+	kvs = kvs.Add([]byte("backup_enabled"), c.BackupEnabled, false, true)
+	kvs = kvs.Add([]byte("disk_size_gb"), c.DiskSizeGB, false, true)
+
+	if j, err := json.Marshal(c); err == nil {
+		kvs = kvs.Add([]byte("message"), string(j), false, true)
+	}
+
+	return point.NewPointV2([]byte(measurementCluster), kvs, ipt.pointOptions(point.DefaultObjectOptions(), ts)...)
+}
+
+// collectAlerts returns keyevents of alerts opened or closed since last time.
+// On the first time, only alerts opened within the interval are sent.
+func (ipt *Input) collectAlerts(ctx context.Context, p *project, ts time.Time) ([]*point.Point, error) {
+	alerts, err := list[alert](ctx, ipt.client, "/groups/"+p.ID+"/alerts", url.Values{"status": []string{"OPEN"}})
+	if err != nil {
+		return nil, err
+	}
+
+	prev, seen := ipt.openAlerts[p.ID]
+	cur := make(map[string]*alert, len(alerts))
+
+	var pts []*point.Point
+	for _, a := range alerts {
+		cur[a.ID] = a
+		if _, ok := prev[a.ID]; ok {
+			continue
+		}
+
+		if !seen {
+			if created, err := time.Parse(time.RFC3339, a.Created); err != nil ||
+				created.Before(ts.Add(-ipt.Interval.Duration)) {
+				continue
+			}
+		}
+
+		pts = append(pts, ipt.alertEvent(p, a, ts))
+	}
+
+	for id, a := range prev {
+		if _, ok := cur[id]; !ok {
+			a.Status = "CLOSED"
+			pts = append(pts, ipt.alertEvent(p, a, ts))
+		}
+	}
+
+	ipt.openAlerts[p.ID] = cur
+	return pts, nil
+}
+
+func (ipt *Input) alertEvent(p *project, a *alert, ts time.Time) *point.Point {
+	target := a.ClusterName
+	if a.HostnameAndPort != "" {
+		target = a.HostnameAndPort
+	}
+	if target == "" {
+		target = p.Name
+	}
+
+	status, verb := "warning", "opened"
+	if a.Status == "CLOSED" {
+		status, verb = "ok", "closed"
+	}
+
+	title := fmt.Sprintf("Atlas alert %s %s on %s", a.EventTypeName, verb, target)
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	fmt.Fprintf(&sb, "\nproject: %s", p.Name)
+	if a.ClusterName != "" {
+		fmt.Fprintf(&sb, "\ncluster: %s", a.ClusterName)
+	}
+	if a.MetricName != "" {
+		fmt.Fprintf(&sb, "\nmetric: %s", a.MetricName)
+	}
+	if a.CurrentValue != nil {
+		fmt.Fprintf(&sb, "\ncurrent value: %g %s", a.CurrentValue.Number, a.CurrentValue.Units)
+	}
+	fmt.Fprintf(&sb, "\ncreated: %s", a.Created)
+
+	kvs := ipt.kvs(map[string]string{
+		"project":     p.Name,
+		"project_id":  p.ID,
+		"cluster":     a.ClusterName,
+		"process":     a.HostnameAndPort,
+		"replica_set": a.ReplicaSetName,
+		"event_type":  a.EventTypeName,
+		"alert_id":    a.ID,
+		"status":      a.Status,
+	})
+
+	fields := map[string]interface{}{
+		"df_source":   inputName,
+		"df_status":   status,
+		"df_event_id": a.ID,
+		"df_title":    title,
+		"df_message":  sb.String(),
+		"metric_name": a.MetricName,
+	}
+	for k, v := range fields {
+		kvs = kvs.Add([]byte(k), v, false, true)
+	}
+	if a.CurrentValue != nil {
+		kvs = kvs.Add([]byte("current_value"), a.CurrentValue.Number, false, true)
+	}
+
+	var opts []point.Option
+	opts = ipt.pointOptions(opts, ts)
+	return point.NewPointV2([]byte(measurementAlert), kvs, opts...)
+}
+
+// kvs returns tags of the input and the resource, empty tags skipped.
+func (ipt *Input) kvs(tags map[string]string) point.KVs {
+	var kvs point.KVs
+	for k, v := range ipt.Tags {
+		kvs = kvs.AddTag([]byte(k), []byte(v))
+	}
+
+	for k, v := range tags {
+		if v != "" {
+			kvs = kvs.MustAddTag([]byte(k), []byte(v))
+		}
+	}
+
+	return kvs
+}
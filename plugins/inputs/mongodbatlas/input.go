@@ -0,0 +1,324 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package mongodbatlas collects metrics, objects and alerts of MongoDB Atlas by the Atlas Admin API.
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName   = "mongodb_atlas"
+	catalogName = "db"
+
+	defaultBaseURL = "https://cloud.mongodb.com/api/atlas/v2"
+
+	minInterval = time.Minute // the finest granularity of Atlas measurements
+	maxInterval = time.Hour
+
+	sampleCfg = `
+[[inputs.mongodb_atlas]]
+  ## Programmatic API key of the organization or projects, the role
+  ## Organization Read Only or Project Read Only is enough.
+  public_key  = "<PUBLIC_KEY>"
+  private_key = "<PRIVATE_KEY>"
+
+  ## Base URL of the Atlas Admin API, change it for Atlas for Government.
+  base_url = "https://cloud.mongodb.com/api/atlas/v2"
+
+  ## Projects to collect, by names or IDs. All projects accessible by the
+  ## API key are collected if empty.
+  projects = []
+
+  interval = "1m"
+
+  ## Interval of projects and clusters objects
+  object_interval = "5m"
+
+  timeout = "30s"
+
+  ## Granularity of measurements, PT1M is available for M10+ clusters only,
+  ## use PT5M for others.
+  granularity = "PT1M"
+
+  ## Measurements of processes and disk partitions, names are listed in
+  ## the docs of the Atlas Admin API.
+  process_metrics = [
+    "CONNECTIONS",
+    "OPCOUNTER_CMD",
+    "OPCOUNTER_QUERY",
+    "OPCOUNTER_INSERT",
+    "OPCOUNTER_UPDATE",
+    "OPCOUNTER_DELETE",
+    "OPCOUNTER_GETMORE",
+    "QUERY_TARGETING_SCANNED_OBJECTS_PER_RETURNED",
+    "SYSTEM_NORMALIZED_CPU_USER",
+    "SYSTEM_NORMALIZED_CPU_KERNEL",
+    "SYSTEM_MEMORY_USED",
+    "SYSTEM_MEMORY_AVAILABLE",
+    "CACHE_USED_BYTES",
+    "OPLOG_SLAVE_LAG_MASTER_TIME",
+    "OPLOG_MASTER_TIME",
+    "NETWORK_BYTES_IN",
+    "NETWORK_BYTES_OUT",
+  ]
+
+  disk_metrics = [
+    "DISK_PARTITION_IOPS_READ",
+    "DISK_PARTITION_IOPS_WRITE",
+    "DISK_PARTITION_LATENCY_READ",
+    "DISK_PARTITION_LATENCY_WRITE",
+    "DISK_PARTITION_SPACE_USED",
+    "DISK_PARTITION_SPACE_FREE",
+    "DISK_PARTITION_SPACE_PERCENT_USED",
+  ]
+
+  ## Send alerts of the projects as keyevents, on opened and closed.
+  alerts = true
+
+  ## Atlas is collected by the elected DataKit only
+  election = true
+
+  [inputs.mongodb_atlas.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	PublicKey      string           `toml:"public_key"`
+	PrivateKey     string           `toml:"private_key"`
+	BaseURL        string           `toml:"base_url"`
+	Projects       []string         `toml:"projects"`
+	Interval       datakit.Duration `toml:"interval"`
+	ObjectInterval datakit.Duration `toml:"object_interval"`
+	Timeout        datakit.Duration `toml:"timeout"`
+	Granularity    string           `toml:"granularity"`
+	ProcessMetrics []string         `toml:"process_metrics"`
+	DiskMetrics    []string         `toml:"disk_metrics"`
+	Alerts         bool             `toml:"alerts"`
+	Election       bool             `toml:"election"`
+
+	Tags map[string]string `toml:"tags"`
+
+	client        *client
+	lastObjectsAt time.Time
+
+	// open alerts of each project, by alert ID
+	openAlerts map[string]map[string]*alert
+
+	pause   bool
+	pauseCh chan bool
+
+	feeder  dkio.Feeder
+	semStop *cliutils.Sem // start stop signal
+}
+
+var (
+	_ inputs.ElectionInput = (*Input)(nil)
+	_ inputs.InputV2       = (*Input)(nil)
+)
+
+func (*Input) Catalog() string { return catalogName }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOSWithElection }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&processMeasurement{},
+		&diskMeasurement{},
+		&projectMeasurement{},
+		&clusterMeasurement{},
+		&alertMeasurement{},
+	}
+}
+
+func (ipt *Input) ElectionEnabled() bool {
+	return ipt.Election
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		if ipt.pause {
+			l.Debugf("not leader, skipped")
+		} else {
+			ipt.collect()
+		}
+
+		select {
+		case <-datakit.Exit.Wait():
+			l.Info(inputName + " exit")
+			return
+		case <-ipt.semStop.Wait():
+			l.Info(inputName + " return")
+			return
+		case ipt.pause = <-ipt.pauseCh:
+		case <-tick.C:
+		}
+	}
+}
+
+func (ipt *Input) setup() error {
+	if ipt.PublicKey == "" || ipt.PrivateKey == "" {
+		return fmt.Errorf("public_key and private_key required")
+	}
+
+	if ipt.BaseURL == "" {
+		ipt.BaseURL = defaultBaseURL
+	}
+
+	u, err := url.Parse(ipt.BaseURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid base_url %q", ipt.BaseURL)
+	}
+
+	if _, err := parseGranularity(ipt.Granularity); err != nil {
+		return err
+	}
+
+	ipt.client = &client{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		cli: &http.Client{
+			Transport: &digestTransport{
+				username: ipt.PublicKey,
+				password: ipt.PrivateKey,
+				base:     http.DefaultTransport,
+			},
+			Timeout: ipt.Timeout.Duration,
+		},
+	}
+
+	return nil
+}
+
+func (ipt *Input) collect() {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ipt.Interval.Duration)
+	defer cancel()
+
+	withObjects := time.Since(ipt.lastObjectsAt) >= ipt.ObjectInterval.Duration
+
+	res, err := ipt.collectAll(ctx, withObjects, start)
+	if err != nil {
+		l.Errorf("collect: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+	}
+
+	if len(res.metrics) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.Metric, res.metrics,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed metrics: %s", err)
+		}
+	}
+
+	if len(res.objects) > 0 {
+		ipt.lastObjectsAt = start
+		if err := ipt.feeder.Feed(inputName, point.Object, res.objects,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed objects: %s", err)
+		}
+	}
+
+	if len(res.events) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.KeyEvent, res.events, nil); err != nil {
+			l.Errorf("feed keyevents: %s", err)
+		}
+	}
+}
+
+// pointOptions returns options of points, with the election tags if enabled.
+func (ipt *Input) pointOptions(opts []point.Option, ts time.Time) []point.Option {
+	opts = append(opts, point.WithTime(ts))
+	if ipt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+	return opts
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (ipt *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (ipt *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		BaseURL:        defaultBaseURL,
+		Interval:       datakit.Duration{Duration: time.Minute},
+		ObjectInterval: datakit.Duration{Duration: 5 * time.Minute},
+		Timeout:        datakit.Duration{Duration: 30 * time.Second},
+		Granularity:    "PT1M",
+		ProcessMetrics: strings.Fields(defaultProcessMetrics),
+		DiskMetrics:    strings.Fields(defaultDiskMetrics),
+		Alerts:         true,
+		Election:       true,
+
+		Tags:       map[string]string{},
+		openAlerts: map[string]map[string]*alert{},
+		pauseCh:    make(chan bool, inputs.ElectionPauseChannelLength),
+		feeder:     dkio.DefaultFeeder(),
+		semStop:    cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -0,0 +1,258 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package mongodbatlas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	T "testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+const (
+	testRealm = "MMS Public API"
+	testNonce = "nonce-1"
+)
+
+// checkDigest verifies the digest authorization of r with user:pass.
+func checkDigest(r *http.Request, user, pass string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Digest ") {
+		return false
+	}
+
+	params := map[string]string{}
+	for _, kv := range splitParams(auth[len("Digest "):]) {
+		k, v, _ := strings.Cut(strings.TrimSpace(kv), "=")
+		params[k] = strings.Trim(v, `"`)
+	}
+
+	ha1 := md5Hex(user + ":" + testRealm + ":" + pass)
+	ha2 := md5Hex(r.Method + ":" + params["uri"])
+	expect := md5Hex(ha1 + ":" + testNonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+
+	return params["username"] == user && params["nonce"] == testNonce &&
+		params["uri"] == r.URL.RequestURI() && params["response"] == expect
+}
+
+func atlasServer(t *T.T, alerts *atomic.Value, challenges *int32) *httptest.Server {
+	t.Helper()
+
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	resps := map[string]string{
+		"/api/atlas/v2/groups": `{"results":[
+			{"id":"p1","name":"prod","orgId":"o1","clusterCount":1},
+			{"id":"p2","name":"dev","orgId":"o1","clusterCount":0}],"totalCount":2}`,
+		"/api/atlas/v2/groups/p1/clusters": `{"results":[
+			{"id":"c1","name":"Cluster0","clusterType":"REPLICASET","mongoDBVersion":"6.0.8","stateName":"IDLE","diskSizeGB":10.5}],"totalCount":1}`,
+		"/api/atlas/v2/groups/p1/processes": `{"results":[
+			{"id":"atlas-abc-shard-00-00.x.mongodb.net:27017","hostname":"atlas-abc-shard-00-00.x.mongodb.net","port":27017,
+			 "typeName":"REPLICA_PRIMARY","userAlias":"cluster0-shard-00-00.x.mongodb.net","replicaSetName":"atlas-abc-shard-0"}],"totalCount":1}`,
+		"/api/atlas/v2/groups/p1/processes/atlas-abc-shard-00-00.x.mongodb.net:27017/measurements": fmt.Sprintf(`{"measurements":[
+			{"name":"CONNECTIONS","units":"SCALAR","dataPoints":[{"timestamp":%q,"value":10},{"timestamp":%q,"value":12},{"timestamp":%q,"value":null}]},
+			{"name":"OPCOUNTER_QUERY","units":"SCALAR_PER_SECOND","dataPoints":[]}]}`, ts(-3*time.Minute), ts(-2*time.Minute), ts(-time.Minute)),
+		"/api/atlas/v2/groups/p1/processes/atlas-abc-shard-00-00.x.mongodb.net:27017/disks": `{"results":[{"partitionName":"data"}],"totalCount":1}`,
+		"/api/atlas/v2/groups/p1/processes/atlas-abc-shard-00-00.x.mongodb.net:27017/disks/data/measurements": fmt.Sprintf(`{"measurements":[
+			{"name":"DISK_PARTITION_IOPS_READ","units":"SCALAR_PER_SECOND","dataPoints":[{"timestamp":%q,"value":3.5}]}]}`, ts(-2*time.Minute)),
+		"/api/atlas/v2/groups/p2/clusters":  `{"results":[],"totalCount":0}`,
+		"/api/atlas/v2/groups/p2/processes": `{"results":[],"totalCount":0}`,
+		"/api/atlas/v2/groups/p2/alerts":    `{"results":[],"totalCount":0}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkDigest(r, "pub", "priv") {
+			atomic.AddInt32(challenges, 1)
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Digest realm="%s", domain="", nonce="%s", algorithm=MD5, qop="auth", stale=false`, testRealm, testNonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		assert.Equal(t, acceptHeader, r.Header.Get("Accept"))
+
+		if r.URL.Path == "/api/atlas/v2/groups/p1/alerts" {
+			assert.Equal(t, "OPEN", r.URL.Query().Get("status"))
+			_, _ = w.Write([]byte(alerts.Load().(string))) //nolint:forcetypeassert
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/measurements") {
+			assert.Equal(t, "PT1M", r.URL.Query().Get("granularity"))
+			assert.Equal(t, "PT5M", r.URL.Query().Get("period"))
+		}
+
+		resp, ok := resps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":404,"errorCode":"RESOURCE_NOT_FOUND","detail":"not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+}
+
+func TestSampleConfig(t *T.T) {
+	var conf struct {
+		Inputs struct {
+			Atlas []*Input `toml:"mongodb_atlas"`
+		} `toml:"inputs"`
+	}
+	_, err := toml.Decode(sampleCfg, &conf)
+	require.NoError(t, err)
+	require.Len(t, conf.Inputs.Atlas, 1)
+
+	ipt := conf.Inputs.Atlas[0]
+	assert.Equal(t, strings.Fields(defaultProcessMetrics), ipt.ProcessMetrics)
+	assert.Equal(t, strings.Fields(defaultDiskMetrics), ipt.DiskMetrics)
+	assert.True(t, ipt.Election)
+	assert.True(t, ipt.Alerts)
+}
+
+func TestParseChallenge(t *T.T) {
+	chal := parseChallenge(`Digest realm="MMS Public API", domain="", nonce="abc,def", algorithm=MD5, qop="auth,auth-int", stale=false`)
+	require.NotNil(t, chal)
+	assert.Equal(t, "MMS Public API", chal.realm)
+	assert.Equal(t, "abc,def", chal.nonce)
+	assert.Equal(t, "MD5", chal.algorithm)
+	assert.Equal(t, "auth", chal.qop)
+
+	assert.Nil(t, parseChallenge(`Basic realm="x"`))
+	assert.Nil(t, parseChallenge(`Digest realm="x"`))
+}
+
+func TestCollect(t *T.T) {
+	var (
+		alerts     atomic.Value
+		challenges int32
+	)
+	alerts.Store(fmt.Sprintf(`{"results":[
+		{"id":"a-old","eventTypeName":"HOST_DOWN","status":"OPEN","created":%q,"clusterName":"Cluster0"},
+		{"id":"a1","eventTypeName":"OUTSIDE_METRIC_THRESHOLD","status":"OPEN","created":%q,"clusterName":"Cluster0",
+		 "hostnameAndPort":"atlas-abc-shard-00-00.x.mongodb.net:27017","metricName":"CONNECTIONS","currentValue":{"number":500,"units":"RAW"}}
+	],"totalCount":2}`, time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), time.Now().UTC().Format(time.RFC3339)))
+
+	srv := atlasServer(t, &alerts, &challenges)
+	defer srv.Close()
+
+	feeder := dkio.NewMockedFeeder()
+
+	ipt := defaultInput()
+	ipt.PublicKey = "pub"
+	ipt.PrivateKey = "priv"
+	ipt.BaseURL = srv.URL + "/api/atlas/v2/"
+	ipt.Election = false
+	ipt.Tags = map[string]string{"env": "test"}
+	ipt.feeder = feeder
+	require.NoError(t, ipt.setup())
+
+	ipt.collect()
+
+	// challenged once, the challenge reused later
+	assert.Equal(t, int32(1), atomic.LoadInt32(&challenges))
+
+	metrics := feeder.Points(point.Metric)
+	require.Len(t, metrics, 2)
+
+	for _, pt := range metrics {
+		tags := pt.InfluxTags()
+		assert.Equal(t, "prod", tags["project"])
+		assert.Equal(t, "Cluster0", tags["cluster"])
+		assert.Equal(t, "REPLICA_PRIMARY", tags["type_name"])
+		assert.Equal(t, "test", tags["env"])
+
+		switch string(pt.Name()) {
+		case measurementProcess:
+			assert.Equal(t, 12.0, pt.Get([]byte("connections")))
+			assert.Nil(t, pt.Get([]byte("opcounter_query")))
+		case measurementDisk:
+			assert.Equal(t, "data", tags["partition"])
+			assert.Equal(t, 3.5, pt.Get([]byte("iops_read")))
+		default:
+			assert.Failf(t, "unexpected point", "%s", pt.Name())
+		}
+	}
+
+	names := map[string]string{}
+	for _, pt := range feeder.Points(point.Object) {
+		names[pt.InfluxTags()["name"]] = string(pt.Name())
+	}
+	assert.Equal(t, map[string]string{"prod": measurementProject, "dev": measurementProject, "Cluster0": measurementCluster}, names)
+
+	// alerts opened before the first round not sent
+	events := feeder.Points(point.KeyEvent)
+	require.Len(t, events, 1)
+	assert.Equal(t, measurementAlert, string(events[0].Name()))
+	assert.Equal(t, "a1", events[0].InfluxTags()["alert_id"])
+	assert.Equal(t, []byte("warning"), events[0].Get([]byte("df_status")))
+	assert.Equal(t, 500.0, events[0].Get([]byte("current_value")))
+	assert.Contains(t, string(events[0].Get([]byte("df_title")).([]byte)), "OUTSIDE_METRIC_THRESHOLD opened on atlas-abc-shard-00-00.x.mongodb.net:27017") //nolint:forcetypeassert
+
+	// a1 closed, objects not due
+	alerts.Store(`{"results":[{"id":"a-old","eventTypeName":"HOST_DOWN","status":"OPEN"}],"totalCount":1}`)
+	ipt.collect()
+
+	assert.Len(t, feeder.Points(point.Metric), 4)
+	assert.Len(t, feeder.Points(point.Object), 3)
+
+	events = feeder.Points(point.KeyEvent, dkio.WithTag("status", "CLOSED"))
+	require.Len(t, events, 1)
+	assert.Equal(t, "a1", events[0].InfluxTags()["alert_id"])
+	assert.Equal(t, []byte("ok"), events[0].Get([]byte("df_status")))
+	assert.Len(t, feeder.Points(point.KeyEvent), 2)
+
+	assert.Empty(t, feeder.LastErrors())
+}
+
+func TestCollectErrors(t *T.T) {
+	var (
+		alerts     atomic.Value
+		challenges int32
+	)
+	alerts.Store(`{"results":[],"totalCount":0}`)
+
+	srv := atlasServer(t, &alerts, &challenges)
+	defer srv.Close()
+
+	feeder := dkio.NewMockedFeeder()
+
+	ipt := defaultInput()
+	ipt.PublicKey = "pub"
+	ipt.PrivateKey = "wrong"
+	ipt.BaseURL = srv.URL + "/api/atlas/v2"
+	ipt.feeder = feeder
+	require.NoError(t, ipt.setup())
+
+	ipt.collect()
+	le := feeder.LastErrors()
+	require.Len(t, le, 1)
+	assert.Contains(t, le[0][1], "atlas API error 401: Unauthorized")
+
+	ipt = defaultInput()
+	assert.Error(t, ipt.setup())
+
+	ipt.PublicKey, ipt.PrivateKey = "pub", "priv"
+	ipt.Granularity = "1m"
+	assert.Error(t, ipt.setup())
+}
+
+func TestClusterOf(t *T.T) {
+	clusters := []*cluster{{Name: "Cluster0"}, {Name: "analytics"}}
+
+	assert.Equal(t, "Cluster0", clusterOf(&process{UserAlias: "cluster0-shard-00-01.x.mongodb.net"}, clusters))
+	assert.Equal(t, "analytics", clusterOf(&process{Hostname: "analytics-config-00-00.x.mongodb.net"}, clusters))
+	assert.Equal(t, "", clusterOf(&process{Hostname: "atlas-abc-shard-00-00.x.mongodb.net"}, clusters))
+}
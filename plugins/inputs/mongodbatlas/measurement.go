@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package mongodbatlas
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+func processTags() map[string]interface{} {
+	return map[string]interface{}{
+		"project":     &inputs.TagInfo{Desc: "Name of the project."},
+		"project_id":  &inputs.TagInfo{Desc: "ID of the project."},
+		"cluster":     &inputs.TagInfo{Desc: "Name of the cluster running the process, empty if unknown."},
+		"process":     &inputs.TagInfo{Desc: "ID of the process, in `hostname:port`."},
+		"type_name":   &inputs.TagInfo{Desc: "Type of the process, such as `REPLICA_PRIMARY`, `REPLICA_SECONDARY` and `SHARD_MONGOS`."},
+		"replica_set": &inputs.TagInfo{Desc: "Name of the replica set of the process, if any."},
+	}
+}
+
+type processMeasurement struct{}
+
+func (*processMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*processMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementProcess,
+		Type: "metric",
+		Desc: "Latest values of measurements of `mongod` and `mongos` processes. Each measurement in `process_metrics` is collected as a field named in lower case, fields listed are of the default ones.",
+		Tags: processTags(),
+		Fields: map[string]interface{}{
+			"connections":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of connections."},
+			"opcounter_cmd":     &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Commands per second."},
+			"opcounter_query":   &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Queries per second."},
+			"opcounter_insert":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Inserts per second."},
+			"opcounter_update":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Updates per second."},
+			"opcounter_delete":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Deletes per second."},
+			"opcounter_getmore": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Getmore operations per second."},
+			"query_targeting_scanned_objects_per_returned": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Ratio of documents scanned to documents returned."},
+			"system_normalized_cpu_user":                   &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "CPU used by user space, normalized by the number of cores."},
+			"system_normalized_cpu_kernel":                 &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "CPU used by the kernel, normalized by the number of cores."},
+			"system_memory_used":                           &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeKB, Desc: "Physical memory used."},
+			"system_memory_available":                      &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeKB, Desc: "Physical memory available."},
+			"cache_used_bytes":                             &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Bytes of data in the WiredTiger cache."},
+			"oplog_slave_lag_master_time":                  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Replication lag of secondaries."},
+			"oplog_master_time":                            &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Replication oplog window of the primary."},
+			"network_bytes_in":                             &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Bytes received per second."},
+			"network_bytes_out":                            &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Bytes sent per second."},
+		},
+	}
+}
+
+type diskMeasurement struct{}
+
+func (*diskMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*diskMeasurement) Info() *inputs.MeasurementInfo {
+	tags := processTags()
+	tags["partition"] = &inputs.TagInfo{Desc: "Name of the disk partition."}
+
+	return &inputs.MeasurementInfo{
+		Name: measurementDisk,
+		Type: "metric",
+		Desc: "Latest values of measurements of disk partitions of processes. Each measurement in `disk_metrics` is collected as a field named in lower case, with prefix `DISK_PARTITION_` trimmed.",
+		Tags: tags,
+		Fields: map[string]interface{}{
+			"iops_read":          &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Read operations per second."},
+			"iops_write":         &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Write operations per second."},
+			"latency_read":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Latency of reads."},
+			"latency_write":      &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Latency of writes."},
+			"space_used":         &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Disk space used."},
+			"space_free":         &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Disk space free."},
+			"space_percent_used": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Percent of disk space used."},
+		},
+	}
+}
+
+type projectMeasurement struct{}
+
+func (*projectMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*projectMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementProject,
+		Type: "object",
+		Desc: "Projects of Atlas.",
+		Tags: map[string]interface{}{
+			"name":       &inputs.TagInfo{Desc: "Name of the project."},
+			"project_id": &inputs.TagInfo{Desc: "ID of the project."},
+			"org_id":     &inputs.TagInfo{Desc: "ID of the organization of the project."},
+		},
+		Fields: map[string]interface{}{
+			"cluster_count": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of clusters of the project."},
+			"message":       &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "The project in JSON."},
+		},
+	}
+}
+
+type clusterMeasurement struct{}
+
+func (*clusterMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*clusterMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementCluster,
+		Type: "object",
+		Desc: "Clusters of Atlas.",
+		Tags: map[string]interface{}{
+			"name":            &inputs.TagInfo{Desc: "Name of the cluster."},
+			"cluster_id":      &inputs.TagInfo{Desc: "ID of the cluster."},
+			"project":         &inputs.TagInfo{Desc: "Name of the project."},
+			"project_id":      &inputs.TagInfo{Desc: "ID of the project."},
+			"cluster_type":    &inputs.TagInfo{Desc: "Type of the cluster, such as `REPLICASET` and `SHARDED`."},
+			"mongodb_version": &inputs.TagInfo{Desc: "Version of MongoDB."},
+			"state_name":      &inputs.TagInfo{Desc: "State of the cluster, such as `IDLE`, `CREATING` and `UPDATING`."},
+		},
+		Fields: map[string]interface{}{
+			"paused":         &inputs.FieldInfo{DataType: inputs.Bool, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether the cluster is paused."},
+			"backup_enabled": &inputs.FieldInfo{DataType: inputs.Bool, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether backup is enabled."},
+			"disk_size_gb":   &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeGB, Desc: "Storage capacity of each node."},
+			"message":        &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "The cluster in JSON."},
+		},
+	}
+}
+
+type alertMeasurement struct{}
+
+func (*alertMeasurement) LineProto() (*point.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*alertMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: measurementAlert,
+		Type: "keyevent",
+		Desc: "Sent when an alert of Atlas is opened or closed.",
+		Tags: map[string]interface{}{
+			"project":     &inputs.TagInfo{Desc: "Name of the project."},
+			"project_id":  &inputs.TagInfo{Desc: "ID of the project."},
+			"cluster":     &inputs.TagInfo{Desc: "Name of the cluster, if any."},
+			"process":     &inputs.TagInfo{Desc: "Host and port of the process, if any."},
+			"replica_set": &inputs.TagInfo{Desc: "Name of the replica set, if any."},
+			"event_type":  &inputs.TagInfo{Desc: "Event type of the alert, such as `OUTSIDE_METRIC_THRESHOLD` and `HOST_DOWN`."},
+			"alert_id":    &inputs.TagInfo{Desc: "ID of the alert."},
+			"status":      &inputs.TagInfo{Desc: "`OPEN` or `CLOSED`."},
+		},
+		Fields: map[string]interface{}{
+			"df_source":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Fixed to `mongodb_atlas`."},
+			"df_status":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "`warning` for opened alerts, `ok` for closed ones."},
+			"df_event_id":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "ID of the alert."},
+			"df_title":      &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event title."},
+			"df_message":    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event detail."},
+			"metric_name":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Metric of the alert, if any."},
+			"current_value": &inputs.FieldInfo{DataType: inputs.Float, Unit: inputs.UnknownUnit, Desc: "Current value of the metric, if any."},
+		},
+	}
+}
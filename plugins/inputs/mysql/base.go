@@ -527,6 +527,9 @@ func (m *baseMeasurement) Info() *inputs.MeasurementInfo { //nolint:funlen
 			"server": &inputs.TagInfo{
 				Desc: "Server addr",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 
 			"host": &inputs.TagInfo{
 				Desc: "The server host address",
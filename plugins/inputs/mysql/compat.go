@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package mysql
+
+import (
+	"strings"
+)
+
+// Compatibility modes of MySQL-protocol databases.
+const (
+	modeAuto      = "auto"
+	modeMySQL     = "mysql"
+	modeMariaDB   = "mariadb"
+	modeGreatSQL  = "greatsql"
+	modeOceanBase = "oceanbase"
+	modePolarDB   = "polardb"
+	modeTiDB      = "tidb"
+)
+
+// Tables not available on all MySQL-protocol databases.
+const (
+	tableDigest         = "performance_schema.events_statements_summary_by_digest"
+	tableStatusByUser   = "performance_schema.status_by_user"
+	tableUsers          = "performance_schema.users"
+	tableSetupConsumers = "performance_schema.setup_consumers"
+	tableThreads        = "performance_schema.threads"
+	tableInnodbMetrics  = "information_schema.innodb_metrics"
+	tableOBSQLAudit     = "oceanbase.gv$ob_sql_audit" // OceanBase 4.x
+	tableOBSQLAuditV3   = "oceanbase.gv$sql_audit"    // OceanBase 3.x
+)
+
+// compat is the compatibility of the server, detected on connected.
+type compat struct {
+	engine string
+
+	// available tables in lower case "schema.table", nil if not detected,
+	// then all tables are assumed available.
+	tables map[string]bool
+}
+
+func (c *compat) hasTable(table string) bool {
+	if c.tables == nil {
+		return true
+	}
+	return c.tables[table]
+}
+
+// detectEngine returns the engine by VERSION() and @@version_comment.
+func detectEngine(version, comment string) string {
+	s := strings.ToLower(version + " " + comment)
+
+	switch {
+	case strings.Contains(s, "oceanbase"):
+		return modeOceanBase
+	case strings.Contains(s, "tidb"):
+		return modeTiDB
+	case strings.Contains(s, "polardb") || strings.Contains(s, "polarx") || strings.Contains(s, "tddl"):
+		return modePolarDB
+	case strings.Contains(s, "greatsql"):
+		return modeGreatSQL
+	case strings.Contains(s, "mariadb"):
+		return modeMariaDB
+	default:
+		return modeMySQL
+	}
+}
+
+func isKnownMode(mode string) bool {
+	switch mode {
+	case modeMySQL, modeMariaDB, modeGreatSQL, modeOceanBase, modePolarDB, modeTiDB:
+		return true
+	default:
+		return false
+	}
+}
+
+// initCompat detects the engine, unless set by mode, and the tables
+// available, so that collectors skip or substitute missing tables instead
+// of failing on each collection.
+func (i *Input) initCompat() {
+	mode := strings.ToLower(i.Mode)
+
+	switch {
+	case mode == "" || mode == modeAuto:
+		var version, comment string
+		if r := i.q("SELECT VERSION(), @@version_comment"); r != nil {
+			version, comment = getCleanVersionComment(r)
+		}
+
+		i.compat.engine = detectEngine(version, comment)
+
+		// PolarDB for MySQL reports the version of MySQL
+		if i.compat.engine == modeMySQL {
+			if r := i.q("SHOW VARIABLES LIKE 'polardb_version'"); r != nil && getCleanVariableExists(r) {
+				i.compat.engine = modePolarDB
+			}
+		}
+
+		l.Infof("detected engine %s of %s (%s %s)", i.compat.engine, i.Addr, version, comment)
+
+	case isKnownMode(mode):
+		i.compat.engine = mode
+
+	default:
+		l.Warnf("unknown mode %q, detect the engine instead", i.Mode)
+		i.Mode = modeAuto
+		i.initCompat()
+		return
+	}
+
+	tablesSQL := `
+	SELECT LOWER(TABLE_SCHEMA), LOWER(TABLE_NAME)
+	FROM information_schema.tables
+	WHERE LOWER(TABLE_SCHEMA) IN ('performance_schema', 'information_schema', 'oceanbase')
+	`
+	if r := i.q(tablesSQL); r != nil {
+		i.compat.tables = getCleanCompatTables(r)
+	}
+
+	for _, table := range []string{tableDigest, tableStatusByUser, tableUsers, tableSetupConsumers, tableThreads, tableInnodbMetrics} {
+		if !i.compat.hasTable(table) {
+			l.Infof("table %s not available on %s %s, related metrics skipped", table, i.compat.engine, i.Addr)
+		}
+	}
+
+	if _, ok := i.Tags["engine_type"]; !ok {
+		i.Tags["engine_type"] = i.compat.engine
+	}
+}
+
+// schemaQueryExecTimeSQL returns the SQL of average query time of schemas,
+// or empty if not available.
+func (i *Input) schemaQueryExecTimeSQL() string {
+	switch {
+	case i.compat.hasTable(tableDigest):
+		return `
+	SELECT schema_name, ROUND((SUM(sum_timer_wait) / SUM(count_star)) / 1000000) AS avg_us
+	FROM performance_schema.events_statements_summary_by_digest
+	WHERE schema_name IS NOT NULL
+	GROUP BY schema_name;
+	`
+	case i.compat.hasTable(tableOBSQLAudit):
+		return `
+	SELECT db_name, ROUND(AVG(elapsed_time)) AS avg_us
+	FROM oceanbase.GV$OB_SQL_AUDIT
+	WHERE db_name IS NOT NULL AND db_name <> ''
+	GROUP BY db_name;
+	`
+	case i.compat.hasTable(tableOBSQLAuditV3):
+		return `
+	SELECT db_name, ROUND(AVG(elapsed_time)) AS avg_us
+	FROM oceanbase.GV$SQL_AUDIT
+	WHERE db_name IS NOT NULL AND db_name <> ''
+	GROUP BY db_name;
+	`
+	default:
+		return ""
+	}
+}
+
+func getCleanVersionComment(r rows) (version, comment string) {
+	defer closeRows(r)
+
+	for r.Next() {
+		if err := r.Scan(&version, &comment); err != nil {
+			l.Warnf("Scan: %s, ignored", err)
+			continue
+		}
+	}
+
+	return version, comment
+}
+
+func getCleanVariableExists(r rows) bool {
+	defer closeRows(r)
+
+	return r.Next()
+}
+
+func getCleanCompatTables(r rows) map[string]bool {
+	defer closeRows(r)
+
+	res := map[string]bool{}
+	for r.Next() {
+		var schema, table string
+		if err := r.Scan(&schema, &table); err != nil {
+			l.Warnf("Scan: %s, ignored", err)
+			continue
+		}
+
+		res[schema+"."+table] = true
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
@@ -15,6 +15,13 @@ const (
   # sock = "<SOCK>"
   # charset = "utf8"
 
+  ## Compatibility mode of MySQL-protocol databases:
+  ##   auto/mysql/mariadb/greatsql/oceanbase/polardb/tidb
+  ## The engine is detected if auto, and tagged as engine_type. Metrics
+  ## from tables missing on the engine, such as performance_schema ones,
+  ## are skipped.
+  # mode = "auto"
+
   ## @param connect_timeout - number - optional - default: 10s
   # connect_timeout = "10s"
 
@@ -48,6 +48,9 @@ func (m *customerMeasurement) Info() *inputs.MeasurementInfo {
 			"server": &inputs.TagInfo{
 				Desc: "Server addr",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 		},
 	}
 }
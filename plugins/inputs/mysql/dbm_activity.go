@@ -303,9 +303,10 @@ func (m *dbmActivityMeasurement) Info() *inputs.MeasurementInfo {
 			},
 		},
 		Tags: map[string]interface{}{
-			"host":    &inputs.TagInfo{Desc: "The server host address"},
-			"service": &inputs.TagInfo{Desc: "The service name and the value is 'mysql'"},
-			"server":  &inputs.TagInfo{Desc: "The server address"},
+			"host":        &inputs.TagInfo{Desc: "The server host address"},
+			"service":     &inputs.TagInfo{Desc: "The service name and the value is 'mysql'"},
+			"server":      &inputs.TagInfo{Desc: "The server address"},
+			"engine_type": &inputs.TagInfo{Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb"},
 		},
 	}
 }
@@ -232,6 +232,9 @@ func (m *dbmSampleMeasurement) Info() *inputs.MeasurementInfo {
 			"server": &inputs.TagInfo{
 				Desc: "The server address containing both host and port",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 			"service":           &inputs.TagInfo{Desc: "The service name and the value is 'mysql'"},
 			"current_schema":    &inputs.TagInfo{Desc: "The name of the current schema."},
 			"plan_definition":   &inputs.TagInfo{Desc: "The plan definition of JSON format."},
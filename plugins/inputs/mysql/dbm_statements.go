@@ -127,6 +127,9 @@ func (m *dbmStateMeasurement) Info() *inputs.MeasurementInfo {
 			"server": &inputs.TagInfo{
 				Desc: "The server address containing both host and port",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 		},
 	}
 }
@@ -79,6 +79,10 @@ type Input struct {
 
 	Charset string `toml:"charset"`
 
+	// Compatibility mode of MySQL-protocol databases, detected if "auto" or empty.
+	Mode   string `toml:"mode"`
+	compat compat
+
 	Timeout         string `toml:"connect_timeout"`
 	timeoutDuration time.Duration
 
@@ -248,6 +252,7 @@ func (i *Input) initCfg() error {
 	}
 
 	i.globalTag()
+	i.initCompat()
 	if i.Dbm {
 		i.initDbm()
 	}
@@ -454,7 +459,7 @@ func (i *Input) Collect() (map[gcPoint.Category][]*gcPoint.Point, error) {
 		}
 	}
 
-	if i.InnoDB {
+	if i.InnoDB && i.compat.hasTable(tableInnodbMetrics) {
 		// mysql_innodb
 		pts, err := i.metricCollectMysqlInnodb()
 		if err != nil {
@@ -468,7 +473,7 @@ func (i *Input) Collect() (map[gcPoint.Category][]*gcPoint.Point, error) {
 
 	if i.Dbm && (i.DbmMetric.Enabled || i.DbmSample.Enabled || i.DbmActivity.Enabled) {
 		g := goroutine.NewGroup(goroutine.Option{Name: goroutine.GetInputName("mysql")})
-		if i.DbmMetric.Enabled {
+		if i.DbmMetric.Enabled && i.compat.hasTable(tableDigest) {
 			g.Go(func(ctx context.Context) error {
 				// mysql_dbm_metric
 				pts, err := i.metricCollectMysqlDbmMetric()
@@ -483,7 +488,7 @@ func (i *Input) Collect() (map[gcPoint.Category][]*gcPoint.Point, error) {
 			})
 		}
 
-		if i.DbmSample.Enabled {
+		if i.DbmSample.Enabled && i.compat.hasTable(tableSetupConsumers) {
 			g.Go(func(ctx context.Context) error {
 				// mysql_dbm_sample
 				pts, err := i.metricCollectMysqlDbmSample()
@@ -498,7 +503,7 @@ func (i *Input) Collect() (map[gcPoint.Category][]*gcPoint.Point, error) {
 			})
 		}
 
-		if i.DbmActivity.Enabled {
+		if i.DbmActivity.Enabled && i.compat.hasTable(tableThreads) {
 			g.Go(func(ctx context.Context) error {
 				// mysql_dbm_activity
 				if pts, err := i.metricCollectMysqlDbmActivity(); err != nil {
@@ -13,8 +13,6 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
-	dt "github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/GuanceCloud/cliutils/point"
@@ -57,47 +55,46 @@ type caseSpec struct {
 	ipt    *Input
 	feeder *io.MockedFeeder
 
-	pool     *dt.Pool
-	resource *dt.Resource
+	container testutils.Container
 
 	cr *testutils.CaseResult
 }
 
-// getPool generates pool to connect to Docker.
-func (cs *caseSpec) getPool(r *testutils.RemoteInfo) (*dt.Pool, error) {
-	dockerTCP := r.TCPURL()
+// getRuntime gets container runtime of the remote host.
+func (cs *caseSpec) getRuntime(r *testutils.RemoteInfo) (testutils.ContainerRuntime, error) {
+	cs.t.Logf("get remote: %+#v", r)
 
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
-
-	p, err := dt.NewPool(dockerTCP)
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return nil, err
 	}
 
-	err = p.Client.Ping()
+	err = rt.Ping()
 	if err != nil {
-		if r.Host != "0.0.0.0" {
+		if r.Host != "0.0.0.0" || r.Runtime != testutils.RuntimeDocker || r.Socket != "" {
 			return nil, err
 		}
 		// use default docker service
 		cs.t.Log("try default docker")
-		p, err = dt.NewPool("")
+		local := *r
+		local.Socket = "unix:///var/run/docker.sock"
+		rt, err = local.ContainerRuntime()
 		if err != nil {
 			return nil, err
 		} else {
-			if err = p.Client.Ping(); err != nil {
+			if err = rt.Ping(); err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	return p, nil
+	return rt, nil
 }
 
 func (cs *caseSpec) run() error {
 	r := testutils.GetRemote()
 	start := time.Now()
-	p, err := cs.getPool(r)
+	rt, err := cs.getRuntime(r)
 	if err != nil {
 		return err
 	}
@@ -111,38 +108,33 @@ func (cs *caseSpec) run() error {
 	containerName := fmt.Sprintf("%s.%s", hostname, cs.name)
 
 	// remove the container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 	port := testutils.RandPort("tcp")
-	resource, err := p.RunWithOptions(&dt.RunOptions{
+	c, err := rt.Run(&testutils.RunOptions{
 		// specify container image & tag
 		Repository: cs.repo,
 		Tag:        cs.repoTag,
 
 		// port binding
-		PortBindings: map[docker.Port][]docker.PortBinding{
-			"3306/tcp": {{HostIP: "0.0.0.0", HostPort: fmt.Sprintf("%d", port)}},
-		},
+		PortBindings: map[string]string{"3306/tcp": fmt.Sprintf("%d", port)},
 
 		Name: containerName,
 
 		// container run-time envs
 		Env: cs.envs,
-	}, func(c *docker.HostConfig) {
-		c.RestartPolicy = docker.RestartPolicy{Name: "no"}
 	})
 	if err != nil {
 		return err
 	}
 
-	cs.pool = p
-	cs.resource = resource
+	cs.container = c
 	cs.ipt.Port = port
 
 	time.Sleep(10 * time.Second)
 
-	if err := setupContainer(p, resource); err != nil {
+	if err := setupContainer(c); err != nil {
 		return err
 	}
 
@@ -154,7 +146,7 @@ func (cs *caseSpec) run() error {
 	// wait a period of time to ensure that the MySQL service is available.
 	time.Sleep(10 * time.Second)
 
-	if err := initMySQL(resource, cs); err != nil {
+	if err := initMySQL(c, cs); err != nil {
 		return err
 	}
 
@@ -177,11 +169,8 @@ func (cs *caseSpec) run() error {
 			case <-cs.ipt.semStop.Wait():
 				return
 			default:
-				resource.Exec([]string{
+				c.Exec([]string{
 					"/bin/sh", "-c", fmt.Sprintf(`mysql -uroot -p%s -e "%s"`, MySQLPassword, "select * from test.user"),
-				}, dt.ExecOptions{
-					StdOut: os.Stdout,
-					StdErr: os.Stderr,
 				})
 			}
 			time.Sleep(1 * time.Second)
@@ -432,7 +421,7 @@ func assertMeasurements(pts []*point.Point, cs *caseSpec) error {
 	return nil
 }
 
-func initMySQL(resource *dt.Resource, cs *caseSpec) error {
+func initMySQL(c testutils.Container, cs *caseSpec) error {
 	createUserSQL := MySQL5UserSQL
 	if cs.repoTag[0] == '8' {
 		createUserSQL = MySQL8UserSQL
@@ -446,20 +435,17 @@ UPDATE performance_schema.setup_consumers SET enabled='YES' WHERE name = 'events
 
 	initSQL := createUserSQL + MySQLGrantSQL + configConsumerSQL
 	mysqlInitCmd := fmt.Sprintf(`mysql -uroot -p%s -e "%s"`, MySQLPassword, initSQL)
-	_, err := resource.Exec([]string{
+	_, err := c.Exec([]string{
 		"/bin/sh", "-c", mysqlInitCmd,
-	}, dt.ExecOptions{
-		StdOut: os.Stdout,
-		StdErr: os.Stderr,
 	})
 	if err != nil {
 		return err
 	}
 
-	return initDbm(resource, cs.repoTag[0])
+	return initDbm(c, cs.repoTag[0])
 }
 
-func initDbm(resource *dt.Resource, version byte) error {
+func initDbm(c testutils.Container, version byte) error {
 	sql := ""
 	switch version {
 	case '5':
@@ -499,18 +485,15 @@ UPDATE performance_schema.setup_consumers SET enabled='YES' WHERE name = 'events
 `
 
 	sqlCmd := fmt.Sprintf(`mysql -uroot -p%s -e "%s"`, MySQLPassword, sql)
-	_, err := resource.Exec([]string{
+	_, err := c.Exec([]string{
 		"/bin/sh", "-c", sqlCmd,
-	}, dt.ExecOptions{
-		StdOut: os.Stdout,
-		StdErr: os.Stderr,
 	})
 
 	return err
 }
 
-// setupContainer sets up the container for the given Pool and Resource.
-func setupContainer(p *dt.Pool, resource *dt.Resource) error {
+// setupContainer sets up the container.
+func setupContainer(c testutils.Container) error {
 	mysqlConfCmd := `cat > /etc/mysql/conf.d/mysql.cnf <<EOF
 [mysqld]
 	performance_schema = on
@@ -524,14 +507,11 @@ func setupContainer(p *dt.Pool, resource *dt.Resource) error {
 EOF
 `
 
-	resource.Exec([]string{
+	c.Exec([]string{
 		"/bin/sh", "-c", mysqlConfCmd,
-	}, dt.ExecOptions{
-		StdOut: os.Stdout,
-		StdErr: os.Stderr,
 	})
 
-	if err := p.Client.RestartContainer(resource.Container.ID, 30); err != nil {
+	if err := c.Restart(30 * time.Second); err != nil {
 		return err
 	}
 
@@ -553,39 +533,37 @@ func TestMySQLInput(t *testing.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []testutils.TestingCase
 	for _, tc := range cases {
-		func(tc *caseSpec) {
-			t.Run(tc.name, func(t *testing.T) {
-				t.Parallel()
-				tc.t = t
-				caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-				t.Logf("testing %s...", tc.name)
+	testutils.RunCases(t, arr)
+}
 
-				if err := tc.run(); err != nil {
-					tc.cr.Status = testutils.TestFailed
-					tc.cr.FailedMessage = err.Error()
+func (cs *caseSpec) CaseName() string { return cs.name }
 
-					assert.NoError(t, err)
-				} else {
-					tc.cr.Status = testutils.TestPassed
-				}
+func (cs *caseSpec) Result() *testutils.CaseResult { return cs.cr }
 
-				tc.cr.Cost = time.Since(caseStart)
+func (cs *caseSpec) Run(t *testing.T) error {
+	cs.t = t
+	return cs.run()
+}
 
-				assert.NoError(t, testutils.Flush(tc.cr))
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
+	}
 
-				t.Cleanup(func() {
-					// clean remote docker resources
-					if tc.resource == nil {
-						return
-					}
+	return cs.container.Purge()
+}
 
-					assert.NoError(t, tc.pool.Purge(tc.resource))
-				})
-			})
-		}(tc)
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []testutils.Container {
+	if cs.container == nil {
+		return nil
 	}
+
+	return []testutils.Container{cs.container}
 }
@@ -69,16 +69,12 @@ func (i *Input) collectMysqlSchema() error {
 		l.Warn("collect_schema_size_failed")
 	}
 
-	queryExecPerTimeSQL := `
-	SELECT schema_name, ROUND((SUM(sum_timer_wait) / SUM(count_star)) / 1000000) AS avg_us
-	FROM performance_schema.events_statements_summary_by_digest
-	WHERE schema_name IS NOT NULL
-	GROUP BY schema_name;
-	`
-	if res := getCleanSchemaData(i.q(queryExecPerTimeSQL)); res != nil {
-		i.mSchemaQueryExecTime = res
-	} else {
-		l.Warn("collect_schema_failed")
+	if queryExecPerTimeSQL := i.schemaQueryExecTimeSQL(); queryExecPerTimeSQL != "" {
+		if res := getCleanSchemaData(i.q(queryExecPerTimeSQL)); res != nil {
+			i.mSchemaQueryExecTime = res
+		} else {
+			l.Warn("collect_schema_failed")
+		}
 	}
 
 	return nil
@@ -173,23 +169,29 @@ func (i *Input) collectMysqlUserStatus() error {
 	where user = '%s';
     `
 
+	hasStatusByUser, hasUsers := i.compat.hasTable(tableStatusByUser), i.compat.hasTable(tableUsers)
+
 	for user := range i.mUserStatusName {
-		if res := getCleanUserStatusVariable(i.q(fmt.Sprintf(userQuerySQL, user))); res != nil {
-			i.mUserStatusVariable = make(map[string]map[string]interface{})
-			i.mUserStatusVariable[user] = res
+		if hasStatusByUser {
+			if res := getCleanUserStatusVariable(i.q(fmt.Sprintf(userQuerySQL, user))); res != nil {
+				i.mUserStatusVariable = make(map[string]map[string]interface{})
+				i.mUserStatusVariable[user] = res
+			}
 		}
 
-		if res := getCleanUserStatusConnection(i.q(fmt.Sprintf(userConnSQL, user))); res != nil {
-			i.mUserStatusConnection = make(map[string]map[string]interface{})
-			i.mUserStatusConnection[user] = res
+		if hasUsers {
+			if res := getCleanUserStatusConnection(i.q(fmt.Sprintf(userConnSQL, user))); res != nil {
+				i.mUserStatusConnection = make(map[string]map[string]interface{})
+				i.mUserStatusConnection[user] = res
+			}
 		}
 	}
 
-	if len(i.mUserStatusVariable) == 0 {
+	if hasStatusByUser && len(i.mUserStatusVariable) == 0 {
 		l.Warnf("collect_user_variable_failed")
 	}
 
-	if len(i.mUserStatusConnection) == 0 {
+	if hasUsers && len(i.mUserStatusConnection) == 0 {
 		l.Warnf("collect_user_connection_failed")
 	}
 
@@ -583,3 +583,80 @@ func TestGetCleanMysqlCustomQueries(t *testing.T) {
 	// 	})
 	// }
 }
+
+//----------------------------------------------------------------------
+// compatibility modes
+
+// go test -v -timeout 30s -run ^TestDetectEngine$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mysql
+func TestDetectEngine(t *testing.T) {
+	cases := []struct {
+		version, comment string
+		expect           string
+	}{
+		{"8.0.27", "MySQL Community Server - GPL", modeMySQL},
+		{"10.6.12-MariaDB-1:10.6.12+maria~ubu2004", "mariadb.org binary distribution", modeMariaDB},
+		{"8.0.32-24", "GreatSQL, Release 24, Revision 3714067bc8c", modeGreatSQL},
+		{"5.7.25-OceanBase-v4.2.1.0", "OceanBase 4.2.1.0 (r100000102023092807-7b0f43693565654bb1d7343f728e9a0a9b1e6a6d)", modeOceanBase},
+		{"5.6.29-TDDL-5.4.17-16835173", "", modePolarDB},
+		{"5.7.25-TiDB-v7.1.0", "", modeTiDB},
+		{"", "", modeMySQL},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.version, func(t *testing.T) {
+			assert.Equal(t, tc.expect, detectEngine(tc.version, tc.comment))
+		})
+	}
+}
+
+// go test -v -timeout 30s -run ^TestGetCleanCompatTables$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/mysql
+func TestGetCleanCompatTables(t *testing.T) {
+	t.Run("oceanbase", func(t *testing.T) {
+		i := &Input{}
+		i.compat.tables = getCleanCompatTables(&mockRows{
+			t: t,
+			data: [][]interface{}{
+				{"information_schema", "tables"},
+				{"information_schema", "processlist"},
+				{"oceanbase", "gv$ob_sql_audit"},
+			},
+		})
+
+		assert.True(t, i.compat.hasTable("information_schema.tables"))
+		assert.False(t, i.compat.hasTable(tableDigest))
+		assert.False(t, i.compat.hasTable(tableInnodbMetrics))
+		assert.Contains(t, i.schemaQueryExecTimeSQL(), "oceanbase.GV$OB_SQL_AUDIT")
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		i := &Input{}
+		i.compat.tables = getCleanCompatTables(&mockRows{
+			t: t,
+			data: [][]interface{}{
+				{"performance_schema", "events_statements_summary_by_digest"},
+				{"oceanbase", "gv$ob_sql_audit"},
+			},
+		})
+
+		assert.Contains(t, i.schemaQueryExecTimeSQL(), tableDigest)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		i := &Input{}
+		i.compat.tables = getCleanCompatTables(&mockRows{
+			t:    t,
+			data: [][]interface{}{{"information_schema", "tables"}},
+		})
+
+		assert.Empty(t, i.schemaQueryExecTimeSQL())
+	})
+
+	t.Run("not-detected", func(t *testing.T) {
+		i := &Input{}
+		i.compat.tables = getCleanCompatTables(&mockRows{t: t})
+
+		assert.Nil(t, i.compat.tables)
+		assert.True(t, i.compat.hasTable(tableDigest))
+		assert.True(t, i.compat.hasTable(tableInnodbMetrics))
+	})
+}
@@ -63,6 +63,9 @@ func (m *schemaMeasurement) Info() *inputs.MeasurementInfo {
 			"server": &inputs.TagInfo{
 				Desc: "Server addr",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 			"schema_name": &inputs.TagInfo{
 				Desc: "Schema name",
 			},
@@ -80,6 +80,9 @@ func (m *tbMeasurement) Info() *inputs.MeasurementInfo {
 			"server": &inputs.TagInfo{
 				Desc: "Server addr",
 			},
+			"engine_type": &inputs.TagInfo{
+				Desc: "Engine of the server, such as mysql, mariadb, oceanbase or polardb",
+			},
 			"table_name": &inputs.TagInfo{
 				Desc: "The name of the table.",
 			},
@@ -0,0 +1,230 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package netnsstat collects socket and conntrack summary of each network namespace.
+package netnsstat
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var (
+	_ inputs.ReadEnv   = (*Input)(nil)
+	_ inputs.Singleton = (*Input)(nil)
+)
+
+const (
+	minInterval = time.Second * 10
+	maxInterval = time.Minute * 10
+
+	defaultTopTalkers = 10
+)
+
+const (
+	inputName           = "netnsstat"
+	metricName          = inputName
+	metricNameTopTalker = "netnsstat_top_talker"
+	sampleCfg           = `
+[[inputs.netnsstat]]
+  ## (Optional) Collect interval, default is 30 seconds
+  interval = '30s'
+
+  ## Number of remote peers with the most connections reported for each network namespace,
+  ## set to 0 to disable the top talker measurement.
+  top_talkers = 10
+
+  ## Whether to report the host network namespace, pods using host network are counted in it.
+  host_netns = true
+
+[inputs.netnsstat.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	Interval   datakit.Duration  `toml:"interval"`
+	TopTalkers int               `toml:"top_talkers"`
+	HostNetns  bool              `toml:"host_netns"`
+	Tags       map[string]string `toml:"tags"`
+
+	semStop *cliutils.Sem
+}
+
+func (ipt *Input) Singleton() {}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	l.Info("netnsstat input started")
+
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		start := time.Now()
+
+		stats, topTalkers, err := ipt.collect()
+		if err != nil {
+			l.Errorf("collect: %s", err)
+			io.FeedLastError(inputName, err.Error())
+		}
+
+		if len(stats) > 0 {
+			if err := inputs.FeedMeasurement(metricName, datakit.Metric, stats,
+				&io.Option{CollectCost: time.Since(start)}); err != nil {
+				l.Errorf("FeedMeasurement: %s", err)
+			}
+		}
+
+		if len(topTalkers) > 0 {
+			if err := inputs.FeedMeasurement(metricNameTopTalker, datakit.Metric, topTalkers,
+				&io.Option{CollectCost: time.Since(start)}); err != nil {
+				l.Errorf("FeedMeasurement: %s", err)
+			}
+		}
+
+		select {
+		case <-tick.C:
+		case <-datakit.Exit.Wait():
+			l.Info("netnsstat input exit")
+			return
+		case <-ipt.semStop.Wait():
+			l.Info("netnsstat input return")
+			return
+		}
+	}
+}
+
+func (ipt *Input) collect() ([]inputs.Measurement, []inputs.Measurement, error) {
+	summaries, err := collectNetns(ipt.HostNetns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stats, topTalkers []inputs.Measurement
+	for _, s := range summaries {
+		tags := s.tags()
+		for k, v := range ipt.Tags {
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+
+		stats = append(stats, &netnsMeasurement{
+			name:   metricName,
+			tags:   tags,
+			fields: s.fields(),
+		})
+
+		for i, t := range s.topTalkers(ipt.TopTalkers) {
+			ttags := map[string]string{
+				"remote_ip": t.ip,
+				"rank":      strconv.Itoa(i + 1),
+			}
+			for k, v := range tags {
+				ttags[k] = v
+			}
+
+			topTalkers = append(topTalkers, &topTalkerMeasurement{
+				name: metricNameTopTalker,
+				tags: ttags,
+				fields: map[string]interface{}{
+					"connections": t.count,
+				},
+			})
+		}
+	}
+
+	return stats, topTalkers, nil
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (*Input) Catalog() string { return "host" }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string {
+	return []string{datakit.OSLabelLinux, datakit.LabelK8s}
+}
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&netnsMeasurement{},
+		&topTalkerMeasurement{},
+	}
+}
+
+// ReadEnv support envs：only for K8S.
+func (ipt *Input) ReadEnv(envs map[string]string) {
+	// ENV_INPUT_NETNSSTAT_TAGS : "a=b,c=d"
+	if tagsStr, ok := envs["ENV_INPUT_NETNSSTAT_TAGS"]; ok {
+		tags := config.ParseGlobalTags(tagsStr)
+		for k, v := range tags {
+			ipt.Tags[k] = v
+		}
+	}
+
+	// ENV_INPUT_NETNSSTAT_INTERVAL : datakit.Duration
+	if str, ok := envs["ENV_INPUT_NETNSSTAT_INTERVAL"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETNSSTAT_INTERVAL to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, da)
+		}
+	}
+
+	// ENV_INPUT_NETNSSTAT_TOP_TALKERS : int
+	if str, ok := envs["ENV_INPUT_NETNSSTAT_TOP_TALKERS"]; ok {
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETNSSTAT_TOP_TALKERS: %s, ignore", err)
+		} else {
+			ipt.TopTalkers = n
+		}
+	}
+
+	// ENV_INPUT_NETNSSTAT_HOST_NETNS : bool
+	if str, ok := envs["ENV_INPUT_NETNSSTAT_HOST_NETNS"]; ok {
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETNSSTAT_HOST_NETNS: %s, ignore", err)
+		} else {
+			ipt.HostNetns = b
+		}
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Interval:   datakit.Duration{Duration: time.Second * 30},
+		TopTalkers: defaultTopTalkers,
+		HostNetns:  true,
+		Tags:       map[string]string{},
+		semStop:    cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
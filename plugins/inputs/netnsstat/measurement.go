@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netnsstat
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var commonTags = map[string]interface{}{
+	"host":         &inputs.TagInfo{Desc: "Host name"},
+	"netns":        &inputs.TagInfo{Desc: "Inode of the network namespace"},
+	"host_network": &inputs.TagInfo{Desc: "Whether the network namespace is the host network namespace(true/false)"},
+	"process_name": &inputs.TagInfo{Desc: "Name of the process picked to represent the network namespace"},
+	"container_id": &inputs.TagInfo{Desc: "Container ID of the process"},
+	"pod_uid":      &inputs.TagInfo{Desc: "Kubernetes pod UID of the process"},
+}
+
+type netnsMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+func (m *netnsMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOpt())
+}
+
+func newCountFieldInfo(desc string) *inputs.FieldInfo {
+	return &inputs.FieldInfo{
+		Type:     inputs.Gauge,
+		DataType: inputs.Int,
+		Unit:     inputs.NCount,
+		Desc:     desc,
+	}
+}
+
+//nolint:lll
+func (m *netnsMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricName,
+		Fields: map[string]interface{}{
+			"tcp_established":   newCountFieldInfo("The number of TCP connections in ESTABLISHED state."),
+			"tcp_syn_sent":      newCountFieldInfo("The number of TCP connections in SYN_SENT state."),
+			"tcp_syn_recv":      newCountFieldInfo("The number of TCP connections in SYN_RECV state."),
+			"tcp_fin_wait1":     newCountFieldInfo("The number of TCP connections in FIN_WAIT1 state."),
+			"tcp_fin_wait2":     newCountFieldInfo("The number of TCP connections in FIN_WAIT2 state."),
+			"tcp_time_wait":     newCountFieldInfo("The number of TCP connections in TIME_WAIT state."),
+			"tcp_close":         newCountFieldInfo("The number of TCP sockets in CLOSE state."),
+			"tcp_close_wait":    newCountFieldInfo("The number of TCP connections in CLOSE_WAIT state."),
+			"tcp_last_ack":      newCountFieldInfo("The number of TCP connections in LAST_ACK state."),
+			"tcp_listen":        newCountFieldInfo("The number of TCP sockets in LISTEN state."),
+			"tcp_closing":       newCountFieldInfo("The number of TCP connections in CLOSING state."),
+			"tcp_new_syn_recv":  newCountFieldInfo("The number of TCP connections in NEW_SYN_RECV state."),
+			"tcp_total":         newCountFieldInfo("The number of TCP sockets of all states."),
+			"udp_socket":        newCountFieldInfo("The number of UDP sockets."),
+			"conntrack_entries": newCountFieldInfo("The number of conntrack entries of the network namespace, absent if nf_conntrack is not loaded."),
+			"pid":               &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.UnknownUnit, Desc: "PID of the process picked to represent the network namespace."},
+		},
+		Tags: commonTags,
+	}
+}
+
+type topTalkerMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+func (m *topTalkerMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.MOpt())
+}
+
+func (m *topTalkerMeasurement) Info() *inputs.MeasurementInfo {
+	tags := map[string]interface{}{
+		"remote_ip": &inputs.TagInfo{Desc: "Remote IP address"},
+		"rank":      &inputs.TagInfo{Desc: "Rank of the remote peer by connection count in the network namespace, starting from 1"},
+	}
+	for k, v := range commonTags {
+		tags[k] = v
+	}
+
+	return &inputs.MeasurementInfo{
+		Name: metricNameTopTalker,
+		Fields: map[string]interface{}{
+			"connections": newCountFieldInfo("The number of non-listening TCP sockets and UDP sockets connected to the remote peer."),
+		},
+		Tags: tags,
+	}
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build linux
+// +build linux
+
+package netnsstat
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/hostutil/procnet"
+)
+
+// collectNetns walks all processes and summarizes the sockets of each network
+// namespace, the process with smallest pid is used to represent the namespace.
+func collectNetns(withHost bool) ([]*netnsSummary, error) {
+	pids, err := procnet.NetnsPids()
+	if err != nil {
+		return nil, err
+	}
+
+	// without privilege we can not tell which one is the host network namespace
+	hostInode, err := procnet.NetnsInode(1)
+	if err != nil {
+		l.Warnf("get host network namespace: %s", err)
+	}
+
+	var res []*netnsSummary
+	for inode, pid := range pids {
+		if inode == hostInode && !withHost {
+			continue
+		}
+
+		s := newNetnsSummary(inode, pid)
+		s.hostNetns = inode == hostInode
+
+		if err := summarize(s); err != nil {
+			l.Debugf("summarize netns %d of pid %d: %s", inode, pid, err)
+			continue
+		}
+
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].pid < res[j].pid })
+
+	return res, nil
+}
+
+func summarize(s *netnsSummary) error {
+	for _, tbl := range []struct {
+		name  string
+		isTCP bool
+	}{
+		{"tcp", true},
+		{"tcp6", true},
+		{"udp", false},
+		{"udp6", false},
+	} {
+		isTCP := tbl.isTCP
+		if err := procnet.ReadFile(s.pid, func(r io.Reader) error {
+			return s.parseSockTable(r, isTCP)
+		}, "net", tbl.name); err != nil {
+			// ipv6 may be disabled
+			if os.IsNotExist(err) && strings.HasSuffix(tbl.name, "6") {
+				continue
+			}
+			return err
+		}
+	}
+
+	// the conntrack module may not be loaded
+	_ = procnet.ReadFile(s.pid, func(r io.Reader) error {
+		ct, err := procnet.ParseConntrackStat(r)
+		if err != nil {
+			return err
+		}
+		s.conntrackEntries = ct.Entries
+		return nil
+	}, "net", "stat", "nf_conntrack")
+
+	if comm, err := os.ReadFile(procnet.Path(s.pid, "comm")); err == nil {
+		s.processName = strings.TrimSpace(string(comm))
+	}
+
+	_ = procnet.ReadFile(s.pid, func(r io.Reader) error {
+		s.containerID, s.podUID = parseCgroup(r)
+		return nil
+	}, "cgroup")
+
+	return nil
+}
@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netnsstat
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tcpStates maps the hex state in /proc/net/tcp to field name.
+var tcpStates = map[string]string{
+	"01": "tcp_established",
+	"02": "tcp_syn_sent",
+	"03": "tcp_syn_recv",
+	"04": "tcp_fin_wait1",
+	"05": "tcp_fin_wait2",
+	"06": "tcp_time_wait",
+	"07": "tcp_close",
+	"08": "tcp_close_wait",
+	"09": "tcp_last_ack",
+	"0A": "tcp_listen",
+	"0B": "tcp_closing",
+	"0C": "tcp_new_syn_recv",
+}
+
+var (
+	containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+	podUIDRegexp      = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+)
+
+type talker struct {
+	ip    string
+	count int
+}
+
+type netnsSummary struct {
+	inode       uint64
+	pid         int
+	hostNetns   bool
+	processName string
+	containerID string
+	podUID      string
+
+	tcp              map[string]int
+	udpSockets       int
+	conntrackEntries int64
+
+	// connection count by remote IP
+	remotes map[string]int
+}
+
+func newNetnsSummary(inode uint64, pid int) *netnsSummary {
+	s := &netnsSummary{
+		inode:            inode,
+		pid:              pid,
+		tcp:              map[string]int{},
+		remotes:          map[string]int{},
+		conntrackEntries: -1,
+	}
+	for _, v := range tcpStates {
+		s.tcp[v] = 0
+	}
+	return s
+}
+
+func (s *netnsSummary) tags() map[string]string {
+	tags := map[string]string{
+		"netns":        strconv.FormatUint(s.inode, 10),
+		"host_network": strconv.FormatBool(s.hostNetns),
+	}
+
+	if s.processName != "" {
+		tags["process_name"] = s.processName
+	}
+	if s.containerID != "" {
+		tags["container_id"] = s.containerID
+	}
+	if s.podUID != "" {
+		tags["pod_uid"] = s.podUID
+	}
+
+	return tags
+}
+
+func (s *netnsSummary) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"udp_socket": s.udpSockets,
+		"pid":        s.pid,
+	}
+
+	total := 0
+	for k, v := range s.tcp {
+		fields[k] = v
+		total += v
+	}
+	fields["tcp_total"] = total
+
+	if s.conntrackEntries >= 0 {
+		fields["conntrack_entries"] = s.conntrackEntries
+	}
+
+	return fields
+}
+
+// topTalkers returns the n remote peers with the most connections.
+func (s *netnsSummary) topTalkers(n int) []talker {
+	if n <= 0 || len(s.remotes) == 0 {
+		return nil
+	}
+
+	res := make([]talker, 0, len(s.remotes))
+	for ip, cnt := range s.remotes {
+		res = append(res, talker{ip: ip, count: cnt})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].count == res[j].count {
+			return res[i].ip < res[j].ip
+		}
+		return res[i].count > res[j].count
+	})
+
+	if len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+// parseSockTable parses /proc/<pid>/net/{tcp,tcp6,udp,udp6}.
+func (s *netnsSummary) parseSockTable(r io.Reader, isTCP bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // skip header
+
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		remote, err := parseHexAddr(fields[2])
+		if err != nil {
+			return err
+		}
+
+		if isTCP {
+			name, ok := tcpStates[fields[3]]
+			if !ok {
+				continue
+			}
+			s.tcp[name]++
+
+			if name == "tcp_listen" {
+				continue
+			}
+		} else {
+			s.udpSockets++
+		}
+
+		if remote != nil && !remote.IsUnspecified() {
+			s.remotes[remote.String()]++
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseHexAddr parses address like `0100007F:0CEA`, the IP is stored as
+// 32 bit words in host(little endian) byte order.
+func parseHexAddr(s string) (net.IP, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+
+	b, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+
+	if len(b) != net.IPv4len && len(b) != net.IPv6len {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+
+	ip := net.IP(b)
+	if v4 := ip.To4(); v4 != nil {
+		return v4, nil
+	}
+	return ip, nil
+}
+
+// parseCgroup extracts container ID and pod UID from /proc/<pid>/cgroup.
+func parseCgroup(r io.Reader) (containerID, podUID string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if containerID == "" {
+			containerID = containerIDRegexp.FindString(line)
+		}
+		if podUID == "" {
+			if m := podUIDRegexp.FindStringSubmatch(line); len(m) == 2 {
+				podUID = strings.ReplaceAll(m[1], "_", "-")
+			}
+		}
+	}
+	return
+}
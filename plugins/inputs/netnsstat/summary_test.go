@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netnsstat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSockTable(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 1101F40A:C350 0A01F40A:0CEA 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 1101F40A:C351 0A01F40A:0CEA 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 1101F40A:C352 0B01F40A:01BB 06 00000000:00000000 00:00000000 00000000     0        0 0 3 0000000000000000
+`
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00001101F40A:1F90 0000000000000000FFFF00000C01F40A:D431 01 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000 20 4 30 10 -1
+`
+	udp := `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 3001 2 0000000000000000 0
+  101: 1101F40A:E1F1 0A01F40A:0035 01 00000000:00000000 00:00000000 00000000     0        0 3002 2 0000000000000000 0
+`
+
+	s := newNetnsSummary(4026532000, 100)
+	require.NoError(t, s.parseSockTable(strings.NewReader(tcp), true))
+	require.NoError(t, s.parseSockTable(strings.NewReader(tcp6), true))
+	require.NoError(t, s.parseSockTable(strings.NewReader(udp), false))
+
+	fields := s.fields()
+	assert.Equal(t, 3, fields["tcp_established"])
+	assert.Equal(t, 2, fields["tcp_listen"])
+	assert.Equal(t, 1, fields["tcp_time_wait"])
+	assert.Equal(t, 6, fields["tcp_total"])
+	assert.Equal(t, 2, fields["udp_socket"])
+	assert.NotContains(t, fields, "conntrack_entries")
+
+	top := s.topTalkers(2)
+	require.Len(t, top, 2)
+	assert.Equal(t, talker{ip: "10.244.1.10", count: 3}, top[0])
+	assert.Equal(t, talker{ip: "10.244.1.11", count: 1}, top[1])
+
+	assert.Nil(t, s.topTalkers(0))
+}
+
+func TestParseHexAddr(t *testing.T) {
+	ip, err := parseHexAddr("0100007F:0CEA")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip.String())
+
+	ip, err = parseHexAddr("B80D0120000000000000000001000000:0050")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", ip.String())
+
+	_, err = parseHexAddr("0100007F")
+	assert.Error(t, err)
+}
+
+func TestParseCgroup(t *testing.T) {
+	cid, uid := parseCgroup(strings.NewReader(
+		"0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod8f7a6c2e_1b3d_4e5f_9a8b_7c6d5e4f3a2b.slice/" +
+			"cri-containerd-3f4c5e1d2b7a8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d.scope\n"))
+	assert.Equal(t, "3f4c5e1d2b7a8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d", cid)
+	assert.Equal(t, "8f7a6c2e-1b3d-4e5f-9a8b-7c6d5e4f3a2b", uid)
+
+	cid, uid = parseCgroup(strings.NewReader("0::/init.scope\n"))
+	assert.Empty(t, cid)
+	assert.Empty(t, uid)
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netscan
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type arpEntry struct {
+	ip     string
+	mac    string
+	device string
+}
+
+// atfCom is ATF_COM in linux/if_arp.h, set when the entry is completed.
+const atfCom = 0x02
+
+// parseARPTable parses /proc/net/arp, incomplete entries are skipped:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+func parseARPTable(r io.Reader) ([]*arpEntry, error) {
+	var res []*arpEntry
+
+	scanner := bufio.NewScanner(r)
+	for i := 0; scanner.Scan(); i++ {
+		if i == 0 {
+			continue // header
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if err != nil || flags&atfCom == 0 || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+
+		res = append(res, &arpEntry{ip: fields[0], mac: fields[3], device: fields[5]})
+	}
+
+	return res, scanner.Err()
+}
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netscan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	changeHostUp       = "host_up"
+	changeHostDown     = "host_down"
+	changePortsChanged = "ports_changed"
+)
+
+type hostChange struct {
+	kind string
+	host *hostInfo
+
+	openedPorts []int
+	closedPorts []int
+}
+
+// diffHosts compares two scan rounds, changes are sorted by IP.
+func diffHosts(prev, cur map[string]*hostInfo) []*hostChange {
+	var res []*hostChange
+
+	for ip, h := range cur {
+		old, ok := prev[ip]
+		if !ok {
+			res = append(res, &hostChange{kind: changeHostUp, host: h, openedPorts: h.openPorts})
+			continue
+		}
+
+		opened, closed := diffPorts(old.openPorts, h.openPorts)
+		if len(opened) > 0 || len(closed) > 0 {
+			res = append(res, &hostChange{kind: changePortsChanged, host: h, openedPorts: opened, closedPorts: closed})
+		}
+	}
+
+	for ip, h := range prev {
+		if _, ok := cur[ip]; !ok {
+			res = append(res, &hostChange{kind: changeHostDown, host: h, closedPorts: h.openPorts})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].host.ip < res[j].host.ip })
+	return res
+}
+
+// diffPorts returns the ports only in cur and only in prev, both inputs are sorted.
+func diffPorts(prev, cur []int) (opened, closed []int) {
+	i, j := 0, 0
+	for i < len(prev) && j < len(cur) {
+		switch {
+		case prev[i] == cur[j]:
+			i++
+			j++
+		case prev[i] < cur[j]:
+			closed = append(closed, prev[i])
+			i++
+		default:
+			opened = append(opened, cur[j])
+			j++
+		}
+	}
+	closed = append(closed, prev[i:]...)
+	opened = append(opened, cur[j:]...)
+	return
+}
+
+func (c *hostChange) tags() map[string]string {
+	return map[string]string{
+		"ip":          c.host.ip,
+		"cidr":        c.host.cidr,
+		"change_type": c.kind,
+	}
+}
+
+func (c *hostChange) title() string {
+	switch c.kind {
+	case changeHostUp:
+		return fmt.Sprintf("New host %s discovered in %s", c.host.ip, c.host.cidr)
+	case changeHostDown:
+		return fmt.Sprintf("Host %s in %s is no longer reachable", c.host.ip, c.host.cidr)
+	default:
+		return fmt.Sprintf("Open ports of host %s changed", c.host.ip)
+	}
+}
+
+func (c *hostChange) message() string {
+	var sb strings.Builder
+	sb.WriteString(c.title())
+	if c.host.hostname != "" {
+		fmt.Fprintf(&sb, "\nhostname: %s", c.host.hostname)
+	}
+	if c.host.mac != "" {
+		fmt.Fprintf(&sb, "\nmac: %s", c.host.mac)
+	}
+	if len(c.openedPorts) > 0 {
+		fmt.Fprintf(&sb, "\nopened ports: %s", joinPorts(c.openedPorts))
+	}
+	if len(c.closedPorts) > 0 {
+		fmt.Fprintf(&sb, "\nclosed ports: %s", joinPorts(c.closedPorts))
+	}
+	return sb.String()
+}
+
+func (c *hostChange) status() string {
+	if c.kind == changeHostUp {
+		return "info"
+	}
+	return "warning"
+}
+
+func (c *hostChange) fields(interval time.Duration) map[string]interface{} {
+	return map[string]interface{}{
+		"df_source":     "system",
+		"df_status":     c.status(),
+		"df_event_id":   fmt.Sprintf("%s-%s-%s", inputName, c.kind, c.host.ip),
+		"df_title":      c.title(),
+		"df_message":    c.message(),
+		"df_date_range": int64(interval / time.Second),
+		"opened_ports":  joinPorts(c.openedPorts),
+		"closed_ports":  joinPorts(c.closedPorts),
+	}
+}
+
+func joinPorts(ports []int) string {
+	return (&hostInfo{openPorts: ports}).portsString()
+}
@@ -0,0 +1,369 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package netscan scans configured subnets for live hosts and open ports.
+package netscan
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var (
+	_ inputs.ReadEnv   = (*Input)(nil)
+	_ inputs.Singleton = (*Input)(nil)
+)
+
+const (
+	minInterval = time.Minute
+	maxInterval = time.Hour * 24
+
+	defaultTimeout     = time.Second
+	defaultConcurrency = 64
+	maxConcurrency     = 1024
+
+	// maxHostsPerCIDR limits the size of a single subnet to /20 for IPv4.
+	maxHostsPerCIDR = 4096
+)
+
+var defaultPorts = []int{21, 22, 23, 25, 53, 80, 110, 135, 139, 143, 443, 445, 993, 995, 1433, 1521, 3306, 3389, 5432, 5900, 6379, 8080, 8443, 9200, 27017}
+
+const (
+	inputName  = "netscan"
+	objectName = "network_host"
+	eventName  = inputName
+	sampleCfg  = `
+[[inputs.netscan]]
+  ## Subnets to scan, in CIDR notation. A single IP is also accepted.
+  ## Subnets larger than /20 are refused.
+  cidrs = ["192.168.1.0/24"]
+
+  ## TCP ports to probe with connect scan on each address.
+  ## Default is a list of well-known service ports.
+  # ports = [22, 80, 443, 3306, 3389, 6379, 8080]
+
+  ## Interval between two scan rounds, default is 1 hour.
+  interval = "1h"
+
+  ## Timeout of each TCP connect probe.
+  timeout = "1s"
+
+  ## Number of concurrent probes.
+  concurrency = 64
+
+  ## Also report hosts found in the kernel ARP table of the scanned subnets (Linux only),
+  ## which answers hosts without any open port.
+  arp = true
+
+  ## Send keyevents when hosts appear or disappear, or their open ports change.
+  change_events = true
+
+[inputs.netscan.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	CIDRs        []string          `toml:"cidrs"`
+	Ports        []int             `toml:"ports"`
+	Interval     datakit.Duration  `toml:"interval"`
+	Timeout      datakit.Duration  `toml:"timeout"`
+	Concurrency  int               `toml:"concurrency"`
+	ARP          bool              `toml:"arp"`
+	ChangeEvents bool              `toml:"change_events"`
+	Tags         map[string]string `toml:"tags"`
+
+	// last scan result, keyed by IP
+	lastHosts map[string]*hostInfo
+	scanned   bool
+
+	semStop *cliutils.Sem
+}
+
+func (ipt *Input) Singleton() {}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	l.Info("netscan input started")
+
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+	if ipt.Timeout.Duration <= 0 {
+		ipt.Timeout.Duration = defaultTimeout
+	}
+	if ipt.Concurrency <= 0 || ipt.Concurrency > maxConcurrency {
+		ipt.Concurrency = defaultConcurrency
+	}
+	if len(ipt.Ports) == 0 {
+		ipt.Ports = defaultPorts
+	}
+
+	targets, err := expandCIDRs(ipt.CIDRs, maxHostsPerCIDR)
+	if err != nil {
+		l.Errorf("invalid cidrs: %s", err)
+		io.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-datakit.Exit.Wait():
+		case <-ipt.semStop.Wait():
+		}
+		cancel()
+	}()
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		start := time.Now()
+
+		hosts, complete := ipt.scan(ctx, targets)
+		if ctx.Err() == nil {
+			ipt.feed(hosts, complete, start)
+		}
+
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			l.Info("netscan input exit")
+			return
+		}
+	}
+}
+
+// scan returns hosts found, complete is false if any probe skipped by the
+// outbound probe budget.
+func (ipt *Input) scan(ctx context.Context, targets []*target) (hosts map[string]*hostInfo, complete bool) {
+	s := &scanner{
+		ports:       ipt.Ports,
+		timeout:     ipt.Timeout.Duration,
+		concurrency: ipt.Concurrency,
+	}
+
+	hosts = s.scan(ctx, targets)
+	if s.skipped > 0 {
+		l.Warnf("%d probes skipped by outbound probe budget", s.skipped)
+	}
+
+	if ipt.ARP {
+		entries, err := readARPTable()
+		if err != nil {
+			l.Warnf("read arp table: %s", err)
+		}
+		mergeARP(hosts, entries, targets)
+	}
+
+	return hosts, s.skipped == 0
+}
+
+func (ipt *Input) feed(hosts map[string]*hostInfo, complete bool, start time.Time) {
+	var objects []inputs.Measurement
+	for _, h := range hosts {
+		objects = append(objects, &hostObject{
+			name:   objectName,
+			tags:   ipt.mergeTags(h.tags()),
+			fields: h.fields(),
+		})
+	}
+
+	if len(objects) > 0 {
+		if err := inputs.FeedMeasurement(objectName, datakit.CustomObject, objects,
+			&io.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("FeedMeasurement: %s", err)
+		}
+	}
+
+	// hosts of skipped probes are unknown, not gone, so incomplete rounds
+	// are not compared
+	if !complete {
+		return
+	}
+
+	// no events on the first round, every host would be new
+	if ipt.ChangeEvents && ipt.scanned {
+		var events []inputs.Measurement
+		for _, c := range diffHosts(ipt.lastHosts, hosts) {
+			events = append(events, &changeEvent{
+				name:   eventName,
+				tags:   ipt.mergeTags(c.tags()),
+				fields: c.fields(ipt.Interval.Duration),
+				ts:     start,
+			})
+		}
+
+		if len(events) > 0 {
+			if err := inputs.FeedMeasurement(eventName, datakit.KeyEvent, events, nil); err != nil {
+				l.Errorf("FeedMeasurement: %s", err)
+			}
+		}
+	}
+
+	ipt.lastHosts = hosts
+	ipt.scanned = true
+}
+
+func (ipt *Input) mergeTags(tags map[string]string) map[string]string {
+	for k, v := range ipt.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (*Input) Catalog() string { return "network" }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&hostObject{},
+		&changeEvent{},
+	}
+}
+
+// ReadEnv support envs：only for K8S.
+func (ipt *Input) ReadEnv(envs map[string]string) {
+	// ENV_INPUT_NETSCAN_CIDRS : "192.168.1.0/24,10.0.0.0/24"
+	if str, ok := envs["ENV_INPUT_NETSCAN_CIDRS"]; ok {
+		ipt.CIDRs = splitList(str)
+	}
+
+	// ENV_INPUT_NETSCAN_PORTS : "22,80,443"
+	if str, ok := envs["ENV_INPUT_NETSCAN_PORTS"]; ok {
+		ports, err := parsePorts(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_PORTS: %s, ignore", err)
+		} else {
+			ipt.Ports = ports
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_INTERVAL : datakit.Duration
+	if str, ok := envs["ENV_INPUT_NETSCAN_INTERVAL"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_INTERVAL to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, da)
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_TIMEOUT : datakit.Duration
+	if str, ok := envs["ENV_INPUT_NETSCAN_TIMEOUT"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_TIMEOUT to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Timeout.Duration = da
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_CONCURRENCY : int
+	if str, ok := envs["ENV_INPUT_NETSCAN_CONCURRENCY"]; ok {
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_CONCURRENCY: %s, ignore", err)
+		} else {
+			ipt.Concurrency = n
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_ARP : bool
+	if str, ok := envs["ENV_INPUT_NETSCAN_ARP"]; ok {
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_ARP: %s, ignore", err)
+		} else {
+			ipt.ARP = b
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_CHANGE_EVENTS : bool
+	if str, ok := envs["ENV_INPUT_NETSCAN_CHANGE_EVENTS"]; ok {
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_NETSCAN_CHANGE_EVENTS: %s, ignore", err)
+		} else {
+			ipt.ChangeEvents = b
+		}
+	}
+
+	// ENV_INPUT_NETSCAN_TAGS : "a=b,c=d"
+	if tagsStr, ok := envs["ENV_INPUT_NETSCAN_TAGS"]; ok {
+		tags := config.ParseGlobalTags(tagsStr)
+		for k, v := range tags {
+			ipt.Tags[k] = v
+		}
+	}
+}
+
+func splitList(str string) []string {
+	var res []string
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func parsePorts(str string) ([]int, error) {
+	var ports []int
+	for _, s := range splitList(str) {
+		p, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %d", p)
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Interval:     datakit.Duration{Duration: time.Hour},
+		Timeout:      datakit.Duration{Duration: defaultTimeout},
+		Concurrency:  defaultConcurrency,
+		ARP:          true,
+		ChangeEvents: true,
+		Tags:         map[string]string{},
+		semStop:      cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netscan
+
+import (
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+func (h *hostInfo) tags() map[string]string {
+	tags := map[string]string{
+		"name": h.ip,
+		"ip":   h.ip,
+		"cidr": h.cidr,
+	}
+	if h.mac != "" {
+		tags["mac"] = h.mac
+	}
+	if h.hostname != "" {
+		tags["hostname"] = h.hostname
+	}
+	return tags
+}
+
+func (h *hostInfo) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"open_ports":      h.portsString(),
+		"open_port_count": len(h.openPorts),
+		"in_arp_table":    h.arp,
+		"rtt":             h.rtt.Microseconds(),
+	}
+}
+
+type hostObject struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+func (m *hostObject) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.CustomObject})
+}
+
+//nolint:lll
+func (m *hostObject) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: objectName,
+		Type: "custom_object",
+		Desc: "Hosts discovered by the scan, one object per IP.",
+		Tags: map[string]interface{}{
+			"name":     &inputs.TagInfo{Desc: "IP address of the host"},
+			"ip":       &inputs.TagInfo{Desc: "IP address of the host"},
+			"cidr":     &inputs.TagInfo{Desc: "Configured subnet the host belongs to"},
+			"mac":      &inputs.TagInfo{Desc: "MAC address from ARP table, only for hosts in directly attached subnets"},
+			"hostname": &inputs.TagInfo{Desc: "Host name resolved by reverse DNS lookup"},
+		},
+		Fields: map[string]interface{}{
+			"open_ports":      &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Comma separated open TCP ports."},
+			"open_port_count": &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Number of open TCP ports."},
+			"in_arp_table":    &inputs.FieldInfo{DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "Whether the host is found in ARP table."},
+			"rtt":             &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.DurationUS, Desc: "Minimal TCP connect round trip of the probes, 0 if the host is only found in ARP table."},
+		},
+	}
+}
+
+type changeEvent struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *changeEvent) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.KeyEvent, Time: m.ts})
+}
+
+//nolint:lll
+func (m *changeEvent) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: eventName,
+		Type: "keyevent",
+		Desc: "Sent when a host appears or disappears, or its open ports change between two scan rounds.",
+		Tags: map[string]interface{}{
+			"ip":          &inputs.TagInfo{Desc: "IP address of the host"},
+			"cidr":        &inputs.TagInfo{Desc: "Configured subnet the host belongs to"},
+			"change_type": &inputs.TagInfo{Desc: "Type of the change: `host_up`/`host_down`/`ports_changed`"},
+		},
+		Fields: map[string]interface{}{
+			"df_source":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Fixed to `system`."},
+			"df_status":     &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "`info` for new hosts, `warning` for others."},
+			"df_event_id":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event ID."},
+			"df_title":      &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event title."},
+			"df_message":    &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Event detail."},
+			"df_date_range": &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.DurationSecond, Desc: "Scan interval."},
+			"opened_ports":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Comma separated ports newly opened."},
+			"closed_ports":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Comma separated ports closed or no longer reachable."},
+		},
+	}
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandCIDRs(t *testing.T) {
+	targets, err := expandCIDRs([]string{"192.168.1.0/30", "10.0.0.5", "192.168.1.1"}, 16)
+	require.NoError(t, err)
+
+	var ips []string
+	for _, x := range targets {
+		ips = append(ips, x.ip.String())
+	}
+	assert.Equal(t, []string{"192.168.1.1", "192.168.1.2", "10.0.0.5"}, ips)
+	assert.Equal(t, "192.168.1.0/30", targets[0].cidr)
+	assert.Equal(t, "10.0.0.5/32", targets[2].cidr)
+
+	targets, err = expandCIDRs([]string{"10.0.0.0/31"}, 16)
+	require.NoError(t, err)
+	assert.Len(t, targets, 2)
+
+	_, err = expandCIDRs([]string{"10.0.0.0/24"}, 16)
+	assert.Error(t, err)
+
+	_, err = expandCIDRs([]string{"not-an-ip"}, 16)
+	assert.Error(t, err)
+}
+
+func TestScan(t *testing.T) {
+	targets, err := expandCIDRs([]string{"10.1.1.0/30"}, 16)
+	require.NoError(t, err)
+
+	s := &scanner{
+		ports:       []int{22, 80, 443},
+		timeout:     time.Second,
+		concurrency: 4,
+		dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			switch address {
+			case "10.1.1.1:22", "10.1.1.1:443":
+				c, _ := net.Pipe()
+				return c, nil
+			case "10.1.1.1:80":
+				return nil, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+			default:
+				return nil, fmt.Errorf("i/o timeout")
+			}
+		},
+	}
+
+	hosts := s.scan(context.Background(), targets)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, []int{22, 443}, hosts["10.1.1.1"].openPorts)
+	assert.Equal(t, "10.1.1.0/30", hosts["10.1.1.1"].cidr)
+}
+
+func TestParseARPTable(t *testing.T) {
+	data := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.9      0x1         0x6         66:77:88:99:aa:bb     *        eth0
+`
+	entries, err := parseARPTable(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, &arpEntry{ip: "192.168.1.1", mac: "00:11:22:33:44:55", device: "eth0"}, entries[0])
+
+	targets, err := expandCIDRs([]string{"192.168.1.0/29"}, 16)
+	require.NoError(t, err)
+
+	hosts := map[string]*hostInfo{"192.168.1.1": {ip: "192.168.1.1", openPorts: []int{80}}}
+	mergeARP(hosts, entries, targets)
+	// 192.168.1.9 is out of the subnet
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "00:11:22:33:44:55", hosts["192.168.1.1"].mac)
+	assert.True(t, hosts["192.168.1.1"].arp)
+}
+
+func TestDiffHosts(t *testing.T) {
+	prev := map[string]*hostInfo{
+		"10.0.0.1": {ip: "10.0.0.1", openPorts: []int{22, 80}},
+		"10.0.0.2": {ip: "10.0.0.2", openPorts: []int{3306}},
+		"10.0.0.3": {ip: "10.0.0.3"},
+	}
+	cur := map[string]*hostInfo{
+		"10.0.0.1": {ip: "10.0.0.1", openPorts: []int{22, 443}},
+		"10.0.0.3": {ip: "10.0.0.3"},
+		"10.0.0.4": {ip: "10.0.0.4", openPorts: []int{8080}},
+	}
+
+	changes := diffHosts(prev, cur)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, changePortsChanged, changes[0].kind)
+	assert.Equal(t, []int{443}, changes[0].openedPorts)
+	assert.Equal(t, []int{80}, changes[0].closedPorts)
+
+	assert.Equal(t, changeHostDown, changes[1].kind)
+	assert.Equal(t, "10.0.0.2", changes[1].host.ip)
+
+	assert.Equal(t, changeHostUp, changes[2].kind)
+	assert.Equal(t, "8080", changes[2].fields(time.Hour)["opened_ports"])
+	assert.Equal(t, int64(3600), changes[2].fields(time.Hour)["df_date_range"])
+}
+
+func TestParsePorts(t *testing.T) {
+	ports, err := parsePorts("22, 80,443")
+	require.NoError(t, err)
+	assert.Equal(t, []int{22, 80, 443}, ports)
+
+	_, err = parsePorts("22,70000")
+	assert.Error(t, err)
+}
@@ -0,0 +1,266 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package netscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
+)
+
+type target struct {
+	ip   net.IP
+	cidr string
+}
+
+// expandCIDRs returns all usable addresses of the subnets, network and broadcast
+// addresses of IPv4 subnets larger than /31 are skipped.
+func expandCIDRs(cidrs []string, limit int) ([]*target, error) {
+	var (
+		res  []*target
+		seen = map[string]bool{}
+	)
+
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", c)
+			}
+			if ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+
+		ip, ipnet, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+
+		ones, bits := ipnet.Mask.Size()
+		if bits-ones > 30 || 1<<(bits-ones) > limit {
+			return nil, fmt.Errorf("subnet %s too large, at most %d addresses allowed", c, limit)
+		}
+
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		first := ip.Mask(ipnet.Mask)
+		skipEdge := bits == 32 && bits-ones > 1
+
+		for cur := dupIP(first); ipnet.Contains(cur); incIP(cur) {
+			if skipEdge && (cur.Equal(first) || isBroadcast(cur, ipnet)) {
+				continue
+			}
+
+			if s := cur.String(); !seen[s] {
+				seen[s] = true
+				res = append(res, &target{ip: dupIP(cur), cidr: ipnet.String()})
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func dupIP(ip net.IP) net.IP {
+	dup := make(net.IP, len(ip))
+	copy(dup, ip)
+	return dup
+}
+
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+func isBroadcast(ip net.IP, ipnet *net.IPNet) bool {
+	for i := range ip {
+		if ip[i]|ipnet.Mask[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+type hostInfo struct {
+	ip        string
+	cidr      string
+	mac       string
+	hostname  string
+	openPorts []int
+	// whether the host is known by the ARP table
+	arp bool
+	// round trip of the first successful or refused probe
+	rtt time.Duration
+}
+
+func (h *hostInfo) portsString() string {
+	arr := make([]string, 0, len(h.openPorts))
+	for _, p := range h.openPorts {
+		arr = append(arr, strconv.Itoa(p))
+	}
+	return strings.Join(arr, ",")
+}
+
+type scanner struct {
+	ports       []int
+	timeout     time.Duration
+	concurrency int
+
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// probes skipped by the outbound probe budget
+	skipped int64
+}
+
+type probeResult struct {
+	t     *target
+	port  int
+	open  bool
+	alive bool // the host answered, either accepted or refused the connection
+	rtt   time.Duration
+}
+
+// scan does a TCP connect scan on all targets, a host is considered up if any
+// of the ports accepts or actively refuses the connection.
+func (s *scanner) scan(ctx context.Context, targets []*target) map[string]*hostInfo {
+	dial := s.dial
+	if dial == nil {
+		d := &net.Dialer{Timeout: s.timeout}
+		dial = d.DialContext
+	}
+
+	jobs := make(chan *probeResult)
+	results := make(chan *probeResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				s.probe(ctx, dial, job)
+				results <- job
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, t := range targets {
+			for _, p := range s.ports {
+				select {
+				case jobs <- &probeResult{t: t, port: p}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	hosts := map[string]*hostInfo{}
+	for r := range results {
+		if !r.alive {
+			continue
+		}
+
+		ip := r.t.ip.String()
+		h, ok := hosts[ip]
+		if !ok {
+			h = &hostInfo{ip: ip, cidr: r.t.cidr, rtt: r.rtt}
+			hosts[ip] = h
+		}
+		if r.open {
+			h.openPorts = append(h.openPorts, r.port)
+		}
+		if r.rtt < h.rtt {
+			h.rtt = r.rtt
+		}
+	}
+
+	for _, h := range hosts {
+		sort.Ints(h.openPorts)
+		if names, err := net.LookupAddr(h.ip); err == nil && len(names) > 0 {
+			h.hostname = strings.TrimSuffix(names[0], ".")
+		}
+	}
+
+	return hosts
+}
+
+func (s *scanner) probe(ctx context.Context, dial func(context.Context, string, string) (net.Conn, error), r *probeResult) {
+	addr := net.JoinHostPort(r.t.ip.String(), strconv.Itoa(r.port))
+	if err := probelimit.Wait(ctx, addr); err != nil {
+		atomic.AddInt64(&s.skipped, 1)
+		return
+	}
+
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", addr)
+	r.rtt = time.Since(start)
+
+	if err == nil {
+		_ = conn.Close()
+		r.open, r.alive = true, true
+		return
+	}
+
+	// RST from the peer means the host is up with the port closed
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		r.alive = true
+	}
+}
+
+// mergeARP adds hosts of the scanned subnets found in ARP table, and fills MAC
+// address of the scanned hosts.
+func mergeARP(hosts map[string]*hostInfo, entries []*arpEntry, targets []*target) {
+	if len(entries) == 0 {
+		return
+	}
+
+	cidrs := make(map[string]string, len(targets))
+	for _, t := range targets {
+		cidrs[t.ip.String()] = t.cidr
+	}
+
+	for _, e := range entries {
+		cidr, ok := cidrs[e.ip]
+		if !ok {
+			continue
+		}
+
+		h, ok := hosts[e.ip]
+		if !ok {
+			h = &hostInfo{ip: e.ip, cidr: cidr}
+			hosts[e.ip] = h
+		}
+		h.mac = e.mac
+		h.arp = true
+	}
+}
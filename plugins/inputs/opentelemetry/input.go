@@ -17,9 +17,11 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
 	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/ingestauth"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
 	itrace "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/workerpool"
@@ -68,6 +70,42 @@ const (
   # [inputs.opentelemetry.sampler]
     # sampling_rate = 1.0
 
+  ## Tail sampling buffers spans of a trace for decision_wait, then keeps the whole trace
+  ## if it contains error spans, lasts longer than latency_threshold or matches any policy,
+  ## the other traces are kept by sampling_rate. [inputs.opentelemetry.sampler] and error penetration
+  ## are ignored once tail sampling is enabled.
+  # [inputs.opentelemetry.tail_sampling]
+    # decision_wait = "10s"
+    # max_traces = 50000
+    # keep_error = true
+    # latency_threshold = "2s"
+    # sampling_rate = 0.1
+    # [[inputs.opentelemetry.tail_sampling.policies]]
+      # name = "checkout"
+      # service = "checkout"
+      # resource = "^POST /pay"
+      # [inputs.opentelemetry.tail_sampling.policies.tags]
+        # "http.status_code" = "429"
+
+  ## Span metrics generates RED(requests, errors and duration) metrics per service and resource
+  ## from all spans received, before filters and sampling applied, fed as metric "tracing_metrics".
+  ## extra_tags are span tags used as extra dimensions.
+  # [inputs.opentelemetry.span_metrics]
+    # interval = "60s"
+    # extra_tags = ["http_method"]
+
+  ## Metric config converts sums and histograms from different SDKs into the same shape,
+  ## so that they can be aggregated correctly.
+  ## temporality converts sums and histograms to "cumulative" or "delta", kept as is if empty.
+  ## The first point of a series is dropped on converting cumulative to delta.
+  ## expire_after drops states of series not updated for the duration.
+  ## histogram_buckets re-maps buckets of explicit bucket histograms into these upper bounds,
+  ## and buckets are sent as field "<metric>_bucket" with tag "le".
+  # [inputs.opentelemetry.metric]
+    # temporality = "cumulative"
+    # expire_after = "15m"
+    # histogram_buckets = [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0]
+
   # [inputs.opentelemetry.tags]
     # key1 = "value1"
     # key2 = "value2"
@@ -106,6 +144,14 @@ const (
    metric_enable = true
    addr = "127.0.0.1:4317"
 
+  ## Request-level authentication on both HTTP and GRPC, requests without a valid API key are rejected
+  ## with HTTP 401 or GRPC code Unauthenticated. The key is carried by HTTP header or GRPC metadata
+  ## "Authorization: Bearer <key>" or "X-API-Key", or URL query api_key of HTTP.
+  ## pull_keys also accepts API keys configured in center.
+  # [inputs.opentelemetry.auth]
+    # keys = ["<api-key>"]
+    # pull_keys = false
+
   ## If 'expectedHeaders' is well configed, then the obligation of sending certain wanted HTTP headers is on the client side,
   ## otherwise HTTP status code 400(bad request) will be provoked.
   ## Note: expectedHeaders will be effected on both trace and metrics if setted up.
@@ -119,6 +165,7 @@ const (
 var (
 	log              = logger.DefaultSLogger(inputName)
 	statusOK         = 200
+	afterGather      *itrace.AfterGather
 	afterGatherRun   itrace.AfterGatherHandler
 	ignoreKeyRegExps []*regexp.Regexp
 	getAttribute     getAttributeFunc
@@ -127,6 +174,7 @@ var (
 	wkpool           *workerpool.WorkerPool
 	localCache       *storage.Storage
 	otelSvr          *grpc.Server
+	metricConv       *metricConverter
 )
 
 type httpConfig struct {
@@ -150,7 +198,11 @@ type Input struct {
 	CloseResource       map[string][]string          `toml:"close_resource"`
 	OmitErrStatus       []string                     `toml:"omit_err_status"`
 	Sampler             *itrace.Sampler              `toml:"sampler"`
+	TailSampling        *itrace.TailSampling         `toml:"tail_sampling"`
+	SpanMetrics         *itrace.SpanMetrics          `toml:"span_metrics"`
+	MetricConfig        *metricConfig                `toml:"metric"`
 	Tags                map[string]string            `toml:"tags"`
+	Auth                *ingestauth.Auth             `toml:"auth"`
 	WPConfig            *workerpool.WorkerPoolConfig `toml:"threads"`
 	LocalCacheConfig    *storage.StorageConfig       `toml:"storage"`
 }
@@ -162,7 +214,7 @@ func (*Input) AvailableArchs() []string { return datakit.AllOS }
 func (*Input) SampleConfig() string { return sampleConfig }
 
 func (*Input) SampleMeasurement() []inputs.Measurement {
-	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}}
+	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}, &itrace.SpanMetricsMeasurement{}}
 }
 
 func (ipt *Input) RegHTTPHandler() {
@@ -219,12 +271,19 @@ func (ipt *Input) RegHTTPHandler() {
 		}
 	}
 
-	var afterGather *itrace.AfterGather
+	opts := []itrace.Option{itrace.WithLogger(log)}
 	if localCache != nil && localCache.Enabled() {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log), itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
-	} else {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log))
+		opts = append(opts, itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
+	}
+	// pull per-service sampling rates configured in center
+	opts = append(opts, itrace.WithSamplingPull(config.Cfg.Dataway))
+	if ipt.TailSampling != nil {
+		opts = append(opts, itrace.WithTailSampling(ipt.TailSampling))
+	}
+	if ipt.SpanMetrics != nil {
+		opts = append(opts, itrace.WithSpanMetrics(ipt.SpanMetrics))
 	}
+	afterGather = itrace.NewAfterGather(opts...)
 	afterGatherRun = afterGather
 
 	// add filters: the order of appending filters into AfterGather is important!!!
@@ -235,8 +294,10 @@ func (ipt *Input) RegHTTPHandler() {
 		closeResource.UpdateIgnResList(ipt.CloseResource)
 		afterGather.AppendFilter(closeResource.Close)
 	}
-	// add error status penetration
-	afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	// add error status penetration, tail sampling keeps error traces by itself
+	if ipt.TailSampling == nil {
+		afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	}
 	// add rare resource keeper
 	if ipt.KeepRareResource {
 		keepRareResource := &itrace.KeepRareResource{}
@@ -244,13 +305,20 @@ func (ipt *Input) RegHTTPHandler() {
 		afterGather.AppendFilter(keepRareResource.Keep)
 	}
 	// add sampler
-	var sampler *itrace.Sampler
-	if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
-		sampler = ipt.Sampler
-	} else {
-		sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+	if ipt.TailSampling == nil {
+		var sampler *itrace.Sampler
+		if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
+			sampler = ipt.Sampler
+		} else {
+			sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+		}
+		afterGather.AppendFilter(sampler.Sample)
+	}
+
+	// pull API keys configured in center
+	if config.Cfg.Dataway != nil {
+		ipt.Auth.Setup(config.Cfg.Dataway)
 	}
-	afterGather.AppendFilter(sampler.Sample)
 
 	expectedHeaders := map[string][]string{"Content-Type": {"application/x-protobuf", "application/json"}}
 	for k, v := range ipt.ExpectedHeaders {
@@ -266,12 +334,13 @@ func (ipt *Input) RegHTTPHandler() {
 	log.Debugf("### register handler for /otel/v1/trace of agent %s", inputName)
 	statusOK = ipt.HTTPConfig.StatusCodeOK
 	dkhttp.RegHTTPHandler("POST", "/otel/v1/trace",
-		ihttp.CheckExpectedHeaders(
+		ipt.Auth.HTTPWrapper(ihttp.CheckExpectedHeaders(
 			workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
-				storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleOTELTrace)), log, expectedHeaders))
+				storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, handleOTELTrace)), log, expectedHeaders), log))
 
 	log.Debugf("### register handler for /otel/v1/metric of agent %s", inputName)
-	dkhttp.RegHTTPHandler("POST", "/otel/v1/metric", ihttp.CheckExpectedHeaders(handleOTElMetrics, log, expectedHeaders))
+	dkhttp.RegHTTPHandler("POST", "/otel/v1/metric",
+		ipt.Auth.HTTPWrapper(ihttp.CheckExpectedHeaders(handleOTElMetrics, log, expectedHeaders), log))
 }
 
 func (ipt *Input) Run() {
@@ -289,9 +358,14 @@ func (ipt *Input) Run() {
 	getAttribute = getAttrWrapper(ignoreKeyRegExps)
 	extractAtrribute = extractAttrWrapper(ignoreKeyRegExps)
 
+	var err error
+	if metricConv, err = newMetricConverter(ipt.MetricConfig); err != nil {
+		log.Errorf("### metric config: %s, metrics are sent as is", err.Error())
+	}
+
 	g := goroutine.NewGroup(goroutine.Option{Name: "inputs_opentelemetry"})
 	g.Go(func(ctx context.Context) error {
-		runGRPCV1(ipt.GRPCConfig.Address)
+		runGRPCV1(ipt.GRPCConfig.Address, ipt.Auth.ServerOptions()...)
 
 		return nil
 	})
@@ -316,6 +390,10 @@ func (ipt *Input) Terminate() {
 	if otelSvr != nil {
 		otelSvr.GracefulStop()
 	}
+	if afterGather != nil {
+		afterGather.Stop()
+		log.Debug("### after gather stopped")
+	}
 }
 
 func init() { //nolint:gochecknoinits
@@ -6,6 +6,7 @@
 package opentelemetry
 
 import (
+	"strconv"
 	"time"
 
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
@@ -15,6 +16,7 @@ import (
 )
 
 type pointData struct {
+	field  string // field name of value, the metric name if empty
 	value  interface{}
 	tags   map[string]string
 	fields map[string]interface{}
@@ -42,7 +44,11 @@ func (m *OTELMetrics) getPoints() []*point.Point {
 		if m.points[i].fields == nil {
 			m.points[i].fields = make(map[string]interface{})
 		}
-		m.points[i].fields[m.name] = m.points[i].value
+		if m.points[i].field != "" {
+			m.points[i].fields[m.points[i].field] = m.points[i].value
+		} else {
+			m.points[i].fields[m.name] = m.points[i].value
+		}
 
 		var tm time.Time
 		if m.points[i].ts == 0 {
@@ -64,6 +70,8 @@ func (m *OTELMetrics) getPoints() []*point.Point {
 }
 
 func parseResourceMetrics(resmcs []*metricspb.ResourceMetrics) []*OTELMetrics {
+	metricConv.convert(resmcs)
+
 	var omcs []*OTELMetrics
 	for _, resmc := range resmcs {
 		restags, resfields := extractAtrribute(resmc.Resource.Attributes)
@@ -128,6 +136,11 @@ func extractMetricPoints(metric *metricspb.Metric, extratags map[string]string,
 			data.value = pt.GetSum()
 			data.ts = int64(pt.TimeUnixNano)
 			points = append(points, data)
+
+			// buckets are re-mapped into the same bounds, they can be aggregated
+			if metricConv != nil && len(metricConv.bounds) != 0 {
+				points = append(points, histogramBuckets(metric.Name, pt, tags, fields)...)
+			}
 		}
 	case *metricspb.Metric_ExponentialHistogram:
 		for _, pt := range t.ExponentialHistogram.DataPoints {
@@ -155,3 +168,30 @@ func extractMetricPoints(metric *metricspb.Metric, extratags map[string]string,
 
 	return points
 }
+
+// histogramBuckets returns count and cumulative bucket counts of the
+// histogram data point in prometheus style, with upper bounds as tag "le".
+func histogramBuckets(name string, pt *metricspb.HistogramDataPoint,
+	tags map[string]string, fields map[string]interface{},
+) []*pointData {
+	ts := int64(pt.TimeUnixNano)
+	points := []*pointData{{field: name + "_count", value: int64(pt.Count), tags: tags, fields: itrace.MergeFields(fields), ts: ts}}
+
+	var acc uint64
+	for i, c := range pt.BucketCounts {
+		acc += c
+		le := "+Inf"
+		if i < len(pt.ExplicitBounds) {
+			le = strconv.FormatFloat(pt.ExplicitBounds[i], 'f', -1, 64)
+		}
+		points = append(points, &pointData{
+			field:  name + "_bucket",
+			value:  int64(acc),
+			tags:   itrace.MergeTags(tags, map[string]string{"le": le}),
+			fields: itrace.MergeFields(fields),
+			ts:     ts,
+		})
+	}
+
+	return points
+}
@@ -18,7 +18,7 @@ import (
 	_ "google.golang.org/grpc/encoding/gzip"
 )
 
-func runGRPCV1(addr string) {
+func runGRPCV1(addr string, opts ...grpc.ServerOption) {
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Errorf("### opentelemetry grpc server v1 listening on %s failed: %v", addr, err.Error())
@@ -27,7 +27,7 @@ func runGRPCV1(addr string) {
 	}
 	log.Debugf("### opentelemetry grpc v1 listening on: %s", addr)
 
-	otelSvr = grpc.NewServer()
+	otelSvr = grpc.NewServer(opts...)
 	trace.RegisterTraceServiceServer(otelSvr, &TraceServiceServer{})
 	metrics.RegisterMetricsServiceServer(otelSvr, &MetricsServiceServer{})
 
@@ -0,0 +1,382 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package opentelemetry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	commonpb "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry/compiled/v1/common"
+	metricspb "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry/compiled/v1/metrics"
+)
+
+const (
+	temporalityCumulative = "cumulative"
+	temporalityDelta      = "delta"
+
+	defaultSeriesExpire = 15 * time.Minute
+)
+
+type metricConfig struct {
+	Temporality      string        `toml:"temporality"`
+	ExpireAfter      time.Duration `toml:"expire_after"`
+	HistogramBuckets []float64     `toml:"histogram_buckets"`
+}
+
+// seriesState is the last data point of a series kept for temporality conversion.
+// For delta-to-cumulative it's the accumulated values, for cumulative-to-delta
+// it's the last cumulative values reported.
+type seriesState struct {
+	start, end uint64 // start and end time of the state, in unix nano
+	isInt      bool
+	intVal     int64
+	floatVal   float64
+
+	count   uint64
+	sum     float64
+	buckets []uint64
+	bounds  []float64
+
+	lastSeen time.Time
+}
+
+// metricConverter converts sums and histograms to the same temporality and
+// re-maps histogram buckets to the same bounds, so that metrics from SDKs with
+// different configurations can be aggregated. Series are keyed by resource,
+// scope, metric name and data point attributes.
+type metricConverter struct {
+	target  metricspb.AggregationTemporality
+	bounds  []float64
+	expire  time.Duration
+	now     func() time.Time
+	mtx     sync.Mutex
+	series  map[string]*seriesState
+	lastGC  time.Time
+	enabled bool
+}
+
+func newMetricConverter(cfg *metricConfig) (*metricConverter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	mc := &metricConverter{
+		expire: cfg.ExpireAfter,
+		now:    time.Now,
+		series: make(map[string]*seriesState),
+	}
+	if mc.expire <= 0 {
+		mc.expire = defaultSeriesExpire
+	}
+
+	switch strings.ToLower(cfg.Temporality) {
+	case "":
+	case temporalityCumulative:
+		mc.target = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	case temporalityDelta:
+		mc.target = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	default:
+		return nil, fmt.Errorf("invalid temporality %q, expect %q or %q", cfg.Temporality, temporalityCumulative, temporalityDelta)
+	}
+
+	if len(cfg.HistogramBuckets) != 0 {
+		mc.bounds = append([]float64{}, cfg.HistogramBuckets...)
+		sort.Float64s(mc.bounds)
+	}
+
+	mc.enabled = mc.target != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED || len(mc.bounds) != 0
+
+	return mc, nil
+}
+
+// convert converts metrics in place, data points can not be converted yet
+// (such as the first cumulative point of a series on converting to delta) are removed.
+func (mc *metricConverter) convert(resmcs []*metricspb.ResourceMetrics) {
+	if mc == nil || !mc.enabled {
+		return
+	}
+
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	now := mc.now()
+	for _, resmc := range resmcs {
+		reskey := attributesKey(resmc.GetResource().GetAttributes())
+		for _, scopemetrics := range resmc.ScopeMetrics {
+			prefix := reskey + "|" + scopemetrics.GetScope().GetName() + "|"
+			for _, metric := range scopemetrics.Metrics {
+				mc.convertMetric(prefix+metric.Name+"|", metric, now)
+			}
+		}
+	}
+
+	if now.Sub(mc.lastGC) > mc.expire {
+		for k, st := range mc.series {
+			if now.Sub(st.lastSeen) > mc.expire {
+				delete(mc.series, k)
+			}
+		}
+		mc.lastGC = now
+	}
+}
+
+func (mc *metricConverter) convertMetric(prefix string, metric *metricspb.Metric, now time.Time) {
+	switch t := metric.Data.(type) {
+	case *metricspb.Metric_Sum:
+		if !mc.needConvert(t.Sum.AggregationTemporality) {
+			return
+		}
+
+		pts := t.Sum.DataPoints[:0]
+		for _, pt := range t.Sum.DataPoints {
+			if mc.convertNumber(prefix+attributesKey(pt.Attributes), pt, t.Sum.IsMonotonic, now) {
+				pts = append(pts, pt)
+			}
+		}
+		t.Sum.DataPoints = pts
+		t.Sum.AggregationTemporality = mc.target
+
+	case *metricspb.Metric_Histogram:
+		if len(mc.bounds) != 0 {
+			for _, pt := range t.Histogram.DataPoints {
+				pt.BucketCounts, pt.ExplicitBounds = rebucket(pt.BucketCounts, pt.ExplicitBounds, mc.bounds), mc.bounds
+			}
+		}
+
+		if !mc.needConvert(t.Histogram.AggregationTemporality) {
+			return
+		}
+
+		pts := t.Histogram.DataPoints[:0]
+		for _, pt := range t.Histogram.DataPoints {
+			if mc.convertHistogram(prefix+attributesKey(pt.Attributes), pt, now) {
+				pts = append(pts, pt)
+			}
+		}
+		t.Histogram.DataPoints = pts
+		t.Histogram.AggregationTemporality = mc.target
+
+	default: // gauges and summaries have no temporality, exponential histograms are kept as is
+	}
+}
+
+func (mc *metricConverter) needConvert(from metricspb.AggregationTemporality) bool {
+	return mc.target != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED &&
+		from != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED &&
+		from != mc.target
+}
+
+// convertNumber converts a sum data point, it returns false if the point should be dropped.
+func (mc *metricConverter) convertNumber(key string, pt *metricspb.NumberDataPoint, monotonic bool, now time.Time) bool {
+	isInt := false
+	var (
+		iv int64
+		fv float64
+	)
+	switch v := pt.Value.(type) {
+	case *metricspb.NumberDataPoint_AsInt:
+		isInt, iv = true, v.AsInt
+	case *metricspb.NumberDataPoint_AsDouble:
+		fv = v.AsDouble
+	default:
+		return false
+	}
+
+	st, ok := mc.series[key]
+	if ok {
+		if st.isInt != isInt {
+			ok = false // value type changed, restart the series
+		} else if pt.TimeUnixNano <= st.end {
+			return false // out of order or duplicated
+		}
+	}
+
+	if mc.target == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		if !ok {
+			st = &seriesState{start: pt.StartTimeUnixNano, isInt: isInt}
+			if st.start == 0 {
+				st.start = pt.TimeUnixNano
+			}
+			mc.series[key] = st
+		}
+		st.intVal += iv
+		st.floatVal += fv
+		st.end, st.lastSeen = pt.TimeUnixNano, now
+
+		pt.StartTimeUnixNano = st.start
+		if isInt {
+			pt.Value = &metricspb.NumberDataPoint_AsInt{AsInt: st.intVal}
+		} else {
+			pt.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: st.floatVal}
+		}
+		return true
+	}
+
+	// cumulative to delta
+	if !ok {
+		mc.series[key] = &seriesState{start: pt.StartTimeUnixNano, end: pt.TimeUnixNano, isInt: isInt, intVal: iv, floatVal: fv, lastSeen: now}
+		return false
+	}
+
+	reset := pt.StartTimeUnixNano != 0 && pt.StartTimeUnixNano != st.start
+	if monotonic && ((isInt && iv < st.intVal) || (!isInt && fv < st.floatVal)) {
+		reset = true
+	}
+
+	deltaStart := st.end
+	if reset {
+		// counter restarted, the value is the delta since restart
+		deltaStart = pt.StartTimeUnixNano
+	} else {
+		iv -= st.intVal
+		fv -= st.floatVal
+	}
+
+	st.start, st.end, st.lastSeen = pt.StartTimeUnixNano, pt.TimeUnixNano, now
+	if isInt {
+		st.intVal = pt.GetAsInt()
+		pt.Value = &metricspb.NumberDataPoint_AsInt{AsInt: iv}
+	} else {
+		st.floatVal = pt.GetAsDouble()
+		pt.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: fv}
+	}
+	pt.StartTimeUnixNano = deltaStart
+
+	return true
+}
+
+// convertHistogram converts a histogram data point, it returns false if the point should be dropped.
+func (mc *metricConverter) convertHistogram(key string, pt *metricspb.HistogramDataPoint, now time.Time) bool {
+	st, ok := mc.series[key]
+	if ok {
+		if pt.TimeUnixNano <= st.end {
+			return false // out of order or duplicated
+		}
+		if !equalBounds(st.bounds, pt.ExplicitBounds) || len(st.buckets) != len(pt.BucketCounts) {
+			ok = false // buckets changed, restart the series
+		}
+	}
+
+	if mc.target == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		if !ok {
+			st = &seriesState{
+				start:   pt.StartTimeUnixNano,
+				buckets: make([]uint64, len(pt.BucketCounts)),
+				bounds:  pt.ExplicitBounds,
+			}
+			if st.start == 0 {
+				st.start = pt.TimeUnixNano
+			}
+			mc.series[key] = st
+		}
+
+		st.count += pt.Count
+		st.sum += pt.GetSum()
+		for i, c := range pt.BucketCounts {
+			st.buckets[i] += c
+		}
+		st.end, st.lastSeen = pt.TimeUnixNano, now
+
+		pt.StartTimeUnixNano = st.start
+		pt.Count = st.count
+		pt.BucketCounts = append([]uint64{}, st.buckets...)
+		if pt.Sum != nil {
+			sum := st.sum
+			pt.Sum = &sum
+		}
+		// min and max of the delta are not those of the whole series
+		pt.Min, pt.Max = nil, nil
+		return true
+	}
+
+	// cumulative to delta
+	if !ok {
+		mc.series[key] = &seriesState{
+			start:    pt.StartTimeUnixNano,
+			end:      pt.TimeUnixNano,
+			count:    pt.Count,
+			sum:      pt.GetSum(),
+			buckets:  append([]uint64{}, pt.BucketCounts...),
+			bounds:   pt.ExplicitBounds,
+			lastSeen: now,
+		}
+		return false
+	}
+
+	reset := (pt.StartTimeUnixNano != 0 && pt.StartTimeUnixNano != st.start) || pt.Count < st.count
+	for i, c := range pt.BucketCounts {
+		if c < st.buckets[i] {
+			reset = true
+		}
+	}
+
+	start, count, sum, buckets := pt.StartTimeUnixNano, pt.Count, pt.GetSum(), append([]uint64{}, pt.BucketCounts...)
+	deltaStart := st.end
+	if reset {
+		deltaStart = pt.StartTimeUnixNano
+	} else {
+		pt.Count -= st.count
+		for i := range pt.BucketCounts {
+			pt.BucketCounts[i] -= st.buckets[i]
+		}
+		if pt.Sum != nil {
+			delta := sum - st.sum
+			pt.Sum = &delta
+		}
+		pt.Min, pt.Max = nil, nil
+	}
+	pt.StartTimeUnixNano = deltaStart
+
+	st.start, st.end, st.lastSeen = start, pt.TimeUnixNano, now
+	st.count, st.sum, st.buckets = count, sum, buckets
+
+	return true
+}
+
+// rebucket re-maps bucket counts of bounds into target. Each source bucket is
+// counted into the target bucket containing its upper bound, it's exact if
+// target is a subset of bounds.
+func rebucket(counts []uint64, bounds, target []float64) []uint64 {
+	if len(counts) == 0 || equalBounds(bounds, target) {
+		return counts
+	}
+
+	res := make([]uint64, len(target)+1)
+	for i, c := range counts {
+		if i >= len(bounds) { // the +Inf bucket
+			res[len(target)] += c
+			continue
+		}
+		res[sort.SearchFloat64s(target, bounds[i])] += c
+	}
+
+	return res
+}
+
+func equalBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func attributesKey(attributes []*commonpb.KeyValue) string {
+	kvs := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		kvs = append(kvs, attr.Key+"="+attr.GetValue().String())
+	}
+	sort.Strings(kvs)
+
+	return strings.Join(kvs, ",")
+}
@@ -0,0 +1,206 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package opentelemetry
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry/compiled/v1/common"
+	metricspb "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry/compiled/v1/metrics"
+	resourcepb "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/opentelemetry/compiled/v1/resource"
+)
+
+const (
+	deltaTemporality      = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	cumulativeTemporality = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+)
+
+func resourceMetrics(service string, metric *metricspb.Metric) []*metricspb.ResourceMetrics {
+	return []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: service}}},
+		}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope:   &commonpb.InstrumentationScope{Name: "test"},
+			Metrics: []*metricspb.Metric{metric},
+		}},
+	}}
+}
+
+func sumMetric(temporality metricspb.AggregationTemporality, start, end uint64, v int64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: "requests",
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: temporality,
+			IsMonotonic:            true,
+			DataPoints: []*metricspb.NumberDataPoint{{
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v},
+			}},
+		}},
+	}
+}
+
+func histogramMetric(temporality metricspb.AggregationTemporality, start, end uint64,
+	sum float64, bounds []float64, counts ...uint64,
+) *metricspb.Metric {
+	var count uint64
+	for _, c := range counts {
+		count += c
+	}
+
+	return &metricspb.Metric{
+		Name: "latency",
+		Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: temporality,
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Count:             count,
+				Sum:               &sum,
+				BucketCounts:      counts,
+				ExplicitBounds:    bounds,
+			}},
+		}},
+	}
+}
+
+func TestMetricConverter(t *T.T) {
+	t.Run("invalid", func(t *T.T) {
+		_, err := newMetricConverter(&metricConfig{Temporality: "monthly"})
+		assert.Error(t, err)
+
+		var mc *metricConverter
+		mc.convert(resourceMetrics("svc", sumMetric(deltaTemporality, 1, 2, 3))) // nil converter
+	})
+
+	t.Run("delta-to-cumulative", func(t *T.T) {
+		mc, err := newMetricConverter(&metricConfig{Temporality: "cumulative"})
+		require.NoError(t, err)
+
+		var got []int64
+		for i, v := range []int64{3, 5, 2} {
+			m := sumMetric(deltaTemporality, uint64(i*10+1), uint64(i*10+10), v)
+			mc.convert(resourceMetrics("svc", m))
+
+			sum := m.GetSum()
+			assert.Equal(t, cumulativeTemporality, sum.AggregationTemporality)
+			require.Len(t, sum.DataPoints, 1)
+			assert.Equal(t, uint64(1), sum.DataPoints[0].StartTimeUnixNano)
+			got = append(got, sum.DataPoints[0].GetAsInt())
+		}
+		assert.Equal(t, []int64{3, 8, 10}, got)
+
+		// another resource is another series
+		m := sumMetric(deltaTemporality, 0, 10, 1)
+		mc.convert(resourceMetrics("other", m))
+		assert.Equal(t, int64(1), m.GetSum().DataPoints[0].GetAsInt())
+
+		// out of order
+		m = sumMetric(deltaTemporality, 11, 20, 1)
+		mc.convert(resourceMetrics("svc", m))
+		assert.Empty(t, m.GetSum().DataPoints)
+	})
+
+	t.Run("cumulative-to-delta", func(t *T.T) {
+		mc, err := newMetricConverter(&metricConfig{Temporality: "delta"})
+		require.NoError(t, err)
+
+		m := sumMetric(cumulativeTemporality, 1, 10, 3)
+		mc.convert(resourceMetrics("svc", m))
+		assert.Equal(t, deltaTemporality, m.GetSum().AggregationTemporality)
+		assert.Empty(t, m.GetSum().DataPoints, "first point dropped")
+
+		m = sumMetric(cumulativeTemporality, 1, 20, 8)
+		mc.convert(resourceMetrics("svc", m))
+		require.Len(t, m.GetSum().DataPoints, 1)
+		assert.Equal(t, int64(5), m.GetSum().DataPoints[0].GetAsInt())
+		assert.Equal(t, uint64(10), m.GetSum().DataPoints[0].StartTimeUnixNano)
+
+		// counter restarted
+		m = sumMetric(cumulativeTemporality, 25, 30, 2)
+		mc.convert(resourceMetrics("svc", m))
+		require.Len(t, m.GetSum().DataPoints, 1)
+		assert.Equal(t, int64(2), m.GetSum().DataPoints[0].GetAsInt())
+		assert.Equal(t, uint64(25), m.GetSum().DataPoints[0].StartTimeUnixNano)
+
+		m = sumMetric(cumulativeTemporality, 25, 40, 7)
+		mc.convert(resourceMetrics("svc", m))
+		assert.Equal(t, int64(5), m.GetSum().DataPoints[0].GetAsInt())
+	})
+
+	t.Run("histogram", func(t *T.T) {
+		mc, err := newMetricConverter(&metricConfig{Temporality: "delta", HistogramBuckets: []float64{1, 0.1}})
+		require.NoError(t, err)
+		assert.Equal(t, []float64{0.1, 1}, mc.bounds)
+
+		bounds := []float64{0.05, 0.1, 0.5, 1, 5}
+
+		m := histogramMetric(cumulativeTemporality, 1, 10, 10, bounds, 1, 2, 3, 4, 5, 6)
+		mc.convert(resourceMetrics("svc", m))
+		assert.Empty(t, m.GetHistogram().DataPoints)
+
+		m = histogramMetric(cumulativeTemporality, 1, 20, 15, bounds, 2, 2, 4, 4, 6, 8)
+		mc.convert(resourceMetrics("svc", m))
+		require.Len(t, m.GetHistogram().DataPoints, 1)
+
+		pt := m.GetHistogram().DataPoints[0]
+		assert.Equal(t, []float64{0.1, 1}, pt.ExplicitBounds)
+		assert.Equal(t, []uint64{1, 1, 3}, pt.BucketCounts)
+		assert.Equal(t, uint64(5), pt.Count)
+		assert.Equal(t, 5.0, pt.GetSum())
+		assert.Equal(t, uint64(10), pt.StartTimeUnixNano)
+	})
+
+	t.Run("expire", func(t *T.T) {
+		mc, err := newMetricConverter(&metricConfig{Temporality: "cumulative", ExpireAfter: time.Minute})
+		require.NoError(t, err)
+
+		now := time.Now()
+		mc.now = func() time.Time { return now }
+
+		mc.convert(resourceMetrics("svc", sumMetric(deltaTemporality, 0, 10, 1)))
+		assert.Len(t, mc.series, 1)
+
+		now = now.Add(2 * time.Minute)
+		mc.convert(resourceMetrics("other", sumMetric(deltaTemporality, 0, 10, 1)))
+		assert.Len(t, mc.series, 1)
+	})
+}
+
+func TestRebucket(t *T.T) {
+	bounds := []float64{0.05, 0.1, 0.5, 1, 5}
+
+	assert.Equal(t, []uint64{3, 7, 11}, rebucket([]uint64{1, 2, 3, 4, 5, 6}, bounds, []float64{0.1, 1}))
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, rebucket([]uint64{1, 2, 3, 4, 5, 6}, bounds, bounds))
+
+	// source bounds not in target counted into the bucket contains its upper bound
+	assert.Equal(t, []uint64{1, 5, 15}, rebucket([]uint64{1, 2, 3, 4, 5, 6}, bounds, []float64{0.07, 0.7}))
+}
+
+func TestHistogramBuckets(t *T.T) {
+	m := histogramMetric(deltaTemporality, 0, 10, 5, []float64{0.1, 1}, 1, 2, 3)
+	pts := histogramBuckets("latency", m.GetHistogram().DataPoints[0], map[string]string{"k": "v"}, nil)
+	require.Len(t, pts, 4)
+
+	assert.Equal(t, "latency_count", pts[0].field)
+	assert.Equal(t, int64(6), pts[0].value)
+
+	var les []string
+	var values []interface{}
+	for _, pt := range pts[1:] {
+		assert.Equal(t, "latency_bucket", pt.field)
+		assert.Equal(t, "v", pt.tags["k"])
+		les = append(les, pt.tags["le"])
+		values = append(values, pt.value)
+	}
+	assert.Equal(t, []string{"0.1", "1", "+Inf"}, les)
+	assert.Equal(t, []interface{}{int64(1), int64(3), int64(6)}, values)
+}
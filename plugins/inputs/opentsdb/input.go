@@ -0,0 +1,356 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package opentsdb receives metrics of OpenTSDB HTTP API.
+package opentsdb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
+	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "opentsdb"
+	catalog   = "opentsdb"
+
+	sampleCfg = `
+[[inputs.opentsdb]]
+  ## path of the put API, the same as OpenTSDB by default, so clients can
+  ## put to datakit as to OpenTSDB.
+  path = "/api/put"
+
+  ## separator to join parts of measurement and field
+  separator = "_"
+
+  ## templates to map the metric name to measurement, field and tags, such as
+  ##   "sys.* .measurement.field*"
+  ## which maps sys.cpu.nice to measurement cpu and field nice. Default is
+  ## "measurement.field*".
+  ## See https://github.com/influxdata/telegraf/tree/master/docs/TEMPLATE_PATTERN.md
+  templates = [
+    # "sys.* .measurement.field*",
+  ]
+
+  ## max size of the request body
+  max_body_size = 33554432
+
+  [inputs.opentsdb.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+
+	defaultPath        = "/api/put"
+	defaultTemplate    = "measurement.field*"
+	defaultField       = "value"
+	defaultMaxBodySize = 32 << 20
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	Path        string            `toml:"path"`
+	Separator   string            `toml:"separator"`
+	Templates   []string          `toml:"templates"`
+	MaxBodySize int64             `toml:"max_body_size"`
+	Tags        map[string]string `toml:"tags"`
+
+	parser    *graphite.GraphiteParser
+	parserMtx sync.Mutex // templates not safe for concurrent use
+
+	feeder dkio.Feeder
+}
+
+// Make sure Input implements the inputs.InputV2 interface.
+var _ inputs.InputV2 = &Input{}
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement { return nil }
+
+func (*Input) Run() {
+	l.Info("register opentsdb router")
+}
+
+func (*Input) Terminate() {
+	// Nothing to terminate.
+}
+
+func (ipt *Input) RegHTTPHandler() {
+	l = logger.SLogger(inputName)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		return
+	}
+
+	dhttp.RegHTTPHandler(http.MethodPost, ipt.Path, ihttp.ProtectedHandlerFunc(ipt.ServeHTTP, l))
+}
+
+func (ipt *Input) setup() error {
+	if ipt.Path == "" {
+		ipt.Path = defaultPath
+	}
+	if ipt.MaxBodySize <= 0 {
+		ipt.MaxBodySize = defaultMaxBodySize
+	}
+
+	templates := ipt.Templates
+	if len(templates) == 0 {
+		templates = []string{defaultTemplate}
+	}
+
+	p, err := graphite.NewGraphiteParser(ipt.Separator, templates, nil)
+	if err != nil {
+		return err
+	}
+	ipt.parser = p
+
+	return nil
+}
+
+// datapoint of the put API.
+type datapoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp int64             `json:"timestamp"`
+	Value     json.RawMessage   `json:"value"` // number or string of number
+	Tags      map[string]string `json:"tags"`
+}
+
+type datapointError struct {
+	Datapoint *datapoint `json:"datapoint"`
+	Error     string     `json:"error"`
+}
+
+// result of the put API, responded on argument summary or details.
+type result struct {
+	Success int               `json:"success"`
+	Failed  int               `json:"failed"`
+	Errors  []*datapointError `json:"errors,omitempty"`
+}
+
+// ServeHTTP handles the put API, see
+// http://opentsdb.net/docs/build/html/api_http/put.html
+func (ipt *Input) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	dps, err := ipt.readDatapoints(req)
+	if err != nil {
+		l.Warnf("read datapoints: %s", err)
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		res = &result{}
+		pts []*point.Point
+	)
+
+	for _, dp := range dps {
+		pt, err := ipt.makePoint(dp)
+		if err != nil {
+			res.Failed++
+			res.Errors = append(res.Errors, &datapointError{Datapoint: dp, Error: err.Error()})
+			continue
+		}
+
+		res.Success++
+		pts = append(pts, pt)
+	}
+
+	if len(pts) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.Metric, pts, &dkio.Option{}); err != nil {
+			l.Errorf("feed %d points: %s", len(pts), err)
+			writeError(resp, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if res.Failed > 0 {
+		l.Debugf("%d datapoints failed, the first: %s", res.Failed, res.Errors[0].Error)
+	}
+
+	status := http.StatusNoContent
+	if res.Failed > 0 {
+		status = http.StatusBadRequest
+	}
+
+	query := req.URL.Query()
+	_, details := query["details"]
+	_, summary := query["summary"]
+
+	switch {
+	case details:
+	case summary:
+		res.Errors = nil
+	default:
+		if res.Failed > 0 {
+			writeError(resp, status, fmt.Errorf("%d of %d datapoints failed", res.Failed, len(dps)))
+		} else {
+			resp.WriteHeader(status)
+		}
+		return
+	}
+
+	if status == http.StatusNoContent {
+		status = http.StatusOK
+	}
+	writeJSON(resp, status, res)
+}
+
+func (ipt *Input) readDatapoints(req *http.Request) ([]*datapoint, error) {
+	var body io.Reader = http.MaxBytesReader(nil, req.Body, ipt.MaxBodySize)
+
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close() //nolint:errcheck
+		body = io.LimitReader(zr, ipt.MaxBodySize)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// a datapoint or an array of datapoints
+	var dps []*datapoint
+	if err := json.Unmarshal(data, &dps); err != nil {
+		var dp datapoint
+		if err := json.Unmarshal(data, &dp); err != nil {
+			return nil, fmt.Errorf("invalid datapoints: %w", err)
+		}
+		dps = []*datapoint{&dp}
+	}
+
+	return dps, nil
+}
+
+func (ipt *Input) makePoint(dp *datapoint) (*point.Point, error) {
+	if dp.Metric == "" {
+		return nil, errors.New("metric not set")
+	}
+
+	value, err := parseValue(dp.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := parseTimestamp(dp.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	ipt.parserMtx.Lock()
+	name, tags, field, err := ipt.parser.ApplyTemplate(dp.Metric)
+	ipt.parserMtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = dp.Metric
+	}
+	if field == "" {
+		field = defaultField
+	}
+
+	for k, v := range dp.Tags {
+		tags[k] = v
+	}
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+
+	return point.NewPointV2([]byte(name),
+		append(point.NewTags(tags), point.NewKVs(map[string]interface{}{field: value})...),
+		append(point.DefaultMetricOptions(), point.WithTime(ts))...), nil
+}
+
+func parseValue(raw json.RawMessage) (float64, error) {
+	if len(raw) == 0 {
+		return 0, errors.New("value not set")
+	}
+
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return v, nil
+	}
+
+	var s json.Number
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("invalid value %s", raw)
+	}
+
+	v, err := s.Float64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %s", raw)
+	}
+	return v, nil
+}
+
+// parseTimestamp returns time of timestamp in seconds or milliseconds, the
+// same as OpenTSDB, timestamps of more than 10 digits are in milliseconds.
+func parseTimestamp(ts int64) (time.Time, error) {
+	switch {
+	case ts <= 0:
+		return time.Time{}, errors.New("invalid timestamp")
+	case ts > 9999999999:
+		return time.UnixMilli(ts), nil
+	default:
+		return time.Unix(ts, 0), nil
+	}
+}
+
+func writeError(resp http.ResponseWriter, status int, err error) {
+	writeJSON(resp, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": err.Error(),
+		},
+	})
+}
+
+func writeJSON(resp http.ResponseWriter, status int, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	if err := json.NewEncoder(resp).Encode(v); err != nil {
+		l.Debugf("write response: %s", err)
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Path:        defaultPath,
+		Separator:   "_",
+		MaxBodySize: defaultMaxBodySize,
+		Tags:        map[string]string{},
+		feeder:      dkio.DefaultFeeder(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package opentsdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func TestServeHTTP(t *T.T) {
+	feeder := dkio.NewMockedFeeder()
+	ipt := defaultInput()
+	ipt.feeder = feeder
+	ipt.Templates = []string{"sys.* .measurement.field*", "measurement.field*"}
+	ipt.Tags = map[string]string{"env": "test"}
+	require.NoError(t, ipt.setup())
+
+	put := func(query, body string, gz bool) *httptest.ResponseRecorder {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if gz {
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write([]byte(body))
+			require.NoError(t, zw.Close())
+		} else {
+			buf.WriteString(body)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/put"+query, &buf)
+		if gz {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		w := httptest.NewRecorder()
+		ipt.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("single", func(t *T.T) {
+		w := put("", `{"metric":"sys.cpu.nice","timestamp":1346846400,"value":18,"tags":{"host":"web01"}}`, false)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "cpu", string(pts[0].Name()))
+		assert.Equal(t, 18.0, pts[0].Get([]byte("nice")))
+		assert.Equal(t, "web01", string(pts[0].GetTag([]byte("host"))))
+		assert.Equal(t, "test", string(pts[0].GetTag([]byte("env"))))
+		assert.Equal(t, int64(1346846400), pts[0].Time().Unix())
+	})
+
+	t.Run("array-gzip", func(t *T.T) {
+		w := put("", `[
+{"metric":"app.requests","timestamp":1346846400000,"value":"42.5","tags":{}},
+{"metric":"uptime","timestamp":1346846400,"value":1}
+]`, true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		pts, err := feeder.NPoints(2, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "app", string(pts[0].Name()))
+		assert.Equal(t, 42.5, pts[0].Get([]byte("requests")))
+		assert.Equal(t, int64(1346846400000), pts[0].Time().UnixMilli())
+		assert.Equal(t, "uptime", string(pts[1].Name()))
+		assert.Equal(t, 1.0, pts[1].Get([]byte("value")))
+	})
+
+	body := `[
+{"metric":"sys.cpu.user","timestamp":1346846400,"value":1},
+{"metric":"sys.cpu.idle","timestamp":1346846400,"value":"x"},
+{"metric":"","timestamp":1346846400,"value":1},
+{"metric":"sys.cpu.sys","value":1}
+]`
+
+	t.Run("failed", func(t *T.T) {
+		w := put("", body, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, pts[0].Get([]byte("user")))
+	})
+
+	t.Run("summary", func(t *T.T) {
+		w := put("?summary", body, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var res result
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, result{Success: 1, Failed: 3}, res)
+		_, _ = feeder.NPoints(1, time.Second)
+	})
+
+	t.Run("details", func(t *T.T) {
+		w := put("?details", body, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var res result
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 1, res.Success)
+		require.Len(t, res.Errors, 3)
+		assert.Equal(t, "sys.cpu.idle", res.Errors[0].Datapoint.Metric)
+		assert.Equal(t, "invalid timestamp", res.Errors[2].Error)
+		_, _ = feeder.NPoints(1, time.Second)
+
+		w = put("?details", `{"metric":"sys.cpu.user","timestamp":1346846400,"value":1}`, false)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"success":1,"failed":0}`, w.Body.String())
+		_, _ = feeder.NPoints(1, time.Second)
+	})
+
+	t.Run("invalid-body", func(t *T.T) {
+		w := put("", `not json`, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -6,13 +6,9 @@
 package oracle
 
 import (
-	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,11 +17,7 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
-	lp "github.com/GuanceCloud/cliutils/lineproto"
 	"github.com/GuanceCloud/cliutils/point"
-	"github.com/gin-gonic/gin"
-	influxdb "github.com/influxdata/influxdb1-client/v2"
-	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
@@ -48,37 +40,45 @@ func TestOracleInput(t *testing.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []testutils.TestingCase
 	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-			t.Logf("testing %s...", tc.name)
+	testutils.RunCases(t, arr)
+}
 
-			if err := tc.run(); err != nil {
-				tc.cr.Status = testutils.TestFailed
-				tc.cr.FailedMessage = err.Error()
+func (cs *caseSpec) CaseName() string { return cs.name }
 
-				assert.NoError(t, err)
-			} else {
-				tc.cr.Status = testutils.TestPassed
-			}
+func (cs *caseSpec) Result() *testutils.CaseResult { return cs.cr }
 
-			tc.cr.Cost = time.Since(caseStart)
+func (cs *caseSpec) Run(t *testing.T) error {
+	cs.t = t
+	return cs.run()
+}
 
-			assert.NoError(t, testutils.Flush(tc.cr))
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
+	}
 
-			t.Cleanup(func() {
-				// clean remote docker resources
-				if tc.resource == nil {
-					return
-				}
+	return cs.container.Purge()
+}
 
-				assert.NoError(t, tc.pool.Purge(tc.resource))
-			})
-		})
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []testutils.Container {
+	if cs.container == nil {
+		return nil
 	}
+
+	return []testutils.Container{cs.container}
+}
+
+// ExclusiveResources returns the container name and host ports bound, they
+// are fixed and can not be shared with other cases running at the same time.
+func (cs *caseSpec) ExclusiveResources() []string {
+	return append([]string{cs.getContainterName()}, cs.exposedPorts...)
 }
 
 func buildCases(t *testing.T) ([]*caseSpec, error) {
@@ -89,7 +89,9 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 	bases := []struct {
 		name           string // Also used as build image name:tag.
 		conf           string
-		dockerFileText string // Empty if not build image.
+		dockerFileText string // Empty if not build image, rendered by dockerFileVars.
+		dockerFileVars *testutils.DockerfileVars
+		buildArgs      map[string]string
 		exposedPorts   []string
 		opts           []inputs.PointCheckOption
 	}{
@@ -129,6 +131,8 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 			repoTag: repoTag[1],
 
 			dockerFileText: base.dockerFileText,
+			dockerFileVars: base.dockerFileVars,
+			buildArgs:      base.buildArgs,
 			exposedPorts:   base.exposedPorts,
 			opts:           base.opts,
 
@@ -160,82 +164,45 @@ type caseSpec struct {
 	repo           string
 	repoTag        string
 	dockerFileText string
+	dockerFileVars *testutils.DockerfileVars
+	buildArgs      map[string]string
 	exposedPorts   []string
 	opts           []inputs.PointCheckOption
 
 	ipt    *Input
 	feeder *dkio.MockedFeeder
 
-	pool     *dockertest.Pool
-	resource *dockertest.Resource
+	container testutils.Container
 
 	cr *testutils.CaseResult
-}
 
-var (
-	done             chan struct{}
-	errorMsgs        []string
-	mMeasurementName sync.Map
-)
+	checkErr error
 
-type FeedMeasurementBody []struct {
-	Measurement string                 `json:"measurement"`
-	Tags        map[string]string      `json:"tags"`
-	Fields      map[string]interface{} `json:"fields"`
+	checkedMeasurements sync.Map
 }
 
-func (cs *caseSpec) handler(c *gin.Context) {
-	uri, err := url.ParseRequestURI(c.Request.URL.RequestURI())
-	if err != nil {
-		cs.t.Logf("%s", err.Error())
-		return
-	}
-
-	body, err := ioutil.ReadAll(c.Request.Body)
-	defer c.Request.Body.Close()
-	if err != nil {
-		cs.t.Logf("%s", err.Error())
-		return
-	}
-
-	switch uri.Path {
-	case "/v1/write/metric":
-		pts, err := lp.ParsePoints(body, nil)
-		if err != nil {
-			cs.t.Logf("ParsePoints failed: %s", err.Error())
-			return
-		}
-
-		newPts := dkpt2point(pts...)
-		for _, pt := range newPts {
-			if string(pt.Name()) == oracleSystem {
-				if len(pt.Fields()) <= 6 {
-					cs.t.Logf("oracle_system not ready, ignored...")
-					return // not ready, ignore.
-				}
-			}
-		}
-
-		if err := cs.checkPoint(newPts); err != nil {
-			if err != nil {
-				cs.t.Logf("%s", err.Error())
-				assert.NoError(cs.t, err)
-				return
-			}
+// checked checks metric points received, returns true if all measurements
+// checked or some checking failed.
+func (cs *caseSpec) checked(md *testutils.MockDataway) bool {
+	var pts []*point.Point
+	for _, pt := range md.Points("metric") {
+		if string(pt.Name()) == oracleSystem && len(pt.Fields()) <= 6 {
+			continue // not ready, ignore.
 		}
+		pts = append(pts, pt)
+	}
 
-	default:
-		panic("not implement")
+	if err := cs.checkPoint(pts); err != nil {
+		cs.checkErr = err
+		return true
 	}
 
 	length := 0
-	mMeasurementName.Range(func(k, v interface{}) bool {
+	cs.checkedMeasurements.Range(func(k, v interface{}) bool {
 		length++
 		return true
 	})
-	if length == 3 {
-		done <- struct{}{}
-	}
+	return length == 3
 }
 
 func (cs *caseSpec) checkPoint(pts []*point.Point) error {
@@ -248,7 +215,7 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 
 		switch measurement {
 		case oracleProcess:
-			_, ok := mMeasurementName.Load(oracleProcess)
+			_, ok := cs.checkedMeasurements.Load(oracleProcess)
 			if ok {
 				return nil
 			}
@@ -266,11 +233,11 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 				return fmt.Errorf("check measurement %s failed: %+#v", measurement, msgs)
 			} else {
 				cs.t.Logf("oracle_process check completed!")
-				mMeasurementName.Store(oracleProcess, 1)
+				cs.checkedMeasurements.Store(oracleProcess, 1)
 			}
 
 		case oracleTablespace:
-			_, ok := mMeasurementName.Load(oracleTablespace)
+			_, ok := cs.checkedMeasurements.Load(oracleTablespace)
 			if ok {
 				return nil
 			}
@@ -288,11 +255,11 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 				return fmt.Errorf("check measurement %s failed: %+#v", measurement, msgs)
 			} else {
 				cs.t.Logf("oracle_tablespace check completed!")
-				mMeasurementName.Store(oracleTablespace, 1)
+				cs.checkedMeasurements.Store(oracleTablespace, 1)
 			}
 
 		case oracleSystem:
-			_, ok := mMeasurementName.Load(oracleSystem)
+			_, ok := cs.checkedMeasurements.Load(oracleSystem)
 			if ok {
 				return nil
 			}
@@ -310,7 +277,7 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 				return fmt.Errorf("check measurement %s failed: %+#v", measurement, msgs)
 			} else {
 				cs.t.Logf("oracle_system check completed!")
-				mMeasurementName.Store(oracleSystem, 1)
+				cs.checkedMeasurements.Store(oracleSystem, 1)
 			}
 
 		default: // TODO: check other measurement
@@ -342,29 +309,14 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 
 func (cs *caseSpec) run() error {
 	r := testutils.GetRemote()
-	dockerTCP := r.TCPURL()
-
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
 
-	router := gin.New()
-	router.POST("/v1/write/metric", cs.handler)
+	cs.t.Logf("get remote: %+#v", r)
 
-	srv := &http.Server{
-		Addr:    ":59529",
-		Handler: router,
-	}
-
-	go func() {
-		done = nil
-		done = make(chan struct{})
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			panic(err)
-		}
-	}()
+	md := testutils.NewMockDataway(cs.t)
 
 	start := time.Now()
 
-	p, err := cs.getPool(dockerTCP)
+	rt, err := cs.getRuntime(r)
 	if err != nil {
 		return err
 	}
@@ -372,7 +324,7 @@ func (cs *caseSpec) run() error {
 	containerName := cs.getContainterName()
 
 	// Remove the container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 
@@ -387,57 +339,35 @@ func (cs *caseSpec) run() error {
 		return err
 	}
 
-	var resource *dockertest.Resource
-
-	if len(cs.dockerFileText) == 0 {
-		// Just run a container from existing docker image.
-		resource, err = p.RunWithOptions(
-			&dockertest.RunOptions{
-				Name: containerName, // ATTENTION: not cs.name.
-
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{fmt.Sprintf("DATAKIT_HOST=%s", extIP), "DATAKIT_PORT=59529", "ORACLE_PASSWORD=123456", "ORACLE_SID=XE"},
-
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
-
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				// c.AutoRemove = true
-			},
-		)
-	} else {
-		// Build docker image from Dockerfile and run a container from it.
-		resource, err = p.BuildAndRunWithOptions(
-			dockerFilePath,
+	dkEnvs := []string{
+		fmt.Sprintf("DATAKIT_HOST=%s", extIP), fmt.Sprintf("DATAKIT_PORT=%d", md.Port()),
+		"ORACLE_PASSWORD=123456", "ORACLE_SID=XE",
+	}
 
-			&dockertest.RunOptions{
-				Name: cs.name,
+	opts := &testutils.RunOptions{
+		Name: containerName, // ATTENTION: not cs.name.
 
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{fmt.Sprintf("DATAKIT_HOST=%s", extIP), "DATAKIT_PORT=59529", "ORACLE_PASSWORD=123456", "ORACLE_SID=XE"},
+		Repository: cs.repo,
+		Tag:        cs.repoTag,
+		Env:        dkEnvs,
 
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
+		ExposedPorts: cs.exposedPorts,
+		PortBindings: cs.getPortBindings(),
+	}
 
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				// c.AutoRemove = true
-			},
-		)
+	// Build image from Dockerfile and run a container from it.
+	if len(cs.dockerFileText) != 0 {
+		opts.Dockerfile = dockerFilePath
+		opts.BuildArgs = cs.buildArgs
 	}
 
+	c, err := rt.Run(opts)
 	if err != nil {
 		cs.t.Logf("%s", err.Error())
 		return err
 	}
 
-	cs.pool = p
-	cs.resource = resource
+	cs.container = c
 
 	cs.t.Logf("check service(%s:%v)...", r.Host, cs.exposedPorts)
 
@@ -448,81 +378,33 @@ func (cs *caseSpec) run() error {
 	cs.cr.AddField("container_ready_cost", int64(time.Since(start)))
 
 	cs.t.Logf("checking oracle in 5 minutes...")
-	tick := time.NewTicker(5 * time.Minute)
-	out := false
-	for {
-		if out {
-			break
-		}
-
-		select {
-		case <-tick.C:
-			cs.t.Logf("check oracle timeout!")
-			out = true
-		case <-done:
-			cs.t.Logf("check oracle all done!")
-			out = true
-		}
+	if !md.Await(5*time.Minute, cs.checked) {
+		return fmt.Errorf("check oracle timeout")
 	}
 
-	if len(errorMsgs) > 0 {
-		return fmt.Errorf("errorMsgs: %#v", errorMsgs)
-	}
-	errorMsgs = errorMsgs[:0]
-
-	cs.t.Logf("exit...")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil && errors.Is(err, http.ErrServerClosed) {
-		cs.t.Logf("Shutdown failed: %v", err)
+	if cs.checkErr != nil {
+		return cs.checkErr
 	}
 
+	cs.t.Logf("check oracle all done!")
 	return nil
 }
 
-func (cs *caseSpec) getPool(endpoint string) (*dockertest.Pool, error) {
-	p, err := dockertest.NewPool(endpoint)
+func (cs *caseSpec) getRuntime(r *testutils.RemoteInfo) (testutils.ContainerRuntime, error) {
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return nil, err
 	}
-	err = p.Client.Ping()
+	err = rt.Ping()
 	if err != nil {
-		cs.t.Logf("Could not connect to Docker: %v", err)
+		cs.t.Logf("Could not connect to %s: %v", rt.Name(), err)
 		return nil, err
 	}
-	return p, nil
+	return rt, nil
 }
 
 func (cs *caseSpec) getDockerFilePath() (dirName string, fileName string, err error) {
-	tmpDir, err := ioutil.TempDir("", "dockerfiles_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempDir failed: %s", err.Error())
-		return "", "", err
-	}
-
-	tmpFile, err := ioutil.TempFile(tmpDir, "dockerfile_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempFile failed: %s", err.Error())
-		return "", "", err
-	}
-
-	_, err = tmpFile.WriteString(cs.dockerFileText)
-	if err != nil {
-		cs.t.Logf("TempFile.WriteString failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := os.Chmod(tmpFile.Name(), os.ModePerm); err != nil {
-		cs.t.Logf("os.Chmod failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := tmpFile.Close(); err != nil {
-		cs.t.Logf("Close failed: %s", err.Error())
-		return "", "", err
-	}
-
-	return tmpDir, tmpFile.Name(), nil
+	return testutils.WriteDockerfile(cs.dockerFileText, cs.dockerFileVars)
 }
 
 func (cs *caseSpec) getContainterName() string {
@@ -531,11 +413,11 @@ func (cs *caseSpec) getContainterName() string {
 	return name
 }
 
-func (cs *caseSpec) getPortBindings() map[docker.Port][]docker.PortBinding {
-	portBindings := make(map[docker.Port][]docker.PortBinding)
+func (cs *caseSpec) getPortBindings() map[string]string {
+	portBindings := make(map[string]string)
 
 	for _, v := range cs.exposedPorts {
-		portBindings[docker.Port(v)] = []docker.PortBinding{{HostIP: "0.0.0.0", HostPort: docker.Port(v).Port()}}
+		portBindings[v] = docker.Port(v).Port()
 	}
 
 	return portBindings
@@ -588,21 +470,3 @@ func externalIP() (string, error) {
 	}
 	return "", errors.New("are you connected to the network?")
 }
-
-// nolint: deadcode,unused
-// dkpt2point convert old io/point.Point to point.Point.
-func dkpt2point(pts ...*influxdb.Point) (res []*point.Point) {
-	for _, pt := range pts {
-		fs, err := pt.Fields()
-		if err != nil {
-			continue
-		}
-
-		pt := point.NewPointV2([]byte(pt.Name()),
-			append(point.NewTags(pt.Tags()), point.NewKVs(fs)...), nil)
-
-		res = append(res, pt)
-	}
-
-	return res
-}
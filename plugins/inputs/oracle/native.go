@@ -0,0 +1,372 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package oracle
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	go_ora "github.com/sijms/go-ora/v2"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+const (
+	defaultInterval      = time.Second * 10
+	defaultWaitEventsTop = 20
+)
+
+// Queries of built-in mode, GV$ views used to collect all instances of RAC.
+const (
+	processSQL = `
+SELECT i.INSTANCE_NAME, p.PROGRAM, p.PGA_USED_MEM, p.PGA_ALLOC_MEM, p.PGA_FREEABLE_MEM, p.PGA_MAX_MEM
+FROM GV$PROCESS p JOIN GV$INSTANCE i ON i.INST_ID = p.INST_ID`
+
+	systemSQL = `
+SELECT i.INSTANCE_NAME, m.METRIC_NAME, m.VALUE
+FROM GV$SYSMETRIC m JOIN GV$INSTANCE i ON i.INST_ID = m.INST_ID
+ORDER BY m.BEGIN_TIME`
+
+	tablespaceSQL = `
+SELECT
+  m.TABLESPACE_NAME,
+  NVL(m.USED_SPACE * t.BLOCK_SIZE, 0) AS USED_SPACE,
+  m.TABLESPACE_SIZE * t.BLOCK_SIZE AS TS_SIZE,
+  NVL(m.USED_PERCENT, 0) AS IN_USE,
+  NVL2(m.USED_SPACE, 0, 1) AS OFF_USE
+FROM DBA_TABLESPACE_USAGE_METRICS m
+JOIN DBA_TABLESPACES t ON m.TABLESPACE_NAME = t.TABLESPACE_NAME`
+
+	asmSQL = `
+SELECT
+  NAME AS DISK_GROUP, STATE, TYPE,
+  TOTAL_MB * 1048576 AS TOTAL_BYTES,
+  FREE_MB * 1048576 AS FREE_BYTES,
+  USABLE_FILE_MB * 1048576 AS USABLE_FILE_BYTES,
+  ROUND((TOTAL_MB - FREE_MB) * 100 / NULLIF(TOTAL_MB, 0), 2) AS USED_PERCENT,
+  OFFLINE_DISKS
+FROM V$ASM_DISKGROUP`
+
+	// %d: max events of each instance.
+	waitEventSQL = `
+SELECT INSTANCE_NAME, EVENT, WAIT_CLASS, TOTAL_WAITS, TOTAL_TIMEOUTS, TIME_WAITED
+FROM (
+  SELECT i.INSTANCE_NAME, e.EVENT, e.WAIT_CLASS, e.TOTAL_WAITS, e.TOTAL_TIMEOUTS, e.TIME_WAITED_MICRO AS TIME_WAITED,
+    ROW_NUMBER() OVER (PARTITION BY e.INST_ID ORDER BY e.TIME_WAITED_MICRO DESC) AS RN
+  FROM GV$SYSTEM_EVENT e JOIN GV$INSTANCE i ON i.INST_ID = e.INST_ID
+  WHERE e.WAIT_CLASS <> 'Idle'
+) WHERE RN <= %d`
+)
+
+// systemMetrics maps metric names of GV$SYSMETRIC to fields of oracle_system.
+var systemMetrics = map[string]string{
+	"buffer_cache_hit_ratio":       "buffer_cachehit_ratio",
+	"cursor_cache_hit_ratio":       "cursor_cachehit_ratio",
+	"library_cache_hit_ratio":      "library_cachehit_ratio",
+	"shared_pool_free_%":           "shared_pool_free",
+	"physical_read_bytes_per_sec":  "physical_reads",
+	"physical_write_bytes_per_sec": "physical_writes",
+	"enqueue_timeouts_per_sec":     "enqueue_timeouts",
+
+	"gc_cr_block_received_per_second": "gc_cr_block_received",
+	"global_cache_blocks_corrupted":   "cache_blocks_corrupt",
+	"global_cache_blocks_lost":        "cache_blocks_lost",
+	"average_active_sessions":         "active_sessions",
+	"sql_service_response_time":       "service_response_time",
+	"user_rollbacks_per_sec":          "user_rollbacks",
+	"total_sorts_per_user_call":       "sorts_per_user_call",
+	"rows_per_sort":                   "rows_per_sort",
+	"disk_sort_per_sec":               "disk_sorts",
+	"memory_sorts_ratio":              "memory_sorts_ratio",
+	"database_wait_time_ratio":        "database_wait_time_ratio",
+	"session_limit_%":                 "session_limit_usage",
+	"session_count":                   "session_count",
+	"temp_space_used":                 "temp_space_used",
+}
+
+// runNative collects Oracle within DataKit by the pure-Go driver, no
+// external binary or Oracle Instant Client required.
+func (i *Input) runNative() {
+	l.Infof("oracle started in built-in mode, server %s:%d", i.Host, i.Port)
+
+	i.duration = defaultInterval
+	if i.Interval != "" {
+		if du, err := time.ParseDuration(i.Interval); err != nil {
+			l.Warnf("invalid interval %q: %s, use default %s", i.Interval, err, defaultInterval)
+		} else {
+			i.duration = du
+		}
+	}
+
+	tick := time.NewTicker(i.duration)
+	defer tick.Stop()
+
+	for {
+		if i.pause {
+			l.Debugf("not leader, skipped")
+		} else {
+			if i.db == nil {
+				if err := i.connect(); err != nil {
+					l.Errorf("connect: %s", err)
+					i.feeder.FeedLastError(inputName, err.Error())
+				}
+			}
+
+			if i.db != nil {
+				start := time.Now()
+				if pts := i.collect(); len(pts) > 0 {
+					if err := i.feeder.Feed(inputName, point.Metric, pts,
+						&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+						l.Errorf("feed: %s", err)
+					}
+				}
+			}
+		}
+
+		select {
+		case <-datakit.Exit.Wait():
+			i.closeDB()
+			l.Info("oracle exit")
+			return
+
+		case <-i.semStop.Wait():
+			i.closeDB()
+			l.Info("oracle return")
+			return
+
+		case i.pause = <-i.pauseCh:
+
+		case <-tick.C:
+		}
+	}
+}
+
+func (i *Input) connect() error {
+	db, err := sql.Open("oracle", go_ora.BuildUrl(i.Host, i.Port, i.Service, i.User, i.Password, nil))
+	if err != nil {
+		return err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close() //nolint:errcheck,gosec
+		return err
+	}
+
+	i.db = db
+	i.query = i.queryDB
+	return nil
+}
+
+func (i *Input) closeDB() {
+	if i.db == nil {
+		return
+	}
+
+	if err := i.db.Close(); err != nil {
+		l.Warnf("Close: %s", err)
+	}
+	i.db = nil
+}
+
+// queryDB returns rows of the query, column names in lower case.
+func (i *Input) queryDB(query string) ([]map[string]interface{}, error) {
+	rows, err := i.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]interface{}
+	for rows.Next() {
+		vals := make([]interface{}, len(columns))
+		for idx := range vals {
+			vals[idx] = new(interface{})
+		}
+
+		if err := rows.Scan(vals...); err != nil {
+			return nil, err
+		}
+
+		row := map[string]interface{}{}
+		for idx, col := range columns {
+			if v := *(vals[idx].(*interface{})); v != nil {
+				row[strings.ToLower(col)] = v
+			}
+		}
+		res = append(res, row)
+	}
+
+	return res, rows.Err()
+}
+
+// collect queries all measurements, failure of one measurement(such as
+// lack of privilege on the view) does not affect others.
+func (i *Input) collect() []*point.Point {
+	var pts []*point.Point
+
+	collectors := []struct {
+		name    string
+		query   string
+		tagCols []string
+	}{
+		{oracleProcess, processSQL, []string{"instance_name", "program"}},
+		{oracleTablespace, tablespaceSQL, []string{"tablespace_name"}},
+		{oracleASM, asmSQL, []string{"disk_group", "state", "type"}},
+		{oracleWaitEvent, fmt.Sprintf(waitEventSQL, i.waitEventsTop()), []string{"instance_name", "event", "wait_class"}},
+	}
+
+	for _, c := range collectors {
+		rows, err := i.query(c.query)
+		if err != nil {
+			l.Warnf("collect %s: %s", c.name, err)
+			i.feeder.FeedLastError(inputName, fmt.Sprintf("collect %s: %s", c.name, err))
+			continue
+		}
+
+		for _, row := range rows {
+			if pt := i.rowPoint(c.name, row, c.tagCols); pt != nil {
+				pts = append(pts, pt)
+			}
+		}
+	}
+
+	rows, err := i.query(systemSQL)
+	if err != nil {
+		l.Warnf("collect %s: %s", oracleSystem, err)
+		i.feeder.FeedLastError(inputName, fmt.Sprintf("collect %s: %s", oracleSystem, err))
+	} else {
+		pts = append(pts, i.systemPoints(rows)...)
+	}
+
+	return pts
+}
+
+func (i *Input) waitEventsTop() int {
+	if i.WaitEventsTop <= 0 {
+		return defaultWaitEventsTop
+	}
+	return i.WaitEventsTop
+}
+
+// rowPoint builds point of the row, columns in tagCols as tags, others as
+// fields, non-numeric fields dropped.
+func (i *Input) rowPoint(name string, row map[string]interface{}, tagCols []string) *point.Point {
+	tags := i.baseTags()
+	for _, col := range tagCols {
+		if v, ok := row[col]; ok {
+			tags[col] = strings.TrimSpace(fmt.Sprint(v))
+			delete(row, col)
+		}
+	}
+
+	fields := map[string]interface{}{}
+	for col, v := range row {
+		if f, ok := toFloat(v); ok {
+			fields[col] = f
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return i.newPoint(name, tags, fields)
+}
+
+// systemPoints merges metric rows of GV$SYSMETRIC into one point per instance.
+func (i *Input) systemPoints(rows []map[string]interface{}) []*point.Point {
+	var (
+		instances []string
+		fields    = map[string]map[string]interface{}{}
+	)
+
+	for _, row := range rows {
+		inst := fmt.Sprint(row["instance_name"])
+		name := strings.ToLower(strings.ReplaceAll(fmt.Sprint(row["metric_name"]), " ", "_"))
+
+		field, ok := systemMetrics[name]
+		if !ok {
+			continue
+		}
+
+		v, ok := toFloat(row["value"])
+		if !ok {
+			continue
+		}
+
+		if _, ok := fields[inst]; !ok {
+			instances = append(instances, inst)
+			fields[inst] = map[string]interface{}{}
+		}
+		fields[inst][field] = v // later rows are more recent
+	}
+
+	var pts []*point.Point
+	for _, inst := range instances {
+		tags := i.baseTags()
+		tags["instance_name"] = inst
+		pts = append(pts, i.newPoint(oracleSystem, tags, fields[inst]))
+	}
+
+	return pts
+}
+
+func (i *Input) baseTags() map[string]string {
+	tags := map[string]string{
+		"oracle_service": i.Service,
+		"oracle_server":  net.JoinHostPort(i.Host, strconv.Itoa(i.Port)),
+	}
+
+	if i.Host != "localhost" && !net.ParseIP(i.Host).IsLoopback() {
+		tags["host"] = i.Host
+	}
+
+	for k, v := range i.Tags {
+		tags[k] = v
+	}
+
+	return tags
+}
+
+func (i *Input) newPoint(name string, tags map[string]string, fields map[string]interface{}) *point.Point {
+	opts := point.DefaultMetricOptions()
+	if i.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+
+	return point.NewPointV2([]byte(name),
+		append(point.NewTags(tags), point.NewKVs(fields)...),
+		opts...)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package oracle
+
+import (
+	"errors"
+	"strings"
+	T "testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func TestNativeConfig(t *T.T) {
+	var conf struct {
+		Inputs struct {
+			Oracle []*Input `toml:"oracle"`
+		} `toml:"inputs"`
+	}
+	_, err := toml.Decode(configSample, &conf)
+	require.NoError(t, err)
+	require.Len(t, conf.Inputs.Oracle, 1)
+
+	ipt := conf.Inputs.Oracle[0]
+	assert.Empty(t, ipt.Cmd)
+	assert.Equal(t, 1521, ipt.Port)
+	assert.Equal(t, "<oracle-service-name>", ipt.Service)
+	assert.True(t, ipt.Election)
+}
+
+func TestNativeCollect(t *T.T) {
+	ipt := defaultInput()
+	ipt.Host = "10.0.0.1"
+	ipt.Service = "orcl"
+	ipt.Election = false
+	ipt.Tags = map[string]string{"env": "test"}
+
+	feeder := dkio.NewMockedFeeder()
+	ipt.feeder = feeder
+
+	ipt.query = func(q string) ([]map[string]interface{}, error) {
+		switch {
+		case strings.Contains(q, "GV$PROCESS"):
+			return []map[string]interface{}{
+				{"instance_name": "orcl1", "program": "oracle@db1 (PMON)", "pga_used_mem": int64(1024), "pga_max_mem": float64(2048)},
+			}, nil
+		case strings.Contains(q, "DBA_TABLESPACE_USAGE_METRICS"):
+			return []map[string]interface{}{
+				{"tablespace_name": "SYSTEM", "used_space": "8192", "ts_size": int64(16384), "in_use": 50.0, "off_use": int64(0)},
+			}, nil
+		case strings.Contains(q, "V$ASM_DISKGROUP"):
+			return nil, errors.New("ORA-00942: table or view does not exist")
+		case strings.Contains(q, "GV$SYSTEM_EVENT"):
+			assert.Contains(t, q, "RN <= 20")
+			return []map[string]interface{}{
+				{"instance_name": "orcl1", "event": "db file sequential read", "wait_class": "User I/O", "total_waits": int64(10), "time_waited": int64(300)},
+				{"instance_name": "orcl2", "event": "log file sync", "wait_class": "Commit", "total_waits": int64(5), "time_waited": int64(100)},
+			}, nil
+		case strings.Contains(q, "GV$SYSMETRIC"):
+			return []map[string]interface{}{
+				{"instance_name": "orcl1", "metric_name": "Buffer Cache Hit Ratio", "value": 90.0},
+				{"instance_name": "orcl1", "metric_name": "Buffer Cache Hit Ratio", "value": 95.0},
+				{"instance_name": "orcl1", "metric_name": "Unknown Metric", "value": 1.0},
+				{"instance_name": "orcl2", "metric_name": "Session Count", "value": int64(30)},
+			}, nil
+		}
+		return nil, nil
+	}
+
+	pts := ipt.collect()
+	require.Len(t, pts, 6)
+
+	counts := map[string]int{}
+	for _, pt := range pts {
+		counts[string(pt.Name())]++
+
+		tags := pt.InfluxTags()
+		assert.Equal(t, "orcl", tags["oracle_service"])
+		assert.Equal(t, "10.0.0.1:1521", tags["oracle_server"])
+		assert.Equal(t, "10.0.0.1", tags["host"])
+		assert.Equal(t, "test", tags["env"])
+
+		switch string(pt.Name()) {
+		case oracleTablespace:
+			assert.Equal(t, "SYSTEM", tags["tablespace_name"])
+			assert.Equal(t, 8192.0, pt.Get([]byte("used_space")))
+
+		case oracleSystem:
+			if tags["instance_name"] == "orcl1" {
+				assert.Equal(t, 95.0, pt.Get([]byte("buffer_cachehit_ratio")))
+				assert.Len(t, pt.Fields(), 1)
+			} else {
+				assert.Equal(t, 30.0, pt.Get([]byte("session_count")))
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int{oracleProcess: 1, oracleTablespace: 1, oracleWaitEvent: 2, oracleSystem: 2}, counts)
+
+	le := feeder.LastErrors()
+	require.Len(t, le, 1)
+	assert.Contains(t, le[0][1], oracleASM)
+}
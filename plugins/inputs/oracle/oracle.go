@@ -3,52 +3,49 @@
 // This product includes software developed at Guance Cloud (https://www.guance.com/).
 // Copyright 2021-present Guance, Inc.
 
-// Package oracle collect Oracle metrics by wrap a external input.
+// Package oracle collect Oracle metrics by the built-in pure-Go driver, or by
+// wrap a external input if cmd configured.
 package oracle
 
 import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/external"
 )
 
 const (
 	configSample = `
-[[inputs.external]]
-  daemon = true
-  name = 'oracle'
-  cmd  = "/usr/local/datakit/externals/oracle"
+[[inputs.oracle]]
+  ## Oracle server address and listener port
+  host = "<your-oracle-host>"
+  port = 1521
+
+  ## Oracle user name and password
+  user = "<oracle-user-name>"
+  password = "<oracle-password>"
+
+  ## Oracle service name
+  service = "<oracle-service-name>"
+
+  ## Collection interval
+  interval = "1m"
+
+  ## Max wait events collected for each instance, ordered by time waited
+  # wait_events_top = 20
 
   ## Set true to enable election
   election = true
 
-  args = [
-    '--interval'       , '1m'                        ,
-    '--host'           , '<your-oracle-host>'        ,
-    '--port'           , '1521'                      ,
-    '--username'       , '<oracle-user-name>'        ,
-    '--password'       , '<oracle-password>'         ,
-    '--service-name'   , '<oracle-service-name>'     ,
-  ]
-  envs = [
-    'LD_LIBRARY_PATH=/opt/oracle/instantclient_19_8:$LD_LIBRARY_PATH',
-  ]
-
-  [inputs.external.tags]
+  [inputs.oracle.tags]
     # some_tag = "some_value"
     # more_tag = "some_other_value"
-
-  #############################
-  # 参数说明(标 * 为必选项)
-  #############################
-  # *--interval       : 采集的频度，最小粒度5m
-  # *--host           : oracle实例地址(ip)
-  #  --port           : oracle监听端口
-  # *--username       : oracle 用户名
-  # *--password       : oracle 密码
-  # *--service-name   : oracle的服务名
-  # *--query          : 自定义查询语句，格式为<sql:metricName:tags>, sql为自定义采集的语句, tags填入使用tag字段
 `
 )
 
@@ -59,12 +56,73 @@ var (
 )
 
 type Input struct {
+	// Fields of external mode, the input run the external oracle binary if Cmd set.
 	external.ExternalInput
+
+	Host          string `toml:"host"`
+	Port          int    `toml:"port"`
+	User          string `toml:"user"`
+	Password      string `toml:"password"`
+	Service       string `toml:"service"`
+	WaitEventsTop int    `toml:"wait_events_top"`
+
+	db       *sql.DB
+	query    func(string) ([]map[string]interface{}, error)
+	duration time.Duration
+	feeder   dkio.Feeder
+
+	pauseCh chan bool
+	pause   bool
+	semStop *cliutils.Sem
 }
 
 func (i *Input) Run() {
-	l.Info("oracle started...")
-	i.ExternalInput.Run()
+	l = logger.SLogger(inputName)
+
+	if i.Cmd != "" {
+		l.Info("oracle started in external mode...")
+		i.ExternalInput.Run()
+		return
+	}
+
+	i.runNative()
+}
+
+func (i *Input) Pause() error {
+	if i.Cmd != "" {
+		return i.ExternalInput.Pause()
+	}
+
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case i.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (i *Input) Resume() error {
+	if i.Cmd != "" {
+		return i.ExternalInput.Resume()
+	}
+
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case i.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func (i *Input) Terminate() {
+	if i.semStop != nil {
+		i.semStop.Close()
+	}
+	i.ExternalInput.Terminate()
 }
 
 func (i *Input) Catalog() string { return catalogName }
@@ -76,16 +134,22 @@ func (i *Input) SampleMeasurement() []inputs.Measurement {
 		&processMeasurement{},
 		&tablespaceMeasurement{},
 		&systemMeasurement{},
+		&asmMeasurement{},
+		&waitEventMeasurement{},
 	}
 }
 
 func (i *Input) AvailableArchs() []string {
-	return []string{datakit.OSLabelLinux, datakit.LabelElection}
+	return datakit.AllOSWithElection
 }
 
 func defaultInput() *Input {
 	return &Input{
 		ExternalInput: *external.NewExternalInput(),
+		Port:          1521,
+		feeder:        dkio.DefaultFeeder(),
+		pauseCh:       make(chan bool, inputs.ElectionPauseChannelLength),
+		semStop:       cliutils.NewSem(),
 	}
 }
 
@@ -17,6 +17,8 @@ const (
 	oracleProcess    = "oracle_process"
 	oracleTablespace = "oracle_tablespace"
 	oracleSystem     = "oracle_system"
+	oracleASM        = "oracle_asm_diskgroup"
+	oracleWaitEvent  = "oracle_wait_event"
 )
 
 type processMeasurement struct {
@@ -86,6 +88,9 @@ func (m *processMeasurement) Info() *inputs.MeasurementInfo {
 			"program": &inputs.TagInfo{
 				Desc: "Program",
 			},
+			"instance_name": &inputs.TagInfo{
+				Desc: "Instance name, only collected in built-in mode, distinguish instances of RAC",
+			},
 			"host": &inputs.TagInfo{
 				Desc: "host",
 			},
@@ -334,6 +339,129 @@ func (m *systemMeasurement) Info() *inputs.MeasurementInfo {
 			"oracle_service": &inputs.TagInfo{
 				Desc: "Server service",
 			},
+			"instance_name": &inputs.TagInfo{
+				Desc: "Instance name, only collected in built-in mode, distinguish instances of RAC",
+			},
+		},
+	}
+}
+
+type asmMeasurement struct{}
+
+// Point implement MeasurementV2.
+func (m *asmMeasurement) Point() *point.Point { return nil }
+
+func (m *asmMeasurement) LineProto() (*dkpt.Point, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+// 指定指标.
+func (m *asmMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: oracleASM,
+		Desc: "ASM disk groups, only collected in built-in mode.",
+		Fields: map[string]interface{}{
+			"total_bytes": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.SizeByte,
+				Desc:     "Total capacity of the disk group",
+			},
+			"free_bytes": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.SizeByte,
+				Desc:     "Unused capacity of the disk group",
+			},
+			"usable_file_bytes": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.SizeByte,
+				Desc:     "Free space safely usable for files, taking mirroring into account",
+			},
+			"used_percent": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.Percent,
+				Desc:     "Used capacity percentage of the disk group",
+			},
+			"offline_disks": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Gauge,
+				Unit:     inputs.NCount,
+				Desc:     "Number of offline disks in the disk group",
+			},
+		},
+		Tags: map[string]interface{}{
+			"oracle_server": &inputs.TagInfo{
+				Desc: "Server addr",
+			},
+			"oracle_service": &inputs.TagInfo{
+				Desc: "Server service",
+			},
+			"disk_group": &inputs.TagInfo{
+				Desc: "Disk group name",
+			},
+			"state": &inputs.TagInfo{
+				Desc: "State of the disk group, such as `MOUNTED`",
+			},
+			"type": &inputs.TagInfo{
+				Desc: "Redundancy type of the disk group, such as `NORMAL`/`HIGH`/`EXTERN`",
+			},
+		},
+	}
+}
+
+type waitEventMeasurement struct{}
+
+// Point implement MeasurementV2.
+func (m *waitEventMeasurement) Point() *point.Point { return nil }
+
+func (m *waitEventMeasurement) LineProto() (*dkpt.Point, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+// 指定指标.
+func (m *waitEventMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: oracleWaitEvent,
+		Desc: "Top non-idle wait events of each instance ordered by time waited, only collected in built-in mode.",
+		Fields: map[string]interface{}{
+			"total_waits": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Count,
+				Unit:     inputs.NCount,
+				Desc:     "Total number of waits for the event since instance startup",
+			},
+			"total_timeouts": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Count,
+				Unit:     inputs.NCount,
+				Desc:     "Total number of timeouts for the event since instance startup",
+			},
+			"time_waited": &inputs.FieldInfo{
+				DataType: inputs.Float,
+				Type:     inputs.Count,
+				Unit:     inputs.DurationUS,
+				Desc:     "Total time waited for the event since instance startup",
+			},
+		},
+		Tags: map[string]interface{}{
+			"oracle_server": &inputs.TagInfo{
+				Desc: "Server addr",
+			},
+			"oracle_service": &inputs.TagInfo{
+				Desc: "Server service",
+			},
+			"instance_name": &inputs.TagInfo{
+				Desc: "Instance name, distinguish instances of RAC",
+			},
+			"event": &inputs.TagInfo{
+				Desc: "Name of the wait event",
+			},
+			"wait_class": &inputs.TagInfo{
+				Desc: "Class of the wait event, such as `User I/O`",
+			},
 		},
 	}
 }
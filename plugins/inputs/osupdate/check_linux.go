@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build linux
+// +build linux
+
+package osupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+type checkFunc func(ctx context.Context, cacheOnly bool) (*updateStatus, error)
+
+var (
+	// package managers in the order of detection, apt goes first for
+	// rpm/yum may also be installed on debian based distribution.
+	managers = []string{"apt", "dnf", "yum", "zypper"}
+
+	checkers = map[string]checkFunc{
+		"apt": checkApt,
+		"dnf": func(ctx context.Context, cacheOnly bool) (*updateStatus, error) {
+			return checkYum(ctx, "dnf", cacheOnly)
+		},
+		"yum": func(ctx context.Context, cacheOnly bool) (*updateStatus, error) {
+			return checkYum(ctx, "yum", cacheOnly)
+		},
+		"zypper": checkZypper,
+	}
+
+	binaries = map[string]string{
+		"apt":    "apt-get",
+		"dnf":    "dnf",
+		"yum":    "yum",
+		"zypper": "zypper",
+	}
+)
+
+func (ipt *Input) check(ctx context.Context) (*updateStatus, error) {
+	name := ipt.PackageManager
+	if name == "" {
+		for _, m := range managers {
+			if _, err := exec.LookPath(binaries[m]); err == nil {
+				name = m
+				break
+			}
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("no supported package manager(%v) found", managers)
+		}
+	}
+
+	fn, ok := checkers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported package manager %q, should be one of %v", name, managers)
+	}
+
+	s, err := fn(ctx, ipt.CacheOnly)
+	if err != nil {
+		return nil, fmt.Errorf("check %s: %w", name, err)
+	}
+
+	s.manager = name
+	return s, nil
+}
+
+// run runs the command and returns its stdout and exit code, non-zero exit
+// code is not an error, for some package managers exit with it to mark
+// updates available.
+func run(ctx context.Context, name string, args ...string) ([]byte, int, error) {
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), "LANG=C", "LC_ALL=C")
+
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, -1, fmt.Errorf("%s: %w", name, ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return out, 0, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func checkApt(ctx context.Context, _ bool) (*updateStatus, error) {
+	// simulation reads the local package lists only
+	out, code, err := run(ctx, "apt-get", "-s", "-o", "Debug::NoLocking=true", "dist-upgrade")
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("apt-get exit with code %d", code)
+	}
+
+	s := &updateStatus{
+		rebootRequired: fileExists("/var/run/reboot-required"),
+	}
+	s.pending, s.security = parseAptSimulate(out)
+
+	if fi, err := os.Stat("/var/lib/dpkg/status"); err == nil {
+		s.lastPatch = fi.ModTime()
+	}
+
+	return s, nil
+}
+
+func checkYum(ctx context.Context, bin string, cacheOnly bool) (*updateStatus, error) {
+	args := []string{"-q"}
+	if cacheOnly {
+		args = append(args, "-C")
+	}
+
+	// exit code 100 means updates available
+	out, code, err := run(ctx, bin, append(args, "check-update")...)
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 && code != 100 {
+		return nil, fmt.Errorf("%s check-update exit with code %d", bin, code)
+	}
+
+	s := &updateStatus{
+		pending: parseYumCheckUpdate(out),
+	}
+
+	if out, code, err = run(ctx, bin, append(args, "updateinfo", "list", "security")...); err != nil {
+		return nil, err
+	} else if code != 0 {
+		l.Warnf("%s updateinfo exit with code %d, security updates unknown", bin, code)
+	} else {
+		s.security = parseYumSecurity(out)
+	}
+
+	// needs-restarting -r exits with 1 if reboot required
+	restart := []string{"needs-restarting", "-r"}
+	if bin == "dnf" {
+		restart = append([]string{"dnf", "-q"}, restart...)
+	}
+
+	if _, err := exec.LookPath(restart[0]); err == nil {
+		if _, code, err := run(ctx, restart[0], restart[1:]...); err == nil {
+			s.rebootRequired = code == 1
+		}
+	}
+
+	if out, _, err := run(ctx, "rpm", "-qa", "--qf", "%{INSTALLTIME}\n"); err == nil {
+		s.lastPatch = parseInstallTimes(out)
+	}
+
+	return s, nil
+}
+
+func checkZypper(ctx context.Context, cacheOnly bool) (*updateStatus, error) {
+	args := []string{"-n", "--xmlout"}
+	if cacheOnly {
+		args = append(args, "--no-refresh")
+	}
+
+	// exit codes 100~103 are informational, like updates or reboot needed
+	validCode := func(code int) bool { return code == 0 || (code >= 100 && code <= 103) }
+
+	out, code, err := run(ctx, "zypper", append(args, "list-updates")...)
+	if err != nil {
+		return nil, err
+	}
+	if !validCode(code) {
+		return nil, fmt.Errorf("zypper list-updates exit with code %d", code)
+	}
+
+	s := &updateStatus{}
+	if s.pending, err = parseZypperUpdates(out); err != nil {
+		return nil, fmt.Errorf("parse zypper list-updates: %w", err)
+	}
+
+	if out, code, err = run(ctx, "zypper", append(args, "list-patches", "--category", "security")...); err != nil {
+		return nil, err
+	} else if !validCode(code) {
+		l.Warnf("zypper list-patches exit with code %d, security updates unknown", code)
+	} else if s.security, err = parseZypperSecurityPatches(out); err != nil {
+		l.Warnf("parse zypper list-patches: %s, security updates unknown", err)
+	}
+
+	// needs-rebooting exits with 102 if reboot required
+	s.rebootRequired = fileExists("/run/reboot-needed")
+	if !s.rebootRequired {
+		if _, code, err := run(ctx, "zypper", "needs-rebooting"); err == nil {
+			s.rebootRequired = code == 102
+		}
+	}
+
+	if out, _, err := run(ctx, "rpm", "-qa", "--qf", "%{INSTALLTIME}\n"); err == nil {
+		s.lastPatch = parseInstallTimes(out)
+	}
+
+	return s, nil
+}
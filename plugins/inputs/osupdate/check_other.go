@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package osupdate
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func (ipt *Input) check(_ context.Context) (*updateStatus, error) {
+	return nil, fmt.Errorf("os update check not supported on %s", runtime.GOOS)
+}
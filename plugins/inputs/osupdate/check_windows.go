@@ -0,0 +1,236 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+//go:build windows
+// +build windows
+
+package osupdate
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+const (
+	managerWUA = "wua"
+
+	// search criteria of updates not installed yet
+	wuaCriteria = "IsInstalled=0 and Type='Software' and IsHidden=0"
+
+	// category ID of "Security Updates", the name is localized
+	securityCategoryID = "0fa1201d-4330-4fa8-8ae9-b877473b6441"
+
+	sFalse = 0x00000001
+)
+
+// check queries Windows Update Agent. The search can not be canceled,
+// on timeout it's left running in background and the result dropped.
+func (ipt *Input) check(ctx context.Context) (*updateStatus, error) {
+	type result struct {
+		s   *updateStatus
+		err error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		s, err := checkWUA()
+		ch <- result{s, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.s, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("search windows update: %w", ctx.Err())
+	}
+}
+
+func checkWUA() (*updateStatus, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		oleCode := err.(*ole.OleError).Code() //nolint:errorlint
+		if oleCode != ole.S_OK && oleCode != sFalse {
+			return nil, err
+		}
+	}
+	defer ole.CoUninitialize()
+
+	s := &updateStatus{manager: managerWUA}
+
+	if err := searchUpdates(s); err != nil {
+		return nil, err
+	}
+
+	if v, err := objectProperty("Microsoft.Update.SystemInfo", "RebootRequired"); err != nil {
+		l.Warnf("get RebootRequired: %s, ignored", err)
+	} else if b, ok := v.(bool); ok {
+		s.rebootRequired = b
+	}
+
+	if t, err := lastInstallation(); err != nil {
+		l.Warnf("get LastInstallationSuccessDate: %s, ignored", err)
+	} else {
+		s.lastPatch = t
+	}
+
+	return s, nil
+}
+
+func createObject(progID string) (*ole.IDispatch, error) {
+	unknown, err := oleutil.CreateObject(progID)
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", progID, err)
+	}
+	defer unknown.Release()
+
+	return unknown.QueryInterface(ole.IID_IDispatch)
+}
+
+func objectProperty(progID, name string) (interface{}, error) {
+	obj, err := createObject(progID)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Release()
+
+	v, err := oleutil.GetProperty(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	defer v.Clear() //nolint:errcheck
+
+	return v.Value(), nil
+}
+
+func lastInstallation() (time.Time, error) {
+	au, err := createObject("Microsoft.Update.AutoUpdate")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer au.Release()
+
+	rv, err := oleutil.GetProperty(au, "Results")
+	if err != nil {
+		return time.Time{}, err
+	}
+	results := rv.ToIDispatch()
+	defer results.Release()
+
+	v, err := oleutil.GetProperty(results, "LastInstallationSuccessDate")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer v.Clear() //nolint:errcheck
+
+	if t, ok := v.Value().(time.Time); ok {
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+func searchUpdates(s *updateStatus) error {
+	session, err := createObject("Microsoft.Update.Session")
+	if err != nil {
+		return err
+	}
+	defer session.Release()
+
+	sv, err := oleutil.CallMethod(session, "CreateUpdateSearcher")
+	if err != nil {
+		return fmt.Errorf("CreateUpdateSearcher: %w", err)
+	}
+	searcher := sv.ToIDispatch()
+	defer searcher.Release()
+
+	rv, err := oleutil.CallMethod(searcher, "Search", wuaCriteria)
+	if err != nil {
+		return fmt.Errorf("search %q: %w", wuaCriteria, err)
+	}
+	result := rv.ToIDispatch()
+	defer result.Release()
+
+	uv, err := oleutil.GetProperty(result, "Updates")
+	if err != nil {
+		return err
+	}
+	updates := uv.ToIDispatch()
+	defer updates.Release()
+
+	cv, err := oleutil.GetProperty(updates, "Count")
+	if err != nil {
+		return err
+	}
+
+	count := int(cv.Val)
+	s.pending = count
+
+	for i := 0; i < count; i++ {
+		iv, err := oleutil.GetProperty(updates, "Item", i)
+		if err != nil {
+			return err
+		}
+
+		item := iv.ToIDispatch()
+		if isSecurityUpdate(item) {
+			s.security++
+		}
+		item.Release()
+	}
+
+	return nil
+}
+
+// isSecurityUpdate checks if the update in category Security Updates, or
+// rated by MSRC, which is only for security updates.
+func isSecurityUpdate(item *ole.IDispatch) bool {
+	if v, err := oleutil.GetProperty(item, "MsrcSeverity"); err == nil {
+		severity := v.ToString()
+		v.Clear() //nolint:errcheck,gosec
+		if severity != "" {
+			return true
+		}
+	}
+
+	cv, err := oleutil.GetProperty(item, "Categories")
+	if err != nil {
+		return false
+	}
+	categories := cv.ToIDispatch()
+	defer categories.Release()
+
+	nv, err := oleutil.GetProperty(categories, "Count")
+	if err != nil {
+		return false
+	}
+
+	for i := 0; i < int(nv.Val); i++ {
+		iv, err := oleutil.GetProperty(categories, "Item", i)
+		if err != nil {
+			return false
+		}
+
+		category := iv.ToIDispatch()
+		idv, err := oleutil.GetProperty(category, "CategoryID")
+		category.Release()
+		if err != nil {
+			continue
+		}
+
+		id := idv.ToString()
+		idv.Clear() //nolint:errcheck,gosec
+		if id == securityCategoryID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package osupdate collects pending updates and patch status of the operating system.
+package osupdate
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/hostobject"
+)
+
+var (
+	_ inputs.ReadEnv   = (*Input)(nil)
+	_ inputs.Singleton = (*Input)(nil)
+)
+
+const (
+	minInterval = 10 * time.Minute
+	maxInterval = time.Hour * 24
+
+	defaultTimeout = 5 * time.Minute
+)
+
+const (
+	inputName  = "osupdate"
+	metricName = "os_update"
+	sampleCfg  = `
+[[inputs.osupdate]]
+  ## Interval between two checks, default is 1 hour, at least 10 minutes.
+  interval = "1h"
+
+  ## Timeout of a single check, the package manager may refresh its
+  ## repository metadata during the check.
+  timeout = "5m"
+
+  ## Package manager used on Linux: apt/dnf/yum/zypper, auto detected if empty.
+  ## It's ignored on Windows, where Windows Update Agent is used.
+  # package_manager = ""
+
+  ## Only check with local cached repository metadata, no refresh from the
+  ## network. The result may be out of date if the cache not refreshed by others.
+  cache_only = false
+
+[inputs.osupdate.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type Input struct {
+	Interval       datakit.Duration  `toml:"interval"`
+	Timeout        datakit.Duration  `toml:"timeout"`
+	PackageManager string            `toml:"package_manager"`
+	CacheOnly      bool              `toml:"cache_only"`
+	Tags           map[string]string `toml:"tags"`
+
+	semStop *cliutils.Sem
+}
+
+func (ipt *Input) Singleton() {}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	l.Info("osupdate input started")
+
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+	if ipt.Timeout.Duration <= 0 {
+		ipt.Timeout.Duration = defaultTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-datakit.Exit.Wait():
+		case <-ipt.semStop.Wait():
+		}
+		cancel()
+	}()
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		ipt.collect(ctx)
+
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			hostobject.SetExtraFields(inputName, nil)
+			l.Info("osupdate input exit")
+			return
+		}
+	}
+}
+
+func (ipt *Input) collect(ctx context.Context) {
+	start := time.Now()
+
+	tctx, cancel := context.WithTimeout(ctx, ipt.Timeout.Duration)
+	defer cancel()
+
+	s, err := ipt.check(tctx)
+	if err != nil {
+		if ctx.Err() != nil { // exiting
+			return
+		}
+
+		l.Errorf("check: %s", err)
+		io.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	hostobject.SetExtraFields(inputName, s.hostFields())
+
+	m := &updateMeasurement{
+		name:   metricName,
+		tags:   ipt.mergeTags(s.tags()),
+		fields: s.fields(),
+		ts:     start,
+	}
+
+	if err := inputs.FeedMeasurement(metricName, datakit.Metric, []inputs.Measurement{m},
+		&io.Option{CollectCost: time.Since(start)}); err != nil {
+		l.Errorf("FeedMeasurement: %s", err)
+	}
+}
+
+func (ipt *Input) mergeTags(tags map[string]string) map[string]string {
+	for k, v := range ipt.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (*Input) Catalog() string { return "host" }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string {
+	return []string{datakit.OSLabelLinux, datakit.OSLabelWindows}
+}
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{
+		&updateMeasurement{},
+	}
+}
+
+// ReadEnv support envs：only for K8S.
+func (ipt *Input) ReadEnv(envs map[string]string) {
+	// ENV_INPUT_OSUPDATE_INTERVAL : datakit.Duration
+	if str, ok := envs["ENV_INPUT_OSUPDATE_INTERVAL"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_OSUPDATE_INTERVAL to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, da)
+		}
+	}
+
+	// ENV_INPUT_OSUPDATE_TIMEOUT : datakit.Duration
+	if str, ok := envs["ENV_INPUT_OSUPDATE_TIMEOUT"]; ok {
+		da, err := time.ParseDuration(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_OSUPDATE_TIMEOUT to time.Duration: %s, ignore", err)
+		} else {
+			ipt.Timeout.Duration = da
+		}
+	}
+
+	// ENV_INPUT_OSUPDATE_PACKAGE_MANAGER : string
+	if str, ok := envs["ENV_INPUT_OSUPDATE_PACKAGE_MANAGER"]; ok {
+		ipt.PackageManager = str
+	}
+
+	// ENV_INPUT_OSUPDATE_CACHE_ONLY : bool
+	if str, ok := envs["ENV_INPUT_OSUPDATE_CACHE_ONLY"]; ok {
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			l.Warnf("parse ENV_INPUT_OSUPDATE_CACHE_ONLY: %s, ignore", err)
+		} else {
+			ipt.CacheOnly = b
+		}
+	}
+
+	// ENV_INPUT_OSUPDATE_TAGS : "a=b,c=d"
+	if tagsStr, ok := envs["ENV_INPUT_OSUPDATE_TAGS"]; ok {
+		tags := config.ParseGlobalTags(tagsStr)
+		for k, v := range tags {
+			ipt.Tags[k] = v
+		}
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Interval: datakit.Duration{Duration: time.Hour},
+		Timeout:  datakit.Duration{Duration: defaultTimeout},
+		Tags:     map[string]string{},
+		semStop:  cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
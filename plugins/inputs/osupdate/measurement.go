@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package osupdate
+
+import (
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+type updateMeasurement struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	ts     time.Time
+}
+
+func (m *updateMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.Metric, Time: m.ts})
+}
+
+//nolint:lll
+func (m *updateMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: metricName,
+		Type: "metric",
+		Desc: "Pending updates and patch status of the operating system.",
+		Tags: map[string]interface{}{
+			"host":            &inputs.TagInfo{Desc: "Hostname"},
+			"package_manager": &inputs.TagInfo{Desc: "Package manager checked, one of `apt/dnf/yum/zypper/wua`, `wua` is the Windows Update Agent"},
+		},
+		Fields: map[string]interface{}{
+			"pending_updates":          &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Number of packages(updates on Windows) to be updated"},
+			"pending_security_updates": &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.NCount, Desc: "Number of packages(updates on Windows) to be updated for security fixes"},
+			"reboot_required":          &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Bool, Unit: inputs.UnknownUnit, Desc: "Whether reboot required to finish the installed updates"},
+			"last_patch_time":          &inputs.FieldInfo{Type: inputs.Gauge, DataType: inputs.Int, Unit: inputs.TimestampSec, Desc: "Time of the last package installed or updated(last successful installation on Windows), absent if unknown"},
+		},
+	}
+}
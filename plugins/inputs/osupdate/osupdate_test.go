@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package osupdate
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseApt(t *T.T) {
+	out := `NOTE: This is only a simulation!
+Reading package lists...
+Inst openssl [3.0.2-0ubuntu1.9] (3.0.2-0ubuntu1.10 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+Inst libc6 [2.31-13+deb11u5] (2.31-13+deb11u6 Debian-Security:11/stable-security [amd64])
+Inst vim [2:8.2.3995-1ubuntu2] (2:8.2.3995-1ubuntu2.1 Ubuntu:22.04/jammy-updates [amd64])
+Inst security-tool (1.0 Ubuntu:22.04/jammy-updates [amd64])
+Conf openssl (3.0.2-0ubuntu1.10 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+`
+	pending, security := parseAptSimulate([]byte(out))
+	assert.Equal(t, 4, pending)
+	assert.Equal(t, 2, security)
+}
+
+func TestParseYum(t *T.T) {
+	t.Run("check-update", func(t *T.T) {
+		out := `
+kernel.x86_64                       4.18.0-425.13.1.el8_7          baseos
+openssl.x86_64                      1:1.1.1k-9.el8_7               baseos
+python3-some-very-long-package-name.noarch
+                                    1.2.3-4.el8                    appstream
+Obsoleting Packages
+grub2-tools.x86_64                  1:2.02-142.el8_7.1             baseos
+    grub2-tools.x86_64              1:2.02-123.el8                 @anaconda
+`
+		assert.Equal(t, 3, parseYumCheckUpdate([]byte(out)))
+		assert.Equal(t, 0, parseYumCheckUpdate(nil))
+	})
+
+	t.Run("security", func(t *T.T) {
+		out := `RHSA-2023:0832 Important/Sec. kernel-4.18.0-425.13.1.el8_7.x86_64
+RHSA-2023:0833 Moderate/Sec.  kernel-4.18.0-425.13.1.el8_7.x86_64
+RHSA-2023:0402 Important/Sec. openssl-1:1.1.1k-9.el8_7.x86_64
+RHBA-2023:0100 bugfix         vim-enhanced-2:8.0.1763-19.el8_6.4.x86_64
+`
+		assert.Equal(t, 2, parseYumSecurity([]byte(out)))
+	})
+
+	t.Run("install-time", func(t *T.T) {
+		assert.Equal(t, time.Unix(1680000000, 0), parseInstallTimes([]byte("1670000000\n1680000000\n(none)\n1600000000\n")))
+		assert.True(t, parseInstallTimes(nil).IsZero())
+	})
+}
+
+func TestParseZypper(t *T.T) {
+	updates := `<?xml version='1.0'?>
+<stream>
+<update-status version="0.6">
+<update-list>
+<update kind="package" name="openssl" edition="1.1.1l-150400.7.25.1" arch="x86_64" edition-old="1.1.1l-150400.7.22.1"><summary/></update>
+<update kind="package" name="vim" edition="9.0.1386-150000.5.37.1" arch="x86_64"><summary/></update>
+</update-list>
+</update-status>
+</stream>`
+
+	n, err := parseZypperUpdates([]byte(updates))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	patches := `<?xml version='1.0'?>
+<stream>
+<update-status version="0.6">
+<update-list>
+<update kind="patch" name="SUSE-2023-1" category="security" severity="important" status="needed"/>
+<update kind="patch" name="SUSE-2023-2" category="security" severity="moderate" status="applied"/>
+<update kind="patch" name="SUSE-2023-3" category="recommended" status="needed"/>
+</update-list>
+</update-status>
+</stream>`
+
+	n, err = parseZypperSecurityPatches([]byte(patches))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = parseZypperUpdates([]byte("not xml"))
+	assert.Error(t, err)
+}
+
+func TestStatusFields(t *T.T) {
+	s := &updateStatus{manager: "apt", pending: 3, security: 1, rebootRequired: true}
+
+	assert.Equal(t, map[string]string{"package_manager": "apt"}, s.tags())
+	assert.NotContains(t, s.fields(), "last_patch_time")
+
+	s.lastPatch = time.Unix(1680000000, 0)
+	f := s.hostFields()
+	assert.Equal(t, 3, f["os_pending_updates"])
+	assert.Equal(t, 1, f["os_pending_security_updates"])
+	assert.Equal(t, true, f["os_reboot_required"])
+	assert.Equal(t, int64(1680000000), f["os_last_patch_time"])
+}
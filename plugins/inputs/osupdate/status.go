@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package osupdate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type updateStatus struct {
+	manager        string
+	pending        int
+	security       int
+	rebootRequired bool
+	lastPatch      time.Time
+}
+
+func (s *updateStatus) tags() map[string]string {
+	return map[string]string{
+		"package_manager": s.manager,
+	}
+}
+
+func (s *updateStatus) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"pending_updates":          s.pending,
+		"pending_security_updates": s.security,
+		"reboot_required":          s.rebootRequired,
+	}
+	if !s.lastPatch.IsZero() {
+		fields["last_patch_time"] = s.lastPatch.Unix()
+	}
+	return fields
+}
+
+// hostFields are fields appended to the host object.
+func (s *updateStatus) hostFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	for k, v := range s.fields() {
+		fields["os_"+k] = v
+	}
+	return fields
+}
+
+// parseAptSimulate counts packages to be installed in output of
+// `apt-get -s dist-upgrade`, like:
+//
+//	Inst openssl [3.0.2-0ubuntu1.9] (3.0.2-0ubuntu1.10 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+//
+// packages from any *-security suite are security updates.
+func parseAptSimulate(out []byte) (pending, security int) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+
+		pending++
+		if idx := strings.Index(line, "("); idx > 0 &&
+			strings.Contains(strings.ToLower(line[idx:]), "security") {
+			security++
+		}
+	}
+	return
+}
+
+// parseYumCheckUpdate counts packages in output of `yum/dnf -q check-update`,
+// in which each package is a line of `name.arch version repo`, and long
+// names wrap the version and repo to the next line. Obsoleting packages
+// listed at the end are not counted.
+func parseYumCheckUpdate(out []byte) int {
+	n := 0
+	wrapped := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && strings.Contains(fields[0], "."):
+			n++
+			wrapped = false
+		case len(fields) == 1 && strings.Contains(fields[0], ".") && !strings.HasPrefix(line, " "):
+			wrapped = true
+		case len(fields) == 2 && wrapped:
+			n++
+			wrapped = false
+		default:
+			wrapped = false
+		}
+	}
+	return n
+}
+
+// parseYumSecurity counts packages in output of `yum/dnf updateinfo list
+// security`, like:
+//
+//	RHSA-2023:0832 Important/Sec. kernel-4.18.0-425.13.1.el8_7.x86_64
+//
+// a package fixed by multiple advisories counted once.
+func parseYumSecurity(out []byte) int {
+	pkgs := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.Contains(strings.ToLower(fields[1]), "sec") {
+			continue
+		}
+		pkgs[fields[2]] = true
+	}
+	return len(pkgs)
+}
+
+type zypperStream struct {
+	Updates []struct {
+		Kind     string `xml:"kind,attr"`
+		Name     string `xml:"name,attr"`
+		Category string `xml:"category,attr"`
+		Status   string `xml:"status,attr"`
+	} `xml:"update-status>update-list>update"`
+}
+
+// parseZypperUpdates counts packages in output of
+// `zypper --xmlout list-updates`.
+func parseZypperUpdates(out []byte) (int, error) {
+	var s zypperStream
+	if err := xml.Unmarshal(out, &s); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, u := range s.Updates {
+		if u.Kind == "" || u.Kind == "package" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// parseZypperSecurityPatches counts needed security patches in output of
+// `zypper --xmlout list-patches --category security`.
+func parseZypperSecurityPatches(out []byte) (int, error) {
+	var s zypperStream
+	if err := xml.Unmarshal(out, &s); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, u := range s.Updates {
+		if u.Kind == "patch" && u.Category == "security" && (u.Status == "" || u.Status == "needed") {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// parseInstallTimes returns the latest time in output of
+// `rpm -qa --qf '%{INSTALLTIME}\n'`, each line is a unix timestamp.
+func parseInstallTimes(out []byte) time.Time {
+	var latest int64
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		ts, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err != nil {
+			continue
+		}
+		if ts > latest {
+			latest = ts
+		}
+	}
+
+	if latest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(latest, 0)
+}
@@ -56,6 +56,12 @@ const sampleConfig = `
   # 单位 "ns", "us" (or "µs"), "ms", "s", "m", "h"
   interval = "10s"
 
+  ## 采集主从复制指标（pg_stat_replication、复制槽保留的 WAL 及逻辑解码延迟），需 PostgreSQL 10 及以上版本
+  # replication = true
+
+  ## 采集 pg_stat_statements 中总执行时间最长的 N 条查询，需安装 pg_stat_statements 插件，0 表示不采集
+  # statements_top = 0
+
   ## Set true to enable election
   election = true
 
@@ -121,6 +127,8 @@ type Input struct {
 	Interval         string            `toml:"interval"`
 	Tags             map[string]string `toml:"tags"`
 	Log              *postgresqllog    `toml:"log"`
+	Replication      bool              `toml:"replication"`
+	StatementsTop    int               `toml:"statements_top"`
 
 	MaxLifetimeDeprecated string `toml:"max_lifetime,omitempty"`
 
@@ -128,7 +136,9 @@ type Input struct {
 	tail         *tailer.Tailer
 	duration     time.Duration
 	collectCache []inputs.Measurement
-	host         string
+	// top queries of pg_stat_statements, fed as logging
+	statementCache []inputs.Measurement
+	host           string
 
 	Election bool `toml:"election"`
 	pause    bool
@@ -184,6 +194,9 @@ func (*Input) AvailableArchs() []string {
 func (*Input) SampleMeasurement() []inputs.Measurement {
 	return []inputs.Measurement{
 		&inputMeasurement{},
+		&replicationMeasurement{},
+		&replicationSlotMeasurement{},
+		&statementMeasurement{},
 	}
 }
 
@@ -343,7 +356,26 @@ func (ipt *Input) Collect() error {
 		return err
 	})
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if !ipt.Replication && ipt.StatementsTop <= 0 {
+		return nil
+	}
+
+	version, err := ipt.serverVersion()
+	if err != nil {
+		return err
+	}
+
+	if ipt.Replication {
+		if err := ipt.getReplicationMetrics(version); err != nil {
+			return err
+		}
+	}
+
+	return ipt.getStatementMetrics(version)
 }
 
 func (ipt *Input) accRow(columnMap map[string]*interface{}) error {
@@ -487,6 +519,16 @@ func (ipt *Input) Run() {
 				ipt.collectCache = ipt.collectCache[:0]
 			}
 
+			if len(ipt.statementCache) > 0 {
+				err := inputs.FeedMeasurement(inputName, datakit.Logging, ipt.statementCache,
+					&io.Option{CollectCost: time.Since(start)})
+				if err != nil {
+					io.FeedLastError(inputName, err.Error())
+					l.Error(err.Error())
+				}
+				ipt.statementCache = ipt.statementCache[:0]
+			}
+
 		case ipt.pause = <-ipt.pauseCh:
 			// nil
 		}
@@ -410,3 +410,75 @@ func TestInput_setHostIfNotLoopback(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicationMetrics(t *testing.T) {
+	t.Run("slots", func(t *testing.T) {
+		input := &Input{Address: "host=localhost", Tags: map[string]string{}}
+		input.service = &MockCollectService{
+			mockData: getMockData(map[string]interface{}{
+				"slot_name":                 []uint8("sub1"),
+				"slot_type":                 []uint8("logical"),
+				"plugin":                    []uint8("pgoutput"),
+				"database":                  []uint8("app"),
+				"active":                    true,
+				"retained_wal_bytes":        []uint8("1024"),
+				"confirmed_flush_lag_bytes": []uint8("512"),
+			}),
+		}
+
+		rows, err := input.queryRows(replicationSlotQuery)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 1)
+
+		m := input.rowMeasurement("postgresql_replication_slot", rows[0],
+			[]string{"slot_name", "slot_type", "plugin", "database"}, map[string]string{"database": "db"})
+		assert.Equal(t, "sub1", m.tags["slot_name"])
+		assert.Equal(t, "app", m.tags["db"])
+		assert.Equal(t, 1024.0, m.fields["retained_wal_bytes"])
+		assert.Equal(t, 512.0, m.fields["confirmed_flush_lag_bytes"])
+		assert.NotContains(t, m.fields, "slot_name")
+	})
+
+	t.Run("version", func(t *testing.T) {
+		input := &Input{Replication: true, Tags: map[string]string{}}
+		input.service = &MockCollectService{
+			mockData: getMockData(map[string]interface{}{"server_version_num": []uint8("90600")}),
+		}
+
+		v, err := input.serverVersion()
+		assert.NoError(t, err)
+		assert.Equal(t, 90600, v)
+
+		// skipped before PostgreSQL 10
+		assert.NoError(t, input.getReplicationMetrics(v))
+		assert.Empty(t, input.collectCache)
+	})
+
+	t.Run("statements", func(t *testing.T) {
+		input := &Input{StatementsTop: 10, Tags: map[string]string{}}
+		input.service = &MockCollectService{
+			mockData: getMockData(map[string]interface{}{
+				"queryid":    int64(-123),
+				"datname":    []uint8("app"),
+				"rolname":    []uint8("postgres"),
+				"message":    []uint8("SELECT 1"),
+				"calls":      int64(10),
+				"total_time": 12.5,
+			}),
+		}
+
+		assert.NoError(t, input.getStatementMetrics(pgVersion13))
+		assert.Len(t, input.statementCache, 1)
+
+		pt, err := input.statementCache[0].LineProto()
+		assert.NoError(t, err)
+		assert.Equal(t, "postgresql_statements", pt.Name())
+		assert.Equal(t, "-123", pt.Tags()["query_id"])
+		assert.Equal(t, "postgres", pt.Tags()["user"])
+
+		fields, err := pt.Fields()
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT 1", fields["message"])
+		assert.Equal(t, int64(10), fields["calls"])
+	})
+}
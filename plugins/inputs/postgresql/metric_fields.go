@@ -39,4 +39,8 @@ var postgreFields = map[string]interface{}{
 	// CONNECTION_METRICS
 	"max_connections":           &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "The maximum number of client connections allowed to this database."},
 	"percent_usage_connections": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "The number of connections to this database as a fraction of the maximum number of allowed connections."},
+
+	// REPLICATION_METRICS(standby)
+	"replication_delay":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "The time since the last transaction replayed on the standby, 0 if all WAL received replayed. Collected on standby if `replication` enabled."},
+	"replication_delay_bytes": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL received but not replayed on the standby. Collected on standby if `replication` enabled."},
 }
@@ -0,0 +1,363 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package postgresql
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	pgVersion10 = 100000
+	pgVersion13 = 130000
+
+	maxQueryLen = 1024
+)
+
+// current WAL location, the received one on standby.
+const currentLSN = `CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END`
+
+//nolint:lll
+var (
+	replicationQuery = `
+		SELECT application_name, client_addr, state, sync_state,
+		pg_wal_lsn_diff(pg_current_wal_lsn(), sent_lsn) AS sent_lag_bytes,
+		pg_wal_lsn_diff(pg_current_wal_lsn(), write_lsn) AS write_lag_bytes,
+		pg_wal_lsn_diff(pg_current_wal_lsn(), flush_lsn) AS flush_lag_bytes,
+		pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn) AS replay_lag_bytes,
+		EXTRACT(EPOCH FROM write_lag) AS write_lag,
+		EXTRACT(EPOCH FROM flush_lag) AS flush_lag,
+		EXTRACT(EPOCH FROM replay_lag) AS replay_lag
+		FROM pg_stat_replication
+		WHERE NOT pg_is_in_recovery()
+	`
+
+	standbyQuery = `
+		SELECT
+		CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END AS replication_delay,
+		pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn()) AS replication_delay_bytes
+		WHERE pg_is_in_recovery()
+	`
+
+	replicationSlotQuery = `
+		SELECT slot_name, slot_type, COALESCE(plugin, '') AS plugin, COALESCE(database, '') AS database, active,
+		pg_wal_lsn_diff(` + currentLSN + `, restart_lsn) AS retained_wal_bytes,
+		pg_wal_lsn_diff(` + currentLSN + `, confirmed_flush_lsn) AS confirmed_flush_lag_bytes
+		FROM pg_replication_slots
+	`
+
+	// %[1]s/%[2]s: total/mean time columns, renamed to total_exec_time/mean_exec_time since PostgreSQL 13
+	statementsQuery = `
+		SELECT s.queryid, d.datname, r.rolname, LEFT(s.query, %[3]d) AS message, s.calls,
+		s.%[1]s AS total_time, s.%[2]s AS mean_time, s.rows, s.shared_blks_hit, s.shared_blks_read
+		FROM pg_stat_statements s
+		JOIN pg_database d ON d.oid = s.dbid
+		JOIN pg_roles r ON r.oid = s.userid
+		ORDER BY s.%[1]s DESC LIMIT %[4]d
+	`
+)
+
+type replicationMeasurement struct {
+	inputMeasurement
+}
+
+//nolint:lll
+func (m replicationMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "postgresql_replication",
+		Desc: "Replicas connected to the primary, from `pg_stat_replication`, PostgreSQL 10+ required.",
+		Type: "metric",
+		Fields: map[string]interface{}{
+			"sent_lag_bytes":   &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL not sent to the replica yet."},
+			"write_lag_bytes":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL not written to disk by the replica yet."},
+			"flush_lag_bytes":  &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL not flushed to disk by the replica yet."},
+			"replay_lag_bytes": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL not replayed by the replica yet."},
+			"write_lag":        &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Time elapsed between flushing recent WAL locally and receiving notification that the replica has written it."},
+			"flush_lag":        &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Time elapsed between flushing recent WAL locally and receiving notification that the replica has written and flushed it."},
+			"replay_lag":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Time elapsed between flushing recent WAL locally and receiving notification that the replica has written, flushed and applied it."},
+		},
+		Tags: map[string]interface{}{
+			"server":           inputs.NewTagInfo("The server address"),
+			"application_name": inputs.NewTagInfo("Name of the application connected as replica"),
+			"client_addr":      inputs.NewTagInfo("IP address of the replica"),
+			"state":            inputs.NewTagInfo("State of the WAL sender, such as `streaming`"),
+			"sync_state":       inputs.NewTagInfo("Synchronous state of the replica, such as `async`/`sync`"),
+		},
+	}
+}
+
+type replicationSlotMeasurement struct {
+	inputMeasurement
+}
+
+//nolint:lll
+func (m replicationSlotMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "postgresql_replication_slot",
+		Desc: "Replication slots, from `pg_replication_slots`, PostgreSQL 10+ required.",
+		Type: "metric",
+		Fields: map[string]interface{}{
+			"active":                    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether the slot is currently being used, 1 for yes."},
+			"retained_wal_bytes":        &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL retained by the slot, which can not be removed."},
+			"confirmed_flush_lag_bytes": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "WAL not confirmed by the consumer of the logical slot, i.e. lag of logical decoding."},
+		},
+		Tags: map[string]interface{}{
+			"server":    inputs.NewTagInfo("The server address"),
+			"slot_name": inputs.NewTagInfo("Name of the slot"),
+			"slot_type": inputs.NewTagInfo("Type of the slot, `physical` or `logical`"),
+			"plugin":    inputs.NewTagInfo("Output plugin of the logical slot"),
+			"db":        inputs.NewTagInfo("Database of the logical slot"),
+		},
+	}
+}
+
+type statementMeasurement struct {
+	inputMeasurement
+}
+
+func (m statementMeasurement) LineProto() (*point.Point, error) {
+	return point.NewPoint(m.name, m.tags, m.fields, point.LOptElectionV2(m.election))
+}
+
+//nolint:lll
+func (m statementMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "postgresql_statements",
+		Desc: "Top queries ordered by total execution time, from `pg_stat_statements`, collected if `statements_top` set.",
+		Type: "logging",
+		Fields: map[string]interface{}{
+			"message":          &inputs.FieldInfo{DataType: inputs.String, Type: inputs.String, Unit: inputs.UnknownUnit, Desc: "Text of the query, at most 1024 characters."},
+			"calls":            &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Number of times executed."},
+			"total_time":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Count, Unit: inputs.DurationMS, Desc: "Total time spent executing the query."},
+			"mean_time":        &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Mean time spent executing the query."},
+			"rows":             &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Total number of rows retrieved or affected."},
+			"shared_blks_hit":  &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Total number of shared block cache hits."},
+			"shared_blks_read": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Count, Unit: inputs.NCount, Desc: "Total number of shared blocks read."},
+		},
+		Tags: map[string]interface{}{
+			"server":   inputs.NewTagInfo("The server address"),
+			"query_id": inputs.NewTagInfo("Hash code of the query"),
+			"db":       inputs.NewTagInfo("The database name"),
+			"user":     inputs.NewTagInfo("The user executed the query"),
+		},
+	}
+}
+
+// queryRows returns rows of the query, bytes values converted to string.
+func (ipt *Input) queryRows(query string) ([]map[string]interface{}, error) {
+	rows, err := ipt.service.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]interface{}
+	for rows.Next() {
+		columnMap, err := ipt.service.GetColumnMap(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		row := map[string]interface{}{}
+		for col, val := range columnMap {
+			if val == nil || *val == nil {
+				continue
+			}
+
+			if b, ok := (*val).([]uint8); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = *val
+			}
+		}
+		res = append(res, row)
+	}
+
+	return res, nil
+}
+
+func (ipt *Input) serverVersion() (int, error) {
+	rows, err := ipt.queryRows(`SHOW server_version_num`)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("server_version_num not found")
+	}
+
+	v, ok := toFloat(rows[0]["server_version_num"])
+	if !ok {
+		return 0, fmt.Errorf("invalid server_version_num: %v", rows[0]["server_version_num"])
+	}
+
+	return int(v), nil
+}
+
+// getReplicationMetrics collects replicas on the primary, replication
+// delay on the standby and the replication slots.
+func (ipt *Input) getReplicationMetrics(version int) error {
+	if version < pgVersion10 {
+		l.Debugf("replication metrics require PostgreSQL 10+, got %d, skipped", version)
+		return nil
+	}
+
+	rows, err := ipt.queryRows(replicationQuery)
+	if err != nil {
+		return fmt.Errorf("pg_stat_replication: %w", err)
+	}
+	for _, row := range rows {
+		ipt.collectCache = append(ipt.collectCache, &replicationMeasurement{ipt.rowMeasurement("postgresql_replication",
+			row, []string{"application_name", "client_addr", "state", "sync_state"}, nil)})
+	}
+
+	rows, err = ipt.queryRows(standbyQuery)
+	if err != nil {
+		return fmt.Errorf("standby replication delay: %w", err)
+	}
+	for _, row := range rows {
+		if err := ipt.accRow(wrapRow(row)); err != nil {
+			return err
+		}
+	}
+
+	rows, err = ipt.queryRows(replicationSlotQuery)
+	if err != nil {
+		return fmt.Errorf("pg_replication_slots: %w", err)
+	}
+	for _, row := range rows {
+		if v, ok := row["active"].(bool); ok {
+			row["active"] = 0
+			if v {
+				row["active"] = 1
+			}
+		}
+
+		ipt.collectCache = append(ipt.collectCache, &replicationSlotMeasurement{ipt.rowMeasurement("postgresql_replication_slot",
+			row, []string{"slot_name", "slot_type", "plugin", "database"}, map[string]string{"database": "db"})})
+	}
+
+	return nil
+}
+
+// getStatementMetrics collects top queries of pg_stat_statements.
+func (ipt *Input) getStatementMetrics(version int) error {
+	if ipt.StatementsTop <= 0 {
+		return nil
+	}
+
+	totalCol, meanCol := "total_time", "mean_time"
+	if version >= pgVersion13 {
+		totalCol, meanCol = "total_exec_time", "mean_exec_time"
+	}
+
+	rows, err := ipt.queryRows(fmt.Sprintf(statementsQuery, totalCol, meanCol, maxQueryLen, ipt.StatementsTop))
+	if err != nil {
+		return fmt.Errorf("pg_stat_statements: %w", err)
+	}
+
+	for _, row := range rows {
+		ipt.statementCache = append(ipt.statementCache, &statementMeasurement{ipt.rowMeasurement("postgresql_statements",
+			row, []string{"queryid", "datname", "rolname"}, map[string]string{"queryid": "query_id", "datname": "db", "rolname": "user"})})
+	}
+
+	return nil
+}
+
+// rowMeasurement build measurement from the row, columns in tagCols as
+// tags(renamed by renames), others as fields.
+func (ipt *Input) rowMeasurement(name string, row map[string]interface{}, tagCols []string, renames map[string]string) inputMeasurement {
+	tags := ipt.baseTags()
+
+	for _, col := range tagCols {
+		v, ok := row[col]
+		if !ok {
+			continue
+		}
+		delete(row, col)
+
+		if s := fmt.Sprint(v); s != "" {
+			if x, ok := renames[col]; ok {
+				col = x
+			}
+			tags[col] = s
+		}
+	}
+
+	fields := map[string]interface{}{}
+	for col, v := range row {
+		if s, ok := v.(string); ok && col != "message" {
+			// numeric types returned as string
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				fields[col] = f
+			}
+			continue
+		}
+		fields[col] = v
+	}
+
+	return inputMeasurement{
+		name:     name,
+		tags:     tags,
+		fields:   fields,
+		ts:       time.Now(),
+		election: ipt.Election,
+	}
+}
+
+func (ipt *Input) baseTags() map[string]string {
+	tags := map[string]string{}
+	if addr, err := ipt.SanitizedAddress(); err == nil {
+		tags["server"] = addr
+	}
+
+	if ipt.host != "" {
+		tags["host"] = ipt.host
+	}
+
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+
+	return tags
+}
+
+// wrapRow converts row of queryRows to the column map accepted by accRow.
+func wrapRow(row map[string]interface{}) map[string]*interface{} {
+	res := map[string]*interface{}{}
+	for k, v := range row {
+		if f, ok := toFloat(v); ok {
+			v = f
+		}
+
+		x := v
+		res[k] = &x
+	}
+	return res
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int64:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
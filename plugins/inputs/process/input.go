@@ -24,6 +24,7 @@ import (
 	"github.com/tweekmonster/luser"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -119,10 +120,14 @@ func (p *Input) Run() {
 
 	procRecorder := newProcRecorder()
 	for {
-		processList := p.getProcesses(false)
-		tn := time.Now().UTC()
-		p.WriteObject(processList, procRecorder, tn)
-		procRecorder.flush(processList, tn)
+		if loadshed.Shedding(loadshed.Object) {
+			l.Debug("process object shed on high load, skipped")
+		} else {
+			processList := p.getProcesses(false)
+			tn := time.Now().UTC()
+			p.WriteObject(processList, procRecorder, tn)
+			procRecorder.flush(processList, tn)
+		}
 		select {
 		case <-tick.C:
 		case <-datakit.Exit.Wait():
@@ -30,6 +30,7 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/loadshed"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
@@ -75,6 +76,10 @@ const (
 #[inputs.profile.go.tags]
   # tag1 = "val1"
 
+  ## headers of pull requests, such as auth of protected pprof endpoints
+#[inputs.profile.go.headers]
+  # Authorization = "Bearer <token>"
+
 ## pyroscope config
 #[[inputs.profile.pyroscope]]
   ## listen url
@@ -448,6 +453,18 @@ func profilingProxyURL() (*url.URL, error) {
 	return nil, lastErr
 }
 
+// shedOnHighLoad rejects profiling data while profiling shed on high load.
+func shedOnHighLoad(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if loadshed.Shedding(loadshed.Profiling) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		next(w, req)
+	}
+}
+
 // RegHTTPHandler simply proxy profiling request to dataway.
 func (i *Input) RegHTTPHandler() {
 	URL, err := profilingProxyURL()
@@ -579,9 +596,12 @@ func (i *Input) RegHTTPHandler() {
 	}
 
 	for _, endpoint := range i.Endpoints {
-		dkhttp.RegHTTPHandler(http.MethodPost, endpoint, proxy.ServeHTTP)
+		dkhttp.RegHTTPHandler(http.MethodPost, endpoint, shedOnHighLoad(withJFR(proxy.ServeHTTP)))
 		log.Infof("pattern: %s registered", endpoint)
 	}
+
+	dkhttp.RegHTTPHandler(http.MethodPost, triggerEndpoint, handleTrigger)
+	log.Infof("pattern: %s registered", triggerEndpoint)
 }
 
 func (i *Input) Catalog() string {
@@ -37,6 +37,9 @@ type GoProfiler struct {
 	Tags         map[string]string `toml:"tags"`
 	EnabledTypes []string          `toml:"enabled_types"` // cpu,goroutine,heap,mutex,block
 
+	// headers of pull requests, such as Authorization of protected pprof endpoints
+	Headers map[string]string `toml:"headers"`
+
 	TLSOpen            bool   `toml:"tls_open"`
 	CacertFile         string `toml:"tls_ca"`
 	CertFile           string `toml:"tls_cert"`
@@ -158,6 +161,9 @@ func (g *GoProfiler) run(i *Input) error {
 		return fmt.Errorf("init go profiler error: %w", err)
 	}
 
+	registerOnDemand(g)
+	defer unregisterOnDemand(g)
+
 	tick := time.NewTicker(g.interval)
 	defer tick.Stop()
 
@@ -283,6 +289,10 @@ func (g *GoProfiler) pullProfileItem(profileType string, item ProfileItem) (*pro
 }
 
 func (g *GoProfiler) pullProfileData(path string, params url.Values) (*bytes.Buffer, error) {
+	return g.pullProfileDataWith(g.client, path, params)
+}
+
+func (g *GoProfiler) pullProfileDataWith(client *http.Client, path string, params url.Values) (*bytes.Buffer, error) {
 	u := url.URL{
 		Path:   path,
 		Scheme: g.url.Scheme,
@@ -298,11 +308,19 @@ func (g *GoProfiler) pullProfileData(path string, params url.Values) (*bytes.Buf
 		return nil, err
 	}
 
-	if g.client == nil {
+	for k, v := range g.Headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+
+	if client == nil {
 		return nil, fmt.Errorf("http client should be initialized")
 	}
 
-	resp, err := g.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
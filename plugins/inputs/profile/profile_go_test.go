@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package profile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoProfilerHeaders(t *testing.T) {
+	var host string
+	pprofSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tkn" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		host = r.Host
+		_, _ = w.Write([]byte("goroutine"))
+	}))
+	defer pprofSvr.Close()
+
+	g := &GoProfiler{URL: pprofSvr.URL, Interval: "10s", input: &Input{}}
+	require.NoError(t, g.init())
+
+	_, err := g.pullProfileData("/debug/pprof/goroutine", nil)
+	assert.ErrorContains(t, err, "401")
+
+	g.Headers = map[string]string{"Authorization": "Bearer tkn", "host": "app.internal"}
+
+	buf, err := g.pullProfileData("/debug/pprof/goroutine", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "goroutine", buf.String())
+	assert.Equal(t, "app.internal", host)
+}
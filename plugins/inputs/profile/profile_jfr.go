@@ -0,0 +1,326 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/pyroscope-io/jfr-parser/parser"
+)
+
+const (
+	jfrReportFamily = "java"
+	jfrReportFormat = "pprof"
+
+	// max size of decompressed JFR recordings.
+	jfrMaxSize = 8 * profileMaxSize
+)
+
+var jfrMagic = []byte{'F', 'L', 'R', 0}
+
+// withJFR handles JFR recordings posted to the profiling endpoints, other
+// requests(multipart/form-data from ddtrace and async-profiler) are passed
+// to next.
+func withJFR(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if mt, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mt == "multipart/form-data" {
+			next(w, req)
+			return
+		}
+
+		handleJFR(w, req)
+	}
+}
+
+// handleJFR converts the JFR recording to pprof profiles of CPU, allocation
+// and lock, and uploads them like profiles pulled from Go. Tags of the
+// profile are set by URL query, such as:
+//
+//	?service=order&env=prod&version=1.0&host=web-1&tags=k1:v1,k2:v2
+func handleJFR(w http.ResponseWriter, req *http.Request) {
+	body, err := readJFR(req)
+	if err != nil {
+		log.Warnf("read JFR recording: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	start, end, profiles, err := parseJFR(body)
+	if err != nil {
+		log.Warnf("parse JFR recording: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	tags := jfrTags(req)
+
+	log.Infof("receive JFR recording of %s, %d bytes, %d profiles", tags[TagService], len(body), len(profiles))
+
+	if err := pushProfileData(&pushProfileDataOpt{
+		startTime:       start,
+		endTime:         end,
+		profiledatas:    profiles,
+		reportFamily:    jfrReportFamily,
+		reportFormat:    jfrReportFormat,
+		endPoint:        req.URL.Path,
+		inputTags:       tags,
+		inputNameSuffix: "/jfr",
+	}); err != nil {
+		log.Errorf("push JFR profiles: %s", err)
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// readJFR reads the JFR recording from body, gzipped recordings are decompressed.
+func readJFR(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, fmt.Errorf("empty body")
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, profileMaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Header.Get("Content-Encoding") == "gzip" || isGzip(body) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		if body, err = ioutil.ReadAll(io.LimitReader(zr, jfrMaxSize+1)); err != nil {
+			return nil, err
+		}
+
+		if len(body) > jfrMaxSize {
+			return nil, fmt.Errorf("decompressed JFR recording exceeds %d bytes", jfrMaxSize)
+		}
+	}
+
+	if !bytes.HasPrefix(body, jfrMagic) {
+		return nil, fmt.Errorf("profiling data not supported, expect multipart/form-data or JFR recording")
+	}
+
+	return body, nil
+}
+
+func isGzip(b []byte) bool {
+	return len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b
+}
+
+func jfrTags(req *http.Request) map[string]string {
+	q := req.URL.Query()
+
+	var arr []string
+	for _, s := range strings.Split(q.Get("tags"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			arr = append(arr, s)
+		}
+	}
+
+	tags := NewTags(arr)
+	for _, k := range []string{TagService, TagEnv, TagVersion, TagHost} {
+		if v := q.Get(k); v != "" {
+			tags[k] = v
+		}
+	}
+
+	if tags[TagService] == "" {
+		tags[TagService] = "unnamed-service"
+	}
+
+	return tags
+}
+
+// jfrProfile builds a pprof profile from stack traces of JFR events, samples
+// of the same stack are merged.
+type jfrProfile struct {
+	fileName string
+	prof     *pprofile.Profile
+
+	funcs   map[string]*pprofile.Function
+	locs    map[string]*pprofile.Location
+	samples map[string]*pprofile.Sample
+}
+
+func newJFRProfile(fileName string, sampleTypes ...*pprofile.ValueType) *jfrProfile {
+	return &jfrProfile{
+		fileName: fileName,
+		prof:     &pprofile.Profile{SampleType: sampleTypes},
+		funcs:    map[string]*pprofile.Function{},
+		locs:     map[string]*pprofile.Location{},
+		samples:  map[string]*pprofile.Sample{},
+	}
+}
+
+func (p *jfrProfile) location(f *parser.StackFrame) *pprofile.Location {
+	name := "<unknown>"
+	if m := f.Method; m != nil && m.Name != nil {
+		name = m.Name.String
+		if m.Type != nil && m.Type.Name != nil {
+			name = strings.ReplaceAll(m.Type.Name.String, "/", ".") + "." + name
+		}
+	}
+
+	key := name + ":" + strconv.Itoa(int(f.LineNumber))
+	if loc, ok := p.locs[key]; ok {
+		return loc
+	}
+
+	fn, ok := p.funcs[name]
+	if !ok {
+		fn = &pprofile.Function{ID: uint64(len(p.prof.Function) + 1), Name: name, SystemName: name}
+		p.funcs[name] = fn
+		p.prof.Function = append(p.prof.Function, fn)
+	}
+
+	loc := &pprofile.Location{
+		ID:   uint64(len(p.prof.Location) + 1),
+		Line: []pprofile.Line{{Function: fn, Line: int64(f.LineNumber)}},
+	}
+	p.locs[key] = loc
+	p.prof.Location = append(p.prof.Location, loc)
+
+	return loc
+}
+
+// add adds a sample of the stack trace, frames of JFR stack trace are
+// leaf first, the same as pprof.
+func (p *jfrProfile) add(st *parser.StackTrace, values ...int64) {
+	if st == nil || len(st.Frames) == 0 {
+		return
+	}
+
+	locs := make([]*pprofile.Location, 0, len(st.Frames))
+	ids := make([]string, 0, len(st.Frames))
+	for _, f := range st.Frames {
+		loc := p.location(f)
+		locs = append(locs, loc)
+		ids = append(ids, strconv.FormatUint(loc.ID, 10))
+	}
+
+	key := strings.Join(ids, ",")
+	if s, ok := p.samples[key]; ok {
+		for i, v := range values {
+			s.Value[i] += v
+		}
+		return
+	}
+
+	s := &pprofile.Sample{Location: locs, Value: append([]int64(nil), values...)}
+	p.samples[key] = s
+	p.prof.Sample = append(p.prof.Sample, s)
+}
+
+func (p *jfrProfile) data(start, end time.Time) (*profileData, error) {
+	p.prof.TimeNanos = start.UnixNano()
+	p.prof.DurationNanos = end.Sub(start).Nanoseconds()
+
+	if err := p.prof.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.prof.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return &profileData{fileName: p.fileName, buf: buf, startTime: start, endTime: end}, nil
+}
+
+// parseJFR extracts CPU, allocation and lock events of the JFR recording to
+// pprof profiles, and returns the time range of the recording.
+func parseJFR(data []byte) (time.Time, time.Time, []*profileData, error) {
+	var start, end time.Time
+
+	chunks, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return start, end, nil, fmt.Errorf("invalid JFR recording: %w", err)
+	}
+
+	var (
+		cpu = newJFRProfile("cpu.pprof",
+			&pprofile.ValueType{Type: "samples", Unit: "count"})
+		alloc = newJFRProfile("alloc.pprof",
+			&pprofile.ValueType{Type: "alloc_samples", Unit: "count"},
+			&pprofile.ValueType{Type: "alloc_space", Unit: "bytes"})
+		lock = newJFRProfile("lock.pprof",
+			&pprofile.ValueType{Type: "contentions", Unit: "count"},
+			&pprofile.ValueType{Type: "delay", Unit: "nanoseconds"})
+	)
+
+	for _, c := range chunks {
+		chunkStart := time.Unix(0, c.Header.StartTimeNanos)
+		chunkEnd := chunkStart.Add(time.Duration(c.Header.DurationNanos))
+
+		if start.IsZero() || chunkStart.Before(start) {
+			start = chunkStart
+		}
+		if chunkEnd.After(end) {
+			end = chunkEnd
+		}
+
+		// durations of events are in ticks
+		ticks2ns := func(ticks int64) int64 {
+			if c.Header.TicksPerSecond <= 0 {
+				return ticks
+			}
+			return int64(float64(ticks) * float64(time.Second) / float64(c.Header.TicksPerSecond))
+		}
+
+		for _, e := range c.Events {
+			switch x := e.(type) {
+			case *parser.ExecutionSample:
+				if x.State == nil || x.State.Name == "STATE_RUNNABLE" {
+					cpu.add(x.StackTrace, 1)
+				}
+			case *parser.ObjectAllocationInNewTLAB:
+				alloc.add(x.StackTrace, 1, x.TLABSize)
+			case *parser.ObjectAllocationOutsideTLAB:
+				alloc.add(x.StackTrace, 1, x.AllocationSize)
+			case *parser.JavaMonitorEnter:
+				lock.add(x.StackTrace, 1, ticks2ns(x.Duration))
+			case *parser.ThreadPark:
+				lock.add(x.StackTrace, 1, ticks2ns(x.Duration))
+			}
+		}
+	}
+
+	var profiles []*profileData
+	for _, p := range []*jfrProfile{cpu, alloc, lock} {
+		if len(p.prof.Sample) == 0 {
+			continue
+		}
+
+		pd, err := p.data(start, end)
+		if err != nil {
+			return start, end, nil, fmt.Errorf("build %s: %w", p.fileName, err)
+		}
+		profiles = append(profiles, pd)
+	}
+
+	if len(profiles) == 0 {
+		return start, end, nil, fmt.Errorf("no CPU, allocation or lock events in the JFR recording")
+	}
+
+	return start, end, profiles, nil
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package profile
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJFR(t *testing.T) {
+	gz, err := os.ReadFile("testdata/example.jfr.gz")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/profiling/v1/input", bytes.NewReader(gz))
+	data, err := readJFR(req)
+	require.NoError(t, err)
+
+	start, end, profiles, err := parseJFR(data)
+	require.NoError(t, err)
+	assert.True(t, end.After(start))
+	require.NotEmpty(t, profiles)
+
+	for _, pd := range profiles {
+		prof, err := pprofile.Parse(pd.buf)
+		require.NoError(t, err, pd.fileName)
+		assert.NotEmpty(t, prof.Sample, pd.fileName)
+		assert.Equal(t, start.UnixNano(), prof.TimeNanos)
+	}
+
+	t.Run("not-jfr", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/profiling/v1/input", bytes.NewReader([]byte("hello")))
+		_, err := readJFR(req)
+		assert.Error(t, err)
+
+		_, _, _, err = parseJFR([]byte("FLR\x00broken"))
+		assert.Error(t, err)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost,
+			"/profiling/v1/input?env=prod&tags=team:pay,service:ignored&service=order", nil)
+		tags := jfrTags(req)
+		assert.Equal(t, "order", tags[TagService])
+		assert.Equal(t, "prod", tags[TagEnv])
+		assert.Equal(t, "pay", tags["team"])
+
+		tags = jfrTags(httptest.NewRequest(http.MethodPost, "/profiling/v1/input", nil))
+		assert.Equal(t, "unnamed-service", tags[TagService])
+	})
+}
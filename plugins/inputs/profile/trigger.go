@@ -0,0 +1,249 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+)
+
+const (
+	triggerEndpoint       = "/profiling/v1/trigger"
+	defaultTriggerSeconds = 10
+	maxTriggerSeconds     = 300
+)
+
+// onDemandProfiler is implemented by profilers which datakit can request
+// profiles from at any time. Agents pushing profiles(ddtrace, async-profiler)
+// have no control channel to datakit, they are not triggerable.
+type onDemandProfiler interface {
+	service() string
+	endpoint() string
+	profileOnDemand(types []string, seconds int) error
+}
+
+var (
+	onDemandMtx       sync.RWMutex
+	onDemandProfilers = map[onDemandProfiler]struct{}{}
+	onDemandRunning   = map[onDemandProfiler]struct{}{}
+
+	// mocked in testing.
+	pushProfile = pushProfileData
+	checkToken  = dkhttp.CheckToken
+)
+
+func registerOnDemand(p onDemandProfiler) {
+	onDemandMtx.Lock()
+	defer onDemandMtx.Unlock()
+	onDemandProfilers[p] = struct{}{}
+}
+
+func unregisterOnDemand(p onDemandProfiler) {
+	onDemandMtx.Lock()
+	defer onDemandMtx.Unlock()
+	delete(onDemandProfilers, p)
+}
+
+// startOnDemand marks the profiler running, false if it's running already.
+func startOnDemand(p onDemandProfiler) bool {
+	onDemandMtx.Lock()
+	defer onDemandMtx.Unlock()
+
+	if _, ok := onDemandRunning[p]; ok {
+		return false
+	}
+	onDemandRunning[p] = struct{}{}
+	return true
+}
+
+func finishOnDemand(p onDemandProfiler) {
+	onDemandMtx.Lock()
+	defer onDemandMtx.Unlock()
+	delete(onDemandRunning, p)
+}
+
+func isOnDemandRunning(p onDemandProfiler) bool {
+	onDemandMtx.RLock()
+	defer onDemandMtx.RUnlock()
+	_, ok := onDemandRunning[p]
+	return ok
+}
+
+func matchOnDemand(service, endpoint string) []onDemandProfiler {
+	onDemandMtx.RLock()
+	defer onDemandMtx.RUnlock()
+
+	var arr []onDemandProfiler
+	for p := range onDemandProfilers {
+		if service != "" && p.service() != service {
+			continue
+		}
+		if endpoint != "" && p.endpoint() != endpoint {
+			continue
+		}
+		arr = append(arr, p)
+	}
+
+	return arr
+}
+
+type triggerResult struct {
+	Service  string `json:"service"`
+	Endpoint string `json:"endpoint"`
+	Error    string `json:"error,omitempty"`
+}
+
+func parseTriggerArgs(q url.Values) (types []string, seconds int, err error) {
+	types = []string{"cpu"}
+	if s := q.Get("types"); s != "" {
+		types = nil
+		for _, t := range strings.Split(s, ",") {
+			t = strings.TrimSpace(t)
+			if _, ok := profileConfigMap[t]; !ok {
+				return nil, 0, fmt.Errorf("invalid profile type: %q", t)
+			}
+			types = append(types, t)
+		}
+	}
+
+	seconds = defaultTriggerSeconds
+	if s := q.Get("seconds"); s != "" {
+		if seconds, err = strconv.Atoi(s); err != nil || seconds <= 0 || seconds > maxTriggerSeconds {
+			return nil, 0, fmt.Errorf("invalid seconds %q, should be within (0, %d]", s, maxTriggerSeconds)
+		}
+	}
+
+	return types, seconds, nil
+}
+
+// handleTrigger requests profiles from profilers matched by service and
+// endpoint(all if not set) immediately, profiles are sent as Profiling data.
+// Profiling may last longer than the timeout of the API, so it responds
+// once profiling started, and profilers running already are skipped.
+func handleTrigger(w http.ResponseWriter, req *http.Request) {
+	if err := checkToken(req); err != nil {
+		w.WriteHeader(dkhttp.ErrInvalidToken.HttpCode)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	q := req.URL.Query()
+
+	types, seconds, err := parseTriggerArgs(q)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	profilers := matchOnDemand(q.Get("service"), q.Get("endpoint"))
+	if len(profilers) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no profiler matched, only pull mode profilers (such as inputs.profile.go) can be triggered"))
+		return
+	}
+
+	log.Infof("profiling triggered on %d profilers, types: %v, seconds: %d", len(profilers), types, seconds)
+
+	results := make([]*triggerResult, len(profilers))
+	g := goroutine.NewGroup(goroutine.Option{Name: "profile_trigger"})
+	for idx, p := range profilers {
+		p := p
+		results[idx] = &triggerResult{Service: p.service(), Endpoint: p.endpoint()}
+		if !startOnDemand(p) {
+			results[idx].Error = "profiling in progress"
+			continue
+		}
+
+		g.Go(func(ctx context.Context) error {
+			defer finishOnDemand(p)
+			if err := p.profileOnDemand(types, seconds); err != nil {
+				log.Warnf("on-demand profile of %s failed: %s", p.endpoint(), err.Error())
+			}
+			return nil
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"content": results})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write(body)
+}
+
+func (g *GoProfiler) service() string { return g.Service }
+
+func (g *GoProfiler) endpoint() string { return g.url.String() }
+
+// profileOnDemand pulls the types of profiles, absolute values are used
+// instead of delta for heap, mutex and block.
+func (g *GoProfiler) profileOnDemand(types []string, seconds int) error {
+	tags := map[string]string{"trigger": "on_demand"}
+	for k, v := range g.tags {
+		tags[k] = v
+	}
+
+	if g.client == nil {
+		return fmt.Errorf("http client should be initialized")
+	}
+
+	// the cpu profile lasts for seconds
+	client := *g.client
+	client.Timeout += time.Duration(seconds) * time.Second
+
+	var datas []*profileData
+	for _, t := range types {
+		item, ok := profileConfigMap[t]
+		if !ok {
+			return fmt.Errorf("invalid profile type: %q", t)
+		}
+
+		params := item.params
+		if t == "cpu" {
+			params = url.Values{"seconds": []string{strconv.Itoa(seconds)}}
+		}
+
+		start := time.Now()
+		buf, err := g.pullProfileDataWith(&client, item.path, params)
+		if err != nil {
+			return fmt.Errorf("pull %s profile error: %w", t, err)
+		}
+
+		datas = append(datas, &profileData{
+			fileName:  strings.TrimPrefix(item.fileName, "delta-"),
+			buf:       buf,
+			startTime: start,
+			endTime:   time.Now(),
+		})
+	}
+
+	return pushProfile(&pushProfileDataOpt{
+		startTime:       datas[0].startTime,
+		endTime:         datas[len(datas)-1].endTime,
+		profiledatas:    datas,
+		reportFamily:    goReportFamily,
+		reportFormat:    goReportFormat,
+		endPoint:        g.url.String(),
+		inputTags:       tags,
+		election:        g.input.Election,
+		inputNameSuffix: "/go",
+	})
+}
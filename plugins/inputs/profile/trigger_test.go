@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
+)
+
+func TestHandleTrigger(t *testing.T) {
+	defer func(f func(*pushProfileDataOpt) error) { pushProfile = f }(pushProfile)
+	defer func(f func(*http.Request) error) { checkToken = f }(checkToken)
+
+	checkToken = func(r *http.Request) error {
+		if r.URL.Query().Get("token") != "tkn_abc123" {
+			return dkhttp.ErrInvalidToken
+		}
+		return nil
+	}
+
+	var (
+		mtx        sync.Mutex
+		cpuSeconds string
+		pushed     []*pushProfileDataOpt
+	)
+	pprofSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug/pprof/profile":
+			mtx.Lock()
+			cpuSeconds = r.URL.Query().Get("seconds")
+			mtx.Unlock()
+			_, _ = w.Write([]byte("cpu"))
+		case "/debug/pprof/heap":
+			_, _ = w.Write([]byte("heap"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer pprofSvr.Close()
+
+	pushProfile = func(opt *pushProfileDataOpt) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		pushed = append(pushed, opt)
+		return nil
+	}
+
+	g := &GoProfiler{URL: pprofSvr.URL, Interval: "10s", Service: "go-demo", input: &Input{}}
+	assert.NoError(t, g.init())
+	registerOnDemand(g)
+	defer unregisterOnDemand(g)
+
+	trigger := func(q url.Values) *httptest.ResponseRecorder {
+		if _, ok := q["token"]; !ok {
+			q.Set("token", "tkn_abc123")
+		}
+		w := httptest.NewRecorder()
+		handleTrigger(w, httptest.NewRequest(http.MethodPost, triggerEndpoint+"?"+q.Encode(), nil))
+		return w
+	}
+
+	finished := func() bool { return !isOnDemandRunning(g) }
+
+	t.Run("ok", func(t *testing.T) {
+		w := trigger(url.Values{"service": {"go-demo"}, "types": {"cpu,heap"}, "seconds": {"3"}})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		var res struct {
+			Content []*triggerResult `json:"content"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, []*triggerResult{{Service: "go-demo", Endpoint: pprofSvr.URL}}, res.Content)
+
+		assert.Eventually(t, finished, 5*time.Second, 10*time.Millisecond)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, "3", cpuSeconds)
+		assert.Len(t, pushed, 1)
+		assert.Equal(t, "on_demand", pushed[0].inputTags["trigger"])
+		assert.Equal(t, "go-demo", pushed[0].inputTags["service"])
+		assert.Len(t, pushed[0].profiledatas, 2)
+		assert.Equal(t, "heap.pprof", pushed[0].profiledatas[1].fileName)
+		assert.Equal(t, "heap", pushed[0].profiledatas[1].buf.String())
+	})
+
+	t.Run("pull-failed", func(t *testing.T) {
+		assert.Equal(t, http.StatusAccepted, trigger(url.Values{"types": {"mutex"}}).Code)
+		assert.Eventually(t, finished, 5*time.Second, 10*time.Millisecond)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Len(t, pushed, 1)
+	})
+
+	t.Run("in-progress", func(t *testing.T) {
+		assert.True(t, startOnDemand(g))
+		defer finishOnDemand(g)
+
+		w := trigger(url.Values{})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), "profiling in progress")
+	})
+
+	t.Run("invalid-token", func(t *testing.T) {
+		assert.Equal(t, dkhttp.ErrInvalidToken.HttpCode, trigger(url.Values{"token": {"tkn_other"}}).Code)
+	})
+
+	t.Run("not-matched", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, trigger(url.Values{"service": {"other"}}).Code)
+	})
+
+	t.Run("invalid-args", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, trigger(url.Values{"types": {"cpu,foo"}}).Code)
+		assert.Equal(t, http.StatusBadRequest, trigger(url.Values{"seconds": {"3600"}}).Code)
+	})
+}
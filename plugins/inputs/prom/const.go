@@ -66,6 +66,10 @@ const sampleCfg = `
   # disable setting instance tag for this input
   disable_instance_tag = false
 
+  ## Prefer the protobuf exposition format to collect native histograms and
+  # exemplars, fallback to text format if not supported by the exporter.
+  # scrape_protobuf = false
+
   # Ignore tags. Multi supported.
   # The matched tags would be dropped, but the item would still be sent.
   # tags_ignore = ["xxxx"]
@@ -112,6 +116,40 @@ const sampleCfg = `
     enable = false
     service = "service_name"
 
+  ## Discover targets in Kubernetes, instead of (or besides) static urls.
+  ## Each target is tagged by namespace, pod_name, service_name and node_name.
+  # [inputs.prom.kubernetes_discovery]
+  #   kubernetes_url = "https://kubernetes.default:443"
+  #   bearer_token = "/run/secrets/kubernetes.io/serviceaccount/token"
+  #   # bearer_token_string = "<your-token-string>"
+  #
+  #   ## "service" for endpoints (by endpoint slices) of services, or "pod" for pods.
+  #   role = "service"
+  #
+  #   ## Namespaces to discover, all namespaces if empty.
+  #   namespaces = []
+  #
+  #   ## Select services or pods by labels, such as "app=nginx,tier!=db".
+  #   label_selector = ""
+  #
+  #   ## Discover targets on the node of the DataKit only, with election
+  #   ## disabled each DataKit of the DaemonSet scrapes its own node.
+  #   node_local = false
+  #
+  #   ## Only discover ones annotated with prometheus.io/scrape: "true".
+  #   scrape_annotated_only = true
+  #
+  #   ## Defaults of the annotations prometheus.io/port, prometheus.io/path
+  #   ## and prometheus.io/scheme. Port is a number or the name of the port.
+  #   port = ""
+  #   path = "/metrics"
+  #   scheme = "http"
+  #
+  #   ## Labels of services or pods added as tags.
+  #   labels_as_tags = ["app"]
+  #
+  #   refresh_interval = "1m"
+
   ## Customize tags.
   [inputs.prom.tags]
   # some_tag = "some_value"
@@ -7,9 +7,11 @@
 package prom
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
+	"reflect"
 	"regexp"
 	"time"
 
@@ -72,8 +74,16 @@ type Input struct {
 
 	Auth map[string]string `toml:"auth" json:"auth"`
 
+	ScrapeProtobuf bool `toml:"scrape_protobuf" json:"scrape_protobuf"`
+
+	KubernetesDiscovery *KubernetesDiscovery `toml:"kubernetes_discovery" json:"kubernetes_discovery"`
+
 	pm *iprom.Prom
 
+	// targets discovered in Kubernetes
+	targets       []*scrapeTarget
+	lastRefreshed time.Time
+
 	Election bool `toml:"election"`
 	chPause  chan bool
 	pause    bool
@@ -114,7 +124,7 @@ func (i *Input) Run() {
 		return
 	}
 
-	tick := time.NewTicker(i.pm.Option().GetIntervalDuration())
+	tick := time.NewTicker(i.GetIntervalDuration())
 	defer tick.Stop()
 
 	i.l.Info("prom start")
@@ -154,6 +164,9 @@ func (i *Input) GetIntervalDuration() time.Duration {
 			return defaultIntervalDuration
 		}
 	}
+	if i.pm == nil { // no static URLs, only targets discovered
+		return i.promOption(nil, nil).GetIntervalDuration()
+	}
 	return i.pm.Option().GetIntervalDuration()
 }
 
@@ -166,6 +179,8 @@ func (i *Input) RunningCollect() error {
 
 	ioname := inputName + "/" + i.Source
 
+	i.refreshTargets(ioname)
+
 	start := time.Now()
 	pts := i.doCollect()
 	if i.AsLogging != nil && i.AsLogging.Enable {
@@ -265,6 +280,30 @@ func (i *Input) Init() error {
 		i.urls = append(i.urls, uu)
 	}
 
+	if i.KubernetesDiscovery != nil {
+		if err := i.KubernetesDiscovery.setup(); err != nil {
+			i.l.Warnf("kubernetes_discovery: %s", err)
+			return err
+		}
+
+		if len(i.URLs) == 0 { // urls optional if targets discovered
+			i.isInitialized = true
+			return nil
+		}
+	}
+
+	pm, err := iprom.NewProm(i.promOption(i.URLs, i.Tags))
+	if err != nil {
+		i.l.Warnf("prom.NewProm: %s, ignored", err)
+		return err
+	}
+	i.pm = pm
+	i.isInitialized = true
+
+	return nil
+}
+
+func (i *Input) promOption(urls []string, tags map[string]string) *iprom.Option {
 	kvIgnore := iprom.IgnoreTagKeyValMatch{}
 	for k, arr := range i.IgnoreTagKV {
 		for _, x := range arr {
@@ -282,8 +321,7 @@ func (i *Input) Init() error {
 		Interval: i.Interval,
 		Timeout:  i.Timeout,
 
-		URL:  i.URL,
-		URLs: i.URLs,
+		URLs: urls,
 
 		MetricTypes: i.MetricTypes,
 
@@ -300,7 +338,7 @@ func (i *Input) Init() error {
 		CertFile:   i.CertFile,
 		KeyFile:    i.KeyFile,
 
-		Tags:               i.Tags,
+		Tags:               tags,
 		TagsIgnore:         i.TagsIgnore,
 		IgnoreTagKV:        kvIgnore,
 		HTTPHeaders:        i.HTTPHeaders,
@@ -313,23 +351,17 @@ func (i *Input) Init() error {
 		MaxFileSize: i.MaxFileSize,
 		Auth:        i.Auth,
 
-		Election: i.Election,
-	}
+		ScrapeProtobuf: i.ScrapeProtobuf,
 
-	pm, err := iprom.NewProm(opt)
-	if err != nil {
-		i.l.Warnf("prom.NewProm: %s, ignored", err)
-		return err
+		Election: i.Election,
 	}
-	i.pm = pm
-	i.isInitialized = true
 
-	return nil
+	return opt
 }
 
-// Collect collects metrics from all URLs.
+// Collect collects metrics from all URLs and targets discovered.
 func (i *Input) Collect() ([]*point.Point, error) {
-	if i.pm == nil {
+	if i.pm == nil && len(i.targets) == 0 {
 		return nil, nil
 	}
 	var points []*point.Point
@@ -349,9 +381,80 @@ func (i *Input) Collect() ([]*point.Point, error) {
 		}
 		points = append(points, pts...)
 	}
+
+	// targets come and go with pods, failed ones not break others
+	for _, t := range i.targets {
+		pts, err := t.pm.CollectFromHTTP(t.url)
+		if err != nil {
+			i.l.Warnf("collect target %s: %s", t.url, err)
+			continue
+		}
+		points = append(points, pts...)
+	}
+
 	return points, nil
 }
 
+type scrapeTarget struct {
+	*target
+	pm *iprom.Prom
+}
+
+// refreshTargets discovers targets if refresh_interval elapsed. Targets
+// unchanged are kept, previous targets kept on failure.
+func (i *Input) refreshTargets(ioname string) {
+	d := i.KubernetesDiscovery
+	if d == nil || time.Since(i.lastRefreshed) < d.RefreshInterval.Duration {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.RefreshInterval.Duration)
+	defer cancel()
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		i.l.Errorf("kubernetes_discovery: %s", err)
+		io.FeedLastError(ioname, err.Error())
+		return
+	}
+	i.lastRefreshed = time.Now()
+	sortTargets(targets)
+
+	prev := map[string]*scrapeTarget{}
+	for _, t := range i.targets {
+		prev[t.url] = t
+	}
+
+	var res []*scrapeTarget
+	for _, t := range targets {
+		if x, ok := prev[t.url]; ok && reflect.DeepEqual(x.tags, t.tags) {
+			res = append(res, x)
+			continue
+		}
+
+		// tags configured take precedence over tags of the target
+		tags := map[string]string{}
+		for k, v := range t.tags {
+			tags[k] = v
+		}
+		for k, v := range i.Tags {
+			tags[k] = v
+		}
+
+		pm, err := iprom.NewProm(i.promOption([]string{t.url}, tags))
+		if err != nil {
+			i.l.Warnf("prom.NewProm(%s): %s, ignored", t.url, err)
+			continue
+		}
+		res = append(res, &scrapeTarget{target: t, pm: pm})
+	}
+
+	if len(res) != len(i.targets) {
+		i.l.Infof("kubernetes_discovery: %d targets discovered", len(res))
+	}
+	i.targets = res
+}
+
 func (i *Input) CollectFromHTTP(u string) ([]*point.Point, error) {
 	if i.pm == nil {
 		return nil, nil
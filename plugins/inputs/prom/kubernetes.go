@@ -0,0 +1,378 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	rolePod     = "pod"
+	roleService = "service"
+
+	annotationScrape = "prometheus.io/scrape"
+	annotationPort   = "prometheus.io/port"
+	annotationPath   = "prometheus.io/path"
+	annotationScheme = "prometheus.io/scheme"
+
+	defaultRefreshInterval = time.Minute
+)
+
+// KubernetesDiscovery discovers scrape targets from pods, or from endpoint
+// slices of services, of the Kubernetes cluster.
+type KubernetesDiscovery struct {
+	K8sURL               string `toml:"kubernetes_url"`
+	K8sBearerToken       string `toml:"bearer_token"`
+	K8sBearerTokenString string `toml:"bearer_token_string"`
+
+	Role          string   `toml:"role"`
+	Namespaces    []string `toml:"namespaces"`
+	LabelSelector string   `toml:"label_selector"`
+	NodeLocal     bool     `toml:"node_local"`
+
+	// Targets without annotation prometheus.io/scrape skipped if set,
+	// annotated ones with prometheus.io/scrape "false" always skipped.
+	ScrapeAnnotatedOnly bool `toml:"scrape_annotated_only"`
+
+	// Defaults of targets not annotated by prometheus.io/port, path and scheme.
+	Port   string `toml:"port"`
+	Path   string `toml:"path"`
+	Scheme string `toml:"scheme"`
+
+	LabelsAsTags    []string         `toml:"labels_as_tags"`
+	RefreshInterval datakit.Duration `toml:"refresh_interval"`
+
+	client   kubernetes.Interface
+	nodeName string
+}
+
+// target is a discovered exporter URL, with tags of the Kubernetes resource.
+type target struct {
+	url  string
+	tags map[string]string
+}
+
+func (d *KubernetesDiscovery) setup() error {
+	switch d.Role {
+	case "":
+		d.Role = roleService
+	case roleService, rolePod:
+	default:
+		return fmt.Errorf("invalid role %q, only %q and %q supported", d.Role, roleService, rolePod)
+	}
+
+	if d.Path == "" {
+		d.Path = "/metrics"
+	}
+	if d.Scheme == "" {
+		d.Scheme = "http"
+	}
+	if d.RefreshInterval.Duration <= 0 {
+		d.RefreshInterval.Duration = defaultRefreshInterval
+	}
+	if len(d.Namespaces) == 0 {
+		d.Namespaces = []string{metav1.NamespaceAll}
+	}
+
+	if d.NodeLocal {
+		if d.nodeName = os.Getenv("ENV_K8S_NODE_NAME"); d.nodeName == "" {
+			return fmt.Errorf("node_local requires ENV_K8S_NODE_NAME")
+		}
+	}
+
+	if d.K8sURL == "" {
+		return fmt.Errorf("invalid kubernetes_url, cannot be empty")
+	}
+
+	token := d.K8sBearerTokenString
+	if token == "" && d.K8sBearerToken != "" {
+		b, err := os.ReadFile(filepath.Clean(d.K8sBearerToken))
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	cli, err := kubernetes.NewForConfig(&rest.Config{
+		Host:        d.K8sURL,
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: true,
+		},
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(100, 100),
+	})
+	if err != nil {
+		return err
+	}
+
+	d.client = cli
+	return nil
+}
+
+// targets lists targets of all namespaces configured.
+func (d *KubernetesDiscovery) targets(ctx context.Context) ([]*target, error) {
+	var res []*target
+
+	for _, ns := range d.Namespaces {
+		var (
+			x   []*target
+			err error
+		)
+
+		if d.Role == rolePod {
+			x, err = d.listPodTargets(ctx, ns)
+		} else {
+			x, err = d.listServiceTargets(ctx, ns)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, x...)
+	}
+
+	return res, nil
+}
+
+func (d *KubernetesDiscovery) listPodTargets(ctx context.Context, namespace string) ([]*target, error) {
+	fieldSelector := "status.phase=Running"
+	if d.nodeName != "" {
+		fieldSelector += ",spec.nodeName=" + d.nodeName
+	}
+
+	list, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: d.LabelSelector,
+		FieldSelector: fieldSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+
+	return d.podTargets(list.Items), nil
+}
+
+func (d *KubernetesDiscovery) listServiceTargets(ctx context.Context, namespace string) ([]*target, error) {
+	services, err := d.client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{LabelSelector: d.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+
+	if len(services.Items) == 0 {
+		return nil, nil
+	}
+
+	slices, err := d.client.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list endpointslices: %w", err)
+	}
+
+	// endpoint slices of each service, by namespace/name
+	slicesOf := map[string][]discoveryv1.EndpointSlice{}
+	for _, s := range slices.Items {
+		svc := s.Labels[discoveryv1.LabelServiceName]
+		if svc == "" {
+			continue
+		}
+		key := s.Namespace + "/" + svc
+		slicesOf[key] = append(slicesOf[key], s)
+	}
+
+	var res []*target
+	for i := range services.Items {
+		svc := &services.Items[i]
+		res = append(res, d.serviceTargets(svc, slicesOf[svc.Namespace+"/"+svc.Name])...)
+	}
+
+	return res, nil
+}
+
+// podTargets returns a target for each running pod to scrape.
+func (d *KubernetesDiscovery) podTargets(pods []corev1.Pod) []*target {
+	var res []*target
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.PodIP == "" || !d.shouldScrape(pod.Annotations) {
+			continue
+		}
+
+		port := d.annotated(pod.Annotations, annotationPort, d.Port)
+		if _, err := strconv.Atoi(port); err != nil {
+			port = containerPort(pod, port)
+		}
+		if port == "" {
+			l.Debugf("no port of pod %s/%s, skipped", pod.Namespace, pod.Name)
+			continue
+		}
+
+		tags := d.labelTags(pod.Labels)
+		tags["namespace"] = pod.Namespace
+		tags["pod_name"] = pod.Name
+		if pod.Spec.NodeName != "" {
+			tags["node_name"] = pod.Spec.NodeName
+		}
+
+		res = append(res, &target{url: d.targetURL(pod.Annotations, pod.Status.PodIP, port), tags: tags})
+	}
+
+	return res
+}
+
+// serviceTargets returns a target for each ready endpoint of the service.
+func (d *KubernetesDiscovery) serviceTargets(svc *corev1.Service, slices []discoveryv1.EndpointSlice) []*target {
+	if !d.shouldScrape(svc.Annotations) {
+		return nil
+	}
+
+	want := d.annotated(svc.Annotations, annotationPort, d.Port)
+
+	var res []*target
+	for i := range slices {
+		s := &slices[i]
+		if s.AddressType != discoveryv1.AddressTypeIPv4 && s.AddressType != discoveryv1.AddressTypeIPv6 {
+			continue
+		}
+
+		port := slicePort(s, want)
+		if port == "" {
+			continue
+		}
+
+		for _, ep := range s.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+
+			if d.nodeName != "" && (ep.NodeName == nil || *ep.NodeName != d.nodeName) {
+				continue
+			}
+
+			for _, addr := range ep.Addresses {
+				tags := d.labelTags(svc.Labels)
+				tags["namespace"] = svc.Namespace
+				tags["service_name"] = svc.Name
+				if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+					tags["pod_name"] = ep.TargetRef.Name
+				}
+				if ep.NodeName != nil && *ep.NodeName != "" {
+					tags["node_name"] = *ep.NodeName
+				}
+
+				res = append(res, &target{url: d.targetURL(svc.Annotations, addr, port), tags: tags})
+			}
+		}
+	}
+
+	return res
+}
+
+func (d *KubernetesDiscovery) shouldScrape(annotations map[string]string) bool {
+	v, ok := annotations[annotationScrape]
+	if !ok {
+		return !d.ScrapeAnnotatedOnly
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.Warnf("invalid annotation %s: %q, skipped", annotationScrape, v)
+		return false
+	}
+
+	return b
+}
+
+func (*KubernetesDiscovery) annotated(annotations map[string]string, key, def string) string {
+	if v := annotations[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+func (d *KubernetesDiscovery) targetURL(annotations map[string]string, host, port string) string {
+	u := url.URL{
+		Scheme: d.annotated(annotations, annotationScheme, d.Scheme),
+		Host:   net.JoinHostPort(host, port),
+		Path:   d.annotated(annotations, annotationPath, d.Path),
+	}
+	return u.String()
+}
+
+// labelTags returns tags of labels configured in labels_as_tags, with '.',
+// '/' and '-' in keys replaced by '_'.
+func (d *KubernetesDiscovery) labelTags(labels map[string]string) map[string]string {
+	tags := map[string]string{}
+	for _, k := range d.LabelsAsTags {
+		if v, ok := labels[k]; ok {
+			tags[strings.NewReplacer(".", "_", "/", "_", "-", "_").Replace(k)] = v
+		}
+	}
+	return tags
+}
+
+// containerPort returns the port number of the container port named name,
+// or the only container port if name is empty.
+func containerPort(pod *corev1.Pod, name string) string {
+	var ports []corev1.ContainerPort
+	for _, c := range pod.Spec.Containers {
+		ports = append(ports, c.Ports...)
+	}
+
+	for _, p := range ports {
+		if (name == "" && len(ports) == 1) || (name != "" && p.Name == name) {
+			return strconv.Itoa(int(p.ContainerPort))
+		}
+	}
+
+	return ""
+}
+
+// slicePort returns the port number of the endpoint slice port named want,
+// or want itself if it's a number. The only port of the slice is used if
+// want is empty.
+func slicePort(s *discoveryv1.EndpointSlice, want string) string {
+	if _, err := strconv.Atoi(want); err == nil {
+		return want
+	}
+
+	for _, p := range s.Ports {
+		if p.Port == nil {
+			continue
+		}
+
+		name := ""
+		if p.Name != nil {
+			name = *p.Name
+		}
+
+		if (want == "" && len(s.Ports) == 1) || (want != "" && name == want) {
+			return strconv.Itoa(int(*p.Port))
+		}
+	}
+
+	return ""
+}
+
+// sortTargets sorts targets by URL, so scraping order is stable between refreshes.
+func sortTargets(targets []*target) {
+	sort.Slice(targets, func(i, j int) bool { return targets[i].url < targets[j].url })
+}
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package prom
+
+import (
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodTargets(t *T.T) {
+	pod := func(name, ip string, annotations map[string]string, ports ...corev1.ContainerPort) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "web",
+				Labels:      map[string]string{"app.kubernetes.io/name": "nginx"},
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []corev1.Container{{Ports: ports}},
+			},
+			Status: corev1.PodStatus{PodIP: ip},
+		}
+	}
+
+	d := &KubernetesDiscovery{
+		ScrapeAnnotatedOnly: true,
+		Port:                "metrics",
+		Path:                "/metrics",
+		Scheme:              "http",
+		LabelsAsTags:        []string{"app.kubernetes.io/name"},
+	}
+
+	targets := d.podTargets([]corev1.Pod{
+		pod("annotated", "10.0.0.1", map[string]string{
+			annotationScrape: "true",
+			annotationPort:   "9113",
+			annotationPath:   "/stats",
+		}),
+		pod("named-port", "10.0.0.2", map[string]string{annotationScrape: "true"},
+			corev1.ContainerPort{Name: "http", ContainerPort: 80},
+			corev1.ContainerPort{Name: "metrics", ContainerPort: 9100}),
+		pod("not-annotated", "10.0.0.3", nil, corev1.ContainerPort{Name: "metrics", ContainerPort: 9100}),
+		pod("disabled", "10.0.0.4", map[string]string{annotationScrape: "false"}),
+		pod("no-ip", "", map[string]string{annotationScrape: "true", annotationPort: "9113"}),
+		pod("no-port", "10.0.0.5", map[string]string{annotationScrape: "true"}),
+	})
+
+	assert.Len(t, targets, 2)
+	assert.Equal(t, "http://10.0.0.1:9113/stats", targets[0].url)
+	assert.Equal(t, map[string]string{
+		"namespace":              "web",
+		"pod_name":               "annotated",
+		"node_name":              "node-1",
+		"app_kubernetes_io_name": "nginx",
+	}, targets[0].tags)
+	assert.Equal(t, "http://10.0.0.2:9100/metrics", targets[1].url)
+
+	// scrape ones not annotated
+	d.ScrapeAnnotatedOnly = false
+	assert.Len(t, d.podTargets([]corev1.Pod{
+		pod("not-annotated", "10.0.0.3", nil, corev1.ContainerPort{Name: "metrics", ContainerPort: 9100}),
+	}), 1)
+}
+
+func TestServiceTargets(t *T.T) {
+	var (
+		ready, notReady = true, false
+		portName        = "metrics"
+		port            = int32(9100)
+		node1, node2    = "node-1", "node-2"
+	)
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "nginx",
+			Namespace:   "web",
+			Annotations: map[string]string{annotationScrape: "true", annotationScheme: "https"},
+		},
+	}
+
+	slices := []discoveryv1.EndpointSlice{
+		{
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+			Endpoints: []discoveryv1.Endpoint{
+				{
+					Addresses:  []string{"10.0.0.1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					NodeName:   &node1,
+					TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "nginx-1"},
+				},
+				{
+					Addresses:  []string{"10.0.0.2"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+				},
+				{
+					Addresses: []string{"10.0.0.3"},
+					NodeName:  &node2,
+				},
+			},
+		},
+		{
+			AddressType: discoveryv1.AddressTypeFQDN,
+			Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"nginx.example.com"}}},
+		},
+	}
+
+	d := &KubernetesDiscovery{Port: "metrics", Path: "/metrics", Scheme: "http"}
+
+	targets := d.serviceTargets(svc, slices)
+	assert.Len(t, targets, 2)
+	assert.Equal(t, "https://10.0.0.1:9100/metrics", targets[0].url)
+	assert.Equal(t, map[string]string{
+		"namespace":    "web",
+		"service_name": "nginx",
+		"pod_name":     "nginx-1",
+		"node_name":    "node-1",
+	}, targets[0].tags)
+	assert.Equal(t, "https://10.0.0.3:9100/metrics", targets[1].url)
+
+	// endpoints of the node only
+	d.nodeName = node2
+	targets = d.serviceTargets(svc, slices)
+	assert.Len(t, targets, 1)
+	assert.Equal(t, "https://10.0.0.3:9100/metrics", targets[0].url)
+
+	// port of the name not found
+	d.nodeName = ""
+	d.Port = "http"
+	assert.Empty(t, d.serviceTargets(svc, slices))
+}
+
+func TestKubernetesDiscoverySetup(t *T.T) {
+	d := &KubernetesDiscovery{Role: "node", K8sURL: "https://kubernetes.default:443"}
+	assert.Error(t, d.setup())
+
+	d = &KubernetesDiscovery{K8sURL: "https://kubernetes.default:443", K8sBearerTokenString: "token"}
+	assert.NoError(t, d.setup())
+	assert.Equal(t, roleService, d.Role)
+	assert.Equal(t, "/metrics", d.Path)
+	assert.Equal(t, "http", d.Scheme)
+	assert.Equal(t, defaultRefreshInterval, d.RefreshInterval.Duration)
+	assert.Equal(t, []string{""}, d.Namespaces)
+}
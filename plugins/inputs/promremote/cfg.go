@@ -8,6 +8,7 @@ package promremote
 import (
 	"time"
 
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -54,6 +55,10 @@ const (
   # measurement_prefix will be added to the start of measurement_name
   # measurement_name = "prom_remote_write"
 
+  ## keep exemplars(such as trace_id of the sample) as field <metric-name>_exemplar,
+  ## labels of the exemplar are added as tags
+  # keep_exemplars = false
+
   ## max body size in bytes, default set to 500MB
   # max_body_size = 0
 
@@ -102,7 +107,7 @@ type Measurement struct {
 }
 
 func (m *Measurement) LineProto() (*point.Point, error) {
-	return point.NewPoint(m.name, m.tags, m.fields, point.MOpt())
+	return point.NewPoint(m.name, m.tags, m.fields, &point.PointOption{Category: datakit.Metric, Time: m.ts})
 }
 
 //nolint:lll
@@ -282,14 +282,21 @@ func (h *Input) collectBody(res http.ResponseWriter, req *http.Request) ([]byte,
 		return bytes, true
 	case "snappy":
 		defer req.Body.Close() //nolint:errcheck
-		bytes, err := io.ReadAll(req.Body)
+		bytes, err := io.ReadAll(http.MaxBytesReader(res, req.Body, h.MaxBodySize))
 		if err != nil {
-			l.Debug(err.Error())
-			if err := badRequest(res); err != nil {
-				l.Debugf("error in bad-request: %v", err)
+			if err := tooLarge(res); err != nil {
+				l.Debugf("error in too-large: %v", err)
 			}
 			return nil, false
 		}
+
+		if n, err := snappy.DecodedLen(bytes); err == nil && int64(n) > h.MaxBodySize {
+			if err := tooLarge(res); err != nil {
+				l.Debugf("error in too-large: %v", err)
+			}
+			return nil, false
+		}
+
 		// snappy block format is only supported by decode/encode not snappy reader/writer
 		bytes, err = snappy.Decode(nil, bytes)
 		if err != nil {
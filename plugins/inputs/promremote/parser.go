@@ -21,8 +21,12 @@ type Parser struct {
 	MeasurementNameFilter []string `toml:"measurement_name_filter"`
 	MeasurementPrefix     string   `toml:"measurement_prefix"`
 	MeasurementName       string   `toml:"measurement_name"`
+	KeepExemplars         bool     `toml:"keep_exemplars"`
 }
 
+// exemplarSuffix is appended to the metric name of exemplar fields.
+const exemplarSuffix = "_exemplar"
+
 // Parse parses given byte as protocol buffer. it performs necessary
 // metric filtering and prefixing, and returns parsed measurements.
 func (p *Parser) Parse(buf []byte) ([]inputs.Measurement, error) {
@@ -74,6 +78,44 @@ func (p *Parser) Parse(buf []byte) ([]inputs.Measurement, error) {
 				metrics = append(metrics, m)
 			}
 		}
+
+		if p.KeepExemplars {
+			metrics = append(metrics, exemplars(ts.Exemplars, measurementName, metricName, tags, now)...)
+		}
 	}
 	return metrics, err
 }
+
+// exemplars converts exemplars of a series into measurements, labels of the
+// exemplar(such as trace_id and span_id) are added to the tags of the series.
+func exemplars(exs []prompb.Exemplar, measurementName, metricName string,
+	seriesTags map[string]string, now time.Time,
+) []inputs.Measurement {
+	var metrics []inputs.Measurement
+	for _, e := range exs {
+		if math.IsNaN(e.Value) {
+			continue
+		}
+
+		tags := make(map[string]string, len(seriesTags)+len(e.Labels))
+		for k, v := range seriesTags {
+			tags[k] = v
+		}
+		for _, l := range e.Labels {
+			tags[l.Name] = l.Value
+		}
+
+		t := now
+		if e.Timestamp > 0 {
+			t = time.Unix(0, e.Timestamp*1000000)
+		}
+
+		metrics = append(metrics, &Measurement{
+			name:   measurementName,
+			tags:   tags,
+			fields: map[string]interface{}{metricName + exemplarSuffix: e.Value},
+			ts:     t,
+		})
+	}
+	return metrics
+}
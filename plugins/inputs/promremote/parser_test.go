@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package promremote
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRequest(t *testing.T) []byte {
+	t.Helper()
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "http_request_duration_seconds_bucket"},
+					{Name: "le", Value: "0.5"},
+				},
+				Samples: []prompb.Sample{
+					{Value: 3, Timestamp: 1680000000000},
+				},
+				Exemplars: []prompb.Exemplar{
+					{
+						Labels:    []prompb.Label{{Name: "trace_id", Value: "abc123"}},
+						Value:     0.27,
+						Timestamp: 1680000000001,
+					},
+				},
+			},
+		},
+	}
+
+	data, err := proto.Marshal(req)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseExemplars(t *testing.T) {
+	data := writeRequest(t)
+
+	t.Run("drop-exemplars", func(t *testing.T) {
+		p := &Parser{}
+		ms, err := p.Parse(data)
+		require.NoError(t, err)
+		require.Len(t, ms, 1)
+
+		m := ms[0].(*Measurement)
+		assert.Equal(t, "http", m.name)
+		assert.Equal(t, map[string]interface{}{"request_duration_seconds_bucket": 3.0}, m.fields)
+		assert.Equal(t, time.UnixMilli(1680000000000), m.ts)
+
+		pt, err := m.LineProto()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1680000000000)*int64(time.Millisecond), pt.Time().UnixNano())
+	})
+
+	t.Run("keep-exemplars", func(t *testing.T) {
+		p := &Parser{KeepExemplars: true}
+		ms, err := p.Parse(data)
+		require.NoError(t, err)
+		require.Len(t, ms, 2)
+
+		m := ms[1].(*Measurement)
+		assert.Equal(t, "http", m.name)
+		assert.Equal(t, map[string]string{"le": "0.5", "trace_id": "abc123"}, m.tags)
+		assert.Equal(t, map[string]interface{}{"request_duration_seconds_bucket_exemplar": 0.27}, m.fields)
+		assert.Equal(t, time.UnixMilli(1680000000001), m.ts)
+
+		// tags of the sample not polluted by exemplar labels
+		assert.Equal(t, map[string]string{"le": "0.5"}, ms[0].(*Measurement).tags)
+	})
+}
+
+func TestCollectSnappyBody(t *testing.T) {
+	data := writeRequest(t)
+	compressed := snappy.Encode(nil, data)
+
+	t.Run("ok", func(t *testing.T) {
+		ipt := NewInput()
+		req := httptest.NewRequest(http.MethodPost, "/prom_remote_write", bytes.NewReader(compressed))
+		req.Header.Set("Content-Encoding", "snappy")
+		rec := httptest.NewRecorder()
+
+		body, ok := ipt.collectBody(rec, req)
+		require.True(t, ok)
+		assert.Equal(t, data, body)
+	})
+
+	t.Run("too-large", func(t *testing.T) {
+		// compressed body within the limit, but decoded not
+		large := bytes.Repeat([]byte("a"), 1024)
+		ipt := NewInput()
+		ipt.MaxBodySize = 512
+		req := httptest.NewRequest(http.MethodPost, "/prom_remote_write", bytes.NewReader(snappy.Encode(nil, large)))
+		req.Header.Set("Content-Encoding", "snappy")
+		rec := httptest.NewRecorder()
+
+		_, ok := ipt.collectBody(rec, req)
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
@@ -27,15 +27,27 @@ const (
   #  以 legacy 版本接口处理请求时设置为 true，对应 loki 的 API 为 /api/prom/push。
   legacy = false
 
+  # Use value of the stream label as source(and default pipeline <source>.p) of
+  # logs, such as "job". If not set or the label not found, source is from URL
+  # argument source.
+  # source_label = "job"
+
   [inputs.promtail.tags]
     # some_tag = "some_value"
     # more_tag = "some_other_value"
 `
 )
 
+const (
+	// Loki push APIs, so promtail and Grafana Agent can push to datakit as to Loki.
+	lokiPushPath       = "/loki/api/v1/push"
+	lokiLegacyPushPath = "/api/prom/push"
+)
+
 type Input struct {
-	Legacy bool              `toml:"legacy"`
-	Tags   map[string]string `toml:"tags"`
+	Legacy      bool              `toml:"legacy"`
+	SourceLabel string            `toml:"source_label"`
+	Tags        map[string]string `toml:"tags"`
 }
 
 type promtailSampleMeasurement struct{}
@@ -60,7 +72,8 @@ func (i *Input) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	var (
 		pipelinePath = getPipelinePath(req)
 		source       = getSource(req)
-		pts          []*point.Point
+		customTags   = getCustomTags(req)
+		pts          = map[string][]*point.Point{} // source -> points
 	)
 	l.Debugf("receive log from %s, source = %s, pipeline = %s", req.URL.String(), source, pipelinePath)
 	request, err := i.parseRequest(req)
@@ -85,7 +98,6 @@ func (i *Input) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		for _, lb := range lbs {
 			tags[lb.Name] = lb.Value
 		}
-		customTags := getCustomTags(req)
 		for k, v := range customTags {
 			tags[k] = v
 		}
@@ -93,24 +105,43 @@ func (i *Input) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 			tags[k] = v
 		}
 
+		streamSource := i.streamSource(source, tags)
+
 		for _, e := range s.Entries {
-			pt, err := point.NewPoint(source, tags, map[string]interface{}{
+			pt, err := point.NewPoint(streamSource, tags, map[string]interface{}{
 				pipeline.FieldMessage: e.Line,
 				pipeline.FieldStatus:  pipeline.DefaultStatus,
-			}, point.LOpt())
+			}, &point.PointOption{Category: datakit.Logging, Time: e.Timestamp})
 			if err != nil {
 				l.Error(err)
 			} else {
-				pts = append(pts, pt)
+				pts[streamSource] = append(pts[streamSource], pt)
 			}
 		}
 	}
-	l.Debugf("received %d logs from promtail, feeding to io...", len(pts))
-	if err := dkio.Feed(source, datakit.Logging, pts, &dkio.Option{PlScript: map[string]string{source: pipelinePath}}); err != nil {
-		resp.WriteHeader(http.StatusInternalServerError)
-	} else {
-		resp.WriteHeader(http.StatusNoContent)
+
+	status := http.StatusNoContent
+	for src, arr := range pts {
+		l.Debugf("received %d logs of %s from promtail, feeding to io...", len(arr), src)
+		if err := dkio.Feed(src, datakit.Logging, arr, &dkio.Option{PlScript: map[string]string{src: pipelinePath}}); err != nil {
+			l.Warnf("feed %d logs of %s: %s", len(arr), src, err)
+			status = http.StatusInternalServerError
+		}
 	}
+	resp.WriteHeader(status)
+}
+
+// streamSource returns value of the source label of the stream if found, or
+// the source of the request.
+func (i *Input) streamSource(source string, tags map[string]string) string {
+	if i.SourceLabel == "" {
+		return source
+	}
+
+	if v := tags[i.SourceLabel]; v != "" {
+		return v
+	}
+	return source
 }
 
 func (i *Input) Catalog() string {
@@ -140,6 +171,12 @@ func (i *Input) Terminate() {
 func (i *Input) RegHTTPHandler() {
 	l = logger.SLogger(inputName)
 	dhttp.RegHTTPHandler("POST", "/v1/write/promtail", ihttp.ProtectedHandlerFunc(i.ServeHTTP, l))
+
+	pushPath := lokiPushPath
+	if i.Legacy {
+		pushPath = lokiLegacyPushPath
+	}
+	dhttp.RegHTTPHandler("POST", pushPath, ihttp.ProtectedHandlerFunc(i.ServeHTTP, l))
 }
 
 //nolint:gochecknoinits
@@ -296,3 +296,36 @@ func TestGetCustomTags(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamSource(t *testing.T) {
+	cases := []struct {
+		name        string
+		sourceLabel string
+		tags        map[string]string
+		expected    string
+	}{
+		{
+			name:     "no source label",
+			tags:     map[string]string{"job": "nginx"},
+			expected: "default",
+		},
+		{
+			name:        "source label found",
+			sourceLabel: "job",
+			tags:        map[string]string{"job": "nginx"},
+			expected:    "nginx",
+		},
+		{
+			name:        "source label not found",
+			sourceLabel: "app",
+			tags:        map[string]string{"job": "nginx"},
+			expected:    "default",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			i := &Input{SourceLabel: tc.sourceLabel}
+			assert.Equal(t, tc.expected, i.streamSource("default", tc.tags))
+		})
+	}
+}
@@ -7,6 +7,7 @@ package inputs
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"time"
 
@@ -31,6 +32,11 @@ type ptChecker struct {
 
 	optionalFields []string
 	optionalTags   []string
+	volatileKeys   []string
+
+	// tolerances on comparing with expected point
+	numericTolerance float64
+	timeTolerance    time.Duration
 
 	// check result
 	checkMsg []string
@@ -113,6 +119,26 @@ func WithOptionalTags(keys ...string) PointCheckOption {
 	}
 }
 
+// WithNumericTolerance set relative tolerance on comparing numeric field
+// values with expected point, i.e., 0.05 means 5% jitter accepted.
+func WithNumericTolerance(rel float64) PointCheckOption {
+	return func(c *ptChecker) { c.numericTolerance = rel }
+}
+
+// WithTimeTolerance enable checking point time against expected point, the
+// time difference within d are accepted.
+func WithTimeTolerance(d time.Duration) PointCheckOption {
+	return func(c *ptChecker) { c.timeTolerance = d }
+}
+
+// WithVolatileKeys set tag/field keys whose value are changed between runs,
+// such as host or pid, only their existence and type are checked.
+func WithVolatileKeys(keys ...string) PointCheckOption {
+	return func(c *ptChecker) {
+		c.volatileKeys = append(c.volatileKeys, keys...)
+	}
+}
+
 // CheckPoint used to check pt with various options. If any checking
 // failed, the failed message are returned.
 func CheckPoint(pt *point.Point, opts ...PointCheckOption) []string {
@@ -159,8 +185,11 @@ func (c *ptChecker) checkOnPoint(pt *point.Point) {
 	}
 
 	for _, kv := range c.expTags {
-		if c.gotTags.Get(kv.Key) == nil {
+		if got := c.gotTags.Get(kv.Key); got == nil {
 			c.addMsg(fmt.Sprintf("unknown tag %q", kv.Key))
+		} else if c.checkValues && !c.isVolatileKey(string(kv.Key)) && kv.GetD() != nil &&
+			string(kv.GetD()) != string(got.GetD()) {
+			c.addMsg(fmt.Sprintf("expect tag %q value %q got %q", kv.Key, kv.GetD(), got.GetD()))
 		}
 	}
 
@@ -168,7 +197,7 @@ func (c *ptChecker) checkOnPoint(pt *point.Point) {
 		if got := c.gotFields.Get(kv.Key); got == nil {
 			c.addMsg(fmt.Sprintf("expect field %q not found", kv.Key))
 		} else if c.checkTypes || c.checkValues {
-			if !reflect.DeepEqual(kv.Val, got.Val) {
+			if !c.valueEqual(kv, got) {
 				c.addMsg(fmt.Sprintf("expect field %q type %q value %v got type %q value %v",
 					kv.Key,
 					reflect.TypeOf(kv.Val), kv.Val,
@@ -176,6 +205,45 @@ func (c *ptChecker) checkOnPoint(pt *point.Point) {
 			}
 		}
 	}
+
+	if c.timeTolerance > 0 {
+		if diff := c.gotTime.Sub(c.expTime); diff > c.timeTolerance || diff < -c.timeTolerance {
+			c.addMsg(fmt.Sprintf("expect time %s got %s, exceed tolerance %s", c.expTime, c.gotTime, c.timeTolerance))
+		}
+	}
+}
+
+func (c *ptChecker) valueEqual(exp, got *point.Field) bool {
+	if reflect.TypeOf(exp.Val) != reflect.TypeOf(got.Val) {
+		return false
+	}
+
+	if c.isVolatileKey(string(exp.Key)) {
+		return true
+	}
+
+	if c.numericTolerance > 0 {
+		x, ok1 := numericValue(exp)
+		y, ok2 := numericValue(got)
+		if ok1 && ok2 {
+			return math.Abs(x-y) <= c.numericTolerance*math.Max(math.Abs(x), math.Abs(y))
+		}
+	}
+
+	return reflect.DeepEqual(exp.Val, got.Val)
+}
+
+func numericValue(f *point.Field) (float64, bool) {
+	switch x := f.Val.(type) {
+	case *point.Field_I:
+		return float64(x.I), true
+	case *point.Field_U:
+		return float64(x.U), true
+	case *point.Field_F:
+		return x.F, true
+	default:
+		return 0, false
+	}
 }
 
 func (c *ptChecker) checkOnDoc(pt *point.Point) {
@@ -281,6 +349,16 @@ func (c *ptChecker) isOptionalTag(key string) bool {
 	return false
 }
 
+func (c *ptChecker) isVolatileKey(key string) bool {
+	for _, x := range c.volatileKeys {
+		if x == key {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *ptChecker) isOptionalField(key string) bool {
 	for _, x := range c.optionalFields {
 		if x == key {
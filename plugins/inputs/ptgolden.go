@@ -0,0 +1,257 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package inputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+// CheckGolden compare pts with points in golden file path. On first run(the
+// file not exist) or golden updating enabled, pts are dumped to the file and
+// nothing checked.
+//
+// Points are paired by measurement and tags(volatile keys excluded), then each
+// pair checked by CheckPoint with value checking on, and opts applied, so
+// tolerances on timestamp and numeric jitter can be set via
+// WithTimeTolerance/WithNumericTolerance.
+func CheckGolden(path string, pts []*point.Point, opts ...PointCheckOption) ([]string, error) {
+	expect, err := LoadGolden(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if expect == nil || goldenUpdate() {
+		return nil, DumpGolden(path, pts)
+	}
+
+	c := newPointChecker()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+
+	got := map[string][]*point.Point{}
+	for _, pt := range pts {
+		k := c.goldenKey(pt)
+		got[k] = append(got[k], pt)
+	}
+
+	var msgs []string
+	for _, exp := range expect {
+		k := c.goldenKey(exp)
+
+		candidates := got[k]
+		if len(candidates) == 0 {
+			msgs = append(msgs, fmt.Sprintf("[%s] expected point not found", k))
+			continue
+		}
+
+		// select the best matched one if there are points with the same tags.
+		idx := -1
+		var best []string
+		for i, pt := range candidates {
+			res := CheckPoint(pt, append([]PointCheckOption{WithValueChecking(true)},
+				append(opts, WithExpectPoint(exp))...)...)
+			if idx == -1 || len(res) < len(best) {
+				idx, best = i, res
+			}
+			if len(best) == 0 {
+				break
+			}
+		}
+
+		for _, m := range best {
+			msgs = append(msgs, fmt.Sprintf("[%s] %s", k, m))
+		}
+
+		got[k] = append(candidates[:idx], candidates[idx+1:]...)
+	}
+
+	var keys []string
+	for k, arr := range got {
+		for range arr {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		msgs = append(msgs, fmt.Sprintf("[%s] unexpected point", k))
+	}
+
+	return msgs, nil
+}
+
+func (c *ptChecker) goldenKey(pt *point.Point) string {
+	var arr []string
+	for k, v := range pt.InfluxTags() {
+		if c.isVolatileKey(k) {
+			continue
+		}
+		arr = append(arr, k+"="+v)
+	}
+	sort.Strings(arr)
+
+	return strings.Join(append([]string{string(pt.Name())}, arr...), ",")
+}
+
+// goldenUpdate returns true if golden files should be re-generated, set
+// env TESTING_GOLDEN_UPDATE=on to update them after expected changes.
+func goldenUpdate() bool {
+	return os.Getenv("TESTING_GOLDEN_UPDATE") == "on"
+}
+
+// goldenPoint is the normalized JSON form of a point in golden file.
+type goldenPoint struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Fields      map[string]goldenValue `json:"fields"`
+	Time        int64                  `json:"time"`
+}
+
+// goldenValue keep the field type within JSON: floats are always written
+// with a decimal point, so that int and float are distinguishable on loading.
+type goldenValue struct {
+	v any
+}
+
+func (gv goldenValue) MarshalJSON() ([]byte, error) {
+	switch x := gv.v.(type) {
+	case float64:
+		s := strconv.FormatFloat(x, 'f', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") { // NaN/Inf not expected
+			s += ".0"
+		}
+		return []byte(s), nil
+	default:
+		return json.Marshal(x)
+	}
+}
+
+func (gv *goldenValue) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var x any
+	if err := dec.Decode(&x); err != nil {
+		return err
+	}
+
+	switch v := x.(type) {
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			f, err := v.Float64()
+			if err != nil {
+				return err
+			}
+			gv.v = f
+		} else {
+			i, err := v.Int64()
+			if err != nil {
+				return err
+			}
+			gv.v = i
+		}
+	case string, bool:
+		gv.v = v
+	default:
+		return fmt.Errorf("unsupported golden value %s", string(data))
+	}
+
+	return nil
+}
+
+func (gp *goldenPoint) key() string {
+	keys := make([]string, 0, len(gp.Tags))
+	for k := range gp.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(gp.Measurement)
+	for _, k := range keys {
+		sb.WriteString("," + k + "=" + gp.Tags[k])
+	}
+
+	return sb.String()
+}
+
+// DumpGolden write pts to golden file path in normalized JSON: points are
+// sorted by measurement and tags, keys within point are sorted.
+func DumpGolden(path string, pts []*point.Point) error {
+	arr := make([]*goldenPoint, 0, len(pts))
+
+	for _, pt := range pts {
+		gp := &goldenPoint{
+			Measurement: string(pt.Name()),
+			Tags:        pt.InfluxTags(),
+			Fields:      map[string]goldenValue{},
+			Time:        pt.Time().UnixNano(),
+		}
+
+		for k, v := range pt.InfluxFields() {
+			gp.Fields[k] = goldenValue{v: v}
+		}
+
+		arr = append(arr, gp)
+	}
+
+	sort.SliceStable(arr, func(i, j int) bool {
+		return arr[i].key() < arr[j].key()
+	})
+
+	j, err := json.MarshalIndent(arr, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(j, '\n'), 0o600)
+}
+
+// LoadGolden read points from golden file path.
+func LoadGolden(path string) ([]*point.Point, error) {
+	j, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var arr []*goldenPoint
+	if err := json.Unmarshal(j, &arr); err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %w", path, err)
+	}
+
+	pts := make([]*point.Point, 0, len(arr))
+	for _, gp := range arr {
+		kvs := point.NewTags(gp.Tags)
+		for k, v := range gp.Fields {
+			kvs = append(kvs, point.NewKV([]byte(k), v.v))
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(gp.Measurement), kvs, point.WithTime(time.Unix(0, gp.Time))))
+	}
+
+	return pts, nil
+}
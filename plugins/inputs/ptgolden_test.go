@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package inputs
+
+import (
+	"os"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckGolden(t *T.T) {
+	now := time.Unix(1680000000, 0)
+
+	newPts := func(usage float64, cores int64, host string, ts time.Time) []*point.Point {
+		return []*point.Point{
+			point.NewPointV2([]byte("cpu"),
+				append(point.NewTags(map[string]string{"host": host, "cpu": "cpu0"}),
+					point.NewKVs(map[string]any{"usage": usage, "cores": cores, "model": "x86"})...),
+				point.WithTime(ts)),
+			point.NewPointV2([]byte("cpu"),
+				append(point.NewTags(map[string]string{"host": host, "cpu": "cpu1"}),
+					point.NewKVs(map[string]any{"usage": 2.0, "cores": cores, "model": "x86"})...),
+				point.WithTime(ts)),
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "golden", "cpu.json")
+
+	// first run: dump golden
+	msgs, err := CheckGolden(path, newPts(12.5, 4, "h1", now))
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+
+	j, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"usage": 2.0`, "float keep decimal point")
+	assert.Contains(t, string(j), `"cores": 4`)
+	t.Logf("golden:\n%s", j)
+
+	t.Run("equal", func(t *T.T) {
+		msgs, err := CheckGolden(path, newPts(12.5, 4, "h1", now))
+		require.NoError(t, err)
+		assert.Empty(t, msgs)
+	})
+
+	t.Run("tolerances", func(t *T.T) {
+		pts := newPts(12.6, 4, "h2", now.Add(time.Second))
+
+		msgs, err := CheckGolden(path, pts)
+		require.NoError(t, err)
+		assert.NotEmpty(t, msgs)
+		for _, m := range msgs {
+			t.Logf("%s", m)
+		}
+
+		msgs, err = CheckGolden(path, pts,
+			WithNumericTolerance(0.01),
+			WithTimeTolerance(2*time.Second),
+			WithVolatileKeys("host"))
+		require.NoError(t, err)
+		assert.Empty(t, msgs)
+
+		msgs, err = CheckGolden(path, pts,
+			WithNumericTolerance(0.01),
+			WithTimeTolerance(time.Millisecond),
+			WithVolatileKeys("host"))
+		require.NoError(t, err)
+		assert.Len(t, msgs, 2)
+	})
+
+	t.Run("type-changed", func(t *T.T) {
+		pts := newPts(12.5, 4, "h1", now)
+		pts[0].MustAdd([]byte("cores"), 4.0)
+
+		msgs, err := CheckGolden(path, pts)
+		require.NoError(t, err)
+		assert.Len(t, msgs, 1)
+		t.Logf("%s", msgs[0])
+	})
+
+	t.Run("missing-and-unexpected", func(t *T.T) {
+		pts := newPts(12.5, 4, "h1", now)
+		pts[1] = point.NewPointV2([]byte("mem"), point.NewKVs(map[string]any{"used": 1}), point.WithTime(now))
+
+		msgs, err := CheckGolden(path, pts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"[cpu,cpu=cpu1,host=h1] expected point not found",
+			"[mem] unexpected point",
+		}, msgs)
+	})
+}
@@ -0,0 +1,310 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package pythond
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+)
+
+/*
+Frames on the unix socket between python framework and datakit, integers
+are big-endian:
+
+request:  | len(4) | flags(1) | len(1) | category | len(1) | input | [len(2) | pipelines] | PBPoints |
+          `len` in front counts all bytes after it, flags bit 0 is ignore_global_tags,
+          bit 1 is global_election_tags(global host tags are not added then), bit 2
+          means pipelines present, they are JSON of <measurement>: <script name>
+response: | status(1) | len(2) | message |
+*/
+
+const (
+	channelUnix = "unix"
+
+	socketEnv        = "DATAKIT_PYTHOND_SOCKET"
+	maxFrameSize     = 32 << 20
+	flagIgnoreGlobal = 1 << 0
+	flagElection     = 1 << 1
+	flagPipelines    = 1 << 2
+
+	statusOK   byte = 0
+	statusBusy byte = 1 // io busy, points dropped, should slow down and retry
+	statusErr  byte = 2
+)
+
+var errInvalidFrame = errors.New("invalid frame")
+
+type dataRequest struct {
+	category         point.Category
+	input            string
+	ignoreGlobalTags bool
+	electionTags     bool
+	pipelines        []byte // JSON of pipeline script of each measurement
+	pbpts            []byte
+}
+
+func socketPath(name string) string {
+	return filepath.Join(datakit.DataDir, inputName, name+".sock")
+}
+
+func readFrame(r io.Reader) (*dataRequest, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	if n == 0 || n > maxFrameSize {
+		return nil, fmt.Errorf("%w: length %d", errInvalidFrame, n)
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	flags := buf[0]
+	req := &dataRequest{
+		ignoreGlobalTags: flags&flagIgnoreGlobal != 0,
+		electionTags:     flags&flagElection != 0,
+	}
+	buf = buf[1:]
+
+	var strs [2]string
+	for i := range strs {
+		if len(buf) == 0 || len(buf) < 1+int(buf[0]) {
+			return nil, errInvalidFrame
+		}
+		strs[i] = string(buf[1 : 1+buf[0]])
+		buf = buf[1+buf[0]:]
+	}
+
+	req.category = point.CatString(strs[0])
+	req.input = strs[1]
+
+	if flags&flagPipelines != 0 {
+		if len(buf) < 2 {
+			return nil, errInvalidFrame
+		}
+
+		n := 2 + int(binary.BigEndian.Uint16(buf))
+		if len(buf) < n {
+			return nil, errInvalidFrame
+		}
+		req.pipelines = buf[2:n]
+		buf = buf[n:]
+	}
+
+	req.pbpts = buf
+
+	return req, nil
+}
+
+// parsePipelines parses pipeline script name of each measurement, the
+// suffix `.p` of script name is optional.
+func parsePipelines(data []byte) (map[string]string, error) {
+	var pipelines map[string]string
+	if err := json.Unmarshal(data, &pipelines); err != nil {
+		return nil, err
+	}
+
+	for k, v := range pipelines {
+		if v == "" {
+			delete(pipelines, k)
+			continue
+		}
+		pipelines[k] = strings.TrimSuffix(v, ".p") + ".p"
+	}
+
+	return pipelines, nil
+}
+
+func writeResponse(w io.Writer, status byte, msg string) error {
+	if len(msg) > 0xffff {
+		msg = msg[:0xffff]
+	}
+
+	buf := make([]byte, 3, 3+len(msg))
+	buf[0] = status
+	binary.BigEndian.PutUint16(buf[1:], uint16(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+func categoryOptions(c point.Category) []point.Option {
+	switch c { //nolint:exhaustive
+	case point.Metric:
+		return point.DefaultMetricOptions()
+	case point.Logging:
+		return point.DefaultLoggingOptions()
+	case point.Object, point.CustomObject:
+		return point.DefaultObjectOptions()
+	default:
+		return nil
+	}
+}
+
+// decodeRequest decodes points as the HTTP API /v1/write/:category does.
+func decodeRequest(req *dataRequest) ([]*point.Point, error) {
+	dec := point.GetDecoder(point.WithDecEncoding(point.Protobuf))
+	defer point.PutDecoder(dec)
+
+	pts, err := dec.Decode(req.pbpts, categoryOptions(req.category)...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, pt := range pts {
+		if pt.Time().UnixNano() == 0 {
+			pt.SetTime(now)
+		}
+
+		switch {
+		case req.electionTags:
+			for k, v := range dkpt.GlobalElectionTags() {
+				pt.AddTag([]byte(k), []byte(v))
+			}
+		case !req.ignoreGlobalTags:
+			for k, v := range dkpt.GlobalHostTags() {
+				pt.AddTag([]byte(k), []byte(v))
+			}
+		}
+	}
+
+	return pts, nil
+}
+
+// handleRequest feeds the points and returns the status replied to python.
+func (pe *Input) handleRequest(req *dataRequest) (byte, string) {
+	if req.category == point.UnknownCategory {
+		return statusErr, "unknown category"
+	}
+
+	var opts []*dkio.Option
+	if len(req.pipelines) > 0 {
+		pipelines, err := parsePipelines(req.pipelines)
+		if err != nil {
+			return statusErr, "invalid pipelines: " + err.Error()
+		}
+		opts = append(opts, &dkio.Option{PlScript: pipelines})
+	}
+
+	pts, err := decodeRequest(req)
+	if err != nil {
+		return statusErr, err.Error()
+	}
+
+	if len(pts) == 0 {
+		return statusOK, ""
+	}
+
+	input := req.input
+	switch {
+	case input != "":
+	case req.category == point.CustomObject:
+		input = "custom_object" // same as HTTP API
+	default:
+		input = inputName + "/" + pe.Name
+	}
+
+	if err := pe.feeder.Feed(input, req.category, pts, opts...); err != nil {
+		if errors.Is(err, dkio.ErrIOBusy) {
+			return statusBusy, err.Error()
+		}
+		return statusErr, err.Error()
+	}
+
+	return statusOK, ""
+}
+
+func (pe *Input) serveConn(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck,gosec
+
+	r := bufio.NewReader(conn)
+	for {
+		req, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				l.Warnf("pythond %s read frame failed: %s", pe.Name, err.Error())
+				_ = writeResponse(conn, statusErr, err.Error())
+			}
+			return
+		}
+
+		status, msg := pe.handleRequest(req)
+		if err := writeResponse(conn, status, msg); err != nil {
+			l.Warnf("pythond %s write response failed: %s", pe.Name, err.Error())
+			return
+		}
+	}
+}
+
+// listenChannel listens the unix socket for points sent by python
+// framework, the socket path is passed to python processes by env.
+func (pe *Input) listenChannel() (net.Listener, error) {
+	sock := socketPath(pe.Name)
+	if err := os.MkdirAll(filepath.Dir(sock), datakit.ConfPerm); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(sock)
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+
+	g := datakit.G("inputs_pythond")
+	g.Go(func(ctx context.Context) error {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				l.Infof("pythond %s channel %s closed: %s", pe.Name, sock, err.Error())
+				return nil
+			}
+
+			g.Go(func(ctx context.Context) error {
+				pe.serveConn(conn)
+				return nil
+			})
+		}
+	})
+
+	g.Go(func(ctx context.Context) error {
+		select {
+		case <-datakit.Exit.Wait():
+		case <-pe.semStop.Wait():
+		}
+		_ = ln.Close()
+		_ = os.Remove(sock)
+		return nil
+	})
+
+	l.Infof("pythond %s data channel listening on %s", pe.Name, sock)
+	return ln, nil
+}
+
+// channelEnvs passes the socket to python, nil envs means envs of datakit.
+func channelEnvs(sock string, envs []string) []string {
+	if envs == nil {
+		envs = os.Environ()
+	}
+
+	return append(append([]string{}, envs...), socketEnv+"="+sock)
+}
@@ -6,25 +6,14 @@
 package pythond
 
 import (
-	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"net"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/BurntSushi/toml"
-	"github.com/gin-gonic/gin"
-	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
@@ -47,37 +36,45 @@ func TestPythondInput(t *testing.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []testutils.TestingCase
 	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-			t.Logf("testing %s...", tc.name)
+	testutils.RunCases(t, arr)
+}
 
-			if err := tc.run(); err != nil {
-				tc.cr.Status = testutils.TestFailed
-				tc.cr.FailedMessage = err.Error()
+func (cs *caseSpec) CaseName() string { return cs.name }
 
-				assert.NoError(t, err)
-			} else {
-				tc.cr.Status = testutils.TestPassed
-			}
+func (cs *caseSpec) Result() *testutils.CaseResult { return cs.cr }
 
-			tc.cr.Cost = time.Since(caseStart)
+func (cs *caseSpec) Run(t *testing.T) error {
+	cs.t = t
+	return cs.run()
+}
 
-			assert.NoError(t, testutils.Flush(tc.cr))
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
+	}
 
-			t.Cleanup(func() {
-				// clean remote docker resources
-				if tc.resource == nil {
-					return
-				}
+	return cs.container.Purge()
+}
 
-				assert.NoError(t, tc.pool.Purge(tc.resource))
-			})
-		})
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []testutils.Container {
+	if cs.container == nil {
+		return nil
 	}
+
+	return []testutils.Container{cs.container}
+}
+
+// ExclusiveResources returns the container name and host ports bound, they
+// are fixed and can not be shared with other cases running at the same time.
+func (cs *caseSpec) ExclusiveResources() []string {
+	return append([]string{cs.getContainterName()}, cs.exposedPorts...)
 }
 
 func buildCases(t *testing.T) ([]*caseSpec, error) {
@@ -88,7 +85,9 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 	bases := []struct {
 		name           string // Also used as build image name:tag.
 		conf           string
-		dockerFileText string // Empty if not build image.
+		dockerFileText string // Empty if not build image, rendered by dockerFileVars.
+		dockerFileVars *testutils.DockerfileVars
+		buildArgs      map[string]string
 		exposedPorts   []string
 		opts           []inputs.PointCheckOption
 	}{
@@ -120,6 +119,8 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 			repoTag: repoTag[1],
 
 			dockerFileText: base.dockerFileText,
+			dockerFileVars: base.dockerFileVars,
+			buildArgs:      base.buildArgs,
 			exposedPorts:   base.exposedPorts,
 			opts:           base.opts,
 
@@ -151,166 +152,37 @@ type caseSpec struct {
 	repo           string
 	repoTag        string
 	dockerFileText string
+	dockerFileVars *testutils.DockerfileVars
+	buildArgs      map[string]string
 	exposedPorts   []string
 	opts           []inputs.PointCheckOption
 
 	ipt    *Input
 	feeder *dkio.MockedFeeder
 
-	pool     *dockertest.Pool
-	resource *dockertest.Resource
+	container testutils.Container
 
 	cr *testutils.CaseResult
 }
 
-var (
-	done      chan struct{}
-	lock      sync.RWMutex
-	errorMsgs []string
-	count     uint32
-)
+// goldenCategories are categories of points sent by the scripts in the
+// testing image, expected points are in testdata/golden/<category>.json.
+var goldenCategories = []string{"metric", "logging", "object", "keyevent"}
 
-type FeedMeasurementBody []struct {
-	Measurement string                 `json:"measurement"`
-	Tags        map[string]string      `json:"tags"`
-	Fields      map[string]interface{} `json:"fields"`
-}
-
-func addErrorMsgs(str string) {
-	lock.RLock()
-	defer lock.RUnlock()
-	errorMsgs = append(errorMsgs, str)
-}
-
-func (cs *caseSpec) addErrorMsgs(str string) {
-	addErrorMsgs(str)
-}
-
-func (cs *caseSpec) handler(c *gin.Context) {
-	uri, err := url.ParseRequestURI(c.Request.URL.RequestURI())
-	if err != nil {
-		cs.t.Logf("%s", err.Error())
-		return
-	}
-
-	body, err := ioutil.ReadAll(c.Request.Body)
-	defer c.Request.Body.Close()
-	if err != nil {
-		cs.t.Logf("%s", err.Error())
-		return
-	}
-	str := string(body)
-	cs.t.Logf(str)
-	cs.t.Logf("\n")
-
-	switch uri.Path {
-	case "/v1/write/metrics":
-	case "/v1/write/metric":
-		atomic.AddUint32(&count, 1)
-		if uri.RawQuery == "input=py_from_docker" {
-			if str != `[{"measurement": "measurement1", "tags": {"tag_name": "tag_value"}, "fields": {"count": 1}}]` {
-				cs.addErrorMsgs("[ERROR] 10001")
-			}
-		} else {
-			if str != `[{"measurement": "measurement2", "tags": {"tag1": "val1", "tag2": "val2"}, "fields": {"custom_field1": "val1", "custom_field2": 1000, "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}, "time": null}]` {
-				cs.addErrorMsgs("[ERROR] 10002")
-			}
-		}
-	case "/v1/write/network":
-	case "/v1/write/keyevent":
-		atomic.AddUint32(&count, 1)
-		parsedBody := FeedMeasurementBody{}
-		if err := json.Unmarshal(body, &parsedBody); err != nil {
-			cs.t.Logf("json.Unmarshal failed: %s", err.Error())
-			return
-		}
-		if len(parsedBody) == 0 {
-			cs.t.Logf("parse body failed: body length 0")
-			return
-		}
-		src, ok := parsedBody[0].Fields["df_source"]
-		if !ok {
-			cs.t.Logf("parse body failed: no df_source")
-			return
-		}
-		srcStr, ok := src.(string)
-		if !ok {
-			cs.t.Logf("parse body failed: df_source not string")
-			return
-		}
-		switch srcStr {
-		case "user":
-			if str != `[{"measurement": "measurement", "tags": {"tag1": "val1", "tag2": "val2"}, "fields": {"df_date_range": 10, "df_source": "user", "df_user_id": "user_id", "df_status": "info", "df_event_id": "event_id", "df_title": "title", "df_message": "message", "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}}]` {
-				cs.addErrorMsgs("[ERROR] 10007")
-			}
-		case "monitor":
-			if str != `[{"measurement": "measurement", "tags": {"tag1": "val1", "tag2": "val2"}, "fields": {"df_date_range": 10, "df_source": "monitor", "df_dimension_tags": "{\"host\":\"web01\"}", "df_status": "info", "df_event_id": "event_id", "df_title": "title", "df_message": "message", "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}}]` {
-				cs.addErrorMsgs("[ERROR] 10005")
-			}
-		case "system":
-			if str != `[{"measurement": "measurement", "tags": {"tag1": "val1", "tag2": "val2"}, "fields": {"df_date_range": 10, "df_source": "system", "df_status": "info", "df_event_id": "event_id", "df_title": "feed_system_event", "df_message": "message", "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}}]` {
-				cs.addErrorMsgs("[ERROR] 10006")
-			}
-		}
-	case "/v1/write/object":
-		atomic.AddUint32(&count, 1)
-		if str != `[{"measurement": "measurement4", "tags": {"tag1": "val1", "tag2": "val2", "name": "name"}, "fields": {"custom_field1": "val1", "custom_field2": 1000, "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}, "time": null}]` {
-			cs.addErrorMsgs("[ERROR] 10004")
-		}
-	case "/v1/write/custom_object":
-	case "/v1/write/logging":
-		atomic.AddUint32(&count, 1)
-		if str != `[{"measurement": "measurement3", "tags": {"tag1": "val1", "tag2": "val2"}, "fields": {"message": "This is the message for testing", "custom_key1": "custom_value1", "custom_key2": "custom_value2", "custom_key3": "custom_value3"}, "time": null}]` {
-			cs.addErrorMsgs("[ERROR] 10003")
-		}
-	case "/v1/write/tracing":
-	case "/v1/write/rum":
-	case "/v1/write/security":
-	case "/v1/write/profiling":
-	}
-
-	val := atomic.LoadUint32(&count)
-	if val == 7 {
-		atomic.SwapUint32(&count, 0)
-		done <- struct{}{}
-	}
+func goldenPath(category string) string {
+	return filepath.Join("testdata", "golden", category+".json")
 }
 
 func (cs *caseSpec) run() error {
 	r := testutils.GetRemote()
-	dockerTCP := r.TCPURL()
-
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
-
-	router := gin.New()
-	router.POST("/v1/write/metrics", cs.handler)
-	router.POST("/v1/write/metric", cs.handler)
-	router.POST("/v1/write/network", cs.handler)
-	router.POST("/v1/write/keyevent", cs.handler)
-	router.POST("/v1/write/object", cs.handler)
-	router.POST("/v1/write/custom_object", cs.handler)
-	router.POST("/v1/write/logging", cs.handler)
-	router.POST("/v1/write/tracing", cs.handler)
-	router.POST("/v1/write/rum", cs.handler)
-	router.POST("/v1/write/security", cs.handler)
-	router.POST("/v1/write/profiling", cs.handler)
-
-	srv := &http.Server{
-		Addr:    ":59529",
-		Handler: router,
-	}
 
-	go func() {
-		done = nil
-		done = make(chan struct{})
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			panic(err)
-		}
-	}()
+	cs.t.Logf("get remote: %+#v", r)
+
+	md := testutils.NewMockDataway(cs.t)
 
 	start := time.Now()
 
-	p, err := cs.getPool(dockerTCP)
+	rt, err := cs.getRuntime(r)
 	if err != nil {
 		return err
 	}
@@ -318,7 +190,7 @@ func (cs *caseSpec) run() error {
 	containerName := cs.getContainterName()
 
 	// Remove the container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 
@@ -328,62 +200,38 @@ func (cs *caseSpec) run() error {
 	}
 	defer os.RemoveAll(dockerFileDir)
 
-	extIP, err := externalIP()
+	extIP, err := testutils.ExternalIP()
 	if err != nil {
 		return err
 	}
 
-	var resource *dockertest.Resource
-
-	if len(cs.dockerFileText) == 0 {
-		// Just run a container from existing docker image.
-		resource, err = p.RunWithOptions(
-			&dockertest.RunOptions{
-				Name: containerName, // ATTENTION: not cs.name.
-
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{fmt.Sprintf("DATAKIT_HOST=%s", extIP), "DATAKIT_PORT=59529"},
-
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
-
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				c.AutoRemove = true
-			},
-		)
-	} else {
-		// Build docker image from Dockerfile and run a container from it.
-		resource, err = p.BuildAndRunWithOptions(
-			dockerFilePath,
+	dkEnvs := []string{fmt.Sprintf("DATAKIT_HOST=%s", extIP), fmt.Sprintf("DATAKIT_PORT=%d", md.Port())}
 
-			&dockertest.RunOptions{
-				Name: cs.name,
+	opts := &testutils.RunOptions{
+		Name: containerName, // ATTENTION: not cs.name.
 
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{fmt.Sprintf("DATAKIT_HOST=%s", extIP), "DATAKIT_PORT=59529"},
+		Repository: cs.repo,
+		Tag:        cs.repoTag,
+		Env:        dkEnvs,
 
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
+		ExposedPorts: cs.exposedPorts,
+		PortBindings: cs.getPortBindings(),
+		AutoRemove:   true,
+	}
 
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				c.AutoRemove = true
-			},
-		)
+	// Build image from Dockerfile and run a container from it.
+	if len(cs.dockerFileText) != 0 {
+		opts.Dockerfile = dockerFilePath
+		opts.BuildArgs = cs.buildArgs
 	}
 
+	c, err := rt.Run(opts)
 	if err != nil {
 		cs.t.Logf("%s", err.Error())
 		return err
 	}
 
-	cs.pool = p
-	cs.resource = resource
+	cs.container = c
 
 	cs.t.Logf("check service(%s:%v)...", r.Host, cs.exposedPorts)
 
@@ -393,79 +241,61 @@ func (cs *caseSpec) run() error {
 
 	cs.cr.AddField("container_ready_cost", int64(time.Since(start)))
 
-	tick := time.NewTicker(time.Second * 30)
-	out := false
-	for {
-		if out {
-			break
+	wanted := map[string]int{}
+	for _, category := range goldenCategories {
+		pts, err := inputs.LoadGolden(goldenPath(category))
+		if err != nil {
+			return err
 		}
+		wanted[category] = len(pts)
+	}
 
-		select {
-		case <-tick.C:
-			out = true
-		case <-done:
-			out = true
+	if !md.Await(30*time.Second, func(md *testutils.MockDataway) bool {
+		for category, n := range wanted {
+			if len(md.Points(category)) < n {
+				return false
+			}
 		}
+		return true
+	}) {
+		return fmt.Errorf("points not received in 30s, expect %v", wanted)
+	}
+
+	if errs := md.Errors(); len(errs) > 0 {
+		return fmt.Errorf("invalid bodies: %v", errs)
 	}
 
-	if len(errorMsgs) > 0 {
-		return fmt.Errorf("errorMsgs: %#v", errorMsgs)
+	var msgs []string
+	for _, category := range goldenCategories {
+		res, err := inputs.CheckGolden(goldenPath(category), md.Points(category), cs.opts...)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, res...)
 	}
-	errorMsgs = errorMsgs[:0]
 
-	cs.t.Logf("exit...")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil && errors.Is(err, http.ErrServerClosed) {
-		cs.t.Logf("Shutdown failed: %v", err)
+	if len(msgs) > 0 {
+		return fmt.Errorf("golden checking failed:\n%s", strings.Join(msgs, "\n"))
 	}
 
 	return nil
 }
 
-func (cs *caseSpec) getPool(endpoint string) (*dockertest.Pool, error) {
-	p, err := dockertest.NewPool(endpoint)
+func (cs *caseSpec) getRuntime(r *testutils.RemoteInfo) (testutils.ContainerRuntime, error) {
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return nil, err
 	}
-	err = p.Client.Ping()
+	err = rt.Ping()
 	if err != nil {
-		cs.t.Logf("Could not connect to Docker: %v", err)
+		cs.t.Logf("Could not connect to %s: %v", rt.Name(), err)
 		return nil, err
 	}
-	return p, nil
+	return rt, nil
 }
 
 func (cs *caseSpec) getDockerFilePath() (dirName string, fileName string, err error) {
-	tmpDir, err := ioutil.TempDir("", "dockerfiles_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempDir failed: %s", err.Error())
-		return "", "", err
-	}
-
-	tmpFile, err := ioutil.TempFile(tmpDir, "dockerfile_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempFile failed: %s", err.Error())
-		return "", "", err
-	}
-
-	_, err = tmpFile.WriteString(cs.dockerFileText)
-	if err != nil {
-		cs.t.Logf("TempFile.WriteString failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := os.Chmod(tmpFile.Name(), os.ModePerm); err != nil {
-		cs.t.Logf("os.Chmod failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := tmpFile.Close(); err != nil {
-		cs.t.Logf("Close failed: %s", err.Error())
-		return "", "", err
-	}
-
-	return tmpDir, tmpFile.Name(), nil
+	return testutils.WriteDockerfile(cs.dockerFileText, cs.dockerFileVars)
 }
 
 func (cs *caseSpec) getContainterName() string {
@@ -474,11 +304,11 @@ func (cs *caseSpec) getContainterName() string {
 	return name
 }
 
-func (cs *caseSpec) getPortBindings() map[docker.Port][]docker.PortBinding {
-	portBindings := make(map[docker.Port][]docker.PortBinding)
+func (cs *caseSpec) getPortBindings() map[string]string {
+	portBindings := make(map[string]string)
 
 	for _, v := range cs.exposedPorts {
-		portBindings[docker.Port(v)] = []docker.PortBinding{{HostIP: "0.0.0.0", HostPort: docker.Port(v).Port()}}
+		portBindings[v] = docker.Port(v).Port()
 	}
 
 	return portBindings
@@ -492,42 +322,3 @@ func (cs *caseSpec) portsOK(r *testutils.RemoteInfo) error {
 	}
 	return nil
 }
-
-////////////////////////////////////////////////////////////////////////////////
-
-func externalIP() (string, error) {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		return "", err
-	}
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 {
-			continue // interface down
-		}
-		if iface.Flags&net.FlagLoopback != 0 {
-			continue // loopback interface
-		}
-		addrs, err := iface.Addrs()
-		if err != nil {
-			return "", err
-		}
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-			if ip == nil || ip.IsLoopback() {
-				continue
-			}
-			ip = ip.To4()
-			if ip == nil {
-				continue // not an ipv4 address
-			}
-			return ip.String(), nil
-		}
-	}
-	return "", errors.New("are you connected to the network?")
-}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package pythond
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
+)
+
+// exitCodeTimeout is the exit code of cli.py when some script run timeout,
+// same as the coreutils timeout command.
+const exitCodeTimeout = 124
+
+var (
+	newProcessCgroup = cgroup.NewProcessCgroup
+
+	cgroupNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+)
+
+// cgroupPathOf returns the cgroup path of the idx-th set of the input.
+func cgroupPathOf(name string, idx int) string {
+	return fmt.Sprintf("/datakit-pythond-%s-%d", cgroupNameReplacer.ReplaceAllString(name, "_"), idx)
+}
+
+// initLimit checks the CPU/memory limits and the timeout of the set.
+func (ss *ScriptSet) initLimit() error {
+	if ss.CPUMax < 0 || ss.CPUMax > 100 {
+		return fmt.Errorf("invalid cpu_max %v, should be in range of (0.0, 100.0]", ss.CPUMax)
+	}
+
+	if ss.MemMaxMB < 0 {
+		return fmt.Errorf("invalid mem_max_mb %d", ss.MemMaxMB)
+	}
+
+	if ss.Timeout != "" {
+		du, err := time.ParseDuration(ss.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", ss.Timeout, err)
+		}
+
+		if du < time.Second {
+			return fmt.Errorf("invalid timeout %q, should be at least 1s", ss.Timeout)
+		}
+		ss.timeout = du
+	}
+
+	return nil
+}
+
+// setupLimit creates the cgroup of the set before the python process
+// started, and returns the args of cli.py about limits. If cgroup not
+// available, the memory is limited by rlimit within python itself, and the CPU
+// is not limited.
+func (ss *ScriptSet) setupLimit() (args []string) {
+	if ss.timeout > 0 {
+		args = append(args, "--timeout="+strconv.Itoa(int(ss.timeout.Seconds())))
+	}
+
+	if ss.CPUMax == 0 && ss.MemMaxMB == 0 {
+		return args
+	}
+
+	cg, err := newProcessCgroup(ss.cgroupPath, ss.CPUMax, ss.MemMaxMB)
+	if err != nil {
+		l.Warnf("cgroup of dirs %v not available: %s, CPU not limited", ss.Dirs, err.Error())
+
+		if ss.MemMaxMB > 0 {
+			args = append(args, "--mem_max_mb="+strconv.FormatInt(ss.MemMaxMB, 10))
+		}
+		return args
+	}
+
+	ss.cgroup = cg
+	return args
+}
+
+// addToCgroup adds the started python process into the cgroup of the set.
+func (ss *ScriptSet) addToCgroup() {
+	if ss.cgroup == nil {
+		return
+	}
+
+	if err := ss.cgroup.AddProc(ss.cmd.Process.Pid); err != nil {
+		l.Warnf("add dirs %v to cgroup %s failed: %s, not limited", ss.Dirs, ss.cgroupPath, err.Error())
+	}
+}
+
+// watchExit waits the started python process in background.
+func (ss *ScriptSet) watchExit() {
+	exited := make(chan error, 1)
+	ss.exited = exited
+
+	cmd := ss.cmd
+	go func() {
+		exited <- cmd.Wait()
+	}()
+}
+
+// wait waits the python process exited, and removes the cgroup of it.
+func (ss *ScriptSet) wait() error {
+	err := <-ss.exited
+	ss.deleteCgroup()
+	return err
+}
+
+func (ss *ScriptSet) deleteCgroup() {
+	if ss.cgroup == nil {
+		return
+	}
+
+	if err := ss.cgroup.Delete(); err != nil {
+		l.Warnf("delete cgroup %s failed: %s", ss.cgroupPath, err.Error())
+	}
+	ss.cgroup = nil
+}
+
+// exitedMessage describes why the python process exited unexpectedly.
+func (ss *ScriptSet) exitedMessage(name string, err error) string {
+	msg := fmt.Sprintf("python worker of pythond %s dirs %v exited", name, ss.Dirs)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return msg + ", restarting"
+
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == exitCodeTimeout:
+		return fmt.Sprintf("%s: killed for script run exceeded timeout %s, restarting", msg, ss.timeout)
+
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == -1 && ss.MemMaxMB > 0: // killed by signal
+		return fmt.Sprintf("%s: %s(may exceed mem_max_mb %d), restarting", msg, err.Error(), ss.MemMaxMB)
+
+	default:
+		return fmt.Sprintf("%s: %s, restarting", msg, err.Error())
+	}
+}
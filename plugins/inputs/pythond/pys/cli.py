@@ -4,6 +4,7 @@ import os
 import sys
 import time
 import importlib
+import traceback
 import threading
 import argparse
 import logging
@@ -20,6 +21,9 @@ PY3 = sys.version_info[0] == 3
 
 logger = logging.getLogger('pythond_cli')
 
+# exit code on script run timeout, same as the coreutils timeout command
+EXIT_TIMEOUT = 124
+
 def init_log():
     log_path = os.path.join(os.path.expanduser('~'), "_datakit_pythond_cli.log")
     print(log_path)
@@ -39,16 +43,60 @@ class RunThread (threading.Thread):
 		self.__plugin = plugin
 		if self.__plugin.interval:
 			self.__interval = self.__plugin.interval
+		self.script = type(self.__plugin).__module__ + "." + type(self.__plugin).__name__
+		self.failures = 0
+		self.started = None # start time of the running plugin.run()
 
 	def run(self):
 		if self.__plugin:
+			script = self.script
+			last_tb = None
 			while True:
+				self.started = time.time()
 				try:
 					self.__plugin.run()
-				except:
-					mylog("Unexpected error: info = %s, script = '%s'", sys.exc_info(), self.__plugin.name)
+					if self.failures > 0:
+						self.__plugin.report_script_recovered(script, self.failures)
+					self.failures, last_tb = 0, None
+				except Exception:
+					tb = traceback.format_exc()
+					self.failures += 1
+					mylog("Unexpected error: script = '%s', failures = %d\n%s", script, self.failures, tb)
+					# event only on first failure or traceback changed, not every interval
+					self.__plugin.report_script_error(script, tb, self.failures, tb != last_tb)
+					last_tb = tb
+				finally:
+					self.started = None
 				time.sleep(self.__interval)
 
+	def report_timeout(self, timeout):
+		msg = "script run exceeded timeout %ds, python worker killed" % timeout
+		self.__plugin.report_script_error(self.script, msg, self.failures + 1, True)
+
+def watchdog(threads, timeout):
+	# threads can not be killed, exit the whole process and datakit restarts it
+	while True:
+		time.sleep(1)
+		now = time.time()
+		for t in threads:
+			started = t.started
+			if started is not None and now - started > timeout:
+				mylog("script '%s' run exceeded timeout %ds, exit", t.script, timeout)
+				try:
+					t.report_timeout(timeout)
+				finally:
+					os._exit(EXIT_TIMEOUT)
+
+def limit_memory(mem_max_mb):
+	try:
+		import resource
+	except ImportError: # not available on windows
+		mylog("resource module not available, memory not limited")
+		return
+
+	n = mem_max_mb * 1024 * 1024
+	resource.setrlimit(resource.RLIMIT_AS, (n, n))
+
 def search_plugin(plugin_path):
 	try:
 		mod = importlib.import_module(plugin_path)
@@ -66,7 +114,7 @@ def search_plugin(plugin_path):
 
 	return plugins
 
-def main(*args):
+def main(*args, **kwargs):
     plugins = []
     threads = []
 
@@ -80,13 +128,25 @@ def main(*args):
         thd.start()
         threads.append(thd)
 
+    timeout = kwargs.get('timeout', 0)
+    if timeout > 0 and len(threads) > 0:
+        wd = threading.Thread(target=watchdog, args=(threads, timeout))
+        wd.daemon = True
+        wd.start()
+
     for t in threads:
         t.join()
 
 if __name__ == '__main__':
 	parser = argparse.ArgumentParser(description="datakit framework")
 	parser.add_argument('--logname', '-l', help='indicates datakit framework log name, required')
+	parser.add_argument('--error_event', action='store_true', help='report script errors as keyevent')
+	parser.add_argument('--election', action='store_true', help='add global election tags instead of global host tags')
+	parser.add_argument('--timeout', type=int, default=0, help='max seconds of each script run, exit if exceeded')
+	parser.add_argument('--mem_max_mb', type=int, default=0, help='max memory(MB) by rlimit')
 	args = parser.parse_args()
+	DataKitFramework.error_event = args.error_event
+	DataKitFramework.election = args.election
 	if args.logname:
 		DataKitFramework.log_name = args.logname
 	else:
@@ -94,4 +154,6 @@ if __name__ == '__main__':
 		sys.exit(-1)
 
 	init_log()
-	main(${CustomerDefinedScriptName})
\ No newline at end of file
+	if args.mem_max_mb > 0:
+		limit_memory(args.mem_max_mb)
+	main(${CustomerDefinedScriptName}, timeout=args.timeout)
\ No newline at end of file
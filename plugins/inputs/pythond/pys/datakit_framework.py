@@ -2,6 +2,9 @@
 
 import os
 import sys
+import json
+import socket
+import struct
 from string import Template
 import logging
 from logging.handlers import RotatingFileHandler
@@ -9,6 +12,163 @@ import requests
 
 logger = logging.getLogger('pythond_framework')
 
+'''
+数据通道
+
+datakit 开启 unix socket 数据通道时(环境变量 DATAKIT_PYTHOND_SOCKET)，
+数据以 protobuf 编码的 PBPoints 上报，不可用时使用 HTTP 上报
+'''
+
+SOCKET_ENV = 'DATAKIT_PYTHOND_SOCKET'
+
+STATUS_OK = 0
+STATUS_BUSY = 1
+STATUS_ERR = 2
+
+FLAG_IGNORE_GLOBAL_TAGS = 1 << 0
+FLAG_GLOBAL_ELECTION_TAGS = 1 << 1
+FLAG_PIPELINES = 1 << 2
+
+CATEGORIES = (
+    ('M', 'metric'),
+    ('L', 'logging'),
+    ('R', 'rum'),
+    ('O', 'object'),
+    ('CO', 'custom_object'),
+    ('E', 'keyevent'),
+)
+
+PRECISIONS = {
+    'n': 1, 'ns': 1,
+    'u': 1000, 'us': 1000,
+    'ms': 1000000,
+    's': 1000000000,
+    'm': 60000000000,
+    'h': 3600000000000,
+}
+
+def pb_varint(v):
+    v &= 0xFFFFFFFFFFFFFFFF # negative int64 as two's complement
+    out = bytearray()
+    while True:
+        b = v & 0x7F
+        v >>= 7
+        if v:
+            out.append(b | 0x80)
+        else:
+            out.append(b)
+            return bytes(out)
+
+def pb_bytes(num, b):
+    return pb_varint(num << 3 | 2) + pb_varint(len(b)) + b
+
+def pb_str(s):
+    if isinstance(s, bytes):
+        return s
+    return str(s).encode('utf8')
+
+def pb_field(key, value, is_tag=False):
+    '''
+    point.Field: key = 1, i = 2, u = 3, f = 4, b = 5, d = 6, is_tag = 8
+    '''
+    out = pb_bytes(1, pb_str(key))
+    if is_tag:
+        out += pb_bytes(6, pb_str(value)) + pb_varint(8 << 3) + pb_varint(1)
+    elif isinstance(value, bool):
+        out += pb_varint(5 << 3) + pb_varint(1 if value else 0)
+    elif isinstance(value, int):
+        if value > 0x7FFFFFFFFFFFFFFF:
+            out += pb_varint(3 << 3) + pb_varint(value)
+        else:
+            out += pb_varint(2 << 3) + pb_varint(value)
+    elif isinstance(value, float):
+        out += pb_varint(4 << 3 | 1) + struct.pack('<d', value)
+    else:
+        out += pb_bytes(6, pb_str(value))
+    return out
+
+def pb_points(points, precision=''):
+    '''
+    points are dicts of measurement, tags, fields and time, encoded into point.PBPoints
+    '''
+    scale = PRECISIONS.get(precision or 'n', 1)
+    arr = bytearray()
+    for pt in points:
+        body = pb_bytes(1, pb_str(pt.get('measurement')))
+        for k, v in (pt.get('tags') or {}).items():
+            body += pb_bytes(2, pb_field(k, v, True))
+        for k, v in (pt.get('fields') or {}).items():
+            if v is None:
+                continue
+            body += pb_bytes(2, pb_field(k, v))
+        ts = pt.get('time')
+        if ts:
+            body += pb_varint(3 << 3) + pb_varint(int(ts) * scale)
+        arr += pb_bytes(1, body)
+    return bytes(arr)
+
+class DataChannel(object):
+    '''
+    unix socket 数据通道，请求与响应的格式见 channel.go
+    '''
+    def __init__(self, path):
+        self.path = path
+        self.sock = None
+
+    def connect(self):
+        if self.sock is None:
+            sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+            sock.connect(self.path)
+            self.sock = sock
+        return self.sock
+
+    def close(self):
+        if self.sock is not None:
+            try:
+                self.sock.close()
+            except Exception:
+                pass
+            self.sock = None
+
+    def recv_exactly(self, n):
+        buf = bytearray()
+        while len(buf) < n:
+            chunk = self.sock.recv(n - len(buf))
+            if not chunk:
+                raise ConnectionError('datakit closed the data channel')
+            buf += chunk
+        return bytes(buf)
+
+    def send(self, category, points, input='', precision='', ignore_global_tags=False, election=False, pipelines=None):
+        '''
+        returns (status, message), raises on channel errors
+        '''
+        flags = 0
+        if ignore_global_tags:
+            flags |= FLAG_IGNORE_GLOBAL_TAGS
+        if election:
+            flags |= FLAG_GLOBAL_ELECTION_TAGS
+        pl = b''
+        if pipelines:
+            flags |= FLAG_PIPELINES
+            pl = pb_str(json.dumps(pipelines))
+            pl = struct.pack('>H', len(pl)) + pl
+        cat = pb_str(category)
+        inp = pb_str(input or '')[:255]
+        payload = bytes(bytearray([flags, len(cat)])) + cat + \
+            bytes(bytearray([len(inp)])) + inp + pl + pb_points(points, precision)
+
+        try:
+            sock = self.connect()
+            sock.sendall(struct.pack('>I', len(payload)) + payload)
+            status, size = struct.unpack('>BH', self.recv_exactly(3))
+            msg = self.recv_exactly(size).decode('utf8', 'replace') if size else ''
+        except Exception:
+            self.close()
+            raise
+
+        return status, msg
+
 '''
 DataKitFramework
 所有plugin的基类
@@ -25,6 +185,17 @@ class DataKitFramework(object):
     log_name = ""
     is_init_log = False
 
+    # datakit 繁忙时置为 True，数据已被丢弃，脚本应降低上报频率并重试
+    backpressure = False
+    channel = None
+    channel_checked = False
+
+    # 开启选举时数据带上 global election tags 而非 global host tags，由 datakit 配置 election 开启
+    election = False
+
+    # 脚本异常时是否上报 keyevent，由 datakit 配置 script_error_event 开启
+    error_event = False
+
     def __init__(self, **kwargs):
         ip = kwargs.get("ip")
         if ip:
@@ -40,7 +211,23 @@ class DataKitFramework(object):
         print("log_name = ", self.log_name)
         mylog("789")
 
+    def data_channel(self):
+        if not self.channel_checked:
+            self.channel_checked = True
+            sock = os.environ.get(SOCKET_ENV)
+            if sock and hasattr(socket, 'AF_UNIX'):
+                self.channel = DataChannel(sock)
+        return self.channel
+
     def report(self, data):
+        if self.data_channel() is not None:
+            data = dict(data)
+            try:
+                return self.report_channel(data)
+            except Exception as e:
+                # categories sent are removed from data
+                mylog("data channel unavailable, fallback to HTTP: %s", e)
+
         M = ""
         L = ""
         R = ""
@@ -67,6 +254,7 @@ class DataKitFramework(object):
         input = ""
         ignore_global_tags = ""
         version = ""
+        pipelines = data.get('pipelines')
 
         if 'precision' in data:
             precision = data['precision']
@@ -76,6 +264,8 @@ class DataKitFramework(object):
             ignore_global_tags = data['ignore_global_tags']
         if 'version' in data:
             version = data['version']
+        if self.election:
+            ignore_global_tags = "true"
 
         s = Template('http://${s1}:${s2}/v1/write/${s3}?')
         origin_url = s.safe_substitute(s1=self.__dk_host, s2=self.__dk_port, s3=self.__magic)
@@ -87,6 +277,8 @@ class DataKitFramework(object):
             origin_url += "ignore_global_tags=" + ignore_global_tags + '&'
         if version:
             origin_url += "version=" + version + '&'
+        if self.election:
+            origin_url += "global_election_tags=true&"
 
         if origin_url[-1] in ('&', '?'):
             origin_url = origin_url[:-1]
@@ -96,31 +288,70 @@ class DataKitFramework(object):
 
         if M:
             url = origin_url.replace(self.__magic, "metric")
-            response = self.http_post_json(url, M)
+            response = self.post_points(url, M, pipelines)
         if L:
             url = origin_url.replace(self.__magic, "logging")
-            response = self.http_post_json(url, L)
+            response = self.post_points(url, L, pipelines)
         if R:
             url = origin_url.replace(self.__magic, "rum")
-            response = self.http_post_json(url, R)
+            response = self.post_points(url, R, pipelines)
         if O:
             url = origin_url.replace(self.__magic, "object")
-            response = self.http_post_json(url, O)
+            response = self.post_points(url, O, pipelines)
         if CO:
             url = origin_url.replace(self.__magic, "custom_object")
-            response = self.http_post_json(url, CO)
+            response = self.post_points(url, CO, pipelines)
         if E:
             url = origin_url.replace(self.__magic, "keyevent")
-            response = self.http_post_json(url, E)
+            response = self.post_points(url, E, pipelines)
 
         return response
 
 
+    def post_points(self, url, points, pipelines):
+        '''
+        points with pipeline are posted in groups of the pipeline script
+        '''
+        if not pipelines:
+            return self.http_post_json(url, points)
+
+        groups = {}
+        for pt in points:
+            groups.setdefault(pipelines.get(pt.get('measurement'), ''), []).append(pt)
+
+        response = ""
+        for script, pts in groups.items():
+            u = url
+            if script:
+                u += ('&' if '?' in u else '?') + 'pipeline=' + script
+            response = self.http_post_json(u, pts)
+        return response
+
+    def report_channel(self, data):
+        response = ""
+        self.backpressure = False
+        for key, category in CATEGORIES:
+            if not data.get(key):
+                continue
+
+            status, msg = self.channel.send(category, data[key], data.get('input'),
+                data.get('precision'), bool(data.get('ignore_global_tags')), self.election, data.get('pipelines'))
+            del data[key]
+
+            if status == STATUS_BUSY:
+                self.backpressure = True
+                mylog("datakit busy, %s points dropped: %s", category, msg)
+            elif status != STATUS_OK:
+                mylog("report %s points failed: %s", category, msg)
+            response = msg
+
+        return response
+
     def checkArgEmpty(self, name, checkStr):
         if not checkStr:
             raise ValueError('arguments missing \"' + name + '\"')
 
-    def feed_metric(self, input=None, measurement=None, tags=None, fields=None, time=None, **kwargs):
+    def feed_metric(self, input=None, measurement=None, tags=None, fields=None, time=None, pipeline=None, **kwargs):
         self.checkArgEmpty("measurement", measurement)
         self.checkArgEmpty("fields", fields)
         data = {
@@ -138,10 +369,12 @@ class DataKitFramework(object):
             'M': dataArr,
             'input':input,
         }
+        if pipeline:
+            in_data['pipelines'] = {measurement: pipeline}
 
         return self.report(in_data)
 
-    def feed_logging(self, input=None, source=None, tags=None, message=None, time=None, **kwargs):
+    def feed_logging(self, input=None, source=None, tags=None, message=None, time=None, pipeline=None, **kwargs):
         self.checkArgEmpty("source", source)
         data = {
             "measurement": source,
@@ -160,11 +393,13 @@ class DataKitFramework(object):
             'L': dataArr,
             'input':input,
         }
+        if pipeline:
+            in_data['pipelines'] = {source: pipeline}
 
         return self.report(in_data)
 
     # cls is class.
-    def feed_object(self, input=None, cls=None, name=None, tags=None, fields=None, time=None, **kwargs):
+    def feed_object(self, input=None, cls=None, name=None, tags=None, fields=None, time=None, pipeline=None, **kwargs):
         self.checkArgEmpty("cls", cls)
         self.checkArgEmpty("fields", fields)
         data = {
@@ -187,6 +422,8 @@ class DataKitFramework(object):
             'O': dataArr,
             'input':input,
         }
+        if pipeline:
+            in_data['pipelines'] = {cls: pipeline}
 
         return self.report(in_data)
 
@@ -296,6 +533,73 @@ class DataKitFramework(object):
         return self.report(in_data)
 
 
+    def feed_event(self, title=None, severity=None, source=None, dedup_key=None, message=None, entities=None, tags=None, time=None, input=None):
+        '''
+        上报结构化事件(/v1/write/events)，datakit 将其转为 keyevent:
+        severity 为 critical/error/warning/info/ok 之一，entities 为关联的实体，如 {"host": "web-1", "service": "checkout"}，
+        dedup_key 相同的事件视为同一事件，time 单位为秒
+        '''
+        self.checkArgEmpty("title", title)
+        self.checkArgEmpty("severity", severity)
+        self.checkArgEmpty("source", source)
+
+        data = {
+            "title": title,
+            "severity": severity,
+            "source": source,
+        }
+        if dedup_key:
+            data["dedup_key"] = dedup_key
+        if message:
+            data["message"] = message
+        if entities:
+            data["entities"] = entities
+        if tags:
+            data["tags"] = tags
+        if time:
+            data["time"] = int(time)
+
+        url = self.construct_url("v1/write/events?precision=s")
+        if input:
+            url += "&input=" + input
+        if self.election:
+            url += "&ignore_global_tags=true&global_election_tags=true"
+
+        return self.http_post_json(url, data)
+
+    def report_script_error(self, script, tb, count, new_event=True):
+        '''
+        脚本 run() 抛出异常时调用: 错误作为采集器的 last error 上报(monitor 中可见)，
+        开启 error_event 时 new_event 为 True 则同时上报 keyevent
+        '''
+        lines = tb.strip().splitlines()
+        summary = lines[-1] if lines else ""
+        try:
+            self.set_lasterror("pythond", "%s: script %s failed %d times: %s" % (self.log_name, script, count, summary))
+            if self.error_event and new_event:
+                self.feed_system_event(tags=self.script_event_tags(script), df_status="error",
+                    df_event_id="pythond-" + script, df_title="pythond script %s failed: %s" % (script, summary),
+                    df_message=tb, script=script, traceback=tb, count=count)
+        except Exception as e:
+            mylog("report script error failed: %s", e)
+
+    def report_script_recovered(self, script, count):
+        '''
+        脚本在连续失败 count 次后恢复时调用
+        '''
+        mylog("script %s recovered after %d failures", script, count)
+        if not self.error_event:
+            return
+        try:
+            self.feed_system_event(tags=self.script_event_tags(script), df_status="ok",
+                df_event_id="pythond-" + script, df_title="pythond script %s recovered" % script,
+                df_message="recovered after %d failures" % count, script=script, count=count)
+        except Exception as e:
+            mylog("report script recovered failed: %s", e)
+
+    def script_event_tags(self, script):
+        return {"name": self.log_name, "script": script, "source": "pythond"}
+
     def construct_url(self, path):
         s = Template('http://${s1}:${s2}/${s3}')
         return s.safe_substitute(s1=self.__dk_host, s2=self.__dk_port, s3=path)
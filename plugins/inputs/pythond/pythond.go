@@ -8,6 +8,7 @@ package pythond
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -16,6 +17,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -42,8 +44,48 @@ const (
 	# Python 采集器可执行程序路径(尽可能写绝对路径)
 	cmd = "python3" # required. python3 is recommended.
 
+	# virtualenv 路径，设置后使用该 virtualenv 中的 python 运行，cmd 将被忽略
+	#virtualenv = "/path/to/venv"
+
 	# 用户脚本的相对路径(填写文件夹，填好后该文件夹下一级目录的模块和 py 文件都将得到应用)
 	dirs = []
+
+	# 安装 dirs 下的 requirements.txt 到独立的 virtualenv 中(设置了 virtualenv 时安装到该 virtualenv 中)，
+	# 设置 wheel_dir 后将只从该目录离线安装
+	#install_requirements = false
+	#wheel_dir = "/path/to/wheels"
+
+	# 脚本(包括 git 同步的脚本)变更后自动重启对应的 Python 进程，无需重启 datakit
+	#hot_reload = false
+
+	# Python 采集器上报数据的通道: http 或 unix(通过 unix socket 上报 protobuf 数据，不可用时使用 http)
+	#data_channel = "http"
+
+	# 脚本抛出异常时，除上报 last error(monitor 中可见)外，同时上报 keyevent(包含脚本名、traceback 及连续失败次数)
+	#script_error_event = false
+
+	# 开启选举后，只在选举成功的 datakit 上运行，适用于集群级别的采集(比如轮询某个 SaaS API)
+	#election = false
+
+	# Python 进程的 CPU(占所有 CPU 的百分比)及内存上限，Linux 上通过 cgroup 限制，
+	# cgroup 不可用时只通过 rlimit 限制内存
+	#cpu_max = 10.0
+	#mem_max_mb = 512
+
+	# 单次脚本运行的最长时间，超时后杀掉并重启 Python 进程，同时上报 last error
+	#timeout = "1m"
+
+	# 依赖冲突的脚本可以放到不同的 script_sets 中，每组使用各自的 virtualenv 或 cmd 在独立的进程中运行，
+	# 未设置 cmd/virtualenv/envs 时沿用上面的配置
+	#[[inputs.pythond.script_sets]]
+	#	dirs = []
+	#	virtualenv = "/path/to/another/venv"
+	#	# cmd = "/usr/bin/python3.9"
+	#	# envs = []
+	#	# install_requirements = true
+	#	# cpu_max = 10.0
+	#	# mem_max_mb = 512
+	#	# timeout = "1m"
 `
 )
 
@@ -54,6 +96,8 @@ var (
 	onceSetLog          sync.Once
 )
 
+var _ inputs.ElectionInput = (*Input)(nil)
+
 type Input struct {
 	Name string            `toml:"name"`
 	Cmd  string            `toml:"cmd"`
@@ -61,19 +105,41 @@ type Input struct {
 	Envs []string          `toml:"envs"`
 	Tags map[string]string `toml:"tags"` // TODO
 
-	cmd    *exec.Cmd
+	Virtualenv string       `toml:"virtualenv"`
+	ScriptSets []*ScriptSet `toml:"script_sets"`
+
+	InstallRequirements bool   `toml:"install_requirements"`
+	WheelDir            string `toml:"wheel_dir"`
+
+	HotReload bool `toml:"hot_reload"`
+
+	// http(default) or unix, HTTP API is used if unix socket not available
+	DataChannel string `toml:"data_channel"`
+
+	// report exceptions of scripts as keyevent besides last error
+	ScriptErrorEvent bool `toml:"script_error_event"`
+
+	Election bool `toml:"election"`
+
+	// limits of each python process, and max runtime of each script run
+	CPUMax   float64 `toml:"cpu_max"`
+	MemMaxMB int64   `toml:"mem_max_mb"`
+	Timeout  string  `toml:"timeout"`
+
+	pause   int32 // python processes stopped if paused by election, accessed atomically
+	pauseCh chan bool
+
 	feeder io.Feeder // TODO
+	sock   string    // unix socket listening for points
 
-	semStop    *cliutils.Sem // start stop signal
-	scriptName string
-	scriptRoot string
+	semStop *cliutils.Sem // start stop signal
 }
 
 func (*Input) Catalog() string { return inputName }
 
 func (*Input) SampleConfig() string { return configSample }
 
-func (*Input) AvailableArchs() []string { return datakit.AllOS }
+func (*Input) AvailableArchs() []string { return datakit.AllOSWithElection }
 
 func (*Input) SampleMeasurement() []inputs.Measurement { return []inputs.Measurement{} }
 
@@ -87,8 +153,8 @@ func getCliPyScript(scriptRoot, scriptName string) string {
 	return os.Expand(pyCli, func(k string) string { return replacePair[k] })
 }
 
-func (pe *Input) start() error {
-	cli := getCliPyScript(pe.scriptRoot, pe.scriptName)
+func (pe *Input) start(ss *ScriptSet) error {
+	cli := getCliPyScript(ss.scriptRoot, ss.scriptName)
 
 	pyTmpFle, err := ioutil.TempFile("", "pythond_")
 	if err != nil {
@@ -104,24 +170,40 @@ func (pe *Input) start() error {
 
 	l.Debugf("python tmp = %s, written: %d", pyTmpFle.Name(), n)
 
-	pe.cmd = exec.Command(pe.Cmd, pyTmpFle.Name(), fmt.Sprintf("--logname=%s", pe.Name)) //nolint:gosec
-	if pe.Envs != nil {
-		pe.cmd.Env = pe.Envs
+	args := []string{pyTmpFle.Name(), fmt.Sprintf("--logname=%s", pe.Name)}
+	if pe.ScriptErrorEvent {
+		args = append(args, "--error_event")
+	}
+	if pe.Election {
+		args = append(args, "--election")
+	}
+	args = append(args, ss.setupLimit()...)
+
+	ss.cmd = exec.Command(ss.interpreter, args...) //nolint:gosec
+	if ss.env != nil {
+		ss.cmd.Env = ss.env
+	}
+	if pe.sock != "" {
+		ss.cmd.Env = channelEnvs(pe.sock, ss.env)
 	}
 
-	stdout, err := pe.cmd.StdoutPipe()
+	stdout, err := ss.cmd.StdoutPipe()
 	if err != nil {
 		l.Errorf("cmd.StdoutPipe failed: %s", err.Error())
 		return err
 	}
-	pe.cmd.Stderr = pe.cmd.Stdout
+	ss.cmd.Stderr = ss.cmd.Stdout
 
-	l.Infof("starting cmd %s, envs: %+#v", pe.cmd.String(), pe.cmd.Env)
-	if err := pe.cmd.Start(); err != nil {
+	l.Infof("starting cmd %s, envs: %+#v", ss.cmd.String(), ss.cmd.Env)
+	if err := ss.cmd.Start(); err != nil {
 		l.Errorf("start pythond input %s failed: %s", pe.Name, err.Error())
+		ss.deleteCgroup()
 		return err
 	}
 
+	ss.addToCgroup()
+	ss.watchExit()
+
 	g := datakit.G("inputs_pythond")
 
 	g.Go(func(ctx context.Context) error {
@@ -241,22 +323,25 @@ func (*pythondImpl) GitHasEnabled() bool {
 
 //------------------------------------------------------------------------------
 
+// getPythonPath returns the full path of dir configured.
+func getPythonPath(dir string, ipd IPythond) string {
+	if ipd.GitHasEnabled() {
+		// enabled git
+		if filepath.IsAbs(dir) {
+			return dir
+		}
+		return searchPythondDir(dir, []string{datakit.GitReposRepoName}, ipd)
+	}
+
+	// not enabled git
+	return filepath.Join(datakit.PythonDDir, dir)
+}
+
 // Splicing Python related module information.
 func getScriptNameRoot(dirs []string, ipd IPythond) (scriptName, scriptRoot string, err error) {
 	var pyModules, modulesRoot []string
 	for _, v := range dirs {
-		var pythonPath string
-		if ipd.GitHasEnabled() {
-			// enabled git
-			if filepath.IsAbs(v) {
-				pythonPath = v
-			} else {
-				pythonPath = searchPythondDir(v, []string{datakit.GitReposRepoName}, ipd)
-			}
-		} else {
-			// not enabled git
-			pythonPath = filepath.Join(datakit.PythonDDir, v)
-		}
+		pythonPath := getPythonPath(v, ipd)
 
 		if ipd.IsDir(pythonPath) {
 			pyModules = append(pyModules, getPyModules(pythonPath, ipd)...)
@@ -298,44 +383,132 @@ func (pe *Input) Run() {
 		l.Error("name should not be empty.")
 		return
 	}
-	if len(pe.Dirs) == 0 {
+	sets := pe.scriptSets()
+	if len(sets) == 0 {
 		l.Error("dirs should not be empty.")
 		return
 	}
 
-	var err error
-	if pe.scriptName, pe.scriptRoot, err = getScriptNameRoot(pe.Dirs, &pythondImpl{}); err != nil {
-		l.Error(err)
-		return
+	// validate all sets before start, invalid ones are skipped
+	var ready []*ScriptSet
+	for i, ss := range sets {
+		ss.cgroupPath = cgroupPathOf(pe.Name, i)
+		if err := ss.init(&pythondImpl{}); err != nil {
+			l.Errorf("pythond input %s, dirs %v disabled: %s", pe.Name, ss.Dirs, err.Error())
+			io.FeedLastError(inputName, fmt.Sprintf("dirs %v disabled: %s", ss.Dirs, err.Error()))
+			continue
+		}
+
+		l.Debugf("scriptName = %v, scriptRoot = %v", ss.scriptName, ss.scriptRoot)
+		ready = append(ready, ss)
+	}
+
+	if pe.DataChannel == channelUnix {
+		if runtime.GOOS == datakit.OSWindows {
+			l.Warnf("pythond input %s: unix data channel not supported on windows, use HTTP", pe.Name)
+		} else if _, err := pe.listenChannel(); err != nil {
+			l.Warnf("pythond input %s: listen data channel failed: %s, use HTTP", pe.Name, err.Error())
+		} else {
+			pe.sock = socketPath(pe.Name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	g := datakit.G("inputs_pythond")
+
+	if pe.Election {
+		g.Go(func(ctx context.Context) error {
+			for {
+				select {
+				case pause := <-pe.pauseCh:
+					pe.setPause(pause)
+				case <-datakit.Exit.Wait():
+					return nil
+				case <-pe.semStop.Wait():
+					return nil
+				}
+			}
+		})
+	}
+	for _, ss := range ready {
+		func(ss *ScriptSet) {
+			wg.Add(1)
+			g.Go(func(ctx context.Context) error {
+				defer wg.Done()
+
+				pe.startRetry(ss)
+
+				if err := pe.MonitProc(ss); err != nil { // blocking here...
+					l.Errorf("datakit.MonitProc: %s", err.Error())
+				}
+
+				return nil
+			})
+		}(ss)
 	}
 
-	l.Debugf("pe.scriptName = %v, pe.scriptRoot = %v", pe.scriptName, pe.scriptRoot)
+	wg.Wait()
+}
 
+func (pe *Input) startRetry(ss *ScriptSet) {
 	for {
-		if err := pe.start(); err != nil { // start failed, retry
+		if err := pe.start(ss); err != nil { // start failed, retry
 			time.Sleep(time.Second)
 			continue
 		}
 		break
 	}
-
-	if err := pe.MonitProc(); err != nil { // blocking here...
-		l.Errorf("datakit.MonitProc: %s", err.Error())
-	}
 }
 
-func (pe *Input) MonitProc() error {
+func (pe *Input) MonitProc(ss *ScriptSet) error {
 	tick := time.NewTicker(time.Second)
 	defer tick.Stop()
 
-	if pe.cmd.Process == nil {
+	var reloadCh <-chan time.Time
+	if pe.HotReload {
+		reload := time.NewTicker(reloadCheckInterval)
+		defer reload.Stop()
+		reloadCh = reload.C
+	}
+
+	if ss.cmd.Process == nil {
 		return fmt.Errorf("invalid proc %s", pe.Name)
 	}
 
 	for {
 		select {
 		case <-tick.C:
-			p, err := os.FindProcess(pe.cmd.Process.Pid)
+			if pe.paused() {
+				if ss.cmd != nil {
+					l.Infof("pythond input %s paused by election, stop dirs %v", pe.Name, ss.Dirs)
+					if err := pe.stop(ss); err == nil {
+						_ = ss.wait() //nolint:errcheck
+						ss.cmd = nil
+					}
+				}
+				continue
+			}
+
+			if ss.cmd == nil {
+				l.Infof("pythond input %s resumed by election, start dirs %v", pe.Name, ss.Dirs)
+				pe.startRetry(ss)
+				continue
+			}
+
+			// exited unexpectedly, such as killed for timeout or OOM, restart it
+			select {
+			case err := <-ss.exited:
+				ss.deleteCgroup()
+				msg := ss.exitedMessage(pe.Name, err)
+				l.Warn(msg)
+				pe.feeder.FeedLastError(inputName, msg)
+
+				pe.startRetry(ss)
+				continue
+			default:
+			}
+
+			p, err := os.FindProcess(ss.cmd.Process.Pid)
 			if err != nil {
 				continue
 			}
@@ -349,35 +522,83 @@ func (pe *Input) MonitProc() error {
 				}
 			}
 
+		case <-reloadCh:
+			pe.reload(ss)
+
 		case <-datakit.Exit.Wait():
-			if err := pe.stop(); err != nil { // XXX: should we wait here?
-				return err
-			}
-			return nil
+			return pe.stopWait(ss)
 
 		case <-pe.semStop.Wait():
-			if err := pe.stop(); err != nil { // XXX: should we wait here?
-				return err
-			}
-			return nil
+			return pe.stopWait(ss)
 		}
 	}
 }
 
+func (pe *Input) ElectionEnabled() bool { return pe.Election }
+
+func (pe *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case pe.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (pe *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case pe.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func (pe *Input) setPause(pause bool) {
+	var v int32
+	if pause {
+		v = 1
+	}
+	atomic.StoreInt32(&pe.pause, v)
+}
+
+func (pe *Input) paused() bool { return atomic.LoadInt32(&pe.pause) == 1 }
+
 func (pe *Input) Terminate() {
 	if pe.semStop != nil {
 		pe.semStop.Close()
 	}
 }
 
-func (pe *Input) stop() error {
-	if err := pe.cmd.Process.Kill(); err != nil {
+func (pe *Input) stop(ss *ScriptSet) error {
+	if ss.cmd == nil { // stopped by election
+		return nil
+	}
+
+	if err := ss.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
 		l.Errorf("Input kill failed: %v", err)
 		return err
 	}
 	return nil
 }
 
+// stopWait stops the python process and waits it exited.
+func (pe *Input) stopWait(ss *ScriptSet) error {
+	if ss.cmd == nil {
+		return nil
+	}
+
+	if err := pe.stop(ss); err != nil {
+		return err
+	}
+	_ = ss.wait() //nolint:errcheck
+	return nil
+}
+
 func setLog() {
 	onceSetLog.Do(func() {
 		l = logger.SLogger(inputName)
@@ -388,6 +609,7 @@ func defaultInput() *Input {
 	return &Input{
 		feeder:  io.DefaultFeeder(),
 		semStop: cliutils.NewSem(),
+		pauseCh: make(chan bool, inputs.ElectionPauseChannelLength),
 	}
 }
 
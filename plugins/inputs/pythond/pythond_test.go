@@ -6,15 +6,30 @@
 package pythond
 
 import (
+	"context"
 	"crypto/md5"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/GuanceCloud/cliutils/point"
 	"github.com/stretchr/testify/assert"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 )
 
 func md5sum(str string) string {
@@ -42,7 +57,7 @@ func TestGetCliPyScript(t *testing.T) {
 
 	cli := getCliPyScript(scriptRoot, scriptName)
 
-	expectMD5 := "3701245679c4e4e4a4b9c7bafdba8947"
+	expectMD5 := "92b2afa1f1f7432cae5e51a6db6d6c29"
 
 	fmt.Println(cli)
 	assert.Equal(t, expectMD5, md5sum(cli), "md5 not equal!")
@@ -264,3 +279,511 @@ func TestGetScriptNameRoot(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+// go test -v -timeout 30s -run ^TestScriptSets$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestScriptSets(t *testing.T) {
+	pe := &Input{
+		Cmd:  "python3",
+		Dirs: []string{"a"},
+		Envs: []string{"A=1"},
+		ScriptSets: []*ScriptSet{
+			{Dirs: []string{"b"}},
+			{Dirs: []string{"c"}, Virtualenv: "/opt/venv/c", Envs: []string{}},
+		},
+	}
+
+	sets := pe.scriptSets()
+	assert.Len(t, sets, 3)
+
+	assert.Equal(t, &ScriptSet{Dirs: []string{"a"}, Cmd: "python3", Envs: []string{"A=1"}}, sets[0])
+	assert.Equal(t, &ScriptSet{Dirs: []string{"b"}, Cmd: "python3", Envs: []string{"A=1"}}, sets[1])
+	assert.Equal(t, &ScriptSet{Dirs: []string{"c"}, Virtualenv: "/opt/venv/c", Envs: []string{}}, sets[2])
+
+	// only script_sets configured
+	pe = &Input{Cmd: "python3", ScriptSets: []*ScriptSet{{Dirs: []string{"b"}, Cmd: "/usr/bin/python3.9"}}}
+	sets = pe.scriptSets()
+	assert.Len(t, sets, 1)
+	assert.Equal(t, "/usr/bin/python3.9", sets[0].Cmd)
+}
+
+// go test -v -timeout 30s -run ^TestResolveInterpreter$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestResolveInterpreter(t *testing.T) {
+	if runtime.GOOS == datakit.OSWindows {
+		t.Skip("skip on windows")
+	}
+
+	venv := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(venv, "bin"), 0o755))
+	python := filepath.Join(venv, "bin", "python")
+	assert.NoError(t, os.WriteFile(python, []byte("#!/bin/sh\necho Python 3.10.4\n"), 0o755)) //nolint:gosec
+
+	// virtualenv takes precedence over cmd
+	p, err := resolveInterpreter("python-not-exist", venv)
+	assert.NoError(t, err)
+	assert.Equal(t, python, p)
+
+	version, err := validateInterpreter(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "Python 3.10.4", version)
+
+	_, err = resolveInterpreter("", t.TempDir())
+	assert.Error(t, err, "virtualenv without python")
+
+	_, err = resolveInterpreter("python-not-exist", "")
+	assert.Error(t, err, "cmd not found")
+
+	_, err = resolveInterpreter("", "")
+	assert.Error(t, err, "cmd and virtualenv empty")
+
+	// explicit interpreter binary
+	p, err = resolveInterpreter(python, "")
+	assert.NoError(t, err)
+	assert.Equal(t, python, p)
+
+	// broken interpreter
+	assert.NoError(t, os.WriteFile(python, []byte("#!/bin/sh\nexit 1\n"), 0o755)) //nolint:gosec
+	_, err = validateInterpreter(python)
+	assert.Error(t, err)
+}
+
+// go test -v -timeout 30s -run ^TestVirtualenvEnvs$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestVirtualenvEnvs(t *testing.T) {
+	assert.Nil(t, virtualenvEnvs("", nil))
+	assert.Equal(t, []string{"A=1"}, virtualenvEnvs("", []string{"A=1"}))
+
+	envs := virtualenvEnvs("/opt/venv", []string{"A=1"})
+	assert.Equal(t, "A=1", envs[0])
+	assert.Equal(t, "VIRTUAL_ENV=/opt/venv", envs[1])
+	assert.True(t, strings.HasPrefix(envs[2], "PATH="+virtualenvBin("/opt/venv")))
+
+	// inherit envs of datakit
+	assert.Greater(t, len(virtualenvEnvs("/opt/venv", nil)), 2)
+}
+
+// go test -v -timeout 30s -run ^TestInstallRequirements$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestInstallRequirements(t *testing.T) {
+	if runtime.GOOS == datakit.OSWindows {
+		t.Skip("skip on windows")
+	}
+
+	defer func(pyd, cache string, f func(context.Context, []string, string, ...string) ([]byte, error)) {
+		datakit.PythonDDir, datakit.CacheDir, runCmd = pyd, cache, f
+	}(datakit.PythonDDir, datakit.CacheDir, runCmd)
+
+	datakit.PythonDDir = t.TempDir()
+	datakit.CacheDir = t.TempDir()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(datakit.PythonDDir, "mytest"), 0o755))
+	req := filepath.Join(datakit.PythonDDir, "mytest", requirementsFile)
+	assert.NoError(t, os.WriteFile(req, []byte("requests==2.28.1\n"), 0o600))
+
+	var cmds []string
+	runCmd = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+		cmds = append(cmds, strings.Join(append([]string{filepath.Base(name)}, args...), " "))
+
+		if len(args) > 3 && args[1] == "venv" {
+			venv := args[3]
+			if err := os.MkdirAll(filepath.Join(venv, "bin"), 0o755); err != nil {
+				return nil, err
+			}
+			return nil, os.WriteFile(filepath.Join(venv, "bin", "python"), nil, 0o755) //nolint:gosec
+		}
+
+		return nil, nil
+	}
+
+	newSet := func() *ScriptSet {
+		return &ScriptSet{Dirs: []string{"mytest"}, InstallRequirements: true, WheelDir: "/opt/wheels", interpreter: "/usr/bin/python3"}
+	}
+
+	ss := newSet()
+	assert.NoError(t, ss.installRequirements(&pythondImpl{}))
+
+	venv := managedVirtualenv("/usr/bin/python3", []string{"mytest"})
+	assert.Equal(t, venv, ss.venv)
+	assert.Equal(t, filepath.Join(venv, "bin", "python"), ss.interpreter)
+	assert.Equal(t, []string{
+		"python3 -m venv --system-site-packages " + venv,
+		"python -m pip install --disable-pip-version-check --no-index --find-links /opt/wheels -r " + req,
+	}, cmds)
+
+	// skipped if requirements not changed
+	cmds = nil
+	ss = newSet()
+	assert.NoError(t, ss.installRequirements(&pythondImpl{}))
+	assert.Empty(t, cmds)
+	assert.Equal(t, venv, ss.venv)
+
+	// reinstalled on requirements changed
+	assert.NoError(t, os.WriteFile(req, []byte("requests==2.31.0\n"), 0o600))
+	ss = newSet()
+	assert.NoError(t, ss.installRequirements(&pythondImpl{}))
+	assert.Len(t, cmds, 2)
+
+	// no requirements.txt
+	cmds = nil
+	ss = &ScriptSet{Dirs: []string{"not-exist"}, InstallRequirements: true, interpreter: "/usr/bin/python3"}
+	assert.NoError(t, ss.installRequirements(&pythondImpl{}))
+	assert.Empty(t, cmds)
+	assert.Equal(t, "/usr/bin/python3", ss.interpreter)
+}
+
+// go test -v -timeout 30s -run ^TestReload$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestReload(t *testing.T) {
+	if runtime.GOOS == datakit.OSWindows {
+		t.Skip("skip on windows")
+	}
+
+	defer func(pyd string) { datakit.PythonDDir = pyd }(datakit.PythonDDir)
+	datakit.PythonDDir = t.TempDir()
+
+	// fake python: answer --version and keep running as worker
+	python := filepath.Join(t.TempDir(), "python")
+	assert.NoError(t, os.WriteFile(python,
+		[]byte("#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then echo Python 3.8.0; exit 0; fi\nsleep 30\n"), 0o755)) //nolint:gosec
+
+	dir := filepath.Join(datakit.PythonDDir, "mytest")
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.py"), []byte("a = 1\n"), 0o600))
+
+	feeder := dkio.NewMockedFeeder()
+	pe := defaultInput()
+	pe.Name = "test"
+	pe.feeder = feeder
+
+	ss := &ScriptSet{Dirs: []string{"mytest"}, Cmd: python}
+	assert.NoError(t, ss.init(&pythondImpl{}))
+	assert.NoError(t, pe.start(ss))
+	defer func() { _ = pe.stop(ss) }()
+
+	pid := ss.cmd.Process.Pid
+
+	// not changed
+	pe.reload(ss)
+	assert.Equal(t, pid, ss.cmd.Process.Pid)
+
+	// changed
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.py"), []byte("a = 12\n"), 0o600))
+	pe.reload(ss)
+	assert.NotEqual(t, pid, ss.cmd.Process.Pid)
+	assert.Empty(t, feeder.LastErrors())
+
+	pts, err := feeder.AnyPoints(time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, pts, 1)
+	assert.Equal(t, []byte("info"), pts[0].Get([]byte("status")))
+
+	// changed but failed to init, keep the running one
+	pid = ss.cmd.Process.Pid
+	assert.NoError(t, os.Remove(filepath.Join(dir, "demo.py")))
+	pe.reload(ss)
+	assert.Equal(t, pid, ss.cmd.Process.Pid)
+	assert.Len(t, feeder.LastErrors(), 1)
+
+	pts, err = feeder.AnyPoints(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("error"), pts[0].Get([]byte("status")))
+}
+
+// go test -v -timeout 30s -run ^TestDataChannel$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestDataChannel(t *testing.T) {
+	if runtime.GOOS == datakit.OSWindows {
+		t.Skip("skip on windows")
+	}
+
+	frame := func(flags byte, category, input, pipelines string, pts ...*point.Point) []byte {
+		enc := point.GetEncoder(point.WithEncEncoding(point.Protobuf))
+		defer point.PutEncoder(enc)
+
+		var pb []byte
+		if len(pts) > 0 {
+			arr, err := enc.Encode(pts)
+			assert.NoError(t, err)
+			pb = arr[0]
+		}
+
+		payload := append([]byte{flags, byte(len(category))}, category...)
+		payload = append(append(payload, byte(len(input))), input...)
+		if flags&flagPipelines != 0 {
+			n := make([]byte, 2)
+			binary.BigEndian.PutUint16(n, uint16(len(pipelines)))
+			payload = append(append(payload, n...), pipelines...)
+		}
+		payload = append(payload, pb...)
+
+		buf := make([]byte, 4, 4+len(payload))
+		binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+		return append(buf, payload...)
+	}
+
+	readResp := func(r io.Reader) (byte, string) {
+		hdr := make([]byte, 3)
+		_, err := io.ReadFull(r, hdr)
+		assert.NoError(t, err)
+		msg := make([]byte, binary.BigEndian.Uint16(hdr[1:]))
+		_, err = io.ReadFull(r, msg)
+		assert.NoError(t, err)
+		return hdr[0], string(msg)
+	}
+
+	defer func(dir string) { datakit.DataDir = dir }(datakit.DataDir)
+	datakit.DataDir = t.TempDir()
+
+	feeder := &optsFeeder{MockedFeeder: dkio.NewMockedFeeder()}
+	pe := defaultInput()
+	pe.Name = "test"
+	pe.feeder = feeder
+
+	ln, err := pe.listenChannel()
+	assert.NoError(t, err)
+	defer ln.Close() //nolint:errcheck
+
+	conn, err := net.Dial("unix", socketPath(pe.Name))
+	assert.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	pt := point.NewPointV2([]byte("m1"),
+		append(point.NewTags(map[string]string{"t1": "v1"}), point.NewKVs(map[string]interface{}{"f1": 1.5})...),
+		point.WithTime(time.Unix(0, 123)))
+
+	_, err = conn.Write(frame(flagIgnoreGlobal, "metric", "my-input", "", pt))
+	assert.NoError(t, err)
+	status, msg := readResp(conn)
+	assert.Equal(t, statusOK, status, msg)
+
+	pts, err := feeder.AnyPoints(time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, pts, 1)
+	assert.Equal(t, pt.LineProto(), pts[0].LineProto())
+	assert.Empty(t, feeder.lastOpts())
+
+	// pipeline of measurement
+	_, err = conn.Write(frame(flagPipelines, "logging", "", `{"m1":"nginx","m2":"m2.p","m3":""}`, pt))
+	assert.NoError(t, err)
+	status, msg = readResp(conn)
+	assert.Equal(t, statusOK, status, msg)
+
+	_, err = feeder.AnyPoints(time.Second)
+	assert.NoError(t, err)
+	opts := feeder.lastOpts()
+	assert.Len(t, opts, 1)
+	assert.Equal(t, map[string]string{"m1": "nginx.p", "m2": "m2.p"}, opts[0].PlScript)
+
+	_, err = conn.Write(frame(flagPipelines, "logging", "", `{invalid`, pt))
+	assert.NoError(t, err)
+	status, _ = readResp(conn)
+	assert.Equal(t, statusErr, status)
+
+	// unknown category replied with error, connection kept
+	_, err = conn.Write(frame(0, "foo", "", "", pt))
+	assert.NoError(t, err)
+	status, _ = readResp(conn)
+	assert.Equal(t, statusErr, status)
+
+	// empty points
+	_, err = conn.Write(frame(0, "logging", "", ""))
+	assert.NoError(t, err)
+	status, _ = readResp(conn)
+	assert.Equal(t, statusOK, status)
+
+	t.Run("env", func(t *testing.T) {
+		envs := channelEnvs("/path/to/sock", []string{"A=1"})
+		assert.Equal(t, []string{"A=1", socketEnv + "=/path/to/sock"}, envs)
+	})
+}
+
+type optsFeeder struct {
+	*dkio.MockedFeeder
+
+	mtx  sync.Mutex
+	opts []*dkio.Option
+}
+
+func (f *optsFeeder) Feed(name string, category point.Category, pts []*point.Point, opts ...*dkio.Option) error {
+	f.mtx.Lock()
+	f.opts = opts
+	f.mtx.Unlock()
+
+	return f.MockedFeeder.Feed(name, category, pts, opts...)
+}
+
+func (f *optsFeeder) lastOpts() []*dkio.Option {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.opts
+}
+
+// go test -v -timeout 30s -run ^TestElection$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestElection(t *testing.T) {
+	if runtime.GOOS == datakit.OSWindows {
+		t.Skip("skip on windows")
+	}
+
+	defer func(pyd string) { datakit.PythonDDir = pyd }(datakit.PythonDDir)
+	datakit.PythonDDir = t.TempDir()
+
+	// fake python: record pid of each worker started
+	pids := filepath.Join(t.TempDir(), "pids")
+	python := filepath.Join(t.TempDir(), "python")
+	assert.NoError(t, os.WriteFile(python, []byte(fmt.Sprintf(
+		"#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then echo Python 3.8.0; exit 0; fi\necho $$ >> %s\nexec sleep 30\n", pids)), 0o755)) //nolint:gosec
+
+	dir := filepath.Join(datakit.PythonDDir, "mytest")
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.py"), []byte("a = 1\n"), 0o600))
+
+	running := func() (started int, alive bool) {
+		data, err := os.ReadFile(filepath.Clean(pids))
+		if err != nil {
+			return 0, false
+		}
+		arr := strings.Fields(string(data))
+		pid, err := strconv.Atoi(arr[len(arr)-1])
+		assert.NoError(t, err)
+		p, err := os.FindProcess(pid)
+		assert.NoError(t, err)
+		return len(arr), p.Signal(syscall.Signal(0)) == nil
+	}
+
+	pe := defaultInput()
+	pe.Name = "test"
+	pe.Election = true
+
+	ss := &ScriptSet{Dirs: []string{"mytest"}, Cmd: python}
+	assert.NoError(t, ss.init(&pythondImpl{}))
+	pe.startRetry(ss)
+
+	done := make(chan error)
+	go func() { done <- pe.MonitProc(ss) }()
+
+	assert.Eventually(t, func() bool { n, alive := running(); return n == 1 && alive }, 5*time.Second, 100*time.Millisecond)
+
+	pe.setPause(true)
+	assert.Eventually(t, func() bool { n, alive := running(); return n == 1 && !alive }, 5*time.Second, 100*time.Millisecond)
+
+	pe.setPause(false)
+	assert.Eventually(t, func() bool { n, alive := running(); return n == 2 && alive }, 5*time.Second, 100*time.Millisecond)
+
+	pe.Terminate()
+	assert.NoError(t, <-done)
+
+	t.Run("election-tags", func(t *testing.T) {
+		defer dkpt.ClearGlobalTags()
+		dkpt.SetGlobalHostTags("host", "h1")
+		dkpt.SetGlobalElectionTags("cluster", "c1")
+
+		enc := point.GetEncoder(point.WithEncEncoding(point.Protobuf))
+		defer point.PutEncoder(enc)
+		arr, err := enc.Encode([]*point.Point{
+			point.NewPointV2([]byte("m1"), point.NewKVs(map[string]interface{}{"f1": 1}), point.WithTime(time.Now())),
+		})
+		assert.NoError(t, err)
+
+		pts, err := decodeRequest(&dataRequest{category: point.Metric, electionTags: true, pbpts: arr[0]})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("c1"), pts[0].Get([]byte("cluster")))
+		assert.Nil(t, pts[0].Get([]byte("host")))
+	})
+}
+
+type mockCgroup struct {
+	pids    []int
+	deleted bool
+}
+
+func (c *mockCgroup) AddProc(pid int) error { c.pids = append(c.pids, pid); return nil }
+func (c *mockCgroup) Delete() error         { c.deleted = true; return nil }
+
+// go test -v -timeout 30s -run ^TestLimit$ gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/pythond
+func TestLimit(t *testing.T) {
+	t.Run("init", func(t *testing.T) {
+		ss := &ScriptSet{CPUMax: 10, MemMaxMB: 512, Timeout: "1m"}
+		assert.NoError(t, ss.initLimit())
+		assert.Equal(t, time.Minute, ss.timeout)
+
+		for _, ss := range []*ScriptSet{
+			{CPUMax: 101},
+			{MemMaxMB: -1},
+			{Timeout: "1x"},
+			{Timeout: "100ms"},
+		} {
+			assert.Error(t, ss.initLimit())
+		}
+
+		assert.Equal(t, "/datakit-pythond-some_python_inputs-1", cgroupPathOf("some.python inputs", 1))
+	})
+
+	t.Run("setup", func(t *testing.T) {
+		defer func(f func(string, float64, int64) (cgroup.ProcessCgroup, error)) { newProcessCgroup = f }(newProcessCgroup)
+
+		cg := &mockCgroup{}
+		newProcessCgroup = func(string, float64, int64) (cgroup.ProcessCgroup, error) { return cg, nil }
+
+		ss := &ScriptSet{MemMaxMB: 512, timeout: time.Minute}
+		assert.Equal(t, []string{"--timeout=60"}, ss.setupLimit())
+		assert.Equal(t, cg, ss.cgroup)
+
+		// cgroup not available, memory limited by rlimit
+		newProcessCgroup = func(string, float64, int64) (cgroup.ProcessCgroup, error) { return nil, fmt.Errorf("not supported") }
+		ss = &ScriptSet{CPUMax: 10, MemMaxMB: 512}
+		assert.Equal(t, []string{"--mem_max_mb=512"}, ss.setupLimit())
+		assert.Nil(t, ss.cgroup)
+
+		// no limits
+		ss = &ScriptSet{}
+		assert.Empty(t, ss.setupLimit())
+	})
+
+	t.Run("restart-on-exit", func(t *testing.T) {
+		if runtime.GOOS == datakit.OSWindows {
+			t.Skip("skip on windows")
+		}
+
+		defer func(pyd string) { datakit.PythonDDir = pyd }(datakit.PythonDDir)
+		datakit.PythonDDir = t.TempDir()
+
+		defer func(f func(string, float64, int64) (cgroup.ProcessCgroup, error)) { newProcessCgroup = f }(newProcessCgroup)
+		cg := &mockCgroup{}
+		newProcessCgroup = func(string, float64, int64) (cgroup.ProcessCgroup, error) { return cg, nil }
+
+		// fake python: the first worker exit as script run timeout, the later keep running
+		pids := filepath.Join(t.TempDir(), "pids")
+		python := filepath.Join(t.TempDir(), "python")
+		assert.NoError(t, os.WriteFile(python, []byte(fmt.Sprintf(
+			"#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then echo Python 3.8.0; exit 0; fi\n"+
+				"if [ -f %[1]s ]; then echo $$ >> %[1]s; exec sleep 30; fi\necho $$ >> %[1]s\nexit %[2]d\n",
+			pids, exitCodeTimeout)), 0o755)) //nolint:gosec
+
+		dir := filepath.Join(datakit.PythonDDir, "mytest")
+		assert.NoError(t, os.MkdirAll(dir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.py"), []byte("a = 1\n"), 0o600))
+
+		feeder := dkio.NewMockedFeeder()
+		pe := defaultInput()
+		pe.Name = "test"
+		pe.feeder = feeder
+
+		ss := &ScriptSet{Dirs: []string{"mytest"}, Cmd: python, MemMaxMB: 512, Timeout: "10s"}
+		assert.NoError(t, ss.init(&pythondImpl{}))
+		pe.startRetry(ss)
+
+		done := make(chan error)
+		go func() { done <- pe.MonitProc(ss) }()
+
+		assert.Eventually(t, func() bool {
+			data, err := os.ReadFile(filepath.Clean(pids))
+			return err == nil && len(strings.Fields(string(data))) == 2
+		}, 5*time.Second, 100*time.Millisecond)
+
+		pe.Terminate()
+		assert.NoError(t, <-done)
+
+		assert.Len(t, cg.pids, 2)
+		assert.True(t, cg.deleted)
+
+		errs := feeder.LastErrors()
+		assert.Len(t, errs, 1)
+		assert.Contains(t, errs[0][1], "exceeded timeout 10s")
+	})
+}
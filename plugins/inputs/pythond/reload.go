@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package pythond
+
+import (
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const reloadCheckInterval = 10 * time.Second
+
+// scriptsFingerprint returns the fingerprint of files under dirs of the set,
+// any file added, removed or modified(including pulled by git) changes it.
+func scriptsFingerprint(dirs []string, ipd IPythond) string {
+	h := md5.New() //nolint:gosec
+
+	for _, dir := range dirs {
+		root := getPythonPath(dir, ipd)
+		_ = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			if fi.IsDir() {
+				if p != root && (fi.Name() == "__pycache__" || strings.HasPrefix(fi.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			fmt.Fprintf(h, "%s %d %d\n", p, fi.Size(), fi.ModTime().UnixNano())
+			return nil
+		})
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// reload restarts the python worker of the set if its scripts changed. The
+// running worker is kept if the changed scripts failed to init.
+func (pe *Input) reload(ss *ScriptSet) {
+	if ss.cmd == nil { // stopped by election, changes applied on resumed
+		return
+	}
+
+	ipd := &pythondImpl{}
+	fp := scriptsFingerprint(ss.Dirs, ipd)
+	if fp == ss.fingerprint {
+		return
+	}
+
+	l.Infof("scripts of dirs %v changed, reloading...", ss.Dirs)
+
+	next := ss.clone()
+	if err := next.init(ipd); err != nil {
+		// not retry until changed again
+		ss.fingerprint = fp
+		pe.feedReload(ss, err)
+		return
+	}
+
+	if err := pe.stop(ss); err != nil {
+		pe.feedReload(ss, err)
+		return
+	}
+	_ = ss.wait() //nolint:errcheck
+
+	*ss = *next
+	pe.startRetry(ss)
+	pe.feedReload(ss, nil)
+}
+
+func (pe *Input) feedReload(ss *ScriptSet, err error) {
+	status, msg := "info", fmt.Sprintf("pythond %s reloaded dirs %v", pe.Name, ss.Dirs)
+	if err != nil {
+		status, msg = "error", fmt.Sprintf("pythond %s reload dirs %v failed: %s", pe.Name, ss.Dirs, err.Error())
+		l.Error(msg)
+		pe.feeder.FeedLastError(inputName, msg)
+	} else {
+		l.Info(msg)
+	}
+
+	tags := map[string]string{"name": pe.Name, "service": inputName, "status": status}
+	for k, v := range pe.Tags {
+		tags[k] = v
+	}
+
+	pt := point.NewPointV2([]byte(inputName),
+		append(point.NewTags(tags), point.NewKVs(map[string]interface{}{"message": msg})...),
+		point.DefaultLoggingOptions()...)
+
+	if err := pe.feeder.Feed(inputName, point.Logging, []*point.Point{pt}); err != nil {
+		l.Warnf("feed reload log failed: %s", err.Error())
+	}
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package pythond
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+const (
+	requirementsFile   = "requirements.txt"
+	installedMarker    = ".datakit_requirements"
+	installTimeout     = 10 * time.Minute
+	venvCacheDirectory = "pythond_venv"
+)
+
+// runCmd runs command and returns the combined output, mocked in testing.
+var runCmd = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec
+	cmd.Env = env
+	return cmd.CombinedOutput()
+}
+
+// findRequirements returns requirements.txt under dirs of the set.
+func findRequirements(dirs []string, ipd IPythond) []string {
+	var arr []string
+	for _, dir := range dirs {
+		pythonPath := getPythonPath(dir, ipd)
+		if !ipd.IsDir(pythonPath) {
+			continue
+		}
+
+		if f := filepath.Join(pythonPath, requirementsFile); ipd.FileExist(f) {
+			arr = append(arr, f)
+		}
+	}
+
+	sort.Strings(arr)
+	return arr
+}
+
+// requirementsHash identifies the content of requirements and how they installed.
+func requirementsHash(interpreter, wheelDir string, requirements []string) (string, error) {
+	h := md5.New() //nolint:gosec
+	h.Write([]byte(interpreter + "\n" + wheelDir + "\n"))
+
+	for _, f := range requirements {
+		data, err := os.ReadFile(filepath.Clean(f))
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(f + "\n"))
+		h.Write(data)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// managedVirtualenv returns the virtualenv path created by datakit for the set.
+func managedVirtualenv(interpreter string, dirs []string) string {
+	sum := md5.Sum([]byte(interpreter + "\n" + strings.Join(dirs, "\n"))) //nolint:gosec
+	return filepath.Join(datakit.CacheDir, venvCacheDirectory, fmt.Sprintf("%x", sum)[:16])
+}
+
+// installRequirements installs requirements.txt of the set into the
+// virtualenv configured, or a virtualenv created by datakit based on the
+// interpreter. Installing skipped if requirements not changed since last time.
+func (ss *ScriptSet) installRequirements(ipd IPythond) error {
+	requirements := findRequirements(ss.Dirs, ipd)
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	hash, err := requirementsHash(ss.interpreter, ss.WheelDir, requirements)
+	if err != nil {
+		return fmt.Errorf("read requirements failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), installTimeout)
+	defer cancel()
+
+	venv := ss.Virtualenv
+	if venv == "" {
+		venv = managedVirtualenv(ss.interpreter, ss.Dirs)
+	}
+
+	marker := filepath.Join(venv, installedMarker)
+	if data, err := os.ReadFile(filepath.Clean(marker)); err == nil && string(data) == hash {
+		l.Infof("requirements %v already installed in %s", requirements, venv)
+	} else {
+		if err := ss.doInstall(ctx, venv, requirements); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(marker, []byte(hash), datakit.ConfPerm); err != nil {
+			return err
+		}
+	}
+
+	python, err := resolveInterpreter("", venv)
+	if err != nil {
+		return err
+	}
+
+	ss.venv = venv
+	ss.interpreter = python
+
+	return nil
+}
+
+func (ss *ScriptSet) doInstall(ctx context.Context, venv string, requirements []string) error {
+	if ss.Virtualenv == "" {
+		// recreate on requirements changed, packages of system site are
+		// kept visible for the dependencies of datakit framework.
+		if err := os.RemoveAll(venv); err != nil {
+			return err
+		}
+
+		if out, err := runCmd(ctx, nil, ss.interpreter, "-m", "venv", "--system-site-packages", venv); err != nil {
+			return fmt.Errorf("create virtualenv %s failed: %w, output: %s", venv, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	python, err := resolveInterpreter("", venv)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check"}
+	if ss.WheelDir != "" {
+		args = append(args, "--no-index", "--find-links", ss.WheelDir)
+	}
+	for _, f := range requirements {
+		args = append(args, "-r", f)
+	}
+
+	l.Infof("installing requirements %v into %s...", requirements, venv)
+	if out, err := runCmd(ctx, virtualenvEnvs(venv, ss.Envs), python, args...); err != nil {
+		return fmt.Errorf("install requirements %v failed: %w, output: %s", requirements, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package pythond
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/cgroup"
+)
+
+const validateTimeout = 10 * time.Second
+
+// ScriptSet is a group of dirs running with the same interpreter, scripts with
+// conflicting dependencies can be put into different sets with their own virtualenv.
+type ScriptSet struct {
+	Dirs       []string `toml:"dirs"`
+	Cmd        string   `toml:"cmd"`
+	Virtualenv string   `toml:"virtualenv"`
+	Envs       []string `toml:"envs"`
+
+	// install requirements.txt under dirs, from wheel_dir only if set
+	InstallRequirements bool   `toml:"install_requirements"`
+	WheelDir            string `toml:"wheel_dir"`
+
+	// CPU(percent of all CPUs) and memory limits of the python process, and
+	// max runtime of each script run
+	CPUMax   float64 `toml:"cpu_max"`
+	MemMaxMB int64   `toml:"mem_max_mb"`
+	Timeout  string  `toml:"timeout"`
+
+	interpreter string
+	venv        string // virtualenv activated, may be created by datakit
+	env         []string
+	fingerprint string
+	cmd         *exec.Cmd
+	exited      chan error // Wait() result of cmd
+	scriptName  string
+	scriptRoot  string
+
+	timeout    time.Duration
+	cgroupPath string
+	cgroup     cgroup.ProcessCgroup
+}
+
+// scriptSets returns the top-level dirs as the first set, then the sets
+// configured, cmd and envs not set are inherited from the top-level ones.
+func (pe *Input) scriptSets() []*ScriptSet {
+	var sets []*ScriptSet
+	if len(pe.Dirs) > 0 {
+		sets = append(sets, &ScriptSet{
+			Dirs:       pe.Dirs,
+			Cmd:        pe.Cmd,
+			Virtualenv: pe.Virtualenv,
+			Envs:       pe.Envs,
+
+			InstallRequirements: pe.InstallRequirements,
+			WheelDir:            pe.WheelDir,
+
+			CPUMax:   pe.CPUMax,
+			MemMaxMB: pe.MemMaxMB,
+			Timeout:  pe.Timeout,
+		})
+	}
+
+	for _, set := range pe.ScriptSets {
+		if set == nil {
+			continue
+		}
+		if set.Cmd == "" && set.Virtualenv == "" {
+			set.Cmd = pe.Cmd
+			set.Virtualenv = pe.Virtualenv
+		}
+		if set.Envs == nil {
+			set.Envs = pe.Envs
+		}
+		if !set.InstallRequirements {
+			set.InstallRequirements = pe.InstallRequirements
+		}
+		if set.WheelDir == "" {
+			set.WheelDir = pe.WheelDir
+		}
+		if set.CPUMax == 0 {
+			set.CPUMax = pe.CPUMax
+		}
+		if set.MemMaxMB == 0 {
+			set.MemMaxMB = pe.MemMaxMB
+		}
+		if set.Timeout == "" {
+			set.Timeout = pe.Timeout
+		}
+		sets = append(sets, set)
+	}
+
+	return sets
+}
+
+// init resolves the interpreter and the scripts of the set.
+func (ss *ScriptSet) init(ipd IPythond) error {
+	if len(ss.Dirs) == 0 {
+		return fmt.Errorf("dirs should not be empty")
+	}
+
+	interpreter, err := resolveInterpreter(ss.Cmd, ss.Virtualenv)
+	if err != nil {
+		return err
+	}
+
+	version, err := validateInterpreter(interpreter)
+	if err != nil {
+		return err
+	}
+
+	l.Infof("dirs %v run with %s(%s)", ss.Dirs, interpreter, version)
+
+	if err := ss.initLimit(); err != nil {
+		return err
+	}
+
+	ss.interpreter = interpreter
+	ss.venv = ss.Virtualenv
+	ss.fingerprint = scriptsFingerprint(ss.Dirs, ipd)
+	if ss.InstallRequirements {
+		if err := ss.installRequirements(ipd); err != nil {
+			return err
+		}
+	}
+	ss.env = virtualenvEnvs(ss.venv, ss.Envs)
+
+	ss.scriptName, ss.scriptRoot, err = getScriptNameRoot(ss.Dirs, ipd)
+
+	return err
+}
+
+// clone returns the set with configured fields only, for init again.
+func (ss *ScriptSet) clone() *ScriptSet {
+	return &ScriptSet{
+		Dirs:       ss.Dirs,
+		Cmd:        ss.Cmd,
+		Virtualenv: ss.Virtualenv,
+		Envs:       ss.Envs,
+
+		InstallRequirements: ss.InstallRequirements,
+		WheelDir:            ss.WheelDir,
+
+		CPUMax:   ss.CPUMax,
+		MemMaxMB: ss.MemMaxMB,
+		Timeout:  ss.Timeout,
+
+		cgroupPath: ss.cgroupPath,
+	}
+}
+
+func virtualenvBin(venv string) string {
+	if runtime.GOOS == datakit.OSWindows {
+		return filepath.Join(venv, "Scripts")
+	}
+
+	return filepath.Join(venv, "bin")
+}
+
+// resolveInterpreter returns the python of virtualenv if set, or the cmd searched in PATH.
+func resolveInterpreter(cmd, venv string) (string, error) {
+	if venv != "" {
+		python := filepath.Join(virtualenvBin(venv), "python")
+		if runtime.GOOS == datakit.OSWindows {
+			python += ".exe"
+		}
+
+		if fi, err := os.Stat(python); err != nil || fi.IsDir() {
+			return "", fmt.Errorf("invalid virtualenv %s: python not found at %s", venv, python)
+		}
+
+		return python, nil
+	}
+
+	if cmd == "" {
+		return "", fmt.Errorf("cmd or virtualenv should not be empty")
+	}
+
+	python, err := exec.LookPath(cmd)
+	if err != nil {
+		return "", fmt.Errorf("invalid cmd %s: %w", cmd, err)
+	}
+
+	return python, nil
+}
+
+// validateInterpreter runs the interpreter to make sure it works, returns its version.
+func validateInterpreter(python string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, python, "--version").CombinedOutput() //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("run %s --version failed: %w, output: %s", python, err, strings.TrimSpace(string(out)))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// virtualenvEnvs activates the virtualenv by VIRTUAL_ENV and PATH, nil
+// returned means inheriting envs of datakit.
+func virtualenvEnvs(venv string, envs []string) []string {
+	if venv == "" {
+		return envs
+	}
+
+	if envs == nil {
+		envs = os.Environ()
+	}
+
+	path := virtualenvBin(venv)
+	if p := os.Getenv("PATH"); p != "" {
+		path += string(os.PathListSeparator) + p
+	}
+
+	// the later ones take effect on duplicated keys
+	return append(append([]string{}, envs...), "VIRTUAL_ENV="+venv, "PATH="+path)
+}
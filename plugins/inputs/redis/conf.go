@@ -52,6 +52,15 @@ const (
   ## Collect INFO COMMANDSTATS output as metrics.
   # command_stats = false
 
+  ## @param mode - string - optional - default: ""
+  ## Discover and collect all nodes of the deployment from a single conf:
+  ##   "cluster":  host/port above is any node of the Redis Cluster
+  ##   "sentinel": host/port above is the Sentinel, master_name required
+  ## All nodes share the username/password above.
+  # mode = "cluster"
+  # master_name = "mymaster"
+  # sentinel_password = ""
+
   ## Set true to enable election
   election = true
   
@@ -69,6 +69,10 @@ type Input struct {
 	DBS               []int             `toml:"dbs"`
 	Log               *redislog         `toml:"log"`
 
+	Mode             string `toml:"mode"`
+	MasterName       string `toml:"master_name"`
+	SentinelPassword string `toml:"sentinel_password"`
+
 	MatchDeprecated   string   `toml:"match,omitempty"`
 	ServersDeprecated []string `toml:"servers,omitempty"`
 
@@ -79,6 +83,7 @@ type Input struct {
 	collectors []func() ([]*point.Point, error)
 
 	client *redis.Client
+	topo   *topology
 
 	Election        bool `toml:"election"`
 	pause           bool
@@ -113,6 +118,20 @@ func (i *Input) initCfg() error {
 
 	i.Addr = fmt.Sprintf("%s:%d", i.Host, i.Port)
 
+	switch i.Mode {
+	case "", modeCluster:
+	case modeSentinel:
+		if i.MasterName == "" {
+			return fmt.Errorf("master_name required in %s mode", modeSentinel)
+		}
+
+		if err := i.initSentinel(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown mode %q, only %s and %s supported", i.Mode, modeCluster, modeSentinel)
+	}
+
 	client := redis.NewClient(&redis.Options{
 		Addr:     i.Addr,
 		Username: i.Username,
@@ -142,6 +161,33 @@ func (i *Input) initCfg() error {
 	return nil
 }
 
+// initSentinel connects to the sentinel configured, and the client of the
+// input connects to the master got from the sentinel.
+func (i *Input) initSentinel() error {
+	if i.topo == nil {
+		i.topo = newTopology()
+	}
+
+	if i.topo.sentinel == nil {
+		i.topo.sentinel = redis.NewSentinelClient(&redis.Options{
+			Addr:        i.Addr,
+			Password:    i.SentinelPassword,
+			DialTimeout: i.timeoutDuration,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeoutDuration)
+	defer cancel()
+
+	addr, err := i.sentinelMaster(ctx)
+	if err != nil {
+		return err
+	}
+
+	i.Addr = addr
+	return nil
+}
+
 func (*Input) PipelineConfig() map[string]string {
 	pipelineMap := map[string]string{
 		inputName: pipelineCfg,
@@ -364,6 +410,13 @@ func (i *Input) Run() {
 		}
 	}
 
+	if i.Mode != "" {
+		if i.topo == nil {
+			i.topo = newTopology()
+		}
+		i.collectors = append(i.collectors, i.collectTopology)
+	}
+
 	if len(i.Keys) > 0 {
 		i.collectors = append(i.collectors, i.collectBigKeyMeasurement)
 	}
@@ -402,6 +455,10 @@ func (i *Input) exit() {
 		i.tail.Close()
 		l.Info("redis log exit")
 	}
+
+	if i.topo != nil {
+		i.topo.close()
+	}
 }
 
 func (i *Input) Terminate() {
@@ -423,9 +480,12 @@ func (*Input) SampleMeasurement() []inputs.Measurement {
 		&clusterMeasurement{},
 		&commandMeasurement{},
 		&dbMeasurement{},
+		&failoverMeasurement{},
 		&infoMeasurement{},
 		&latencyMeasurement{},
+		&nodeMeasurement{},
 		&slowlogMeasurement{},
+		&topologyMeasurement{},
 	}
 }
 
@@ -6,7 +6,9 @@
 package redis
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"sync"
@@ -15,8 +17,6 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/GuanceCloud/cliutils/point"
-	dt "github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	tu "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
@@ -32,11 +32,13 @@ type caseSpec struct {
 	envs        []string
 	servicePort string
 
+	// toxics are network faults injected between redis and the input.
+	toxics []*tu.Toxic
+
 	ipt    *Input
 	feeder *io.MockedFeeder
 
-	pool     *dt.Pool
-	resource *dt.Resource
+	container tu.Container
 
 	cr *tu.CaseResult
 }
@@ -47,17 +49,30 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 	remote := tu.GetRemote()
 
 	bases := []struct {
-		name string
-		conf string
+		name   string
+		conf   string
+		toxics []*tu.Toxic
 	}{
 		{
 			name: "remote-redis",
 
+			conf: fmt.Sprintf(`
+host = "%s"`,
+				remote.Host),
+		},
+
+		{
+			name: "remote-redis-slow-network",
+
 			conf: fmt.Sprintf(`
 host = "%s"
-port = %s`,
-				remote.Host,
-				fmt.Sprintf("%d", tu.RandPort("tcp"))),
+connect_timeout = "10s"`,
+				remote.Host),
+
+			toxics: []*tu.Toxic{
+				tu.Latency(500*time.Millisecond, 100*time.Millisecond),
+				tu.Bandwidth(64),
+			},
 		},
 	}
 
@@ -85,6 +100,9 @@ port = %s`,
 			_, err := toml.Decode(base.conf, ipt)
 			assert.NoError(t, err)
 
+			// cases run in parallel, each binds its own port
+			ipt.Port = tu.RandPort("tcp")
+
 			envs := []string{}
 
 			// ipport, err := netip.ParseAddrPort(ipt.Host)
@@ -96,6 +114,7 @@ port = %s`,
 				name:   base.name,
 				feeder: feeder,
 				envs:   envs,
+				toxics: base.toxics,
 
 				repo:    img[0],
 				repoTag: img[1],
@@ -210,15 +229,14 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 }
 
 func (cs *caseSpec) run() error {
-	// start remote sqlserver
+	// start remote redis
 	r := tu.GetRemote()
-	dockerTCP := r.TCPURL()
 
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
+	cs.t.Logf("get remote: %+#v", r)
 
 	start := time.Now()
 
-	p, err := dt.NewPool(dockerTCP)
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return err
 	}
@@ -229,38 +247,34 @@ func (cs *caseSpec) run() error {
 		hostname = "unknown-hostname"
 	}
 
-	containerName := fmt.Sprintf("%s.%s", hostname, cs.name)
+	// cases of different images share the same name
+	containerName := fmt.Sprintf("%s.%s.%s", hostname, cs.name, cs.repoTag)
 
 	// remove container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 
-	resource, err := p.RunWithOptions(&dt.RunOptions{
+	c, err := rt.Run(&tu.RunOptions{
 		// specify container image & tag
 		Repository: cs.repo,
 		Tag:        cs.repoTag,
 
 		// port binding
-		PortBindings: map[docker.Port][]docker.PortBinding{
-			"1433/tcp": {{HostIP: "0.0.0.0", HostPort: cs.servicePort}},
-		},
+		PortBindings: map[string]string{"6379/tcp": cs.servicePort},
 
 		Name: containerName,
 
 		// container run-time envs
 		Env: cs.envs,
-	}, func(c *docker.HostConfig) {
-		c.RestartPolicy = docker.RestartPolicy{Name: "no"}
 	})
 	if err != nil {
 		return err
 	}
-	cs.pool = p
 
-	// set input's port to port exposed by docker
-	cs.resource = resource
-	exposedPort := resource.GetPort("6379/tcp")
+	// set input's port to port exposed by container
+	cs.container = c
+	exposedPort := c.GetPort("6379/tcp")
 	cs.ipt.Port, _ = strconv.Atoi(exposedPort)
 
 	cs.t.Logf("check service(%s:%s)...", r.Host, exposedPort)
@@ -270,6 +284,12 @@ func (cs *caseSpec) run() error {
 
 	cs.cr.AddField("container_ready_cost", int64(time.Since(start)))
 
+	if len(cs.toxics) > 0 {
+		if err := cs.injectToxics(net.JoinHostPort(r.Host, exposedPort)); err != nil {
+			return err
+		}
+	}
+
 	var wg sync.WaitGroup
 
 	// start input
@@ -283,9 +303,12 @@ func (cs *caseSpec) run() error {
 	// wait data
 	start = time.Now()
 	cs.t.Logf("wait points...")
-	pts, err := cs.feeder.AnyPoints()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pts, err := cs.feeder.AnyPointsWithin(ctx, point.Metric, 1)
 	if err != nil {
-		return err
+		return fmt.Errorf("wait points: %w", err)
 	}
 
 	cs.cr.AddField("point_latency", int64(time.Since(start)))
@@ -320,35 +343,68 @@ func TestRedisInput(t *testing.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []tu.TestingCase
 	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-			t.Logf("testing %s...", tc.name)
+	tu.RunCases(t, arr)
+}
 
-			if err := tc.run(); err != nil {
-				tc.cr.Status = tu.TestFailed
-				tc.cr.FailedMessage = err.Error()
+// injectToxics starts a toxiproxy between the input and redis on upstream,
+// and applies toxics of the case on it.
+func (cs *caseSpec) injectToxics(upstream string) error {
+	port := tu.RandPort("tcp")
 
-				assert.NoError(t, err)
-			} else {
-				tc.cr.Status = tu.TestPassed
-			}
+	tp, err := tu.StartToxiproxy(cs.t, port)
+	if err != nil {
+		return err
+	}
 
-			tc.cr.Cost = time.Since(caseStart)
+	p, err := tp.CreateProxy(cs.name, port, upstream)
+	if err != nil {
+		return err
+	}
+
+	for _, toxic := range cs.toxics {
+		if err := p.AddToxic(toxic); err != nil {
+			return err
+		}
+	}
 
-			assert.NoError(t, tu.Flush(tc.cr))
+	cs.t.Logf("redis %s proxied on %s with %d toxics", upstream, p.Addr(), len(cs.toxics))
 
-			t.Cleanup(func() {
-				// clean remote docker resources
-				if tc.resource == nil {
-					return
-				}
+	// the input connects to redis by the proxy
+	cs.ipt.Host = p.Host()
+	cs.ipt.Port = p.Port()
+	cs.cr.AddTag("toxics", fmt.Sprintf("%d", len(cs.toxics)))
 
-				assert.NoError(t, tc.pool.Purge(tc.resource))
-			})
-		})
+	return nil
+}
+
+func (cs *caseSpec) CaseName() string { return cs.name }
+
+func (cs *caseSpec) Result() *tu.CaseResult { return cs.cr }
+
+func (cs *caseSpec) Run(t *testing.T) error {
+	cs.t = t
+	return cs.run()
+}
+
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
 	}
+
+	return cs.container.Purge()
+}
+
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []tu.Container {
+	if cs.container == nil {
+		return nil
+	}
+
+	return []tu.Container{cs.container}
 }
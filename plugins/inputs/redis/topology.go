@@ -0,0 +1,735 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/go-redis/redis/v8"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	modeCluster  = "cluster"
+	modeSentinel = "sentinel"
+
+	roleMaster  = "master"
+	roleReplica = "slave"
+
+	clusterSlots = 16384
+
+	failoverEventName = "redis_failover"
+)
+
+// redisNode is a node discovered from the Redis Cluster or Sentinel.
+type redisNode struct {
+	ID         string // node ID, cluster only
+	Addr       string
+	Role       string
+	MasterID   string // cluster only
+	MasterAddr string
+	Slots      [][2]int // slot ranges served, cluster only
+	Failed     bool
+	Myself     bool // the node configured
+}
+
+// key identifies the node across discoveries.
+func (n *redisNode) key() string {
+	if n.ID != "" {
+		return n.ID
+	}
+	return n.Addr
+}
+
+func (n *redisNode) slotCount() int {
+	cnt := 0
+	for _, r := range n.Slots {
+		cnt += r[1] - r[0] + 1
+	}
+	return cnt
+}
+
+// topology keeps clients of discovered nodes and the last seen state used
+// to detect failover.
+type topology struct {
+	sentinel *redis.SentinelClient
+	clients  map[string]*redis.Client
+	cpuUsage map[string]*redisCPUUsage
+
+	lastRoles  map[string]string
+	lastFailed map[string]bool
+}
+
+func newTopology() *topology {
+	return &topology{
+		clients:  map[string]*redis.Client{},
+		cpuUsage: map[string]*redisCPUUsage{},
+	}
+}
+
+func (t *topology) close() {
+	for addr, cli := range t.clients {
+		_ = cli.Close() //nolint:errcheck
+		delete(t.clients, addr)
+	}
+
+	if t.sentinel != nil {
+		_ = t.sentinel.Close() //nolint:errcheck
+	}
+}
+
+// prune closes clients of nodes gone.
+func (t *topology) prune(nodes []*redisNode) {
+	alive := map[string]bool{}
+	for _, n := range nodes {
+		alive[n.Addr] = true
+	}
+
+	for addr, cli := range t.clients {
+		if !alive[addr] {
+			_ = cli.Close() //nolint:errcheck
+			delete(t.clients, addr)
+			delete(t.cpuUsage, addr)
+		}
+	}
+}
+
+type topologyEvent struct {
+	node    *redisNode
+	status  string
+	title   string
+	message string
+}
+
+// diff compares nodes with the last discovery, returns events on replica
+// promoted to master(failover happened) and node failed.
+func (t *topology) diff(nodes []*redisNode) []*topologyEvent {
+	var events []*topologyEvent
+
+	if t.lastRoles != nil {
+		for _, n := range nodes {
+			if t.lastRoles[n.key()] == roleReplica && n.Role == roleMaster {
+				events = append(events, &topologyEvent{
+					node:    n,
+					status:  "warning",
+					title:   fmt.Sprintf("Redis node %s promoted to master", n.Addr),
+					message: fmt.Sprintf("Redis replica %s has been promoted to master, failover happened", n.Addr),
+				})
+			}
+
+			if n.Failed && !t.lastFailed[n.key()] {
+				events = append(events, &topologyEvent{
+					node:    n,
+					status:  "critical",
+					title:   fmt.Sprintf("Redis node %s failed", n.Addr),
+					message: fmt.Sprintf("Redis %s %s has been marked as failed", n.Role, n.Addr),
+				})
+			}
+		}
+	}
+
+	t.lastRoles = map[string]string{}
+	t.lastFailed = map[string]bool{}
+	for _, n := range nodes {
+		t.lastRoles[n.key()] = n.Role
+		t.lastFailed[n.key()] = n.Failed
+	}
+
+	return events
+}
+
+// parseClusterNodes parses output of CLUSTER NODES, such as
+//
+//	<id> <ip:port@cport[,hostname]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+func parseClusterNodes(text string) []*redisNode {
+	var (
+		nodes []*redisNode
+		addrs = map[string]string{}
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 8 {
+			continue
+		}
+
+		flags := strings.Split(parts[2], ",")
+		if hasFlag(flags, "noaddr", "handshake") {
+			continue
+		}
+
+		addr := parts[1]
+		if idx := strings.IndexAny(addr, "@,"); idx >= 0 {
+			addr = addr[:idx]
+		}
+
+		n := &redisNode{
+			ID:     parts[0],
+			Addr:   addr,
+			Role:   roleReplica,
+			Failed: hasFlag(flags, "fail"),
+			Myself: hasFlag(flags, "myself"),
+		}
+
+		if hasFlag(flags, "master") {
+			n.Role = roleMaster
+		} else if parts[3] != "-" {
+			n.MasterID = parts[3]
+		}
+
+		for _, s := range parts[8:] {
+			if strings.HasPrefix(s, "[") { // slot migrating or importing
+				continue
+			}
+
+			from, to := s, s
+			if idx := strings.Index(s, "-"); idx > 0 {
+				from, to = s[:idx], s[idx+1:]
+			}
+
+			start, err := strconv.Atoi(from)
+			if err != nil {
+				continue
+			}
+			end, err := strconv.Atoi(to)
+			if err != nil {
+				continue
+			}
+			n.Slots = append(n.Slots, [2]int{start, end})
+		}
+
+		addrs[n.ID] = n.Addr
+		nodes = append(nodes, n)
+	}
+
+	for _, n := range nodes {
+		if n.MasterID != "" {
+			n.MasterAddr = addrs[n.MasterID]
+		}
+	}
+
+	return nodes
+}
+
+// sentinelNode builds the node from replies of SENTINEL MASTER/REPLICAS.
+func sentinelNode(kv map[string]string, masterAddr string) *redisNode {
+	flags := strings.Split(kv["flags"], ",")
+
+	n := &redisNode{
+		Addr:   net.JoinHostPort(kv["ip"], kv["port"]),
+		Role:   roleReplica,
+		Failed: hasFlag(flags, "s_down", "o_down"),
+	}
+
+	if hasFlag(flags, "master") {
+		n.Role = roleMaster
+	} else {
+		n.MasterAddr = masterAddr
+	}
+
+	return n
+}
+
+// sentinelReplyMap converts the flat key-value reply of sentinel to map.
+func sentinelReplyMap(v interface{}) map[string]string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	kv := map[string]string{}
+	for i := 0; i+1 < len(arr); i += 2 {
+		kv[fmt.Sprint(arr[i])] = fmt.Sprint(arr[i+1])
+	}
+	return kv
+}
+
+func hasFlag(flags []string, want ...string) bool {
+	for _, f := range flags {
+		for _, w := range want {
+			if f == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// slotsCovered counts slots served by masters not failed.
+func slotsCovered(nodes []*redisNode) int {
+	var covered [clusterSlots]bool
+	for _, n := range nodes {
+		if n.Role != roleMaster || n.Failed {
+			continue
+		}
+
+		for _, r := range n.Slots {
+			for s := r[0]; s <= r[1] && s < clusterSlots; s++ {
+				if s >= 0 {
+					covered[s] = true
+				}
+			}
+		}
+	}
+
+	cnt := 0
+	for _, c := range covered {
+		if c {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// replLag returns bytes the replica behind its master, -1 if unknown.
+func replLag(masterInfo, replicaInfo map[string]interface{}) int64 {
+	if masterInfo == nil || replicaInfo == nil {
+		return -1
+	}
+
+	masterOffset, ok := infoInt(masterInfo, "master_repl_offset")
+	if !ok {
+		return -1
+	}
+
+	offset, ok := infoInt(replicaInfo, "slave_repl_offset")
+	if !ok {
+		if offset, ok = infoInt(replicaInfo, "master_repl_offset"); !ok {
+			return -1
+		}
+	}
+
+	// master offset got a bit earlier than replica's
+	if lag := masterOffset - offset; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+func infoInt(info map[string]interface{}, key string) (int64, bool) {
+	v, ok := info[key]
+	if !ok {
+		return 0, false
+	}
+
+	x, err := strconv.ParseInt(strings.TrimSpace(fmt.Sprint(v)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return x, true
+}
+
+func (i *Input) newNodeClient(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:        addr,
+		Username:    i.Username,
+		Password:    i.Password,
+		DialTimeout: i.timeoutDuration,
+	})
+}
+
+// sentinelMaster asks the sentinel for address of the master.
+func (i *Input) sentinelMaster(ctx context.Context) (string, error) {
+	addr, err := i.topo.sentinel.GetMasterAddrByName(ctx, i.MasterName).Result()
+	if err != nil {
+		return "", fmt.Errorf("get address of master %q: %w", i.MasterName, err)
+	}
+
+	if len(addr) != 2 {
+		return "", fmt.Errorf("invalid address of master %q: %v", i.MasterName, addr)
+	}
+
+	return net.JoinHostPort(addr[0], addr[1]), nil
+}
+
+func (i *Input) discoverNodes(ctx context.Context) ([]*redisNode, error) {
+	switch i.Mode {
+	case modeCluster:
+		text, err := i.client.ClusterNodes(ctx).Result()
+		if err != nil {
+			return nil, fmt.Errorf("cluster nodes: %w", err)
+		}
+		return parseClusterNodes(text), nil
+
+	case modeSentinel:
+		master, err := i.topo.sentinel.Master(ctx, i.MasterName).Result()
+		if err != nil {
+			return nil, fmt.Errorf("sentinel master %q: %w", i.MasterName, err)
+		}
+
+		mn := sentinelNode(master, "")
+		nodes := []*redisNode{mn}
+
+		replicas, err := i.topo.sentinel.Slaves(ctx, i.MasterName).Result()
+		if err != nil {
+			return nil, fmt.Errorf("sentinel replicas %q: %w", i.MasterName, err)
+		}
+
+		for _, r := range replicas {
+			if kv := sentinelReplyMap(r); kv != nil {
+				nodes = append(nodes, sentinelNode(kv, mn.Addr))
+			}
+		}
+
+		for _, n := range nodes {
+			n.Myself = n.Addr == i.Addr
+		}
+		return nodes, nil
+
+	default:
+		return nil, fmt.Errorf("unknown mode %q", i.Mode)
+	}
+}
+
+// switchMaster points the client to the new master after failover in
+// sentinel mode, so collectors of the input follow the master.
+func (i *Input) switchMaster(nodes []*redisNode) {
+	for _, n := range nodes {
+		if n.Role != roleMaster || n.Addr == i.Addr {
+			continue
+		}
+
+		l.Infof("master %q switched from %s to %s", i.MasterName, i.Addr, n.Addr)
+
+		if err := i.client.Close(); err != nil {
+			l.Warnf("close client of %s: %s", i.Addr, err)
+		}
+
+		i.client = i.newNodeClient(n.Addr)
+		i.Addr = n.Addr
+		i.Tags["server"] = i.Addr
+
+		for _, x := range nodes {
+			x.Myself = x.Addr == i.Addr
+		}
+		return
+	}
+}
+
+// collectTopology discovers all nodes of the Redis Cluster or Sentinel,
+// collects redis_info of nodes other than the configured one, and
+// redis_node/redis_topology of all nodes.
+func (i *Input) collectTopology() ([]*point.Point, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeoutDuration)
+	nodes, err := i.discoverNodes(ctx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Mode == modeSentinel {
+		i.switchMaster(nodes)
+	}
+	i.topo.prune(nodes)
+
+	var (
+		pts   []*point.Point
+		infos = map[string]map[string]interface{}{}
+	)
+
+	for _, n := range nodes {
+		info, pt, err := i.nodeInfo(n)
+		if err != nil {
+			l.Warnf("collect node %s: %s", n.Addr, err)
+			continue
+		}
+
+		infos[n.Addr] = info
+		if pt != nil {
+			pts = append(pts, pt)
+		}
+	}
+
+	for _, n := range nodes {
+		pts = append(pts, i.nodePoint(n, infos))
+	}
+	pts = append(pts, i.topologyPoint(nodes))
+
+	i.feedFailoverEvents(i.topo.diff(nodes))
+
+	return pts, nil
+}
+
+// nodeInfo gets INFO of the node, the redis_info point returned for nodes
+// other than the configured one, which collected by collectInfoMeasurement.
+func (i *Input) nodeInfo(n *redisNode) (map[string]interface{}, *point.Point, error) {
+	if n.Myself {
+		ctx, cancel := context.WithTimeout(context.Background(), i.timeoutDuration)
+		defer cancel()
+
+		text, err := i.client.Info(ctx, "replication").Result()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m := &infoMeasurement{resData: map[string]interface{}{}, tags: map[string]string{}}
+		if err := m.parseInfoData(text); err != nil {
+			return nil, nil, err
+		}
+		return m.resData, nil, nil
+	}
+
+	cli, ok := i.topo.clients[n.Addr]
+	if !ok {
+		cli = i.newNodeClient(n.Addr)
+		i.topo.clients[n.Addr] = cli
+		i.topo.cpuUsage[n.Addr] = &redisCPUUsage{}
+	}
+
+	m := &infoMeasurement{
+		cli:         cli,
+		name:        "redis_info",
+		resData:     make(map[string]interface{}),
+		tags:        i.nodeTags(n),
+		fields:      make(map[string]interface{}),
+		election:    i.Election,
+		lastCollect: i.topo.cpuUsage[n.Addr],
+	}
+
+	if err := m.getData(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.submit(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(m.fields) == 0 {
+		return m.resData, nil, nil
+	}
+
+	return m.resData, i.newPoint(m.name, m.tags, m.fields), nil
+}
+
+func (i *Input) nodeTags(n *redisNode) map[string]string {
+	tags := map[string]string{}
+	for k, v := range i.Tags {
+		tags[k] = v
+	}
+
+	tags["server"] = n.Addr
+	if host, _, err := net.SplitHostPort(n.Addr); err == nil {
+		setHostTagIfNotLoopback(tags, host)
+	}
+
+	return tags
+}
+
+func (i *Input) nodePoint(n *redisNode, infos map[string]map[string]interface{}) *point.Point {
+	tags := i.nodeTags(n)
+	tags["role"] = n.Role
+	tags["mode"] = i.Mode
+	if n.ID != "" {
+		tags["node_id"] = n.ID
+	}
+	if n.MasterAddr != "" {
+		tags["master_addr"] = n.MasterAddr
+	}
+
+	info := infos[n.Addr]
+	fields := map[string]interface{}{
+		"up":     boolInt(info != nil),
+		"failed": boolInt(n.Failed),
+	}
+
+	if i.Mode == modeCluster && n.Role == roleMaster {
+		fields["slots"] = n.slotCount()
+	}
+
+	if offset, ok := infoInt(info, "master_repl_offset"); ok {
+		fields["repl_offset"] = offset
+	}
+
+	if n.Role == roleReplica {
+		if status, ok := info["master_link_status"]; ok {
+			fields["master_link_up"] = boolInt(fmt.Sprint(status) == "up")
+		}
+
+		if lag := replLag(infos[n.MasterAddr], info); lag >= 0 {
+			fields["repl_lag"] = lag
+		}
+	}
+
+	return i.newPoint("redis_node", tags, fields)
+}
+
+func (i *Input) topologyPoint(nodes []*redisNode) *point.Point {
+	tags := map[string]string{}
+	for k, v := range i.Tags {
+		tags[k] = v
+	}
+	tags["mode"] = i.Mode
+	if i.Mode == modeSentinel {
+		tags["master_name"] = i.MasterName
+	}
+
+	var masters, replicas, failed int
+	for _, n := range nodes {
+		if n.Role == roleMaster {
+			masters++
+		} else {
+			replicas++
+		}
+
+		if n.Failed {
+			failed++
+		}
+	}
+
+	fields := map[string]interface{}{
+		"nodes":        len(nodes),
+		"masters":      masters,
+		"replicas":     replicas,
+		"failed_nodes": failed,
+	}
+
+	if i.Mode == modeCluster {
+		covered := slotsCovered(nodes)
+		fields["slots_covered"] = covered
+		fields["slots_coverage"] = Round(float64(covered)*100/clusterSlots, 2)
+	}
+
+	return i.newPoint("redis_topology", tags, fields)
+}
+
+func (i *Input) newPoint(name string, tags map[string]string, fields map[string]interface{}) *point.Point {
+	var opts []point.Option
+	if i.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+
+	return point.NewPointV2([]byte(name),
+		append(point.NewTags(tags), point.NewKVs(fields)...),
+		opts...)
+}
+
+func (i *Input) feedFailoverEvents(events []*topologyEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var pts []*point.Point
+	for _, e := range events {
+		tags := map[string]string{
+			"server": e.node.Addr,
+			"role":   e.node.Role,
+			"mode":   i.Mode,
+		}
+		for k, v := range i.Tags {
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+
+		fields := map[string]interface{}{
+			"df_source":  inputName,
+			"df_status":  e.status,
+			"df_title":   e.title,
+			"df_message": e.message,
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(failoverEventName),
+			append(point.NewTags(tags), point.NewKVs(fields)...), point.WithTime(now)))
+	}
+
+	if err := i.feeder.Feed(inputName, point.KeyEvent, pts, nil); err != nil {
+		l.Warnf("feed failover keyevent: %s", err)
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type nodeMeasurement struct{}
+
+func (*nodeMeasurement) LineProto() (*dkpt.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*nodeMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "redis_node",
+		Type: "metric",
+		Desc: "Node of the Redis Cluster or Sentinel deployment, collected if `mode` configured.",
+		Fields: map[string]interface{}{
+			"up":             &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether INFO of the node got, 1 for yes"},
+			"failed":         &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether the node marked as failed by the cluster(`fail`) or sentinel(`s_down`/`o_down`), 1 for yes"},
+			"slots":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of hash slots served by the master, cluster only"},
+			"repl_offset":    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Replication offset of the node"},
+			"repl_lag":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Bytes the replica behind its master"},
+			"master_link_up": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether link of the replica to its master is up, 1 for yes"},
+		},
+		Tags: map[string]interface{}{
+			"server":      &inputs.TagInfo{Desc: "Node addr"},
+			"host":        &inputs.TagInfo{Desc: "Hostname of the node"},
+			"role":        &inputs.TagInfo{Desc: "Role of the node, `master` or `slave`"},
+			"mode":        &inputs.TagInfo{Desc: "Mode of the deployment, `cluster` or `sentinel`"},
+			"node_id":     &inputs.TagInfo{Desc: "Node ID, cluster only"},
+			"master_addr": &inputs.TagInfo{Desc: "Master addr of the replica"},
+		},
+	}
+}
+
+type topologyMeasurement struct{}
+
+func (*topologyMeasurement) LineProto() (*dkpt.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*topologyMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "redis_topology",
+		Type: "metric",
+		Desc: "Overview of the Redis Cluster or Sentinel deployment, collected if `mode` configured.",
+		Fields: map[string]interface{}{
+			"nodes":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of nodes discovered"},
+			"masters":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of masters"},
+			"replicas":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of replicas"},
+			"failed_nodes":   &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of nodes marked as failed"},
+			"slots_covered":  &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of hash slots served by masters not failed, cluster only"},
+			"slots_coverage": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Percent of the 16384 hash slots covered, cluster only"},
+		},
+		Tags: map[string]interface{}{
+			"server":      &inputs.TagInfo{Desc: "Server addr configured, or the master in sentinel mode"},
+			"mode":        &inputs.TagInfo{Desc: "Mode of the deployment, `cluster` or `sentinel`"},
+			"master_name": &inputs.TagInfo{Desc: "Master name monitored by the sentinel"},
+		},
+	}
+}
+
+type failoverMeasurement struct{}
+
+func (*failoverMeasurement) LineProto() (*dkpt.Point, error) { return nil, nil }
+
+//nolint:lll
+func (*failoverMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: failoverEventName,
+		Type: "keyevent",
+		Desc: "Keyevent sent when a replica promoted to master or a node marked as failed.",
+		Fields: map[string]interface{}{
+			"df_source":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Source of the event, always `redis`"},
+			"df_status":  &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Status of the event, `warning` for promotion, `critical` for node failure"},
+			"df_title":   &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Title of the event"},
+			"df_message": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Detail of the event"},
+		},
+		Tags: map[string]interface{}{
+			"server": &inputs.TagInfo{Desc: "Addr of the node promoted or failed"},
+			"role":   &inputs.TagInfo{Desc: "Role of the node"},
+			"mode":   &inputs.TagInfo{Desc: "Mode of the deployment, `cluster` or `sentinel`"},
+		},
+	}
+}
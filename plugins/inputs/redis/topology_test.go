@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var clusterNodes = `07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,hostname4 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,hostname2 master - 0 1426238316232 2 connected 5461-10922
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003,hostname3 master,fail - 0 1426238318243 3 connected 10923-16383
+6ec23923021cf3ffec47632106199cb7f496ce01 127.0.0.1:30005@31005,hostname5 slave 67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 0 1426238316232 5 connected
+824fe116063bc5fcf9f4ffd895bc17aee7731ac3 127.0.0.1:30006@31006,hostname6 slave 292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 0 1426238317741 6 connected
+e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001,hostname1 myself,master - 0 0 1 connected 0-5460 [5461->-67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1]
+a0b1c2d3e4f5a0b1c2d3e4f5a0b1c2d3e4f5a0b1 :0@0 master,noaddr - 1426238317239 1426238317239 0 disconnected
+`
+
+func TestParseClusterNodes(t *testing.T) {
+	nodes := parseClusterNodes(clusterNodes)
+	require.Len(t, nodes, 6)
+
+	n := nodes[0]
+	assert.Equal(t, "127.0.0.1:30004", n.Addr)
+	assert.Equal(t, roleReplica, n.Role)
+	assert.Equal(t, "127.0.0.1:30001", n.MasterAddr)
+
+	n = nodes[2]
+	assert.Equal(t, roleMaster, n.Role)
+	assert.True(t, n.Failed)
+	assert.Equal(t, 5461, n.slotCount())
+
+	n = nodes[5]
+	assert.True(t, n.Myself)
+	assert.Equal(t, [][2]int{{0, 5460}}, n.Slots)
+
+	assert.Equal(t, 10923, slotsCovered(nodes))
+}
+
+func TestSentinelNode(t *testing.T) {
+	kv := sentinelReplyMap([]interface{}{"name", "10.0.0.2:6379", "ip", "10.0.0.2", "port", "6379", "flags", "slave,s_down"})
+	n := sentinelNode(kv, "10.0.0.1:6379")
+
+	assert.Equal(t, "10.0.0.2:6379", n.Addr)
+	assert.Equal(t, roleReplica, n.Role)
+	assert.Equal(t, "10.0.0.1:6379", n.MasterAddr)
+	assert.True(t, n.Failed)
+
+	n = sentinelNode(map[string]string{"ip": "10.0.0.1", "port": "6379", "flags": "master"}, "")
+	assert.Equal(t, roleMaster, n.Role)
+	assert.False(t, n.Failed)
+
+	assert.Nil(t, sentinelReplyMap("invalid"))
+}
+
+func TestReplLag(t *testing.T) {
+	master := map[string]interface{}{"master_repl_offset": "1000"}
+
+	assert.Equal(t, int64(100), replLag(master, map[string]interface{}{"slave_repl_offset": "900", "master_repl_offset": "950"}))
+	assert.Equal(t, int64(50), replLag(master, map[string]interface{}{"master_repl_offset": "950"}))
+	assert.Equal(t, int64(0), replLag(master, map[string]interface{}{"slave_repl_offset": "1010"}))
+	assert.Equal(t, int64(-1), replLag(nil, map[string]interface{}{"slave_repl_offset": "900"}))
+	assert.Equal(t, int64(-1), replLag(master, map[string]interface{}{}))
+}
+
+func TestTopologyDiff(t *testing.T) {
+	topo := newTopology()
+
+	assert.Empty(t, topo.diff([]*redisNode{
+		{Addr: "10.0.0.1:6379", Role: roleMaster},
+		{Addr: "10.0.0.2:6379", Role: roleReplica},
+	}))
+
+	events := topo.diff([]*redisNode{
+		{Addr: "10.0.0.1:6379", Role: roleMaster, Failed: true},
+		{Addr: "10.0.0.2:6379", Role: roleMaster},
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, "critical", events[0].status)
+	assert.Equal(t, "10.0.0.1:6379", events[0].node.Addr)
+	assert.Equal(t, "warning", events[1].status)
+	assert.Equal(t, "10.0.0.2:6379", events[1].node.Addr)
+
+	// nothing changed
+	assert.Empty(t, topo.diff([]*redisNode{
+		{Addr: "10.0.0.1:6379", Role: roleMaster, Failed: true},
+		{Addr: "10.0.0.2:6379", Role: roleMaster},
+	}))
+}
@@ -14,7 +14,6 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"strings"
 	"time"
@@ -25,6 +24,7 @@ import (
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/ingestauth"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/workerpool"
@@ -40,7 +40,6 @@ var (
 const (
 	inputName         = "rum"
 	ReplayFileMaxSize = 1 << 22 // 1024 * 1024 * 4  4Mib
-	ProxyErrorHeader  = "X-Proxy-Error"
 	// nolint: lll
 	sampleConfig = `
 [[inputs.rum]]
@@ -67,6 +66,13 @@ const (
   ## such as https://github.com/everettjf/atosl-rs
   atos_bin_path = "/usr/local/datakit/data/rum/tools/atosl"
 
+  ## Request-level authentication, requests without a valid API key are rejected with 401.
+  ## The key is carried by header "Authorization: Bearer <key>" or "X-API-Key", or URL query api_key
+  ## for browsers unable to set headers. pull_keys also accepts API keys configured in center.
+  # [inputs.rum.auth]
+    # keys = ["<api-key>"]
+    # pull_keys = false
+
   ## Threads config controls how many goroutines an agent cloud start to handle HTTP request.
   ## buffer is the size of jobs' buffering of worker channel.
   ## threads is the total number fo goroutines at running time.
@@ -81,6 +87,19 @@ const (
     # path = "./rum_storage"
     # capacity = 5120
 
+  ## Session replay segments are queued and forwarded to dataway in batches.
+  ## app_ids limits applications allowed to upload, all allowed if empty.
+  ## Segments are spilled to spill_path(under cache dir, capacity in MB) when
+  ## queue is full or dataway is slow, and rejected with 503 if spill_path not set.
+  # [inputs.rum.session_replay]
+    # app_ids = []
+    # queue_size = 256
+    # batch_size = 16
+    # workers = 2
+    # forward_timeout = "30s"
+    # spill_path = "rum_replay"
+    # spill_capacity = 1024
+
   # Provide a list to resolve CDN of your static resource.
   # Below is the Datakit default built-in CDN list, you can uncomment that and change it to your cdn list,
   # it's a JSON array like: [{"domain": "CDN domain", "name": "CDN human readable name", "website": "CDN official website"},...],
@@ -115,8 +134,10 @@ type Input struct {
 	ProguardHome           string                       `toml:"proguard_home"`
 	NDKHome                string                       `toml:"ndk_home"`
 	AtosBinPath            string                       `toml:"atos_bin_path"`
+	Auth                   *ingestauth.Auth             `toml:"auth"`
 	WPConfig               *workerpool.WorkerPoolConfig `toml:"threads"`
 	LocalCacheConfig       *storage.StorageConfig       `toml:"storage"`
+	Replay                 *ReplayConfig                `toml:"session_replay"`
 	CDNMap                 string                       `toml:"cdn_map"`
 }
 
@@ -169,73 +190,6 @@ func (*Input) SampleMeasurement() []inputs.Measurement {
 	return []inputs.Measurement{&trace.TraceMeasurement{Name: inputName}}
 }
 
-func replayUploadHandler() (*httputil.ReverseProxy, error) {
-	endpoints := config.Cfg.Dataway.GetAvailableEndpoints()
-
-	if len(endpoints) == 0 {
-		return nil, fmt.Errorf("no available dataway endpoint now")
-	}
-
-	var (
-		validURL *url.URL
-		lastErr  error
-	)
-	for _, ep := range endpoints {
-		replayURL := ep.GetCategoryURL()[datakit.SessionReplayUpload]
-		if replayURL == "" {
-			lastErr = fmt.Errorf("empty category url")
-			continue
-		}
-		parsedURL, err := url.Parse(replayURL)
-		if err == nil {
-			validURL = parsedURL
-			break
-		}
-		lastErr = err
-	}
-
-	if validURL == nil {
-		if lastErr != nil {
-			return nil, lastErr
-		}
-		return nil, fmt.Errorf("no available dataway endpoint")
-	}
-
-	return &httputil.ReverseProxy{
-		Director: func(req *http.Request) {
-			if req.ContentLength > ReplayFileMaxSize {
-				req.URL = nil // this will trigger a proxy err, and let request complete earlier
-				req.Header.Set(ProxyErrorHeader, fmt.Sprintf("request body size [%d] exceeds the limit", req.ContentLength))
-				return
-			}
-
-			req.URL = validURL
-			req.Host = validURL.Host
-			if req.Body != nil {
-				req.Body = newLimitReader(req.Body, ReplayFileMaxSize)
-			}
-		},
-
-		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
-			proxyErr := req.Header.Get(ProxyErrorHeader)
-			if proxyErr != "" {
-				log.Errorf("proxy error: %s", proxyErr)
-				w.WriteHeader(http.StatusBadRequest)
-				_, _ = w.Write([]byte(proxyErr))
-				return
-			}
-			if errors.Is(err, errLimitReader) {
-				log.Errorf("request body is too large: %s", err)
-				w.WriteHeader(http.StatusBadRequest)
-			} else {
-				log.Errorf("other rum replay err: %s", err)
-				w.WriteHeader(http.StatusBadGateway)
-			}
-			_, _ = w.Write([]byte(err.Error()))
-		},
-	}, nil
-}
-
 func (ipt *Input) RegHTTPHandler() {
 	log = logger.SLogger(inputName)
 
@@ -289,22 +243,39 @@ func (ipt *Input) RegHTTPHandler() {
 		}
 	}
 
+	// pull API keys configured in center
+	if config.Cfg.Dataway != nil {
+		ipt.Auth.Setup(config.Cfg.Dataway)
+	}
+
 	for _, endpoint := range ipt.Endpoints {
 		dkhttp.RegHTTPHandler(http.MethodPost, endpoint,
-			workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
-				storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, ipt.handleRUM)))
+			ipt.Auth.HTTPWrapper(workerpool.HTTPWrapper(httpStatusRespFunc, wkpool,
+				storage.HTTPWrapper(storage.HTTP_KEY, httpStatusRespFunc, localCache, ipt.handleRUM)), log))
 
 		log.Infof("### register RUM endpoint: %s", endpoint)
 	}
 
-	proxy, err := replayUploadHandler()
+	if len(ipt.SessionReplayEndpoints) == 0 {
+		return
+	}
+
+	replayURL, err := replayURL()
 	if err != nil {
-		log.Errorf("register rum replay upload proxy fail: %s", err)
-	} else {
-		for _, endpoint := range ipt.SessionReplayEndpoints {
-			dkhttp.RegHTTPHandler(http.MethodPost, endpoint, proxy.ServeHTTP)
-			log.Infof("register RUM replay upload endpoint: %s", endpoint)
-		}
+		log.Errorf("register rum replay upload endpoint fail: %s", err)
+		return
+	}
+
+	uploader, err := newReplayUploader(ipt.Replay, replayURL)
+	if err != nil {
+		log.Errorf("register rum replay upload endpoint fail: %s", err)
+		return
+	}
+	uploader.start()
+
+	for _, endpoint := range ipt.SessionReplayEndpoints {
+		dkhttp.RegHTTPHandler(http.MethodPost, endpoint, ipt.Auth.HTTPWrapper(uploader.handle, log))
+		log.Infof("register RUM replay upload endpoint: %s", endpoint)
 	}
 }
 
@@ -0,0 +1,485 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package rum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dc "github.com/GuanceCloud/cliutils/diskcache"
+	uhttp "github.com/GuanceCloud/cliutils/network/http"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultReplayQueueSize      = 256
+	defaultReplayBatchSize      = 16
+	defaultReplayWorkers        = 2
+	defaultReplayForwardTimeout = 30 * time.Second
+	defaultReplaySpillCapacity  = 1024 // MB
+
+	maxReplayFormValue = 1024
+)
+
+var (
+	errReplayTooLarge = uhttp.NewErr(fmt.Errorf("session replay segment exceeds %d bytes", ReplayFileMaxSize),
+		http.StatusRequestEntityTooLarge)
+	errReplayInvalid = uhttp.NewErr(errors.New("invalid session replay segment"), http.StatusBadRequest)
+	errReplayAppID   = uhttp.NewErr(errors.New("app_id of session replay not allowed"), http.StatusForbidden)
+	errReplayBusy    = uhttp.NewErr(errors.New("session replay queue full"), http.StatusServiceUnavailable)
+
+	// form fields of app ID, sent by different SDKs.
+	replayAppIDFields = []string{"app_id", "application.id"}
+)
+
+// ReplayConfig configures uploading of session replay segments.
+type ReplayConfig struct {
+	// AppIDs allowed to upload session replay, all allowed if empty.
+	AppIDs []string `toml:"app_ids"`
+
+	// QueueSize is the number of segments queued in memory.
+	QueueSize int `toml:"queue_size"`
+
+	// BatchSize is the max number of segments forwarded by a worker at a time,
+	// rest of the batch are spilled to disk once forwarding failed.
+	BatchSize int `toml:"batch_size"`
+
+	Workers        int           `toml:"workers"`
+	ForwardTimeout time.Duration `toml:"forward_timeout"`
+
+	// SpillPath under the cache dir to keep segments when dataway is slow or
+	// unavailable, segments are rejected on full queue if not set.
+	SpillPath     string `toml:"spill_path"`
+	SpillCapacity int    `toml:"spill_capacity"` // MB
+}
+
+func (c *ReplayConfig) setDefault() {
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultReplayQueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultReplayBatchSize
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultReplayWorkers
+	}
+	if c.ForwardTimeout <= 0 {
+		c.ForwardTimeout = defaultReplayForwardTimeout
+	}
+	if c.SpillCapacity <= 0 {
+		c.SpillCapacity = defaultReplaySpillCapacity
+	}
+}
+
+// replaySegment is a session replay upload, forwarded to dataway as is.
+type replaySegment struct {
+	header http.Header
+	body   []byte
+}
+
+// replayUploader validates, queues and forwards session replay segments to
+// the replay API of dataway.
+type replayUploader struct {
+	cfg    *ReplayConfig
+	appIDs map[string]bool
+	url    string
+	cli    *http.Client
+
+	queue chan *replaySegment
+	spill *dc.DiskCache
+}
+
+func newReplayUploader(cfg *ReplayConfig, replayURL string) (*replayUploader, error) {
+	if cfg == nil {
+		cfg = &ReplayConfig{}
+	}
+	cfg.setDefault()
+
+	u := &replayUploader{
+		cfg:    cfg,
+		appIDs: map[string]bool{},
+		url:    replayURL,
+		cli:    &http.Client{Timeout: cfg.ForwardTimeout},
+		queue:  make(chan *replaySegment, cfg.QueueSize),
+	}
+
+	for _, id := range cfg.AppIDs {
+		u.appIDs[id] = true
+	}
+
+	if cfg.SpillPath != "" {
+		cache, err := dc.Open(
+			dc.WithPath(datakit.JoinToCacheDir(cfg.SpillPath)),
+			dc.WithCapacity(int64(cfg.SpillCapacity)<<20))
+		if err != nil {
+			return nil, fmt.Errorf("open session replay spill cache: %w", err)
+		}
+		u.spill = cache
+	}
+
+	return u, nil
+}
+
+// replayURL returns the replay API of the first available dataway endpoint.
+func replayURL() (string, error) {
+	endpoints := config.Cfg.Dataway.GetAvailableEndpoints()
+
+	if len(endpoints) == 0 {
+		return "", fmt.Errorf("no available dataway endpoint now")
+	}
+
+	var lastErr error
+	for _, ep := range endpoints {
+		u := ep.GetCategoryURL()[datakit.SessionReplayUpload]
+		if u == "" {
+			lastErr = fmt.Errorf("empty category url")
+			continue
+		}
+
+		if _, err := url.Parse(u); err != nil {
+			lastErr = err
+			continue
+		}
+
+		return u, nil
+	}
+
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("no available dataway endpoint")
+}
+
+func (u *replayUploader) start() {
+	g := goroutine.NewGroup(goroutine.Option{Name: "inputs_rum_replay"})
+
+	for i := 0; i < u.cfg.Workers; i++ {
+		g.Go(func(ctx context.Context) error {
+			u.forwardWorker()
+			return nil
+		})
+	}
+
+	if u.spill != nil {
+		g.Go(func(ctx context.Context) error {
+			u.spillWorker()
+			return nil
+		})
+	}
+}
+
+func (u *replayUploader) handle(resp http.ResponseWriter, req *http.Request) {
+	if req.ContentLength > ReplayFileMaxSize {
+		httpErr(resp, errReplayTooLarge)
+		return
+	}
+
+	body, err := io.ReadAll(newLimitReader(req.Body, ReplayFileMaxSize))
+	if err != nil {
+		if errors.Is(err, errLimitReader) {
+			httpErr(resp, errReplayTooLarge)
+		} else {
+			httpErr(resp, err)
+		}
+		return
+	}
+
+	appID, err := checkReplaySegment(req.Header, body)
+	if err != nil {
+		log.Warnf("invalid session replay segment from %s: %s", req.RemoteAddr, err)
+		httpErr(resp, uhttp.Errorf(errReplayInvalid, "%s", err))
+		return
+	}
+
+	if len(u.appIDs) > 0 && !u.appIDs[appID] {
+		log.Warnf("session replay of app_id %q not allowed", appID)
+		httpErr(resp, errReplayAppID)
+		return
+	}
+
+	seg := &replaySegment{header: http.Header{}, body: body}
+	for _, k := range []string{"Content-Type", "Content-Encoding"} {
+		if v := req.Header.Get(k); v != "" {
+			seg.header.Set(k, v)
+		}
+	}
+
+	if err := u.enqueue(seg); err != nil {
+		httpErr(resp, err)
+		return
+	}
+
+	httpOK(resp, nil)
+}
+
+// checkReplaySegment checks the multipart body carries the app ID and a
+// non-empty segment file, and returns the app ID. Compressed bodies are
+// decompressed for checking only, and forwarded as is.
+func checkReplaySegment(header http.Header, body []byte) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+
+	if mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", fmt.Errorf("expect multipart/form-data, got %q", mediaType)
+	}
+
+	var r io.Reader = bytes.NewReader(body)
+	switch enc := strings.ToLower(header.Get("Content-Encoding")); enc {
+	case "":
+	case "gzip":
+		if r, err = gzip.NewReader(r); err != nil {
+			return "", err
+		}
+	case "deflate":
+		if r, err = zlib.NewReader(r); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported Content-Encoding %q", enc)
+	}
+
+	var (
+		appID      string
+		hasSegment bool
+		mr         = multipart.NewReader(io.LimitReader(r, 4*ReplayFileMaxSize), params["boundary"])
+	)
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", err
+		}
+
+		if part.FileName() != "" {
+			n, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return "", err
+			}
+			hasSegment = hasSegment || n > 0
+			continue
+		}
+
+		for _, f := range replayAppIDFields {
+			if part.FormName() == f {
+				v, err := io.ReadAll(io.LimitReader(part, maxReplayFormValue))
+				if err != nil {
+					return "", err
+				}
+				appID = string(v)
+			}
+		}
+	}
+
+	if appID == "" {
+		return "", fmt.Errorf("app_id required")
+	}
+
+	if !hasSegment {
+		return "", fmt.Errorf("segment file required")
+	}
+
+	return appID, nil
+}
+
+// enqueue queues the segment, or spills it to disk if the queue is full.
+func (u *replayUploader) enqueue(seg *replaySegment) error {
+	select {
+	case u.queue <- seg:
+		return nil
+	default:
+	}
+
+	if u.spill == nil {
+		log.Warnf("session replay queue full, segment rejected")
+		return errReplayBusy
+	}
+
+	if err := u.spillSegment(seg); err != nil {
+		log.Warnf("spill session replay segment: %s", err)
+		return errReplayBusy
+	}
+
+	return nil
+}
+
+func (u *replayUploader) spillSegment(seg *replaySegment) error {
+	buf, err := proto.Marshal(&storage.Request{
+		Header: storage.ConvertMapToMapEntries(seg.header),
+		Body:   seg.body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.spill.Put(buf)
+}
+
+func (u *replayUploader) forwardWorker() {
+	batch := make([]*replaySegment, 0, u.cfg.BatchSize)
+
+	for {
+		select {
+		case <-datakit.Exit.Wait():
+			u.drain()
+			return
+
+		case seg := <-u.queue:
+			batch = append(batch[:0], seg)
+
+		collect:
+			for len(batch) < u.cfg.BatchSize {
+				select {
+				case seg := <-u.queue:
+					batch = append(batch, seg)
+				default:
+					break collect
+				}
+			}
+
+			u.forwardBatch(batch)
+		}
+	}
+}
+
+// forwardBatch forwards segments one by one. Once forwarding failed, rest of
+// the batch are spilled to disk, so a slow dataway is not hit by each of them.
+func (u *replayUploader) forwardBatch(batch []*replaySegment) {
+	for i, seg := range batch {
+		err := u.forward(seg)
+		if err == nil {
+			continue
+		}
+
+		log.Warnf("forward session replay segment: %s, %d segments left", err, len(batch)-i)
+
+		for _, seg := range batch[i:] {
+			if u.spill == nil {
+				continue // dropped
+			}
+
+			if err := u.spillSegment(seg); err != nil {
+				log.Warnf("spill session replay segment: %s, dropped", err)
+			}
+		}
+		return
+	}
+}
+
+// drain spills segments queued on exit.
+func (u *replayUploader) drain() {
+	for {
+		select {
+		case seg := <-u.queue:
+			if u.spill != nil {
+				if err := u.spillSegment(seg); err != nil {
+					log.Warnf("spill session replay segment: %s, dropped", err)
+				}
+			}
+		default:
+			return
+		}
+	}
+}
+
+// spillWorker forwards segments spilled to disk, segments failed are kept
+// and retried later.
+func (u *replayUploader) spillWorker() {
+	for {
+		select {
+		case <-datakit.Exit.Wait():
+			if err := u.spill.Close(); err != nil {
+				log.Warnf("close session replay spill cache: %s", err)
+			}
+			return
+		default:
+		}
+
+		err := u.consumeSpilled()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, dc.ErrEOF):
+			time.Sleep(time.Second)
+		default:
+			log.Debugf("forward spilled session replay segment: %s, retry later", err)
+			time.Sleep(u.cfg.ForwardTimeout / 3)
+		}
+	}
+}
+
+// consumeSpilled forwards a segment spilled to disk, the segment is kept on error.
+func (u *replayUploader) consumeSpilled() error {
+	var fwdErr error
+
+	// Get do not return error of the callback on fallback, so keep it here.
+	if err := u.spill.Get(func(buf []byte) error {
+		req := &storage.Request{}
+		if err := proto.Unmarshal(buf, req); err != nil {
+			log.Warnf("unmarshal spilled session replay segment: %s, dropped", err)
+			return nil
+		}
+
+		fwdErr = u.forward(&replaySegment{
+			header: storage.ConvertMapEntriesToMap(req.Header),
+			body:   req.Body,
+		})
+		return fwdErr
+	}); err != nil {
+		return err
+	}
+
+	return fwdErr
+}
+
+func (u *replayUploader) forward(seg *replaySegment) error {
+	req, err := http.NewRequest(http.MethodPost, u.url, bytes.NewReader(seg.body))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range seg.header {
+		req.Header[k] = v
+	}
+
+	resp, err := u.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return fmt.Errorf("dataway: %s, %s", resp.Status, body)
+	default:
+		// retrying the bad segment not help
+		log.Warnf("session replay segment rejected by dataway: %s, %s, dropped", resp.Status, body)
+		return nil
+	}
+}
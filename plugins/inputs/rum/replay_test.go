@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package rum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	dc "github.com/GuanceCloud/cliutils/diskcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replayBody(t *testing.T, appID string, segment []byte) (string, []byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	if appID != "" {
+		require.NoError(t, w.WriteField("app_id", appID))
+	}
+	require.NoError(t, w.WriteField("session_id", "s-1"))
+
+	if segment != nil {
+		fw, err := w.CreateFormFile("segment", "segment.json")
+		require.NoError(t, err)
+		_, err = fw.Write(segment)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestReplayHandle(t *testing.T) {
+	u, err := newReplayUploader(&ReplayConfig{AppIDs: []string{"app-1"}, QueueSize: 1}, "http://localhost")
+	require.NoError(t, err)
+
+	post := func(contentType, encoding string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/write/rum/replay", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		u.handle(w, req)
+		return w.Code
+	}
+
+	ct, body := replayBody(t, "app-1", []byte(`{"records":[]}`))
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err = zw.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	assert.Equal(t, http.StatusOK, post(ct, "gzip", gz.Bytes()))
+	require.Len(t, u.queue, 1)
+	seg := <-u.queue
+	assert.Equal(t, "gzip", seg.header.Get("Content-Encoding"))
+	assert.Equal(t, gz.Bytes(), seg.body)
+
+	assert.Equal(t, http.StatusOK, post(ct, "", body))
+	// queue full and no spill
+	assert.Equal(t, http.StatusServiceUnavailable, post(ct, "", body))
+
+	ct, body = replayBody(t, "app-2", []byte(`{}`))
+	assert.Equal(t, http.StatusForbidden, post(ct, "", body))
+
+	ct, body = replayBody(t, "", []byte(`{}`))
+	assert.Equal(t, http.StatusBadRequest, post(ct, "", body))
+
+	ct, body = replayBody(t, "app-1", nil)
+	assert.Equal(t, http.StatusBadRequest, post(ct, "", body))
+
+	assert.Equal(t, http.StatusBadRequest, post("application/json", "", []byte(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, post(ct, "gzip", body))
+}
+
+func TestReplayForwardSpill(t *testing.T) {
+	var (
+		down     int32 = 1
+		received int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		atomic.AddInt32(&received, 1)
+	}))
+	defer srv.Close()
+
+	u, err := newReplayUploader(&ReplayConfig{}, srv.URL)
+	require.NoError(t, err)
+
+	u.spill, err = dc.Open(dc.WithPath(t.TempDir()), dc.WithBatchSize(1<<20))
+	require.NoError(t, err)
+	defer u.spill.Close() //nolint:errcheck
+
+	seg := &replaySegment{header: http.Header{"Content-Encoding": []string{"gzip"}}, body: []byte("segment")}
+
+	// dataway unavailable, the whole batch spilled
+	u.forwardBatch([]*replaySegment{seg, seg, seg})
+	assert.Equal(t, int32(0), atomic.LoadInt32(&received))
+	require.NoError(t, u.spill.Rotate())
+
+	// still unavailable, the segment is kept
+	assert.Error(t, u.consumeSpilled())
+
+	atomic.StoreInt32(&down, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, u.consumeSpilled())
+	}
+	assert.ErrorIs(t, u.consumeSpilled(), dc.ErrEOF)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+}
@@ -26,7 +26,7 @@ import (
 	"google.golang.org/grpc"
 )
 
-func runGRPCV3(addr string) {
+func runGRPCV3(addr string, opts ...grpc.ServerOption) {
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Errorf("### skywalking grpc server v3 listening on %s failed: %v", addr, err)
@@ -35,16 +35,21 @@ func runGRPCV3(addr string) {
 	}
 	log.Debugf("### skywalking grpc v3 listening on: %s", addr)
 
-	skySvr = grpc.NewServer()
+	skySvr = grpc.NewServer(opts...)
 	// register API version 8.3.0
 	agentv3old.RegisterTraceSegmentReportServiceServer(skySvr, &TraceReportServerV3Old{})
 	agentv3old.RegisterJVMMetricReportServiceServer(skySvr, &JVMMetricReportServerV3Old{})
+	agentv3old.RegisterCLRMetricReportServiceServer(skySvr, &CLRMetricReportServerV3Old{})
+	agentv3old.RegisterBrowserPerfServiceServer(skySvr, &BrowserPerfServerV3Old{})
 	profilev3old.RegisterProfileTaskServer(skySvr, &ProfileTaskServerV3Old{})
 	mgmtv3old.RegisterManagementServiceServer(skySvr, &ManagementServerV3Old{})
 	// register API version 9.3.0
 	agentv3.RegisterTraceSegmentReportServiceServer(skySvr, &TraceReportServerV3{})
 	eventv3.RegisterEventServiceServer(skySvr, &EventServerV3{})
 	agentv3.RegisterJVMMetricReportServiceServer(skySvr, &JVMMetricReportServerV3{})
+	agentv3.RegisterCLRMetricReportServiceServer(skySvr, &CLRMetricReportServerV3{})
+	agentv3.RegisterMeterReportServiceServer(skySvr, &MeterReportServerV3{})
+	agentv3.RegisterBrowserPerfServiceServer(skySvr, &BrowserPerfServerV3{})
 	loggingv3.RegisterLogReportServiceServer(skySvr, &LoggingServerV3{})
 	profilev3.RegisterProfileTaskServer(skySvr, &ProfileTaskServerV3{})
 	mgmtv3.RegisterManagementServiceServer(skySvr, &ManagementServerV3{})
@@ -172,6 +177,107 @@ func (*JVMMetricReportServerV3Old) Collect(ctx context.Context, jvm *agentv3old.
 	return &commonv3old.Commands{}, nil
 }
 
+type CLRMetricReportServerV3Old struct {
+	agentv3old.UnimplementedCLRMetricReportServiceServer
+}
+
+func (*CLRMetricReportServerV3Old) Collect(ctx context.Context, clr *agentv3old.CLRMetricCollection) (*commonv3old.Commands, error) {
+	log.Debugf("### CLRMetricReportServerV3Old:Collect %#v", clr)
+
+	newCLR := agentv3.CLRMetricCollection{
+		Metrics:         make([]*agentv3.CLRMetric, len(clr.Metrics)),
+		Service:         clr.Service,
+		ServiceInstance: clr.ServiceInstance,
+	}
+	for i := range clr.Metrics {
+		newCLR.Metrics[i] = &agentv3.CLRMetric{Time: clr.Metrics[i].Time}
+		if clr.Metrics[i].Cpu != nil {
+			newCLR.Metrics[i].Cpu = &commonv3.CPU{UsagePercent: clr.Metrics[i].Cpu.UsagePercent}
+		}
+		if clr.Metrics[i].Gc != nil {
+			newCLR.Metrics[i].Gc = &agentv3.ClrGC{
+				Gen0CollectCount: clr.Metrics[i].Gc.Gen0CollectCount,
+				Gen1CollectCount: clr.Metrics[i].Gc.Gen1CollectCount,
+				Gen2CollectCount: clr.Metrics[i].Gc.Gen2CollectCount,
+				HeapMemory:       clr.Metrics[i].Gc.HeapMemory,
+			}
+		}
+		if clr.Metrics[i].Thread != nil {
+			newCLR.Metrics[i].Thread = &agentv3.ClrThread{
+				AvailableCompletionPortThreads: clr.Metrics[i].Thread.AvailableCompletionPortThreads,
+				AvailableWorkerThreads:         clr.Metrics[i].Thread.AvailableWorkerThreads,
+				MaxCompletionPortThreads:       clr.Metrics[i].Thread.MaxCompletionPortThreads,
+				MaxWorkerThreads:               clr.Metrics[i].Thread.MaxWorkerThreads,
+			}
+		}
+	}
+	api.ProcessCLRMetrics(&newCLR)
+
+	return &commonv3old.Commands{}, nil
+}
+
+type BrowserPerfServerV3Old struct {
+	agentv3old.UnimplementedBrowserPerfServiceServer
+}
+
+func (*BrowserPerfServerV3Old) CollectPerfData(ctx context.Context, perf *agentv3old.BrowserPerfData) (*commonv3old.Commands, error) {
+	log.Debugf("### BrowserPerfServerV3Old:CollectPerfData BrowserPerfData: %#v", perf)
+
+	api.ProcessBrowserPerf(&agentv3.BrowserPerfData{
+		Service:         perf.Service,
+		ServiceVersion:  perf.ServiceVersion,
+		Time:            perf.Time,
+		PagePath:        perf.PagePath,
+		RedirectTime:    perf.RedirectTime,
+		DnsTime:         perf.DnsTime,
+		TtfbTime:        perf.TtfbTime,
+		TcpTime:         perf.TcpTime,
+		TransTime:       perf.TransTime,
+		DomAnalysisTime: perf.DomAnalysisTime,
+		FptTime:         perf.FptTime,
+		DomReadyTime:    perf.DomReadyTime,
+		LoadPageTime:    perf.LoadPageTime,
+		ResTime:         perf.ResTime,
+		SslTime:         perf.SslTime,
+		TtlTime:         perf.TtlTime,
+		FirstPackTime:   perf.FirstPackTime,
+		FmpTime:         perf.FmpTime,
+	})
+
+	return &commonv3old.Commands{}, nil
+}
+
+func (*BrowserPerfServerV3Old) CollectErrorLogs(esrv agentv3old.BrowserPerfService_CollectErrorLogsServer) error {
+	for {
+		errLog, err := esrv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return esrv.SendAndClose(&commonv3old.Commands{})
+			}
+			log.Debug(err.Error())
+
+			return err
+		}
+		log.Debugf("### BrowserPerfServerV3Old:CollectErrorLogs BrowserErrorLog: %#v", errLog)
+
+		api.ProcessBrowserErrorLog(&agentv3.BrowserErrorLog{
+			UniqueId:           errLog.UniqueId,
+			Service:            errLog.Service,
+			ServiceVersion:     errLog.ServiceVersion,
+			Time:               errLog.Time,
+			PagePath:           errLog.PagePath,
+			Category:           agentv3.ErrorCategory(errLog.Category),
+			Grade:              errLog.Grade,
+			Message:            errLog.Message,
+			Line:               errLog.Line,
+			Col:                errLog.Col,
+			Stack:              errLog.Stack,
+			ErrorUrl:           errLog.ErrorUrl,
+			FirstReportedError: errLog.FirstReportedError,
+		})
+	}
+}
+
 type ProfileTaskServerV3Old struct {
 	profilev3old.UnimplementedProfileTaskServer
 }
@@ -290,6 +396,88 @@ func (*JVMMetricReportServerV3) Collect(ctx context.Context, jvm *agentv3.JVMMet
 	return &commonv3.Commands{}, nil
 }
 
+type CLRMetricReportServerV3 struct {
+	agentv3.UnimplementedCLRMetricReportServiceServer
+}
+
+func (*CLRMetricReportServerV3) Collect(ctx context.Context, clr *agentv3.CLRMetricCollection) (*commonv3.Commands, error) {
+	log.Debugf("### CLRMetricReportServerV3:Collect CLRMetricCollection: %#v", clr)
+
+	api.ProcessCLRMetrics(clr)
+
+	return &commonv3.Commands{}, nil
+}
+
+type MeterReportServerV3 struct {
+	agentv3.UnimplementedMeterReportServiceServer
+}
+
+func (*MeterReportServerV3) Collect(msrv agentv3.MeterReportService_CollectServer) error {
+	var meters []*agentv3.MeterData
+	for {
+		meter, err := msrv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				api.ProcessMeters(meters)
+
+				return msrv.SendAndClose(&commonv3.Commands{})
+			}
+			log.Error(err.Error())
+
+			return err
+		}
+		log.Debugf("### MeterReportServerV3:Collect MeterData: %#v", meter)
+
+		meters = append(meters, meter)
+	}
+}
+
+func (*MeterReportServerV3) CollectBatch(msrv agentv3.MeterReportService_CollectBatchServer) error {
+	for {
+		coll, err := msrv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return msrv.SendAndClose(&commonv3.Commands{})
+			}
+			log.Error(err.Error())
+
+			return err
+		}
+		log.Debugf("### MeterReportServerV3:CollectBatch MeterDataCollection: %#v", coll)
+
+		api.ProcessMeters(coll.MeterData)
+	}
+}
+
+type BrowserPerfServerV3 struct {
+	agentv3.UnimplementedBrowserPerfServiceServer
+}
+
+func (*BrowserPerfServerV3) CollectPerfData(ctx context.Context, perf *agentv3.BrowserPerfData) (*commonv3.Commands, error) {
+	log.Debugf("### BrowserPerfServerV3:CollectPerfData BrowserPerfData: %#v", perf)
+
+	api.ProcessBrowserPerf(perf)
+
+	return &commonv3.Commands{}, nil
+}
+
+func (*BrowserPerfServerV3) CollectErrorLogs(esrv agentv3.BrowserPerfService_CollectErrorLogsServer) error {
+	for {
+		errLog, err := esrv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return esrv.SendAndClose(&commonv3.Commands{})
+			}
+			log.Debug(err.Error())
+
+			return err
+		}
+		log.Debugf("### BrowserPerfServerV3:CollectErrorLogs BrowserErrorLog: %#v", errLog)
+
+		api.ProcessBrowserErrorLog(errLog)
+	}
+}
+
 type LoggingServerV3 struct {
 	loggingv3.UnsafeLogReportServiceServer
 }
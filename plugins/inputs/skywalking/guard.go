@@ -0,0 +1,223 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalking
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxLimitedServices limits services tracked by the rate limiter, services
+// beyond it share one limiter, so random service names can not blow it up.
+const maxLimitedServices = 10000
+
+// RateLimit limits data(segments, metrics, logs and so on) ingested of each
+// service, data exceeding the limit dropped.
+type RateLimit struct {
+	Rate  float64 `toml:"rate"`  // data per second of each service
+	Burst int     `toml:"burst"` // default 2 * rate
+}
+
+// serviceData is any data reported by agents with its service.
+type serviceData interface {
+	GetService() string
+}
+
+// guard validates auth tokens and limits ingest rate of requests.
+type guard struct {
+	authentication string
+	serviceTokens  map[string]string
+	limit          *RateLimit
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	overflow *rate.Limiter
+}
+
+func newGuard(authentication string, serviceTokens map[string]string, limit *RateLimit) *guard {
+	if limit != nil && limit.Rate <= 0 {
+		limit = nil
+	}
+
+	if authentication == "" && len(serviceTokens) == 0 && limit == nil {
+		return nil
+	}
+
+	g := &guard{
+		authentication: authentication,
+		serviceTokens:  serviceTokens,
+		limit:          limit,
+		limiters:       map[string]*rate.Limiter{},
+	}
+
+	if limit != nil {
+		g.overflow = g.newLimiter()
+	}
+
+	return g
+}
+
+func (g *guard) newLimiter() *rate.Limiter {
+	burst := g.limit.Burst
+	if burst <= 0 {
+		burst = int(2 * g.limit.Rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return rate.NewLimiter(rate.Limit(g.limit.Rate), burst)
+}
+
+// token returns the auth token carried by metadata "Authentication", the
+// same as SkyWalking OAP.
+func token(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if v := md.Get("authentication"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// checkToken checks the token is any of tokens configured.
+func (g *guard) checkToken(tk string) error {
+	if g.authentication == "" && len(g.serviceTokens) == 0 {
+		return nil
+	}
+
+	ok := g.authentication != "" && equal(g.authentication, tk)
+	for _, v := range g.serviceTokens {
+		if equal(v, tk) {
+			ok = true
+		}
+	}
+
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid authentication token")
+	}
+	return nil
+}
+
+// checkService checks data of the service is reported with its own token
+// and not exceeding the rate limit.
+func (g *guard) checkService(tk, service string) error {
+	if service == "" {
+		return nil
+	}
+
+	if expected, ok := g.serviceTokens[service]; ok {
+		if !equal(expected, tk) {
+			return status.Errorf(codes.PermissionDenied, "token not allowed to report service %q", service)
+		}
+	} else if g.authentication != "" && !equal(g.authentication, tk) {
+		return status.Errorf(codes.PermissionDenied, "token not allowed to report service %q", service)
+	}
+
+	if g.limit != nil && !g.limiter(service).Allow() {
+		return status.Errorf(codes.ResourceExhausted, "rate limit of service %q exceeded", service)
+	}
+
+	return nil
+}
+
+func (g *guard) limiter(service string) *rate.Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if l, ok := g.limiters[service]; ok {
+		return l
+	}
+
+	if len(g.limiters) >= maxLimitedServices {
+		return g.overflow
+	}
+
+	l := g.newLimiter()
+	g.limiters[service] = l
+	return l
+}
+
+func (g *guard) check(tk string, req interface{}) error {
+	if x, ok := req.(serviceData); ok {
+		return g.checkService(tk, x.GetService())
+	}
+	return nil
+}
+
+// ServerOptions returns interceptors of the guard, nil if nothing to guard.
+func (g *guard) ServerOptions() []grpc.ServerOption {
+	if g == nil {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{},
+			info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			tk := token(ctx)
+			if err := g.checkToken(tk); err != nil {
+				return nil, err
+			}
+
+			if err := g.check(tk, req); err != nil {
+				log.Debugf("%s: %s", info.FullMethod, err)
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream,
+			info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+		) error {
+			tk := token(ss.Context())
+			if err := g.checkToken(tk); err != nil {
+				return err
+			}
+			return handler(srv, &guardedStream{ServerStream: ss, g: g, token: tk})
+		}),
+	}
+}
+
+// guardedStream checks each message received, messages exceeding the rate
+// limit are dropped silently to keep the stream of the agent alive.
+type guardedStream struct {
+	grpc.ServerStream
+	g     *guard
+	token string
+}
+
+func (s *guardedStream) RecvMsg(m interface{}) error {
+	for {
+		if err := s.ServerStream.RecvMsg(m); err != nil {
+			return err
+		}
+
+		err := s.g.check(s.token, m)
+		if err == nil {
+			return nil
+		}
+
+		if status.Code(err) != codes.ResourceExhausted {
+			return err
+		}
+
+		log.Debugf("%s, dropped", err)
+	}
+}
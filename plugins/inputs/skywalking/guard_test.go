@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package skywalking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	T "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	agentv3 "gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/skywalking/compiled/v9.3.0/language/agent/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewGuard(t *T.T) {
+	assert.Nil(t, newGuard("", nil, nil))
+	assert.Nil(t, newGuard("", nil, &RateLimit{}))
+	assert.Nil(t, newGuard("", nil, nil).ServerOptions())
+
+	g := newGuard("", nil, &RateLimit{Rate: 0.1})
+	require.NotNil(t, g)
+	assert.Equal(t, 1, g.overflow.Burst())
+}
+
+func TestGuardCheck(t *T.T) {
+	g := newGuard("common", map[string]string{"pay": "pay-token"}, nil)
+
+	assert.NoError(t, g.checkToken("common"))
+	assert.NoError(t, g.checkToken("pay-token"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(g.checkToken("")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(g.checkToken("other")))
+
+	assert.NoError(t, g.check("pay-token", &agentv3.SegmentObject{Service: "pay"}))
+	assert.NoError(t, g.check("common", &agentv3.SegmentObject{Service: "cart"}))
+	assert.NoError(t, g.check("common", &agentv3.SegmentObject{}))
+	assert.NoError(t, g.check("common", "not service data"))
+
+	// spoof other services
+	assert.Equal(t, codes.PermissionDenied, status.Code(g.check("common", &agentv3.SegmentObject{Service: "pay"})))
+	assert.Equal(t, codes.PermissionDenied, status.Code(g.check("pay-token", &agentv3.CLRMetricCollection{Service: "cart"})))
+
+	// without common token, services not listed are accepted with any valid token
+	g = newGuard("", map[string]string{"pay": "pay-token"}, nil)
+	assert.NoError(t, g.check("pay-token", &agentv3.JVMMetricCollection{Service: "cart"}))
+}
+
+func TestGuardRateLimit(t *T.T) {
+	g := newGuard("", nil, &RateLimit{Rate: 1, Burst: 2})
+
+	seg := &agentv3.SegmentObject{Service: "pay"}
+	assert.NoError(t, g.check("", seg))
+	assert.NoError(t, g.check("", seg))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(g.check("", seg)))
+
+	// limited per service
+	assert.NoError(t, g.check("", &agentv3.SegmentObject{Service: "cart"}))
+
+	// services beyond maxLimitedServices share the overflow limiter
+	for i := len(g.limiters); i < maxLimitedServices; i++ {
+		g.limiters[fmt.Sprintf("svc-%d", i)] = g.newLimiter()
+	}
+	assert.Equal(t, g.overflow, g.limiter("new-service"))
+	assert.Len(t, g.limiters, maxLimitedServices)
+}
+
+type mockStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []*agentv3.SegmentObject
+}
+
+func (s *mockStream) Context() context.Context { return s.ctx }
+
+func (s *mockStream) RecvMsg(m interface{}) error {
+	if len(s.msgs) == 0 {
+		return io.EOF
+	}
+
+	seg := m.(*agentv3.SegmentObject) //nolint:forcetypeassert
+	seg.Service = s.msgs[0].Service
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+func TestGuardedStream(t *T.T) {
+	g := newGuard("common", map[string]string{"pay": "pay-token"}, &RateLimit{Rate: 1, Burst: 1})
+
+	recvAll := func(tk string, services ...string) ([]string, error) {
+		ms := &mockStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("authentication", tk))}
+		for _, svc := range services {
+			ms.msgs = append(ms.msgs, &agentv3.SegmentObject{Service: svc})
+		}
+
+		var got []string
+		stream := &guardedStream{ServerStream: ms, g: g, token: token(ms.ctx)}
+		for {
+			seg := &agentv3.SegmentObject{}
+			if err := stream.RecvMsg(seg); err != nil {
+				if errors.Is(err, io.EOF) {
+					return got, nil
+				}
+				return got, err
+			}
+			got = append(got, seg.Service)
+		}
+	}
+
+	// over-limit messages dropped, stream kept
+	got, err := recvAll("common", "cart", "cart", "shop")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cart", "shop"}, got)
+
+	// spoofing aborts the stream
+	got, err = recvAll("common", "order", "pay")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, []string{"order"}, got)
+}
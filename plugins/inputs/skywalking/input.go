@@ -8,15 +8,20 @@ package skywalking
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/goroutine"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/ingestauth"
+	dknet "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/net"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/skywalkingapi"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
 	itrace "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/trace"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var _ inputs.InputV2 = &Input{}
@@ -38,6 +43,11 @@ const (
   ## keys in [inputs.skywalking.tags]. DOT(.) IN KEY WILL BE REPLACED BY DASH(_) WHEN SENDING.
   # customer_tags = ["key1", "key2", ...]
 
+  ## Convert SkyWalking trace and span IDs to OTel-compatible(W3C) IDs, so that traces crossing
+  ## SkyWalking and OpenTelemetry instrumented services can be linked. The original trace ID
+  ## is kept in tag sw_trace_id.
+  # otel_compatible_id = false
+
   ## Keep rare tracing resources list switch.
   ## If some resources are rare enough(not presend in 1 hour), those resource will always send
   ## to data center and do not consider samplers and filters.
@@ -59,6 +69,60 @@ const (
   # [inputs.skywalking.sampler]
     # sampling_rate = 1.0
 
+  ## Tail sampling buffers spans of a trace for decision_wait, then keeps the whole trace
+  ## if it contains error spans, lasts longer than latency_threshold or matches any policy,
+  ## the other traces are kept by sampling_rate. [inputs.skywalking.sampler] and error penetration
+  ## are ignored once tail sampling is enabled.
+  # [inputs.skywalking.tail_sampling]
+    # decision_wait = "10s"
+    # max_traces = 50000
+    # keep_error = true
+    # latency_threshold = "2s"
+    # sampling_rate = 0.1
+    # [[inputs.skywalking.tail_sampling.policies]]
+      # name = "checkout"
+      # service = "checkout"
+      # resource = "^POST /pay"
+      # [inputs.skywalking.tail_sampling.policies.tags]
+        # "http.status_code" = "429"
+
+  ## Span metrics generates RED(requests, errors and duration) metrics per service and resource
+  ## from all spans received, before filters and sampling applied, fed as metric "tracing_metrics".
+  ## extra_tags are span tags used as extra dimensions.
+  # [inputs.skywalking.span_metrics]
+    # interval = "60s"
+    # extra_tags = ["http_method"]
+
+  ## Request-level authentication, requests without a valid API key are rejected with GRPC code
+  ## Unauthenticated. The key is carried by GRPC metadata "Authorization: Bearer <key>" or "X-API-Key".
+  ## pull_keys also accepts API keys configured in center.
+  # [inputs.skywalking.auth]
+    # keys = ["<api-key>"]
+    # pull_keys = false
+
+  ## Authentication token, the same as agent.authentication of SkyWalking agents. Requests without
+  ## the token in GRPC metadata "Authentication" are rejected with GRPC code Unauthenticated.
+  # authentication = "<token>"
+
+  ## Tokens of services, data of the service is accepted only if reported with its own token,
+  ## so that agents can not report data as other services. Data of services not listed here
+  ## requires the authentication token above if set.
+  # [inputs.skywalking.service_tokens]
+    # service1 = "<token1>"
+    # service2 = "<token2>"
+
+  ## Rate limit of data(segments, metrics, logs and so on) ingested of each service, data
+  ## exceeding the limit is dropped. burst defaults to 2 * rate.
+  # [inputs.skywalking.rate_limit]
+    # rate = 1000.0
+    # burst = 2000
+
+  ## TLS of the GRPC server, client certificates are required and verified if ca_certs set.
+  # [inputs.skywalking.tls]
+    # cert = "/path/to/server.crt"
+    # cert_key = "/path/to/server.key"
+    # ca_certs = ["/path/to/ca.crt"]
+
   # [inputs.skywalking.tags]
     # key1 = "value1"
     # key2 = "value2"
@@ -87,10 +151,18 @@ type Input struct {
 	Address          string                 `toml:"address"`
 	Plugins          []string               `toml:"plugins"`
 	CustomerTags     []string               `toml:"customer_tags"`
+	OTelCompatibleID bool                   `toml:"otel_compatible_id"`
 	KeepRareResource bool                   `toml:"keep_rare_resource"`
 	CloseResource    map[string][]string    `toml:"close_resource"`
 	Sampler          *itrace.Sampler        `toml:"sampler"`
+	TailSampling     *itrace.TailSampling   `toml:"tail_sampling"`
+	SpanMetrics      *itrace.SpanMetrics    `toml:"span_metrics"`
 	Tags             map[string]string      `toml:"tags"`
+	Auth             *ingestauth.Auth       `toml:"auth"`
+	Authentication   string                 `toml:"authentication"`
+	ServiceTokens    map[string]string      `toml:"service_tokens"`
+	RateLimit        *RateLimit             `toml:"rate_limit"`
+	TLS              *dknet.TLSServerConfig `toml:"tls"`
 	LocalCacheConfig *storage.StorageConfig `toml:"storage"`
 }
 
@@ -101,37 +173,72 @@ func (*Input) AvailableArchs() []string { return datakit.AllOS }
 func (*Input) SampleConfig() string { return sampleConfig }
 
 func (ipt *Input) SampleMeasurement() []inputs.Measurement {
-	return []inputs.Measurement{&skywalkingapi.MetricMeasurement{}}
+	return []inputs.Measurement{
+		&skywalkingapi.MetricMeasurement{},
+		&skywalkingapi.CLRMetricMeasurement{},
+		&skywalkingapi.MeterMeasurement{},
+		&skywalkingapi.BrowserViewMeasurement{},
+		&skywalkingapi.BrowserErrorMeasurement{},
+		&itrace.SpanMetricsMeasurement{},
+	}
 }
 
 func (ipt *Input) Run() {
 	log = logger.SLogger(inputName)
 
+	// pull API keys configured in center
+	if config.Cfg.Dataway != nil {
+		ipt.Auth.Setup(config.Cfg.Dataway)
+	}
+
 	api = skywalkingapi.InitApiPluginAges(ipt.Plugins, ipt.LocalCacheConfig, ipt.CloseResource,
-		ipt.KeepRareResource, ipt.Sampler, ipt.CustomerTags, ipt.Tags, inputName)
+		ipt.KeepRareResource, ipt.Sampler, ipt.TailSampling, ipt.SpanMetrics, ipt.CustomerTags, ipt.Tags, inputName)
+	api.SetOTelCompatibleID(ipt.OTelCompatibleID)
 	log.Debug("start skywalking grpc v3 server")
 
 	// start up grpc v3 routine
 	if len(ipt.Address) == 0 {
 		ipt.Address = address
 	}
-	g := goroutine.NewGroup(goroutine.Option{Name: "inputs_skywalking"})
-	g.Go(func(ctx context.Context) error {
-		runGRPCV3(ipt.Address)
-
-		return nil
-	})
+	opts, err := ipt.serverOptions()
+	if err != nil {
+		log.Errorf("skywalking grpc server not started: %s", err)
+	} else {
+		g := goroutine.NewGroup(goroutine.Option{Name: "inputs_skywalking"})
+		g.Go(func(ctx context.Context) error {
+			runGRPCV3(ipt.Address, opts...)
+
+			return nil
+		})
+	}
 
 	<-datakit.Exit.Wait()
 	ipt.Terminate()
 }
 
+func (ipt *Input) serverOptions() ([]grpc.ServerOption, error) {
+	opts := ipt.Auth.ServerOptions()
+	opts = append(opts, newGuard(ipt.Authentication, ipt.ServiceTokens, ipt.RateLimit).ServerOptions()...)
+
+	tlsConf, err := ipt.TLS.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	if tlsConf != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+
+	return opts, nil
+}
+
 func (ipt *Input) Terminate() {
 	if skySvr != nil {
 		skySvr.Stop()
 	}
 	if api != nil {
 		api.CloseLocalCache()
+		api.StopAfterGather()
 	}
 }
 
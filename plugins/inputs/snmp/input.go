@@ -118,6 +118,11 @@ const (
   # bind_host = "0.0.0.0"
   # port = 9162
   # stop_timeout = 3    # stop timeout in seconds.
+  ## Traps and informs sent as "keyevent"(default) or "logging".
+  # output = "keyevent"
+  ## Community strings of v1/v2c traps accepted besides v2_community_string,
+  ## v3 traps authenticated by v3_user and its keys.
+  # community_strings = ["public"]
 `  // sampleCfg
 
 	defaultPort              = uint16(161)
@@ -207,10 +212,12 @@ type Input struct {
 }
 
 type TrapsConfig struct {
-	Enable      bool   `toml:"enable"`
-	BindHost    string `toml:"bind_host"`
-	Port        uint16 `toml:"port"`
-	StopTimeout int    `toml:"stop_timeout"`
+	Enable           bool     `toml:"enable"`
+	BindHost         string   `toml:"bind_host"`
+	Port             uint16   `toml:"port"`
+	StopTimeout      int      `toml:"stop_timeout"`
+	Output           string   `toml:"output"`
+	CommunityStrings []string `toml:"community_strings"`
 }
 
 func (*Input) Catalog() string { return snmpmeasurement.InputName }
@@ -220,7 +227,7 @@ func (*Input) SampleConfig() string { return sampleCfg }
 func (*Input) AvailableArchs() []string { return datakit.AllOS }
 
 func (*Input) SampleMeasurement() []inputs.Measurement {
-	return []inputs.Measurement{&snmpmeasurement.SNMPObject{}, &snmpmeasurement.SNMPMetric{}}
+	return []inputs.Measurement{&snmpmeasurement.SNMPObject{}, &snmpmeasurement.SNMPMetric{}, &snmpmeasurement.SNMPTrap{}}
 }
 
 func (ipt *Input) Run() {
@@ -239,6 +246,7 @@ func (ipt *Input) Run() {
 		if len(ipt.V2CommunityString) > 0 {
 			communityStrings = []string{ipt.V2CommunityString}
 		}
+		communityStrings = append(communityStrings, ipt.Traps.CommunityStrings...)
 		var v3 []traps.UserV3
 		if len(ipt.V3User) > 0 {
 			v3 = []traps.UserV3{
@@ -260,6 +268,9 @@ func (ipt *Input) Run() {
 			Users:            v3,
 			StopTimeout:      ipt.Traps.StopTimeout,
 			Election:         ipt.Election,
+			Output:           ipt.Traps.Output,
+			Tags:             ipt.Tags,
+			Feeder:           ipt.feeder,
 		}); err != nil {
 			l.Errorf("traps.StartServer failed: %v, port = %d", err, ipt.Traps.Port)
 			return
@@ -7,7 +7,6 @@ package snmp
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,7 +17,6 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/GuanceCloud/cliutils/point"
 	"github.com/gosnmp/gosnmp"
-	dockertest "github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
@@ -42,37 +40,45 @@ func TestSNMPInput(t *testing.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []testutils.TestingCase
 	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-			t.Logf("testing %s...", tc.name)
+	testutils.RunCases(t, arr)
+}
 
-			if err := tc.run(); err != nil {
-				tc.cr.Status = testutils.TestFailed
-				tc.cr.FailedMessage = err.Error()
+func (cs *caseSpec) CaseName() string { return cs.name }
 
-				assert.NoError(t, err)
-			} else {
-				tc.cr.Status = testutils.TestPassed
-			}
+func (cs *caseSpec) Result() *testutils.CaseResult { return cs.cr }
 
-			tc.cr.Cost = time.Since(caseStart)
+func (cs *caseSpec) Run(t *testing.T) error {
+	cs.t = t
+	return cs.run()
+}
 
-			assert.NoError(t, testutils.Flush(tc.cr))
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
+	}
 
-			t.Cleanup(func() {
-				// clean remote docker resources
-				if tc.resource == nil {
-					return
-				}
+	return cs.container.Purge()
+}
 
-				assert.NoError(t, tc.pool.Purge(tc.resource))
-			})
-		})
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []testutils.Container {
+	if cs.container == nil {
+		return nil
 	}
+
+	return []testutils.Container{cs.container}
+}
+
+// ExclusiveResources returns the container name and host ports bound, they
+// are fixed and can not be shared with other cases running at the same time.
+func (cs *caseSpec) ExclusiveResources() []string {
+	return append([]string{cs.getContainterName()}, cs.exposedPorts...)
 }
 
 func buildCases(t *testing.T) ([]*caseSpec, error) {
@@ -83,7 +89,9 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 	bases := []struct {
 		name           string // Also used as build image name:tag.
 		conf           string
-		dockerFileText string // Empty if not build image.
+		dockerFileText string // Empty if not build image, rendered by dockerFileVars.
+		dockerFileVars *testutils.DockerfileVars
+		buildArgs      map[string]string
 		exposedPorts   []string
 		optsObject     []inputs.PointCheckOption
 		optsMetric     []inputs.PointCheckOption
@@ -148,6 +156,8 @@ func buildCases(t *testing.T) ([]*caseSpec, error) {
 			repoTag: repoTag[1],
 
 			dockerFileText: base.dockerFileText,
+			dockerFileVars: base.dockerFileVars,
+			buildArgs:      base.buildArgs,
 			exposedPorts:   base.exposedPorts,
 			optsObject:     base.optsObject,
 			optsMetric:     base.optsMetric,
@@ -180,6 +190,8 @@ type caseSpec struct {
 	repo           string
 	repoTag        string
 	dockerFileText string
+	dockerFileVars *testutils.DockerfileVars
+	buildArgs      map[string]string
 	exposedPorts   []string
 	optsObject     []inputs.PointCheckOption
 	optsMetric     []inputs.PointCheckOption
@@ -187,8 +199,7 @@ type caseSpec struct {
 	ipt    *Input
 	feeder *io.MockedFeeder
 
-	pool     *dockertest.Pool
-	resource *dockertest.Resource
+	container testutils.Container
 
 	cr *testutils.CaseResult
 }
@@ -260,13 +271,12 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 
 func (cs *caseSpec) run() error {
 	r := testutils.GetRemote()
-	dockerTCP := r.TCPURL()
 
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
+	cs.t.Logf("get remote: %+#v", r)
 
 	start := time.Now()
 
-	p, err := cs.getPool(dockerTCP)
+	rt, err := cs.getRuntime(r)
 	if err != nil {
 		return err
 	}
@@ -274,7 +284,7 @@ func (cs *caseSpec) run() error {
 	containerName := cs.getContainterName()
 
 	// Remove the container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 
@@ -284,56 +294,30 @@ func (cs *caseSpec) run() error {
 	}
 	defer os.RemoveAll(dockerFileDir)
 
-	var resource *dockertest.Resource
-
-	if len(cs.dockerFileText) == 0 {
-		// Just run a container from existing docker image.
-		resource, err = p.RunWithOptions(
-			&dockertest.RunOptions{
-				Name: containerName, // ATTENTION: not cs.name.
-
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{"EXTRA_FLAGS=--v3-user=testing --v3-auth-key=testing123 --v3-auth-proto=MD5 --v3-priv-key=12345678 --v3-priv-proto=DES"},
-
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
-
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				c.AutoRemove = true
-			},
-		)
-	} else {
-		// Build docker image from Dockerfile and run a container from it.
-		resource, err = p.BuildAndRunWithOptions(
-			dockerFilePath,
+	opts := &testutils.RunOptions{
+		Name: containerName, // ATTENTION: not cs.name.
 
-			&dockertest.RunOptions{
-				Name: cs.name,
+		Repository: cs.repo,
+		Tag:        cs.repoTag,
+		Env:        []string{"EXTRA_FLAGS=--v3-user=testing --v3-auth-key=testing123 --v3-auth-proto=MD5 --v3-priv-key=12345678 --v3-priv-proto=DES"},
 
-				Repository: cs.repo,
-				Tag:        cs.repoTag,
-				Env:        []string{"EXTRA_FLAGS=--v3-user=testing --v3-auth-key=testing123 --v3-auth-proto=MD5 --v3-priv-key=12345678 --v3-priv-proto=DES"},
-
-				ExposedPorts: cs.exposedPorts,
-				PortBindings: cs.getPortBindings(),
-			},
+		ExposedPorts: cs.exposedPorts,
+		PortBindings: cs.getPortBindings(),
+		AutoRemove:   true,
+	}
 
-			func(c *docker.HostConfig) {
-				c.RestartPolicy = docker.RestartPolicy{Name: "no"}
-				c.AutoRemove = true
-			},
-		)
+	// Build image from Dockerfile and run a container from it.
+	if len(cs.dockerFileText) != 0 {
+		opts.Dockerfile = dockerFilePath
+		opts.BuildArgs = cs.buildArgs
 	}
 
+	c, err := rt.Run(opts)
 	if err != nil {
 		return err
 	}
 
-	cs.pool = p
-	cs.resource = resource
+	cs.container = c
 
 	cs.t.Logf("check service(%s:%v)...", r.Host, cs.exposedPorts)
 
@@ -383,49 +367,21 @@ func (cs *caseSpec) run() error {
 	return nil
 }
 
-func (cs *caseSpec) getPool(endpoint string) (*dockertest.Pool, error) {
-	p, err := dockertest.NewPool(endpoint)
+func (cs *caseSpec) getRuntime(r *testutils.RemoteInfo) (testutils.ContainerRuntime, error) {
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return nil, err
 	}
-	err = p.Client.Ping()
+	err = rt.Ping()
 	if err != nil {
-		cs.t.Logf("Could not connect to Docker: %v", err)
+		cs.t.Logf("Could not connect to %s: %v", rt.Name(), err)
 		return nil, err
 	}
-	return p, nil
+	return rt, nil
 }
 
 func (cs *caseSpec) getDockerFilePath() (dirName string, fileName string, err error) {
-	tmpDir, err := ioutil.TempDir("", "dockerfiles_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempDir failed: %s", err.Error())
-		return "", "", err
-	}
-
-	tmpFile, err := ioutil.TempFile(tmpDir, "dockerfile_")
-	if err != nil {
-		cs.t.Logf("ioutil.TempFile failed: %s", err.Error())
-		return "", "", err
-	}
-
-	_, err = tmpFile.WriteString(cs.dockerFileText)
-	if err != nil {
-		cs.t.Logf("TempFile.WriteString failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := os.Chmod(tmpFile.Name(), os.ModePerm); err != nil {
-		cs.t.Logf("os.Chmod failed: %s", err.Error())
-		return "", "", err
-	}
-
-	if err := tmpFile.Close(); err != nil {
-		cs.t.Logf("Close failed: %s", err.Error())
-		return "", "", err
-	}
-
-	return tmpDir, tmpFile.Name(), nil
+	return testutils.WriteDockerfile(cs.dockerFileText, cs.dockerFileVars)
 }
 
 func (cs *caseSpec) getContainterName() string {
@@ -434,11 +390,11 @@ func (cs *caseSpec) getContainterName() string {
 	return name
 }
 
-func (cs *caseSpec) getPortBindings() map[docker.Port][]docker.PortBinding {
-	portBindings := make(map[docker.Port][]docker.PortBinding)
+func (cs *caseSpec) getPortBindings() map[string]string {
+	portBindings := make(map[string]string)
 
 	for _, v := range cs.exposedPorts {
-		portBindings[docker.Port(v)] = []docker.PortBinding{{HostIP: "0.0.0.0", HostPort: docker.Port(v).Port()}}
+		portBindings[v] = docker.Port(v).Port()
 	}
 
 	return portBindings
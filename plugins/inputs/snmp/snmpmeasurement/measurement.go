@@ -21,6 +21,7 @@ const (
 	InputName      = "snmp"
 	SNMPObjectName = "snmp_object"
 	SNMPMetricName = "snmp_metric"
+	SNMPTrapName   = "snmp_trap"
 )
 
 //------------------------------------------------------------------------------
@@ -203,3 +204,36 @@ func newOtherFieldInfo(datatype, ftype, unit, desc string) *inputs.FieldInfo {
 		Desc:     desc,
 	}
 }
+
+//------------------------------------------------------------------------------
+
+// SNMPTrap is only for docs, traps are made into points in package traps.
+type SNMPTrap struct{}
+
+func (m *SNMPTrap) LineProto() (*dkpt.Point, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+//nolint:lll
+func (m *SNMPTrap) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: SNMPTrapName,
+		Desc: "SNMP traps and informs, as keyevent by default, or as logging if `output = \"logging\"`. Variables resolved by MIB are also added as fields named by the MIB, such as `ifIndex` and `ifOperStatus`.",
+		Type: "keyevent",
+		Fields: map[string]interface{}{
+			"df_title":   newOtherFieldInfo(inputs.String, inputs.UnknownType, inputs.UnknownUnit, "Title of the event, with trap name and the device IP."),
+			"df_message": newOtherFieldInfo(inputs.String, inputs.UnknownType, inputs.UnknownUnit, "Title and all variables of the trap, one per line. Field `message` for logging."),
+			"df_status":  newOtherFieldInfo(inputs.String, inputs.UnknownType, inputs.UnknownUnit, "`warning` for linkDown, authenticationFailure and egpNeighborLoss, `ok` for linkUp and `info` for others. Field `status` for logging."),
+			"df_source":  newOtherFieldInfo(inputs.String, inputs.UnknownType, inputs.UnknownUnit, "Always `snmp`."),
+		},
+		Tags: map[string]interface{}{
+			"host":             inputs.NewTagInfo("Device IP."),
+			"snmp_device":      inputs.NewTagInfo("Device IP."),
+			"snmp_version":     inputs.NewTagInfo("SNMP version of the trap, `1`, `2` or `3`."),
+			"device_namespace": inputs.NewTagInfo("Device namespace."),
+			"snmp_trap_oid":    inputs.NewTagInfo("OID of the trap."),
+			"snmp_trap_name":   inputs.NewTagInfo("Name of the trap resolved by MIB. Optional."),
+			"snmp_trap_mib":    inputs.NewTagInfo("MIB of the trap. Optional."),
+		},
+	}
+}
@@ -20,6 +20,7 @@ const agentSource string = "snmp-traps"
 // Formatter is an interface to extract and format raw SNMP Traps.
 type Formatter interface {
 	FormatPacket(packet *SnmpPacket) ([]byte, error)
+	FormatTrap(packet *SnmpPacket) (map[string]interface{}, error)
 }
 
 // JSONFormatter is a Formatter implementation that transforms Traps into JSON.
@@ -32,6 +33,8 @@ type trapVariable struct {
 	OID     string      `json:"oid"`
 	VarType string      `json:"type"`
 	Value   interface{} `json:"value"`
+
+	name string // resolved by MIB, empty if not resolved
 }
 
 const (
@@ -70,7 +73,15 @@ func NewJSONFormatter(oidResolver OIDResolver, namespace string) (JSONFormatter,
 //   }
 // }.
 func (f JSONFormatter) FormatPacket(packet *SnmpPacket) ([]byte, error) {
-	payload := make(map[string]interface{})
+	formattedTrap, err := f.FormatTrap(packet)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"trap": formattedTrap})
+}
+
+// FormatTrap returns the trap data of FormatPacket, before marshaled to JSON.
+func (f JSONFormatter) FormatTrap(packet *SnmpPacket) (map[string]interface{}, error) {
 	var formattedTrap map[string]interface{}
 	var err error
 	if packet.Content.Version == gosnmp.Version1 {
@@ -84,8 +95,7 @@ func (f JSONFormatter) FormatPacket(packet *SnmpPacket) ([]byte, error) {
 	formattedTrap["agent_source"] = agentSource
 	formattedTrap["agent_tags"] = strings.Join(f.getTags(packet), ",")
 	formattedTrap["timestamp"] = packet.Timestamp
-	payload["trap"] = formattedTrap
-	return json.Marshal(payload)
+	return formattedTrap, nil
 }
 
 // GetTags returns a list of tags associated to an SNMP trap packet.
@@ -300,6 +310,8 @@ func (f JSONFormatter) parseVariables(trapOID string, variables []gosnmp.SnmpPDU
 			continue
 		}
 
+		tv.name = varMetadata.Name
+
 		if len(varMetadata.Enumeration) > 0 && len(varMetadata.Bits) > 0 { // nolint:gocritic
 			l.Errorf("Unable to enrich variable, trap variable %q has mappings for both integer enum and bits.", varMetadata.Name)
 		} else if len(varMetadata.Enumeration) > 0 {
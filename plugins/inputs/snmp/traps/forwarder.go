@@ -8,12 +8,14 @@ package traps
 
 import (
 	"context"
-	"net"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
-	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"github.com/GuanceCloud/cliutils/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
-	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/snmp/snmpmeasurement"
 )
 
@@ -25,16 +27,16 @@ type TrapForwarder struct {
 	trapsIn   PacketsChannel
 	formatter Formatter
 	stopChan  chan struct{}
-	Election  bool
+	opt       TrapsServerOpt
 }
 
 // NewTrapForwarder creates a simple TrapForwarder instance.
-func NewTrapForwarder(formatter Formatter, packets PacketsChannel, election bool) (*TrapForwarder, error) {
+func NewTrapForwarder(formatter Formatter, packets PacketsChannel, opt TrapsServerOpt) (*TrapForwarder, error) {
 	return &TrapForwarder{
 		trapsIn:   packets,
 		formatter: formatter,
 		stopChan:  make(chan struct{}),
-		Election:  election,
+		opt:       opt,
 	}, nil
 }
 
@@ -65,44 +67,180 @@ func (tf *TrapForwarder) run() {
 }
 
 func (tf *TrapForwarder) sendTrap(packet *SnmpPacket) {
-	data, err := tf.formatter.FormatPacket(packet)
+	trap, err := tf.formatter.FormatTrap(packet)
 	if err != nil {
 		l.Errorf("failed to format packet: %v", err)
 		return
 	}
-	payload := string(data)
-	l.Debugf("send trap payload: %s", payload)
 
-	host, _, err := net.SplitHostPort(packet.Addr.String())
+	tn := time.Now()
+
+	var (
+		pt       *point.Point
+		category point.Category
+	)
+
+	switch tf.opt.Output {
+	case OutputObject:
+		pt, err = tf.objectPoint(packet, trap)
+		category = point.Object
+	case OutputLogging:
+		pt = tf.eventPoint(packet, trap, false)
+		category = point.Logging
+	default:
+		pt = tf.eventPoint(packet, trap, true)
+		category = point.KeyEvent
+	}
+
 	if err != nil {
-		l.Errorf("net.SplitHostPort failed: %v", err)
+		l.Errorf("make point of trap from %s: %v", packet.Addr, err)
 		return
 	}
-	if len(host) == 0 {
-		l.Warn("host is empty")
-		return
+
+	if err := tf.opt.Feeder.Feed(snmpmeasurement.SNMPTrapName, category, []*point.Point{pt},
+		&io.Option{CollectCost: time.Since(tn)}); err != nil {
+		l.Errorf("feed trap: %v", err)
+	}
+}
+
+// objectPoint returns the trap as the JSON payload of object traps.
+func (tf *TrapForwarder) objectPoint(packet *SnmpPacket, trap map[string]interface{}) (*point.Point, error) {
+	data, err := json.Marshal(map[string]interface{}{"trap": trap})
+	if err != nil {
+		return nil, err
+	}
+	payload := string(data)
+	l.Debugf("send trap payload: %s", payload)
+
+	opts := point.DefaultObjectOptions()
+	if tf.opt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
 	}
 
 	tags := map[string]string{
-		"host": host,
-	}
-	Fields := map[string]interface{}{
-		"trap_payload": payload,
-	}
-	tn := time.Now().UTC()
-	var measurements []inputs.Measurement
-	measurements = append(measurements, &snmpmeasurement.SNMPObject{
-		Name:     "traps",
-		Tags:     tags,
-		Fields:   Fields,
-		TS:       tn,
-		Election: tf.Election,
-	})
-
-	if err := inputs.FeedMeasurement("traps-object",
-		datakit.Object,
-		measurements,
-		&io.Option{CollectCost: time.Since(tn)}); err != nil {
-		l.Errorf("FeedMeasurement object err: %v", err)
+		"host": packet.Addr.IP.String(),
+		"name": packet.Addr.IP.String(),
+	}
+	for k, v := range tf.opt.Tags {
+		tags[k] = v
+	}
+
+	return point.NewPointV2([]byte("traps"),
+		append(point.NewTags(tags), point.NewKVs(map[string]interface{}{"trap_payload": payload})...),
+		opts...), nil
+}
+
+// eventPoint returns the trap as keyevent or logging, variables resolved by
+// MIB as fields, and all variables in the message.
+func (tf *TrapForwarder) eventPoint(packet *SnmpPacket, trap map[string]interface{}, keyevent bool) *point.Point {
+	ip := packet.Addr.IP.String()
+	trapOID, _ := trap["snmpTrapOID"].(string)
+	trapName, _ := trap["snmpTrapName"].(string)
+	trapMIB, _ := trap["snmpTrapMIB"].(string)
+
+	tags := map[string]string{
+		"host":             ip,
+		"snmp_device":      ip,
+		"snmp_version":     formatVersion(packet.Content),
+		"device_namespace": tf.opt.Namespace,
+		"snmp_trap_oid":    trapOID,
+	}
+	if trapName != "" {
+		tags["snmp_trap_name"] = trapName
+	}
+	if trapMIB != "" {
+		tags["snmp_trap_mib"] = trapMIB
+	}
+	for k, v := range tf.opt.Tags {
+		tags[k] = v
+	}
+
+	title := trapName
+	if title == "" {
+		title = trapOID
+	}
+	title = fmt.Sprintf("SNMP trap %s from %s", title, ip)
+
+	fields := map[string]interface{}{}
+	lines := []string{title}
+
+	variables, _ := trap["variables"].([]trapVariable)
+	for _, v := range variables {
+		if v.name == "" {
+			lines = append(lines, fmt.Sprintf("%s: %v", v.OID, fieldValue(v.Value)))
+			continue
+		}
+
+		value := fieldValue(trap[v.name])
+		lines = append(lines, fmt.Sprintf("%s: %v", v.name, value))
+
+		if _, ok := reservedFields[v.name]; !ok {
+			fields[v.name] = value
+		}
+	}
+
+	message := strings.Join(lines, "\n")
+	status := trapStatus(trapName)
+
+	if keyevent {
+		fields["df_title"] = title
+		fields["df_message"] = message
+		fields["df_status"] = status
+		fields["df_source"] = snmpmeasurement.InputName
+	} else {
+		fields["message"] = message
+		fields["status"] = status
+	}
+
+	opts := []point.Option{point.WithTime(time.UnixMilli(packet.Timestamp))}
+	if !keyevent {
+		opts = append(point.DefaultLoggingOptions(), opts...)
+	}
+	if tf.opt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+
+	return point.NewPointV2([]byte(snmpmeasurement.SNMPTrapName),
+		append(point.NewTags(tags), point.NewKVs(fields)...),
+		opts...)
+}
+
+// reservedFields are fields of keyevent and logging, not overwritten by
+// variables.
+var reservedFields = map[string]struct{}{
+	"df_title":   {},
+	"df_message": {},
+	"df_status":  {},
+	"df_source":  {},
+	"message":    {},
+	"status":     {},
+}
+
+// trapStatus returns the status of the generic trap, info for others.
+func trapStatus(trapName string) string {
+	switch trapName {
+	case "linkDown", "authenticationFailure", "egpNeighborLoss":
+		return "warning"
+	case "linkUp":
+		return "ok"
+	default:
+		return "info"
+	}
+}
+
+// fieldValue converts enabled BITS to string, as list not allowed in
+// fields.
+func fieldValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		arr := make([]string, 0, len(x))
+		for _, item := range x {
+			arr = append(arr, fmt.Sprint(item))
+		}
+		return strings.Join(arr, ",")
+	case nil:
+		return ""
+	default:
+		return x
 	}
 }
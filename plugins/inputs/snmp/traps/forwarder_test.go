@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package traps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func TestSendTrap(t *testing.T) {
+	str := func(pt *point.Point, key string) string {
+		b, _ := pt.Get([]byte(key)).([]byte)
+		return string(b)
+	}
+
+	newForwarder := func(t *testing.T, output string) (*TrapForwarder, *io.MockedFeeder) {
+		t.Helper()
+
+		feeder := io.NewMockedFeeder()
+		opt := TrapsServerOpt{
+			Enabled:   true,
+			Namespace: "porco_rosso",
+			Output:    output,
+			Tags:      map[string]string{"some_tag": "some_value"},
+			Feeder:    feeder,
+		}
+		require.NoError(t, checkDefaultConfig(&opt, "test-host"))
+
+		formatter, err := NewJSONFormatter(resolverWithData, opt.Namespace)
+		require.NoError(t, err)
+
+		tf, err := NewTrapForwarder(formatter, nil, opt)
+		require.NoError(t, err)
+		return tf, feeder
+	}
+
+	t.Run("keyevent", func(t *testing.T) {
+		tf, feeder := newForwarder(t, "")
+
+		packet := createTestV1GenericPacket()
+		packet.Timestamp = 1700000000000
+		tf.sendTrap(packet)
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		pt := pts[0]
+
+		assert.Equal(t, "snmp_trap", string(pt.Name()))
+		assert.Equal(t, int64(1700000000000), pt.Time().UnixMilli())
+
+		assert.Equal(t, "127.0.0.1", string(pt.GetTag([]byte("host"))))
+		assert.Equal(t, "1", string(pt.GetTag([]byte("snmp_version"))))
+		assert.Equal(t, "porco_rosso", string(pt.GetTag([]byte("device_namespace"))))
+		assert.Equal(t, "1.3.6.1.6.3.1.1.5.3", string(pt.GetTag([]byte("snmp_trap_oid"))))
+		assert.Equal(t, "ifDown", string(pt.GetTag([]byte("snmp_trap_name"))))
+		assert.Equal(t, "IF-MIB", string(pt.GetTag([]byte("snmp_trap_mib"))))
+		assert.Equal(t, "some_value", string(pt.GetTag([]byte("some_tag"))))
+
+		assert.Equal(t, "SNMP trap ifDown from 127.0.0.1", str(pt, "df_title"))
+		assert.Equal(t, "info", str(pt, "df_status"))
+		assert.Equal(t, "snmp", str(pt, "df_source"))
+		assert.Equal(t, "SNMP trap ifDown from 127.0.0.1\n"+
+			"ifIndex: 2\n"+
+			"ifAdminStatus: up\n"+
+			"ifOperStatus: down\n"+
+			"myFakeVarType: test0,test1,2,test3,test12,13,14,test15,95,test130",
+			str(pt, "df_message"))
+
+		assert.Equal(t, int64(2), pt.Get([]byte("ifIndex")))
+		assert.Equal(t, "down", str(pt, "ifOperStatus"))
+		assert.Equal(t, "test0,test1,2,test3,test12,13,14,test15,95,test130", str(pt, "myFakeVarType"))
+	})
+
+	t.Run("logging", func(t *testing.T) {
+		tf, feeder := newForwarder(t, OutputLogging)
+
+		tf.sendTrap(createTestPacket(LinkUpExampleV2Trap))
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		pt := pts[0]
+
+		assert.Equal(t, "snmp_trap", string(pt.Name()))
+		assert.Equal(t, "2", string(pt.GetTag([]byte("snmp_version"))))
+		assert.Equal(t, "ok", str(pt, "status"))
+		assert.Nil(t, pt.Get([]byte("df_title")))
+		assert.Contains(t, str(pt, "message"), "SNMP trap linkUp from 127.0.0.1\n")
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		tf, feeder := newForwarder(t, OutputKeyEvent)
+		formatter, err := NewJSONFormatter(NoOpOIDResolver{}, "totoro")
+		require.NoError(t, err)
+		tf.formatter = formatter
+
+		tf.sendTrap(createTestPacket(NetSNMPExampleHeartbeatNotification))
+
+		pts, err := feeder.NPoints(1, time.Second)
+		require.NoError(t, err)
+		pt := pts[0]
+
+		assert.Nil(t, pt.GetTag([]byte("snmp_trap_name")))
+		assert.Equal(t, "SNMP trap 1.3.6.1.4.1.8072.2.3.0.1 from 127.0.0.1\n"+
+			"1.3.6.1.4.1.8072.2.3.2.1: 1024\n"+
+			"1.3.6.1.4.1.8072.2.3.2.2: test",
+			str(pt, "df_message"))
+	})
+
+	t.Run("invalid-output", func(t *testing.T) {
+		opt := TrapsServerOpt{Enabled: true, Output: "metric"}
+		assert.Error(t, checkDefaultConfig(&opt, "test-host"))
+	})
+}
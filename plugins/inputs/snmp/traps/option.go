@@ -11,10 +11,18 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 )
 
 const packageName = "traps"
 
+// Outputs of traps.
+const (
+	OutputKeyEvent = "keyevent"
+	OutputLogging  = "logging"
+	OutputObject   = "object" // JSON payload as object, kept for compatibility
+)
+
 var (
 	l = logger.DefaultSLogger(packageName)
 	g = datakit.G("snmp_traps")
@@ -29,6 +37,9 @@ type TrapsServerOpt struct {
 	Users                 []UserV3
 	StopTimeout           int
 	Election              bool
+	Output                string
+	Tags                  map[string]string
+	Feeder                io.Feeder
 	authoritativeEngineID string
 }
 
@@ -16,6 +16,7 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"github.com/gosnmp/gosnmp"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs/snmp/snmputil"
 )
 
@@ -116,6 +117,18 @@ func checkDefaultConfig(c *TrapsServerOpt, agentHostName string) error {
 		return fmt.Errorf("unable to load config: %w", err)
 	}
 
+	switch c.Output {
+	case "":
+		c.Output = OutputKeyEvent
+	case OutputKeyEvent, OutputLogging, OutputObject:
+	default:
+		return fmt.Errorf("unknown output %q, only %s, %s and %s supported", c.Output, OutputKeyEvent, OutputLogging, OutputObject)
+	}
+
+	if c.Feeder == nil {
+		c.Feeder = io.DefaultFeeder()
+	}
+
 	if c.CommunityStrings == nil {
 		c.CommunityStrings = []string{}
 	}
@@ -150,7 +163,7 @@ func NewTrapServer(opt TrapsServerOpt, formatter Formatter) (*TrapServer, error)
 		return nil, err
 	}
 
-	trapForwarder, err := startSNMPTrapForwarder(formatter, packets, opt.Election)
+	trapForwarder, err := startSNMPTrapForwarder(formatter, packets, opt)
 	if err != nil {
 		return nil, fmt.Errorf("unable to start trapForwarder: %w. Will not listen for SNMP traps", err)
 	}
@@ -163,8 +176,8 @@ func NewTrapServer(opt TrapsServerOpt, formatter Formatter) (*TrapServer, error)
 	return server, nil
 }
 
-func startSNMPTrapForwarder(formatter Formatter, packets PacketsChannel, election bool) (*TrapForwarder, error) {
-	trapForwarder, err := NewTrapForwarder(formatter, packets, election)
+func startSNMPTrapForwarder(formatter Formatter, packets PacketsChannel, opt TrapsServerOpt) (*TrapForwarder, error) {
+	trapForwarder, err := NewTrapForwarder(formatter, packets, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -7,6 +7,7 @@
 package socket
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"runtime"
@@ -17,6 +18,7 @@ import (
 	clipt "github.com/GuanceCloud/cliutils/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/probelimit"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -94,6 +96,11 @@ func (i *Input) Collect() error {
 			return fmt.Errorf("inpust socket parse dest_url error %w", err)
 		}
 
+		if err := probelimit.Wait(context.Background(), resURL.Host); err != nil {
+			l.Warnf("skip %s: %s", cont, err)
+			continue
+		}
+
 		switch resURL.Scheme {
 		case TCP:
 			err := i.CollectTCP(resURL.Hostname(), resURL.Port())
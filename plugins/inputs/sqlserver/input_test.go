@@ -16,8 +16,6 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/GuanceCloud/cliutils/point"
-	dt "github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	tu "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/testutils"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
@@ -38,8 +36,7 @@ type caseSpec struct {
 	ipt    *Input
 	feeder *io.MockedFeeder
 
-	pool     *dt.Pool
-	resource *dt.Resource
+	container tu.Container
 
 	cr *tu.CaseResult
 }
@@ -90,13 +87,12 @@ func (cs *caseSpec) checkPoint(pts []*point.Point) error {
 func (cs *caseSpec) run() error {
 	// start remote sqlserver
 	r := tu.GetRemote()
-	dockerTCP := r.TCPURL()
 
-	cs.t.Logf("get remote: %+#v, TCP: %s", r, dockerTCP)
+	cs.t.Logf("get remote: %+#v", r)
 
 	start := time.Now()
 
-	p, err := dt.NewPool(dockerTCP)
+	rt, err := r.ContainerRuntime()
 	if err != nil {
 		return err
 	}
@@ -107,36 +103,32 @@ func (cs *caseSpec) run() error {
 		hostname = "unknown-hostname"
 	}
 
-	containerName := fmt.Sprintf("%s.%s", hostname, cs.name)
+	// cases of different images share the same name
+	containerName := fmt.Sprintf("%s.%s.%s", hostname, cs.name, cs.repoTag)
 
 	// remove the container if exist.
-	if err := p.RemoveContainerByName(containerName); err != nil {
+	if err := rt.RemoveByName(containerName); err != nil {
 		return err
 	}
 
-	resource, err := p.RunWithOptions(&dt.RunOptions{
+	c, err := rt.Run(&tu.RunOptions{
 		// specify container image & tag
 		Repository: cs.repo,
 		Tag:        cs.repoTag,
 
 		// port binding
-		PortBindings: map[docker.Port][]docker.PortBinding{
-			"1433/tcp": {{HostIP: "0.0.0.0", HostPort: cs.servicePort}},
-		},
+		PortBindings: map[string]string{"1433/tcp": cs.servicePort},
 
 		Name: containerName,
 
 		// container run-time envs
 		Env: cs.envs,
-	}, func(c *docker.HostConfig) {
-		c.RestartPolicy = docker.RestartPolicy{Name: "no"}
 	})
 	if err != nil {
 		return err
 	}
 
-	cs.pool = p
-	cs.resource = resource
+	cs.container = c
 
 	cs.t.Logf("check service(%s:%s)...", r.Host, cs.servicePort)
 	if !r.PortOK(cs.servicePort, time.Minute) {
@@ -288,37 +280,39 @@ func TestSQLServerInput(t *T.T) {
 		return
 	}
 
-	t.Logf("testing %d cases...", len(cases))
-
+	var arr []tu.TestingCase
 	for _, tc := range cases {
-		t.Run(tc.name, func(t *T.T) {
-			caseStart := time.Now()
+		arr = append(arr, tc)
+	}
 
-			t.Logf("testing %s...", tc.name)
+	tu.RunCases(t, arr)
+}
 
-			if err := tc.run(); err != nil {
-				tc.cr.Status = tu.TestFailed
-				tc.cr.FailedMessage = err.Error()
+func (cs *caseSpec) CaseName() string { return cs.name }
 
-				assert.NoError(t, err)
-			} else {
-				tc.cr.Status = tu.TestPassed
-			}
+func (cs *caseSpec) Result() *tu.CaseResult { return cs.cr }
 
-			tc.cr.Cost = time.Since(caseStart)
+func (cs *caseSpec) Run(t *T.T) error {
+	cs.t = t
+	return cs.run()
+}
 
-			assert.NoError(t, tu.Flush(tc.cr))
+// Cleanup removes remote container of the case.
+func (cs *caseSpec) Cleanup() error {
+	if cs.container == nil {
+		return nil
+	}
 
-			t.Cleanup(func() {
-				// clean remote docker resources
-				if tc.resource == nil {
-					return
-				}
+	return cs.container.Purge()
+}
 
-				assert.NoError(t, tc.pool.Purge(tc.resource))
-			})
-		})
+// Containers returns containers of the case, captured on the case failed.
+func (cs *caseSpec) Containers() []tu.Container {
+	if cs.container == nil {
+		return nil
 	}
+
+	return []tu.Container{cs.container}
 }
 
 func Test_setHostTagIfNotLoopback(t *T.T) {
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package inputs
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// other inputs start randomly within it.
+	defaultStartJitter   = 10 * time.Second
+	defaultStaggerWindow = time.Minute
+)
+
+// StartupCfg configures how inputs start. Immediate inputs start without delay,
+// staggered inputs start one by one evenly within StaggerWindow, and the other
+// inputs start randomly within 10s.
+type StartupCfg struct {
+	ImmediateInputs []string `toml:"immediate_inputs"`
+	StaggeredInputs []string `toml:"staggered_inputs"`
+	StaggerWindow   string   `toml:"stagger_window"`
+}
+
+func DefaultStartupCfg() *StartupCfg {
+	return &StartupCfg{
+		ImmediateInputs: []string{"cpu", "mem", "disk", "diskio", "net", "system", "swap", "logging"},
+		StaggeredInputs: []string{"container", "snmp", "netscan"},
+		StaggerWindow:   "1m",
+	}
+}
+
+var (
+	startupMtx sync.RWMutex
+	startup    = DefaultStartupCfg()
+)
+
+// SetStartup sets the startup config used by RunInputs.
+func SetStartup(c *StartupCfg) {
+	if c == nil {
+		return
+	}
+
+	startupMtx.Lock()
+	defer startupMtx.Unlock()
+
+	startup = c
+}
+
+func getStartup() *StartupCfg {
+	startupMtx.RLock()
+	defer startupMtx.RUnlock()
+
+	return startup
+}
+
+type startScheduler struct {
+	immediate map[string]bool
+	staggered map[string]bool
+	window    time.Duration
+	total     int // instances of staggered inputs
+	next      int
+}
+
+func newStartScheduler(c *StartupCfg) *startScheduler {
+	s := &startScheduler{
+		immediate: map[string]bool{},
+		staggered: map[string]bool{},
+		window:    defaultStaggerWindow,
+	}
+
+	for _, name := range c.ImmediateInputs {
+		s.immediate[name] = true
+	}
+	for _, name := range c.StaggeredInputs {
+		s.staggered[name] = true
+	}
+
+	if c.StaggerWindow != "" {
+		if du, err := time.ParseDuration(c.StaggerWindow); err != nil {
+			l.Warnf("invalid stagger_window %q: %s, use default %s", c.StaggerWindow, err, defaultStaggerWindow)
+		} else if du >= 0 {
+			s.window = du
+		}
+	}
+
+	return s
+}
+
+// delay returns how long the next instance of input name waits before start.
+func (s *startScheduler) delay(name string) time.Duration {
+	switch {
+	case s.immediate[name]:
+		return 0
+
+	case s.staggered[name]:
+		// spread evenly within the window, exclude both ends
+		s.next++
+		return s.window * time.Duration(s.next) / time.Duration(s.total+1)
+
+	default:
+		return time.Duration(rand.Int63n(int64(defaultStartJitter))) //nolint:gosec
+	}
+}
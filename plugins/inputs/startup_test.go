@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package inputs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartScheduler(t *testing.T) {
+	s := newStartScheduler(&StartupCfg{
+		ImmediateInputs: []string{"cpu", "logging"},
+		StaggeredInputs: []string{"container", "snmp"},
+		StaggerWindow:   "90s",
+	})
+	s.total = 2
+
+	for _, name := range []string{"cpu", "logging"} {
+		if d := s.delay(name); d != 0 {
+			t.Errorf("%s: expect start immediately, got %s", name, d)
+		}
+	}
+
+	if d := s.delay("container"); d != 30*time.Second {
+		t.Errorf("container: expect 30s, got %s", d)
+	}
+	if d := s.delay("snmp"); d != 60*time.Second {
+		t.Errorf("snmp: expect 60s, got %s", d)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := s.delay("mysql"); d < 0 || d >= defaultStartJitter {
+			t.Fatalf("mysql: expect delay within %s, got %s", defaultStartJitter, d)
+		}
+	}
+}
+
+func TestStartSchedulerBadWindow(t *testing.T) {
+	s := newStartScheduler(&StartupCfg{StaggeredInputs: []string{"snmp"}, StaggerWindow: "bad"})
+	if s.window != defaultStaggerWindow {
+		t.Errorf("expect default window %s, got %s", defaultStaggerWindow, s.window)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
 	dkpoint "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
 )
@@ -18,9 +19,14 @@ type point struct {
 	tags   map[string]string
 	fields map[string]interface{}
 	tm     time.Time
+
+	category string // metric if empty
 }
 
 func (p *point) LineProto() (*dkpoint.Point, error) {
+	if p.category == datakit.KeyEvent {
+		return dkpoint.NewPoint(p.name, p.tags, p.fields, &dkpoint.PointOption{Category: datakit.KeyEvent, Time: p.tm})
+	}
 	return dkpoint.NewPoint(p.name, p.tags, p.fields, dkpoint.MOpt())
 }
 
@@ -31,9 +37,10 @@ func (p *point) Info() *inputs.MeasurementInfo {
 type accumulator struct {
 	ref    *input
 	points []inputs.Measurement
+	events []inputs.Measurement
 }
 
-func (a *accumulator) addFields(name string, fields map[string]interface{}, tags map[string]string, ts time.Time) {
+func (a *accumulator) applyTags(tags map[string]string) {
 	for k, v := range a.ref.Tags {
 		tags[k] = v // may override tags in real-data
 	}
@@ -42,6 +49,34 @@ func (a *accumulator) addFields(name string, fields map[string]interface{}, tags
 		l.Debugf("drop tag %s", t)
 		delete(tags, t)
 	}
+}
+
+// addEvent adds a DogStatsD event as keyevent.
+func (a *accumulator) addEvent(fields map[string]interface{}, tags map[string]string, ts time.Time) {
+	a.applyTags(tags)
+	a.events = append(a.events, &point{
+		name:     eventName,
+		fields:   fields,
+		tags:     tags,
+		tm:       ts,
+		category: datakit.KeyEvent,
+	})
+}
+
+// addServiceCheck adds a DogStatsD service check as metric, metric mapping
+// not applied.
+func (a *accumulator) addServiceCheck(fields map[string]interface{}, tags map[string]string, ts time.Time) {
+	a.applyTags(tags)
+	a.points = append(a.points, &point{
+		name:   serviceCheckName,
+		fields: fields,
+		tags:   tags,
+		tm:     ts,
+	})
+}
+
+func (a *accumulator) addFields(name string, fields map[string]interface{}, tags map[string]string, ts time.Time) {
+	a.applyTags(tags)
 
 	// Requrements: there shoule be only 1 field, the field key should be `value'
 	if len(fields) != 1 {
@@ -24,8 +24,23 @@ const (
 	eventWarning = "warning"
 	eventError   = "error"
 	eventSuccess = "success"
+
+	// status of service check.
+	checkOK      = 0
+	checkUnknown = 3
+
+	eventName        = "statsd_event"
+	serviceCheckName = "statsd_service_check"
 )
 
+// eventStatus maps alert type of event to df_status of keyevent.
+var eventStatus = map[string]string{
+	eventInfo:    "info",
+	eventWarning: "warning",
+	eventError:   "error",
+	eventSuccess: "ok",
+}
+
 var uncommenter = strings.NewReplacer("\\n", "\n")
 
 func (ipt *input) parseEventMessage(now time.Time, message string, defaultHostname string) error {
@@ -83,71 +98,153 @@ func (ipt *input) parseEventMessage(now time.Time, message string, defaultHostna
 		return fmt.Errorf("invalid event message format: empty 'title' or 'text' field")
 	}
 
-	name := rawTitle
 	tags := make(map[string]string, strings.Count(message, ",")+2) // allocate for the approximate number of tags
 	fields := make(map[string]interface{}, 9)
+	fields["df_title"] = rawTitle
+	fields["df_message"] = uncommenter.Replace(rawText)
+	fields["df_source"] = "user"
 	fields["alert_type"] = eventInfo // default event type
-	fields["text"] = uncommenter.Replace(rawText)
 	if defaultHostname != "" {
 		tags["source"] = defaultHostname
 	}
 	fields["priority"] = priorityNormal
 	ts := now
-	if len(message) < 2 {
-		ipt.acc.addFields(name, fields, tags, ts)
-		return nil
-	}
 
-	rawMetadataFields := strings.Split(message[1:], "|")
-	for i := range rawMetadataFields {
-		if len(rawMetadataFields[i]) < 2 {
-			l.Warnf("invalid message format: %s", message)
-			return errors.New("too short metadata field")
-		}
-		switch rawMetadataFields[i][:2] {
-		case "d:":
-			ts, err := strconv.ParseInt(rawMetadataFields[i][2:], 10, 64)
-			if err != nil {
-				continue
-			}
-			fields["ts"] = ts
-		case "p:":
-			switch rawMetadataFields[i][2:] {
-			case priorityLow:
-				fields["priority"] = priorityLow
-			case priorityNormal: // we already used this as a default
-			default:
-				continue
+	if len(message) > 1 {
+		rawMetadataFields := strings.Split(message[1:], "|")
+		for i := range rawMetadataFields {
+			if len(rawMetadataFields[i]) < 2 {
+				l.Warnf("invalid message format: %s", message)
+				return errors.New("too short metadata field")
 			}
-		case "h:":
-			tags["source"] = rawMetadataFields[i][2:]
-		case "t:":
-			switch rawMetadataFields[i][2:] {
-			case eventError, eventWarning, eventSuccess, eventInfo:
-				fields["alert_type"] = rawMetadataFields[i][2:] // already set for info
+			switch rawMetadataFields[i][:2] {
+			case "d:":
+				sec, err := strconv.ParseInt(rawMetadataFields[i][2:], 10, 64)
+				if err != nil {
+					continue
+				}
+				ts = time.Unix(sec, 0)
+			case "p:":
+				switch rawMetadataFields[i][2:] {
+				case priorityLow:
+					fields["priority"] = priorityLow
+				case priorityNormal: // we already used this as a default
+				default:
+					continue
+				}
+			case "h:":
+				tags["source"] = rawMetadataFields[i][2:]
+			case "t:":
+				switch rawMetadataFields[i][2:] {
+				case eventError, eventWarning, eventSuccess, eventInfo:
+					fields["alert_type"] = rawMetadataFields[i][2:] // already set for info
+				default:
+					continue
+				}
+			case "k:":
+				tags["aggregation_key"] = rawMetadataFields[i][2:]
+			case "s:":
+				fields["source_type_name"] = rawMetadataFields[i][2:]
 			default:
-				continue
-			}
-		case "k:":
-			tags["aggregation_key"] = rawMetadataFields[i][2:]
-		case "s:":
-			fields["source_type_name"] = rawMetadataFields[i][2:]
-		default:
-			if rawMetadataFields[i][0] == '#' {
-				parseDataDogTags(tags, rawMetadataFields[i][1:])
-			} else {
-				l.Warnf("invalid message format: %s", message)
-				return fmt.Errorf("unknown metadata type: '%s'", rawMetadataFields[i])
+				if rawMetadataFields[i][0] == '#' {
+					parseDataDogTags(tags, rawMetadataFields[i][1:])
+				} else {
+					l.Warnf("invalid message format: %s", message)
+					return fmt.Errorf("unknown metadata type: '%s'", rawMetadataFields[i])
+				}
 			}
 		}
 	}
+
 	// Use source tag because host is reserved tag key in Telegraf.
 	// In datadog the host tag and `h:` are interchangeable, so we have to chech for the host tag.
 	if host, ok := tags["host"]; ok {
 		delete(tags, "host")
 		tags["source"] = host
 	}
-	ipt.acc.addFields(name, fields, tags, ts)
+
+	fields["df_status"] = eventStatus[fields["alert_type"].(string)]
+
+	ipt.Lock()
+	defer ipt.Unlock()
+	ipt.acc.addEvent(fields, tags, ts)
+	return nil
+}
+
+func (ipt *input) parseServiceCheckMessage(now time.Time, message string, defaultHostname string) error {
+	// _sc|name|status
+	//  [
+	//   |d:timestamp
+	//   |h:hostname
+	//   |#tag1,tag2
+	//   |m:service_check_message
+	//  ]
+	//
+	//
+	// the message is the last one and may contain '|'
+	parts := strings.SplitN(message, "|", 4)
+	if len(parts) < 3 || parts[1] == "" {
+		return fmt.Errorf("invalid service check format")
+	}
+
+	status, err := strconv.Atoi(parts[2])
+	if err != nil || status < checkOK || status > checkUnknown {
+		return fmt.Errorf("invalid service check status: '%s'", parts[2])
+	}
+
+	tags := map[string]string{"check": parts[1]}
+	if defaultHostname != "" {
+		tags["source"] = defaultHostname
+	}
+	fields := map[string]interface{}{"status": status}
+	ts := now
+
+	if len(parts) == 4 {
+		rest := parts[3]
+		for rest != "" {
+			var field string
+			if strings.HasPrefix(rest, "m:") {
+				field, rest = rest, ""
+			} else if i := strings.IndexByte(rest, '|'); i >= 0 {
+				field, rest = rest[:i], rest[i+1:]
+			} else {
+				field, rest = rest, ""
+			}
+
+			if len(field) < 2 {
+				return errors.New("too short metadata field")
+			}
+
+			switch field[:2] {
+			case "d:":
+				sec, err := strconv.ParseInt(field[2:], 10, 64)
+				if err != nil {
+					continue
+				}
+				ts = time.Unix(sec, 0)
+			case "h:":
+				tags["source"] = field[2:]
+			case "m:":
+				// not kept: string field not allowed in metric
+				l.Debugf("service check %s: %s", parts[1], uncommenter.Replace(field[2:]))
+			default:
+				if field[0] == '#' {
+					parseDataDogTags(tags, field[1:])
+				} else {
+					return fmt.Errorf("unknown metadata type: '%s'", field)
+				}
+			}
+		}
+	}
+
+	if host, ok := tags["host"]; ok {
+		delete(tags, "host")
+		tags["source"] = host
+	}
+
+	ipt.Lock()
+	defer ipt.Unlock()
+	ipt.acc.addServiceCheck(fields, tags, ts)
 	return nil
 }
 
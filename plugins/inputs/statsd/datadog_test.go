@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+func TestParseEventMessage(t *testing.T) {
+	ipt := defaultInput()
+	ipt.Tags = map[string]string{"env": "test"}
+	ipt.acc = &accumulator{ref: ipt}
+	now := time.Now()
+
+	require.NoError(t, ipt.parseEventMessage(now,
+		`_e{9,10}:disk full|line1\nend|d:1600000000|h:web01|p:low|t:error|k:disk|s:nagios|#team:ops`, "1.2.3.4"))
+	require.NoError(t, ipt.parseEventMessage(now, `_e{5,4}:title|text`, "1.2.3.4"))
+
+	assert.Error(t, ipt.parseEventMessage(now, `_e{5,40}:title|text`, ""))
+	assert.Error(t, ipt.parseEventMessage(now, `_e{5,4}:title|text|x:y`, ""))
+
+	require.Len(t, ipt.acc.events, 2)
+	assert.Empty(t, ipt.acc.points)
+
+	ev := ipt.acc.events[0].(*point)
+	assert.Equal(t, eventName, ev.name)
+	assert.Equal(t, datakit.KeyEvent, ev.category)
+	assert.Equal(t, time.Unix(1600000000, 0), ev.tm)
+	assert.Equal(t, map[string]string{
+		"source":          "web01",
+		"aggregation_key": "disk",
+		"team":            "ops",
+		"env":             "test",
+	}, ev.tags)
+	assert.Equal(t, "disk full", ev.fields["df_title"])
+	assert.Equal(t, "line1\nend", ev.fields["df_message"])
+	assert.Equal(t, "error", ev.fields["df_status"])
+	assert.Equal(t, "low", ev.fields["priority"])
+	assert.Equal(t, "nagios", ev.fields["source_type_name"])
+
+	pt, err := ev.LineProto()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1600000000), pt.Time().Unix())
+
+	ev = ipt.acc.events[1].(*point)
+	assert.Equal(t, now, ev.tm)
+	assert.Equal(t, "1.2.3.4", ev.tags["source"])
+	assert.Equal(t, "info", ev.fields["df_status"])
+}
+
+func TestParseServiceCheckMessage(t *testing.T) {
+	ipt := defaultInput()
+	ipt.DropTags = []string{"drop"}
+	ipt.acc = &accumulator{ref: ipt}
+	now := time.Now()
+
+	require.NoError(t, ipt.parseServiceCheckMessage(now,
+		`_sc|redis.can_connect|2|d:1600000000|h:db01|#port:6379,drop:x|m:connect refused|retry later`, "1.2.3.4"))
+	require.NoError(t, ipt.parseServiceCheckMessage(now, `_sc|app.up|0`, "1.2.3.4"))
+
+	for _, bad := range []string{
+		`_sc|name`,
+		`_sc||0`,
+		`_sc|name|4`,
+		`_sc|name|ok`,
+		`_sc|name|0|x:y`,
+	} {
+		assert.Error(t, ipt.parseServiceCheckMessage(now, bad, ""), bad)
+	}
+
+	require.Len(t, ipt.acc.points, 2)
+	assert.Empty(t, ipt.acc.events)
+
+	sc := ipt.acc.points[0].(*point)
+	assert.Equal(t, serviceCheckName, sc.name)
+	assert.Equal(t, time.Unix(1600000000, 0), sc.tm)
+	assert.Equal(t, map[string]string{
+		"check":  "redis.can_connect",
+		"source": "db01",
+		"port":   "6379",
+	}, sc.tags)
+	assert.Equal(t, map[string]interface{}{"status": 2}, sc.fields)
+
+	_, err := sc.LineProto()
+	require.NoError(t, err)
+
+	sc = ipt.acc.points[1].(*point)
+	assert.Equal(t, "1.2.3.4", sc.tags["source"])
+	assert.Equal(t, map[string]interface{}{"status": 0}, sc.fields)
+}
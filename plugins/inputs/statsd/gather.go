@@ -99,5 +99,16 @@ func (ipt *input) gather() {
 		}
 	}
 
+	if len(ipt.acc.events) > 0 {
+		if err := inputs.FeedMeasurement(inputName,
+			datakit.KeyEvent,
+			ipt.acc.events,
+			nil); err != nil {
+			l.Error(err)
+		} else {
+			ipt.acc.events = ipt.acc.events[:0]
+		}
+	}
+
 	ipt.expireCachedMetrics()
 }
@@ -229,7 +229,8 @@ const sampleConfig = `
   ## http://docs.datadoghq.com/guides/dogstatsd/
   parse_data_dog_tags = true
 
-  ## Parses datadog extensions to the statsd format
+  ## Parses datadog extensions to the statsd format, events(_e{...}) and
+  ## service checks(_sc|...) are also accepted.
   datadog_extensions = true
 
   ## Parses distributions metric as specified in the datadog statsd format
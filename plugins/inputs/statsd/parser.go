@@ -35,13 +35,17 @@ func (ipt *input) parser(idx int) {
 
 				switch {
 				case line == "":
-				case ipt.DataDogExtensions && strings.HasPrefix(line, "_e"):
+				case ipt.DataDogExtensions && strings.HasPrefix(line, "_e{"):
 					if err := ipt.parseEventMessage(in.Time, line, in.Addr); err != nil {
 						l.Warnf("[%d] parseEventMessage: %s, ignored", idx, err.Error())
 					}
+				case ipt.DataDogExtensions && strings.HasPrefix(line, "_sc|"):
+					if err := ipt.parseServiceCheckMessage(in.Time, line, in.Addr); err != nil {
+						l.Warnf("[%d] parseServiceCheckMessage: %s, ignored", idx, err.Error())
+					}
 				default:
 					if err := ipt.parseStatsdLine(line); err != nil {
-						l.Warnf("[%d] parseEventMessage: %s, ignored", idx, err.Error())
+						l.Warnf("[%d] parseStatsdLine: %s, ignored", idx, err.Error())
 					}
 				}
 			}
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Framing of syslog over TCP, see RFC6587.
+const (
+	framingAuto           = "auto"
+	framingOctetCounting  = "octet_counting"
+	framingNonTransparent = "non_transparent"
+)
+
+var errTooLarge = errors.New("message too large")
+
+// frameReader reads syslog messages from a stream.
+type frameReader struct {
+	r       *bufio.Reader
+	framing string
+	trailer byte
+	maxSize int
+}
+
+func newFrameReader(r io.Reader, framing string, trailer byte, maxSize int) *frameReader {
+	return &frameReader{
+		r:       bufio.NewReaderSize(r, maxSize+16), // and the MSG-LEN
+		framing: framing,
+		trailer: trailer,
+		maxSize: maxSize,
+	}
+}
+
+// next returns the next message, the returned bytes only valid before the
+// next call.
+func (f *frameReader) next() ([]byte, error) {
+	switch f.framing {
+	case framingOctetCounting:
+		return f.octetCounting()
+	case framingNonTransparent:
+		return f.nonTransparent()
+	default:
+		// octet counting if the frame starts with MSG-LEN, the message of
+		// non-transparent framing starts with '<'.
+		c, err := f.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		if c[0] >= '1' && c[0] <= '9' {
+			return f.octetCounting()
+		}
+		return f.nonTransparent()
+	}
+}
+
+// octetCounting reads a frame of MSG-LEN SP SYSLOG-MSG.
+func (f *frameReader) octetCounting() ([]byte, error) {
+	head, err := f.r.ReadSlice(' ')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, fmt.Errorf("MSG-LEN not found")
+		}
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(string(head[:len(head)-1]))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid MSG-LEN %q", head[:len(head)-1])
+	}
+
+	if n > f.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", errTooLarge, n)
+	}
+
+	buf, err := f.r.Peek(n)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	_, _ = f.r.Discard(n)
+	return buf, nil
+}
+
+// nonTransparent reads a frame ends with the trailer, the last one without
+// the trailer accepted on EOF.
+func (f *frameReader) nonTransparent() ([]byte, error) {
+	for {
+		buf, err := f.r.ReadSlice(f.trailer)
+		switch {
+		case err == nil:
+			buf = bytes.TrimRight(buf[:len(buf)-1], "\r")
+		case errors.Is(err, bufio.ErrBufferFull):
+			return nil, errTooLarge
+		case errors.Is(err, io.EOF) && len(buf) > 0:
+		default:
+			return nil, err
+		}
+
+		if len(buf) > 0 {
+			return buf, nil
+		}
+		// skip empty lines
+	}
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package syslog
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	T "testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFrames(t *T.T, fr *frameReader) ([]string, error) {
+	t.Helper()
+
+	var res []string
+	for {
+		data, err := fr.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return res, nil
+			}
+			return res, err
+		}
+		res = append(res, string(data))
+	}
+}
+
+// oc frames s in octet counting.
+func oc(s string) string {
+	return strconv.Itoa(len(s)) + " " + s
+}
+
+func TestFrameReader(t *T.T) {
+	msg1 := "<34>1 - host app - - - hello\nworld"
+	msg2 := "<13>Feb  5 17:32:18 host app: hi"
+
+	t.Run("octet-counting", func(t *T.T) {
+		body := oc(msg1) + oc(msg2)
+		fr := newFrameReader(iotest.OneByteReader(strings.NewReader(body)), framingOctetCounting, '\n', 1024)
+
+		res, err := readFrames(t, fr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{msg1, msg2}, res)
+	})
+
+	t.Run("non-transparent", func(t *T.T) {
+		body := msg2 + "\r\n\n" + msg2
+		fr := newFrameReader(strings.NewReader(body), framingNonTransparent, '\n', 1024)
+
+		res, err := readFrames(t, fr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{msg2, msg2}, res)
+	})
+
+	t.Run("non-transparent-nul", func(t *T.T) {
+		body := msg1 + "\x00" + msg2 + "\x00"
+		fr := newFrameReader(strings.NewReader(body), framingNonTransparent, 0, 1024)
+
+		res, err := readFrames(t, fr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{msg1, msg2}, res)
+	})
+
+	t.Run("auto", func(t *T.T) {
+		body := oc(msg1) + msg2 + "\n" + oc(msg2)
+		fr := newFrameReader(strings.NewReader(body), framingAuto, '\n', 1024)
+
+		res, err := readFrames(t, fr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{msg1, msg2, msg2}, res)
+	})
+
+	t.Run("too-large", func(t *T.T) {
+		fr := newFrameReader(strings.NewReader(oc(msg1)), framingOctetCounting, '\n', 16)
+		_, err := readFrames(t, fr)
+		assert.ErrorIs(t, err, errTooLarge)
+
+		fr = newFrameReader(strings.NewReader(strings.Repeat("x", 100)+"\n"), framingNonTransparent, '\n', 16)
+		_, err = readFrames(t, fr)
+		assert.ErrorIs(t, err, errTooLarge)
+	})
+
+	t.Run("truncated", func(t *T.T) {
+		fr := newFrameReader(strings.NewReader("35 <34>1"), framingOctetCounting, '\n', 1024)
+		_, err := readFrames(t, fr)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		fr = newFrameReader(strings.NewReader("x5 <34>1"), framingOctetCounting, '\n', 1024)
+		_, err = readFrames(t, fr)
+		assert.Error(t, err)
+	})
+}
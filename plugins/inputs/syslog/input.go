@@ -0,0 +1,525 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package syslog receives syslog messages of RFC5424 and RFC3164 over TCP,
+// TLS and UDP.
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/pipeline"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "syslog"
+	catalog   = "log"
+
+	sampleCfg = `
+[[inputs.syslog]]
+  ## listen address, with protocol scheme(tcp or udp) and port
+  listen = "tcp://0.0.0.0:601"
+
+  ## framing of TCP(RFC6587): auto, octet_counting or non_transparent.
+  ## auto uses octet counting if the frame starts with a digit.
+  framing = "auto"
+
+  ## trailer of non_transparent framing: LF or NUL
+  trailer = "LF"
+
+  ## max size of a message
+  max_message_size = 65536
+
+  ## source of logs, if it's empty, use 'syslog'
+  source = ""
+
+  ## add service tag, if it's empty, use $source.
+  service = ""
+
+  ## pipeline script name, if it's empty, use $source.p
+  pipeline = ""
+
+  ## enable TLS if both set
+  # tls_cert = "/path/to/cert.pem"
+  # tls_key = "/path/to/key.pem"
+
+  ## verify client certificates by the CA if set
+  # tls_ca = "/path/to/ca.pem"
+
+  [inputs.syslog.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+
+	defaultMaxMessageSize = 64 * 1024
+
+	feedBatch    = 1024
+	feedInterval = time.Second
+)
+
+var (
+	l = logger.DefaultSLogger(inputName)
+	g = datakit.G("inputs_syslog")
+)
+
+type Input struct {
+	Listen         string            `toml:"listen"`
+	Framing        string            `toml:"framing"`
+	Trailer        string            `toml:"trailer"`
+	MaxMessageSize int               `toml:"max_message_size"`
+	Source         string            `toml:"source"`
+	Service        string            `toml:"service"`
+	Pipeline       string            `toml:"pipeline"`
+	TLSCert        string            `toml:"tls_cert"`
+	TLSKey         string            `toml:"tls_key"`
+	TLSCA          string            `toml:"tls_ca"`
+	Tags           map[string]string `toml:"tags"`
+
+	feeder dkio.Feeder
+	ch     chan *point.Point
+
+	mtx     sync.RWMutex
+	closers map[io.Closer]struct{} // listener and connections
+	exited  bool
+
+	semStop *cliutils.Sem // start stop signal
+}
+
+// Make sure Input implements the inputs.InputV2 interface.
+var _ inputs.InputV2 = &Input{}
+
+func (*Input) Catalog() string { return catalog }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOS }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	return []inputs.Measurement{&loggingMeasurement{}}
+}
+
+type loggingMeasurement struct{}
+
+func (*loggingMeasurement) LineProto() (*dkpt.Point, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+//nolint:lll
+func (*loggingMeasurement) Info() *inputs.MeasurementInfo {
+	return &inputs.MeasurementInfo{
+		Name: "syslog",
+		Type: "logging",
+		Desc: "Using `source` field in the config file, default is `syslog`.",
+		Tags: map[string]interface{}{
+			"facility": inputs.NewTagInfo("Facility name of the message, such as `daemon`."),
+			"hostname": inputs.NewTagInfo("HOSTNAME of the message."),
+			"app_name": inputs.NewTagInfo("APP-NAME of RFC5424 message, or TAG of RFC3164 message."),
+			"service":  inputs.NewTagInfo("Service name, equal to `service` field in the config file."),
+		},
+		Fields: map[string]interface{}{
+			"message":       &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "MSG of the message."},
+			"status":        &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "Log status by the severity: `emerg/alert/critical/error/warning/notice/info/debug`."},
+			"proc_id":       &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "PROCID of the message."},
+			"msg_id":        &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "MSGID of RFC5424 message."},
+			"version":       &inputs.FieldInfo{DataType: inputs.Int, Unit: inputs.UnknownUnit, Desc: "VERSION of RFC5424 message, 0 for RFC3164."},
+			"<sd-id>_<key>": &inputs.FieldInfo{DataType: inputs.String, Unit: inputs.UnknownUnit, Desc: "PARAM of STRUCTURED-DATA in RFC5424 message."},
+		},
+	}
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		return
+	}
+
+	u, err := url.Parse(ipt.Listen)
+	if err != nil {
+		l.Errorf("invalid listen %q: %s", ipt.Listen, err)
+		return
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		listener, err := ipt.listenTCP(u.Scheme, u.Host)
+		if err != nil {
+			l.Errorf("listen %s: %s", ipt.Listen, err)
+			return
+		}
+		g.Go(func(ctx context.Context) error {
+			ipt.acceptLoop(listener)
+			return nil
+		})
+
+	case "udp", "udp4", "udp6":
+		conn, err := net.ListenPacket(u.Scheme, u.Host)
+		if err != nil {
+			l.Errorf("listen %s: %s", ipt.Listen, err)
+			return
+		}
+		ipt.track(conn)
+		g.Go(func(ctx context.Context) error {
+			ipt.readPackets(conn)
+			return nil
+		})
+
+	default:
+		l.Errorf("unsupported protocol %q, only tcp and udp supported", u.Scheme)
+		return
+	}
+
+	l.Infof("listening on %s", ipt.Listen)
+
+	g.Go(func(ctx context.Context) error {
+		ipt.feedLoop()
+		return nil
+	})
+
+	select {
+	case <-datakit.Exit.Wait():
+		l.Info(inputName + " exit")
+	case <-ipt.semStop.Wait():
+		l.Info(inputName + " return")
+	}
+
+	ipt.exit()
+}
+
+func (ipt *Input) setup() error {
+	if ipt.Source == "" {
+		ipt.Source = inputName
+	}
+	if ipt.Service == "" {
+		ipt.Service = ipt.Source
+	}
+	if ipt.MaxMessageSize <= 0 {
+		ipt.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	switch ipt.Framing {
+	case "":
+		ipt.Framing = framingAuto
+	case framingAuto, framingOctetCounting, framingNonTransparent:
+	default:
+		return fmt.Errorf("invalid framing %q", ipt.Framing)
+	}
+
+	if _, err := ipt.trailer(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ipt *Input) trailer() (byte, error) {
+	switch strings.ToUpper(ipt.Trailer) {
+	case "", "LF":
+		return '\n', nil
+	case "NUL":
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("invalid trailer %q, LF or NUL expected", ipt.Trailer)
+	}
+}
+
+func (ipt *Input) tlsConfig() (*tls.Config, error) {
+	if ipt.TLSCert == "" || ipt.TLSKey == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(ipt.TLSCert, ipt.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS cert: %w", err)
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if ipt.TLSCA != "" {
+		ca, err := os.ReadFile(ipt.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", ipt.TLSCA)
+		}
+
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+func (ipt *Input) listenTCP(network, addr string) (net.Listener, error) {
+	conf, err := ipt.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf != nil {
+		listener = tls.NewListener(listener, conf)
+	}
+
+	ipt.track(listener)
+	return listener, nil
+}
+
+// track records the listener or connection to close on exit, false returned
+// if exited.
+func (ipt *Input) track(c io.Closer) bool {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	if ipt.exited {
+		return false
+	}
+	ipt.closers[c] = struct{}{}
+	return true
+}
+
+func (ipt *Input) untrack(c io.Closer) {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	delete(ipt.closers, c)
+	_ = c.Close()
+}
+
+func (ipt *Input) exit() {
+	ipt.mtx.Lock()
+	defer ipt.mtx.Unlock()
+
+	for c := range ipt.closers {
+		_ = c.Close()
+	}
+	ipt.closers = map[io.Closer]struct{}{}
+
+	if !ipt.exited {
+		ipt.exited = true
+		close(ipt.ch)
+	}
+}
+
+func (ipt *Input) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.Warnf("accept: %s", err)
+			continue
+		}
+
+		if !ipt.track(conn) {
+			_ = conn.Close()
+			return
+		}
+
+		go ipt.serve(conn)
+	}
+}
+
+func (ipt *Input) serve(conn net.Conn) {
+	defer ipt.untrack(conn)
+
+	trailer, _ := ipt.trailer()
+	fr := newFrameReader(conn, ipt.Framing, trailer, ipt.MaxMessageSize)
+	remote := conn.RemoteAddr().String()
+
+	for {
+		data, err := fr.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				l.Warnf("read from %s: %s", remote, err)
+			}
+			return
+		}
+
+		if !ipt.handle(data) {
+			return
+		}
+	}
+}
+
+func (ipt *Input) readPackets(conn net.PacketConn) {
+	buf := make([]byte, ipt.MaxMessageSize)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.Warnf("read: %s", err)
+			}
+			return
+		}
+
+		if n == len(buf) {
+			l.Warnf("message from %s truncated to %d bytes", addr, n)
+		}
+
+		if !ipt.handle(buf[:n]) {
+			return
+		}
+	}
+}
+
+// handle parses the message and queues it to feed, false returned if exited.
+func (ipt *Input) handle(data []byte) bool {
+	msg, err := parse(data, time.Now())
+	if err != nil {
+		l.Debugf("parse %q: %s", data, err)
+		// keep the message not in syslog format
+		msg = &message{severity: 6, message: string(data)}
+	}
+
+	pt := ipt.makePoint(msg)
+
+	// ch closed on exit under the write lock
+	ipt.mtx.RLock()
+	defer ipt.mtx.RUnlock()
+
+	if ipt.exited {
+		return false
+	}
+
+	ipt.ch <- pt
+	return true
+}
+
+func (ipt *Input) makePoint(msg *message) *point.Point {
+	tags := map[string]string{
+		"facility": msg.facilityName(),
+		"service":  ipt.Service,
+	}
+	if msg.hostname != "" {
+		tags["hostname"] = msg.hostname
+	}
+	if msg.appName != "" {
+		tags["app_name"] = msg.appName
+	}
+	for k, v := range ipt.Tags {
+		tags[k] = v
+	}
+
+	fields := map[string]interface{}{
+		pipeline.FieldMessage: msg.message,
+		pipeline.FieldStatus:  msg.status(),
+		"version":             msg.version,
+	}
+	if msg.procID != "" {
+		fields["proc_id"] = msg.procID
+	}
+	if msg.msgID != "" {
+		fields["msg_id"] = msg.msgID
+	}
+	for id, params := range msg.structuredData {
+		for k, v := range params {
+			fields[id+"_"+k] = v
+		}
+	}
+
+	opts := point.DefaultLoggingOptions()
+	if !msg.timestamp.IsZero() {
+		opts = append(opts, point.WithTime(msg.timestamp))
+	}
+
+	return point.NewPointV2([]byte(ipt.Source),
+		append(point.NewTags(tags), point.NewKVs(fields)...), opts...)
+}
+
+// feedLoop feeds points in batch, on each feed interval or the batch full.
+func (ipt *Input) feedLoop() {
+	tick := time.NewTicker(feedInterval)
+	defer tick.Stop()
+
+	var pts []*point.Point
+
+	flush := func() {
+		if len(pts) == 0 {
+			return
+		}
+
+		if err := ipt.feeder.Feed(inputName, point.Logging, pts, &dkio.Option{
+			PlScript: map[string]string{ipt.Source: ipt.Pipeline},
+		}); err != nil {
+			l.Errorf("feed %d logs: %s", len(pts), err)
+		}
+		pts = nil
+	}
+
+	for {
+		select {
+		case pt, ok := <-ipt.ch:
+			if !ok {
+				flush()
+				return
+			}
+
+			pts = append(pts, pt)
+			if len(pts) >= feedBatch {
+				flush()
+			}
+
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func defaultInput() *Input {
+	return &Input{
+		Listen:         "tcp://0.0.0.0:601",
+		Framing:        framingAuto,
+		MaxMessageSize: defaultMaxMessageSize,
+		Tags:           map[string]string{},
+		feeder:         dkio.DefaultFeeder(),
+		ch:             make(chan *point.Point, feedBatch),
+		closers:        map[io.Closer]struct{}{},
+		semStop:        cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
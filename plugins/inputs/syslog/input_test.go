@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package syslog
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+func newTestInput(t *T.T) (*Input, *dkio.MockedFeeder) {
+	t.Helper()
+
+	feeder := dkio.NewMockedFeeder()
+	ipt := defaultInput()
+	ipt.feeder = feeder
+	ipt.Tags = map[string]string{"env": "test"}
+	require.NoError(t, ipt.setup())
+
+	go ipt.feedLoop()
+	return ipt, feeder
+}
+
+func TestMakePoint(t *T.T) {
+	ipt := defaultInput()
+	require.NoError(t, ipt.setup())
+
+	m, err := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [meta@1 ip="10.0.0.1"] hello`), time.Now())
+	require.NoError(t, err)
+
+	pt := ipt.makePoint(m)
+	assert.Equal(t, "syslog", string(pt.Name()))
+	assert.Equal(t, int64(1065910455003000000), pt.Time().UnixNano())
+	assert.Equal(t, "local4", string(pt.GetTag([]byte("facility"))))
+	assert.Equal(t, "host", string(pt.GetTag([]byte("hostname"))))
+	assert.Equal(t, "app", string(pt.GetTag([]byte("app_name"))))
+	assert.Equal(t, "syslog", string(pt.GetTag([]byte("service"))))
+	assert.Equal(t, []byte("hello"), pt.Get([]byte("message")))
+	assert.Equal(t, []byte("notice"), pt.Get([]byte("status")))
+	assert.Equal(t, []byte("1234"), pt.Get([]byte("proc_id")))
+	assert.Equal(t, []byte("ID47"), pt.Get([]byte("msg_id")))
+	assert.Equal(t, []byte("10.0.0.1"), pt.Get([]byte("meta@1_ip")))
+}
+
+func TestTCP(t *T.T) {
+	ipt, feeder := newTestInput(t)
+
+	listener, err := ipt.listenTCP("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go ipt.acceptLoop(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte(oc("<34>1 - host app - - - hello") + "<13>Feb  5 17:32:18 host app: hi\nnot syslog\n"))
+	require.NoError(t, err)
+
+	pts, err := feeder.NPoints(3, 5*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	assert.Equal(t, []byte("hello"), pts[0].Get([]byte("message")))
+	assert.Equal(t, []byte("hi"), pts[1].Get([]byte("message")))
+	assert.Equal(t, []byte("not syslog"), pts[2].Get([]byte("message")))
+	assert.Equal(t, "test", string(pts[2].GetTag([]byte("env"))))
+
+	ipt.exit()
+}
+
+func TestUDP(t *T.T) {
+	ipt, feeder := newTestInput(t)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ipt.track(pc)
+	go ipt.readPackets(pc)
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	_, err = conn.Write([]byte("<34>1 - host app - - - over udp\n"))
+	require.NoError(t, err)
+
+	pts, err := feeder.NPoints(1, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("over udp"), pts[0].Get([]byte("message")))
+
+	ipt.exit()
+}
+
+func TestTLSClientCert(t *T.T) {
+	dir := t.TempDir()
+
+	ca, caKey := genCert(t, nil, nil, "ca")
+	srv, srvKey := genCert(t, ca, caKey, "server")
+	cli, cliKey := genCert(t, ca, caKey, "client")
+
+	ipt, feeder := newTestInput(t)
+	ipt.TLSCA = writePEM(t, dir, "ca.pem", "CERTIFICATE", ca.Raw)
+	ipt.TLSCert = writePEM(t, dir, "cert.pem", "CERTIFICATE", srv.Raw)
+	ipt.TLSKey = writeKey(t, dir, "key.pem", srvKey)
+
+	listener, err := ipt.listenTCP("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go ipt.acceptLoop(listener)
+	defer ipt.exit()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	t.Run("verified", func(t *T.T) {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+			RootCAs: roots,
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cli.Raw},
+				PrivateKey:  cliKey,
+			}},
+			ServerName: "127.0.0.1",
+			MinVersion: tls.VersionTLS12,
+		})
+		require.NoError(t, err)
+		defer conn.Close() //nolint:errcheck
+
+		_, err = conn.Write([]byte(oc("<34>1 - host app - - - over tls")))
+		require.NoError(t, err)
+
+		pts, err := feeder.NPoints(1, 5*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("over tls"), pts[0].Get([]byte("message")))
+	})
+
+	t.Run("no-client-cert", func(t *T.T) {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+			RootCAs:    roots,
+			ServerName: "127.0.0.1",
+			MinVersion: tls.VersionTLS12,
+		})
+		if err == nil { // TLS 1.3 fails on read after the handshake
+			defer conn.Close() //nolint:errcheck
+			_, _ = conn.Write([]byte(oc("<34>1 - host app - - - rejected")))
+			_, err = conn.Read(make([]byte, 1))
+		}
+		assert.Error(t, err)
+
+		_, err = feeder.NPoints(1, 500*time.Millisecond)
+		assert.Error(t, err)
+	})
+}
+
+func genCert(t *T.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, cn string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	if parent == nil { // self-signed CA
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func writePEM(t *T.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+
+	f := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+	return f
+}
+
+func writeKey(t *T.T, dir, name string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, dir, name, "EC PRIVATE KEY", der)
+}
@@ -0,0 +1,301 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nilValue = "-"
+
+var (
+	errNoPriority = errors.New("priority not found")
+
+	// severities of syslog as log status, 0(emerg) ~ 7(debug).
+	severityStatus = []string{"emerg", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+	facilities = []string{
+		"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+		"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+	}
+
+	bom = []byte("\xEF\xBB\xBF")
+)
+
+// message is a parsed syslog message of RFC5424 or RFC3164.
+type message struct {
+	facility int
+	severity int
+	version  int // 0 for RFC3164
+
+	timestamp time.Time // zero if not set
+
+	hostname string
+	appName  string
+	procID   string
+	msgID    string
+
+	// SD-ID -> PARAM-NAME -> PARAM-VALUE
+	structuredData map[string]map[string]string
+
+	message string
+}
+
+func (m *message) facilityName() string {
+	if m.facility >= 0 && m.facility < len(facilities) {
+		return facilities[m.facility]
+	}
+	return strconv.Itoa(m.facility)
+}
+
+func (m *message) status() string {
+	return severityStatus[m.severity]
+}
+
+// parse parses the message in RFC5424 if it starts with a version, or in
+// RFC3164. Parts of RFC3164 message not recognized are kept in the message.
+func parse(data []byte, now time.Time) (*message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+
+	pri, rest, err := parsePriority(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &message{facility: pri / 8, severity: pri % 8}
+
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' {
+		if sp := bytes.IndexByte(rest, ' '); sp > 0 && sp <= 3 {
+			if v, err := strconv.Atoi(string(rest[:sp])); err == nil {
+				m.version = v
+				return m, parse5424(m, rest[sp+1:])
+			}
+		}
+	}
+
+	parse3164(m, rest, now)
+	return m, nil
+}
+
+func parsePriority(data []byte) (int, []byte, error) {
+	if len(data) < 3 || data[0] != '<' {
+		return 0, nil, errNoPriority
+	}
+
+	end := bytes.IndexByte(data[:min(len(data), 5)], '>')
+	if end < 2 {
+		return 0, nil, errNoPriority
+	}
+
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, fmt.Errorf("invalid priority %q", data[1:end])
+	}
+
+	return pri, data[end+1:], nil
+}
+
+// parse5424 parses the rest of RFC5424 message after the version:
+//
+//	TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parse5424(m *message, data []byte) error {
+	var fields [5]string
+	for i := range fields {
+		sp := bytes.IndexByte(data, ' ')
+		if sp < 0 {
+			return fmt.Errorf("RFC5424 header incomplete")
+		}
+		fields[i], data = string(data[:sp]), data[sp+1:]
+	}
+
+	if fields[0] != nilValue {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q: %w", fields[0], err)
+		}
+		m.timestamp = t
+	}
+
+	m.hostname = nilToEmpty(fields[1])
+	m.appName = nilToEmpty(fields[2])
+	m.procID = nilToEmpty(fields[3])
+	m.msgID = nilToEmpty(fields[4])
+
+	sd, rest, err := parseStructuredData(data)
+	if err != nil {
+		return err
+	}
+	m.structuredData = sd
+
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return fmt.Errorf("space expected after STRUCTURED-DATA")
+		}
+		m.message = string(bytes.TrimPrefix(rest[1:], bom))
+	}
+
+	return nil
+}
+
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
+
+// parseStructuredData parses "-" or [SD-ID *(SP PARAM-NAME="PARAM-VALUE")]...
+func parseStructuredData(data []byte) (map[string]map[string]string, []byte, error) {
+	if len(data) == 0 {
+		return nil, data, fmt.Errorf("STRUCTURED-DATA not found")
+	}
+
+	if data[0] == '-' {
+		return nil, data[1:], nil
+	}
+
+	sd := map[string]map[string]string{}
+
+	for len(data) > 0 && data[0] == '[' {
+		data = data[1:]
+
+		end := bytes.IndexAny(data, " ]")
+		if end <= 0 {
+			return nil, nil, fmt.Errorf("invalid SD-ID")
+		}
+
+		id := string(data[:end])
+		params := map[string]string{}
+		sd[id] = params
+		data = data[end:]
+
+		for len(data) > 0 && data[0] == ' ' {
+			data = data[1:]
+
+			eq := bytes.IndexByte(data, '=')
+			if eq <= 0 || len(data) < eq+2 || data[eq+1] != '"' {
+				return nil, nil, fmt.Errorf("invalid SD-PARAM of %s", id)
+			}
+
+			name := string(data[:eq])
+			value, rest, err := parseParamValue(data[eq+2:])
+			if err != nil {
+				return nil, nil, fmt.Errorf("SD-PARAM %s of %s: %w", name, id, err)
+			}
+
+			params[name] = value
+			data = rest
+		}
+
+		if len(data) == 0 || data[0] != ']' {
+			return nil, nil, fmt.Errorf("SD-ELEMENT %s not closed", id)
+		}
+		data = data[1:]
+	}
+
+	return sd, data, nil
+}
+
+// parseParamValue parses the value after the opening '"', with '"', '\' and
+// ']' escaped by '\'.
+func parseParamValue(data []byte) (string, []byte, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '\\':
+			if i+1 < len(data) {
+				if n := data[i+1]; n == '"' || n == '\\' || n == ']' {
+					sb.WriteByte(n)
+					i++
+					continue
+				}
+			}
+			sb.WriteByte(c)
+		case '"':
+			return sb.String(), data[i+1:], nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", nil, fmt.Errorf("value not closed")
+}
+
+// RFC3164 timestamp without year, such as "Jan  2 15:04:05".
+const stamp3164 = time.Stamp
+
+// parse3164 parses the rest of RFC3164 message after the priority:
+//
+//	TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+func parse3164(m *message, data []byte, now time.Time) {
+	if len(data) >= len(stamp3164) {
+		if t, err := time.ParseInLocation(stamp3164, string(data[:len(stamp3164)]), now.Location()); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) { // message of last year
+				t = t.AddDate(-1, 0, 0)
+			}
+			m.timestamp = t
+			data = bytes.TrimLeft(data[len(stamp3164):], " ")
+		}
+	}
+
+	if m.timestamp.IsZero() { // some senders use RFC3339 timestamp
+		if sp := bytes.IndexByte(data, ' '); sp > 0 {
+			if t, err := time.Parse(time.RFC3339Nano, string(data[:sp])); err == nil {
+				m.timestamp = t
+				data = data[sp+1:]
+			}
+		}
+	}
+
+	if !m.timestamp.IsZero() {
+		// hostname is only present after the timestamp
+		if sp := bytes.IndexByte(data, ' '); sp > 0 && !isTag(data[:sp]) {
+			m.hostname = string(data[:sp])
+			data = data[sp+1:]
+		}
+	}
+
+	if sp := bytes.IndexByte(data, ' '); sp > 0 && isTag(data[:sp]) {
+		tag := data[:sp-1] // without ':'
+		if lb := bytes.IndexByte(tag, '['); lb > 0 && tag[len(tag)-1] == ']' {
+			m.procID = string(tag[lb+1 : len(tag)-1])
+			tag = tag[:lb]
+		}
+		m.appName = string(tag)
+		data = data[sp+1:]
+	}
+
+	m.message = string(data)
+}
+
+// isTag returns true if s is like "app:" or "app[123]:".
+func isTag(s []byte) bool {
+	if len(s) < 2 || s[len(s)-1] != ':' {
+		return false
+	}
+
+	for _, c := range s[:len(s)-1] {
+		if c == ' ' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package syslog
+
+import (
+	T "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse5424(t *T.T) {
+	now := time.Now()
+
+	t.Run("full", func(t *T.T) {
+		m, err := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 `+
+			`[exampleSDID@32473 iut="3" eventSource="Application \"A\" \]"][examplePriority@32473 class="high"] `+
+			"\xEF\xBB\xBFAn application event log entry...\n"), now)
+		require.NoError(t, err)
+
+		assert.Equal(t, 20, m.facility)
+		assert.Equal(t, "local4", m.facilityName())
+		assert.Equal(t, "notice", m.status())
+		assert.Equal(t, 1, m.version)
+		assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), m.timestamp.UTC())
+		assert.Equal(t, "mymachine.example.com", m.hostname)
+		assert.Equal(t, "evntslog", m.appName)
+		assert.Equal(t, "", m.procID)
+		assert.Equal(t, "ID47", m.msgID)
+		assert.Equal(t, map[string]map[string]string{
+			"exampleSDID@32473":     {"iut": "3", "eventSource": `Application "A" ]`},
+			"examplePriority@32473": {"class": "high"},
+		}, m.structuredData)
+		assert.Equal(t, "An application event log entry...", m.message)
+	})
+
+	t.Run("nil-values", func(t *T.T) {
+		m, err := parse([]byte(`<34>1 - - - - - -`), now)
+		require.NoError(t, err)
+
+		assert.Equal(t, "auth", m.facilityName())
+		assert.Equal(t, "critical", m.status())
+		assert.True(t, m.timestamp.IsZero())
+		assert.Empty(t, m.hostname)
+		assert.Nil(t, m.structuredData)
+		assert.Empty(t, m.message)
+	})
+
+	t.Run("invalid", func(t *T.T) {
+		for _, s := range []string{
+			`<34>1 2003-10-11T22:14:15.003Z host`,
+			`<34>1 not-a-time host app - - - msg`,
+			`<34>1 - host app - - [id k="v" msg`,
+			`<34>1 - host app - - [id k=v] msg`,
+			`<34>1 - host app - - -msg`,
+			`<192>1 - - - - - -`,
+			`no priority`,
+		} {
+			_, err := parse([]byte(s), now)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestParse3164(t *T.T) {
+	now := time.Date(2023, 3, 1, 0, 0, 0, 0, time.Local)
+
+	t.Run("full", func(t *T.T) {
+		m, err := parse([]byte("<13>Feb  5 17:32:18 10.0.0.99 sshd[2481]: Accepted publickey for root\n"), now)
+		require.NoError(t, err)
+
+		assert.Equal(t, "user", m.facilityName())
+		assert.Equal(t, "notice", m.status())
+		assert.Equal(t, 0, m.version)
+		assert.Equal(t, time.Date(2023, 2, 5, 17, 32, 18, 0, time.Local), m.timestamp)
+		assert.Equal(t, "10.0.0.99", m.hostname)
+		assert.Equal(t, "sshd", m.appName)
+		assert.Equal(t, "2481", m.procID)
+		assert.Equal(t, "Accepted publickey for root", m.message)
+	})
+
+	t.Run("last-year", func(t *T.T) {
+		m, err := parse([]byte("<13>Dec 31 23:59:59 host app: bye"), now)
+		require.NoError(t, err)
+		assert.Equal(t, 2022, m.timestamp.Year())
+	})
+
+	t.Run("without-hostname", func(t *T.T) {
+		m, err := parse([]byte("<30>Feb  5 17:32:18 systemd: Started Session"), now)
+		require.NoError(t, err)
+
+		assert.Empty(t, m.hostname)
+		assert.Equal(t, "systemd", m.appName)
+		assert.Equal(t, "Started Session", m.message)
+	})
+
+	t.Run("rfc3339-timestamp", func(t *T.T) {
+		m, err := parse([]byte("<30>2023-02-05T17:32:18+08:00 web nginx: GET /"), now)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(1675589538), m.timestamp.Unix())
+		assert.Equal(t, "web", m.hostname)
+		assert.Equal(t, "nginx", m.appName)
+		assert.Equal(t, "GET /", m.message)
+	})
+
+	t.Run("message-only", func(t *T.T) {
+		m, err := parse([]byte("<30>just a message"), now)
+		require.NoError(t, err)
+
+		assert.True(t, m.timestamp.IsZero())
+		assert.Empty(t, m.appName)
+		assert.Equal(t, "just a message", m.message)
+	})
+}
@@ -0,0 +1,488 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+)
+
+const (
+	kindHost      = "host"
+	kindVM        = "vm"
+	kindDatastore = "datastore"
+	kindCluster   = "cluster"
+
+	// intervals of statistics, realtime or the 5 minutes rollup
+	intervalRealtime = 20
+	intervalHistory  = 300
+
+	perfBatchSize = 64 // entities per QueryPerf
+
+	defaultHostMetrics = `cpu.usage.average cpu.usagemhz.average
+mem.usage.average mem.consumed.average mem.active.average
+net.received.average net.transmitted.average
+disk.read.average disk.write.average disk.maxTotalLatency.latest`
+
+	defaultVMMetrics = `cpu.usage.average cpu.ready.summation
+mem.usage.average mem.active.average
+net.received.average net.transmitted.average
+disk.read.average disk.write.average`
+)
+
+// property is a property of the managed object, collected as a tag or a field.
+type property struct {
+	path string
+	key  string
+	tag  bool
+}
+
+// kind is a kind of resources, such as hosts and VMs.
+type kind struct {
+	name       string // suffix of measurement
+	moType     string // type of managed object
+	intervalID int
+	props      []property
+}
+
+var kinds = []*kind{
+	{
+		name:       kindCluster,
+		moType:     "ClusterComputeResource",
+		intervalID: intervalHistory,
+		props: []property{
+			{path: "summary.numHosts", key: "num_hosts"},
+			{path: "summary.numEffectiveHosts", key: "num_effective_hosts"},
+			{path: "summary.totalCpu", key: "total_cpu"},
+			{path: "summary.totalMemory", key: "total_memory"},
+			{path: "summary.effectiveCpu", key: "effective_cpu"},
+			{path: "summary.effectiveMemory", key: "effective_memory"},
+		},
+	},
+	{
+		name:       kindHost,
+		moType:     "HostSystem",
+		intervalID: intervalRealtime,
+		props: []property{
+			{path: "runtime.connectionState", key: "connection_state", tag: true},
+			{path: "runtime.powerState", key: "power_state", tag: true},
+			{path: "summary.hardware.vendor", key: "vendor", tag: true},
+			{path: "summary.hardware.model", key: "model", tag: true},
+			{path: "summary.config.product.fullName", key: "product", tag: true},
+			{path: "summary.hardware.numCpuCores", key: "cpu_cores"},
+			{path: "summary.hardware.cpuMhz", key: "cpu_mhz"},
+			{path: "summary.hardware.memorySize", key: "memory_size"},
+			{path: "summary.quickStats.overallCpuUsage", key: "overall_cpu_usage"},
+			{path: "summary.quickStats.overallMemoryUsage", key: "overall_memory_usage"},
+			{path: "summary.quickStats.uptime", key: "uptime"},
+		},
+	},
+	{
+		name:       kindVM,
+		moType:     "VirtualMachine",
+		intervalID: intervalRealtime,
+		props: []property{
+			{path: "runtime.powerState", key: "power_state", tag: true},
+			{path: "config.guestFullName", key: "guest_os", tag: true},
+			{path: "guest.ipAddress", key: "ip", tag: true},
+			{path: "summary.config.numCpu", key: "num_cpu"},
+			{path: "summary.config.memorySizeMB", key: "memory_size_mb"},
+			{path: "summary.quickStats.overallCpuUsage", key: "overall_cpu_usage"},
+			{path: "summary.quickStats.guestMemoryUsage", key: "guest_memory_usage"},
+			{path: "summary.quickStats.hostMemoryUsage", key: "host_memory_usage"},
+			{path: "summary.quickStats.uptimeSeconds", key: "uptime"},
+		},
+	},
+	{
+		name:       kindDatastore,
+		moType:     "Datastore",
+		intervalID: intervalHistory,
+		props: []property{
+			{path: "summary.type", key: "type", tag: true},
+			{path: "summary.url", key: "url", tag: true},
+			{path: "summary.accessible", key: "accessible"},
+			{path: "summary.capacity", key: "capacity"},
+			{path: "summary.freeSpace", key: "free_space"},
+		},
+	},
+}
+
+// Properties resolved to tags of other resources.
+const (
+	propName     = "name"
+	propParent   = "parent"
+	propHost     = "runtime.host"
+	propTemplate = "config.template"
+)
+
+func (k *kind) measurement() string {
+	return inputName + "_" + k.name
+}
+
+func (k *kind) paths() []string {
+	res := []string{propName}
+	switch k.name {
+	case kindHost:
+		res = append(res, propParent)
+	case kindVM:
+		res = append(res, propHost, propTemplate)
+	}
+
+	for _, p := range k.props {
+		res = append(res, p.path)
+	}
+	return res
+}
+
+// resource is a managed object selected to collect.
+type resource struct {
+	kind   *kind
+	ref    moRef
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	props  map[string]interface{}
+}
+
+func (r *resource) perfEnabled() bool {
+	switch r.kind.name {
+	case kindHost:
+		return r.tags["connection_state"] == "connected"
+	case kindVM:
+		return r.tags["power_state"] == "poweredOn"
+	default:
+		return true
+	}
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (rc *resourceConfig) selected(name string) bool {
+	return matchAny(rc.Include, name) && !matchAny(rc.Exclude, name)
+}
+
+// login logs in if not yet, or the session is expired.
+func (ipt *Input) login(ctx context.Context) error {
+	if ipt.client.content != nil {
+		return nil
+	}
+	return ipt.client.login(ctx, ipt.Username, ipt.Password)
+}
+
+// collectAll returns metrics of all resources selected, and objects if
+// withObjects is set.
+func (ipt *Input) collectAll(ctx context.Context, withObjects bool) (metrics, objects []*point.Point, err error) {
+	if err := ipt.login(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	ts := time.Now()
+
+	// names of clusters and hosts by moref, for tags of hosts and VMs
+	names := map[string]string{}
+
+	for _, k := range kinds {
+		rc := ipt.resourceConfig(k)
+		if rc.Disabled {
+			continue
+		}
+
+		resources, err := ipt.retrieveResources(ctx, k, names)
+		if err != nil {
+			return metrics, objects, err
+		}
+
+		pts, err := ipt.collectPerf(ctx, k, resources, ts)
+		if err != nil {
+			l.Warnf("collect performance counters of %s: %s", k.name, err)
+		}
+		metrics = append(metrics, pts...)
+
+		if withObjects {
+			objects = append(objects, ipt.buildObjects(resources, ts)...)
+		}
+	}
+
+	return metrics, objects, nil
+}
+
+func (ipt *Input) retrieveResources(ctx context.Context, k *kind, names map[string]string) ([]*resource, error) {
+	contents, err := ipt.client.retrieve(ctx, k.moType, k.paths())
+	if err != nil {
+		return nil, err
+	}
+
+	rc := ipt.resourceConfig(k)
+
+	var res []*resource
+	for i := range contents {
+		props := contents[i].props()
+
+		name, _ := props[propName].(string)
+		names[contents[i].Obj.Value] = name
+
+		if template, _ := props[propTemplate].(bool); template {
+			continue
+		}
+
+		if !rc.selected(name) {
+			continue
+		}
+
+		r := &resource{
+			kind:   k,
+			ref:    contents[i].Obj,
+			name:   name,
+			tags:   map[string]string{"moid": contents[i].Obj.Value},
+			fields: map[string]interface{}{},
+			props:  props,
+		}
+
+		switch k.name {
+		case kindHost:
+			r.tags["esx_host"] = name
+			if parent, ok := props[propParent].(moRef); ok && parent.Type == "ClusterComputeResource" {
+				if cluster := names[parent.Value]; cluster != "" {
+					r.tags["cluster"] = cluster
+				}
+			}
+		case kindVM:
+			r.tags["vm_name"] = name
+			if host, ok := props[propHost].(moRef); ok {
+				if esx := names[host.Value]; esx != "" {
+					r.tags["esx_host"] = esx
+				}
+			}
+		default:
+			r.tags[k.name] = name
+		}
+
+		for _, p := range k.props {
+			v, ok := props[p.path]
+			if !ok {
+				continue
+			}
+
+			if p.tag {
+				if s, ok := v.(string); ok && s != "" {
+					r.tags[p.key] = s
+				}
+				continue
+			}
+
+			switch x := v.(type) {
+			case int64, float64, bool:
+				r.fields[p.key] = x
+			}
+		}
+
+		if k.name == kindDatastore {
+			capacity, _ := r.fields["capacity"].(int64)
+			free, _ := r.fields["free_space"].(int64)
+			if capacity > 0 {
+				r.fields["used_percent"] = float64(capacity-free) / float64(capacity) * 100
+			}
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// metricIDs returns IDs of counters matched with the patterns.
+func (ipt *Input) metricIDs(patterns []string, instances bool) []perfMetricID {
+	instance := ""
+	if instances {
+		instance = "*"
+	}
+
+	var ids []perfMetricID
+	for key, pc := range ipt.client.counters {
+		if matchAny(patterns, pc.name()) {
+			ids = append(ids, perfMetricID{CounterID: key, Instance: instance})
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i].CounterID < ids[j].CounterID })
+	return ids
+}
+
+// collectPerf returns a point for each resource, with the latest sample of
+// each counter, and a point for each instance if instances enabled.
+func (ipt *Input) collectPerf(ctx context.Context, k *kind, resources []*resource, ts time.Time) ([]*point.Point, error) {
+	rc := ipt.resourceConfig(k)
+
+	type series struct {
+		tags   map[string]string
+		fields map[string]interface{}
+	}
+
+	var (
+		all   []*series
+		index = map[string]*series{}
+		byRef = map[string]*resource{}
+	)
+
+	get := func(r *resource, instance string) *series {
+		key := r.ref.Value + "\n" + instance
+		if s, ok := index[key]; ok {
+			return s
+		}
+
+		s := &series{tags: map[string]string{}, fields: map[string]interface{}{}}
+		for k, v := range r.tags {
+			s.tags[k] = v
+		}
+		if instance != "" {
+			s.tags["instance"] = instance
+		}
+
+		index[key] = s
+		all = append(all, s)
+		return s
+	}
+
+	var specs []perfQuerySpec
+	ids := ipt.metricIDs(rc.Metrics, rc.Instances)
+
+	for _, r := range resources {
+		s := get(r, "")
+		for k, v := range r.fields {
+			s.fields[k] = v
+		}
+
+		byRef[r.ref.Value] = r
+		if len(ids) > 0 && r.perfEnabled() {
+			specs = append(specs, perfQuerySpec{
+				Entity:     r.ref,
+				MaxSample:  1,
+				MetricID:   ids,
+				IntervalID: k.intervalID,
+			})
+		}
+	}
+
+	var lastErr error
+	for start := 0; start < len(specs); start += perfBatchSize {
+		end := start + perfBatchSize
+		if end > len(specs) {
+			end = len(specs)
+		}
+
+		metrics, err := ipt.client.queryPerf(ctx, specs[start:end])
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, m := range metrics {
+			r, ok := byRef[m.Entity.Value]
+			if !ok {
+				continue
+			}
+
+			for _, v := range m.Value {
+				pc, ok := ipt.client.counters[v.ID.CounterID]
+				if !ok || len(v.Value) == 0 {
+					continue
+				}
+
+				value := v.Value[len(v.Value)-1]
+				if value < 0 { // not available
+					continue
+				}
+
+				field := strings.ReplaceAll(pc.name(), ".", "_")
+				s := get(r, v.ID.Instance)
+
+				// percentages are in hundredths of a percent
+				if pc.UnitInfo.Key == "percent" {
+					s.fields[field] = float64(value) / 100
+				} else {
+					s.fields[field] = value
+				}
+			}
+		}
+	}
+
+	opts := ipt.pointOptions(point.DefaultMetricOptions(), ts)
+
+	var pts []*point.Point
+	for _, s := range all {
+		if len(s.fields) == 0 {
+			continue
+		}
+
+		kvs := ipt.kvs(s.tags)
+		for k, v := range s.fields {
+			kvs = kvs.Add([]byte(k), v, false, true)
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(k.measurement()), kvs, opts...))
+	}
+
+	return pts, lastErr
+}
+
+func (ipt *Input) buildObjects(resources []*resource, ts time.Time) []*point.Point {
+	opts := ipt.pointOptions(point.DefaultObjectOptions(), ts)
+
+	var pts []*point.Point
+	for _, r := range resources {
+		kvs := ipt.kvs(r.tags)
+		kvs = kvs.AddTag([]byte("name"), []byte(r.name))
+
+		for k, v := range r.fields {
+			kvs = kvs.Add([]byte(k), v, false, true)
+		}
+
+		props := make(map[string]interface{}, len(r.props))
+		for k, v := range r.props {
+			if ref, ok := v.(moRef); ok {
+				v = ref.Value
+			}
+			props[k] = v
+		}
+
+		if j, err := json.Marshal(props); err == nil {
+			kvs = kvs.Add([]byte("message"), string(j), false, true)
+		}
+
+		pts = append(pts, point.NewPointV2([]byte(r.kind.measurement()), kvs, opts...))
+	}
+
+	return pts
+}
+
+// kvs returns tags of the input and the resource.
+func (ipt *Input) kvs(tags map[string]string) point.KVs {
+	var kvs point.KVs
+	kvs = kvs.AddTag([]byte("vcenter"), []byte(ipt.vcenter))
+
+	for k, v := range ipt.Tags {
+		kvs = kvs.AddTag([]byte(k), []byte(v))
+	}
+
+	for k, v := range tags {
+		kvs = kvs.MustAddTag([]byte(k), []byte(v))
+	}
+
+	return kvs
+}
@@ -0,0 +1,362 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+// Package vsphere collects performance counters and inventory of vSphere by vCenter.
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GuanceCloud/cliutils"
+	"github.com/GuanceCloud/cliutils/logger"
+	"github.com/GuanceCloud/cliutils/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
+	dknet "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/net"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+	dkpt "gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+const (
+	inputName = "vsphere"
+
+	minInterval = 20 * time.Second // the realtime interval of vCenter
+	maxInterval = 10 * time.Minute
+
+	sampleCfg = `
+[[inputs.vsphere]]
+  ## SDK URL of vCenter
+  vcenter  = "https://vcenter.example.com/sdk"
+  username = "datakit@vsphere.local"
+  password = "<PASS>"
+
+  ## Interval of performance counters, realtime statistics of hosts and
+  ## VMs are sampled every 20s.
+  interval = "20s"
+
+  ## Interval of inventory objects
+  object_interval = "5m"
+
+  timeout = "30s"
+
+  ## vCenter is collected by the elected DataKit only
+  election = true
+
+  # [inputs.vsphere.tlsconf]
+  #   ca_certs = ["/path/to/ca.pem"]
+  #   insecure_skip_verify = true
+
+  ## Resources to collect. Resources are selected by names matched with
+  ## include and not with exclude, in glob patterns. Performance counters
+  ## are selected by names of "group.name.rollup", in glob patterns too,
+  ## such as "cpu.usage.average" and "disk.*.average". Set instances to
+  ## collect values of each instance (cores, NICs, disks) besides the
+  ## aggregated value.
+  [inputs.vsphere.host]
+    # disabled = false
+    include = ["*"]
+    exclude = []
+    metrics = [
+      "cpu.usage.average",
+      "cpu.usagemhz.average",
+      "mem.usage.average",
+      "mem.consumed.average",
+      "mem.active.average",
+      "net.received.average",
+      "net.transmitted.average",
+      "disk.read.average",
+      "disk.write.average",
+      "disk.maxTotalLatency.latest",
+    ]
+    instances = false
+
+  [inputs.vsphere.vm]
+    # disabled = false
+    include = ["*"]
+    exclude = []
+    metrics = [
+      "cpu.usage.average",
+      "cpu.ready.summation",
+      "mem.usage.average",
+      "mem.active.average",
+      "net.received.average",
+      "net.transmitted.average",
+      "disk.read.average",
+      "disk.write.average",
+    ]
+    instances = false
+
+  ## Statistics of datastores and clusters are rolled up every 5 minutes.
+  [inputs.vsphere.datastore]
+    # disabled = false
+    include = ["*"]
+    exclude = []
+    metrics = []
+
+  [inputs.vsphere.cluster]
+    # disabled = false
+    include = ["*"]
+    exclude = []
+    metrics = []
+
+  [inputs.vsphere.tags]
+  # some_tag = "some_value"
+  # more_tag = "some_other_value"
+`
+)
+
+var l = logger.DefaultSLogger(inputName)
+
+type resourceConfig struct {
+	Disabled  bool     `toml:"disabled"`
+	Include   []string `toml:"include"`
+	Exclude   []string `toml:"exclude"`
+	Metrics   []string `toml:"metrics"`
+	Instances bool     `toml:"instances"`
+}
+
+type Input struct {
+	VCenter        string                 `toml:"vcenter"`
+	Username       string                 `toml:"username"`
+	Password       string                 `toml:"password"`
+	Interval       datakit.Duration       `toml:"interval"`
+	ObjectInterval datakit.Duration       `toml:"object_interval"`
+	Timeout        datakit.Duration       `toml:"timeout"`
+	Election       bool                   `toml:"election"`
+	TLSConf        *dknet.TLSClientConfig `toml:"tlsconf"`
+
+	Host      resourceConfig `toml:"host"`
+	VM        resourceConfig `toml:"vm"`
+	Datastore resourceConfig `toml:"datastore"`
+	Cluster   resourceConfig `toml:"cluster"`
+
+	Tags map[string]string `toml:"tags"`
+
+	client        *client
+	vcenter       string // host of the vCenter URL, as tag
+	lastObjectsAt time.Time
+
+	pause   bool
+	pauseCh chan bool
+
+	feeder  dkio.Feeder
+	semStop *cliutils.Sem // start stop signal
+}
+
+var (
+	_ inputs.ElectionInput = (*Input)(nil)
+	_ inputs.InputV2       = (*Input)(nil)
+)
+
+func (*Input) Catalog() string { return inputName }
+
+func (*Input) SampleConfig() string { return sampleCfg }
+
+func (*Input) AvailableArchs() []string { return datakit.AllOSWithElection }
+
+func (*Input) SampleMeasurement() []inputs.Measurement {
+	var res []inputs.Measurement
+	for _, k := range kinds {
+		res = append(res, &metricMeasurement{kind: k}, &objectMeasurement{kind: k})
+	}
+	return res
+}
+
+func (ipt *Input) ElectionEnabled() bool {
+	return ipt.Election
+}
+
+func (ipt *Input) Run() {
+	l = logger.SLogger(inputName)
+	ipt.Interval.Duration = config.ProtectedInterval(minInterval, maxInterval, ipt.Interval.Duration)
+
+	if err := ipt.setup(); err != nil {
+		l.Errorf("setup: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+		return
+	}
+
+	tick := time.NewTicker(ipt.Interval.Duration)
+	defer tick.Stop()
+
+	for {
+		if ipt.pause {
+			l.Debugf("not leader, skipped")
+		} else {
+			ipt.collect()
+		}
+
+		select {
+		case <-datakit.Exit.Wait():
+			ipt.exit()
+			l.Info(inputName + " exit")
+			return
+		case <-ipt.semStop.Wait():
+			ipt.exit()
+			l.Info(inputName + " return")
+			return
+		case ipt.pause = <-ipt.pauseCh:
+		case <-tick.C:
+		}
+	}
+}
+
+func (ipt *Input) setup() error {
+	u, err := url.Parse(ipt.VCenter)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid vcenter %q", ipt.VCenter)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/sdk"
+	}
+	ipt.vcenter = u.Hostname()
+
+	tr := &http.Transport{}
+	if ipt.TLSConf != nil {
+		conf, err := ipt.TLSConf.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("TLS config: %w", err)
+		}
+		tr.TLSClientConfig = conf
+	}
+
+	ipt.client = &client{
+		url: u.String(),
+		cli: &http.Client{Transport: tr, Timeout: ipt.Timeout.Duration},
+	}
+
+	return nil
+}
+
+func (ipt *Input) collect() {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ipt.Interval.Duration)
+	defer cancel()
+
+	collectObjects := time.Since(ipt.lastObjectsAt) >= ipt.ObjectInterval.Duration
+
+	metrics, objects, err := ipt.collectAll(ctx, collectObjects)
+	if err != nil {
+		l.Errorf("collect: %s", err)
+		ipt.feeder.FeedLastError(inputName, err.Error())
+
+		// login again next time on session expired
+		var fault *soapFault
+		if errors.As(err, &fault) && fault.notAuthenticated() {
+			ipt.client.content = nil
+		}
+	}
+
+	if len(metrics) > 0 {
+		if err := ipt.feeder.Feed(inputName, point.Metric, metrics,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed metrics: %s", err)
+		}
+	}
+
+	if len(objects) > 0 {
+		ipt.lastObjectsAt = start
+		if err := ipt.feeder.Feed(inputName, point.Object, objects,
+			&dkio.Option{CollectCost: time.Since(start)}); err != nil {
+			l.Errorf("feed objects: %s", err)
+		}
+	}
+}
+
+func (ipt *Input) exit() {
+	if ipt.client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ipt.client.logout(ctx)
+	}
+}
+
+// pointOptions returns options of points, with the election tags if enabled.
+func (ipt *Input) pointOptions(opts []point.Option, ts time.Time) []point.Option {
+	opts = append(opts, point.WithTime(ts))
+	if ipt.Election {
+		opts = append(opts, point.WithExtraTags(dkpt.GlobalElectionTags()))
+	}
+	return opts
+}
+
+func (ipt *Input) resourceConfig(k *kind) *resourceConfig {
+	switch k.name {
+	case kindHost:
+		return &ipt.Host
+	case kindVM:
+		return &ipt.VM
+	case kindDatastore:
+		return &ipt.Datastore
+	case kindCluster:
+		return &ipt.Cluster
+	default:
+		panic("unknown kind " + k.name)
+	}
+}
+
+func (ipt *Input) Terminate() {
+	if ipt.semStop != nil {
+		ipt.semStop.Close()
+	}
+}
+
+func (ipt *Input) Pause() error {
+	tick := time.NewTicker(inputs.ElectionPauseTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- true:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("pause %s failed", inputName)
+	}
+}
+
+func (ipt *Input) Resume() error {
+	tick := time.NewTicker(inputs.ElectionResumeTimeout)
+	defer tick.Stop()
+	select {
+	case ipt.pauseCh <- false:
+		return nil
+	case <-tick.C:
+		return fmt.Errorf("resume %s failed", inputName)
+	}
+}
+
+func defaultInput() *Input {
+	all := []string{"*"}
+
+	return &Input{
+		Interval:       datakit.Duration{Duration: 20 * time.Second},
+		ObjectInterval: datakit.Duration{Duration: 5 * time.Minute},
+		Timeout:        datakit.Duration{Duration: 30 * time.Second},
+		Election:       true,
+
+		Host:      resourceConfig{Include: all, Metrics: strings.Fields(defaultHostMetrics)},
+		VM:        resourceConfig{Include: all, Metrics: strings.Fields(defaultVMMetrics)},
+		Datastore: resourceConfig{Include: all},
+		Cluster:   resourceConfig{Include: all},
+
+		Tags:    map[string]string{},
+		pauseCh: make(chan bool, inputs.ElectionPauseChannelLength),
+		feeder:  dkio.DefaultFeeder(),
+		semStop: cliutils.NewSem(),
+	}
+}
+
+func init() { //nolint:gochecknoinits
+	inputs.Add(inputName, func() inputs.Input {
+		return defaultInput()
+	})
+}
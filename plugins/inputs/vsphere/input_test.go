@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package vsphere
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GuanceCloud/cliutils/point"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dkio "gitlab.jiagouyun.com/cloudcare-tools/datakit/io"
+)
+
+const (
+	respServiceContent = `<RetrieveServiceContentResponse xmlns="urn:vim25"><returnval>
+<rootFolder type="Folder">group-d1</rootFolder>
+<propertyCollector type="PropertyCollector">propertyCollector</propertyCollector>
+<viewManager type="ViewManager">ViewManager</viewManager>
+<sessionManager type="SessionManager">SessionManager</sessionManager>
+<perfManager type="PerformanceManager">PerfMgr</perfManager>
+</returnval></RetrieveServiceContentResponse>`
+
+	respCounters = `<QueryPerfCounterByLevelResponse xmlns="urn:vim25">
+<returnval><key>2</key><nameInfo><key>usage</key></nameInfo><groupInfo><key>cpu</key></groupInfo><unitInfo><key>percent</key></unitInfo><rollupType>average</rollupType></returnval>
+<returnval><key>24</key><nameInfo><key>usage</key></nameInfo><groupInfo><key>mem</key></groupInfo><unitInfo><key>percent</key></unitInfo><rollupType>average</rollupType></returnval>
+<returnval><key>143</key><nameInfo><key>received</key></nameInfo><groupInfo><key>net</key></groupInfo><unitInfo><key>kiloBytesPerSecond</key></unitInfo><rollupType>average</rollupType></returnval>
+</QueryPerfCounterByLevelResponse>`
+
+	respCluster = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="ClusterComputeResource">domain-c7</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">prod</val></propSet>
+<propSet><name>summary.numHosts</name><val xsi:type="xsd:int">1</val></propSet>
+</objects></returnval></RetrievePropertiesExResponse>`
+
+	respHost = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="HostSystem">host-10</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">esx-01</val></propSet>
+<propSet><name>parent</name><val type="ClusterComputeResource" xsi:type="ManagedObjectReference">domain-c7</val></propSet>
+<propSet><name>runtime.connectionState</name><val xsi:type="HostSystemConnectionState">connected</val></propSet>
+<propSet><name>summary.hardware.memorySize</name><val xsi:type="xsd:long">68719476736</val></propSet>
+</objects></returnval></RetrievePropertiesExResponse>`
+
+	respVM = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<token>next</token>
+<objects><obj type="VirtualMachine">vm-42</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">web-01</val></propSet>
+<propSet><name>runtime.host</name><val type="HostSystem" xsi:type="ManagedObjectReference">host-10</val></propSet>
+<propSet><name>runtime.powerState</name><val xsi:type="VirtualMachinePowerState">poweredOn</val></propSet>
+<propSet><name>config.template</name><val xsi:type="xsd:boolean">false</val></propSet>
+<propSet><name>summary.config.numCpu</name><val xsi:type="xsd:int">4</val></propSet>
+</objects></returnval></RetrievePropertiesExResponse>`
+
+	respVMNext = `<ContinueRetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="VirtualMachine">vm-43</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">tpl-01</val></propSet>
+<propSet><name>config.template</name><val xsi:type="xsd:boolean">true</val></propSet>
+</objects>
+<objects><obj type="VirtualMachine">vm-44</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">test-01</val></propSet>
+</objects></returnval></ContinueRetrievePropertiesExResponse>`
+
+	respDatastore = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="Datastore">datastore-11</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">ds-01</val></propSet>
+<propSet><name>summary.type</name><val xsi:type="xsd:string">VMFS</val></propSet>
+<propSet><name>summary.capacity</name><val xsi:type="xsd:long">1000</val></propSet>
+<propSet><name>summary.freeSpace</name><val xsi:type="xsd:long">250</val></propSet>
+<propSet><name>summary.accessible</name><val xsi:type="xsd:boolean">true</val></propSet>
+</objects></returnval></RetrievePropertiesExResponse>`
+
+	respPerf = `<QueryPerfResponse xmlns="urn:vim25">
+<returnval xsi:type="PerfEntityMetric"><entity type="HostSystem">host-10</entity>
+<sampleInfo><timestamp>2024-01-01T00:00:00Z</timestamp><interval>20</interval></sampleInfo>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance></instance></id><value>1234</value></value>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>143</counterId><instance></instance></id><value>-1</value></value>
+</returnval>
+<returnval xsi:type="PerfEntityMetric"><entity type="VirtualMachine">vm-42</entity>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance></instance></id><value>500</value></value>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance>0</instance></id><value>700</value></value>
+</returnval>
+</QueryPerfResponse>`
+)
+
+var reOperation = regexp.MustCompile(`<soapenv:Body><(\w+)`)
+
+func fakeVCenter(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		m := reOperation.FindSubmatch(body)
+		require.Len(t, m, 2, string(body))
+
+		op := string(m[1])
+		if op != "RetrieveServiceContent" && op != "Login" {
+			ck, err := r.Cookie("vmware_soap_session")
+			if err != nil || ck.Value != "s1" {
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, soapEnvelope(`<soapenv:Fault><faultcode>ServerFaultCode</faultcode>`+ //nolint:errcheck
+					`<faultstring>The session is not authenticated.</faultstring>`+
+					`<detail><NotAuthenticatedFault xmlns="urn:vim25"/></detail></soapenv:Fault>`))
+				return
+			}
+		}
+
+		var resp string
+		switch op {
+		case "RetrieveServiceContent":
+			resp = respServiceContent
+		case "Login":
+			http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: "s1"})
+			resp = `<LoginResponse xmlns="urn:vim25"><returnval></returnval></LoginResponse>`
+		case "QueryPerfCounterByLevel":
+			resp = respCounters
+		case "CreateContainerView":
+			typ := regexp.MustCompile(`<type>(\w+)</type>`).FindSubmatch(body)
+			resp = `<CreateContainerViewResponse xmlns="urn:vim25"><returnval type="ContainerView">view-` +
+				string(typ[1]) + `</returnval></CreateContainerViewResponse>`
+		case "RetrievePropertiesEx":
+			switch {
+			case strings.Contains(string(body), "view-ClusterComputeResource"):
+				resp = respCluster
+			case strings.Contains(string(body), "view-HostSystem"):
+				resp = respHost
+			case strings.Contains(string(body), "view-VirtualMachine"):
+				resp = respVM
+			case strings.Contains(string(body), "view-Datastore"):
+				resp = respDatastore
+			}
+		case "ContinueRetrievePropertiesEx":
+			resp = respVMNext
+		case "QueryPerf":
+			resp = respPerf
+		case "DestroyView", "Logout":
+		default:
+			t.Errorf("unexpected operation %s", op)
+		}
+
+		io.WriteString(w, soapEnvelope(resp)) //nolint:errcheck
+	}))
+}
+
+func soapEnvelope(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"` +
+		` xmlns:xsd="http://www.w3.org/2001/XMLSchema"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+}
+
+func TestCollect(t *testing.T) {
+	ts := fakeVCenter(t)
+	defer ts.Close()
+
+	ipt := defaultInput()
+	ipt.VCenter = ts.URL + "/sdk"
+	ipt.Username = "user"
+	ipt.Password = "pass"
+	ipt.Election = false
+	ipt.VM.Exclude = []string{"test-*"}
+	ipt.VM.Instances = true
+	require.NoError(t, ipt.setup())
+
+	feeder := dkio.NewMockedFeeder()
+	ipt.feeder = feeder
+
+	ipt.collect()
+
+	metrics, err := feeder.NPoints(9, time.Second)
+	require.NoError(t, err)
+
+	byName := map[string][]*point.Point{}
+	for _, pt := range metrics {
+		byName[string(pt.Name())] = append(byName[string(pt.Name())], pt)
+	}
+
+	t.Run("cluster", func(t *testing.T) {
+		require.Len(t, byName["vsphere_cluster"], 2) // metric and object
+		for _, pt := range byName["vsphere_cluster"] {
+			assert.Equal(t, []byte("prod"), pt.Get([]byte("cluster")))
+			assert.Equal(t, int64(1), pt.Get([]byte("num_hosts")))
+		}
+	})
+
+	t.Run("host", func(t *testing.T) {
+		require.Len(t, byName["vsphere_host"], 2)
+
+		pt := byName["vsphere_host"][0]
+		assert.Equal(t, []byte("esx-01"), pt.Get([]byte("esx_host")))
+		assert.Equal(t, []byte("prod"), pt.Get([]byte("cluster")))
+		assert.Equal(t, []byte("host-10"), pt.Get([]byte("moid")))
+		assert.Equal(t, []byte("127.0.0.1"), pt.Get([]byte("vcenter")))
+		assert.Equal(t, int64(68719476736), pt.Get([]byte("memory_size")))
+		assert.Equal(t, 12.34, pt.Get([]byte("cpu_usage_average")))
+		assert.Nil(t, pt.Get([]byte("net_received_average")))
+	})
+
+	t.Run("vm", func(t *testing.T) {
+		var instances []*point.Point
+		for _, pt := range byName["vsphere_vm"] {
+			assert.Equal(t, []byte("web-01"), pt.Get([]byte("vm_name")))
+			assert.Equal(t, []byte("esx-01"), pt.Get([]byte("esx_host")))
+			if pt.Get([]byte("instance")) != nil {
+				instances = append(instances, pt)
+			}
+		}
+
+		require.Len(t, byName["vsphere_vm"], 3) // aggregated, instance 0 and object
+		require.Len(t, instances, 1)
+		assert.Equal(t, 7.0, instances[0].Get([]byte("cpu_usage_average")))
+	})
+
+	t.Run("datastore", func(t *testing.T) {
+		require.Len(t, byName["vsphere_datastore"], 2)
+		for _, pt := range byName["vsphere_datastore"] {
+			assert.Equal(t, []byte("VMFS"), pt.Get([]byte("type")))
+			assert.Equal(t, 75.0, pt.Get([]byte("used_percent")))
+		}
+	})
+
+	t.Run("session-expired", func(t *testing.T) {
+		ipt.client.cookie = "vmware_soap_session=expired"
+		ipt.collect()
+		assert.Nil(t, ipt.client.content)
+
+		ipt.collect()
+		assert.NotNil(t, ipt.client.content)
+	})
+}
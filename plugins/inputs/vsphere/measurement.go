@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package vsphere
+
+import (
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/io/point"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/plugins/inputs"
+)
+
+var kindDescs = map[string]string{
+	kindCluster:   "clusters",
+	kindHost:      "ESXi hosts",
+	kindVM:        "virtual machines, templates excluded",
+	kindDatastore: "datastores",
+}
+
+//nolint:lll
+var kindTags = map[string]map[string]interface{}{
+	kindCluster: {
+		"cluster": &inputs.TagInfo{Desc: "Name of the cluster."},
+	},
+	kindHost: {
+		"esx_host":         &inputs.TagInfo{Desc: "Name of the host."},
+		"cluster":          &inputs.TagInfo{Desc: "Name of the cluster of the host, if any."},
+		"connection_state": &inputs.TagInfo{Desc: "Connection state of the host, such as `connected` and `disconnected`."},
+		"power_state":      &inputs.TagInfo{Desc: "Power state of the host, such as `poweredOn` and `standBy`."},
+		"vendor":           &inputs.TagInfo{Desc: "Vendor of the hardware."},
+		"model":            &inputs.TagInfo{Desc: "Model of the hardware."},
+		"product":          &inputs.TagInfo{Desc: "Full name of the ESXi product, with the version."},
+	},
+	kindVM: {
+		"vm_name":     &inputs.TagInfo{Desc: "Name of the virtual machine."},
+		"esx_host":    &inputs.TagInfo{Desc: "Name of the host running the virtual machine."},
+		"power_state": &inputs.TagInfo{Desc: "Power state of the virtual machine, such as `poweredOn` and `poweredOff`."},
+		"guest_os":    &inputs.TagInfo{Desc: "Full name of the guest OS."},
+		"ip":          &inputs.TagInfo{Desc: "Primary IP of the guest, reported by VMware Tools."},
+	},
+	kindDatastore: {
+		"datastore": &inputs.TagInfo{Desc: "Name of the datastore."},
+		"type":      &inputs.TagInfo{Desc: "Type of the file system, such as `VMFS`, `NFS` and `vsan`."},
+		"url":       &inputs.TagInfo{Desc: "URL of the datastore."},
+	},
+}
+
+//nolint:lll
+var kindFields = map[string]map[string]interface{}{
+	kindCluster: {
+		"num_hosts":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of hosts."},
+		"num_effective_hosts": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of hosts available to run virtual machines."},
+		"total_cpu":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "Total CPU resources of all hosts."},
+		"total_memory":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Total memory of all hosts."},
+		"effective_cpu":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "CPU resources available to run virtual machines."},
+		"effective_memory":    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeMB, Desc: "Memory available to run virtual machines."},
+	},
+	kindHost: {
+		"cpu_cores":            &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of physical CPU cores."},
+		"cpu_mhz":              &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "Speed of the CPU cores."},
+		"memory_size":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Physical memory size."},
+		"overall_cpu_usage":    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "Aggregated CPU usage across all cores."},
+		"overall_memory_usage": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeMB, Desc: "Physical memory usage."},
+		"uptime":               &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Uptime of the host."},
+	},
+	kindVM: {
+		"num_cpu":            &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.NCount, Desc: "Number of virtual CPUs."},
+		"memory_size_mb":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeMB, Desc: "Memory size of the virtual machine."},
+		"overall_cpu_usage":  &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "CPU usage."},
+		"guest_memory_usage": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeMB, Desc: "Guest memory actively used."},
+		"host_memory_usage":  &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeMB, Desc: "Host memory consumed by the virtual machine."},
+		"uptime":             &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationSecond, Desc: "Uptime of the virtual machine, absent if powered off."},
+	},
+	kindDatastore: {
+		"accessible":   &inputs.FieldInfo{DataType: inputs.Bool, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Whether the datastore is accessible."},
+		"capacity":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Capacity of the datastore."},
+		"free_space":   &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeByte, Desc: "Free space of the datastore."},
+		"used_percent": &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Percent of the used space."},
+	},
+}
+
+// kindPerfFields are fields of the default performance counters.
+//
+//nolint:lll
+var kindPerfFields = map[string]map[string]interface{}{
+	kindHost: {
+		"cpu_usage_average":           &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "CPU usage, of counter `cpu.usage.average`."},
+		"cpu_usagemhz_average":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.FrequencyMHz, Desc: "CPU usage, of counter `cpu.usagemhz.average`."},
+		"mem_usage_average":           &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Memory usage, of counter `mem.usage.average`."},
+		"mem_consumed_average":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeKB, Desc: "Memory consumed, of counter `mem.consumed.average`."},
+		"mem_active_average":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeKB, Desc: "Memory actively used, of counter `mem.active.average`."},
+		"net_received_average":        &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Network receive rate in KB/s, of counter `net.received.average`."},
+		"net_transmitted_average":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Network transmit rate in KB/s, of counter `net.transmitted.average`."},
+		"disk_read_average":           &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Disk read rate in KB/s, of counter `disk.read.average`."},
+		"disk_write_average":          &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Disk write rate in KB/s, of counter `disk.write.average`."},
+		"disk_maxTotalLatency_latest": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Highest latency of all disks, of counter `disk.maxTotalLatency.latest`."},
+	},
+	kindVM: {
+		"cpu_usage_average":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "CPU usage, of counter `cpu.usage.average`."},
+		"cpu_ready_summation":     &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.DurationMS, Desc: "Time ready to run but not scheduled in the interval, of counter `cpu.ready.summation`."},
+		"mem_usage_average":       &inputs.FieldInfo{DataType: inputs.Float, Type: inputs.Gauge, Unit: inputs.Percent, Desc: "Memory usage, of counter `mem.usage.average`."},
+		"mem_active_average":      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.SizeKB, Desc: "Memory actively used, of counter `mem.active.average`."},
+		"net_received_average":    &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Network receive rate in KB/s, of counter `net.received.average`."},
+		"net_transmitted_average": &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Network transmit rate in KB/s, of counter `net.transmitted.average`."},
+		"disk_read_average":       &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Disk read rate in KB/s, of counter `disk.read.average`."},
+		"disk_write_average":      &inputs.FieldInfo{DataType: inputs.Int, Type: inputs.Gauge, Unit: inputs.UnknownUnit, Desc: "Disk write rate in KB/s, of counter `disk.write.average`."},
+	},
+}
+
+func commonTags(k *kind) map[string]interface{} {
+	tags := map[string]interface{}{
+		"vcenter": &inputs.TagInfo{Desc: "Host of the vCenter."},
+		"moid":    &inputs.TagInfo{Desc: "ID of the managed object, such as `host-10` and `vm-42`."},
+	}
+
+	for k, v := range kindTags[k.name] {
+		tags[k] = v
+	}
+	return tags
+}
+
+type metricMeasurement struct {
+	kind *kind
+}
+
+func (m *metricMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+func (m *metricMeasurement) Info() *inputs.MeasurementInfo {
+	tags := commonTags(m.kind)
+	tags["instance"] = &inputs.TagInfo{Desc: "Instance of performance counters, such as a CPU core or a NIC, only if `instances` enabled."}
+
+	fields := map[string]interface{}{}
+	for k, v := range kindFields[m.kind.name] {
+		fields[k] = v
+	}
+	for k, v := range kindPerfFields[m.kind.name] {
+		fields[k] = v
+	}
+
+	return &inputs.MeasurementInfo{
+		Name:   m.kind.measurement(),
+		Type:   "metric",
+		Desc:   "Metrics of " + kindDescs[m.kind.name] + ". Besides fields listed, each performance counter in `metrics` is collected as a field named `group_name_rollup`, percentages in 0~100.",
+		Tags:   tags,
+		Fields: fields,
+	}
+}
+
+type objectMeasurement struct {
+	kind *kind
+}
+
+func (m *objectMeasurement) LineProto() (*point.Point, error) {
+	return nil, nil
+}
+
+func (m *objectMeasurement) Info() *inputs.MeasurementInfo {
+	tags := commonTags(m.kind)
+	tags["name"] = &inputs.TagInfo{Desc: "Name of the resource."}
+
+	fields := map[string]interface{}{
+		"message": &inputs.FieldInfo{DataType: inputs.String, Type: inputs.UnknownType, Unit: inputs.UnknownUnit, Desc: "Properties of the resource in JSON."},
+	}
+
+	for k, v := range kindFields[m.kind.name] {
+		fields[k] = v
+	}
+
+	return &inputs.MeasurementInfo{
+		Name:   m.kind.measurement(),
+		Type:   "object",
+		Desc:   "Inventory of " + kindDescs[m.kind.name] + ".",
+		Tags:   tags,
+		Fields: fields,
+	}
+}
@@ -0,0 +1,456 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A minimal client of the vSphere Web Services API (vim25 over SOAP), with
+// only methods needed to retrieve inventory properties and performance
+// counters.
+
+const (
+	soapAction = "urn:vim25/6.5"
+	xsiNS      = "http://www.w3.org/2001/XMLSchema-instance"
+
+	envelopeHead = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"` +
+		` xmlns:xsd="http://www.w3.org/2001/XMLSchema"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<soapenv:Body>`
+	envelopeTail = `</soapenv:Body></soapenv:Envelope>`
+
+	maxObjects = 500
+)
+
+type moRef struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type serviceContent struct {
+	RootFolder        moRef `xml:"rootFolder"`
+	PropertyCollector moRef `xml:"propertyCollector"`
+	ViewManager       moRef `xml:"viewManager"`
+	SessionManager    moRef `xml:"sessionManager"`
+	PerfManager       moRef `xml:"perfManager"`
+}
+
+// soapFault is returned by the server on failed calls.
+type soapFault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Detail struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"detail"`
+}
+
+func (f *soapFault) Error() string {
+	return fmt.Sprintf("%s: %s", f.Code, f.String)
+}
+
+// notAuthenticated returns true if the session is expired.
+func (f *soapFault) notAuthenticated() bool {
+	return bytes.Contains(f.Detail.Inner, []byte("NotAuthenticated"))
+}
+
+type client struct {
+	url      string
+	cli      *http.Client
+	cookie   string
+	content  *serviceContent
+	counters map[int32]*perfCounter
+}
+
+// call posts the request in the envelope and decodes the body of response
+// into resp.
+func (c *client) call(ctx context.Context, req, resp interface{}) error {
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBufferString(envelopeHead)
+	buf.Write(body)
+	buf.WriteString(envelopeTail)
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, buf)
+	if err != nil {
+		return err
+	}
+
+	hreq.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	hreq.Header.Set("SOAPAction", soapAction)
+	if c.cookie != "" {
+		hreq.Header.Set("Cookie", c.cookie)
+	}
+
+	hresp, err := c.cli.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer hresp.Body.Close() //nolint:errcheck
+
+	for _, ck := range hresp.Cookies() {
+		if ck.Name == "vmware_soap_session" {
+			c.cookie = ck.Name + "=" + ck.Value
+		}
+	}
+
+	data, err := io.ReadAll(hresp.Body)
+	if err != nil {
+		return err
+	}
+
+	var env struct {
+		Body struct {
+			Fault *soapFault `xml:"Fault"`
+			Inner []byte     `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("invalid response of status %d: %w", hresp.StatusCode, err)
+	}
+
+	if env.Body.Fault != nil {
+		return env.Body.Fault
+	}
+
+	if hresp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", hresp.StatusCode)
+	}
+
+	if resp == nil {
+		return nil
+	}
+	return xml.Unmarshal(env.Body.Inner, resp)
+}
+
+// login retrieves the service content, logs in and fetches counters of the
+// performance manager.
+func (c *client) login(ctx context.Context, username, password string) error {
+	var sc struct {
+		Returnval serviceContent `xml:"returnval"`
+	}
+
+	if err := c.call(ctx, &struct {
+		XMLName xml.Name `xml:"urn:vim25 RetrieveServiceContent"`
+		This    moRef    `xml:"_this"`
+	}{
+		This: moRef{Type: "ServiceInstance", Value: "ServiceInstance"},
+	}, &sc); err != nil {
+		return fmt.Errorf("RetrieveServiceContent: %w", err)
+	}
+
+	c.content = &sc.Returnval
+	c.cookie = ""
+
+	if err := c.call(ctx, &struct {
+		XMLName  xml.Name `xml:"urn:vim25 Login"`
+		This     moRef    `xml:"_this"`
+		UserName string   `xml:"userName"`
+		Password string   `xml:"password"`
+	}{
+		This:     c.content.SessionManager,
+		UserName: username,
+		Password: password,
+	}, nil); err != nil {
+		c.content = nil
+		return fmt.Errorf("Login: %w", err)
+	}
+
+	counters, err := c.queryPerfCounters(ctx)
+	if err != nil {
+		c.content = nil
+		return err
+	}
+	c.counters = counters
+
+	return nil
+}
+
+func (c *client) logout(ctx context.Context) {
+	if c.content == nil {
+		return
+	}
+
+	_ = c.call(ctx, &struct {
+		XMLName xml.Name `xml:"urn:vim25 Logout"`
+		This    moRef    `xml:"_this"`
+	}{
+		This: c.content.SessionManager,
+	}, nil)
+
+	c.content = nil
+	c.cookie = ""
+}
+
+// propValue is the value of a property, of types string, int64, float64,
+// bool, or moRef.
+type propValue struct {
+	v interface{}
+}
+
+func (pv *propValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var xsiType, refType string
+	for _, a := range start.Attr {
+		switch {
+		// the prefix is kept as the space if not declared in the body
+		case a.Name.Local == "type" && (a.Name.Space == xsiNS || a.Name.Space == "xsi"):
+			xsiType = a.Value
+		case a.Name.Local == "type" && a.Name.Space == "":
+			refType = a.Value
+		}
+	}
+
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	switch xsiType {
+	case "xsd:int", "xsd:long", "xsd:short", "xsd:byte":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		pv.v = n
+	case "xsd:float", "xsd:double":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		pv.v = f
+	case "xsd:boolean":
+		pv.v = s == "true" || s == "1"
+	case "ManagedObjectReference":
+		pv.v = moRef{Type: refType, Value: s}
+	default: // xsd:string and enums
+		pv.v = s
+	}
+
+	return nil
+}
+
+type objectContent struct {
+	Obj     moRef `xml:"obj"`
+	PropSet []struct {
+		Name string    `xml:"name"`
+		Val  propValue `xml:"val"`
+	} `xml:"propSet"`
+}
+
+// props returns properties of the object by name.
+func (oc *objectContent) props() map[string]interface{} {
+	res := make(map[string]interface{}, len(oc.PropSet))
+	for _, p := range oc.PropSet {
+		res[p.Name] = p.Val.v
+	}
+	return res
+}
+
+type propertyFilterSpec struct {
+	PropSet struct {
+		Type    string   `xml:"type"`
+		PathSet []string `xml:"pathSet"`
+	} `xml:"propSet"`
+	ObjectSet struct {
+		Obj       moRef `xml:"obj"`
+		Skip      bool  `xml:"skip"`
+		SelectSet struct {
+			XSIType string `xml:"xsi:type,attr"`
+			Name    string `xml:"name"`
+			Type    string `xml:"type"`
+			Path    string `xml:"path"`
+			Skip    bool   `xml:"skip"`
+		} `xml:"selectSet"`
+	} `xml:"objectSet"`
+}
+
+type retrieveResult struct {
+	Returnval *struct {
+		Token   string          `xml:"token"`
+		Objects []objectContent `xml:"objects"`
+	} `xml:"returnval"`
+}
+
+// retrieve returns properties of all objects of the type under the root
+// folder, by a container view.
+func (c *client) retrieve(ctx context.Context, typ string, paths []string) ([]objectContent, error) {
+	var view struct {
+		Returnval moRef `xml:"returnval"`
+	}
+
+	if err := c.call(ctx, &struct {
+		XMLName   xml.Name `xml:"urn:vim25 CreateContainerView"`
+		This      moRef    `xml:"_this"`
+		Container moRef    `xml:"container"`
+		Type      []string `xml:"type"`
+		Recursive bool     `xml:"recursive"`
+	}{
+		This:      c.content.ViewManager,
+		Container: c.content.RootFolder,
+		Type:      []string{typ},
+		Recursive: true,
+	}, &view); err != nil {
+		return nil, fmt.Errorf("CreateContainerView: %w", err)
+	}
+
+	defer func() {
+		_ = c.call(ctx, &struct {
+			XMLName xml.Name `xml:"urn:vim25 DestroyView"`
+			This    moRef    `xml:"_this"`
+		}{
+			This: view.Returnval,
+		}, nil)
+	}()
+
+	var spec propertyFilterSpec
+	spec.PropSet.Type = typ
+	spec.PropSet.PathSet = paths
+	spec.ObjectSet.Obj = view.Returnval
+	spec.ObjectSet.Skip = true
+	spec.ObjectSet.SelectSet.XSIType = "TraversalSpec"
+	spec.ObjectSet.SelectSet.Name = "traverseEntities"
+	spec.ObjectSet.SelectSet.Type = "ContainerView"
+	spec.ObjectSet.SelectSet.Path = "view"
+
+	var res retrieveResult
+	if err := c.call(ctx, &struct {
+		XMLName xml.Name           `xml:"urn:vim25 RetrievePropertiesEx"`
+		This    moRef              `xml:"_this"`
+		SpecSet propertyFilterSpec `xml:"specSet"`
+		Options struct {
+			MaxObjects int `xml:"maxObjects"`
+		} `xml:"options"`
+	}{
+		This:    c.content.PropertyCollector,
+		SpecSet: spec,
+		Options: struct {
+			MaxObjects int `xml:"maxObjects"`
+		}{MaxObjects: maxObjects},
+	}, &res); err != nil {
+		return nil, fmt.Errorf("RetrievePropertiesEx: %w", err)
+	}
+
+	var objects []objectContent
+	for res.Returnval != nil {
+		objects = append(objects, res.Returnval.Objects...)
+
+		token := res.Returnval.Token
+		if token == "" {
+			break
+		}
+
+		res = retrieveResult{}
+		if err := c.call(ctx, &struct {
+			XMLName xml.Name `xml:"urn:vim25 ContinueRetrievePropertiesEx"`
+			This    moRef    `xml:"_this"`
+			Token   string   `xml:"token"`
+		}{
+			This:  c.content.PropertyCollector,
+			Token: token,
+		}, &res); err != nil {
+			return nil, fmt.Errorf("ContinueRetrievePropertiesEx: %w", err)
+		}
+	}
+
+	return objects, nil
+}
+
+type perfCounter struct {
+	Key      int32 `xml:"key"`
+	NameInfo struct {
+		Key string `xml:"key"`
+	} `xml:"nameInfo"`
+	GroupInfo struct {
+		Key string `xml:"key"`
+	} `xml:"groupInfo"`
+	UnitInfo struct {
+		Key string `xml:"key"`
+	} `xml:"unitInfo"`
+	RollupType string `xml:"rollupType"`
+}
+
+// name returns the name of the counter as "group.name.rollup",
+// such as "cpu.usage.average".
+func (pc *perfCounter) name() string {
+	return pc.GroupInfo.Key + "." + pc.NameInfo.Key + "." + pc.RollupType
+}
+
+func (c *client) queryPerfCounters(ctx context.Context) (map[int32]*perfCounter, error) {
+	var res struct {
+		Returnval []*perfCounter `xml:"returnval"`
+	}
+
+	if err := c.call(ctx, &struct {
+		XMLName xml.Name `xml:"urn:vim25 QueryPerfCounterByLevel"`
+		This    moRef    `xml:"_this"`
+		Level   int      `xml:"level"`
+	}{
+		This:  c.content.PerfManager,
+		Level: 4,
+	}, &res); err != nil {
+		return nil, fmt.Errorf("QueryPerfCounterByLevel: %w", err)
+	}
+
+	counters := make(map[int32]*perfCounter, len(res.Returnval))
+	for _, pc := range res.Returnval {
+		counters[pc.Key] = pc
+	}
+	return counters, nil
+}
+
+type perfMetricID struct {
+	CounterID int32  `xml:"counterId"`
+	Instance  string `xml:"instance"`
+}
+
+type perfQuerySpec struct {
+	Entity     moRef          `xml:"entity"`
+	MaxSample  int            `xml:"maxSample"`
+	MetricID   []perfMetricID `xml:"metricId"`
+	IntervalID int            `xml:"intervalId"`
+}
+
+type perfEntityMetric struct {
+	Entity     moRef `xml:"entity"`
+	SampleInfo []struct {
+		Timestamp time.Time `xml:"timestamp"`
+	} `xml:"sampleInfo"`
+	Value []struct {
+		ID    perfMetricID `xml:"id"`
+		Value []int64      `xml:"value"`
+	} `xml:"value"`
+}
+
+func (c *client) queryPerf(ctx context.Context, specs []perfQuerySpec) ([]perfEntityMetric, error) {
+	var res struct {
+		Returnval []perfEntityMetric `xml:"returnval"`
+	}
+
+	if err := c.call(ctx, &struct {
+		XMLName   xml.Name        `xml:"urn:vim25 QueryPerf"`
+		This      moRef           `xml:"_this"`
+		QuerySpec []perfQuerySpec `xml:"querySpec"`
+	}{
+		This:      c.content.PerfManager,
+		QuerySpec: specs,
+	}, &res); err != nil {
+		return nil, fmt.Errorf("QueryPerf: %w", err)
+	}
+
+	return res.Returnval, nil
+}
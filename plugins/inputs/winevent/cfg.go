@@ -11,6 +11,7 @@ package winevent
 import (
 	"bytes"
 	"fmt"
+	"time"
 	"unicode/utf16"
 	"unicode/utf8"
 
@@ -43,13 +44,21 @@ var (
 )
 
 type Input struct {
-	Query string            `toml:"xpath_query"`
-	Tags  map[string]string `toml:"tags,omitempty"`
+	Query    string            `toml:"xpath_query"`
+	Channels []*Channel        `toml:"channels"`
+	Tags     map[string]string `toml:"tags,omitempty"`
 
 	subscription EvtHandle
 	buf          []byte
 	collectCache []inputs.Measurement
 
+	bookmark      EvtHandle
+	bookmarkPath  string
+	bookmarkDirty bool
+	bookmarkSaved time.Time
+
+	publishers map[string]EvtHandle // publisher name -> metadata handle
+
 	semStop *cliutils.Sem // start stop signal
 }
 
@@ -76,6 +85,14 @@ type Event struct {
 	OpcodeText    string
 }
 
+// eventData returns the application-provided XML data of the event.
+func (e *Event) eventData() []byte {
+	if len(e.EventData.InnerXML) > 0 {
+		return e.EventData.InnerXML
+	}
+	return e.UserData.InnerXML
+}
+
 // UserData Application-provided XML data.
 type UserData struct {
 	InnerXML []byte `xml:",innerxml"`
@@ -20,8 +20,9 @@ var _ inputs.InputV2 = (*Input)(nil)
 type EvtHandle uintptr
 
 type Input struct {
-	Query string            `toml:"xpath_query"`
-	Tags  map[string]string `toml:"tags,omitempty"`
+	Query    string            `toml:"xpath_query"`
+	Channels []*Channel        `toml:"channels"`
+	Tags     map[string]string `toml:"tags,omitempty"`
 }
 
 func (*Input) SampleConfig() string {
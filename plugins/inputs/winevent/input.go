@@ -9,7 +9,6 @@
 package winevent
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
@@ -28,6 +27,8 @@ import (
 
 var statusList = []string{"info", "critical", "error", "warning", "info"}
 
+const bookmarkFlushInterval = time.Second * 3
+
 func (*Input) SampleConfig() string {
 	return sample
 }
@@ -52,14 +53,24 @@ func (ipt *Input) SampleMeasurement() []inputs.Measurement {
 
 func (ipt *Input) Run() {
 	l = logger.SLogger("win event log")
-	var err error
 
-	ipt.subscription, err = ipt.evtSubscribe("", ipt.Query)
-	if err != nil {
+	if len(ipt.Channels) > 0 {
+		q, err := buildQuery(ipt.Channels)
+		if err != nil {
+			l.Error(err)
+			io.FeedLastError(inputName, err.Error())
+			return
+		}
+		ipt.Query = q
+	}
+
+	if err := ipt.subscribe(); err != nil {
 		io.FeedLastError(inputName, err.Error())
 		return
 	}
 
+	defer ipt.cleanup()
+
 	for {
 		select {
 		case <-datakit.Exit.Wait():
@@ -71,6 +82,8 @@ func (ipt *Input) Run() {
 			return
 
 		default:
+			ipt.flushBookmark(false)
+
 			time.Sleep(time.Millisecond * 1)
 			start := time.Now()
 			events, err := ipt.fetchEvents(ipt.subscription)
@@ -98,6 +111,120 @@ func (ipt *Input) Run() {
 	}
 }
 
+// subscribe subscribes events after the bookmark saved, or future events
+// if no bookmark available.
+func (ipt *Input) subscribe() error {
+	ipt.bookmarkPath = bookmarkPath(ipt.Query)
+
+	bm, err := loadBookmark(ipt.bookmarkPath)
+	if err != nil {
+		l.Warnf("load bookmark %s: %s", ipt.bookmarkPath, err)
+	}
+
+	if bm != "" {
+		if ipt.bookmark, err = createBookmark(bm); err == nil {
+			ipt.subscription, err = ipt.evtSubscribe("", ipt.Query, ipt.bookmark, EvtSubscribeStartAfterBookmark)
+			if err == nil {
+				l.Infof("subscribe events after bookmark %s", ipt.bookmarkPath)
+				return nil
+			}
+
+			_EvtClose(ipt.bookmark) // nolint:errcheck,gosec
+		}
+
+		l.Warnf("subscribe events after bookmark %s: %s, subscribe future events", ipt.bookmarkPath, err)
+	}
+
+	if ipt.bookmark, err = createBookmark(""); err != nil {
+		return err
+	}
+
+	ipt.subscription, err = ipt.evtSubscribe("", ipt.Query, 0, EvtSubscribeToFutureEvents)
+	return err
+}
+
+func (ipt *Input) cleanup() {
+	ipt.flushBookmark(true)
+
+	for _, h := range ipt.publishers {
+		if h != 0 {
+			_EvtClose(h) // nolint:errcheck,gosec
+		}
+	}
+	ipt.publishers = nil
+
+	if ipt.bookmark != 0 {
+		_EvtClose(ipt.bookmark) // nolint:errcheck,gosec
+		ipt.bookmark = 0
+	}
+
+	if ipt.subscription != 0 {
+		_EvtClose(ipt.subscription) // nolint:errcheck,gosec
+		ipt.subscription = 0
+	}
+}
+
+// flushBookmark saves the bookmark of the last event fed. The bookmark
+// updated on each batch, and saved at most every bookmarkFlushInterval
+// unless forced.
+func (ipt *Input) flushBookmark(force bool) {
+	if !ipt.bookmarkDirty {
+		return
+	}
+
+	if !force && time.Since(ipt.bookmarkSaved) < bookmarkFlushInterval {
+		return
+	}
+
+	bm, err := ipt.renderBookmark()
+	if err != nil {
+		l.Warnf("render bookmark: %s", err)
+		return
+	}
+
+	if err := saveBookmark(ipt.bookmarkPath, bm); err != nil {
+		l.Warnf("save bookmark %s: %s", ipt.bookmarkPath, err)
+		return
+	}
+
+	ipt.bookmarkDirty = false
+	ipt.bookmarkSaved = time.Now()
+}
+
+func (ipt *Input) renderBookmark() (string, error) {
+	var bufferUsed, propertyCount uint32
+
+	buf := ipt.buf
+	err := _EvtRender(0, ipt.bookmark, EvtRenderBookmark, uint32(len(buf)), &buf[0], &bufferUsed, &propertyCount)
+	if errors.Is(err, ErrorInsufficientBuffer) {
+		buf = make([]byte, bufferUsed)
+		err = _EvtRender(0, ipt.bookmark, EvtRenderBookmark, uint32(len(buf)), &buf[0], &bufferUsed, &propertyCount)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	bm, err := DecodeUTF16(buf[:bufferUsed])
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.Trim(bm, "\x00")), nil
+}
+
+func createBookmark(bookmarkXML string) (EvtHandle, error) {
+	if bookmarkXML == "" {
+		return _EvtCreateBookmark(nil)
+	}
+
+	p, err := syscall.UTF16PtrFromString(bookmarkXML)
+	if err != nil {
+		return 0, err
+	}
+
+	return _EvtCreateBookmark(p)
+}
+
 func (ipt *Input) Terminate() {
 	if ipt.semStop != nil {
 		ipt.semStop.Close()
@@ -132,6 +259,7 @@ func (ipt *Input) handleEvent(event Event) {
 		"computer":        event.Computer,
 		"message":         event.Message,
 		"level":           event.LevelText,
+		"opcode":          event.OpcodeText,
 		"total_message":   string(msg),
 		"status":          ipt.getEventStatus(event.Level),
 	}
@@ -152,7 +280,7 @@ func (ipt *Input) getEventStatus(level int) string {
 	return "info"
 }
 
-func (ipt *Input) evtSubscribe(logName, xquery string) (EvtHandle, error) {
+func (ipt *Input) evtSubscribe(logName, xquery string, bookmark EvtHandle, flags EvtSubscribeFlag) (EvtHandle, error) {
 	var logNamePtr, xqueryPtr *uint16
 
 	sigEvent, err := windows.CreateEvent(nil, 0, 0, nil)
@@ -172,7 +300,7 @@ func (ipt *Input) evtSubscribe(logName, xquery string) (EvtHandle, error) {
 	}
 
 	subsHandle, err := _EvtSubscribe(0, uintptr(sigEvent), logNamePtr, xqueryPtr,
-		0, 0, 0, EvtSubscribeToFutureEvents)
+		bookmark, 0, 0, flags)
 	if err != nil {
 		return 0, err
 	}
@@ -217,6 +345,15 @@ func (ipt *Input) fetchEvents(subsHandle EvtHandle) ([]Event, error) {
 		}
 	}
 
+	// the bookmark saved after the events fed
+	if n := len(eventHandles); n > 0 && ipt.bookmark != 0 && eventHandles[n-1] != 0 {
+		if err := _EvtUpdateBookmark(ipt.bookmark, eventHandles[n-1]); err != nil {
+			l.Warnf("update bookmark: %s", err)
+		} else {
+			ipt.bookmarkDirty = true
+		}
+	}
+
 	for i := 0; i < len(eventHandles); i++ {
 		err := _EvtClose(eventHandles[i])
 		if err != nil {
@@ -247,11 +384,12 @@ func (ipt *Input) renderEvent(eventHandle EvtHandle) (Event, error) {
 		return event, nil //nolint:nilerr
 	}
 
-	publisherHandle, err := openPublisherMetadata(0, event.Source.Name, 0)
-	if err != nil {
-		return event, nil //nolint:nilerr
+	publisherHandle := ipt.publisherMetadata(event.Source.Name)
+	if publisherHandle == 0 {
+		// no message strings of the publisher, such as forwarded events
+		event.Message = eventDataText(event.eventData())
+		return event, nil
 	}
-	defer _EvtClose(publisherHandle) // nolint:errcheck
 
 	// Populating text values
 	keywords, err := formatEventString(EvtFormatMessageKeyword, eventHandle, publisherHandle)
@@ -262,12 +400,12 @@ func (ipt *Input) renderEvent(eventHandle EvtHandle) (Event, error) {
 	}
 	message, err := formatEventString(EvtFormatMessageEvent, eventHandle, publisherHandle)
 	if err == nil {
-		scanner := bufio.NewScanner(strings.NewReader(message))
-		scanner.Scan()
-		message = scanner.Text()
-		event.Message = message
+		event.Message = strings.TrimSpace(message)
 	} else {
-		l.Warn(err)
+		l.Debugf("format message of event %d from %s: %s", event.EventID, event.Source.Name, err)
+	}
+	if event.Message == "" {
+		event.Message = eventDataText(event.eventData())
 	}
 	level, err := formatEventString(EvtFormatMessageLevel, eventHandle, publisherHandle)
 	if err == nil {
@@ -332,6 +470,27 @@ func formatEventString(
 	return out, nil
 }
 
+// publisherMetadata returns the cached metadata handle of the publisher, 0
+// if not available.
+func (ipt *Input) publisherMetadata(name string) EvtHandle {
+	if h, ok := ipt.publishers[name]; ok {
+		return h
+	}
+
+	if ipt.publishers == nil {
+		ipt.publishers = map[string]EvtHandle{}
+	}
+
+	h, err := openPublisherMetadata(0, name, 0)
+	if err != nil {
+		l.Debugf("open metadata of publisher %s: %s", name, err)
+		h = 0
+	}
+
+	ipt.publishers[name] = h
+	return h
+}
+
 // openPublisherMetadata opens a handle to the publisher's metadata. Close must
 // be called on returned EvtHandle when finished with the handle.
 func openPublisherMetadata(
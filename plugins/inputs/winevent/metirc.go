@@ -38,6 +38,19 @@ var (
     </Query>
   </QueryList>
 	'''
+
+  ## XPath filters per channel, overrides the xpath_query above if set.
+  ## The query selects events of the channel(all events if not set), and
+  ## the events matched by the suppress are excluded.
+  # [[inputs.windows_event.channels]]
+  #   name  = "Application"
+  #   query = "*[System[(Level <= 3)]]"
+  #
+  # [[inputs.windows_event.channels]]
+  #   name     = "Security"
+  #   query    = "*"
+  #   suppress = ["*[System[(EventID >= 5152 and EventID <= 5158)]]"]
+
   [inputs.windows_event.tags]
   # some_tag = "some_value"
   # more_tag = "some_other_value"
@@ -75,6 +88,7 @@ func (m *Measurement) Info() *inputs.MeasurementInfo {
 			"computer":        inputs.NewTagInfo("计算机"),
 			"message":         inputs.NewTagInfo("事件内容"),
 			"level":           inputs.NewTagInfo("级别"),
+			"opcode":          inputs.NewTagInfo("操作码"),
 			"total_message":   inputs.NewTagInfo("事件全文"),
 		},
 	}
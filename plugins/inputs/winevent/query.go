@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package winevent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+)
+
+// Channel is an event channel subscribed with its own XPath filters.
+type Channel struct {
+	Name     string   `toml:"name"`
+	Query    string   `toml:"query"`    // XPath of events selected, all events if empty
+	Suppress []string `toml:"suppress"` // XPath of events excluded
+}
+
+// buildQuery builds the structured XML query of the channels.
+func buildQuery(channels []*Channel) (string, error) {
+	var buf bytes.Buffer
+
+	escape := func(s string) string {
+		var b bytes.Buffer
+		_ = xml.EscapeText(&b, []byte(s)) // never fail on bytes.Buffer
+		return b.String()
+	}
+
+	buf.WriteString("<QueryList>\n")
+	for i, ch := range channels {
+		name := strings.TrimSpace(ch.Name)
+		if name == "" {
+			return "", fmt.Errorf("name of the %dth channel not set", i)
+		}
+
+		sel := strings.TrimSpace(ch.Query)
+		if sel == "" {
+			sel = "*"
+		}
+
+		fmt.Fprintf(&buf, "  <Query Id=\"%d\" Path=\"%s\">\n", i, escape(name))
+		fmt.Fprintf(&buf, "    <Select Path=\"%s\">%s</Select>\n", escape(name), escape(sel))
+		for _, s := range ch.Suppress {
+			if s = strings.TrimSpace(s); s != "" {
+				fmt.Fprintf(&buf, "    <Suppress Path=\"%s\">%s</Suppress>\n", escape(name), escape(s))
+			}
+		}
+		buf.WriteString("  </Query>\n")
+	}
+	buf.WriteString("</QueryList>")
+
+	return buf.String(), nil
+}
+
+// bookmarkPath returns the file the bookmark of the query saved in. The
+// bookmark is bound to the query: a bookmark of other query may point to
+// an event the query never selected.
+func bookmarkPath(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return filepath.Join(datakit.DataDir, inputName, "bookmark-"+hex.EncodeToString(sum[:8])+".xml")
+}
+
+// loadBookmark returns the bookmark XML saved, empty if not saved yet.
+func loadBookmark(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func saveBookmark(path, bookmark string) error {
+	if err := os.MkdirAll(filepath.Dir(path), datakit.ConfPerm); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(bookmark), datakit.ConfPerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// eventDataText renders the EventData/UserData of the event as `name: value`
+// lines, used as the message if the publisher has no message string for
+// the event.
+func eventDataText(innerXML []byte) string {
+	var (
+		lines []string
+		names []string
+		dec   = xml.NewDecoder(bytes.NewReader(innerXML))
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		switch x := tok.(type) {
+		case xml.StartElement:
+			name := x.Name.Local
+			for _, attr := range x.Attr {
+				if attr.Name.Local == "Name" { // <Data Name="xxx">
+					name = attr.Value
+				}
+			}
+			names = append(names, name)
+
+		case xml.EndElement:
+			if len(names) > 0 {
+				names = names[:len(names)-1]
+			}
+
+		case xml.CharData:
+			v := strings.TrimSpace(string(x))
+			if v == "" {
+				continue
+			}
+
+			if len(names) > 0 {
+				lines = append(lines, names[len(names)-1]+": "+v)
+			} else {
+				lines = append(lines, v)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the MIT License.
+// This product includes software developed at Guance Cloud (https://www.guance.com/).
+// Copyright 2021-present Guance, Inc.
+
+package winevent
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildQuery(t *testing.T) {
+	q, err := buildQuery([]*Channel{
+		{Name: "Application", Query: "*[System[(Level <= 3)]]"},
+		{Name: "Security", Suppress: []string{"*[System[(EventID >= 5152 and EventID <= 5158)]]", " "}},
+	})
+	require.NoError(t, err)
+
+	var ql struct {
+		Queries []struct {
+			ID       int    `xml:"Id,attr"`
+			Path     string `xml:"Path,attr"`
+			Select   string `xml:"Select"`
+			Suppress []string
+		} `xml:"Query"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(q), &ql))
+	require.Len(t, ql.Queries, 2)
+
+	assert.Equal(t, "Application", ql.Queries[0].Path)
+	assert.Equal(t, "*[System[(Level <= 3)]]", ql.Queries[0].Select)
+	assert.Empty(t, ql.Queries[0].Suppress)
+
+	assert.Equal(t, 1, ql.Queries[1].ID)
+	assert.Equal(t, "*", ql.Queries[1].Select)
+	assert.Equal(t, []string{"*[System[(EventID >= 5152 and EventID <= 5158)]]"}, ql.Queries[1].Suppress)
+
+	_, err = buildQuery([]*Channel{{Query: "*"}})
+	assert.Error(t, err)
+}
+
+func TestBookmark(t *testing.T) {
+	assert.Equal(t, bookmarkPath("q1"), bookmarkPath("q1"))
+	assert.NotEqual(t, bookmarkPath("q1"), bookmarkPath("q2"))
+
+	path := filepath.Join(t.TempDir(), inputName, "bookmark.xml")
+
+	bm, err := loadBookmark(path)
+	require.NoError(t, err)
+	assert.Empty(t, bm)
+
+	x := `<BookmarkList><Bookmark Channel="Application" RecordId="1024" IsCurrent="true"/></BookmarkList>`
+	require.NoError(t, saveBookmark(path, x))
+
+	bm, err = loadBookmark(path)
+	require.NoError(t, err)
+	assert.Equal(t, x, bm)
+}
+
+func TestEventDataText(t *testing.T) {
+	assert.Equal(t, "SubjectUserName: SYSTEM\nLogonType: 5",
+		eventDataText([]byte(`<Data Name='SubjectUserName'>SYSTEM</Data><Data Name='LogonType'>5</Data><Data Name='IpAddress'> </Data>`)))
+
+	assert.Equal(t, "Param1: svc\nBinary: 0A",
+		eventDataText([]byte(`<EventXML xmlns="Event_NS"><Param1>svc</Param1><Binary>0A</Binary></EventXML>`)))
+
+	assert.Equal(t, "", eventDataText(nil))
+}
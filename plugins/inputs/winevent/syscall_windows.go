@@ -26,7 +26,8 @@ type EvtSubscribeFlag uint32
 // EVT_SUBSCRIBE_FLAGS enumeration
 // https://msdn.microsoft.com/en-us/library/windows/desktop/aa385588(v=vs.85).aspx
 const (
-	EvtSubscribeToFutureEvents EvtSubscribeFlag = 1
+	EvtSubscribeToFutureEvents     EvtSubscribeFlag = 1
+	EvtSubscribeStartAfterBookmark EvtSubscribeFlag = 3
 )
 
 // EvtRenderFlag uint32.
@@ -39,5 +40,8 @@ const (
 	// Render the event as an XML string. For details on the contents of the
 	// XML string, see the Event schema.
 	EvtRenderEventXML EvtRenderFlag = 1
+	// Render the bookmark as an XML string, so that you can easily persist the
+	// bookmark for use later.
+	EvtRenderBookmark EvtRenderFlag = 2
 	//revive:enable:var-naming
 )
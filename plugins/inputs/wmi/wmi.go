@@ -10,7 +10,6 @@ package wmi
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"github.com/GuanceCloud/cliutils"
@@ -105,10 +104,8 @@ func (ag *Instance) run(ctx context.Context) error {
 				continue
 			}
 
-			hostname, _ := os.Hostname()
-
 			tags := map[string]string{
-				"host": hostname,
+				"host": datakit.DatakitHostName,
 			}
 
 			for k, v := range ag.Tags {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/GuanceCloud/cliutils/logger"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit"
+	"gitlab.jiagouyun.com/cloudcare-tools/datakit/config"
 	dkhttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/http"
 	ihttp "gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/http"
 	"gitlab.jiagouyun.com/cloudcare-tools/datakit/internal/storage"
@@ -62,6 +63,30 @@ const (
   # [inputs.zipkin.sampler]
     # sampling_rate = 1.0
 
+  ## Tail sampling buffers spans of a trace for decision_wait, then keeps the whole trace
+  ## if it contains error spans, lasts longer than latency_threshold or matches any policy,
+  ## the other traces are kept by sampling_rate. [inputs.zipkin.sampler] and error penetration
+  ## are ignored once tail sampling is enabled.
+  # [inputs.zipkin.tail_sampling]
+    # decision_wait = "10s"
+    # max_traces = 50000
+    # keep_error = true
+    # latency_threshold = "2s"
+    # sampling_rate = 0.1
+    # [[inputs.zipkin.tail_sampling.policies]]
+      # name = "checkout"
+      # service = "checkout"
+      # resource = "^POST /pay"
+      # [inputs.zipkin.tail_sampling.policies.tags]
+        # "http.status_code" = "429"
+
+  ## Span metrics generates RED(requests, errors and duration) metrics per service and resource
+  ## from all spans received, before filters and sampling applied, fed as metric "tracing_metrics".
+  ## extra_tags are span tags used as extra dimensions.
+  # [inputs.zipkin.span_metrics]
+    # interval = "60s"
+    # extra_tags = ["http_method"]
+
   # [inputs.zipkin.tags]
     # key1 = "value1"
     # key2 = "value2"
@@ -87,6 +112,7 @@ var (
 	log            = logger.DefaultSLogger(inputName)
 	apiv1Path      = "/api/v1/spans"
 	apiv2Path      = "/api/v2/spans"
+	afterGather    *itrace.AfterGather
 	afterGatherRun itrace.AfterGatherHandler
 	customerKeys   []string
 	tags           map[string]string
@@ -102,6 +128,8 @@ type Input struct {
 	KeepRareResource bool                         `toml:"keep_rare_resource"`
 	CloseResource    map[string][]string          `toml:"close_resource"`
 	Sampler          *itrace.Sampler              `toml:"sampler"`
+	TailSampling     *itrace.TailSampling         `toml:"tail_sampling"`
+	SpanMetrics      *itrace.SpanMetrics          `toml:"span_metrics"`
 	Tags             map[string]string            `toml:"tags"`
 	WPConfig         *workerpool.WorkerPoolConfig `toml:"threads"`
 	LocalCacheConfig *storage.StorageConfig       `toml:"storage"`
@@ -114,7 +142,7 @@ func (*Input) AvailableArchs() []string { return datakit.AllOS }
 func (*Input) SampleConfig() string { return sampleConfig }
 
 func (*Input) SampleMeasurement() []inputs.Measurement {
-	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}}
+	return []inputs.Measurement{&itrace.TraceMeasurement{Name: inputName}, &itrace.SpanMetricsMeasurement{}}
 }
 
 func (ipt *Input) RegHTTPHandler() {
@@ -202,12 +230,19 @@ func (ipt *Input) RegHTTPHandler() {
 		}
 	}
 
-	var afterGather *itrace.AfterGather
+	opts := []itrace.Option{itrace.WithLogger(log)}
 	if localCache != nil && localCache.Enabled() {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log), itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
-	} else {
-		afterGather = itrace.NewAfterGather(itrace.WithLogger(log))
+		opts = append(opts, itrace.WithRetry(100*time.Millisecond), itrace.WithBlockIOModel(true))
+	}
+	// pull per-service sampling rates configured in center
+	opts = append(opts, itrace.WithSamplingPull(config.Cfg.Dataway))
+	if ipt.TailSampling != nil {
+		opts = append(opts, itrace.WithTailSampling(ipt.TailSampling))
 	}
+	if ipt.SpanMetrics != nil {
+		opts = append(opts, itrace.WithSpanMetrics(ipt.SpanMetrics))
+	}
+	afterGather = itrace.NewAfterGather(opts...)
 	afterGatherRun = afterGather
 
 	// add filters: the order of appending filters into AfterGather is important!!!
@@ -218,8 +253,10 @@ func (ipt *Input) RegHTTPHandler() {
 		closeResource.UpdateIgnResList(ipt.CloseResource)
 		afterGather.AppendFilter(closeResource.Close)
 	}
-	// add error status penetration
-	afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	// add error status penetration, tail sampling keeps error traces by itself
+	if ipt.TailSampling == nil {
+		afterGather.AppendFilter(itrace.PenetrateErrorTracing)
+	}
 	// add rare resource keeper
 	if ipt.KeepRareResource {
 		keepRareResource := &itrace.KeepRareResource{}
@@ -227,13 +264,15 @@ func (ipt *Input) RegHTTPHandler() {
 		afterGather.AppendFilter(keepRareResource.Keep)
 	}
 	// add sampler
-	var sampler *itrace.Sampler
-	if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
-		sampler = ipt.Sampler
-	} else {
-		sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+	if ipt.TailSampling == nil {
+		var sampler *itrace.Sampler
+		if ipt.Sampler != nil && (ipt.Sampler.SamplingRateGlobal >= 0 && ipt.Sampler.SamplingRateGlobal <= 1) {
+			sampler = ipt.Sampler
+		} else {
+			sampler = &itrace.Sampler{SamplingRateGlobal: 1}
+		}
+		afterGather.AppendFilter(sampler.Sample)
 	}
-	afterGather.AppendFilter(sampler.Sample)
 
 	if ipt.PathV1 == "" {
 		ipt.PathV1 = apiv1Path
@@ -273,6 +312,10 @@ func (*Input) Terminate() {
 		}
 		log.Debug("### storage closed")
 	}
+	if afterGather != nil {
+		afterGather.Stop()
+		log.Debug("### after gather stopped")
+	}
 }
 
 func init() { //nolint:gochecknoinits